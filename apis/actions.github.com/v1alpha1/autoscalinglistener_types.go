@@ -66,10 +66,34 @@ type AutoscalingListenerSpec struct {
 
 	// +optional
 	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+
+	// Replicas is the number of listener pods. Only the elected leader listens for the jobs.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	Replicas int `json:"replicas,omitempty"`
+
+	// MaxAcquireJobsPerInterval limits the number of jobs acquired per AcquireJobsInterval.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	MaxAcquireJobsPerInterval *int `json:"maxAcquireJobsPerInterval,omitempty"`
+
+	// +optional
+	AcquireJobsInterval *metav1.Duration `json:"acquireJobsInterval,omitempty"`
+
+	// +optional
+	Federation *FederationMember `json:"federation,omitempty"`
+
+	// +optional
+	ListenerSession *ListenerSessionConfig `json:"listenerSession,omitempty"`
 }
 
 // AutoscalingListenerStatus defines the observed state of AutoscalingListener
-type AutoscalingListenerStatus struct{}
+type AutoscalingListenerStatus struct {
+	// ObservedGeneration is the generation of the listener whose spec the listener pods and their RBAC resources
+	// were last reconciled for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -41,6 +41,7 @@ import (
 // +kubebuilder:printcolumn:JSONPath=".status.runningEphemeralRunners",name=Running Runners,type=integer
 // +kubebuilder:printcolumn:JSONPath=".status.finishedEphemeralRunners",name=Finished Runners,type=integer
 // +kubebuilder:printcolumn:JSONPath=".status.deletingEphemeralRunners",name=Deleting Runners,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.paused",name=Paused,type=boolean,priority=1
 
 // AutoscalingRunnerSet is the Schema for the autoscalingrunnersets API
 type AutoscalingRunnerSet struct {
@@ -51,6 +52,13 @@ type AutoscalingRunnerSet struct {
 	Status AutoscalingRunnerSetStatus `json:"status,omitempty"`
 }
 
+const (
+	// DedicatedToDependabot dedicates a runner scale set to the jobs of Dependabot.
+	DedicatedToDependabot = "dependabot"
+	// DedicatedToCodeScanning dedicates a runner scale set to the jobs of the default setup of code scanning.
+	DedicatedToCodeScanning = "code-scanning"
+)
+
 // AutoscalingRunnerSetSpec defines the desired state of AutoscalingRunnerSet
 type AutoscalingRunnerSetSpec struct {
 	// Required
@@ -65,6 +73,12 @@ type AutoscalingRunnerSetSpec struct {
 	// +optional
 	RunnerScaleSetName string `json:"runnerScaleSetName,omitempty"`
 
+	// DedicatedTo dedicates the runner scale set to the jobs of Dependabot or of the default setup of code scanning.
+	// These jobs only run on runners labeled dependabot or code-scanning, so the runner scale set is named after the label.
+	// +optional
+	// +kubebuilder:validation:Enum=dependabot;code-scanning
+	DedicatedTo string `json:"dedicatedTo,omitempty"`
+
 	// +optional
 	Proxy *ProxyConfig `json:"proxy,omitempty"`
 
@@ -80,6 +94,34 @@ type AutoscalingRunnerSetSpec struct {
 	// +optional
 	ListenerTemplate *corev1.PodTemplateSpec `json:"listenerTemplate,omitempty"`
 
+	// ListenerReplicas is the number of listener pods of the scale set.
+	// When greater than 1, the listeners elect a leader that listens for the jobs
+	// while the others stand by to take over when it goes away.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	ListenerReplicas *int `json:"listenerReplicas,omitempty"`
+
+	// MaxAcquireJobsPerInterval limits the number of jobs the listener acquires per AcquireJobsInterval,
+	// so that a large scale set creates the runners gradually.
+	// The jobs over the limit are left for the other scale sets of the runner group.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	MaxAcquireJobsPerInterval *int `json:"maxAcquireJobsPerInterval,omitempty"`
+
+	// AcquireJobsInterval is the interval of MaxAcquireJobsPerInterval. Defaults to 1m.
+	// +optional
+	AcquireJobsInterval *metav1.Duration `json:"acquireJobsInterval,omitempty"`
+
+	// Federation makes the scale set a member of a RunnerScaleSetFederation,
+	// which splits the jobs of the runner scale set across the clusters running it.
+	// +optional
+	Federation *FederationMember `json:"federation,omitempty"`
+
+	// ListenerSession configures how the listener keeps its message session with the Actions service,
+	// for the networks whose proxies or load balancers kill the long polls of the listener.
+	// +optional
+	ListenerSession *ListenerSessionConfig `json:"listenerSession,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:Minimum:=0
 	MaxRunners *int `json:"maxRunners,omitempty"`
@@ -87,6 +129,41 @@ type AutoscalingRunnerSetSpec struct {
 	// +optional
 	// +kubebuilder:validation:Minimum:=0
 	MinRunners *int `json:"minRunners,omitempty"`
+
+	// ScaleDownPolicy configures which ephemeral runners are deleted first when the desired number of runners decreases.
+	// +optional
+	ScaleDownPolicy *ScaleDownPolicy `json:"scaleDownPolicy,omitempty"`
+
+	// WarmPool is the number of idle runners registered ahead of demand on top of the runners running jobs,
+	// refilled by the controller as soon as a runner of the pool picks up a job. The pool never exceeds MaxRunners.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	WarmPool *int `json:"warmPool,omitempty"`
+
+	// ImagePrePull makes the controller run a DaemonSet pre-pulling the images of the runners
+	// onto the nodes the runners can be scheduled on.
+	// +optional
+	ImagePrePull *ImagePrePull `json:"imagePrePull,omitempty"`
+
+	// CapacityDistribution splits the runners between the spot and the on-demand capacity with the node selectors and the tolerations of each.
+	// It takes effect without replacing the runners.
+	// +optional
+	CapacityDistribution *CapacityDistribution `json:"capacityDistribution,omitempty"`
+
+	// Paused freezes the scaling of the runner scale set, so that the number of the runners stays as it is when paused.
+	// The listener keeps receiving the jobs and updating the metrics while paused.
+	// The runner scale set can also be paused temporarily with the `actions.github.com/paused-until` annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// ImagePrePull configures the DaemonSet pre-pulling the images of the runners.
+// The images of the containers of the runner template are always pre-pulled.
+// Every image must have a shell, since the DaemonSet pulls the images by running `sh -c true` in them.
+type ImagePrePull struct {
+	// Images are pre-pulled along with the images of the runner template, for example the commonly used job container images.
+	// +optional
+	Images []string `json:"images,omitempty"`
 }
 
 type GitHubServerTLSConfig struct {
@@ -261,6 +338,70 @@ type HistogramMetric struct {
 	Buckets []float64 `json:"buckets,omitempty"`
 }
 
+// FederationMember configures the membership of a scale set in a RunnerScaleSetFederation.
+type FederationMember struct {
+	// Name is the name of the RunnerScaleSetFederation in the broker cluster.
+	// Required
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the RunnerScaleSetFederation and its lease in the broker cluster.
+	// Required
+	Namespace string `json:"namespace"`
+
+	// MemberName identifies the cluster among the members of the federation.
+	// Required
+	MemberName string `json:"memberName"`
+
+	// Weight is the share of the jobs the member gets relative to the other members.
+	// The members with 0 only get the jobs over the capacity of the weighted members.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	Weight *int `json:"weight,omitempty"`
+
+	// KubeconfigSecretName is the name of the secret in the namespace of the AutoscalingRunnerSet
+	// with the kubeconfig of the broker cluster in the "kubeconfig" key.
+	// Required
+	KubeconfigSecretName string `json:"kubeconfigSecretName"`
+}
+
+// ListenerSessionConfig configures the message session of the listener.
+type ListenerSessionConfig struct {
+	// Transport is how the listener gets the messages. With "long-poll", each request is kept open
+	// until the Actions service has a message or about 50 seconds pass.
+	// With "short-poll", each request ends after PollTimeout and a new one is started.
+	// Defaults to "long-poll".
+	// +optional
+	// +kubebuilder:validation:Enum=long-poll;short-poll
+	Transport string `json:"transport,omitempty"`
+
+	// PollTimeout is how long each request of the "short-poll" transport is kept open. Defaults to 25s.
+	// +optional
+	PollTimeout *metav1.Duration `json:"pollTimeout,omitempty"`
+
+	// FallbackAfterFailures switches the "long-poll" transport to "short-poll"
+	// after this many consecutive failures to get the messages. 0 never falls back.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	FallbackAfterFailures int `json:"fallbackAfterFailures,omitempty"`
+
+	// MaxReconnects is the number of consecutive times the listener re-establishes the session
+	// after failing to get the messages before it exits and gets restarted by the controller.
+	// 0 exits on the first failure.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxReconnects int `json:"maxReconnects,omitempty"`
+
+	// MaxReconnectBackoff caps the jittered exponential backoff between the reconnects. Defaults to 2m.
+	// +optional
+	MaxReconnectBackoff *metav1.Duration `json:"maxReconnectBackoff,omitempty"`
+
+	// KeepAlive is the interval of the TCP keep-alive probes of the connections to the Actions service,
+	// which keeps the idle long polls from being dropped by the middleboxes. Defaults to 30s.
+	// +optional
+	KeepAlive *metav1.Duration `json:"keepAlive,omitempty"`
+}
+
 // AutoscalingRunnerSetStatus defines the observed state of AutoscalingRunnerSet
 type AutoscalingRunnerSetStatus struct {
 	// +optional
@@ -277,10 +418,31 @@ type AutoscalingRunnerSetStatus struct {
 	RunningEphemeralRunners int `json:"runningEphemeralRunners"`
 	// +optional
 	FailedEphemeralRunners int `json:"failedEphemeralRunners"`
+
+	// Paused is true while the runner scale set is paused by either spec.paused or the paused-until annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// PausedUntil is when the pause by the paused-until annotation expires.
+	// +optional
+	PausedUntil *metav1.Time `json:"pausedUntil,omitempty"`
+
+	// ObservedGeneration is the generation of the runner scale set whose spec the runner counts, the pause
+	// and the conditions of the status were last reconciled for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the standard conditions of the runner scale set, Ready, Synced, GitHubRegistered and ScalingActive.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 func (ars *AutoscalingRunnerSet) ListenerSpecHash() string {
 	arsSpec := ars.Spec.DeepCopy()
+	// Pausing and resuming the scale set doesn't restart the listener
+	arsSpec.Paused = false
 	spec := arsSpec
 	return hash.ComputeTemplateHash(&spec)
 }
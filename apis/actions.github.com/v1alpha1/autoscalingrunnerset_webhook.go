@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// defaultRunnerGroup is the runner group the runner scale sets are added to when runnerGroup is empty.
+const defaultRunnerGroup = "default"
+
+func (ars *AutoscalingRunnerSet) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(ars).
+		WithValidator(&AutoscalingRunnerSetValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-actions-github-com-v1alpha1-autoscalingrunnerset,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.github.com,resources=autoscalingrunnersets,versions=v1alpha1,name=validate.autoscalingrunnerset.actions.github.com,sideEffects=None,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &AutoscalingRunnerSetValidator{}
+
+// AutoscalingRunnerSetValidator rejects an AutoscalingRunnerSet whose runner scale set would have the same name
+// as the runner scale set of another AutoscalingRunnerSet in the same runner group, as GitHub would reject it.
+type AutoscalingRunnerSetValidator struct {
+	Client client.Reader
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *AutoscalingRunnerSetValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ars, ok := obj.(*AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected AutoscalingRunnerSet object, got %T", obj)
+	}
+
+	return nil, v.validate(ctx, ars)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *AutoscalingRunnerSetValidator) ValidateUpdate(ctx context.Context, old, obj runtime.Object) (admission.Warnings, error) {
+	ars, ok := obj.(*AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected AutoscalingRunnerSet object, got %T", obj)
+	}
+	oldARS, ok := old.(*AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected AutoscalingRunnerSet object, got %T", old)
+	}
+
+	// Let the finalizers of a deleted AutoscalingRunnerSet be removed whatever its spec is.
+	if !ars.DeletionTimestamp.IsZero() || ars.Spec.runnerScaleSetKey(ars.Name) == oldARS.Spec.runnerScaleSetKey(oldARS.Name) {
+		return nil, ars.validate(nil)
+	}
+
+	return nil, v.validate(ctx, ars)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (*AutoscalingRunnerSetValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *AutoscalingRunnerSetValidator) validate(ctx context.Context, ars *AutoscalingRunnerSet) error {
+	var list AutoscalingRunnerSetList
+	if err := v.Client.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list autoscaling runner sets: %w", err)
+	}
+
+	var errList field.ErrorList
+
+	key := ars.Spec.runnerScaleSetKey(ars.Name)
+	for _, other := range list.Items {
+		if other.Namespace == ars.Namespace && other.Name == ars.Name {
+			continue
+		}
+		if other.Spec.runnerScaleSetKey(other.Name) != key {
+			continue
+		}
+
+		runnerGroup := ars.Spec.RunnerGroup
+		if runnerGroup == "" {
+			runnerGroup = defaultRunnerGroup
+		}
+		name := ars.Spec.runnerScaleSetName(ars.Name)
+		errList = append(errList, field.Invalid(field.NewPath("spec", "runnerScaleSetName"), name,
+			fmt.Sprintf("runner scale set %q in runner group %q of %s is already managed by AutoscalingRunnerSet %s/%s. Set a different runnerScaleSetName or runnerGroup",
+				name, runnerGroup, ars.Spec.GitHubConfigUrl, other.Namespace, other.Name)))
+	}
+
+	return ars.validate(errList)
+}
+
+// validate returns the errors of the other AutoscalingRunnerSets along with the errors of the spec.
+func (ars *AutoscalingRunnerSet) validate(errList field.ErrorList) error {
+	specPath := field.NewPath("spec")
+	if ars.Spec.MinRunners != nil && ars.Spec.MaxRunners != nil && *ars.Spec.MinRunners > *ars.Spec.MaxRunners {
+		errList = append(errList, field.Invalid(specPath.Child("minRunners"), *ars.Spec.MinRunners,
+			fmt.Sprintf("must be less than or equal to maxRunners (%d)", *ars.Spec.MaxRunners)))
+	}
+
+	if ars.Spec.CapacityDistribution != nil {
+		errList = append(errList, ars.Spec.CapacityDistribution.validate(specPath.Child("capacityDistribution"))...)
+	}
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AutoscalingRunnerSet").GroupKind(), ars.Name, errList)
+	}
+
+	return nil
+}
+
+// runnerScaleSetKey identifies a runner scale set in GitHub.
+type runnerScaleSetKey struct {
+	gitHubConfigURL string
+	runnerGroup     string
+	name            string
+}
+
+// runnerScaleSetKey returns the key of the runner scale set of the AutoscalingRunnerSet named name.
+// GitHub compares the urls, runner group names and runner scale set names case-insensitively.
+func (s *AutoscalingRunnerSetSpec) runnerScaleSetKey(name string) runnerScaleSetKey {
+	runnerGroup := s.RunnerGroup
+	if runnerGroup == "" {
+		runnerGroup = defaultRunnerGroup
+	}
+
+	return runnerScaleSetKey{
+		gitHubConfigURL: strings.ToLower(strings.TrimRight(s.GitHubConfigUrl, "/")),
+		runnerGroup:     strings.ToLower(runnerGroup),
+		name:            strings.ToLower(s.runnerScaleSetName(name)),
+	}
+}
+
+// runnerScaleSetName returns the name of the runner scale set of the AutoscalingRunnerSet named name.
+func (s *AutoscalingRunnerSetSpec) runnerScaleSetName(name string) string {
+	switch {
+	case s.RunnerScaleSetName != "":
+		return s.RunnerScaleSetName
+	case s.DedicatedTo != "":
+		return s.DedicatedTo
+	default:
+		return name
+	}
+}
@@ -0,0 +1,69 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAutoscalingRunnerSetValidator(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, AddToScheme(sc))
+
+	newARS := func(namespace, name, runnerGroup, runnerScaleSetName string) *AutoscalingRunnerSet {
+		return &AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				RunnerGroup:        runnerGroup,
+				RunnerScaleSetName: runnerScaleSetName,
+			},
+		}
+	}
+
+	existing := newARS("arc-runners", "arc-runner-set", "", "")
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(existing).Build()
+
+	v := &AutoscalingRunnerSetValidator{Client: c}
+
+	t.Run("unique name", func(t *testing.T) {
+		_, err := v.ValidateCreate(ctx, newARS("other", "arc-runner-set", "", "other"))
+		assert.NoError(t, err)
+
+		_, err = v.ValidateCreate(ctx, newARS("other", "arc-runner-set", "security", ""))
+		assert.NoError(t, err)
+	})
+
+	t.Run("duplicate name in the same runner group", func(t *testing.T) {
+		_, err := v.ValidateCreate(ctx, newARS("other", "arc-runner-set", "", ""))
+		assert.ErrorContains(t, err, `runner scale set "arc-runner-set" in runner group "default" of https://github.com/my-org is already managed by AutoscalingRunnerSet arc-runners/arc-runner-set`)
+
+		ars := newARS("other", "renamed", "Default", "ARC-Runner-Set")
+		ars.Spec.GitHubConfigUrl = "https://github.com/My-Org/"
+		_, err = v.ValidateCreate(ctx, ars)
+		assert.ErrorContains(t, err, "already managed by AutoscalingRunnerSet arc-runners/arc-runner-set")
+	})
+
+	t.Run("update of the same autoscaling runner set", func(t *testing.T) {
+		updated := existing.DeepCopy()
+		updated.Spec.RunnerScaleSetName = "renamed"
+		_, err := v.ValidateUpdate(ctx, existing, updated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("minRunners greater than maxRunners", func(t *testing.T) {
+		minRunners, maxRunners := 5, 3
+		ars := newARS("other", "other", "", "")
+		ars.Spec.MinRunners = &minRunners
+		ars.Spec.MaxRunners = &maxRunners
+		_, err := v.ValidateCreate(ctx, ars)
+		assert.ErrorContains(t, err, "spec.minRunners: Invalid value: 5: must be less than or equal to maxRunners (3)")
+	})
+}
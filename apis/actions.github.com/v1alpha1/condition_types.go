@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// The types of the conditions in status.conditions.
+// Each resource sets only the types that apply to it, and the reasons are CamelCase words for machines to match.
+const (
+	// ConditionTypeReady is true when the resource serves the workflow jobs as specified.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeSynced is true when the last reconciliation of the resource succeeded.
+	ConditionTypeSynced = "Synced"
+	// ConditionTypeGitHubRegistered is true when GitHub knows the runner, or the runner scale set.
+	ConditionTypeGitHubRegistered = "GitHubRegistered"
+	// ConditionTypeScalingActive is true when the number of the runners follows the demand.
+	ConditionTypeScalingActive = "ScalingActive"
+	// ConditionTypeRateLimited is true while the GitHub API rate limit holds back the reconciliation.
+	ConditionTypeRateLimited = "RateLimited"
+	// ConditionTypeListenerSessionHealthy is true while the listener gets the messages from the Actions service without reconnecting.
+	ConditionTypeListenerSessionHealthy = "ListenerSessionHealthy"
+)
+
+// The reasons of the conditions.
+const (
+	ConditionReasonReconciled      = "Reconciled"
+	ConditionReasonReconcileFailed = "ReconcileFailed"
+	ConditionReasonSpecDrifted     = "SpecDrifted"
+
+	ConditionReasonRegistered         = "Registered"
+	ConditionReasonRegistrationFailed = "RegistrationFailed"
+
+	ConditionReasonListenerRunning    = "ListenerRunning"
+	ConditionReasonWaitingForListener = "WaitingForListener"
+	ConditionReasonPaused             = "Paused"
+
+	ConditionReasonSessionHealthy      = "SessionHealthy"
+	ConditionReasonSessionReconnecting = "SessionReconnecting"
+)
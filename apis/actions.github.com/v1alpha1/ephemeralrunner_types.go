@@ -135,6 +135,11 @@ type EphemeralRunnerStatus struct {
 
 	// +optional
 	JobDisplayName string `json:"jobDisplayName,omitempty"`
+
+	// ObservedGeneration is the generation of the ephemeral runner whose pod the phase and the readiness
+	// of the status were last synced from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
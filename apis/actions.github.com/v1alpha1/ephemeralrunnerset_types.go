@@ -17,7 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // EphemeralRunnerSetSpec defines the desired state of EphemeralRunnerSet
@@ -28,6 +30,158 @@ type EphemeralRunnerSetSpec struct {
 	PatchID int `json:"patchID"`
 	// EphemeralRunnerSpec is the spec of the ephemeral runner
 	EphemeralRunnerSpec EphemeralRunnerSpec `json:"ephemeralRunnerSpec,omitempty"`
+	// ScaleDownPolicy configures which ephemeral runners are deleted first when the desired replicas decrease.
+	// +optional
+	ScaleDownPolicy *ScaleDownPolicy `json:"scaleDownPolicy,omitempty"`
+
+	// WarmPool keeps idle runners registered ahead of demand on top of the runners running jobs.
+	// +optional
+	WarmPool *WarmPool `json:"warmPool,omitempty"`
+
+	// CapacityDistribution splits the ephemeral runners between the spot and the on-demand capacity.
+	// +optional
+	CapacityDistribution *CapacityDistribution `json:"capacityDistribution,omitempty"`
+
+	// Paused keeps the number of the ephemeral runners as it is when paused, ignoring the replicas patched by the listener.
+	// The runners finishing jobs are replaced, and no runners are deleted for scaling down.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// WarmPool is a pool of idle ephemeral runners refilled as soon as a runner of the pool picks up a job,
+// without waiting for the listener to scale the EphemeralRunnerSet.
+type WarmPool struct {
+	// Size is the number of idle runners kept on top of the runners running jobs.
+	// +kubebuilder:validation:Minimum:=0
+	Size int `json:"size"`
+
+	// MaxReplicas caps the replicas created to refill the pool. 0 means no limit.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxReplicas int `json:"maxReplicas,omitempty"`
+}
+
+const (
+	// CapacityDistributionPolicyWeighted keeps the given percentage of the runners on the spot capacity.
+	CapacityDistributionPolicyWeighted = "Weighted"
+
+	// CapacityDistributionPolicySpotFirst puts all the runners on the spot capacity,
+	// and falls back to the on-demand capacity while the spot capacity is unavailable.
+	CapacityDistributionPolicySpotFirst = "SpotFirst"
+
+	CapacityTypeSpot     = "spot"
+	CapacityTypeOnDemand = "on-demand"
+)
+
+// CapacityDistribution is how the ephemeral runners are split between the spot and the on-demand capacity.
+type CapacityDistribution struct {
+	// Policy is either Weighted or SpotFirst. Defaults to Weighted.
+	// +optional
+	// +kubebuilder:validation:Enum=Weighted;SpotFirst
+	Policy string `json:"policy,omitempty"`
+
+	// SpotPercentage is the percentage of the runners put on the spot capacity by the Weighted policy.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	SpotPercentage int `json:"spotPercentage,omitempty"`
+
+	// Spot is how the runner pods are scheduled onto the spot capacity.
+	Spot CapacitySubset `json:"spot"`
+
+	// OnDemand is how the runner pods are scheduled onto the on-demand capacity.
+	OnDemand CapacitySubset `json:"onDemand"`
+
+	// FallbackAfter is how long a runner pod of the SpotFirst policy can stay unschedulable on the spot capacity
+	// before it's replaced with an on-demand runner. Defaults to 3m.
+	// +optional
+	FallbackAfter *metav1.Duration `json:"fallbackAfter,omitempty"`
+
+	// SpotRetryInterval is how long the SpotFirst policy stays on the on-demand capacity after falling back,
+	// before it tries the spot capacity again and moves the idle on-demand runners back. Defaults to 15m.
+	// +optional
+	SpotRetryInterval *metav1.Duration `json:"spotRetryInterval,omitempty"`
+}
+
+// CapacitySubset is the scheduling of the runner pods onto a capacity type, added to the ones of the runner template.
+type CapacitySubset struct {
+	// NodeSelector selects the nodes of the capacity type, like karpenter.sh/capacity-type: spot.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations tolerate the taints of the nodes of the capacity type.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+func (d *CapacityDistribution) validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if d.Policy == CapacityDistributionPolicySpotFirst && d.SpotPercentage != 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("spotPercentage"), d.SpotPercentage, "must not be set with the SpotFirst policy"))
+	}
+
+	if len(d.Spot.NodeSelector) == 0 && len(d.Spot.Tolerations) == 0 {
+		errList = append(errList, field.Required(rootPath.Child("spot"), "either nodeSelector or tolerations is required to schedule the runner pods onto the spot capacity"))
+	}
+
+	validate := func(name string, d *metav1.Duration) {
+		if d != nil && d.Duration <= 0 {
+			errList = append(errList, field.Invalid(rootPath.Child(name), d.Duration.String(), "must be greater than 0"))
+		}
+	}
+
+	validate("fallbackAfter", d.FallbackAfter)
+	validate("spotRetryInterval", d.SpotRetryInterval)
+
+	return errList
+}
+
+// CapacityDistributionStatus is the number of the runners on each capacity type.
+type CapacityDistributionStatus struct {
+	// Spot is the number of the runners on the spot capacity.
+	Spot int `json:"spot"`
+
+	// OnDemand is the number of the runners on the on-demand capacity.
+	OnDemand int `json:"onDemand"`
+
+	// SpotUnavailableSince is when the SpotFirst policy fell back to the on-demand capacity.
+	// It's cleared once the spot capacity is tried again after spotRetryInterval.
+	// +optional
+	SpotUnavailableSince *metav1.Time `json:"spotUnavailableSince,omitempty"`
+}
+
+const (
+	// UnregisteredRunnersDeleteFirst deletes the runners not yet registered with the Actions service before any other runners.
+	UnregisteredRunnersDeleteFirst = "DeleteFirst"
+	// UnregisteredRunnersKeep keeps the runners not yet registered with the Actions service until they are registered.
+	UnregisteredRunnersKeep = "Keep"
+
+	// IdleRunnerOrderOldestFirst deletes the oldest idle runners first.
+	IdleRunnerOrderOldestFirst = "OldestFirst"
+	// IdleRunnerOrderNewestFirst deletes the newest idle runners first.
+	IdleRunnerOrderNewestFirst = "NewestFirst"
+)
+
+// ScaleDownPolicy configures the order the ephemeral runners are deleted in on scale down.
+// The pending runners are deleted first, then the idle running runners.
+// The runners running jobs, and by default the runners not yet registered with the Actions service, are never deleted.
+type ScaleDownPolicy struct {
+	// UnregisteredRunners is either Keep or DeleteFirst. Defaults to Keep.
+	// +optional
+	// +kubebuilder:validation:Enum=DeleteFirst;Keep
+	UnregisteredRunners string `json:"unregisteredRunners,omitempty"`
+
+	// IdleRunnerOrder is the order of deleting the registered idle runners by their age, either OldestFirst or NewestFirst.
+	// Defaults to OldestFirst.
+	// +optional
+	// +kubebuilder:validation:Enum=OldestFirst;NewestFirst
+	IdleRunnerOrder string `json:"idleRunnerOrder,omitempty"`
+
+	// MinIdleRunnerAge prevents the registered idle runners younger than it from being deleted,
+	// so that the runners created for a burst of jobs aren't deleted before picking up the jobs.
+	// +optional
+	MinIdleRunnerAge *metav1.Duration `json:"minIdleRunnerAge,omitempty"`
 }
 
 // EphemeralRunnerSetStatus defines the observed state of EphemeralRunnerSet
@@ -40,6 +194,16 @@ type EphemeralRunnerSetStatus struct {
 	RunningEphemeralRunners int `json:"runningEphemeralRunners"`
 	// +optional
 	FailedEphemeralRunners int `json:"failedEphemeralRunners"`
+	// CapacityDistribution is the number of the runners on each capacity type, available only when spec.capacityDistribution is set.
+	// +optional
+	CapacityDistribution *CapacityDistributionStatus `json:"capacityDistribution,omitempty"`
+	// PausedReplicas is the number of the ephemeral runners kept while spec.paused is set.
+	// +optional
+	PausedReplicas *int `json:"pausedReplicas,omitempty"`
+	// ObservedGeneration is the generation of the ephemeral runner set whose replicas the runner counts of the status
+	// were last reconciled against. Every patch of the replicas by the listener bumps the generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
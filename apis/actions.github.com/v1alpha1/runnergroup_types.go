@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RunnerGroupVisibilityAll allows all the repositories of the organization to use the runner group.
+	RunnerGroupVisibilityAll = "all"
+	// RunnerGroupVisibilitySelected allows the repositories listed in the runner group to use it.
+	RunnerGroupVisibilitySelected = "selected"
+	// RunnerGroupVisibilityPrivate allows the private repositories of the organization to use the runner group.
+	RunnerGroupVisibilityPrivate = "private"
+)
+
+// RunnerGroupSpec defines the desired state of RunnerGroup
+type RunnerGroupSpec struct {
+	// GitHubConfigUrl is the url of the organization of the runner group.
+	// Required
+	GitHubConfigUrl string `json:"githubConfigUrl,omitempty"`
+
+	// GitHubConfigSecret is the secret with the credentials allowed to administer the runner groups of the organization.
+	// Required
+	GitHubConfigSecret string `json:"githubConfigSecret,omitempty"`
+
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+
+	// +optional
+	GitHubServerTLS *GitHubServerTLSConfig `json:"githubServerTLS,omitempty"`
+
+	// Name is the name of the runner group in GitHub. Defaults to the name of the RunnerGroup.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Visibility is either all, selected, or private. Defaults to all.
+	// +optional
+	// +kubebuilder:validation:Enum=all;selected;private
+	Visibility string `json:"visibility,omitempty"`
+
+	// Repositories are the names of the repositories of the organization allowed to use the runner group
+	// when the visibility is selected.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// AllowsPublicRepositories allows the public repositories to use the runner group.
+	// +optional
+	AllowsPublicRepositories bool `json:"allowsPublicRepositories,omitempty"`
+
+	// SelectedWorkflows restricts the runner group to the given workflows,
+	// e.g. my-org/my-repo/.github/workflows/build.yaml@refs/heads/main.
+	// +optional
+	SelectedWorkflows []string `json:"selectedWorkflows,omitempty"`
+}
+
+// RunnerGroupStatus defines the observed state of RunnerGroup
+type RunnerGroupStatus struct {
+	// ID is the id of the runner group in GitHub.
+	// +optional
+	ID int64 `json:"id,omitempty"`
+
+	// ObservedGeneration is the generation of the spec last applied to the runner group in GitHub.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.id",name=ID,type=integer
+// +kubebuilder:printcolumn:JSONPath=".spec.visibility",name=Visibility,type=string
+
+// RunnerGroup is the Schema for the runnergroups API.
+// The controller creates the runner group in the organization if it is missing, and keeps its access settings up to date.
+type RunnerGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunnerGroupSpec   `json:"spec,omitempty"`
+	Status RunnerGroupStatus `json:"status,omitempty"`
+}
+
+// GitHubName returns the name of the runner group in GitHub.
+func (rg *RunnerGroup) GitHubName() string {
+	if rg.Spec.Name != "" {
+		return rg.Spec.Name
+	}
+	return rg.Name
+}
+
+// +kubebuilder:object:root=true
+
+// RunnerGroupList contains a list of RunnerGroup
+type RunnerGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunnerGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunnerGroup{}, &RunnerGroupList{})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunnerInventorySpec defines the desired state of RunnerInventory
+type RunnerInventorySpec struct {
+	// GitHubConfigUrl is the url of the enterprise, organization or repository to list the runners of.
+	// Required
+	GitHubConfigUrl string `json:"githubConfigUrl,omitempty"`
+}
+
+// RunnerInventoryStatus defines the observed state of RunnerInventory
+type RunnerInventoryStatus struct {
+	// Runners are the runners registered by this installation under the GitHub config url, sorted by namespace and name.
+	// +optional
+	Runners []InventoryRunner `json:"runners,omitempty"`
+
+	// +optional
+	TotalRunners int `json:"totalRunners"`
+
+	// +optional
+	BusyRunners int `json:"busyRunners"`
+}
+
+// InventoryRunner is a runner registered in GitHub by an EphemeralRunner of this installation.
+type InventoryRunner struct {
+	// ID is the id of the runner in GitHub.
+	ID int `json:"id"`
+
+	// Name is the name of the runner in GitHub.
+	Name string `json:"name"`
+
+	// GitHubConfigUrl is the url the runner is registered at.
+	GitHubConfigUrl string `json:"githubConfigUrl"`
+
+	// RunnerScaleSet is the name of the runner scale set of the runner in GitHub.
+	// +optional
+	RunnerScaleSet string `json:"runnerScaleSet,omitempty"`
+
+	// Busy is true when the runner has been assigned a job.
+	Busy bool `json:"busy"`
+
+	// Version is the tag of the runner image, if any.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Labels are the labels of the runner in GitHub.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Pod is the pod running the runner.
+	Pod InventoryRunnerPod `json:"pod"`
+
+	// +optional
+	Phase corev1.PodPhase `json:"phase,omitempty"`
+}
+
+// InventoryRunnerPod references the pod of a runner.
+type InventoryRunnerPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".spec.githubConfigUrl",name="GitHub Config URL",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.totalRunners",name=Runners,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.busyRunners",name=Busy,type=integer
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RunnerInventory is the Schema for the runnerinventories API.
+// The controller lists the runners registered by this installation under the GitHub config url in its status.
+type RunnerInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunnerInventorySpec   `json:"spec,omitempty"`
+	Status RunnerInventoryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RunnerInventoryList contains a list of RunnerInventory
+type RunnerInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunnerInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunnerInventory{}, &RunnerInventoryList{})
+}
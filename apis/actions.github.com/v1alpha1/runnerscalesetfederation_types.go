@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunnerScaleSetFederationSpec defines the desired state of RunnerScaleSetFederation
+type RunnerScaleSetFederationSpec struct {
+	// MemberTimeout is how long a member can go without a heartbeat before the broker stops assigning jobs to it.
+	// Defaults to 1m.
+	// +optional
+	MemberTimeout *metav1.Duration `json:"memberTimeout,omitempty"`
+}
+
+// RunnerScaleSetFederationStatus defines the observed state of RunnerScaleSetFederation
+type RunnerScaleSetFederationStatus struct {
+	// Broker is the member whose listener holds the lease and splits the jobs across the members.
+	// +optional
+	Broker string `json:"broker,omitempty"`
+
+	// AssignedJobs is the number of jobs assigned to the runner scale set when the broker last split them.
+	// +optional
+	AssignedJobs int `json:"assignedJobs,omitempty"`
+
+	// SplitGeneration is incremented every time the broker splits the assigned jobs across the members.
+	// +optional
+	SplitGeneration int64 `json:"splitGeneration,omitempty"`
+
+	// +optional
+	Members []RunnerScaleSetFederationMemberStatus `json:"members,omitempty"`
+}
+
+// RunnerScaleSetFederationMemberStatus is the state of a member cluster reported by its listener,
+// along with its share of the jobs decided by the broker.
+type RunnerScaleSetFederationMemberStatus struct {
+	Name string `json:"name"`
+
+	// +optional
+	Weight int `json:"weight,omitempty"`
+
+	// Capacity is the number of jobs the member can run on top of its minRunners.
+	// +optional
+	Capacity int `json:"capacity,omitempty"`
+
+	// +optional
+	LastHeartbeatTime metav1.Time `json:"lastHeartbeatTime,omitempty"`
+
+	// AssignedJobs is the share of the jobs assigned to the member by the broker.
+	// +optional
+	AssignedJobs int `json:"assignedJobs,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.broker",name=Broker,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.assignedJobs",name=Assigned Jobs,type=integer
+
+// RunnerScaleSetFederation is the Schema for the runnerscalesetfederations API.
+// It coordinates the AutoscalingRunnerSets of multiple clusters sharing a runner scale set.
+type RunnerScaleSetFederation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunnerScaleSetFederationSpec   `json:"spec,omitempty"`
+	Status RunnerScaleSetFederationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RunnerScaleSetFederationList contains a list of RunnerScaleSetFederation
+type RunnerScaleSetFederationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunnerScaleSetFederation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunnerScaleSetFederation{}, &RunnerScaleSetFederationList{})
+}
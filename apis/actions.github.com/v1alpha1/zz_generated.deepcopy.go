@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -112,6 +113,26 @@ func (in *AutoscalingListenerSpec) DeepCopyInto(out *AutoscalingListenerSpec) {
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MaxAcquireJobsPerInterval != nil {
+		in, out := &in.MaxAcquireJobsPerInterval, &out.MaxAcquireJobsPerInterval
+		*out = new(int)
+		**out = **in
+	}
+	if in.AcquireJobsInterval != nil {
+		in, out := &in.AcquireJobsInterval, &out.AcquireJobsInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(FederationMember)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ListenerSession != nil {
+		in, out := &in.ListenerSession, &out.ListenerSession
+		*out = new(ListenerSessionConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingListenerSpec.
@@ -145,7 +166,7 @@ func (in *AutoscalingRunnerSet) DeepCopyInto(out *AutoscalingRunnerSet) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSet.
@@ -222,6 +243,31 @@ func (in *AutoscalingRunnerSetSpec) DeepCopyInto(out *AutoscalingRunnerSetSpec)
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ListenerReplicas != nil {
+		in, out := &in.ListenerReplicas, &out.ListenerReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxAcquireJobsPerInterval != nil {
+		in, out := &in.MaxAcquireJobsPerInterval, &out.MaxAcquireJobsPerInterval
+		*out = new(int)
+		**out = **in
+	}
+	if in.AcquireJobsInterval != nil {
+		in, out := &in.AcquireJobsInterval, &out.AcquireJobsInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(FederationMember)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ListenerSession != nil {
+		in, out := &in.ListenerSession, &out.ListenerSession
+		*out = new(ListenerSessionConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MaxRunners != nil {
 		in, out := &in.MaxRunners, &out.MaxRunners
 		*out = new(int)
@@ -232,6 +278,26 @@ func (in *AutoscalingRunnerSetSpec) DeepCopyInto(out *AutoscalingRunnerSetSpec)
 		*out = new(int)
 		**out = **in
 	}
+	if in.ScaleDownPolicy != nil {
+		in, out := &in.ScaleDownPolicy, &out.ScaleDownPolicy
+		*out = new(ScaleDownPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarmPool != nil {
+		in, out := &in.WarmPool, &out.WarmPool
+		*out = new(int)
+		**out = **in
+	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePull)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistribution)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSetSpec.
@@ -247,6 +313,17 @@ func (in *AutoscalingRunnerSetSpec) DeepCopy() *AutoscalingRunnerSetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoscalingRunnerSetStatus) DeepCopyInto(out *AutoscalingRunnerSetStatus) {
 	*out = *in
+	if in.PausedUntil != nil {
+		in, out := &in.PausedUntil, &out.PausedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingRunnerSetStatus.
@@ -259,6 +336,81 @@ func (in *AutoscalingRunnerSetStatus) DeepCopy() *AutoscalingRunnerSetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityDistribution) DeepCopyInto(out *CapacityDistribution) {
+	*out = *in
+	in.Spot.DeepCopyInto(&out.Spot)
+	in.OnDemand.DeepCopyInto(&out.OnDemand)
+	if in.FallbackAfter != nil {
+		in, out := &in.FallbackAfter, &out.FallbackAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SpotRetryInterval != nil {
+		in, out := &in.SpotRetryInterval, &out.SpotRetryInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityDistribution.
+func (in *CapacityDistribution) DeepCopy() *CapacityDistribution {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityDistribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityDistributionStatus) DeepCopyInto(out *CapacityDistributionStatus) {
+	*out = *in
+	if in.SpotUnavailableSince != nil {
+		in, out := &in.SpotUnavailableSince, &out.SpotUnavailableSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityDistributionStatus.
+func (in *CapacityDistributionStatus) DeepCopy() *CapacityDistributionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityDistributionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacitySubset) DeepCopyInto(out *CapacitySubset) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacitySubset.
+func (in *CapacitySubset) DeepCopy() *CapacitySubset {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacitySubset)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CounterMetric) DeepCopyInto(out *CounterMetric) {
 	*out = *in
@@ -344,7 +496,7 @@ func (in *EphemeralRunnerSet) DeepCopyInto(out *EphemeralRunnerSet) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSet.
@@ -401,6 +553,21 @@ func (in *EphemeralRunnerSetList) DeepCopyObject() runtime.Object {
 func (in *EphemeralRunnerSetSpec) DeepCopyInto(out *EphemeralRunnerSetSpec) {
 	*out = *in
 	in.EphemeralRunnerSpec.DeepCopyInto(&out.EphemeralRunnerSpec)
+	if in.ScaleDownPolicy != nil {
+		in, out := &in.ScaleDownPolicy, &out.ScaleDownPolicy
+		*out = new(ScaleDownPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarmPool != nil {
+		in, out := &in.WarmPool, &out.WarmPool
+		*out = new(WarmPool)
+		**out = **in
+	}
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistribution)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetSpec.
@@ -416,6 +583,16 @@ func (in *EphemeralRunnerSetSpec) DeepCopy() *EphemeralRunnerSetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EphemeralRunnerSetStatus) DeepCopyInto(out *EphemeralRunnerSetStatus) {
 	*out = *in
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistributionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PausedReplicas != nil {
+		in, out := &in.PausedReplicas, &out.PausedReplicas
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralRunnerSetStatus.
@@ -476,6 +653,26 @@ func (in *EphemeralRunnerStatus) DeepCopy() *EphemeralRunnerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationMember) DeepCopyInto(out *FederationMember) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationMember.
+func (in *FederationMember) DeepCopy() *FederationMember {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GaugeMetric) DeepCopyInto(out *GaugeMetric) {
 	*out = *in
@@ -541,6 +738,92 @@ func (in *HistogramMetric) DeepCopy() *HistogramMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePrePull) DeepCopyInto(out *ImagePrePull) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePrePull.
+func (in *ImagePrePull) DeepCopy() *ImagePrePull {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePrePull)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryRunner) DeepCopyInto(out *InventoryRunner) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Pod = in.Pod
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryRunner.
+func (in *InventoryRunner) DeepCopy() *InventoryRunner {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryRunner)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryRunnerPod) DeepCopyInto(out *InventoryRunnerPod) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryRunnerPod.
+func (in *InventoryRunnerPod) DeepCopy() *InventoryRunnerPod {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryRunnerPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListenerSessionConfig) DeepCopyInto(out *ListenerSessionConfig) {
+	*out = *in
+	if in.PollTimeout != nil {
+		in, out := &in.PollTimeout, &out.PollTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxReconnectBackoff != nil {
+		in, out := &in.MaxReconnectBackoff, &out.MaxReconnectBackoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.KeepAlive != nil {
+		in, out := &in.KeepAlive, &out.KeepAlive
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListenerSessionConfig.
+func (in *ListenerSessionConfig) DeepCopy() *ListenerSessionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ListenerSessionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsConfig) DeepCopyInto(out *MetricsConfig) {
 	*out = *in
@@ -649,6 +932,348 @@ func (in *ProxyServerConfig) DeepCopy() *ProxyServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroup) DeepCopyInto(out *RunnerGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroup.
+func (in *RunnerGroup) DeepCopy() *RunnerGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupList) DeepCopyInto(out *RunnerGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupList.
+func (in *RunnerGroupList) DeepCopy() *RunnerGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupSpec) DeepCopyInto(out *RunnerGroupSpec) {
+	*out = *in
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitHubServerTLS != nil {
+		in, out := &in.GitHubServerTLS, &out.GitHubServerTLS
+		*out = new(GitHubServerTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SelectedWorkflows != nil {
+		in, out := &in.SelectedWorkflows, &out.SelectedWorkflows
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupSpec.
+func (in *RunnerGroupSpec) DeepCopy() *RunnerGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerGroupStatus) DeepCopyInto(out *RunnerGroupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGroupStatus.
+func (in *RunnerGroupStatus) DeepCopy() *RunnerGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerInventory) DeepCopyInto(out *RunnerInventory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerInventory.
+func (in *RunnerInventory) DeepCopy() *RunnerInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerInventory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerInventoryList) DeepCopyInto(out *RunnerInventoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerInventory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerInventoryList.
+func (in *RunnerInventoryList) DeepCopy() *RunnerInventoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerInventoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerInventoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerInventorySpec) DeepCopyInto(out *RunnerInventorySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerInventorySpec.
+func (in *RunnerInventorySpec) DeepCopy() *RunnerInventorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerInventorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerInventoryStatus) DeepCopyInto(out *RunnerInventoryStatus) {
+	*out = *in
+	if in.Runners != nil {
+		in, out := &in.Runners, &out.Runners
+		*out = make([]InventoryRunner, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerInventoryStatus.
+func (in *RunnerInventoryStatus) DeepCopy() *RunnerInventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerInventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerScaleSetFederation) DeepCopyInto(out *RunnerScaleSetFederation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerScaleSetFederation.
+func (in *RunnerScaleSetFederation) DeepCopy() *RunnerScaleSetFederation {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerScaleSetFederation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerScaleSetFederation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerScaleSetFederationList) DeepCopyInto(out *RunnerScaleSetFederationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerScaleSetFederation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerScaleSetFederationList.
+func (in *RunnerScaleSetFederationList) DeepCopy() *RunnerScaleSetFederationList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerScaleSetFederationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerScaleSetFederationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerScaleSetFederationMemberStatus) DeepCopyInto(out *RunnerScaleSetFederationMemberStatus) {
+	*out = *in
+	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerScaleSetFederationMemberStatus.
+func (in *RunnerScaleSetFederationMemberStatus) DeepCopy() *RunnerScaleSetFederationMemberStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerScaleSetFederationMemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerScaleSetFederationSpec) DeepCopyInto(out *RunnerScaleSetFederationSpec) {
+	*out = *in
+	if in.MemberTimeout != nil {
+		in, out := &in.MemberTimeout, &out.MemberTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerScaleSetFederationSpec.
+func (in *RunnerScaleSetFederationSpec) DeepCopy() *RunnerScaleSetFederationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerScaleSetFederationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerScaleSetFederationStatus) DeepCopyInto(out *RunnerScaleSetFederationStatus) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]RunnerScaleSetFederationMemberStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerScaleSetFederationStatus.
+func (in *RunnerScaleSetFederationStatus) DeepCopy() *RunnerScaleSetFederationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerScaleSetFederationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownPolicy) DeepCopyInto(out *ScaleDownPolicy) {
+	*out = *in
+	if in.MinIdleRunnerAge != nil {
+		in, out := &in.MinIdleRunnerAge, &out.MinIdleRunnerAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownPolicy.
+func (in *ScaleDownPolicy) DeepCopy() *ScaleDownPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSCertificateSource) DeepCopyInto(out *TLSCertificateSource) {
 	*out = *in
@@ -668,3 +1293,18 @@ func (in *TLSCertificateSource) DeepCopy() *TLSCertificateSource {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmPool) DeepCopyInto(out *WarmPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmPool.
+func (in *WarmPool) DeepCopy() *WarmPool {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmPool)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GitHubCredentialSpec defines the GitHub API credentials of a namespace and the GitHub resources they may be used for
+type GitHubCredentialSpec struct {
+	// SecretRef is the secret in the namespace of the GitHubCredential holding the GitHub API credentials,
+	// with the same keys as the secret referenced by githubAPICredentialsFrom.
+	SecretRef SecretReference `json:"secretRef"`
+
+	// Enterprises are the enterprises the runners in the namespace may register to.
+	// +optional
+	Enterprises []string `json:"enterprises,omitempty"`
+
+	// Organizations are the organizations the runners in the namespace may register to,
+	// along with the repositories of the organizations.
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+
+	// Repositories are the repositories, in the OWNER/REPO format, the runners in the namespace may register to.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+// Allows returns true when the runners of the enterprise, organization, or repository may be registered with the credentials.
+// The owners and the repositories are compared case-insensitively, as GitHub does.
+func (s GitHubCredentialSpec) Allows(enterprise, organization, repository string) bool {
+	contains := func(list []string, v string) bool {
+		for _, l := range list {
+			if strings.EqualFold(l, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case enterprise != "":
+		return contains(s.Enterprises, enterprise)
+	case organization != "":
+		return contains(s.Organizations, organization)
+	case repository != "":
+		if contains(s.Repositories, repository) {
+			return true
+		}
+
+		owner, _, _ := strings.Cut(repository, "/")
+
+		return contains(s.Organizations, owner)
+	default:
+		return false
+	}
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ghcred
+// +kubebuilder:printcolumn:JSONPath=".spec.secretRef.name",name=Secret,type=string
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// GitHubCredential is the Schema for the githubcredentials API.
+// The runners, runner sets, and autoscalers in its namespace use its credentials by default,
+// and the runners in the namespace can only be registered to the GitHub resources it allows.
+type GitHubCredential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GitHubCredentialSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GitHubCredentialList contains a list of GitHubCredential
+type GitHubCredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GitHubCredential `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GitHubCredential{}, &GitHubCredentialList{})
+}
@@ -17,6 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,11 +38,24 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	MaxReplicas *int `json:"maxReplicas,omitempty"`
 
+	// MaxReplicasFromCapacity derives the maximum number of replicas from the allocatable resources of the nodes,
+	// so that the limit follows the cluster as the nodes join and leave.
+	// When MaxReplicas is also set, the lower of the two applies.
+	// +optional
+	MaxReplicasFromCapacity *MaxReplicasFromCapacity `json:"maxReplicasFromCapacity,omitempty"`
+
 	// ScaleDownDelaySecondsAfterScaleUp is the approximate delay for a scale down followed by a scale up
 	// Used to prevent flapping (down->up->down->... loop)
 	// +optional
 	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
 
+	// ScaleDownDelayFromJobRunDuration extends the scale down delay to a percentile of the run times of
+	// the workflow jobs that can run on the runners, so that the runners that are statistically likely to be needed again
+	// within minutes are not terminated.
+	// This requires the controller to be configured with the URL of the actions-metrics-server's metrics endpoint.
+	// +optional
+	ScaleDownDelayFromJobRunDuration *ScaleDownDelayFromJobRunDuration `json:"scaleDownDelayFromJobRunDuration,omitempty"`
+
 	// Metrics is the collection of various metric targets to calculate desired number of runners
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
@@ -53,6 +71,18 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// receive a webhook from GitHub, so that you can loosely expect MinReplicas runners to be always available.
 	ScaleUpTriggers []ScaleUpTrigger `json:"scaleUpTriggers,omitempty"`
 
+	// Priority is the preference of this HRA over the other HRAs matching the same webhook event,
+	// like a queued workflow job whose labels match the runners of multiple HRAs.
+	// The webhookBasedAutoscaler scales the HRA with the highest priority that is not at MaxReplicas yet.
+	// Defaults to 0.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+
+	// Sandbox marks this HRA as scaling the sandboxed runners for the untrusted jobs, like the ones of pull requests from forks of public repositories.
+	// When the webhookBasedAutoscaler runs with the "sandbox" fork pull request policy, it routes the untrusted jobs only to the sandbox HRAs.
+	// +optional
+	Sandbox bool `json:"sandbox,omitempty"`
+
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 
 	// ScheduledOverrides is the list of ScheduledOverride.
@@ -63,19 +93,171 @@ type HorizontalRunnerAutoscalerSpec struct {
 
 	// +optional
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// Budget caps the desired replicas so that the cost of running the runners
+	// doesn't exceed the configured hourly and daily limits.
+	// The budget takes precedence over MinReplicas, ScheduledOverrides, and CapacityReservations.
+	// +optional
+	Budget *BudgetSpec `json:"budget,omitempty"`
+
+	// Paused freezes the scaling decisions of the HRA, so that the replicas of the scale target are left as they are.
+	// The HRA keeps computing the desired replicas into the status and the metrics while paused.
+	// The HRA can also be paused temporarily with the `actions-runner-controller/paused-until` annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// DryRun makes the HRA compute the desired replicas into the status, the events, and the metrics
+	// without changing the replicas of the scale target, for evaluating a scaling strategy against the production traffic.
+	// Defaults to the --hra-dry-run flag of the controller. Paused takes precedence over DryRun.
+	// +optional
+	DryRun *bool `json:"dryRun,omitempty"`
+
+	// ServiceLevelObjectives are the objectives on the queue and the run times of the workflow jobs that can run on the runners,
+	// evaluated against the histograms of the actions-metrics-server into the error budget metrics and the ServiceLevelObjectivesMet condition.
+	// This requires the controller to be configured with the URL of the actions-metrics-server's metrics endpoint.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ServiceLevelObjectives []ServiceLevelObjective `json:"serviceLevelObjectives,omitempty"`
+}
+
+// BudgetSpec defines the cost ceilings of a HorizontalRunnerAutoscaler.
+// All the amounts are decimal numbers in an arbitrary but consistent currency, like "0.25".
+type BudgetSpec struct {
+	// CostPerRunnerHour is the cost of running a runner for an hour.
+	// If omitted, the value of the `actions-runner-controller/cost-per-runner-hour` annotation
+	// on the scale target is used.
+	// +optional
+	CostPerRunnerHour string `json:"costPerRunnerHour,omitempty"`
+
+	// HourlyLimit is the maximum cost of running all the runners for an hour.
+	// The desired replicas never exceeds HourlyLimit divided by the cost per runner hour.
+	// +optional
+	HourlyLimit string `json:"hourlyLimit,omitempty"`
+
+	// DailyLimit is the maximum cost spent on the runners per day, in UTC.
+	// The controller accumulates the cost spent today in the status, and
+	// the desired replicas never exceeds the number of runners that can run for another hour
+	// within the rest of the daily budget.
+	// +optional
+	DailyLimit string `json:"dailyLimit,omitempty"`
+}
+
+// MaxReplicasFromCapacity is how much of the allocatable resources of the nodes the runner pods of the scale target can request in total.
+// Each of CPU and Memory is either a percentage of the allocatable resources, like "40%",
+// or an absolute quantity, like "32" or "128Gi", which is capped by the allocatable resources too.
+// The maximum replicas is the number of the runner pods whose resource requests fit within all the given resources.
+type MaxReplicasFromCapacity struct {
+	// NodeSelector selects the nodes whose allocatable resources are counted, like `nodepool: ci`.
+	// All the nodes are counted when omitted. The unschedulable nodes are never counted.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// CPU is the CPU the runner pods can request in total, either a percentage like "40%" or a quantity like "32".
+	// +optional
+	CPU string `json:"cpu,omitempty"`
+
+	// Memory is the memory the runner pods can request in total, either a percentage like "40%" or a quantity like "128Gi".
+	// +optional
+	Memory string `json:"memory,omitempty"`
+}
+
+// CapacityShare returns the share of the allocatable resource given by either a percentage like "40%" or a quantity like "32".
+// The quantity is capped by the allocatable resource.
+func CapacityShare(share string, allocatable resource.Quantity) (resource.Quantity, error) {
+	if p, ok := strings.CutSuffix(share, "%"); ok {
+		percentage, err := strconv.Atoi(p)
+		if err != nil || percentage < 0 || percentage > 100 {
+			return resource.Quantity{}, fmt.Errorf("%q must be a percentage between 0%% and 100%%", share)
+		}
+
+		return *resource.NewMilliQuantity(allocatable.MilliValue()*int64(percentage)/100, allocatable.Format), nil
+	}
+
+	q, err := resource.ParseQuantity(share)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("%q must be either a percentage or a quantity: %w", share, err)
+	} else if q.Sign() < 0 {
+		return resource.Quantity{}, fmt.Errorf("%q must not be negative", share)
+	}
+
+	if q.Cmp(allocatable) > 0 {
+		return allocatable.DeepCopy(), nil
+	}
+
+	return q, nil
+}
+
+type ScaleDownDelayFromJobRunDuration struct {
+	// Percentile is the percentile of the job run times used as the scale down delay. Defaults to 90.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	Percentile *int `json:"percentile,omitempty"`
+
+	// MaxDelaySeconds caps the scale down delay derived from the job run times.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxDelaySeconds *int `json:"maxDelaySeconds,omitempty"`
+}
+
+const (
+	ServiceLevelObjectiveMetricQueueDuration = "QueueDuration"
+	ServiceLevelObjectiveMetricRunDuration   = "RunDuration"
+)
+
+// ServiceLevelObjective is an objective that the given percentile of the workflow jobs is within the threshold,
+// like "the 95th percentile of the queue times of the jobs running on ubuntu-xlarge is within 60s".
+// The rest of the jobs, 5% in this example, is the error budget.
+type ServiceLevelObjective struct {
+	// Name identifies the objective in the metrics and the condition.
+	Name string `json:"name"`
+
+	// Metric is the duration of the jobs the objective is on, either QueueDuration or RunDuration. Defaults to QueueDuration.
+	// +optional
+	// +kubebuilder:validation:Enum=QueueDuration;RunDuration
+	Metric string `json:"metric,omitempty"`
+
+	// RunsOn selects the jobs that can run on the runners with these labels.
+	// Defaults to the labels of the runners of the scale target.
+	// +optional
+	RunsOn []string `json:"runsOn,omitempty"`
+
+	// Percentile is the percentage of the jobs that must be within the threshold.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=99
+	Percentile int `json:"percentile"`
+
+	// Threshold is the duration the jobs must be within, like "60s".
+	Threshold metav1.Duration `json:"threshold"`
 }
 
 type ScaleUpTrigger struct {
 	GitHubEvent *GitHubEventScaleUpTriggerSpec `json:"githubEvent,omitempty"`
 	Amount      int                            `json:"amount,omitempty"`
 	Duration    metav1.Duration                `json:"duration,omitempty"`
+
+	// ScaleDownFactor is the multiplicative factor applied to the amount to determine
+	// how many of the reserved runners are released on each completed workflow_job event.
+	// It must be between 0 and 1. Defaults to 1, which releases all the runners reserved for the job.
+	// With 0, the runners are released only after the duration elapses.
+	// +optional
+	ScaleDownFactor string `json:"scaleDownFactor,omitempty"`
 }
 
 type GitHubEventScaleUpTriggerSpec struct {
 	CheckRun    *CheckRunSpec    `json:"checkRun,omitempty"`
+	Deployment  *DeploymentSpec  `json:"deployment,omitempty"`
 	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
 	Push        *PushSpec        `json:"push,omitempty"`
 	WorkflowJob *WorkflowJobSpec `json:"workflowJob,omitempty"`
+
+	// Filter is a CEL expression evaluated against the webhook payload, bound to the `event` variable,
+	// and the webhook event type like "workflow_job", bound to the `eventType` variable.
+	// The trigger applies to the webhook event only when the expression evaluates to true,
+	// e.g. `event.workflow_job.labels.exists(l, l == "gpu") && event.repository.visibility == "private"`.
+	// +optional
+	Filter string `json:"filter,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#check_run
@@ -95,8 +277,39 @@ type CheckRunSpec struct {
 	Repositories []string `json:"repositories,omitempty"`
 }
 
+// DeploymentSpec is the condition for triggering scale-up on deployment event
+// Also see https://docs.github.com/en/webhooks/webhook-events-and-payloads#deployment
+type DeploymentSpec struct {
+	// Environments is a list of GitHub Actions glob patterns.
+	// Any deployment event whose environment matches one of patterns in the list can trigger autoscaling.
+	// +optional
+	Environments []string `json:"environments,omitempty"`
+
+	// Repositories is a list of GitHub repositories.
+	// Any deployment event whose repository matches one of repositories in the list can trigger autoscaling.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+}
+
 // https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
 type WorkflowJobSpec struct {
+	// ReserveForWorkflowRun makes the webhook-based autoscaler reserve capacity for all the queued jobs
+	// of a workflow run at once, on receiving a workflow_run event whose status is queued.
+	// Without this, a workflow run with a large matrix results in a gradual scale-up, as GitHub sends
+	// workflow_job events one by one while the jobs are queued.
+	// The workflow_job events for the jobs that were already reserved for don't add any more capacity.
+	// This requires the webhook-based autoscaler to be configured with GitHub API credentials, and
+	// the GitHub webhook to deliver workflow_run events.
+	// +optional
+	ReserveForWorkflowRun bool `json:"reserveForWorkflowRun,omitempty"`
+
+	// Labels is the runs-on label set of the workflow jobs this trigger applies to.
+	// A workflow_job event matches the trigger only when the job's labels equal to this label set,
+	// ignoring the "self-hosted" label and the case.
+	// This allows an HRA to have a scale trigger with its own amount, duration, and scaleDownFactor
+	// per runs-on label set. A trigger without labels matches any workflow job that no other trigger matched.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#pull_request
@@ -119,6 +332,10 @@ type CapacityReservation struct {
 
 	// +optional
 	EffectiveTime metav1.Time `json:"effectiveTime,omitempty"`
+
+	// Profile is the name of the label resource profile of the RunnerDeployment that serves the workflow job the capacity is reserved for.
+	// +optional
+	Profile string `json:"profile,omitempty"`
 }
 
 type ScaleTargetRef struct {
@@ -175,6 +392,10 @@ type MetricSpec struct {
 // ScheduledOverride can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
 // A schedule can optionally be recurring, so that the corresponding override happens every day, week, month, or year.
 type ScheduledOverride struct {
+	// Name is an optional name of the override, shown in the status while the override is active.
+	// +optional
+	Name string `json:"name,omitempty"`
+
 	// StartTime is the time at which the first override starts.
 	StartTime metav1.Time `json:"startTime"`
 
@@ -190,6 +411,13 @@ type ScheduledOverride struct {
 
 	// +optional
 	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
+
+	// TimeZone is the IANA time zone name, like "Europe/Berlin", in which the override recurs.
+	// When specified, every recurrence starts and ends at the same local time as StartTime and EndTime in the time zone,
+	// regardless of daylight saving time transitions.
+	// If omitted, the override recurs at the UTC offset of StartTime.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type RecurrenceRule struct {
@@ -204,11 +432,19 @@ type RecurrenceRule struct {
 	// If empty, the schedule recurs forever.
 	// +optional
 	UntilTime metav1.Time `json:"untilTime,omitempty"`
+
+	// Weekdays limits a daily recurrence to the given days of the week, like "Monday" and "Friday".
+	// It can be used only with the "Daily" frequency.
+	// +optional
+	Weekdays []Weekday `json:"weekdays,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
 type HorizontalRunnerAutoscalerStatus struct {
-	// ObservedGeneration is the most recent generation observed for the target. It corresponds to e.g.
-	// RunnerDeployment's generation, which is updated on mutation by the API Server.
+	// ObservedGeneration is the generation of the autoscaler whose scale triggers and metrics the desired replicas
+	// of the status were last computed from.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
@@ -228,6 +464,88 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// for observability.
 	// +optional
 	ScheduledOverridesSummary *string `json:"scheduledOverridesSummary,omitempty"`
+
+	// ActiveScheduledOverride is the scheduled override that is currently active, if any.
+	// +optional
+	ActiveScheduledOverride *ActiveScheduledOverride `json:"activeScheduledOverride,omitempty"`
+
+	// Budget is the observed state of the budget, available only when spec.budget is set.
+	// +optional
+	Budget *BudgetStatus `json:"budget,omitempty"`
+
+	// MaxReplicasFromCapacity is the maximum replicas derived from the allocatable resources of the nodes,
+	// available only when spec.maxReplicasFromCapacity is set.
+	// +optional
+	MaxReplicasFromCapacity *int `json:"maxReplicasFromCapacity,omitempty"`
+
+	// Paused is true while the HRA is paused by either spec.paused or the paused-until annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// PausedUntil is when the pause by the paused-until annotation expires.
+	// +optional
+	// +nullable
+	PausedUntil *metav1.Time `json:"pausedUntil,omitempty"`
+
+	// DryRun is true while the HRA computes the desired replicas without applying them to the scale target.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// SuggestedReplicas is the desired replicas computed while paused or in dry-run, which is applied to the scale target once resumed.
+	// +optional
+	SuggestedReplicas *int `json:"suggestedReplicas,omitempty"`
+
+	// Conditions are the standard conditions of the autoscaler, Ready, Synced, ScalingActive and RateLimited,
+	// along with ServiceLevelObjectivesMet when spec.serviceLevelObjectives is set.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ActiveScheduledOverride describes the currently active window of a scheduled override.
+type ActiveScheduledOverride struct {
+	// Name is the name of the scheduled override.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// TimeZone is the time zone of the scheduled override.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// StartTime is the time at which the current window started.
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is the time at which the current window ends.
+	EndTime metav1.Time `json:"endTime"`
+
+	// MinReplicas is the minReplicas overridden during the current window.
+	// +optional
+	MinReplicas *int `json:"minReplicas,omitempty"`
+}
+
+type BudgetStatus struct {
+	// Date is the UTC date, in the YYYY-MM-DD format, that Spent is accumulated for.
+	// +optional
+	Date string `json:"date,omitempty"`
+
+	// Spent is the estimated cost spent on the runners on Date.
+	// +optional
+	Spent string `json:"spent,omitempty"`
+
+	// LastAccountedTime is the time at which Spent was last updated.
+	// +optional
+	// +nullable
+	LastAccountedTime *metav1.Time `json:"lastAccountedTime,omitempty"`
+
+	// WithheldReplicas is the number of replicas that were not added due to the budget.
+	// +optional
+	WithheldReplicas int `json:"withheldReplicas,omitempty"`
+
+	// LimitedBy is the name of the limit that clamped the desired replicas, either "hourly" or "daily".
+	// Empty when the desired replicas is within the budget.
+	// +optional
+	LimitedBy string `json:"limitedBy,omitempty"`
 }
 
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
@@ -245,6 +563,10 @@ type CacheEntry struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.maxReplicas",name=Max,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.desiredReplicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.scheduledOverridesSummary",name=Schedule,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.budget.withheldReplicas",name=Withheld,type=number,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.paused",name=Paused,type=boolean,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.dryRun",name=DryRun,type=boolean,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.suggestedReplicas",name=Suggested,type=number,priority=1
 
 // HorizontalRunnerAutoscaler is the Schema for the horizontalrunnerautoscaler API
 type HorizontalRunnerAutoscaler struct {
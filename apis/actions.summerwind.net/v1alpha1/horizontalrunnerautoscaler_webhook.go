@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var horizontalRunnerAutoscalerLog = logf.Log.WithName("horizontalrunnerautoscaler-resource")
+
+func (r *HorizontalRunnerAutoscaler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&HorizontalRunnerAutoscalerValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=validate.horizontalrunnerautoscaler.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.CustomValidator = &HorizontalRunnerAutoscalerValidator{}
+
+// HorizontalRunnerAutoscalerValidator validates a HorizontalRunnerAutoscaler along with the resources it relates to,
+// its scale target and the other HorizontalRunnerAutoscalers of the namespace.
+type HorizontalRunnerAutoscalerValidator struct {
+	Client client.Reader
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *HorizontalRunnerAutoscalerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	r, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected HorizontalRunnerAutoscaler object, got %T", obj)
+	}
+	horizontalRunnerAutoscalerLog.Info("validate resource to be created", "name", r.Name)
+
+	errList, err := v.validateScaleTarget(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, r.validate(errList)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *HorizontalRunnerAutoscalerValidator) ValidateUpdate(ctx context.Context, old, obj runtime.Object) (admission.Warnings, error) {
+	r, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected HorizontalRunnerAutoscaler object, got %T", obj)
+	}
+	oldR, ok := old.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected HorizontalRunnerAutoscaler object, got %T", old)
+	}
+	horizontalRunnerAutoscalerLog.Info("validate resource to be updated", "name", r.Name)
+
+	// The scale target is only checked when it changes, so that the HorizontalRunnerAutoscaler
+	// of a deleted scale target can still be updated, e.g. to remove its finalizers.
+	var errList field.ErrorList
+	if r.DeletionTimestamp.IsZero() && r.Spec.ScaleTargetRef != oldR.Spec.ScaleTargetRef {
+		var err error
+		errList, err = v.validateScaleTarget(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, r.validate(errList)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (*HorizontalRunnerAutoscalerValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateScaleTarget checks that the scale target exists, and that no other HorizontalRunnerAutoscaler scales it.
+func (v *HorizontalRunnerAutoscalerValidator) validateScaleTarget(ctx context.Context, r *HorizontalRunnerAutoscaler) (field.ErrorList, error) {
+	var errList field.ErrorList
+
+	refPath := field.NewPath("spec", "scaleTargetRef")
+	kind := r.Spec.ScaleTargetRef.scaleTargetKind()
+	name := r.Spec.ScaleTargetRef.Name
+	if name == "" {
+		return append(errList, field.Required(refPath.Child("name"), fmt.Sprintf("set the name of the %s to scale", kind))), nil
+	}
+
+	var target client.Object
+	switch kind {
+	case "RunnerSet":
+		target = &RunnerSet{}
+	default:
+		target = &RunnerDeployment{}
+	}
+
+	if err := v.Client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: name}, target); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s %s: %w", kind, name, err)
+		}
+		errList = append(errList, field.Invalid(refPath.Child("name"), name,
+			fmt.Sprintf("%s %q does not exist in namespace %q. Create the %s first, or fix spec.scaleTargetRef", kind, name, r.Namespace, kind)))
+	}
+
+	var hraList HorizontalRunnerAutoscalerList
+	if err := v.Client.List(ctx, &hraList, client.InNamespace(r.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list horizontal runner autoscalers: %w", err)
+	}
+
+	for _, other := range hraList.Items {
+		if other.Name == r.Name || other.Spec.ScaleTargetRef.scaleTargetKind() != kind || other.Spec.ScaleTargetRef.Name != name {
+			continue
+		}
+		errList = append(errList, field.Invalid(refPath, r.Spec.ScaleTargetRef,
+			fmt.Sprintf("%s %q is already scaled by HorizontalRunnerAutoscaler %q. A %s can only be scaled by a single HorizontalRunnerAutoscaler, delete one of them or merge their metrics and scaleUpTriggers", kind, name, other.Name, kind)))
+	}
+
+	return errList, nil
+}
+
+// validate returns the errors of the scale target along with the errors of the spec.
+func (r *HorizontalRunnerAutoscaler) validate(errList field.ErrorList) error {
+	errList = append(errList, r.Spec.Validate(field.NewPath("spec"))...)
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
+	}
+
+	return nil
+}
+
+// Validate validates the spec on its own.
+func (s *HorizontalRunnerAutoscalerSpec) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if s.MinReplicas != nil && s.MaxReplicas != nil && *s.MinReplicas > *s.MaxReplicas {
+		errList = append(errList, field.Invalid(rootPath.Child("minReplicas"), *s.MinReplicas,
+			fmt.Sprintf("must be less than or equal to maxReplicas (%d)", *s.MaxReplicas)))
+	}
+
+	if c := s.MaxReplicasFromCapacity; c != nil {
+		path := rootPath.Child("maxReplicasFromCapacity")
+
+		if c.CPU == "" && c.Memory == "" {
+			errList = append(errList, field.Required(path, "either cpu or memory is required"))
+		}
+
+		for _, r := range []struct{ name, share string }{{"cpu", c.CPU}, {"memory", c.Memory}} {
+			if r.share == "" {
+				continue
+			}
+			if _, err := CapacityShare(r.share, resource.Quantity{}); err != nil {
+				errList = append(errList, field.Invalid(path.Child(r.name), r.share, err.Error()))
+			}
+		}
+	}
+
+	for i, o := range s.ScheduledOverrides {
+		if o.MinReplicas != nil && s.MaxReplicas != nil && *o.MinReplicas > *s.MaxReplicas {
+			errList = append(errList, field.Invalid(rootPath.Child("scheduledOverrides").Index(i).Child("minReplicas"), *o.MinReplicas,
+				fmt.Sprintf("must be less than or equal to maxReplicas (%d)", *s.MaxReplicas)))
+		}
+	}
+
+	for i, o := range s.ServiceLevelObjectives {
+		if o.Threshold.Duration <= 0 {
+			errList = append(errList, field.Invalid(rootPath.Child("serviceLevelObjectives").Index(i).Child("threshold"), o.Threshold.Duration.String(),
+				"must be positive"))
+		}
+	}
+
+	return errList
+}
+
+func (r ScaleTargetRef) scaleTargetKind() string {
+	if r.Kind == "" {
+		return "RunnerDeployment"
+	}
+	return r.Kind
+}
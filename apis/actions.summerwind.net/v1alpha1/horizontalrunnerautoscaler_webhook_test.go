@@ -0,0 +1,108 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHorizontalRunnerAutoscalerValidator(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, AddToScheme(sc))
+
+	intPtr := func(v int) *int { return &v }
+
+	newHRA := func(name string, ref ScaleTargetRef) *HorizontalRunnerAutoscaler {
+		return &HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: ref,
+				MinReplicas:    intPtr(1),
+				MaxReplicas:    intPtr(3),
+			},
+		}
+	}
+
+	existing := newHRA("existing", ScaleTargetRef{Name: "taken"})
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		&RunnerDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}},
+		&RunnerDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "taken"}},
+		&RunnerSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}},
+		existing,
+	).Build()
+
+	v := &HorizontalRunnerAutoscalerValidator{Client: c}
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := v.ValidateCreate(ctx, newHRA("example", ScaleTargetRef{Name: "example"}))
+		assert.NoError(t, err)
+
+		_, err = v.ValidateCreate(ctx, newHRA("example", ScaleTargetRef{Kind: "RunnerSet", Name: "example"}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("nonexistent scale target", func(t *testing.T) {
+		_, err := v.ValidateCreate(ctx, newHRA("example", ScaleTargetRef{Name: "missing"}))
+		assert.ErrorContains(t, err, `RunnerDeployment "missing" does not exist in namespace "default"`)
+
+		_, err = v.ValidateCreate(ctx, newHRA("example", ScaleTargetRef{Kind: "RunnerSet", Name: "taken"}))
+		assert.ErrorContains(t, err, `RunnerSet "taken" does not exist in namespace "default"`)
+	})
+
+	t.Run("scale target already scaled", func(t *testing.T) {
+		_, err := v.ValidateCreate(ctx, newHRA("example", ScaleTargetRef{Kind: "RunnerDeployment", Name: "taken"}))
+		assert.ErrorContains(t, err, `RunnerDeployment "taken" is already scaled by HorizontalRunnerAutoscaler "existing"`)
+	})
+
+	t.Run("minReplicas greater than maxReplicas", func(t *testing.T) {
+		hra := newHRA("example", ScaleTargetRef{Name: "example"})
+		hra.Spec.MinReplicas = intPtr(5)
+		_, err := v.ValidateCreate(ctx, hra)
+		assert.ErrorContains(t, err, "spec.minReplicas: Invalid value: 5: must be less than or equal to maxReplicas (3)")
+	})
+
+	t.Run("non-positive service level objective threshold", func(t *testing.T) {
+		hra := newHRA("example", ScaleTargetRef{Name: "example"})
+		hra.Spec.ServiceLevelObjectives = []ServiceLevelObjective{{Name: "queue-time", Percentile: 95}}
+		_, err := v.ValidateCreate(ctx, hra)
+		assert.ErrorContains(t, err, `spec.serviceLevelObjectives[0].threshold: Invalid value: "0s": must be positive`)
+	})
+
+	t.Run("invalid maxReplicasFromCapacity", func(t *testing.T) {
+		hra := newHRA("example", ScaleTargetRef{Name: "example"})
+		hra.Spec.MaxReplicasFromCapacity = &MaxReplicasFromCapacity{}
+		_, err := v.ValidateCreate(ctx, hra)
+		assert.ErrorContains(t, err, "spec.maxReplicasFromCapacity: Required value: either cpu or memory is required")
+
+		hra.Spec.MaxReplicasFromCapacity = &MaxReplicasFromCapacity{CPU: "120%"}
+		_, err = v.ValidateCreate(ctx, hra)
+		assert.ErrorContains(t, err, `spec.maxReplicasFromCapacity.cpu: Invalid value: "120%"`)
+
+		hra.Spec.MaxReplicasFromCapacity = &MaxReplicasFromCapacity{CPU: "50%", Memory: "64Gi"}
+		_, err = v.ValidateCreate(ctx, hra)
+		assert.NoError(t, err)
+	})
+
+	t.Run("update keeping the scale target", func(t *testing.T) {
+		old := newHRA("example", ScaleTargetRef{Name: "missing"})
+		updated := old.DeepCopy()
+		updated.Spec.MaxReplicas = intPtr(10)
+		_, err := v.ValidateUpdate(ctx, old, updated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("update changing the scale target", func(t *testing.T) {
+		_, err := v.ValidateUpdate(ctx, existing, newHRA("existing", ScaleTargetRef{Name: "missing"}))
+		assert.ErrorContains(t, err, `RunnerDeployment "missing" does not exist`)
+
+		_, err = v.ValidateUpdate(ctx, newHRA("existing", ScaleTargetRef{Name: "example"}), existing)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The states of the maintenance windows notified in status.notifiedState.
+const (
+	MaintenanceStateStarted = "Started"
+	MaintenanceStateEnded   = "Ended"
+)
+
+// MaintenanceWindowSpec defines the periods of maintenance of the runners in a namespace
+type MaintenanceWindowSpec struct {
+	// Selector selects the RunnerDeployments and the RunnerSets in the namespace of the MaintenanceWindow by their labels.
+	// The HorizontalRunnerAutoscalers scaling the selected ones refuse to scale up during the maintenance.
+	// Selects all of them in the namespace when omitted.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Schedules are the periods of the maintenance, each of which recurs like the scheduled overrides of HorizontalRunnerAutoscalers.
+	// +kubebuilder:validation:MinItems=1
+	Schedules []MaintenanceSchedule `json:"schedules"`
+
+	// DrainIdleRunners scales the selected RunnerDeployments and RunnerSets down to zero during the maintenance,
+	// so that the idle runners are removed while the busy ones complete their jobs.
+	// When false, the runners are kept and only the scale up is refused.
+	// +optional
+	DrainIdleRunners bool `json:"drainIdleRunners,omitempty"`
+
+	// Notification posts the start and the end of the maintenance.
+	// +optional
+	Notification *MaintenanceWindowNotification `json:"notification,omitempty"`
+}
+
+type MaintenanceSchedule struct {
+	// StartTime is the time at which the first maintenance starts.
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is the time at which the first maintenance ends.
+	EndTime metav1.Time `json:"endTime"`
+
+	// +optional
+	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
+
+	// TimeZone is the IANA time zone name, like "Europe/Berlin", in which the maintenance recurs.
+	// If omitted, the maintenance recurs at the UTC offset of StartTime.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type MaintenanceWindowNotification struct {
+	// Slack posts the start and the end of the maintenance to the channel of a Slack incoming webhook.
+	// +optional
+	Slack *SlackNotification `json:"slack,omitempty"`
+
+	// GitHubStatus sets a commit status on the head of the default branch of a repository,
+	// which is pending during the maintenance and successful otherwise, so that the workflows can check for the maintenance.
+	// +optional
+	GitHubStatus *GitHubStatusNotification `json:"githubStatus,omitempty"`
+}
+
+type SlackNotification struct {
+	// WebhookURLSecretRef is the secret in the namespace of the MaintenanceWindow holding the URL of the incoming webhook
+	// in the slack_webhook_url key.
+	WebhookURLSecretRef SecretReference `json:"webhookURLSecretRef"`
+}
+
+type GitHubStatusNotification struct {
+	// Repository is the repository in the OWNER/REPO format, like the .github repository of the organization.
+	Repository string `json:"repository"`
+
+	// Context is the context of the commit status. Defaults to actions-runner-controller/maintenance.
+	// +optional
+	Context string `json:"context,omitempty"`
+
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+}
+
+type MaintenanceWindowStatus struct {
+	// ObservedGeneration is the generation of the maintenance window the status was last reconciled for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Active is true during the maintenance.
+	// +optional
+	Active bool `json:"active,omitempty"`
+
+	// CurrentPeriod is the ongoing maintenance.
+	// +optional
+	// +nullable
+	CurrentPeriod *MaintenancePeriod `json:"currentPeriod,omitempty"`
+
+	// NextPeriod is the next maintenance, if any.
+	// +optional
+	// +nullable
+	NextPeriod *MaintenancePeriod `json:"nextPeriod,omitempty"`
+
+	// Targets are the HorizontalRunnerAutoscalers held by the ongoing maintenance, along with the replicas they had when it started.
+	// +optional
+	Targets []MaintenanceTarget `json:"targets,omitempty"`
+
+	// NotifiedState is the state of the maintenance last notified, either Started or Ended.
+	// +optional
+	NotifiedState string `json:"notifiedState,omitempty"`
+
+	// Conditions are the standard conditions of the maintenance window, Ready and Synced.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type MaintenancePeriod struct {
+	StartTime metav1.Time `json:"startTime"`
+	EndTime   metav1.Time `json:"endTime"`
+}
+
+type MaintenanceTarget struct {
+	// HorizontalRunnerAutoscaler is the name of the HorizontalRunnerAutoscaler held by the maintenance.
+	HorizontalRunnerAutoscaler string `json:"horizontalRunnerAutoscaler"`
+
+	// ScaleTargetKind is either RunnerDeployment or RunnerSet.
+	ScaleTargetKind string `json:"scaleTargetKind"`
+	ScaleTargetName string `json:"scaleTargetName"`
+
+	// MinReplicas is the minReplicas of the HorizontalRunnerAutoscaler when the maintenance started, which applies again after it.
+	// +optional
+	// +nullable
+	MinReplicas *int `json:"minReplicas,omitempty"`
+
+	// DesiredReplicas is the desired replicas of the HorizontalRunnerAutoscaler when the maintenance started.
+	// +optional
+	// +nullable
+	DesiredReplicas *int `json:"desiredReplicas,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=mw
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.active",name=Active,type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.currentPeriod.endTime",name=Until,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.nextPeriod.startTime",name=Next,type=string
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// MaintenanceWindow is the Schema for the maintenancewindows API.
+// During its periods, the HorizontalRunnerAutoscalers of the RunnerDeployments and the RunnerSets it selects refuse to scale up,
+// and optionally drain the idle runners.
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MaintenanceWindowSpec   `json:"spec,omitempty"`
+	Status MaintenanceWindowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
@@ -19,12 +19,14 @@ package v1alpha1
 import (
 	"errors"
 	"fmt"
+	"regexp"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // RunnerSpec defines the desired state of Runner
@@ -55,6 +57,19 @@ type RunnerConfig struct {
 	// +optional
 	Ephemeral *bool `json:"ephemeral,omitempty"`
 
+	// JITConfig makes the runners register themselves with the just-in-time configurations generated by ARC, instead of registration tokens.
+	// Each runner pod is given a single-use configuration via a secret, and ARC no longer needs the remove token to unregister the runner.
+	// It is supported by the ephemeral runners of Runners and RunnerDeployments.
+	// +optional
+	JITConfig bool `json:"jitConfig,omitempty"`
+
+	// JITConfigDelivery is how the just-in-time configurations are delivered to the runner pods,
+	// either secret, vault, or secrets-store-csi. Defaults to the --runner-jit-config-delivery flag of the controller.
+	// Requires jitConfig.
+	// +optional
+	// +kubebuilder:validation:Enum=secret;vault;secrets-store-csi
+	JITConfigDelivery string `json:"jitConfigDelivery,omitempty"`
+
 	// +optional
 	Image string `json:"image"`
 
@@ -76,16 +91,241 @@ type RunnerConfig struct {
 	// +optional
 	VolumeStorageMedium *string `json:"volumeStorageMedium,omitempty"`
 
+	// ContainerMode replaces the privileged docker sidecar with an alternative way to run containers in jobs.
+	// `kubernetes` runs job containers as separate pods via the runner container hooks,
+	// `sysbox` runs dockerd within the unprivileged runner container under the sysbox-runc runtime class,
+	// `kata` runs the docker sidecar within a Kata Containers VM under the kata runtime class,
+	// and `buildkit` replaces dockerd with a rootless buildkitd sidecar for daemonless image builds.
+	// Defaults to the docker sidecar.
 	// +optional
 	ContainerMode string `json:"containerMode,omitempty"`
 
+	// OS is the operating system of the runner pods, either linux or windows. Defaults to linux.
+	// Windows runner pods use the Windows runner image by default and are scheduled onto Windows nodes.
+	// They don't support docker or the container modes.
+	// +optional
+	// +kubebuilder:validation:Enum=linux;windows
+	OS string `json:"os,omitempty"`
+
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// GitHubAPITokenScope restricts the GitHub App installation token used to obtain the registration token of the runner,
+	// so that a runner for a repository never receives a registration token obtained with access to another repository.
+	// +optional
+	GitHubAPITokenScope *GitHubAPITokenScope `json:"githubAPITokenScope,omitempty"`
+
+	// DrainTimeout is how long ARC waits for the busy runner to complete its current job before deleting the runner pod
+	// on scale down or rollout. While draining, the custom labels of the runner are removed so that it isn't assigned another job.
+	// The runner pod is deleted anyway once the timeout elapses. Defaults to waiting until the job completes.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+
+	// PodTemplatePatch is a strategic merge patch applied to the runner pod generated by ARC, in the form of a PodTemplateSpec like
+	// `{"spec": {"runtimeClassName": "gvisor"}}`. It allows setting any pod field ARC doesn't have a dedicated field for.
+	// A full PodTemplateSpec is also accepted, but note that the containers are merged by their names.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	PodTemplatePatch *runtime.RawExtension `json:"podTemplatePatch,omitempty"`
+
+	// Sidecars are the containers that run alongside the runner container for the lifetime of the runner pod, like log shippers,
+	// secret agents, and proxies. Unlike sidecarContainers, they are started before the runner container, and stopped once the runner
+	// container completes, so that ephemeral runner pods terminate.
+	// They are injected as native sidecar containers, that is, init containers with `restartPolicy: Always`, which require Kubernetes 1.29 or later.
+	// +optional
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+
+	// LifecycleHooks are the commands and HTTP calls run at registration, after each job, and before the deletion of the runner,
+	// like to warm caches, rotate cloud credentials, or verify a malware scan of the runner.
+	// +optional
+	LifecycleHooks *RunnerLifecycleHooks `json:"lifecycleHooks,omitempty"`
+
+	// NodeAutoscalerHints are the hints added to the runner pods for node autoscalers like Karpenter and Cluster Autoscaler,
+	// so that they provision the nodes of the right instance types for the pending runner pods and don't disrupt the busy ones.
+	// +optional
+	NodeAutoscalerHints *NodeAutoscalerHints `json:"nodeAutoscalerHints,omitempty"`
+
+	// GPU attaches the GPUs to the runner container through the device plugin of the vendor,
+	// and keeps the runner container from being stopped on scale down while a process is still using the GPUs.
+	// +optional
+	GPU *RunnerGPU `json:"gpu,omitempty"`
 }
 
 type GitHubAPICredentialsFrom struct {
 	SecretRef SecretReference `json:"secretRef,omitempty"`
 }
 
+// GitHubAPITokenScope is the set of repositories and permissions requested along with a GitHub App installation token.
+// It is only supported when ARC authenticates to GitHub as a GitHub App.
+type GitHubAPITokenScope struct {
+	// Repositories is the list of the names of repositories the installation token can access.
+	// Either a bare repository name or an owner/name pair is accepted.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// Permissions maps a permission name like `administration` to its access level like `write`.
+	// See https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+	// +optional
+	Permissions map[string]string `json:"permissions,omitempty"`
+}
+
+// RunnerLifecycleHooks are the hooks run at the points of the lifecycle of the runner.
+type RunnerLifecycleHooks struct {
+	// PreRegister is run by the runner entrypoint before the runner registers itself to GitHub.
+	// The runner container exits without registering the runner when the hook fails.
+	// +optional
+	PreRegister *RunnerLifecycleHook `json:"preRegister,omitempty"`
+
+	// PostJob is run by the runner after each job completes. A failing hook doesn't fail the job.
+	// +optional
+	PostJob *RunnerLifecycleHook `json:"postJob,omitempty"`
+
+	// PreDestroy is called by ARC once the runner is unregistered from GitHub, before the runner pod is deleted.
+	// ARC keeps retrying the failing hook and doesn't delete the runner pod until it succeeds.
+	// Only http is supported, as the runner container may have already stopped.
+	// +optional
+	PreDestroy *RunnerLifecycleHook `json:"preDestroy,omitempty"`
+}
+
+// RunnerLifecycleHook is either a command run within the runner container or an HTTP call.
+type RunnerLifecycleHook struct {
+	// Command is the command and its arguments run within the runner container. It isn't run within a shell.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// +optional
+	HTTP *RunnerLifecycleHTTPHook `json:"http,omitempty"`
+}
+
+// RunnerLifecycleHTTPHook is the HTTP call made for a lifecycle hook. The request body is a JSON object
+// containing the name of the hook and the name and namespace of the runner pod. Any 2xx response is a success.
+type RunnerLifecycleHTTPHook struct {
+	URL string `json:"url"`
+
+	// Method is the HTTP method of the request. Defaults to POST.
+	// +optional
+	// +kubebuilder:validation:Enum=GET;POST;PUT
+	Method string `json:"method,omitempty"`
+}
+
+// NodeAutoscalerHints are the hints for node autoscalers added to the runner pods.
+type NodeAutoscalerHints struct {
+	// NodePool is the name of the node pool the runner pods are scheduled onto.
+	// It's added to the node selector of the runner pods with nodePoolLabelKey.
+	// +optional
+	NodePool string `json:"nodePool,omitempty"`
+
+	// NodePoolLabelKey is the node label key that tells the node pool of the node, like `cloud.google.com/gke-nodepool`
+	// or `eks.amazonaws.com/nodegroup`. Defaults to `karpenter.sh/nodepool`.
+	// +optional
+	NodePoolLabelKey string `json:"nodePoolLabelKey,omitempty"`
+
+	// DoNotDisruptWhenBusy annotates the runner pods with `karpenter.sh/do-not-disrupt: "true"` and
+	// `cluster-autoscaler.kubernetes.io/safe-to-evict: "false"` while they are running workflow jobs,
+	// so that the node autoscalers don't consolidate or scale down their nodes.
+	// The busy state is notified by the workflow_job events received by the github webhook server.
+	// +optional
+	DoNotDisruptWhenBusy bool `json:"doNotDisruptWhenBusy,omitempty"`
+
+	// ProvisioningRequest creates a ProvisioningRequest of Cluster Autoscaler for each runner pod,
+	// so that the node is provisioned for the runner pod as a whole before the pod is scheduled.
+	// +optional
+	ProvisioningRequest *ProvisioningRequestHint `json:"provisioningRequest,omitempty"`
+}
+
+// ProvisioningRequestHint configures the ProvisioningRequests created for the runner pods.
+type ProvisioningRequestHint struct {
+	// ClassName is the provisioning class of the ProvisioningRequests.
+	// Defaults to `best-effort-atomic-scale-up.autoscaling.x-k8s.io`.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+}
+
+const (
+	GPUVendorNVIDIA = "nvidia"
+	GPUVendorAMD    = "amd"
+	GPUVendorIntel  = "intel"
+)
+
+// RunnerGPU is the GPUs attached to the runner container.
+type RunnerGPU struct {
+	// Count is the number of the GPUs, or the MIG instances with migProfile, requested by the runner container.
+	// +kubebuilder:validation:Minimum=1
+	Count int `json:"count"`
+
+	// Vendor is either nvidia, amd, or intel, and decides the extended resource requested from the device plugin,
+	// like nvidia.com/gpu. Defaults to nvidia.
+	// +optional
+	// +kubebuilder:validation:Enum=nvidia;amd;intel
+	Vendor string `json:"vendor,omitempty"`
+
+	// MIGProfile is the NVIDIA Multi-Instance GPU profile like 1g.5gb, which requests nvidia.com/mig-1g.5gb instead of nvidia.com/gpu.
+	// It requires the mixed MIG strategy of the NVIDIA device plugin.
+	// +optional
+	MIGProfile string `json:"migProfile,omitempty"`
+
+	// RuntimeClassName is the runtime class of the runner pod that exposes the GPUs to the containers.
+	// Defaults to nvidia for the nvidia vendor, unless the runner pod already has a runtime class.
+	// Set it to an empty string to not set any.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// DrainTimeout is how long the runner container waits on termination for the processes still using the NVIDIA GPUs to exit,
+	// like a CUDA process left running by the job, before it stops. Defaults to 5m.
+	// terminationGracePeriodSeconds defaults to cover it, unless set explicitly.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+}
+
+var migProfilePattern = regexp.MustCompile(`^([0-9]+c\.)?[0-9]+g\.[0-9]+gb(\+me)?$`)
+
+// ResourceName returns the extended resource of the GPUs advertised by the device plugin of the vendor.
+func (g *RunnerGPU) ResourceName() corev1.ResourceName {
+	switch g.Vendor {
+	case GPUVendorAMD:
+		return "amd.com/gpu"
+	case GPUVendorIntel:
+		return "gpu.intel.com/i915"
+	}
+
+	if g.MIGProfile != "" {
+		return corev1.ResourceName("nvidia.com/mig-" + g.MIGProfile)
+	}
+
+	return "nvidia.com/gpu"
+}
+
+func (g *RunnerGPU) validate(path *field.Path, os string) field.ErrorList {
+	if g == nil {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	if g.Count < 1 {
+		errList = append(errList, field.Invalid(path.Child("count"), g.Count, "must be greater than 0"))
+	}
+
+	if g.MIGProfile != "" {
+		if g.Vendor != "" && g.Vendor != GPUVendorNVIDIA {
+			errList = append(errList, field.Invalid(path.Child("migProfile"), g.MIGProfile, "MIG profiles are supported only by the nvidia vendor"))
+		} else if !migProfilePattern.MatchString(g.MIGProfile) {
+			errList = append(errList, field.Invalid(path.Child("migProfile"), g.MIGProfile, "must be a MIG profile like 1g.5gb"))
+		}
+	}
+
+	if g.DrainTimeout != nil && g.DrainTimeout.Duration < 0 {
+		errList = append(errList, field.Invalid(path.Child("drainTimeout"), g.DrainTimeout.Duration.String(), "must not be negative"))
+	}
+
+	if os == "windows" {
+		errList = append(errList, field.Forbidden(path, "GPUs are not supported by windows runners"))
+	}
+
+	return errList
+}
+
 type SecretReference struct {
 	Name string `json:"name"`
 }
@@ -180,6 +420,135 @@ type RunnerPodSpec struct {
 
 	// +optional
 	WorkVolumeClaimTemplate *WorkVolumeClaimTemplate `json:"workVolumeClaimTemplate,omitempty"`
+
+	// ContainerHookTemplate is the template of the workflow pods the container hooks of `containerMode: kubernetes` create for the jobs,
+	// rendered with the variables of the labels of the runner.
+	// +optional
+	ContainerHookTemplate *ContainerHookTemplate `json:"containerHookTemplate,omitempty"`
+
+	// Identity projects a short-lived service account token into the runner container,
+	// so that the jobs can authenticate to the cloud providers and Vault via OIDC federation.
+	// +optional
+	Identity *RunnerIdentity `json:"identity,omitempty"`
+
+	// SecretMounts are the bundles of secrets mounted into the runner container.
+	// The kubelet updates the mounted files when the secrets change, without restarting the runner pod.
+	// +optional
+	SecretMounts []RunnerSecretMount `json:"secretMounts,omitempty"`
+
+	// ForensicSnapshot captures the diagnostics of the runner pod when the runner container exits with any of the exit codes,
+	// uploads them to the object storage configured by the --forensic-snapshot-url flag of the controller,
+	// and links them from an event on the runner.
+	// +optional
+	ForensicSnapshot *ForensicSnapshot `json:"forensicSnapshot,omitempty"`
+}
+
+// ForensicSnapshot is the diagnostics captured from the runner pod whose job failed, for debugging the flaky infrastructure.
+// The snapshot includes the status and the logs of the containers of the pod, along with the kernel ring buffer,
+// the processes, and the network connections seen from a container that is still running, like the docker sidecar.
+type ForensicSnapshot struct {
+	// ExitCodes are the exit codes of the runner container that trigger the snapshot, like 137 for the runner killed by the OOM killer.
+	// +kubebuilder:validation:MinItems=1
+	ExitCodes []int32 `json:"exitCodes"`
+
+	// Container is the container the diagnostic commands are run in. It should have the privileges to read the kernel ring buffer.
+	// Defaults to the docker sidecar, or any other container that is still running.
+	// +optional
+	Container string `json:"container,omitempty"`
+}
+
+// Captures returns true when the snapshot is captured for the exit code of the runner container.
+func (s *ForensicSnapshot) Captures(exitCode int32) bool {
+	if s == nil {
+		return false
+	}
+
+	for _, c := range s.ExitCodes {
+		if c == exitCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *ForensicSnapshot) validate(path *field.Path) field.ErrorList {
+	if s == nil {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	if len(s.ExitCodes) == 0 {
+		errList = append(errList, field.Required(path.Child("exitCodes"), "at least one exit code must be set"))
+	}
+
+	for i, c := range s.ExitCodes {
+		if c < 1 || c > 255 {
+			errList = append(errList, field.Invalid(path.Child("exitCodes").Index(i), c, "must be between 1 and 255"))
+		}
+	}
+
+	return errList
+}
+
+// ContainerHookTemplate is a hook template of the runner container hooks held by a ConfigMap,
+// which the hooks merge into the workflow pods of the jobs, like the resources, the node selector, the tolerations, and the security context.
+// See https://github.com/actions/runner-container-hooks/blob/main/docs/adrs/0096-hook-extensions.md for the format.
+//
+// The template references the variables as ${NAME}, which are resolved from LabelVariables of the labels of the runner,
+// and then from Variables. A reference to an undefined variable fails the creation of the runner pod.
+type ContainerHookTemplate struct {
+	// ConfigMapName is the name of the ConfigMap in the namespace of the runner.
+	ConfigMapName string `json:"configMapName"`
+
+	// Key is the key of the template in the ConfigMap. Defaults to template.yaml.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Variables are the default values of the variables.
+	// +optional
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// LabelVariables are the values of the variables for the runners with the labels, keyed by the label,
+	// so that the jobs of each runs-on label get their own workflow pods. The former labels of the runner take precedence.
+	// +optional
+	LabelVariables map[string]map[string]string `json:"labelVariables,omitempty"`
+}
+
+// RunnerSecretMount is a bundle of secrets mounted into the runner container as files.
+// Either SecretNames or SecretProviderClass must be set.
+type RunnerSecretMount struct {
+	// Name is the name of the bundle, unique in the runner.
+	Name string `json:"name"`
+
+	// MountPath is the directory the bundle is mounted at.
+	// Defaults to /var/run/secrets/actions-runner/NAME.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// SecretNames are the secrets in the namespace of the runner whose keys are mounted as files.
+	// The keys of the latter secrets override the same keys of the former ones.
+	// +optional
+	SecretNames []string `json:"secretNames,omitempty"`
+
+	// SecretProviderClass is the SecretProviderClass of the Secrets Store CSI Driver in the namespace of the runner
+	// that fetches the bundle from an external secret store like Vault or AWS Secrets Manager.
+	// +optional
+	SecretProviderClass string `json:"secretProviderClass,omitempty"`
+}
+
+// RunnerIdentity is the OIDC identity of the runner pod, issued by the Kubernetes API server as a projected service account token.
+// The token is bound to the runner pod and is invalidated once the pod is deleted.
+type RunnerIdentity struct {
+	// Audience is the intended audience of the token, like sts.amazonaws.com or the Vault address.
+	Audience string `json:"audience"`
+
+	// ExpirationSeconds is the validity of the token. The kubelet rotates the token before it expires.
+	// Defaults to 3600.
+	// +optional
+	// +kubebuilder:validation:Minimum=600
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
 }
 
 func (rs *RunnerSpec) Validate(rootPath *field.Path) field.ErrorList {
@@ -198,9 +567,56 @@ func (rs *RunnerSpec) Validate(rootPath *field.Path) field.ErrorList {
 		errList = append(errList, field.Invalid(rootPath.Child("workVolumeClaimTemplate"), rs.WorkVolumeClaimTemplate, err.Error()))
 	}
 
+	errList = append(errList, rs.validateSidecars(rootPath.Child("sidecars"))...)
+
+	errList = append(errList, rs.LifecycleHooks.validate(rootPath.Child("lifecycleHooks"))...)
+
+	errList = append(errList, rs.validateSecretMounts(rootPath.Child("secretMounts"))...)
+
+	if t := rs.ContainerHookTemplate; t != nil {
+		if rs.ContainerMode != "kubernetes" {
+			errList = append(errList, field.Invalid(rootPath.Child("containerHookTemplate"), t.ConfigMapName, "the container hook template requires containerMode: kubernetes"))
+		} else if t.ConfigMapName == "" {
+			errList = append(errList, field.Required(rootPath.Child("containerHookTemplate", "configMapName"), "the ConfigMap holding the template must be set"))
+		}
+	}
+
+	errList = append(errList, rs.GPU.validate(rootPath.Child("gpu"), rs.OS)...)
+
+	errList = append(errList, rs.ForensicSnapshot.validate(rootPath.Child("forensicSnapshot"))...)
+
+	if rs.JITConfig && rs.Ephemeral != nil && !*rs.Ephemeral {
+		errList = append(errList, field.Invalid(rootPath.Child("jitConfig"), rs.JITConfig, "just-in-time configurations are only supported by ephemeral runners"))
+	}
+
+	if rs.JITConfigDelivery != "" && !rs.JITConfig {
+		errList = append(errList, field.Invalid(rootPath.Child("jitConfigDelivery"), rs.JITConfigDelivery, "the delivery of just-in-time configurations requires jitConfig"))
+	} else if rs.JITConfigDelivery != "" && rs.JITConfigDelivery != "secret" && rs.OS == "windows" {
+		errList = append(errList, field.Invalid(rootPath.Child("jitConfigDelivery"), rs.JITConfigDelivery, "delivering just-in-time configurations via vault is not supported by windows runners"))
+	}
+
+	if rs.ContainerMode != "" && !isKnownContainerMode(rs.ContainerMode) {
+		errList = append(errList, field.NotSupported(rootPath.Child("containerMode"), rs.ContainerMode, ContainerModes))
+	} else if rs.OS == "windows" && rs.ContainerMode != "" {
+		errList = append(errList, field.Invalid(rootPath.Child("containerMode"), rs.ContainerMode, fmt.Sprintf("the %s container mode is not supported by windows runners", rs.ContainerMode)))
+	}
+
 	return errList
 }
 
+// ContainerModes is the supported values of RunnerConfig.ContainerMode.
+var ContainerModes = []string{"kubernetes", "sysbox", "kata", "buildkit"}
+
+func isKnownContainerMode(mode string) bool {
+	for _, m := range ContainerModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ValidateRepository validates repository field.
 func (rs *RunnerSpec) validateRepository() error {
 	// Enterprise, Organization and repository are both exclusive.
@@ -236,6 +652,106 @@ func (rs *RunnerSpec) validateWorkVolumeClaimTemplate() error {
 	return rs.WorkVolumeClaimTemplate.validate()
 }
 
+// validateSidecars validates that the sidecars have unique names that don't collide with the containers ARC manages,
+// nor with the sidecarContainers, the init containers and the containers of the runner pod.
+func (rs *RunnerSpec) validateSidecars(path *field.Path) field.ErrorList {
+	return validateSidecars(path, rs.Sidecars, rs.SidecarContainers, rs.InitContainers, rs.Containers)
+}
+
+// validateSidecars validates the sidecars against the other containers of the runner pod, whose names must be unique
+// across the init containers and the containers, as the sidecars are injected as init containers.
+func validateSidecars(path *field.Path, sidecars []corev1.Container, podContainers ...[]corev1.Container) field.ErrorList {
+	var errList field.ErrorList
+
+	containers := map[string]bool{}
+	for _, cs := range podContainers {
+		for _, c := range cs {
+			containers[c.Name] = true
+		}
+	}
+
+	names := map[string]bool{}
+
+	for i, c := range sidecars {
+		p := path.Index(i).Child("name")
+
+		switch {
+		case c.Name == "":
+			errList = append(errList, field.Required(p, "sidecar must have a name"))
+		case c.Name == "runner" || c.Name == "docker":
+			errList = append(errList, field.Invalid(p, c.Name, "the name is reserved for the container managed by ARC"))
+		case containers[c.Name]:
+			errList = append(errList, field.Invalid(p, c.Name, "the name collides with another container of the runner pod"))
+		case names[c.Name]:
+			errList = append(errList, field.Duplicate(p, c.Name))
+		}
+
+		names[c.Name] = true
+	}
+
+	return errList
+}
+
+func (rs *RunnerSpec) validateSecretMounts(path *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	names := map[string]bool{}
+
+	for i, m := range rs.SecretMounts {
+		p := path.Index(i)
+
+		switch {
+		case m.Name == "":
+			errList = append(errList, field.Required(p.Child("name"), "secret mount must have a name"))
+		case names[m.Name]:
+			errList = append(errList, field.Duplicate(p.Child("name"), m.Name))
+		}
+
+		names[m.Name] = true
+
+		if (len(m.SecretNames) == 0) == (m.SecretProviderClass == "") {
+			errList = append(errList, field.Invalid(p, m.Name, "exactly one of secretNames and secretProviderClass must be set"))
+		}
+	}
+
+	return errList
+}
+
+func (h *RunnerLifecycleHooks) validate(path *field.Path) field.ErrorList {
+	if h == nil {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	errList = append(errList, h.PreRegister.validate(path.Child("preRegister"))...)
+	errList = append(errList, h.PostJob.validate(path.Child("postJob"))...)
+	errList = append(errList, h.PreDestroy.validate(path.Child("preDestroy"))...)
+
+	if h.PreDestroy != nil && len(h.PreDestroy.Command) > 0 {
+		errList = append(errList, field.Invalid(path.Child("preDestroy", "command"), h.PreDestroy.Command, "the preDestroy hook supports only http"))
+	}
+
+	return errList
+}
+
+func (h *RunnerLifecycleHook) validate(path *field.Path) field.ErrorList {
+	if h == nil {
+		return nil
+	}
+
+	switch {
+	case len(h.Command) == 0 && h.HTTP == nil:
+		return field.ErrorList{field.Required(path, "either command or http must be specified")}
+	case len(h.Command) > 0 && h.HTTP != nil:
+		return field.ErrorList{field.Invalid(path, h, "command and http are mutually exclusive")}
+	case h.HTTP != nil && h.HTTP.URL == "":
+		return field.ErrorList{field.Required(path.Child("http", "url"), "url must be specified")}
+	}
+
+	return nil
+}
+
 // RunnerStatus defines the observed state of Runner
 type RunnerStatus struct {
 	// Turns true only if the runner pod is ready.
@@ -251,9 +767,23 @@ type RunnerStatus struct {
 	Message string `json:"message,omitempty"`
 	// +optional
 	WorkflowStatus *WorkflowStatus `json:"workflow"`
+	// State is the state of the runner last reported by the runner entrypoint and the job hooks.
+	// +optional
+	State *RunnerState `json:"state,omitempty"`
 	// +optional
 	// +nullable
 	LastRegistrationCheckTime *metav1.Time `json:"lastRegistrationCheckTime,omitempty"`
+
+	// ObservedGeneration is the generation of the runner whose pod the phase and the conditions of the status
+	// were last read from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the standard conditions of the runner, Ready, Synced and GitHubRegistered.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // WorkflowStatus contains various information that is propagated
@@ -304,6 +834,30 @@ type WorkflowStatus struct {
 	Action string `json:"action,omitempty"`
 }
 
+// The states the runner entrypoint and the job hooks report in RunnerState.
+const (
+	RunnerStateRegistering  = "Registering"
+	RunnerStateIdle         = "Idle"
+	RunnerStateJobStarted   = "JobStarted"
+	RunnerStateJobCompleted = "JobCompleted"
+)
+
+// RunnerState is the state of the runner reported by the runner entrypoint and the job hooks,
+// via the actions-runner/state annotation of the runner pod.
+type RunnerState struct {
+	// State is one of Registering, Idle, JobStarted and JobCompleted.
+	State string `json:"state"`
+	// Conclusion is the conclusion of the job, like succeeded and failed, reported with JobCompleted when the hook knows it.
+	// +optional
+	Conclusion string `json:"conclusion,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time is when the runner reported the state.
+	// +optional
+	// +nullable
+	Time *metav1.Time `json:"time,omitempty"`
+}
+
 // RunnerStatusRegistration contains runner registration status
 type RunnerStatusRegistration struct {
 	Enterprise   string      `json:"enterprise,omitempty"`
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestRunnerSpecValidateSidecars(t *testing.T) {
+	sidecars := func(names ...string) []corev1.Container {
+		var cs []corev1.Container
+		for _, n := range names {
+			cs = append(cs, corev1.Container{Name: n})
+		}
+		return cs
+	}
+
+	testcases := []struct {
+		name      string
+		spec      RunnerSpec
+		wantField string
+		wantType  field.ErrorType
+	}{
+		{
+			name: "unique names",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{Sidecars: sidecars("fluent-bit", "vault-agent")},
+				RunnerPodSpec: RunnerPodSpec{SidecarContainers: sidecars("proxy"), InitContainers: sidecars("init"), Containers: sidecars("runner")},
+			},
+		},
+		{
+			name:      "missing name",
+			spec:      RunnerSpec{RunnerConfig: RunnerConfig{Sidecars: sidecars("")}},
+			wantField: "sidecars[0].name",
+			wantType:  field.ErrorTypeRequired,
+		},
+		{
+			name:      "reserved name",
+			spec:      RunnerSpec{RunnerConfig: RunnerConfig{Sidecars: sidecars("fluent-bit", "docker")}},
+			wantField: "sidecars[1].name",
+			wantType:  field.ErrorTypeInvalid,
+		},
+		{
+			name:      "duplicate name",
+			spec:      RunnerSpec{RunnerConfig: RunnerConfig{Sidecars: sidecars("fluent-bit", "fluent-bit")}},
+			wantField: "sidecars[1].name",
+			wantType:  field.ErrorTypeDuplicate,
+		},
+		{
+			name: "collides with sidecarContainers",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{Sidecars: sidecars("proxy")},
+				RunnerPodSpec: RunnerPodSpec{SidecarContainers: sidecars("proxy")},
+			},
+			wantField: "sidecars[0].name",
+			wantType:  field.ErrorTypeInvalid,
+		},
+		{
+			name: "collides with init container",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{Sidecars: sidecars("fluent-bit", "init")},
+				RunnerPodSpec: RunnerPodSpec{InitContainers: sidecars("init")},
+			},
+			wantField: "sidecars[1].name",
+			wantType:  field.ErrorTypeInvalid,
+		},
+		{
+			name: "collides with container",
+			spec: RunnerSpec{
+				RunnerConfig:  RunnerConfig{Sidecars: sidecars("log-shipper")},
+				RunnerPodSpec: RunnerPodSpec{Containers: sidecars("runner", "log-shipper")},
+			},
+			wantField: "sidecars[0].name",
+			wantType:  field.ErrorTypeInvalid,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.spec.validateSidecars(field.NewPath("sidecars"))
+
+			if tc.wantField == "" {
+				require.Empty(t, errs)
+				return
+			}
+
+			require.Len(t, errs, 1)
+			require.Equal(t, tc.wantField, errs[0].Field)
+			require.Equal(t, tc.wantType, errs[0].Type)
+		})
+	}
+}
+
+func TestRunnerSetSpecValidateSidecars(t *testing.T) {
+	spec := RunnerSetSpec{
+		RunnerConfig: RunnerConfig{Sidecars: []corev1.Container{{Name: "fluent-bit"}}},
+	}
+	spec.Template.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+	spec.Template.Spec.Containers = []corev1.Container{{Name: "runner"}}
+
+	require.NoError(t, spec.ValidateSidecars())
+
+	spec.Template.Spec.Containers = append(spec.Template.Spec.Containers, corev1.Container{Name: "fluent-bit"})
+	require.ErrorContains(t, spec.ValidateSidecars(), "spec.sidecars[0].name")
+}
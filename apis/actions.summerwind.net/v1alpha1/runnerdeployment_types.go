@@ -17,7 +17,17 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
@@ -43,6 +53,903 @@ type RunnerDeploymentSpec struct {
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
 	Template RunnerTemplate        `json:"template"`
+
+	// Strategy is how the runners are replaced with the ones with the updated template.
+	// When omitted, the runners of the updated template are created all at once,
+	// and the outdated runners are deleted once all the updated runners are available.
+	// +optional
+	Strategy *RunnerDeploymentStrategy `json:"strategy,omitempty"`
+
+	// SchedulingBudget caps the total resource requests of all the runner pods of the RunnerDeployment,
+	// so that a single pool of runners can't consume the whole cluster. Runners beyond the budget aren't created,
+	// even if more replicas are requested by spec.replicas or the autoscaler.
+	// +optional
+	SchedulingBudget *SchedulingBudget `json:"schedulingBudget,omitempty"`
+
+	// BusyRunnerPodDisruptionBudget makes ARC create a PodDisruptionBudget that prevents the runner pods running jobs from being evicted,
+	// so that node drains, like the ones by cluster-autoscaler and node maintenance, don't interrupt the jobs.
+	// The idle runner pods can still be evicted. It requires the runner status update hook to tell which runners are running jobs.
+	// +optional
+	BusyRunnerPodDisruptionBudget bool `json:"busyRunnerPodDisruptionBudget,omitempty"`
+
+	// EgressPolicy makes ARC create a network policy that restricts the egress of the runner pods to GitHub, DNS, and the allowlist,
+	// and blocks the cloud metadata endpoints, so that malicious jobs can't exfiltrate the node credentials or the secrets anywhere.
+	// +optional
+	EgressPolicy *EgressPolicy `json:"egressPolicy,omitempty"`
+
+	// ImageBuild makes ARC build the runner image from a Dockerfile in a git repository with an in-cluster BuildKit job,
+	// push it to a registry, and roll the runners out with the image whenever its digest changes.
+	// The image overrides template.spec.image.
+	// +optional
+	ImageBuild *RunnerImageBuild `json:"imageBuild,omitempty"`
+
+	// ImagePolicy pins the runners to the digest of a tracked tag of the runner image, like the latest upstream runner image,
+	// and rolls them out to the new digest of the tag within the rollout windows, once the digest is verified.
+	// The image overrides template.spec.image.
+	// +optional
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// ImageVerification makes ARC verify the digest of template.spec.image before rolling the runners out to it,
+	// and pins the runners to the verified digest. A digest failing the verification is refused,
+	// and the runners stay on the digest verified last.
+	// +optional
+	ImageVerification *ImageVerification `json:"imageVerification,omitempty"`
+
+	// ToolCache mounts a tool cache shared among the runner pods into the runner containers as RUNNER_TOOL_CACHE,
+	// so that the setup actions like actions/setup-node download each tool version once instead of once per ephemeral runner.
+	// +optional
+	ToolCache *ToolCache `json:"toolCache,omitempty"`
+
+	// CapacityDistribution splits the runners between the spot and the on-demand capacity with the node selectors and the tolerations of each,
+	// and rebalances the runners as the spot capacity comes and goes. It takes effect without replacing the runners.
+	// +optional
+	CapacityDistribution *CapacityDistribution `json:"capacityDistribution,omitempty"`
+
+	// ConcurrencyGate blocks the scale up and the rollout of the RunnerDeployment while an external condition doesn't hold,
+	// like to freeze the CI capacity during incident response or cluster maintenance without deleting the HorizontalRunnerAutoscaler.
+	// Scale down isn't blocked.
+	// +optional
+	ConcurrencyGate *ConcurrencyGate `json:"concurrencyGate,omitempty"`
+
+	// ScaleUpWaves creates the runners of a large scale up in waves, each of which starts once the previous one is ready,
+	// instead of all at once, so that the image pulls and the node provisioning aren't overwhelmed.
+	// The waves grow while they become ready quickly, and shrink while they don't.
+	// +optional
+	ScaleUpWaves *ScaleUpWaves `json:"scaleUpWaves,omitempty"`
+
+	// LabelResourceProfiles are the classes of the runners keyed by the profile names, each of which registers the runner with the additional labels
+	// and overrides the resources and the node selector of the runner pod, so that a RunnerDeployment serves the jobs of various sizes,
+	// like runs-on: [self-hosted, xlarge], without a RunnerDeployment per size.
+	// The profile of each runner is picked on the runner creation, following ProfileReplicas.
+	// +optional
+	LabelResourceProfiles map[string]LabelResourceProfile `json:"labelResourceProfiles,omitempty"`
+
+	// ProfileReplicas is the number of the runners created with each of LabelResourceProfiles, out of Replicas.
+	// The rest of the runners are created without a profile.
+	// It is usually set by the webhook-based autoscaler via HRA from the labels of the queued workflow jobs.
+	// +optional
+	ProfileReplicas map[string]int `json:"profileReplicas,omitempty"`
+
+	// Quarantine makes the webhook-based autoscaler create a quarantined runner for each workflow job it deems suspicious,
+	// like the ones of the pull requests modifying the workflows, instead of a regular runner.
+	// The quarantined runner pods run under the gVisor runtime class with a read-only root filesystem, can connect to nothing but GitHub,
+	// and have the workflow jobs they run logged in detail.
+	// +optional
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+}
+
+const (
+	// CapacityDistributionPolicyWeighted keeps the given percentage of the runners on the spot capacity.
+	CapacityDistributionPolicyWeighted = "Weighted"
+
+	// CapacityDistributionPolicySpotFirst puts all the runners on the spot capacity,
+	// and falls back to the on-demand capacity while the spot capacity is unavailable.
+	CapacityDistributionPolicySpotFirst = "SpotFirst"
+
+	CapacityTypeSpot     = "spot"
+	CapacityTypeOnDemand = "on-demand"
+)
+
+// CapacityDistribution is how the runners are split between the spot and the on-demand capacity.
+type CapacityDistribution struct {
+	// Policy is either Weighted or SpotFirst. Defaults to Weighted.
+	// +optional
+	// +kubebuilder:validation:Enum=Weighted;SpotFirst
+	Policy string `json:"policy,omitempty"`
+
+	// SpotPercentage is the percentage of the runners put on the spot capacity by the Weighted policy.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SpotPercentage int `json:"spotPercentage,omitempty"`
+
+	// Spot is how the runner pods are scheduled onto the spot capacity.
+	Spot CapacitySubset `json:"spot"`
+
+	// OnDemand is how the runner pods are scheduled onto the on-demand capacity.
+	OnDemand CapacitySubset `json:"onDemand"`
+
+	// FallbackAfter is how long a runner pod of the SpotFirst policy can stay unschedulable on the spot capacity
+	// before it's replaced with an on-demand runner. Defaults to 3m.
+	// +optional
+	FallbackAfter *metav1.Duration `json:"fallbackAfter,omitempty"`
+
+	// SpotRetryInterval is how long the SpotFirst policy stays on the on-demand capacity after falling back,
+	// before it tries the spot capacity again and moves the idle on-demand runners back. Defaults to 15m.
+	// +optional
+	SpotRetryInterval *metav1.Duration `json:"spotRetryInterval,omitempty"`
+}
+
+// CapacitySubset is the scheduling of the runner pods onto a capacity type, added to the ones of the runner template.
+type CapacitySubset struct {
+	// NodeSelector selects the nodes of the capacity type, like karpenter.sh/capacity-type: spot.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations tolerate the taints of the nodes of the capacity type.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+func (d *CapacityDistribution) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if d.Policy == CapacityDistributionPolicySpotFirst && d.SpotPercentage != 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("spotPercentage"), d.SpotPercentage, "must not be set with the SpotFirst policy"))
+	}
+
+	if d.SpotPercentage < 0 || d.SpotPercentage > 100 {
+		errList = append(errList, field.Invalid(rootPath.Child("spotPercentage"), d.SpotPercentage, "must be between 0 and 100"))
+	}
+
+	if len(d.Spot.NodeSelector) == 0 && len(d.Spot.Tolerations) == 0 {
+		errList = append(errList, field.Required(rootPath.Child("spot"), "either nodeSelector or tolerations is required to schedule the runner pods onto the spot capacity"))
+	}
+
+	validate := func(name string, d *metav1.Duration) {
+		if d != nil && d.Duration <= 0 {
+			errList = append(errList, field.Invalid(rootPath.Child(name), d.Duration.String(), "must be greater than 0"))
+		}
+	}
+
+	validate("fallbackAfter", d.FallbackAfter)
+	validate("spotRetryInterval", d.SpotRetryInterval)
+
+	return errList
+}
+
+const (
+	// ToolCacheTypePersistentVolumeClaim shares the tool cache among all the runner pods with a ReadWriteMany persistent volume claim.
+	ToolCacheTypePersistentVolumeClaim = "PersistentVolumeClaim"
+
+	// ToolCacheTypeHostPath shares the tool cache among the runner pods on the same node with a directory on the node.
+	ToolCacheTypeHostPath = "HostPath"
+)
+
+// ToolCache is the tool cache shared among the runner pods.
+type ToolCache struct {
+	// Name is the name of the tool cache. The RunnerDeployments in the same namespace with the same name share the tool cache.
+	// Defaults to default.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Type is either PersistentVolumeClaim or HostPath. Defaults to PersistentVolumeClaim.
+	// +optional
+	// +kubebuilder:validation:Enum=PersistentVolumeClaim;HostPath
+	Type string `json:"type,omitempty"`
+
+	// StorageClassName is the storage class of the persistent volume claim, which needs to support ReadWriteMany.
+	// Defaults to the default storage class.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested size of the persistent volume claim. Defaults to 10Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// HostPath is the directory on the nodes for the HostPath type. Defaults to /var/lib/actions-runner/tool-cache/NAME.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+}
+
+// ToolCacheName returns the name of the tool cache, defaulted.
+func (c *ToolCache) ToolCacheName() string {
+	if c.Name == "" {
+		return "default"
+	}
+
+	return c.Name
+}
+
+func (c *ToolCache) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if errs := validation.IsDNS1123Label(c.ToolCacheName()); len(errs) > 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("name"), c.Name, strings.Join(errs, ", ")))
+	}
+
+	if c.Type == ToolCacheTypeHostPath {
+		if c.StorageClassName != nil || c.Size != nil {
+			errList = append(errList, field.Invalid(rootPath.Child("type"), c.Type, "storageClassName and size are only supported by the PersistentVolumeClaim type"))
+		}
+	} else if c.HostPath != "" {
+		errList = append(errList, field.Invalid(rootPath.Child("hostPath"), c.HostPath, "hostPath is only supported by the HostPath type"))
+	}
+
+	if c.Size != nil && c.Size.Sign() <= 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("size"), c.Size.String(), "must be greater than 0"))
+	}
+
+	return errList
+}
+
+// RunnerImageBuild is the build of the runner image of a RunnerDeployment.
+type RunnerImageBuild struct {
+	// Git is the URL of the git repository containing the build context, like https://github.com/example/runner-images.git.
+	Git string `json:"git"`
+
+	// Ref is the branch, tag, or commit of the git repository to build. Defaults to the default branch.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// ContextDir is the directory of the build context in the git repository. Defaults to the root.
+	// +optional
+	ContextDir string `json:"contextDir,omitempty"`
+
+	// Dockerfile is the path of the Dockerfile relative to the build context. Defaults to Dockerfile.
+	// +optional
+	Dockerfile string `json:"dockerfile,omitempty"`
+
+	// Image is the repository the image is pushed to, like registry.example.com/runners/ci.
+	Image string `json:"image"`
+
+	// PushSecretRef is the kubernetes.io/dockerconfigjson secret with the credentials of the registry.
+	// +optional
+	PushSecretRef *SecretReference `json:"pushSecretRef,omitempty"`
+
+	// RebuildInterval is the interval the image is rebuilt at, to pick up the changes of the branch and the base image.
+	// The runners are rolled out only when the digest of the image changes.
+	// When omitted, the image is rebuilt only when imageBuild changes.
+	// +optional
+	// +nullable
+	RebuildInterval *metav1.Duration `json:"rebuildInterval,omitempty"`
+}
+
+// RunnerImageBuildStatus is the observed state of the runner image build.
+type RunnerImageBuildStatus struct {
+	// Image is the image, by digest, the runners use. It's the image of the last successful build.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Job is the name of the last build job.
+	// +optional
+	Job string `json:"job,omitempty"`
+
+	// Phase is the phase of the last build job, one of Building, Succeeded, and Failed.
+	// The runners keep using the image of the last successful build while building and when the build failed.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+}
+
+// ImagePolicy is how the runner image is tracked, verified, and rolled out.
+type ImagePolicy struct {
+	// Image is the name of the tracked runner image, without the tag. Defaults to summerwind/actions-runner, the upstream runner image.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Tag is the tracked tag. Defaults to latest.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// PullSecretRef is the kubernetes.io/dockerconfigjson secret with the credentials of the registry.
+	// The registry is accessed anonymously when omitted.
+	// +optional
+	PullSecretRef *SecretReference `json:"pullSecretRef,omitempty"`
+
+	// CheckInterval is the interval the tag is resolved at. Defaults to 1h.
+	// +optional
+	// +nullable
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// Verification verifies the digest of the tag before rolling it out.
+	// The digest of the manifest is always verified against its content.
+	// +optional
+	Verification *ImageVerification `json:"verification,omitempty"`
+
+	// RolloutWindows are the periods the new digests are rolled out in. A new digest is rolled out as soon as it's found when omitted.
+	// The first digest is pinned right away, regardless of the rollout windows.
+	// +optional
+	RolloutWindows []MaintenanceSchedule `json:"rolloutWindows,omitempty"`
+}
+
+// ImageVerification is how the digest of the runner image is verified.
+type ImageVerification struct {
+	// CosignPublicKeySecretRef is the secret with the public key of cosign in the cosign.pub key,
+	// like the one created by cosign generate-key-pair k8s://NAMESPACE/NAME.
+	// The digest must be signed with the private key by cosign sign --key.
+	// +optional
+	CosignPublicKeySecretRef *SecretReference `json:"cosignPublicKeySecretRef,omitempty"`
+
+	// Keyless verifies the keyless signatures made by cosign sign with the Fulcio certificates issued to any of the identities,
+	// instead of the public key.
+	// +optional
+	Keyless *SigstoreVerification `json:"keyless,omitempty"`
+
+	// Attestations are the predicate types of the attestations made by cosign attest, like https://slsa.dev/provenance/v1,
+	// that the digest must have in addition to the signature. They're verified with the same public key or identities as the signature.
+	// +optional
+	Attestations []string `json:"attestations,omitempty"`
+}
+
+func (v *ImageVerification) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if v.CosignPublicKeySecretRef != nil && v.Keyless != nil {
+		errList = append(errList, field.Forbidden(rootPath.Child("keyless"), "cosignPublicKeySecretRef and keyless are mutually exclusive"))
+	}
+
+	if v.Keyless != nil {
+		errList = append(errList, v.Keyless.Validate(rootPath.Child("keyless"), true)...)
+	}
+
+	// Otherwise nothing would be verified while the runners are reported to run the verified image
+	if v.CosignPublicKeySecretRef == nil && v.Keyless == nil {
+		errList = append(errList, field.Required(rootPath.Child("cosignPublicKeySecretRef"), "either cosignPublicKeySecretRef or keyless must be set"))
+	}
+
+	for i, t := range v.Attestations {
+		if t == "" {
+			errList = append(errList, field.Required(rootPath.Child("attestations").Index(i), "the predicate type must be set"))
+		}
+	}
+
+	return errList
+}
+
+// SigstoreVerification is how the keyless signatures of Sigstore are verified.
+type SigstoreVerification struct {
+	// Identities are the OIDC identities the Fulcio certificates of the signatures must be issued to, any of which is allowed.
+	// +optional
+	Identities []SigstoreIdentity `json:"identities,omitempty"`
+
+	// TrustedRootConfigMapRef is the ConfigMap with the PEM encoded Fulcio certificates in the fulcio_v1.crt.pem key
+	// and the PEM encoded Rekor public keys in the rekor.pub key, like the targets of the same names in the TUF repository of Sigstore.
+	TrustedRootConfigMapRef ConfigMapReference `json:"trustedRootConfigMapRef"`
+}
+
+// SigstoreIdentity is an OIDC identity allowed to sign.
+type SigstoreIdentity struct {
+	// Issuer is the OIDC issuer, like https://token.actions.githubusercontent.com for the GitHub Actions workflows.
+	Issuer string `json:"issuer"`
+
+	// Subject is the email or the URI the certificate is issued to,
+	// like https://github.com/OWNER/REPO/.github/workflows/release.yml@refs/heads/main for a GitHub Actions workflow.
+	// +optional
+	Subject string `json:"subject,omitempty"`
+
+	// SubjectRegExp is the regular expression matching the subject, instead of Subject.
+	// +optional
+	SubjectRegExp string `json:"subjectRegExp,omitempty"`
+}
+
+// ConfigMapReference is a reference to a ConfigMap in the namespace of the resource.
+type ConfigMapReference struct {
+	Name string `json:"name"`
+}
+
+// Validate validates the verification, in which the identities are required unless defaulted.
+func (v *SigstoreVerification) Validate(rootPath *field.Path, identitiesRequired bool) field.ErrorList {
+	var errList field.ErrorList
+
+	if identitiesRequired && len(v.Identities) == 0 {
+		errList = append(errList, field.Required(rootPath.Child("identities"), "at least one identity must be set"))
+	}
+
+	for i, id := range v.Identities {
+		path := rootPath.Child("identities").Index(i)
+
+		if id.Issuer == "" {
+			errList = append(errList, field.Required(path.Child("issuer"), "the OIDC issuer must be set"))
+		}
+
+		if (id.Subject == "") == (id.SubjectRegExp == "") {
+			errList = append(errList, field.Invalid(path.Child("subject"), id.Subject, "exactly one of subject and subjectRegExp must be set"))
+		}
+
+		if id.SubjectRegExp != "" {
+			if _, err := regexp.Compile(id.SubjectRegExp); err != nil {
+				errList = append(errList, field.Invalid(path.Child("subjectRegExp"), id.SubjectRegExp, err.Error()))
+			}
+		}
+	}
+
+	if v.TrustedRootConfigMapRef.Name == "" {
+		errList = append(errList, field.Required(rootPath.Child("trustedRootConfigMapRef", "name"), "the ConfigMap with the trusted root must be set"))
+	}
+
+	return errList
+}
+
+func (p *ImagePolicy) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if strings.Contains(p.Image, "@") || strings.LastIndex(p.Image, ":") > strings.LastIndex(p.Image, "/") {
+		errList = append(errList, field.Invalid(rootPath.Child("image"), p.Image, "must not have a tag or a digest"))
+	}
+
+	if strings.ContainsAny(p.Tag, ":@/") {
+		errList = append(errList, field.Invalid(rootPath.Child("tag"), p.Tag, "must be a tag"))
+	}
+
+	if p.CheckInterval != nil && p.CheckInterval.Duration <= 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("checkInterval"), p.CheckInterval.Duration.String(), "must be greater than 0"))
+	}
+
+	if p.Verification != nil {
+		errList = append(errList, p.Verification.Validate(rootPath.Child("verification"))...)
+	}
+
+	for i, w := range p.RolloutWindows {
+		if !w.EndTime.After(w.StartTime.Time) {
+			errList = append(errList, field.Invalid(rootPath.Child("rolloutWindows").Index(i).Child("endTime"), w.EndTime, "must be after startTime"))
+		}
+	}
+
+	return errList
+}
+
+// ImagePolicyStatus is the observed state of the image policy.
+type ImagePolicyStatus struct {
+	// Image is the image, by digest, the runners use.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Digest is the digest of Image.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// LatestDigest is the verified digest of the tracked tag as of the last check.
+	// It's rolled out in the next rollout window when it differs from Digest.
+	// +optional
+	LatestDigest string `json:"latestDigest,omitempty"`
+
+	// Tracked is the image and the tag checked last, in the NAME:TAG format.
+	// +optional
+	Tracked string `json:"tracked,omitempty"`
+
+	// LastCheckTime is the time the tag was resolved last.
+	// +optional
+	// +nullable
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// Message is why the last check or verification failed. Empty when it succeeded.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Rollouts are the recent rollouts of the image, the latest last, for auditing.
+	// +optional
+	Rollouts []ImageRollout `json:"rollouts,omitempty"`
+}
+
+// ImageRollout is a rollout of the runners from a digest of the runner image to another.
+type ImageRollout struct {
+	// OldDigest is the digest rolled out from. Empty for the first digest.
+	// +optional
+	OldDigest string `json:"oldDigest,omitempty"`
+
+	// NewDigest is the digest rolled out to.
+	NewDigest string `json:"newDigest"`
+
+	// Time is when the rollout started.
+	Time metav1.Time `json:"time"`
+}
+
+// ImageVerificationStatus is the observed state of the image verification.
+type ImageVerificationStatus struct {
+	// Image is template.spec.image as of the last verification.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Digest is the verified digest of Image. Empty while the digest of Image fails the verification.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// VerifiedImage is the image, by the digest verified last, the runners use.
+	// +optional
+	VerifiedImage string `json:"verifiedImage,omitempty"`
+
+	// LastCheckTime is when Image was verified last.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// Message is why the last verification failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	RunnerImageBuildPhaseBuilding  = "Building"
+	RunnerImageBuildPhaseSucceeded = "Succeeded"
+	RunnerImageBuildPhaseFailed    = "Failed"
+)
+
+const (
+	// EgressPolicyProviderNetworkPolicy restricts the egress with a Kubernetes NetworkPolicy.
+	// As NetworkPolicies can't match hostnames, it allows HTTP and HTTPS to any address except the cloud metadata endpoints,
+	// and any traffic to AllowedCIDRs.
+	EgressPolicyProviderNetworkPolicy = "NetworkPolicy"
+	// EgressPolicyProviderCilium restricts the egress with a CiliumNetworkPolicy, which allows HTTP and HTTPS only to the GitHub hostnames
+	// and AllowedFQDNs, and any traffic to AllowedCIDRs.
+	EgressPolicyProviderCilium = "Cilium"
+)
+
+// EgressPolicy is the network policy restricting the egress of the runner pods of a RunnerDeployment.
+type EgressPolicy struct {
+	// Provider is the kind of the network policy, either NetworkPolicy or Cilium. Defaults to NetworkPolicy.
+	// +optional
+	// +kubebuilder:validation:Enum=NetworkPolicy;Cilium
+	Provider string `json:"provider,omitempty"`
+
+	// AllowedFQDNs are the hostnames the runner pods can connect to over HTTP and HTTPS in addition to GitHub,
+	// like registry.npmjs.org or *.docker.io. Requires the Cilium provider.
+	// +optional
+	AllowedFQDNs []string `json:"allowedFQDNs,omitempty"`
+
+	// AllowedCIDRs are the IP ranges the runner pods can connect to on any port, like the ones of an in-cluster registry or a proxy.
+	// +optional
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+
+	// AllowCloudMetadata stops blocking the cloud metadata endpoints, like 169.254.169.254, for the jobs that need the node credentials.
+	// +optional
+	AllowCloudMetadata bool `json:"allowCloudMetadata,omitempty"`
+}
+
+func (p *EgressPolicy) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	switch p.Provider {
+	case "", EgressPolicyProviderNetworkPolicy:
+		if len(p.AllowedFQDNs) > 0 {
+			errList = append(errList, field.Invalid(rootPath.Child("allowedFQDNs"), p.AllowedFQDNs, "requires the Cilium provider, as NetworkPolicies can't match hostnames"))
+		}
+	case EgressPolicyProviderCilium:
+	default:
+		errList = append(errList, field.NotSupported(rootPath.Child("provider"), p.Provider, []string{EgressPolicyProviderNetworkPolicy, EgressPolicyProviderCilium}))
+	}
+
+	for i, cidr := range p.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errList = append(errList, field.Invalid(rootPath.Child("allowedCIDRs").Index(i), cidr, "must be a CIDR like 10.0.0.0/8"))
+		}
+	}
+
+	for i, fqdn := range p.AllowedFQDNs {
+		if fqdn == "" || strings.ContainsAny(fqdn, "/: ") {
+			errList = append(errList, field.Invalid(rootPath.Child("allowedFQDNs").Index(i), fqdn, "must be a hostname like registry.npmjs.org or *.docker.io"))
+		}
+	}
+
+	return errList
+}
+
+// SchedulingBudget is the maximum total resource requests of the runner pods of a RunnerDeployment.
+// The requests of a runner pod are the sum of the requests, or the limits when the requests are omitted, of its containers.
+type SchedulingBudget struct {
+	// CPU is the maximum total CPU requests of the runner pods.
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Memory is the maximum total memory requests of the runner pods.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+func (b *SchedulingBudget) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	validate := func(name string, q *resource.Quantity) {
+		if q != nil && q.Sign() <= 0 {
+			errList = append(errList, field.Invalid(rootPath.Child(name), q.String(), "must be greater than 0"))
+		}
+	}
+
+	validate("cpu", b.CPU)
+	validate("memory", b.Memory)
+
+	return errList
+}
+
+const (
+	// ConcurrencyGateFailurePolicyOpen opens the gate when a condition can't be checked.
+	ConcurrencyGateFailurePolicyOpen = "Open"
+
+	// ConcurrencyGateFailurePolicyClosed closes the gate when a condition can't be checked.
+	ConcurrencyGateFailurePolicyClosed = "Closed"
+)
+
+// ScaleUpWaves is how the runners of a scale up are created in waves.
+type ScaleUpWaves struct {
+	// WaveSize is the number of the runners created by the first wave of a scale up.
+	// +kubebuilder:validation:Minimum=1
+	WaveSize int `json:"waveSize"`
+
+	// MaxWaveSize is the maximum number of the runners created by a wave. Unlimited when omitted.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxWaveSize *int `json:"maxWaveSize,omitempty"`
+
+	// WaveInterval is how long a wave can take to become ready. Defaults to 2m.
+	// A wave ready within half of it doubles the size of the next wave, and the next wave starts right away.
+	// A wave not ready within it halves the size of the next wave, which starts anyway, so that a stuck runner doesn't block the scale up.
+	// +optional
+	WaveInterval *metav1.Duration `json:"waveInterval,omitempty"`
+}
+
+func (w *ScaleUpWaves) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if w.WaveSize < 1 {
+		errList = append(errList, field.Invalid(rootPath.Child("waveSize"), w.WaveSize, "must be greater than 0"))
+	}
+
+	if w.MaxWaveSize != nil && *w.MaxWaveSize < w.WaveSize {
+		errList = append(errList, field.Invalid(rootPath.Child("maxWaveSize"), *w.MaxWaveSize, "must not be less than waveSize"))
+	}
+
+	if w.WaveInterval != nil && w.WaveInterval.Duration <= 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("waveInterval"), w.WaveInterval.Duration.String(), "must be greater than 0"))
+	}
+
+	return errList
+}
+
+// ConcurrencyGate is the set of the external conditions that must all hold for the RunnerDeployment to scale up.
+// At least one of configMap, http, and lease must be set.
+type ConcurrencyGate struct {
+	// ConfigMap holds while the key of the ConfigMap in the namespace of the RunnerDeployment has the value.
+	// +optional
+	ConfigMap *ConcurrencyGateConfigMap `json:"configMap,omitempty"`
+
+	// HTTP holds while a GET request to the URL returns a 2xx response.
+	// +optional
+	HTTP *ConcurrencyGateHTTP `json:"http,omitempty"`
+
+	// Lease holds while nobody holds the Lease in the namespace of the RunnerDeployment,
+	// so that maintenance tooling can freeze the scale up by acquiring the Lease.
+	// +optional
+	Lease *ConcurrencyGateLease `json:"lease,omitempty"`
+
+	// CheckInterval is how often the conditions are checked. Defaults to 30s.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// FailurePolicy is either Open or Closed, and tells whether the gate opens or closes when a condition can't be checked,
+	// like when the HTTP endpoint is unreachable. A missing ConfigMap or Lease is not a failure. Defaults to Open.
+	// +optional
+	// +kubebuilder:validation:Enum=Open;Closed
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+type ConcurrencyGateConfigMap struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+
+	// Value is the value of the key that opens the gate. Defaults to "true".
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+type ConcurrencyGateHTTP struct {
+	URL string `json:"url"`
+
+	// Timeout is how long the request can take until it's considered a failure. Defaults to 10s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type ConcurrencyGateLease struct {
+	Name string `json:"name"`
+}
+
+func (g *ConcurrencyGate) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if g.ConfigMap == nil && g.HTTP == nil && g.Lease == nil {
+		errList = append(errList, field.Required(rootPath, "at least one of configMap, http, and lease must be set"))
+	}
+
+	if g.ConfigMap != nil {
+		if g.ConfigMap.Name == "" {
+			errList = append(errList, field.Required(rootPath.Child("configMap", "name"), "name must be specified"))
+		}
+		if g.ConfigMap.Key == "" {
+			errList = append(errList, field.Required(rootPath.Child("configMap", "key"), "key must be specified"))
+		}
+	}
+
+	if g.HTTP != nil {
+		if u, err := url.Parse(g.HTTP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errList = append(errList, field.Invalid(rootPath.Child("http", "url"), g.HTTP.URL, "must be an http or https URL"))
+		}
+	}
+
+	if g.Lease != nil && g.Lease.Name == "" {
+		errList = append(errList, field.Required(rootPath.Child("lease", "name"), "name must be specified"))
+	}
+
+	if g.CheckInterval != nil && g.CheckInterval.Duration <= 0 {
+		errList = append(errList, field.Invalid(rootPath.Child("checkInterval"), g.CheckInterval.Duration.String(), "must be greater than 0"))
+	}
+
+	return errList
+}
+
+// LabelResourceProfile is a class of the runners for the workflow jobs that request the labels with runs-on.
+type LabelResourceProfile struct {
+	// Labels are the runner labels added to the ones of the runner template.
+	// A workflow job is served by the profile when the job requests all the labels.
+	// +kubebuilder:validation:MinItems=1
+	Labels []string `json:"labels"`
+
+	// Resources overrides the resources of the runner container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is added to the node selector of the runner pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// ValidateLabelResourceProfiles validates the profiles and the number of the runners created with each.
+func ValidateLabelResourceProfiles(profiles map[string]LabelResourceProfile, replicas map[string]int, rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	profilesPath := rootPath.Child("labelResourceProfiles")
+
+	for name, p := range profiles {
+		path := profilesPath.Key(name)
+
+		for _, msg := range validation.IsValidLabelValue(name) {
+			errList = append(errList, field.Invalid(path, name, msg))
+		}
+
+		if name == "" {
+			errList = append(errList, field.Required(path, "profile name must not be empty"))
+		}
+
+		if len(p.Labels) == 0 {
+			errList = append(errList, field.Required(path.Child("labels"), "at least one label is required to match the workflow jobs"))
+		}
+
+		for i, l := range p.Labels {
+			if strings.TrimSpace(l) == "" {
+				errList = append(errList, field.Invalid(path.Child("labels").Index(i), l, "must not be empty"))
+			}
+		}
+	}
+
+	for name, n := range replicas {
+		if n < 0 {
+			errList = append(errList, field.Invalid(rootPath.Child("profileReplicas").Key(name), n, "must be greater than or equal to 0"))
+		}
+	}
+
+	return errList
+}
+
+// QuarantineProfileName is the name of the label resource profile reserved for the quarantined runners.
+const QuarantineProfileName = "quarantine"
+
+// DefaultQuarantineRuntimeClassName is the runtime class of the quarantined runner pods unless overridden.
+const DefaultQuarantineRuntimeClassName = "gvisor"
+
+// Quarantine configures which workflow jobs are suspicious and how the quarantined runners are isolated.
+// Only the jobs of the workflow runs triggered by pull requests are deemed suspicious.
+type Quarantine struct {
+	// FirstTimeContributor deems the jobs of the pull requests from the authors who have never contributed to the repository suspicious.
+	// +optional
+	FirstTimeContributor bool `json:"firstTimeContributor,omitempty"`
+
+	// WorkflowFileModified deems the jobs of the pull requests modifying the files under .github/workflows suspicious.
+	// +optional
+	WorkflowFileModified bool `json:"workflowFileModified,omitempty"`
+
+	// SecretsRequested deems the jobs of the workflows referencing secrets other than GITHUB_TOKEN suspicious.
+	// +optional
+	SecretsRequested bool `json:"secretsRequested,omitempty"`
+
+	// RuntimeClassName is the runtime class of the quarantined runner pods. Defaults to gvisor.
+	// +optional
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+
+	// EgressProvider is the kind of the network policy restricting the egress of the quarantined runner pods,
+	// either NetworkPolicy or Cilium. Defaults to NetworkPolicy.
+	// Only Cilium can restrict the egress to the GitHub hostnames, as NetworkPolicies can't match hostnames.
+	// +optional
+	// +kubebuilder:validation:Enum=NetworkPolicy;Cilium
+	EgressProvider string `json:"egressProvider,omitempty"`
+}
+
+func (q *Quarantine) Validate(spec *RunnerDeploymentSpec, rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	if !q.FirstTimeContributor && !q.WorkflowFileModified && !q.SecretsRequested {
+		errList = append(errList, field.Required(rootPath, "at least one of firstTimeContributor, workflowFileModified, and secretsRequested is required"))
+	}
+
+	switch q.EgressProvider {
+	case "", EgressPolicyProviderNetworkPolicy, EgressPolicyProviderCilium:
+	default:
+		errList = append(errList, field.NotSupported(rootPath.Child("egressProvider"), q.EgressProvider, []string{EgressPolicyProviderNetworkPolicy, EgressPolicyProviderCilium}))
+	}
+
+	if _, ok := spec.LabelResourceProfiles[QuarantineProfileName]; ok {
+		errList = append(errList, field.Invalid(field.NewPath("spec", "labelResourceProfiles").Key(QuarantineProfileName), QuarantineProfileName, "the profile name is reserved for the quarantined runners"))
+	}
+
+	template := field.NewPath("spec", "template", "spec")
+
+	if spec.Template.Spec.ContainerMode != "" {
+		errList = append(errList, field.Invalid(template.Child("containerMode"), spec.Template.Spec.ContainerMode, "the quarantined runners run without containers"))
+	}
+
+	if spec.Template.Spec.OS == "windows" {
+		errList = append(errList, field.Invalid(template.Child("os"), spec.Template.Spec.OS, "the quarantined runners require the linux runner pods"))
+	}
+
+	if spec.Template.Spec.GPU != nil {
+		errList = append(errList, field.Invalid(template.Child("gpu"), "", "the quarantined runners can't use the GPU runtime class"))
+	}
+
+	return errList
+}
+
+// RunnerDeploymentStrategy is the rolling update strategy of a RunnerDeployment, modeled after the one of a Deployment.
+type RunnerDeploymentStrategy struct {
+	// MaxSurge is the maximum number of runners that can be created over the desired replicas during an update.
+	// Value can be an absolute number (ex: 5) or a percentage of the desired replicas (ex: 10%), rounded up.
+	// Defaults to 25%.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable is the maximum number of runners that can be unavailable during an update.
+	// Value can be an absolute number (ex: 5) or a percentage of the desired replicas (ex: 10%), rounded down.
+	// Defaults to 25%.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// Canary stops an update once the given percentage of the runners are replaced, until the update is promoted.
+	// +optional
+	Canary *RunnerDeploymentCanary `json:"canary,omitempty"`
+}
+
+func (s *RunnerDeploymentStrategy) Validate(rootPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	validate := func(name string, v *intstr.IntOrString) {
+		if v == nil {
+			return
+		}
+
+		if n, err := intstr.GetScaledValueFromIntOrPercent(v, 100, true); err != nil {
+			errList = append(errList, field.Invalid(rootPath.Child(name), v.String(), err.Error()))
+		} else if n < 0 {
+			errList = append(errList, field.Invalid(rootPath.Child(name), v.String(), "must not be negative"))
+		}
+	}
+
+	validate("maxSurge", s.MaxSurge)
+	validate("maxUnavailable", s.MaxUnavailable)
+
+	return errList
+}
+
+type RunnerDeploymentCanary struct {
+	// Percentage is the percentage of the desired replicas, rounded up, that are replaced before the update waits for the promotion.
+	// Annotate the RunnerDeployment with `actions-runner-controller/promote-canary=true` to promote the update.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
 }
 
 type RunnerDeploymentStatus struct {
@@ -72,6 +979,121 @@ type RunnerDeploymentStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// SchedulingBudget is the observed state of the scheduling budget, available only when spec.schedulingBudget is set.
+	// +optional
+	SchedulingBudget *SchedulingBudgetStatus `json:"schedulingBudget,omitempty"`
+
+	// ImageBuild is the observed state of the runner image build, available only when spec.imageBuild is set.
+	// +optional
+	ImageBuild *RunnerImageBuildStatus `json:"imageBuild,omitempty"`
+
+	// ImagePolicy is the observed state of the image policy, available only when spec.imagePolicy is set.
+	// +optional
+	ImagePolicy *ImagePolicyStatus `json:"imagePolicy,omitempty"`
+
+	// ImageVerification is the observed state of the image verification, available only when spec.imageVerification is set.
+	// +optional
+	ImageVerification *ImageVerificationStatus `json:"imageVerification,omitempty"`
+
+	// CapacityDistribution is the number of the runners on each capacity type, available only when spec.capacityDistribution is set.
+	// +optional
+	CapacityDistribution *CapacityDistributionStatus `json:"capacityDistribution,omitempty"`
+
+	// ConcurrencyGate is the observed state of the concurrency gate, available only when spec.concurrencyGate is set.
+	// +optional
+	ConcurrencyGate *ConcurrencyGateStatus `json:"concurrencyGate,omitempty"`
+
+	// ScaleUpWave is the progress of the ongoing wave of the scale up, available only while spec.scaleUpWaves is scaling up the runners.
+	// +optional
+	ScaleUpWave *ScaleUpWaveStatus `json:"scaleUpWave,omitempty"`
+
+	// ObservedGeneration is the generation of the runner deployment whose template the updated replicas and the conditions
+	// of the status were last computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the standard conditions of the runner deployment, Ready and Synced.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ScaleUpWaveStatus is the progress of a wave of a scale up.
+type ScaleUpWaveStatus struct {
+	// RunnerReplicaSet is the name of the runner replica set scaled up by the wave.
+	RunnerReplicaSet string `json:"runnerReplicaSet"`
+
+	// Number is the sequence number of the wave in the scale up, starting at 1.
+	Number int `json:"number"`
+
+	// Size is the number of the runners the wave creates.
+	Size int `json:"size"`
+
+	// Replicas is the replicas of the runner replica set with the runners of the wave.
+	Replicas int `json:"replicas"`
+
+	// ReadyReplicas is the number of the ready runners of the runner replica set.
+	ReadyReplicas int `json:"readyReplicas"`
+
+	// DesiredReplicas is the replicas the scale up ends at.
+	DesiredReplicas int `json:"desiredReplicas"`
+
+	// StartTime is when the wave started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// ConcurrencyGateStatus is the result of the last check of the conditions of the concurrency gate.
+type ConcurrencyGateStatus struct {
+	// Open is true while all the conditions hold.
+	Open bool `json:"open"`
+
+	// Reason tells which condition closed the gate, or why a condition couldn't be checked.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// LastCheckTime is when the conditions were checked last.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// WithheldReplicas is the number of desired replicas that were not created as the gate is closed.
+	// +optional
+	WithheldReplicas int `json:"withheldReplicas,omitempty"`
+}
+
+// CapacityDistributionStatus is the number of the runners on each capacity type.
+type CapacityDistributionStatus struct {
+	// Spot is the number of the runners on the spot capacity.
+	Spot int `json:"spot"`
+
+	// OnDemand is the number of the runners on the on-demand capacity.
+	OnDemand int `json:"onDemand"`
+
+	// SpotUnavailableSince is when the SpotFirst policy fell back to the on-demand capacity.
+	// It's cleared once the spot capacity is tried again after spotRetryInterval.
+	// +optional
+	SpotUnavailableSince *metav1.Time `json:"spotUnavailableSince,omitempty"`
+}
+
+// SchedulingBudgetStatus is the resource usage of the runner pods of a RunnerDeployment against its scheduling budget.
+type SchedulingBudgetStatus struct {
+	// CPU is the total CPU requests of the runner pods ARC allows to exist.
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Memory is the total memory requests of the runner pods ARC allows to exist.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// WithheldReplicas is the number of desired replicas that were not created due to the scheduling budget.
+	// +optional
+	WithheldReplicas int `json:"withheldReplicas,omitempty"`
+
+	// LimitedBy is the resource that clamped the desired replicas, either "cpu" or "memory".
+	// Empty when the desired replicas is within the scheduling budget.
+	// +optional
+	LimitedBy string `json:"limitedBy,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -87,6 +87,66 @@ func (*RunnerDeploymentValidator) ValidateDelete(context.Context, runtime.Object
 func (r *RunnerDeployment) Validate() error {
 	errList := r.Spec.Template.Spec.Validate(field.NewPath("spec", "template", "spec"))
 
+	if r.Spec.Strategy != nil {
+		errList = append(errList, r.Spec.Strategy.Validate(field.NewPath("spec", "strategy"))...)
+	}
+
+	if r.Spec.SchedulingBudget != nil {
+		errList = append(errList, r.Spec.SchedulingBudget.Validate(field.NewPath("spec", "schedulingBudget"))...)
+	}
+
+	if r.Spec.EgressPolicy != nil {
+		errList = append(errList, r.Spec.EgressPolicy.Validate(field.NewPath("spec", "egressPolicy"))...)
+	}
+
+	if r.Spec.ImagePolicy != nil {
+		errList = append(errList, r.Spec.ImagePolicy.Validate(field.NewPath("spec", "imagePolicy"))...)
+
+		if r.Spec.ImageBuild != nil {
+			errList = append(errList, field.Forbidden(field.NewPath("spec", "imagePolicy"), "imagePolicy and imageBuild are mutually exclusive"))
+		}
+	}
+
+	if v := r.Spec.ImageVerification; v != nil {
+		path := field.NewPath("spec", "imageVerification")
+
+		errList = append(errList, v.Validate(path)...)
+
+		if v.CosignPublicKeySecretRef == nil && v.Keyless == nil {
+			errList = append(errList, field.Required(path.Child("keyless"), "either cosignPublicKeySecretRef or keyless must be set"))
+		}
+
+		if r.Spec.Template.Spec.Image == "" {
+			errList = append(errList, field.Required(field.NewPath("spec", "template", "spec", "image"), "the image must be set to be verified"))
+		}
+
+		if r.Spec.ImageBuild != nil || r.Spec.ImagePolicy != nil {
+			errList = append(errList, field.Forbidden(path, "imageVerification can't be combined with imageBuild or imagePolicy, which set the image. Use imagePolicy.verification instead"))
+		}
+	}
+
+	if r.Spec.ToolCache != nil {
+		errList = append(errList, r.Spec.ToolCache.Validate(field.NewPath("spec", "toolCache"))...)
+	}
+
+	if r.Spec.CapacityDistribution != nil {
+		errList = append(errList, r.Spec.CapacityDistribution.Validate(field.NewPath("spec", "capacityDistribution"))...)
+	}
+
+	if r.Spec.ConcurrencyGate != nil {
+		errList = append(errList, r.Spec.ConcurrencyGate.Validate(field.NewPath("spec", "concurrencyGate"))...)
+	}
+
+	if r.Spec.ScaleUpWaves != nil {
+		errList = append(errList, r.Spec.ScaleUpWaves.Validate(field.NewPath("spec", "scaleUpWaves"))...)
+	}
+
+	errList = append(errList, ValidateLabelResourceProfiles(r.Spec.LabelResourceProfiles, r.Spec.ProfileReplicas, field.NewPath("spec"))...)
+
+	if r.Spec.Quarantine != nil {
+		errList = append(errList, r.Spec.Quarantine.Validate(&r.Spec, field.NewPath("spec", "quarantine"))...)
+	}
+
 	if len(errList) > 0 {
 		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
 	}
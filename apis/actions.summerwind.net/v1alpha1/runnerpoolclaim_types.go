@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunnerPoolClaimSpec defines the pool of runners a team requests.
+// Exactly one of organization and repository is required.
+type RunnerPoolClaimSpec struct {
+	// Organization is the organization the runners are registered to.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[^/]+$`
+	Organization string `json:"organization,omitempty"`
+
+	// Repository is the repository, in the OWNER/REPO format, the runners are registered to.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[^/]+/[^/]+$`
+	Repository string `json:"repository,omitempty"`
+
+	// Labels are the custom labels of the runners the workflow jobs select them by.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Group is the runner group of the organization the runners are added to.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// MinReplicas is the number of the runners kept even when there's no workflow job. Defaults to 0.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the number of the runners the pool scales up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int `json:"maxReplicas"`
+}
+
+type RunnerPoolClaimStatus struct {
+	// ObservedGeneration is the generation of the claim the status was last reconciled for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Namespace is the namespace managed by the platform administrators the RunnerDeployment
+	// and the HorizontalRunnerAutoscaler of the pool are created in.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// RunnerDeployment is the name of the RunnerDeployment, and of the HorizontalRunnerAutoscaler, of the pool.
+	// +optional
+	RunnerDeployment string `json:"runnerDeployment,omitempty"`
+
+	// Replicas is the number of the runners of the pool.
+	// +optional
+	Replicas int `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of the runners of the pool registered to GitHub and running.
+	// +optional
+	ReadyReplicas int `json:"readyReplicas,omitempty"`
+
+	// Conditions are the standard conditions of the claim, Ready and Synced.
+	// Ready is false with the PolicyViolation reason when the admin policy denies the claim.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=rpc
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".spec.organization",name=Organization,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.repository",name=Repository,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.maxReplicas",name=Max,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.readyReplicas",name=Ready,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type=='Ready')].reason",name=Reason,type=string
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RunnerPoolClaim is the Schema for the runnerpoolclaims API.
+// A team creates it in its own namespace to request a pool of runners, which the controller provisions as a RunnerDeployment
+// and a HorizontalRunnerAutoscaler in the namespace managed by the platform administrators, as far as their policy allows.
+type RunnerPoolClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunnerPoolClaimSpec   `json:"spec,omitempty"`
+	Status RunnerPoolClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RunnerPoolClaimList contains a list of RunnerPoolClaim
+type RunnerPoolClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunnerPoolClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RunnerPoolClaim{}, &RunnerPoolClaimList{})
+}
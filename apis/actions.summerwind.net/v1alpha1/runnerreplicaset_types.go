@@ -39,6 +39,26 @@ type RunnerReplicaSetSpec struct {
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
 	Template RunnerTemplate        `json:"template"`
+
+	// CapacityDistribution splits the runners between the spot and the on-demand capacity.
+	// It is inherited from the RunnerDeployment.
+	// +optional
+	CapacityDistribution *CapacityDistribution `json:"capacityDistribution,omitempty"`
+
+	// LabelResourceProfiles are the classes of the runners keyed by the profile names.
+	// It is inherited from the RunnerDeployment.
+	// +optional
+	LabelResourceProfiles map[string]LabelResourceProfile `json:"labelResourceProfiles,omitempty"`
+
+	// ProfileReplicas is the number of the runners created with each of LabelResourceProfiles, out of Replicas.
+	// It is inherited from the RunnerDeployment.
+	// +optional
+	ProfileReplicas map[string]int `json:"profileReplicas,omitempty"`
+
+	// Quarantine configures the quarantined runners, which are counted in ProfileReplicas as the quarantine profile.
+	// It is inherited from the RunnerDeployment.
+	// +optional
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
 }
 
 type RunnerReplicaSetStatus struct {
@@ -55,6 +75,21 @@ type RunnerReplicaSetStatus struct {
 	// AvailableReplicas is the number of runners that are created and Runnning.
 	// This is currently same as ReadyReplicas but perserved for future use.
 	AvailableReplicas *int `json:"availableReplicas"`
+
+	// CapacityDistribution is the number of the runners on each capacity type, available only when spec.capacityDistribution is set.
+	// +optional
+	CapacityDistribution *CapacityDistributionStatus `json:"capacityDistribution,omitempty"`
+
+	// ObservedGeneration is the generation of the runner replica set whose replicas the ready and the available replicas
+	// of the status were last counted against.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the latest observations of the runner replica set, like whether its spec has drifted.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 type RunnerTemplate struct {
@@ -17,8 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // RunnerSetSpec defines the desired state of RunnerSet
@@ -39,9 +44,102 @@ type RunnerSetSpec struct {
 	// +optional
 	WorkVolumeClaimTemplate *WorkVolumeClaimTemplate `json:"workVolumeClaimTemplate,omitempty"`
 
+	// CacheVolumeClaimTemplate is the template of the persistent volume claims that keep build caches across ephemeral runners.
+	// Each runner pod gets an idle cache volume previously used by another runner of the RunnerSet for the same repository and labels,
+	// or a new one when there's none.
+	//
+	// +optional
+	CacheVolumeClaimTemplate *CacheVolumeClaimTemplate `json:"cacheVolumeClaimTemplate,omitempty"`
+
+	// RunnerUpgrade makes the runner pods download and run the specified or the latest release of actions/runner on start,
+	// instead of the one built into the runner image, and replaces the runners of other versions at the configured pace.
+	//
+	// +optional
+	RunnerUpgrade *RunnerUpgrade `json:"runnerUpgrade,omitempty"`
+
 	appsv1.StatefulSetSpec `json:",inline"`
 }
 
+var runnerVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// RunnerUpgrade configures the in-place upgrades of the actions/runner binaries of a RunnerSet.
+type RunnerUpgrade struct {
+	// Version is the version of actions/runner like 2.311.0, or `latest` to follow the latest release. Defaults to latest.
+	//
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// CheckInterval is how often ARC checks for the latest release of actions/runner. Defaults to 1h.
+	//
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+
+	// BatchSize is the maximum number of outdated runners replaced at once. Defaults to 1.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	BatchSize *int `json:"batchSize,omitempty"`
+
+	// Interval is how long ARC waits after replacing a batch of outdated runners before replacing the next one. Defaults to 5m.
+	//
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Verification makes ARC verify the GitHub artifact attestations of the actions/runner release binaries before upgrading the runners to it,
+	// and the runner pods check the downloaded binaries against the attested digests.
+	// A release failing the verification is refused, and the runners stay on the version verified last.
+	// The identities default to the release workflow of actions/runner.
+	//
+	// +optional
+	Verification *SigstoreVerification `json:"verification,omitempty"`
+}
+
+func (u *RunnerUpgrade) Validate() error {
+	if u.Version != "" && u.Version != "latest" && !runnerVersionPattern.MatchString(u.Version) {
+		return fmt.Errorf("runnerUpgrade.version must be either latest or a version of actions/runner like 2.311.0, but was %q", u.Version)
+	}
+
+	if u.BatchSize != nil && *u.BatchSize < 1 {
+		return errors.New("runnerUpgrade.batchSize must be greater than 0")
+	}
+
+	if u.Verification != nil {
+		if err := u.Verification.Validate(field.NewPath("runnerUpgrade", "verification"), false).ToAggregate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSidecars validates the sidecars of the RunnerSet against the init containers and the containers of its pod template.
+func (s *RunnerSetSpec) ValidateSidecars() error {
+	return validateSidecars(field.NewPath("spec", "sidecars"), s.Sidecars, s.Template.Spec.InitContainers, s.Template.Spec.Containers).ToAggregate()
+}
+
+// CacheVolumeClaimTemplate is the template of the cache volumes of a RunnerSet.
+type CacheVolumeClaimTemplate struct {
+	WorkVolumeClaimTemplate `json:",inline"`
+
+	// MountPath is the path the cache volume is mounted at in the runner container.
+	// Other containers can mount the volume named "cache" via the pod template.
+	MountPath string `json:"mountPath"`
+
+	// TTL is how long a cache volume is kept without being attached to any runner pod before it is deleted.
+	// Defaults to 168h.
+	//
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+func (c *CacheVolumeClaimTemplate) Validate() error {
+	if c.MountPath == "" {
+		return errors.New("cacheVolumeClaimTemplate.mountPath must be specified")
+	}
+
+	return c.WorkVolumeClaimTemplate.validate()
+}
+
 type RunnerSetStatus struct {
 	// See K8s deployment controller code for reference
 	// https://github.com/kubernetes/kubernetes/blob/ea0764452222146c47ec826977f49d7001b0ea8c/pkg/controller/deployment/sync.go#L487-L505
@@ -69,6 +167,21 @@ type RunnerSetStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// RunnerVersion is the version of actions/runner the new runner pods download and run when spec.runnerUpgrade is set.
+	// +optional
+	RunnerVersion string `json:"runnerVersion,omitempty"`
+
+	// ObservedGeneration is the generation of the runner set whose template the replicas and the updated replicas
+	// of the status were last counted against.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the latest observations of the runner set, like whether the actions/runner release is verified.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -24,362 +24,1395 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CacheEntry) DeepCopyInto(out *CacheEntry) {
+func (in *ActiveScheduledOverride) DeepCopyInto(out *ActiveScheduledOverride) {
 	*out = *in
-	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheEntry.
-func (in *CacheEntry) DeepCopy() *CacheEntry {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveScheduledOverride.
+func (in *ActiveScheduledOverride) DeepCopy() *ActiveScheduledOverride {
 	if in == nil {
 		return nil
 	}
-	out := new(CacheEntry)
+	out := new(ActiveScheduledOverride)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
+func (in *BudgetSpec) DeepCopyInto(out *BudgetSpec) {
 	*out = *in
-	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
-	in.EffectiveTime.DeepCopyInto(&out.EffectiveTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
-func (in *CapacityReservation) DeepCopy() *CapacityReservation {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BudgetSpec.
+func (in *BudgetSpec) DeepCopy() *BudgetSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(CapacityReservation)
+	out := new(BudgetSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CheckRunSpec) DeepCopyInto(out *CheckRunSpec) {
+func (in *BudgetStatus) DeepCopyInto(out *BudgetStatus) {
 	*out = *in
-	if in.Types != nil {
-		in, out := &in.Types, &out.Types
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Names != nil {
-		in, out := &in.Names, &out.Names
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Repositories != nil {
-		in, out := &in.Repositories, &out.Repositories
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.LastAccountedTime != nil {
+		in, out := &in.LastAccountedTime, &out.LastAccountedTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckRunSpec.
-func (in *CheckRunSpec) DeepCopy() *CheckRunSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BudgetStatus.
+func (in *BudgetStatus) DeepCopy() *BudgetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(CheckRunSpec)
+	out := new(BudgetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GitHubAPICredentialsFrom) DeepCopyInto(out *GitHubAPICredentialsFrom) {
+func (in *CacheEntry) DeepCopyInto(out *CacheEntry) {
 	*out = *in
-	out.SecretRef = in.SecretRef
+	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAPICredentialsFrom.
-func (in *GitHubAPICredentialsFrom) DeepCopy() *GitHubAPICredentialsFrom {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheEntry.
+func (in *CacheEntry) DeepCopy() *CacheEntry {
 	if in == nil {
 		return nil
 	}
-	out := new(GitHubAPICredentialsFrom)
+	out := new(CacheEntry)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTriggerSpec) {
+func (in *CacheVolumeClaimTemplate) DeepCopyInto(out *CacheVolumeClaimTemplate) {
 	*out = *in
-	if in.CheckRun != nil {
-		in, out := &in.CheckRun, &out.CheckRun
-		*out = new(CheckRunSpec)
-		(*in).DeepCopyInto(*out)
+	in.WorkVolumeClaimTemplate.DeepCopyInto(&out.WorkVolumeClaimTemplate)
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
 	}
-	if in.PullRequest != nil {
-		in, out := &in.PullRequest, &out.PullRequest
-		*out = new(PullRequestSpec)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheVolumeClaimTemplate.
+func (in *CacheVolumeClaimTemplate) DeepCopy() *CacheVolumeClaimTemplate {
+	if in == nil {
+		return nil
 	}
-	if in.Push != nil {
-		in, out := &in.Push, &out.Push
-		*out = new(PushSpec)
+	out := new(CacheVolumeClaimTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityDistribution) DeepCopyInto(out *CapacityDistribution) {
+	*out = *in
+	in.Spot.DeepCopyInto(&out.Spot)
+	in.OnDemand.DeepCopyInto(&out.OnDemand)
+	if in.FallbackAfter != nil {
+		in, out := &in.FallbackAfter, &out.FallbackAfter
+		*out = new(metav1.Duration)
 		**out = **in
 	}
-	if in.WorkflowJob != nil {
-		in, out := &in.WorkflowJob, &out.WorkflowJob
-		*out = new(WorkflowJobSpec)
+	if in.SpotRetryInterval != nil {
+		in, out := &in.SpotRetryInterval, &out.SpotRetryInterval
+		*out = new(metav1.Duration)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubEventScaleUpTriggerSpec.
-func (in *GitHubEventScaleUpTriggerSpec) DeepCopy() *GitHubEventScaleUpTriggerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityDistribution.
+func (in *CapacityDistribution) DeepCopy() *CapacityDistribution {
 	if in == nil {
 		return nil
 	}
-	out := new(GitHubEventScaleUpTriggerSpec)
+	out := new(CapacityDistribution)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HorizontalRunnerAutoscaler) DeepCopyInto(out *HorizontalRunnerAutoscaler) {
+func (in *CapacityDistributionStatus) DeepCopyInto(out *CapacityDistributionStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.SpotUnavailableSince != nil {
+		in, out := &in.SpotUnavailableSince, &out.SpotUnavailableSince
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscaler.
-func (in *HorizontalRunnerAutoscaler) DeepCopy() *HorizontalRunnerAutoscaler {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityDistributionStatus.
+func (in *CapacityDistributionStatus) DeepCopy() *CapacityDistributionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(HorizontalRunnerAutoscaler)
+	out := new(CapacityDistributionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HorizontalRunnerAutoscaler) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
+	*out = *in
+	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
+	in.EffectiveTime.DeepCopyInto(&out.EffectiveTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
+func (in *CapacityReservation) DeepCopy() *CapacityReservation {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(CapacityReservation)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HorizontalRunnerAutoscalerList) DeepCopyInto(out *HorizontalRunnerAutoscalerList) {
+func (in *CapacitySubset) DeepCopyInto(out *CapacitySubset) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]HorizontalRunnerAutoscaler, len(*in))
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerList.
-func (in *HorizontalRunnerAutoscalerList) DeepCopy() *HorizontalRunnerAutoscalerList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacitySubset.
+func (in *CapacitySubset) DeepCopy() *CapacitySubset {
 	if in == nil {
 		return nil
 	}
-	out := new(HorizontalRunnerAutoscalerList)
+	out := new(CapacitySubset)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HorizontalRunnerAutoscalerList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAutoscalerSpec) {
+func (in *CheckRunSpec) DeepCopyInto(out *CheckRunSpec) {
 	*out = *in
-	out.ScaleTargetRef = in.ScaleTargetRef
-	if in.MinReplicas != nil {
-		in, out := &in.MinReplicas, &out.MinReplicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.MaxReplicas != nil {
-		in, out := &in.MaxReplicas, &out.MaxReplicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.ScaleDownDelaySecondsAfterScaleUp != nil {
-		in, out := &in.ScaleDownDelaySecondsAfterScaleUp, &out.ScaleDownDelaySecondsAfterScaleUp
-		*out = new(int)
-		**out = **in
-	}
-	if in.Metrics != nil {
-		in, out := &in.Metrics, &out.Metrics
-		*out = make([]MetricSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.ScaleUpTriggers != nil {
-		in, out := &in.ScaleUpTriggers, &out.ScaleUpTriggers
-		*out = make([]ScaleUpTrigger, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.CapacityReservations != nil {
-		in, out := &in.CapacityReservations, &out.CapacityReservations
-		*out = make([]CapacityReservation, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.ScheduledOverrides != nil {
-		in, out := &in.ScheduledOverrides, &out.ScheduledOverrides
-		*out = make([]ScheduledOverride, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.GitHubAPICredentialsFrom != nil {
-		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
-		*out = new(GitHubAPICredentialsFrom)
-		**out = **in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerSpec.
-func (in *HorizontalRunnerAutoscalerSpec) DeepCopy() *HorizontalRunnerAutoscalerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckRunSpec.
+func (in *CheckRunSpec) DeepCopy() *CheckRunSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HorizontalRunnerAutoscalerSpec)
+	out := new(CheckRunSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAutoscalerStatus) {
+func (in *ConcurrencyGate) DeepCopyInto(out *ConcurrencyGate) {
 	*out = *in
-	if in.DesiredReplicas != nil {
-		in, out := &in.DesiredReplicas, &out.DesiredReplicas
-		*out = new(int)
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConcurrencyGateConfigMap)
 		**out = **in
 	}
-	if in.LastSuccessfulScaleOutTime != nil {
-		in, out := &in.LastSuccessfulScaleOutTime, &out.LastSuccessfulScaleOutTime
-		*out = (*in).DeepCopy()
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(ConcurrencyGateHTTP)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.CacheEntries != nil {
-		in, out := &in.CacheEntries, &out.CacheEntries
-		*out = make([]CacheEntry, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Lease != nil {
+		in, out := &in.Lease, &out.Lease
+		*out = new(ConcurrencyGateLease)
+		**out = **in
 	}
-	if in.ScheduledOverridesSummary != nil {
-		in, out := &in.ScheduledOverridesSummary, &out.ScheduledOverridesSummary
-		*out = new(string)
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(metav1.Duration)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerStatus.
-func (in *HorizontalRunnerAutoscalerStatus) DeepCopy() *HorizontalRunnerAutoscalerStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyGate.
+func (in *ConcurrencyGate) DeepCopy() *ConcurrencyGate {
 	if in == nil {
 		return nil
 	}
-	out := new(HorizontalRunnerAutoscalerStatus)
+	out := new(ConcurrencyGate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
+func (in *ConcurrencyGateConfigMap) DeepCopyInto(out *ConcurrencyGateConfigMap) {
 	*out = *in
-	if in.RepositoryNames != nil {
-		in, out := &in.RepositoryNames, &out.RepositoryNames
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
-func (in *MetricSpec) DeepCopy() *MetricSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyGateConfigMap.
+func (in *ConcurrencyGateConfigMap) DeepCopy() *ConcurrencyGateConfigMap {
 	if in == nil {
 		return nil
 	}
-	out := new(MetricSpec)
+	out := new(ConcurrencyGateConfigMap)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PullRequestSpec) DeepCopyInto(out *PullRequestSpec) {
+func (in *ConcurrencyGateHTTP) DeepCopyInto(out *ConcurrencyGateHTTP) {
 	*out = *in
-	if in.Types != nil {
-		in, out := &in.Types, &out.Types
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Branches != nil {
-		in, out := &in.Branches, &out.Branches
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestSpec.
-func (in *PullRequestSpec) DeepCopy() *PullRequestSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyGateHTTP.
+func (in *ConcurrencyGateHTTP) DeepCopy() *ConcurrencyGateHTTP {
 	if in == nil {
 		return nil
 	}
-	out := new(PullRequestSpec)
+	out := new(ConcurrencyGateHTTP)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PushSpec) DeepCopyInto(out *PushSpec) {
+func (in *ConcurrencyGateLease) DeepCopyInto(out *ConcurrencyGateLease) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PushSpec.
-func (in *PushSpec) DeepCopy() *PushSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyGateLease.
+func (in *ConcurrencyGateLease) DeepCopy() *ConcurrencyGateLease {
 	if in == nil {
 		return nil
 	}
-	out := new(PushSpec)
+	out := new(ConcurrencyGateLease)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RecurrenceRule) DeepCopyInto(out *RecurrenceRule) {
+func (in *ConcurrencyGateStatus) DeepCopyInto(out *ConcurrencyGateStatus) {
 	*out = *in
-	in.UntilTime.DeepCopyInto(&out.UntilTime)
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurrenceRule.
-func (in *RecurrenceRule) DeepCopy() *RecurrenceRule {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyGateStatus.
+func (in *ConcurrencyGateStatus) DeepCopy() *ConcurrencyGateStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RecurrenceRule)
+	out := new(ConcurrencyGateStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Runner) DeepCopyInto(out *Runner) {
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerHookTemplate) DeepCopyInto(out *ContainerHookTemplate) {
+	*out = *in
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LabelVariables != nil {
+		in, out := &in.LabelVariables, &out.LabelVariables
+		*out = make(map[string]map[string]string, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerHookTemplate.
+func (in *ContainerHookTemplate) DeepCopy() *ContainerHookTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerHookTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSpec) DeepCopyInto(out *DeploymentSpec) {
+	*out = *in
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSpec.
+func (in *DeploymentSpec) DeepCopy() *DeploymentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressPolicy) DeepCopyInto(out *EgressPolicy) {
+	*out = *in
+	if in.AllowedFQDNs != nil {
+		in, out := &in.AllowedFQDNs, &out.AllowedFQDNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCIDRs != nil {
+		in, out := &in.AllowedCIDRs, &out.AllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressPolicy.
+func (in *EgressPolicy) DeepCopy() *EgressPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForensicSnapshot) DeepCopyInto(out *ForensicSnapshot) {
+	*out = *in
+	if in.ExitCodes != nil {
+		in, out := &in.ExitCodes, &out.ExitCodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForensicSnapshot.
+func (in *ForensicSnapshot) DeepCopy() *ForensicSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(ForensicSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubAPICredentialsFrom) DeepCopyInto(out *GitHubAPICredentialsFrom) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAPICredentialsFrom.
+func (in *GitHubAPICredentialsFrom) DeepCopy() *GitHubAPICredentialsFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubAPICredentialsFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubAPITokenScope) DeepCopyInto(out *GitHubAPITokenScope) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAPITokenScope.
+func (in *GitHubAPITokenScope) DeepCopy() *GitHubAPITokenScope {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubAPITokenScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubCredential) DeepCopyInto(out *GitHubCredential) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubCredential.
+func (in *GitHubCredential) DeepCopy() *GitHubCredential {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubCredential)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitHubCredential) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubCredentialList) DeepCopyInto(out *GitHubCredentialList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitHubCredential, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubCredentialList.
+func (in *GitHubCredentialList) DeepCopy() *GitHubCredentialList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubCredentialList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitHubCredentialList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubCredentialSpec) DeepCopyInto(out *GitHubCredentialSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	if in.Enterprises != nil {
+		in, out := &in.Enterprises, &out.Enterprises
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Organizations != nil {
+		in, out := &in.Organizations, &out.Organizations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubCredentialSpec.
+func (in *GitHubCredentialSpec) DeepCopy() *GitHubCredentialSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubCredentialSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTriggerSpec) {
+	*out = *in
+	if in.CheckRun != nil {
+		in, out := &in.CheckRun, &out.CheckRun
+		*out = new(CheckRunSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deployment != nil {
+		in, out := &in.Deployment, &out.Deployment
+		*out = new(DeploymentSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PullRequest != nil {
+		in, out := &in.PullRequest, &out.PullRequest
+		*out = new(PullRequestSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Push != nil {
+		in, out := &in.Push, &out.Push
+		*out = new(PushSpec)
+		**out = **in
+	}
+	if in.WorkflowJob != nil {
+		in, out := &in.WorkflowJob, &out.WorkflowJob
+		*out = new(WorkflowJobSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubEventScaleUpTriggerSpec.
+func (in *GitHubEventScaleUpTriggerSpec) DeepCopy() *GitHubEventScaleUpTriggerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubEventScaleUpTriggerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubStatusNotification) DeepCopyInto(out *GitHubStatusNotification) {
+	*out = *in
+	if in.GitHubAPICredentialsFrom != nil {
+		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
+		*out = new(GitHubAPICredentialsFrom)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubStatusNotification.
+func (in *GitHubStatusNotification) DeepCopy() *GitHubStatusNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubStatusNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HorizontalRunnerAutoscaler) DeepCopyInto(out *HorizontalRunnerAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscaler.
+func (in *HorizontalRunnerAutoscaler) DeepCopy() *HorizontalRunnerAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(HorizontalRunnerAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HorizontalRunnerAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HorizontalRunnerAutoscalerList) DeepCopyInto(out *HorizontalRunnerAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HorizontalRunnerAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerList.
+func (in *HorizontalRunnerAutoscalerList) DeepCopy() *HorizontalRunnerAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(HorizontalRunnerAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HorizontalRunnerAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAutoscalerSpec) {
+	*out = *in
+	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxReplicasFromCapacity != nil {
+		in, out := &in.MaxReplicasFromCapacity, &out.MaxReplicasFromCapacity
+		*out = new(MaxReplicasFromCapacity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDownDelaySecondsAfterScaleUp != nil {
+		in, out := &in.ScaleDownDelaySecondsAfterScaleUp, &out.ScaleDownDelaySecondsAfterScaleUp
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleDownDelayFromJobRunDuration != nil {
+		in, out := &in.ScaleDownDelayFromJobRunDuration, &out.ScaleDownDelayFromJobRunDuration
+		*out = new(ScaleDownDelayFromJobRunDuration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScaleUpTriggers != nil {
+		in, out := &in.ScaleUpTriggers, &out.ScaleUpTriggers
+		*out = make([]ScaleUpTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = make([]CapacityReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScheduledOverrides != nil {
+		in, out := &in.ScheduledOverrides, &out.ScheduledOverrides
+		*out = make([]ScheduledOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GitHubAPICredentialsFrom != nil {
+		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
+		*out = new(GitHubAPICredentialsFrom)
+		**out = **in
+	}
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = new(BudgetSpec)
+		**out = **in
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ServiceLevelObjectives != nil {
+		in, out := &in.ServiceLevelObjectives, &out.ServiceLevelObjectives
+		*out = make([]ServiceLevelObjective, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerSpec.
+func (in *HorizontalRunnerAutoscalerSpec) DeepCopy() *HorizontalRunnerAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HorizontalRunnerAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAutoscalerStatus) {
+	*out = *in
+	if in.DesiredReplicas != nil {
+		in, out := &in.DesiredReplicas, &out.DesiredReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastSuccessfulScaleOutTime != nil {
+		in, out := &in.LastSuccessfulScaleOutTime, &out.LastSuccessfulScaleOutTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CacheEntries != nil {
+		in, out := &in.CacheEntries, &out.CacheEntries
+		*out = make([]CacheEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScheduledOverridesSummary != nil {
+		in, out := &in.ScheduledOverridesSummary, &out.ScheduledOverridesSummary
+		*out = new(string)
+		**out = **in
+	}
+	if in.ActiveScheduledOverride != nil {
+		in, out := &in.ActiveScheduledOverride, &out.ActiveScheduledOverride
+		*out = new(ActiveScheduledOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = new(BudgetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxReplicasFromCapacity != nil {
+		in, out := &in.MaxReplicasFromCapacity, &out.MaxReplicasFromCapacity
+		*out = new(int)
+		**out = **in
+	}
+	if in.PausedUntil != nil {
+		in, out := &in.PausedUntil, &out.PausedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.SuggestedReplicas != nil {
+		in, out := &in.SuggestedReplicas, &out.SuggestedReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerStatus.
+func (in *HorizontalRunnerAutoscalerStatus) DeepCopy() *HorizontalRunnerAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HorizontalRunnerAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePolicy) DeepCopyInto(out *ImagePolicy) {
+	*out = *in
+	if in.PullSecretRef != nil {
+		in, out := &in.PullSecretRef, &out.PullSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ImageVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RolloutWindows != nil {
+		in, out := &in.RolloutWindows, &out.RolloutWindows
+		*out = make([]MaintenanceSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePolicy.
+func (in *ImagePolicy) DeepCopy() *ImagePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePolicyStatus) DeepCopyInto(out *ImagePolicyStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Rollouts != nil {
+		in, out := &in.Rollouts, &out.Rollouts
+		*out = make([]ImageRollout, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePolicyStatus.
+func (in *ImagePolicyStatus) DeepCopy() *ImagePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRollout) DeepCopyInto(out *ImageRollout) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRollout.
+func (in *ImageRollout) DeepCopy() *ImageRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerification) DeepCopyInto(out *ImageVerification) {
+	*out = *in
+	if in.CosignPublicKeySecretRef != nil {
+		in, out := &in.CosignPublicKeySecretRef, &out.CosignPublicKeySecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Keyless != nil {
+		in, out := &in.Keyless, &out.Keyless
+		*out = new(SigstoreVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Attestations != nil {
+		in, out := &in.Attestations, &out.Attestations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerification.
+func (in *ImageVerification) DeepCopy() *ImageVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerificationStatus) DeepCopyInto(out *ImageVerificationStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerificationStatus.
+func (in *ImageVerificationStatus) DeepCopy() *ImageVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelResourceProfile) DeepCopyInto(out *LabelResourceProfile) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelResourceProfile.
+func (in *LabelResourceProfile) DeepCopy() *LabelResourceProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelResourceProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenancePeriod) DeepCopyInto(out *MaintenancePeriod) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenancePeriod.
+func (in *MaintenancePeriod) DeepCopy() *MaintenancePeriod {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenancePeriod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceSchedule) DeepCopyInto(out *MaintenanceSchedule) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	in.RecurrenceRule.DeepCopyInto(&out.RecurrenceRule)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceSchedule.
+func (in *MaintenanceSchedule) DeepCopy() *MaintenanceSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceTarget) DeepCopyInto(out *MaintenanceTarget) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.DesiredReplicas != nil {
+		in, out := &in.DesiredReplicas, &out.DesiredReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceTarget.
+func (in *MaintenanceTarget) DeepCopy() *MaintenanceTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowNotification) DeepCopyInto(out *MaintenanceWindowNotification) {
+	*out = *in
+	if in.Slack != nil {
+		in, out := &in.Slack, &out.Slack
+		*out = new(SlackNotification)
+		**out = **in
+	}
+	if in.GitHubStatus != nil {
+		in, out := &in.GitHubStatus, &out.GitHubStatus
+		*out = new(GitHubStatusNotification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowNotification.
+func (in *MaintenanceWindowNotification) DeepCopy() *MaintenanceWindowNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]MaintenanceSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(MaintenanceWindowNotification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
+	*out = *in
+	if in.CurrentPeriod != nil {
+		in, out := &in.CurrentPeriod, &out.CurrentPeriod
+		*out = new(MaintenancePeriod)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NextPeriod != nil {
+		in, out := &in.NextPeriod, &out.NextPeriod
+		*out = new(MaintenancePeriod)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]MaintenanceTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaxReplicasFromCapacity) DeepCopyInto(out *MaxReplicasFromCapacity) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaxReplicasFromCapacity.
+func (in *MaxReplicasFromCapacity) DeepCopy() *MaxReplicasFromCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(MaxReplicasFromCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
+	*out = *in
+	if in.RepositoryNames != nil {
+		in, out := &in.RepositoryNames, &out.RepositoryNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
+func (in *MetricSpec) DeepCopy() *MetricSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAutoscalerHints) DeepCopyInto(out *NodeAutoscalerHints) {
+	*out = *in
+	if in.ProvisioningRequest != nil {
+		in, out := &in.ProvisioningRequest, &out.ProvisioningRequest
+		*out = new(ProvisioningRequestHint)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAutoscalerHints.
+func (in *NodeAutoscalerHints) DeepCopy() *NodeAutoscalerHints {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAutoscalerHints)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningRequestHint) DeepCopyInto(out *ProvisioningRequestHint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningRequestHint.
+func (in *ProvisioningRequestHint) DeepCopy() *ProvisioningRequestHint {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningRequestHint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullRequestSpec) DeepCopyInto(out *PullRequestSpec) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestSpec.
+func (in *PullRequestSpec) DeepCopy() *PullRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PullRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PushSpec) DeepCopyInto(out *PushSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PushSpec.
+func (in *PushSpec) DeepCopy() *PushSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PushSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quarantine) DeepCopyInto(out *Quarantine) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quarantine.
+func (in *Quarantine) DeepCopy() *Quarantine {
+	if in == nil {
+		return nil
+	}
+	out := new(Quarantine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurrenceRule) DeepCopyInto(out *RecurrenceRule) {
+	*out = *in
+	in.UntilTime.DeepCopyInto(&out.UntilTime)
+	if in.Weekdays != nil {
+		in, out := &in.Weekdays, &out.Weekdays
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurrenceRule.
+func (in *RecurrenceRule) DeepCopy() *RecurrenceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurrenceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Runner) DeepCopyInto(out *Runner) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
@@ -440,203 +1473,551 @@ func (in *RunnerConfig) DeepCopyInto(out *RunnerConfig) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
-	if in.VolumeSizeLimit != nil {
-		in, out := &in.VolumeSizeLimit, &out.VolumeSizeLimit
-		x := (*in).DeepCopy()
-		*out = &x
+	if in.VolumeSizeLimit != nil {
+		in, out := &in.VolumeSizeLimit, &out.VolumeSizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.VolumeStorageMedium != nil {
+		in, out := &in.VolumeStorageMedium, &out.VolumeStorageMedium
+		*out = new(string)
+		**out = **in
+	}
+	if in.GitHubAPICredentialsFrom != nil {
+		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
+		*out = new(GitHubAPICredentialsFrom)
+		**out = **in
+	}
+	if in.GitHubAPITokenScope != nil {
+		in, out := &in.GitHubAPITokenScope, &out.GitHubAPITokenScope
+		*out = new(GitHubAPITokenScope)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DrainTimeout != nil {
+		in, out := &in.DrainTimeout, &out.DrainTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PodTemplatePatch != nil {
+		in, out := &in.PodTemplatePatch, &out.PodTemplatePatch
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LifecycleHooks != nil {
+		in, out := &in.LifecycleHooks, &out.LifecycleHooks
+		*out = new(RunnerLifecycleHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeAutoscalerHints != nil {
+		in, out := &in.NodeAutoscalerHints, &out.NodeAutoscalerHints
+		*out = new(NodeAutoscalerHints)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(RunnerGPU)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerConfig.
+func (in *RunnerConfig) DeepCopy() *RunnerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDefaulter) DeepCopyInto(out *RunnerDefaulter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDefaulter.
+func (in *RunnerDefaulter) DeepCopy() *RunnerDefaulter {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDefaulter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeployment) DeepCopyInto(out *RunnerDeployment) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeployment.
+func (in *RunnerDeployment) DeepCopy() *RunnerDeployment {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeployment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerDeployment) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentCanary) DeepCopyInto(out *RunnerDeploymentCanary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentCanary.
+func (in *RunnerDeploymentCanary) DeepCopy() *RunnerDeploymentCanary {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeploymentCanary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentDefaulter) DeepCopyInto(out *RunnerDeploymentDefaulter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentDefaulter.
+func (in *RunnerDeploymentDefaulter) DeepCopy() *RunnerDeploymentDefaulter {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeploymentDefaulter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentList) DeepCopyInto(out *RunnerDeploymentList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerDeployment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentList.
+func (in *RunnerDeploymentList) DeepCopy() *RunnerDeploymentList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeploymentList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerDeploymentList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentSpec) DeepCopyInto(out *RunnerDeploymentSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.EffectiveTime != nil {
+		in, out := &in.EffectiveTime, &out.EffectiveTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Strategy != nil {
+		in, out := &in.Strategy, &out.Strategy
+		*out = new(RunnerDeploymentStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchedulingBudget != nil {
+		in, out := &in.SchedulingBudget, &out.SchedulingBudget
+		*out = new(SchedulingBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EgressPolicy != nil {
+		in, out := &in.EgressPolicy, &out.EgressPolicy
+		*out = new(EgressPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageBuild != nil {
+		in, out := &in.ImageBuild, &out.ImageBuild
+		*out = new(RunnerImageBuild)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePolicy != nil {
+		in, out := &in.ImagePolicy, &out.ImagePolicy
+		*out = new(ImagePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageVerification != nil {
+		in, out := &in.ImageVerification, &out.ImageVerification
+		*out = new(ImageVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ToolCache != nil {
+		in, out := &in.ToolCache, &out.ToolCache
+		*out = new(ToolCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistribution)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConcurrencyGate != nil {
+		in, out := &in.ConcurrencyGate, &out.ConcurrencyGate
+		*out = new(ConcurrencyGate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleUpWaves != nil {
+		in, out := &in.ScaleUpWaves, &out.ScaleUpWaves
+		*out = new(ScaleUpWaves)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LabelResourceProfiles != nil {
+		in, out := &in.LabelResourceProfiles, &out.LabelResourceProfiles
+		*out = make(map[string]LabelResourceProfile, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ProfileReplicas != nil {
+		in, out := &in.ProfileReplicas, &out.ProfileReplicas
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Quarantine != nil {
+		in, out := &in.Quarantine, &out.Quarantine
+		*out = new(Quarantine)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentSpec.
+func (in *RunnerDeploymentSpec) DeepCopy() *RunnerDeploymentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeploymentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentStatus) DeepCopyInto(out *RunnerDeploymentStatus) {
+	*out = *in
+	if in.AvailableReplicas != nil {
+		in, out := &in.AvailableReplicas, &out.AvailableReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.ReadyReplicas != nil {
+		in, out := &in.ReadyReplicas, &out.ReadyReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.UpdatedReplicas != nil {
+		in, out := &in.UpdatedReplicas, &out.UpdatedReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.DesiredReplicas != nil {
+		in, out := &in.DesiredReplicas, &out.DesiredReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.SchedulingBudget != nil {
+		in, out := &in.SchedulingBudget, &out.SchedulingBudget
+		*out = new(SchedulingBudgetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageBuild != nil {
+		in, out := &in.ImageBuild, &out.ImageBuild
+		*out = new(RunnerImageBuildStatus)
+		**out = **in
+	}
+	if in.ImagePolicy != nil {
+		in, out := &in.ImagePolicy, &out.ImagePolicy
+		*out = new(ImagePolicyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageVerification != nil {
+		in, out := &in.ImageVerification, &out.ImageVerification
+		*out = new(ImageVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistributionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConcurrencyGate != nil {
+		in, out := &in.ConcurrencyGate, &out.ConcurrencyGate
+		*out = new(ConcurrencyGateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleUpWave != nil {
+		in, out := &in.ScaleUpWave, &out.ScaleUpWave
+		*out = new(ScaleUpWaveStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentStatus.
+func (in *RunnerDeploymentStatus) DeepCopy() *RunnerDeploymentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerDeploymentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerDeploymentStrategy) DeepCopyInto(out *RunnerDeploymentStrategy) {
+	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
 	}
-	if in.VolumeStorageMedium != nil {
-		in, out := &in.VolumeStorageMedium, &out.VolumeStorageMedium
-		*out = new(string)
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
 		**out = **in
 	}
-	if in.GitHubAPICredentialsFrom != nil {
-		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
-		*out = new(GitHubAPICredentialsFrom)
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(RunnerDeploymentCanary)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerConfig.
-func (in *RunnerConfig) DeepCopy() *RunnerConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentStrategy.
+func (in *RunnerDeploymentStrategy) DeepCopy() *RunnerDeploymentStrategy {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerConfig)
+	out := new(RunnerDeploymentStrategy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDefaulter) DeepCopyInto(out *RunnerDefaulter) {
+func (in *RunnerDeploymentValidator) DeepCopyInto(out *RunnerDeploymentValidator) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDefaulter.
-func (in *RunnerDefaulter) DeepCopy() *RunnerDefaulter {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentValidator.
+func (in *RunnerDeploymentValidator) DeepCopy() *RunnerDeploymentValidator {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDefaulter)
+	out := new(RunnerDeploymentValidator)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeployment) DeepCopyInto(out *RunnerDeployment) {
+func (in *RunnerGPU) DeepCopyInto(out *RunnerGPU) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.DrainTimeout != nil {
+		in, out := &in.DrainTimeout, &out.DrainTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeployment.
-func (in *RunnerDeployment) DeepCopy() *RunnerDeployment {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerGPU.
+func (in *RunnerGPU) DeepCopy() *RunnerGPU {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeployment)
+	out := new(RunnerGPU)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RunnerDeployment) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeploymentDefaulter) DeepCopyInto(out *RunnerDeploymentDefaulter) {
+func (in *RunnerIdentity) DeepCopyInto(out *RunnerIdentity) {
 	*out = *in
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentDefaulter.
-func (in *RunnerDeploymentDefaulter) DeepCopy() *RunnerDeploymentDefaulter {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerIdentity.
+func (in *RunnerIdentity) DeepCopy() *RunnerIdentity {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeploymentDefaulter)
+	out := new(RunnerIdentity)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeploymentList) DeepCopyInto(out *RunnerDeploymentList) {
+func (in *RunnerImageBuild) DeepCopyInto(out *RunnerImageBuild) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]RunnerDeployment, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.PushSecretRef != nil {
+		in, out := &in.PushSecretRef, &out.PushSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.RebuildInterval != nil {
+		in, out := &in.RebuildInterval, &out.RebuildInterval
+		*out = new(metav1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentList.
-func (in *RunnerDeploymentList) DeepCopy() *RunnerDeploymentList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerImageBuild.
+func (in *RunnerImageBuild) DeepCopy() *RunnerImageBuild {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeploymentList)
+	out := new(RunnerImageBuild)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RunnerDeploymentList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerImageBuildStatus) DeepCopyInto(out *RunnerImageBuildStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerImageBuildStatus.
+func (in *RunnerImageBuildStatus) DeepCopy() *RunnerImageBuildStatus {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(RunnerImageBuildStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeploymentSpec) DeepCopyInto(out *RunnerDeploymentSpec) {
+func (in *RunnerLifecycleHTTPHook) DeepCopyInto(out *RunnerLifecycleHTTPHook) {
 	*out = *in
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.EffectiveTime != nil {
-		in, out := &in.EffectiveTime, &out.EffectiveTime
-		*out = (*in).DeepCopy()
-	}
-	if in.Selector != nil {
-		in, out := &in.Selector, &out.Selector
-		*out = new(metav1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	in.Template.DeepCopyInto(&out.Template)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentSpec.
-func (in *RunnerDeploymentSpec) DeepCopy() *RunnerDeploymentSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerLifecycleHTTPHook.
+func (in *RunnerLifecycleHTTPHook) DeepCopy() *RunnerLifecycleHTTPHook {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeploymentSpec)
+	out := new(RunnerLifecycleHTTPHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeploymentStatus) DeepCopyInto(out *RunnerDeploymentStatus) {
+func (in *RunnerLifecycleHook) DeepCopyInto(out *RunnerLifecycleHook) {
 	*out = *in
-	if in.AvailableReplicas != nil {
-		in, out := &in.AvailableReplicas, &out.AvailableReplicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.ReadyReplicas != nil {
-		in, out := &in.ReadyReplicas, &out.ReadyReplicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.UpdatedReplicas != nil {
-		in, out := &in.UpdatedReplicas, &out.UpdatedReplicas
-		*out = new(int)
-		**out = **in
-	}
-	if in.DesiredReplicas != nil {
-		in, out := &in.DesiredReplicas, &out.DesiredReplicas
-		*out = new(int)
-		**out = **in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int)
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(RunnerLifecycleHTTPHook)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentStatus.
-func (in *RunnerDeploymentStatus) DeepCopy() *RunnerDeploymentStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerLifecycleHook.
+func (in *RunnerLifecycleHook) DeepCopy() *RunnerLifecycleHook {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeploymentStatus)
+	out := new(RunnerLifecycleHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RunnerDeploymentValidator) DeepCopyInto(out *RunnerDeploymentValidator) {
+func (in *RunnerLifecycleHooks) DeepCopyInto(out *RunnerLifecycleHooks) {
 	*out = *in
+	if in.PreRegister != nil {
+		in, out := &in.PreRegister, &out.PreRegister
+		*out = new(RunnerLifecycleHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostJob != nil {
+		in, out := &in.PostJob, &out.PostJob
+		*out = new(RunnerLifecycleHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreDestroy != nil {
+		in, out := &in.PreDestroy, &out.PreDestroy
+		*out = new(RunnerLifecycleHook)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerDeploymentValidator.
-func (in *RunnerDeploymentValidator) DeepCopy() *RunnerDeploymentValidator {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerLifecycleHooks.
+func (in *RunnerLifecycleHooks) DeepCopy() *RunnerLifecycleHooks {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerDeploymentValidator)
+	out := new(RunnerLifecycleHooks)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -799,36 +2180,164 @@ func (in *RunnerPodSpec) DeepCopyInto(out *RunnerPodSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.TopologySpreadConstraints != nil {
-		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
-		*out = make([]v1.TopologySpreadConstraint, len(*in))
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.DnsConfig != nil {
+		in, out := &in.DnsConfig, &out.DnsConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WorkVolumeClaimTemplate != nil {
+		in, out := &in.WorkVolumeClaimTemplate, &out.WorkVolumeClaimTemplate
+		*out = new(WorkVolumeClaimTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerHookTemplate != nil {
+		in, out := &in.ContainerHookTemplate, &out.ContainerHookTemplate
+		*out = new(ContainerHookTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Identity != nil {
+		in, out := &in.Identity, &out.Identity
+		*out = new(RunnerIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretMounts != nil {
+		in, out := &in.SecretMounts, &out.SecretMounts
+		*out = make([]RunnerSecretMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ForensicSnapshot != nil {
+		in, out := &in.ForensicSnapshot, &out.ForensicSnapshot
+		*out = new(ForensicSnapshot)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPodSpec.
+func (in *RunnerPodSpec) DeepCopy() *RunnerPodSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerPodSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerPoolClaim) DeepCopyInto(out *RunnerPoolClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPoolClaim.
+func (in *RunnerPoolClaim) DeepCopy() *RunnerPoolClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerPoolClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerPoolClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerPoolClaimList) DeepCopyInto(out *RunnerPoolClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RunnerPoolClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPoolClaimList.
+func (in *RunnerPoolClaimList) DeepCopy() *RunnerPoolClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerPoolClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RunnerPoolClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerPoolClaimSpec) DeepCopyInto(out *RunnerPoolClaimSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPoolClaimSpec.
+func (in *RunnerPoolClaimSpec) DeepCopy() *RunnerPoolClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerPoolClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerPoolClaimStatus) DeepCopyInto(out *RunnerPoolClaimStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.RuntimeClassName != nil {
-		in, out := &in.RuntimeClassName, &out.RuntimeClassName
-		*out = new(string)
-		**out = **in
-	}
-	if in.DnsConfig != nil {
-		in, out := &in.DnsConfig, &out.DnsConfig
-		*out = new(v1.PodDNSConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.WorkVolumeClaimTemplate != nil {
-		in, out := &in.WorkVolumeClaimTemplate, &out.WorkVolumeClaimTemplate
-		*out = new(WorkVolumeClaimTemplate)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPodSpec.
-func (in *RunnerPodSpec) DeepCopy() *RunnerPodSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPoolClaimStatus.
+func (in *RunnerPoolClaimStatus) DeepCopy() *RunnerPoolClaimStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RunnerPodSpec)
+	out := new(RunnerPoolClaimStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -925,6 +2434,30 @@ func (in *RunnerReplicaSetSpec) DeepCopyInto(out *RunnerReplicaSetSpec) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.Template.DeepCopyInto(&out.Template)
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistribution)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LabelResourceProfiles != nil {
+		in, out := &in.LabelResourceProfiles, &out.LabelResourceProfiles
+		*out = make(map[string]LabelResourceProfile, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ProfileReplicas != nil {
+		in, out := &in.ProfileReplicas, &out.ProfileReplicas
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Quarantine != nil {
+		in, out := &in.Quarantine, &out.Quarantine
+		*out = new(Quarantine)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerReplicaSetSpec.
@@ -955,6 +2488,18 @@ func (in *RunnerReplicaSetStatus) DeepCopyInto(out *RunnerReplicaSetStatus) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.CapacityDistribution != nil {
+		in, out := &in.CapacityDistribution, &out.CapacityDistribution
+		*out = new(CapacityDistributionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerReplicaSetStatus.
@@ -982,6 +2527,26 @@ func (in *RunnerReplicaSetValidator) DeepCopy() *RunnerReplicaSetValidator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerSecretMount) DeepCopyInto(out *RunnerSecretMount) {
+	*out = *in
+	if in.SecretNames != nil {
+		in, out := &in.SecretNames, &out.SecretNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerSecretMount.
+func (in *RunnerSecretMount) DeepCopy() *RunnerSecretMount {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerSecretMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RunnerSet) DeepCopyInto(out *RunnerSet) {
 	*out = *in
@@ -1054,6 +2619,16 @@ func (in *RunnerSetSpec) DeepCopyInto(out *RunnerSetSpec) {
 		*out = new(WorkVolumeClaimTemplate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CacheVolumeClaimTemplate != nil {
+		in, out := &in.CacheVolumeClaimTemplate, &out.CacheVolumeClaimTemplate
+		*out = new(CacheVolumeClaimTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RunnerUpgrade != nil {
+		in, out := &in.RunnerUpgrade, &out.RunnerUpgrade
+		*out = new(RunnerUpgrade)
+		(*in).DeepCopyInto(*out)
+	}
 	in.StatefulSetSpec.DeepCopyInto(&out.StatefulSetSpec)
 }
 
@@ -1095,6 +2670,13 @@ func (in *RunnerSetStatus) DeepCopyInto(out *RunnerSetStatus) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerSetStatus.
@@ -1124,6 +2706,25 @@ func (in *RunnerSpec) DeepCopy() *RunnerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerState) DeepCopyInto(out *RunnerState) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerState.
+func (in *RunnerState) DeepCopy() *RunnerState {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerState)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RunnerStatus) DeepCopyInto(out *RunnerStatus) {
 	*out = *in
@@ -1133,10 +2734,22 @@ func (in *RunnerStatus) DeepCopyInto(out *RunnerStatus) {
 		*out = new(WorkflowStatus)
 		**out = **in
 	}
+	if in.State != nil {
+		in, out := &in.State, &out.State
+		*out = new(RunnerState)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LastRegistrationCheckTime != nil {
 		in, out := &in.LastRegistrationCheckTime, &out.LastRegistrationCheckTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerStatus.
@@ -1187,6 +2800,41 @@ func (in *RunnerTemplate) DeepCopy() *RunnerTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerUpgrade) DeepCopyInto(out *RunnerUpgrade) {
+	*out = *in
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BatchSize != nil {
+		in, out := &in.BatchSize, &out.BatchSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(SigstoreVerification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerUpgrade.
+func (in *RunnerUpgrade) DeepCopy() *RunnerUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RunnerValidator) DeepCopyInto(out *RunnerValidator) {
 	*out = *in
@@ -1202,6 +2850,31 @@ func (in *RunnerValidator) DeepCopy() *RunnerValidator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownDelayFromJobRunDuration) DeepCopyInto(out *ScaleDownDelayFromJobRunDuration) {
+	*out = *in
+	if in.Percentile != nil {
+		in, out := &in.Percentile, &out.Percentile
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxDelaySeconds != nil {
+		in, out := &in.MaxDelaySeconds, &out.MaxDelaySeconds
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownDelayFromJobRunDuration.
+func (in *ScaleDownDelayFromJobRunDuration) DeepCopy() *ScaleDownDelayFromJobRunDuration {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownDelayFromJobRunDuration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleTargetRef) DeepCopyInto(out *ScaleTargetRef) {
 	*out = *in
@@ -1238,6 +2911,47 @@ func (in *ScaleUpTrigger) DeepCopy() *ScaleUpTrigger {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleUpWaveStatus) DeepCopyInto(out *ScaleUpWaveStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleUpWaveStatus.
+func (in *ScaleUpWaveStatus) DeepCopy() *ScaleUpWaveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleUpWaveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleUpWaves) DeepCopyInto(out *ScaleUpWaves) {
+	*out = *in
+	if in.MaxWaveSize != nil {
+		in, out := &in.MaxWaveSize, &out.MaxWaveSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.WaveInterval != nil {
+		in, out := &in.WaveInterval, &out.WaveInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleUpWaves.
+func (in *ScaleUpWaves) DeepCopy() *ScaleUpWaves {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleUpWaves)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScheduledOverride) DeepCopyInto(out *ScheduledOverride) {
 	*out = *in
@@ -1261,6 +2975,56 @@ func (in *ScheduledOverride) DeepCopy() *ScheduledOverride {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingBudget) DeepCopyInto(out *SchedulingBudget) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingBudget.
+func (in *SchedulingBudget) DeepCopy() *SchedulingBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingBudgetStatus) DeepCopyInto(out *SchedulingBudgetStatus) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingBudgetStatus.
+func (in *SchedulingBudgetStatus) DeepCopy() *SchedulingBudgetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingBudgetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -1276,6 +3040,104 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceLevelObjective) DeepCopyInto(out *ServiceLevelObjective) {
+	*out = *in
+	if in.RunsOn != nil {
+		in, out := &in.RunsOn, &out.RunsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Threshold = in.Threshold
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceLevelObjective.
+func (in *ServiceLevelObjective) DeepCopy() *ServiceLevelObjective {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceLevelObjective)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigstoreIdentity) DeepCopyInto(out *SigstoreIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigstoreIdentity.
+func (in *SigstoreIdentity) DeepCopy() *SigstoreIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(SigstoreIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigstoreVerification) DeepCopyInto(out *SigstoreVerification) {
+	*out = *in
+	if in.Identities != nil {
+		in, out := &in.Identities, &out.Identities
+		*out = make([]SigstoreIdentity, len(*in))
+		copy(*out, *in)
+	}
+	out.TrustedRootConfigMapRef = in.TrustedRootConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigstoreVerification.
+func (in *SigstoreVerification) DeepCopy() *SigstoreVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SigstoreVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackNotification) DeepCopyInto(out *SlackNotification) {
+	*out = *in
+	out.WebhookURLSecretRef = in.WebhookURLSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackNotification.
+func (in *SlackNotification) DeepCopy() *SlackNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ToolCache) DeepCopyInto(out *ToolCache) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToolCache.
+func (in *ToolCache) DeepCopy() *ToolCache {
+	if in == nil {
+		return nil
+	}
+	out := new(ToolCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkVolumeClaimTemplate) DeepCopyInto(out *WorkVolumeClaimTemplate) {
 	*out = *in
@@ -1300,6 +3162,11 @@ func (in *WorkVolumeClaimTemplate) DeepCopy() *WorkVolumeClaimTemplate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowJobSpec) DeepCopyInto(out *WorkflowJobSpec) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowJobSpec.
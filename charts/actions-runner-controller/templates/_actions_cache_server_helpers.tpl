@@ -0,0 +1,44 @@
+{{/*
+Expand the name of the chart.
+*/}}
+{{- define "actions-runner-controller-actions-cache-server.name" -}}
+{{- default .Chart.Name .Values.actionsCacheServer.nameOverride | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{- define "actions-runner-controller-actions-cache-server.instance" -}}
+{{- printf "%s-%s" .Release.Name "actions-cache-server" }}
+{{- end }}
+
+{{/*
+Create a default fully qualified app name.
+We truncate at 63 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
+If release name contains chart name it will be used as a full name.
+*/}}
+{{- define "actions-runner-controller-actions-cache-server.fullname" -}}
+{{- if .Values.actionsCacheServer.fullnameOverride }}
+{{- .Values.actionsCacheServer.fullnameOverride | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- $name := default .Chart.Name .Values.actionsCacheServer.nameOverride }}
+{{- $instance := include "actions-runner-controller-actions-cache-server.instance" . }}
+{{- if contains $name $instance }}
+{{- $instance | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- printf "%s-%s-%s" .Release.Name $name "actions-cache-server" | trunc 63 | trimSuffix "-" }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+Selector labels
+*/}}
+{{- define "actions-runner-controller-actions-cache-server.selectorLabels" -}}
+app.kubernetes.io/name: {{ include "actions-runner-controller-actions-cache-server.name" . }}
+app.kubernetes.io/instance: {{ include "actions-runner-controller-actions-cache-server.instance" . }}
+{{- end }}
+
+{{/*
+The URL of the cache server the runners use
+*/}}
+{{- define "actions-runner-controller-actions-cache-server.url" -}}
+{{- printf "http://%s.%s.svc:%v/" (include "actions-runner-controller-actions-cache-server.fullname" .) (include "actions-runner-controller.namespace" .) .Values.actionsCacheServer.service.port }}
+{{- end }}
@@ -23,12 +23,15 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/github"
 	"github.com/actions/actions-runner-controller/logging"
 	"github.com/actions/actions-runner-controller/pkg/actionsmetrics"
+	gogithub "github.com/google/go-github/v52/github"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -39,7 +42,9 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,6 +54,9 @@ var (
 
 const (
 	webhookSecretTokenEnvName = "GITHUB_WEBHOOK_SECRET_TOKEN"
+
+	remoteWritePasswordEnvName    = "REMOTE_WRITE_PASSWORD"
+	remoteWriteBearerTokenEnvName = "REMOTE_WRITE_BEARER_TOKEN"
 )
 
 func init() {
@@ -72,6 +80,16 @@ func main() {
 		logLevel  string
 		logFormat string
 
+		jobLogArchiveURL        string
+		jobLogArchiveS3Endpoint string
+		jobLogArchiveRetention  time.Duration
+		jobIndexAddr            string
+		costModelFile           string
+		infraFailures           bool
+
+		remoteWrite               actionsmetrics.RemoteWriter
+		remoteWriteExternalLabels string
+
 		ghClient *github.Client
 	)
 
@@ -98,6 +116,20 @@ func main() {
 	flag.StringVar(&c.BasicauthPassword, "github-basicauth-password", c.BasicauthPassword, "Password for GitHub basic auth to use instead of PAT or GitHub APP in case it's running behind a proxy API")
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
 	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+	flag.StringVar(&jobLogArchiveURL, "job-log-archive-url", "", "The URL of the object storage the logs of the completed workflow jobs are archived to, one of s3://BUCKET/PREFIX, gs://BUCKET/PREFIX, and https://ACCOUNT.blob.core.windows.net/CONTAINER/PREFIX. The logs aren't archived if empty.")
+	flag.StringVar(&jobLogArchiveS3Endpoint, "job-log-archive-s3-endpoint", "", "The endpoint of the S3-compatible storage like MinIO the s3:// job log archive url points to.")
+	flag.DurationVar(&jobLogArchiveRetention, "job-log-archive-retention", 0, "How long the archived workflow job logs are kept, like 8760h. The archived logs are kept forever if zero.")
+	flag.StringVar(&jobIndexAddr, "job-index-addr", ":8081", "The address the query API over the archived workflow jobs binds to. Served only with -job-log-archive-url. Disabled if empty.")
+	flag.StringVar(&remoteWrite.URL, "remote-write-url", "", "The URL of the Prometheus remote write endpoint the metrics are pushed to, like https://prometheus.example.com/api/v1/write. The metrics are only served for scraping if empty.")
+	flag.DurationVar(&remoteWrite.Interval, "remote-write-interval", actionsmetrics.DefaultRemoteWriteInterval, "How often the metrics are pushed to the remote write endpoint.")
+	flag.IntVar(&remoteWrite.BatchSize, "remote-write-batch-size", actionsmetrics.DefaultRemoteWriteBatchSize, "The maximum number of series sent in a request to the remote write endpoint.")
+	flag.IntVar(&remoteWrite.MaxRetries, "remote-write-max-retries", actionsmetrics.DefaultRemoteWriteMaxRetries, "How many times a request failed with a network error, 429, or 5xx is retried before the batch is dropped.")
+	flag.StringVar(&remoteWrite.Username, "remote-write-username", "", "The username of the basic auth to the remote write endpoint.")
+	flag.StringVar(&remoteWrite.Password, "remote-write-password", os.Getenv(remoteWritePasswordEnvName), fmt.Sprintf("The password of the basic auth to the remote write endpoint. Defaults to the value of %s.", remoteWritePasswordEnvName))
+	flag.StringVar(&remoteWrite.BearerToken, "remote-write-bearer-token", os.Getenv(remoteWriteBearerTokenEnvName), fmt.Sprintf("The bearer token to the remote write endpoint, taking precedence over the basic auth. Defaults to the value of %s.", remoteWriteBearerTokenEnvName))
+	flag.StringVar(&remoteWriteExternalLabels, "remote-write-external-labels", "", "The comma-separated NAME=VALUE labels added to every series pushed to the remote write endpoint, like cluster=prod.")
+	flag.StringVar(&costModelFile, "cost-model", "", "The path of the YAML file of the hourly costs of the runners, by default and by the instance types of the nodes. The runner-hours and the estimated costs of the workflow jobs are exported only when set.")
+	flag.BoolVar(&infraFailures, "infra-failures", false, "Watch the runner pods, including the pods of the EphemeralRunners of the runner scale sets, to count the workflow jobs failed because their runner pods were killed by the OOM killer, evicted, or preempted. Requires the permissions to list and watch the pods.")
 
 	flag.Parse()
 
@@ -113,6 +145,20 @@ func main() {
 		webhookSecretToken = webhookSecretTokenEnv
 	}
 
+	if remoteWriteExternalLabels != "" {
+		remoteWrite.ExternalLabels = map[string]string{}
+
+		for _, kv := range strings.Split(remoteWriteExternalLabels, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok || k == "" {
+				fmt.Fprintf(os.Stderr, "Error: -remote-write-external-labels must be comma-separated NAME=VALUE pairs: %q\n", kv)
+				os.Exit(1)
+			}
+
+			remoteWrite.ExternalLabels[k] = v
+		}
+	}
+
 	if webhookSecretToken == "" {
 		logger.Info(fmt.Sprintf("-github-webhook-secret-token and %s are missing or empty. Create one following https://docs.github.com/en/developers/webhooks-and-events/securing-your-webhooks and specify it via the flag or the envvar", webhookSecretTokenEnvName))
 	}
@@ -140,17 +186,175 @@ func main() {
 		InProgressJobs: make(map[int64]actionsmetrics.InProgressJob),
 	}
 
+	eventHooks := []actionsmetrics.EventHook{eventReader.HandleWorkflowJobEvent}
+
+	var logArchiver *actionsmetrics.LogArchiver
+
+	if jobLogArchiveURL != "" {
+		if ghClient == nil {
+			fmt.Fprintln(os.Stderr, "Error: -job-log-archive-url requires GitHub API credentials to download the workflow job logs")
+			os.Exit(1)
+		}
+
+		store, prefix, err := actionsmetrics.NewLogStore(context.Background(), jobLogArchiveURL, jobLogArchiveS3Endpoint)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		logArchiver = &actionsmetrics.LogArchiver{
+			Log:          ctrl.Log.WithName("workflowjoblogs-archiver"),
+			GitHubClient: ghClient,
+			Store:        store,
+			Prefix:       prefix,
+			Retention:    jobLogArchiveRetention,
+			Events:       make(chan *gogithub.WorkflowJobEvent, 1024*1024),
+		}
+
+		// The nodes of the runner pods are recorded only when the server can look up the pods
+		if cfg, err := ctrl.GetConfig(); err != nil {
+			logger.Info("Kubernetes API is not available. The nodes of the runners aren't recorded in the archived jobs", "error", err.Error())
+		} else if kubeClient, err := client.New(cfg, client.Options{Scheme: scheme}); err != nil {
+			logger.Info("Kubernetes client is not initialized. The nodes of the runners aren't recorded in the archived jobs", "error", err.Error())
+		} else {
+			logArchiver.Client = kubeClient
+		}
+
+		if jobIndexAddr != "" {
+			logArchiver.Index = actionsmetrics.NewJobIndex()
+
+			n, err := logArchiver.Index.Load(context.Background(), store, prefix)
+			if err != nil {
+				logger.Error(err, "loading the archived workflow jobs into the index")
+			}
+			logger.Info("Loaded the archived workflow jobs into the index", "count", n)
+		}
+
+		eventHooks = append(eventHooks, logArchiver.HandleWorkflowJobEvent)
+	}
+
+	var costTracker *actionsmetrics.CostTracker
+
+	if costModelFile != "" {
+		model, err := actionsmetrics.LoadCostModel(costModelFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		costTracker = &actionsmetrics.CostTracker{
+			Log:    ctrl.Log.WithName("workflowjobcosts-tracker"),
+			Model:  model,
+			Events: make(chan *gogithub.WorkflowJobEvent, 1024*1024),
+		}
+
+		// The costs annotated on the runner pods and the prices of the instance types apply only when the server can look up the pods
+		if cfg, err := ctrl.GetConfig(); err != nil {
+			logger.Info("Kubernetes API is not available. Every runner costs the default hourly cost", "error", err.Error())
+		} else if kubeClient, err := client.New(cfg, client.Options{Scheme: scheme}); err != nil {
+			logger.Info("Kubernetes client is not initialized. Every runner costs the default hourly cost", "error", err.Error())
+		} else {
+			costTracker.Client = kubeClient
+		}
+
+		eventHooks = append(eventHooks, costTracker.HandleWorkflowJobEvent)
+	}
+
+	var (
+		infraFailureTracker *actionsmetrics.InfraFailureTracker
+		infraFailureConfig  *rest.Config
+	)
+
+	if infraFailures {
+		infraFailureConfig, err = ctrl.GetConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -infra-failures requires the Kubernetes API to watch the runner pods: %v\n", err)
+			os.Exit(1)
+		}
+
+		infraFailureTracker = &actionsmetrics.InfraFailureTracker{
+			Log:    ctrl.Log.WithName("workflowjobinfrafailures-tracker"),
+			Events: make(chan *gogithub.WorkflowJobEvent, 1024*1024),
+		}
+
+		eventHooks = append(eventHooks, infraFailureTracker.HandleWorkflowJobEvent)
+	}
+
 	webhookServer := &actionsmetrics.WebhookServer{
 		Log:            ctrl.Log.WithName("workflowjobmetrics-webhookserver"),
 		SecretKeyBytes: []byte(webhookSecretToken),
 		GitHubClient:   ghClient,
-		EventHooks:     []actionsmetrics.EventHook{eventReader.HandleWorkflowJobEvent},
+		EventHooks:     eventHooks,
 	}
 
 	var wg sync.WaitGroup
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if logArchiver != nil {
+		wg.Add(1)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+			logArchiver.Run(ctx)
+		}()
+	}
+
+	if costTracker != nil {
+		wg.Add(1)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+			costTracker.Run(ctx)
+		}()
+	}
+
+	if infraFailureTracker != nil {
+		wg.Add(2)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+			infraFailureTracker.Run(ctx)
+		}()
+		go func() {
+			defer cancel()
+			defer wg.Done()
+			if err := infraFailureTracker.Watch(ctx, infraFailureConfig, scheme); err != nil {
+				logger.Error(err, "problem watching the runner pods")
+			}
+		}()
+	}
+
+	// Job Query API
+
+	if logArchiver != nil && logArchiver.Index != nil {
+		jobIndexMux := http.NewServeMux()
+		jobIndexMux.Handle("/jobs", logArchiver.Index)
+
+		jobIndexSrv := http.Server{
+			Addr:    jobIndexAddr,
+			Handler: jobIndexMux,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+
+			go func() {
+				<-ctx.Done()
+
+				jobIndexSrv.Shutdown(context.Background())
+			}()
+
+			if err := jobIndexSrv.ListenAndServe(); err != nil {
+				if !errors.Is(err, http.ErrServerClosed) {
+					logger.Error(err, "problem running job query server")
+				}
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer cancel()
@@ -158,6 +362,18 @@ func main() {
 		eventReader.ProcessWorkflowJobEvents(ctx)
 	}()
 
+	if remoteWrite.URL != "" {
+		remoteWrite.Log = ctrl.Log.WithName("metrics-remotewriter")
+		remoteWrite.Gatherer = metrics.Registry
+
+		wg.Add(1)
+		go func() {
+			defer cancel()
+			defer wg.Done()
+			remoteWrite.Run(ctx)
+		}()
+	}
+
 	// Metrics Server
 
 	metricsHandler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
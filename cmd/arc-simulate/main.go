@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/autoscalingsim"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		hraFile        string
+		deliveriesFile string
+		jobsFile       string
+		runnerLabels   string
+		timelineFile   string
+		output         string
+
+		minReplicas, maxReplicas, amount int
+		duration                         time.Duration
+
+		cfg autoscalingsim.Config
+	)
+
+	flag.StringVar(&hraFile, "hra", "", "The YAML manifest of the candidate HorizontalRunnerAutoscaler, which scales by a `githubEvent.workflowJob` scale trigger.")
+	flag.StringVar(&deliveriesFile, "deliveries", "", "The webhook deliveries recorded by the github-webhook-server to replay, as served on its /debug/deliveries endpoint or one delivery per line.")
+	flag.StringVar(&jobsFile, "jobs", "", "The job records exported by the actions-metrics-server to replay, as served on its /jobs endpoint or one record per line. Used instead of --deliveries.")
+	flag.StringVar(&runnerLabels, "runner-labels", "", "The comma-separated labels of the runners. The jobs requiring other labels are ignored. Empty to replay all the jobs.")
+	flag.DurationVar(&cfg.RunnerStartup, "runner-startup", autoscalingsim.DefaultRunnerStartup, "How long a runner takes to become available after it was created.")
+	flag.DurationVar(&cfg.Step, "step", autoscalingsim.DefaultStep, "The resolution of the simulation.")
+	flag.DurationVar(&cfg.DefaultScaleDownDelay, "default-scale-down-delay", autoscalingsim.DefaultScaleDownDelay, "The scale down delay of the HorizontalRunnerAutoscaler without scaleDownDelaySecondsAfterScaleOut, like the --default-scale-down-delay of the controller.")
+	flag.IntVar(&minReplicas, "min", -1, "Overrides the minReplicas of the HorizontalRunnerAutoscaler.")
+	flag.IntVar(&maxReplicas, "max", -1, "Overrides the maxReplicas of the HorizontalRunnerAutoscaler.")
+	flag.IntVar(&amount, "amount", 0, "Overrides the amount of the scale trigger, which applies only when the trigger has labels or scaleDownFactor.")
+	flag.DurationVar(&duration, "duration", 0, "Overrides the duration of the scale trigger.")
+	flag.StringVar(&timelineFile, "timeline", "", "The file the timeline of the replicas is written to as CSV.")
+	flag.StringVar(&output, "output", "text", `The format of the report. Valid values are "text" and "json".`)
+	flag.Parse()
+
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output %q", output)
+	}
+
+	if hraFile == "" {
+		return errors.New("--hra is required")
+	}
+
+	if (deliveriesFile == "") == (jobsFile == "") {
+		return errors.New("either --deliveries or --jobs is required")
+	}
+
+	data, err := os.ReadFile(hraFile)
+	if err != nil {
+		return err
+	}
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	if err := yaml.UnmarshalStrict(data, &hra); err != nil {
+		return fmt.Errorf("reading %s: %w", hraFile, err)
+	}
+
+	cfg.Spec = hra.Spec
+
+	if minReplicas >= 0 {
+		cfg.Spec.MinReplicas = &minReplicas
+	}
+
+	if maxReplicas >= 0 {
+		cfg.Spec.MaxReplicas = &maxReplicas
+	}
+
+	for i, t := range cfg.Spec.ScaleUpTriggers {
+		if t.GitHubEvent == nil || t.GitHubEvent.WorkflowJob == nil {
+			continue
+		}
+
+		if amount > 0 {
+			cfg.Spec.ScaleUpTriggers[i].Amount = amount
+		}
+
+		if duration > 0 {
+			cfg.Spec.ScaleUpTriggers[i].Duration.Duration = duration
+		}
+
+		break
+	}
+
+	if runnerLabels != "" {
+		cfg.RunnerLabels = strings.Split(runnerLabels, ",")
+	}
+
+	var jobs []autoscalingsim.Job
+
+	if deliveriesFile != "" {
+		f, err := os.Open(deliveriesFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		jobs, err = autoscalingsim.ReadDeliveries(f)
+		if err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Open(jobsFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		jobs, err = autoscalingsim.ReadJobRecords(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := autoscalingsim.Simulate(cfg, jobs)
+	if err != nil {
+		return err
+	}
+
+	if timelineFile != "" {
+		f, err := os.Create(timelineFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := report.WriteTimelineCSV(f); err != nil {
+			return fmt.Errorf("writing %s: %w", timelineFile, err)
+		}
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	return report.WriteText(os.Stdout)
+}
@@ -4,14 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/config"
+	"github.com/actions/actions-runner-controller/cmd/ghalistener/federation"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/listener"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/metrics"
 	"github.com/actions/actions-runner-controller/cmd/ghalistener/worker"
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/go-logr/logr"
 	"golang.org/x/sync/errgroup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	leaderElectionLeaseDuration = 15 * time.Second
+	leaderElectionRenewDeadline = 10 * time.Second
+	leaderElectionRetryPeriod   = 2 * time.Second
 )
 
 // App is responsible for initializing required components and running the app.
@@ -24,6 +41,10 @@ type App struct {
 	listener Listener
 	worker   Worker
 	metrics  metrics.ServerExporter
+
+	// set when the listener is a member of a federation
+	federation   *federation.Member
+	brokerLeases coordinationv1.LeasesGetter
 }
 
 //go:generate mockery --name Listener --output ./mocks --outpkg mocks --case underscore
@@ -88,6 +109,50 @@ func New(config config.Config) (*App, error) {
 	}
 	app.worker = worker
 
+	if config.Federation != nil {
+		restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(config.Federation.Kubeconfig))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse federation kubeconfig: %w", err)
+		}
+
+		client, err := federation.NewClient(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create federation client: %w", err)
+		}
+
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create federation kubernetes client: %w", err)
+		}
+		app.brokerLeases = clientset.CoordinationV1()
+
+		member, err := federation.NewMember(federation.Config{
+			Client:     client,
+			Name:       config.Federation.Name,
+			Namespace:  config.Federation.Namespace,
+			MemberName: config.Federation.MemberName,
+			Weight:     config.Federation.Weight,
+			Capacity:   config.MaxRunners - config.MinRunners,
+			Worker:     worker,
+			Logger:     app.logger.WithName("federation"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create federation member: %w", err)
+		}
+		app.federation = member
+	}
+
+	var session listener.SessionConfig
+	if config.Session != nil {
+		session = listener.SessionConfig{
+			Transport:             config.Session.Transport,
+			PollTimeout:           config.Session.PollTimeout.Duration,
+			FallbackAfterFailures: config.Session.FallbackAfterFailures,
+			MaxReconnects:         config.Session.MaxReconnects,
+			MaxReconnectBackoff:   config.Session.MaxReconnectBackoff.Duration,
+		}
+	}
+
 	listener, err := listener.New(listener.Config{
 		Client:     actionsClient,
 		ScaleSetID: app.config.RunnerScaleSetId,
@@ -95,6 +160,12 @@ func New(config config.Config) (*App, error) {
 		MaxRunners: app.config.MaxRunners,
 		Logger:     app.logger.WithName("listener"),
 		Metrics:    app.metrics,
+
+		MaxAcquireJobsPerInterval: app.config.MaxAcquireJobsPerInterval,
+		AcquireJobsInterval:       app.config.AcquireJobsInterval.Duration,
+
+		Session:         session,
+		SessionReporter: worker,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new listener: %w", err)
@@ -107,6 +178,75 @@ func New(config config.Config) (*App, error) {
 }
 
 func (app *App) Run(ctx context.Context) error {
+	run := func(ctx context.Context) error {
+		return app.run(ctx, app.worker)
+	}
+	if app.federation != nil {
+		run = app.runFederated
+	}
+
+	if app.config.LeaderElectionLeaseName != "" {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get in-cluster config: %w", err)
+		}
+
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create kubernetes client: %w", err)
+		}
+
+		identity, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get hostname: %w", err)
+		}
+
+		return app.runWithLeaderElection(
+			ctx,
+			clientset.CoordinationV1(),
+			app.config.LeaderElectionLeaseName,
+			app.config.LeaderElectionLeaseNamespace,
+			identity,
+			run,
+		)
+	}
+
+	return run(ctx)
+}
+
+// runFederated sends the heartbeats of the member to the federation,
+// and runs the listener as the broker of the federation while holding its lease in the broker cluster.
+func (app *App) runFederated(ctx context.Context) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		app.logger.Info("Starting federation member", "federation", app.config.Federation.Name, "member", app.config.Federation.MemberName)
+		return app.federation.Run(ctx)
+	})
+
+	g.Go(func() error {
+		return app.runWithLeaderElection(
+			ctx,
+			app.brokerLeases,
+			app.config.Federation.Name,
+			app.config.Federation.Namespace,
+			// Listener pods of different clusters may share the same name
+			app.config.Federation.MemberName+"_"+hostname,
+			func(ctx context.Context) error {
+				return app.run(ctx, federation.NewBroker(app.federation))
+			},
+		)
+	})
+
+	return g.Wait()
+}
+
+func (app *App) run(ctx context.Context, handler listener.Handler) error {
 	var errs []error
 	if app.worker == nil {
 		errs = append(errs, fmt.Errorf("worker not initialized"))
@@ -123,7 +263,7 @@ func (app *App) Run(ctx context.Context) error {
 
 	g.Go(func() error {
 		app.logger.Info("Starting listener")
-		listnerErr := app.listener.Listen(ctx, app.worker)
+		listnerErr := app.listener.Listen(ctx, handler)
 		cancelMetrics(fmt.Errorf("Listener exited: %w", listnerErr))
 		return listnerErr
 	})
@@ -137,3 +277,74 @@ func (app *App) Run(ctx context.Context) error {
 
 	return g.Wait()
 }
+
+// runWithLeaderElection calls run only while the identity holds the lease,
+// so that a standby takes over when the leader goes away.
+// It returns an error when the leadership is lost, so that the listener pod is recreated as a standby.
+func (app *App) runWithLeaderElection(ctx context.Context, leases coordinationv1.LeasesGetter, leaseName, leaseNamespace, identity string, run func(context.Context) error) error {
+	var (
+		leading atomic.Bool
+		done    = make(chan struct{})
+		runErr  error
+	)
+
+	electionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: leaseNamespace,
+			},
+			Client: leases,
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: identity,
+			},
+		},
+		LeaseDuration:   leaderElectionLeaseDuration,
+		RenewDeadline:   leaderElectionRenewDeadline,
+		RetryPeriod:     leaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				leading.Store(true)
+				defer close(done)
+
+				app.logger.Info("Started leading", "identity", identity)
+				runErr = run(ctx)
+				// Release the lease right away for a standby replica to take over
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				app.logger.Info("Stopped leading", "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					app.logger.Info("Standing by for the leader", "leader", leader)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	app.logger.Info("Starting leader election", "lease", leaseName, "namespace", leaseNamespace, "identity", identity)
+	elector.Run(electionCtx)
+
+	if !leading.Load() {
+		return nil
+	}
+
+	<-done
+	if runErr != nil {
+		return runErr
+	}
+	if ctx.Err() == nil {
+		return fmt.Errorf("lost the leader election")
+	}
+
+	return nil
+}
@@ -1,39 +1,69 @@
 package config
 
 import (
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/build"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/httptransport"
 	"github.com/actions/actions-runner-controller/logging"
 	"github.com/go-logr/logr"
 	"golang.org/x/net/http/httpproxy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Config struct {
-	ConfigureUrl                string                  `json:"configure_url"`
-	AppID                       int64                   `json:"app_id"`
-	AppInstallationID           int64                   `json:"app_installation_id"`
-	AppPrivateKey               string                  `json:"app_private_key"`
-	Token                       string                  `json:"token"`
-	EphemeralRunnerSetNamespace string                  `json:"ephemeral_runner_set_namespace"`
-	EphemeralRunnerSetName      string                  `json:"ephemeral_runner_set_name"`
-	MaxRunners                  int                     `json:"max_runners"`
-	MinRunners                  int                     `json:"min_runners"`
-	RunnerScaleSetId            int                     `json:"runner_scale_set_id"`
-	RunnerScaleSetName          string                  `json:"runner_scale_set_name"`
-	ServerRootCA                string                  `json:"server_root_ca"`
-	LogLevel                    string                  `json:"log_level"`
-	LogFormat                   string                  `json:"log_format"`
-	MetricsAddr                 string                  `json:"metrics_addr"`
-	MetricsEndpoint             string                  `json:"metrics_endpoint"`
-	Metrics                     *v1alpha1.MetricsConfig `json:"metrics"`
+	ConfigureUrl                 string                  `json:"configure_url"`
+	AppID                        int64                   `json:"app_id"`
+	AppInstallationID            int64                   `json:"app_installation_id"`
+	AppPrivateKey                string                  `json:"app_private_key"`
+	Token                        string                  `json:"token"`
+	EphemeralRunnerSetNamespace  string                  `json:"ephemeral_runner_set_namespace"`
+	EphemeralRunnerSetName       string                  `json:"ephemeral_runner_set_name"`
+	MaxRunners                   int                     `json:"max_runners"`
+	MinRunners                   int                     `json:"min_runners"`
+	RunnerScaleSetId             int                     `json:"runner_scale_set_id"`
+	RunnerScaleSetName           string                  `json:"runner_scale_set_name"`
+	ServerRootCA                 string                  `json:"server_root_ca"`
+	TLSMinVersion                string                  `json:"tls_min_version"`
+	LogLevel                     string                  `json:"log_level"`
+	LogFormat                    string                  `json:"log_format"`
+	MetricsAddr                  string                  `json:"metrics_addr"`
+	MetricsEndpoint              string                  `json:"metrics_endpoint"`
+	Metrics                      *v1alpha1.MetricsConfig `json:"metrics"`
+	LeaderElectionLeaseName      string                  `json:"leader_election_lease_name"`
+	LeaderElectionLeaseNamespace string                  `json:"leader_election_lease_namespace"`
+	MaxAcquireJobsPerInterval    int                     `json:"max_acquire_jobs_per_interval"`
+	AcquireJobsInterval          metav1.Duration         `json:"acquire_jobs_interval"`
+	Federation                   *FederationConfig       `json:"federation"`
+	Session                      *SessionConfig          `json:"session"`
+}
+
+// SessionConfig configures how the listener keeps its message session with the Actions service.
+type SessionConfig struct {
+	Transport             string          `json:"transport"`
+	PollTimeout           metav1.Duration `json:"poll_timeout"`
+	FallbackAfterFailures int             `json:"fallback_after_failures"`
+	MaxReconnects         int             `json:"max_reconnects"`
+	MaxReconnectBackoff   metav1.Duration `json:"max_reconnect_backoff"`
+	// KeepAlive is the interval of the TCP keep-alive probes of the connections to the Actions service.
+	KeepAlive metav1.Duration `json:"keep_alive"`
+}
+
+// FederationConfig configures the membership of the listener in a RunnerScaleSetFederation.
+type FederationConfig struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	MemberName string `json:"member_name"`
+	Weight     int    `json:"weight"`
+	// Kubeconfig is the kubeconfig of the broker cluster running the RunnerScaleSetFederation.
+	Kubeconfig string `json:"kubeconfig"`
 }
 
 func Read(path string) (Config, error) {
@@ -84,6 +114,20 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("only one GitHub auth method supported at a time. Have both PAT and App auth: token length: '%d', appId: '%d', installationId: '%d', private key length: '%d", len(c.Token), c.AppID, c.AppInstallationID, len(c.AppPrivateKey))
 	}
 
+	if len(c.LeaderElectionLeaseName) > 0 && len(c.LeaderElectionLeaseNamespace) == 0 {
+		return fmt.Errorf("LeaderElectionLeaseNamespace is missing for the lease '%s'", c.LeaderElectionLeaseName)
+	}
+
+	if c.Federation != nil {
+		if len(c.Federation.Name) == 0 || len(c.Federation.Namespace) == 0 || len(c.Federation.MemberName) == 0 {
+			return fmt.Errorf("Federation name '%s', namespace '%s' or member name '%s' is missing", c.Federation.Name, c.Federation.Namespace, c.Federation.MemberName)
+		}
+
+		if len(c.Federation.Kubeconfig) == 0 {
+			return fmt.Errorf("Federation kubeconfig is missing")
+		}
+	}
+
 	return nil
 }
 
@@ -123,19 +167,11 @@ func (c *Config) ActionsClient(logger logr.Logger, clientOptions ...actions.Clie
 		actions.WithLogger(logger),
 	}, clientOptions...)
 
-	if c.ServerRootCA != "" {
-		systemPool, err := x509.SystemCertPool()
-		if err != nil {
-			return nil, fmt.Errorf("failed to load system cert pool: %w", err)
-		}
-		pool := systemPool.Clone()
-		ok := pool.AppendCertsFromPEM([]byte(c.ServerRootCA))
-		if !ok {
-			return nil, fmt.Errorf("failed to parse root certificate")
-		}
-
-		options = append(options, actions.WithRootCAs(pool))
-	}
+	options = append(options, actions.WithTransportConfig(httptransport.Config{
+		RootCAs:       c.ServerRootCA,
+		TLSMinVersion: c.TLSMinVersion,
+		KeepAlive:     c.keepAlive(),
+	}))
 
 	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
 	options = append(options, actions.WithProxy(func(req *http.Request) (*url.URL, error) {
@@ -158,6 +194,13 @@ func (c *Config) ActionsClient(logger logr.Logger, clientOptions ...actions.Clie
 	return client, nil
 }
 
+func (c *Config) keepAlive() time.Duration {
+	if c.Session == nil {
+		return 0
+	}
+	return c.Session.KeepAlive.Duration
+}
+
 func hasProxy() bool {
 	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
 	return proxyFunc != nil
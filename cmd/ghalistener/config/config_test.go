@@ -90,3 +90,46 @@ func TestConfigValidationConfigUrl(t *testing.T) {
 
 	assert.ErrorContains(t, err, "GitHubConfigUrl is not provided", "Expected error about missing ConfigureUrl")
 }
+
+func TestConfigValidationLeaderElection(t *testing.T) {
+	config := &Config{
+		ConfigureUrl:                "https://github.com/actions",
+		EphemeralRunnerSetNamespace: "namespace",
+		EphemeralRunnerSetName:      "deployment",
+		RunnerScaleSetId:            1,
+		Token:                       "asdf",
+		LeaderElectionLeaseName:     "lease",
+	}
+
+	err := config.Validate()
+	assert.ErrorContains(t, err, "LeaderElectionLeaseNamespace is missing for the lease 'lease'", "Expected error about missing lease namespace")
+
+	config.LeaderElectionLeaseNamespace = "namespace"
+	err = config.Validate()
+	assert.NoError(t, err, "Expected no error")
+}
+
+func TestConfigValidationFederation(t *testing.T) {
+	config := &Config{
+		ConfigureUrl:                "https://github.com/actions",
+		EphemeralRunnerSetNamespace: "namespace",
+		EphemeralRunnerSetName:      "deployment",
+		RunnerScaleSetId:            1,
+		Token:                       "asdf",
+		Federation: &FederationConfig{
+			Name:      "federation",
+			Namespace: "arc-runners",
+		},
+	}
+
+	err := config.Validate()
+	assert.ErrorContains(t, err, "Federation name 'federation', namespace 'arc-runners' or member name '' is missing", "Expected error about missing member name")
+
+	config.Federation.MemberName = "east"
+	err = config.Validate()
+	assert.ErrorContains(t, err, "Federation kubeconfig is missing", "Expected error about missing kubeconfig")
+
+	config.Federation.Kubeconfig = "kubeconfig"
+	err = config.Validate()
+	assert.NoError(t, err, "Expected no error")
+}
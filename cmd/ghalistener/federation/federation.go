@@ -0,0 +1,348 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/cmd/ghalistener/listener"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	heartbeatInterval    = 5 * time.Second
+	defaultMemberTimeout = time.Minute
+)
+
+type Config struct {
+	// Client is the client of the broker cluster running the RunnerScaleSetFederation.
+	Client    client.Client
+	Name      string
+	Namespace string
+	// MemberName identifies the cluster of the listener in the federation.
+	MemberName string
+	Weight     int
+	// Capacity is the number of jobs the member can run on top of its min runners.
+	Capacity int
+	// Worker scales the runners of the member.
+	Worker listener.Handler
+	Logger logr.Logger
+}
+
+func (c *Config) Validate() error {
+	if c.Client == nil {
+		return fmt.Errorf("client is required")
+	}
+	if c.Name == "" || c.Namespace == "" || c.MemberName == "" {
+		return fmt.Errorf("federation name '%s', namespace '%s' or member name '%s' is missing", c.Name, c.Namespace, c.MemberName)
+	}
+	if c.Weight < 0 {
+		return fmt.Errorf("weight must be greater than or equal to 0")
+	}
+	if c.Worker == nil {
+		return fmt.Errorf("worker is required")
+	}
+	return nil
+}
+
+// NewClient returns a client of the broker cluster for the given kubeconfig.
+func NewClient(restConfig *rest.Config) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add v1alpha1 to scheme: %w", err)
+	}
+
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
+
+// Member reports the state of the member to the RunnerScaleSetFederation,
+// and scales the runners of the member to the share of the jobs assigned to it by the broker.
+type Member struct {
+	client    client.Client
+	key       types.NamespacedName
+	name      string
+	weight    int
+	capacity  int
+	worker    listener.Handler
+	logger    logr.Logger
+	heartbeat time.Duration
+
+	mu                sync.Mutex
+	appliedGeneration int64
+	desiredRunners    int
+}
+
+func NewMember(config Config) (*Member, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid federation config: %w", err)
+	}
+
+	return &Member{
+		client:    config.Client,
+		key:       types.NamespacedName{Namespace: config.Namespace, Name: config.Name},
+		name:      config.MemberName,
+		weight:    config.Weight,
+		capacity:  config.Capacity,
+		worker:    config.Worker,
+		logger:    config.Logger,
+		heartbeat: heartbeatInterval,
+	}, nil
+}
+
+// Run sends a heartbeat to the federation until the context is cancelled,
+// applying the share of the member every time the broker splits the jobs.
+func (m *Member) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		federation, err := m.sendHeartbeat(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to send heartbeat: %w", err)
+		}
+
+		if _, err := m.apply(ctx, federation); err != nil {
+			return fmt.Errorf("failed to apply the share of the member: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Member) sendHeartbeat(ctx context.Context) (*v1alpha1.RunnerScaleSetFederation, error) {
+	federation := new(v1alpha1.RunnerScaleSetFederation)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := m.client.Get(ctx, m.key, federation); err != nil {
+			return err
+		}
+
+		status := v1alpha1.RunnerScaleSetFederationMemberStatus{Name: m.name}
+		i := slices.IndexFunc(federation.Status.Members, func(member v1alpha1.RunnerScaleSetFederationMemberStatus) bool {
+			return member.Name == m.name
+		})
+		if i >= 0 {
+			status = federation.Status.Members[i]
+		}
+
+		status.Weight = m.weight
+		status.Capacity = m.capacity
+		status.LastHeartbeatTime = metav1.Now()
+
+		if i >= 0 {
+			federation.Status.Members[i] = status
+		} else {
+			federation.Status.Members = append(federation.Status.Members, status)
+		}
+
+		return m.client.Status().Update(ctx, federation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return federation, nil
+}
+
+// apply scales the runners of the member to its share of the jobs when the broker split them again.
+func (m *Member) apply(ctx context.Context, federation *v1alpha1.RunnerScaleSetFederation) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if federation.Status.SplitGeneration == m.appliedGeneration {
+		return m.desiredRunners, nil
+	}
+
+	share := 0
+	for _, member := range federation.Status.Members {
+		if member.Name == m.name {
+			share = member.AssignedJobs
+			break
+		}
+	}
+
+	m.logger.Info("Applying the share of the jobs assigned by the broker", "broker", federation.Status.Broker, "splitGeneration", federation.Status.SplitGeneration, "assignedJobs", share)
+
+	// Report a completed job so that the worker scales down when the share of the member shrinks
+	desiredRunners, err := m.worker.HandleDesiredRunnerCount(ctx, share, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	m.appliedGeneration = federation.Status.SplitGeneration
+	m.desiredRunners = desiredRunners
+
+	return desiredRunners, nil
+}
+
+// Broker handles the messages of the runner scale set on behalf of the federation,
+// splitting the assigned jobs across the healthy members.
+// Only the listener holding the lease of the federation should run as the broker.
+type Broker struct {
+	member *Member
+
+	lastCount int
+	hasSplit  bool
+}
+
+var _ listener.Handler = (*Broker)(nil)
+
+func NewBroker(member *Member) *Broker {
+	return &Broker{member: member}
+}
+
+// HandleJobStarted forwards the job started message to the worker of the member.
+// The worker skips the runners of the other members since they are not found in its cluster.
+func (b *Broker) HandleJobStarted(ctx context.Context, jobInfo *actions.JobStarted) error {
+	return b.member.worker.HandleJobStarted(ctx, jobInfo)
+}
+
+// HandleDesiredRunnerCount splits the assigned jobs across the members and applies the share of the member.
+// On an empty batch, the last count is split again only when the healthy members changed,
+// so that the share of a member that went away is reassigned.
+func (b *Broker) HandleDesiredRunnerCount(ctx context.Context, count, jobsCompleted int) (int, error) {
+	emptyBatch := count == 0 && jobsCompleted == 0 && b.hasSplit
+	if emptyBatch {
+		count = b.lastCount
+	}
+
+	federation := new(v1alpha1.RunnerScaleSetFederation)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := b.member.client.Get(ctx, b.member.key, federation); err != nil {
+			return err
+		}
+
+		timeout := defaultMemberTimeout
+		if federation.Spec.MemberTimeout != nil {
+			timeout = federation.Spec.MemberTimeout.Duration
+		}
+
+		shares := split(count, healthyMembers(federation.Status.Members, time.Now(), timeout))
+
+		changed := federation.Status.Broker != b.member.name || federation.Status.AssignedJobs != count
+		for i := range federation.Status.Members {
+			member := &federation.Status.Members[i]
+			if member.AssignedJobs != shares[member.Name] {
+				changed = true
+			}
+			member.AssignedJobs = shares[member.Name]
+		}
+
+		if emptyBatch && !changed {
+			return nil
+		}
+
+		federation.Status.Broker = b.member.name
+		federation.Status.AssignedJobs = count
+		federation.Status.SplitGeneration++
+
+		b.member.logger.Info("Splitting the assigned jobs across the members", "assignedJobs", count, "splitGeneration", federation.Status.SplitGeneration, "shares", fmt.Sprint(shares))
+		return b.member.client.Status().Update(ctx, federation)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to split the assigned jobs: %w", err)
+	}
+
+	b.lastCount = count
+	b.hasSplit = true
+
+	return b.member.apply(ctx, federation)
+}
+
+func healthyMembers(members []v1alpha1.RunnerScaleSetFederationMemberStatus, now time.Time, timeout time.Duration) []v1alpha1.RunnerScaleSetFederationMemberStatus {
+	var healthy []v1alpha1.RunnerScaleSetFederationMemberStatus
+	for _, member := range members {
+		if now.Sub(member.LastHeartbeatTime.Time) <= timeout {
+			healthy = append(healthy, member)
+		}
+	}
+	return healthy
+}
+
+// split distributes count jobs across the members proportionally to their weights,
+// without assigning a member more jobs than its capacity.
+// The jobs left once the weighted members are full overflow to the members with weight 0, in name order.
+func split(count int, members []v1alpha1.RunnerScaleSetFederationMemberStatus) map[string]int {
+	members = slices.Clone(members)
+	slices.SortFunc(members, func(a, b v1alpha1.RunnerScaleSetFederationMemberStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	shares := make(map[string]int, len(members))
+	remaining := count
+
+	var weighted []v1alpha1.RunnerScaleSetFederationMemberStatus
+	for _, member := range members {
+		if member.Weight > 0 && member.Capacity > 0 {
+			weighted = append(weighted, member)
+		}
+	}
+
+	for remaining > 0 && len(weighted) > 0 {
+		totalWeight := 0
+		for _, member := range weighted {
+			totalWeight += member.Weight
+		}
+
+		// Largest remainder method, breaking ties in name order
+		quotas := make([]int, len(weighted))
+		remainders := make([]int, len(weighted))
+		assigned := 0
+		for i, member := range weighted {
+			quotas[i] = remaining * member.Weight / totalWeight
+			remainders[i] = remaining * member.Weight % totalWeight
+			assigned += quotas[i]
+		}
+
+		order := make([]int, len(weighted))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			return remainders[b] - remainders[a]
+		})
+		for _, i := range order[:remaining-assigned] {
+			quotas[i]++
+		}
+
+		var notFull []v1alpha1.RunnerScaleSetFederationMemberStatus
+		for i, member := range weighted {
+			room := member.Capacity - shares[member.Name]
+			share := min(quotas[i], room)
+			shares[member.Name] += share
+			remaining -= share
+			if share < room {
+				notFull = append(notFull, member)
+			}
+		}
+		weighted = notFull
+	}
+
+	for _, member := range members {
+		if remaining == 0 {
+			break
+		}
+		if member.Weight > 0 {
+			continue
+		}
+		share := min(remaining, member.Capacity)
+		shares[member.Name] += share
+		remaining -= share
+	}
+
+	return shares
+}
@@ -0,0 +1,169 @@
+package federation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeWorker struct {
+	counts []int
+}
+
+func (w *fakeWorker) HandleJobStarted(ctx context.Context, jobInfo *actions.JobStarted) error {
+	return nil
+}
+
+func (w *fakeWorker) HandleDesiredRunnerCount(ctx context.Context, count, jobsCompleted int) (int, error) {
+	w.counts = append(w.counts, count)
+	return count, nil
+}
+
+func TestSplit(t *testing.T) {
+	member := func(name string, weight, capacity int) v1alpha1.RunnerScaleSetFederationMemberStatus {
+		return v1alpha1.RunnerScaleSetFederationMemberStatus{Name: name, Weight: weight, Capacity: capacity}
+	}
+
+	t.Run("proportional to weights", func(t *testing.T) {
+		shares := split(10, []v1alpha1.RunnerScaleSetFederationMemberStatus{
+			member("b", 1, 100),
+			member("a", 3, 100),
+			member("c", 1, 100),
+		})
+		assert.Equal(t, map[string]int{"a": 6, "b": 2, "c": 2}, shares)
+	})
+
+	t.Run("remainders go in name order on ties", func(t *testing.T) {
+		shares := split(1, []v1alpha1.RunnerScaleSetFederationMemberStatus{
+			member("b", 1, 100),
+			member("a", 1, 100),
+		})
+		assert.Equal(t, map[string]int{"a": 1, "b": 0}, shares)
+	})
+
+	t.Run("capped by capacity", func(t *testing.T) {
+		shares := split(10, []v1alpha1.RunnerScaleSetFederationMemberStatus{
+			member("a", 1, 2),
+			member("b", 1, 100),
+		})
+		assert.Equal(t, map[string]int{"a": 2, "b": 8}, shares)
+	})
+
+	t.Run("overflow to members with weight 0", func(t *testing.T) {
+		shares := split(10, []v1alpha1.RunnerScaleSetFederationMemberStatus{
+			member("a", 1, 4),
+			member("c", 0, 3),
+			member("b", 0, 5),
+		})
+		assert.Equal(t, map[string]int{"a": 4, "b": 5, "c": 1}, shares)
+	})
+
+	t.Run("more jobs than the capacity of the federation", func(t *testing.T) {
+		shares := split(10, []v1alpha1.RunnerScaleSetFederationMemberStatus{
+			member("a", 1, 2),
+			member("b", 0, 3),
+		})
+		assert.Equal(t, map[string]int{"a": 2, "b": 3}, shares)
+	})
+
+	t.Run("no members", func(t *testing.T) {
+		assert.Empty(t, split(10, nil))
+	})
+}
+
+func TestHealthyMembers(t *testing.T) {
+	now := time.Now()
+	members := []v1alpha1.RunnerScaleSetFederationMemberStatus{
+		{Name: "a", LastHeartbeatTime: metav1.NewTime(now.Add(-10 * time.Second))},
+		{Name: "b", LastHeartbeatTime: metav1.NewTime(now.Add(-2 * time.Minute))},
+	}
+
+	healthy := healthyMembers(members, now, time.Minute)
+	assert.Len(t, healthy, 1)
+	assert.Equal(t, "a", healthy[0].Name)
+}
+
+func TestBroker_HandleDesiredRunnerCount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	now := metav1.Now()
+	federation := &v1alpha1.RunnerScaleSetFederation{
+		ObjectMeta: metav1.ObjectMeta{Name: "federation", Namespace: "arc-runners"},
+		Status: v1alpha1.RunnerScaleSetFederationStatus{
+			Members: []v1alpha1.RunnerScaleSetFederationMemberStatus{
+				{Name: "east", Weight: 1, Capacity: 10, LastHeartbeatTime: now},
+				{Name: "west", Weight: 1, Capacity: 10, LastHeartbeatTime: now},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(federation).WithStatusSubresource(federation).Build()
+
+	worker := &fakeWorker{}
+	member, err := NewMember(Config{
+		Client:     c,
+		Name:       "federation",
+		Namespace:  "arc-runners",
+		MemberName: "east",
+		Weight:     1,
+		Capacity:   10,
+		Worker:     worker,
+		Logger:     logr.Discard(),
+	})
+	require.NoError(t, err)
+	broker := NewBroker(member)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "federation", Namespace: "arc-runners"}
+	getStatus := func() v1alpha1.RunnerScaleSetFederationStatus {
+		var federation v1alpha1.RunnerScaleSetFederation
+		require.NoError(t, c.Get(ctx, key, &federation))
+		return federation.Status
+	}
+
+	desired, err := broker.HandleDesiredRunnerCount(ctx, 4, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, desired)
+
+	status := getStatus()
+	assert.Equal(t, "east", status.Broker)
+	assert.Equal(t, 4, status.AssignedJobs)
+	assert.Equal(t, int64(1), status.SplitGeneration)
+	assert.Equal(t, 2, status.Members[0].AssignedJobs)
+	assert.Equal(t, 2, status.Members[1].AssignedJobs)
+
+	t.Run("empty batch keeps the split", func(t *testing.T) {
+		desired, err := broker.HandleDesiredRunnerCount(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, desired)
+		assert.Equal(t, int64(1), getStatus().SplitGeneration)
+		assert.Equal(t, []int{2}, worker.counts)
+	})
+
+	t.Run("empty batch reassigns the share of an unhealthy member", func(t *testing.T) {
+		var federation v1alpha1.RunnerScaleSetFederation
+		require.NoError(t, c.Get(ctx, key, &federation))
+		federation.Status.Members[1].LastHeartbeatTime = metav1.NewTime(now.Add(-2 * defaultMemberTimeout))
+		require.NoError(t, c.Status().Update(ctx, &federation))
+
+		desired, err := broker.HandleDesiredRunnerCount(ctx, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 4, desired)
+
+		status := getStatus()
+		assert.Equal(t, int64(2), status.SplitGeneration)
+		assert.Equal(t, 4, status.Members[0].AssignedJobs)
+		assert.Equal(t, 0, status.Members[1].AssignedJobs)
+		assert.Equal(t, []int{2, 4}, worker.counts)
+	})
+}
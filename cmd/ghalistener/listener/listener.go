@@ -27,6 +27,9 @@ const (
 	messageTypeJobCompleted = "JobCompleted"
 )
 
+// defaultAcquireJobsInterval is the interval of MaxAcquireJobsPerInterval when not configured.
+const defaultAcquireJobsInterval = time.Minute
+
 //go:generate mockery --name Client --output ./mocks --outpkg mocks --case underscore
 type Client interface {
 	GetAcquirableJobs(ctx context.Context, runnerScaleSetId int) (*actions.AcquirableJobList, error)
@@ -45,6 +48,15 @@ type Config struct {
 	MaxRunners int
 	Logger     logr.Logger
 	Metrics    metrics.Publisher
+
+	// MaxAcquireJobsPerInterval limits the number of jobs acquired per AcquireJobsInterval.
+	// Zero means unlimited.
+	MaxAcquireJobsPerInterval int
+	AcquireJobsInterval       time.Duration
+
+	Session SessionConfig
+	// SessionReporter is told the health of the message session. Optional.
+	SessionReporter SessionReporter
 }
 
 func (c *Config) Validate() error {
@@ -63,7 +75,13 @@ func (c *Config) Validate() error {
 	if c.MaxRunners > 0 && c.MinRunners > c.MaxRunners {
 		return errors.New("minRunners must be less than or equal to maxRunners")
 	}
-	return nil
+	if c.MaxAcquireJobsPerInterval < 0 {
+		return errors.New("maxAcquireJobsPerInterval must be greater than or equal to 0")
+	}
+	if c.AcquireJobsInterval < 0 {
+		return errors.New("acquireJobsInterval must be greater than or equal to 0")
+	}
+	return c.Session.Validate()
 }
 
 // The Listener's role is to manage all interactions with the actions service.
@@ -74,6 +92,9 @@ type Listener struct {
 	client     Client            // The client used to interact with the scale set.
 	metrics    metrics.Publisher // The publisher used to publish metrics.
 
+	maxAcquireJobsPerInterval int           // The maximum number of jobs acquired per interval, unlimited when zero.
+	acquireJobsInterval       time.Duration // The interval of maxAcquireJobsPerInterval.
+
 	// internal fields
 	logger   logr.Logger // The logger used for logging.
 	hostname string      // The hostname of the listener.
@@ -82,6 +103,16 @@ type Listener struct {
 	lastMessageID int64                          // The ID of the last processed message.
 	maxCapacity   int                            // The maximum number of runners that can be created.
 	session       *actions.RunnerScaleSetSession // The session for managing the runner scale set.
+
+	acquireIntervalStart time.Time // The start of the current interval of maxAcquireJobsPerInterval.
+	acquiredInInterval   int       // The number of jobs acquired in the current interval.
+
+	sessionConfig   SessionConfig   // How the message session is kept.
+	sessionReporter SessionReporter // The reporter of the session health, if any.
+	transport       string          // The transport in use, which changes on the fallback.
+	pollFailures    int             // The number of consecutive failures to get messages.
+	reconnects      int             // The number of consecutive reconnects of the session.
+	sessionState    *SessionState   // The session state last reported.
 }
 
 func New(config Config) (*Listener, error) {
@@ -95,6 +126,27 @@ func New(config Config) (*Listener, error) {
 		logger:      config.Logger,
 		metrics:     metrics.Discard,
 		maxCapacity: config.MaxRunners,
+
+		maxAcquireJobsPerInterval: config.MaxAcquireJobsPerInterval,
+		acquireJobsInterval:       config.AcquireJobsInterval,
+
+		sessionConfig:   config.Session,
+		sessionReporter: config.SessionReporter,
+		transport:       config.Session.Transport,
+	}
+
+	if listener.acquireJobsInterval == 0 {
+		listener.acquireJobsInterval = defaultAcquireJobsInterval
+	}
+
+	if listener.transport == "" {
+		listener.transport = TransportLongPoll
+	}
+	if listener.sessionConfig.PollTimeout == 0 {
+		listener.sessionConfig.PollTimeout = defaultShortPollTimeout
+	}
+	if listener.sessionConfig.MaxReconnectBackoff == 0 {
+		listener.sessionConfig.MaxReconnectBackoff = defaultMaxReconnectBackoff
 	}
 
 	if config.Metrics != nil {
@@ -162,9 +214,19 @@ func (l *Listener) Listen(ctx context.Context, handler Handler) error {
 
 		msg, err := l.getMessage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get message: %w", err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err := l.reconnect(ctx, handler, err); err != nil {
+				return fmt.Errorf("failed to get message: %w", err)
+			}
+
+			continue
 		}
 
+		l.sessionSucceeded(ctx)
+
 		if msg == nil {
 			_, err := handler.HandleDesiredRunnerCount(ctx, 0, 0)
 			if err != nil {
@@ -187,12 +249,14 @@ func (l *Listener) handleMessage(ctx context.Context, handler Handler, msg *acti
 		return fmt.Errorf("failed to parse message: %w", err)
 	}
 	l.metrics.PublishStatistics(parsedMsg.statistics)
+	l.metrics.PublishSessionLag(sessionLag(parsedMsg.jobsAvailable, time.Now()))
 
-	if len(parsedMsg.jobsAvailable) > 0 {
-		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, parsedMsg.jobsAvailable)
+	if jobsAvailable := l.jobsToAcquire(parsedMsg.jobsAvailable, time.Now()); len(jobsAvailable) > 0 {
+		acquiredJobIDs, err := l.acquireAvailableJobs(ctx, jobsAvailable)
 		if err != nil {
 			return fmt.Errorf("failed to acquire jobs: %w", err)
 		}
+		l.acquiredInInterval += len(acquiredJobIDs)
 
 		l.logger.Info("Jobs are acquired", "count", len(acquiredJobIDs), "requestIds", fmt.Sprint(acquiredJobIDs))
 	}
@@ -268,8 +332,8 @@ func (l *Listener) createSession(ctx context.Context) error {
 }
 
 func (l *Listener) getMessage(ctx context.Context) (*actions.RunnerScaleSetMessage, error) {
-	l.logger.Info("Getting next message", "lastMessageID", l.lastMessageID)
-	msg, err := l.client.GetMessage(ctx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	l.logger.Info("Getting next message", "lastMessageID", l.lastMessageID, "transport", l.transport)
+	msg, err := l.poll(ctx)
 	if err == nil { // if NO error
 		return msg, nil
 	}
@@ -285,7 +349,7 @@ func (l *Listener) getMessage(ctx context.Context) (*actions.RunnerScaleSetMessa
 
 	l.logger.Info("Getting next message", "lastMessageID", l.lastMessageID)
 
-	msg, err = l.client.GetMessage(ctx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	msg, err = l.poll(ctx)
 	if err != nil { // if NO error
 		return nil, fmt.Errorf("failed to get next message after message session refresh: %w", err)
 	}
@@ -397,6 +461,48 @@ func (l *Listener) parseMessage(ctx context.Context, msg *actions.RunnerScaleSet
 	return parsedMsg, nil
 }
 
+// sessionLag returns how long the oldest available job waited in the message session
+// before the listener handled it. It is zero when the message has no available jobs.
+func sessionLag(jobsAvailable []*actions.JobAvailable, now time.Time) time.Duration {
+	var lag time.Duration
+	for _, job := range jobsAvailable {
+		if job.ScaleSetAssignTime.IsZero() {
+			continue
+		}
+		if d := now.Sub(job.ScaleSetAssignTime); d > lag {
+			lag = d
+		}
+	}
+	return lag
+}
+
+// jobsToAcquire returns the available jobs that can be acquired within the current interval of maxAcquireJobsPerInterval.
+// The jobs over the limit are not acquired, which leaves them for the other scale sets of the runner group.
+func (l *Listener) jobsToAcquire(jobsAvailable []*actions.JobAvailable, now time.Time) []*actions.JobAvailable {
+	if l.maxAcquireJobsPerInterval <= 0 || len(jobsAvailable) == 0 {
+		return jobsAvailable
+	}
+
+	if now.Sub(l.acquireIntervalStart) >= l.acquireJobsInterval {
+		l.acquireIntervalStart = now
+		l.acquiredInInterval = 0
+	}
+
+	remaining := max(l.maxAcquireJobsPerInterval-l.acquiredInInterval, 0)
+	if len(jobsAvailable) <= remaining {
+		return jobsAvailable
+	}
+
+	l.logger.Info(
+		"Deferring jobs over the acquisition limit",
+		"count", len(jobsAvailable)-remaining,
+		"maxAcquireJobsPerInterval", l.maxAcquireJobsPerInterval,
+		"acquireJobsInterval", l.acquireJobsInterval.String(),
+	)
+
+	return jobsAvailable[:remaining]
+}
+
 func (l *Listener) acquireAvailableJobs(ctx context.Context, jobsAvailable []*actions.JobAvailable) ([]int64, error) {
 	ids := make([]int64, 0, len(jobsAvailable))
 	for _, job := range jobsAvailable {
@@ -830,6 +830,87 @@ func TestListener_acquireAvailableJobs(t *testing.T) {
 	})
 }
 
+func TestListener_jobsToAcquire(t *testing.T) {
+	t.Parallel()
+
+	jobs := func(ids ...int64) []*actions.JobAvailable {
+		var jobs []*actions.JobAvailable
+		for _, id := range ids {
+			jobs = append(jobs, &actions.JobAvailable{
+				JobMessageBase: actions.JobMessageBase{RunnerRequestId: id},
+			})
+		}
+		return jobs
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		t.Parallel()
+
+		config := Config{
+			Client:     listenermocks.NewClient(t),
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+		}
+
+		l, err := New(config)
+		require.Nil(t, err)
+
+		available := jobs(1, 2, 3)
+		assert.Equal(t, available, l.jobsToAcquire(available, time.Now()))
+	})
+
+	t.Run("LimitsJobsPerInterval", func(t *testing.T) {
+		t.Parallel()
+
+		config := Config{
+			Client:                    listenermocks.NewClient(t),
+			ScaleSetID:                1,
+			Metrics:                   metrics.Discard,
+			MaxAcquireJobsPerInterval: 2,
+			AcquireJobsInterval:       time.Minute,
+		}
+
+		l, err := New(config)
+		require.Nil(t, err)
+
+		now := time.Now()
+
+		available := jobs(1, 2, 3)
+		assert.Equal(t, available[:2], l.jobsToAcquire(available, now))
+		l.acquiredInInterval += 2
+
+		// The limit is reached until the interval elapses
+		assert.Empty(t, l.jobsToAcquire(jobs(4), now.Add(30*time.Second)))
+
+		available = jobs(5, 6, 7)
+		assert.Equal(t, available[:2], l.jobsToAcquire(available, now.Add(time.Minute)))
+	})
+
+	t.Run("CountsOnlyAcquiredJobs", func(t *testing.T) {
+		t.Parallel()
+
+		config := Config{
+			Client:                    listenermocks.NewClient(t),
+			ScaleSetID:                1,
+			Metrics:                   metrics.Discard,
+			MaxAcquireJobsPerInterval: 2,
+		}
+
+		l, err := New(config)
+		require.Nil(t, err)
+		assert.Equal(t, defaultAcquireJobsInterval, l.acquireJobsInterval)
+
+		now := time.Now()
+
+		assert.Len(t, l.jobsToAcquire(jobs(1, 2), now), 2)
+		// Only one of the jobs was acquired, as the other one was acquired by another scale set
+		l.acquiredInInterval++
+
+		available := jobs(3, 4)
+		assert.Equal(t, available[:1], l.jobsToAcquire(available, now.Add(time.Second)))
+	})
+}
+
 func TestListener_parseMessage(t *testing.T) {
 	t.Run("FailOnEmptyStatistics", func(t *testing.T) {
 		msg := &actions.RunnerScaleSetMessage{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	listenermocks "github.com/actions/actions-runner-controller/cmd/ghalistener/listener/mocks"
 	metricsmocks "github.com/actions/actions-runner-controller/cmd/ghalistener/metrics/mocks"
@@ -171,6 +172,7 @@ func TestHandleMessageMetrics(t *testing.T) {
 	metrics := metricsmocks.NewPublisher(t)
 	metrics.On("PublishStatic", 0, 0).Once()
 	metrics.On("PublishStatistics", msg.Statistics).Once()
+	metrics.On("PublishSessionLag", time.Duration(0)).Once()
 	metrics.On("PublishJobCompleted", jobsCompleted[0]).Once()
 	metrics.On("PublishJobCompleted", jobsCompleted[1]).Once()
 	metrics.On("PublishJobStarted", jobsStarted[0]).Once()
@@ -203,3 +205,33 @@ func TestHandleMessageMetrics(t *testing.T) {
 	err = l.handleMessage(context.Background(), handler, msg)
 	require.NoError(t, err)
 }
+
+func TestSessionLag(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	jobsAvailable := []*actions.JobAvailable{
+		{
+			JobMessageBase: actions.JobMessageBase{
+				RunnerRequestId:    1,
+				ScaleSetAssignTime: now.Add(-10 * time.Second),
+			},
+		},
+		{
+			JobMessageBase: actions.JobMessageBase{
+				RunnerRequestId:    2,
+				ScaleSetAssignTime: now.Add(-30 * time.Second),
+			},
+		},
+		{
+			// the assign time is unknown
+			JobMessageBase: actions.JobMessageBase{
+				RunnerRequestId: 3,
+			},
+		},
+	}
+
+	assert.Equal(t, 30*time.Second, sessionLag(jobsAvailable, now))
+	assert.Equal(t, time.Duration(0), sessionLag(nil, now))
+}
@@ -0,0 +1,199 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+)
+
+// The transports of the message session.
+const (
+	// TransportLongPoll keeps each GetMessage request open until the Actions service has a message for the listener,
+	// or about 50 seconds pass without any.
+	TransportLongPoll = "long-poll"
+	// TransportShortPoll ends each GetMessage request after the poll timeout and starts a new one,
+	// for the proxies that kill the connections idle for shorter than a long poll.
+	TransportShortPoll = "short-poll"
+)
+
+const (
+	defaultShortPollTimeout    = 25 * time.Second
+	defaultMaxReconnectBackoff = 2 * time.Minute
+	reconnectBaseBackoff       = 2 * time.Second
+)
+
+// SessionConfig configures how the listener keeps its message session with the Actions service.
+type SessionConfig struct {
+	// Transport is either TransportLongPoll or TransportShortPoll. Defaults to TransportLongPoll.
+	Transport string
+	// PollTimeout is how long each GetMessage request of TransportShortPoll is kept open. Defaults to 25s.
+	PollTimeout time.Duration
+	// FallbackAfterFailures switches TransportLongPoll to TransportShortPoll after this many consecutive failures to get messages.
+	// Zero never falls back.
+	FallbackAfterFailures int
+	// MaxReconnects is the number of consecutive times the listener re-establishes the session after failing to get messages
+	// before giving up. Zero makes the listener exit on the first failure, leaving the restart to the controller.
+	MaxReconnects int
+	// MaxReconnectBackoff caps the exponential backoff between the reconnects. Defaults to 2m.
+	MaxReconnectBackoff time.Duration
+}
+
+func (c *SessionConfig) Validate() error {
+	switch c.Transport {
+	case "", TransportLongPoll, TransportShortPoll:
+	default:
+		return fmt.Errorf("session transport must be either %s or %s: %q", TransportLongPoll, TransportShortPoll, c.Transport)
+	}
+	if c.PollTimeout < 0 {
+		return errors.New("session pollTimeout must be greater than or equal to 0")
+	}
+	if c.FallbackAfterFailures < 0 {
+		return errors.New("session fallbackAfterFailures must be greater than or equal to 0")
+	}
+	if c.MaxReconnects < 0 {
+		return errors.New("session maxReconnects must be greater than or equal to 0")
+	}
+	if c.MaxReconnectBackoff < 0 {
+		return errors.New("session maxReconnectBackoff must be greater than or equal to 0")
+	}
+	return nil
+}
+
+// SessionState is the health of the message session.
+type SessionState struct {
+	// Healthy is false while the listener is re-establishing the session.
+	Healthy bool `json:"healthy"`
+	// Transport is the transport in use, which becomes TransportShortPoll once TransportLongPoll falls back.
+	Transport string `json:"transport"`
+	// Reconnects is the number of consecutive reconnects so far.
+	Reconnects int `json:"reconnects,omitempty"`
+	// Message is the error that made the listener reconnect.
+	Message string `json:"message,omitempty"`
+}
+
+// SessionReporter is told the health of the message session, like the worker that records it for the controller.
+type SessionReporter interface {
+	// ReportSession is called when the health of the session changes.
+	ReportSession(ctx context.Context, state SessionState) error
+}
+
+// poll gets the next message with the current transport.
+// A short poll that times out is an empty poll, like a long poll the Actions service ends without any message.
+func (l *Listener) poll(ctx context.Context) (*actions.RunnerScaleSetMessage, error) {
+	if l.transport != TransportShortPoll {
+		return l.client.GetMessage(ctx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, l.sessionConfig.PollTimeout)
+	defer cancel()
+
+	msg, err := l.client.GetMessage(pollCtx, l.session.MessageQueueUrl, l.session.MessageQueueAccessToken, l.lastMessageID, l.maxCapacity)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, nil
+	}
+
+	return msg, err
+}
+
+// reconnect re-establishes the session after the listener failed to get messages with cause,
+// waiting for the jittered exponential backoff before each attempt.
+// It returns an error when the listener should give up.
+func (l *Listener) reconnect(ctx context.Context, handler Handler, cause error) error {
+	l.pollFailures++
+
+	if l.transport == TransportLongPoll && l.sessionConfig.FallbackAfterFailures > 0 && l.pollFailures >= l.sessionConfig.FallbackAfterFailures {
+		l.logger.Info("Falling back to short polls after consecutive failures of long polls", "failures", l.pollFailures, "pollTimeout", l.sessionConfig.PollTimeout.String())
+		l.transport = TransportShortPoll
+	}
+
+	for {
+		if l.reconnects >= l.sessionConfig.MaxReconnects {
+			return cause
+		}
+		l.reconnects++
+
+		l.metrics.PublishSessionReconnect()
+		l.reportSession(ctx, false, cause)
+
+		backoff := reconnectBackoff(l.reconnects, l.sessionConfig.MaxReconnectBackoff)
+		l.logger.Info("Re-establishing the message session", "reconnects", l.reconnects, "backoff", backoff.String(), "error", cause.Error())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		// The session may still be alive on the Actions service, which refuses to create another one for the same owner
+		if err := l.deleteMessageSession(); err != nil {
+			l.logger.Info("Failed to delete the previous message session", "error", err.Error())
+		}
+
+		if err := l.createSession(ctx); err != nil {
+			cause = err
+			continue
+		}
+
+		l.lastMessageID = 0
+		l.metrics.PublishStatistics(l.session.Statistics)
+
+		desiredRunners, err := handler.HandleDesiredRunnerCount(ctx, l.session.Statistics.TotalAssignedJobs, 0)
+		if err != nil {
+			return fmt.Errorf("handling the statistics of the re-established session failed: %w", err)
+		}
+		l.metrics.PublishDesiredRunners(desiredRunners)
+
+		return nil
+	}
+}
+
+// sessionSucceeded marks the session healthy after getting messages.
+func (l *Listener) sessionSucceeded(ctx context.Context) {
+	l.pollFailures = 0
+	l.reconnects = 0
+
+	if l.sessionState == nil || !l.sessionState.Healthy || l.sessionState.Transport != l.transport {
+		l.reportSession(ctx, true, nil)
+	}
+}
+
+func (l *Listener) reportSession(ctx context.Context, healthy bool, cause error) {
+	state := SessionState{
+		Healthy:    healthy,
+		Transport:  l.transport,
+		Reconnects: l.reconnects,
+	}
+	if cause != nil {
+		state.Message = cause.Error()
+	}
+
+	l.sessionState = &state
+	l.metrics.PublishSessionState(healthy, l.transport == TransportShortPoll)
+
+	if l.sessionReporter == nil {
+		return
+	}
+
+	if err := l.sessionReporter.ReportSession(ctx, state); err != nil {
+		l.logger.Error(err, "Failed to report the session state")
+	}
+}
+
+// reconnectBackoff returns the delay before the nth reconnect, which doubles on each reconnect up to maxBackoff,
+// with the latter half jittered so that the listeners behind the same proxy don't reconnect at once.
+func reconnectBackoff(n int, maxBackoff time.Duration) time.Duration {
+	backoff := reconnectBaseBackoff
+	for i := 1; i < n && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,181 @@
+package listener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	listenermocks "github.com/actions/actions-runner-controller/cmd/ghalistener/listener/mocks"
+	"github.com/actions/actions-runner-controller/cmd/ghalistener/metrics"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessionReporter struct {
+	states []SessionState
+}
+
+func (r *fakeSessionReporter) ReportSession(ctx context.Context, state SessionState) error {
+	r.states = append(r.states, state)
+	return nil
+}
+
+func TestSessionConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&SessionConfig{}).Validate())
+	assert.NoError(t, (&SessionConfig{Transport: TransportShortPoll, PollTimeout: time.Second}).Validate())
+	assert.Error(t, (&SessionConfig{Transport: "websocket"}).Validate())
+	assert.Error(t, (&SessionConfig{MaxReconnects: -1}).Validate())
+}
+
+func TestListener_poll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ShortPollTimeoutIsEmptyPoll", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		config := Config{
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			MaxRunners: 10,
+			Session: SessionConfig{
+				Transport:   TransportShortPoll,
+				PollTimeout: 10 * time.Millisecond,
+			},
+		}
+
+		client := listenermocks.NewClient(t)
+		client.On("GetMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, 10).
+			Run(func(args mock.Arguments) {
+				<-args.Get(0).(context.Context).Done()
+			}).
+			Return(nil, context.DeadlineExceeded).
+			Once()
+		config.Client = client
+
+		l, err := New(config)
+		require.Nil(t, err)
+		l.session = &actions.RunnerScaleSetSession{}
+
+		msg, err := l.poll(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, msg)
+	})
+
+	t.Run("LongPollKeepsContext", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		config := Config{
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			MaxRunners: 10,
+		}
+
+		client := listenermocks.NewClient(t)
+		client.On("GetMessage", ctx, mock.Anything, mock.Anything, mock.Anything, 10).Return(nil, context.DeadlineExceeded).Once()
+		config.Client = client
+
+		l, err := New(config)
+		require.Nil(t, err)
+		l.session = &actions.RunnerScaleSetSession{}
+
+		_, err = l.poll(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestListener_reconnect(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RecreatesSessionAndFallsBack", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		reporter := &fakeSessionReporter{}
+		config := Config{
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			MaxRunners: 10,
+			Session: SessionConfig{
+				FallbackAfterFailures: 1,
+				MaxReconnects:         3,
+				MaxReconnectBackoff:   time.Millisecond,
+			},
+			SessionReporter: reporter,
+		}
+
+		oldSessionID := uuid.New()
+		session := &actions.RunnerScaleSetSession{
+			SessionId:      &oldSessionID,
+			RunnerScaleSet: &actions.RunnerScaleSet{Id: 1},
+			Statistics:     &actions.RunnerScaleSetStatistic{TotalAssignedJobs: 3},
+		}
+
+		client := listenermocks.NewClient(t)
+		client.On("DeleteMessageSession", mock.Anything, 1, &oldSessionID).Return(nil).Once()
+		client.On("CreateMessageSession", ctx, 1, mock.Anything).Return(nil, assert.AnError).Once()
+		client.On("DeleteMessageSession", mock.Anything, 1, &oldSessionID).Return(nil).Once()
+		client.On("CreateMessageSession", ctx, 1, mock.Anything).Return(session, nil).Once()
+		config.Client = client
+
+		handler := listenermocks.NewHandler(t)
+		handler.On("HandleDesiredRunnerCount", ctx, 3, 0).Return(3, nil).Once()
+
+		l, err := New(config)
+		require.Nil(t, err)
+		l.session = session
+		l.lastMessageID = 5
+
+		err = l.reconnect(ctx, handler, assert.AnError)
+		require.NoError(t, err)
+
+		assert.Equal(t, TransportShortPoll, l.transport)
+		assert.Equal(t, int64(0), l.lastMessageID)
+		assert.Equal(t, 2, l.reconnects)
+		require.Len(t, reporter.states, 2)
+		assert.False(t, reporter.states[1].Healthy)
+		assert.Equal(t, TransportShortPoll, reporter.states[1].Transport)
+
+		l.sessionSucceeded(ctx)
+
+		assert.Equal(t, 0, l.reconnects)
+		require.Len(t, reporter.states, 3)
+		assert.Equal(t, SessionState{Healthy: true, Transport: TransportShortPoll}, reporter.states[2])
+
+		l.sessionSucceeded(ctx)
+		assert.Len(t, reporter.states, 3, "the unchanged state is not reported again")
+	})
+
+	t.Run("GivesUpAfterMaxReconnects", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		config := Config{
+			ScaleSetID: 1,
+			Metrics:    metrics.Discard,
+			MaxRunners: 10,
+			Client:     listenermocks.NewClient(t),
+		}
+
+		l, err := New(config)
+		require.Nil(t, err)
+
+		err = l.reconnect(ctx, listenermocks.NewHandler(t), assert.AnError)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, TransportLongPoll, l.transport)
+	})
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	for n, want := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 3: 8 * time.Second, 10: time.Minute} {
+		for i := 0; i < 10; i++ {
+			got := reconnectBackoff(n, time.Minute)
+			assert.GreaterOrEqual(t, got, want/2)
+			assert.LessOrEqual(t, got, want)
+		}
+	}
+}
@@ -33,6 +33,13 @@ const (
 // Names of all metrics available on the listener
 const (
 	MetricAssignedJobs                = "gha_assigned_jobs"
+	MetricAvailableJobs               = "gha_available_jobs"
+	MetricAcquiredJobs                = "gha_acquired_jobs"
+	MetricAssignedUnstartedJobs       = "gha_assigned_unstarted_jobs"
+	MetricSessionLagSeconds           = "gha_session_lag_seconds"
+	MetricSessionHealthy              = "gha_session_healthy"
+	MetricSessionShortPolling         = "gha_session_short_polling"
+	MetricSessionReconnectsTotal      = "gha_session_reconnects_total"
 	MetricRunningJobs                 = "gha_running_jobs"
 	MetricRegisteredRunners           = "gha_registered_runners"
 	MetricBusyRunners                 = "gha_busy_runners"
@@ -54,18 +61,25 @@ type metricsHelpRegistry struct {
 
 var metricsHelp = metricsHelpRegistry{
 	counters: map[string]string{
-		MetricStartedJobsTotal:   "Total number of jobs started.",
-		MetricCompletedJobsTotal: "Total number of jobs completed.",
+		MetricStartedJobsTotal:       "Total number of jobs started.",
+		MetricCompletedJobsTotal:     "Total number of jobs completed.",
+		MetricSessionReconnectsTotal: "Total number of times the listener re-established the message session after failing to get messages.",
 	},
 	gauges: map[string]string{
-		MetricAssignedJobs:      "Number of jobs assigned to this scale set.",
-		MetricRunningJobs:       "Number of jobs running (or about to be run).",
-		MetricRegisteredRunners: "Number of runners registered by the scale set.",
-		MetricBusyRunners:       "Number of registered runners running a job.",
-		MetricMinRunners:        "Minimum number of runners.",
-		MetricMaxRunners:        "Maximum number of runners.",
-		MetricDesiredRunners:    "Number of runners desired by the scale set.",
-		MetricIdleRunners:       "Number of registered runners not running a job.",
+		MetricAssignedJobs:          "Number of jobs assigned to this scale set.",
+		MetricAvailableJobs:         "Number of jobs available to this scale set and not acquired yet.",
+		MetricAcquiredJobs:          "Number of jobs acquired by this scale set.",
+		MetricAssignedUnstartedJobs: "Number of jobs assigned to this scale set and not started on a runner yet.",
+		MetricSessionLagSeconds:     "Time the oldest job available in the last message waited for the listener to handle it (in seconds).",
+		MetricSessionHealthy:        "1 when the listener gets messages from the message session, 0 while it's re-establishing the session.",
+		MetricSessionShortPolling:   "1 when the listener polls messages with short polls, including after falling back from long polls.",
+		MetricRunningJobs:           "Number of jobs running (or about to be run).",
+		MetricRegisteredRunners:     "Number of runners registered by the scale set.",
+		MetricBusyRunners:           "Number of registered runners running a job.",
+		MetricMinRunners:            "Minimum number of runners.",
+		MetricMaxRunners:            "Maximum number of runners.",
+		MetricDesiredRunners:        "Number of runners desired by the scale set.",
+		MetricIdleRunners:           "Number of registered runners not running a job.",
 	},
 	histograms: map[string]string{
 		MetricJobStartupDurationSeconds:   "Time spent waiting for workflow job to get started on the runner owned by the scale set (in seconds).",
@@ -100,6 +114,9 @@ type Publisher interface {
 	PublishJobStarted(msg *actions.JobStarted)
 	PublishJobCompleted(msg *actions.JobCompleted)
 	PublishDesiredRunners(count int)
+	PublishSessionLag(lag time.Duration)
+	PublishSessionState(healthy, shortPolling bool)
+	PublishSessionReconnect()
 }
 
 //go:generate mockery --name ServerPublisher --output ./mocks --outpkg mocks --case underscore
@@ -333,6 +350,9 @@ func (e *exporter) PublishStatistics(stats *actions.RunnerScaleSetStatistic) {
 	e.setGauge(MetricRegisteredRunners, e.scaleSetLabels, float64(stats.TotalRegisteredRunners))
 	e.setGauge(MetricBusyRunners, e.scaleSetLabels, float64(float64(stats.TotalBusyRunners)))
 	e.setGauge(MetricIdleRunners, e.scaleSetLabels, float64(stats.TotalIdleRunners))
+	e.setGauge(MetricAvailableJobs, e.scaleSetLabels, float64(stats.TotalAvailableJobs))
+	e.setGauge(MetricAcquiredJobs, e.scaleSetLabels, float64(stats.TotalAcquiredJobs))
+	e.setGauge(MetricAssignedUnstartedJobs, e.scaleSetLabels, float64(max(stats.TotalAssignedJobs-stats.TotalRunningJobs, 0)))
 }
 
 func (e *exporter) PublishJobStarted(msg *actions.JobStarted) {
@@ -355,6 +375,26 @@ func (e *exporter) PublishDesiredRunners(count int) {
 	e.setGauge(MetricDesiredRunners, e.scaleSetLabels, float64(count))
 }
 
+func (e *exporter) PublishSessionLag(lag time.Duration) {
+	e.setGauge(MetricSessionLagSeconds, e.scaleSetLabels, lag.Seconds())
+}
+
+func (e *exporter) PublishSessionState(healthy, shortPolling bool) {
+	e.setGauge(MetricSessionHealthy, e.scaleSetLabels, boolToFloat(healthy))
+	e.setGauge(MetricSessionShortPolling, e.scaleSetLabels, boolToFloat(shortPolling))
+}
+
+func (e *exporter) PublishSessionReconnect() {
+	e.incCounter(MetricSessionReconnectsTotal, e.scaleSetLabels)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 type discard struct{}
 
 func (*discard) PublishStatic(int, int)                             {}
@@ -362,6 +402,9 @@ func (*discard) PublishStatistics(*actions.RunnerScaleSetStatistic) {}
 func (*discard) PublishJobStarted(*actions.JobStarted)              {}
 func (*discard) PublishJobCompleted(*actions.JobCompleted)          {}
 func (*discard) PublishDesiredRunners(int)                          {}
+func (*discard) PublishSessionLag(time.Duration)                    {}
+func (*discard) PublishSessionState(bool, bool)                     {}
+func (*discard) PublishSessionReconnect()                           {}
 
 var defaultRuntimeBuckets []float64 = []float64{
 	0.01,
@@ -2,10 +2,13 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -86,3 +89,44 @@ func TestInstallMetrics(t *testing.T) {
 	assert.Equal(t, duration.config.Labels, metricsConfig.Histograms[MetricJobStartupDurationSeconds].Labels)
 	assert.Equal(t, duration.config.Buckets, defaultRuntimeBuckets)
 }
+
+func TestPublishStatisticsBackpressure(t *testing.T) {
+	labels := []string{labelKeyRunnerScaleSetName}
+	metricsConfig := v1alpha1.MetricsConfig{
+		Gauges: map[string]*v1alpha1.GaugeMetric{
+			MetricAvailableJobs:         {Labels: labels},
+			MetricAcquiredJobs:          {Labels: labels},
+			MetricAssignedUnstartedJobs: {Labels: labels},
+			MetricSessionLagSeconds:     {Labels: labels},
+		},
+	}
+
+	e := &exporter{
+		scaleSetLabels: prometheus.Labels{labelKeyRunnerScaleSetName: "test"},
+		metrics:        installMetrics(metricsConfig, prometheus.NewRegistry(), logr.Discard()),
+	}
+
+	e.PublishStatistics(&actions.RunnerScaleSetStatistic{
+		TotalAvailableJobs: 1,
+		TotalAcquiredJobs:  2,
+		TotalAssignedJobs:  5,
+		TotalRunningJobs:   3,
+	})
+	e.PublishSessionLag(1500 * time.Millisecond)
+
+	gauge := func(name string) float64 {
+		return testutil.ToFloat64(e.metrics.gauges[name].gauge.With(prometheus.Labels{labelKeyRunnerScaleSetName: "test"}))
+	}
+
+	assert.Equal(t, 1.0, gauge(MetricAvailableJobs))
+	assert.Equal(t, 2.0, gauge(MetricAcquiredJobs))
+	assert.Equal(t, 2.0, gauge(MetricAssignedUnstartedJobs))
+	assert.Equal(t, 1.5, gauge(MetricSessionLagSeconds))
+
+	// running jobs reported ahead of the assigned ones don't make the gauge negative
+	e.PublishStatistics(&actions.RunnerScaleSetStatistic{
+		TotalAssignedJobs: 1,
+		TotalRunningJobs:  2,
+	})
+	assert.Equal(t, 0.0, gauge(MetricAssignedUnstartedJobs))
+}
@@ -6,6 +6,8 @@ import (
 	actions "github.com/actions/actions-runner-controller/github/actions"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Publisher is an autogenerated mock type for the Publisher type
@@ -28,6 +30,21 @@ func (_m *Publisher) PublishJobStarted(msg *actions.JobStarted) {
 	_m.Called(msg)
 }
 
+// PublishSessionLag provides a mock function with given fields: lag
+func (_m *Publisher) PublishSessionLag(lag time.Duration) {
+	_m.Called(lag)
+}
+
+// PublishSessionReconnect provides a mock function with given fields:
+func (_m *Publisher) PublishSessionReconnect() {
+	_m.Called()
+}
+
+// PublishSessionState provides a mock function with given fields: healthy, shortPolling
+func (_m *Publisher) PublishSessionState(healthy bool, shortPolling bool) {
+	_m.Called(healthy, shortPolling)
+}
+
 // PublishStatic provides a mock function with given fields: min, max
 func (_m *Publisher) PublishStatic(min int, max int) {
 	_m.Called(min, max)
@@ -8,6 +8,8 @@ import (
 	actions "github.com/actions/actions-runner-controller/github/actions"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // ServerPublisher is an autogenerated mock type for the ServerPublisher type
@@ -44,6 +46,21 @@ func (_m *ServerPublisher) PublishJobStarted(msg *actions.JobStarted) {
 	_m.Called(msg)
 }
 
+// PublishSessionLag provides a mock function with given fields: lag
+func (_m *ServerPublisher) PublishSessionLag(lag time.Duration) {
+	_m.Called(lag)
+}
+
+// PublishSessionReconnect provides a mock function with given fields:
+func (_m *ServerPublisher) PublishSessionReconnect() {
+	_m.Called()
+}
+
+// PublishSessionState provides a mock function with given fields: healthy, shortPolling
+func (_m *ServerPublisher) PublishSessionState(healthy bool, shortPolling bool) {
+	_m.Called(healthy, shortPolling)
+}
+
 // PublishStatic provides a mock function with given fields: min, max
 func (_m *ServerPublisher) PublishStatic(min int, max int) {
 	_m.Called(min, max)
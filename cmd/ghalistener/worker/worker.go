@@ -255,3 +255,43 @@ func (w *Worker) setDesiredWorkerState(count, jobsCompleted int) int {
 
 	return desiredPatchID
 }
+
+// annotationKeyListenerSession is the annotation of the ephemeral runner set the session state is recorded in,
+// which must match the one the AutoscalingRunnerSet controller reads the ListenerSessionHealthy condition from.
+const annotationKeyListenerSession = "actions.github.com/listener-session"
+
+// ReportSession records the health of the message session of the listener onto the ephemeral runner set,
+// so that the controller can surface it in the conditions of the AutoscalingRunnerSet.
+func (w *Worker) ReportSession(ctx context.Context, state listener.SessionState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session state: %w", err)
+	}
+
+	mergePatch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				annotationKeyListenerSession: string(value),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session state patch: %w", err)
+	}
+
+	err = w.clientset.RESTClient().
+		Patch(types.MergePatchType).
+		Prefix("apis", v1alpha1.GroupVersion.Group, v1alpha1.GroupVersion.Version).
+		Namespace(w.config.EphemeralRunnerSetNamespace).
+		Resource("ephemeralrunnersets").
+		Name(w.config.EphemeralRunnerSetName).
+		Body(mergePatch).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("could not patch ephemeral runner set with session state, patch JSON: %s, error: %w", string(mergePatch), err)
+	}
+
+	w.logger.Info("Reported session state", "healthy", state.Healthy, "transport", state.Transport, "reconnects", state.Reconnects)
+	return nil
+}
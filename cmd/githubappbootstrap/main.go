@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/githubappbootstrap"
+	gogithub "github.com/google/go-github/v52/github"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+}
+
+func main() {
+	var (
+		err error
+
+		addr      string
+		logLevel  string
+		logFormat string
+
+		b githubappbootstrap.Bootstrapper
+	)
+
+	secretNamespace := os.Getenv("POD_NAMESPACE")
+	if secretNamespace == "" {
+		secretNamespace = "actions-runner-system"
+	}
+
+	flag.StringVar(&addr, "addr", ":8080", "The address the bootstrap server binds to.")
+	flag.StringVar(&b.PublicURL, "public-url", "http://localhost:8080", "The URL at which the browser reaches the bootstrap server, like the one forwarded by kubectl port-forward. GitHub redirects the browser back to this URL.")
+	flag.StringVar(&b.GitHubURL, "github-url", githubappbootstrap.DefaultGitHubURL, "The URL of GitHub or GitHub Enterprise Server")
+	flag.StringVar(&b.Organization, "organization", "", "The organization that owns the GitHub App. Set to empty for creating the app on your user account.")
+	flag.StringVar(&b.AppName, "app-name", "", "The name of the GitHub App, which must be unique on GitHub. You can change it on GitHub before creating the app.")
+	flag.StringVar(&b.WebhookURL, "webhook-url", "", "The URL of the github-webhook-server for webhook driven scaling. The GitHub App is created without a webhook when empty.")
+	flag.StringVar(&b.SecretNamespace, "secret-namespace", secretNamespace, "The namespace of the secret to store the GitHub App credentials into. Defaults to the value of POD_NAMESPACE or actions-runner-system.")
+	flag.StringVar(&b.SecretName, "secret-name", "controller-manager", "The name of the secret to store the GitHub App credentials into. The secret is created when missing.")
+	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
+	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+	flag.Parse()
+
+	logger, err := logging.NewLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: creating logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctrl.SetLogger(logger)
+
+	b.Log = logger.WithName("githubappbootstrap")
+
+	if strings.TrimSuffix(b.GitHubURL, "/") == githubappbootstrap.DefaultGitHubURL {
+		b.GitHubClient = gogithub.NewClient(nil)
+	} else {
+		apiURL := strings.TrimSuffix(b.GitHubURL, "/") + "/api/v3/"
+
+		b.GitHubClient, err = gogithub.NewEnterpriseClient(apiURL, apiURL, nil)
+		if err != nil {
+			logger.Error(err, "unable to create GitHub Enterprise Server client", "url", b.GitHubURL)
+			os.Exit(1)
+		}
+	}
+
+	b.Client, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		logger.Error(err, "unable to create kubernetes client")
+		os.Exit(1)
+	}
+
+	srv := http.Server{
+		Addr:    addr,
+		Handler: b.Handler(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-b.Done():
+			logger.Info("GitHub App is created and installed. Restart the controller to use the credentials", "secret", b.SecretNamespace+"/"+b.SecretName)
+		case <-ctrl.SetupSignalHandler().Done():
+		}
+
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Error(err, "problem shutting down http server")
+		}
+	}()
+
+	logger.Info(fmt.Sprintf("Open %s in your browser to create a GitHub App for actions-runner-controller", b.PublicURL))
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "problem running http server")
+		os.Exit(1)
+	}
+}
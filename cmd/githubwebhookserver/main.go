@@ -18,11 +18,14 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,16 +33,24 @@ import (
 	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
 	"github.com/actions/actions-runner-controller/github"
 	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/actionsmetrics"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+	"github.com/actions/actions-runner-controller/pkg/webhookingest"
 
+	gogithub "github.com/google/go-github/v52/github"
 	"github.com/kelseyhightower/envconfig"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
@@ -50,7 +61,11 @@ var (
 )
 
 const (
-	webhookSecretTokenEnvName = "GITHUB_WEBHOOK_SECRET_TOKEN"
+	webhookSecretTokenEnvName   = "GITHUB_WEBHOOK_SECRET_TOKEN"
+	webhookSecretTokensEnvName  = "GITHUB_WEBHOOK_SECRET_TOKENS"
+	debugDeliveriesTokenEnvName = "DEBUG_DELIVERIES_TOKEN"
+	ingestPasswordEnvName       = "INGEST_PASSWORD"
+	ingestTokenEnvName          = "INGEST_TOKEN"
 )
 
 func init() {
@@ -71,11 +86,65 @@ func main() {
 		webhookSecretToken    string
 		webhookSecretTokenEnv string
 
+		// The additional secret tokens accepted while rotating the webhook secret
+		webhookSecretTokens    string
+		webhookSecretTokensEnv string
+
 		watchNamespace string
 
-		logLevel   string
-		queueLimit int
-		logFormat  string
+		logLevel      string
+		queueLimit    int
+		logFormat     string
+		routingPolicy string
+
+		forkPullRequestPolicy string
+
+		redeliverer actionssummerwindnet.WebhookDeliveryRedeliverer
+
+		deduplicationStore string
+		deliveryStore      actionssummerwindnet.LeaseWebhookDeliveryStore
+
+		deliveryRecorder actionssummerwindnet.WebhookDeliveryRecorder
+
+		fanoutConfig string
+
+		ingestSource        string
+		ingestURL           string
+		ingestTopic         string
+		ingestStream        string
+		ingestConsumer      string
+		ingestUsername      string
+		ingestPassword      string
+		ingestToken         string
+		ingestSASLMechanism string
+		ingestTLS           bool
+		ingestBatchSize     int
+		ingestPollTimeout   time.Duration
+		ingester            webhookingest.Ingester
+
+		utilizationReport  bool
+		utilizationTracker actionssummerwindnet.UtilizationTracker
+
+		cloudEventsSinkURL string
+		cloudEventsSource  string
+		cloudEventsMode    string
+
+		policyHookURL      string
+		policyHookCELRules string
+		policyHookFailOpen bool
+
+		sourceVerifier       actionssummerwindnet.WebhookSourceVerifier
+		verifySourceWithMeta bool
+		sourceCIDRs          string
+		trustedProxies       string
+
+		tlsCertFile  string
+		tlsKeyFile   string
+		clientCAFile string
+
+		shardName            string
+		shardConfigMap       string
+		shardRefreshInterval time.Duration
 
 		ghClient *github.Client
 	)
@@ -88,13 +157,17 @@ func main() {
 	}
 
 	webhookSecretTokenEnv = os.Getenv(webhookSecretTokenEnvName)
+	webhookSecretTokensEnv = os.Getenv(webhookSecretTokensEnvName)
 
 	flag.StringVar(&webhookAddr, "webhook-addr", ":8000", "The address the metric endpoint binds to.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&watchNamespace, "watch-namespace", "", "The namespace to watch for HorizontalRunnerAutoscaler's to scale on Webhook. Set to empty for letting it watch for all namespaces.")
 	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
 	flag.IntVar(&queueLimit, "queue-limit", actionssummerwindnet.DefaultQueueLimit, `The maximum length of the scale operation queue. The scale opration is enqueued per every matching webhook event, and the server returns a 500 HTTP status when the queue was already full on enqueue attempt.`)
+	flag.StringVar(&routingPolicy, "routing-policy", actionssummerwindnet.RoutingPolicyFailover, `How a webhook event is routed when multiple HorizontalRunnerAutoscalers of the same priority match the event. Valid values are "failover" and "spread". "failover" routes it to the first one in the order of the names that is not at maxReplicas, and "spread" routes it to the one with the fewest replicas.`)
+	flag.StringVar(&forkPullRequestPolicy, "fork-pull-request-policy", actionssummerwindnet.ForkPullRequestPolicyAllow, `How the jobs of pull requests from forks of public repositories are routed. Valid values are "allow", "sandbox", and "refuse". "allow" routes them like any other job, "sandbox" routes them only to the HorizontalRunnerAutoscalers with "sandbox: true", and "refuse" never scales up for them. "sandbox" and "refuse" require GitHub authentication to look up the workflow runs.`)
 	flag.StringVar(&webhookSecretToken, "github-webhook-secret-token", "", "The personal access token of GitHub.")
+	flag.StringVar(&webhookSecretTokens, "github-webhook-secret-tokens", "", "Comma or newline separated webhook secret tokens accepted in addition to -github-webhook-secret-token. Used to rotate the webhook secret without downtime.")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
 	flag.Int64Var(&c.AppInstallationID, "github-app-installation-id", c.AppInstallationID, "The installation ID of GitHub App.")
@@ -105,7 +178,48 @@ func main() {
 	flag.StringVar(&c.BasicauthPassword, "github-basicauth-password", c.BasicauthPassword, "Password for GitHub basic auth to use instead of PAT or GitHub APP in case it's running behind a proxy API")
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
 	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
-
+	flag.Int64Var(&redeliverer.HookID, "redelivery-hook-id", 0, "The ID of the GitHub webhook whose failed deliveries are redelivered. Requires GitHub API credentials that can read and redeliver the webhook's deliveries. Set to 0 for disabling redelivery.")
+	flag.StringVar(&redeliverer.Organization, "redelivery-hook-organization", "", "The organization that owns the webhook specified by -redelivery-hook-id")
+	flag.StringVar(&redeliverer.Repository, "redelivery-hook-repository", "", "The repository, in the OWNER/REPO format, that owns the webhook specified by -redelivery-hook-id. Takes precedence over -redelivery-hook-organization")
+	flag.DurationVar(&redeliverer.Interval, "redelivery-interval", actionssummerwindnet.DefaultWebhookRedeliveryInterval, "The interval between the checks for failed webhook deliveries")
+	flag.DurationVar(&redeliverer.MaxAge, "redelivery-max-age", actionssummerwindnet.DefaultWebhookRedeliveryMaxAge, "How old a failed webhook delivery can be to be redelivered")
+
+	flag.StringVar(&deduplicationStore, "deduplication-store", "", `The store used to deduplicate webhook deliveries across the replicas of the webhook server. Valid values are "" and "lease". Set to "lease" when running more than one replica.`)
+	flag.StringVar(&deliveryStore.Namespace, "deduplication-namespace", os.Getenv("POD_NAMESPACE"), "The namespace in which the leases for deduplicating webhook deliveries are created. Defaults to the value of POD_NAMESPACE")
+	flag.DurationVar(&deliveryStore.TTL, "deduplication-ttl", actionssummerwindnet.DefaultWebhookDeliveryDeduplicationTTL, "How long a webhook delivery is remembered for deduplication")
+	flag.IntVar(&deliveryRecorder.Size, "record-deliveries", 0, "The number of the last webhook deliveries recorded for troubleshooting. The recorded deliveries are served on /debug/deliveries with their payloads redacted. Requires -debug-deliveries-token. Set to 0 for disabling the recording.")
+	flag.StringVar(&deliveryRecorder.Token, "debug-deliveries-token", os.Getenv(debugDeliveriesTokenEnvName), fmt.Sprintf("The bearer token required to read /debug/deliveries. Defaults to the value of %s", debugDeliveriesTokenEnvName))
+	flag.StringVar(&fanoutConfig, "fanout-config", "", "The path of the YAML file of the consumers the received webhook events are fanned out to, like the workflow job metrics and HTTP notification sinks, with their event filters. Set to empty for disabling the fan-out.")
+	flag.StringVar(&ingestSource, "ingest-source", "", `The message broker to consume the webhook deliveries from in addition to receiving them over HTTP. Either "kafka" for a Kafka topic, or "nats" for a NATS JetStream pull consumer. Requires -deduplication-store, which skips the deliveries the broker redelivers. Set to empty for disabling the ingestion.`)
+	flag.StringVar(&ingestURL, "ingest-url", "", "The comma-separated Kafka brokers like kafka-0.kafka:9092,kafka-1.kafka:9092, or the NATS server like nats://nats:4222 or tls://nats:4222")
+	flag.StringVar(&ingestTopic, "ingest-topic", "", "The Kafka topic of the webhook deliveries")
+	flag.StringVar(&ingestStream, "ingest-stream", "", "The NATS JetStream stream of the webhook deliveries")
+	flag.StringVar(&ingestConsumer, "ingest-consumer", "github-webhook-server", "The Kafka consumer group, or the durable NATS JetStream pull consumer, shared by the replicas of the webhook server")
+	flag.StringVar(&ingestUsername, "ingest-username", "", "The username to authenticate to the message broker with")
+	flag.StringVar(&ingestPassword, "ingest-password", os.Getenv(ingestPasswordEnvName), fmt.Sprintf("The password of -ingest-username. Defaults to the value of %s", ingestPasswordEnvName))
+	flag.StringVar(&ingestToken, "ingest-token", os.Getenv(ingestTokenEnvName), fmt.Sprintf("The token to authenticate to the NATS server with. Defaults to the value of %s", ingestTokenEnvName))
+	flag.StringVar(&ingestSASLMechanism, "ingest-sasl-mechanism", webhookingest.KafkaSASLPlain, fmt.Sprintf("The SASL mechanism to authenticate to the Kafka brokers with -ingest-username. Either %q, %q, or %q", webhookingest.KafkaSASLPlain, webhookingest.KafkaSASLSCRAMSHA256, webhookingest.KafkaSASLSCRAMSHA512))
+	flag.BoolVar(&ingestTLS, "ingest-tls", false, "Connect to the message broker with TLS. The NATS server is also connected with TLS for a tls:// URL, or when the server requires it")
+	flag.IntVar(&ingestBatchSize, "ingest-batch-size", webhookingest.DefaultNATSBatchSize, "The number of the messages fetched from the NATS JetStream consumer at once")
+	flag.DurationVar(&ingestPollTimeout, "ingest-poll-timeout", webhookingest.DefaultKafkaPollTimeout, "How long a fetch from the message broker waits for the messages")
+	flag.BoolVar(&utilizationReport, "utilization-report", false, "Serves the utilization of the runners of each HorizontalRunnerAutoscaler over the last 1h, 24h, and 7d on /utilization of the metrics server, aggregated from the workflow job events this replica received.")
+	flag.DurationVar(&utilizationTracker.SampleInterval, "utilization-sample-interval", actionssummerwindnet.DefaultUtilizationSampleInterval, "The interval between the samples of the desired replicas of HorizontalRunnerAutoscalers for -utilization-report")
+	flag.BoolVar(&verifySourceWithMeta, "verify-webhook-source", false, "Reject the webhook deliveries from outside of the hooks IP ranges published on the /meta endpoint of GitHub or GitHub Enterprise Server. The ranges are refreshed every -webhook-source-refresh-interval.")
+	flag.StringVar(&sourceCIDRs, "webhook-source-cidrs", "", "Comma-separated CIDRs the webhook deliveries are accepted from, in addition to the ranges of -verify-webhook-source. Setting this alone rejects the deliveries from anywhere else.")
+	flag.StringVar(&trustedProxies, "webhook-trusted-proxies", "", "Comma-separated CIDRs of the load balancers and the ingress controllers in front of the webhook server, whose X-Forwarded-For header tells the source of the webhook deliveries.")
+	flag.DurationVar(&sourceVerifier.RefreshInterval, "webhook-source-refresh-interval", actionssummerwindnet.DefaultWebhookSourceRefreshInterval, "The interval between the fetches of the hooks IP ranges from the /meta endpoint")
+	flag.StringVar(&tlsCertFile, "webhook-tls-cert-file", "", "The path of the TLS certificate the webhook server serves with. The webhook server serves plain HTTP when omitted.")
+	flag.StringVar(&tlsKeyFile, "webhook-tls-key-file", "", "The path of the private key of -webhook-tls-cert-file")
+	flag.StringVar(&clientCAFile, "webhook-client-ca-file", "", "The path of the CA certificates that the client certificates of the webhook deliveries must be signed by, like the one GitHub Enterprise Server presents via a reverse proxy. Requires -webhook-tls-cert-file.")
+	flag.StringVar(&shardName, "shard-name", "", "The shard key of the webhook server. The deliveries for the organizations claimed by the other shards in -shard-config-map are forwarded to the webhookURL of the shards. Set to empty for disabling sharding.")
+	flag.StringVar(&shardConfigMap, "shard-config-map", "", "The coordination ConfigMap in the NAMESPACE/NAME format that lists the shards. Required by -shard-name.")
+	flag.DurationVar(&shardRefreshInterval, "shard-refresh-interval", sharding.DefaultRefreshInterval, "The interval to re-read -shard-config-map. The webhook server restarts when the shards change.")
+	flag.StringVar(&cloudEventsSinkURL, "cloudevents-sink-url", "", "The URL to POST the workflow jobs assigned to the runners to as CloudEvents, like a Knative broker. Set to empty for disabling the CloudEvents.")
+	flag.StringVar(&cloudEventsSource, "cloudevents-source", cloudevents.DefaultSource, "The source of the CloudEvents, like the name of the cluster, which tells the events of the installations apart.")
+	flag.StringVar(&cloudEventsMode, "cloudevents-mode", cloudevents.ModeStructured, `The content mode of the CloudEvents, either "structured" for the whole events as the JSON body, or "binary" for the data as the body and the attributes as the ce- headers.`)
+	flag.StringVar(&policyHookURL, "policy-hook-url", "", `The URL of the policy endpoint, like the Data API of Open Policy Agent, asked whether the HorizontalRunnerAutoscalers may scale up for the queued workflow jobs. Set to empty for not asking the endpoint.`)
+	flag.StringVar(&policyHookCELRules, "policy-hook-cel-rules", "", "The path to the YAML file of the CEL rules that must all hold for the HorizontalRunnerAutoscalers to scale up for the queued workflow jobs. Set to empty for not evaluating the rules.")
+	flag.BoolVar(&policyHookFailOpen, "policy-hook-fail-open", false, "Allow the scale ups when the policy can't be evaluated, like when the policy endpoint is down. They are refused otherwise.")
 	flag.Parse()
 
 	logger, err := logging.NewLogger(logLevel, logFormat)
@@ -120,10 +234,33 @@ func main() {
 		webhookSecretToken = webhookSecretTokenEnv
 	}
 
-	if webhookSecretToken == "" {
+	if webhookSecretTokens == "" && webhookSecretTokensEnv != "" {
+		logger.Info(fmt.Sprintf("Using the value from %s for -github-webhook-secret-tokens", webhookSecretTokensEnvName))
+		webhookSecretTokens = webhookSecretTokensEnv
+	}
+
+	var additionalSecretKeys [][]byte
+
+	for _, t := range github.ParseWebhookSecretTokens(webhookSecretTokens) {
+		additionalSecretKeys = append(additionalSecretKeys, []byte(t))
+	}
+
+	if webhookSecretToken == "" && len(additionalSecretKeys) == 0 {
 		logger.Info(fmt.Sprintf("-github-webhook-secret-token and %s are missing or empty. Create one following https://docs.github.com/en/developers/webhooks-and-events/securing-your-webhooks and specify it via the flag or the envvar", webhookSecretTokenEnvName))
 	}
 
+	if routingPolicy != actionssummerwindnet.RoutingPolicyFailover && routingPolicy != actionssummerwindnet.RoutingPolicySpread {
+		logger.Error(fmt.Errorf("unsupported routing policy %q", routingPolicy), "-routing-policy must be either \"failover\" or \"spread\"")
+		os.Exit(1)
+	}
+
+	switch forkPullRequestPolicy {
+	case actionssummerwindnet.ForkPullRequestPolicyAllow, actionssummerwindnet.ForkPullRequestPolicySandbox, actionssummerwindnet.ForkPullRequestPolicyRefuse:
+	default:
+		logger.Error(fmt.Errorf("unsupported fork pull request policy %q", forkPullRequestPolicy), "-fork-pull-request-policy must be either \"allow\", \"sandbox\", or \"refuse\"")
+		os.Exit(1)
+	}
+
 	if watchNamespace == "" {
 		logger.Info("-watch-namespace is empty. HorizontalRunnerAutoscalers in all the namespaces are watched, cached, and considered as scale targets.")
 	} else {
@@ -148,6 +285,16 @@ func main() {
 		}
 	} else {
 		logger.Info("GitHub client is not initialized. Runner groups with custom visibility are not supported. If needed, please provide GitHub authentication. This will incur in extra GitHub API calls")
+
+		if forkPullRequestPolicy != actionssummerwindnet.ForkPullRequestPolicyAllow {
+			logger.Error(errors.New("GitHub client is not initialized"), fmt.Sprintf("-fork-pull-request-policy=%s requires GitHub authentication to look up the workflow runs", forkPullRequestPolicy))
+			os.Exit(1)
+		}
+	}
+
+	var metricsExtraHandlers map[string]http.Handler
+	if utilizationReport {
+		metricsExtraHandlers = map[string]http.Handler{"/utilization": &utilizationTracker}
 	}
 
 	syncPeriod := 10 * time.Minute
@@ -160,7 +307,8 @@ func main() {
 			},
 		},
 		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
+			BindAddress:   metricsAddr,
+			ExtraHandlers: metricsExtraHandlers,
 		},
 		WebhookServer: webhook.NewServer(webhook.Options{
 			Port: 9443,
@@ -172,15 +320,260 @@ func main() {
 	}
 
 	hraGitHubWebhook := &actionssummerwindnet.HorizontalRunnerAutoscalerGitHubWebhook{
-		Name:           "webhookbasedautoscaler",
-		Client:         mgr.GetClient(),
-		Log:            ctrl.Log.WithName("controllers").WithName("webhookbasedautoscaler"),
-		Recorder:       nil,
-		Scheme:         mgr.GetScheme(),
-		SecretKeyBytes: []byte(webhookSecretToken),
-		Namespace:      watchNamespace,
-		GitHubClient:   ghClient,
-		QueueLimit:     queueLimit,
+		Name:                 "webhookbasedautoscaler",
+		Client:               mgr.GetClient(),
+		Log:                  ctrl.Log.WithName("controllers").WithName("webhookbasedautoscaler"),
+		Recorder:             nil,
+		Scheme:               mgr.GetScheme(),
+		SecretKeyBytes:       []byte(webhookSecretToken),
+		AdditionalSecretKeys: additionalSecretKeys,
+		Namespace:            watchNamespace,
+		GitHubClient:         ghClient,
+		QueueLimit:           queueLimit,
+		RoutingPolicy:        routingPolicy,
+
+		ForkPullRequestPolicy: forkPullRequestPolicy,
+	}
+
+	if deliveryRecorder.Size > 0 {
+		if deliveryRecorder.Token == "" {
+			logger.Error(errors.New("token is not specified"), fmt.Sprintf("-record-deliveries requires -debug-deliveries-token or %s", debugDeliveriesTokenEnvName))
+			os.Exit(1)
+		}
+
+		deliveryRecorder.Log = ctrl.Log.WithName("webhookdeliveryrecorder")
+
+		hraGitHubWebhook.DeliveryRecorder = &deliveryRecorder
+	}
+
+	if fanoutConfig != "" {
+		config, err := actionssummerwindnet.LoadWebhookFanoutConfig(fanoutConfig)
+		if err != nil {
+			logger.Error(err, "unable to load webhook fan-out config")
+			os.Exit(1)
+		}
+
+		eventBus := &actionssummerwindnet.WebhookEventBus{
+			Log: ctrl.Log.WithName("webhookeventbus"),
+		}
+
+		for _, c := range config.Consumers {
+			consumer := actionssummerwindnet.WebhookEventConsumer{
+				Name:       c.Name,
+				Filter:     c.WebhookEventFilter,
+				QueueLimit: c.QueueLimit,
+			}
+
+			switch c.Type {
+			case actionssummerwindnet.WebhookFanoutConsumerAutoscaler:
+				filter := c.WebhookEventFilter
+				hraGitHubWebhook.EventFilter = &filter
+				continue
+			case actionssummerwindnet.WebhookFanoutConsumerMetrics:
+				eventReader := &actionsmetrics.EventReader{
+					Log:            ctrl.Log.WithName("workflowjobmetrics-eventreader"),
+					GitHubClient:   ghClient,
+					Events:         make(chan interface{}, 1024*1024),
+					InProgressJobs: make(map[int64]actionsmetrics.InProgressJob),
+				}
+
+				if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+					eventReader.ProcessWorkflowJobEvents(ctx)
+					return nil
+				})); err != nil {
+					logger.Error(err, "unable to add workflow job metrics event reader")
+					os.Exit(1)
+				}
+
+				consumer.Handle = func(_ context.Context, e actionssummerwindnet.WebhookEvent) error {
+					eventReader.HandleWorkflowJobEvent(e.Event)
+					return nil
+				}
+			case actionssummerwindnet.WebhookFanoutConsumerHTTP:
+				sink, err := c.NewWebhookEventHTTPSink()
+				if err != nil {
+					logger.Error(err, "unable to create webhook event sink")
+					os.Exit(1)
+				}
+
+				consumer.Handle = sink.Handle
+			}
+
+			eventBus.Register(consumer)
+		}
+
+		if err = mgr.Add(eventBus); err != nil {
+			logger.Error(err, "unable to add webhook event bus")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.EventBus = eventBus
+	}
+
+	switch ingestSource {
+	case "":
+	case "kafka":
+		if ingestURL == "" || ingestTopic == "" {
+			logger.Error(errors.New("url or topic is not specified"), "-ingest-source=kafka requires -ingest-url and -ingest-topic")
+			os.Exit(1)
+		}
+
+		source := &webhookingest.KafkaSource{
+			Brokers:       strings.Split(ingestURL, ","),
+			Topic:         ingestTopic,
+			Group:         ingestConsumer,
+			Username:      ingestUsername,
+			Password:      ingestPassword,
+			SASLMechanism: ingestSASLMechanism,
+			PollTimeout:   ingestPollTimeout,
+		}
+
+		if ingestTLS {
+			source.TLSConfig = &tls.Config{}
+		}
+
+		ingester.Source = source
+	case "nats":
+		if ingestURL == "" || ingestStream == "" {
+			logger.Error(errors.New("url or stream is not specified"), "-ingest-source=nats requires -ingest-url and -ingest-stream")
+			os.Exit(1)
+		}
+
+		source := &webhookingest.NATSSource{
+			URL:         ingestURL,
+			Stream:      ingestStream,
+			Consumer:    ingestConsumer,
+			Username:    ingestUsername,
+			Password:    ingestPassword,
+			Token:       ingestToken,
+			BatchSize:   ingestBatchSize,
+			PollTimeout: ingestPollTimeout,
+		}
+
+		if ingestTLS {
+			source.TLSConfig = &tls.Config{}
+		}
+
+		ingester.Source = source
+	default:
+		logger.Error(fmt.Errorf("unsupported ingest source %q", ingestSource), `-ingest-source must be either "kafka" or "nats"`)
+		os.Exit(1)
+	}
+
+	// The brokers redeliver the messages that were handled but not yet acknowledged, like on the restarts of the replicas,
+	// which only the delivery store shared by the replicas can tell from the new deliveries
+	if ingester.Source != nil && deduplicationStore == "" {
+		logger.Error(errors.New("deduplication store is not specified"), "-ingest-source requires -deduplication-store")
+		os.Exit(1)
+	}
+
+	if ingester.Source != nil {
+		ingester.Log = ctrl.Log.WithName("webhookingester")
+		ingester.Handler = http.HandlerFunc(hraGitHubWebhook.Handle)
+
+		if err = mgr.Add(&ingester); err != nil {
+			logger.Error(err, "unable to add webhook delivery ingester")
+			os.Exit(1)
+		}
+	}
+
+	if cloudEventsSinkURL != "" {
+		sink, err := cloudevents.NewHTTPSink(cloudEventsSinkURL, cloudEventsSource, cloudEventsMode, ctrl.Log.WithName("cloudevents"))
+		if err != nil {
+			logger.Error(err, "unable to create CloudEvents sink")
+			os.Exit(1)
+		}
+
+		if err = mgr.Add(sink); err != nil {
+			logger.Error(err, "unable to add CloudEvents sink")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.CloudEvents = sink
+	}
+
+	hraGitHubWebhook.Policy, err = policyhook.New(policyHookURL, policyHookCELRules, policyHookFailOpen, ctrl.Log.WithName("policyhook"))
+	if err != nil {
+		logger.Error(err, "unable to create policy hook")
+		os.Exit(1)
+	}
+
+	if utilizationReport {
+		utilizationTracker.Client = mgr.GetClient()
+		utilizationTracker.Namespace = watchNamespace
+		utilizationTracker.Log = ctrl.Log.WithName("utilizationtracker")
+
+		if err = mgr.Add(&utilizationTracker); err != nil {
+			logger.Error(err, "unable to add utilization tracker")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.Utilization = &utilizationTracker
+	}
+
+	switch deduplicationStore {
+	case "":
+	case "lease":
+		if deliveryStore.Namespace == "" {
+			logger.Error(errors.New("namespace is not specified"), "-deduplication-store=lease requires -deduplication-namespace or POD_NAMESPACE")
+			os.Exit(1)
+		}
+
+		deliveryStore.Identity, err = os.Hostname()
+		if err != nil {
+			logger.Error(err, "unable to get hostname for the identity of the webhook delivery store")
+			os.Exit(1)
+		}
+
+		deliveryStore.Client = mgr.GetClient()
+		deliveryStore.Reader = mgr.GetAPIReader()
+		deliveryStore.Log = ctrl.Log.WithName("webhookdeliverystore")
+
+		if err = mgr.Add(&deliveryStore); err != nil {
+			logger.Error(err, "unable to add webhook delivery store")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.DeliveryStore = &deliveryStore
+	default:
+		logger.Error(fmt.Errorf("unsupported deduplication store %q", deduplicationStore), "-deduplication-store must be either empty or \"lease\"")
+		os.Exit(1)
+	}
+
+	if shardName != "" {
+		ns, name, ok := strings.Cut(shardConfigMap, "/")
+		if !ok || ns == "" || name == "" {
+			logger.Error(fmt.Errorf("invalid -shard-config-map %q", shardConfigMap), "-shard-config-map must be in the NAMESPACE/NAME format")
+			os.Exit(1)
+		}
+		shardConfigMapKey := types.NamespacedName{Namespace: ns, Name: name}
+
+		shards, err := sharding.Load(context.Background(), mgr.GetAPIReader(), shardConfigMapKey)
+		if err != nil {
+			logger.Error(err, "unable to read shards")
+			os.Exit(1)
+		}
+
+		sharder, err := sharding.New(shardName, shards)
+		if err != nil {
+			logger.Error(err, "unable to configure shard")
+			os.Exit(1)
+		}
+
+		if err = mgr.Add(&sharding.Watcher{
+			Client:   mgr.GetAPIReader(),
+			Key:      shardConfigMapKey,
+			Interval: shardRefreshInterval,
+			Log:      ctrl.Log.WithName("sharding"),
+			Shards:   shards,
+		}); err != nil {
+			logger.Error(err, "unable to add shard watcher")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.ShardRouter = &actionssummerwindnet.WebhookShardRouter{Sharder: sharder}
+
+		logger.Info("Sharding enabled", "shard", shardName, "configMap", shardConfigMap)
 	}
 
 	if err = hraGitHubWebhook.SetupWithManager(mgr); err != nil {
@@ -188,6 +581,59 @@ func main() {
 		os.Exit(1)
 	}
 
+	if verifySourceWithMeta || sourceCIDRs != "" {
+		sourceVerifier.CIDRs, err = actionssummerwindnet.ParseCIDRs(sourceCIDRs)
+		if err != nil {
+			logger.Error(err, "invalid -webhook-source-cidrs")
+			os.Exit(1)
+		}
+
+		sourceVerifier.TrustedProxies, err = actionssummerwindnet.ParseCIDRs(trustedProxies)
+		if err != nil {
+			logger.Error(err, "invalid -webhook-trusted-proxies")
+			os.Exit(1)
+		}
+
+		if verifySourceWithMeta {
+			// /meta doesn't require authentication, so it's fetched without the GitHub API credentials when they are not given
+			if ghClient != nil {
+				sourceVerifier.GitHubClient = ghClient.Client
+			} else if c.URL != "" {
+				sourceVerifier.GitHubClient, err = gogithub.NewEnterpriseClient(c.URL, c.URL, nil)
+				if err != nil {
+					logger.Error(err, "unable to create the client for /meta")
+					os.Exit(1)
+				}
+			} else {
+				sourceVerifier.GitHubClient = gogithub.NewClient(nil)
+			}
+		}
+
+		sourceVerifier.Log = ctrl.Log.WithName("webhooksourceverifier")
+
+		if err = mgr.Add(&sourceVerifier); err != nil {
+			logger.Error(err, "unable to add webhook source verifier")
+			os.Exit(1)
+		}
+
+		hraGitHubWebhook.SourceVerifier = &sourceVerifier
+	}
+
+	if redeliverer.HookID != 0 {
+		if ghClient == nil {
+			logger.Error(errors.New("github client is not initialized"), "-redelivery-hook-id requires GitHub API credentials")
+			os.Exit(1)
+		}
+
+		redeliverer.GitHubClient = ghClient
+		redeliverer.Log = ctrl.Log.WithName("webhookdeliveryredeliverer")
+
+		if err = mgr.Add(&redeliverer); err != nil {
+			logger.Error(err, "unable to add webhook delivery redeliverer")
+			os.Exit(1)
+		}
+	}
+
 	var wg sync.WaitGroup
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -207,11 +653,40 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", hraGitHubWebhook.Handle)
 
+	if hraGitHubWebhook.DeliveryRecorder != nil {
+		mux.Handle("/debug/deliveries", hraGitHubWebhook.DeliveryRecorder)
+	}
+
 	srv := http.Server{
 		Addr:    webhookAddr,
 		Handler: mux,
 	}
 
+	if clientCAFile != "" {
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			logger.Error(errors.New("TLS certificate is not specified"), "-webhook-client-ca-file requires -webhook-tls-cert-file and -webhook-tls-key-file")
+			os.Exit(1)
+		}
+
+		ca, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			logger.Error(err, "unable to read -webhook-client-ca-file")
+			os.Exit(1)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			logger.Error(errors.New("no certificate found"), "invalid -webhook-client-ca-file", "path", clientCAFile)
+			os.Exit(1)
+		}
+
+		srv.TLSConfig = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
 	wg.Add(1)
 	go func() {
 		defer cancel()
@@ -223,7 +698,14 @@ func main() {
 			srv.Shutdown(context.Background())
 		}()
 
-		if err := srv.ListenAndServe(); err != nil {
+		var err error
+		if tlsCertFile != "" {
+			err = srv.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil {
 			if !errors.Is(err, http.ErrServerClosed) {
 				logger.Error(err, "problem running http server")
 			}
@@ -0,0 +1,245 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-arc is the kubectl plugin for operating the runners of actions-runner-controller.
+// Install it in the PATH to run it as kubectl arc.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/kubectlarc"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = summerwindv1alpha1.AddToScheme(scheme)
+	_ = githubv1alpha1.AddToScheme(scheme)
+}
+
+const usage = `Usage: kubectl arc COMMAND [flags] ARGS
+
+Commands:
+  runners                                   List the runners with their busy state and job
+  describe autoscaler NAME                  Describe the decisions of a HorizontalRunnerAutoscaler or AutoscalingRunnerSet
+  drain RUNNER                              Unregister and replace a Runner, or delete an idle EphemeralRunner
+  scale RUNNER_DEPLOYMENT REPLICAS          Scale a RunnerDeployment, through its HorizontalRunnerAutoscaler if any
+  logs JOB_URL                              Print the logs of the runner pod running a workflow job
+  validate-credentials [AUTOSCALING_RUNNER_SET]
+                                            Authenticate to GitHub with the credentials of an AutoscalingRunnerSet or a secret
+  doctor                                    Check the installation for the causes of the most common issues
+
+Run kubectl arc COMMAND -h for the flags of the command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	var (
+		namespace string
+
+		allNamespaces bool
+		force         bool
+		follow        bool
+		duration      time.Duration
+
+		githubConfigURL    string
+		githubConfigSecret string
+		runnerGroup        string
+
+		doctorOpts kubectlarc.DoctorOptions
+		output     string
+	)
+
+	fs := flag.NewFlagSet("kubectl arc "+command, flag.ExitOnError)
+	fs.StringVar(&namespace, "n", "", "The namespace of the resources. Defaults to the namespace of the current kubeconfig context.")
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the resources. Defaults to the namespace of the current kubeconfig context.")
+
+	switch command {
+	case "runners":
+		fs.BoolVar(&allNamespaces, "A", false, "List the runners of all the namespaces.")
+		fs.BoolVar(&allNamespaces, "all-namespaces", false, "List the runners of all the namespaces.")
+	case "describe":
+	case "drain":
+		fs.BoolVar(&force, "force", false, "Drain the EphemeralRunner even when it is running a job, which cancels the job.")
+	case "scale":
+		fs.DurationVar(&duration, "duration", time.Hour, "How long the RunnerDeployment scaled by a HorizontalRunnerAutoscaler keeps the replicas.")
+	case "logs":
+		fs.BoolVar(&follow, "f", false, "Follow the logs.")
+		fs.BoolVar(&follow, "follow", false, "Follow the logs.")
+	case "validate-credentials":
+		fs.StringVar(&githubConfigURL, "github-config-url", "", "The URL of the enterprise, organization or repository to authenticate to, when no AutoscalingRunnerSet is given.")
+		fs.StringVar(&githubConfigSecret, "github-config-secret", "", "The secret holding the GitHub App or PAT, when no AutoscalingRunnerSet is given.")
+		fs.StringVar(&runnerGroup, "runner-group", "", "The runner group to look up, when no AutoscalingRunnerSet is given. Defaults to the default runner group.")
+	case "doctor":
+		fs.BoolVar(&doctorOpts.AllNamespaces, "A", false, "Check the AutoscalingRunnerSets of all the namespaces.")
+		fs.BoolVar(&doctorOpts.AllNamespaces, "all-namespaces", false, "Check the AutoscalingRunnerSets of all the namespaces.")
+		fs.StringVar(&doctorOpts.ControllerNamespace, "controller-namespace", "actions-runner-system", "The namespace of the legacy controller and its github webhook server.")
+		fs.StringVar(&doctorOpts.ControllerSecret, "controller-secret", "controller-manager", "The secret holding the GitHub App or PAT of the legacy controller.")
+		fs.StringVar(&doctorOpts.GitHubEnterpriseURL, "github-enterprise-url", "", "The URL of the GitHub Enterprise Server the legacy controller is connected to.")
+		fs.StringVar(&doctorOpts.WebhookURL, "webhook-url", "", "The public URL of the github webhook server GitHub delivers the events to.")
+		fs.StringVar(&doctorOpts.WebhookSecret, "webhook-secret", "", "The secret holding the github_webhook_secret_token of the github webhook server.")
+		fs.StringVar(&doctorOpts.Organization, "organization", "", "The organization the webhook of --webhook-url is registered in.")
+		fs.StringVar(&doctorOpts.Repository, "repository", "", "The repository, in the OWNER/NAME format, the webhook of --webhook-url is registered in.")
+		fs.StringVar(&output, "o", "text", `The format of the report, "text" or "json".`)
+		fs.StringVar(&output, "output", "text", `The format of the report, "text" or "json".`)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+
+	// kubectl passes the flags after the arguments, like kubectl arc drain RUNNER -n NAMESPACE.
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	logger, err := logging.NewLogger(logging.LogLevelError, "text")
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	ctrl.SetLogger(logger)
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	if namespace == "" {
+		namespace, _, err = kubeConfig.Namespace()
+		if err != nil {
+			return fmt.Errorf("failed to get the namespace of the current context: %w", err)
+		}
+	}
+
+	config, err := kubeConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	a := kubectlarc.Arc{
+		Namespace: namespace,
+		Out:       os.Stdout,
+		NewActionsClient: func(ctx context.Context, githubConfigURL string, secretData actions.KubernetesSecretData) (actions.ActionsService, error) {
+			return actions.NewMultiClient(logger).GetClientFromSecret(ctx, githubConfigURL, namespace, secretData)
+		},
+	}
+
+	a.Client, err = client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("unable to create kubernetes client: %w", err)
+	}
+
+	a.Clientset, err = kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("unable to create kubernetes clientset: %w", err)
+	}
+
+	switch command {
+	case "runners":
+		if len(positional) != 0 {
+			return fmt.Errorf("usage: kubectl arc runners [-A]")
+		}
+		return a.ListRunners(ctx, allNamespaces)
+	case "describe":
+		if len(positional) != 2 || positional[0] != "autoscaler" {
+			return fmt.Errorf("usage: kubectl arc describe autoscaler NAME")
+		}
+		return a.DescribeAutoscaler(ctx, positional[1])
+	case "drain":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: kubectl arc drain RUNNER [--force]")
+		}
+		return a.Drain(ctx, positional[0], force)
+	case "scale":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: kubectl arc scale RUNNER_DEPLOYMENT REPLICAS [--duration DURATION]")
+		}
+		replicas, err := strconv.Atoi(positional[1])
+		if err != nil || replicas < 0 {
+			return fmt.Errorf("invalid number of replicas %q", positional[1])
+		}
+		return a.Scale(ctx, positional[0], replicas, duration)
+	case "logs":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: kubectl arc logs JOB_URL [-f]")
+		}
+		return a.Logs(ctx, positional[0], follow)
+	case "doctor":
+		if len(positional) != 0 {
+			return fmt.Errorf("usage: kubectl arc doctor [flags]")
+		}
+		report, err := a.Doctor(ctx, doctorOpts)
+		if err != nil {
+			return err
+		}
+		if err := a.WriteReport(report, output); err != nil {
+			return err
+		}
+		if n := report.Errors(); n > 0 {
+			return fmt.Errorf("%d checks failed", n)
+		}
+		return nil
+	default: // validate-credentials
+		switch {
+		case len(positional) == 1:
+			return a.ValidateAutoscalingRunnerSetCredentials(ctx, positional[0])
+		case len(positional) == 0 && githubConfigURL != "" && githubConfigSecret != "":
+			return a.ValidateCredentials(ctx, githubConfigURL, githubConfigSecret, runnerGroup)
+		default:
+			return fmt.Errorf("usage: kubectl arc validate-credentials AUTOSCALING_RUNNER_SET, or kubectl arc validate-credentials --github-config-url URL --github-config-secret SECRET [--runner-group GROUP]")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/runnerdeploymentmigration"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/exec"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = summerwindv1alpha1.AddToScheme(scheme)
+	_ = githubv1alpha1.AddToScheme(scheme)
+}
+
+// labelRunnerGroups is the LABEL=GROUP flag that can be specified multiple times.
+type labelRunnerGroups map[string]string
+
+func (m labelRunnerGroups) String() string {
+	return fmt.Sprintf("%v", map[string]string(m))
+}
+
+func (m labelRunnerGroups) Set(value string) error {
+	label, group, ok := strings.Cut(value, "=")
+	if !ok || label == "" || group == "" {
+		return fmt.Errorf("expected LABEL=GROUP, got %q", value)
+	}
+	m[label] = group
+	return nil
+}
+
+func main() {
+	var (
+		err error
+
+		logLevel  string
+		logFormat string
+
+		m = runnerdeploymentmigration.Migrator{Out: os.Stdout}
+
+		labelGroups = labelRunnerGroups{}
+	)
+
+	flag.StringVar(&m.Namespace, "namespace", "", "The namespace of the RunnerDeployments to migrate. Set to empty for migrating the RunnerDeployments of all the namespaces.")
+	flag.BoolVar(&m.Apply, "apply", false, "Create or update the AutoscalingRunnerSets, and scale down the RunnerDeployments and their HorizontalRunnerAutoscalers. Otherwise, the resources are only printed.")
+	flag.StringVar(&m.Options.GitHubURL, "github-url", runnerdeploymentmigration.DefaultGitHubURL, "The URL of GitHub or GitHub Enterprise Server the runners are registered at.")
+	flag.StringVar(&m.Options.GitHubConfigSecret, "github-config-secret", "", "The secret holding the GitHub App or PAT of the AutoscalingRunnerSets, which must exist in the namespace of every RunnerDeployment.")
+	flag.StringVar(&m.Options.ControllerVersion, "controller-version", "", "The version of the installed gha-runner-scale-set-controller, like 0.10.1. The controller deletes the AutoscalingRunnerSets of other versions.")
+	flag.StringVar(&m.Options.RunnerImage, "runner-image", runnerdeploymentmigration.DefaultRunnerImage, "The image of the runner container of the AutoscalingRunnerSets.")
+	flag.StringVar(&m.Options.DockerImage, "docker-image", runnerdeploymentmigration.DefaultDockerImage, "The image of the dind sidecar of the AutoscalingRunnerSets migrated from the RunnerDeployments with docker enabled.")
+	flag.Var(labelGroups, "label-runner-group", "The runner group in the LABEL=GROUP format the runner scale sets of the RunnerDeployments having the runner label are added to, like gpu=gpu-runners. Can be specified multiple times.")
+	flag.IntVar(&m.Options.CapacityPercent, "capacity-percent", 100, "The percentage of the minimum and maximum number of runners of the RunnerDeployments moved to the AutoscalingRunnerSets. The RunnerDeployments keep the rest, so that both run side by side.")
+	flag.StringVar(&logLevel, "log-level", logging.LogLevelInfo, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "info".`)
+	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+	flag.Parse()
+
+	m.Options.LabelRunnerGroups = labelGroups
+
+	logger, err := logging.NewLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: creating logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctrl.SetLogger(logger)
+
+	m.Log = logger.WithName("runnerdeploymentmigrator")
+
+	m.Client, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		logger.Error(err, "unable to create kubernetes client")
+		os.Exit(1)
+	}
+
+	if err := m.Run(context.Background()); err != nil {
+		logger.Error(err, "unable to migrate runner deployments")
+		os.Exit(1)
+	}
+}
@@ -35,6 +35,8 @@ import (
 	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
 	hash "github.com/actions/actions-runner-controller/hash"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,6 +58,9 @@ type AutoscalingListenerReconciler struct {
 	ListenerMetricsEndpoint string
 
 	ResourceBuilder
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
@@ -64,6 +69,7 @@ type AutoscalingListenerReconciler struct {
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=create;delete;get;list;watch;update
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=create;delete;get;list;watch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners/finalizers,verbs=update
@@ -77,6 +83,10 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(autoscalingListener.Spec.AutoscalingRunnerSetNamespace, sharding.OwnerOfGitHubConfigURL(autoscalingListener.Spec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !autoscalingListener.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(autoscalingListener, autoscalingListenerFinalizerName) {
 			return ctrl.Result{}, nil
@@ -188,7 +198,7 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// Make sure the listener role has the up-to-date rules
 	existingRuleHash := listenerRole.Labels["role-policy-rules-hash"]
-	desiredRules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})
+	desiredRules := rulesForListener(autoscalingListener)
 	desiredRulesHash := hash.ComputeTemplateHash(&desiredRules)
 	if existingRuleHash != desiredRulesHash {
 		log.Info("Updating the listener role with the up-to-date rules")
@@ -225,75 +235,97 @@ func (r *AutoscalingListenerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	// TODO: make sure the role binding has the up-to-date role and service account
 
-	listenerPod := new(corev1.Pod)
-	if err := r.Get(ctx, client.ObjectKey{Namespace: autoscalingListener.Namespace, Name: autoscalingListener.Name}, listenerPod); err != nil {
-		if !kerrors.IsNotFound(err) {
-			log.Error(err, "Unable to get listener pod", "namespace", autoscalingListener.Namespace, "name", autoscalingListener.Name)
-			return ctrl.Result{}, err
-		}
-
-		if err := r.publishRunningListener(autoscalingListener, false); err != nil {
-			// If publish fails, URL is incorrect which means the listener pod would never be able to start
-			return ctrl.Result{}, nil
-		}
+	// Each replica is reconciled on its own, so that a standby listener is recreated
+	// while the leader keeps listening, and vice versa
+	running := false
+	for replica := 0; replica < listenerReplicas(autoscalingListener); replica++ {
+		podName := scaleSetListenerPodName(autoscalingListener, replica)
 
-		// Create a listener pod in the controller namespace
-		log.Info("Creating a listener pod")
-		return r.createListenerPod(ctx, &autoscalingRunnerSet, autoscalingListener, serviceAccount, mirrorSecret, log)
-	}
+		listenerPod := new(corev1.Pod)
+		if err := r.Get(ctx, client.ObjectKey{Namespace: autoscalingListener.Namespace, Name: podName}, listenerPod); err != nil {
+			if !kerrors.IsNotFound(err) {
+				log.Error(err, "Unable to get listener pod", "namespace", autoscalingListener.Namespace, "name", podName)
+				return ctrl.Result{}, err
+			}
 
-	cs := listenerContainerStatus(listenerPod)
-	switch {
-	case cs == nil:
-		log.Info("Listener pod is not ready", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
-		return ctrl.Result{}, nil
-	case cs.State.Terminated != nil:
-		log.Info("Listener pod is terminated", "namespace", listenerPod.Namespace, "name", listenerPod.Name, "reason", cs.State.Terminated.Reason, "message", cs.State.Terminated.Message)
+			if err := r.publishRunningListener(autoscalingListener, running); err != nil {
+				// If publish fails, URL is incorrect which means the listener pod would never be able to start
+				return ctrl.Result{}, nil
+			}
 
-		if err := r.publishRunningListener(autoscalingListener, false); err != nil {
-			log.Error(err, "Unable to publish runner listener down metric", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
+			// Create a listener pod in the controller namespace
+			log.Info("Creating a listener pod", "name", podName)
+			return r.createListenerPod(ctx, &autoscalingRunnerSet, autoscalingListener, serviceAccount, mirrorSecret, podName, log)
 		}
 
-		if listenerPod.DeletionTimestamp.IsZero() {
-			log.Info("Deleting the listener pod", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
-			if err := r.Delete(ctx, listenerPod); err != nil && !kerrors.IsNotFound(err) {
-				log.Error(err, "Unable to delete the listener pod", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
-				return ctrl.Result{}, err
+		cs := listenerContainerStatus(listenerPod)
+		switch {
+		case cs == nil:
+			log.Info("Listener pod is not ready", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
+		case cs.State.Terminated != nil:
+			log.Info("Listener pod is terminated", "namespace", listenerPod.Namespace, "name", listenerPod.Name, "reason", cs.State.Terminated.Reason, "message", cs.State.Terminated.Message)
+
+			if listenerPod.DeletionTimestamp.IsZero() {
+				log.Info("Deleting the listener pod", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
+				if err := r.Delete(ctx, listenerPod); err != nil && !kerrors.IsNotFound(err) {
+					log.Error(err, "Unable to delete the listener pod", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
+					return ctrl.Result{}, err
+				}
 			}
+		case cs.State.Running != nil:
+			running = true
 		}
+	}
+
+	if err := r.publishRunningListener(autoscalingListener, running); err != nil {
+		log.Error(err, "Unable to publish running listener", "namespace", autoscalingListener.Namespace, "name", autoscalingListener.Name)
+		// stop reconciling. We should never get to this point but if we do,
+		// listener won't be able to start up, and the crash from the pod should
+		// notify the reconciler again.
 		return ctrl.Result{}, nil
-	case cs.State.Running != nil:
-		if err := r.publishRunningListener(autoscalingListener, true); err != nil {
-			log.Error(err, "Unable to publish running listener", "namespace", listenerPod.Namespace, "name", listenerPod.Name)
-			// stop reconciling. We should never get to this point but if we do,
-			// listener won't be able to start up, and the crash from the pod should
-			// notify the reconciler again.
-			return ctrl.Result{}, nil
+	}
+
+	if autoscalingListener.Status.ObservedGeneration != autoscalingListener.Generation {
+		if err := patchSubResource(ctx, r.Status(), autoscalingListener, func(obj *v1alpha1.AutoscalingListener) {
+			obj.Status.ObservedGeneration = obj.Generation
+		}); err != nil {
+			log.Error(err, "Failed to update autoscaling listener status with the observed generation")
+			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
 	}
+
 	return ctrl.Result{}, nil
 }
 
+// listenerReplicas returns the number of listener pods, defaulting to one for the listeners created
+// before the replicas were configurable.
+func listenerReplicas(autoscalingListener *v1alpha1.AutoscalingListener) int {
+	if autoscalingListener.Spec.Replicas < 1 {
+		return 1
+	}
+	return autoscalingListener.Spec.Replicas
+}
+
 func (r *AutoscalingListenerReconciler) cleanupResources(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, logger logr.Logger) (done bool, err error) {
-	logger.Info("Cleaning up the listener pod")
-	listenerPod := new(corev1.Pod)
-	err = r.Get(ctx, types.NamespacedName{Name: autoscalingListener.Name, Namespace: autoscalingListener.Namespace}, listenerPod)
-	switch {
-	case err == nil:
-		if listenerPod.ObjectMeta.DeletionTimestamp.IsZero() {
-			logger.Info("Deleting the listener pod")
-			if err := r.Delete(ctx, listenerPod); err != nil {
-				return false, fmt.Errorf("failed to delete listener pod: %w", err)
+	logger.Info("Cleaning up the listener pods")
+	for replica := 0; replica < listenerReplicas(autoscalingListener); replica++ {
+		listenerPod := new(corev1.Pod)
+		err = r.Get(ctx, types.NamespacedName{Name: scaleSetListenerPodName(autoscalingListener, replica), Namespace: autoscalingListener.Namespace}, listenerPod)
+		switch {
+		case err == nil:
+			if listenerPod.ObjectMeta.DeletionTimestamp.IsZero() {
+				logger.Info("Deleting the listener pod", "name", listenerPod.Name)
+				if err := r.Delete(ctx, listenerPod); err != nil {
+					return false, fmt.Errorf("failed to delete listener pod: %w", err)
+				}
 			}
+			return false, nil
+		case !kerrors.IsNotFound(err):
+			return false, fmt.Errorf("failed to get listener pods: %w", err)
 		}
-		return false, nil
-	case kerrors.IsNotFound(err):
-		_ = r.publishRunningListener(autoscalingListener, false) // If error is returned, we never published metrics so it is safe to ignore
-	default:
-		return false, fmt.Errorf("failed to get listener pods: %w", err)
 	}
-	logger.Info("Listener pod is deleted")
+	_ = r.publishRunningListener(autoscalingListener, false) // If error is returned, we never published metrics so it is safe to ignore
+	logger.Info("Listener pods are deleted")
 
 	var secret corev1.Secret
 	err = r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Namespace, Name: scaleSetListenerConfigName(autoscalingListener)}, &secret)
@@ -398,7 +430,7 @@ func (r *AutoscalingListenerReconciler) createServiceAccountForListener(ctx cont
 	return ctrl.Result{}, nil
 }
 
-func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, logger logr.Logger) (ctrl.Result, error) {
+func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, autoscalingListener *v1alpha1.AutoscalingListener, serviceAccount *corev1.ServiceAccount, secret *corev1.Secret, podName string, logger logr.Logger) (ctrl.Result, error) {
 	var envs []corev1.EnvVar
 	if autoscalingListener.Spec.Proxy != nil {
 		httpURL := corev1.EnvVar{
@@ -450,6 +482,15 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 		}
 	}
 
+	federationKubeconfig := ""
+	if autoscalingListener.Spec.Federation != nil {
+		var err error
+		federationKubeconfig, err = r.federationKubeconfig(ctx, autoscalingListener)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get federation kubeconfig for listener: %w", err)
+		}
+	}
+
 	var metricsConfig *listenerMetricsServerConfig
 	if r.ListenerMetricsAddr != "0" {
 		metricsConfig = &listenerMetricsServerConfig{
@@ -467,7 +508,7 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 
 		logger.Info("Creating listener config secret")
 
-		podConfig, err := r.ResourceBuilder.newScaleSetListenerConfig(autoscalingListener, secret, metricsConfig, cert)
+		podConfig, err := r.ResourceBuilder.newScaleSetListenerConfig(autoscalingListener, secret, metricsConfig, cert, federationKubeconfig)
 		if err != nil {
 			logger.Error(err, "Failed to build listener config secret")
 			return ctrl.Result{}, err
@@ -491,6 +532,7 @@ func (r *AutoscalingListenerReconciler) createListenerPod(ctx context.Context, a
 		logger.Error(err, "Failed to build listener pod")
 		return ctrl.Result{}, err
 	}
+	newPod.Name = podName
 
 	if err := ctrl.SetControllerReference(autoscalingListener, newPod, r.Scheme); err != nil {
 		logger.Error(err, "Failed to set controller reference")
@@ -545,6 +587,23 @@ func (r *AutoscalingListenerReconciler) certificate(ctx context.Context, autosca
 	return certificate, nil
 }
 
+// federationKubeconfig returns the kubeconfig of the broker cluster of the federation the listener is a member of.
+func (r *AutoscalingListenerReconciler) federationKubeconfig(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener) (string, error) {
+	name := autoscalingListener.Spec.Federation.KubeconfigSecretName
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingListener.Spec.AutoscalingRunnerSetNamespace, Name: name}, &secret); err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+
+	kubeconfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		return "", fmt.Errorf("key kubeconfig is not found in secret %s", name)
+	}
+
+	return string(kubeconfig), nil
+}
+
 func (r *AutoscalingListenerReconciler) createSecretsForListener(ctx context.Context, autoscalingListener *v1alpha1.AutoscalingListener, secret *corev1.Secret, logger logr.Logger) (ctrl.Result, error) {
 	newListenerSecret := r.ResourceBuilder.newScaleSetListenerSecretMirror(autoscalingListener, secret)
 
@@ -729,7 +788,7 @@ func (r *AutoscalingListenerReconciler) SetupWithManager(mgr ctrl.Manager) error
 		Watches(&rbacv1.Role{}, handler.EnqueueRequestsFromMapFunc(labelBasedWatchFunc)).
 		Watches(&rbacv1.RoleBinding{}, handler.EnqueueRequestsFromMapFunc(labelBasedWatchFunc)).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
+		Complete(reconcilemetrics.Observe("autoscalinglistener", "AutoscalingListener", r))
 }
 
 func listenerContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
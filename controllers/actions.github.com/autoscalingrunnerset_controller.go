@@ -18,20 +18,29 @@ package actionsgithubcom
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/build"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -49,6 +58,9 @@ const (
 
 	autoscalingRunnerSetFinalizerName = "autoscalingrunnerset.actions.github.com/finalizer"
 	runnerScaleSetIdAnnotationKey     = "runner-scale-set-id"
+
+	defaultRunnerGroupName = "Default"
+	dedicatedToDocsURL     = "https://github.com/actions/actions-runner-controller/blob/master/docs/gha-runner-scale-set-controller/README.md#dependabot-and-code-scanning-runners"
 )
 
 type UpdateStrategy string
@@ -79,26 +91,37 @@ type AutoscalingRunnerSetReconciler struct {
 	DefaultRunnerScaleSetListenerImagePullSecrets []string
 	UpdateStrategy                                UpdateStrategy
 	ActionsClient                                 actions.MultiClient
+	Recorder                                      record.EventRecorder
 	ResourceBuilder
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalingrunnersets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalingrunnersets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalingrunnersets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunnersets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunnersets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.github.com,resources=autoscalinglisteners/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;delete
 
 // Reconcile a AutoscalingRunnerSet resource to meet its desired spec.
 func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("autoscalingrunnerset", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "AutoscalingRunnerSet", req.NamespacedName)
 
 	autoscalingRunnerSet := new(v1alpha1.AutoscalingRunnerSet)
 	if err := r.Get(ctx, req.NamespacedName, autoscalingRunnerSet); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(autoscalingRunnerSet.Namespace, sharding.OwnerOfGitHubConfigURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !autoscalingRunnerSet.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(autoscalingRunnerSet, autoscalingRunnerSetFinalizerName) {
 			return ctrl.Result{}, nil
@@ -180,17 +203,32 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, nil
 	}
 
+	if drift, err := specDrift(autoscalingRunnerSet); err != nil {
+		log.Error(err, "Ignoring the suspend-on-drift annotation")
+	} else if drift != "" {
+		log.Info("Suspended the reconciliation as the spec has drifted", "drift", drift)
+		r.Recorder.Event(autoscalingRunnerSet, corev1.EventTypeWarning, v1alpha1.ConditionReasonSpecDrifted, drift)
+		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeSynced, false, v1alpha1.ConditionReasonSpecDrifted, drift)
+		}); err != nil {
+			log.Error(err, "Failed to update autoscaling runner set status conditions")
+		}
+		// The listener and the ephemeral runner set keep the last synced spec.
+		// Syncing the spec back bumps the generation, which triggers the next reconciliation.
+		return ctrl.Result{}, nil
+	}
+
 	scaleSetIdRaw, ok := autoscalingRunnerSet.Annotations[runnerScaleSetIdAnnotationKey]
 	if !ok {
 		// Need to create a new runner scale set on Actions service
 		log.Info("Runner scale set id annotation does not exist. Creating a new runner scale set.")
-		return r.createRunnerScaleSet(ctx, autoscalingRunnerSet, log)
+		return r.registerRunnerScaleSet(ctx, autoscalingRunnerSet, log)
 	}
 
 	if id, err := strconv.Atoi(scaleSetIdRaw); err != nil || id <= 0 {
 		log.Info("Runner scale set id annotation is not an id, or is <= 0. Creating a new runner scale set.")
 		// something modified the scaleSetId. Try to create one
-		return r.createRunnerScaleSet(ctx, autoscalingRunnerSet, log)
+		return r.registerRunnerScaleSet(ctx, autoscalingRunnerSet, log)
 	}
 
 	// Make sure the runner group of the scale set is up to date
@@ -202,7 +240,7 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 
 	// Make sure the runner scale set name is up to date
 	currentRunnerScaleSetName, ok := autoscalingRunnerSet.Annotations[AnnotationKeyGitHubRunnerScaleSetName]
-	if !ok || (len(autoscalingRunnerSet.Spec.RunnerScaleSetName) > 0 && !strings.EqualFold(currentRunnerScaleSetName, autoscalingRunnerSet.Spec.RunnerScaleSetName)) {
+	if desiredRunnerScaleSetName := runnerScaleSetName(autoscalingRunnerSet); !ok || (len(desiredRunnerScaleSetName) > 0 && !strings.EqualFold(currentRunnerScaleSetName, desiredRunnerScaleSetName)) {
 		log.Info("AutoScalingRunnerSet runner scale set name changed. Updating the runner scale set.")
 		return r.updateRunnerScaleSetName(ctx, autoscalingRunnerSet, log)
 	}
@@ -212,6 +250,7 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		log.Error(err, "Failed to find GitHub config secret.",
 			"namespace", autoscalingRunnerSet.Namespace,
 			"name", autoscalingRunnerSet.Spec.GitHubConfigSecret)
+		r.setFailedConditions(ctx, autoscalingRunnerSet, v1alpha1.ConditionReasonReconcileFailed, err, log)
 		return ctrl.Result{}, err
 	}
 
@@ -231,6 +270,62 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 		log.Info("Find existing ephemeral runner set", "name", runnerSet.Name, "specHash", runnerSet.Annotations[annotationKeyRunnerSpecHash])
 	}
 
+	// The scale down policy takes effect without replacing the runners, as it isn't a part of the runner spec hash
+	if !equality.Semantic.DeepEqual(latestRunnerSet.Spec.ScaleDownPolicy, autoscalingRunnerSet.Spec.ScaleDownPolicy) {
+		log.Info("Updating the scale down policy of the latest runner set", "ephemeralRunnerSetName", latestRunnerSet.Name)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.ScaleDownPolicy = autoscalingRunnerSet.Spec.ScaleDownPolicy
+		}); err != nil {
+			log.Error(err, "Failed to update the scale down policy of the latest runner set")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The warm pool also takes effect without replacing the runners
+	if warmPool := ephemeralRunnerSetWarmPool(autoscalingRunnerSet); !equality.Semantic.DeepEqual(latestRunnerSet.Spec.WarmPool, warmPool) {
+		log.Info("Updating the warm pool of the latest runner set", "ephemeralRunnerSetName", latestRunnerSet.Name)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.WarmPool = warmPool
+		}); err != nil {
+			log.Error(err, "Failed to update the warm pool of the latest runner set")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// So does the capacity distribution, which applies to the runners created afterwards
+	if !equality.Semantic.DeepEqual(latestRunnerSet.Spec.CapacityDistribution, autoscalingRunnerSet.Spec.CapacityDistribution) {
+		log.Info("Updating the capacity distribution of the latest runner set", "ephemeralRunnerSetName", latestRunnerSet.Name)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.CapacityDistribution = autoscalingRunnerSet.Spec.CapacityDistribution
+		}); err != nil {
+			log.Error(err, "Failed to update the capacity distribution of the latest runner set")
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+
+	paused, pausedUntil, err := autoscalingRunnerSetPause(now, autoscalingRunnerSet)
+	if err != nil {
+		log.Error(err, "Ignoring the invalid paused-until annotation")
+	}
+
+	// Pausing also takes effect without replacing the runners, while the listener keeps running
+	if latestRunnerSet.Spec.Paused != paused {
+		log.Info("Updating the pause of the latest runner set", "ephemeralRunnerSetName", latestRunnerSet.Name, "paused", paused)
+		if err := patch(ctx, r.Client, latestRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+			obj.Spec.Paused = paused
+		}); err != nil {
+			log.Error(err, "Failed to update the pause of the latest runner set")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.reconcileImagePrePull(ctx, autoscalingRunnerSet, log); err != nil {
+		log.Error(err, "Failed to reconcile the image pre-pull daemonset")
+		return ctrl.Result{}, err
+	}
+
 	// Make sure the AutoscalingListener is up and running in the controller namespace
 	listener := new(v1alpha1.AutoscalingListener)
 	listenerFound := true
@@ -294,26 +389,133 @@ func (r *AutoscalingRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl
 	if !listenerFound {
 		if r.drainingJobs(&latestRunnerSet.Status) {
 			log.Info("Creating a new AutoscalingListener is waiting for the running and pending runners to finish. Waiting for the running and pending runners to finish:", "running", latestRunnerSet.Status.RunningEphemeralRunners, "pending", latestRunnerSet.Status.PendingEphemeralRunners)
+			if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+				message := "Waiting for the running and pending runners to finish before creating the listener"
+				setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonWaitingForListener, message)
+				setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonWaitingForListener, message)
+			}); err != nil {
+				log.Error(err, "Failed to update autoscaling runner set status conditions")
+			}
 			return ctrl.Result{}, nil
 		}
 		log.Info("Creating a new AutoscalingListener for the runner set", "ephemeralRunnerSetName", latestRunnerSet.Name)
 		return r.createAutoScalingListenerForRunnerSet(ctx, autoscalingRunnerSet, latestRunnerSet, log)
 	}
 
+	var result ctrl.Result
+	var pausedUntilTime *metav1.Time
+
+	if pausedUntil != nil {
+		pausedUntilTime = &metav1.Time{Time: *pausedUntil}
+
+		// Resume right after the pause expires
+		result.RequeueAfter = pausedUntil.Sub(now)
+	}
+
+	conditions := autoscalingRunnerSetConditions(autoscalingRunnerSet, paused, latestRunnerSet.Annotations[AnnotationKeyListenerSession])
+
 	// Update the status of autoscaling runner set.
-	if latestRunnerSet.Status.CurrentReplicas != autoscalingRunnerSet.Status.CurrentRunners {
+	if latestRunnerSet.Status.CurrentReplicas != autoscalingRunnerSet.Status.CurrentRunners ||
+		paused != autoscalingRunnerSet.Status.Paused ||
+		!equality.Semantic.DeepEqual(pausedUntilTime, autoscalingRunnerSet.Status.PausedUntil) ||
+		autoscalingRunnerSet.Generation != autoscalingRunnerSet.Status.ObservedGeneration ||
+		!equality.Semantic.DeepEqual(conditions, autoscalingRunnerSet.Status.Conditions) {
 		if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			obj.Status.Conditions = conditions
 			obj.Status.CurrentRunners = latestRunnerSet.Status.CurrentReplicas
 			obj.Status.PendingEphemeralRunners = latestRunnerSet.Status.PendingEphemeralRunners
 			obj.Status.RunningEphemeralRunners = latestRunnerSet.Status.RunningEphemeralRunners
 			obj.Status.FailedEphemeralRunners = latestRunnerSet.Status.FailedEphemeralRunners
+			obj.Status.Paused = paused
+			obj.Status.PausedUntil = pausedUntilTime
+			obj.Status.ObservedGeneration = obj.Generation
 		}); err != nil {
 			log.Error(err, "Failed to update autoscaling runner set status with current runner count")
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// autoscalingRunnerSetPause tells whether the scaling of the runner scale set is paused at now, either by spec.paused or by the paused-until annotation.
+// until is the expiry of the pause by the annotation, which is nil when it's paused by spec.paused or not paused at all.
+// The annotation with an invalid time is reported as an error and ignored.
+func autoscalingRunnerSetPause(now time.Time, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (paused bool, until *time.Time, err error) {
+	if v, ok := autoscalingRunnerSet.Annotations[AnnotationKeyPausedUntil]; ok && !autoscalingRunnerSet.Spec.Paused {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return false, nil, fmt.Errorf("parsing the %s annotation %q: %w", AnnotationKeyPausedUntil, v, err)
+		}
+
+		if now.Before(t) {
+			return true, &t, nil
+		}
+	}
+
+	return autoscalingRunnerSet.Spec.Paused, nil, nil
+}
+
+// listenerSessionState is the health of the message session the listener records in AnnotationKeyListenerSession.
+type listenerSessionState struct {
+	Healthy    bool   `json:"healthy"`
+	Transport  string `json:"transport"`
+	Reconnects int    `json:"reconnects,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// autoscalingRunnerSetConditions returns the conditions of the autoscaling runner set whose listener is up.
+// listenerSession is the session state recorded by the listener, which is empty until the listener reports it.
+func autoscalingRunnerSetConditions(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, paused bool, listenerSession string) []metav1.Condition {
+	conditions := append([]metav1.Condition{}, autoscalingRunnerSet.Status.Conditions...)
+	generation := autoscalingRunnerSet.Generation
+
+	setCondition(&conditions, generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonListenerRunning, "")
+	setCondition(&conditions, generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+	setCondition(&conditions, generation, v1alpha1.ConditionTypeGitHubRegistered, true, v1alpha1.ConditionReasonRegistered, fmt.Sprintf("Runner scale set ID is %s", autoscalingRunnerSet.Annotations[runnerScaleSetIdAnnotationKey]))
+
+	if paused {
+		setCondition(&conditions, generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonPaused, "")
+	} else {
+		setCondition(&conditions, generation, v1alpha1.ConditionTypeScalingActive, true, v1alpha1.ConditionReasonListenerRunning, "")
+	}
+
+	// A malformed session state leaves the condition as it was
+	var session listenerSessionState
+	if listenerSession != "" && json.Unmarshal([]byte(listenerSession), &session) == nil {
+		if session.Healthy {
+			setCondition(&conditions, generation, v1alpha1.ConditionTypeListenerSessionHealthy, true, v1alpha1.ConditionReasonSessionHealthy, fmt.Sprintf("Getting messages with %s", session.Transport))
+		} else {
+			setCondition(&conditions, generation, v1alpha1.ConditionTypeListenerSessionHealthy, false, v1alpha1.ConditionReasonSessionReconnecting, fmt.Sprintf("Reconnect %d with %s: %s", session.Reconnects, session.Transport, session.Message))
+		}
+	}
+
+	return conditions
+}
+
+// registerRunnerScaleSet creates the runner scale set on the Actions service,
+// and reports the failure in the conditions of the autoscaling runner set.
+func (r *AutoscalingRunnerSetReconciler) registerRunnerScaleSet(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (ctrl.Result, error) {
+	result, err := r.createRunnerScaleSet(ctx, autoscalingRunnerSet, logger)
+	if err != nil {
+		r.setFailedConditions(ctx, autoscalingRunnerSet, v1alpha1.ConditionReasonRegistrationFailed, err, logger)
+	}
+
+	return result, err
+}
+
+// setFailedConditions patches the conditions of the autoscaling runner set that failed to be reconciled for the reason.
+func (r *AutoscalingRunnerSetReconciler) setFailedConditions(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, reason string, err error, logger logr.Logger) {
+	if err := patchSubResource(ctx, r.Status(), autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+		setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeReady, false, reason, err.Error())
+		setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeSynced, false, reason, err.Error())
+
+		if reason == v1alpha1.ConditionReasonRegistrationFailed {
+			setCondition(&obj.Status.Conditions, obj.Generation, v1alpha1.ConditionTypeGitHubRegistered, false, reason, err.Error())
+		}
+	}); err != nil {
+		logger.Error(err, "Failed to update autoscaling runner set status conditions")
+	}
 }
 
 // Prevents overprovisioning of runners.
@@ -403,6 +605,7 @@ func (r *AutoscalingRunnerSetReconciler) removeFinalizersFromDependentResources(
 func (r *AutoscalingRunnerSetReconciler) createRunnerScaleSet(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) (ctrl.Result, error) {
 	logger.Info("Creating a new runner scale set")
 	actionsClient, err := r.actionsClientFor(ctx, autoscalingRunnerSet)
+	autoscalingRunnerSet.Spec.RunnerScaleSetName = runnerScaleSetName(autoscalingRunnerSet)
 	if len(autoscalingRunnerSet.Spec.RunnerScaleSetName) == 0 {
 		autoscalingRunnerSet.Spec.RunnerScaleSetName = autoscalingRunnerSet.Name
 	}
@@ -411,6 +614,11 @@ func (r *AutoscalingRunnerSetReconciler) createRunnerScaleSet(ctx context.Contex
 		return ctrl.Result{}, err
 	}
 
+	if err := validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logger); err != nil {
+		logger.Error(err, "Invalid runner scale set dedicated to Dependabot or code scanning")
+		return ctrl.Result{}, err
+	}
+
 	runnerGroupId := 1
 	if len(autoscalingRunnerSet.Spec.RunnerGroup) > 0 {
 		runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, autoscalingRunnerSet.Spec.RunnerGroup)
@@ -504,6 +712,11 @@ func (r *AutoscalingRunnerSetReconciler) updateRunnerScaleSetRunnerGroup(ctx con
 		return ctrl.Result{}, err
 	}
 
+	if err := validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logger); err != nil {
+		logger.Error(err, "Invalid runner scale set dedicated to Dependabot or code scanning")
+		return ctrl.Result{}, err
+	}
+
 	runnerGroupId := 1
 	if len(autoscalingRunnerSet.Spec.RunnerGroup) > 0 {
 		runnerGroup, err := actionsClient.GetRunnerGroupByName(ctx, autoscalingRunnerSet.Spec.RunnerGroup)
@@ -541,7 +754,8 @@ func (r *AutoscalingRunnerSetReconciler) updateRunnerScaleSetName(ctx context.Co
 		return ctrl.Result{}, err
 	}
 
-	if len(autoscalingRunnerSet.Spec.RunnerScaleSetName) == 0 {
+	name := runnerScaleSetName(autoscalingRunnerSet)
+	if len(name) == 0 {
 		logger.Info("Runner scale set name is not specified, skipping")
 		return ctrl.Result{}, nil
 	}
@@ -552,7 +766,12 @@ func (r *AutoscalingRunnerSetReconciler) updateRunnerScaleSetName(ctx context.Co
 		return ctrl.Result{}, err
 	}
 
-	updatedRunnerScaleSet, err := actionsClient.UpdateRunnerScaleSet(ctx, runnerScaleSetId, &actions.RunnerScaleSet{Name: autoscalingRunnerSet.Spec.RunnerScaleSetName})
+	if err := validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logger); err != nil {
+		logger.Error(err, "Invalid runner scale set dedicated to Dependabot or code scanning")
+		return ctrl.Result{}, err
+	}
+
+	updatedRunnerScaleSet, err := actionsClient.UpdateRunnerScaleSet(ctx, runnerScaleSetId, &actions.RunnerScaleSet{Name: name})
 	if err != nil {
 		logger.Error(err, "Failed to update runner scale set", "runnerScaleSetId", runnerScaleSetId)
 		return ctrl.Result{}, err
@@ -587,6 +806,19 @@ func (r *AutoscalingRunnerSetReconciler) deleteRunnerScaleSet(ctx context.Contex
 		//    Then, manual deletion of the scale set is required.
 		return nil
 	}
+	if autoscalingRunnerSet.Spec.Federation != nil {
+		// The other members of the federation keep using the runner scale set
+		logger.Info("Leaving the runner scale set of the federation in Actions service", "federation", autoscalingRunnerSet.Spec.Federation.Name)
+		err := patch(ctx, r.Client, autoscalingRunnerSet, func(obj *v1alpha1.AutoscalingRunnerSet) {
+			delete(obj.Annotations, runnerScaleSetIdAnnotationKey)
+		})
+		if err != nil {
+			logger.Error(err, "Failed to patch autoscaling runner set with annotation removed", "annotation", runnerScaleSetIdAnnotationKey)
+			return err
+		}
+		return nil
+	}
+
 	logger.Info("Deleting the runner scale set from Actions service")
 	runnerScaleSetId, err := strconv.Atoi(scaleSetId)
 	if err != nil {
@@ -667,6 +899,58 @@ func (r *AutoscalingRunnerSetReconciler) createAutoScalingListenerForRunnerSet(c
 	return ctrl.Result{}, nil
 }
 
+// reconcileImagePrePull creates, updates, or deletes the DaemonSet pre-pulling the images of the runners,
+// depending on the image pre-pull configuration of the AutoscalingRunnerSet.
+func (r *AutoscalingRunnerSetReconciler) reconcileImagePrePull(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, log logr.Logger) error {
+	daemonSet := new(appsv1.DaemonSet)
+	daemonSetFound := true
+	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingRunnerSet.Namespace, Name: imagePrePullDaemonSetName(autoscalingRunnerSet)}, daemonSet); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get image pre-pull daemonset: %w", err)
+		}
+		daemonSetFound = false
+	}
+
+	if autoscalingRunnerSet.Spec.ImagePrePull == nil {
+		if !daemonSetFound {
+			return nil
+		}
+
+		log.Info("Deleting the image pre-pull daemonset", "name", daemonSet.Name)
+		if err := r.Delete(ctx, daemonSet); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete image pre-pull daemonset: %w", err)
+		}
+		return nil
+	}
+
+	desiredDaemonSet := r.newImagePrePullDaemonSet(autoscalingRunnerSet)
+	if !daemonSetFound {
+		if err := ctrl.SetControllerReference(autoscalingRunnerSet, desiredDaemonSet, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set controller reference on image pre-pull daemonset: %w", err)
+		}
+
+		log.Info("Creating the image pre-pull daemonset", "name", desiredDaemonSet.Name)
+		if err := r.Create(ctx, desiredDaemonSet); err != nil {
+			return fmt.Errorf("failed to create image pre-pull daemonset: %w", err)
+		}
+		return nil
+	}
+
+	// Pull the new images whenever the images of the runner template change
+	if daemonSet.Labels["daemonset-spec-hash"] == desiredDaemonSet.Labels["daemonset-spec-hash"] {
+		return nil
+	}
+
+	log.Info("Updating the image pre-pull daemonset", "name", daemonSet.Name)
+	daemonSet.Labels = desiredDaemonSet.Labels
+	daemonSet.Spec.Template = desiredDaemonSet.Spec.Template
+	if err := r.Update(ctx, daemonSet); err != nil {
+		return fmt.Errorf("failed to update image pre-pull daemonset: %w", err)
+	}
+
+	return nil
+}
+
 func (r *AutoscalingRunnerSetReconciler) listEphemeralRunnerSets(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (*EphemeralRunnerSets, error) {
 	list := new(v1alpha1.EphemeralRunnerSetList)
 	if err := r.List(ctx, list, client.InNamespace(autoscalingRunnerSet.Namespace), client.MatchingFields{resourceOwnerKey: autoscalingRunnerSet.Name}); err != nil {
@@ -676,6 +960,55 @@ func (r *AutoscalingRunnerSetReconciler) listEphemeralRunnerSets(ctx context.Con
 	return &EphemeralRunnerSets{list: list}, nil
 }
 
+// runnerScaleSetName returns the name of the runner scale set in GitHub,
+// defaulting to the label of the jobs the runner scale set is dedicated to.
+// An empty name stands for the name of the AutoscalingRunnerSet.
+func runnerScaleSetName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	if len(autoscalingRunnerSet.Spec.RunnerScaleSetName) > 0 {
+		return autoscalingRunnerSet.Spec.RunnerScaleSetName
+	}
+	return autoscalingRunnerSet.Spec.DedicatedTo
+}
+
+// validateDedicatedTo returns an error if the settings of the runner scale set
+// keep it from running the jobs of Dependabot or code scanning it is dedicated to.
+func validateDedicatedTo(ctx context.Context, actionsClient actions.ActionsService, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet, logger logr.Logger) error {
+	dedicatedTo := autoscalingRunnerSet.Spec.DedicatedTo
+	if len(dedicatedTo) == 0 {
+		return nil
+	}
+
+	if name := autoscalingRunnerSet.Spec.RunnerScaleSetName; len(name) > 0 && !strings.EqualFold(name, dedicatedTo) {
+		return fmt.Errorf("runnerScaleSetName %q must be empty or %q, since the jobs of %s only run on runners labeled %s, see %s", name, dedicatedTo, dedicatedTo, dedicatedTo, dedicatedToDocsURL)
+	}
+
+	githubConfig, err := actions.ParseGitHubConfigFromURL(autoscalingRunnerSet.Spec.GitHubConfigUrl)
+	if err != nil {
+		return fmt.Errorf("failed to parse github config from url: %w", err)
+	}
+	if githubConfig.Scope != actions.GitHubScopeOrganization {
+		return nil
+	}
+
+	runnerGroupName := autoscalingRunnerSet.Spec.RunnerGroup
+	if len(runnerGroupName) == 0 {
+		runnerGroupName = defaultRunnerGroupName
+	}
+
+	// The settings of the runner group are only checked on a best effort basis,
+	// since the credentials of the scale set may not be allowed to read them.
+	runnerGroup, err := actionsClient.GetOrganizationRunnerGroupByName(ctx, runnerGroupName)
+	if err != nil {
+		logger.Info("Failed to get the settings of the runner group, skipping their validation", "runnerGroup", runnerGroupName, "error", err.Error())
+		return nil
+	}
+	if runnerGroup != nil && runnerGroup.RestrictedToWorkflows {
+		return fmt.Errorf("runner group %q is restricted to selected workflows, which excludes the jobs of %s since they don't run a workflow of the repository, see %s", runnerGroupName, dedicatedTo, dedicatedToDocsURL)
+	}
+
+	return nil
+}
+
 func (r *AutoscalingRunnerSetReconciler) actionsClientFor(ctx context.Context, autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (actions.ActionsService, error) {
 	var configSecret corev1.Secret
 	if err := r.Get(ctx, types.NamespacedName{Namespace: autoscalingRunnerSet.Namespace, Name: autoscalingRunnerSet.Spec.GitHubConfigSecret}, &configSecret); err != nil {
@@ -746,6 +1079,8 @@ func (r *AutoscalingRunnerSetReconciler) actionsClientOptionsFor(ctx context.Con
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AutoscalingRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("autoscalingrunnerset-controller")
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.AutoscalingRunnerSet{}).
 		Owns(&v1alpha1.EphemeralRunnerSet{}).
@@ -763,7 +1098,7 @@ func (r *AutoscalingRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) erro
 			},
 		)).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
+		Complete(reconcilemetrics.Observe("autoscalingrunnerset", "AutoscalingRunnerSet", r))
 }
 
 type autoscalingRunnerSetFinalizerDependencyCleaner struct {
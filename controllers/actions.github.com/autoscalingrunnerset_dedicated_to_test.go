@@ -0,0 +1,85 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunnerScaleSetName(t *testing.T) {
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{ObjectMeta: metav1.ObjectMeta{Name: "arc-runner-set"}}
+	assert.Equal(t, "", runnerScaleSetName(autoscalingRunnerSet))
+
+	autoscalingRunnerSet.Spec.DedicatedTo = v1alpha1.DedicatedToDependabot
+	assert.Equal(t, "dependabot", runnerScaleSetName(autoscalingRunnerSet))
+
+	autoscalingRunnerSet.Spec.RunnerScaleSetName = "Dependabot"
+	assert.Equal(t, "Dependabot", runnerScaleSetName(autoscalingRunnerSet))
+}
+
+func TestValidateDedicatedTo(t *testing.T) {
+	ctx := context.Background()
+
+	newAutoscalingRunnerSet := func(configURL string) *v1alpha1.AutoscalingRunnerSet {
+		return &v1alpha1.AutoscalingRunnerSet{
+			Spec: v1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl: configURL,
+				DedicatedTo:     v1alpha1.DedicatedToCodeScanning,
+			},
+		}
+	}
+
+	t.Run("not dedicated", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org")
+		autoscalingRunnerSet.Spec.DedicatedTo = ""
+		assert.NoError(t, validateDedicatedTo(ctx, actions.NewMockActionsService(t), autoscalingRunnerSet, logr.Discard()))
+	})
+
+	t.Run("conflicting runner scale set name", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org")
+		autoscalingRunnerSet.Spec.RunnerScaleSetName = "arc-runner-set"
+		err := validateDedicatedTo(ctx, actions.NewMockActionsService(t), autoscalingRunnerSet, logr.Discard())
+		assert.ErrorContains(t, err, `runnerScaleSetName "arc-runner-set" must be empty or "code-scanning"`)
+	})
+
+	t.Run("runner group restricted to selected workflows", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org")
+		autoscalingRunnerSet.Spec.RunnerGroup = "security"
+
+		actionsClient := actions.NewMockActionsService(t)
+		actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "security").Return(&actions.OrganizationRunnerGroup{ID: 2, Name: "security", RestrictedToWorkflows: true}, nil)
+
+		err := validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logr.Discard())
+		assert.ErrorContains(t, err, `runner group "security" is restricted to selected workflows`)
+	})
+
+	t.Run("default runner group", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org")
+
+		actionsClient := actions.NewMockActionsService(t)
+		actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "Default").Return(&actions.OrganizationRunnerGroup{ID: 1, Name: "Default", Visibility: "all"}, nil)
+
+		assert.NoError(t, validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logr.Discard()))
+	})
+
+	t.Run("runner group settings can't be read", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org")
+
+		actionsClient := actions.NewMockActionsService(t)
+		actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "Default").Return(nil, errors.New("forbidden"))
+
+		assert.NoError(t, validateDedicatedTo(ctx, actionsClient, autoscalingRunnerSet, logr.Discard()))
+	})
+
+	t.Run("repository scope", func(t *testing.T) {
+		autoscalingRunnerSet := newAutoscalingRunnerSet("https://github.com/my-org/my-repo")
+		assert.NoError(t, validateDedicatedTo(ctx, actions.NewMockActionsService(t), autoscalingRunnerSet, logr.Discard()))
+	})
+}
@@ -0,0 +1,38 @@
+package actionsgithubcom
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoscalingRunnerSetListenerSessionCondition(t *testing.T) {
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{}
+
+	conditions := autoscalingRunnerSetConditions(autoscalingRunnerSet, false, "")
+	assert.Nil(t, meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeListenerSessionHealthy), "no condition until the listener reports the session")
+
+	conditions = autoscalingRunnerSetConditions(autoscalingRunnerSet, false, `{"healthy":false,"transport":"short-poll","reconnects":2,"message":"connection reset by peer"}`)
+	condition := meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeListenerSessionHealthy)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, v1alpha1.ConditionReasonSessionReconnecting, condition.Reason)
+	assert.Equal(t, "Reconnect 2 with short-poll: connection reset by peer", condition.Message)
+
+	autoscalingRunnerSet.Status.Conditions = conditions
+
+	conditions = autoscalingRunnerSetConditions(autoscalingRunnerSet, false, `{"healthy":true,"transport":"short-poll"}`)
+	condition = meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeListenerSessionHealthy)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, v1alpha1.ConditionReasonSessionHealthy, condition.Reason)
+
+	autoscalingRunnerSet.Status.Conditions = conditions
+
+	conditions = autoscalingRunnerSetConditions(autoscalingRunnerSet, false, "not json")
+	assert.Equal(t, metav1.ConditionTrue, meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeListenerSessionHealthy).Status, "a malformed state is ignored")
+}
@@ -0,0 +1,55 @@
+package actionsgithubcom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoscalingRunnerSetPause(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{}
+
+	paused, until, err := autoscalingRunnerSetPause(now, autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.False(t, paused)
+	assert.Nil(t, until)
+
+	autoscalingRunnerSet.Annotations = map[string]string{AnnotationKeyPausedUntil: "2026-10-17T12:30:00Z"}
+
+	paused, until, err = autoscalingRunnerSetPause(now, autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.True(t, paused)
+	assert.Equal(t, now.Add(30*time.Minute), *until)
+
+	paused, until, err = autoscalingRunnerSetPause(now.Add(time.Hour), autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.False(t, paused, "the expired annotation is ignored")
+	assert.Nil(t, until)
+
+	autoscalingRunnerSet.Spec.Paused = true
+
+	paused, until, err = autoscalingRunnerSetPause(now, autoscalingRunnerSet)
+	require.NoError(t, err)
+	assert.True(t, paused)
+	assert.Nil(t, until, "spec.paused outlives the annotation")
+
+	autoscalingRunnerSet.Spec.Paused = false
+	autoscalingRunnerSet.Annotations[AnnotationKeyPausedUntil] = "tomorrow"
+
+	paused, _, err = autoscalingRunnerSetPause(now, autoscalingRunnerSet)
+	assert.Error(t, err)
+	assert.False(t, paused)
+}
+
+func TestAutoscalingRunnerSetPauseKeepsListener(t *testing.T) {
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{}
+	hash := autoscalingRunnerSet.ListenerSpecHash()
+
+	autoscalingRunnerSet.Spec.Paused = true
+	assert.Equal(t, hash, autoscalingRunnerSet.ListenerSpecHash())
+}
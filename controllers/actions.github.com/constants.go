@@ -34,6 +34,10 @@ const (
 	LabelKeyGitHubEnterprise        = "actions.github.com/enterprise"
 	LabelKeyGitHubOrganization      = "actions.github.com/organization"
 	LabelKeyGitHubRepository        = "actions.github.com/repository"
+
+	// LabelKeyCapacityType is the label on the ephemeral runners and their pods that tells which capacity type,
+	// either spot or on-demand, spec.capacityDistribution put the runner on.
+	LabelKeyCapacityType = "actions.github.com/capacity-type"
 )
 
 // Finalizer used to protect resources from deletion while AutoscalingRunnerSet is running
@@ -43,6 +47,18 @@ const (
 	AnnotationKeyGitHubRunnerGroupName    = "actions.github.com/runner-group-name"
 	AnnotationKeyGitHubRunnerScaleSetName = "actions.github.com/runner-scale-set-name"
 	AnnotationKeyPatchID                  = "actions.github.com/patch-id"
+
+	// AnnotationKeyPausedUntil is the annotation on an AutoscalingRunnerSet that pauses its scaling until the RFC3339 time,
+	// regardless of spec.paused. The annotation is ignored once the time has passed.
+	AnnotationKeyPausedUntil = "actions.github.com/paused-until"
+
+	// AnnotationKeySuspendOnDrift is the annotation on an AutoscalingRunnerSet that lists the comma-separated field managers of the GitOps tools,
+	// like "argocd-controller". The runner scale set is not reconciled while its spec has been changed by any other field manager.
+	AnnotationKeySuspendOnDrift = "actions.github.com/suspend-on-drift"
+
+	// AnnotationKeyListenerSession is the annotation the listener records the health of its message session in, onto its EphemeralRunnerSet,
+	// as the JSON of the healthy flag, the transport, the number of the reconnects, and the error that made it reconnect.
+	AnnotationKeyListenerSession = "actions.github.com/listener-session"
 )
 
 // Labels applied to listener roles
@@ -25,6 +25,9 @@ import (
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -48,6 +51,9 @@ type EphemeralRunnerReconciler struct {
 	Scheme        *runtime.Scheme
 	ActionsClient actions.MultiClient
 	ResourceBuilder
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunners,verbs=get;list;watch;create;update;patch;delete
@@ -64,12 +70,17 @@ type EphemeralRunnerReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.6.4/pkg/reconcile
 func (r *EphemeralRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("ephemeralrunner", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "EphemeralRunner", req.NamespacedName)
 
 	ephemeralRunner := new(v1alpha1.EphemeralRunner)
 	if err := r.Get(ctx, req.NamespacedName, ephemeralRunner); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(ephemeralRunner.Namespace, sharding.OwnerOfGitHubConfigURL(ephemeralRunner.Spec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !ephemeralRunner.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(ephemeralRunner, ephemeralRunnerFinalizerName) {
 			return ctrl.Result{}, nil
@@ -701,8 +712,9 @@ func (r *EphemeralRunnerReconciler) updateRunStatusFromPod(ctx context.Context,
 
 	phaseChanged := ephemeralRunner.Status.Phase != pod.Status.Phase
 	readyChanged := ready != ephemeralRunner.Status.Ready
+	generationChanged := ephemeralRunner.Status.ObservedGeneration != ephemeralRunner.Generation
 
-	if !phaseChanged && !readyChanged {
+	if !phaseChanged && !readyChanged && !generationChanged {
 		return nil
 	}
 
@@ -718,6 +730,7 @@ func (r *EphemeralRunnerReconciler) updateRunStatusFromPod(ctx context.Context,
 		obj.Status.Ready = ready
 		obj.Status.Reason = pod.Status.Reason
 		obj.Status.Message = pod.Status.Message
+		obj.Status.ObservedGeneration = obj.Generation
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update runner status for Phase/Reason/Message/Ready: %w", err)
@@ -847,7 +860,7 @@ func (r *EphemeralRunnerReconciler) SetupWithManager(mgr ctrl.Manager, opts ...O
 			Owns(&corev1.Pod{}).
 			WithEventFilter(predicate.ResourceVersionChangedPredicate{}),
 		opts,
-	).Complete(r)
+	).Complete(reconcilemetrics.Observe("ephemeralrunner", "EphemeralRunner", r))
 }
 
 func runnerContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
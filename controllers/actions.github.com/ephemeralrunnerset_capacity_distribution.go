@@ -0,0 +1,180 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/pkg/capacityplacement"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newCapacityPlacement returns the placement of the ephemeral runners of the EphemeralRunnerSet,
+// which counts the pending and running ephemeral runners already on each capacity type.
+func newCapacityPlacement(ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, state *ephemeralRunnerState, replicas int, now time.Time) *capacityplacement.Placement {
+	d := ephemeralRunnerSet.Spec.CapacityDistribution
+
+	var spotUnavailableSince *metav1.Time
+	if s := ephemeralRunnerSet.Status.CapacityDistribution; s != nil {
+		spotUnavailableSince = s.SpotUnavailableSince
+	}
+
+	p := capacityplacement.New(capacityplacement.Distribution{
+		SpotFirst:         d.Policy == v1alpha1.CapacityDistributionPolicySpotFirst,
+		SpotPercentage:    d.SpotPercentage,
+		FallbackAfter:     d.FallbackAfter,
+		SpotRetryInterval: d.SpotRetryInterval,
+	}, replicas, spotUnavailableSince, now)
+
+	for _, runners := range [][]*v1alpha1.EphemeralRunner{state.pending, state.running} {
+		for _, r := range runners {
+			p.Add(r.Labels[LabelKeyCapacityType], 1)
+		}
+	}
+
+	return p
+}
+
+func capacityDistributionStatus(p *capacityplacement.Placement) *v1alpha1.CapacityDistributionStatus {
+	return &v1alpha1.CapacityDistributionStatus{
+		Spot:                 p.Spot,
+		OnDemand:             p.OnDemand,
+		SpotUnavailableSince: p.SpotUnavailableSince,
+	}
+}
+
+// applyCapacityType labels the ephemeral runner with the capacity type,
+// and adds the node selector and the tolerations of the capacity type to the pod template of the runner.
+func applyCapacityType(ephemeralRunner *v1alpha1.EphemeralRunner, d *v1alpha1.CapacityDistribution, capacityType string) {
+	subset := d.OnDemand
+	if capacityType == v1alpha1.CapacityTypeSpot {
+		subset = d.Spot
+	}
+
+	if ephemeralRunner.Labels == nil {
+		ephemeralRunner.Labels = map[string]string{}
+	}
+	ephemeralRunner.Labels[LabelKeyCapacityType] = capacityType
+
+	spec := &ephemeralRunner.Spec.PodTemplateSpec.Spec
+
+	if len(subset.NodeSelector) > 0 {
+		nodeSelector := make(map[string]string, len(spec.NodeSelector)+len(subset.NodeSelector))
+		for k, v := range spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		for k, v := range subset.NodeSelector {
+			nodeSelector[k] = v
+		}
+		spec.NodeSelector = nodeSelector
+	}
+
+	spec.Tolerations = append(append([]corev1.Toleration{}, spec.Tolerations...), subset.Tolerations...)
+}
+
+// fallBackToOnDemand replaces the pending spot runners of the SpotFirst policy whose pods have been unschedulable for longer than fallbackAfter
+// with on-demand runners. It returns true once it marked the spot capacity unavailable.
+func (r *EphemeralRunnerSetReconciler) fallBackToOnDemand(ctx context.Context, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, p *capacityplacement.Placement, pending []*v1alpha1.EphemeralRunner, now time.Time, log logr.Logger) (bool, error) {
+	if !p.FallsBack() {
+		return false, nil
+	}
+
+	var unschedulable []*v1alpha1.EphemeralRunner
+
+	for _, ephemeralRunner := range pending {
+		if ephemeralRunner.Labels[LabelKeyCapacityType] != v1alpha1.CapacityTypeSpot {
+			continue
+		}
+
+		pod := new(corev1.Pod)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ephemeralRunner.Namespace, Name: ephemeralRunner.Name}, pod); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		if p.Unschedulable(pod, now) {
+			unschedulable = append(unschedulable, ephemeralRunner)
+		}
+	}
+
+	if len(unschedulable) == 0 {
+		return false, nil
+	}
+
+	log.Info("Falling back to the on-demand capacity as the spot runners are unschedulable",
+		"count", len(unschedulable), "fallbackAfter", p.FallbackAfter(), "spotRetryInterval", p.SpotRetryInterval())
+
+	p.FallBack(now)
+
+	return true, r.replaceEphemeralRunners(ctx, ephemeralRunnerSet, p, unschedulable, log)
+}
+
+// rebalanceCapacity replaces one idle runner that is on the surplus capacity type, or has no capacity type yet,
+// with a runner on the capacity type that has fewer runners than the target.
+// The runners are replaced one at a time, only while no runners are pending.
+func (r *EphemeralRunnerSetReconciler) rebalanceCapacity(ctx context.Context, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, p *capacityplacement.Placement, running []*v1alpha1.EphemeralRunner, log logr.Logger) error {
+	deficit := p.Deficit()
+	if deficit == "" {
+		return nil
+	}
+
+	for _, ephemeralRunner := range running {
+		if ephemeralRunner.Labels[LabelKeyCapacityType] == deficit {
+			continue
+		}
+
+		if ephemeralRunner.Status.RunnerId == 0 || ephemeralRunner.Status.JobRequestId > 0 {
+			continue
+		}
+
+		log.Info("Replacing the idle ephemeral runner to rebalance the capacity types",
+			"name", ephemeralRunner.Name, "capacityType", ephemeralRunner.Labels[LabelKeyCapacityType], "deficit", deficit)
+
+		return r.replaceEphemeralRunners(ctx, ephemeralRunnerSet, p, []*v1alpha1.EphemeralRunner{ephemeralRunner}, log)
+	}
+
+	return nil
+}
+
+// replaceEphemeralRunners deletes the ephemeral runners and creates one runner for each of the deleted ones,
+// placed by the capacity placement. The runners that turned out to be running jobs are kept.
+func (r *EphemeralRunnerSetReconciler) replaceEphemeralRunners(ctx context.Context, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, p *capacityplacement.Placement, ephemeralRunners []*v1alpha1.EphemeralRunner, log logr.Logger) error {
+	var (
+		actionsClient actions.ActionsService
+		replaced      int
+	)
+
+	for _, ephemeralRunner := range ephemeralRunners {
+		var ok bool
+		var err error
+
+		if ephemeralRunner.Status.RunnerId == 0 {
+			ok, err = r.deleteUnregisteredEphemeralRunner(ctx, ephemeralRunner, log)
+		} else {
+			if actionsClient == nil {
+				if actionsClient, err = r.actionsClientFor(ctx, ephemeralRunnerSet); err != nil {
+					return err
+				}
+			}
+			ok, err = r.deleteEphemeralRunnerWithActionsClient(ctx, ephemeralRunner, actionsClient, log)
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		p.Add(ephemeralRunner.Labels[LabelKeyCapacityType], -1)
+		replaced++
+	}
+
+	return r.createEphemeralRunners(ctx, ephemeralRunnerSet, replaced, p, log)
+}
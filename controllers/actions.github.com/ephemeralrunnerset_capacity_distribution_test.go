@@ -0,0 +1,156 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCapacityPlacement(t *testing.T) {
+	now := time.Now()
+
+	newRunner := func(capacityType string) *v1alpha1.EphemeralRunner {
+		r := &v1alpha1.EphemeralRunner{}
+		if capacityType != "" {
+			r.Labels = map[string]string{LabelKeyCapacityType: capacityType}
+		}
+		return r
+	}
+
+	weighted := &v1alpha1.EphemeralRunnerSet{
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{SpotPercentage: 80},
+		},
+	}
+
+	p := newCapacityPlacement(weighted, &ephemeralRunnerState{}, 5, now)
+
+	var capacityTypes []string
+	for i := 0; i < 5; i++ {
+		capacityTypes = append(capacityTypes, p.Next())
+	}
+	assert.Equal(t, []string{"spot", "spot", "spot", "spot", "on-demand"}, capacityTypes)
+	assert.Empty(t, p.Deficit())
+
+	// The runners without the capacity type label are replaced to fill the deficit
+	state := &ephemeralRunnerState{
+		pending: []*v1alpha1.EphemeralRunner{newRunner("spot")},
+		running: []*v1alpha1.EphemeralRunner{newRunner("on-demand"), newRunner("")},
+	}
+	p = newCapacityPlacement(weighted, state, 3, now)
+	assert.Equal(t, v1alpha1.CapacityTypeSpot, p.Deficit())
+
+	spotFirst := &v1alpha1.EphemeralRunnerSet{
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{Policy: v1alpha1.CapacityDistributionPolicySpotFirst},
+		},
+		Status: v1alpha1.EphemeralRunnerSetStatus{
+			CapacityDistribution: &v1alpha1.CapacityDistributionStatus{
+				SpotUnavailableSince: &metav1.Time{Time: now.Add(-10 * time.Minute)},
+			},
+		},
+	}
+
+	state = &ephemeralRunnerState{running: []*v1alpha1.EphemeralRunner{newRunner("on-demand")}}
+
+	p = newCapacityPlacement(spotFirst, state, 2, now)
+	assert.Equal(t, v1alpha1.CapacityTypeOnDemand, p.Next())
+	assert.Empty(t, p.Deficit(), "the spot capacity is unavailable")
+
+	// The spot capacity is retried after the retry interval, moving the on-demand runners back
+	p = newCapacityPlacement(spotFirst, state, 2, now.Add(6*time.Minute))
+	assert.Nil(t, p.SpotUnavailableSince)
+	assert.Equal(t, v1alpha1.CapacityTypeSpot, p.Next())
+	assert.Equal(t, v1alpha1.CapacityTypeSpot, p.Deficit())
+}
+
+func TestEphemeralRunnerSetFallBackToOnDemand(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "EphemeralRunnerSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "arc", Namespace: "default", UID: "uid"},
+		Spec: v1alpha1.EphemeralRunnerSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{
+				Policy:   v1alpha1.CapacityDistributionPolicySpotFirst,
+				Spot:     v1alpha1.CapacitySubset{NodeSelector: map[string]string{"karpenter.sh/capacity-type": "spot"}},
+				OnDemand: v1alpha1.CapacitySubset{NodeSelector: map[string]string{"karpenter.sh/capacity-type": "on-demand"}},
+			},
+		},
+	}
+
+	newRunner := func(name string) *v1alpha1.EphemeralRunner {
+		return &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{LabelKeyCapacityType: v1alpha1.CapacityTypeSpot},
+			},
+		}
+	}
+
+	newPod := func(name string, unschedulableFor time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					Reason:             corev1.PodReasonUnschedulable,
+					LastTransitionTime: metav1.Time{Time: now.Add(-unschedulableFor)},
+				}},
+			},
+		}
+	}
+
+	pending := []*v1alpha1.EphemeralRunner{newRunner("pending"), newRunner("unschedulable")}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(
+		ephemeralRunnerSet,
+		pending[0], newPod("pending", time.Minute),
+		pending[1], newPod("unschedulable", 5*time.Minute),
+	).Build()
+
+	r := &EphemeralRunnerSetReconciler{Client: c, Scheme: sc}
+
+	p := newCapacityPlacement(ephemeralRunnerSet, &ephemeralRunnerState{pending: pending}, 2, now)
+
+	fellBack, err := r.fallBackToOnDemand(ctx, ephemeralRunnerSet, p, pending, now, logr.Discard())
+	require.NoError(t, err)
+	assert.True(t, fellBack)
+	assert.Equal(t, now, p.SpotUnavailableSince.Time)
+
+	var runners v1alpha1.EphemeralRunnerList
+	require.NoError(t, c.List(ctx, &runners, client.InNamespace("default")))
+	require.Len(t, runners.Items, 2)
+
+	capacityTypes := map[string]string{}
+	for _, runner := range runners.Items {
+		capacityTypes[runner.Labels[LabelKeyCapacityType]] = runner.Name
+		if runner.Labels[LabelKeyCapacityType] == v1alpha1.CapacityTypeOnDemand {
+			assert.Equal(t, map[string]string{"karpenter.sh/capacity-type": "on-demand"}, runner.Spec.PodTemplateSpec.Spec.NodeSelector)
+		}
+	}
+
+	assert.Equal(t, "pending", capacityTypes[v1alpha1.CapacityTypeSpot], "the runner pending for less than fallbackAfter is kept")
+	assert.Contains(t, capacityTypes, v1alpha1.CapacityTypeOnDemand, "the unschedulable runner is replaced on the on-demand capacity")
+	assert.Equal(t, 1, p.Spot)
+	assert.Equal(t, 1, p.OnDemand)
+}
@@ -23,13 +23,19 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/capacityplacement"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 	"github.com/go-logr/logr"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -54,6 +60,9 @@ type EphemeralRunnerSetReconciler struct {
 	PublishMetrics bool
 
 	ResourceBuilder
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 // +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunnersets,verbs=get;list;watch;create;update;patch;delete
@@ -76,12 +85,17 @@ type EphemeralRunnerSetReconciler struct {
 // until it is safe to do so
 func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("ephemeralrunnerset", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "EphemeralRunnerSet", req.NamespacedName)
 
 	ephemeralRunnerSet := new(v1alpha1.EphemeralRunnerSet)
 	if err := r.Get(ctx, req.NamespacedName, ephemeralRunnerSet); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(ephemeralRunnerSet.Namespace, sharding.OwnerOfGitHubConfigURL(ephemeralRunnerSet.Spec.EphemeralRunnerSpec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
 	// Requested deletion does not need reconciled.
 	if !ephemeralRunnerSet.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(ephemeralRunnerSet, ephemeralRunnerSetFinalizerName) {
@@ -190,29 +204,79 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 	}
 
 	total := ephemeralRunnerState.scaleTotal()
-	if ephemeralRunnerSet.Spec.PatchID == 0 || ephemeralRunnerSet.Spec.PatchID != ephemeralRunnerState.latestPatchID {
+	desiredReplicas := ephemeralRunnerState.desiredReplicas(&ephemeralRunnerSet.Spec)
+
+	// While paused, the number of the runners when paused is kept regardless of the replicas patched by the listener
+	var pausedReplicas *int
+	if ephemeralRunnerSet.Spec.Paused {
+		pausedReplicas = ephemeralRunnerSet.Status.PausedReplicas
+		if pausedReplicas == nil {
+			pausedReplicas = &total
+		}
+		desiredReplicas = *pausedReplicas
+	}
+
+	var capacity *capacityplacement.Placement
+	var capacityStatus *v1alpha1.CapacityDistributionStatus
+
+	if ephemeralRunnerSet.Spec.CapacityDistribution != nil {
+		now := time.Now()
+
+		capacity = newCapacityPlacement(ephemeralRunnerSet, ephemeralRunnerState, desiredReplicas, now)
+		capacityStatus = capacityDistributionStatus(capacity)
+
+		fellBack, err := r.fallBackToOnDemand(ctx, ephemeralRunnerSet, capacity, ephemeralRunnerState.pending, now, log)
+		if err != nil {
+			log.Error(err, "Failed to fall back to the on-demand capacity")
+			return ctrl.Result{}, err
+		}
+
+		if fellBack {
+			// Remember when the spot capacity became unavailable, so that the runners created afterwards go to the on-demand capacity
+			if err := patchSubResource(ctx, r.Status(), ephemeralRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
+				obj.Status.CapacityDistribution = &v1alpha1.CapacityDistributionStatus{
+					Spot:                 capacityStatus.Spot,
+					OnDemand:             capacityStatus.OnDemand,
+					SpotUnavailableSince: capacity.SpotUnavailableSince,
+				}
+			}); err != nil {
+				log.Error(err, "Failed to update status with the unavailable spot capacity")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+	}
+	// The warm pool is refilled as soon as its runners pick up jobs, without waiting for the next patch of the listener
+	refillWarmPool := desiredReplicas > ephemeralRunnerSet.Spec.Replicas && total < desiredReplicas
+	scale := ephemeralRunnerSet.Spec.PatchID == 0 || ephemeralRunnerSet.Spec.PatchID != ephemeralRunnerState.latestPatchID || refillWarmPool
+	if ephemeralRunnerSet.Spec.Paused {
+		// The runners finishing jobs are replaced, but no runners are deleted for scaling down
+		scale = total < desiredReplicas || len(ephemeralRunnerState.finished) > 0
+	}
+	if scale {
 		defer func() {
 			if err := r.cleanupFinishedEphemeralRunners(ctx, ephemeralRunnerState.finished, log); err != nil {
 				log.Error(err, "failed to cleanup finished ephemeral runners")
 			}
 		}()
-		log.Info("Scaling comparison", "current", total, "desired", ephemeralRunnerSet.Spec.Replicas)
+		log.Info("Scaling comparison", "current", total, "desired", desiredReplicas, "replicas", ephemeralRunnerSet.Spec.Replicas)
 		switch {
-		case total < ephemeralRunnerSet.Spec.Replicas: // Handle scale up
-			count := ephemeralRunnerSet.Spec.Replicas - total
+		case total < desiredReplicas: // Handle scale up
+			count := desiredReplicas - total
 			log.Info("Creating new ephemeral runners (scale up)", "count", count)
-			if err := r.createEphemeralRunners(ctx, ephemeralRunnerSet, count, log); err != nil {
+			if err := r.createEphemeralRunners(ctx, ephemeralRunnerSet, count, capacity, log); err != nil {
 				log.Error(err, "failed to make ephemeral runner")
 				return ctrl.Result{}, err
 			}
 
-		case ephemeralRunnerSet.Spec.PatchID > 0 && total >= ephemeralRunnerSet.Spec.Replicas: // Handle scale down scenario.
+		case ephemeralRunnerSet.Spec.PatchID > 0 && total >= desiredReplicas: // Handle scale down scenario.
 			// If ephemeral runner did not yet update the phase to succeeded, but the scale down
 			// request is issued, we should ignore the scale down request.
 			// Eventually, the ephemeral runner will be cleaned up on the next patch request, which happens
 			// on the next batch
-		case ephemeralRunnerSet.Spec.PatchID == 0 && total > ephemeralRunnerSet.Spec.Replicas:
-			count := total - ephemeralRunnerSet.Spec.Replicas
+		case ephemeralRunnerSet.Spec.PatchID == 0 && total > desiredReplicas && !ephemeralRunnerSet.Spec.Paused:
+			count := total - desiredReplicas
 			log.Info("Deleting ephemeral runners (scale down)", "count", count)
 			if err := r.deleteIdleEphemeralRunners(
 				ctx,
@@ -228,15 +292,36 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 		}
 	}
 
+	var result ctrl.Result
+
+	if capacity != nil {
+		if len(ephemeralRunnerState.pending) == 0 && total == desiredReplicas {
+			if err := r.rebalanceCapacity(ctx, ephemeralRunnerSet, capacity, ephemeralRunnerState.running, log); err != nil {
+				log.Error(err, "Failed to rebalance the capacity types")
+				return ctrl.Result{}, err
+			}
+		}
+
+		// The pods pending on the spot capacity are checked for the fallback, and the spot capacity is retried after the interval
+		if since := capacity.SpotUnavailableSince; since != nil {
+			result.RequeueAfter = time.Until(since.Add(capacity.SpotRetryInterval()))
+		} else if capacity.SpotFirst() && len(ephemeralRunnerState.pending) > 0 {
+			result.RequeueAfter = capacity.FallbackAfter()
+		}
+	}
+
 	desiredStatus := v1alpha1.EphemeralRunnerSetStatus{
 		CurrentReplicas:         total,
 		PendingEphemeralRunners: len(ephemeralRunnerState.pending),
 		RunningEphemeralRunners: len(ephemeralRunnerState.running),
 		FailedEphemeralRunners:  len(ephemeralRunnerState.failed),
+		CapacityDistribution:    capacityStatus,
+		PausedReplicas:          pausedReplicas,
+		ObservedGeneration:      ephemeralRunnerSet.Generation,
 	}
 
 	// Update the status if needed.
-	if ephemeralRunnerSet.Status != desiredStatus {
+	if !equality.Semantic.DeepEqual(ephemeralRunnerSet.Status, desiredStatus) {
 		log.Info("Updating status with current runners count", "count", total)
 		if err := patchSubResource(ctx, r.Status(), ephemeralRunnerSet, func(obj *v1alpha1.EphemeralRunnerSet) {
 			obj.Status = desiredStatus
@@ -246,7 +331,7 @@ func (r *EphemeralRunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.R
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func (r *EphemeralRunnerSetReconciler) cleanupFinishedEphemeralRunners(ctx context.Context, finishedEphemeralRunners []*v1alpha1.EphemeralRunner, log logr.Logger) error {
@@ -356,7 +441,8 @@ func (r *EphemeralRunnerSetReconciler) cleanUpEphemeralRunners(ctx context.Conte
 }
 
 // createEphemeralRunners provisions `count` number of v1alpha1.EphemeralRunner resources in the cluster.
-func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Context, runnerSet *v1alpha1.EphemeralRunnerSet, count int, log logr.Logger) error {
+// The runners are put on the capacity types decided by the capacity placement, if any.
+func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Context, runnerSet *v1alpha1.EphemeralRunnerSet, count int, capacity *capacityplacement.Placement, log logr.Logger) error {
 	// Track multiple errors at once and return the bundle.
 	errs := make([]error, 0)
 	for i := 0; i < count; i++ {
@@ -364,6 +450,9 @@ func (r *EphemeralRunnerSetReconciler) createEphemeralRunners(ctx context.Contex
 		if runnerSet.Spec.EphemeralRunnerSpec.Proxy != nil {
 			ephemeralRunner.Spec.ProxySecretRef = proxyEphemeralRunnerSetSecretName(runnerSet)
 		}
+		if capacity != nil {
+			applyCapacityType(ephemeralRunner, runnerSet.Spec.CapacityDistribution, capacity.Next())
+		}
 
 		// Make sure that we own the resource we create.
 		if err := ctrl.SetControllerReference(runnerSet, ephemeralRunner, r.Scheme); err != nil {
@@ -424,19 +513,20 @@ func (r *EphemeralRunnerSetReconciler) createProxySecret(ctx context.Context, ep
 }
 
 // deleteIdleEphemeralRunners try to deletes `count` number of v1alpha1.EphemeralRunner resources in the cluster.
-// It will only delete `v1alpha1.EphemeralRunner` that has registered with Actions service
-// which has a `v1alpha1.EphemeralRunner.Status.RunnerId` set.
+// The victims are selected by the scale down policy of the EphemeralRunnerSet, and the runners running jobs are never deleted.
+// The ephemeral runners that have registered with Actions service, which has a `v1alpha1.EphemeralRunner.Status.RunnerId` set,
+// are removed from the service before they are deleted.
 // So, it is possible that this function will not delete enough ephemeral runners
-// if there are not enough ephemeral runners that have registered with Actions service.
+// if there are not enough idle ephemeral runners.
 // When this happens, the next reconcile loop will try to delete the remaining ephemeral runners
 // after we get notified by any of the `v1alpha1.EphemeralRunner.Status` updates.
 func (r *EphemeralRunnerSetReconciler) deleteIdleEphemeralRunners(ctx context.Context, ephemeralRunnerSet *v1alpha1.EphemeralRunnerSet, pendingEphemeralRunners, runningEphemeralRunners []*v1alpha1.EphemeralRunner, count int, log logr.Logger) error {
 	if count <= 0 {
 		return nil
 	}
-	runners := newEphemeralRunnerStepper(pendingEphemeralRunners, runningEphemeralRunners)
-	if runners.len() == 0 {
-		log.Info("No pending or running ephemeral runners running at this time for scale down")
+	candidates := scaleDownCandidates(ephemeralRunnerSet.Spec.ScaleDownPolicy, pendingEphemeralRunners, runningEphemeralRunners, time.Now(), log)
+	if len(candidates) == 0 {
+		log.Info("No idle ephemeral runners at this time for scale down")
 		return nil
 	}
 	actionsClient, err := r.actionsClientFor(ctx, ephemeralRunnerSet)
@@ -445,21 +535,15 @@ func (r *EphemeralRunnerSetReconciler) deleteIdleEphemeralRunners(ctx context.Co
 	}
 	var errs []error
 	deletedCount := 0
-	for runners.next() {
-		ephemeralRunner := runners.object()
-		isDone := ephemeralRunner.IsDone()
-		if !isDone && ephemeralRunner.Status.RunnerId == 0 {
-			log.Info("Skipping ephemeral runner since it is not registered yet", "name", ephemeralRunner.Name)
-			continue
+	for _, ephemeralRunner := range candidates {
+		var ok bool
+		if ephemeralRunner.Status.RunnerId == 0 {
+			log.Info("Removing the ephemeral runner not registered yet", "name", ephemeralRunner.Name)
+			ok, err = r.deleteUnregisteredEphemeralRunner(ctx, ephemeralRunner, log)
+		} else {
+			log.Info("Removing the idle ephemeral runner", "name", ephemeralRunner.Name)
+			ok, err = r.deleteEphemeralRunnerWithActionsClient(ctx, ephemeralRunner, actionsClient, log)
 		}
-
-		if !isDone && ephemeralRunner.Status.JobRequestId > 0 {
-			log.Info("Skipping ephemeral runner since it is running a job", "name", ephemeralRunner.Name, "jobRequestId", ephemeralRunner.Status.JobRequestId)
-			continue
-		}
-
-		log.Info("Removing the idle ephemeral runner", "name", ephemeralRunner.Name)
-		ok, err := r.deleteEphemeralRunnerWithActionsClient(ctx, ephemeralRunner, actionsClient, log)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -476,6 +560,17 @@ func (r *EphemeralRunnerSetReconciler) deleteIdleEphemeralRunners(ctx context.Co
 	return multierr.Combine(errs...)
 }
 
+// deleteUnregisteredEphemeralRunner deletes the ephemeral runner that has no runner in the service yet.
+// The finalizer of the ephemeral runner removes the runner from the service in case it got registered in the meantime.
+func (r *EphemeralRunnerSetReconciler) deleteUnregisteredEphemeralRunner(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, log logr.Logger) (bool, error) {
+	if err := r.Delete(ctx, ephemeralRunner); err != nil && !kerrors.IsNotFound(err) {
+		return false, err
+	}
+
+	log.Info("Deleted ephemeral runner not registered yet", "name", ephemeralRunner.Name)
+	return true, nil
+}
+
 func (r *EphemeralRunnerSetReconciler) deleteEphemeralRunnerWithActionsClient(ctx context.Context, ephemeralRunner *v1alpha1.EphemeralRunner, actionsClient actions.ActionsService, log logr.Logger) (bool, error) {
 	if err := actionsClient.RemoveRunner(ctx, int64(ephemeralRunner.Status.RunnerId)); err != nil {
 		actionsError := &actions.ActionsError{}
@@ -575,51 +670,68 @@ func (r *EphemeralRunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) error
 		For(&v1alpha1.EphemeralRunnerSet{}).
 		Owns(&v1alpha1.EphemeralRunner{}).
 		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
-}
-
-type ephemeralRunnerStepper struct {
-	items []*v1alpha1.EphemeralRunner
-	index int
+		Complete(reconcilemetrics.Observe("ephemeralrunnerset", "EphemeralRunnerSet", r))
 }
 
-func newEphemeralRunnerStepper(primary []*v1alpha1.EphemeralRunner, othersOrdered ...[]*v1alpha1.EphemeralRunner) *ephemeralRunnerStepper {
-	sort.Slice(primary, func(i, j int) bool {
-		return primary[i].GetCreationTimestamp().Time.Before(primary[j].GetCreationTimestamp().Time)
-	})
-	for _, bucket := range othersOrdered {
-		sort.Slice(bucket, func(i, j int) bool {
-			return bucket[i].GetCreationTimestamp().Time.Before(bucket[j].GetCreationTimestamp().Time)
-		})
-	}
-
-	for _, bucket := range othersOrdered {
-		primary = append(primary, bucket...)
-	}
+// scaleDownCandidates returns the ephemeral runners that can be deleted on scale down, in the order they should be deleted.
+// The runners not yet registered with the service are kept unless the policy deletes them first,
+// followed by the registered pending runners and the idle running runners, each ordered by age as the policy says.
+// The runners running jobs, and the registered idle runners younger than the minimum age of the policy, are never returned.
+func scaleDownCandidates(policy *v1alpha1.ScaleDownPolicy, pending, running []*v1alpha1.EphemeralRunner, now time.Time, log logr.Logger) []*v1alpha1.EphemeralRunner {
+	var (
+		keepUnregistered = true
+		newestFirst      bool
+		minIdleAge       time.Duration
+	)
 
-	return &ephemeralRunnerStepper{
-		items: primary,
-		index: -1,
+	if policy != nil {
+		keepUnregistered = policy.UnregisteredRunners != v1alpha1.UnregisteredRunnersDeleteFirst
+		newestFirst = policy.IdleRunnerOrder == v1alpha1.IdleRunnerOrderNewestFirst
+		if policy.MinIdleRunnerAge != nil {
+			minIdleAge = policy.MinIdleRunnerAge.Duration
+		}
 	}
-}
 
-func (s *ephemeralRunnerStepper) next() bool {
-	if s.index+1 < len(s.items) {
-		s.index++
-		return true
+	var unregistered, registeredPending, idle []*v1alpha1.EphemeralRunner
+
+	for _, buckets := range []struct {
+		runners    []*v1alpha1.EphemeralRunner
+		registered *[]*v1alpha1.EphemeralRunner
+	}{
+		{pending, &registeredPending},
+		{running, &idle},
+	} {
+		for _, ephemeralRunner := range buckets.runners {
+			switch {
+			case ephemeralRunner.Status.RunnerId == 0:
+				if keepUnregistered {
+					log.Info("Skipping ephemeral runner since it is not registered yet", "name", ephemeralRunner.Name)
+					continue
+				}
+				unregistered = append(unregistered, ephemeralRunner)
+			case ephemeralRunner.Status.JobRequestId > 0:
+				log.Info("Skipping ephemeral runner since it is running a job", "name", ephemeralRunner.Name, "jobRequestId", ephemeralRunner.Status.JobRequestId)
+			case now.Sub(ephemeralRunner.GetCreationTimestamp().Time) < minIdleAge:
+				log.Info("Skipping ephemeral runner since it is younger than the minimum idle runner age", "name", ephemeralRunner.Name, "minIdleRunnerAge", minIdleAge)
+			default:
+				*buckets.registered = append(*buckets.registered, ephemeralRunner)
+			}
+		}
 	}
-	return false
-}
 
-func (s *ephemeralRunnerStepper) object() *v1alpha1.EphemeralRunner {
-	if s.index >= 0 && s.index < len(s.items) {
-		return s.items[s.index]
+	var candidates []*v1alpha1.EphemeralRunner
+	for _, bucket := range [][]*v1alpha1.EphemeralRunner{unregistered, registeredPending, idle} {
+		sort.SliceStable(bucket, func(i, j int) bool {
+			a, b := bucket[i].GetCreationTimestamp().Time, bucket[j].GetCreationTimestamp().Time
+			if newestFirst {
+				return a.After(b)
+			}
+			return a.Before(b)
+		})
+		candidates = append(candidates, bucket...)
 	}
-	return nil
-}
 
-func (s *ephemeralRunnerStepper) len() int {
-	return len(s.items)
+	return candidates
 }
 
 type ephemeralRunnerState struct {
@@ -667,3 +779,27 @@ func newEphemeralRunnerState(ephemeralRunnerList *v1alpha1.EphemeralRunnerList)
 func (s *ephemeralRunnerState) scaleTotal() int {
 	return len(s.pending) + len(s.running) + len(s.failed)
 }
+
+// desiredReplicas returns the replicas of the spec, raised to keep the warm pool of idle runners
+// on top of the runners running jobs, up to the max replicas of the warm pool.
+func (s *ephemeralRunnerState) desiredReplicas(spec *v1alpha1.EphemeralRunnerSetSpec) int {
+	if spec.WarmPool == nil {
+		return spec.Replicas
+	}
+
+	busy := 0
+	for _, runners := range [][]*v1alpha1.EphemeralRunner{s.pending, s.running} {
+		for _, r := range runners {
+			if r.Status.JobRequestId != 0 {
+				busy++
+			}
+		}
+	}
+
+	warm := busy + spec.WarmPool.Size
+	if spec.WarmPool.MaxReplicas > 0 {
+		warm = min(warm, spec.WarmPool.MaxReplicas)
+	}
+
+	return max(spec.Replicas, warm)
+}
@@ -35,6 +35,24 @@ const (
 	ephemeralRunnerSetTestGitHubToken = "gh_token"
 )
 
+// observedEphemeralRunnerSetStatus returns the status of the ephemeral runner set once it's reconciled for the latest generation,
+// leaving out the observed generation so that the rest of the status can be compared as is.
+func observedEphemeralRunnerSetStatus(ctx context.Context, key client.ObjectKey) (v1alpha1.EphemeralRunnerSetStatus, error) {
+	updated := new(v1alpha1.EphemeralRunnerSet)
+	if err := k8sClient.Get(ctx, key, updated); err != nil {
+		return v1alpha1.EphemeralRunnerSetStatus{}, err
+	}
+
+	if updated.Status.ObservedGeneration != updated.Generation {
+		return v1alpha1.EphemeralRunnerSetStatus{}, fmt.Errorf("generation %d is not observed yet", updated.Generation)
+	}
+
+	status := updated.Status
+	status.ObservedGeneration = 0
+
+	return status, nil
+}
+
 var _ = Describe("Test EphemeralRunnerSet controller", func() {
 	var ctx context.Context
 	var mgr ctrl.Manager
@@ -1011,12 +1029,7 @@ var _ = Describe("Test EphemeralRunnerSet controller", func() {
 			}
 			Eventually(
 				func() (v1alpha1.EphemeralRunnerSetStatus, error) {
-					updated := new(v1alpha1.EphemeralRunnerSet)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: ephemeralRunnerSet.Name, Namespace: ephemeralRunnerSet.Namespace}, updated)
-					if err != nil {
-						return v1alpha1.EphemeralRunnerSetStatus{}, err
-					}
-					return updated.Status, nil
+					return observedEphemeralRunnerSetStatus(ctx, client.ObjectKeyFromObject(ephemeralRunnerSet))
 				},
 				ephemeralRunnerSetTestTimeout,
 				ephemeralRunnerSetTestInterval,
@@ -1054,12 +1067,7 @@ var _ = Describe("Test EphemeralRunnerSet controller", func() {
 
 			Eventually(
 				func() (v1alpha1.EphemeralRunnerSetStatus, error) {
-					updated := new(v1alpha1.EphemeralRunnerSet)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: ephemeralRunnerSet.Name, Namespace: ephemeralRunnerSet.Namespace}, updated)
-					if err != nil {
-						return v1alpha1.EphemeralRunnerSetStatus{}, err
-					}
-					return updated.Status, nil
+					return observedEphemeralRunnerSetStatus(ctx, client.ObjectKeyFromObject(ephemeralRunnerSet))
 				},
 				ephemeralRunnerSetTestTimeout,
 				ephemeralRunnerSetTestInterval,
@@ -1071,12 +1079,7 @@ var _ = Describe("Test EphemeralRunnerSet controller", func() {
 			desiredStatus = v1alpha1.EphemeralRunnerSetStatus{} // empty
 			Eventually(
 				func() (v1alpha1.EphemeralRunnerSetStatus, error) {
-					updated := new(v1alpha1.EphemeralRunnerSet)
-					err := k8sClient.Get(ctx, client.ObjectKey{Name: ephemeralRunnerSet.Name, Namespace: ephemeralRunnerSet.Namespace}, updated)
-					if err != nil {
-						return v1alpha1.EphemeralRunnerSetStatus{}, err
-					}
-					return updated.Status, nil
+					return observedEphemeralRunnerSetStatus(ctx, client.ObjectKeyFromObject(ephemeralRunnerSet))
 				},
 				ephemeralRunnerSetTestTimeout,
 				ephemeralRunnerSetTestInterval,
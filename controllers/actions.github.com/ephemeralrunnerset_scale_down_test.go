@@ -0,0 +1,88 @@
+package actionsgithubcom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScaleDownCandidates(t *testing.T) {
+	now := time.Now()
+
+	newRunner := func(name string, age time.Duration, runnerID, jobRequestID int) *v1alpha1.EphemeralRunner {
+		r := &v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+		r.Status.RunnerId = runnerID
+		r.Status.JobRequestId = int64(jobRequestID)
+		return r
+	}
+
+	pending := func() []*v1alpha1.EphemeralRunner {
+		return []*v1alpha1.EphemeralRunner{
+			newRunner("pending-registered", 3*time.Minute, 1, 0),
+			newRunner("pending-unregistered", 1*time.Minute, 0, 0),
+		}
+	}
+
+	running := func() []*v1alpha1.EphemeralRunner {
+		return []*v1alpha1.EphemeralRunner{
+			newRunner("idle-new", 2*time.Minute, 2, 0),
+			newRunner("busy", 30*time.Minute, 3, 1),
+			newRunner("idle-old", 20*time.Minute, 4, 0),
+		}
+	}
+
+	names := func(runners []*v1alpha1.EphemeralRunner) []string {
+		var n []string
+		for _, r := range runners {
+			n = append(n, r.Name)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name   string
+		policy *v1alpha1.ScaleDownPolicy
+		want   []string
+	}{
+		{
+			name: "default",
+			want: []string{"pending-registered", "idle-old", "idle-new"},
+		},
+		{
+			name:   "newest first",
+			policy: &v1alpha1.ScaleDownPolicy{IdleRunnerOrder: v1alpha1.IdleRunnerOrderNewestFirst},
+			want:   []string{"pending-registered", "idle-new", "idle-old"},
+		},
+		{
+			name:   "keep unregistered",
+			policy: &v1alpha1.ScaleDownPolicy{UnregisteredRunners: v1alpha1.UnregisteredRunnersKeep},
+			want:   []string{"pending-registered", "idle-old", "idle-new"},
+		},
+		{
+			name:   "delete unregistered first",
+			policy: &v1alpha1.ScaleDownPolicy{UnregisteredRunners: v1alpha1.UnregisteredRunnersDeleteFirst},
+			want:   []string{"pending-unregistered", "pending-registered", "idle-old", "idle-new"},
+		},
+		{
+			name:   "min idle runner age",
+			policy: &v1alpha1.ScaleDownPolicy{MinIdleRunnerAge: &metav1.Duration{Duration: 5 * time.Minute}},
+			want:   []string{"idle-old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scaleDownCandidates(tt.policy, pending(), running(), now, logr.Discard())
+			assert.Equal(t, tt.want, names(got))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package actionsgithubcom
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEphemeralRunnerStateDesiredReplicas(t *testing.T) {
+	newRunner := func(jobRequestID int) *v1alpha1.EphemeralRunner {
+		r := &v1alpha1.EphemeralRunner{}
+		r.Status.JobRequestId = int64(jobRequestID)
+		return r
+	}
+
+	state := &ephemeralRunnerState{
+		pending: []*v1alpha1.EphemeralRunner{newRunner(0), newRunner(1)},
+		running: []*v1alpha1.EphemeralRunner{newRunner(2), newRunner(3), newRunner(0)},
+	}
+
+	tests := []struct {
+		name string
+		spec v1alpha1.EphemeralRunnerSetSpec
+		want int
+	}{
+		{
+			name: "no warm pool",
+			spec: v1alpha1.EphemeralRunnerSetSpec{Replicas: 4},
+			want: 4,
+		},
+		{
+			name: "warm pool on top of busy runners",
+			spec: v1alpha1.EphemeralRunnerSetSpec{Replicas: 4, WarmPool: &v1alpha1.WarmPool{Size: 2}},
+			want: 5,
+		},
+		{
+			name: "replicas over the warm pool",
+			spec: v1alpha1.EphemeralRunnerSetSpec{Replicas: 10, WarmPool: &v1alpha1.WarmPool{Size: 2}},
+			want: 10,
+		},
+		{
+			name: "warm pool capped by max replicas",
+			spec: v1alpha1.EphemeralRunnerSetSpec{Replicas: 3, WarmPool: &v1alpha1.WarmPool{Size: 5, MaxReplicas: 6}},
+			want: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, state.desiredReplicas(&tt.spec))
+		})
+	}
+}
+
+func TestEphemeralRunnerSetWarmPool(t *testing.T) {
+	warmPool := 3
+	maxRunners := 10
+
+	autoscalingRunnerSet := &v1alpha1.AutoscalingRunnerSet{}
+	assert.Nil(t, ephemeralRunnerSetWarmPool(autoscalingRunnerSet))
+
+	autoscalingRunnerSet.Spec.WarmPool = &warmPool
+	assert.Equal(t, &v1alpha1.WarmPool{Size: 3}, ephemeralRunnerSetWarmPool(autoscalingRunnerSet))
+
+	autoscalingRunnerSet.Spec.MaxRunners = &maxRunners
+	assert.Equal(t, &v1alpha1.WarmPool{Size: 3, MaxReplicas: 10}, ephemeralRunnerSetWarmPool(autoscalingRunnerSet))
+
+	maxRunners = 0
+	assert.Nil(t, ephemeralRunnerSetWarmPool(autoscalingRunnerSet))
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/actions/actions-runner-controller/hash"
 	"github.com/actions/actions-runner-controller/logging"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,6 +40,9 @@ var commonLabelKeys = [...]string{
 
 const labelValueKubernetesPartOf = "gha-runner-scale-set"
 
+// imagePrePullPauseImage keeps the pods of the image pre-pull DaemonSet running once the images are pulled.
+const imagePrePullPauseImage = "registry.k8s.io/pause:3.10"
+
 var (
 	scaleSetListenerLogLevel   = DefaultScaleSetListenerLogLevel
 	scaleSetListenerLogFormat  = DefaultScaleSetListenerLogFormat
@@ -71,6 +75,8 @@ func SetListenerEntrypoint(entrypoint string) {
 
 type ResourceBuilder struct {
 	ExcludeLabelPropagationPrefixes []string
+	// TLSMinVersion is the minimum TLS version the listeners use to talk to GitHub.
+	TLSMinVersion string
 }
 
 // boolPtr returns a pointer to a bool value
@@ -93,6 +99,11 @@ func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.
 		effectiveMinRunners = *autoscalingRunnerSet.Spec.MinRunners
 	}
 
+	replicas := 1
+	if autoscalingRunnerSet.Spec.ListenerReplicas != nil {
+		replicas = *autoscalingRunnerSet.Spec.ListenerReplicas
+	}
+
 	labels := b.mergeLabels(autoscalingRunnerSet.Labels, map[string]string{
 		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
 		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
@@ -132,6 +143,11 @@ func (b *ResourceBuilder) newAutoScalingListener(autoscalingRunnerSet *v1alpha1.
 			GitHubServerTLS:               autoscalingRunnerSet.Spec.GitHubServerTLS,
 			Metrics:                       autoscalingRunnerSet.Spec.ListenerMetrics,
 			Template:                      autoscalingRunnerSet.Spec.ListenerTemplate,
+			Replicas:                      replicas,
+			MaxAcquireJobsPerInterval:     autoscalingRunnerSet.Spec.MaxAcquireJobsPerInterval,
+			AcquireJobsInterval:           autoscalingRunnerSet.Spec.AcquireJobsInterval,
+			Federation:                    autoscalingRunnerSet.Spec.Federation,
+			ListenerSession:               autoscalingRunnerSet.Spec.ListenerSession,
 		},
 	}
 
@@ -159,7 +175,7 @@ func (lm *listenerMetricsServerConfig) containerPort() (corev1.ContainerPort, er
 	}, nil
 }
 
-func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha1.AutoscalingListener, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, cert string) (*corev1.Secret, error) {
+func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha1.AutoscalingListener, secret *corev1.Secret, metricsConfig *listenerMetricsServerConfig, cert string, federationKubeconfig string) (*corev1.Secret, error) {
 	var (
 		metricsAddr     = ""
 		metricsEndpoint = ""
@@ -200,6 +216,7 @@ func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha
 		RunnerScaleSetId:            autoscalingListener.Spec.RunnerScaleSetId,
 		RunnerScaleSetName:          autoscalingListener.Spec.AutoscalingRunnerSetName,
 		ServerRootCA:                cert,
+		TLSMinVersion:               b.TLSMinVersion,
 		LogLevel:                    scaleSetListenerLogLevel,
 		LogFormat:                   scaleSetListenerLogFormat,
 		MetricsAddr:                 metricsAddr,
@@ -207,6 +224,50 @@ func (b *ResourceBuilder) newScaleSetListenerConfig(autoscalingListener *v1alpha
 		Metrics:                     autoscalingListener.Spec.Metrics,
 	}
 
+	if autoscalingListener.Spec.MaxAcquireJobsPerInterval != nil {
+		config.MaxAcquireJobsPerInterval = *autoscalingListener.Spec.MaxAcquireJobsPerInterval
+	}
+	if autoscalingListener.Spec.AcquireJobsInterval != nil {
+		config.AcquireJobsInterval = *autoscalingListener.Spec.AcquireJobsInterval
+	}
+
+	if session := autoscalingListener.Spec.ListenerSession; session != nil {
+		config.Session = &listenerconfig.SessionConfig{
+			Transport:             session.Transport,
+			FallbackAfterFailures: session.FallbackAfterFailures,
+			MaxReconnects:         session.MaxReconnects,
+		}
+		if session.PollTimeout != nil {
+			config.Session.PollTimeout = *session.PollTimeout
+		}
+		if session.MaxReconnectBackoff != nil {
+			config.Session.MaxReconnectBackoff = *session.MaxReconnectBackoff
+		}
+		if session.KeepAlive != nil {
+			config.Session.KeepAlive = *session.KeepAlive
+		}
+	}
+
+	if federation := autoscalingListener.Spec.Federation; federation != nil {
+		weight := 1
+		if federation.Weight != nil {
+			weight = *federation.Weight
+		}
+
+		config.Federation = &listenerconfig.FederationConfig{
+			Name:       federation.Name,
+			Namespace:  federation.Namespace,
+			MemberName: federation.MemberName,
+			Weight:     weight,
+			Kubeconfig: federationKubeconfig,
+		}
+	}
+
+	if autoscalingListener.Spec.Replicas > 1 {
+		config.LeaderElectionLeaseName = scaleSetListenerLeaseName(autoscalingListener)
+		config.LeaderElectionLeaseNamespace = autoscalingListener.Spec.AutoscalingRunnerSetNamespace
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(config); err != nil {
 		return nil, fmt.Errorf("failed to encode config: %w", err)
@@ -440,7 +501,7 @@ func (b *ResourceBuilder) newScaleSetListenerServiceAccount(autoscalingListener
 }
 
 func (b *ResourceBuilder) newScaleSetListenerRole(autoscalingListener *v1alpha1.AutoscalingListener) *rbacv1.Role {
-	rules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})
+	rules := rulesForListener(autoscalingListener)
 	rulesHash := hash.ComputeTemplateHash(&rules)
 	newRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
@@ -515,6 +576,109 @@ func (b *ResourceBuilder) newScaleSetListenerSecretMirror(autoscalingListener *v
 	return newListenerSecret
 }
 
+// newImagePrePullDaemonSet returns the DaemonSet pre-pulling the images of the runners onto the nodes matching the runner template.
+// The images are pulled by init containers exiting right away, and a pause container keeps the pods running.
+func (b *ResourceBuilder) newImagePrePullDaemonSet(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) *appsv1.DaemonSet {
+	selectorLabels := map[string]string{
+		LabelKeyKubernetesComponent:     "image-prepull",
+		LabelKeyGitHubScaleSetName:      autoscalingRunnerSet.Name,
+		LabelKeyGitHubScaleSetNamespace: autoscalingRunnerSet.Namespace,
+	}
+
+	template := autoscalingRunnerSet.Spec.Template.Spec
+	podSpec := corev1.PodSpec{
+		NodeSelector:     template.NodeSelector,
+		Affinity:         template.Affinity,
+		Tolerations:      template.Tolerations,
+		ImagePullSecrets: template.ImagePullSecrets,
+		Containers: []corev1.Container{
+			{
+				Name:  "pause",
+				Image: imagePrePullPauseImage,
+			},
+		},
+	}
+
+	for i, image := range imagePrePullImages(autoscalingRunnerSet) {
+		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+			Name:            fmt.Sprintf("prepull-%d", i),
+			Image:           image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{"sh", "-c", "true"},
+		})
+	}
+
+	spec := appsv1.DaemonSetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: selectorLabels,
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: selectorLabels,
+			},
+			Spec: podSpec,
+		},
+	}
+
+	labels := b.mergeLabels(autoscalingRunnerSet.Labels, selectorLabels)
+	labels[LabelKeyKubernetesPartOf] = labelValueKubernetesPartOf
+	labels["daemonset-spec-hash"] = hash.ComputeTemplateHash(&spec)
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      imagePrePullDaemonSetName(autoscalingRunnerSet),
+			Namespace: autoscalingRunnerSet.Namespace,
+			Labels:    labels,
+		},
+		Spec: spec,
+	}
+}
+
+// imagePrePullImages returns the images of the containers of the runner template, followed by the additional images, without duplicates.
+func imagePrePullImages(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) []string {
+	var images []string
+	seen := make(map[string]bool)
+	add := func(image string) {
+		if image == "" || seen[image] {
+			return
+		}
+		seen[image] = true
+		images = append(images, image)
+	}
+
+	for _, c := range autoscalingRunnerSet.Spec.Template.Spec.InitContainers {
+		add(c.Image)
+	}
+	for _, c := range autoscalingRunnerSet.Spec.Template.Spec.Containers {
+		add(c.Image)
+	}
+	if autoscalingRunnerSet.Spec.ImagePrePull != nil {
+		for _, image := range autoscalingRunnerSet.Spec.ImagePrePull.Images {
+			add(image)
+		}
+	}
+
+	return images
+}
+
+// ephemeralRunnerSetWarmPool returns the warm pool of the EphemeralRunnerSet of the AutoscalingRunnerSet,
+// capped by its max runners.
+func ephemeralRunnerSetWarmPool(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) *v1alpha1.WarmPool {
+	if autoscalingRunnerSet.Spec.WarmPool == nil || *autoscalingRunnerSet.Spec.WarmPool == 0 {
+		return nil
+	}
+	if autoscalingRunnerSet.Spec.MaxRunners != nil && *autoscalingRunnerSet.Spec.MaxRunners == 0 {
+		return nil
+	}
+
+	warmPool := &v1alpha1.WarmPool{Size: *autoscalingRunnerSet.Spec.WarmPool}
+	if autoscalingRunnerSet.Spec.MaxRunners != nil {
+		warmPool.MaxReplicas = *autoscalingRunnerSet.Spec.MaxRunners
+	}
+
+	return warmPool
+}
+
 func (b *ResourceBuilder) newEphemeralRunnerSet(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) (*v1alpha1.EphemeralRunnerSet, error) {
 	runnerScaleSetId, err := strconv.Atoi(autoscalingRunnerSet.Annotations[runnerScaleSetIdAnnotationKey])
 	if err != nil {
@@ -568,6 +732,9 @@ func (b *ResourceBuilder) newEphemeralRunnerSet(autoscalingRunnerSet *v1alpha1.A
 				GitHubServerTLS:    autoscalingRunnerSet.Spec.GitHubServerTLS,
 				PodTemplateSpec:    autoscalingRunnerSet.Spec.Template,
 			},
+			ScaleDownPolicy:      autoscalingRunnerSet.Spec.ScaleDownPolicy,
+			WarmPool:             ephemeralRunnerSetWarmPool(autoscalingRunnerSet),
+			CapacityDistribution: autoscalingRunnerSet.Spec.CapacityDistribution,
 		},
 	}
 
@@ -713,6 +880,10 @@ func scaleSetListenerName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) s
 	return fmt.Sprintf("%v-%v-listener", autoscalingRunnerSet.Name, namespaceHash)
 }
 
+func imagePrePullDaemonSetName(autoscalingRunnerSet *v1alpha1.AutoscalingRunnerSet) string {
+	return fmt.Sprintf("%v-image-prepull", autoscalingRunnerSet.Name)
+}
+
 func scaleSetListenerServiceAccountName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
@@ -721,6 +892,19 @@ func scaleSetListenerServiceAccountName(autoscalingListener *v1alpha1.Autoscalin
 	return fmt.Sprintf("%v-%v-listener", autoscalingListener.Spec.AutoscalingRunnerSetName, namespaceHash)
 }
 
+// scaleSetListenerPodName returns the name of the listener pod of the given replica.
+// The first replica keeps the name of the listener.
+func scaleSetListenerPodName(autoscalingListener *v1alpha1.AutoscalingListener, replica int) string {
+	if replica == 0 {
+		return autoscalingListener.Name
+	}
+	return fmt.Sprintf("%v-%v", autoscalingListener.Name, replica)
+}
+
+func scaleSetListenerLeaseName(autoscalingListener *v1alpha1.AutoscalingListener) string {
+	return scaleSetListenerRoleName(autoscalingListener)
+}
+
 func scaleSetListenerRoleName(autoscalingListener *v1alpha1.AutoscalingListener) string {
 	namespaceHash := hash.FNVHashString(autoscalingListener.Spec.AutoscalingRunnerSetNamespace)
 	if len(namespaceHash) > 8 {
@@ -769,6 +953,30 @@ func rulesForListenerRole(resourceNames []string) []rbacv1.PolicyRule {
 	}
 }
 
+// rulesForListener returns the rules of the listener role, including the ones for the leader election
+// when the listener has more than one replica.
+func rulesForListener(autoscalingListener *v1alpha1.AutoscalingListener) []rbacv1.PolicyRule {
+	rules := rulesForListenerRole([]string{autoscalingListener.Spec.EphemeralRunnerSetName})
+	if autoscalingListener.Spec.Replicas <= 1 {
+		return rules
+	}
+
+	return append(rules,
+		rbacv1.PolicyRule{
+			APIGroups:     []string{"coordination.k8s.io"},
+			Resources:     []string{"leases"},
+			ResourceNames: []string{scaleSetListenerLeaseName(autoscalingListener)},
+			Verbs:         []string{"get", "update"},
+		},
+		// create can't be restricted by resource name
+		rbacv1.PolicyRule{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     []string{"create"},
+		},
+	)
+}
+
 func applyGitHubURLLabels(url string, labels map[string]string) error {
 	githubConfig, err := actions.ParseGitHubConfigFromURL(url)
 	if err != nil {
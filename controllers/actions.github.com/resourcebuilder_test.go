@@ -2,11 +2,13 @@ package actionsgithubcom
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	listenerconfig "github.com/actions/actions-runner-controller/cmd/ghalistener/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
@@ -248,3 +250,119 @@ func TestOwnershipRelationships(t *testing.T) {
 	assert.Equal(t, true, *ownerRef.Controller, "Controller flag should be true")
 	assert.Equal(t, true, *ownerRef.BlockOwnerDeletion, "BlockOwnerDeletion flag should be true")
 }
+
+func TestListenerReplicas(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				runnerScaleSetIdAnnotationKey: "1",
+			},
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org/repo",
+		},
+	}
+	ephemeralRunnerSet := &v1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-scale-set-abcde",
+		},
+	}
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			"github_token": []byte("token"),
+		},
+	}
+
+	var b ResourceBuilder
+
+	t.Run("single listener", func(t *testing.T) {
+		listener, err := b.newAutoScalingListener(&autoscalingRunnerSet, ephemeralRunnerSet, "arc-systems", "test:latest", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, listener.Spec.Replicas)
+		assert.Equal(t, listener.Name, scaleSetListenerPodName(listener, 0))
+		assert.Equal(t, rulesForListenerRole([]string{ephemeralRunnerSet.Name}), rulesForListener(listener))
+
+		podConfig, err := b.newScaleSetListenerConfig(listener, secret, nil, "", "")
+		require.NoError(t, err)
+
+		var config listenerconfig.Config
+		require.NoError(t, json.Unmarshal(podConfig.Data["config.json"], &config))
+		assert.Empty(t, config.LeaderElectionLeaseName)
+		assert.Empty(t, config.LeaderElectionLeaseNamespace)
+	})
+
+	t.Run("leader-elected listeners", func(t *testing.T) {
+		ars := autoscalingRunnerSet.DeepCopy()
+		replicas := 2
+		ars.Spec.ListenerReplicas = &replicas
+
+		listener, err := b.newAutoScalingListener(ars, ephemeralRunnerSet, "arc-systems", "test:latest", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, listener.Spec.Replicas)
+		assert.Equal(t, listener.Name+"-1", scaleSetListenerPodName(listener, 1))
+
+		rules := rulesForListener(listener)
+		require.Len(t, rules, 4)
+		assert.Equal(t, []string{"leases"}, rules[2].Resources)
+		assert.Equal(t, []string{scaleSetListenerLeaseName(listener)}, rules[2].ResourceNames)
+		assert.Equal(t, []string{"create"}, rules[3].Verbs)
+
+		podConfig, err := b.newScaleSetListenerConfig(listener, secret, nil, "", "")
+		require.NoError(t, err)
+
+		var config listenerconfig.Config
+		require.NoError(t, json.Unmarshal(podConfig.Data["config.json"], &config))
+		assert.Equal(t, scaleSetListenerLeaseName(listener), config.LeaderElectionLeaseName)
+		assert.Equal(t, ars.Namespace, config.LeaderElectionLeaseNamespace)
+	})
+}
+
+func TestImagePrePullDaemonSet(t *testing.T) {
+	autoscalingRunnerSet := v1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-scale-set",
+			Namespace: "test-ns",
+		},
+		Spec: v1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl: "https://github.com/org/repo",
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{"pool": "runners"},
+					InitContainers: []corev1.Container{
+						{Name: "init", Image: "ghcr.io/actions/actions-runner:latest"},
+					},
+					Containers: []corev1.Container{
+						{Name: "runner", Image: "ghcr.io/actions/actions-runner:latest"},
+						{Name: "dind", Image: "docker:dind"},
+					},
+				},
+			},
+			ImagePrePull: &v1alpha1.ImagePrePull{
+				Images: []string{"node:20", "docker:dind"},
+			},
+		},
+	}
+
+	var b ResourceBuilder
+	daemonSet := b.newImagePrePullDaemonSet(&autoscalingRunnerSet)
+
+	assert.Equal(t, "test-scale-set-image-prepull", daemonSet.Name)
+	assert.Equal(t, "test-ns", daemonSet.Namespace)
+	assert.Equal(t, daemonSet.Spec.Selector.MatchLabels, daemonSet.Spec.Template.Labels)
+	assert.Equal(t, map[string]string{"pool": "runners"}, daemonSet.Spec.Template.Spec.NodeSelector)
+
+	var images []string
+	for _, c := range daemonSet.Spec.Template.Spec.InitContainers {
+		images = append(images, c.Image)
+		assert.Equal(t, []string{"sh", "-c", "true"}, c.Command)
+	}
+	assert.Equal(t, []string{"ghcr.io/actions/actions-runner:latest", "docker:dind", "node:20"}, images)
+	require.Len(t, daemonSet.Spec.Template.Spec.Containers, 1)
+	assert.Equal(t, imagePrePullPauseImage, daemonSet.Spec.Template.Spec.Containers[0].Image)
+
+	updated := autoscalingRunnerSet.DeepCopy()
+	updated.Spec.Template.Spec.Containers[0].Image = "ghcr.io/actions/actions-runner:2.320.0"
+	assert.NotEqual(t, daemonSet.Labels["daemonset-spec-hash"], b.newImagePrePullDaemonSet(updated).Labels["daemonset-spec-hash"])
+}
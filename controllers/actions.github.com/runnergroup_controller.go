@@ -0,0 +1,260 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsgithubcom
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// RunnerGroupReconciler reconciles a RunnerGroup object
+type RunnerGroupReconciler struct {
+	client.Client
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	ActionsClient actions.MultiClient
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+}
+
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnergroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnergroups/status,verbs=get;update;patch
+
+// Reconcile creates the runner group in the organization if it is missing,
+// and updates its visibility, repository access list and allowed workflows to match the spec.
+// Deleting a RunnerGroup leaves the runner group in GitHub, since runner scale sets may still use it.
+func (r *RunnerGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("runnergroup", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "RunnerGroup", req.NamespacedName)
+
+	runnerGroup := new(v1alpha1.RunnerGroup)
+	if err := r.Get(ctx, req.NamespacedName, runnerGroup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !r.Sharder.Owns(runnerGroup.Namespace, sharding.OwnerOfGitHubConfigURL(runnerGroup.Spec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
+	if !runnerGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	config, err := actions.ParseGitHubConfigFromURL(runnerGroup.Spec.GitHubConfigUrl)
+	if err != nil {
+		log.Error(err, "Failed to parse GitHub config url")
+		return ctrl.Result{}, nil
+	}
+	if config.Scope != actions.GitHubScopeOrganization {
+		log.Info("Runner groups can only be managed for an organization, skipping", "githubConfigUrl", runnerGroup.Spec.GitHubConfigUrl)
+		return ctrl.Result{}, nil
+	}
+
+	actionsClient, err := r.actionsClientFor(ctx, runnerGroup)
+	if err != nil {
+		log.Error(err, "Failed to initialize Actions service client")
+		return ctrl.Result{}, err
+	}
+
+	var repositoryIDs []int64
+	if desiredRunnerGroupVisibility(runnerGroup) == v1alpha1.RunnerGroupVisibilitySelected {
+		for _, name := range runnerGroup.Spec.Repositories {
+			repository, err := actionsClient.GetRepository(ctx, config.Organization, name)
+			if err != nil {
+				log.Error(err, "Failed to get repository", "repository", name)
+				return ctrl.Result{}, err
+			}
+			repositoryIDs = append(repositoryIDs, repository.ID)
+		}
+	}
+
+	desired := desiredOrganizationRunnerGroup(runnerGroup)
+
+	current, err := actionsClient.GetOrganizationRunnerGroupByName(ctx, desired.Name)
+	if err != nil {
+		log.Error(err, "Failed to get runner group", "name", desired.Name)
+		return ctrl.Result{}, err
+	}
+
+	if current == nil {
+		desired.SelectedRepositoryIDs = repositoryIDs
+		log.Info("Creating runner group", "name", desired.Name, "visibility", desired.Visibility)
+		current, err = actionsClient.CreateOrganizationRunnerGroup(ctx, desired)
+		if err != nil {
+			log.Error(err, "Failed to create runner group", "name", desired.Name)
+			return ctrl.Result{}, err
+		}
+	} else {
+		if organizationRunnerGroupChanged(current, desired) {
+			log.Info("Updating runner group", "name", desired.Name, "id", current.ID, "visibility", desired.Visibility)
+			current, err = actionsClient.UpdateOrganizationRunnerGroup(ctx, current.ID, desired)
+			if err != nil {
+				log.Error(err, "Failed to update runner group", "name", desired.Name)
+				return ctrl.Result{}, err
+			}
+		}
+
+		// The repository access list is not part of the runner group, so it is always applied
+		if desired.Visibility == v1alpha1.RunnerGroupVisibilitySelected {
+			if err := actionsClient.SetOrganizationRunnerGroupRepositories(ctx, current.ID, repositoryIDs); err != nil {
+				log.Error(err, "Failed to set the repositories of the runner group", "name", desired.Name, "id", current.ID)
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	if runnerGroup.Status.ID == current.ID && runnerGroup.Status.ObservedGeneration == runnerGroup.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	if err := patchSubResource(ctx, r.Status(), runnerGroup, func(obj *v1alpha1.RunnerGroup) {
+		obj.Status.ID = current.ID
+		obj.Status.ObservedGeneration = obj.Generation
+	}); err != nil {
+		log.Error(err, "Failed to update runner group status")
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Reconciled runner group", "name", desired.Name, "id", current.ID)
+	return ctrl.Result{}, nil
+}
+
+func desiredRunnerGroupVisibility(runnerGroup *v1alpha1.RunnerGroup) string {
+	if runnerGroup.Spec.Visibility == "" {
+		return v1alpha1.RunnerGroupVisibilityAll
+	}
+	return runnerGroup.Spec.Visibility
+}
+
+// desiredOrganizationRunnerGroup returns the runner group described by the spec, without its repositories.
+func desiredOrganizationRunnerGroup(runnerGroup *v1alpha1.RunnerGroup) *actions.OrganizationRunnerGroup {
+	selectedWorkflows := make([]string, 0, len(runnerGroup.Spec.SelectedWorkflows))
+	selectedWorkflows = append(selectedWorkflows, runnerGroup.Spec.SelectedWorkflows...)
+
+	return &actions.OrganizationRunnerGroup{
+		Name:                     runnerGroup.GitHubName(),
+		Visibility:               desiredRunnerGroupVisibility(runnerGroup),
+		AllowsPublicRepositories: runnerGroup.Spec.AllowsPublicRepositories,
+		RestrictedToWorkflows:    len(selectedWorkflows) > 0,
+		SelectedWorkflows:        selectedWorkflows,
+	}
+}
+
+func organizationRunnerGroupChanged(current, desired *actions.OrganizationRunnerGroup) bool {
+	if current.Visibility != desired.Visibility ||
+		current.AllowsPublicRepositories != desired.AllowsPublicRepositories ||
+		current.RestrictedToWorkflows != desired.RestrictedToWorkflows {
+		return true
+	}
+
+	currentWorkflows := slices.Clone(current.SelectedWorkflows)
+	desiredWorkflows := slices.Clone(desired.SelectedWorkflows)
+	slices.Sort(currentWorkflows)
+	slices.Sort(desiredWorkflows)
+	return !slices.Equal(currentWorkflows, desiredWorkflows)
+}
+
+func (r *RunnerGroupReconciler) actionsClientFor(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup) (actions.ActionsService, error) {
+	var configSecret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: runnerGroup.Spec.GitHubConfigSecret}, &configSecret); err != nil {
+		return nil, fmt.Errorf("failed to find GitHub config secret: %w", err)
+	}
+
+	opts, err := r.actionsClientOptionsFor(ctx, runnerGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actions client options: %w", err)
+	}
+
+	return r.ActionsClient.GetClientFromSecret(
+		ctx,
+		runnerGroup.Spec.GitHubConfigUrl,
+		runnerGroup.Namespace,
+		configSecret.Data,
+		opts...,
+	)
+}
+
+func (r *RunnerGroupReconciler) actionsClientOptionsFor(ctx context.Context, runnerGroup *v1alpha1.RunnerGroup) ([]actions.ClientOption, error) {
+	var options []actions.ClientOption
+
+	if runnerGroup.Spec.Proxy != nil {
+		proxyFunc, err := runnerGroup.Spec.Proxy.ProxyFunc(func(s string) (*corev1.Secret, error) {
+			var secret corev1.Secret
+			err := r.Get(ctx, types.NamespacedName{Namespace: runnerGroup.Namespace, Name: s}, &secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get proxy secret %s: %w", s, err)
+			}
+
+			return &secret, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get proxy func: %w", err)
+		}
+
+		options = append(options, actions.WithProxy(proxyFunc))
+	}
+
+	tlsConfig := runnerGroup.Spec.GitHubServerTLS
+	if tlsConfig != nil {
+		pool, err := tlsConfig.ToCertPool(func(name, key string) ([]byte, error) {
+			var configmap corev1.ConfigMap
+			err := r.Get(
+				ctx,
+				types.NamespacedName{
+					Namespace: runnerGroup.Namespace,
+					Name:      name,
+				},
+				&configmap,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get configmap %s: %w", name, err)
+			}
+
+			return []byte(configmap.Data[key]), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tls config: %w", err)
+		}
+
+		options = append(options, actions.WithRootCAs(pool))
+	}
+
+	return options, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RunnerGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.RunnerGroup{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(reconcilemetrics.Observe("runnergroup", "RunnerGroup", r))
+}
@@ -0,0 +1,147 @@
+package actionsgithubcom
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/actions/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDesiredOrganizationRunnerGroup(t *testing.T) {
+	runnerGroup := &v1alpha1.RunnerGroup{ObjectMeta: metav1.ObjectMeta{Name: "linux"}}
+
+	desired := desiredOrganizationRunnerGroup(runnerGroup)
+	assert.Equal(t, &actions.OrganizationRunnerGroup{
+		Name:              "linux",
+		Visibility:        v1alpha1.RunnerGroupVisibilityAll,
+		SelectedWorkflows: []string{},
+	}, desired)
+
+	runnerGroup.Spec.Name = "Linux runners"
+	runnerGroup.Spec.Visibility = v1alpha1.RunnerGroupVisibilitySelected
+	runnerGroup.Spec.SelectedWorkflows = []string{
+		"my-org/my-repo/.github/workflows/build.yaml@refs/heads/main",
+		"my-org/my-repo/.github/workflows/release.yaml@refs/heads/main",
+	}
+
+	desired = desiredOrganizationRunnerGroup(runnerGroup)
+	assert.Equal(t, "Linux runners", desired.Name)
+	assert.Equal(t, v1alpha1.RunnerGroupVisibilitySelected, desired.Visibility)
+	assert.True(t, desired.RestrictedToWorkflows)
+
+	current := *desired
+	current.SelectedWorkflows = slices.Clone(desired.SelectedWorkflows)
+	slices.Reverse(current.SelectedWorkflows)
+	assert.False(t, organizationRunnerGroupChanged(&current, desired))
+
+	current.AllowsPublicRepositories = true
+	assert.True(t, organizationRunnerGroupChanged(&current, desired))
+}
+
+func TestRunnerGroupReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	newRunnerGroup := func() *v1alpha1.RunnerGroup {
+		return &v1alpha1.RunnerGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "linux", Namespace: "arc-systems", Generation: 2},
+			Spec: v1alpha1.RunnerGroupSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "github-config",
+				Visibility:         v1alpha1.RunnerGroupVisibilitySelected,
+				Repositories:       []string{"my-repo"},
+			},
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-config", Namespace: "arc-systems"},
+		Data:       map[string][]byte{"github_token": []byte("token")},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "linux", Namespace: "arc-systems"}}
+	ctx := context.Background()
+
+	t.Run("creates the missing runner group", func(t *testing.T) {
+		runnerGroup := newRunnerGroup()
+		c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(runnerGroup, secret).WithStatusSubresource(runnerGroup).Build()
+
+		actionsClient := actions.NewMockActionsService(t)
+		actionsClient.On("GetRepository", mock.Anything, "my-org", "my-repo").Return(&actions.Repository{ID: 42}, nil)
+		actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "linux").Return(nil, nil)
+		actionsClient.On("CreateOrganizationRunnerGroup", mock.Anything, &actions.OrganizationRunnerGroup{
+			Name:                  "linux",
+			Visibility:            v1alpha1.RunnerGroupVisibilitySelected,
+			SelectedWorkflows:     []string{},
+			SelectedRepositoryIDs: []int64{42},
+		}).Return(&actions.OrganizationRunnerGroup{ID: 7, Name: "linux"}, nil)
+
+		r := &RunnerGroupReconciler{
+			Client:        c,
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+		_, err := r.Reconcile(ctx, req)
+		require.NoError(t, err)
+
+		var got v1alpha1.RunnerGroup
+		require.NoError(t, c.Get(ctx, req.NamespacedName, &got))
+		assert.Equal(t, int64(7), got.Status.ID)
+		assert.Equal(t, got.Generation, got.Status.ObservedGeneration)
+	})
+
+	t.Run("updates the existing runner group", func(t *testing.T) {
+		runnerGroup := newRunnerGroup()
+		c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(runnerGroup, secret).WithStatusSubresource(runnerGroup).Build()
+
+		existing := &actions.OrganizationRunnerGroup{ID: 7, Name: "linux", Visibility: v1alpha1.RunnerGroupVisibilityAll}
+		actionsClient := actions.NewMockActionsService(t)
+		actionsClient.On("GetRepository", mock.Anything, "my-org", "my-repo").Return(&actions.Repository{ID: 42}, nil)
+		actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "linux").Return(existing, nil)
+		actionsClient.On("UpdateOrganizationRunnerGroup", mock.Anything, int64(7), mock.MatchedBy(func(rg *actions.OrganizationRunnerGroup) bool {
+			return rg.Visibility == v1alpha1.RunnerGroupVisibilitySelected
+		})).Return(&actions.OrganizationRunnerGroup{ID: 7, Name: "linux", Visibility: v1alpha1.RunnerGroupVisibilitySelected}, nil)
+		actionsClient.On("SetOrganizationRunnerGroupRepositories", mock.Anything, int64(7), []int64{42}).Return(nil)
+
+		r := &RunnerGroupReconciler{
+			Client:        c,
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actionsClient, nil)),
+		}
+		_, err := r.Reconcile(ctx, req)
+		require.NoError(t, err)
+
+		var got v1alpha1.RunnerGroup
+		require.NoError(t, c.Get(ctx, req.NamespacedName, &got))
+		assert.Equal(t, int64(7), got.Status.ID)
+	})
+
+	t.Run("skips repository config urls", func(t *testing.T) {
+		runnerGroup := newRunnerGroup()
+		runnerGroup.Spec.GitHubConfigUrl = "https://github.com/my-org/my-repo"
+		c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(runnerGroup, secret).WithStatusSubresource(runnerGroup).Build()
+
+		r := &RunnerGroupReconciler{
+			Client:        c,
+			Log:           logr.Discard(),
+			Scheme:        scheme,
+			ActionsClient: fake.NewMultiClient(fake.WithDefaultClient(actions.NewMockActionsService(t), nil)),
+		}
+		_, err := r.Reconcile(ctx, req)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionsgithubcom
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RunnerInventoryReconciler reconciles a RunnerInventory object
+type RunnerInventoryReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+}
+
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnerinventories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.github.com,resources=runnerinventories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.github.com,resources=ephemeralrunners,verbs=get;list;watch
+
+// Reconcile lists the runners registered by the ephemeral runners of this installation
+// under the GitHub config url of the RunnerInventory in its status.
+func (r *RunnerInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("runnerinventory", req.NamespacedName)
+
+	runnerInventory := new(v1alpha1.RunnerInventory)
+	if err := r.Get(ctx, req.NamespacedName, runnerInventory); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !r.Sharder.Owns(runnerInventory.Namespace, sharding.OwnerOfGitHubConfigURL(runnerInventory.Spec.GitHubConfigUrl)) {
+		return ctrl.Result{}, nil
+	}
+
+	if !runnerInventory.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	inventoryConfig, err := actions.ParseGitHubConfigFromURL(runnerInventory.Spec.GitHubConfigUrl)
+	if err != nil {
+		log.Error(err, "Failed to parse GitHub config url")
+		return ctrl.Result{}, nil
+	}
+
+	var ephemeralRunnerList v1alpha1.EphemeralRunnerList
+	if err := r.List(ctx, &ephemeralRunnerList); err != nil {
+		log.Error(err, "Failed to list ephemeral runners")
+		return ctrl.Result{}, err
+	}
+
+	status := runnerInventoryStatus(inventoryConfig, ephemeralRunnerList.Items)
+	if equality.Semantic.DeepEqual(runnerInventory.Status, status) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := patchSubResource(ctx, r.Status(), runnerInventory, func(obj *v1alpha1.RunnerInventory) {
+		obj.Status = status
+	}); err != nil {
+		log.Error(err, "Failed to update runner inventory status")
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Updated runner inventory", "totalRunners", status.TotalRunners, "busyRunners", status.BusyRunners)
+	return ctrl.Result{}, nil
+}
+
+// runnerInventoryStatus returns the runners registered by the ephemeral runners under the GitHub config of the inventory.
+func runnerInventoryStatus(inventoryConfig *actions.GitHubConfig, ephemeralRunners []v1alpha1.EphemeralRunner) v1alpha1.RunnerInventoryStatus {
+	var status v1alpha1.RunnerInventoryStatus
+
+	for i := range ephemeralRunners {
+		ephemeralRunner := &ephemeralRunners[i]
+		if ephemeralRunner.Status.RunnerId == 0 || !ephemeralRunner.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		runnerConfig, err := actions.ParseGitHubConfigFromURL(ephemeralRunner.Spec.GitHubConfigUrl)
+		if err != nil || !gitHubConfigContains(inventoryConfig, runnerConfig) {
+			continue
+		}
+
+		runner := v1alpha1.InventoryRunner{
+			ID:              ephemeralRunner.Status.RunnerId,
+			Name:            ephemeralRunner.Status.RunnerName,
+			GitHubConfigUrl: ephemeralRunner.Spec.GitHubConfigUrl,
+			RunnerScaleSet:  ephemeralRunner.Annotations[AnnotationKeyGitHubRunnerScaleSetName],
+			Busy:            ephemeralRunner.Status.JobRequestId != 0,
+			Version:         ephemeralRunnerVersion(ephemeralRunner),
+			Pod: v1alpha1.InventoryRunnerPod{
+				Namespace: ephemeralRunner.Namespace,
+				Name:      ephemeralRunner.Name,
+			},
+			Phase: ephemeralRunner.Status.Phase,
+		}
+		// Runners of a scale set are labeled with the name of the scale set
+		if runner.RunnerScaleSet != "" {
+			runner.Labels = []string{runner.RunnerScaleSet}
+		}
+
+		status.Runners = append(status.Runners, runner)
+		status.TotalRunners++
+		if runner.Busy {
+			status.BusyRunners++
+		}
+	}
+
+	sort.Slice(status.Runners, func(i, j int) bool {
+		if status.Runners[i].Pod.Namespace != status.Runners[j].Pod.Namespace {
+			return status.Runners[i].Pod.Namespace < status.Runners[j].Pod.Namespace
+		}
+		return status.Runners[i].Pod.Name < status.Runners[j].Pod.Name
+	})
+
+	return status
+}
+
+// gitHubConfigContains returns true if the runners registered at the child config are also listed under the parent config,
+// e.g. the runners of the repositories of an organization are listed under the organization.
+func gitHubConfigContains(parent, child *actions.GitHubConfig) bool {
+	if !strings.EqualFold(parent.ConfigURL.Host, child.ConfigURL.Host) {
+		return false
+	}
+
+	switch parent.Scope {
+	case actions.GitHubScopeEnterprise:
+		return strings.EqualFold(parent.Enterprise, child.Enterprise)
+	case actions.GitHubScopeOrganization:
+		return strings.EqualFold(parent.Organization, child.Organization)
+	case actions.GitHubScopeRepository:
+		return strings.EqualFold(parent.Organization, child.Organization) && strings.EqualFold(parent.Repository, child.Repository)
+	default:
+		return false
+	}
+}
+
+// ephemeralRunnerVersion returns the tag of the image of the runner container, if any.
+func ephemeralRunnerVersion(ephemeralRunner *v1alpha1.EphemeralRunner) string {
+	for _, container := range ephemeralRunner.Spec.Spec.Containers {
+		if container.Name != v1alpha1.EphemeralRunnerContainerName {
+			continue
+		}
+
+		image := container.Image
+		if i := strings.Index(image, "@"); i >= 0 {
+			image = image[:i]
+		}
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			return image[i+1:]
+		}
+		return ""
+	}
+	return ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RunnerInventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.RunnerInventory{}).
+		Watches(&v1alpha1.EphemeralRunner{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, o client.Object) []reconcile.Request {
+				var runnerInventoryList v1alpha1.RunnerInventoryList
+				if err := r.List(ctx, &runnerInventoryList); err != nil {
+					r.Log.Error(err, "Failed to list runner inventories")
+					return nil
+				}
+
+				requests := make([]reconcile.Request, 0, len(runnerInventoryList.Items))
+				for _, runnerInventory := range runnerInventoryList.Items {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Namespace: runnerInventory.Namespace,
+							Name:      runnerInventory.Name,
+						},
+					})
+				}
+				return requests
+			},
+		)).
+		Complete(reconcilemetrics.Observe("runnerinventory", "RunnerInventory", r))
+}
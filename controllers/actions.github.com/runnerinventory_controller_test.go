@@ -0,0 +1,85 @@
+package actionsgithubcom
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunnerInventoryStatus(t *testing.T) {
+	newRunner := func(namespace, name, configURL string, runnerID int, jobRequestID int64) v1alpha1.EphemeralRunner {
+		r := v1alpha1.EphemeralRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Annotations: map[string]string{AnnotationKeyGitHubRunnerScaleSetName: "arc-runner-set"},
+			},
+		}
+		r.Spec.GitHubConfigUrl = configURL
+		r.Spec.Spec.Containers = []corev1.Container{
+			{Name: "sidecar", Image: "busybox:1.36"},
+			{Name: v1alpha1.EphemeralRunnerContainerName, Image: "ghcr.io/actions/actions-runner:2.321.0"},
+		}
+		r.Status.RunnerId = runnerID
+		r.Status.RunnerName = name
+		r.Status.JobRequestId = jobRequestID
+		r.Status.Phase = corev1.PodRunning
+		return r
+	}
+
+	runners := []v1alpha1.EphemeralRunner{
+		newRunner("ns-b", "org-runner", "https://github.com/my-org", 1, 10),
+		newRunner("ns-a", "repo-runner", "https://github.com/my-org/my-repo", 2, 0),
+		newRunner("ns-a", "other-org-runner", "https://github.com/other-org", 3, 0),
+		newRunner("ns-a", "enterprise-runner", "https://github.com/enterprises/my-enterprise", 4, 0),
+		newRunner("ns-a", "unregistered-runner", "https://github.com/my-org", 0, 0),
+	}
+
+	config, err := actions.ParseGitHubConfigFromURL("https://github.com/My-Org")
+	require.NoError(t, err)
+
+	status := runnerInventoryStatus(config, runners)
+	assert.Equal(t, 2, status.TotalRunners)
+	assert.Equal(t, 1, status.BusyRunners)
+	require.Len(t, status.Runners, 2)
+	assert.Equal(t, v1alpha1.InventoryRunner{
+		ID:              2,
+		Name:            "repo-runner",
+		GitHubConfigUrl: "https://github.com/my-org/my-repo",
+		RunnerScaleSet:  "arc-runner-set",
+		Version:         "2.321.0",
+		Labels:          []string{"arc-runner-set"},
+		Pod:             v1alpha1.InventoryRunnerPod{Namespace: "ns-a", Name: "repo-runner"},
+		Phase:           corev1.PodRunning,
+	}, status.Runners[0])
+	assert.Equal(t, "org-runner", status.Runners[1].Name)
+	assert.True(t, status.Runners[1].Busy)
+
+	config, err = actions.ParseGitHubConfigFromURL("https://github.com/enterprises/my-enterprise")
+	require.NoError(t, err)
+
+	status = runnerInventoryStatus(config, runners)
+	require.Len(t, status.Runners, 1)
+	assert.Equal(t, "enterprise-runner", status.Runners[0].Name)
+}
+
+func TestEphemeralRunnerVersion(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/actions/actions-runner:2.321.0":                 "2.321.0",
+		"ghcr.io/actions/actions-runner":                         "",
+		"registry.local:5000/actions-runner":                     "",
+		"registry.local:5000/actions-runner:latest":              "latest",
+		"ghcr.io/actions/actions-runner:2.321.0@sha256:0123abcd": "2.321.0",
+	}
+
+	for image, want := range tests {
+		runner := &v1alpha1.EphemeralRunner{}
+		runner.Spec.Spec.Containers = []corev1.Container{{Name: v1alpha1.EphemeralRunnerContainerName, Image: image}}
+		assert.Equal(t, want, ephemeralRunnerVersion(runner), image)
+	}
+}
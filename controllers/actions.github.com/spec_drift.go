@@ -0,0 +1,30 @@
+package actionsgithubcom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/specdrift"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// specDrift tells how the spec of the object has been changed outside of the GitOps tools listed in the suspend-on-drift annotation.
+// It returns an empty message when the object has no such annotation, or has not drifted.
+func specDrift(obj client.Object) (string, error) {
+	v, ok := obj.GetAnnotations()[AnnotationKeySuspendOnDrift]
+	if !ok {
+		return "", nil
+	}
+
+	drifts, err := specdrift.Detect(obj, specdrift.ParseManagers(v))
+	if err != nil {
+		return "", fmt.Errorf("detecting the drift of the spec: %w", err)
+	}
+
+	var changes []string
+	for _, d := range drifts {
+		changes = append(changes, d.String())
+	}
+
+	return strings.Join(changes, "; "), nil
+}
@@ -310,9 +310,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		repository   = st.repo
 	)
 
-	// ListRunners will return all runners managed by GitHub - not restricted to ns
+	// ListRunners will return all runners managed by GitHub - not restricted to ns.
+	// The polling is shed first when the rate limit runs low, leaving the rest to the webhook-based autoscaling.
 	runners, err := ghc.ListRunners(
-		ctx,
+		arcgithub.WithRequestPriority(ctx, arcgithub.PriorityLow),
 		enterprise,
 		organization,
 		repository)
@@ -0,0 +1,55 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	gogithub "github.com/google/go-github/v52/github"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// setCondition sets the condition of the type to True when ok, or False otherwise,
+// keeping the last transition time while the status stays the same.
+func setCondition(conditions *[]metav1.Condition, generation int64, conditionType string, ok bool, reason, message string) {
+	status := metav1.ConditionFalse
+	if ok {
+		status = metav1.ConditionTrue
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// patchConditions patches the status of the object with the conditions set by set, only when they changed.
+// conditions returns the conditions in the status of the object.
+func patchConditions[T client.Object](ctx context.Context, c client.Client, obj T, conditions func(T) *[]metav1.Condition, set func(*[]metav1.Condition)) error {
+	updated := obj.DeepCopyObject().(T)
+
+	set(conditions(updated))
+
+	if equality.Semantic.DeepEqual(*conditions(obj), *conditions(updated)) {
+		return nil
+	}
+
+	return c.Status().Patch(ctx, updated, client.MergeFrom(obj))
+}
+
+// isRateLimited returns true when the error is caused by the GitHub API rate limit,
+// either enforced by GitHub or by the client-side rate limiter.
+func isRateLimited(err error) bool {
+	var (
+		rateLimitErr      *gogithub.RateLimitError
+		abuseRateLimitErr *gogithub.AbuseRateLimitError
+	)
+
+	return arcgithub.IsRateLimitShed(err) || errors.As(err, &rateLimitErr) || errors.As(err, &abuseRateLimitErr)
+}
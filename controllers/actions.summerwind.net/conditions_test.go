@@ -0,0 +1,89 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetRunnerPodConditions(t *testing.T) {
+	pod := &corev1.Pod{}
+	pod.Status.Phase = corev1.PodPending
+
+	var conditions []metav1.Condition
+
+	setRunnerPodConditions(&conditions, 1, pod, false)
+
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeSynced))
+	require.Equal(t, v1alpha1.ConditionReasonPodNotReady, meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeReady).Reason)
+	require.Equal(t, v1alpha1.ConditionReasonWaitingForRegistration, meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeGitHubRegistered).Reason)
+
+	pod.Annotations = map[string]string{AnnotationKeyRunnerID: "123"}
+	pod.Status.Phase = corev1.PodRunning
+
+	setRunnerPodConditions(&conditions, 1, pod, true)
+
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeReady))
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeGitHubRegistered))
+	require.Equal(t, "Runner ID is 123", meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeGitHubRegistered).Message)
+}
+
+func TestRunnerDeploymentStatusConditions(t *testing.T) {
+	rd := &v1alpha1.RunnerDeployment{}
+	rd.Generation = 2
+
+	conditions := runnerDeploymentStatusConditions(rd, 3, 1)
+
+	ready := meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeReady)
+	require.Equal(t, metav1.ConditionFalse, ready.Status)
+	require.Equal(t, v1alpha1.ConditionReasonReplicasUnavailable, ready.Reason)
+	require.Equal(t, "1 of 3 runners available", ready.Message)
+	require.Equal(t, int64(2), ready.ObservedGeneration)
+
+	rd.Status.Conditions = conditions
+
+	conditions = runnerDeploymentStatusConditions(rd, 3, 3)
+
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeReady))
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeSynced))
+	// The conditions of the previous status are left intact
+	require.Equal(t, metav1.ConditionFalse, meta.FindStatusCondition(rd.Status.Conditions, v1alpha1.ConditionTypeReady).Status)
+}
+
+func TestSetHRAScalingConditions(t *testing.T) {
+	var conditions []metav1.Condition
+
+	setHRAScalingConditions(&conditions, 1, true, false, 5)
+
+	scaling := meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeScalingActive)
+	require.Equal(t, metav1.ConditionFalse, scaling.Status)
+	require.Equal(t, v1alpha1.ConditionReasonPaused, scaling.Reason)
+	require.True(t, meta.IsStatusConditionFalse(conditions, v1alpha1.ConditionTypeRateLimited))
+
+	setHRAScalingConditions(&conditions, 1, false, true, 5)
+
+	scaling = meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeScalingActive)
+	require.Equal(t, metav1.ConditionFalse, scaling.Status)
+	require.Equal(t, v1alpha1.ConditionReasonDryRun, scaling.Reason)
+	require.Equal(t, "Suggested 5 replicas in dry-run", scaling.Message)
+
+	setHRAScalingConditions(&conditions, 1, false, false, 5)
+
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeScalingActive))
+	require.True(t, meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionTypeReady))
+}
+
+func TestIsRateLimited(t *testing.T) {
+	require.True(t, isRateLimited(fmt.Errorf("listing runners: %w", &gogithub.RateLimitError{})))
+	require.True(t, isRateLimited(&gogithub.AbuseRateLimitError{}))
+	require.True(t, isRateLimited(fmt.Errorf("minting token: %w", &arcgithub.RateLimitShedError{Until: time.Now()})))
+	require.False(t, isRateLimited(fmt.Errorf("not found")))
+}
@@ -5,6 +5,10 @@ import "time"
 const (
 	LabelKeyRunnerSetName = "runnerset-name"
 	LabelKeyRunner        = "actions-runner"
+
+	// LabelKeyRunnerBusy is the label added onto the runner pod while its runner is running a job,
+	// so that the PodDisruptionBudget of the RunnerDeployment can select the busy runner pods.
+	LabelKeyRunnerBusy = "actions-runner/busy"
 )
 
 const (
@@ -17,6 +21,23 @@ const (
 
 	AnnotationKeyLastRegistrationCheckTime = "actions-runner-controller/last-registration-check-time"
 
+	// AnnotationKeyCostPerRunnerHour is the annotation on a RunnerDeployment or a RunnerSet that tells
+	// the cost of running one of its runners for an hour. It is used by HRA's budget when spec.budget.costPerRunnerHour is omitted.
+	AnnotationKeyCostPerRunnerHour = "actions-runner-controller/cost-per-runner-hour"
+
+	// AnnotationKeyPausedUntil is the annotation on a HorizontalRunnerAutoscaler that pauses its scaling until the RFC3339 time,
+	// regardless of spec.paused. The annotation is ignored once the time has passed.
+	AnnotationKeyPausedUntil = "actions-runner-controller/paused-until"
+
+	// AnnotationKeySuspendOnDrift is the annotation on a RunnerDeployment, a RunnerReplicaSet, a RunnerSet or a HorizontalRunnerAutoscaler
+	// that lists the comma-separated field managers of the GitOps tools, like "argocd-controller".
+	// The resource is not reconciled while its spec has been changed by any other field manager, until the tool syncs it back.
+	AnnotationKeySuspendOnDrift = "actions-runner-controller/suspend-on-drift"
+
+	// AnnotationKeyPromoteCanary is the annotation on a RunnerDeployment that promotes the update paused by spec.strategy.canary.
+	// It is removed once the update completes.
+	AnnotationKeyPromoteCanary = "actions-runner-controller/promote-canary"
+
 	// AnnotationKeyUnregistrationFailureMessage is the annotation that is added onto the pod once it failed to be unregistered from GitHub due to e.g. 422 error
 	AnnotationKeyUnregistrationFailureMessage = annotationKeyPrefix + "unregistration-failure-message"
 
@@ -39,6 +60,51 @@ const (
 
 	AnnotationKeyRunnerID = annotationKeyPrefix + "id"
 
+	// AnnotationKeyRunnerState is the annotation that the runner entrypoint and the job hooks put onto the runner pod
+	// to report the state of the runner in JSON, like {"state":"JobStarted","time":"2023-01-02T15:04:05Z","workflow":{"repository":"owner/repo"}}.
+	// See runnerStateReport for the fields.
+	AnnotationKeyRunnerState = annotationKeyPrefix + "state"
+
+	// AnnotationKeyContainerHookTemplate is the annotation on the runner pod that holds the rendered container hook template,
+	// projected into the runner container for the kubernetes container hooks to create the workflow pods with.
+	AnnotationKeyContainerHookTemplate = annotationKeyPrefix + "container-hook-template"
+
+	// AnnotationKeySecurityDefaults is the annotation on the runner pod template that opts the runner pods out of the
+	// security baseline applied by the pod security defaulting webhook when set to "false".
+	AnnotationKeySecurityDefaults = "actions-runner-controller/security-defaults"
+
+	// AnnotationKeyRegistrationTimeoutTimestamp is the annotation that is added onto the pod once ARC found the runner
+	// not registered to GitHub within the registration timeout. The pod is deleted and recreated right after that.
+	AnnotationKeyRegistrationTimeoutTimestamp = annotationKeyPrefix + "registration-timeout-timestamp"
+
+	// AnnotationKeyDrainTimeout is the annotation on the runner pod that contains the drainTimeout of the runner.
+	AnnotationKeyDrainTimeout = annotationKeyPrefix + "drain-timeout"
+
+	// AnnotationKeyDrainStartTimestamp is the annotation that is added onto the pod once ARC found the runner busy on unregistration
+	// and removed its custom labels so that it isn't assigned another job.
+	AnnotationKeyDrainStartTimestamp = annotationKeyPrefix + "drain-start-timestamp"
+
+	// AnnotationKeyDrainJobCompletedTimestamp is the annotation that the github webhook server adds onto the draining runner pod
+	// on receiving the workflow_job completed event for the runner, so that ARC retries the unregistration without waiting for the retry delay.
+	AnnotationKeyDrainJobCompletedTimestamp = annotationKeyPrefix + "drain-job-completed-timestamp"
+
+	// AnnotationKeyRunnerBusy is the annotation on the runner pod and the Runner that is "true" while the runner is running a workflow job and "false" afterwards,
+	// as notified by the workflow_job webhook events.
+	AnnotationKeyRunnerBusy = annotationKeyPrefix + "busy"
+
+	// AnnotationKeyRunnerJobURL is the annotation on the busy runner pod and Runner that contains the URL of the workflow job the runner is running.
+	AnnotationKeyRunnerJobURL = annotationKeyPrefix + "job-url"
+
+	// AnnotationKeyQuarantineReasons is the annotation on the busy quarantined runner pod that tells why the workflow job it's running is deemed suspicious,
+	// as a comma-separated list of the quarantine reasons.
+	AnnotationKeyQuarantineReasons = annotationKeyPrefix + "quarantine-reasons"
+
+	// AnnotationKeyPreDestroyHook is the annotation on the runner pod that contains the http preDestroy lifecycle hook of the runner in JSON.
+	AnnotationKeyPreDestroyHook = annotationKeyPrefix + "pre-destroy-hook"
+
+	// AnnotationKeyPreDestroyHookCompleteTimestamp is the annotation that is added onto the pod once its preDestroy lifecycle hook succeeded.
+	AnnotationKeyPreDestroyHookCompleteTimestamp = annotationKeyPrefix + "pre-destroy-hook-complete-timestamp"
+
 	// This can be any value but a larger value can make an unregistration timeout longer than configured in practice.
 	DefaultUnregistrationRetryDelay = time.Minute
 
@@ -67,6 +133,17 @@ const (
 	EnvVarRunnerName  = "RUNNER_NAME"
 	EnvVarRunnerToken = "RUNNER_TOKEN"
 
+	// EnvVarRunnerJITConfig is the environment variable the actions runner reads the just-in-time configuration from.
+	EnvVarRunnerJITConfig = "ACTIONS_RUNNER_INPUT_JITCONFIG"
+	// EnvVarRunnerJITConfigFile is the environment variable the runner entrypoint reads the path of the file
+	// containing the just-in-time configuration from, when the configuration is delivered via Vault.
+	EnvVarRunnerJITConfigFile = "RUNNER_JITCONFIG_FILE"
+
+	// EnvVarRunnerPreRegisterHook and EnvVarRunnerPostJobHook are the environment variables the runner entrypoint reads
+	// the preRegister and postJob lifecycle hooks from, in JSON.
+	EnvVarRunnerPreRegisterHook = "RUNNER_PRE_REGISTER_HOOK"
+	EnvVarRunnerPostJobHook     = "RUNNER_POST_JOB_HOOK"
+
 	// defaultHookPath is path to the hook script used when the "containerMode: kubernetes" is specified
 	defaultRunnerHookPath = "/runner/k8s/index.js"
 )
@@ -40,6 +40,17 @@ type batchScaleOperation struct {
 type scaleOperation struct {
 	trigger v1alpha1.ScaleUpTrigger
 	log     logr.Logger
+
+	// workflowRunID is the ID of the workflow run that the scale operation is triggered for.
+	// It is non-zero only when the HRA reserves capacity per workflow run.
+	workflowRunID int64
+
+	// reservedForWorkflowRun is true when the scale operation reserves capacity
+	// for all the queued jobs of the workflow run at once.
+	reservedForWorkflowRun bool
+
+	// profile is the name of the label resource profile the capacity is reserved for.
+	profile string
 }
 
 // Add the scale target to the unbounded queue, blocking until the target is successfully added to the queue.
@@ -92,8 +103,11 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 							}
 						}
 						b.scaleOps = append(b.scaleOps, scaleOperation{
-							log:     *st.log,
-							trigger: st.ScaleUpTrigger,
+							log:                    *st.log,
+							trigger:                st.ScaleUpTrigger,
+							workflowRunID:          st.workflowRunID,
+							reservedForWorkflowRun: st.reservedForWorkflowRun,
+							profile:                st.profile,
 						})
 						batches[nsName] = b
 						ops++
@@ -236,7 +250,13 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 		// This could be fixed by matching events' `workflow_job.run_id` with capacity reservations,
 		// but that would be a lot of work. So for now we allow for some slop, and hope that
 		// GitHub provides a better autoscaling solution soon.
-		if amount > 0 {
+		if amount > 0 && scale.workflowRunID != 0 {
+			n := reserveForWorkflowRun(copy, scale, now)
+
+			scale.log.V(2).Info("Adding capacity reservation for workflow run", "amount", amount, "added", n, "workflowRunID", scale.workflowRunID)
+
+			added += n
+		} else if amount > 0 {
 			scale.log.V(2).Info("Adding capacity reservation", "amount", amount)
 
 			// Parts of this function require that Spec.CapacityReservations.Replicas always equals 1.
@@ -246,6 +266,7 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 					EffectiveTime:  metav1.Time{Time: now},
 					ExpirationTime: metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
 					Replicas:       1,
+					Profile:        scale.profile,
 				})
 			}
 			added += amount
@@ -253,7 +274,9 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 			scale.log.V(2).Info("Removing capacity reservation", "amount", -amount)
 
 			// Remove the requested number of reservations unless there are not that many left
-			if len(copy.Spec.CapacityReservations) > -amount {
+			if scale.profile != "" {
+				copy.Spec.CapacityReservations = removeProfileCapacityReservations(copy.Spec.CapacityReservations, scale.profile, -amount)
+			} else if len(copy.Spec.CapacityReservations) > -amount {
 				copy.Spec.CapacityReservations = copy.Spec.CapacityReservations[-amount:]
 			} else {
 				copy.Spec.CapacityReservations = nil
@@ -279,3 +302,103 @@ func (s *batchScaler) planBatchScale(ctx context.Context, batch batchScaleOperat
 
 	return copy, nil
 }
+
+// reserveForWorkflowRun adds capacity reservations for the scale operation triggered for a workflow run,
+// without reserving twice for the same job, and returns the number of the added reservations.
+//
+// A workflow run can result in both a workflow_run event that reserves capacity for all the queued jobs of the run at once,
+// and a workflow_job event per job, in any order.
+// To not double count, we name the reservations after the workflow run.
+// A reservation made for the whole workflow run is claimed by the first subsequent workflow_job event of the run,
+// instead of the workflow_job event adding another reservation.
+// Similarly, a workflow_run event adds reservations only for the jobs that are not yet reserved by workflow_job events.
+func reserveForWorkflowRun(hra *v1alpha1.HorizontalRunnerAutoscaler, scale scaleOperation, now time.Time) int {
+	runName := workflowRunReservationName(scale.workflowRunID)
+	jobName := workflowRunJobReservationName(scale.workflowRunID)
+
+	var amount int
+
+	if scale.reservedForWorkflowRun {
+		amount = scale.trigger.Amount
+
+		for _, r := range hra.Spec.CapacityReservations {
+			if r.Name == runName || r.Name == jobName {
+				amount--
+			}
+		}
+	} else {
+		amount = scale.trigger.Amount
+
+		for i, r := range hra.Spec.CapacityReservations {
+			if amount == 0 {
+				break
+			}
+
+			if r.Name == runName {
+				hra.Spec.CapacityReservations[i].Name = jobName
+				hra.Spec.CapacityReservations[i].Profile = scale.profile
+				amount--
+			}
+		}
+
+		runName = jobName
+	}
+
+	for i := 0; i < amount; i++ {
+		hra.Spec.CapacityReservations = append(hra.Spec.CapacityReservations, v1alpha1.CapacityReservation{
+			Name:           runName,
+			EffectiveTime:  metav1.Time{Time: now},
+			ExpirationTime: metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
+			Replicas:       1,
+			Profile:        scale.profile,
+		})
+	}
+
+	if amount < 0 {
+		return 0
+	}
+
+	return amount
+}
+
+// removeProfileCapacityReservations removes the oldest n reservations for the label resource profile,
+// so that the completion of a job releases the runner of the same profile.
+// It removes the oldest reservations without a profile instead when there are not enough reservations for the profile,
+// like the ones added before the profile was configured.
+func removeProfileCapacityReservations(reservations []v1alpha1.CapacityReservation, profile string, n int) []v1alpha1.CapacityReservation {
+	removed := make([]bool, len(reservations))
+
+	for _, matches := range []func(r v1alpha1.CapacityReservation) bool{
+		func(r v1alpha1.CapacityReservation) bool { return r.Profile == profile },
+		func(r v1alpha1.CapacityReservation) bool { return r.Profile == "" },
+	} {
+		for i, r := range reservations {
+			if n == 0 {
+				break
+			}
+
+			if !removed[i] && matches(r) {
+				removed[i] = true
+				n--
+			}
+		}
+	}
+
+	var remaining []v1alpha1.CapacityReservation
+
+	for i, r := range reservations {
+		if !removed[i] {
+			remaining = append(remaining, r)
+		}
+	}
+
+	return remaining
+}
+
+func workflowRunReservationName(runID int64) string {
+	return fmt.Sprintf("workflow-run-%d", runID)
+}
+
+func workflowRunJobReservationName(runID int64) string {
+	return fmt.Sprintf("workflow-run-%d-job", runID)
+}
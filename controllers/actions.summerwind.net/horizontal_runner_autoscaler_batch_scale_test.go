@@ -164,3 +164,103 @@ func TestPlanBatchScale(t *testing.T) {
 		})
 	})
 }
+
+func TestPlanBatchScale_WorkflowRun(t *testing.T) {
+	s := &batchScaler{Log: logr.Discard()}
+
+	var (
+		expiry = 10 * time.Minute
+		now    = time.Now()
+		runID  = int64(123)
+	)
+
+	reservation := func(name string) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			Name:           name,
+			EffectiveTime:  metav1.NewTime(now),
+			ExpirationTime: metav1.NewTime(now.Add(expiry)),
+			Replicas:       1,
+		}
+	}
+
+	op := func(amount int, reservedForWorkflowRun bool) scaleOperation {
+		return scaleOperation{
+			log: logr.Discard(),
+			trigger: v1alpha1.ScaleUpTrigger{
+				Amount:   amount,
+				Duration: metav1.Duration{Duration: expiry},
+			},
+			workflowRunID:          runID,
+			reservedForWorkflowRun: reservedForWorkflowRun,
+		}
+	}
+
+	check := func(t *testing.T, existing []v1alpha1.CapacityReservation, ops []scaleOperation, want []v1alpha1.CapacityReservation) {
+		t.Helper()
+
+		hra := &v1alpha1.HorizontalRunnerAutoscaler{
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				CapacityReservations: existing,
+			},
+		}
+
+		got, err := s.planBatchScale(context.Background(), batchScaleOperation{scaleOps: ops}, hra, now)
+
+		require.NoError(t, err)
+		require.Equal(t, want, got.Spec.CapacityReservations)
+	}
+
+	t.Run("workflow run reserves for all the queued jobs", func(t *testing.T) {
+		check(t,
+			nil,
+			[]scaleOperation{op(3, true)},
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123"),
+				reservation("workflow-run-123"),
+				reservation("workflow-run-123"),
+			},
+		)
+	})
+
+	t.Run("workflow job claims the reservation made for the workflow run", func(t *testing.T) {
+		check(t,
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123"),
+				reservation("workflow-run-123"),
+			},
+			[]scaleOperation{op(1, false)},
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123-job"),
+				reservation("workflow-run-123"),
+			},
+		)
+	})
+
+	t.Run("workflow job reserves when the workflow run has no reservation left", func(t *testing.T) {
+		check(t,
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123-job"),
+			},
+			[]scaleOperation{op(1, false)},
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123-job"),
+				reservation("workflow-run-123-job"),
+			},
+		)
+	})
+
+	t.Run("workflow run does not reserve again for the jobs already reserved", func(t *testing.T) {
+		check(t,
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123-job"),
+				reservation("workflow-run-123-job"),
+			},
+			[]scaleOperation{op(3, true)},
+			[]v1alpha1.CapacityReservation{
+				reservation("workflow-run-123-job"),
+				reservation("workflow-run-123-job"),
+				reservation("workflow-run-123"),
+			},
+		)
+	})
+}
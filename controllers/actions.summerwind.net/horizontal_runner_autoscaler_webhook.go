@@ -21,7 +21,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,23 +34,37 @@ import (
 
 	"github.com/go-logr/logr"
 	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/ghes"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/actions/actions-runner-controller/pkg/webhookingest"
 	"github.com/actions/actions-runner-controller/simulator"
 )
 
 const (
 	scaleTargetKey = "scaleTarget"
 
+	runnerPodNameKey = "runnerPodName"
+
 	keyPrefixEnterprise = "enterprises/"
 	keyRunnerGroup      = "/group/"
 
 	DefaultQueueLimit = 100
+
+	DefaultWorkflowRunJobsRetryInterval = 2 * time.Second
+
+	// workflowRunJobsAttempts is the number of times the jobs of a queued workflow run are listed until any of them is queued.
+	// It's bounded so that the webhook delivery is responded to before GitHub times it out.
+	workflowRunJobsAttempts = 3
 )
 
 // HorizontalRunnerAutoscalerGitHubWebhook autoscales a HorizontalRunnerAutoscaler and the RunnerDeployment on each
@@ -62,6 +79,11 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// the administrator is generated and specified in GitHub Web UI.
 	SecretKeyBytes []byte
 
+	// AdditionalSecretKeys is the list of webhook secret tokens accepted in addition to SecretKeyBytes.
+	// Configure both the current and the new secrets while rotating the webhook secret in GitHub
+	// so that no webhook delivery is rejected during the rotation.
+	AdditionalSecretKeys [][]byte
+
 	// GitHub Client to discover runner groups assigned to a repository
 	GitHubClient *github.Client
 
@@ -75,8 +97,62 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// A scale target is enqueued on each retrieval of each eligible webhook event, so that it is processed asynchronously.
 	QueueLimit int
 
+	// RoutingPolicy is either RoutingPolicyFailover or RoutingPolicySpread, and decides to which HRA a webhook event is routed
+	// when multiple HRAs of the same priority match the event. Defaults to RoutingPolicyFailover.
+	RoutingPolicy string
+
+	// ForkPullRequestPolicy is either ForkPullRequestPolicyAllow, ForkPullRequestPolicySandbox, or ForkPullRequestPolicyRefuse,
+	// and decides how the jobs of pull requests from forks of public repositories are routed. Defaults to ForkPullRequestPolicyAllow.
+	ForkPullRequestPolicy string
+
+	// DeliveryRecorder records the last webhook deliveries for troubleshooting.
+	// Set to nil for disabling the recording.
+	DeliveryRecorder *WebhookDeliveryRecorder
+
+	// SourceVerifier rejects the webhook deliveries from outside of the IP ranges GitHub sends webhooks from.
+	// Set to nil for accepting the deliveries from anywhere.
+	SourceVerifier *WebhookSourceVerifier
+
+	// DeliveryStore deduplicates webhook deliveries across the replicas of the webhook server.
+	// Set to nil when the webhook server runs with a single replica.
+	DeliveryStore WebhookDeliveryStore
+
+	// ShardRouter forwards the deliveries for the organizations claimed by the other shards to their webhook servers.
+	// Set to nil when sharding is disabled.
+	ShardRouter *WebhookShardRouter
+
+	// Utilization aggregates the busy time of the runners from the completed workflow jobs for the utilization report.
+	// Set to nil for disabling the report.
+	Utilization *UtilizationTracker
+
+	// EventBus fans the received webhook events out to the other consumers, like the workflow job metrics and the notification sinks.
+	// Set to nil for disabling the fan-out.
+	EventBus *WebhookEventBus
+
+	// EventFilter selects the webhook events the HorizontalRunnerAutoscalers are scaled on.
+	// Set to nil for scaling on all the events.
+	EventFilter *WebhookEventFilter
+
+	// CloudEvents emits the workflow jobs assigned to the runners.
+	// Set to nil for disabling the CloudEvents.
+	CloudEvents cloudevents.Emitter
+
+	// Policy decides whether the HRAs may be scaled up for the queued workflow jobs.
+	// Set to nil for disabling the policy hook.
+	Policy *policyhook.Hook
+
+	// WorkflowRunJobsRetryInterval is how long to wait before listing the jobs of a queued workflow run again
+	// when none of them is queued yet. Defaults to DefaultWorkflowRunJobsRetryInterval.
+	WorkflowRunJobsRetryInterval time.Duration
+
 	worker     *worker
 	workerInit sync.Once
+
+	// filters caches the compiled scale trigger filters by expression
+	filters sync.Map
+
+	// quarantines caches the quarantine reasons of the workflow runs
+	quarantines quarantineCache
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -87,14 +163,56 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(_ context.C
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;patch
+
+// secretKeys returns the webhook secret tokens to validate the webhook payloads against.
+// SecretKeyBytes is always the first one so that the secret index in the log tells if the current secret matched.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) secretKeys() [][]byte {
+	var keys [][]byte
+
+	if len(autoscaler.SecretKeyBytes) > 0 {
+		keys = append(keys, autoscaler.SecretKeyBytes)
+	}
+
+	for _, k := range autoscaler.AdditionalSecretKeys {
+		if len(k) > 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.ResponseWriter, r *http.Request) {
 	var (
 		ok bool
 
 		err error
+
+		payload []byte
+
+		// recorded is set once the payload is validated, so that only the authentic deliveries are recorded
+		recorded *RecordedDelivery
 	)
 
+	if autoscaler.DeliveryRecorder != nil {
+		rw := &recordingResponseWriter{ResponseWriter: w}
+		w = rw
+
+		// This is deferred first to run last, so that it sees the error response written on failure
+		defer func() {
+			if recorded == nil {
+				return
+			}
+
+			recorded.StatusCode = rw.statusCode
+			recorded.Response = rw.body.String()
+
+			autoscaler.DeliveryRecorder.Record(*recorded, payload)
+		}()
+	}
+
 	defer func() {
 		if !ok {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -121,15 +239,30 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	var payload []byte
+	// The deliveries consumed from a message broker have no source address to verify
+	if autoscaler.SourceVerifier != nil && !webhookingest.FromBroker(r.Context()) {
+		if err := autoscaler.SourceVerifier.Verify(r); err != nil {
+			autoscaler.Log.Info("Rejected a webhook delivery from an unknown source", "error", err.Error(), "delivery", r.Header.Get("X-GitHub-Delivery"))
 
-	if len(autoscaler.SecretKeyBytes) > 0 {
-		payload, err = gogithub.ValidatePayload(r, autoscaler.SecretKeyBytes)
+			metrics.IncWebhookDeliveriesRejectedBySource()
+
+			ok = true
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	if secretKeys := autoscaler.secretKeys(); len(secretKeys) > 0 {
+		var matched int
+
+		payload, matched, err = github.ValidateWebhookPayload(r, secretKeys)
 		if err != nil {
 			autoscaler.Log.Error(err, "error validating request body")
 
 			return
 		}
+
+		autoscaler.Log.V(1).Info("validated request body", "secretIndex", matched)
 	} else {
 		payload, err = io.ReadAll(r.Body)
 		if err != nil {
@@ -152,7 +285,14 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	var target *ScaleTarget
+	if autoscaler.DeliveryRecorder != nil {
+		recorded = newRecordedDelivery(r, webhookType, payload)
+	}
+
+	var (
+		target  *ScaleTarget
+		targets []*ScaleTarget
+	)
 
 	log := autoscaler.Log.WithValues(
 		"event", webhookType,
@@ -174,6 +314,101 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 	enterpriseSlug := enterpriseEvent.Enterprise.Slug
 
+	if autoscaler.ShardRouter != nil {
+		if shardURL := autoscaler.ShardRouter.route(r, webhookOwner(payload)); shardURL != "" {
+			if err = autoscaler.ShardRouter.forward(w, r, shardURL, payload); err != nil {
+				log.Error(err, "Could not forward the webhook delivery to its shard", "url", shardURL)
+
+				return
+			}
+
+			ok = true
+
+			log.V(1).Info("Forwarded the webhook delivery to its shard", "url", shardURL)
+
+			return
+		}
+	}
+
+	// scaled is set once any scale target is enqueued, after which the claimed delivery is never released
+	var scaled bool
+
+	// The delivery is claimed before any side effect, like publishing it to the event bus and marking the runners busy,
+	// so that a delivery redelivered by GitHub or received by more than one webhook server replica takes effect only once
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+
+	if autoscaler.DeliveryStore != nil && deliveryID != "" && webhookType != "ping" {
+		var claimed bool
+
+		claimed, err = autoscaler.DeliveryStore.Claim(context.TODO(), deliveryID)
+		if err != nil {
+			log.Error(err, "Could not claim the webhook delivery")
+
+			return
+		}
+
+		if !claimed {
+			log.V(1).Info("Ignoring the webhook delivery as it is already processed by another webhook server replica")
+
+			metrics.IncWebhookDeliveriesDeduplicated()
+
+			ok = true
+
+			w.WriteHeader(http.StatusOK)
+
+			msg := "ignored duplicate delivery"
+
+			if written, err := w.Write([]byte(msg)); err != nil {
+				log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			}
+
+			return
+		}
+
+		// Let a redelivery be processed when this one failed before scaling for any of the targets
+		defer func() {
+			if ok || scaled {
+				return
+			}
+
+			if err := autoscaler.DeliveryStore.Release(context.TODO(), deliveryID); err != nil {
+				log.Error(err, "Could not release the webhook delivery")
+			}
+		}()
+	}
+
+	action := webhookAction(payload)
+
+	if autoscaler.EventBus != nil && webhookType != "ping" {
+		autoscaler.EventBus.Publish(WebhookEvent{
+			Type:       webhookType,
+			Action:     action,
+			DeliveryID: r.Header.Get("X-GitHub-Delivery"),
+			HookID:     r.Header.Get("X-GitHub-Hook-ID"),
+			ReceivedAt: time.Now(),
+			Payload:    payload,
+			Event:      event,
+		})
+	}
+
+	if autoscaler.EventFilter != nil && webhookType != "ping" && !autoscaler.EventFilter.Matches(webhookType, action) {
+		ok = true
+
+		w.WriteHeader(http.StatusOK)
+
+		msg := "ignored by the event filter of the autoscaler"
+
+		log.V(2).Info("Received and ignored a webhook event as it's filtered out of the autoscaler", "action", action)
+
+		if written, err := w.Write([]byte(msg)); err != nil {
+			log.Error(err, "failed writing http response", "msg", msg, "written", written)
+		}
+
+		return
+	}
+
+	filter := autoscaler.scaleUpTriggerFilter(log, webhookType, payload)
+
 	switch e := event.(type) {
 	case *gogithub.WorkflowJobEvent:
 		if workflowJob := e.GetWorkflowJob(); workflowJob != nil {
@@ -190,10 +425,35 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 			)
 		}
 
+		autoscaler.updateRunnerBusyState(context.TODO(), log, e)
+
 		labels := e.WorkflowJob.Labels
 
 		switch action := e.GetAction(); action {
 		case "queued", "completed":
+			var untrusted bool
+
+			untrusted, err = autoscaler.isUntrustedWorkflowRun(context.TODO(), e.Repo, e.WorkflowJob.GetRunID())
+			if err != nil {
+				break
+			}
+
+			if untrusted && autoscaler.ForkPullRequestPolicy == ForkPullRequestPolicyRefuse {
+				ok = true
+
+				w.WriteHeader(http.StatusOK)
+
+				msg := "refused to scale for a pull request from a fork"
+
+				log.Info("Refused to scale for the workflow_job event as it's of a pull request from a fork of the public repository")
+
+				if written, err := w.Write([]byte(msg)); err != nil {
+					log.Error(err, "failed writing http response", "msg", msg, "written", written)
+				}
+
+				return
+			}
+
 			target, err = autoscaler.getJobScaleUpTargetForRepoOrOrg(
 				context.TODO(),
 				log,
@@ -202,13 +462,55 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 				e.Repo.Owner.GetType(),
 				enterpriseSlug,
 				labels,
+				action == "completed",
+				untrusted,
+				filter,
 			)
 			if target == nil {
 				break
 			}
 
+			if target.quarantine != nil {
+				if reasons := autoscaler.quarantineReasons(context.TODO(), log, e.Repo, e.WorkflowJob.GetRunID(), target.quarantine); len(reasons) > 0 {
+					target.profile = v1alpha1.QuarantineProfileName
+
+					log.Info("The workflow job is deemed suspicious and served by a quarantined runner", "reasons", reasons)
+				}
+			}
+
+			if action == "completed" {
+				autoscaler.markDrainingRunnerJobCompleted(context.TODO(), log, target.HorizontalRunnerAutoscaler.Namespace, e.GetWorkflowJob().GetRunnerName())
+
+				if autoscaler.Utilization != nil {
+					autoscaler.Utilization.RecordWorkflowJob(target.HorizontalRunnerAutoscaler, e.GetWorkflowJob())
+				}
+			}
+
 			if e.GetAction() == "queued" {
-				target.Amount = 1
+				if autoscaler.Policy != nil {
+					input := workflowJobPolicyInput(target, e, enterpriseSlug)
+					input.RepositoryInfo = policyRepositoryInfo(context.TODO(), log, autoscaler.Policy, autoscaler.GitHubClient, input.Repository)
+
+					if d := autoscaler.Policy.Decide(context.TODO(), input); !d.Allowed {
+						ok = true
+
+						w.WriteHeader(http.StatusOK)
+
+						msg := "refused to scale by the policy: " + d.Reason
+
+						log.Info("Refused to scale for the workflow_job event as the policy denied it", "reason", d.Reason)
+
+						if written, err := w.Write([]byte(msg)); err != nil {
+							log.Error(err, "failed writing http response", "msg", msg, "written", written)
+						}
+
+						return
+					}
+				}
+
+				if reservesForWorkflowRun(target.HorizontalRunnerAutoscaler) {
+					target.workflowRunID = e.WorkflowJob.GetRunID()
+				}
 				break
 			} else if e.GetAction() == "completed" && e.GetWorkflowJob().GetConclusion() != "skipped" {
 				// We want to filter out "completed" events sent by check runs.
@@ -224,8 +526,14 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 					// that erases the oldest CapacityReservation with the same amount.
 					// If the first CapacityReservation was with Replicas=1, this negative scale target erases that,
 					// so that the resulting desired replicas decreases by 1.
-					target.Amount = -1
-					break
+					var amount int
+					amount, err = workflowJobScaleDownAmount(target.ScaleUpTrigger)
+					if err != nil || amount > 0 {
+						target.Amount = -amount
+						break
+					}
+
+					log.V(1).Info("Ignoring workflow_job event because the scale trigger releases the reserved capacity only on expiration")
 				}
 			}
 			// If the conclusion is "skipped", we will ignore it and fallthrough to the default case.
@@ -239,6 +547,97 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 			return
 		}
+	case *gogithub.WorkflowRunEvent:
+		workflowRun := e.GetWorkflowRun()
+
+		log = log.WithValues(
+			"workflowRun.status", workflowRun.GetStatus(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.Owner.GetLogin(),
+			"repository.owner.type", e.Repo.Owner.GetType(),
+			"enterprise.slug", enterpriseSlug,
+			"action", e.GetAction(),
+			"workflowRun.ID", workflowRun.GetID(),
+		)
+
+		if workflowRun.GetStatus() != "queued" {
+			ok = true
+
+			w.WriteHeader(http.StatusOK)
+
+			log.V(2).Info("Received and ignored a workflow_run event as it is not queued", "action", e.GetAction())
+
+			return
+		}
+
+		untrusted := autoscaler.protectsFromForks(e.Repo) && isForkPullRequestRun(workflowRun)
+
+		if untrusted && autoscaler.ForkPullRequestPolicy == ForkPullRequestPolicyRefuse {
+			ok = true
+
+			w.WriteHeader(http.StatusOK)
+
+			msg := "refused to scale for a pull request from a fork"
+
+			log.Info("Refused to scale for the workflow_run event as it's of a pull request from a fork of the public repository")
+
+			if written, err := w.Write([]byte(msg)); err != nil {
+				log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			}
+
+			return
+		}
+
+		targets, err = autoscaler.getWorkflowRunScaleUpTargets(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			enterpriseSlug,
+			workflowRun.GetID(),
+			untrusted,
+			filter,
+		)
+	case *gogithub.CheckRunEvent:
+		log = log.WithValues(
+			"checkRun.status", e.GetCheckRun().GetStatus(),
+			"checkRun.name", e.GetCheckRun().GetName(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.Owner.GetLogin(),
+			"repository.owner.type", e.Repo.Owner.GetType(),
+			"enterprise.slug", enterpriseSlug,
+			"action", e.GetAction(),
+		)
+
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			enterpriseSlug,
+			and(autoscaler.MatchCheckRunEvent(e), filter),
+		)
+	case *gogithub.DeploymentEvent:
+		log = log.WithValues(
+			"deployment.environment", e.GetDeployment().GetEnvironment(),
+			"deployment.ID", e.GetDeployment().GetID(),
+			"repository.name", e.Repo.GetName(),
+			"repository.owner.login", e.Repo.Owner.GetLogin(),
+			"repository.owner.type", e.Repo.Owner.GetType(),
+			"enterprise.slug", enterpriseSlug,
+		)
+
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			enterpriseSlug,
+			and(autoscaler.MatchDeploymentEvent(e), filter),
+		)
 	case *gogithub.PingEvent:
 		ok = true
 
@@ -260,12 +659,16 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 
 	if err != nil {
-		log.Error(err, "handling check_run event")
+		log.Error(err, "handling github event")
 
 		return
 	}
 
-	if target == nil {
+	if target != nil {
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
 		log.V(1).Info(
 			"Scale target not found. If this is unexpected, ensure that there is exactly one repository-wide or organizational runner deployment that matches this webhook event. If --watch-namespace is set ensure this is configured correctly.",
 		)
@@ -293,17 +696,26 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		autoscaler.worker = newWorker(context.Background(), queueLimit, batchScaler.Add)
 	})
 
-	target.log = &log
-	if ok := autoscaler.worker.Add(target); !ok {
-		log.Error(err, "Could not scale up due to queue full")
-		return
+	var msgs []string
+
+	for _, target := range targets {
+		target.log = &log
+		if ok := autoscaler.worker.Add(target); !ok {
+			log.Error(err, "Could not scale up due to queue full")
+
+			return
+		}
+
+		scaled = true
+
+		msgs = append(msgs, fmt.Sprintf("scaled %s by %d", target.Name, target.Amount))
 	}
 
 	ok = true
 
 	w.WriteHeader(http.StatusOK)
 
-	msg := fmt.Sprintf("scaled %s by %d", target.Name, target.Amount)
+	msg := strings.Join(msgs, ", ")
 
 	log.Info(msg)
 
@@ -347,15 +759,261 @@ type ScaleTarget struct {
 	v1alpha1.HorizontalRunnerAutoscaler
 	v1alpha1.ScaleUpTrigger
 
+	// workflowRunID is the ID of the workflow run that the triggering event belongs to.
+	// It is set only when the HRA reserves capacity per workflow run.
+	workflowRunID int64
+
+	// reservedForWorkflowRun is true when the Amount is the number of all the queued jobs of the workflow run.
+	reservedForWorkflowRun bool
+
+	// profile is the name of the label resource profile of the RunnerDeployment that serves the workflow job.
+	profile string
+
+	// quarantine is the quarantine configuration of the RunnerDeployment that serves the workflow job.
+	quarantine *v1alpha1.Quarantine
+
 	log *logr.Logger
 }
 
+// getWorkflowRunScaleUpTargets returns the scale targets for all the queued jobs of the workflow run.
+// The queued jobs are grouped by their labels so that each group is matched against the scale targets
+// the same way as a workflow_job event. Only the scale targets opted in to reserving capacity per workflow run are returned.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getWorkflowRunScaleUpTargets(
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, runID int64, untrusted bool, filter func(v1alpha1.ScaleUpTrigger) bool,
+) ([]*ScaleTarget, error) {
+	if autoscaler.GitHubClient == nil {
+		log.V(1).Info("Ignoring workflow_run event because GitHub client is not initialized. Provide GitHub authentication to reserve capacity per workflow run")
+		return nil, nil
+	}
+
+	jobs, err := autoscaler.listQueuedWorkflowJobs(ctx, log, owner, repo, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	type queuedJobs struct {
+		labels []string
+		count  int
+	}
+
+	var (
+		groups []*queuedJobs
+		byKey  = map[string]*queuedJobs{}
+	)
+
+	for _, job := range jobs {
+		if job.GetStatus() != "queued" || len(job.Labels) == 0 {
+			continue
+		}
+
+		sorted := append([]string{}, job.Labels...)
+		sort.Strings(sorted)
+		key := strings.Join(sorted, ",")
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &queuedJobs{labels: job.Labels}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.count++
+	}
+
+	var targets []*ScaleTarget
+
+	byHRA := map[types.NamespacedName]*ScaleTarget{}
+
+	for _, g := range groups {
+		target, err := autoscaler.getJobScaleUpTargetForRepoOrOrg(ctx, log, repo, owner, ownerType, enterprise, g.labels, false, untrusted, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if target == nil || !reservesForWorkflowRun(target.HorizontalRunnerAutoscaler) {
+			continue
+		}
+
+		// The amount of the matched scale trigger is per job
+		amount := g.count * target.Amount
+
+		nsName := types.NamespacedName{Namespace: target.Namespace, Name: target.Name}
+		if t, ok := byHRA[nsName]; ok {
+			t.Amount += amount
+			continue
+		}
+
+		target.Amount = amount
+		target.workflowRunID = runID
+		target.reservedForWorkflowRun = true
+
+		byHRA[nsName] = target
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// listQueuedWorkflowJobs lists the jobs of the workflow run, retrying while none of them is queued.
+// GitHub may deliver the workflow_run event before it creates the jobs of the run, like the ones of a matrix.
+// The jobs still missing after the retries are reserved for by their own workflow_job events instead.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) listQueuedWorkflowJobs(ctx context.Context, log logr.Logger, owner, repo string, runID int64) ([]*gogithub.WorkflowJob, error) {
+	interval := autoscaler.WorkflowRunJobsRetryInterval
+	if interval <= 0 {
+		interval = DefaultWorkflowRunJobsRetryInterval
+	}
+
+	for attempt := 1; ; attempt++ {
+		jobs, err := autoscaler.GitHubClient.ListWorkflowJobs(ctx, owner, repo, runID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, job := range jobs {
+			if job.GetStatus() == "queued" {
+				return jobs, nil
+			}
+		}
+
+		if attempt >= workflowRunJobsAttempts {
+			log.V(1).Info("None of the jobs of the workflow run is queued yet. The jobs are reserved for by their workflow_job events", "jobs", len(jobs), "attempts", attempt)
+
+			return jobs, nil
+		}
+
+		log.V(1).Info("Listing the jobs of the workflow run again as none of them is queued yet", "jobs", len(jobs), "retryInterval", interval)
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// selectWorkflowJobScaleUpTrigger returns the workflowJob scale trigger that applies to the workflow job with the labels.
+// A trigger keyed by the job's runs-on label set takes precedence over the trigger without labels.
+// When no trigger applies, it returns nil along with the reason.
+func selectWorkflowJobScaleUpTrigger(triggers []v1alpha1.ScaleUpTrigger, labels []string) (*v1alpha1.ScaleUpTrigger, string) {
+	if len(triggers) == 1 {
+		t := triggers[0]
+
+		if t.GitHubEvent == nil {
+			return nil, "it has no `githubEvent` scale trigger configured"
+		}
+
+		if t.GitHubEvent.WorkflowJob == nil {
+			return nil, "it has no `githubEvent.workflowJob` scale trigger configured"
+		}
+	}
+
+	var (
+		keyed, unkeyed *v1alpha1.ScaleUpTrigger
+	)
+
+	for i := range triggers {
+		t := &triggers[i]
+
+		if t.GitHubEvent == nil || t.GitHubEvent.WorkflowJob == nil {
+			return nil, "it has too many ScaleUpTriggers to be used in workflow_job based scaling"
+		}
+
+		if len(t.GitHubEvent.WorkflowJob.Labels) == 0 {
+			if unkeyed != nil {
+				return nil, "it has too many ScaleUpTriggers without labels to be used in workflow_job based scaling"
+			}
+
+			unkeyed = t
+
+			continue
+		}
+
+		if keyed == nil && labelSetEqual(t.GitHubEvent.WorkflowJob.Labels, labels) {
+			keyed = t
+		}
+	}
+
+	if keyed != nil {
+		return keyed, ""
+	}
+
+	if unkeyed != nil {
+		return unkeyed, ""
+	}
+
+	return nil, "it has no `githubEvent.workflowJob` scale trigger whose labels match the workflow job"
+}
+
+// labelSetEqual returns true when the two label sets are the same, ignoring the "self-hosted" label and the case.
+func labelSetEqual(a, b []string) bool {
+	toSet := func(labels []string) map[string]struct{} {
+		set := map[string]struct{}{}
+		for _, l := range labels {
+			if l == "self-hosted" {
+				continue
+			}
+			set[strings.ToLower(l)] = struct{}{}
+		}
+		return set
+	}
+
+	sa, sb := toSet(a), toSet(b)
+
+	if len(sa) != len(sb) {
+		return false
+	}
+
+	for l := range sa {
+		if _, ok := sb[l]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// workflowJobScaleDownAmount returns the number of the reserved runners to be released on a completed workflow_job event.
+func workflowJobScaleDownAmount(trigger v1alpha1.ScaleUpTrigger) (int, error) {
+	if trigger.ScaleDownFactor == "" {
+		return trigger.Amount, nil
+	}
+
+	factor, err := strconv.ParseFloat(trigger.ScaleDownFactor, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse scaleDownFactor %q: %w", trigger.ScaleDownFactor, err)
+	}
+
+	if factor < 0 || factor > 1 {
+		return 0, fmt.Errorf("scaleDownFactor must be between 0 and 1, but got %s", trigger.ScaleDownFactor)
+	}
+
+	return int(math.Ceil(float64(trigger.Amount) * factor)), nil
+}
+
+func reservesForWorkflowRun(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	for _, t := range hra.Spec.ScaleUpTriggers {
+		if t.GitHubEvent != nil && t.GitHubEvent.WorkflowJob != nil && t.GitHubEvent.WorkflowJob.ReserveForWorkflowRun {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleUpTargetForRepoOrOrg(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string,
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string, scaleDown, untrusted bool, filter func(v1alpha1.ScaleUpTrigger) bool,
 ) (*ScaleTarget, error) {
 
 	scaleTarget := func(value string) (*ScaleTarget, error) {
-		return autoscaler.getJobScaleTarget(ctx, value, labels)
+		return autoscaler.getJobScaleTarget(ctx, value, labels, scaleDown, untrusted, filter)
+	}
+	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, f func(v1alpha1.ScaleUpTrigger) bool,
+) (*ScaleTarget, error) {
+	scaleTarget := func(value string) (*ScaleTarget, error) {
+		return autoscaler.getScaleTarget(ctx, value, f)
 	}
 	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
 }
@@ -397,7 +1055,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 	}
 
 	var visibleGroups *simulator.VisibleRunnerGroups
-	if autoscaler.GitHubClient != nil {
+	if autoscaler.GitHubClient != nil && autoscaler.GitHubClient.Supports(ctx, ghes.CapabilityRunnerGroups) {
 		simu := &simulator.Simulator{
 			Client: autoscaler.GitHubClient,
 			Log:    log,
@@ -416,7 +1074,11 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 		// visibility=all to honor the previous implementation, therefore any available enterprise/organization runner
 		// is a potential target for scaling. This will also avoid doing extra API calls caused by
 		// GitHubClient.GetRunnerGroupsVisibleToRepository in case users are not using custom visibility on their runner
-		// groups or they are using only default runner groups
+		// groups or they are using only default runner groups.
+		// The same applies to GitHub Enterprise Server versions that lack the runner groups API.
+		if autoscaler.GitHubClient != nil {
+			log.V(1).Info("Assuming all runner groups are visible to the repository as the GitHub Enterprise Server lacks the runner groups API")
+		}
 		visibleGroups = managedRunnerGroups
 	}
 
@@ -552,7 +1214,9 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroup
 	return groups, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, name string, labels []string) (*ScaleTarget, error) {
+// getJobScaleTarget returns the HRA found by the key whose runners have the labels of the workflow job.
+// When untrusted is true, only the sandbox HRAs are considered, as the job is of a pull request from a fork of a public repository.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, name string, labels []string, scaleDown, untrusted bool, filter func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
 	hras, err := autoscaler.findHRAsByKey(ctx, name)
 	if err != nil {
 		return nil, err
@@ -560,44 +1224,38 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx
 
 	autoscaler.Log.V(1).Info(fmt.Sprintf("Found %d HRAs by key", len(hras)), "key", name)
 
+	var candidates []*ScaleTarget
+
 HRA:
 	for _, hra := range hras {
 		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
 			continue
 		}
 
-		if len(hra.Spec.ScaleUpTriggers) > 1 {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has too many ScaleUpTriggers to be used in workflow_job based scaling", "hra", hra.Name)
-			continue
-		}
-
 		if len(hra.Spec.ScaleUpTriggers) == 0 {
 			autoscaler.Log.V(1).Info("Skipping this HRA as it has no ScaleUpTriggers configured", "hra", hra.Name)
 			continue
 		}
 
-		scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
-
-		if scaleUpTrigger.GitHubEvent == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent` scale trigger configured", "hra", hra.Name)
+		if untrusted && !hra.Spec.Sandbox {
+			autoscaler.Log.V(1).Info("Skipping this HRA as it isn't a sandbox for the jobs of pull requests from forks", "hra", hra.Name)
+			continue
+		}
 
+		triggers := filterScaleUpTriggers(hra.Spec.ScaleUpTriggers, filter)
+		if len(triggers) == 0 {
+			autoscaler.Log.V(1).Info("Skipping this HRA as none of its ScaleUpTriggers' filters match the event", "hra", hra.Name)
 			continue
 		}
 
-		if scaleUpTrigger.GitHubEvent.WorkflowJob == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent.workflowJob` scale trigger configured", "hra", hra.Name)
+		scaleUpTrigger, reason := selectWorkflowJobScaleUpTrigger(triggers, labels)
+		if scaleUpTrigger == nil {
+			autoscaler.Log.V(1).Info("Skipping this HRA as "+reason, "hra", hra.Name)
 
 			continue
 		}
 
-		duration := scaleUpTrigger.Duration
-		if duration.Duration <= 0 {
-			// Try to release the reserved capacity after at least 10 minutes by default,
-			// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
-			// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
-			// Probably we'd better make this configurable via custom resources in the future?
-			duration.Duration = 10 * time.Minute
-		}
+		trigger := workflowJobScaleUpTrigger(*scaleUpTrigger)
 
 		switch hra.Spec.ScaleTargetRef.Kind {
 		case "RunnerSet":
@@ -630,7 +1288,7 @@ HRA:
 				}
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			candidates = append(candidates, &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: trigger})
 		case "RunnerDeployment", "":
 			var rd v1alpha1.RunnerDeployment
 
@@ -638,36 +1296,113 @@ HRA:
 				return nil, err
 			}
 
-			// Ensure that the RunnerDeployment-managed runners have all the labels requested by the workflow_job.
-			for _, l := range labels {
-				var matched bool
+			// Ensure that the RunnerDeployment-managed runners have all the labels requested by the workflow_job,
+			// either with or without one of the label resource profiles.
+			// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
+			profile, ok := matchLabelResourceProfile(rd.Spec.Template.Spec.Labels, rd.Spec.LabelResourceProfiles, labels)
+			if !ok {
+				continue HRA
+			}
 
-				// ignore "self-hosted" label as all instance here are self-hosted
-				if l == "self-hosted" {
-					continue
-				}
+			candidates = append(candidates, &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: trigger, profile: profile, quarantine: rd.Spec.Quarantine})
+		default:
+			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+		}
+	}
 
-				// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
+	return autoscaler.selectScaleTarget(candidates, scaleDown), nil
+}
 
-				for _, l2 := range rd.Spec.Template.Spec.Labels {
-					if strings.EqualFold(l, l2) {
-						matched = true
-						break
-					}
-				}
+// and returns a func that reports whether the scale trigger satisfies all the funcs.
+func and(fs ...func(v1alpha1.ScaleUpTrigger) bool) func(v1alpha1.ScaleUpTrigger) bool {
+	return func(t v1alpha1.ScaleUpTrigger) bool {
+		for _, f := range fs {
+			if !f(t) {
+				return false
+			}
+		}
 
-				if !matched {
-					continue HRA
-				}
+		return true
+	}
+}
+
+// defaultScaleUpTrigger returns the amount, duration, and scaleDownFactor of the scale trigger that a matching webhook event results in.
+func defaultScaleUpTrigger(scaleUpTrigger v1alpha1.ScaleUpTrigger) v1alpha1.ScaleUpTrigger {
+	duration := scaleUpTrigger.Duration
+	if duration.Duration <= 0 {
+		// Try to release the reserved capacity after at least 10 minutes by default,
+		// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
+		// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
+		// Probably we'd better make this configurable via custom resources in the future?
+		duration.Duration = 10 * time.Minute
+	}
+
+	amount := scaleUpTrigger.Amount
+	if amount <= 0 {
+		amount = 1
+	}
+
+	return v1alpha1.ScaleUpTrigger{
+		Amount:          amount,
+		Duration:        duration,
+		ScaleDownFactor: scaleUpTrigger.ScaleDownFactor,
+	}
+}
+
+// workflowJobScaleUpTrigger is defaultScaleUpTrigger of the workflowJob scale trigger, whose amount applies only along with labels or scaleDownFactor.
+// Otherwise each workflow_job event adds or subtracts a single runner, as the amount of the workflowJob scale triggers used to be ignored.
+func workflowJobScaleUpTrigger(scaleUpTrigger v1alpha1.ScaleUpTrigger) v1alpha1.ScaleUpTrigger {
+	trigger := defaultScaleUpTrigger(scaleUpTrigger)
+
+	if !workflowJobHonorsAmount(scaleUpTrigger) {
+		trigger.Amount = 1
+	}
+
+	return trigger
+}
+
+// workflowJobHonorsAmount returns true when the workflowJob scale trigger sets labels or scaleDownFactor, with which its amount applies.
+func workflowJobHonorsAmount(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	if scaleUpTrigger.ScaleDownFactor != "" {
+		return true
+	}
+
+	return scaleUpTrigger.GitHubEvent != nil && scaleUpTrigger.GitHubEvent.WorkflowJob != nil && len(scaleUpTrigger.GitHubEvent.WorkflowJob.Labels) > 0
+}
+
+// getScaleTarget returns the HRA found by the key that has a scale trigger matching the webhook event.
+// When multiple HRAs match, the one to scale is selected by selectScaleTarget.
+// Unlike getJobScaleTarget, it doesn't check the runner labels as check_run and deployment events don't tell which runners they need.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx context.Context, name string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscaler.Log.V(1).Info(fmt.Sprintf("Found %d HRAs by key", len(hras)), "key", name)
+
+	var candidates []*ScaleTarget
+
+HRA:
+	for _, hra := range hras {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		for _, scaleUpTrigger := range hra.Spec.ScaleUpTriggers {
+			if !f(scaleUpTrigger) {
+				continue
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
-		default:
-			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+			candidates = append(candidates, &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: defaultScaleUpTrigger(scaleUpTrigger)})
+
+			continue HRA
 		}
+
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has no ScaleUpTriggers matching the event", "hra", hra.Name)
 	}
 
-	return nil, nil
+	return autoscaler.selectScaleTarget(candidates, false), nil
 }
 
 func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
@@ -696,6 +1431,10 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr
 		return err
 	}
 
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, runnerPodNameKey, runnerPodNameIndexer); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
 		Named(name).
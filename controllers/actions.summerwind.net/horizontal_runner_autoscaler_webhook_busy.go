@@ -0,0 +1,137 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updateRunnerBusyState annotates the runner pod and the Runner that picked up or completed the workflow job with whether the runner is busy,
+// so that the busy runners can be told apart with kubectl without calling the GitHub API per runner.
+// The job picked up by the runner is also emitted as a CloudEvent.
+//
+// It's best-effort. The annotations can be stale when the events are lost or delivered out of order.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) updateRunnerBusyState(ctx context.Context, log logr.Logger, e *gogithub.WorkflowJobEvent) {
+	runnerName := e.GetWorkflowJob().GetRunnerName()
+	if runnerName == "" {
+		return
+	}
+
+	var busy bool
+
+	switch e.GetAction() {
+	case "in_progress":
+		busy = true
+	case "completed":
+		busy = false
+	default:
+		return
+	}
+
+	jobURL := e.GetWorkflowJob().GetHTMLURL()
+
+	opts := []client.ListOption{client.MatchingFields{runnerPodNameKey: runnerName}}
+	if autoscaler.Namespace != "" {
+		opts = append(opts, client.InNamespace(autoscaler.Namespace))
+	}
+
+	var pods corev1.PodList
+	if err := autoscaler.Client.List(ctx, &pods, opts...); err != nil {
+		log.Error(err, "Failed to list the runner pods that picked up the workflow job", "runner", runnerName)
+		return
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if busy {
+			emitCloudEvent(ctx, log, autoscaler.CloudEvents, cloudevents.TypeRunnerJobAssigned, runnerCloudEventSubject(pod.Namespace, pod.Name), cloudevents.JobAssignedData{
+				Runner: runnerPodCloudEventData(pod),
+				Job:    workflowJobCloudEventData(e.GetWorkflowJob(), e.GetRepo().GetFullName()),
+			})
+		}
+
+		if busy {
+			autoscaler.auditQuarantine(ctx, log, pod, e)
+		}
+
+		if err := patchRunnerBusyAnnotations(ctx, autoscaler.Client, pod, busy, jobURL); err != nil {
+			log.Error(err, "Failed to annotate the runner pod with the busy state", "runner", runnerName)
+			continue
+		}
+
+		// RunnerSet pods have no Runner
+		var runner v1alpha1.Runner
+		if err := autoscaler.Client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &runner); err != nil {
+			if !kerrors.IsNotFound(err) {
+				log.Error(err, "Failed to get the runner that picked up the workflow job", "runner", runnerName)
+			}
+			continue
+		}
+
+		if err := patchRunnerBusyAnnotations(ctx, autoscaler.Client, &runner, busy, jobURL); err != nil {
+			log.Error(err, "Failed to annotate the runner with the busy state", "runner", runnerName)
+			continue
+		}
+
+		log.V(1).Info("Updated the busy state of the runner", "runner", runnerName, "namespace", pod.Namespace, "busy", busy)
+	}
+}
+
+// patchRunnerBusyAnnotations sets the busy annotation of the runner pod or the Runner, along with the job URL while it's busy.
+// The runner pod opted in with nodeAutoscalerHints.doNotDisruptWhenBusy also gets the do-not-disrupt annotations of the node autoscalers.
+// The completion of a job doesn't clear the URL of another job the runner picked up in the meantime.
+func patchRunnerBusyAnnotations(ctx context.Context, c client.Client, obj client.Object, busy bool, jobURL string) error {
+	current := obj.GetAnnotations()
+
+	annotations := make(map[string]string, len(current)+2)
+	for k, v := range current {
+		annotations[k] = v
+	}
+
+	if busy {
+		annotations[AnnotationKeyRunnerJobURL] = jobURL
+	} else {
+		if u, ok := current[AnnotationKeyRunnerJobURL]; ok && u != jobURL {
+			return nil
+		}
+
+		delete(annotations, AnnotationKeyRunnerJobURL)
+	}
+
+	annotations[AnnotationKeyRunnerBusy] = strconv.FormatBool(busy)
+
+	if _, ok := obj.(*corev1.Pod); ok && current[AnnotationKeyDoNotDisruptWhenBusy] == "true" {
+		setDoNotDisruptAnnotations(annotations, busy)
+	}
+
+	if reflect.DeepEqual(current, annotations) {
+		return nil
+	}
+
+	updated := obj.DeepCopyObject().(client.Object)
+	updated.SetAnnotations(annotations)
+
+	return c.Patch(ctx, updated, client.MergeFrom(obj))
+}
+
+// runnerPodNameIndexer indexes the runner pods by name, so that the runner pod that picked up a workflow job can be found
+// without knowing its namespace.
+func runnerPodNameIndexer(rawObj client.Object) []string {
+	pod := rawObj.(*corev1.Pod)
+
+	if _, ok := pod.Labels[LabelKeyRunner]; !ok {
+		return nil
+	}
+
+	return []string{pod.Name}
+}
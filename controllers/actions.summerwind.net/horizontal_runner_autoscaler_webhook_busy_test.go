@@ -0,0 +1,83 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateRunnerBusyState(t *testing.T) {
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde-fghij",
+			Namespace: "runners",
+			Labels:    map[string]string{LabelKeyRunner: ""},
+		},
+	}
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde-fghij",
+			Namespace: "runners",
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithIndex(&corev1.Pod{}, runnerPodNameKey, runnerPodNameIndexer).
+		WithObjects(pod, runner).
+		Build()
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Client: c}
+
+	event := func(action, url string) *gogithub.WorkflowJobEvent {
+		return &gogithub.WorkflowJobEvent{
+			Action: gogithub.String(action),
+			WorkflowJob: &gogithub.WorkflowJob{
+				RunnerName: gogithub.String("example-abcde-fghij"),
+				HTMLURL:    gogithub.String(url),
+			},
+		}
+	}
+
+	key := types.NamespacedName{Namespace: "runners", Name: "example-abcde-fghij"}
+
+	requireAnnotations := func(want map[string]string) {
+		t.Helper()
+
+		var updatedPod corev1.Pod
+		require.NoError(t, c.Get(ctx, key, &updatedPod))
+		require.Equal(t, want, updatedPod.Annotations)
+
+		var updatedRunner v1alpha1.Runner
+		require.NoError(t, c.Get(ctx, key, &updatedRunner))
+		require.Equal(t, want, updatedRunner.Annotations)
+	}
+
+	autoscaler.updateRunnerBusyState(ctx, logr.Discard(), event("in_progress", "https://github.com/test/valid/actions/runs/1/job/1"))
+	requireAnnotations(map[string]string{
+		AnnotationKeyRunnerBusy:   "true",
+		AnnotationKeyRunnerJobURL: "https://github.com/test/valid/actions/runs/1/job/1",
+	})
+
+	// The completion of the previous job delivered late doesn't mark the runner running another job idle
+	autoscaler.updateRunnerBusyState(ctx, logr.Discard(), event("completed", "https://github.com/test/valid/actions/runs/0/job/0"))
+	requireAnnotations(map[string]string{
+		AnnotationKeyRunnerBusy:   "true",
+		AnnotationKeyRunnerJobURL: "https://github.com/test/valid/actions/runs/1/job/1",
+	})
+
+	autoscaler.updateRunnerBusyState(ctx, logr.Discard(), event("completed", "https://github.com/test/valid/actions/runs/1/job/1"))
+	requireAnnotations(map[string]string{
+		AnnotationKeyRunnerBusy: "false",
+	})
+}
@@ -0,0 +1,202 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultWebhookDeliveryDeduplicationTTL = time.Hour
+
+	// LabelKeyWebhookDelivery is the label put on the leases created by LeaseWebhookDeliveryStore,
+	// so that the expired ones can be garbage-collected.
+	LabelKeyWebhookDelivery = "actions-runner-controller/github-webhook-delivery"
+)
+
+// WebhookDeliveryStore records the GitHub webhook deliveries that are being or were processed by any replica of the webhook server,
+// so that the webhook server can run with multiple replicas behind a Service without scaling twice for the same delivery.
+type WebhookDeliveryStore interface {
+	// Claim returns true when the delivery identified by the X-GitHub-Delivery header was not claimed yet.
+	Claim(ctx context.Context, deliveryID string) (bool, error)
+
+	// Release forgets the claim so that a redelivery of the delivery can be processed.
+	// It's called when the webhook server failed to process the claimed delivery.
+	Release(ctx context.Context, deliveryID string) error
+}
+
+// LeaseWebhookDeliveryStore is a WebhookDeliveryStore that claims a delivery by creating a Kubernetes Lease named after it.
+// As creating an object is atomic, exactly one replica succeeds in claiming a delivery.
+//
+// It implements controller-runtime's manager.Runnable to periodically delete the expired leases.
+type LeaseWebhookDeliveryStore struct {
+	// Client is used to create and delete leases.
+	Client client.Client
+	// Reader is used to read leases. This should be an uncached reader like the manager's APIReader,
+	// as the webhook server's cache is limited to HRAs and it must see the leases created by other replicas.
+	Reader client.Reader
+	Log    logr.Logger
+
+	// Namespace is the namespace in which the leases are created.
+	Namespace string
+	// Identity is recorded as the holder of the leases created by this replica.
+	Identity string
+	// TTL is how long a delivery is remembered. Keep this longer than the period GitHub or the redeliverer redelivers a delivery in.
+	TTL time.Duration
+}
+
+func (s *LeaseWebhookDeliveryStore) ttl() time.Duration {
+	if s.TTL == 0 {
+		return DefaultWebhookDeliveryDeduplicationTTL
+	}
+
+	return s.TTL
+}
+
+// leaseName returns a valid object name for the delivery ID, which is usually but not necessarily a UUID.
+func (s *LeaseWebhookDeliveryStore) leaseName(deliveryID string) string {
+	sum := sha256.Sum256([]byte(deliveryID))
+
+	return "github-webhook-delivery-" + hex.EncodeToString(sum[:])[:32]
+}
+
+func (s *LeaseWebhookDeliveryStore) Claim(ctx context.Context, deliveryID string) (bool, error) {
+	return s.claim(ctx, deliveryID, time.Now())
+}
+
+func (s *LeaseWebhookDeliveryStore) claim(ctx context.Context, deliveryID string, now time.Time) (bool, error) {
+	ttlSeconds := int32(s.ttl().Seconds())
+	acquireTime := metav1.NewMicroTime(now)
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: s.Namespace,
+			Name:      s.leaseName(deliveryID),
+			Labels: map[string]string{
+				LabelKeyWebhookDelivery: "true",
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &s.Identity,
+			AcquireTime:          &acquireTime,
+			LeaseDurationSeconds: &ttlSeconds,
+		},
+	}
+
+	err := s.Client.Create(ctx, lease)
+	if err == nil {
+		return true, nil
+	} else if !kerrors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	var existing coordinationv1.Lease
+
+	if err := s.Reader.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: lease.Name}, &existing); err != nil {
+		if kerrors.IsNotFound(err) {
+			// Released by another replica in the meantime. Let GitHub or the redeliverer retry.
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if !leaseExpired(existing, now) {
+		return false, nil
+	}
+
+	// The lease is a leftover that the garbage collection didn't delete yet
+	if err := s.Client.Delete(ctx, &existing, client.Preconditions{UID: &existing.UID}); err != nil && !kerrors.IsNotFound(err) {
+		return false, err
+	}
+
+	lease.ResourceVersion = ""
+
+	if err := s.Client.Create(ctx, lease); err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *LeaseWebhookDeliveryStore) Release(ctx context.Context, deliveryID string) error {
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: s.Namespace,
+			Name:      s.leaseName(deliveryID),
+		},
+	}
+
+	return client.IgnoreNotFound(s.Client.Delete(ctx, lease))
+}
+
+func (s *LeaseWebhookDeliveryStore) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.ttl())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.deleteExpired(ctx, time.Now()); err != nil {
+				s.Log.Error(err, "Could not delete expired webhook delivery leases")
+			}
+		}
+	}
+}
+
+// deleteExpired deletes the leases of the deliveries claimed more than TTL ago, returning the number of deleted leases.
+func (s *LeaseWebhookDeliveryStore) deleteExpired(ctx context.Context, now time.Time) (int, error) {
+	var leases coordinationv1.LeaseList
+
+	if err := s.Reader.List(ctx, &leases, client.InNamespace(s.Namespace), client.MatchingLabels{LabelKeyWebhookDelivery: "true"}); err != nil {
+		return 0, err
+	}
+
+	var deleted int
+
+	for i := range leases.Items {
+		l := &leases.Items[i]
+
+		if !leaseExpired(*l, now) {
+			continue
+		}
+
+		if err := s.Client.Delete(ctx, l, client.Preconditions{UID: &l.UID}); err != nil {
+			if kerrors.IsNotFound(err) || kerrors.IsConflict(err) {
+				continue
+			}
+
+			return deleted, err
+		}
+
+		deleted++
+	}
+
+	if deleted > 0 {
+		s.Log.V(1).Info("Deleted expired webhook delivery leases", "count", deleted)
+	}
+
+	return deleted, nil
+}
+
+func leaseExpired(l coordinationv1.Lease, now time.Time) bool {
+	if l.Spec.AcquireTime == nil || l.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	return !now.Before(l.Spec.AcquireTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second))
+}
@@ -0,0 +1,126 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLeaseWebhookDeliveryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).Build()
+
+	newStore := func(identity string) *LeaseWebhookDeliveryStore {
+		return &LeaseWebhookDeliveryStore{
+			Client:    c,
+			Reader:    c,
+			Log:       logr.Discard(),
+			Namespace: "default",
+			Identity:  identity,
+			TTL:       time.Hour,
+		}
+	}
+
+	a, b := newStore("a"), newStore("b")
+
+	claimed, err := a.claim(ctx, "72d3162e-cc78-11e3-81ab-4c9367dc0958", now)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// Another replica receiving the same delivery
+	claimed, err = b.claim(ctx, "72d3162e-cc78-11e3-81ab-4c9367dc0958", now)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	claimed, err = b.claim(ctx, "another-delivery", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// A released delivery can be claimed again, e.g. on redelivery
+	require.NoError(t, a.Release(ctx, "another-delivery"))
+
+	claimed, err = a.claim(ctx, "another-delivery", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// An expired lease doesn't prevent the delivery from being claimed
+	claimed, err = b.claim(ctx, "72d3162e-cc78-11e3-81ab-4c9367dc0958", now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	deleted, err := a.deleteExpired(ctx, now.Add(time.Hour+time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 0, deleted)
+
+	deleted, err = a.deleteExpired(ctx, now.Add(time.Hour+2*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+
+	var leases coordinationv1.LeaseList
+	require.NoError(t, c.List(ctx, &leases, client.InNamespace("default")))
+	require.Len(t, leases.Items, 1)
+	require.Equal(t, "b", *leases.Items[0].Spec.HolderIdentity)
+}
+
+func TestWebhookClaimsDeliveryBeforeSideEffects(t *testing.T) {
+	c := clientfake.NewClientBuilder().WithScheme(sc).Build()
+
+	bus := &WebhookEventBus{Log: logr.Discard()}
+	bus.Register(WebhookEventConsumer{Name: "all", Handle: func(context.Context, WebhookEvent) error { return nil }})
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:   c,
+		Log:      logr.Discard(),
+		EventBus: bus,
+		DeliveryStore: &LeaseWebhookDeliveryStore{
+			Client:    c,
+			Reader:    c,
+			Log:       logr.Discard(),
+			Namespace: "default",
+			Identity:  "a",
+		},
+	}
+
+	deliver := func(eventType, deliveryID, payload string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(payload))
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		webhook.Handle(rec, req)
+		return rec
+	}
+
+	job := `{"action":"in_progress","workflow_job":{"id":1,"runner_name":"example-runner"},"repository":{"name":"test","owner":{"login":"test"}}}`
+
+	require.Equal(t, http.StatusOK, deliver("workflow_job", "1", job).Code)
+	require.Len(t, bus.subscriptions[0].queue, 1)
+
+	// The redelivery is neither published nor marks the runner busy again
+	rec := deliver("workflow_job", "1", job)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ignored duplicate delivery", rec.Body.String())
+	require.Len(t, bus.subscriptions[0].queue, 1)
+
+	require.Equal(t, http.StatusOK, deliver("workflow_job", "2", job).Code)
+	require.Len(t, bus.subscriptions[0].queue, 2)
+
+	// The delivery that failed is released, so that its redelivery is processed
+	require.Equal(t, http.StatusInternalServerError, deliver("star", "3", `{"action":"created"}`).Code)
+	require.Equal(t, http.StatusInternalServerError, deliver("star", "3", `{"action":"created"}`).Code)
+	require.Len(t, bus.subscriptions[0].queue, 4)
+}
@@ -0,0 +1,38 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// markDrainingRunnerJobCompleted annotates the draining runner pod that ran the completed workflow job,
+// so that the runner controller retries unregistering the runner right away rather than after the unregistration retry delay.
+// Runner pods that aren't draining are left untouched.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) markDrainingRunnerJobCompleted(ctx context.Context, log logr.Logger, namespace, runnerName string) {
+	if runnerName == "" {
+		return
+	}
+
+	var pod corev1.Pod
+	if err := autoscaler.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: runnerName}, &pod); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to get the runner pod that completed the workflow job", "runner", runnerName)
+		}
+		return
+	}
+
+	if _, ok := getAnnotation(&pod, AnnotationKeyDrainStartTimestamp); !ok {
+		return
+	}
+
+	if _, err := annotatePodOnce(ctx, autoscaler.Client, log, &pod, AnnotationKeyDrainJobCompletedTimestamp, time.Now().Format(time.RFC3339)); err != nil {
+		return
+	}
+
+	log.V(1).Info("Marked the draining runner pod as completed its workflow job", "runner", runnerName)
+}
@@ -0,0 +1,69 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMarkDrainingRunnerJobCompleted(t *testing.T) {
+	ctx := context.Background()
+
+	draining := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "draining",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKeyDrainTimeout:        "10m",
+				AnnotationKeyDrainStartTimestamp: time.Now().Format(time.RFC3339),
+			},
+		},
+	}
+	idle := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "idle",
+			Namespace: "default",
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(draining, idle).Build()
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Client: c}
+
+	for _, name := range []string{"draining", "idle", "missing", ""} {
+		autoscaler.markDrainingRunnerJobCompleted(ctx, logr.Discard(), "default", name)
+	}
+
+	var pod corev1.Pod
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "draining"}, &pod))
+	require.Contains(t, pod.Annotations, AnnotationKeyDrainJobCompletedTimestamp)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "idle"}, &pod))
+	require.NotContains(t, pod.Annotations, AnnotationKeyDrainJobCompletedTimestamp)
+}
+
+func TestDrainTimedOut(t *testing.T) {
+	pod := func(timeout string, start time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					AnnotationKeyDrainTimeout:        timeout,
+					AnnotationKeyDrainStartTimestamp: start.Format(time.RFC3339),
+				},
+			},
+		}
+	}
+
+	require.False(t, drainTimedOut(logr.Discard(), &corev1.Pod{}))
+	require.False(t, drainTimedOut(logr.Discard(), pod("10m", time.Now().Add(-time.Minute))))
+	require.True(t, drainTimedOut(logr.Discard(), pod("10m", time.Now().Add(-11*time.Minute))))
+	require.False(t, drainTimedOut(logr.Discard(), pod("invalid", time.Now().Add(-11*time.Minute))))
+}
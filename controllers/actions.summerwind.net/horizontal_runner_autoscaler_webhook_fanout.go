@@ -0,0 +1,331 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultWebhookFanoutQueueLimit  = 1000
+	DefaultWebhookFanoutSinkTimeout = 10 * time.Second
+
+	// The types of the consumers in the fan-out configuration
+	WebhookFanoutConsumerAutoscaler = "autoscaler"
+	WebhookFanoutConsumerMetrics    = "metrics"
+	WebhookFanoutConsumerHTTP       = "http"
+
+	webhookFanoutResultDelivered = "delivered"
+	webhookFanoutResultFailed    = "failed"
+	webhookFanoutResultDropped   = "dropped"
+)
+
+// WebhookEvent is a webhook delivery received by the webhook server, fanned out by WebhookEventBus to the consumers.
+type WebhookEvent struct {
+	Type       string
+	Action     string
+	DeliveryID string
+	HookID     string
+	ReceivedAt time.Time
+
+	Payload []byte
+
+	// Event is the payload parsed by go-github, like *github.WorkflowJobEvent.
+	Event interface{}
+}
+
+// WebhookEventFilter selects the webhook events a consumer receives.
+type WebhookEventFilter struct {
+	// Events are the types of the webhook events, like workflow_job. Empty for any type.
+	Events []string `json:"events,omitempty"`
+
+	// Actions are the actions of the webhook events, like queued and completed. Empty for any action.
+	Actions []string `json:"actions,omitempty"`
+}
+
+// Matches tells whether the event of the type and the action passes the filter.
+func (f WebhookEventFilter) Matches(eventType, action string) bool {
+	return (len(f.Events) == 0 || slices.Contains(f.Events, eventType)) && (len(f.Actions) == 0 || slices.Contains(f.Actions, action))
+}
+
+// WebhookEventConsumer receives the webhook events that pass its filter, one at a time in the order of their receipt.
+type WebhookEventConsumer struct {
+	Name string
+
+	Filter WebhookEventFilter
+
+	// QueueLimit is the number of the events waiting for the consumer, beyond which the new events are dropped,
+	// so that a slow consumer never slows down the webhook server or the other consumers.
+	QueueLimit int
+
+	Handle func(ctx context.Context, e WebhookEvent) error
+}
+
+type webhookEventSubscription struct {
+	WebhookEventConsumer
+
+	queue chan WebhookEvent
+}
+
+// WebhookEventBus fans each webhook delivery received by the webhook server out to the registered consumers,
+// like the actions-metrics EventReader and the notification sinks, so that a single webhook can serve all of them.
+//
+// The autoscaler handles the deliveries synchronously in the webhook handler, so that its response tells how it scaled,
+// while each of the other consumers handles them asynchronously from its own bounded queue.
+//
+// It implements controller-runtime's manager.Runnable so that it can be added to the webhook server's manager.
+type WebhookEventBus struct {
+	Log logr.Logger
+
+	subscriptions []*webhookEventSubscription
+}
+
+// Register adds the consumer. Must be called before Start.
+func (b *WebhookEventBus) Register(c WebhookEventConsumer) {
+	queueLimit := c.QueueLimit
+	if queueLimit <= 0 {
+		queueLimit = DefaultWebhookFanoutQueueLimit
+	}
+
+	b.subscriptions = append(b.subscriptions, &webhookEventSubscription{
+		WebhookEventConsumer: c,
+		queue:                make(chan WebhookEvent, queueLimit),
+	})
+}
+
+// Publish enqueues the event for each consumer whose filter it passes, without waiting for the consumers.
+func (b *WebhookEventBus) Publish(e WebhookEvent) {
+	for _, s := range b.subscriptions {
+		if !s.Filter.Matches(e.Type, e.Action) {
+			continue
+		}
+
+		select {
+		case s.queue <- e:
+			metrics.SetWebhookFanoutQueueLength(s.Name, len(s.queue))
+		default:
+			b.Log.Info("Dropped a webhook event as the queue of the consumer is full", "consumer", s.Name, "event", e.Type, "delivery", e.DeliveryID)
+
+			metrics.IncWebhookFanoutEvents(s.Name, webhookFanoutResultDropped)
+		}
+	}
+}
+
+func (b *WebhookEventBus) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for _, s := range b.subscriptions {
+		s := s
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			b.consume(ctx, s)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (b *WebhookEventBus) consume(ctx context.Context, s *webhookEventSubscription) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-s.queue:
+			metrics.SetWebhookFanoutQueueLength(s.Name, len(s.queue))
+
+			result := webhookFanoutResultDelivered
+
+			if err := s.Handle(ctx, e); err != nil {
+				b.Log.Error(err, "Consumer failed handling a webhook event", "consumer", s.Name, "event", e.Type, "delivery", e.DeliveryID)
+
+				result = webhookFanoutResultFailed
+			}
+
+			metrics.IncWebhookFanoutEvents(s.Name, result)
+			metrics.ObserveWebhookFanoutLag(s.Name, time.Since(e.ReceivedAt))
+		}
+	}
+}
+
+// WebhookEventHTTPSink is a notification sink that posts the payloads of the webhook events to an HTTP endpoint,
+// with the same headers as GitHub sends, so that the endpoint can handle them like the webhook deliveries of GitHub.
+type WebhookEventHTTPSink struct {
+	URL string
+
+	// Secret signs the payloads into the X-Hub-Signature-256 header. The payloads are unsigned when empty.
+	Secret []byte
+
+	// HTTPClient posts the payloads. Defaults to a client timing out after DefaultWebhookFanoutSinkTimeout.
+	HTTPClient *http.Client
+}
+
+func (s *WebhookEventHTTPSink) Handle(ctx context.Context, e WebhookEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(e.Payload))
+	if err != nil {
+		return fmt.Errorf("creating the request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "actions-runner-controller-github-webhook-server")
+	req.Header.Set("X-GitHub-Event", e.Type)
+	req.Header.Set("X-GitHub-Delivery", e.DeliveryID)
+	req.Header.Set("X-GitHub-Hook-ID", e.HookID)
+
+	if len(s.Secret) > 0 {
+		mac := hmac.New(sha256.New, s.Secret)
+		mac.Write(e.Payload)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookFanoutSinkTimeout}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", s.URL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+		return fmt.Errorf("posting to %s: unexpected status %s: %s", s.URL, res.Status, bytes.TrimSpace(msg))
+	}
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return nil
+}
+
+// WebhookFanoutConfig is the configuration of the consumers of the webhook events, read from the file of -fanout-config.
+type WebhookFanoutConfig struct {
+	Consumers []WebhookFanoutConsumer `json:"consumers"`
+}
+
+// WebhookFanoutConsumer configures a consumer of the webhook events.
+type WebhookFanoutConsumer struct {
+	// Name identifies the consumer in the logs and the metrics.
+	Name string `json:"name"`
+
+	// Type is either "autoscaler", "metrics", or "http".
+	// "autoscaler" filters the events the HorizontalRunnerAutoscalers are scaled on, which are all the events when omitted.
+	// "metrics" exports the workflow job metrics of the actions-metrics-server from the webhook server.
+	// "http" posts the events to the URL.
+	Type string `json:"type"`
+
+	WebhookEventFilter
+
+	QueueLimit int `json:"queueLimit,omitempty"`
+
+	// URL, SecretEnv, and Timeout configure the "http" consumer.
+	// SecretEnv is the name of the environment variable of the secret to sign the payloads with.
+	URL       string           `json:"url,omitempty"`
+	SecretEnv string           `json:"secretEnv,omitempty"`
+	Timeout   *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LoadWebhookFanoutConfig reads the fan-out configuration from the YAML or JSON file.
+func LoadWebhookFanoutConfig(path string) (*WebhookFanoutConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c WebhookFanoutConfig
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("reading fan-out config %s: %w", path, err)
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("reading fan-out config %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+func (c *WebhookFanoutConfig) validate() error {
+	names := map[string]bool{}
+	types := map[string]bool{}
+
+	for _, consumer := range c.Consumers {
+		if consumer.Name == "" {
+			return errors.New("consumer name is required")
+		}
+
+		if names[consumer.Name] {
+			return fmt.Errorf("consumer %s is duplicated", consumer.Name)
+		}
+		names[consumer.Name] = true
+
+		switch consumer.Type {
+		case WebhookFanoutConsumerAutoscaler, WebhookFanoutConsumerMetrics:
+			if types[consumer.Type] {
+				return fmt.Errorf("consumer %s: only one consumer of type %s is allowed", consumer.Name, consumer.Type)
+			}
+		case WebhookFanoutConsumerHTTP:
+			if consumer.URL == "" {
+				return fmt.Errorf("consumer %s: url is required", consumer.Name)
+			}
+		default:
+			return fmt.Errorf("consumer %s: type must be one of %q, %q, and %q", consumer.Name, WebhookFanoutConsumerAutoscaler, WebhookFanoutConsumerMetrics, WebhookFanoutConsumerHTTP)
+		}
+		types[consumer.Type] = true
+	}
+
+	return nil
+}
+
+// NewWebhookEventHTTPSink returns the sink of the "http" consumer.
+func (c WebhookFanoutConsumer) NewWebhookEventHTTPSink() (*WebhookEventHTTPSink, error) {
+	sink := &WebhookEventHTTPSink{URL: c.URL}
+
+	if c.SecretEnv != "" {
+		secret := os.Getenv(c.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("consumer %s: environment variable %s is empty", c.Name, c.SecretEnv)
+		}
+
+		sink.Secret = []byte(secret)
+	}
+
+	if c.Timeout != nil {
+		sink.HTTPClient = &http.Client{Timeout: c.Timeout.Duration}
+	}
+
+	return sink, nil
+}
+
+// webhookAction returns the action of the webhook payload, like queued and completed of workflow_job, or empty when it has none.
+func webhookAction(payload []byte) string {
+	var e struct {
+		Action string `json:"action"`
+	}
+
+	// The payload is already parsed successfully by the webhook handler
+	_ = json.Unmarshal(payload, &e)
+
+	return e.Action
+}
@@ -0,0 +1,163 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEventBus(t *testing.T) {
+	bus := &WebhookEventBus{Log: logr.Discard()}
+
+	var all, completed []string
+
+	bus.Register(WebhookEventConsumer{
+		Name: "all",
+		Handle: func(_ context.Context, e WebhookEvent) error {
+			all = append(all, e.DeliveryID)
+			return nil
+		},
+	})
+	bus.Register(WebhookEventConsumer{
+		Name:       "completed",
+		Filter:     WebhookEventFilter{Events: []string{"workflow_job"}, Actions: []string{"completed"}},
+		QueueLimit: 1,
+		Handle: func(_ context.Context, e WebhookEvent) error {
+			completed = append(completed, e.DeliveryID)
+			return nil
+		},
+	})
+
+	bus.Publish(WebhookEvent{Type: "workflow_job", Action: "queued", DeliveryID: "1"})
+	bus.Publish(WebhookEvent{Type: "workflow_job", Action: "completed", DeliveryID: "2"})
+	bus.Publish(WebhookEvent{Type: "check_run", Action: "completed", DeliveryID: "3"})
+	// Dropped as the queue of the second consumer is full
+	bus.Publish(WebhookEvent{Type: "workflow_job", Action: "completed", DeliveryID: "4"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = bus.Start(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(bus.subscriptions[0].queue) == 0 && len(bus.subscriptions[1].queue) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	require.Equal(t, []string{"1", "2", "3", "4"}, all)
+	require.Equal(t, []string{"2"}, completed)
+}
+
+func TestWebhookEventHTTPSink(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+
+		if r.Header.Get("X-GitHub-Delivery") == "fail" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	sink := &WebhookEventHTTPSink{URL: srv.URL, Secret: []byte("secret")}
+
+	payload := []byte(`{"action":"queued"}`)
+
+	require.NoError(t, sink.Handle(context.Background(), WebhookEvent{Type: "workflow_job", DeliveryID: "1", HookID: "2", Payload: payload}))
+	require.Equal(t, payload, body)
+	require.Equal(t, "workflow_job", header.Get("X-GitHub-Event"))
+	require.Equal(t, "1", header.Get("X-GitHub-Delivery"))
+	require.Equal(t, "2", header.Get("X-GitHub-Hook-ID"))
+
+	// The sink can validate the payload with the secret like a delivery of GitHub
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header = header.Clone()
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	_, err := gogithub.ValidatePayload(req, []byte("secret"))
+	require.NoError(t, err)
+
+	err = sink.Handle(context.Background(), WebhookEvent{Type: "workflow_job", DeliveryID: "fail", Payload: payload})
+	require.ErrorContains(t, err, "503 Service Unavailable: unavailable")
+}
+
+func TestLoadWebhookFanoutConfig(t *testing.T) {
+	load := func(t *testing.T, content string) (*WebhookFanoutConfig, error) {
+		t.Helper()
+
+		path := filepath.Join(t.TempDir(), "fanout.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		return LoadWebhookFanoutConfig(path)
+	}
+
+	c, err := load(t, `
+consumers:
+- name: autoscaler
+  type: autoscaler
+  events: [workflow_job]
+- name: metrics
+  type: metrics
+  events: [workflow_job]
+- name: notifications
+  type: http
+  url: https://example.com/hooks
+  actions: [completed]
+  timeout: 5s
+`)
+	require.NoError(t, err)
+	require.Len(t, c.Consumers, 3)
+	require.Equal(t, []string{"workflow_job"}, c.Consumers[0].Events)
+	require.Equal(t, []string{"completed"}, c.Consumers[2].Actions)
+	require.Equal(t, 5*time.Second, c.Consumers[2].Timeout.Duration)
+
+	_, err = load(t, `
+consumers:
+- name: a
+  type: http
+`)
+	require.ErrorContains(t, err, "consumer a: url is required")
+
+	_, err = load(t, `
+consumers:
+- name: a
+  type: metrics
+- name: b
+  type: metrics
+`)
+	require.ErrorContains(t, err, "only one consumer of type metrics is allowed")
+
+	_, err = load(t, `
+consumers:
+- name: a
+  type: slack
+`)
+	require.ErrorContains(t, err, "consumer a: type must be one of")
+
+	_, err = load(t, `
+consumers:
+- name: a
+  type: metrics
+  unknown: true
+`)
+	require.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/celfilter"
+	"github.com/go-logr/logr"
+)
+
+// scaleUpTriggerFilter returns a func that reports whether the `githubEvent.filter` of the scale trigger matches the webhook payload.
+// A trigger without filter matches any payload. A filter that is invalid or fails to evaluate is logged and treated as not matching.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) scaleUpTriggerFilter(log logr.Logger, eventType string, payload []byte) func(v1alpha1.ScaleUpTrigger) bool {
+	var (
+		event   map[string]interface{}
+		decoded bool
+	)
+
+	return func(t v1alpha1.ScaleUpTrigger) bool {
+		if t.GitHubEvent == nil || t.GitHubEvent.Filter == "" {
+			return true
+		}
+
+		expr := t.GitHubEvent.Filter
+
+		f, err := autoscaler.compileFilter(expr)
+		if err != nil {
+			log.Error(err, "Ignoring the scale trigger as its filter is invalid", "filter", expr)
+
+			return false
+		}
+
+		// Decode the payload only once and only when any of the triggers has a filter
+		if !decoded {
+			decoded = true
+
+			if err := json.Unmarshal(payload, &event); err != nil {
+				log.Error(err, "Could not decode the webhook payload for evaluating scale trigger filters")
+			}
+		}
+
+		matched, err := f.Match(eventType, event)
+		if err != nil {
+			log.V(1).Info("Ignoring the scale trigger as its filter failed to evaluate against the event", "error", err.Error())
+
+			return false
+		}
+
+		if !matched {
+			log.V(1).Info("Ignoring the scale trigger as its filter doesn't match the event", "filter", expr)
+		}
+
+		return matched
+	}
+}
+
+// compileFilter compiles the CEL expression, caching the result as the same filters are evaluated on every webhook event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) compileFilter(expr string) (*celfilter.Filter, error) {
+	if f, ok := autoscaler.filters.Load(expr); ok {
+		return f.(*celfilter.Filter), nil
+	}
+
+	f, err := celfilter.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscaler.filters.Store(expr, f)
+
+	return f, nil
+}
+
+// filterScaleUpTriggers returns the scale triggers for which the filter returns true.
+func filterScaleUpTriggers(triggers []v1alpha1.ScaleUpTrigger, filter func(v1alpha1.ScaleUpTrigger) bool) []v1alpha1.ScaleUpTrigger {
+	if filter == nil {
+		return triggers
+	}
+
+	var filtered []v1alpha1.ScaleUpTrigger
+
+	for _, t := range triggers {
+		if filter(t) {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered
+}
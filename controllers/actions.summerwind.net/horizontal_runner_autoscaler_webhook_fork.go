@@ -0,0 +1,69 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+const (
+	// ForkPullRequestPolicyAllow routes the jobs of pull requests from forks like any other job.
+	ForkPullRequestPolicyAllow = "allow"
+
+	// ForkPullRequestPolicySandbox routes the jobs of pull requests from forks of public repositories only to the HRAs with `sandbox: true`.
+	ForkPullRequestPolicySandbox = "sandbox"
+
+	// ForkPullRequestPolicyRefuse never scales up for the jobs of pull requests from forks of public repositories.
+	ForkPullRequestPolicyRefuse = "refuse"
+)
+
+// pullRequestEvents are the events triggering the workflow runs that may run the code of the pull request.
+// pull_request_target runs the workflow of the base branch, but it's commonly used to check out and build the head of the pull request.
+var pullRequestEvents = map[string]bool{
+	"pull_request":                true,
+	"pull_request_review":         true,
+	"pull_request_review_comment": true,
+	"pull_request_target":         true,
+}
+
+// isForkPullRequestRun returns true when the workflow run is triggered by a pull request whose head is in another repository.
+func isForkPullRequestRun(run *gogithub.WorkflowRun) bool {
+	if !pullRequestEvents[run.GetEvent()] {
+		return false
+	}
+
+	head := run.GetHeadRepository()
+
+	return head != nil && head.GetID() != run.GetRepository().GetID()
+}
+
+// protectsFromForks returns true when the webhook server needs to tell if the workflow runs of the repository are triggered by pull requests from forks.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) protectsFromForks(repo *gogithub.Repository) bool {
+	switch autoscaler.ForkPullRequestPolicy {
+	case ForkPullRequestPolicySandbox, ForkPullRequestPolicyRefuse:
+		return !repo.GetPrivate()
+	default:
+		return false
+	}
+}
+
+// isUntrustedWorkflowRun returns true when the workflow run is of a pull request from a fork of the public repository,
+// and the fork pull request policy doesn't allow it to be routed like any other job.
+// The workflow_job event doesn't tell the event that triggered the run, so the run is looked up with the GitHub API.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) isUntrustedWorkflowRun(ctx context.Context, repo *gogithub.Repository, runID int64) (bool, error) {
+	if !autoscaler.protectsFromForks(repo) {
+		return false, nil
+	}
+
+	if autoscaler.GitHubClient == nil {
+		return false, errors.New("the fork pull request policy requires GitHub authentication to look up the workflow run")
+	}
+
+	run, err := autoscaler.GitHubClient.GetWorkflowRun(ctx, repo.GetOwner().GetLogin(), repo.GetName(), runID)
+	if err != nil {
+		return false, err
+	}
+
+	return isForkPullRequestRun(run), nil
+}
@@ -0,0 +1,174 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v52/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestIsForkPullRequestRun(t *testing.T) {
+	base := &github.Repository{ID: github.Int64(1)}
+	fork := &github.Repository{ID: github.Int64(2)}
+
+	tests := []struct {
+		event string
+		head  *github.Repository
+		want  bool
+	}{
+		{"pull_request", fork, true},
+		{"pull_request_target", fork, true},
+		{"pull_request", base, false},
+		{"push", fork, false},
+		{"pull_request", nil, false},
+	}
+
+	for _, tt := range tests {
+		run := &github.WorkflowRun{Event: github.String(tt.event), Repository: base, HeadRepository: tt.head}
+
+		if got := isForkPullRequestRun(run); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.event, tt.want, got)
+		}
+	}
+}
+
+func TestWebhookWorkflowJobFromFork(t *testing.T) {
+	newEvent := func(private bool) *github.WorkflowJobEvent {
+		return &github.WorkflowJobEvent{
+			WorkflowJob: &github.WorkflowJob{
+				ID:     github.Int64(1),
+				RunID:  github.Int64(123),
+				Labels: []string{"label1"},
+			},
+			Action: github.String("queued"),
+			Repo: &github.Repository{
+				ID:      github.Int64(1),
+				Name:    github.String("valid"),
+				Private: github.Bool(private),
+				Owner: &github.User{
+					Login: github.String("test"),
+					Type:  github.String("Organization"),
+				},
+			},
+		}
+	}
+
+	newObjs := func() []runtime.Object {
+		var objs []runtime.Object
+
+		for _, name := range []string{"privileged", "sandbox"} {
+			objs = append(objs,
+				&actionsv1alpha1.HorizontalRunnerAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+					Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+						ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+							Name: name,
+						},
+						ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+							{
+								GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+									WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+								},
+							},
+						},
+						Sandbox: name == "sandbox",
+					},
+				},
+				&actionsv1alpha1.RunnerDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+					Spec: actionsv1alpha1.RunnerDeploymentSpec{
+						Template: actionsv1alpha1.RunnerTemplate{
+							Spec: actionsv1alpha1.RunnerSpec{
+								RunnerConfig: actionsv1alpha1.RunnerConfig{
+									Repository: "test/valid",
+									Labels:     []string{"label1"},
+								},
+							},
+						},
+					},
+				},
+			)
+		}
+
+		return objs
+	}
+
+	newWebhook := func(policy string, headRepoID int64) *HorizontalRunnerAutoscalerGitHubWebhook {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/test/valid/actions/runs/123", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id": 123, "event": "pull_request", "repository": {"id": 1}, "head_repository": {"id": %d}}`, headRepoID)
+		})
+
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		return &HorizontalRunnerAutoscalerGitHubWebhook{
+			GitHubClient:          newGithubClient(server),
+			ForkPullRequestPolicy: policy,
+		}
+	}
+
+	t.Run("Allow", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(ForkPullRequestPolicyAllow, 2),
+			"workflow_job",
+			newEvent(false),
+			200,
+			"scaled privileged by 1",
+			newObjs(),
+		)
+	})
+
+	t.Run("Sandbox", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(ForkPullRequestPolicySandbox, 2),
+			"workflow_job",
+			newEvent(false),
+			200,
+			"scaled sandbox by 1",
+			newObjs(),
+		)
+	})
+
+	t.Run("SandboxNotFork", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(ForkPullRequestPolicySandbox, 1),
+			"workflow_job",
+			newEvent(false),
+			200,
+			"scaled privileged by 1",
+			newObjs(),
+		)
+	})
+
+	t.Run("SandboxPrivateRepository", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(ForkPullRequestPolicySandbox, 2),
+			"workflow_job",
+			newEvent(true),
+			200,
+			"scaled privileged by 1",
+			newObjs(),
+		)
+	})
+
+	t.Run("Refuse", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(ForkPullRequestPolicyRefuse, 2),
+			"workflow_job",
+			newEvent(false),
+			200,
+			"refused to scale for a pull request from a fork",
+			newObjs(),
+		)
+	})
+}
@@ -0,0 +1,86 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsglob"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+// MatchCheckRunEvent returns a func that reports whether the scale trigger is a `githubEvent.checkRun` trigger whose conditions match the check_run event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(event *gogithub.CheckRunEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil || g.CheckRun == nil {
+			return false
+		}
+
+		cr := g.CheckRun
+
+		if !matchTriggerConditionAgainstEvent(cr.Types, event.GetAction()) {
+			return false
+		}
+
+		if cr.Status != "" && event.GetCheckRun().GetStatus() != cr.Status {
+			return false
+		}
+
+		if !matchAnyGlob(cr.Names, event.GetCheckRun().GetName()) {
+			return false
+		}
+
+		return matchRepository(cr.Repositories, event.GetRepo())
+	}
+}
+
+// matchTriggerConditionAgainstEvent returns true when the event action is one of the types of the trigger.
+// A trigger without types matches any action.
+func matchTriggerConditionAgainstEvent(types []string, action string) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	for _, tpe := range types {
+		if tpe == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchAnyGlob returns true when the value matches any of the GitHub Actions glob patterns.
+// An empty list of patterns matches any value.
+func matchAnyGlob(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pat := range patterns {
+		if pat == "" {
+			continue
+		}
+
+		if actionsglob.Match(pat, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchRepository returns true when the repository is one of the repositories, each of which is either a repository name or OWNER/REPO.
+// An empty list of repositories matches any repository.
+func matchRepository(repositories []string, repo *gogithub.Repository) bool {
+	if len(repositories) == 0 {
+		return true
+	}
+
+	for _, r := range repositories {
+		if r == repo.GetName() || r == repo.GetFullName() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+// MatchDeploymentEvent returns a func that reports whether the scale trigger is a `githubEvent.deployment` trigger whose conditions match the deployment event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchDeploymentEvent(event *gogithub.DeploymentEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil || g.Deployment == nil {
+			return false
+		}
+
+		d := g.Deployment
+
+		if !matchAnyGlob(d.Environments, event.GetDeployment().GetEnvironment()) {
+			return false
+		}
+
+		return matchRepository(d.Repositories, event.GetRepo())
+	}
+}
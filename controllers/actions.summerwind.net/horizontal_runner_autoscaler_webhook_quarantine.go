@@ -0,0 +1,281 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The reasons a workflow job is deemed suspicious and run on a quarantined runner.
+const (
+	QuarantineReasonFirstTimeContributor = "first-time-contributor"
+	QuarantineReasonWorkflowFileModified = "workflow-file-modified"
+	QuarantineReasonSecretsRequested     = "secrets-requested"
+
+	// QuarantineReasonEvaluationFailed quarantines the jobs whose pull requests or workflow files couldn't be looked up,
+	// so that a GitHub API outage doesn't let the suspicious jobs run on the regular runners.
+	QuarantineReasonEvaluationFailed = "evaluation-failed"
+)
+
+// EventReasonQuarantinedJobStarted is the reason of the event emitted on the quarantined runner pod when it picks up a workflow job.
+const EventReasonQuarantinedJobStarted = "QuarantinedJobStarted"
+
+// quarantineReasonsTTL is how long the quarantine reasons of a workflow run are reused,
+// so that the completed events of the jobs release the capacity reserved for the same profile as the queued events.
+const quarantineReasonsTTL = 24 * time.Hour
+
+// firstTimeContributorAssociations are the author associations of the authors who have never had a commit in the repository.
+var firstTimeContributorAssociations = map[string]bool{
+	"FIRST_TIME_CONTRIBUTOR": true,
+	"FIRST_TIMER":            true,
+	"NONE":                   true,
+}
+
+const workflowsDir = ".github/workflows/"
+
+var (
+	// secretReferenceRegexp matches secrets.NAME in the expressions.
+	secretReferenceRegexp = regexp.MustCompile(`(?i)\bsecrets\s*\.\s*([a-z_][a-z0-9_-]*)`)
+	// secretsObjectRegexp matches the expressions referencing the whole secrets context or its keys dynamically, like toJSON(secrets) or secrets[format(...)].
+	secretsObjectRegexp = regexp.MustCompile(`(?i)(\(\s*secrets\s*\)|\bsecrets\s*\[)`)
+	// secretsInheritRegexp matches the calls of the reusable workflows passing all the secrets.
+	secretsInheritRegexp = regexp.MustCompile(`(?im)^\s*secrets\s*:\s*inherit\b`)
+)
+
+// workflowRequestsSecrets returns true when the workflow file references any secret but GITHUB_TOKEN.
+// It errs on the side of quarantining, like on the references within comments.
+func workflowRequestsSecrets(content []byte) bool {
+	if secretsObjectRegexp.Match(content) || secretsInheritRegexp.Match(content) {
+		return true
+	}
+
+	for _, m := range secretReferenceRegexp.FindAllSubmatch(content, -1) {
+		if !strings.EqualFold(string(m[1]), "GITHUB_TOKEN") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pullRequestModifiesWorkflows returns true when any of the files is added, changed, or renamed from or to under .github/workflows.
+func pullRequestModifiesWorkflows(files []*gogithub.CommitFile) bool {
+	for _, f := range files {
+		if strings.HasPrefix(f.GetFilename(), workflowsDir) || strings.HasPrefix(f.GetPreviousFilename(), workflowsDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type quarantineKey struct {
+	runID      int64
+	quarantine v1alpha1.Quarantine
+}
+
+type cachedQuarantineReasons struct {
+	reasons   []string
+	expiresAt time.Time
+}
+
+// quarantineCache remembers the quarantine reasons of the workflow runs. The zero value is ready to use.
+type quarantineCache struct {
+	mu      sync.Mutex
+	reasons map[quarantineKey]cachedQuarantineReasons
+}
+
+func (c *quarantineCache) get(key quarantineKey, now time.Time) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.reasons[key]
+	if !ok || !now.Before(cached.expiresAt) {
+		return nil, false
+	}
+
+	return cached.reasons, true
+}
+
+func (c *quarantineCache) set(key quarantineKey, reasons []string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reasons == nil {
+		c.reasons = map[quarantineKey]cachedQuarantineReasons{}
+	}
+
+	for k, v := range c.reasons {
+		if !now.Before(v.expiresAt) {
+			delete(c.reasons, k)
+		}
+	}
+
+	c.reasons[key] = cachedQuarantineReasons{reasons: reasons, expiresAt: now.Add(quarantineReasonsTTL)}
+}
+
+// forRun returns the quarantine reasons of the workflow run, evaluated for any of the RunnerDeployments.
+func (c *quarantineCache) forRun(runID int64, now time.Time) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reasons []string
+
+	for k, v := range c.reasons {
+		if k.runID != runID || !now.Before(v.expiresAt) {
+			continue
+		}
+
+	REASONS:
+		for _, r := range v.reasons {
+			for _, r2 := range reasons {
+				if r == r2 {
+					continue REASONS
+				}
+			}
+			reasons = append(reasons, r)
+		}
+	}
+
+	return reasons
+}
+
+// quarantineReasons returns why the jobs of the workflow run are deemed suspicious per the quarantine configuration,
+// or nil when they aren't. The reasons are cached per workflow run, so that the GitHub API is called once per run
+// and the completed events of the jobs agree with the queued events.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) quarantineReasons(ctx context.Context, log logr.Logger, repo *gogithub.Repository, runID int64, q *v1alpha1.Quarantine) []string {
+	key := quarantineKey{runID: runID, quarantine: *q}
+	now := time.Now()
+
+	if reasons, ok := autoscaler.quarantines.get(key, now); ok {
+		return reasons
+	}
+
+	reasons, err := autoscaler.evaluateQuarantine(ctx, repo, runID, q)
+	if err != nil {
+		log.Error(err, "Could not tell if the workflow job is suspicious. Quarantining it", "workflowRun.ID", runID)
+
+		reasons = []string{QuarantineReasonEvaluationFailed}
+	}
+
+	autoscaler.quarantines.set(key, reasons, now)
+
+	return reasons
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) evaluateQuarantine(ctx context.Context, repo *gogithub.Repository, runID int64, q *v1alpha1.Quarantine) ([]string, error) {
+	ghc := autoscaler.GitHubClient
+	if ghc == nil {
+		return nil, errors.New("the quarantine requires GitHub authentication to look up the workflow run")
+	}
+
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+
+	run, err := ghc.GetWorkflowRun(ctx, owner, name, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pullRequestEvents[run.GetEvent()] {
+		return nil, nil
+	}
+
+	var reasons []string
+
+	if q.FirstTimeContributor || q.WorkflowFileModified {
+		pr, err := ghc.GetWorkflowRunPullRequest(ctx, owner, name, run)
+		if err != nil {
+			return nil, err
+		}
+
+		if pr == nil {
+			return nil, errors.New("the pull request of the workflow run was not found")
+		}
+
+		if q.FirstTimeContributor && firstTimeContributorAssociations[pr.GetAuthorAssociation()] {
+			reasons = append(reasons, QuarantineReasonFirstTimeContributor)
+		}
+
+		if q.WorkflowFileModified {
+			files, err := ghc.ListPullRequestFiles(ctx, owner, name, pr.GetNumber())
+			if err != nil {
+				return nil, err
+			}
+
+			if pullRequestModifiesWorkflows(files) {
+				reasons = append(reasons, QuarantineReasonWorkflowFileModified)
+			}
+		}
+	}
+
+	if q.SecretsRequested {
+		// The head SHA of a pull_request_target run is the one of the base branch, whose workflow file the run follows
+		_, content, err := ghc.GetWorkflowFile(ctx, owner, name, run.GetWorkflowID(), run.GetHeadSHA())
+		if err != nil {
+			return nil, err
+		}
+
+		if workflowRequestsSecrets(content) {
+			reasons = append(reasons, QuarantineReasonSecretsRequested)
+		}
+	}
+
+	return reasons, nil
+}
+
+// auditQuarantine records the workflow job picked up by the quarantined runner pod in detail, with a log, an event, and an annotation
+// telling why the job is suspicious. It warns about the suspicious jobs picked up by the other runners,
+// as GitHub assigns a job to any idle runner with the matching labels, not necessarily to the quarantined runner created for the job.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) auditQuarantine(ctx context.Context, log logr.Logger, pod *corev1.Pod, e *gogithub.WorkflowJobEvent) {
+	job := e.GetWorkflowJob()
+	reasons := autoscaler.quarantines.forRun(job.GetRunID(), time.Now())
+
+	if !isQuarantined(pod.Labels) {
+		if len(reasons) > 0 {
+			log.Info("A suspicious workflow job was picked up by a runner that isn't quarantined", "runner", pod.Name, "namespace", pod.Namespace, "reasons", reasons, "jobURL", job.GetHTMLURL())
+		}
+		return
+	}
+
+	log.Info(
+		"A quarantined runner picked up the workflow job",
+		"runner", pod.Name,
+		"namespace", pod.Namespace,
+		"reasons", reasons,
+		"repository", e.GetRepo().GetFullName(),
+		"workflow", job.GetWorkflowName(),
+		"job", job.GetName(),
+		"headBranch", job.GetHeadBranch(),
+		"headSHA", job.GetHeadSHA(),
+		"jobURL", job.GetHTMLURL(),
+		"sender", e.GetSender().GetLogin(),
+	)
+
+	if autoscaler.Recorder != nil {
+		autoscaler.Recorder.Eventf(pod, corev1.EventTypeNormal, EventReasonQuarantinedJobStarted,
+			"Picked up the workflow job %s of %s triggered by %s, deemed suspicious for %v", job.GetHTMLURL(), e.GetRepo().GetFullName(), e.GetSender().GetLogin(), reasons)
+	}
+
+	if len(reasons) == 0 || pod.Annotations[AnnotationKeyQuarantineReasons] == strings.Join(reasons, ",") {
+		return
+	}
+
+	updated := pod.DeepCopy()
+	updated.Annotations = CloneAndAddLabel(pod.Annotations, AnnotationKeyQuarantineReasons, strings.Join(reasons, ","))
+
+	if err := autoscaler.Client.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		log.Error(err, "Failed to annotate the quarantined runner pod with the quarantine reasons", "runner", pod.Name)
+		return
+	}
+
+	*pod = *updated
+}
@@ -0,0 +1,106 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowRequestsSecrets(t *testing.T) {
+	for _, tc := range []struct {
+		workflow string
+		want     bool
+	}{
+		{workflow: "steps:\n- run: make test\n", want: false},
+		{workflow: "env:\n  GH_TOKEN: ${{ secrets.GITHUB_TOKEN }}\n", want: false},
+		{workflow: "env:\n  NPM_TOKEN: ${{ secrets.NPM_TOKEN }}\n", want: true},
+		{workflow: "env:\n  ALL: ${{ toJSON(secrets) }}\n", want: true},
+		{workflow: "env:\n  TOKEN: ${{ secrets[format('{0}_TOKEN', matrix.registry)] }}\n", want: true},
+		{workflow: "jobs:\n  call:\n    uses: ./.github/workflows/deploy.yaml\n    secrets: inherit\n", want: true},
+	} {
+		require.Equal(t, tc.want, workflowRequestsSecrets([]byte(tc.workflow)), tc.workflow)
+	}
+}
+
+func TestQuarantineReasons(t *testing.T) {
+	repo := &github.Repository{
+		ID:   github.Int64(1),
+		Name: github.String("valid"),
+		Owner: &github.User{
+			Login: github.String("test"),
+		},
+	}
+
+	workflow := base64.StdEncoding.EncodeToString([]byte("env:\n  NPM_TOKEN: ${{ secrets.NPM_TOKEN }}\n"))
+
+	var calls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test/valid/actions/runs/1", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"id": 1, "event": "push", "head_sha": "abc"}`)
+	})
+	mux.HandleFunc("/repos/test/valid/actions/runs/2", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"id": 2, "event": "pull_request", "workflow_id": 10, "head_sha": "def", "head_branch": "patch-1", "head_repository": {"id": 2, "owner": {"login": "fork"}}, "repository": {"id": 1}}`)
+	})
+	mux.HandleFunc("/repos/test/valid/actions/runs/3", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/repos/test/valid/pulls", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "fork:patch-1", r.URL.Query().Get("head"))
+		fmt.Fprint(w, `[{"number": 5, "head": {"sha": "def"}}]`)
+	})
+	mux.HandleFunc("/repos/test/valid/pulls/5", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 5, "author_association": "FIRST_TIME_CONTRIBUTOR"}`)
+	})
+	mux.HandleFunc("/repos/test/valid/pulls/5/files", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"filename": "src/main.go"}, {"filename": ".github/workflows/ci.yaml"}]`)
+	})
+	mux.HandleFunc("/repos/test/valid/actions/workflows/10", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 10, "path": ".github/workflows/ci.yaml"}`)
+	})
+	mux.HandleFunc("/repos/test/valid/contents/.github/workflows/ci.yaml", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "def", r.URL.Query().Get("ref"))
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, workflow)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{GitHubClient: newGithubClient(server)}
+
+	ctx := context.Background()
+	all := &v1alpha1.Quarantine{FirstTimeContributor: true, WorkflowFileModified: true, SecretsRequested: true}
+
+	// The jobs of the runs not triggered by pull requests are never suspicious
+	for i := 0; i < 2; i++ {
+		require.Empty(t, autoscaler.quarantineReasons(ctx, logr.Discard(), repo, 1, all))
+	}
+	require.Equal(t, 1, calls, "the reasons are cached per workflow run")
+
+	require.Equal(t, []string{
+		QuarantineReasonFirstTimeContributor,
+		QuarantineReasonWorkflowFileModified,
+		QuarantineReasonSecretsRequested,
+	}, autoscaler.quarantineReasons(ctx, logr.Discard(), repo, 2, all))
+
+	require.Equal(t, []string{QuarantineReasonSecretsRequested}, autoscaler.quarantineReasons(ctx, logr.Discard(), repo, 2, &v1alpha1.Quarantine{SecretsRequested: true}))
+
+	require.Equal(t, []string{QuarantineReasonEvaluationFailed}, autoscaler.quarantineReasons(ctx, logr.Discard(), repo, 3, all), "the jobs are quarantined when they can't be evaluated")
+
+	require.ElementsMatch(t, []string{
+		QuarantineReasonFirstTimeContributor,
+		QuarantineReasonWorkflowFileModified,
+		QuarantineReasonSecretsRequested,
+	}, autoscaler.quarantines.forRun(2, time.Now()))
+}
@@ -0,0 +1,244 @@
+package actionssummerwindnet
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// DefaultWebhookRecorderMaxPayloadBytes is the maximum size of a recorded payload.
+	// A larger payload is recorded without its body to bound the memory usage of the recorder.
+	DefaultWebhookRecorderMaxPayloadBytes = 64 * 1024
+
+	redactedValue = "REDACTED"
+)
+
+// RecordedDelivery is a webhook delivery recorded by WebhookDeliveryRecorder along with how the webhook server handled it.
+type RecordedDelivery struct {
+	ReceivedAt time.Time `json:"receivedAt"`
+	DeliveryID string    `json:"deliveryID,omitempty"`
+	HookID     string    `json:"hookID,omitempty"`
+	Event      string    `json:"event"`
+	Action     string    `json:"action,omitempty"`
+	Repository string    `json:"repository,omitempty"`
+
+	// StatusCode and Response are what the webhook server responded with, like "scaled example by 1".
+	StatusCode int    `json:"statusCode"`
+	Response   string `json:"response,omitempty"`
+
+	// Payload is the redacted payload. It's omitted when the payload was too large to be recorded.
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// WebhookDeliveryRecorder records the last webhook deliveries in a ring buffer, and serves them on an authenticated HTTP endpoint,
+// so that one can see why a workflow job didn't scale a runner pool without capturing the traffic to the webhook server.
+//
+// The values of the fields that may contain personal information or credentials, like emails and tokens, are redacted from the recorded payloads.
+type WebhookDeliveryRecorder struct {
+	// Size is the number of deliveries to keep.
+	Size int
+
+	// Token is the bearer token required to read the recorded deliveries.
+	Token string
+
+	Log logr.Logger
+
+	mu         sync.Mutex
+	deliveries []RecordedDelivery
+	next       int
+}
+
+// Record adds the delivery to the ring buffer, overwriting the oldest one when it's full.
+func (r *WebhookDeliveryRecorder) Record(d RecordedDelivery, payload []byte) {
+	if r.Size <= 0 {
+		return
+	}
+
+	if len(payload) > DefaultWebhookRecorderMaxPayloadBytes {
+		d.Truncated = true
+	} else if redacted, err := redactWebhookPayload(payload); err != nil {
+		r.Log.V(1).Info("Recording the webhook delivery without its payload as it could not be redacted", "error", err.Error())
+
+		d.Truncated = true
+	} else {
+		d.Payload = redacted
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.deliveries) < r.Size {
+		r.deliveries = append(r.deliveries, d)
+		return
+	}
+
+	r.deliveries[r.next] = d
+	r.next = (r.next + 1) % r.Size
+}
+
+// List returns the recorded deliveries whose repository and event match the non-empty arguments, newest first.
+// The repository matches either the repository name or OWNER/REPO.
+func (r *WebhookDeliveryRecorder) List(repository, event string, limit int) []RecordedDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ds []RecordedDelivery
+
+	for i := 0; i < len(r.deliveries); i++ {
+		// Walk backwards from the newest one
+		idx := (r.next - 1 - i + len(r.deliveries)) % len(r.deliveries)
+		d := r.deliveries[idx]
+
+		if event != "" && d.Event != event {
+			continue
+		}
+
+		if repository != "" && d.Repository != repository && !strings.HasSuffix(d.Repository, "/"+repository) {
+			continue
+		}
+
+		ds = append(ds, d)
+
+		if limit > 0 && len(ds) >= limit {
+			break
+		}
+	}
+
+	return ds
+}
+
+// ServeHTTP serves the recorded deliveries as JSON. The optional `repository`, `event`, and `limit` query parameters filter them.
+func (r *WebhookDeliveryRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || r.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	q := req.URL.Query()
+
+	var limit int
+
+	if l := q.Get("limit"); l != "" {
+		var err error
+
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ds := r.List(q.Get("repository"), q.Get("event"), limit)
+	if ds == nil {
+		ds = []RecordedDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(ds); err != nil {
+		r.Log.Error(err, "failed writing recorded webhook deliveries")
+	}
+}
+
+func newRecordedDelivery(r *http.Request, eventType string, payload []byte) *RecordedDelivery {
+	var event struct {
+		Action     string `json:"action,omitempty"`
+		Repository struct {
+			FullName string `json:"full_name,omitempty"`
+		} `json:"repository,omitempty"`
+	}
+
+	// The payload is already parsed successfully by the webhook handler
+	_ = json.Unmarshal(payload, &event)
+
+	return &RecordedDelivery{
+		ReceivedAt: time.Now(),
+		DeliveryID: r.Header.Get("X-GitHub-Delivery"),
+		HookID:     r.Header.Get("X-GitHub-Hook-ID"),
+		Event:      eventType,
+		Action:     event.Action,
+		Repository: event.Repository.FullName,
+	}
+}
+
+// redactWebhookPayload replaces the values of the fields that may contain personal information or credentials with a placeholder.
+func redactWebhookPayload(payload []byte) (json.RawMessage, error) {
+	var v interface{}
+
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(redact(v))
+}
+
+func redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range t {
+			if isSensitiveWebhookPayloadKey(k) {
+				if vv != nil && vv != "" {
+					t[k] = redactedValue
+				}
+				continue
+			}
+
+			t[k] = redact(vv)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = redact(t[i])
+		}
+	}
+
+	return v
+}
+
+func isSensitiveWebhookPayloadKey(k string) bool {
+	k = strings.ToLower(k)
+
+	for _, s := range []string{"email", "token", "secret", "password", "key"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordingResponseWriter captures the status code and the body written by the webhook handler for WebhookDeliveryRecorder.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode int
+	body       strings.Builder
+}
+
+func (w *recordingResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	w.body.Write(b)
+
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,124 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryRecorder(t *testing.T) {
+	recorder := &WebhookDeliveryRecorder{
+		Size:  2,
+		Token: "debugtoken",
+		Log:   logr.Discard(),
+	}
+
+	testServerWithWebhook(t,
+		&HorizontalRunnerAutoscalerGitHubWebhook{DeliveryRecorder: recorder},
+		"ping",
+		&github.PingEvent{},
+		200,
+		"pong",
+		nil,
+	)
+
+	for _, repo := range []string{"myorg/repo1", "myorg/repo2"} {
+		e := &github.WorkflowJobEvent{
+			Action: github.String("queued"),
+			WorkflowJob: &github.WorkflowJob{
+				Labels: []string{"self-hosted"},
+			},
+			Repo: &github.Repository{
+				Name:     github.String(repo[len("myorg/"):]),
+				FullName: github.String(repo),
+				Owner: &github.User{
+					Login: github.String("myorg"),
+					Type:  github.String("Organization"),
+				},
+			},
+			Sender: &github.User{
+				Login: github.String("octocat"),
+				Email: github.String("octocat@example.com"),
+			},
+		}
+
+		testServerWithWebhook(t,
+			&HorizontalRunnerAutoscalerGitHubWebhook{DeliveryRecorder: recorder},
+			"workflow_job",
+			e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			nil,
+		)
+	}
+
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	get := func(token, query string) (int, []RecordedDelivery) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/debug/deliveries"+query, nil)
+		require.NoError(t, err)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var ds []RecordedDelivery
+
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&ds))
+		}
+
+		return res.StatusCode, ds
+	}
+
+	code, _ := get("", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = get("wrong", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// The ping event was overwritten as the recorder keeps the last 2 deliveries
+	code, ds := get("debugtoken", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, ds, 2)
+	require.Equal(t, "myorg/repo2", ds[0].Repository)
+	require.Equal(t, "myorg/repo1", ds[1].Repository)
+	require.Equal(t, "workflow_job", ds[0].Event)
+	require.Equal(t, "queued", ds[0].Action)
+	require.Equal(t, http.StatusOK, ds[0].StatusCode)
+	require.Equal(t, "no horizontalrunnerautoscaler to scale for this github event", ds[0].Response)
+
+	var payload struct {
+		Sender struct {
+			Login string `json:"login"`
+			Email string `json:"email"`
+		} `json:"sender"`
+	}
+	require.NoError(t, json.Unmarshal(ds[0].Payload, &payload))
+	require.Equal(t, "octocat", payload.Sender.Login)
+	require.Equal(t, redactedValue, payload.Sender.Email)
+
+	_, ds = get("debugtoken", "?repository=repo1")
+	require.Len(t, ds, 1)
+	require.Equal(t, "myorg/repo1", ds[0].Repository)
+
+	_, ds = get("debugtoken", "?event=ping")
+	require.Empty(t, ds)
+
+	_, ds = get("debugtoken", fmt.Sprintf("?limit=%d", 1))
+	require.Len(t, ds, 1)
+	require.Equal(t, "myorg/repo2", ds[0].Repository)
+}
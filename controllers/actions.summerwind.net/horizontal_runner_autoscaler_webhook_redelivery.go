@@ -0,0 +1,185 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+const (
+	DefaultWebhookRedeliveryInterval    = time.Minute
+	DefaultWebhookRedeliveryMaxAge      = 15 * time.Minute
+	DefaultWebhookRedeliveryMaxAttempts = 3
+)
+
+// WebhookDeliveryRedeliverer periodically lists the recent deliveries of the GitHub webhook
+// and asks GitHub to redeliver the ones that our webhook server missed, e.g. because the webhook server was down
+// or responded with 5xx, so that autoscaling doesn't silently diverge after a brief outage of the webhook server.
+//
+// It implements controller-runtime's manager.Runnable so that it can be added to the webhook server's manager.
+type WebhookDeliveryRedeliverer struct {
+	GitHubClient *github.Client
+	Log          logr.Logger
+
+	// Organization and Repository specify the owner of the webhook.
+	// The webhook is the repository webhook when Repository is specified in the OWNER/REPO format,
+	// or the organization webhook otherwise.
+	Organization string
+	Repository   string
+	HookID       int64
+
+	// Interval is the interval between the checks for failed deliveries.
+	Interval time.Duration
+
+	// MaxAge is how old a failed delivery can be to be redelivered.
+	// Keep this short, as a stale event like a long-gone queued workflow job results in an unnecessary scale up.
+	MaxAge time.Duration
+
+	// MaxAttempts is the maximum number of redeliveries of a failed delivery.
+	MaxAttempts int
+
+	// requested is the time at which we requested the last redelivery, keyed by the delivery GUID.
+	// A GUID is shared among the original delivery and all its redeliveries.
+	requested map[string]time.Time
+
+	// abandoned is the set of delivery GUIDs that we gave up redelivering.
+	abandoned map[string]bool
+}
+
+func (r *WebhookDeliveryRedeliverer) Start(ctx context.Context) error {
+	interval := r.Interval
+	if interval == 0 {
+		interval = DefaultWebhookRedeliveryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.redeliverFailedDeliveries(ctx, time.Now()); err != nil {
+				r.Log.Error(err, "Could not redeliver failed webhook deliveries")
+			}
+		}
+	}
+}
+
+// redeliverFailedDeliveries requests redeliveries of the deliveries that failed since MaxAge ago, returning the number of requested redeliveries.
+func (r *WebhookDeliveryRedeliverer) redeliverFailedDeliveries(ctx context.Context, now time.Time) (int, error) {
+	maxAge := r.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultWebhookRedeliveryMaxAge
+	}
+
+	maxAttempts := r.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultWebhookRedeliveryMaxAttempts
+	}
+
+	interval := r.Interval
+	if interval == 0 {
+		interval = DefaultWebhookRedeliveryInterval
+	}
+
+	if r.requested == nil {
+		r.requested = map[string]time.Time{}
+		r.abandoned = map[string]bool{}
+	}
+
+	for guid, t := range r.requested {
+		if t.Before(now.Add(-maxAge)) {
+			delete(r.requested, guid)
+			delete(r.abandoned, guid)
+		}
+	}
+
+	deliveries, err := r.GitHubClient.ListHookDeliveries(ctx, r.Organization, r.Repository, r.HookID, now.Add(-maxAge))
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		guids        []string
+		settled      = map[string]bool{}
+		latestFailed = map[string]*gogithub.HookDelivery{}
+		redeliveries = map[string]int{}
+	)
+
+	// Deliveries are listed newest first
+	for _, d := range deliveries {
+		guid := d.GetGUID()
+
+		if d.GetRedelivery() {
+			redeliveries[guid]++
+		}
+
+		// A GUID is settled once any of its deliveries succeeded or failed with a non-retriable error
+		if !isFailedWebhookDelivery(d) {
+			settled[guid] = true
+			continue
+		}
+
+		if _, ok := latestFailed[guid]; !ok {
+			latestFailed[guid] = d
+			guids = append(guids, guid)
+		}
+	}
+
+	var requested int
+
+	for _, guid := range guids {
+		if settled[guid] || r.abandoned[guid] {
+			continue
+		}
+
+		d := latestFailed[guid]
+
+		log := r.Log.WithValues("guid", guid, "event", d.GetEvent(), "action", d.GetAction(), "statusCode", d.GetStatusCode())
+
+		// Give GitHub some time to make the redelivery we requested in the previous check
+		if t, ok := r.requested[guid]; ok && now.Sub(t) < interval {
+			continue
+		}
+
+		if redeliveries[guid] >= maxAttempts {
+			log.Info("Giving up redelivering the failed webhook delivery", "attempts", redeliveries[guid])
+
+			metrics.IncWebhookDeliveriesAbandoned()
+
+			r.abandoned[guid] = true
+			r.requested[guid] = now
+
+			continue
+		}
+
+		if err := r.GitHubClient.RedeliverHookDelivery(ctx, r.Organization, r.Repository, r.HookID, d.GetID()); err != nil {
+			log.Error(err, "Could not redeliver the failed webhook delivery")
+
+			continue
+		}
+
+		log.Info("Requested redelivery of the failed webhook delivery", "deliveredAt", d.GetDeliveredAt())
+
+		metrics.IncWebhookDeliveriesRedelivered()
+
+		r.requested[guid] = now
+		requested++
+	}
+
+	return requested, nil
+}
+
+// isFailedWebhookDelivery returns true when the delivery failed due to a possibly transient error, like the webhook server being down
+// or responding with 5xx. A 4xx response is not retried as redelivering the same payload would result in the same error.
+func isFailedWebhookDelivery(d *gogithub.HookDelivery) bool {
+	code := d.GetStatusCode()
+
+	return code == 0 || code >= 500
+}
@@ -0,0 +1,93 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryRedeliverer(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	delivery := func(id int64, guid string, ago time.Duration, statusCode int, redelivery bool) map[string]interface{} {
+		return map[string]interface{}{
+			"id":           id,
+			"guid":         guid,
+			"delivered_at": now.Add(-ago).Format(time.RFC3339),
+			"status_code":  statusCode,
+			"redelivery":   redelivery,
+			"event":        "workflow_job",
+			"action":       "queued",
+		}
+	}
+
+	// Newest first, as GitHub lists them
+	deliveries := []map[string]interface{}{
+		// Failed once and then succeeded on redelivery
+		delivery(7, "succeeded-on-redelivery", 1*time.Minute, 200, true),
+		// Failed with 5xx
+		delivery(6, "failed", 2*time.Minute, 502, false),
+		// The webhook server was unreachable
+		delivery(5, "unreachable", 3*time.Minute, 0, false),
+		// Rejected by the webhook server, which won't change on redelivery
+		delivery(4, "rejected", 4*time.Minute, 400, false),
+		// Failed too many times
+		delivery(3, "exhausted", 5*time.Minute, 500, true),
+		delivery(2, "succeeded-on-redelivery", 5*time.Minute, 500, false),
+		delivery(1, "exhausted", 6*time.Minute, 500, true),
+		delivery(0, "exhausted", 7*time.Minute, 500, true),
+		// Too old to be redelivered
+		delivery(-1, "old", time.Hour, 500, false),
+	}
+
+	var (
+		mu          sync.Mutex
+		redelivered []string
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/test/hooks/123/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(deliveries)
+	})
+	mux.HandleFunc("/orgs/test/hooks/123/deliveries/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		redelivered = append(redelivered, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	r := &WebhookDeliveryRedeliverer{
+		GitHubClient: newGithubClient(server),
+		Log:          logr.Discard(),
+		Organization: "test",
+		HookID:       123,
+	}
+
+	n, err := r.redeliverFailedDeliveries(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{
+		"/orgs/test/hooks/123/deliveries/6/attempts",
+		"/orgs/test/hooks/123/deliveries/5/attempts",
+	}, redelivered)
+
+	// The redeliveries requested in the previous check are not requested again until the interval elapses
+	n, err = r.redeliverFailedDeliveries(context.Background(), now.Add(10*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	n, err = r.redeliverFailedDeliveries(context.Background(), now.Add(DefaultWebhookRedeliveryInterval))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
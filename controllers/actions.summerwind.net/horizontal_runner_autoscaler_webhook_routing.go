@@ -0,0 +1,121 @@
+package actionssummerwindnet
+
+import (
+	"sort"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const (
+	// RoutingPolicyFailover routes a webhook event to the first HRA, in the order of the names, among the matching HRAs of the highest priority
+	// that is not at maxReplicas yet.
+	RoutingPolicyFailover = "failover"
+
+	// RoutingPolicySpread routes a webhook event to the HRA with the fewest replicas among the matching HRAs of the highest priority
+	// that are not at maxReplicas yet.
+	RoutingPolicySpread = "spread"
+)
+
+// selectScaleTarget returns the scale target to which the webhook event is routed among the scale targets of all the HRAs matching the event.
+//
+// The HRAs with higher priorities are preferred, and the HRAs with lower priorities are used as overflow when all the preferred ones are at maxReplicas.
+// When all of them are at maxReplicas, the event is routed to the most preferred one so that the capacity reservation waits for a runner there.
+//
+// A scale down is routed in the reverse order to the least preferred HRA that has any capacity reservation,
+// so that the overflow is drained first.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) selectScaleTarget(candidates []*ScaleTarget, scaleDown bool) *ScaleTarget {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].HorizontalRunnerAutoscaler, candidates[j].HorizontalRunnerAutoscaler
+
+		if a.Spec.Priority != b.Spec.Priority {
+			return a.Spec.Priority > b.Spec.Priority
+		}
+
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		return a.Name < b.Name
+	})
+
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	if scaleDown {
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if len(getValidCapacityReservations(&candidates[i].HorizontalRunnerAutoscaler)) > 0 {
+				return candidates[i]
+			}
+		}
+
+		return candidates[0]
+	}
+
+	for start := 0; start < len(candidates); {
+		priority := candidates[start].Spec.Priority
+
+		end := start
+		for end < len(candidates) && candidates[end].Spec.Priority == priority {
+			end++
+		}
+
+		var selected *ScaleTarget
+
+		for _, c := range candidates[start:end] {
+			if !hasHeadroom(c.HorizontalRunnerAutoscaler) {
+				continue
+			}
+
+			if selected == nil {
+				selected = c
+
+				if autoscaler.RoutingPolicy != RoutingPolicySpread {
+					break
+				}
+
+				continue
+			}
+
+			if estimatedReplicas(c.HorizontalRunnerAutoscaler) < estimatedReplicas(selected.HorizontalRunnerAutoscaler) {
+				selected = c
+			}
+		}
+
+		if selected != nil {
+			return selected
+		}
+
+		start = end
+	}
+
+	return candidates[0]
+}
+
+// estimatedReplicas returns the number of replicas the HRA is expected to have after it reconciles the capacity reservations
+// added so far. The status alone lags behind the capacity reservations added by the webhook events received in the meantime.
+func estimatedReplicas(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	var n int
+
+	if hra.Spec.MinReplicas != nil {
+		n = *hra.Spec.MinReplicas
+	}
+
+	for _, r := range getValidCapacityReservations(&hra) {
+		n += r.Replicas
+	}
+
+	if d := hra.Status.DesiredReplicas; d != nil && *d > n {
+		n = *d
+	}
+
+	return n
+}
+
+func hasHeadroom(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	return hra.Spec.MaxReplicas == nil || estimatedReplicas(hra) < *hra.Spec.MaxReplicas
+}
@@ -0,0 +1,108 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectScaleTarget(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	reservations := func(n int) []actionsv1alpha1.CapacityReservation {
+		var rs []actionsv1alpha1.CapacityReservation
+		for i := 0; i < n; i++ {
+			rs = append(rs, actionsv1alpha1.CapacityReservation{
+				ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+				Replicas:       1,
+			})
+		}
+		return rs
+	}
+
+	target := func(name string, priority, max, reserved int) *ScaleTarget {
+		return &ScaleTarget{
+			HorizontalRunnerAutoscaler: actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas:          intPtr(0),
+					MaxReplicas:          intPtr(max),
+					Priority:             priority,
+					CapacityReservations: reservations(reserved),
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name       string
+		policy     string
+		candidates []*ScaleTarget
+		scaleDown  bool
+		want       string
+	}{
+		{
+			name:       "higher priority first",
+			candidates: []*ScaleTarget{target("on-demand", 0, 10, 0), target("spot", 10, 10, 0)},
+			want:       "spot",
+		},
+		{
+			name:       "overflow to lower priority",
+			candidates: []*ScaleTarget{target("on-demand", 0, 10, 0), target("spot", 10, 10, 10)},
+			want:       "on-demand",
+		},
+		{
+			name:       "all at max",
+			candidates: []*ScaleTarget{target("on-demand", 0, 2, 2), target("spot", 10, 2, 2)},
+			want:       "spot",
+		},
+		{
+			name:       "failover within the same priority",
+			candidates: []*ScaleTarget{target("b", 0, 10, 1), target("a", 0, 10, 5)},
+			want:       "a",
+		},
+		{
+			name:       "spread within the same priority",
+			policy:     RoutingPolicySpread,
+			candidates: []*ScaleTarget{target("a", 0, 10, 5), target("b", 0, 10, 1), target("c", 0, 10, 3)},
+			want:       "b",
+		},
+		{
+			name:       "spread skips the ones at max",
+			policy:     RoutingPolicySpread,
+			candidates: []*ScaleTarget{target("a", 0, 5, 5), target("b", 0, 10, 7), target("c", 0, 10, 3), target("d", 1, 1, 1)},
+			want:       "c",
+		},
+		{
+			name:       "scale down drains overflow first",
+			candidates: []*ScaleTarget{target("on-demand", 0, 10, 2), target("spot", 10, 10, 10)},
+			scaleDown:  true,
+			want:       "on-demand",
+		},
+		{
+			name:       "scale down from preferred once overflow is drained",
+			candidates: []*ScaleTarget{target("on-demand", 0, 10, 0), target("spot", 10, 10, 3)},
+			scaleDown:  true,
+			want:       "spot",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{RoutingPolicy: tc.policy}
+
+			got := autoscaler.selectScaleTarget(tc.candidates, tc.scaleDown)
+			if got == nil {
+				t.Fatal("want scale target, got nil")
+			}
+
+			if got.Name != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+)
+
+const (
+	DefaultWebhookShardForwardTimeout = 10 * time.Second
+
+	// headerForwardedByShard is set on the forwarded deliveries to the name of the forwarding shard,
+	// so that a shard never forwards a delivery again even when the shards disagree on the owner of the delivery.
+	headerForwardedByShard = "X-ARC-Forwarded-By-Shard"
+)
+
+// webhookShardForwardedHeaders are the headers of the delivery kept on forwarding,
+// which the webhook server of the shard needs to validate and handle the delivery.
+var webhookShardForwardedHeaders = []string{
+	"Content-Type",
+	"User-Agent",
+	"X-GitHub-Event",
+	"X-GitHub-Delivery",
+	"X-GitHub-Hook-ID",
+	"X-GitHub-Hook-Installation-Target-ID",
+	"X-GitHub-Hook-Installation-Target-Type",
+	"X-Hub-Signature",
+	"X-Hub-Signature-256",
+}
+
+// WebhookShardRouter forwards the webhook deliveries for the organizations claimed by the other shards
+// to the github-webhook-server of the shards, so that a single webhook per enterprise or GitHub App can serve all the shards.
+// The deliveries for the organizations claimed by no other shard, or by a shard without a webhook URL, are handled locally.
+type WebhookShardRouter struct {
+	Sharder *sharding.Sharder
+
+	// HTTPClient forwards the deliveries. Defaults to a client timing out after DefaultWebhookShardForwardTimeout.
+	HTTPClient *http.Client
+}
+
+// route returns the webhook URL of the shard the delivery for the owner is forwarded to,
+// or an empty string when the delivery is handled locally.
+func (r *WebhookShardRouter) route(req *http.Request, owner string) string {
+	if req.Header.Get(headerForwardedByShard) != "" {
+		return ""
+	}
+
+	shard := r.Sharder.ShardOf(owner)
+	if shard == nil || shard.Name == r.Sharder.Name() {
+		return ""
+	}
+
+	return shard.WebhookURL
+}
+
+// forward relays the delivery to the webhook server at url, and writes its response back.
+// Nothing is written on error, so that the caller responds with an error and GitHub sees the delivery failed.
+func (r *WebhookShardRouter) forward(w http.ResponseWriter, req *http.Request, url string, payload []byte) error {
+	fwd, err := http.NewRequestWithContext(req.Context(), http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating the request: %w", err)
+	}
+
+	for _, h := range webhookShardForwardedHeaders {
+		if v := req.Header.Get(h); v != "" {
+			fwd.Header.Set(h, v)
+		}
+	}
+
+	fwd.Header.Set(headerForwardedByShard, r.Sharder.Name())
+
+	client := r.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookShardForwardTimeout}
+	}
+
+	res, err := client.Do(fwd)
+	if err != nil {
+		return fmt.Errorf("forwarding the delivery: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading the response: %w", err)
+	}
+
+	w.WriteHeader(res.StatusCode)
+
+	_, err = w.Write(body)
+
+	return err
+}
+
+// webhookOwner returns the owner of the runners the delivery is for, which is the organization,
+// the owner of the repository, or the enterprise of the delivery, in the order of precedence of sharding.Owner.
+func webhookOwner(payload []byte) string {
+	var e struct {
+		Organization struct {
+			Login string `json:"login"`
+		} `json:"organization"`
+		Repository struct {
+			Owner struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		} `json:"repository"`
+		Enterprise struct {
+			Slug string `json:"slug"`
+		} `json:"enterprise"`
+	}
+
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return ""
+	}
+
+	if e.Organization.Login != "" {
+		return e.Organization.Login
+	}
+
+	if e.Repository.Owner.Login != "" {
+		return e.Repository.Owner.Login
+	}
+
+	return e.Enterprise.Slug
+}
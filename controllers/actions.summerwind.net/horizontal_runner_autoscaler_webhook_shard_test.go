@@ -0,0 +1,73 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookShardRouter(t *testing.T) {
+	var forwarded *http.Request
+	var forwardedBody []byte
+
+	shardB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		forwardedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("scaled by shard-b"))
+	}))
+	defer shardB.Close()
+
+	sharder, err := sharding.New("shard-a", []sharding.Shard{
+		{Name: "shard-a", Organizations: []string{"org-a"}},
+		{Name: "shard-b", Organizations: []string{"org-b"}, WebhookURL: shardB.URL},
+	})
+	require.NoError(t, err)
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Log:         logr.Discard(),
+		ShardRouter: &WebhookShardRouter{Sharder: sharder},
+	}
+
+	payload := []byte(`{"action":"queued","organization":{"login":"Org-B"},"repository":{"name":"repo","owner":{"login":"org-b"}}}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", "workflow_job")
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	req.Header.Set("Authorization", "not forwarded")
+
+	res := httptest.NewRecorder()
+	webhook.Handle(res, req)
+
+	require.Equal(t, http.StatusAccepted, res.Code)
+	require.Equal(t, "scaled by shard-b", res.Body.String())
+
+	require.NotNil(t, forwarded)
+	require.Equal(t, payload, forwardedBody)
+	require.Equal(t, "workflow_job", forwarded.Header.Get("X-GitHub-Event"))
+	require.Equal(t, "delivery-1", forwarded.Header.Get("X-GitHub-Delivery"))
+	require.Equal(t, "shard-a", forwarded.Header.Get(headerForwardedByShard))
+	require.Empty(t, forwarded.Header.Get("Authorization"))
+
+	// A forwarded delivery is never forwarded again
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(headerForwardedByShard, "shard-c")
+	require.Empty(t, webhook.ShardRouter.route(req, "org-b"))
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	require.Empty(t, webhook.ShardRouter.route(req, "org-a"))
+	require.Empty(t, webhook.ShardRouter.route(req, "org-c"))
+}
+
+func TestWebhookOwner(t *testing.T) {
+	require.Equal(t, "org", webhookOwner([]byte(`{"organization":{"login":"org"},"repository":{"owner":{"login":"owner"}},"enterprise":{"slug":"ent"}}`)))
+	require.Equal(t, "owner", webhookOwner([]byte(`{"repository":{"owner":{"login":"owner"}},"enterprise":{"slug":"ent"}}`)))
+	require.Equal(t, "ent", webhookOwner([]byte(`{"enterprise":{"slug":"ent"}}`)))
+	require.Empty(t, webhookOwner([]byte(`not json`)))
+}
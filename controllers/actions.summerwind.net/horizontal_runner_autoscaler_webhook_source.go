@@ -0,0 +1,206 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+const (
+	DefaultWebhookSourceRefreshInterval = time.Hour
+)
+
+// WebhookSourceVerifier rejects the webhook deliveries that don't come from the IP ranges GitHub sends webhooks from,
+// so that a leaked webhook secret alone is not enough to spoof webhook events to an exposed webhook server.
+//
+// The ranges are the "hooks" ranges published on the /meta endpoint of GitHub or GitHub Enterprise Server, refreshed periodically,
+// plus the static CIDRs. It implements controller-runtime's manager.Runnable to refresh the ranges.
+type WebhookSourceVerifier struct {
+	// GitHubClient is used to fetch the /meta endpoint. Set to nil for allowing only the static CIDRs.
+	// The endpoint doesn't require authentication, so this can be an unauthenticated client.
+	GitHubClient *gogithub.Client
+	Log          logr.Logger
+
+	// CIDRs are the IP ranges allowed in addition to the ones published on /meta,
+	// like the addresses of a GitHub Enterprise Server that doesn't publish its hook ranges.
+	CIDRs []*net.IPNet
+
+	// TrustedProxies are the IP ranges of the load balancers and the ingress controllers in front of the webhook server.
+	// The source of a delivery relayed by one of them is taken from the X-Forwarded-For header.
+	TrustedProxies []*net.IPNet
+
+	// RefreshInterval is the interval between the fetches of the /meta endpoint.
+	RefreshInterval time.Duration
+
+	mu    sync.RWMutex
+	hooks []*net.IPNet
+}
+
+// ParseCIDRs parses the comma-separated CIDRs. A bare IP address is taken as a single address range.
+func ParseCIDRs(v string) ([]*net.IPNet, error) {
+	var cidrs []*net.IPNet
+
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", s)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+
+		cidrs = append(cidrs, cidr)
+	}
+
+	return cidrs, nil
+}
+
+func (v *WebhookSourceVerifier) Start(ctx context.Context) error {
+	if v.GitHubClient == nil {
+		return nil
+	}
+
+	interval := v.RefreshInterval
+	if interval == 0 {
+		interval = DefaultWebhookSourceRefreshInterval
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		v.Log.Error(err, "Could not fetch the webhook source IP ranges. Only the static CIDRs are allowed until the next refresh")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// The last fetched ranges are kept on failure, as GitHub rarely changes them
+			if err := v.refresh(ctx); err != nil {
+				v.Log.Error(err, "Could not refresh the webhook source IP ranges")
+			}
+		}
+	}
+}
+
+// refresh fetches the hooks ranges from the /meta endpoint.
+func (v *WebhookSourceVerifier) refresh(ctx context.Context) error {
+	meta, _, err := v.GitHubClient.APIMeta(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching /meta: %w", err)
+	}
+
+	hooks, err := ParseCIDRs(strings.Join(meta.Hooks, ","))
+	if err != nil {
+		return fmt.Errorf("parsing the hooks ranges in /meta: %w", err)
+	}
+
+	if len(hooks) == 0 {
+		return errors.New("/meta has no hooks ranges")
+	}
+
+	v.mu.Lock()
+	v.hooks = hooks
+	v.mu.Unlock()
+
+	v.Log.V(1).Info("Refreshed the webhook source IP ranges", "hooks", meta.Hooks)
+
+	return nil
+}
+
+// Verify returns an error when the request doesn't come from the allowed IP ranges.
+func (v *WebhookSourceVerifier) Verify(r *http.Request) error {
+	ip, err := v.sourceIP(r)
+	if err != nil {
+		return err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	for _, ranges := range [][]*net.IPNet{v.hooks, v.CIDRs} {
+		for _, cidr := range ranges {
+			if cidr.Contains(ip) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("source %s is not in the allowed IP ranges", ip)
+}
+
+// sourceIP returns the address the request comes from.
+// X-Forwarded-For is walked from the right, skipping the trusted proxies, as only the addresses appended by them can be trusted.
+func (v *WebhookSourceVerifier) sourceIP(r *http.Request) (net.IP, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid remote address %q", r.RemoteAddr)
+	}
+
+	if !v.trusted(ip) {
+		return ip, nil
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+
+		ip = net.ParseIP(hop)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid X-Forwarded-For address %q", hop)
+		}
+
+		if !v.trusted(ip) {
+			return ip, nil
+		}
+	}
+
+	return ip, nil
+}
+
+func (v *WebhookSourceVerifier) trusted(ip net.IP) bool {
+	for _, cidr := range v.TrustedProxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
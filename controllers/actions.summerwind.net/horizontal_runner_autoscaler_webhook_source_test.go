@@ -0,0 +1,69 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSourceVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/meta", r.URL.Path)
+		_, _ = w.Write([]byte(`{"hooks":["192.30.252.0/22","2620:112:3000::/44"]}`))
+	}))
+	defer server.Close()
+
+	client := gogithub.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	static, err := ParseCIDRs("10.0.0.5, 172.16.0.0/12")
+	require.NoError(t, err)
+
+	proxies, err := ParseCIDRs("10.1.0.0/16")
+	require.NoError(t, err)
+
+	v := &WebhookSourceVerifier{
+		GitHubClient:   client,
+		Log:            logr.Discard(),
+		CIDRs:          static,
+		TrustedProxies: proxies,
+	}
+
+	verify := func(remoteAddr string, forwardedFor ...string) error {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for _, f := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		return v.Verify(req)
+	}
+
+	// Only the static CIDRs are allowed until /meta is fetched
+	require.Error(t, verify("192.30.252.10:443"))
+	require.NoError(t, verify("10.0.0.5:443"))
+	require.Error(t, verify("10.0.0.6:443"))
+
+	require.NoError(t, v.refresh(context.Background()))
+
+	require.NoError(t, verify("192.30.252.10:443"))
+	require.NoError(t, verify("[2620:112:3000::1]:443"))
+	require.NoError(t, verify("172.20.1.1:443"))
+	require.Error(t, verify("203.0.113.1:443"))
+
+	// The spoofed addresses prepended by the client are ignored
+	require.NoError(t, verify("10.1.2.3:443", "203.0.113.1, 192.30.252.10"))
+	require.Error(t, verify("10.1.2.3:443", "192.30.252.10, 203.0.113.1"))
+	require.NoError(t, verify("10.1.2.3:443", "192.30.252.10", "10.1.0.1"))
+
+	// X-Forwarded-For is ignored when the request doesn't come from a trusted proxy
+	require.Error(t, verify("203.0.113.1:443", "192.30.252.10"))
+
+	_, err = ParseCIDRs("192.30.252.0/33")
+	require.Error(t, err)
+}
@@ -13,12 +13,13 @@ import (
 	"time"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v52/github"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var (
@@ -201,6 +202,319 @@ func TestWebhookWorkflowJob(t *testing.T) {
 	})
 }
 
+func TestWebhookWorkflowJobWithScaleUpTriggersPerLabels(t *testing.T) {
+	setupTest := func(action, conclusion string) github.WorkflowJobEvent {
+		f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+		if err != nil {
+			t.Fatalf("could not open the fixture: %s", err)
+		}
+		defer f.Close()
+		var e github.WorkflowJobEvent
+		if err := json.NewDecoder(f).Decode(&e); err != nil {
+			t.Fatalf("invalid json: %s", err)
+		}
+
+		e.Action = github.String(action)
+		if conclusion != "" {
+			e.WorkflowJob.Conclusion = github.String(conclusion)
+			e.WorkflowJob.RunnerID = github.Int64(1)
+		}
+
+		return e
+	}
+
+	initObjs := func(scaleDownFactor string) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+					},
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+								Labels: []string{"self-hosted", "LABEL1"},
+							},
+						},
+						Amount:          3,
+						ScaleDownFactor: scaleDownFactor,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	t.Run("ScaleUp", func(t *testing.T) {
+		e := setupTest("queued", "")
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "scaled test-name by 3", initObjs(""))
+	})
+
+	t.Run("ScaleDown", func(t *testing.T) {
+		e := setupTest("completed", "success")
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "scaled test-name by -3", initObjs(""))
+	})
+
+	t.Run("ScaleDownWithFactor", func(t *testing.T) {
+		e := setupTest("completed", "success")
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "scaled test-name by -2", initObjs("0.5"))
+	})
+
+	t.Run("ScaleDownOnlyOnExpiration", func(t *testing.T) {
+		e := setupTest("completed", "success")
+		testServerWithInitObjs(t, "workflow_job", &e, 200, "", initObjs("0"))
+	})
+}
+
+func TestWorkflowJobScaleUpTrigger(t *testing.T) {
+	testcases := []struct {
+		name       string
+		trigger    actionsv1alpha1.ScaleUpTrigger
+		wantAmount int
+	}{
+		{
+			name: "amount is ignored without labels or scaleDownFactor",
+			trigger: actionsv1alpha1.ScaleUpTrigger{
+				GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}},
+				Amount:      3,
+			},
+			wantAmount: 1,
+		},
+		{
+			name: "amount applies with labels",
+			trigger: actionsv1alpha1.ScaleUpTrigger{
+				GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{Labels: []string{"gpu"}}},
+				Amount:      3,
+			},
+			wantAmount: 3,
+		},
+		{
+			name: "amount applies with scaleDownFactor",
+			trigger: actionsv1alpha1.ScaleUpTrigger{
+				GitHubEvent:     &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{}},
+				Amount:          3,
+				ScaleDownFactor: "0.5",
+			},
+			wantAmount: 3,
+		},
+		{
+			name: "amount defaults to one",
+			trigger: actionsv1alpha1.ScaleUpTrigger{
+				GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{Labels: []string{"gpu"}}},
+			},
+			wantAmount: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := workflowJobScaleUpTrigger(tc.trigger)
+			if got.Amount != tc.wantAmount {
+				t.Errorf("unexpected amount: want %d, got %d", tc.wantAmount, got.Amount)
+			}
+		})
+	}
+}
+
+func TestSelectWorkflowJobScaleUpTrigger(t *testing.T) {
+	workflowJobTrigger := func(amount int, labels ...string) actionsv1alpha1.ScaleUpTrigger {
+		return actionsv1alpha1.ScaleUpTrigger{
+			GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+					Labels: labels,
+				},
+			},
+			Amount: amount,
+		}
+	}
+
+	testcases := []struct {
+		name       string
+		triggers   []actionsv1alpha1.ScaleUpTrigger
+		labels     []string
+		wantAmount int
+		wantNil    bool
+	}{
+		{
+			name:       "single trigger without labels",
+			triggers:   []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1)},
+			labels:     []string{"self-hosted", "linux"},
+			wantAmount: 1,
+		},
+		{
+			name:     "single trigger with unmatched labels",
+			triggers: []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1, "gpu")},
+			labels:   []string{"self-hosted", "linux"},
+			wantNil:  true,
+		},
+		{
+			name:       "keyed trigger takes precedence",
+			triggers:   []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1), workflowJobTrigger(2, "linux", "gpu")},
+			labels:     []string{"self-hosted", "gpu", "linux"},
+			wantAmount: 2,
+		},
+		{
+			name:       "falls back to trigger without labels",
+			triggers:   []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1), workflowJobTrigger(2, "linux", "gpu")},
+			labels:     []string{"self-hosted", "linux"},
+			wantAmount: 1,
+		},
+		{
+			name:     "two triggers without labels",
+			triggers: []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1), workflowJobTrigger(2)},
+			labels:   []string{"self-hosted", "linux"},
+			wantNil:  true,
+		},
+		{
+			name:     "non workflowJob trigger",
+			triggers: []actionsv1alpha1.ScaleUpTrigger{workflowJobTrigger(1), {GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{}}},
+			labels:   []string{"self-hosted", "linux"},
+			wantNil:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, reason := selectWorkflowJobScaleUpTrigger(tc.triggers, tc.labels)
+			if tc.wantNil {
+				if got != nil {
+					t.Fatalf("want nil, got %+v", got)
+				}
+				return
+			}
+
+			if got == nil {
+				t.Fatalf("want trigger, got nil: %s", reason)
+			}
+
+			if got.Amount != tc.wantAmount {
+				t.Errorf("want amount %d, got %d", tc.wantAmount, got.Amount)
+			}
+		})
+	}
+}
+
+func TestWebhookWorkflowJobWithFilter(t *testing.T) {
+	f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+	if err != nil {
+		t.Fatalf("could not open the fixture: %s", err)
+	}
+	defer f.Close()
+	var e github.WorkflowJobEvent
+	if err := json.NewDecoder(f).Decode(&e); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+
+	initObjs := func(triggers ...actionsv1alpha1.ScaleUpTrigger) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: triggers,
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	trigger := func(amount int, filter string) actionsv1alpha1.ScaleUpTrigger {
+		return actionsv1alpha1.ScaleUpTrigger{
+			GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+					Labels: []string{"label1"},
+				},
+				Filter: filter,
+			},
+			Amount: amount,
+		}
+	}
+
+	t.Run("Matched", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"scaled test-name by 3",
+			initObjs(trigger(3, `eventType == "workflow_job" && event.workflow_job.labels.exists(l, l == "label1") && event.repository.name == "MYREPO"`)),
+		)
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(trigger(3, `event.repository.name == "OTHER"`)),
+		)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(trigger(3, `event.repository.name ==`)),
+		)
+	})
+
+	t.Run("EvaluationError", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(trigger(3, `event.deployment.environment == "production"`)),
+		)
+	})
+}
+
 func TestWebhookWorkflowJobWithSelfHostedLabel(t *testing.T) {
 	setupTest := func() github.WorkflowJobEvent {
 		f, err := os.Open("testdata/org_webhook_workflow_job_with_self_hosted_label_payload.json")
@@ -361,6 +675,310 @@ func TestWebhookWorkflowJobWithSelfHostedLabel(t *testing.T) {
 	})
 }
 
+func TestWebhookWorkflowRun(t *testing.T) {
+	const jobs = `{"total_count": 3, "jobs": [
+  {"id": 1, "run_id": 123, "status": "queued", "labels": ["self-hosted", "label1"]},
+  {"id": 2, "run_id": 123, "status": "queued", "labels": ["self-hosted", "label1"]},
+  {"id": 3, "run_id": 123, "status": "in_progress", "labels": ["self-hosted", "label1"]}
+]}`
+
+	newEvent := func(status string) *github.WorkflowRunEvent {
+		return &github.WorkflowRunEvent{
+			Action: github.String("requested"),
+			WorkflowRun: &github.WorkflowRun{
+				ID:     github.Int64(123),
+				Status: github.String(status),
+			},
+			Repo: &github.Repository{
+				Name: github.String("valid"),
+				Owner: &github.User{
+					Login: github.String("test"),
+					Type:  github.String("Organization"),
+				},
+			},
+		}
+	}
+
+	newObjs := func(reserveForWorkflowRun bool) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+								ReserveForWorkflowRun: reserveForWorkflowRun,
+							},
+						},
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Repository: "test/valid",
+							Labels:     []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	newWebhook := func() *HorizontalRunnerAutoscalerGitHubWebhook {
+		server := fake.NewServer(
+			fake.WithListRepositoryWorkflowRunsResponse(http.StatusOK, "", "", ""),
+			fake.WithListWorkflowJobsResponse(http.StatusOK, map[int]string{123: jobs}),
+			fake.WithListRunnersResponse(http.StatusOK, fake.RunnersListBody),
+		)
+		t.Cleanup(server.Close)
+
+		return &HorizontalRunnerAutoscalerGitHubWebhook{
+			GitHubClient: newGithubClient(server),
+		}
+	}
+
+	t.Run("Successful", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(),
+			"workflow_run",
+			newEvent("queued"),
+			200,
+			"scaled test-name by 2",
+			newObjs(true),
+		)
+	})
+
+	t.Run("JobsNotCreatedYet", func(t *testing.T) {
+		server := fake.NewServer(
+			fake.WithListRepositoryWorkflowRunsResponse(http.StatusOK, "", "", ""),
+			fake.WithListRunnersResponse(http.StatusOK, fake.RunnersListBody),
+		)
+		t.Cleanup(server.Close)
+
+		// The jobs of the run are created after the workflow_run event is delivered
+		var listed int
+
+		handler := server.Config.Handler
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/test/valid/actions/runs/123/jobs" {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			listed++
+
+			if listed == 1 {
+				fmt.Fprint(w, `{"total_count": 0, "jobs": []}`)
+				return
+			}
+
+			fmt.Fprint(w, jobs)
+		})
+
+		testServerWithWebhook(t,
+			&HorizontalRunnerAutoscalerGitHubWebhook{
+				GitHubClient:                 newGithubClient(server),
+				WorkflowRunJobsRetryInterval: time.Millisecond,
+			},
+			"workflow_run",
+			newEvent("queued"),
+			200,
+			"scaled test-name by 2",
+			newObjs(true),
+		)
+
+		if listed != 2 {
+			t.Errorf("listed the jobs %d times, want 2", listed)
+		}
+	})
+
+	t.Run("NotQueued", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(),
+			"workflow_run",
+			newEvent("in_progress"),
+			200,
+			"",
+			newObjs(true),
+		)
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(),
+			"workflow_run",
+			newEvent("queued"),
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newObjs(false),
+		)
+	})
+}
+
+func TestWebhookCheckRunAndDeployment(t *testing.T) {
+	repo := &github.Repository{
+		Name:     github.String("myrepo"),
+		FullName: github.String("MYORG/myrepo"),
+		Owner: &github.User{
+			Login: github.String("MYORG"),
+			Type:  github.String("Organization"),
+		},
+	}
+
+	initObjs := func(trigger actionsv1alpha1.GitHubEventScaleUpTriggerSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &trigger,
+						Amount:      2,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Repository: "MYORG/myrepo",
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	checkRun := &github.CheckRunEvent{
+		Action: github.String("rerequested"),
+		CheckRun: &github.CheckRun{
+			Name:   github.String("integration-test"),
+			Status: github.String("queued"),
+		},
+		Repo: repo,
+	}
+
+	deployment := &github.DeploymentEvent{
+		Deployment: &github.Deployment{
+			ID:          github.Int64(1),
+			Environment: github.String("production-eu"),
+		},
+		Repo: repo,
+	}
+
+	t.Run("CheckRun", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"check_run",
+			checkRun,
+			200,
+			"scaled test-name by 2",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				CheckRun: &actionsv1alpha1.CheckRunSpec{
+					Types:        []string{"rerequested"},
+					Names:        []string{"integration-*"},
+					Repositories: []string{"myrepo"},
+				},
+			}),
+		)
+	})
+
+	t.Run("CheckRunWithUnmatchedName", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"check_run",
+			checkRun,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				CheckRun: &actionsv1alpha1.CheckRunSpec{
+					Names: []string{"lint"},
+				},
+			}),
+		)
+	})
+
+	t.Run("CheckRunWithUnmatchedType", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"check_run",
+			checkRun,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				CheckRun: &actionsv1alpha1.CheckRunSpec{
+					Types: []string{"created"},
+				},
+			}),
+		)
+	})
+
+	t.Run("Deployment", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"deployment",
+			deployment,
+			200,
+			"scaled test-name by 2",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				Deployment: &actionsv1alpha1.DeploymentSpec{
+					Environments: []string{"production-*"},
+					Repositories: []string{"MYORG/myrepo"},
+				},
+			}),
+		)
+	})
+
+	t.Run("DeploymentWithUnmatchedEnvironment", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"deployment",
+			deployment,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				Deployment: &actionsv1alpha1.DeploymentSpec{
+					Environments: []string{"staging"},
+				},
+			}),
+		)
+	})
+
+	t.Run("DeploymentWithCheckRunTrigger", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"deployment",
+			deployment,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				CheckRun: &actionsv1alpha1.CheckRunSpec{},
+			}),
+		)
+	})
+}
+
 func TestGetRequest(t *testing.T) {
 	hra := HorizontalRunnerAutoscalerGitHubWebhook{}
 	request, _ := http.NewRequest(http.MethodGet, "/", nil)
@@ -434,9 +1052,13 @@ func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.
 func testServerWithInitObjs(t *testing.T, eventType string, event interface{}, wantCode int, wantBody string, initObjs []runtime.Object) {
 	t.Helper()
 
-	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
+	testServerWithWebhook(t, &HorizontalRunnerAutoscalerGitHubWebhook{}, eventType, event, wantCode, wantBody, initObjs)
+}
+
+func testServerWithWebhook(t *testing.T, hraWebhook *HorizontalRunnerAutoscalerGitHubWebhook, eventType string, event interface{}, wantCode int, wantBody string, initObjs []runtime.Object) {
+	t.Helper()
 
-	client := fake.NewClientBuilder().
+	client := clientfake.NewClientBuilder().
 		WithScheme(sc).
 		WithRuntimeObjects(initObjs...).
 		WithIndex(&actionsv1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, hraWebhook.indexer).
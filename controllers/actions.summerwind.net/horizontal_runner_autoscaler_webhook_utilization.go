@@ -0,0 +1,397 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultUtilizationSampleInterval = time.Minute
+
+	// utilizationBucketDuration is the resolution of the utilization history.
+	utilizationBucketDuration = 5 * time.Minute
+
+	// utilizationRetention is the longest report window, beyond which the history is forgotten.
+	utilizationRetention = 7 * 24 * time.Hour
+)
+
+// utilizationWindows are the trailing windows the utilization is reported over.
+var utilizationWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", utilizationRetention},
+}
+
+// UtilizationReport is the utilization of the runners scaled by a HorizontalRunnerAutoscaler over the trailing windows.
+type UtilizationReport struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	ScaleTarget string `json:"scaleTarget,omitempty"`
+
+	Windows []UtilizationWindow `json:"windows"`
+}
+
+// UtilizationWindow is the utilization of the runners over a trailing window.
+//
+// The runners are provisioned for the desired replicas of the HorizontalRunnerAutoscaler, and busy while running the workflow jobs
+// whose completed webhook events were routed to it.
+type UtilizationWindow struct {
+	Window string `json:"window"`
+
+	// ObservedSeconds is how much of the window the desired replicas were sampled for.
+	// It's shorter than the window until the history builds up after the webhook server started.
+	ObservedSeconds float64 `json:"observedSeconds"`
+
+	BusySeconds        float64 `json:"busySeconds"`
+	ProvisionedSeconds float64 `json:"provisionedSeconds"`
+
+	// Utilization is BusySeconds divided by ProvisionedSeconds.
+	// It can exceed 1 when the jobs ran on the runners that were being scaled down.
+	Utilization    float64 `json:"utilization"`
+	IdlePercentage float64 `json:"idlePercentage"`
+
+	Jobs              int     `json:"jobs"`
+	JobsPerRunnerHour float64 `json:"jobsPerRunnerHour"`
+
+	AverageBusyRunners        float64 `json:"averageBusyRunners"`
+	AverageProvisionedRunners float64 `json:"averageProvisionedRunners"`
+
+	// MinReplicas and MaxReplicas are the fewest and the most desired replicas sampled within the window.
+	MinReplicas *int `json:"minReplicas,omitempty"`
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+}
+
+type utilizationBucket struct {
+	start time.Time
+
+	observedSeconds    float64
+	busySeconds        float64
+	provisionedSeconds float64
+	jobs               int
+
+	sampled                  bool
+	minReplicas, maxReplicas int
+}
+
+type utilizationSeries struct {
+	scaleTarget string
+
+	lastSampled  time.Time
+	lastReplicas int
+
+	// buckets are ordered by their start times
+	buckets []*utilizationBucket
+}
+
+// bucket returns the bucket that t falls into, adding it when missing.
+func (s *utilizationSeries) bucket(t time.Time) *utilizationBucket {
+	start := t.Truncate(utilizationBucketDuration)
+
+	i := sort.Search(len(s.buckets), func(i int) bool { return !s.buckets[i].start.Before(start) })
+	if i < len(s.buckets) && s.buckets[i].start.Equal(start) {
+		return s.buckets[i]
+	}
+
+	b := &utilizationBucket{start: start}
+
+	s.buckets = append(s.buckets, nil)
+	copy(s.buckets[i+1:], s.buckets[i:])
+	s.buckets[i] = b
+
+	return b
+}
+
+// spread calls f with each bucket overlapping the period from `from` to `to`, along with the seconds of the overlap.
+func (s *utilizationSeries) spread(from, to time.Time, f func(b *utilizationBucket, seconds float64)) {
+	for from.Before(to) {
+		end := from.Truncate(utilizationBucketDuration).Add(utilizationBucketDuration)
+		if end.After(to) {
+			end = to
+		}
+
+		f(s.bucket(from), end.Sub(from).Seconds())
+
+		from = end
+	}
+}
+
+func (s *utilizationSeries) prune(now time.Time) {
+	cutoff := now.Add(-utilizationRetention)
+
+	for len(s.buckets) > 0 && !s.buckets[0].start.Add(utilizationBucketDuration).After(cutoff) {
+		s.buckets = s.buckets[1:]
+	}
+}
+
+// UtilizationTracker aggregates the utilization of the runners scaled by each HorizontalRunnerAutoscaler from the workflow job webhook events,
+// and serves it on an HTTP endpoint, so that the capacity owners can rightsize minReplicas and maxReplicas.
+//
+// The history is kept in memory, and is lost on restart. Each replica of the webhook server reports only on the events it received.
+//
+// It implements controller-runtime's manager.Runnable so that it can be added to the webhook server's manager.
+type UtilizationTracker struct {
+	Client client.Reader
+	Log    logr.Logger
+
+	// Namespace is the namespace of the HorizontalRunnerAutoscalers to sample.
+	// Set to empty for sampling all namespaces.
+	Namespace string
+
+	// SampleInterval is the interval between the samples of the desired replicas of the HorizontalRunnerAutoscalers.
+	SampleInterval time.Duration
+
+	mu     sync.Mutex
+	series map[types.NamespacedName]*utilizationSeries
+}
+
+func (t *UtilizationTracker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(t.sampleInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := t.Sample(ctx, time.Now()); err != nil {
+				t.Log.Error(err, "Could not sample the desired replicas of HorizontalRunnerAutoscalers")
+			}
+		}
+	}
+}
+
+func (t *UtilizationTracker) sampleInterval() time.Duration {
+	if t.SampleInterval <= 0 {
+		return DefaultUtilizationSampleInterval
+	}
+
+	return t.SampleInterval
+}
+
+// getSeries returns the series of the HRA, adding it when missing. Must be called with mu held.
+func (t *UtilizationTracker) getSeries(hra types.NamespacedName) *utilizationSeries {
+	if t.series == nil {
+		t.series = map[types.NamespacedName]*utilizationSeries{}
+	}
+
+	s, ok := t.series[hra]
+	if !ok {
+		s = &utilizationSeries{}
+		t.series[hra] = s
+	}
+
+	return s
+}
+
+// RecordWorkflowJob adds the run of the completed workflow job to the busy time of the runners of the HRA the job was routed to.
+// The jobs that were never assigned to a runner, like the ones canceled while queued, are ignored.
+func (t *UtilizationTracker) RecordWorkflowJob(hra v1alpha1.HorizontalRunnerAutoscaler, job *gogithub.WorkflowJob) {
+	if job.GetRunnerName() == "" || job.StartedAt == nil || job.CompletedAt == nil {
+		return
+	}
+
+	t.RecordJob(types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}, job.GetStartedAt().Time, job.GetCompletedAt().Time)
+}
+
+// RecordJob adds the job that ran from startedAt to completedAt to the busy time of the runners of the HRA.
+func (t *UtilizationTracker) RecordJob(hra types.NamespacedName, startedAt, completedAt time.Time) {
+	if !completedAt.After(startedAt) {
+		return
+	}
+
+	if cutoff := completedAt.Add(-utilizationRetention); startedAt.Before(cutoff) {
+		startedAt = cutoff
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getSeries(hra)
+
+	s.spread(startedAt, completedAt, func(b *utilizationBucket, seconds float64) {
+		b.busySeconds += seconds
+	})
+
+	s.bucket(completedAt).jobs++
+}
+
+// Sample adds the time since the last sample to the provisioned time of the HRAs at their desired replicas.
+// The first sample of an HRA only starts its history, and a gap longer than a few intervals, like while the API server was unavailable,
+// is not counted as the replicas in the gap are unknown.
+func (t *UtilizationTracker) Sample(ctx context.Context, now time.Time) error {
+	var hras v1alpha1.HorizontalRunnerAutoscalerList
+
+	if err := t.Client.List(ctx, &hras, client.InNamespace(t.Namespace)); err != nil {
+		return err
+	}
+
+	maxGap := 3 * t.sampleInterval()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := map[types.NamespacedName]bool{}
+
+	for _, hra := range hras.Items {
+		key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+		seen[key] = true
+
+		replicas := 0
+		if hra.Status.DesiredReplicas != nil {
+			replicas = *hra.Status.DesiredReplicas
+		}
+
+		s := t.getSeries(key)
+
+		kind := hra.Spec.ScaleTargetRef.Kind
+		if kind == "" {
+			kind = "RunnerDeployment"
+		}
+		s.scaleTarget = kind + "/" + hra.Spec.ScaleTargetRef.Name
+
+		if !s.lastSampled.IsZero() && now.After(s.lastSampled) && now.Sub(s.lastSampled) <= maxGap {
+			// The desired replicas are assumed to have stayed at the last sampled value until now
+			last := s.lastReplicas
+
+			s.spread(s.lastSampled, now, func(b *utilizationBucket, seconds float64) {
+				b.observedSeconds += seconds
+				b.provisionedSeconds += seconds * float64(last)
+			})
+		}
+
+		b := s.bucket(now)
+		if !b.sampled || replicas < b.minReplicas {
+			b.minReplicas = replicas
+		}
+		if !b.sampled || replicas > b.maxReplicas {
+			b.maxReplicas = replicas
+		}
+		b.sampled = true
+
+		s.lastSampled = now
+		s.lastReplicas = replicas
+
+		s.prune(now)
+	}
+
+	for key := range t.series {
+		if !seen[key] {
+			delete(t.series, key)
+		}
+	}
+
+	return nil
+}
+
+// Report returns the utilization of the HRAs over the trailing windows until now, ordered by the namespaces and the names.
+// The empty namespace and name match any.
+func (t *UtilizationTracker) Report(now time.Time, namespace, name string) []UtilizationReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reports := []UtilizationReport{}
+
+	for key, s := range t.series {
+		if (namespace != "" && key.Namespace != namespace) || (name != "" && key.Name != name) {
+			continue
+		}
+
+		r := UtilizationReport{
+			Namespace:   key.Namespace,
+			Name:        key.Name,
+			ScaleTarget: s.scaleTarget,
+		}
+
+		for _, w := range utilizationWindows {
+			r.Windows = append(r.Windows, s.window(now, w.name, w.duration))
+		}
+
+		reports = append(reports, r)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Namespace != reports[j].Namespace {
+			return reports[i].Namespace < reports[j].Namespace
+		}
+
+		return reports[i].Name < reports[j].Name
+	})
+
+	return reports
+}
+
+// window aggregates the buckets that start within the window until now.
+func (s *utilizationSeries) window(now time.Time, name string, d time.Duration) UtilizationWindow {
+	w := UtilizationWindow{Window: name}
+
+	from := now.Add(-d)
+
+	for _, b := range s.buckets {
+		if b.start.Before(from) || b.start.After(now) {
+			continue
+		}
+
+		w.ObservedSeconds += b.observedSeconds
+		w.BusySeconds += b.busySeconds
+		w.ProvisionedSeconds += b.provisionedSeconds
+		w.Jobs += b.jobs
+
+		if b.sampled {
+			if w.MinReplicas == nil || b.minReplicas < *w.MinReplicas {
+				fewest := b.minReplicas
+				w.MinReplicas = &fewest
+			}
+			if w.MaxReplicas == nil || b.maxReplicas > *w.MaxReplicas {
+				most := b.maxReplicas
+				w.MaxReplicas = &most
+			}
+		}
+	}
+
+	if w.ProvisionedSeconds > 0 {
+		w.Utilization = w.BusySeconds / w.ProvisionedSeconds
+		w.JobsPerRunnerHour = float64(w.Jobs) / (w.ProvisionedSeconds / time.Hour.Seconds())
+
+		if w.Utilization < 1 {
+			w.IdlePercentage = (1 - w.Utilization) * 100
+		}
+	}
+
+	if w.ObservedSeconds > 0 {
+		w.AverageBusyRunners = w.BusySeconds / w.ObservedSeconds
+		w.AverageProvisionedRunners = w.ProvisionedSeconds / w.ObservedSeconds
+	}
+
+	return w
+}
+
+// ServeHTTP serves the utilization report as JSON. The optional `namespace` and `name` query parameters filter the HRAs.
+func (t *UtilizationTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+
+	reports := t.Report(time.Now(), q.Get("namespace"), q.Get("name"))
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(reports); err != nil {
+		t.Log.Error(err, "failed writing utilization report")
+	}
+}
@@ -0,0 +1,98 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUtilizationTracker(t *testing.T) {
+	ctx := context.Background()
+
+	replicas := 2
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "example-runners"},
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{DesiredReplicas: &replicas},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	tracker := &UtilizationTracker{Client: c, Log: logr.Discard()}
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= 60; i++ {
+		if i == 30 {
+			replicas = 4
+			hra.Status.DesiredReplicas = &replicas
+			require.NoError(t, c.Update(ctx, hra))
+		}
+
+		require.NoError(t, tracker.Sample(ctx, t0.Add(time.Duration(i)*time.Minute)))
+	}
+
+	startedAt := gogithub.Timestamp{Time: t0.Add(10 * time.Minute)}
+	completedAt := gogithub.Timestamp{Time: t0.Add(40 * time.Minute)}
+
+	tracker.RecordWorkflowJob(*hra, &gogithub.WorkflowJob{RunnerName: gogithub.String("runner-1"), StartedAt: &startedAt, CompletedAt: &completedAt})
+
+	// Canceled while queued, so never assigned to a runner
+	tracker.RecordWorkflowJob(*hra, &gogithub.WorkflowJob{StartedAt: &startedAt, CompletedAt: &completedAt})
+
+	reports := tracker.Report(t0.Add(time.Hour), "", "")
+	require.Len(t, reports, 1)
+	require.Equal(t, "RunnerDeployment/example-runners", reports[0].ScaleTarget)
+	require.Len(t, reports[0].Windows, 3)
+
+	w := reports[0].Windows[0]
+	require.Equal(t, "1h", w.Window)
+	require.Equal(t, 3600.0, w.ObservedSeconds)
+	require.Equal(t, 1800.0, w.BusySeconds)
+	// 30 minutes at 2 replicas and 30 minutes at 4 replicas
+	require.Equal(t, 10800.0, w.ProvisionedSeconds)
+	require.InDelta(t, 1.0/6, w.Utilization, 1e-9)
+	require.InDelta(t, 500.0/6, w.IdlePercentage, 1e-9)
+	require.Equal(t, 1, w.Jobs)
+	require.InDelta(t, 1.0/3, w.JobsPerRunnerHour, 1e-9)
+	require.Equal(t, 0.5, w.AverageBusyRunners)
+	require.Equal(t, 3.0, w.AverageProvisionedRunners)
+	require.Equal(t, 2, *w.MinReplicas)
+	require.Equal(t, 4, *w.MaxReplicas)
+
+	// A gap longer than a few intervals isn't counted as provisioned
+	require.NoError(t, tracker.Sample(ctx, t0.Add(70*time.Minute)))
+	require.Equal(t, 10800.0, tracker.Report(t0.Add(70*time.Minute), "default", "example")[0].Windows[1].ProvisionedSeconds)
+
+	require.Empty(t, tracker.Report(t0.Add(70*time.Minute), "other", ""))
+
+	srv := httptest.NewServer(tracker)
+	t.Cleanup(srv.Close)
+
+	res, err := http.Get(srv.URL + "?name=example")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var served []UtilizationReport
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&served))
+	require.Len(t, served, 1)
+	require.Equal(t, "example", served[0].Name)
+
+	// The history of a deleted HRA is forgotten
+	require.NoError(t, c.Delete(ctx, hra))
+	require.NoError(t, tracker.Sample(ctx, t0.Add(71*time.Minute)))
+	require.Empty(t, tracker.Report(t0.Add(71*time.Minute), "", ""))
+}
@@ -0,0 +1,126 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	budgetLimitedByHourly = "hourly"
+	budgetLimitedByDaily  = "daily"
+
+	budgetDateFormat = "2006-01-02"
+)
+
+// applyBudget clamps the desired replicas so that the cost of running the runners
+// doesn't exceed the hourly and daily limits of the HRA's budget.
+// It returns the clamped desired replicas and the budget status to be saved into the HRA status,
+// which is nil when the HRA has no budget.
+func applyBudget(now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, desiredReplicas int) (int, *v1alpha1.BudgetStatus, error) {
+	budget := hra.Spec.Budget
+	if budget == nil {
+		return desiredReplicas, nil, nil
+	}
+
+	costPerRunnerHourStr := budget.CostPerRunnerHour
+	if costPerRunnerHourStr == "" {
+		costPerRunnerHourStr = st.annotations[AnnotationKeyCostPerRunnerHour]
+	}
+
+	if costPerRunnerHourStr == "" {
+		return 0, nil, fmt.Errorf("budget requires either spec.budget.costPerRunnerHour or the %s annotation on %s %s", AnnotationKeyCostPerRunnerHour, st.kind, st.st)
+	}
+
+	costPerRunnerHour, err := parseBudgetAmount("costPerRunnerHour", costPerRunnerHourStr)
+	if err != nil {
+		return 0, nil, err
+	} else if costPerRunnerHour == 0 {
+		return 0, nil, fmt.Errorf("budget: costPerRunnerHour must be greater than 0")
+	}
+
+	utcNow := now.UTC()
+	date := utcNow.Format(budgetDateFormat)
+	startOfDay := time.Date(utcNow.Year(), utcNow.Month(), utcNow.Day(), 0, 0, 0, 0, time.UTC)
+
+	var spent float64
+
+	if prev := hra.Status.Budget; prev != nil {
+		if prev.Date == date && prev.Spent != "" {
+			spent, err = strconv.ParseFloat(prev.Spent, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("budget: parsing status.budget.spent %q: %w", prev.Spent, err)
+			}
+		}
+
+		// Account the cost of the runners that have been running since the last reconciliation.
+		// We approximate the number of running runners with the last desired replicas.
+		if prev.LastAccountedTime != nil && hra.Status.DesiredReplicas != nil {
+			from := prev.LastAccountedTime.Time
+			if from.Before(startOfDay) {
+				from = startOfDay
+			}
+
+			if elapsed := utcNow.Sub(from); elapsed > 0 {
+				spent += float64(*hra.Status.DesiredReplicas) * costPerRunnerHour * elapsed.Hours()
+			}
+		}
+	}
+
+	replicas := desiredReplicas
+
+	var limitedBy string
+
+	if budget.HourlyLimit != "" {
+		hourlyLimit, err := parseBudgetAmount("hourlyLimit", budget.HourlyLimit)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if max := int(math.Floor(hourlyLimit / costPerRunnerHour)); replicas > max {
+			replicas = max
+			limitedBy = budgetLimitedByHourly
+		}
+	}
+
+	if budget.DailyLimit != "" {
+		dailyLimit, err := parseBudgetAmount("dailyLimit", budget.DailyLimit)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		remaining := math.Max(dailyLimit-spent, 0)
+
+		if max := int(math.Floor(remaining / costPerRunnerHour)); replicas > max {
+			replicas = max
+			limitedBy = budgetLimitedByDaily
+		}
+	}
+
+	status := &v1alpha1.BudgetStatus{
+		Date:              date,
+		Spent:             strconv.FormatFloat(spent, 'f', 4, 64),
+		LastAccountedTime: &metav1.Time{Time: now},
+		WithheldReplicas:  desiredReplicas - replicas,
+		LimitedBy:         limitedBy,
+	}
+
+	return replicas, status, nil
+}
+
+func parseBudgetAmount(field, v string) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("budget: parsing %s %q: %w", field, v, err)
+	}
+
+	if f < 0 {
+		return 0, fmt.Errorf("budget: %s must not be negative: %s", field, v)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,151 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyBudget(t *testing.T) {
+	now := time.Date(2021, 5, 8, 12, 0, 0, 0, time.UTC)
+	intPtr := func(v int) *int { return &v }
+
+	testcases := []struct {
+		name          string
+		spec          *v1alpha1.BudgetSpec
+		status        v1alpha1.HorizontalRunnerAutoscalerStatus
+		annotations   map[string]string
+		desired       int
+		want          int
+		wantWithheld  int
+		wantLimitedBy string
+		wantSpent     string
+		wantErr       string
+	}{
+		{
+			name:    "no budget",
+			desired: 10,
+			want:    10,
+		},
+		{
+			name:      "within hourly limit",
+			spec:      &v1alpha1.BudgetSpec{CostPerRunnerHour: "0.5", HourlyLimit: "5"},
+			desired:   10,
+			want:      10,
+			wantSpent: "0.0000",
+		},
+		{
+			name:          "clamped by hourly limit",
+			spec:          &v1alpha1.BudgetSpec{CostPerRunnerHour: "0.5", HourlyLimit: "4.9"},
+			desired:       10,
+			want:          9,
+			wantWithheld:  1,
+			wantLimitedBy: "hourly",
+			wantSpent:     "0.0000",
+		},
+		{
+			name:          "cost from annotation",
+			spec:          &v1alpha1.BudgetSpec{HourlyLimit: "3"},
+			annotations:   map[string]string{AnnotationKeyCostPerRunnerHour: "1"},
+			desired:       5,
+			want:          3,
+			wantWithheld:  2,
+			wantLimitedBy: "hourly",
+			wantSpent:     "0.0000",
+		},
+		{
+			name: "clamped by daily limit after accounting spent cost",
+			spec: &v1alpha1.BudgetSpec{CostPerRunnerHour: "1", DailyLimit: "20"},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(4),
+				Budget: &v1alpha1.BudgetStatus{
+					Date:              "2021-05-08",
+					Spent:             "10",
+					LastAccountedTime: &metav1.Time{Time: now.Add(-30 * time.Minute)},
+				},
+			},
+			desired:       10,
+			want:          8,
+			wantWithheld:  2,
+			wantLimitedBy: "daily",
+			wantSpent:     "12.0000",
+		},
+		{
+			name: "daily budget exhausted",
+			spec: &v1alpha1.BudgetSpec{CostPerRunnerHour: "1", DailyLimit: "20"},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(4),
+				Budget: &v1alpha1.BudgetStatus{
+					Date:              "2021-05-08",
+					Spent:             "19",
+					LastAccountedTime: &metav1.Time{Time: now.Add(-30 * time.Minute)},
+				},
+			},
+			desired:       5,
+			want:          0,
+			wantWithheld:  5,
+			wantLimitedBy: "daily",
+			wantSpent:     "21.0000",
+		},
+		{
+			name: "spent is reset on a new day",
+			spec: &v1alpha1.BudgetSpec{CostPerRunnerHour: "1", DailyLimit: "20"},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(1),
+				Budget: &v1alpha1.BudgetStatus{
+					Date:              "2021-05-07",
+					Spent:             "19",
+					LastAccountedTime: &metav1.Time{Time: time.Date(2021, 5, 7, 23, 0, 0, 0, time.UTC)},
+				},
+			},
+			desired:       30,
+			want:          8,
+			wantWithheld:  22,
+			wantLimitedBy: "daily",
+			wantSpent:     "12.0000",
+		},
+		{
+			name:    "missing cost",
+			spec:    &v1alpha1.BudgetSpec{HourlyLimit: "3"},
+			desired: 5,
+			wantErr: "budget requires either spec.budget.costPerRunnerHour or the actions-runner-controller/cost-per-runner-hour annotation on runnerdeployment rd",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec:   v1alpha1.HorizontalRunnerAutoscalerSpec{Budget: tc.spec},
+				Status: tc.status,
+			}
+
+			st := scaleTarget{st: "rd", kind: "runnerdeployment", annotations: tc.annotations}
+
+			got, status, err := applyBudget(now, hra, st, tc.desired)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+
+			if tc.spec == nil {
+				require.Nil(t, status)
+				return
+			}
+
+			require.NotNil(t, status)
+			require.Equal(t, "2021-05-08", status.Date)
+			require.Equal(t, tc.wantSpent, status.Spent)
+			require.Equal(t, tc.wantWithheld, status.WithheldReplicas)
+			require.Equal(t, tc.wantLimitedBy, status.LimitedBy)
+			require.Equal(t, now, status.LastAccountedTime.Time)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// maxReplicasFromCapacity returns the maximum replicas of the HRA derived from the allocatable resources of the nodes,
+// which is nil when the HRA has no maxReplicasFromCapacity.
+func (r *HorizontalRunnerAutoscalerReconciler) maxReplicasFromCapacity(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget) (*int, error) {
+	c := hra.Spec.MaxReplicasFromCapacity
+	if c == nil {
+		return nil, nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes, client.MatchingLabels(c.NodeSelector)); err != nil {
+		return nil, fmt.Errorf("listing nodes for maxReplicasFromCapacity: %w", err)
+	}
+
+	return capacityMaxReplicas(c, nodes.Items, st.podRequests)
+}
+
+// capacityMaxReplicas returns the number of the runner pods whose resource requests fit within the given share of
+// the allocatable resources of the schedulable nodes. The resources the runner pods don't request don't limit the replicas,
+// and it returns nil when none of the given resources are requested.
+func capacityMaxReplicas(c *v1alpha1.MaxReplicasFromCapacity, nodes []corev1.Node, podRequests corev1.ResourceList) (*int, error) {
+	allocatable := corev1.ResourceList{}
+
+	for _, n := range nodes {
+		if n.Spec.Unschedulable || !n.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		addResourceList(allocatable, n.Status.Allocatable)
+	}
+
+	var maxReplicas *int
+
+	for _, l := range []struct {
+		name  corev1.ResourceName
+		share string
+	}{
+		{corev1.ResourceCPU, c.CPU},
+		{corev1.ResourceMemory, c.Memory},
+	} {
+		perPod, ok := podRequests[l.name]
+		if l.share == "" || !ok || perPod.IsZero() {
+			continue
+		}
+
+		limit, err := v1alpha1.CapacityShare(l.share, allocatable[l.name])
+		if err != nil {
+			return nil, fmt.Errorf("maxReplicasFromCapacity.%s: %w", l.name, err)
+		}
+
+		replicas := int(limit.MilliValue() / perPod.MilliValue())
+		if maxReplicas == nil || replicas < *maxReplicas {
+			maxReplicas = &replicas
+		}
+	}
+
+	return maxReplicas, nil
+}
+
+// runnerSetPodRequests returns the resource requests of a runner pod of the RunnerSet, falling back to the limits for the containers that omit the requests.
+func runnerSetPodRequests(rs v1alpha1.RunnerSet) corev1.ResourceList {
+	return podResourceEnvelope(&corev1.Pod{Spec: rs.Spec.Template.Spec}, func(r corev1.ResourceRequirements) corev1.ResourceList {
+		l := r.Limits.DeepCopy()
+		if l == nil {
+			l = corev1.ResourceList{}
+		}
+		for name, q := range r.Requests {
+			l[name] = q
+		}
+		return l
+	})
+}
+
+// hrasForNode enqueues the HRAs whose maximum replicas are derived from the allocatable resources of the nodes,
+// so that they are recomputed as the nodes join and leave.
+func (r *HorizontalRunnerAutoscalerReconciler) hrasForNode(ctx context.Context, _ client.Object) []reconcile.Request {
+	var hras v1alpha1.HorizontalRunnerAutoscalerList
+	if err := r.List(ctx, &hras); err != nil {
+		r.Log.Error(err, "Failed to list horizontal runner autoscalers for the node change")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, hra := range hras.Items {
+		if hra.Spec.MaxReplicasFromCapacity != nil {
+			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}})
+		}
+	}
+
+	return reqs
+}
+
+// nodeCapacityChanged ignores the node updates that don't change the capacity, like the heartbeats of the node status.
+var nodeCapacityChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNode, ok := e.ObjectOld.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		newNode, ok := e.ObjectNew.(*corev1.Node)
+		if !ok {
+			return false
+		}
+
+		return oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+			!equality.Semantic.DeepEqual(oldNode.Labels, newNode.Labels) ||
+			!equality.Semantic.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable)
+	},
+}
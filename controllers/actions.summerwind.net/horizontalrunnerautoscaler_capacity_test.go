@@ -0,0 +1,60 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCapacityMaxReplicas(t *testing.T) {
+	newNode := func(cpu, memory string, unschedulable bool) corev1.Node {
+		return corev1.Node{
+			Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				},
+			},
+		}
+	}
+
+	nodes := []corev1.Node{
+		newNode("8", "32Gi", false),
+		newNode("8", "32Gi", false),
+		// Cordoned nodes don't count
+		newNode("8", "32Gi", true),
+	}
+
+	podRequests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("2Gi"),
+	}
+
+	max, err := capacityMaxReplicas(&v1alpha1.MaxReplicasFromCapacity{CPU: "50%"}, nodes, podRequests)
+	require.NoError(t, err)
+	require.Equal(t, 16, *max)
+
+	// The lower of the cpu and memory bounds applies
+	max, err = capacityMaxReplicas(&v1alpha1.MaxReplicasFromCapacity{CPU: "50%", Memory: "20Gi"}, nodes, podRequests)
+	require.NoError(t, err)
+	require.Equal(t, 10, *max)
+
+	// The absolute share is capped at the allocatable resources
+	max, err = capacityMaxReplicas(&v1alpha1.MaxReplicasFromCapacity{CPU: "100"}, nodes, podRequests)
+	require.NoError(t, err)
+	require.Equal(t, 32, *max)
+
+	// The resources the runner pods don't request don't limit the replicas
+	max, err = capacityMaxReplicas(&v1alpha1.MaxReplicasFromCapacity{CPU: "50%"}, nodes, corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("2Gi"),
+	})
+	require.NoError(t, err)
+	require.Nil(t, max)
+
+	_, err = capacityMaxReplicas(&v1alpha1.MaxReplicasFromCapacity{CPU: "150%"}, nodes, podRequests)
+	require.Error(t, err)
+}
@@ -25,19 +25,28 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 )
 
 const (
@@ -53,6 +62,26 @@ type HorizontalRunnerAutoscalerReconciler struct {
 	Scheme                *runtime.Scheme
 	DefaultScaleDownDelay time.Duration
 	Name                  string
+
+	// JobRunDurations is used to extend the scale down delay of HRAs with spec.scaleDownDelayFromJobRunDuration.
+	JobRunDurations JobRunDurations
+
+	// JobDurationHistograms is used to evaluate spec.serviceLevelObjectives of HRAs.
+	JobDurationHistograms JobDurationHistograms
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+
+	// DryRun is the default of spec.dryRun of the HRAs.
+	DryRun bool
+
+	// CloudEvents emits the scale decisions of the HRAs. Nil when the CloudEvents are disabled.
+	CloudEvents cloudevents.Emitter
+
+	// Policy decides whether the scale targets may be scaled up. Nil when the policy hook is disabled.
+	Policy *policyhook.Hook
+
+	slos sloTracker
 }
 
 const defaultReplicas = 1
@@ -61,10 +90,12 @@ const defaultReplicas = 1
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=maintenancewindows,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("horizontalrunnerautoscaler", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "HorizontalRunnerAutoscaler", req.NamespacedName)
 
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 	if err := r.Get(ctx, req.NamespacedName, &hra); err != nil {
@@ -73,12 +104,20 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 	if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
 		r.GitHubClient.DeinitForHRA(&hra)
+		r.slos.forget(req.NamespacedName)
+		metrics.DeleteHorizontalRunnerAutoscalerServiceLevelObjectives(hra.ObjectMeta)
 
 		return ctrl.Result{}, nil
 	}
 
 	metrics.SetHorizontalRunnerAutoscalerSpec(hra.ObjectMeta, hra.Spec)
 
+	// The capacity reservations are added by the webhook-based autoscaler
+	// The scale target keeps its last replicas while the scaling is suspended
+	if suspendedOnDrift(ctx, r.Client, r.Recorder, log, &hra, func(obj *v1alpha1.HorizontalRunnerAutoscaler) *[]metav1.Condition { return &obj.Status.Conditions }, []string{"capacityReservations"}, v1alpha1.ConditionTypeScalingActive) {
+		return ctrl.Result{}, nil
+	}
+
 	kind := hra.Spec.ScaleTargetRef.Kind
 
 	switch kind {
@@ -88,6 +127,9 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			Namespace: req.Namespace,
 			Name:      hra.Spec.ScaleTargetRef.Name,
 		}, &rd); err != nil {
+			if kerrors.IsNotFound(err) {
+				r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonScaleTargetNotFound, fmt.Errorf("RunnerDeployment %s not found", hra.Spec.ScaleTargetRef.Name))
+			}
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
@@ -111,10 +153,19 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 				}
 			}
 
+			// The runners for the label resource profiles follow the capacity reservations for the jobs requesting the labels of the profiles,
+			// and the quarantined runners follow the ones for the suspicious jobs
+			var profileReplicas map[string]int
+			if len(rd.Spec.LabelResourceProfiles) > 0 || rd.Spec.Quarantine != nil {
+				profileReplicas = profileReplicasFromReservations(getValidCapacityReservations(&hra))
+			}
+			profileReplicasChanged := !equality.Semantic.DeepEqual(rd.Spec.ProfileReplicas, profileReplicas)
+
 			// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-			if currentDesiredReplicas != newDesiredReplicas {
+			if currentDesiredReplicas != newDesiredReplicas || profileReplicasChanged {
 				copy := rd.DeepCopy()
 				copy.Spec.Replicas = &newDesiredReplicas
+				copy.Spec.ProfileReplicas = profileReplicas
 
 				if ephemeral && effectiveTime != nil {
 					copy.Spec.EffectiveTime = &metav1.Time{Time: *effectiveTime}
@@ -139,6 +190,9 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			Namespace: req.Namespace,
 			Name:      hra.Spec.ScaleTargetRef.Name,
 		}, &rs); err != nil {
+			if kerrors.IsNotFound(err) {
+				r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonScaleTargetNotFound, fmt.Errorf("RunnerSet %s not found", hra.Spec.ScaleTargetRef.Name))
+			}
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
@@ -154,13 +208,16 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 		}
 
 		st := scaleTarget{
-			st:         rs.Name,
-			kind:       "runnerset",
-			enterprise: rs.Spec.Enterprise,
-			org:        rs.Spec.Organization,
-			repo:       rs.Spec.Repository,
-			replicas:   replicas,
-			labels:     rs.Spec.RunnerConfig.Labels,
+			st:           rs.Name,
+			kind:         "runnerset",
+			enterprise:   rs.Spec.Enterprise,
+			org:          rs.Spec.Organization,
+			repo:         rs.Spec.Repository,
+			replicas:     replicas,
+			labels:       rs.Spec.RunnerConfig.Labels,
+			annotations:  rs.Annotations,
+			objectLabels: rs.Labels,
+			podRequests:  runnerSetPodRequests(rs),
 			getRunnerMap: func() (map[string]struct{}, error) {
 				// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 				var runnerPodList corev1.PodList
@@ -247,13 +304,16 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Context, rd v1alpha1.RunnerDeployment) scaleTarget {
 	st := scaleTarget{
-		st:         rd.Name,
-		kind:       "runnerdeployment",
-		enterprise: rd.Spec.Template.Spec.Enterprise,
-		org:        rd.Spec.Template.Spec.Organization,
-		repo:       rd.Spec.Template.Spec.Repository,
-		replicas:   rd.Spec.Replicas,
-		labels:     rd.Spec.Template.Spec.RunnerConfig.Labels,
+		st:           rd.Name,
+		kind:         "runnerdeployment",
+		enterprise:   rd.Spec.Template.Spec.Enterprise,
+		org:          rd.Spec.Template.Spec.Organization,
+		repo:         rd.Spec.Template.Spec.Repository,
+		replicas:     rd.Spec.Replicas,
+		labels:       rd.Spec.Template.Spec.RunnerConfig.Labels,
+		annotations:  rd.Annotations,
+		objectLabels: rd.Labels,
+		podRequests:  runnerPodRequests(rd.Spec.Template.Spec),
 		getRunnerMap: func() (map[string]struct{}, error) {
 			// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 			var runnerList v1alpha1.RunnerList
@@ -297,38 +357,185 @@ type scaleTarget struct {
 	enterprise, repo, org string
 	replicas              *int
 	labels                []string
+	annotations           map[string]string
+	podRequests           corev1.ResourceList
+
+	// objectLabels are the labels of the RunnerDeployment or the RunnerSet, which the MaintenanceWindows select.
+	objectLabels map[string]string
 
 	getRunnerMap func() (map[string]struct{}, error)
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, updatedDesiredReplicas func(int) error) (ctrl.Result, error) {
+	// The shard of the HRA is the shard of its scale target
+	if !r.Sharder.Owns(hra.Namespace, sharding.Owner(st.enterprise, st.org, st.repo)) {
+		return ctrl.Result{}, nil
+	}
+
 	now := time.Now()
 
 	minReplicas, active, upcoming, err := r.getMinReplicas(log, now, hra)
 	if err != nil {
 		log.Error(err, "Could not compute min replicas")
 
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonComputeReplicasFailed, err)
+
 		return ctrl.Result{}, err
 	}
 
 	ghc, err := r.GitHubClient.InitForHRA(context.Background(), &hra)
 	if err != nil {
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonReconcileFailed, err)
+
 		return ctrl.Result{}, err
 	}
 
-	newDesiredReplicas, err := r.computeReplicasWithCache(ghc, log, now, st, hra, minReplicas)
+	maxReplicasFromCapacity, err := r.maxReplicasFromCapacity(ctx, hra, st)
+	if err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+
+		log.Error(err, "Could not compute max replicas from capacity")
+
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonComputeReplicasFailed, err)
+
+		return ctrl.Result{}, err
+	}
+
+	// The lower of maxReplicas and the max replicas from the capacity applies
+	scaled := hra
+	if m := maxReplicasFromCapacity; m != nil && (hra.Spec.MaxReplicas == nil || *m < *hra.Spec.MaxReplicas) {
+		scaled.Spec.MaxReplicas = m
+	}
+
+	newDesiredReplicas, err := r.computeReplicasWithCache(ghc, log, now, st, scaled, minReplicas)
 	if err != nil {
 		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
 		log.Error(err, "Could not compute replicas")
 
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonComputeReplicasFailed, err)
+
+		return ctrl.Result{}, err
+	}
+
+	newDesiredReplicas, budgetStatus, err := applyBudget(now, hra, st, newDesiredReplicas)
+	if err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+
+		log.Error(err, "Could not apply budget")
+
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonComputeReplicasFailed, err)
+
+		return ctrl.Result{}, err
+	}
+
+	if budgetStatus != nil && budgetStatus.WithheldReplicas > 0 {
+		log.Info(
+			fmt.Sprintf("Withheld %d replicas due to the %s budget", budgetStatus.WithheldReplicas, budgetStatus.LimitedBy),
+			"desired", newDesiredReplicas,
+			"spent", budgetStatus.Spent,
+		)
+	}
+
+	maintenanceWindows, err := maintenanceWindowsForHRA(ctx, r.Client, hra.Namespace, st.objectLabels)
+	if err != nil {
+		log.Error(err, "Could not find maintenance windows")
+
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonReconcileFailed, err)
+
 		return ctrl.Result{}, err
 	}
 
-	if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+	// The maintenance holds the scale up, without touching the spec of the HRA, so that its minReplicas applies again after the maintenance
+	var maintenance *v1alpha1.MaintenanceWindow
+	if len(maintenanceWindows) > 0 {
+		maintenance = &maintenanceWindows[0]
+
+		held := maintenanceReplicas(*maintenance, getIntOrDefault(st.replicas, defaultReplicas), newDesiredReplicas)
+		if held != newDesiredReplicas {
+			log.V(1).Info("Held the replicas during the maintenance", "maintenancewindow", maintenance.Name, "computed", newDesiredReplicas, "held", held)
+		}
+		newDesiredReplicas = held
+	}
+
+	// The denied scale up holds the current replicas rather than scaling down, so that the busy runners aren't disrupted
+	var policyDenial *policyhook.Decision
+	if current := getIntOrDefault(st.replicas, defaultReplicas); r.Policy != nil && newDesiredReplicas > current {
+		input := scaleUpPolicyInput(hra, st, current, newDesiredReplicas)
+		input.RepositoryInfo = policyRepositoryInfo(ctx, log, r.Policy, ghc, st.repo)
+
+		if d := r.Policy.Decide(ctx, input); !d.Allowed {
+			log.V(1).Info("Held the replicas as the policy denied the scale up", "computed", newDesiredReplicas, "held", current, "reason", d.Reason)
+
+			policyDenial = &d
+			newDesiredReplicas = current
+		}
+	}
+
+	paused, pausedUntil, err := hraPause(now, hra)
+	if err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "InvalidPausedUntil", err.Error())
+
+		log.Error(err, "Ignoring the invalid paused-until annotation")
+	}
+
+	dryRun := !paused && hraDryRun(hra, r.DryRun)
+
+	var suggestedReplicas *int
+
+	if paused || dryRun {
+		// The replicas of the scale target are left as they are, while the status keeps telling what they would be
+		suggested := newDesiredReplicas
+		suggestedReplicas = &suggested
+		newDesiredReplicas = getIntOrDefault(st.replicas, defaultReplicas)
+
+		if paused {
+			log.V(1).Info("Skipped scaling as the HRA is paused", "suggested", suggested, "current", newDesiredReplicas)
+		} else {
+			log.Info("Skipped scaling as the HRA is in dry-run", "suggested", suggested, "current", newDesiredReplicas)
+
+			// The event is emitted only when the decision changes, not on every sync
+			if suggested != newDesiredReplicas && (hra.Status.SuggestedReplicas == nil || *hra.Status.SuggestedReplicas != suggested) {
+				r.Recorder.Event(&hra, corev1.EventTypeNormal, "DryRunScale", fmt.Sprintf("Would scale %s %s from %d to %d replicas", st.kind, st.st, newDesiredReplicas, suggested))
+			}
+		}
+	} else if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+		r.setFailedConditions(ctx, log, &hra, v1alpha1.ConditionReasonReconcileFailed, err)
+
 		return ctrl.Result{}, err
 	}
 
+	if dryRun != hra.Status.DryRun {
+		if dryRun {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "DryRunStarted", fmt.Sprintf("Started computing the replicas of %s %s without applying them", st.kind, st.st))
+		} else {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "DryRunStopped", fmt.Sprintf("Stopped the dry-run and resumed scaling %s %s", st.kind, st.st))
+		}
+	}
+
+	if paused != hra.Status.Paused {
+		if paused {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "Paused", fmt.Sprintf("Paused scaling %s %s", st.kind, st.st))
+		} else {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "Resumed", fmt.Sprintf("Resumed scaling %s %s", st.kind, st.st))
+		}
+	}
+
+	var decision *cloudevents.ScaleDecisionData
+
+	// The paused HRAs decide nothing, while the HRAs in dry-run decide the replicas they would set, like the DryRunScale event
+	switch {
+	case paused:
+	case dryRun:
+		if suggested := *suggestedReplicas; suggested != newDesiredReplicas && (hra.Status.SuggestedReplicas == nil || *hra.Status.SuggestedReplicas != suggested) {
+			current := newDesiredReplicas
+			decision = scaleDecisionData(hra, scaled, &current, suggested, minReplicas)
+			decision.DryRun = true
+		}
+	case hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas:
+		decision = scaleDecisionData(hra, scaled, hra.Status.DesiredReplicas, newDesiredReplicas, minReplicas)
+	}
+
 	updated := hra.DeepCopy()
 
 	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
@@ -364,6 +571,76 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		updated.Status.ScheduledOverridesSummary = nil
 	}
 
+	if active != nil {
+		updated.Status.ActiveScheduledOverride = &v1alpha1.ActiveScheduledOverride{
+			Name:        active.ScheduledOverride.Name,
+			TimeZone:    active.ScheduledOverride.TimeZone,
+			StartTime:   metav1.Time{Time: active.Period.StartTime},
+			EndTime:     metav1.Time{Time: active.Period.EndTime},
+			MinReplicas: active.ScheduledOverride.MinReplicas,
+		}
+	} else {
+		updated.Status.ActiveScheduledOverride = nil
+	}
+
+	updated.Status.Budget = budgetStatus
+	updated.Status.MaxReplicasFromCapacity = maxReplicasFromCapacity
+
+	updated.Status.Paused = paused
+	updated.Status.DryRun = dryRun
+	updated.Status.SuggestedReplicas = suggestedReplicas
+	updated.Status.PausedUntil = nil
+	updated.Status.ObservedGeneration = hra.Generation
+
+	setHRAScalingConditions(&updated.Status.Conditions, hra.Generation, paused, dryRun, getIntOrDefault(suggestedReplicas, newDesiredReplicas))
+
+	if maintenance != nil && !paused && !dryRun {
+		message := fmt.Sprintf("Held by the maintenance %s", maintenance.Name)
+		if p := maintenance.Status.CurrentPeriod; p != nil {
+			message += " until " + p.EndTime.UTC().Format(time.RFC3339)
+		}
+
+		// The event is emitted only when the maintenance starts holding the HRA, not on every sync
+		if c := meta.FindStatusCondition(hra.Status.Conditions, v1alpha1.ConditionTypeScalingActive); c == nil || c.Reason != v1alpha1.ConditionReasonMaintenanceWindow {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "MaintenanceStarted", fmt.Sprintf("Held scaling %s %s for the maintenance %s", st.kind, st.st, maintenance.Name))
+		}
+
+		setCondition(&updated.Status.Conditions, hra.Generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonMaintenanceWindow, message)
+	}
+
+	if policyDenial != nil && !paused && !dryRun {
+		message := "Scale up denied by the policy: " + policyDenial.Reason
+
+		// The event is emitted only when the policy starts denying the scale up, not on every sync
+		if c := meta.FindStatusCondition(hra.Status.Conditions, v1alpha1.ConditionTypeScalingActive); c == nil || c.Reason != v1alpha1.ConditionReasonPolicyDenied {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, EventReasonPolicyDenied, fmt.Sprintf("Denied scaling up %s %s: %s", st.kind, st.st, policyDenial.Reason))
+		}
+
+		setCondition(&updated.Status.Conditions, hra.Generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonPolicyDenied, message)
+	}
+
+	if len(hra.Spec.ServiceLevelObjectives) > 0 {
+		ok, reason, message := r.serviceLevelObjectivesCondition(ctx, now, hra, st.labels)
+		// The event is emitted only when the objectives start failing for the reason, not on every sync
+		if c := meta.FindStatusCondition(hra.Status.Conditions, v1alpha1.ConditionTypeServiceLevelObjectivesMet); !ok && (c == nil || c.Reason != reason) {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, reason, message)
+		}
+
+		setCondition(&updated.Status.Conditions, hra.Generation, v1alpha1.ConditionTypeServiceLevelObjectivesMet, ok, reason, message)
+	} else {
+		meta.RemoveStatusCondition(&updated.Status.Conditions, v1alpha1.ConditionTypeServiceLevelObjectivesMet)
+		metrics.DeleteHorizontalRunnerAutoscalerServiceLevelObjectives(hra.ObjectMeta)
+	}
+
+	var result ctrl.Result
+
+	if pausedUntil != nil {
+		updated.Status.PausedUntil = &metav1.Time{Time: *pausedUntil}
+
+		// Resume right after the pause expires, instead of waiting for the next sync
+		result.RequeueAfter = pausedUntil.Sub(now)
+	}
+
 	if !reflect.DeepEqual(hra.Status, updated.Status) {
 		metrics.SetHorizontalRunnerAutoscalerStatus(updated.ObjectMeta, updated.Status)
 
@@ -372,7 +649,71 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// The decision is emitted once the status records it, so that a failed patch doesn't emit it twice
+	if decision != nil {
+		emitCloudEvent(ctx, log, r.CloudEvents, cloudevents.TypeScaleDecision, hraCloudEventSubject(hra), decision)
+	}
+
+	return result, nil
+}
+
+// scaleDecisionData returns the data of the event of the HRA deciding the desired replicas of its scale target.
+// scaled is the HRA with the max replicas lowered to the capacity of the cluster.
+func scaleDecisionData(hra, scaled v1alpha1.HorizontalRunnerAutoscaler, previous *int, desired, minReplicas int) *cloudevents.ScaleDecisionData {
+	kind := hra.Spec.ScaleTargetRef.Kind
+	if kind == "" {
+		kind = "RunnerDeployment"
+	}
+
+	var prev *int
+	if previous != nil {
+		v := *previous
+		prev = &v
+	}
+
+	return &cloudevents.ScaleDecisionData{
+		Namespace:        hra.Namespace,
+		Name:             hra.Name,
+		ScaleTargetKind:  kind,
+		ScaleTargetName:  hra.Spec.ScaleTargetRef.Name,
+		PreviousReplicas: prev,
+		DesiredReplicas:  desired,
+		MinReplicas:      minReplicas,
+		MaxReplicas:      scaled.Spec.MaxReplicas,
+	}
+}
+
+// setHRAScalingConditions sets the conditions of the autoscaler that computed the desired replicas of the scale target.
+func setHRAScalingConditions(conditions *[]metav1.Condition, generation int64, paused, dryRun bool, desiredReplicas int) {
+	setCondition(conditions, generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonReconciled, "")
+	setCondition(conditions, generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+	setCondition(conditions, generation, v1alpha1.ConditionTypeRateLimited, false, v1alpha1.ConditionReasonWithinRateLimit, "")
+
+	if paused {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonPaused, fmt.Sprintf("Suggested %d replicas while paused", desiredReplicas))
+	} else if dryRun {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeScalingActive, false, v1alpha1.ConditionReasonDryRun, fmt.Sprintf("Suggested %d replicas in dry-run", desiredReplicas))
+	} else {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeScalingActive, true, v1alpha1.ConditionReasonDesiredReplicasComputed, "")
+	}
+}
+
+// setFailedConditions patches the conditions of the autoscaler that failed to scale the scale target for the reason.
+// RateLimited turns true when the failure is due to the GitHub API rate limit.
+func (r *HorizontalRunnerAutoscalerReconciler) setFailedConditions(ctx context.Context, log logr.Logger, hra *v1alpha1.HorizontalRunnerAutoscaler, reason string, err error) {
+	if err := patchConditions(ctx, r.Client, hra, func(obj *v1alpha1.HorizontalRunnerAutoscaler) *[]metav1.Condition { return &obj.Status.Conditions }, func(conditions *[]metav1.Condition) {
+		setCondition(conditions, hra.Generation, v1alpha1.ConditionTypeReady, false, reason, err.Error())
+		setCondition(conditions, hra.Generation, v1alpha1.ConditionTypeSynced, false, reason, err.Error())
+		setCondition(conditions, hra.Generation, v1alpha1.ConditionTypeScalingActive, false, reason, err.Error())
+
+		if isRateLimited(err) {
+			setCondition(conditions, hra.Generation, v1alpha1.ConditionTypeRateLimited, true, v1alpha1.ConditionReasonRateLimitExceeded, err.Error())
+		} else {
+			setCondition(conditions, hra.Generation, v1alpha1.ConditionTypeRateLimited, false, v1alpha1.ConditionReasonWithinRateLimit, "")
+		}
+	}); err != nil {
+		log.Error(err, "Failed to update horizontalrunnerautoscaler status conditions")
+	}
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -385,8 +726,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.hrasForNode), builder.WithPredicates(nodeCapacityChanged)).
+		Watches(&v1alpha1.MaintenanceWindow{}, handler.EnqueueRequestsFromMapFunc(r.hrasForMaintenanceWindow)).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Observe(name, "HorizontalRunnerAutoscaler", r))
 }
 
 type Override struct {
@@ -408,13 +751,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 			"untilTime", o.RecurrenceRule.UntilTime,
 		)
 
-		a, u, err := MatchSchedule(
-			now, o.StartTime.Time, o.EndTime.Time,
-			RecurrenceRule{
-				Frequency: o.RecurrenceRule.Frequency,
-				UntilTime: o.RecurrenceRule.UntilTime.Time,
-			},
-		)
+		a, u, err := matchRecurrence(now, o.StartTime.Time, o.EndTime.Time, o.TimeZone, o.RecurrenceRule)
 		if err != nil {
 			return minReplicas, nil, nil, err
 		}
@@ -451,6 +788,59 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 	return minReplicas, active, upcoming, nil
 }
 
+// matchRecurrence returns the active and the upcoming periods of the recurrence of the period from startTime to endTime,
+// like a scheduled override or a maintenance window.
+func matchRecurrence(now, startTime, endTime time.Time, timeZone string, rule v1alpha1.RecurrenceRule) (*Period, *Period, error) {
+	untilTime := rule.UntilTime.Time
+
+	// Recur in the time zone's local time so that e.g. a daily 09:00-18:00 override
+	// keeps starting at 09:00 across daylight saving time transitions.
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading time zone %q: %w", timeZone, err)
+		}
+
+		startTime, endTime, untilTime = startTime.In(loc), endTime.In(loc), untilTime.In(loc)
+	}
+
+	weekdays, err := parseWeekdays(rule.Weekdays)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return MatchSchedule(
+		now, startTime, endTime,
+		RecurrenceRule{
+			Frequency: rule.Frequency,
+			UntilTime: untilTime,
+			Weekdays:  weekdays,
+		},
+	)
+}
+
+func parseWeekdays(days []v1alpha1.Weekday) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+
+	for _, d := range days {
+		var found bool
+
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if string(d) == wd.String() {
+				weekdays = append(weekdays, wd)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("invalid weekday %q: It must be one of Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, and Sunday", d)
+		}
+	}
+
+	return weekdays, nil
+}
+
 func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, *Override, *Override, error) {
 	minReplicas := defaultReplicas
 	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
@@ -509,6 +899,11 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 		scaleDownDelay = r.DefaultScaleDownDelay
 	}
 
+	jobRunDurationDelay := r.scaleDownDelayFromJobRunDuration(log, st, hra)
+	if jobRunDurationDelay > scaleDownDelay {
+		scaleDownDelay = jobRunDurationDelay
+	}
+
 	var scaleDownDelayUntil *time.Time
 
 	if hra.Status.DesiredReplicas == nil ||
@@ -541,6 +936,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 		kvs = append(kvs, "max", *maxReplicas)
 	}
 
+	if jobRunDurationDelay > 0 {
+		kvs = append(kvs, "job_run_duration_delay", jobRunDurationDelay)
+	}
+
 	if scaleDownDelayUntil != nil {
 		kvs = append(kvs, "last_scale_up_time", *hra.Status.LastSuccessfulScaleOutTime)
 		kvs = append(kvs, "scale_down_delay_until", scaleDownDelayUntil)
@@ -552,3 +951,43 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 
 	return newDesiredReplicas, nil
 }
+
+const defaultScaleDownDelayJobRunDurationPercentile = 90
+
+// scaleDownDelayFromJobRunDuration returns the configured percentile of the run times of the jobs that can run on the scale target,
+// or 0 if it is not configured or the job run times are not available.
+// A failure to obtain the job run times is logged but doesn't fail the reconciliation, as the scale down delay is just a hysteresis.
+func (r *HorizontalRunnerAutoscalerReconciler) scaleDownDelayFromJobRunDuration(log logr.Logger, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) time.Duration {
+	spec := hra.Spec.ScaleDownDelayFromJobRunDuration
+	if spec == nil {
+		return 0
+	}
+
+	if r.JobRunDurations == nil {
+		log.V(1).Info("Ignoring scaleDownDelayFromJobRunDuration as the controller is not configured with the actions-metrics-server URL")
+
+		return 0
+	}
+
+	percentile := defaultScaleDownDelayJobRunDurationPercentile
+	if spec.Percentile != nil {
+		percentile = *spec.Percentile
+	}
+
+	d, ok, err := r.JobRunDurations.Percentile(context.Background(), st.labels, float64(percentile)/100)
+	if err != nil {
+		log.Error(err, "Could not get job run durations")
+
+		return 0
+	} else if !ok {
+		return 0
+	}
+
+	if spec.MaxDelaySeconds != nil {
+		if max := time.Duration(*spec.MaxDelaySeconds) * time.Second; d > max {
+			d = max
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,36 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+// hraPause tells whether the scaling of the HRA is paused at now, either by spec.paused or by the paused-until annotation.
+// until is the expiry of the pause by the annotation, which is nil when the HRA is paused by spec.paused or not paused at all.
+// The annotation with an invalid time is reported as an error and ignored.
+func hraPause(now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (paused bool, until *time.Time, err error) {
+	if v, ok := hra.Annotations[AnnotationKeyPausedUntil]; ok && !hra.Spec.Paused {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return false, nil, fmt.Errorf("parsing the %s annotation %q: %w", AnnotationKeyPausedUntil, v, err)
+		}
+
+		if now.Before(t) {
+			return true, &t, nil
+		}
+	}
+
+	return hra.Spec.Paused, nil, nil
+}
+
+// hraDryRun tells whether the HRA computes the desired replicas without applying them,
+// which is spec.dryRun, or the default of the controller when it's unset.
+func hraDryRun(hra v1alpha1.HorizontalRunnerAutoscaler, defaultDryRun bool) bool {
+	if hra.Spec.DryRun != nil {
+		return *hra.Spec.DryRun
+	}
+
+	return defaultDryRun
+}
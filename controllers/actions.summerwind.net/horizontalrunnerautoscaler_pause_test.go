@@ -0,0 +1,66 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHRAPause(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	newHRA := func(paused bool, pausedUntil string) v1alpha1.HorizontalRunnerAutoscaler {
+		hra := v1alpha1.HorizontalRunnerAutoscaler{}
+		hra.Spec.Paused = paused
+		if pausedUntil != "" {
+			hra.Annotations = map[string]string{AnnotationKeyPausedUntil: pausedUntil}
+		}
+		return hra
+	}
+
+	paused, until, err := hraPause(now, newHRA(false, ""))
+	require.NoError(t, err)
+	require.False(t, paused)
+	require.Nil(t, until)
+
+	paused, until, err = hraPause(now, newHRA(true, ""))
+	require.NoError(t, err)
+	require.True(t, paused)
+	require.Nil(t, until)
+
+	paused, until, err = hraPause(now, newHRA(false, "2026-10-17T13:00:00Z"))
+	require.NoError(t, err)
+	require.True(t, paused)
+	require.Equal(t, now.Add(time.Hour), *until)
+
+	// The expired annotation is ignored
+	paused, until, err = hraPause(now, newHRA(false, "2026-10-17T11:00:00Z"))
+	require.NoError(t, err)
+	require.False(t, paused)
+	require.Nil(t, until)
+
+	// spec.paused outlives the annotation
+	paused, until, err = hraPause(now, newHRA(true, "2026-10-17T13:00:00Z"))
+	require.NoError(t, err)
+	require.True(t, paused)
+	require.Nil(t, until)
+
+	paused, _, err = hraPause(now, newHRA(false, "in an hour"))
+	require.Error(t, err)
+	require.False(t, paused)
+}
+
+func TestHRADryRun(t *testing.T) {
+	hra := v1alpha1.HorizontalRunnerAutoscaler{}
+
+	require.False(t, hraDryRun(hra, false))
+	require.True(t, hraDryRun(hra, true))
+
+	hra.Spec.DryRun = ptr(false)
+	require.False(t, hraDryRun(hra, true), "spec.dryRun overrides the default of the controller")
+
+	hra.Spec.DryRun = ptr(true)
+	require.True(t, hraDryRun(hra, false))
+}
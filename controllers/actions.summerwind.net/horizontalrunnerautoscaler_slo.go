@@ -0,0 +1,254 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The windows of the error budget burn rates, and the burn rates over them that fail the ServiceLevelObjectivesMet condition.
+// They are the burn rates that spend 2% and 5% of a 30 days error budget within the windows, as in the multiwindow burn rate alerts of the Google SRE workbook.
+const (
+	sloFastBurnWindow = time.Hour
+	sloSlowBurnWindow = 6 * time.Hour
+
+	// The values of the window label of the burn rate metric
+	sloFastBurnWindowLabel = "1h"
+	sloSlowBurnWindowLabel = "6h"
+
+	sloFastBurnRateThreshold = 14.4
+	sloSlowBurnRateThreshold = 6
+)
+
+// JobDurationHistograms provides the histograms of the workflow job durations for the service level objectives of the HRAs.
+type JobDurationHistograms interface {
+	// CountWithin returns the number of the jobs that are runnable on the runners with the given labels and whose durations
+	// in the histogram of the metric are within the threshold, along with the number of all those jobs.
+	// The counts are cumulative since the histograms started counting.
+	CountWithin(ctx context.Context, metricName string, runnerLabels []string, threshold time.Duration) (within, total float64, err error)
+}
+
+// sloSample is the cumulative counts of the jobs of a service level objective at a point in time.
+type sloSample struct {
+	time          time.Time
+	within, total float64
+}
+
+// sloResult is the evaluation of a service level objective.
+type sloResult struct {
+	name string
+
+	// observed is false when no job has been counted for the objective yet, and the rest of the fields are zero.
+	observed bool
+
+	compliance           float64
+	errorBudgetRemaining float64
+	fastBurnRate         float64
+	slowBurnRate         float64
+}
+
+func (r sloResult) burning() bool {
+	return r.fastBurnRate > sloFastBurnRateThreshold || r.slowBurnRate > sloSlowBurnRateThreshold
+}
+
+func (r sloResult) exhausted() bool {
+	return r.observed && r.errorBudgetRemaining <= 0
+}
+
+// sloTracker keeps the recent cumulative counts of the service level objectives of the HRAs,
+// for the burn rates over the windows that the cumulative histograms alone can't tell.
+// The counts are lost on restart, in which case the burn rates are computed over the shorter windows until the history builds up again.
+type sloTracker struct {
+	mu      sync.Mutex
+	samples map[types.NamespacedName]map[string][]sloSample
+}
+
+// record adds the sample of the objective and returns the samples from the oldest that is still needed for the burn rates.
+// The samples of the other objectives of the HRA that aren't in keep are forgotten.
+func (t *sloTracker) record(hra types.NamespacedName, key string, s sloSample, keep map[string]bool) []sloSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.samples == nil {
+		t.samples = map[types.NamespacedName]map[string][]sloSample{}
+	}
+
+	byKey := t.samples[hra]
+	if byKey == nil {
+		byKey = map[string][]sloSample{}
+		t.samples[hra] = byKey
+	}
+
+	for k := range byKey {
+		if !keep[k] {
+			delete(byKey, k)
+		}
+	}
+
+	samples := byKey[key]
+
+	// The histograms were reset, like when the actions-metrics-server restarted
+	if n := len(samples); n > 0 && (s.total < samples[n-1].total || s.within < samples[n-1].within) {
+		samples = nil
+	}
+
+	samples = append(samples, s)
+
+	// Keep the newest sample older than the slow window as the baseline of the slow burn rate
+	for len(samples) > 1 && !samples[1].time.After(s.time.Add(-sloSlowBurnWindow)) {
+		samples = samples[1:]
+	}
+
+	byKey[key] = samples
+
+	return append([]sloSample(nil), samples...)
+}
+
+func (t *sloTracker) forget(hra types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.samples, hra)
+}
+
+// sloKey identifies the history of the counts of the objective, which starts over when the objective is changed.
+func sloKey(slo v1alpha1.ServiceLevelObjective, runsOn []string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", slo.Name, sloMetricName(slo), slo.Threshold.Duration, strings.Join(runsOn, ","))
+}
+
+func sloMetricName(slo v1alpha1.ServiceLevelObjective) string {
+	if slo.Metric == v1alpha1.ServiceLevelObjectiveMetricRunDuration {
+		return jobRunDurationMetricName
+	}
+
+	return jobQueueDurationMetricName
+}
+
+// burnRate returns how many times faster than allowed the error budget was spent over the window until the latest sample.
+// The oldest sample is the baseline when the history is shorter than the window.
+func burnRate(samples []sloSample, window time.Duration, errorBudget float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	latest := samples[len(samples)-1]
+
+	base := samples[0]
+	for _, s := range samples[1:] {
+		if s.time.After(latest.time.Add(-window)) {
+			break
+		}
+		base = s
+	}
+
+	total := latest.total - base.total
+	if total <= 0 {
+		return 0
+	}
+
+	return (1 - (latest.within-base.within)/total) / errorBudget
+}
+
+// evaluateServiceLevelObjectives evaluates the service level objectives of the HRA at now against the job duration histograms.
+// The jobs of the objectives without runsOn are the ones runnable on the runners of the scale target.
+func (r *HorizontalRunnerAutoscalerReconciler) evaluateServiceLevelObjectives(ctx context.Context, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, runnerLabels []string) ([]sloResult, error) {
+	keep := map[string]bool{}
+	for _, slo := range hra.Spec.ServiceLevelObjectives {
+		keep[sloKey(slo, sloRunsOn(slo, runnerLabels))] = true
+	}
+
+	var results []sloResult
+
+	for _, slo := range hra.Spec.ServiceLevelObjectives {
+		runsOn := sloRunsOn(slo, runnerLabels)
+
+		within, total, err := r.JobDurationHistograms.CountWithin(ctx, sloMetricName(slo), runsOn, slo.Threshold.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating service level objective %s: %w", slo.Name, err)
+		}
+
+		samples := r.slos.record(types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}, sloKey(slo, runsOn), sloSample{time: now, within: within, total: total}, keep)
+
+		result := sloResult{name: slo.Name}
+
+		if total > 0 {
+			errorBudget := 1 - float64(slo.Percentile)/100
+
+			result.observed = true
+			result.compliance = within / total
+			result.errorBudgetRemaining = 1 - (1-result.compliance)/errorBudget
+			result.fastBurnRate = burnRate(samples, sloFastBurnWindow, errorBudget)
+			result.slowBurnRate = burnRate(samples, sloSlowBurnWindow, errorBudget)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func sloRunsOn(slo v1alpha1.ServiceLevelObjective, runnerLabels []string) []string {
+	if len(slo.RunsOn) > 0 {
+		return slo.RunsOn
+	}
+
+	return runnerLabels
+}
+
+// serviceLevelObjectivesCondition evaluates the service level objectives of the HRA into the metrics,
+// and returns the status, the reason, and the message of the ServiceLevelObjectivesMet condition.
+// The objectives burning their error budgets fail the condition before the ones whose error budgets are exhausted,
+// as the former need attention sooner.
+func (r *HorizontalRunnerAutoscalerReconciler) serviceLevelObjectivesCondition(ctx context.Context, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, runnerLabels []string) (bool, string, string) {
+	metrics.DeleteHorizontalRunnerAutoscalerServiceLevelObjectives(hra.ObjectMeta)
+
+	if r.JobDurationHistograms == nil {
+		return false, v1alpha1.ConditionReasonMetricsUnavailable, "The controller is not configured with the URL of the actions-metrics-server"
+	}
+
+	results, err := r.evaluateServiceLevelObjectives(ctx, now, hra, runnerLabels)
+	if err != nil {
+		return false, v1alpha1.ConditionReasonMetricsUnavailable, err.Error()
+	}
+
+	var burning, exhausted, summaries []string
+
+	for _, res := range results {
+		if !res.observed {
+			summaries = append(summaries, fmt.Sprintf("%s: no job observed", res.name))
+			continue
+		}
+
+		metrics.SetHorizontalRunnerAutoscalerServiceLevelObjective(hra.ObjectMeta, res.name, res.compliance, res.errorBudgetRemaining, map[string]float64{
+			sloFastBurnWindowLabel: res.fastBurnRate,
+			sloSlowBurnWindowLabel: res.slowBurnRate,
+		})
+
+		summaries = append(summaries, fmt.Sprintf("%s: %.2f%% compliant, %.0f%% of the error budget remaining, burn rates %.1f over %s and %.1f over %s",
+			res.name, res.compliance*100, res.errorBudgetRemaining*100, res.fastBurnRate, sloFastBurnWindowLabel, res.slowBurnRate, sloSlowBurnWindowLabel))
+
+		if res.burning() {
+			burning = append(burning, res.name)
+		} else if res.exhausted() {
+			exhausted = append(exhausted, res.name)
+		}
+	}
+
+	message := strings.Join(summaries, "; ")
+
+	if len(burning) > 0 {
+		return false, v1alpha1.ConditionReasonErrorBudgetBurning, fmt.Sprintf("Burning the error budgets of %s. %s", strings.Join(burning, ", "), message)
+	}
+
+	if len(exhausted) > 0 {
+		return false, v1alpha1.ConditionReasonErrorBudgetExhausted, fmt.Sprintf("Exhausted the error budgets of %s. %s", strings.Join(exhausted, ", "), message)
+	}
+
+	return true, v1alpha1.ConditionReasonObjectivesMet, message
+}
@@ -0,0 +1,107 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeJobDurationHistograms struct {
+	within, total float64
+	metricName    string
+	runnerLabels  []string
+}
+
+func (h *fakeJobDurationHistograms) CountWithin(_ context.Context, metricName string, runnerLabels []string, _ time.Duration) (float64, float64, error) {
+	h.metricName = metricName
+	h.runnerLabels = runnerLabels
+
+	return h.within, h.total, nil
+}
+
+func TestServiceLevelObjectivesCondition(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}}
+	hra.Spec.ServiceLevelObjectives = []v1alpha1.ServiceLevelObjective{
+		{Name: "queue-time", Percentile: 95, Threshold: metav1.Duration{Duration: time.Minute}},
+	}
+
+	r := &HorizontalRunnerAutoscalerReconciler{}
+
+	ok, reason, _ := r.serviceLevelObjectivesCondition(context.Background(), now, hra, []string{"linux"})
+	require.False(t, ok)
+	require.Equal(t, v1alpha1.ConditionReasonMetricsUnavailable, reason)
+
+	h := &fakeJobDurationHistograms{}
+	r.JobDurationHistograms = h
+
+	unobserved := *hra.DeepCopy()
+	unobserved.Name = "unobserved"
+
+	ok, reason, message := r.serviceLevelObjectivesCondition(context.Background(), now, unobserved, []string{"linux"})
+	require.True(t, ok)
+	require.Equal(t, v1alpha1.ConditionReasonObjectivesMet, reason)
+	require.Equal(t, "queue-time: no job observed", message)
+	require.Equal(t, jobQueueDurationMetricName, h.metricName)
+	require.Equal(t, []string{"linux"}, h.runnerLabels)
+
+	// 98% of the jobs were within the threshold so far, spending 40% of the error budget
+	h.within, h.total = 980, 1000
+
+	ok, reason, _ = r.serviceLevelObjectivesCondition(context.Background(), now.Add(time.Minute), hra, []string{"linux"})
+	require.True(t, ok)
+	require.Equal(t, v1alpha1.ConditionReasonObjectivesMet, reason)
+
+	// Every job of the last 30 minutes exceeded the threshold, burning the budget 20 times faster than allowed
+	h.total = 1100
+
+	ok, reason, _ = r.serviceLevelObjectivesCondition(context.Background(), now.Add(31*time.Minute), hra, []string{"linux"})
+	require.False(t, ok)
+	require.Equal(t, v1alpha1.ConditionReasonErrorBudgetBurning, reason)
+
+	// The burst fell out of the 1h window, and the slow burn rate over 6h, (1-600/700)/0.05, is within the threshold,
+	// but the budget is overspent: 1 - (1-1580/1700)/0.05 < 0
+	h.within, h.total = 1580, 1700
+
+	results, err := r.evaluateServiceLevelObjectives(context.Background(), now.Add(5*time.Hour), hra, []string{"linux"})
+	require.NoError(t, err)
+	require.Zero(t, results[0].fastBurnRate)
+	require.InDelta(t, 100.0/700/0.05, results[0].slowBurnRate, 1e-9)
+	require.False(t, results[0].burning())
+	require.True(t, results[0].exhausted())
+
+	// The counts start over when the objective changes
+	hra.Spec.ServiceLevelObjectives[0].RunsOn = []string{"gpu"}
+	hra.Spec.ServiceLevelObjectives[0].Metric = v1alpha1.ServiceLevelObjectiveMetricRunDuration
+
+	results, err = r.evaluateServiceLevelObjectives(context.Background(), now.Add(5*time.Hour), hra, []string{"linux"})
+	require.NoError(t, err)
+	require.Zero(t, results[0].fastBurnRate)
+	require.Equal(t, jobRunDurationMetricName, h.metricName)
+	require.Equal(t, []string{"gpu"}, h.runnerLabels)
+	require.Len(t, r.slos.samples[types.NamespacedName{Namespace: "default", Name: "example"}], 1)
+}
+
+func TestBurnRate(t *testing.T) {
+	t0 := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	samples := []sloSample{
+		{time: t0, within: 0, total: 0},
+		{time: t0.Add(5 * time.Hour), within: 100, total: 100},
+		{time: t0.Add(5*time.Hour + 30*time.Minute), within: 190, total: 200},
+		{time: t0.Add(6 * time.Hour), within: 280, total: 300},
+	}
+
+	// 20 of the 200 jobs in the last hour exceeded the threshold, while the budget is 5%
+	require.InDelta(t, 2.0, burnRate(samples, time.Hour, 0.05), 1e-9)
+	// 20 of the 300 jobs in the last 6 hours
+	require.InDelta(t, 20.0/300/0.05, burnRate(samples, 6*time.Hour, 0.05), 1e-9)
+
+	require.Zero(t, burnRate(samples[:1], time.Hour, 0.05))
+}
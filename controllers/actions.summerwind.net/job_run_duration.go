@@ -0,0 +1,272 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// jobRunDurationMetricName is the name of the histogram of workflow job run times exported by the actions-metrics-server.
+	jobRunDurationMetricName = "github_workflow_job_run_duration_seconds"
+
+	// jobQueueDurationMetricName is the name of the histogram of workflow job queue times exported by the actions-metrics-server.
+	jobQueueDurationMetricName = "github_workflow_job_queue_duration_seconds"
+
+	// DefaultJobRunDurationCacheDuration is how long the scraped job run time histograms are reused
+	// before the actions-metrics-server is scraped again.
+	DefaultJobRunDurationCacheDuration = time.Minute
+)
+
+// JobRunDurations provides the percentiles of the workflow job run times for the jobs that are
+// runnable on the runners with the given labels.
+type JobRunDurations interface {
+	// Percentile returns the p-th percentile, between 0 and 1, of the job run times.
+	// The second return value is false when no job run time has been observed yet.
+	Percentile(ctx context.Context, runnerLabels []string, p float64) (time.Duration, bool, error)
+}
+
+// ActionsMetricsJobRunDurations is the JobRunDurations backed by the run time histograms
+// scraped from the metrics endpoint of the actions-metrics-server.
+type ActionsMetricsJobRunDurations struct {
+	// URL is the URL of the actions-metrics-server's metrics endpoint, like http://actions-metrics-server:8080/metrics.
+	URL string
+
+	HTTPClient    *http.Client
+	CacheDuration time.Duration
+
+	mu        sync.Mutex
+	families  map[string]*dto.MetricFamily
+	fetchedAt time.Time
+}
+
+var (
+	_ JobRunDurations       = &ActionsMetricsJobRunDurations{}
+	_ JobDurationHistograms = &ActionsMetricsJobRunDurations{}
+)
+
+func (d *ActionsMetricsJobRunDurations) Percentile(ctx context.Context, runnerLabels []string, p float64) (time.Duration, bool, error) {
+	buckets, total, err := d.histogram(ctx, jobRunDurationMetricName, runnerLabels)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if total == 0 {
+		return 0, false, nil
+	}
+
+	seconds := histogramQuantile(p, buckets, total)
+
+	return time.Duration(seconds * float64(time.Second)), true, nil
+}
+
+func (d *ActionsMetricsJobRunDurations) CountWithin(ctx context.Context, metricName string, runnerLabels []string, threshold time.Duration) (float64, float64, error) {
+	buckets, total, err := d.histogram(ctx, metricName, runnerLabels)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if total == 0 {
+		return 0, 0, nil
+	}
+
+	return histogramCountWithin(threshold.Seconds(), buckets), float64(total), nil
+}
+
+// histogram returns the cumulative bucket counts and the sample count of the histograms of the metric
+// for all the jobs that can run on the runners.
+func (d *ActionsMetricsJobRunDurations) histogram(ctx context.Context, metricName string, runnerLabels []string) (map[float64]uint64, uint64, error) {
+	families, err := d.metricFamilies(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mf, ok := families[metricName]
+	if !ok {
+		return nil, 0, nil
+	}
+
+	// Merge the histograms of all the jobs that can run on the runners.
+	// Every histogram has the same buckets as they come from the same metric.
+	buckets := map[float64]uint64{}
+
+	var total uint64
+
+	for _, m := range mf.GetMetric() {
+		h := m.GetHistogram()
+		if h == nil {
+			continue
+		}
+
+		var runsOn string
+
+		for _, lp := range m.GetLabel() {
+			if lp.GetName() == "runs_on" {
+				runsOn = lp.GetValue()
+				break
+			}
+		}
+
+		if !jobRunnableOn(strings.Split(runsOn, ","), runnerLabels) {
+			continue
+		}
+
+		for _, b := range h.GetBucket() {
+			buckets[b.GetUpperBound()] += b.GetCumulativeCount()
+		}
+
+		total += h.GetSampleCount()
+	}
+
+	return buckets, total, nil
+}
+
+func (d *ActionsMetricsJobRunDurations) metricFamilies(ctx context.Context) (map[string]*dto.MetricFamily, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cacheDuration := d.CacheDuration
+	if cacheDuration == 0 {
+		cacheDuration = DefaultJobRunDurationCacheDuration
+	}
+
+	if d.families != nil && time.Since(d.fetchedAt) < cacheDuration {
+		return d.families, nil
+	}
+
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scraping job run durations from %s: %w", d.URL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scraping job run durations from %s: unexpected status %s", d.URL, res.Status)
+	}
+
+	var parser expfmt.TextParser
+
+	families, err := parser.TextToMetricFamilies(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing job run durations from %s: %w", d.URL, err)
+	}
+
+	d.families = families
+	d.fetchedAt = time.Now()
+
+	return families, nil
+}
+
+// jobRunnableOn returns true when all the runs-on labels of a job are found in the runner labels,
+// ignoring the "self-hosted" label and the case.
+// This is the same as how the webhook-based autoscaler finds the scale target for a workflow_job event.
+func jobRunnableOn(runsOn, runnerLabels []string) bool {
+	for _, l := range runsOn {
+		if l == "" || l == "self-hosted" {
+			continue
+		}
+
+		var matched bool
+
+		for _, l2 := range runnerLabels {
+			if strings.EqualFold(l, l2) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// histogramQuantile estimates the q-th quantile from the cumulative bucket counts,
+// by linearly interpolating within the bucket that the quantile falls into, like PromQL's histogram_quantile does.
+func histogramQuantile(q float64, buckets map[float64]uint64, total uint64) float64 {
+	bounds := make([]float64, 0, len(buckets))
+	for ub := range buckets {
+		bounds = append(bounds, ub)
+	}
+	sort.Float64s(bounds)
+
+	rank := q * float64(total)
+
+	var (
+		prevBound float64
+		prevCount uint64
+	)
+
+	for _, ub := range bounds {
+		count := buckets[ub]
+
+		if float64(count) >= rank {
+			if math.IsInf(ub, 1) {
+				return prevBound
+			}
+
+			if count == prevCount {
+				return ub
+			}
+
+			return prevBound + (ub-prevBound)*(rank-float64(prevCount))/float64(count-prevCount)
+		}
+
+		prevBound, prevCount = ub, count
+	}
+
+	// The quantile falls into the implicit +Inf bucket
+	return prevBound
+}
+
+// histogramCountWithin estimates the number of the observations within the value from the cumulative bucket counts,
+// by linearly interpolating within the bucket that the value falls into.
+func histogramCountWithin(v float64, buckets map[float64]uint64) float64 {
+	bounds := make([]float64, 0, len(buckets))
+	for ub := range buckets {
+		bounds = append(bounds, ub)
+	}
+	sort.Float64s(bounds)
+
+	var (
+		prevBound float64
+		prevCount uint64
+	)
+
+	for _, ub := range bounds {
+		count := buckets[ub]
+
+		if v <= ub {
+			if math.IsInf(ub, 1) {
+				return float64(prevCount)
+			}
+
+			return float64(prevCount) + float64(count-prevCount)*(v-prevBound)/(ub-prevBound)
+		}
+
+		prevBound, prevCount = ub, count
+	}
+
+	// The value is beyond the largest bucket, so only the observations in the implicit +Inf bucket may not be within it
+	return float64(prevCount)
+}
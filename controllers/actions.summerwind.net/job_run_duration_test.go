@@ -0,0 +1,92 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testJobRunDurationMetrics = `# HELP github_workflow_job_run_duration_seconds Run times for workflow jobs in seconds
+# TYPE github_workflow_job_run_duration_seconds histogram
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,linux",le="60"} 2
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,linux",le="300"} 8
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,linux",le="600"} 10
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,linux",le="+Inf"} 10
+github_workflow_job_run_duration_seconds_sum{job_conclusion="success",runs_on="self-hosted,linux"} 2000
+github_workflow_job_run_duration_seconds_count{job_conclusion="success",runs_on="self-hosted,linux"} 10
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,gpu",le="60"} 0
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,gpu",le="300"} 0
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,gpu",le="600"} 0
+github_workflow_job_run_duration_seconds_bucket{job_conclusion="success",runs_on="self-hosted,gpu",le="+Inf"} 5
+github_workflow_job_run_duration_seconds_sum{job_conclusion="success",runs_on="self-hosted,gpu"} 10000
+github_workflow_job_run_duration_seconds_count{job_conclusion="success",runs_on="self-hosted,gpu"} 5
+`
+
+func TestActionsMetricsJobRunDurations(t *testing.T) {
+	var scrapes int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scrapes++
+		_, _ = w.Write([]byte(testJobRunDurationMetrics))
+	}))
+	t.Cleanup(srv.Close)
+
+	d := &ActionsMetricsJobRunDurations{URL: srv.URL}
+
+	// 90th percentile of 10 jobs falls into the 300-600 bucket: 300 + 300 * (9-8)/(10-8)
+	got, ok, err := d.Percentile(context.Background(), []string{"Linux"}, 0.9)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 450*time.Second, got)
+
+	// The long-running gpu jobs are merged for the runners with linux and gpu labels,
+	// so the 90th percentile falls into the +Inf bucket
+	got, ok, err = d.Percentile(context.Background(), []string{"linux", "gpu"}, 0.9)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 600*time.Second, got)
+
+	_, ok, err = d.Percentile(context.Background(), []string{"windows"}, 0.9)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// 2 jobs within 60s, and 6 of 8 jobs between 60s and 300s are within 240s: 2 + 6 * (240-60)/(300-60) = 6.5
+	within, total, err := d.CountWithin(context.Background(), jobRunDurationMetricName, []string{"linux"}, 240*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 6.5, within)
+	require.Equal(t, 10.0, total)
+
+	within, total, err = d.CountWithin(context.Background(), jobQueueDurationMetricName, []string{"linux"}, 240*time.Second)
+	require.NoError(t, err)
+	require.Zero(t, within)
+	require.Zero(t, total)
+
+	require.Equal(t, 1, scrapes, "the scraped metrics should be cached")
+}
+
+func TestHistogramCountWithin(t *testing.T) {
+	buckets := map[float64]uint64{10: 5, 20: 10, 30: 10, math.Inf(1): 12}
+
+	require.Equal(t, 2.5, histogramCountWithin(5, buckets))
+	require.Equal(t, 7.5, histogramCountWithin(15, buckets))
+	require.Equal(t, 10.0, histogramCountWithin(30, buckets))
+
+	// The observations in the +Inf bucket are never within the value
+	require.Equal(t, 10.0, histogramCountWithin(100, buckets))
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := map[float64]uint64{10: 5, 20: 10, 30: 10}
+
+	require.Equal(t, 5.0, histogramQuantile(0.25, buckets, 10))
+	require.Equal(t, 10.0, histogramQuantile(0.5, buckets, 10))
+	require.Equal(t, 18.0, histogramQuantile(0.9, buckets, 10))
+
+	// Falls into the implicit +Inf bucket
+	require.Equal(t, 30.0, histogramQuantile(0.99, buckets, 12))
+}
@@ -0,0 +1,507 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+)
+
+const (
+	// DefaultMaintenanceStatusContext is the context of the commit status notifying the maintenance, unless spec.notification.githubStatus.context is set.
+	DefaultMaintenanceStatusContext = "actions-runner-controller/maintenance"
+
+	// maintenanceNotificationRetryDelay is the delay before retrying the failed notification.
+	maintenanceNotificationRetryDelay = time.Minute
+
+	slackWebhookURLSecretKey = "slack_webhook_url"
+)
+
+// MaintenanceWindowReconciler reconciles a MaintenanceWindow object.
+// It tracks the periods of the maintenance in the status, which the HorizontalRunnerAutoscalers follow to hold the scale up,
+// and notifies the start and the end of the maintenance.
+type MaintenanceWindowReconciler struct {
+	client.Client
+	GitHubClient *MultiGitHubClient
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+	Scheme       *runtime.Scheme
+	Name         string
+
+	// HTTPClient posts the Slack notifications. Defaults to a client with a timeout of 10 seconds.
+	HTTPClient *http.Client
+}
+
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=maintenancewindows,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=maintenancewindows/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *MaintenanceWindowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("maintenancewindow", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "MaintenanceWindow", req.NamespacedName)
+
+	var mw v1alpha1.MaintenanceWindow
+	if err := r.Get(ctx, req.NamespacedName, &mw); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !mw.ObjectMeta.DeletionTimestamp.IsZero() {
+		r.GitHubClient.DeinitForMaintenanceWindow(&mw)
+
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+
+	current, next, err := maintenancePeriods(now, mw.Spec.Schedules)
+	if err != nil {
+		log.Error(err, "Invalid maintenance schedule")
+		r.Recorder.Event(&mw, corev1.EventTypeWarning, v1alpha1.ConditionReasonInvalidSchedule, err.Error())
+
+		if err := patchConditions(ctx, r.Client, &mw, func(obj *v1alpha1.MaintenanceWindow) *[]metav1.Condition { return &obj.Status.Conditions }, func(conditions *[]metav1.Condition) {
+			setCondition(conditions, mw.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonInvalidSchedule, err.Error())
+			setCondition(conditions, mw.Generation, v1alpha1.ConditionTypeSynced, false, v1alpha1.ConditionReasonInvalidSchedule, err.Error())
+		}); err != nil {
+			log.Error(err, "Failed to update maintenancewindow status conditions")
+		}
+
+		// Fixing the spec triggers the next reconciliation
+		return ctrl.Result{}, nil
+	}
+
+	active := current != nil
+
+	updated := mw.DeepCopy()
+	updated.Status.Active = active
+	updated.Status.CurrentPeriod = maintenancePeriod(current)
+	updated.Status.NextPeriod = maintenancePeriod(next)
+	updated.Status.ObservedGeneration = mw.Generation
+	updated.Status.Targets = nil
+
+	if active {
+		targets, err := r.maintenanceTargets(ctx, mw)
+		if err != nil {
+			if err := patchConditions(ctx, r.Client, &mw, func(obj *v1alpha1.MaintenanceWindow) *[]metav1.Condition { return &obj.Status.Conditions }, func(conditions *[]metav1.Condition) {
+				setCondition(conditions, mw.Generation, v1alpha1.ConditionTypeSynced, false, v1alpha1.ConditionReasonReconcileFailed, err.Error())
+			}); err != nil {
+				log.Error(err, "Failed to update maintenancewindow status conditions")
+			}
+
+			return ctrl.Result{}, err
+		}
+
+		updated.Status.Targets = targets
+	}
+
+	if active != mw.Status.Active {
+		if active {
+			log.Info("Maintenance started", "endTime", current.EndTime, "targets", len(updated.Status.Targets))
+			r.Recorder.Event(&mw, corev1.EventTypeNormal, "MaintenanceStarted", fmt.Sprintf("Started the maintenance of %d HorizontalRunnerAutoscalers until %s", len(updated.Status.Targets), current.EndTime.Format(time.RFC3339)))
+		} else {
+			log.Info("Maintenance ended")
+			r.Recorder.Event(&mw, corev1.EventTypeNormal, "MaintenanceEnded", "Ended the maintenance")
+		}
+	}
+
+	var result ctrl.Result
+
+	// The status follows the periods without waiting for the next sync
+	if boundary := nextMaintenanceBoundary(current, next); boundary != nil {
+		result.RequeueAfter = boundary.Sub(now)
+	}
+
+	state := v1alpha1.MaintenanceStateEnded
+	if active {
+		state = v1alpha1.MaintenanceStateStarted
+	}
+
+	var notifyErr error
+
+	// The end is notified only for the start notified before, so that a new window doesn't notify the end of a maintenance that never started
+	if state != mw.Status.NotifiedState && (active || mw.Status.NotifiedState == v1alpha1.MaintenanceStateStarted) {
+		if notifyErr = r.notify(ctx, mw, current); notifyErr != nil {
+			log.Error(notifyErr, "Failed to notify the maintenance", "state", state)
+			r.Recorder.Event(&mw, corev1.EventTypeWarning, v1alpha1.ConditionReasonNotificationFailed, notifyErr.Error())
+
+			if result.RequeueAfter == 0 || result.RequeueAfter > maintenanceNotificationRetryDelay {
+				result.RequeueAfter = maintenanceNotificationRetryDelay
+			}
+		} else {
+			updated.Status.NotifiedState = state
+		}
+	}
+
+	setCondition(&updated.Status.Conditions, mw.Generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonReconciled, "")
+	if notifyErr != nil {
+		setCondition(&updated.Status.Conditions, mw.Generation, v1alpha1.ConditionTypeSynced, false, v1alpha1.ConditionReasonNotificationFailed, notifyErr.Error())
+	} else {
+		setCondition(&updated.Status.Conditions, mw.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+	}
+
+	if !reflect.DeepEqual(mw.Status, updated.Status) {
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&mw)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("patching maintenancewindow status: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// maintenancePeriods returns the ongoing and the next periods of the maintenance, either of which is nil if there's none.
+// The ongoing period of the schedules ending last wins when the periods of two or more schedules overlap.
+func maintenancePeriods(now time.Time, schedules []v1alpha1.MaintenanceSchedule) (*Period, *Period, error) {
+	var current, next *Period
+
+	for i, s := range schedules {
+		a, u, err := matchRecurrence(now, s.StartTime.Time, s.EndTime.Time, s.TimeZone, s.RecurrenceRule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("schedule %d: %w", i, err)
+		}
+
+		if a != nil && (current == nil || a.EndTime.After(current.EndTime)) {
+			current = a
+		}
+
+		if u != nil && (next == nil || u.StartTime.Before(next.StartTime)) {
+			next = u
+		}
+	}
+
+	return current, next, nil
+}
+
+func maintenancePeriod(p *Period) *v1alpha1.MaintenancePeriod {
+	if p == nil {
+		return nil
+	}
+
+	return &v1alpha1.MaintenancePeriod{
+		StartTime: metav1.Time{Time: p.StartTime},
+		EndTime:   metav1.Time{Time: p.EndTime},
+	}
+}
+
+// nextMaintenanceBoundary returns the time the maintenance starts or ends next.
+func nextMaintenanceBoundary(current, next *Period) *time.Time {
+	var t *time.Time
+
+	if current != nil {
+		t = &current.EndTime
+	}
+
+	if next != nil && (t == nil || next.StartTime.Before(*t)) {
+		t = &next.StartTime
+	}
+
+	return t
+}
+
+// maintenanceWindowSelects tells if the maintenance window selects the RunnerDeployment or the RunnerSet with the labels.
+func maintenanceWindowSelects(mw v1alpha1.MaintenanceWindow, objectLabels map[string]string) (bool, error) {
+	if mw.Spec.Selector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(mw.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid selector of maintenancewindow %s: %w", mw.Name, err)
+	}
+
+	return selector.Matches(labels.Set(objectLabels)), nil
+}
+
+// maintenanceTargets returns the HorizontalRunnerAutoscalers in the namespace of the maintenance window whose scale targets it selects.
+// The replicas of the targets already held by the ongoing maintenance are kept as they were when it started.
+func (r *MaintenanceWindowReconciler) maintenanceTargets(ctx context.Context, mw v1alpha1.MaintenanceWindow) ([]v1alpha1.MaintenanceTarget, error) {
+	var hras v1alpha1.HorizontalRunnerAutoscalerList
+	if err := r.List(ctx, &hras, client.InNamespace(mw.Namespace)); err != nil {
+		return nil, err
+	}
+
+	held := map[string]v1alpha1.MaintenanceTarget{}
+	for _, t := range mw.Status.Targets {
+		held[t.HorizontalRunnerAutoscaler] = t
+	}
+
+	var targets []v1alpha1.MaintenanceTarget
+
+	for _, hra := range hras.Items {
+		kind := hra.Spec.ScaleTargetRef.Kind
+		if kind == "" {
+			kind = "RunnerDeployment"
+		}
+
+		var obj client.Object
+		switch kind {
+		case "RunnerDeployment":
+			obj = &v1alpha1.RunnerDeployment{}
+		case "RunnerSet":
+			obj = &v1alpha1.RunnerSet{}
+		default:
+			continue
+		}
+
+		if err := r.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}, obj); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		selected, err := maintenanceWindowSelects(mw, obj.GetLabels())
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		t, ok := held[hra.Name]
+		if !ok {
+			t = v1alpha1.MaintenanceTarget{
+				HorizontalRunnerAutoscaler: hra.Name,
+				ScaleTargetKind:            kind,
+				ScaleTargetName:            hra.Spec.ScaleTargetRef.Name,
+				MinReplicas:                copyIntPtr(hra.Spec.MinReplicas),
+				DesiredReplicas:            copyIntPtr(hra.Status.DesiredReplicas),
+			}
+		}
+
+		targets = append(targets, t)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].HorizontalRunnerAutoscaler < targets[j].HorizontalRunnerAutoscaler
+	})
+
+	return targets, nil
+}
+
+func copyIntPtr(v *int) *int {
+	if v == nil {
+		return nil
+	}
+
+	c := *v
+
+	return &c
+}
+
+// notify posts the start of the maintenance of the period, or the end of the maintenance when the period is nil,
+// to all the channels of spec.notification.
+func (r *MaintenanceWindowReconciler) notify(ctx context.Context, mw v1alpha1.MaintenanceWindow, period *Period) error {
+	n := mw.Spec.Notification
+	if n == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if n.Slack != nil {
+		if err := r.notifySlack(ctx, mw, maintenanceMessage(mw, period)); err != nil {
+			errs = append(errs, fmt.Errorf("notifying slack: %w", err))
+		}
+	}
+
+	if s := n.GitHubStatus; s != nil {
+		if err := r.notifyGitHubStatus(ctx, mw, *s, period); err != nil {
+			errs = append(errs, fmt.Errorf("notifying github status: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func maintenanceMessage(mw v1alpha1.MaintenanceWindow, period *Period) string {
+	if period == nil {
+		return fmt.Sprintf("The maintenance %s/%s of the self-hosted runners ended. The runners scale as usual.", mw.Namespace, mw.Name)
+	}
+
+	if mw.Spec.DrainIdleRunners {
+		return fmt.Sprintf("The maintenance %s/%s of the self-hosted runners started. The idle runners are drained until %s.", mw.Namespace, mw.Name, period.EndTime.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("The maintenance %s/%s of the self-hosted runners started. The runners don't scale up until %s.", mw.Namespace, mw.Name, period.EndTime.Format(time.RFC3339))
+}
+
+func (r *MaintenanceWindowReconciler) notifySlack(ctx context.Context, mw v1alpha1.MaintenanceWindow, text string) error {
+	ref := mw.Spec.Notification.Slack.WebhookURLSecretRef
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: mw.Namespace, Name: ref.Name}, &secret); err != nil {
+		return fmt.Errorf("getting secret %s: %w", ref.Name, err)
+	}
+
+	url, ok := secret.Data[slackWebhookURLSecretKey]
+	if !ok {
+		return fmt.Errorf("secret %s has no %s key", ref.Name, slackWebhookURLSecretKey)
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, string(bytes.TrimSpace(url)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// notifyGitHubStatus sets the commit status, which is pending during the maintenance and successful after it.
+func (r *MaintenanceWindowReconciler) notifyGitHubStatus(ctx context.Context, mw v1alpha1.MaintenanceWindow, s v1alpha1.GitHubStatusNotification, period *Period) error {
+	ghc, err := r.GitHubClient.InitForMaintenanceWindow(ctx, &mw, s.GitHubAPICredentialsFrom)
+	if err != nil {
+		return err
+	}
+
+	statusContext := s.Context
+	if statusContext == "" {
+		statusContext = DefaultMaintenanceStatusContext
+	}
+
+	state, description := "success", "No maintenance of the self-hosted runners"
+	if period != nil {
+		state, description = "pending", fmt.Sprintf("Maintenance of the self-hosted runners until %s", period.EndTime.UTC().Format(time.RFC3339))
+	}
+
+	return ghc.CreateDefaultBranchStatus(ctx, s.Repository, state, statusContext, description)
+}
+
+// maintenanceWindowsForHRA returns the active maintenance windows selecting the scale target with the labels,
+// the ones draining the idle runners first, so that the first one tells how the scale target is held.
+func maintenanceWindowsForHRA(ctx context.Context, c client.Reader, namespace string, objectLabels map[string]string) ([]v1alpha1.MaintenanceWindow, error) {
+	var mws v1alpha1.MaintenanceWindowList
+	if err := c.List(ctx, &mws, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var active []v1alpha1.MaintenanceWindow
+
+	for _, mw := range mws.Items {
+		if !mw.Status.Active || !mw.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		selected, err := maintenanceWindowSelects(mw, objectLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		if selected {
+			active = append(active, mw)
+		}
+	}
+
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Spec.DrainIdleRunners != active[j].Spec.DrainIdleRunners {
+			return active[i].Spec.DrainIdleRunners
+		}
+		return active[i].Name < active[j].Name
+	})
+
+	return active, nil
+}
+
+// maintenanceReplicas returns the desired replicas of the scale target held by the maintenance window,
+// which drops to zero to drain the idle runners, or otherwise never exceeds the current replicas.
+// The busy runners are kept until they complete their jobs, as the scale targets remove only the idle runners on scale down.
+func maintenanceReplicas(mw v1alpha1.MaintenanceWindow, current, desired int) int {
+	if mw.Spec.DrainIdleRunners {
+		return 0
+	}
+
+	if desired > current {
+		return current
+	}
+
+	return desired
+}
+
+func (r *MaintenanceWindowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "maintenancewindow-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.MaintenanceWindow{}).
+		Named(name).
+		Complete(reconcilemetrics.Observe(name, "MaintenanceWindow", r))
+}
+
+// hrasForMaintenanceWindow enqueues the HorizontalRunnerAutoscalers in the namespace of the maintenance window,
+// so that they hold the scale up and resume as soon as the maintenance starts and ends.
+func (r *HorizontalRunnerAutoscalerReconciler) hrasForMaintenanceWindow(ctx context.Context, obj client.Object) []reconcile.Request {
+	var hras v1alpha1.HorizontalRunnerAutoscalerList
+	if err := r.List(ctx, &hras, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list horizontal runner autoscalers for the maintenance window change")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, hra := range hras.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}})
+	}
+
+	return reqs
+}
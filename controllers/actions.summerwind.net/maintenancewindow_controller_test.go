@@ -0,0 +1,248 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMaintenancePeriods(t *testing.T) {
+	parse := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return v
+	}
+
+	schedules := []v1alpha1.MaintenanceSchedule{
+		{
+			// Every Saturday night
+			StartTime:      metav1.Time{Time: parse("2026-01-03T22:00:00Z")},
+			EndTime:        metav1.Time{Time: parse("2026-01-04T02:00:00Z")},
+			RecurrenceRule: v1alpha1.RecurrenceRule{Frequency: "Weekly"},
+		},
+		{
+			// A one-off maintenance overlapping the weekly one
+			StartTime: metav1.Time{Time: parse("2026-01-10T20:00:00Z")},
+			EndTime:   metav1.Time{Time: parse("2026-01-11T04:00:00Z")},
+		},
+	}
+
+	current, next, err := maintenancePeriods(parse("2026-01-05T12:00:00Z"), schedules)
+	require.NoError(t, err)
+	require.Nil(t, current)
+	require.Equal(t, &Period{StartTime: parse("2026-01-10T20:00:00Z"), EndTime: parse("2026-01-11T04:00:00Z")}, next)
+
+	// The overlapping period ending last wins
+	current, next, err = maintenancePeriods(parse("2026-01-10T23:00:00Z"), schedules)
+	require.NoError(t, err)
+	require.Equal(t, parse("2026-01-11T04:00:00Z"), current.EndTime)
+	require.Equal(t, parse("2026-01-17T22:00:00Z"), next.StartTime)
+	require.Equal(t, parse("2026-01-11T04:00:00Z"), *nextMaintenanceBoundary(current, next))
+
+	_, _, err = maintenancePeriods(parse("2026-01-05T12:00:00Z"), []v1alpha1.MaintenanceSchedule{{
+		StartTime: metav1.Time{Time: parse("2026-01-03T22:00:00Z")},
+		EndTime:   metav1.Time{Time: parse("2026-01-04T02:00:00Z")},
+		TimeZone:  "Mars/Olympus_Mons",
+	}})
+	require.Error(t, err)
+}
+
+func TestMaintenanceReplicas(t *testing.T) {
+	hold := v1alpha1.MaintenanceWindow{}
+	drain := v1alpha1.MaintenanceWindow{Spec: v1alpha1.MaintenanceWindowSpec{DrainIdleRunners: true}}
+
+	// The scale up is refused
+	require.Equal(t, 3, maintenanceReplicas(hold, 3, 5))
+	// The scale down still happens
+	require.Equal(t, 1, maintenanceReplicas(hold, 3, 1))
+	require.Equal(t, 0, maintenanceReplicas(drain, 3, 5))
+}
+
+func TestMaintenanceWindowsForHRA(t *testing.T) {
+	ctx := context.Background()
+
+	window := func(name string, active, drain bool, selector map[string]string) *v1alpha1.MaintenanceWindow {
+		mw := &v1alpha1.MaintenanceWindow{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1alpha1.MaintenanceWindowSpec{DrainIdleRunners: drain},
+			Status:     v1alpha1.MaintenanceWindowStatus{Active: active},
+		}
+		if selector != nil {
+			mw.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		return mw
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		window("a-all", true, false, nil),
+		window("b-gpu", true, true, map[string]string{"pool": "gpu"}),
+		window("c-inactive", false, true, nil),
+		window("d-linux", true, false, map[string]string{"pool": "linux"}),
+	).Build()
+
+	mws, err := maintenanceWindowsForHRA(ctx, c, "default", map[string]string{"pool": "gpu"})
+	require.NoError(t, err)
+	require.Len(t, mws, 2)
+	// The draining window comes first
+	require.Equal(t, "b-gpu", mws[0].Name)
+	require.Equal(t, "a-all", mws[1].Name)
+
+	mws, err = maintenanceWindowsForHRA(ctx, c, "other", map[string]string{"pool": "gpu"})
+	require.NoError(t, err)
+	require.Empty(t, mws)
+}
+
+func TestMaintenanceWindowReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu       sync.Mutex
+		messages []string
+		fail     bool
+	)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+		messages = append(messages, body.Text)
+	}))
+	defer slack.Close()
+
+	now := time.Now()
+
+	mw := &v1alpha1.MaintenanceWindow{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "upgrade", Generation: 1},
+		Spec: v1alpha1.MaintenanceWindowSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+			Schedules: []v1alpha1.MaintenanceSchedule{{
+				StartTime: metav1.Time{Time: now.Add(-time.Hour)},
+				EndTime:   metav1.Time{Time: now.Add(time.Hour)},
+			}},
+			DrainIdleRunners: true,
+			Notification: &v1alpha1.MaintenanceWindowNotification{
+				Slack: &v1alpha1.SlackNotification{WebhookURLSecretRef: v1alpha1.SecretReference{Name: "slack"}},
+			},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithStatusSubresource(&v1alpha1.MaintenanceWindow{}).
+		WithObjects(
+			mw,
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "slack"},
+				Data:       map[string][]byte{"slack_webhook_url": []byte(slack.URL + "\n")},
+			},
+			&v1alpha1.RunnerDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gpu", Labels: map[string]string{"pool": "gpu"}}},
+			&v1alpha1.RunnerDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "linux", Labels: map[string]string{"pool": "linux"}}},
+			&v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gpu"},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "gpu"},
+					MinReplicas:    intPtr(2),
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{DesiredReplicas: intPtr(4)},
+			},
+			&v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "linux"},
+				Spec:       v1alpha1.HorizontalRunnerAutoscalerSpec{ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "linux"}},
+			},
+		).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	r := &MaintenanceWindowReconciler{
+		Client:   c,
+		Log:      logr.Discard(),
+		Recorder: recorder,
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "upgrade"}
+
+	reconcile := func() (ctrl.Result, v1alpha1.MaintenanceWindow) {
+		t.Helper()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		require.NoError(t, err)
+
+		var got v1alpha1.MaintenanceWindow
+		require.NoError(t, c.Get(ctx, key, &got))
+		return res, got
+	}
+
+	res, got := reconcile()
+	require.True(t, got.Status.Active)
+	require.Equal(t, []v1alpha1.MaintenanceTarget{{
+		HorizontalRunnerAutoscaler: "gpu",
+		ScaleTargetKind:            "RunnerDeployment",
+		ScaleTargetName:            "gpu",
+		MinReplicas:                intPtr(2),
+		DesiredReplicas:            intPtr(4),
+	}}, got.Status.Targets)
+	require.Equal(t, v1alpha1.MaintenanceStateStarted, got.Status.NotifiedState)
+	require.True(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeSynced))
+	// Requeued at the end of the maintenance
+	require.InDelta(t, time.Hour, res.RequeueAfter, float64(time.Minute))
+
+	require.Len(t, messages, 1)
+	require.Contains(t, messages[0], "The idle runners are drained until")
+
+	// The start is notified only once
+	reconcile()
+	require.Len(t, messages, 1)
+
+	// The maintenance ends while the notification fails
+	got.Spec.Schedules[0].EndTime = metav1.Time{Time: now.Add(-time.Minute)}
+	require.NoError(t, c.Update(ctx, &got))
+
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+
+	res, got = reconcile()
+	require.False(t, got.Status.Active)
+	require.Empty(t, got.Status.Targets)
+	require.Equal(t, v1alpha1.MaintenanceStateStarted, got.Status.NotifiedState)
+	require.Equal(t, v1alpha1.ConditionReasonNotificationFailed, meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeSynced).Reason)
+	require.Equal(t, maintenanceNotificationRetryDelay, res.RequeueAfter)
+
+	// The end is notified on retry
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	_, got = reconcile()
+	require.Equal(t, v1alpha1.MaintenanceStateEnded, got.Status.NotifiedState)
+	require.True(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeSynced))
+	require.Len(t, messages, 2)
+	require.Contains(t, messages[1], "ended")
+}
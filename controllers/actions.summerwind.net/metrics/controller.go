@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	controllerMetrics = []prometheus.Collector{
+		runnerRegistrationDuration,
+	}
+)
+
+var (
+	runnerRegistrationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "runner_registration_duration_seconds",
+			Help:    "Time from the creation of the Runner, or the runner pod of a RunnerSet, until the runner is seen registered to GitHub",
+			Buckets: []float64{5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 300, 600, 1200},
+		},
+		[]string{runnerNamespace, runnerDeploymentName, runnerSetName},
+	)
+)
+
+// ObserveRunnerRegistrationDuration observes the time from the creation of the Runner of the RunnerDeployment,
+// or the runner pod of the RunnerSet, until the runner is registered. Either runnerDeployment or runnerSet can be empty.
+func ObserveRunnerRegistrationDuration(namespace, runnerDeployment, runnerSet string, created time.Time) {
+	runnerRegistrationDuration.With(prometheus.Labels{
+		runnerNamespace:      namespace,
+		runnerDeploymentName: runnerDeployment,
+		runnerSetName:        runnerSet,
+	}).Observe(time.Since(created).Seconds())
+}
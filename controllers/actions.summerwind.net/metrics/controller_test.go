@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveRunnerRegistrationDuration(t *testing.T) {
+	ObserveRunnerRegistrationDuration("registration", "example-runnerdeploy", "", time.Now().Add(-time.Minute))
+
+	o := runnerRegistrationDuration.With(prometheus.Labels{
+		runnerNamespace:      "registration",
+		runnerDeploymentName: "example-runnerdeploy",
+		runnerSetName:        "",
+	})
+
+	var m dto.Metric
+	require.NoError(t, o.(prometheus.Metric).Write(&m))
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	require.InDelta(t, 60, m.GetHistogram().GetSampleSum(), 5)
+}
@@ -0,0 +1,464 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DashboardsPath is the path the dashboards and the alert rules are served under on the metrics endpoint.
+	DashboardsPath = "/dashboards/"
+
+	// PrometheusRuleFile is the name the PrometheusRule of the alerts is served as under DashboardsPath.
+	PrometheusRuleFile = "prometheusrule.yaml"
+
+	// LabelKeyGrafanaDashboard is the label the dashboard sidecar of the Grafana chart discovers the ConfigMaps of the dashboards by.
+	LabelKeyGrafanaDashboard = "grafana_dashboard"
+
+	dashboardTag = "actions-runner-controller"
+)
+
+type dashboardTarget struct {
+	Expr   string
+	Legend string
+}
+
+type dashboardPanel struct {
+	Title   string
+	Unit    string
+	Targets []dashboardTarget
+}
+
+type dashboard struct {
+	UID    string
+	Title  string
+	Panels []dashboardPanel
+
+	// namespaceMetric is the metric the values of the namespace variable of the dashboard are read from.
+	namespaceMetric string
+}
+
+type alertRule struct {
+	Alert    string
+	Expr     string
+	For      string
+	Severity string
+	Summary  string
+}
+
+// The queries only use the metrics and the labels exported by this package, which the tests verify,
+// so that the dashboards and the alerts keep working as the metrics change.
+var dashboards = []dashboard{
+	{
+		UID:             "arc-autoscaling",
+		Title:           "Actions Runner Controller / Autoscaling",
+		namespaceMetric: "horizontalrunnerautoscaler_spec_max_replicas",
+		Panels: []dashboardPanel{
+			{
+				Title: "Desired replicas",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_status_desired_replicas{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} desired"},
+					{Expr: `horizontalrunnerautoscaler_spec_max_replicas{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} max"},
+					{Expr: `horizontalrunnerautoscaler_spec_min_replicas{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} min"},
+				},
+			},
+			{
+				Title: "Replicas of RunnerDeployments",
+				Targets: []dashboardTarget{
+					{Expr: `runnerdeployment_spec_replicas{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{runnerdeployment}}"},
+					{Expr: `runnerdeployment_replicas_withheld_by_concurrency_gate{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{runnerdeployment}} withheld by concurrency gate"},
+					{Expr: `runnerdeployment_replicas_withheld_by_scheduling_budget{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{runnerdeployment}} withheld by scheduling budget"},
+				},
+			},
+			{
+				Title: "Busy runners (PercentageRunnersBusy)",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_runners_busy{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} busy"},
+					{Expr: `horizontalrunnerautoscaler_runners_registered{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} registered"},
+				},
+			},
+			{
+				Title: "Workflow runs (TotalNumberOfQueuedAndInProgressWorkflowRuns)",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (namespace, horizontalrunnerautoscaler) (horizontalrunnerautoscaler_workflow_runs_queued{namespace=~"$namespace"})`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} queued"},
+					{Expr: `sum by (namespace, horizontalrunnerautoscaler) (horizontalrunnerautoscaler_workflow_runs_in_progress{namespace=~"$namespace"})`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} in progress"},
+				},
+			},
+			{
+				Title: "Replicas withheld by budget",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_replicas_withheld_by_budget{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}}"},
+				},
+			},
+			{
+				Title: "Paused and dry-run",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_paused{namespace=~"$namespace"} == 1`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} paused"},
+					{Expr: `horizontalrunnerautoscaler_status_suggested_replicas{namespace=~"$namespace"} and on (namespace, horizontalrunnerautoscaler) horizontalrunnerautoscaler_dry_run == 1`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} suggested in dry-run"},
+				},
+			},
+			{
+				Title: "Service level objectives error budget remaining",
+				Unit:  "percentunit",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_slo_error_budget_remaining{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} {{slo}}"},
+				},
+			},
+			{
+				Title: "Service level objectives burn rate",
+				Targets: []dashboardTarget{
+					{Expr: `horizontalrunnerautoscaler_slo_error_budget_burn_rate{namespace=~"$namespace"}`, Legend: "{{namespace}}/{{horizontalrunnerautoscaler}} {{slo}} {{window}}"},
+				},
+			},
+		},
+	},
+	{
+		UID:             "arc-runners",
+		Title:           "Actions Runner Controller / Runners",
+		namespaceMetric: "runner_registration_duration_seconds_count",
+		Panels: []dashboardPanel{
+			{
+				Title: "Runner registration duration",
+				Unit:  "s",
+				Targets: []dashboardTarget{
+					{Expr: `histogram_quantile(0.5, sum by (le, namespace) (rate(runner_registration_duration_seconds_bucket{namespace=~"$namespace"}[$__rate_interval])))`, Legend: "{{namespace}} p50"},
+					{Expr: `histogram_quantile(0.9, sum by (le, namespace) (rate(runner_registration_duration_seconds_bucket{namespace=~"$namespace"}[$__rate_interval])))`, Legend: "{{namespace}} p90"},
+				},
+			},
+			{
+				Title: "Runner registration failures",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (namespace) (increase(runner_registration_failures_total{namespace=~"$namespace"}[$__rate_interval]))`, Legend: "{{namespace}}"},
+				},
+			},
+			{
+				Title: "Jobs lost to preemption",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (namespace, reason) (increase(runner_jobs_preempted_total{namespace=~"$namespace"}[$__rate_interval]))`, Legend: "{{namespace}} {{reason}}"},
+				},
+			},
+			{
+				Title: "Offline runners removed",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (namespace) (increase(offline_runners_removed_total{namespace=~"$namespace"}[$__rate_interval]))`, Legend: "{{namespace}}"},
+				},
+			},
+			{
+				Title: "Orphaned workflow resources",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (namespace, kind) (orphaned_workflow_resources{namespace=~"$namespace"})`, Legend: "{{namespace}} {{kind}}"},
+				},
+			},
+			{
+				Title: "Reconcile duration p99",
+				Unit:  "s",
+				Targets: []dashboardTarget{
+					{Expr: `histogram_quantile(0.99, sum by (le, kind, result) (rate(controller_reconcile_duration_seconds_bucket[$__rate_interval])))`, Legend: "{{kind}} {{result}}"},
+				},
+			},
+			{
+				Title: "Webhook deliveries",
+				Unit:  "reqps",
+				Targets: []dashboardTarget{
+					{Expr: `sum(rate(githubwebhook_deliveries_redelivered_total[$__rate_interval]))`, Legend: "redelivered"},
+					{Expr: `sum(rate(githubwebhook_deliveries_abandoned_total[$__rate_interval]))`, Legend: "abandoned"},
+					{Expr: `sum(rate(githubwebhook_deliveries_deduplicated_total[$__rate_interval]))`, Legend: "deduplicated"},
+					{Expr: `sum(rate(githubwebhook_deliveries_rejected_by_source_total[$__rate_interval]))`, Legend: "rejected by source"},
+				},
+			},
+			{
+				Title: "Webhook fan-out lag p99",
+				Unit:  "s",
+				Targets: []dashboardTarget{
+					{Expr: `histogram_quantile(0.99, sum by (le, consumer) (rate(githubwebhook_fanout_lag_seconds_bucket[$__rate_interval])))`, Legend: "{{consumer}}"},
+				},
+			},
+			{
+				Title: "Webhook fan-out events",
+				Unit:  "reqps",
+				Targets: []dashboardTarget{
+					{Expr: `sum by (consumer, result) (rate(githubwebhook_fanout_events_total[$__rate_interval]))`, Legend: "{{consumer}} {{result}}"},
+				},
+			},
+		},
+	},
+}
+
+var alertRules = []alertRule{
+	{
+		Alert:    "ARCHorizontalRunnerAutoscalerAtMaxReplicas",
+		Expr:     `horizontalrunnerautoscaler_status_desired_replicas >= on (namespace, horizontalrunnerautoscaler) horizontalrunnerautoscaler_spec_max_replicas > 0`,
+		For:      "30m",
+		Severity: "warning",
+		Summary:  "HorizontalRunnerAutoscaler {{ $labels.namespace }}/{{ $labels.horizontalrunnerautoscaler }} has been at maxReplicas for 30 minutes, so the jobs may be queued waiting for runners.",
+	},
+	{
+		Alert:    "ARCRunnerRegistrationFailures",
+		Expr:     `sum by (namespace) (increase(runner_registration_failures_total[15m])) > 0`,
+		Severity: "warning",
+		Summary:  "Runners in {{ $labels.namespace }} failed to register to GitHub within the registration timeout.",
+	},
+	{
+		Alert:    "ARCRunnerRegistrationSlow",
+		Expr:     `histogram_quantile(0.9, sum by (le, namespace) (rate(runner_registration_duration_seconds_bucket[30m]))) > 300`,
+		For:      "30m",
+		Severity: "warning",
+		Summary:  "90% of the runners in {{ $labels.namespace }} take more than 5 minutes to register to GitHub.",
+	},
+	{
+		Alert:    "ARCRunnerJobsPreempted",
+		Expr:     `sum by (namespace, reason) (increase(runner_jobs_preempted_total[1h])) > 0`,
+		Severity: "info",
+		Summary:  "Workflow jobs in {{ $labels.namespace }} were lost as their runner pods were preempted by {{ $labels.reason }}.",
+	},
+	{
+		Alert:    "ARCReconcileErrors",
+		Expr:     `sum by (kind) (rate(controller_reconcile_duration_seconds_count{result="error"}[5m])) > 0.1`,
+		For:      "15m",
+		Severity: "warning",
+		Summary:  "The reconciliations of {{ $labels.kind }} keep failing.",
+	},
+	{
+		Alert:    "ARCWebhookDeliveriesAbandoned",
+		Expr:     `increase(githubwebhook_deliveries_abandoned_total[1h]) > 0`,
+		Severity: "warning",
+		Summary:  "Failed GitHub webhook deliveries were abandoned after too many redeliveries, so some workflow jobs may not have scaled the runners.",
+	},
+	{
+		Alert:    "ARCServiceLevelObjectiveFastBurn",
+		Expr:     `horizontalrunnerautoscaler_slo_error_budget_burn_rate{window="1h"} > 14.4`,
+		For:      "2m",
+		Severity: "critical",
+		Summary:  "The service level objective {{ $labels.slo }} of HorizontalRunnerAutoscaler {{ $labels.namespace }}/{{ $labels.horizontalrunnerautoscaler }} is burning 2% of its 30 days error budget per hour.",
+	},
+	{
+		Alert:    "ARCServiceLevelObjectiveSlowBurn",
+		Expr:     `horizontalrunnerautoscaler_slo_error_budget_burn_rate{window="6h"} > 6`,
+		For:      "15m",
+		Severity: "warning",
+		Summary:  "The service level objective {{ $labels.slo }} of HorizontalRunnerAutoscaler {{ $labels.namespace }}/{{ $labels.horizontalrunnerautoscaler }} has burnt 5% of its 30 days error budget in 6 hours.",
+	},
+}
+
+// Dashboards returns the Grafana dashboards over the metrics of the controller and the webhook server, keyed by their file names.
+func Dashboards() map[string][]byte {
+	files := map[string][]byte{}
+
+	for _, d := range dashboards {
+		data, err := json.MarshalIndent(d.grafanaJSON(), "", "  ")
+		if err != nil {
+			panic(fmt.Sprintf("marshaling dashboard %s: %v", d.UID, err))
+		}
+
+		files[d.UID+".json"] = data
+	}
+
+	return files
+}
+
+// PrometheusRule returns the PrometheusRule of the prometheus-operator alerting on the metrics of the controller and the webhook server.
+func PrometheusRule(name, namespace string) []byte {
+	var rules []map[string]interface{}
+
+	for _, r := range alertRules {
+		rule := map[string]interface{}{
+			"alert":       r.Alert,
+			"expr":        r.Expr,
+			"labels":      map[string]string{"severity": r.Severity},
+			"annotations": map[string]string{"summary": r.Summary},
+		}
+
+		if r.For != "" {
+			rule["for"] = r.For
+		}
+
+		rules = append(rules, rule)
+	}
+
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "PrometheusRule",
+		"metadata":   metadata,
+		"spec": map[string]interface{}{
+			"groups": []map[string]interface{}{
+				{"name": dashboardTag, "rules": rules},
+			},
+		},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("marshaling prometheus rule: %v", err))
+	}
+
+	return data
+}
+
+// DashboardsHandler serves the index of the dashboards on DashboardsPath, and the dashboards and the PrometheusRule under it.
+func DashboardsHandler() http.Handler {
+	files := Dashboards()
+	files[PrometheusRuleFile] = PrometheusRule(dashboardTag, "")
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, DashboardsPath)
+
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string][]string{"files": names})
+			return
+		}
+
+		data, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if strings.HasSuffix(name, ".json") {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "application/yaml")
+		}
+
+		_, _ = w.Write(data)
+	})
+}
+
+// DashboardProvisioner creates or updates the ConfigMap of the dashboards labeled for the dashboard sidecar of Grafana on start,
+// so that the dashboards of the installed version of the controller show up in Grafana without importing them.
+type DashboardProvisioner struct {
+	Client client.Client
+	Log    logr.Logger
+
+	ConfigMap types.NamespacedName
+}
+
+func (p *DashboardProvisioner) Start(ctx context.Context) error {
+	data := map[string]string{}
+	for name, d := range Dashboards() {
+		data[name] = string(d)
+	}
+
+	var cm corev1.ConfigMap
+
+	err := p.Client.Get(ctx, p.ConfigMap, &cm)
+	if kerrors.IsNotFound(err) {
+		cm = corev1.ConfigMap{}
+		cm.Namespace = p.ConfigMap.Namespace
+		cm.Name = p.ConfigMap.Name
+		cm.Labels = map[string]string{LabelKeyGrafanaDashboard: "1"}
+		cm.Data = data
+
+		if err := p.Client.Create(ctx, &cm); err != nil {
+			return fmt.Errorf("creating dashboards configmap %s: %w", p.ConfigMap, err)
+		}
+
+		p.Log.Info("Created the configmap of the Grafana dashboards", "configmap", p.ConfigMap.String())
+
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("getting dashboards configmap %s: %w", p.ConfigMap, err)
+	}
+
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	cm.Labels[LabelKeyGrafanaDashboard] = "1"
+	cm.Data = data
+
+	if err := p.Client.Update(ctx, &cm); err != nil {
+		return fmt.Errorf("updating dashboards configmap %s: %w", p.ConfigMap, err)
+	}
+
+	p.Log.Info("Updated the configmap of the Grafana dashboards", "configmap", p.ConfigMap.String())
+
+	return nil
+}
+
+func (d dashboard) grafanaJSON() map[string]interface{} {
+	datasource := map[string]string{"type": "prometheus", "uid": "${datasource}"}
+
+	var panels []map[string]interface{}
+
+	for i, p := range d.Panels {
+		var targets []map[string]interface{}
+		for j, t := range p.Targets {
+			targets = append(targets, map[string]interface{}{
+				"refId":        string(rune('A' + j)),
+				"datasource":   datasource,
+				"expr":         t.Expr,
+				"legendFormat": t.Legend,
+			})
+		}
+
+		unit := p.Unit
+		if unit == "" {
+			unit = "short"
+		}
+
+		panels = append(panels, map[string]interface{}{
+			"id":         i + 1,
+			"type":       "timeseries",
+			"title":      p.Title,
+			"datasource": datasource,
+			"gridPos":    map[string]int{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			"fieldConfig": map[string]interface{}{
+				"defaults":  map[string]string{"unit": unit},
+				"overrides": []interface{}{},
+			},
+			"targets": targets,
+		})
+	}
+
+	return map[string]interface{}{
+		"uid":           d.UID,
+		"title":         d.Title,
+		"tags":          []string{dashboardTag},
+		"editable":      true,
+		"schemaVersion": 38,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{
+				{
+					"name":  "datasource",
+					"label": "Data source",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+				{
+					"name":       "namespace",
+					"label":      "Namespace",
+					"type":       "query",
+					"datasource": datasource,
+					"query":      fmt.Sprintf("label_values(%s, namespace)", d.namespaceMetric),
+					"includeAll": true,
+					"multi":      true,
+					"allValue":   ".*",
+					"refresh":    2,
+				},
+			},
+		},
+		"panels": panels,
+	}
+}
@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	descPattern     = regexp.MustCompile(`fqName: "([^"]+)".*variableLabels: \{([^}]*)\}`)
+	selectorPattern = regexp.MustCompile(`([a-z_]+)(\{[^}]*\})?(\[[^\]]+\])?`)
+	matcherPattern  = regexp.MustCompile(`([a-z_]+)\s*(=~|!=|=)`)
+	groupingPattern = regexp.MustCompile(`\b(?:by|on)\s*\(([^)]*)\)`)
+)
+
+// exportedMetrics returns the labels of the metrics exported by the package by the metric names,
+// along with the series names of the histograms.
+func exportedMetrics(t *testing.T) map[string][]string {
+	var collectors []prometheus.Collector
+	for _, cs := range [][]prometheus.Collector{runnerDeploymentMetrics, horizontalRunnerAutoscalerMetrics, webhookMetrics, runnerMetrics, controllerMetrics, reconcilemetrics.Collectors} {
+		collectors = append(collectors, cs...)
+	}
+
+	metrics := map[string][]string{}
+
+	for _, c := range collectors {
+		ch := make(chan *prometheus.Desc, 1)
+		go func() {
+			c.Describe(ch)
+			close(ch)
+		}()
+
+		for d := range ch {
+			m := descPattern.FindStringSubmatch(d.String())
+			require.NotNil(t, m, d.String())
+
+			var labels []string
+			if m[2] != "" {
+				labels = strings.Split(m[2], ",")
+			}
+
+			metrics[m[1]] = labels
+
+			if _, ok := c.(*prometheus.HistogramVec); ok {
+				metrics[m[1]+"_bucket"] = append(labels, "le")
+				metrics[m[1]+"_count"] = labels
+				metrics[m[1]+"_sum"] = labels
+			}
+		}
+	}
+
+	return metrics
+}
+
+var promQLKeywords = map[string]bool{
+	"sum": true, "rate": true, "increase": true, "histogram_quantile": true, "by": true, "on": true, "and": true, "or": true,
+}
+
+// assertQuery asserts that the query only selects the metrics exported by the package, by their labels.
+func assertQuery(t *testing.T, metrics map[string][]string, query string) {
+	t.Helper()
+
+	var selected []string
+
+	for _, m := range selectorPattern.FindAllStringSubmatch(query, -1) {
+		name := m[1]
+		if promQLKeywords[name] || (m[2] == "" && m[3] == "" && !strings.Contains(name, "_")) {
+			continue
+		}
+
+		// The labels in the selectors like le and namespace in "by (le, namespace)" aren't metrics
+		labels, ok := metrics[name]
+		if !ok {
+			if m[2] == "" && m[3] == "" {
+				continue
+			}
+			assert.Failf(t, "unknown metric", "%s in %s", name, query)
+			continue
+		}
+
+		selected = append(selected, name)
+
+		for _, l := range matcherPattern.FindAllStringSubmatch(m[2], -1) {
+			assert.Containsf(t, labels, l[1], "label %s of %s in %s", l[1], name, query)
+		}
+	}
+
+	require.NotEmptyf(t, selected, "no metric in %s", query)
+
+	for _, g := range groupingPattern.FindAllStringSubmatch(query, -1) {
+		for _, l := range strings.Split(g[1], ",") {
+			l = strings.TrimSpace(l)
+			for _, name := range selected {
+				assert.Containsf(t, metrics[name], l, "label %s of %s in %s", l, name, query)
+			}
+		}
+	}
+}
+
+func TestDashboardQueries(t *testing.T) {
+	metrics := exportedMetrics(t)
+
+	for _, d := range dashboards {
+		assert.Contains(t, metrics[d.namespaceMetric], "namespace", d.UID)
+
+		for _, p := range d.Panels {
+			for _, target := range p.Targets {
+				assertQuery(t, metrics, target.Expr)
+			}
+		}
+	}
+
+	for _, r := range alertRules {
+		assertQuery(t, metrics, r.Expr)
+	}
+}
+
+func TestDashboards(t *testing.T) {
+	files := Dashboards()
+	require.Len(t, files, len(dashboards))
+
+	var d struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal(files["arc-autoscaling.json"], &d))
+	assert.Equal(t, "arc-autoscaling", d.UID)
+	assert.NotEmpty(t, d.Panels[0].Targets[0].Expr)
+
+	var rule struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Groups []struct {
+				Rules []map[string]interface{} `json:"rules"`
+			} `json:"groups"`
+		} `json:"spec"`
+	}
+	require.NoError(t, yaml.Unmarshal(PrometheusRule("arc", "monitoring"), &rule))
+	assert.Equal(t, "PrometheusRule", rule.Kind)
+	assert.Equal(t, "monitoring", rule.Metadata.Namespace)
+	assert.Len(t, rule.Spec.Groups[0].Rules, len(alertRules))
+}
+
+func TestDashboardsHandler(t *testing.T) {
+	h := DashboardsHandler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	res := get(DashboardsPath)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"files":["arc-autoscaling.json","arc-runners.json","prometheusrule.yaml"]}`, res.Body.String())
+
+	res = get(DashboardsPath + "arc-runners.json")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+	res = get(DashboardsPath + PrometheusRuleFile)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "kind: PrometheusRule")
+
+	assert.Equal(t, http.StatusNotFound, get(DashboardsPath+"missing.json").Code)
+}
+
+func TestDashboardProvisioner(t *testing.T) {
+	key := types.NamespacedName{Namespace: "monitoring", Name: "arc-dashboards"}
+
+	c := clientfake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+
+	p := &DashboardProvisioner{Client: c, Log: logr.Discard(), ConfigMap: key}
+	require.NoError(t, p.Start(context.Background()))
+
+	var cm corev1.ConfigMap
+	require.NoError(t, c.Get(context.Background(), key, &cm))
+	assert.Equal(t, "1", cm.Labels[LabelKeyGrafanaDashboard])
+	assert.Len(t, cm.Data, len(dashboards))
+
+	cm.Data = map[string]string{"stale.json": "{}"}
+	cm.Labels["team"] = "ci"
+	require.NoError(t, c.Update(context.Background(), &cm))
+
+	require.NoError(t, p.Start(context.Background()))
+	require.NoError(t, c.Get(context.Background(), key, &cm))
+	assert.NotContains(t, cm.Data, "stale.json")
+	assert.Equal(t, "ci", cm.Labels["team"])
+}
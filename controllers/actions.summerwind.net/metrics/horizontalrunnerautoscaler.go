@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,6 +16,8 @@ const (
 	stRepository   = "repository"
 	stKind         = "kind"
 	stName         = "name"
+	sloName        = "slo"
+	sloWindow      = "window"
 )
 
 var (
@@ -21,6 +25,12 @@ var (
 		horizontalRunnerAutoscalerMinReplicas,
 		horizontalRunnerAutoscalerMaxReplicas,
 		horizontalRunnerAutoscalerDesiredReplicas,
+		horizontalRunnerAutoscalerReplicasWithheldByBudget,
+		horizontalRunnerAutoscalerBudgetSpent,
+		horizontalRunnerAutoscalerPaused,
+		horizontalRunnerAutoscalerDryRun,
+		horizontalRunnerAutoscalerSuggestedReplicas,
+		horizontalRunnerAutoscalerMaxReplicasFromCapacity,
 		horizontalRunnerAutoscalerReplicasDesired,
 		horizontalRunnerAutoscalerRunners,
 		horizontalRunnerAutoscalerRunnersRegistered,
@@ -31,6 +41,9 @@ var (
 		horizontalRunnerAutoscalerWorkflowRunsInProgress,
 		horizontalRunnerAutoscalerWorkflowRunsQueued,
 		horizontalRunnerAutoscalerWorkflowRunsUnknown,
+		horizontalRunnerAutoscalerSLOCompliance,
+		horizontalRunnerAutoscalerSLOErrorBudgetRemaining,
+		horizontalRunnerAutoscalerSLOErrorBudgetBurnRate,
 	}
 )
 
@@ -56,6 +69,48 @@ var (
 		},
 		[]string{hraName, hraNamespace},
 	)
+	horizontalRunnerAutoscalerReplicasWithheldByBudget = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_replicas_withheld_by_budget",
+			Help: "Number of replicas withheld due to the budget of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerBudgetSpent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_budget_spent",
+			Help: "Estimated cost spent today on the runners of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerPaused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_paused",
+			Help: "1 if HorizontalRunnerAutoscaler is paused, 0 otherwise",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerDryRun = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_dry_run",
+			Help: "1 if HorizontalRunnerAutoscaler computes the desired replicas without applying them, 0 otherwise",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerSuggestedReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_status_suggested_replicas",
+			Help: "suggestedReplicas of HorizontalRunnerAutoscaler computed while paused or in dry-run",
+		},
+		[]string{hraName, hraNamespace},
+	)
+	horizontalRunnerAutoscalerMaxReplicasFromCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_max_replicas_from_capacity",
+			Help: "maxReplicas of HorizontalRunnerAutoscaler derived from the allocatable resources of the nodes",
+		},
+		[]string{hraName, hraNamespace},
+	)
 	// PercentageRunnersBusy
 	horizontalRunnerAutoscalerReplicasDesired = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -128,6 +183,27 @@ var (
 		},
 		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
 	)
+	horizontalRunnerAutoscalerSLOCompliance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_slo_compliance",
+			Help: "Ratio of the workflow jobs within the threshold of the service level objective of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace, sloName},
+	)
+	horizontalRunnerAutoscalerSLOErrorBudgetRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_slo_error_budget_remaining",
+			Help: "Ratio of the error budget of the service level objective of HorizontalRunnerAutoscaler left unspent, negative when overspent",
+		},
+		[]string{hraName, hraNamespace, sloName},
+	)
+	horizontalRunnerAutoscalerSLOErrorBudgetBurnRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_slo_error_budget_burn_rate",
+			Help: "How many times faster than allowed the error budget of the service level objective of HorizontalRunnerAutoscaler is spent over the window",
+		},
+		[]string{hraName, hraNamespace, sloName, sloWindow},
+	)
 )
 
 func SetHorizontalRunnerAutoscalerSpec(o metav1.ObjectMeta, spec v1alpha1.HorizontalRunnerAutoscalerSpec) {
@@ -151,6 +227,35 @@ func SetHorizontalRunnerAutoscalerStatus(o metav1.ObjectMeta, status v1alpha1.Ho
 	if status.DesiredReplicas != nil {
 		horizontalRunnerAutoscalerDesiredReplicas.With(labels).Set(float64(*status.DesiredReplicas))
 	}
+	if status.Budget != nil {
+		horizontalRunnerAutoscalerReplicasWithheldByBudget.With(labels).Set(float64(status.Budget.WithheldReplicas))
+		if spent, err := strconv.ParseFloat(status.Budget.Spent, 64); err == nil {
+			horizontalRunnerAutoscalerBudgetSpent.With(labels).Set(spent)
+		}
+	} else {
+		horizontalRunnerAutoscalerReplicasWithheldByBudget.Delete(labels)
+		horizontalRunnerAutoscalerBudgetSpent.Delete(labels)
+	}
+	if status.Paused {
+		horizontalRunnerAutoscalerPaused.With(labels).Set(1)
+	} else {
+		horizontalRunnerAutoscalerPaused.With(labels).Set(0)
+	}
+	if status.DryRun {
+		horizontalRunnerAutoscalerDryRun.With(labels).Set(1)
+	} else {
+		horizontalRunnerAutoscalerDryRun.With(labels).Set(0)
+	}
+	if status.SuggestedReplicas != nil {
+		horizontalRunnerAutoscalerSuggestedReplicas.With(labels).Set(float64(*status.SuggestedReplicas))
+	} else {
+		horizontalRunnerAutoscalerSuggestedReplicas.Delete(labels)
+	}
+	if status.MaxReplicasFromCapacity != nil {
+		horizontalRunnerAutoscalerMaxReplicasFromCapacity.With(labels).Set(float64(*status.MaxReplicasFromCapacity))
+	} else {
+		horizontalRunnerAutoscalerMaxReplicasFromCapacity.Delete(labels)
+	}
 }
 
 func SetHorizontalRunnerAutoscalerPercentageRunnersBusy(
@@ -210,3 +315,37 @@ func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
 	horizontalRunnerAutoscalerWorkflowRunsQueued.With(labels).Set(float64(workflowRunsQueued))
 	horizontalRunnerAutoscalerWorkflowRunsUnknown.With(labels).Set(float64(workflowRunsUnknown))
 }
+
+// SetHorizontalRunnerAutoscalerServiceLevelObjective sets the compliance, the error budget remaining,
+// and the burn rates by the windows of a service level objective of the HRA.
+func SetHorizontalRunnerAutoscalerServiceLevelObjective(o metav1.ObjectMeta, name string, compliance, errorBudgetRemaining float64, burnRates map[string]float64) {
+	labels := prometheus.Labels{
+		hraName:      o.Name,
+		hraNamespace: o.Namespace,
+		sloName:      name,
+	}
+	horizontalRunnerAutoscalerSLOCompliance.With(labels).Set(compliance)
+	horizontalRunnerAutoscalerSLOErrorBudgetRemaining.With(labels).Set(errorBudgetRemaining)
+
+	for window, rate := range burnRates {
+		labels := prometheus.Labels{
+			hraName:      o.Name,
+			hraNamespace: o.Namespace,
+			sloName:      name,
+			sloWindow:    window,
+		}
+		horizontalRunnerAutoscalerSLOErrorBudgetBurnRate.With(labels).Set(rate)
+	}
+}
+
+// DeleteHorizontalRunnerAutoscalerServiceLevelObjectives deletes the metrics of all the service level objectives of the HRA,
+// so that the removed and the not yet observed objectives don't keep reporting stale values.
+func DeleteHorizontalRunnerAutoscalerServiceLevelObjectives(o metav1.ObjectMeta) {
+	labels := prometheus.Labels{
+		hraName:      o.Name,
+		hraNamespace: o.Namespace,
+	}
+	horizontalRunnerAutoscalerSLOCompliance.DeletePartialMatch(labels)
+	horizontalRunnerAutoscalerSLOErrorBudgetRemaining.DeletePartialMatch(labels)
+	horizontalRunnerAutoscalerSLOErrorBudgetBurnRate.DeletePartialMatch(labels)
+}
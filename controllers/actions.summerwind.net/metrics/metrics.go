@@ -11,4 +11,7 @@ import (
 func init() {
 	metrics.Registry.MustRegister(runnerDeploymentMetrics...)
 	metrics.Registry.MustRegister(horizontalRunnerAutoscalerMetrics...)
+	metrics.Registry.MustRegister(webhookMetrics...)
+	metrics.Registry.MustRegister(runnerMetrics...)
+	metrics.Registry.MustRegister(controllerMetrics...)
 }
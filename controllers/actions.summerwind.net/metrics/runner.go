@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	runnerNamespace    = "namespace"
+	runnerEnterprise   = "enterprise"
+	runnerOrganization = "organization"
+	runnerRepository   = "repository"
+
+	runnerDeploymentName = "runnerdeployment"
+	runnerSetName        = "runnerset"
+	preemptionReason     = "reason"
+	runnerRunsOn         = "runs_on"
+	runnerResource       = "resource"
+	resourceKind         = "kind"
+)
+
+var (
+	runnerMetrics = []prometheus.Collector{
+		runnerRegistrationFailures,
+		offlineRunnersRemoved,
+		runnerJobsPreempted,
+		runnerPodResourceRequests,
+		runnerPodResourceLimits,
+		orphanedWorkflowResources,
+		orphanedWorkflowResourcesDeleted,
+	}
+)
+
+var (
+	runnerRegistrationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runner_registration_failures_total",
+			Help: "Total number of runner pods recreated as the runners failed to register themselves to GitHub within the registration timeout",
+		},
+		[]string{runnerNamespace, runnerEnterprise, runnerOrganization, runnerRepository},
+	)
+	offlineRunnersRemoved = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "offline_runners_removed_total",
+			Help: "Total number of offline runners removed from GitHub as they disappeared without unregistering themselves",
+		},
+		[]string{runnerNamespace, runnerEnterprise, runnerOrganization, runnerRepository},
+	)
+	runnerJobsPreempted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runner_jobs_preempted_total",
+			Help: "Total number of workflow jobs lost as their runner pods were preempted, like on spot instance termination or eviction",
+		},
+		[]string{runnerNamespace, runnerDeploymentName, runnerSetName, preemptionReason},
+	)
+	runnerPodResourceRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runner_pod_resource_requests",
+			Help: "Resource requests of the runner pod last created for the runs-on labels, for node autoscalers to size the nodes of the queued runner pods",
+		},
+		[]string{runnerNamespace, runnerRunsOn, runnerResource},
+	)
+	runnerPodResourceLimits = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runner_pod_resource_limits",
+			Help: "Resource limits of the runner pod last created for the runs-on labels",
+		},
+		[]string{runnerNamespace, runnerRunsOn, runnerResource},
+	)
+	orphanedWorkflowResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "orphaned_workflow_resources",
+			Help: "Number of workflow pods, services, secrets, and persistent volume claims whose runner pods no longer exist, waiting for the grace period to be deleted",
+		},
+		[]string{runnerNamespace, resourceKind},
+	)
+	orphanedWorkflowResourcesDeleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orphaned_workflow_resources_deleted_total",
+			Help: "Total number of workflow resources deleted as their runner pods no longer exist",
+		},
+		[]string{runnerNamespace, resourceKind},
+	)
+)
+
+func IncRunnerRegistrationFailures(namespace, enterprise, organization, repository string) {
+	runnerRegistrationFailures.With(prometheus.Labels{
+		runnerNamespace:    namespace,
+		runnerEnterprise:   enterprise,
+		runnerOrganization: organization,
+		runnerRepository:   repository,
+	}).Inc()
+}
+
+func IncOfflineRunnersRemoved(namespace, enterprise, organization, repository string) {
+	offlineRunnersRemoved.With(prometheus.Labels{
+		runnerNamespace:    namespace,
+		runnerEnterprise:   enterprise,
+		runnerOrganization: organization,
+		runnerRepository:   repository,
+	}).Inc()
+}
+
+// IncRunnerJobsPreempted counts the job lost to the preemption of the runner pod of the RunnerDeployment or the RunnerSet.
+// Either runnerDeployment or runnerSet is empty.
+func IncRunnerJobsPreempted(namespace, runnerDeployment, runnerSet, reason string) {
+	runnerJobsPreempted.With(prometheus.Labels{
+		runnerNamespace:      namespace,
+		runnerDeploymentName: runnerDeployment,
+		runnerSetName:        runnerSet,
+		preemptionReason:     reason,
+	}).Inc()
+}
+
+// SetRunnerPodResourceEnvelope sets the resource requests and limits of the runner pod for the runs-on labels,
+// which is the comma-separated runner labels. CPU is in cores and the other resources are in their base units.
+func SetRunnerPodResourceEnvelope(namespace, runsOn string, requests, limits corev1.ResourceList) {
+	for _, v := range []struct {
+		gauge     *prometheus.GaugeVec
+		resources corev1.ResourceList
+	}{
+		{runnerPodResourceRequests, requests},
+		{runnerPodResourceLimits, limits},
+	} {
+		for name, q := range v.resources {
+			v.gauge.With(prometheus.Labels{
+				runnerNamespace: namespace,
+				runnerRunsOn:    runsOn,
+				runnerResource:  string(name),
+			}).Set(q.AsApproximateFloat64())
+		}
+	}
+}
+
+// ResetOrphanedWorkflowResources forgets the orphan counts of the last collection, including the ones of the namespaces no longer having any.
+func ResetOrphanedWorkflowResources() {
+	orphanedWorkflowResources.Reset()
+}
+
+func SetOrphanedWorkflowResources(namespace, kind string, n int) {
+	orphanedWorkflowResources.With(prometheus.Labels{
+		runnerNamespace: namespace,
+		resourceKind:    kind,
+	}).Set(float64(n))
+}
+
+func IncOrphanedWorkflowResourcesDeleted(namespace, kind string) {
+	orphanedWorkflowResourcesDeleted.With(prometheus.Labels{
+		runnerNamespace: namespace,
+		resourceKind:    kind,
+	}).Inc()
+}
@@ -3,6 +3,7 @@ package metrics
 import (
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -13,6 +14,9 @@ const (
 var (
 	runnerDeploymentMetrics = []prometheus.Collector{
 		runnerDeploymentReplicas,
+		runnerDeploymentReplicasWithheldBySchedulingBudget,
+		runnerDeploymentConcurrencyGateOpen,
+		runnerDeploymentReplicasWithheldByConcurrencyGate,
 	}
 )
 
@@ -24,6 +28,27 @@ var (
 		},
 		[]string{rdName, rdNamespace},
 	)
+	runnerDeploymentReplicasWithheldBySchedulingBudget = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_replicas_withheld_by_scheduling_budget",
+			Help: "Number of replicas withheld due to the scheduling budget of RunnerDeployment",
+		},
+		[]string{rdName, rdNamespace},
+	)
+	runnerDeploymentConcurrencyGateOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_concurrency_gate_open",
+			Help: "1 if the concurrency gate of RunnerDeployment is open, 0 if closed",
+		},
+		[]string{rdName, rdNamespace},
+	)
+	runnerDeploymentReplicasWithheldByConcurrencyGate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_replicas_withheld_by_concurrency_gate",
+			Help: "Number of replicas withheld as the concurrency gate of RunnerDeployment is closed",
+		},
+		[]string{rdName, rdNamespace},
+	)
 )
 
 func SetRunnerDeployment(rd v1alpha1.RunnerDeployment) {
@@ -35,3 +60,26 @@ func SetRunnerDeployment(rd v1alpha1.RunnerDeployment) {
 		runnerDeploymentReplicas.With(labels).Set(float64(*rd.Spec.Replicas))
 	}
 }
+
+func SetRunnerDeploymentStatus(o metav1.ObjectMeta, status v1alpha1.RunnerDeploymentStatus) {
+	labels := prometheus.Labels{
+		rdName:      o.Name,
+		rdNamespace: o.Namespace,
+	}
+	if status.SchedulingBudget != nil {
+		runnerDeploymentReplicasWithheldBySchedulingBudget.With(labels).Set(float64(status.SchedulingBudget.WithheldReplicas))
+	} else {
+		runnerDeploymentReplicasWithheldBySchedulingBudget.Delete(labels)
+	}
+	if status.ConcurrencyGate != nil {
+		open := 0.0
+		if status.ConcurrencyGate.Open {
+			open = 1
+		}
+		runnerDeploymentConcurrencyGateOpen.With(labels).Set(open)
+		runnerDeploymentReplicasWithheldByConcurrencyGate.With(labels).Set(float64(status.ConcurrencyGate.WithheldReplicas))
+	} else {
+		runnerDeploymentConcurrencyGateOpen.Delete(labels)
+		runnerDeploymentReplicasWithheldByConcurrencyGate.Delete(labels)
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	webhookMetrics = []prometheus.Collector{
+		webhookDeliveriesRedelivered,
+		webhookDeliveriesAbandoned,
+		webhookDeliveriesDeduplicated,
+		webhookDeliveriesRejectedBySource,
+		webhookFanoutEvents,
+		webhookFanoutLag,
+		webhookFanoutQueueLength,
+	}
+)
+
+var (
+	webhookDeliveriesRedelivered = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "githubwebhook_deliveries_redelivered_total",
+			Help: "Total number of failed GitHub webhook deliveries requested to be redelivered",
+		},
+	)
+	webhookDeliveriesAbandoned = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "githubwebhook_deliveries_abandoned_total",
+			Help: "Total number of failed GitHub webhook deliveries that are no longer redelivered as they failed too many times",
+		},
+	)
+	webhookDeliveriesDeduplicated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "githubwebhook_deliveries_deduplicated_total",
+			Help: "Total number of GitHub webhook deliveries ignored as they were already processed by another webhook server replica",
+		},
+	)
+	webhookDeliveriesRejectedBySource = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "githubwebhook_deliveries_rejected_by_source_total",
+			Help: "Total number of webhook deliveries rejected as they came from outside of the allowed IP ranges",
+		},
+	)
+	webhookFanoutEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "githubwebhook_fanout_events_total",
+			Help: "Total number of webhook events fanned out to the consumer by result, either delivered, failed, or dropped as the queue of the consumer was full",
+		},
+		[]string{"consumer", "result"},
+	)
+	webhookFanoutLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "githubwebhook_fanout_lag_seconds",
+			Help:    "Seconds from the receipt of a webhook event to its handling by the consumer",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+		[]string{"consumer"},
+	)
+	webhookFanoutQueueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "githubwebhook_fanout_queue_length",
+			Help: "Number of the webhook events waiting in the queue of the consumer",
+		},
+		[]string{"consumer"},
+	)
+)
+
+func IncWebhookDeliveriesRedelivered() {
+	webhookDeliveriesRedelivered.Inc()
+}
+
+func IncWebhookDeliveriesAbandoned() {
+	webhookDeliveriesAbandoned.Inc()
+}
+
+func IncWebhookDeliveriesDeduplicated() {
+	webhookDeliveriesDeduplicated.Inc()
+}
+
+func IncWebhookDeliveriesRejectedBySource() {
+	webhookDeliveriesRejectedBySource.Inc()
+}
+
+func IncWebhookFanoutEvents(consumer, result string) {
+	webhookFanoutEvents.WithLabelValues(consumer, result).Inc()
+}
+
+func ObserveWebhookFanoutLag(consumer string, lag time.Duration) {
+	webhookFanoutLag.WithLabelValues(consumer).Observe(lag.Seconds())
+}
+
+func SetWebhookFanoutQueueLength(consumer string, length int) {
+	webhookFanoutQueueLength.WithLabelValues(consumer).Set(float64(length))
+}
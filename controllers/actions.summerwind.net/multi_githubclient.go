@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -20,6 +21,10 @@ const (
 	// The api creds scret annotation is added by the runner controller or the runnerset controller according to runner.spec.githubAPICredentialsFrom.secretRef.name,
 	// so that the runner pod controller can share the same GitHub API credentials and the instance of the GitHub API client with the upstream controllers.
 	annotationKeyGitHubAPICredsSecret = annotationKeyPrefix + "github-api-creds-secret"
+
+	// The api token scope annotation is added by the runner controller or the runnerset controller according to runner.spec.githubAPITokenScope,
+	// so that the runner pod controller and the runner token injector obtain registration tokens using the same scoped installation token.
+	annotationKeyGitHubAPITokenScope = annotationKeyPrefix + "github-api-token-scope"
 )
 
 type runnerOwnerRef struct {
@@ -49,6 +54,7 @@ type savedClient struct {
 
 type resourceReader interface {
 	Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error
+	List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error
 }
 
 type MultiGitHubClient struct {
@@ -63,6 +69,10 @@ type MultiGitHubClient struct {
 	// so that we won't miss any dependent's termination.
 	// The change is the secret is determined using the hash of its contents.
 	clients map[secretRef]savedClient
+
+	// RequireGitHubCredential refuses to use the controller-wide credentials for the objects in the namespaces without a GitHubCredential,
+	// so that every tenant uses its own credentials.
+	RequireGitHubCredential bool
 }
 
 func NewMultiGitHubClient(client resourceReader, githubClient *github.Client) *MultiGitHubClient {
@@ -80,8 +90,32 @@ func (c *MultiGitHubClient) InitForRunnerPod(ctx context.Context, pod *corev1.Po
 	ref := refFromRunnerPod(pod)
 	secretName := pod.Annotations[annotationKeyGitHubAPICredsSecret]
 
+	var enterprise, org, repo string
+	for i := range pod.Spec.Containers {
+		if cont := &pod.Spec.Containers[i]; cont.Name == containerName {
+			enterprise, _ = getEnv(cont, EnvVarEnterprise)
+			org, _ = getEnv(cont, EnvVarOrg)
+			repo, _ = getEnv(cont, EnvVarRepo)
+		}
+	}
+
 	// kind can be any of Pod, Runner, RunnerReplicaSet, RunnerDeployment, or RunnerSet depending on which custom resource the user directly created.
-	return c.initClientWithSecretName(ctx, pod.Namespace, secretName, ref)
+	ghc, err := c.initClientForNamespace(ctx, pod.Namespace, secretName, enterprise, org, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := pod.Annotations[annotationKeyGitHubAPITokenScope]
+	if !ok {
+		return ghc, nil
+	}
+
+	var scope v1alpha1.GitHubAPITokenScope
+	if err := json.Unmarshal([]byte(v), &scope); err != nil {
+		return nil, fmt.Errorf("parsing %s annotation: %w", annotationKeyGitHubAPITokenScope, err)
+	}
+
+	return withTokenScope(ghc, &scope)
 }
 
 // Init sets up and return the *github.Client for the object.
@@ -99,7 +133,12 @@ func (c *MultiGitHubClient) InitForRunner(ctx context.Context, r *v1alpha1.Runne
 	}
 
 	// kind can be any of Runner, RunnerReplicaSet, or RunnerDeployment depending on which custom resource the user directly created.
-	return c.initClientWithSecretName(ctx, r.Namespace, secretName, ref)
+	ghc, err := c.initClientForNamespace(ctx, r.Namespace, secretName, r.Spec.Enterprise, r.Spec.Organization, r.Spec.Repository, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return withTokenScope(ghc, r.Spec.GitHubAPITokenScope)
 }
 
 // Init sets up and return the *github.Client for the object.
@@ -112,7 +151,7 @@ func (c *MultiGitHubClient) InitForRunnerSet(ctx context.Context, rs *v1alpha1.R
 		secretName = rs.Spec.GitHubAPICredentialsFrom.SecretRef.Name
 	}
 
-	return c.initClientWithSecretName(ctx, rs.Namespace, secretName, ref)
+	return c.initClientForNamespace(ctx, rs.Namespace, secretName, rs.Spec.Enterprise, rs.Spec.Organization, rs.Spec.Repository, ref)
 }
 
 // Init sets up and return the *github.Client for the object.
@@ -125,39 +164,40 @@ func (c *MultiGitHubClient) InitForHRA(ctx context.Context, hra *v1alpha1.Horizo
 		secretName = hra.Spec.GitHubAPICredentialsFrom.SecretRef.Name
 	}
 
-	return c.initClientWithSecretName(ctx, hra.Namespace, secretName, ref)
+	// The HRA doesn't register runners, so only the credentials are resolved for its namespace
+	return c.initClientForNamespace(ctx, hra.Namespace, secretName, "", "", "", ref)
 }
 
-func (c *MultiGitHubClient) DeinitForRunnerPod(p *corev1.Pod) {
-	secretName := p.Annotations[annotationKeyGitHubAPICredsSecret]
-	c.derefClient(p.Namespace, secretName, refFromRunnerPod(p))
-}
+// InitForMaintenanceWindow returns the client notifying the maintenance of the runners with a commit status.
+func (c *MultiGitHubClient) InitForMaintenanceWindow(ctx context.Context, mw *v1alpha1.MaintenanceWindow, credentialsFrom *v1alpha1.GitHubAPICredentialsFrom) (*github.Client, error) {
+	ref := refFromMaintenanceWindow(mw)
 
-func (c *MultiGitHubClient) DeinitForRunner(r *v1alpha1.Runner) {
 	var secretName string
-	if r.Spec.GitHubAPICredentialsFrom != nil {
-		secretName = r.Spec.GitHubAPICredentialsFrom.SecretRef.Name
+	if credentialsFrom != nil {
+		secretName = credentialsFrom.SecretRef.Name
 	}
 
-	c.derefClient(r.Namespace, secretName, refFromRunner(r))
+	return c.initClientForNamespace(ctx, mw.Namespace, secretName, "", "", "", ref)
 }
 
-func (c *MultiGitHubClient) DeinitForRunnerSet(rs *v1alpha1.RunnerSet) {
-	var secretName string
-	if rs.Spec.GitHubAPICredentialsFrom != nil {
-		secretName = rs.Spec.GitHubAPICredentialsFrom.SecretRef.Name
-	}
+func (c *MultiGitHubClient) DeinitForRunnerPod(p *corev1.Pod) {
+	c.derefClient(p.Namespace, refFromRunnerPod(p))
+}
+
+func (c *MultiGitHubClient) DeinitForRunner(r *v1alpha1.Runner) {
+	c.derefClient(r.Namespace, refFromRunner(r))
+}
 
-	c.derefClient(rs.Namespace, secretName, refFromRunnerSet(rs))
+func (c *MultiGitHubClient) DeinitForRunnerSet(rs *v1alpha1.RunnerSet) {
+	c.derefClient(rs.Namespace, refFromRunnerSet(rs))
 }
 
 func (c *MultiGitHubClient) DeinitForHRA(hra *v1alpha1.HorizontalRunnerAutoscaler) {
-	var secretName string
-	if hra.Spec.GitHubAPICredentialsFrom != nil {
-		secretName = hra.Spec.GitHubAPICredentialsFrom.SecretRef.Name
-	}
+	c.derefClient(hra.Namespace, refFromHorizontalRunnerAutoscaler(hra))
+}
 
-	c.derefClient(hra.Namespace, secretName, refFromHorizontalRunnerAutoscaler(hra))
+func (c *MultiGitHubClient) DeinitForMaintenanceWindow(mw *v1alpha1.MaintenanceWindow) {
+	c.derefClient(mw.Namespace, refFromMaintenanceWindow(mw))
 }
 
 func (c *MultiGitHubClient) initClientForSecret(secret *corev1.Secret, dependent *runnerOwnerRef) (*savedClient, error) {
@@ -195,6 +235,11 @@ func (c *MultiGitHubClient) initClientForSecret(secret *corev1.Secret, dependent
 			conf.EnterpriseURL = c.githubClient.GithubBaseURL
 		}
 
+		conf.AuditSink = c.githubClient.AuditSink()
+
+		// Each namespace is a tenant, so that the tokens minted with the credentials of a namespace are never used by another one
+		conf.Tenant = secret.Namespace
+
 		cli, err := conf.NewClient()
 		if err != nil {
 			return nil, err
@@ -216,6 +261,59 @@ func (c *MultiGitHubClient) initClientForSecret(secret *corev1.Secret, dependent
 	return &cliRef, nil
 }
 
+// githubCredential returns the GitHubCredential of the namespace, or nil when the namespace has none.
+func (c *MultiGitHubClient) githubCredential(ctx context.Context, ns string) (*v1alpha1.GitHubCredential, error) {
+	var creds v1alpha1.GitHubCredentialList
+	if err := c.client.List(ctx, &creds, client.InNamespace(ns)); err != nil {
+		return nil, err
+	}
+
+	switch len(creds.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &creds.Items[0], nil
+	default:
+		return nil, fmt.Errorf("namespace %s has %d GitHubCredentials, while only one is supported per namespace", ns, len(creds.Items))
+	}
+}
+
+// initClientForNamespace returns the client with the credentials of the secret, or of the GitHubCredential of the namespace when secretName is empty.
+// When the namespace has a GitHubCredential, it returns an error unless the GitHubCredential allows the enterprise, organization, or repository
+// the runners are registered to, so that the objects in a namespace can't act on the GitHub resources of another tenant.
+// Leave enterprise, org, and repo empty for the objects that don't register runners.
+func (c *MultiGitHubClient) initClientForNamespace(ctx context.Context, ns, secretName, enterprise, org, repo string, runRef *runnerOwnerRef) (*github.Client, error) {
+	cred, err := c.githubCredential(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	if cred == nil {
+		if secretName == "" && c.RequireGitHubCredential {
+			return nil, fmt.Errorf("namespace %s has no GitHubCredential, which is required to use the GitHub API", ns)
+		}
+
+		return c.initClientWithSecretName(ctx, ns, secretName, runRef)
+	}
+
+	if (enterprise != "" || org != "" || repo != "") && !cred.Spec.Allows(enterprise, org, repo) {
+		target := "repository " + repo
+		if enterprise != "" {
+			target = "enterprise " + enterprise
+		} else if org != "" {
+			target = "organization " + org
+		}
+
+		return nil, fmt.Errorf("GitHubCredential %s/%s doesn't allow registering runners to the %s", ns, cred.Name, target)
+	}
+
+	if secretName == "" {
+		secretName = cred.Spec.SecretRef.Name
+	}
+
+	return c.initClientWithSecretName(ctx, ns, secretName, runRef)
+}
+
 func (c *MultiGitHubClient) initClientWithSecretName(ctx context.Context, ns, secretName string, runRef *runnerOwnerRef) (*github.Client, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -246,24 +344,34 @@ func (c *MultiGitHubClient) initClientWithSecretName(ctx context.Context, ns, se
 	return savedClient.Client, nil
 }
 
-func (c *MultiGitHubClient) derefClient(ns, secretName string, dependent *runnerOwnerRef) {
+// derefClient releases the clients of the namespace the dependent references.
+// The clients are looked up by the dependent rather than by the secret, as the secret defaulted to the one of the GitHubCredential
+// may have changed since the client was initialized.
+func (c *MultiGitHubClient) derefClient(ns string, dependent *runnerOwnerRef) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	secRef := secretRef{
-		ns:   ns,
-		name: secretName,
-	}
+	for secRef, cliRef := range c.clients {
+		if secRef.ns != ns {
+			continue
+		}
 
-	if dependent != nil {
-		delete(c.clients[secRef].refs, *dependent)
-	}
+		delete(cliRef.refs, *dependent)
 
-	cliRef := c.clients[secRef]
+		if len(cliRef.refs) == 0 {
+			delete(c.clients, secRef)
+		}
+	}
+}
 
-	if dependent == nil || len(cliRef.refs) == 0 {
-		delete(c.clients, secRef)
+// withTokenScope returns the client derived from ghc whose installation tokens are restricted to the scope,
+// or ghc itself when no scope is requested.
+func withTokenScope(ghc *github.Client, scope *v1alpha1.GitHubAPITokenScope) (*github.Client, error) {
+	if scope == nil || (len(scope.Repositories) == 0 && len(scope.Permissions) == 0) {
+		return ghc, nil
 	}
+
+	return ghc.WithTokenScope(scope.Repositories, scope.Permissions)
 }
 
 func secretDataToGitHubClientConfig(data map[string][]byte) (*github.Config, error) {
@@ -336,3 +444,11 @@ func refFromHorizontalRunnerAutoscaler(hra *v1alpha1.HorizontalRunnerAutoscaler)
 		name: hra.Name,
 	}
 }
+
+func refFromMaintenanceWindow(mw *v1alpha1.MaintenanceWindow) *runnerOwnerRef {
+	return &runnerOwnerRef{
+		kind: mw.Kind,
+		ns:   mw.Namespace,
+		name: mw.Name,
+	}
+}
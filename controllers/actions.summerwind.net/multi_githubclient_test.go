@@ -0,0 +1,102 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMultiGitHubClientWithGitHubCredential(t *testing.T) {
+	ctx := context.Background()
+
+	rr := &testResourceReader{
+		objects: map[types.NamespacedName]client.Object{
+			{Namespace: "tenant-a", Name: "creds"}: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "creds"},
+				Data:       map[string][]byte{"github_token": []byte("tenant-a-token")},
+			},
+			{Namespace: "tenant-a", Name: "default"}: &v1alpha1.GitHubCredential{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "default"},
+				Spec: v1alpha1.GitHubCredentialSpec{
+					SecretRef:     v1alpha1.SecretReference{Name: "creds"},
+					Organizations: []string{"org-a"},
+					Repositories:  []string{"other/repo"},
+				},
+			},
+		},
+	}
+
+	controllerWide := &github.Client{}
+	multiClient := NewMultiGitHubClient(rr, controllerWide)
+
+	newRunner := func(ns, name string, config v1alpha1.RunnerConfig) *v1alpha1.Runner {
+		return &v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Spec:       v1alpha1.RunnerSpec{RunnerConfig: config},
+		}
+	}
+
+	// The runners in the namespace use the credentials of the GitHubCredential
+	allowed := newRunner("tenant-a", "org", v1alpha1.RunnerConfig{Organization: "ORG-A"})
+	ghc, err := multiClient.InitForRunner(ctx, allowed)
+	require.NoError(t, err)
+	require.NotSame(t, controllerWide, ghc)
+	require.Contains(t, multiClient.clients, secretRef{ns: "tenant-a", name: "creds"})
+
+	_, err = multiClient.InitForRunner(ctx, newRunner("tenant-a", "repo", v1alpha1.RunnerConfig{Repository: "org-a/repo"}))
+	require.NoError(t, err)
+
+	_, err = multiClient.InitForRunner(ctx, newRunner("tenant-a", "other-repo", v1alpha1.RunnerConfig{Repository: "other/repo"}))
+	require.NoError(t, err)
+
+	// The runners in the namespace can't be registered to the GitHub resources of another tenant
+	_, err = multiClient.InitForRunner(ctx, newRunner("tenant-a", "org-b", v1alpha1.RunnerConfig{Organization: "org-b"}))
+	require.ErrorContains(t, err, "doesn't allow registering runners to the organization org-b")
+
+	_, err = multiClient.InitForRunner(ctx, newRunner("tenant-a", "ent", v1alpha1.RunnerConfig{Enterprise: "ent"}))
+	require.ErrorContains(t, err, "doesn't allow registering runners to the enterprise ent")
+
+	// The namespaces without GitHubCredential use the controller-wide credentials unless required
+	ghc, err = multiClient.InitForRunner(ctx, newRunner("tenant-b", "org-b", v1alpha1.RunnerConfig{Organization: "org-b"}))
+	require.NoError(t, err)
+	require.Same(t, controllerWide, ghc)
+
+	multiClient.RequireGitHubCredential = true
+
+	_, err = multiClient.InitForRunner(ctx, newRunner("tenant-b", "org-b", v1alpha1.RunnerConfig{Organization: "org-b"}))
+	require.ErrorContains(t, err, "namespace tenant-b has no GitHubCredential")
+
+	// The client is released once all the runners referencing it are gone
+	multiClient.DeinitForRunner(allowed)
+	require.Contains(t, multiClient.clients, secretRef{ns: "tenant-a", name: "creds"})
+
+	for _, name := range []string{"repo", "other-repo"} {
+		multiClient.DeinitForRunner(newRunner("tenant-a", name, v1alpha1.RunnerConfig{}))
+	}
+	require.NotContains(t, multiClient.clients, secretRef{ns: "tenant-a", name: "creds"})
+}
+
+func TestGitHubCredentialSpecAllows(t *testing.T) {
+	spec := v1alpha1.GitHubCredentialSpec{
+		Enterprises:   []string{"ent"},
+		Organizations: []string{"org"},
+		Repositories:  []string{"owner/repo"},
+	}
+
+	require.True(t, spec.Allows("ENT", "", ""))
+	require.True(t, spec.Allows("", "org", ""))
+	require.True(t, spec.Allows("", "", "org/any"))
+	require.True(t, spec.Allows("", "", "Owner/Repo"))
+	require.False(t, spec.Allows("other", "", ""))
+	require.False(t, spec.Allows("", "owner", ""))
+	require.False(t, spec.Allows("", "", "owner/other"))
+	// The enterprise takes precedence like in the runner registration
+	require.False(t, spec.Allows("other", "org", ""))
+}
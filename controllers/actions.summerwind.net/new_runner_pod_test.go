@@ -1,18 +1,21 @@
 package actionssummerwindnet
 
 import (
+	"context"
 	"testing"
 
 	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/github"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func newRunnerPod(template corev1.Pod, runnerSpec arcv1alpha1.RunnerConfig, githubBaseURL string, d RunnerPodDefaults) (corev1.Pod, error) {
@@ -1273,3 +1276,334 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRunnerPodWithPodTemplatePatch(t *testing.T) {
+	patch := `{
+  "metadata": {"labels": {"team": "platform"}},
+  "spec": {
+    "runtimeClassName": "gvisor",
+    "priorityClassName": "runners",
+    "containers": [{"name": "runner", "env": [{"name": "FOO", "value": "bar"}]}]
+  }
+}`
+
+	pod, err := newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{
+		Repository:       "test/valid",
+		PodTemplatePatch: &runtime.RawExtension{Raw: []byte(patch)},
+	}, "https://github.com", RunnerPodDefaults{})
+	require.NoError(t, err)
+
+	require.Equal(t, "platform", pod.Labels["team"])
+	require.Equal(t, "gvisor", *pod.Spec.RuntimeClassName)
+	require.Equal(t, "runners", pod.Spec.PriorityClassName)
+
+	var runner *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == "runner" {
+			runner = &pod.Spec.Containers[i]
+		}
+	}
+	require.NotNil(t, runner)
+	require.Contains(t, runner.Env, corev1.EnvVar{Name: "FOO", Value: "bar"})
+	require.Contains(t, runner.Env, corev1.EnvVar{Name: "RUNNER_REPO", Value: "test/valid"})
+
+	_, err = newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{
+		Repository:       "test/valid",
+		PodTemplatePatch: &runtime.RawExtension{Raw: []byte(`{"spec": {"containers": "invalid"}}`)},
+	}, "https://github.com", RunnerPodDefaults{})
+	require.Error(t, err)
+}
+
+func TestNewRunnerPodWithSidecars(t *testing.T) {
+	pod, err := newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+		},
+	}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		Sidecars: []corev1.Container{
+			{Name: "fluent-bit", Image: "fluent/fluent-bit"},
+			{Name: "vault-agent", Image: "hashicorp/vault"},
+		},
+	}, "https://github.com", RunnerPodDefaults{})
+	require.NoError(t, err)
+
+	require.Len(t, pod.Spec.InitContainers, 3)
+	require.Equal(t, "init", pod.Spec.InitContainers[0].Name)
+	require.Nil(t, pod.Spec.InitContainers[0].RestartPolicy)
+
+	for i, name := range []string{"fluent-bit", "vault-agent"} {
+		c := pod.Spec.InitContainers[i+1]
+		require.Equal(t, name, c.Name)
+		require.NotNil(t, c.RestartPolicy)
+		require.Equal(t, corev1.ContainerRestartPolicyAlways, *c.RestartPolicy)
+	}
+
+	for _, c := range pod.Spec.Containers {
+		require.NotEqual(t, "fluent-bit", c.Name)
+	}
+}
+
+func TestNewRunnerPodWithLabelNodeSelectors(t *testing.T) {
+	d := RunnerPodDefaults{
+		LabelNodeSelectors: map[string]map[string]string{
+			"gpu": {"nodepool": "gpu"},
+		},
+		DeriveNodeSelectorFromLabels: true,
+	}
+
+	pod, err := newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{"nodepool": "explicit"},
+		},
+	}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		Labels:     []string{"GPU", "ARM64", "x64", "custom"},
+	}, "https://github.com", d)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		// The explicit node selector takes precedence over the one mapped from the label
+		"nodepool": "explicit",
+		// x64 comes after ARM64
+		corev1.LabelArchStable: "arm64",
+	}, pod.Spec.NodeSelector)
+
+	d.DeriveNodeSelectorFromLabels = false
+
+	pod, err = newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		Labels:     []string{"gpu", "linux"},
+	}, "https://github.com", d)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{"nodepool": "gpu"}, pod.Spec.NodeSelector)
+}
+
+func TestNewRunnerPodWithActionsCacheURL(t *testing.T) {
+	d := RunnerPodDefaults{ActionsCacheURL: "http://actions-cache-server.actions-runner-system:3000/"}
+
+	pod, err := newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+
+	require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "ACTIONS_CACHE_URL", Value: "http://actions-cache-server.actions-runner-system:3000/"})
+	require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "ACTIONS_RESULTS_URL", Value: "http://actions-cache-server.actions-runner-system:3000/"})
+
+	// The env of the runner container takes precedence
+	pod, err = newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "runner",
+					Env:  []corev1.EnvVar{{Name: "ACTIONS_CACHE_URL", Value: "http://cache.example.com/"}},
+				},
+			},
+		},
+	}, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+
+	var cacheURLs []string
+	for _, e := range pod.Spec.Containers[0].Env {
+		if e.Name == "ACTIONS_CACHE_URL" {
+			cacheURLs = append(cacheURLs, e.Value)
+		}
+	}
+	require.Equal(t, []string{"http://cache.example.com/"}, cacheURLs)
+
+	// The sidecars don't run the jobs
+	for _, e := range pod.Spec.Containers[1].Env {
+		require.NotEqual(t, "ACTIONS_CACHE_URL", e.Name)
+	}
+}
+
+func TestParseLabelNodeSelector(t *testing.T) {
+	label, key, value, err := ParseLabelNodeSelector("GPU:cloud.google.com/gke-accelerator=nvidia-tesla-t4")
+	require.NoError(t, err)
+	require.Equal(t, "gpu", label)
+	require.Equal(t, "cloud.google.com/gke-accelerator", key)
+	require.Equal(t, "nvidia-tesla-t4", value)
+
+	for _, s := range []string{"gpu", "gpu:nodepool", ":nodepool=gpu", "gpu:=gpu"} {
+		_, _, _, err := ParseLabelNodeSelector(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNewRunnerPodForWindows(t *testing.T) {
+	d := RunnerPodDefaults{
+		RunnerImage:        "linux-runner-image",
+		WindowsRunnerImage: "windows-runner-image",
+		DockerImage:        "docker-image",
+	}
+
+	pod, err := newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "docker"},
+			},
+		},
+	}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		OS:         "windows",
+	}, "https://github.com", d)
+	require.NoError(t, err)
+
+	require.Equal(t, &corev1.PodOS{Name: corev1.Windows}, pod.Spec.OS)
+	require.Equal(t, "windows", pod.Spec.NodeSelector[corev1.LabelOSStable])
+
+	// No dockerd sidecar, as Windows containers can't run privileged
+	require.Len(t, pod.Spec.Containers, 1)
+
+	runner := pod.Spec.Containers[0]
+	require.Equal(t, "runner", runner.Name)
+	require.Equal(t, "windows-runner-image", runner.Image)
+	require.Equal(t, windowsRunnerCommand, runner.Command)
+	require.Nil(t, runner.SecurityContext.Privileged)
+	require.Equal(t, "ContainerUser", *runner.SecurityContext.WindowsOptions.RunAsUserName)
+	require.Contains(t, runner.Env, corev1.EnvVar{Name: "RUNNER_WORKDIR", Value: `C:\runner\_work`})
+	require.Contains(t, runner.Env, corev1.EnvVar{Name: "DOCKER_ENABLED", Value: "false"})
+	require.Equal(t, []corev1.VolumeMount{{Name: "runner", MountPath: `C:\runner`}}, runner.VolumeMounts)
+
+	// Explicit settings are left as is
+	pod, err = newRunnerPod(corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{
+				"node.kubernetes.io/windows-build": "10.0.20348",
+			},
+			Containers: []corev1.Container{
+				{
+					Name:    "runner",
+					Image:   "custom",
+					Command: []string{"custom.cmd"},
+				},
+			},
+		},
+	}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		OS:         "windows",
+	}, "https://github.com", d)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"node.kubernetes.io/windows-build": "10.0.20348",
+		corev1.LabelOSStable:               "windows",
+	}, pod.Spec.NodeSelector)
+	require.Equal(t, "custom", pod.Spec.Containers[0].Image)
+	require.Equal(t, []string{"custom.cmd"}, pod.Spec.Containers[0].Command)
+}
+
+func TestNewRunnerPodWithContainerModes(t *testing.T) {
+	d := RunnerPodDefaults{
+		RunnerImage:   "runner-image",
+		DockerImage:   "docker-image",
+		BuildkitImage: "buildkit-image",
+	}
+
+	template := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "docker"},
+			},
+		},
+	}
+
+	containerNames := func(pod corev1.Pod) []string {
+		var names []string
+		for _, c := range pod.Spec.Containers {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	// sysbox runs dockerd within the unprivileged runner container
+	pod, err := newRunnerPodWithContainerMode("sysbox", template, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+	require.Equal(t, []string{"runner"}, containerNames(pod))
+	require.Equal(t, "sysbox-runc", *pod.Spec.RuntimeClassName)
+	require.Nil(t, pod.Spec.Containers[0].SecurityContext.Privileged)
+	require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "DOCKERD_IN_RUNNER", Value: "true"})
+
+	// kata keeps the privileged docker sidecar, confined to the VM
+	pod, err = newRunnerPodWithContainerMode("kata", template, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+	require.Equal(t, []string{"runner", "docker"}, containerNames(pod))
+	require.Equal(t, "kata", *pod.Spec.RuntimeClassName)
+	require.True(t, *pod.Spec.Containers[1].SecurityContext.Privileged)
+
+	// An explicit runtime class takes precedence
+	custom := *template.DeepCopy()
+	custom.Spec.RuntimeClassName = strPtr("kata-qemu")
+	pod, err = newRunnerPodWithContainerMode("kata", custom, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+	require.Equal(t, "kata-qemu", *pod.Spec.RuntimeClassName)
+
+	// buildkit replaces dockerd with the rootless buildkitd sidecar
+	pod, err = newRunnerPodWithContainerMode("buildkit", template, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.NoError(t, err)
+	require.Equal(t, []string{"runner", "buildkitd"}, containerNames(pod))
+	require.Nil(t, pod.Spec.RuntimeClassName)
+	require.Equal(t, "buildkit-image", pod.Spec.Containers[1].Image)
+	require.Nil(t, pod.Spec.Containers[1].SecurityContext.Privileged)
+	require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "BUILDKIT_HOST", Value: buildkitAddr})
+	require.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "DOCKER_ENABLED", Value: "false"})
+
+	_, err = newRunnerPodWithContainerMode("podman", template, arcv1alpha1.RunnerConfig{Repository: "test/valid"}, "https://github.com", d)
+	require.Error(t, err)
+}
+
+func TestValidateContainerModePrerequisites(t *testing.T) {
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(&nodev1.RuntimeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "sysbox-runc"},
+		Handler:    "sysbox-runc",
+	}).Build()
+
+	pod := func(runtimeClassName string) *corev1.Pod {
+		return &corev1.Pod{Spec: corev1.PodSpec{RuntimeClassName: &runtimeClassName}}
+	}
+
+	require.NoError(t, validateContainerModePrerequisites(context.Background(), c, "sysbox", pod("sysbox-runc")))
+	require.NoError(t, validateContainerModePrerequisites(context.Background(), c, "buildkit", &corev1.Pod{}))
+
+	err := validateContainerModePrerequisites(context.Background(), c, "kata", pod("kata"))
+	require.ErrorContains(t, err, `the kata container mode requires the runtime class "kata"`)
+}
+
+func TestNewRunnerPodWithGPU(t *testing.T) {
+	pod, err := newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		GPU:        &arcv1alpha1.RunnerGPU{Count: 2, MIGProfile: "1g.5gb"},
+	}, "https://github.com", RunnerPodDefaults{})
+	require.NoError(t, err)
+
+	require.Equal(t, "nvidia", *pod.Spec.RuntimeClassName)
+	require.Equal(t, int64(360), *pod.Spec.TerminationGracePeriodSeconds)
+	require.Contains(t, pod.Spec.Tolerations, corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule})
+
+	for _, c := range pod.Spec.Containers {
+		switch c.Name {
+		case "runner":
+			require.Equal(t, int64(2), c.Resources.Limits.Name("nvidia.com/mig-1g.5gb", resource.DecimalSI).Value())
+			require.Contains(t, c.Env, corev1.EnvVar{Name: "NVIDIA_DRIVER_CAPABILITIES", Value: "compute,utility"})
+			require.Contains(t, c.Env, corev1.EnvVar{Name: "RUNNER_GPU_DRAIN_TIMEOUT", Value: "300"})
+		case "docker":
+			// The docker sidecar doesn't see the GPUs of the node
+			require.Contains(t, c.Env, corev1.EnvVar{Name: "NVIDIA_VISIBLE_DEVICES", Value: "void"})
+		}
+	}
+
+	noRuntimeClass := ""
+	pod, err = newRunnerPod(corev1.Pod{}, arcv1alpha1.RunnerConfig{
+		Repository: "test/valid",
+		GPU:        &arcv1alpha1.RunnerGPU{Count: 1, Vendor: "amd", RuntimeClassName: &noRuntimeClass},
+	}, "https://github.com", RunnerPodDefaults{})
+	require.NoError(t, err)
+
+	require.Nil(t, pod.Spec.RuntimeClassName)
+	require.Nil(t, pod.Spec.TerminationGracePeriodSeconds)
+	require.Equal(t, int64(1), pod.Spec.Containers[0].Resources.Limits.Name("amd.com/gpu", resource.DecimalSI).Value())
+}
@@ -0,0 +1,324 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultOfflineRunnerCollectionInterval = 10 * time.Minute
+)
+
+// OfflineRunnerCollector periodically removes the runners that disappeared uncleanly, like on node loss or OOMKill, from GitHub.
+// Such runners stay listed as offline in GitHub forever, cluttering the UI and the ListRunners responses PercentageRunnersBusy relies on.
+//
+// A runner is removed only when it is named after a RunnerDeployment or a RunnerSet, has all the labels of it,
+// has neither the Runner nor the runner pod of the same name in the namespace, and has been seen offline for the grace period.
+type OfflineRunnerCollector struct {
+	Client       client.Client
+	Log          logr.Logger
+	GitHubClient *MultiGitHubClient
+
+	// Interval is the interval between collections.
+	Interval time.Duration
+
+	// GracePeriod is how long a runner needs to be seen offline before it's removed.
+	// The collector is disabled when it's zero.
+	GracePeriod time.Duration
+
+	// offlineSince maps the keys of the offline runners to when they were first seen offline.
+	offlineSince map[string]time.Time
+}
+
+// offlineRunnerOwner is a RunnerDeployment or a RunnerSet whose runners are subject to the collection.
+type offlineRunnerOwner struct {
+	kind, namespace, name string
+
+	enterprise, organization, repository string
+
+	labels []string
+
+	ghc *github.Client
+}
+
+// offlineRunnerOwnerKinds maps the kinds of the owners to the kinds of the resources recorded in the audit log.
+var offlineRunnerOwnerKinds = map[string]string{
+	"runnerdeployment": "RunnerDeployment",
+	"runnerset":        "RunnerSet",
+}
+
+func (c *OfflineRunnerCollector) SetupWithManager(mgr ctrl.Manager) error {
+	if c.GracePeriod <= 0 {
+		return nil
+	}
+
+	return mgr.Add(c)
+}
+
+// Start runs the collection every interval until the context is canceled.
+// It runs only on the leader, as the controller-runtime manager runs runnables that don't opt out of the leader election only on the leader.
+func (c *OfflineRunnerCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOfflineRunnerCollectionInterval
+	}
+
+	c.Log.Info("Starting offline runner collector", "interval", interval, "gracePeriod", c.GracePeriod)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.collect(ctx, time.Now()); err != nil {
+				c.Log.Error(err, "Failed to collect offline runners")
+			}
+		}
+	}
+}
+
+func (c *OfflineRunnerCollector) collect(ctx context.Context, now time.Time) error {
+	owners, err := c.listOwners(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.offlineSince == nil {
+		c.offlineSince = map[string]time.Time{}
+	}
+
+	seen := map[string]struct{}{}
+
+	// Multiple owners in the same scope share the runners listed in GitHub
+	byScope := map[string][]offlineRunnerOwner{}
+	var scopes []string
+
+	for _, o := range owners {
+		scope := fmt.Sprintf("url=%s,enterprise=%s,organization=%s,repository=%s", o.ghc.GithubBaseURL, o.enterprise, o.organization, o.repository)
+		if _, ok := byScope[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		byScope[scope] = append(byScope[scope], o)
+	}
+
+	for _, scope := range scopes {
+		scopeOwners := byScope[scope]
+		o := scopeOwners[0]
+
+		log := c.Log.WithValues("enterprise", o.enterprise, "organization", o.organization, "repository", o.repository)
+
+		runners, err := o.ghc.ListRunners(github.WithRequestPriority(ctx, github.PriorityLow), o.enterprise, o.organization, o.repository)
+		if err != nil {
+			log.Error(err, "Failed to list runners")
+			continue
+		}
+
+		for _, runner := range runners {
+			if runner.GetStatus() != "offline" {
+				continue
+			}
+
+			owner := findOfflineRunnerOwner(scopeOwners, runner)
+			if owner == nil {
+				continue
+			}
+
+			exists, err := c.runnerExists(ctx, owner.namespace, runner.GetName())
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				continue
+			}
+
+			key := fmt.Sprintf("%s,id=%d", scope, runner.GetID())
+			seen[key] = struct{}{}
+
+			since, ok := c.offlineSince[key]
+			if !ok {
+				c.offlineSince[key] = now
+				continue
+			}
+
+			if now.Sub(since) < c.GracePeriod {
+				continue
+			}
+
+			rlog := log.WithValues("runner", runner.GetName(), "runnerID", runner.GetID(), owner.kind, owner.namespace+"/"+owner.name)
+
+			actor := types.NamespacedName{Namespace: owner.namespace, Name: owner.name}
+			if err := owner.ghc.RemoveRunner(audit.WithActor(ctx, offlineRunnerOwnerKinds[owner.kind], actor), owner.enterprise, owner.organization, owner.repository, runner.GetID()); err != nil {
+				rlog.Error(err, "Failed to remove offline runner")
+				continue
+			}
+
+			delete(c.offlineSince, key)
+
+			metrics.IncOfflineRunnersRemoved(owner.namespace, owner.enterprise, owner.organization, owner.repository)
+
+			rlog.Info("Removed offline runner", "offlineSince", since)
+		}
+	}
+
+	// Forget the runners that came back online or have been removed by someone else
+	for key := range c.offlineSince {
+		if _, ok := seen[key]; !ok {
+			delete(c.offlineSince, key)
+		}
+	}
+
+	return nil
+}
+
+func (c *OfflineRunnerCollector) listOwners(ctx context.Context) ([]offlineRunnerOwner, error) {
+	var owners []offlineRunnerOwner
+
+	var rds v1alpha1.RunnerDeploymentList
+	if err := c.Client.List(ctx, &rds); err != nil {
+		return nil, err
+	}
+
+	for _, rd := range rds.Items {
+		spec := rd.Spec.Template.Spec
+
+		var secretName string
+		if spec.GitHubAPICredentialsFrom != nil {
+			secretName = spec.GitHubAPICredentialsFrom.SecretRef.Name
+		}
+
+		// The client isn't referenced by the RunnerDeployment, as nothing would release it on the RunnerDeployment's deletion
+		ghc, err := c.GitHubClient.initClientForNamespace(ctx, rd.Namespace, secretName, spec.Enterprise, spec.Organization, spec.Repository, nil)
+		if err != nil {
+			c.Log.Error(err, "Failed to initialize GitHub client", "runnerdeployment", rd.Namespace+"/"+rd.Name)
+			continue
+		}
+
+		ghc, err = withTokenScope(ghc, spec.GitHubAPITokenScope)
+		if err != nil {
+			c.Log.Error(err, "Failed to initialize GitHub client", "runnerdeployment", rd.Namespace+"/"+rd.Name)
+			continue
+		}
+
+		owners = append(owners, offlineRunnerOwner{
+			kind:         "runnerdeployment",
+			namespace:    rd.Namespace,
+			name:         rd.Name,
+			enterprise:   spec.Enterprise,
+			organization: spec.Organization,
+			repository:   spec.Repository,
+			labels:       spec.Labels,
+			ghc:          ghc,
+		})
+	}
+
+	var runnerSets v1alpha1.RunnerSetList
+	if err := c.Client.List(ctx, &runnerSets); err != nil {
+		return nil, err
+	}
+
+	for i := range runnerSets.Items {
+		rs := &runnerSets.Items[i]
+
+		ghc, err := c.GitHubClient.InitForRunnerSet(ctx, rs)
+		if err != nil {
+			c.Log.Error(err, "Failed to initialize GitHub client", "runnerset", rs.Namespace+"/"+rs.Name)
+			continue
+		}
+
+		owners = append(owners, offlineRunnerOwner{
+			kind:         "runnerset",
+			namespace:    rs.Namespace,
+			name:         rs.Name,
+			enterprise:   rs.Spec.Enterprise,
+			organization: rs.Spec.Organization,
+			repository:   rs.Spec.Repository,
+			labels:       rs.Spec.Labels,
+			ghc:          ghc,
+		})
+	}
+
+	return owners, nil
+}
+
+// findOfflineRunnerOwner returns the owner the runner is named after, or nil when the runner doesn't have all the labels of the owner.
+// The owner of the longest name wins so that the runners of "example-foo" aren't attributed to "example".
+func findOfflineRunnerOwner(owners []offlineRunnerOwner, runner *gogithub.Runner) *offlineRunnerOwner {
+	var found *offlineRunnerOwner
+
+	for i := range owners {
+		o := &owners[i]
+
+		// Both runners of RunnerDeployments and RunnerSets are named "<name>-<random suffix>"
+		if !strings.HasPrefix(runner.GetName(), o.name+"-") {
+			continue
+		}
+
+		if found == nil || len(o.name) > len(found.name) {
+			found = o
+		}
+	}
+
+	if found == nil || !runnerHasLabels(runner, found.labels) {
+		return nil
+	}
+
+	return found
+}
+
+func runnerHasLabels(runner *gogithub.Runner, labels []string) bool {
+	for _, want := range labels {
+		var ok bool
+
+		for _, l := range runner.Labels {
+			if strings.EqualFold(l.GetName(), want) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runnerExists returns true when either the Runner or the runner pod of the name exists,
+// in which case the runner controller or the runner pod controller is responsible for removing the runner.
+func (c *OfflineRunnerCollector) runnerExists(ctx context.Context, namespace, name string) (bool, error) {
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+
+	var runner v1alpha1.Runner
+	if err := c.Client.Get(ctx, key, &runner); err == nil {
+		return true, nil
+	} else if client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+
+	var pod corev1.Pod
+	if err := c.Client.Get(ctx, key, &pod); err == nil {
+		return true, nil
+	} else if client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+
+	return false, nil
+}
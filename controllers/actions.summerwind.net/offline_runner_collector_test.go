@@ -0,0 +1,112 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestOfflineRunnerCollector(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	newRunnerDeployment := func(name string, labels ...string) *v1alpha1.RunnerDeployment {
+		rd := &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+		}
+		rd.Spec.Template.Spec.Organization = "test"
+		rd.Spec.Template.Spec.Labels = labels
+		return rd
+	}
+
+	runnersList := fake.NewRunnersList()
+
+	addRunner := func(id int64, name, status string, labels ...string) {
+		runner := &gogithub.Runner{
+			ID:     gogithub.Int64(id),
+			Name:   gogithub.String(name),
+			OS:     gogithub.String("linux"),
+			Status: gogithub.String(status),
+			Busy:   gogithub.Bool(false),
+		}
+		for _, l := range append([]string{"self-hosted", "linux", "x64"}, labels...) {
+			runner.Labels = append(runner.Labels, &gogithub.RunnerLabels{Name: gogithub.String(l)})
+		}
+		runnersList.Add(runner)
+	}
+
+	addRunner(1, "example-abcde-fghij", "offline", "gpu")
+	addRunner(2, "example-abcde-klmno", "online", "gpu")
+	// The Runner still exists and the runner controller is responsible for removing it
+	addRunner(3, "example-abcde-pqrst", "offline", "gpu")
+	// Lacks the labels of the RunnerDeployment
+	addRunner(4, "example-abcde-uvwxy", "offline")
+	// Belongs to the RunnerDeployment of the longer name, which requires the label
+	addRunner(5, "example-foo-abcde-fghij", "offline", "gpu")
+	// Not managed by ARC
+	addRunner(6, "my-laptop", "offline", "gpu")
+
+	server := runnersList.GetServer()
+	defer server.Close()
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde-pqrst",
+			Namespace: "default",
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		newRunnerDeployment("example", "gpu"),
+		newRunnerDeployment("example-foo", "arm64"),
+		runner,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}},
+	).Build()
+
+	collector := &OfflineRunnerCollector{
+		Client:       c,
+		Log:          logr.Discard(),
+		GitHubClient: NewMultiGitHubClient(c, newGithubClient(server)),
+		GracePeriod:  time.Hour,
+	}
+
+	listRunnerIDs := func() []int64 {
+		runners, err := newGithubClient(server).ListRunners(ctx, "", "test", "")
+		require.NoError(t, err)
+
+		var ids []int64
+		for _, r := range runners {
+			ids = append(ids, r.GetID())
+		}
+		return ids
+	}
+
+	now := time.Now()
+
+	require.NoError(t, collector.collect(ctx, now))
+	require.Equal(t, []int64{1, 2, 3, 4, 5, 6}, listRunnerIDs(), "no runner is removed until the grace period passes")
+
+	require.NoError(t, collector.collect(ctx, now.Add(30*time.Minute)))
+	require.Equal(t, []int64{1, 2, 3, 4, 5, 6}, listRunnerIDs())
+
+	require.NoError(t, collector.collect(ctx, now.Add(time.Hour)))
+	require.Equal(t, []int64{2, 3, 4, 5, 6}, listRunnerIDs())
+	require.Empty(t, collector.offlineSince)
+}
@@ -0,0 +1,236 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultOrphanedWorkflowResourceCollectionInterval = 5 * time.Minute
+
+	// LabelKeyContainerHookRunnerPod is the label the kubernetes container hooks put onto the workflow pods and secrets they create,
+	// whose value is the name of the runner pod.
+	LabelKeyContainerHookRunnerPod = "runner-pod"
+
+	// LabelKeyRunnerPod is the label for the resources the jobs create by themselves, like the services and the volume claims
+	// created with kubectl from the docker sidecar, whose value is the name of the runner pod, so that they are cleaned up along with it.
+	LabelKeyRunnerPod = annotationKeyPrefix + "runner-pod"
+)
+
+// OrphanedWorkflowResourceCollector periodically deletes the workflow pods, services, secrets, and persistent volume claims
+// whose runner pods no longer exist. The container hooks delete them at the end of the jobs,
+// but they leak when the runner pods crash, are evicted, or get lost with their nodes in the middle of the jobs.
+//
+// A resource is deleted when it has the runner pod label, the runner pod of the name doesn't exist in the namespace,
+// or has been recreated after the resource, and it has been seen orphaned for the grace period.
+type OrphanedWorkflowResourceCollector struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// Namespace limits the collection to the namespace. All the namespaces are looked into when it's empty.
+	Namespace string
+
+	// Interval is the interval between collections.
+	Interval time.Duration
+
+	// GracePeriod is how long a resource needs to be seen orphaned before it's deleted.
+	// The collector is disabled when it's zero.
+	GracePeriod time.Duration
+
+	// orphanedSince maps the keys of the orphaned resources to when they were first seen orphaned.
+	orphanedSince map[string]time.Time
+}
+
+// orphanedWorkflowResourceKinds are the kinds of the resources subject to the collection, keyed by the kind recorded in the metrics.
+var orphanedWorkflowResourceKinds = map[string]func() client.ObjectList{
+	"Pod":                   func() client.ObjectList { return &corev1.PodList{} },
+	"Service":               func() client.ObjectList { return &corev1.ServiceList{} },
+	"Secret":                func() client.ObjectList { return &corev1.SecretList{} },
+	"PersistentVolumeClaim": func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} },
+}
+
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;delete
+
+func (c *OrphanedWorkflowResourceCollector) SetupWithManager(mgr ctrl.Manager) error {
+	if c.GracePeriod <= 0 {
+		return nil
+	}
+
+	return mgr.Add(c)
+}
+
+// Start runs the collection every interval until the context is canceled.
+// Like OfflineRunnerCollector, it runs only on the leader.
+func (c *OrphanedWorkflowResourceCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanedWorkflowResourceCollectionInterval
+	}
+
+	c.Log.Info("Starting orphaned workflow resource collector", "interval", interval, "gracePeriod", c.GracePeriod)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.collect(ctx, time.Now()); err != nil {
+				c.Log.Error(err, "Failed to collect orphaned workflow resources")
+			}
+		}
+	}
+}
+
+func (c *OrphanedWorkflowResourceCollector) collect(ctx context.Context, now time.Time) error {
+	if c.orphanedSince == nil {
+		c.orphanedSince = map[string]time.Time{}
+	}
+
+	seen := map[string]struct{}{}
+
+	// The orphans are counted per namespace and kind, including zeros, so that the gauges drop back once they are deleted
+	counts := map[string]map[string]int{}
+
+	for kind, newList := range orphanedWorkflowResourceKinds {
+		for _, labelKey := range []string{LabelKeyContainerHookRunnerPod, LabelKeyRunnerPod} {
+			list := newList()
+
+			opts := []client.ListOption{client.HasLabels{labelKey}}
+			if c.Namespace != "" {
+				opts = append(opts, client.InNamespace(c.Namespace))
+			}
+
+			if err := c.Client.List(ctx, list, opts...); err != nil {
+				return fmt.Errorf("listing %s resources with the %s label: %w", kind, labelKey, err)
+			}
+
+			objs, err := metaObjects(list)
+			if err != nil {
+				return err
+			}
+
+			for _, obj := range objs {
+				if _, ok := counts[obj.GetNamespace()]; !ok {
+					counts[obj.GetNamespace()] = map[string]int{}
+				}
+
+				if _, ok := counts[obj.GetNamespace()][kind]; !ok {
+					counts[obj.GetNamespace()][kind] = 0
+				}
+
+				// The UID tells the resource apart from another one of the same name created after the deletion
+				key := fmt.Sprintf("%s/%s/%s/%s", kind, obj.GetNamespace(), obj.GetName(), obj.GetUID())
+
+				if _, ok := seen[key]; ok || obj.GetDeletionTimestamp() != nil {
+					continue
+				}
+
+				orphaned, err := c.isOrphaned(ctx, obj, obj.GetLabels()[labelKey])
+				if err != nil {
+					return err
+				}
+
+				if !orphaned {
+					continue
+				}
+
+				seen[key] = struct{}{}
+				counts[obj.GetNamespace()][kind]++
+
+				since, ok := c.orphanedSince[key]
+				if !ok {
+					c.orphanedSince[key] = now
+					continue
+				}
+
+				if now.Sub(since) < c.GracePeriod {
+					continue
+				}
+
+				log := c.Log.WithValues("kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "runnerPod", obj.GetLabels()[labelKey])
+
+				if err := c.Client.Delete(ctx, obj, client.PropagationPolicy("Background")); client.IgnoreNotFound(err) != nil {
+					log.Error(err, "Failed to delete orphaned workflow resource")
+					continue
+				}
+
+				delete(c.orphanedSince, key)
+				counts[obj.GetNamespace()][kind]--
+
+				metrics.IncOrphanedWorkflowResourcesDeleted(obj.GetNamespace(), kind)
+
+				log.Info("Deleted orphaned workflow resource", "orphanedSince", since)
+			}
+		}
+	}
+
+	// Forget the resources that have been deleted by someone else
+	for key := range c.orphanedSince {
+		if _, ok := seen[key]; !ok {
+			delete(c.orphanedSince, key)
+		}
+	}
+
+	metrics.ResetOrphanedWorkflowResources()
+
+	for ns, kinds := range counts {
+		for kind, n := range kinds {
+			metrics.SetOrphanedWorkflowResources(ns, kind, n)
+		}
+	}
+
+	return nil
+}
+
+// isOrphaned tells if the runner pod of the resource is gone.
+// A runner pod of the same name created after the resource, like a RunnerSet pod recreated by the StatefulSet, doesn't own it.
+func (c *OrphanedWorkflowResourceCollector) isOrphaned(ctx context.Context, obj client.Object, runnerPodName string) (bool, error) {
+	if runnerPodName == "" {
+		return false, nil
+	}
+
+	var pod corev1.Pod
+	if err := c.Client.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: runnerPodName}, &pod); err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	created := obj.GetCreationTimestamp()
+
+	return created.Before(&pod.CreationTimestamp), nil
+}
+
+func metaObjects(list client.ObjectList) ([]client.Object, error) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []client.Object
+
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected item type %T", item)
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
@@ -0,0 +1,83 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestOrphanedWorkflowResourceCollector(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	now := time.Now()
+
+	meta := func(name string, created time.Time, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(created),
+		}
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		&corev1.Pod{ObjectMeta: meta("runner-a", now.Add(-time.Hour), nil)},
+		// The runner pod recreated by the StatefulSet of a RunnerSet
+		&corev1.Pod{ObjectMeta: meta("runner-b", now.Add(-time.Minute), nil)},
+		&corev1.Pod{ObjectMeta: meta("runner-a-workflow", now.Add(-30*time.Minute), map[string]string{"runner-pod": "runner-a"})},
+		&corev1.Pod{ObjectMeta: meta("runner-b-workflow", now.Add(-30*time.Minute), map[string]string{"runner-pod": "runner-b"})},
+		&corev1.Pod{ObjectMeta: meta("runner-c-workflow", now.Add(-30*time.Minute), map[string]string{"runner-pod": "runner-c"})},
+		&corev1.Secret{ObjectMeta: meta("runner-c-workflow-env", now.Add(-30*time.Minute), map[string]string{"runner-pod": "runner-c"})},
+		&corev1.Service{ObjectMeta: meta("runner-a-db", now.Add(-30*time.Minute), map[string]string{"actions-runner/runner-pod": "runner-a"})},
+		&corev1.Service{ObjectMeta: meta("runner-c-db", now.Add(-30*time.Minute), map[string]string{"actions-runner/runner-pod": "runner-c"})},
+		&corev1.PersistentVolumeClaim{ObjectMeta: meta("unrelated", now.Add(-30*time.Minute), nil)},
+	).Build()
+
+	collector := &OrphanedWorkflowResourceCollector{
+		Client:      c,
+		Log:         logr.Discard(),
+		GracePeriod: time.Hour,
+	}
+
+	names := func() []string {
+		var names []string
+		for _, list := range []client.ObjectList{&corev1.PodList{}, &corev1.SecretList{}, &corev1.ServiceList{}, &corev1.PersistentVolumeClaimList{}} {
+			require.NoError(t, c.List(ctx, list))
+
+			objs, err := metaObjects(list)
+			require.NoError(t, err)
+
+			for _, obj := range objs {
+				names = append(names, obj.GetName())
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	all := names()
+
+	require.NoError(t, collector.collect(ctx, now))
+	require.Equal(t, all, names(), "nothing is deleted until the grace period passes")
+	require.Len(t, collector.orphanedSince, 4)
+
+	require.NoError(t, collector.collect(ctx, now.Add(30*time.Minute)))
+	require.Equal(t, all, names())
+
+	require.NoError(t, collector.collect(ctx, now.Add(time.Hour)))
+	require.Equal(t, []string{"runner-a", "runner-a-db", "runner-a-workflow", "runner-b", "unrelated"}, names())
+	require.Empty(t, collector.orphanedSince)
+}
@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/actions/actions-runner-controller/github/audit"
 	"github.com/go-logr/logr"
 	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -70,7 +72,9 @@ func (t *PodRunnerTokenInjector) Handle(ctx context.Context, req admission.Reque
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	rt, err := ghc.GetRegistrationToken(context.Background(), enterprise, org, repo, pod.Name)
+	auditCtx := audit.WithActor(context.Background(), "Pod", types.NamespacedName{Namespace: req.Namespace, Name: pod.Name})
+
+	rt, err := ghc.GetRegistrationToken(auditCtx, enterprise, org, repo, pod.Name)
 	if err != nil {
 		t.Log.Error(err, "Failed to get new registration token")
 		return admission.Errored(http.StatusInternalServerError, err)
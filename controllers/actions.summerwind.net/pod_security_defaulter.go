@@ -0,0 +1,139 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// runnerContainerCapabilities are the capabilities kept in the runner container,
+// required by the entrypoint of the runner images to sudo chown the runner home directory.
+var runnerContainerCapabilities = []corev1.Capability{"CHOWN", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID"}
+
+// PodSecurityDefaulter applies a security baseline to the runner pods, leaving the fields set in the pod template as is.
+// The baseline is skipped for the pods annotated with actions-runner-controller/security-defaults: "false".
+//
+// It's served only with --runner-pod-security-defaults, so its webhook is registered by the Helm chart and
+// the config/runner-pod-security-defaults kustomize component only when enabled, rather than by a kubebuilder marker.
+type PodSecurityDefaulter struct {
+	Log     logr.Logger
+	decoder admission.Decoder
+}
+
+func (d *PodSecurityDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var pod corev1.Pod
+	err := d.decoder.Decode(req, &pod)
+	if err != nil {
+		d.Log.Error(err, "Failed to decode request object")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !applyRunnerPodSecurityDefaults(&pod) {
+		return newEmptyResponse()
+	}
+
+	buf, err := json.Marshal(pod)
+	if err != nil {
+		d.Log.Error(err, "Failed to encode new object")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, buf)
+}
+
+// applyRunnerPodSecurityDefaults sets the unset security fields of the runner pod to the baseline:
+// the RuntimeDefault seccomp profile, no service account token unless the pod runs with its own service account,
+// runAsNonRoot for the containers running as a non-root user, and dropping all the capabilities
+// except the ones the runner container needs. The dockerd sidecar and the privileged containers are left as is.
+// It returns false when the pod isn't a linux runner pod or opted out of the baseline.
+func applyRunnerPodSecurityDefaults(pod *corev1.Pod) bool {
+	if pod.Annotations[AnnotationKeySecurityDefaults] == "false" {
+		return false
+	}
+
+	// Windows containers support neither seccomp nor linux capabilities
+	if pod.Spec.OS != nil && pod.Spec.OS.Name == corev1.Windows {
+		return false
+	}
+
+	var isRunnerPod bool
+	for _, c := range pod.Spec.Containers {
+		if c.Name == containerName {
+			isRunnerPod = true
+		}
+	}
+	if !isRunnerPod {
+		return false
+	}
+
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.Spec.SecurityContext.SeccompProfile == nil {
+		pod.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	}
+
+	// The runners of the kubernetes container mode and with the status update hook run with their own service account to call the API.
+	if pod.Spec.AutomountServiceAccountToken == nil && (pod.Spec.ServiceAccountName == "" || pod.Spec.ServiceAccountName == "default") {
+		automount := false
+		pod.Spec.AutomountServiceAccountToken = &automount
+	}
+
+	for i := range pod.Spec.InitContainers {
+		applyContainerSecurityDefaults(&pod.Spec.InitContainers[i], pod.Spec.SecurityContext)
+	}
+	for i := range pod.Spec.Containers {
+		applyContainerSecurityDefaults(&pod.Spec.Containers[i], pod.Spec.SecurityContext)
+	}
+
+	return true
+}
+
+func applyContainerSecurityDefaults(c *corev1.Container, podSecurityContext *corev1.PodSecurityContext) {
+	// dockerd needs its capabilities whether it's privileged or rootless
+	if c.Name == "docker" {
+		return
+	}
+
+	if c.SecurityContext == nil {
+		c.SecurityContext = &corev1.SecurityContext{}
+	}
+	sc := c.SecurityContext
+
+	if sc.Privileged != nil && *sc.Privileged {
+		return
+	}
+
+	if sc.Capabilities == nil {
+		sc.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+		if c.Name == containerName {
+			sc.Capabilities.Add = append([]corev1.Capability(nil), runnerContainerCapabilities...)
+		}
+	}
+
+	// The kubelet can only verify that a container runs as non-root when its user is numeric,
+	// whereas the runner images set the user by name.
+	if sc.RunAsNonRoot == nil {
+		runAsUser := sc.RunAsUser
+		if runAsUser == nil {
+			runAsUser = podSecurityContext.RunAsUser
+		}
+		if runAsUser != nil && *runAsUser != 0 {
+			runAsNonRoot := true
+			sc.RunAsNonRoot = &runAsNonRoot
+		}
+	}
+}
+
+func (d *PodSecurityDefaulter) SetupWithManager(mgr ctrl.Manager) error {
+	d.decoder = admission.NewDecoder(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register("/mutate-runner-pod-security", &admission.Webhook{Handler: d})
+
+	return nil
+}
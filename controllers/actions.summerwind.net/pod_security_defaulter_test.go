@@ -0,0 +1,89 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyRunnerPodSecurityDefaults(t *testing.T) {
+	boolPtr := func(v bool) *bool { return &v }
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-runner"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "runner"},
+					{Name: "docker", SecurityContext: &corev1.SecurityContext{Privileged: boolPtr(true)}},
+					{Name: "sidecar"},
+				},
+			},
+		}
+	}
+
+	t.Run("baseline", func(t *testing.T) {
+		pod := newPod()
+		require.True(t, applyRunnerPodSecurityDefaults(pod))
+
+		assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, pod.Spec.SecurityContext.SeccompProfile.Type)
+		assert.Equal(t, boolPtr(false), pod.Spec.AutomountServiceAccountToken)
+
+		runner := pod.Spec.Containers[0].SecurityContext
+		assert.Equal(t, []corev1.Capability{"ALL"}, runner.Capabilities.Drop)
+		assert.Equal(t, runnerContainerCapabilities, runner.Capabilities.Add)
+		assert.Nil(t, runner.RunAsNonRoot)
+
+		assert.Equal(t, &corev1.SecurityContext{Privileged: boolPtr(true)}, pod.Spec.Containers[1].SecurityContext)
+
+		sidecar := pod.Spec.Containers[2].SecurityContext
+		assert.Equal(t, &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}, sidecar.Capabilities)
+	})
+
+	t.Run("fields set in the template are kept", func(t *testing.T) {
+		pod := newPod()
+		pod.Spec.ServiceAccountName = "example-runner"
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{
+			RunAsUser:      int64Ptr(1001),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+		}
+		pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+		}
+		pod.Spec.Containers[2].SecurityContext = &corev1.SecurityContext{RunAsUser: int64Ptr(0)}
+
+		require.True(t, applyRunnerPodSecurityDefaults(pod))
+
+		assert.Equal(t, corev1.SeccompProfileTypeUnconfined, pod.Spec.SecurityContext.SeccompProfile.Type)
+		assert.Nil(t, pod.Spec.AutomountServiceAccountToken)
+
+		runner := pod.Spec.Containers[0].SecurityContext
+		assert.Equal(t, &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}}, runner.Capabilities)
+		assert.Equal(t, boolPtr(true), runner.RunAsNonRoot)
+
+		assert.Nil(t, pod.Spec.Containers[2].SecurityContext.RunAsNonRoot)
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		pod := newPod()
+		pod.Annotations = map[string]string{AnnotationKeySecurityDefaults: "false"}
+		assert.False(t, applyRunnerPodSecurityDefaults(pod))
+		assert.Nil(t, pod.Spec.SecurityContext)
+	})
+
+	t.Run("windows runner", func(t *testing.T) {
+		pod := newPod()
+		pod.Spec.OS = &corev1.PodOS{Name: corev1.Windows}
+		assert.False(t, applyRunnerPodSecurityDefaults(pod))
+	})
+
+	t.Run("not a runner pod", func(t *testing.T) {
+		pod := newPod()
+		pod.Spec.Containers = pod.Spec.Containers[1:]
+		assert.False(t, applyRunnerPodSecurityDefaults(pod))
+	})
+}
@@ -0,0 +1,91 @@
+package actionssummerwindnet
+
+import (
+	"context"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+)
+
+// EventReasonPolicyDenied is the reason of the events emitted when the policy hook denies a scale up or a runner registration.
+const EventReasonPolicyDenied = "PolicyDenied"
+
+// policyRepositoryInfo returns the info of the repository for the policy to decide on,
+// or nil when the repository is empty or ARC couldn't get it from GitHub.
+// The policy decides without the info rather than failing, so that the policy can tell how to treat the unknown repositories.
+func policyRepositoryInfo(ctx context.Context, log logr.Logger, hook *policyhook.Hook, ghc *github.Client, repo string) *policyhook.RepositoryInfo {
+	if repo == "" || ghc == nil {
+		return nil
+	}
+
+	info, err := hook.RepositoryInfo(ctx, repo, func(ctx context.Context, repo string) (*policyhook.RepositoryInfo, error) {
+		r, branch, err := ghc.GetRepositoryWithDefaultBranch(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		return &policyhook.RepositoryInfo{
+			Visibility:             r.GetVisibility(),
+			Fork:                   r.GetFork(),
+			Archived:               r.GetArchived(),
+			DefaultBranch:          r.GetDefaultBranch(),
+			DefaultBranchProtected: branch.GetProtected(),
+		}, nil
+	})
+	if err != nil {
+		log.Error(err, "Could not get the repository for the policy hook", "repository", repo)
+
+		return nil
+	}
+
+	return info
+}
+
+// scaleUpPolicyInput is the input of the policy deciding on scaling up the target of the HRA from the current to the desired replicas.
+func scaleUpPolicyInput(hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, current, desired int) policyhook.Input {
+	return policyhook.Input{
+		Action:       policyhook.ActionScaleUp,
+		Resource:     policyhook.Resource{Kind: "HorizontalRunnerAutoscaler", Namespace: hra.Namespace, Name: hra.Name},
+		Enterprise:   st.enterprise,
+		Organization: st.org,
+		Repository:   st.repo,
+		Labels:       st.labels,
+		Replicas:     &policyhook.Replicas{Current: current, Desired: desired},
+	}
+}
+
+// registerPolicyInput is the input of the policy deciding on registering the runner.
+func registerPolicyInput(runner v1alpha1.Runner) policyhook.Input {
+	return policyhook.Input{
+		Action:       policyhook.ActionRegister,
+		Resource:     policyhook.Resource{Kind: "Runner", Namespace: runner.Namespace, Name: runner.Name},
+		Enterprise:   runner.Spec.Enterprise,
+		Organization: runner.Spec.Organization,
+		Repository:   runner.Spec.Repository,
+		Labels:       runner.Spec.Labels,
+	}
+}
+
+// workflowJobPolicyInput is the input of the policy deciding on scaling up the target for the queued workflow job.
+// Unlike the scale ups by the HRA controller, the repository and the workflow are known even for the organizational runners.
+func workflowJobPolicyInput(target *ScaleTarget, e *gogithub.WorkflowJobEvent, enterprise string) policyhook.Input {
+	job := e.GetWorkflowJob()
+
+	return policyhook.Input{
+		Action:       policyhook.ActionScaleUp,
+		Resource:     policyhook.Resource{Kind: "HorizontalRunnerAutoscaler", Namespace: target.Namespace, Name: target.Name},
+		Enterprise:   enterprise,
+		Organization: e.GetRepo().GetOwner().GetLogin(),
+		Repository:   e.GetRepo().GetFullName(),
+		Labels:       job.Labels,
+		Workflow: &policyhook.Workflow{
+			Name:       job.GetWorkflowName(),
+			JobName:    job.GetName(),
+			RunID:      job.GetRunID(),
+			HeadBranch: job.GetHeadBranch(),
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newProtectedBranchPolicyHook(t *testing.T) *policyhook.Hook {
+	t.Helper()
+
+	e, err := policyhook.NewCELEngine([]policyhook.Rule{
+		{
+			Name:       "protected-default-branch",
+			Expression: `has(input.repositoryInfo) && input.repositoryInfo.defaultBranchProtected`,
+			Message:    "the default branch of the repository must be protected",
+		},
+	})
+	require.NoError(t, err)
+
+	return &policyhook.Hook{Engines: []policyhook.Engine{e}}
+}
+
+func TestWebhookWorkflowJobPolicy(t *testing.T) {
+	event := &github.WorkflowJobEvent{
+		WorkflowJob: &github.WorkflowJob{
+			ID:           github.Int64(1),
+			RunID:        github.Int64(123),
+			Labels:       []string{"label1"},
+			WorkflowName: github.String("ci"),
+		},
+		Action: github.String("queued"),
+		Repo: &github.Repository{
+			ID:       github.Int64(1),
+			Name:     github.String("valid"),
+			FullName: github.String("test/valid"),
+			Owner: &github.User{
+				Login: github.String("test"),
+				Type:  github.String("Organization"),
+			},
+		},
+	}
+
+	objs := []runtime.Object{
+		&actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+					},
+				},
+			},
+		},
+		&actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Repository: "test/valid",
+							Labels:     []string{"label1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	newWebhook := func(protected bool) *HorizontalRunnerAutoscalerGitHubWebhook {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/test/valid", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"full_name": "test/valid", "visibility": "private", "default_branch": "main"}`)
+		})
+		mux.HandleFunc("/repos/test/valid/branches/main", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"name": "main", "protected": %t}`, protected)
+		})
+
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		return &HorizontalRunnerAutoscalerGitHubWebhook{
+			GitHubClient: newGithubClient(server),
+			Policy:       newProtectedBranchPolicyHook(t),
+		}
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(true),
+			"workflow_job",
+			event,
+			200,
+			"scaled test-name by 1",
+			objs,
+		)
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		testServerWithWebhook(t,
+			newWebhook(false),
+			"workflow_job",
+			event,
+			200,
+			"refused to scale by the policy: the default branch of the repository must be protected",
+			objs,
+		)
+	})
+}
+
+func TestRunnerRegistrationPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	runner := &actionsv1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-runner", Generation: 1},
+		Spec: actionsv1alpha1.RunnerSpec{
+			RunnerConfig: actionsv1alpha1.RunnerConfig{
+				Organization: "test",
+			},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithStatusSubresource(&actionsv1alpha1.Runner{}).
+		WithObjects(runner).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerReconciler{
+		Client:   c,
+		Log:      logr.Discard(),
+		Recorder: recorder,
+		Scheme:   sc,
+		Policy:   newProtectedBranchPolicyHook(t),
+	}
+
+	for i := 0; i < 2; i++ {
+		var got actionsv1alpha1.Runner
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &got))
+
+		res, err := r.processRunnerCreation(ctx, got, logr.Discard())
+		require.NoError(t, err)
+		require.Equal(t, RetryDelayOnCreateRegistrationError, res.RequeueAfter)
+	}
+
+	var got actionsv1alpha1.Runner
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &got))
+
+	registered := meta.FindStatusCondition(got.Status.Conditions, actionsv1alpha1.ConditionTypeGitHubRegistered)
+	require.NotNil(t, registered)
+	require.Equal(t, metav1.ConditionFalse, registered.Status)
+	require.Equal(t, actionsv1alpha1.ConditionReasonPolicyDenied, registered.Reason)
+	require.Contains(t, registered.Message, "the default branch of the repository must be protected")
+
+	require.Len(t, recorder.Events, 1, "the event is emitted only when the policy starts denying the registration")
+}
@@ -0,0 +1,118 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// emitCloudEvent emits the event of the type about the subject, unless the emitter is nil for the CloudEvents being disabled.
+func emitCloudEvent(ctx context.Context, log logr.Logger, emitter cloudevents.Emitter, typ, subject string, data interface{}) {
+	if emitter == nil {
+		return
+	}
+
+	e, err := cloudevents.NewEvent(typ, subject, data)
+	if err != nil {
+		log.Error(err, "Failed to build CloudEvent", "type", typ, "subject", subject)
+		return
+	}
+
+	emitter.Emit(ctx, e)
+}
+
+func runnerCloudEventSubject(namespace, name string) string {
+	return fmt.Sprintf("namespaces/%s/runners/%s", namespace, name)
+}
+
+func hraCloudEventSubject(hra v1alpha1.HorizontalRunnerAutoscaler) string {
+	return fmt.Sprintf("namespaces/%s/horizontalrunnerautoscalers/%s", hra.Namespace, hra.Name)
+}
+
+// runnerCloudEventData returns the data of the events about the runner, with the runner ID and the node from its pod when it has one.
+func runnerCloudEventData(runner *v1alpha1.Runner, pod *corev1.Pod) cloudevents.RunnerData {
+	data := cloudevents.RunnerData{
+		Namespace:        runner.Namespace,
+		Name:             runner.Name,
+		RunnerDeployment: runner.Labels[LabelKeyRunnerDeploymentName],
+		Enterprise:       runner.Spec.Enterprise,
+		Organization:     runner.Spec.Organization,
+		Repository:       runner.Spec.Repository,
+		Group:            runner.Spec.Group,
+		Labels:           runner.Spec.Labels,
+	}
+
+	if pod != nil {
+		data.RunnerID = runnerPodID(pod)
+		data.Node = pod.Spec.NodeName
+	}
+
+	return data
+}
+
+// runnerPodCloudEventData returns the data of the events about the runner of the pod, which is also the pod of a RunnerSet that has no Runner.
+func runnerPodCloudEventData(pod *corev1.Pod) cloudevents.RunnerData {
+	data := cloudevents.RunnerData{
+		Namespace:        pod.Namespace,
+		Name:             pod.Name,
+		RunnerDeployment: pod.Labels[LabelKeyRunnerDeploymentName],
+		RunnerID:         runnerPodID(pod),
+		Node:             pod.Spec.NodeName,
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		data.Enterprise, _ = getEnv(c, EnvVarEnterprise)
+		data.Organization, _ = getEnv(c, EnvVarOrg)
+		data.Repository, _ = getEnv(c, EnvVarRepo)
+		data.Group, _ = getEnv(c, EnvVarGroup)
+
+		if labels, _ := getEnv(c, EnvVarLabels); labels != "" {
+			data.Labels = strings.Split(labels, ",")
+		}
+	}
+
+	return data
+}
+
+func runnerPodID(pod *corev1.Pod) int64 {
+	v, ok := getAnnotation(pod, AnnotationKeyRunnerID)
+	if !ok {
+		return 0
+	}
+
+	id, _ := strconv.ParseInt(v, 10, 64)
+
+	return id
+}
+
+// runnerRegistered tells if the runner became registered to GitHub between the conditions before and after the reconciliation.
+// A runner whose pod is recreated becomes registered again once the new pod registers.
+func runnerRegistered(before, after []metav1.Condition) bool {
+	return !meta.IsStatusConditionTrue(before, v1alpha1.ConditionTypeGitHubRegistered) && meta.IsStatusConditionTrue(after, v1alpha1.ConditionTypeGitHubRegistered)
+}
+
+func workflowJobCloudEventData(job *gogithub.WorkflowJob, repository string) cloudevents.JobData {
+	return cloudevents.JobData{
+		ID:           job.GetID(),
+		RunID:        job.GetRunID(),
+		Name:         job.GetName(),
+		WorkflowName: job.GetWorkflowName(),
+		Repository:   repository,
+		Labels:       job.Labels,
+		URL:          job.GetHTMLURL(),
+	}
+}
@@ -0,0 +1,148 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type recordingEmitter struct {
+	events []cloudevents.Event
+}
+
+func (e *recordingEmitter) Emit(_ context.Context, event cloudevents.Event) {
+	e.events = append(e.events, event)
+}
+
+func TestRunnerRegistered(t *testing.T) {
+	registered := []metav1.Condition{{Type: v1alpha1.ConditionTypeGitHubRegistered, Status: metav1.ConditionTrue}}
+	waiting := []metav1.Condition{{Type: v1alpha1.ConditionTypeGitHubRegistered, Status: metav1.ConditionFalse}}
+
+	require.True(t, runnerRegistered(nil, registered))
+	require.True(t, runnerRegistered(waiting, registered))
+	require.False(t, runnerRegistered(registered, registered))
+	require.False(t, runnerRegistered(nil, waiting))
+	require.False(t, runnerRegistered(registered, waiting))
+}
+
+func TestUpdateRunnerBusyStateEmitsJobAssigned(t *testing.T) {
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example-abcde-fghij",
+			Namespace:   "runners",
+			Labels:      map[string]string{LabelKeyRunner: "", LabelKeyRunnerDeploymentName: "example"},
+			Annotations: map[string]string{AnnotationKeyRunnerID: "42"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			Containers: []corev1.Container{{
+				Name: containerName,
+				Env: []corev1.EnvVar{
+					{Name: EnvVarOrg, Value: "my-org"},
+					{Name: EnvVarLabels, Value: "linux,gpu"},
+				},
+			}},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithIndex(&corev1.Pod{}, runnerPodNameKey, runnerPodNameIndexer).
+		WithObjects(pod).
+		Build()
+
+	emitter := &recordingEmitter{}
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Client: c, CloudEvents: emitter}
+
+	event := func(action string) *gogithub.WorkflowJobEvent {
+		return &gogithub.WorkflowJobEvent{
+			Action: gogithub.String(action),
+			Repo:   &gogithub.Repository{FullName: gogithub.String("my-org/my-repo")},
+			WorkflowJob: &gogithub.WorkflowJob{
+				ID:           gogithub.Int64(7),
+				RunID:        gogithub.Int64(3),
+				Name:         gogithub.String("build"),
+				WorkflowName: gogithub.String("CI"),
+				Labels:       []string{"self-hosted", "gpu"},
+				RunnerName:   gogithub.String("example-abcde-fghij"),
+				HTMLURL:      gogithub.String("https://github.com/my-org/my-repo/actions/runs/3/job/7"),
+			},
+		}
+	}
+
+	autoscaler.updateRunnerBusyState(ctx, logr.Discard(), event("in_progress"))
+	// The completion of the job is not an assignment
+	autoscaler.updateRunnerBusyState(ctx, logr.Discard(), event("completed"))
+
+	require.Len(t, emitter.events, 1)
+
+	e := emitter.events[0]
+	require.Equal(t, cloudevents.TypeRunnerJobAssigned, e.Type)
+	require.Equal(t, "namespaces/runners/runners/example-abcde-fghij", e.Subject)
+
+	var data cloudevents.JobAssignedData
+	require.NoError(t, json.Unmarshal(e.Data, &data))
+	require.Equal(t, cloudevents.JobAssignedData{
+		Runner: cloudevents.RunnerData{
+			Namespace:        "runners",
+			Name:             "example-abcde-fghij",
+			RunnerDeployment: "example",
+			Organization:     "my-org",
+			Labels:           []string{"linux", "gpu"},
+			RunnerID:         42,
+			Node:             "node-1",
+		},
+		Job: cloudevents.JobData{
+			ID:           7,
+			RunID:        3,
+			Name:         "build",
+			WorkflowName: "CI",
+			Repository:   "my-org/my-repo",
+			Labels:       []string{"self-hosted", "gpu"},
+			URL:          "https://github.com/my-org/my-repo/actions/runs/3/job/7",
+		},
+	}, data)
+}
+
+func TestScaleDecisionData(t *testing.T) {
+	previous := 2
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "runners", Name: "example"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "example-rd"},
+			MaxReplicas:    intPtr(10),
+		},
+	}
+
+	// The max replicas lowered to the capacity of the cluster
+	scaled := hra
+	scaled.Spec.MaxReplicas = intPtr(6)
+
+	data := scaleDecisionData(hra, scaled, &previous, 5, 1)
+	// The data keeps the previous replicas of the status it was built from
+	previous = 3
+
+	require.Equal(t, &cloudevents.ScaleDecisionData{
+		Namespace:        "runners",
+		Name:             "example",
+		ScaleTargetKind:  "RunnerDeployment",
+		ScaleTargetName:  "example-rd",
+		PreviousReplicas: intPtr(2),
+		DesiredReplicas:  5,
+		MinReplicas:      1,
+		MaxReplicas:      intPtr(6),
+	}, data)
+}
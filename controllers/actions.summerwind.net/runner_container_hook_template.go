@@ -0,0 +1,146 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	containerHookTemplateVolumeName = "container-hook-template"
+
+	// containerHookTemplateDir is where the rendered hook template is mounted in the runner container.
+	containerHookTemplateDir  = "/etc/actions-runner/container-hook-template"
+	containerHookTemplatePath = "template.yaml"
+
+	// defaultContainerHookTemplateKey is the key of the template in the ConfigMap when ContainerHookTemplate.Key is empty.
+	defaultContainerHookTemplateKey = "template.yaml"
+
+	// EnvVarContainerHookTemplate is the environment variable the kubernetes container hooks read the hook template from.
+	EnvVarContainerHookTemplate = "ACTIONS_RUNNER_CONTAINER_HOOK_TEMPLATE"
+)
+
+var containerHookTemplateVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// containerHookTemplateVariables returns the variables of the template for the runner labels.
+// The variables of the former labels take precedence over the latter ones, and all of them over the defaults.
+func containerHookTemplateVariables(t *v1alpha1.ContainerHookTemplate, labels []string) map[string]string {
+	vars := map[string]string{}
+
+	for k, v := range t.Variables {
+		vars[k] = v
+	}
+
+	for i := len(labels) - 1; i >= 0; i-- {
+		for k, v := range t.LabelVariables[labels[i]] {
+			vars[k] = v
+		}
+	}
+
+	return vars
+}
+
+// renderContainerHookTemplate substitutes the variables referenced in the template as ${NAME}.
+// It fails on the references to undefined variables rather than leaving them as is,
+// so that a typo doesn't end up in workflow pods without the resources or the tolerations intended.
+func renderContainerHookTemplate(tmpl string, vars map[string]string) (string, error) {
+	var undefined []string
+
+	rendered := containerHookTemplateVariable.ReplaceAllStringFunc(tmpl, func(ref string) string {
+		name := containerHookTemplateVariable.FindStringSubmatch(ref)[1]
+
+		v, ok := vars[name]
+		if !ok {
+			undefined = append(undefined, name)
+			return ref
+		}
+
+		return v
+	})
+
+	if len(undefined) > 0 {
+		sort.Strings(undefined)
+		return "", fmt.Errorf("undefined variables in the container hook template: %s", strings.Join(undefined, ", "))
+	}
+
+	var podTemplate corev1.PodTemplateSpec
+	if err := yaml.UnmarshalStrict([]byte(rendered), &podTemplate); err != nil {
+		return "", fmt.Errorf("the rendered container hook template is not a valid pod template: %w", err)
+	}
+
+	return rendered, nil
+}
+
+// applyContainerHookTemplate renders the container hook template of the runner from the ConfigMap,
+// and puts it onto the runner pod as an annotation projected into the runner container.
+// The template is rendered on the creation of the runner pod, so the changes to the ConfigMap apply to the runner pods created afterwards.
+func applyContainerHookTemplate(ctx context.Context, c client.Client, runner *v1alpha1.Runner, pod *corev1.Pod) error {
+	t := runner.Spec.ContainerHookTemplate
+
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, client.ObjectKey{Namespace: runner.Namespace, Name: t.ConfigMapName}, &cm); err != nil {
+		return fmt.Errorf("getting the ConfigMap %s of the container hook template: %w", t.ConfigMapName, err)
+	}
+
+	key := t.Key
+	if key == "" {
+		key = defaultContainerHookTemplateKey
+	}
+
+	tmpl, ok := cm.Data[key]
+	if !ok {
+		return fmt.Errorf("the ConfigMap %s has no container hook template at the key %q", t.ConfigMapName, key)
+	}
+
+	rendered, err := renderContainerHookTemplate(tmpl, containerHookTemplateVariables(t, runner.Spec.Labels))
+	if err != nil {
+		return err
+	}
+
+	setAnnotation(&pod.ObjectMeta, AnnotationKeyContainerHookTemplate, rendered)
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: containerHookTemplateVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{
+					{
+						Path: containerHookTemplatePath,
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: fmt.Sprintf("metadata.annotations['%s']", AnnotationKeyContainerHookTemplate),
+						},
+					},
+				},
+			},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+
+		if c.Name != containerName {
+			continue
+		}
+
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      containerHookTemplateVolumeName,
+			MountPath: containerHookTemplateDir,
+			ReadOnly:  true,
+		})
+
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  EnvVarContainerHookTemplate,
+			Value: filepath.Join(containerHookTemplateDir, containerHookTemplatePath),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testContainerHookTemplate = `spec:
+  nodeSelector:
+    pool: ${POOL}
+  containers:
+  - name: $job
+    resources:
+      limits:
+        memory: ${MEMORY}
+`
+
+func TestApplyContainerHookTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hook-template"},
+		Data:       map[string]string{"template.yaml": testContainerHookTemplate},
+	}).Build()
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+	}
+	runner.Spec.Labels = []string{"gpu", "large"}
+	runner.Spec.ContainerHookTemplate = &v1alpha1.ContainerHookTemplate{
+		ConfigMapName: "hook-template",
+		Variables:     map[string]string{"POOL": "default", "MEMORY": "4Gi"},
+		LabelVariables: map[string]map[string]string{
+			"gpu":   {"POOL": "gpu"},
+			"large": {"POOL": "large", "MEMORY": "16Gi"},
+		},
+	}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "runner"}, {Name: "docker"}},
+		},
+	}
+
+	require.NoError(t, applyContainerHookTemplate(context.Background(), c, runner, pod))
+
+	// The former label wins, and the job container name placeholder of the hooks is kept as is
+	require.Equal(t, `spec:
+  nodeSelector:
+    pool: gpu
+  containers:
+  - name: $job
+    resources:
+      limits:
+        memory: 16Gi
+`, pod.Annotations[AnnotationKeyContainerHookTemplate])
+
+	require.Len(t, pod.Spec.Volumes, 1)
+	require.Equal(t, "metadata.annotations['actions-runner/container-hook-template']", pod.Spec.Volumes[0].DownwardAPI.Items[0].FieldRef.FieldPath)
+	require.Equal(t, []corev1.EnvVar{
+		{Name: EnvVarContainerHookTemplate, Value: "/etc/actions-runner/container-hook-template/template.yaml"},
+	}, pod.Spec.Containers[0].Env)
+	require.Empty(t, pod.Spec.Containers[1].VolumeMounts)
+
+	runner.Spec.ContainerHookTemplate.Variables = nil
+	runner.Spec.Labels = []string{"gpu"}
+
+	err := applyContainerHookTemplate(context.Background(), c, runner, &corev1.Pod{})
+	require.EqualError(t, err, "undefined variables in the container hook template: MEMORY")
+
+	runner.Spec.ContainerHookTemplate.Key = "missing.yaml"
+
+	err = applyContainerHookTemplate(context.Background(), c, runner, &corev1.Pod{})
+	require.Error(t, err)
+}
+
+func TestRenderContainerHookTemplate_InvalidPodTemplate(t *testing.T) {
+	_, err := renderContainerHookTemplate("spec:\n  nodeSelector: ${SELECTOR}\n", map[string]string{"SELECTOR": "[a, b]"})
+	require.Error(t, err)
+}
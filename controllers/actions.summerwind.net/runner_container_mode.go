@@ -0,0 +1,138 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	containerModeKubernetes = "kubernetes"
+	containerModeSysbox     = "sysbox"
+	containerModeKata       = "kata"
+	containerModeBuildkit   = "buildkit"
+
+	// defaultSysboxRuntimeClassName is the name of the runtime class created by the sysbox installer for Kubernetes.
+	defaultSysboxRuntimeClassName = "sysbox-runc"
+	// defaultKataRuntimeClassName is the name of the runtime class created by kata-deploy.
+	defaultKataRuntimeClassName = "kata"
+
+	buildkitContainerName = "buildkitd"
+
+	// buildkitAddr is where the buildkitd sidecar listens, so that docker buildx and buildctl in the runner container can reach it via BUILDKIT_HOST.
+	// It listens on the loopback address as a unix socket created by the rootless buildkitd isn't writable by the runner user.
+	buildkitAddr = "tcp://127.0.0.1:1234"
+)
+
+// containerModeRuntimeClassName returns the name of the runtime class the container mode requires, or an empty string when it requires none.
+func containerModeRuntimeClassName(containerMode string) string {
+	switch containerMode {
+	case containerModeSysbox:
+		return defaultSysboxRuntimeClassName
+	case containerModeKata:
+		return defaultKataRuntimeClassName
+	default:
+		return ""
+	}
+}
+
+func validateContainerMode(containerMode string) error {
+	if containerMode == "" {
+		return nil
+	}
+
+	for _, m := range v1alpha1.ContainerModes {
+		if m == containerMode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported container mode %q: it must be one of %s", containerMode, strings.Join(v1alpha1.ContainerModes, ", "))
+}
+
+// applyBuildkitContainerMode adds the buildkitd sidecar to the pod and points the runner container to it.
+// The buildkitd container in the pod template, if any, is used with the missing fields defaulted.
+func applyBuildkitContainerMode(pod *corev1.Pod, runnerContainer *corev1.Container, image string) {
+	runnerContainer.Env = append(runnerContainer.Env, corev1.EnvVar{
+		Name:  "BUILDKIT_HOST",
+		Value: buildkitAddr,
+	})
+
+	buildkitd := newBuildkitContainer(image)
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != buildkitContainerName {
+			continue
+		}
+
+		if c.Image == "" {
+			c.Image = buildkitd.Image
+		}
+		if len(c.Args) == 0 {
+			c.Args = buildkitd.Args
+		}
+		if c.SecurityContext == nil {
+			c.SecurityContext = buildkitd.SecurityContext
+		}
+
+		return
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, buildkitd)
+}
+
+// newBuildkitContainer returns the rootless buildkitd sidecar of the buildkit container mode.
+func newBuildkitContainer(image string) corev1.Container {
+	var uid int64 = 1000
+
+	return corev1.Container{
+		Name:  buildkitContainerName,
+		Image: image,
+		Args: []string{
+			"--addr", buildkitAddr,
+			// Rootless buildkitd can't create a new PID namespace for the build containers in an unprivileged pod
+			"--oci-worker-no-process-sandbox",
+		},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:  &uid,
+			RunAsGroup: &uid,
+			// Required for rootlesskit to create user namespaces
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeUnconfined,
+			},
+			AppArmorProfile: &corev1.AppArmorProfile{
+				Type: corev1.AppArmorProfileTypeUnconfined,
+			},
+		},
+	}
+}
+
+// validateContainerModePrerequisites returns an error when the nodes aren't prepared for the container mode of the runner pod,
+// so that ARC tells why instead of leaving the pod unable to start.
+// The runtime class names the runtime handler installed on the nodes, and its scheduling constraints keep the pods on those nodes.
+func validateContainerModePrerequisites(ctx context.Context, c client.Reader, containerMode string, pod *corev1.Pod) error {
+	if containerModeRuntimeClassName(containerMode) == "" || pod.Spec.RuntimeClassName == nil {
+		return nil
+	}
+
+	name := *pod.Spec.RuntimeClassName
+
+	var runtimeClass nodev1.RuntimeClass
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, &runtimeClass); err != nil {
+		if kerrors.IsNotFound(err) {
+			return fmt.Errorf("the %s container mode requires the runtime class %q, which doesn't exist. Install %s onto the nodes, or set runtimeClassName to the existing runtime class", containerMode, name, containerMode)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -27,11 +28,16 @@ import (
 	"time"
 
 	"github.com/actions/actions-runner-controller/build"
+	"github.com/actions/actions-runner-controller/github/audit"
 	"github.com/actions/actions-runner-controller/hash"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
 	"github.com/go-logr/logr"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -42,6 +48,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 )
 
 const (
@@ -59,6 +68,11 @@ const (
 	EnvVarEnterprise = "RUNNER_ENTERPRISE"
 	EnvVarEphemeral  = "RUNNER_EPHEMERAL"
 	EnvVarTrue       = "true"
+
+	// The URLs of the cache service read by actions/cache. The legacy cache service uses ACTIONS_CACHE_URL
+	// and the cache service v2 uses ACTIONS_RESULTS_URL.
+	EnvVarActionsCacheURL   = "ACTIONS_CACHE_URL"
+	EnvVarActionsResultsURL = "ACTIONS_RESULTS_URL"
 )
 
 // RunnerReconciler reconciles a Runner object
@@ -74,6 +88,22 @@ type RunnerReconciler struct {
 	UnregistrationRetryDelay    time.Duration
 
 	RunnerPodDefaults RunnerPodDefaults
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+
+	// CloudEvents emits the registrations and the deletions of the runners. Nil when the CloudEvents are disabled.
+	CloudEvents cloudevents.Emitter
+
+	// JITConfigDelivery is how the just-in-time configurations are handed over to the runner pods.
+	JITConfigDelivery JITConfigDelivery
+
+	// Policy decides whether the runners may be registered. Nil when the policy hook is disabled.
+	Policy *policyhook.Hook
+
+	// ForensicSnapshots uploads the forensic snapshots of the runner pods whose runner containers exited with the exit codes of their runners.
+	// Nil when the forensic snapshots are disabled.
+	ForensicSnapshots *ForensicSnapshots
 }
 
 type RunnerPodDefaults struct {
@@ -86,27 +116,53 @@ type RunnerPodDefaults struct {
 	DockerGID string
 
 	UseRunnerStatusUpdateHook bool
+
+	// WindowsRunnerImage is the default image of the runner container of windows runners.
+	WindowsRunnerImage string
+
+	// BuildkitImage is the default image of the buildkitd sidecar of the buildkit container mode.
+	BuildkitImage string
+
+	// ActionsCacheURL is the URL of the self-hosted Actions cache server the runners use instead of the cache service of GitHub.
+	ActionsCacheURL string
+
+	// LabelNodeSelectors maps the lower-cased runner labels to the node selectors added to the pods of the runners having the labels.
+	LabelNodeSelectors map[string]map[string]string
+	// DeriveNodeSelectorFromLabels adds the node selectors for the default runner labels like linux and arm64 to the runner pods.
+	DeriveNodeSelectorFromLabels bool
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
 // +kubebuilder:rbac:groups=core,resources=pods/finalizers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create;get
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=create;delete;get
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=create;delete;get
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=create;delete;get
+// +kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=podtemplates,verbs=create;delete;get
+// +kubebuilder:rbac:groups=autoscaling.x-k8s.io,resources=provisioningrequests,verbs=create;delete;get
+// +kubebuilder:rbac:groups=secrets-store.csi.x-k8s.io,resources=secretproviderclasses,verbs=create;delete;get
 
 func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("runner", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "Runner", req.NamespacedName)
 
 	var runner v1alpha1.Runner
 	if err := r.Get(ctx, req.NamespacedName, &runner); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(runner.Namespace, sharding.Owner(runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository)) {
+		return ctrl.Result{}, nil
+	}
+
 	if runner.ObjectMeta.DeletionTimestamp.IsZero() {
 		finalizers, added := addFinalizer(runner.ObjectMeta.Finalizers, finalizerName)
 
@@ -135,6 +191,9 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 		r.GitHubClient.DeinitForRunner(&runner)
 
+		// The runner pod is deleted along with the runner, so this is the last chance to capture the snapshot
+		r.syncForensicSnapshot(ctx, log, &runner, &pod)
+
 		return r.processRunnerDeletion(runner, ctx, log, &pod)
 	}
 
@@ -147,14 +206,17 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return r.processRunnerCreation(ctx, runner, log)
 	}
 
-	phase := string(pod.Status.Phase)
-	if phase == "" {
-		phase = "Created"
-	}
+	phase := podPhaseOrCreated(&pod)
 
 	ready := runnerPodReady(&pod)
 
-	if (runner.Status.Phase != phase || runner.Status.Ready != ready) && !r.RunnerPodDefaults.UseRunnerStatusUpdateHook || runner.Status.Phase == "" && r.RunnerPodDefaults.UseRunnerStatusUpdateHook {
+	updated := runner.DeepCopy()
+	setRunnerPodConditions(&updated.Status.Conditions, runner.Generation, &pod, ready)
+	updated.Status.ObservedGeneration = runner.Generation
+
+	phaseChanged := (runner.Status.Phase != phase || runner.Status.Ready != ready) && !r.RunnerPodDefaults.UseRunnerStatusUpdateHook || runner.Status.Phase == "" && r.RunnerPodDefaults.UseRunnerStatusUpdateHook
+
+	if phaseChanged {
 		if pod.Status.Phase == corev1.PodRunning {
 			// Seeing this message, you can expect the runner to become `Running` soon.
 			log.V(1).Info(
@@ -163,21 +225,106 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			)
 		}
 
-		updated := runner.DeepCopy()
 		updated.Status.Phase = phase
 		updated.Status.Ready = ready
 		updated.Status.Reason = pod.Status.Reason
 		updated.Status.Message = pod.Status.Message
+	}
 
+	if report, err := runnerPodState(&pod); err != nil {
+		log.Error(err, "Ignoring the runner state reported onto the runner pod")
+	} else if report != nil {
+		applyRunnerState(&updated.Status, report, r.RunnerPodDefaults.UseRunnerStatusUpdateHook)
+	}
+
+	if !equality.Semantic.DeepEqual(runner.Status, updated.Status) {
 		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
 			log.Error(err, "Failed to update runner status for Phase/Reason/Message")
 			return ctrl.Result{}, err
 		}
 	}
 
+	r.syncForensicSnapshot(ctx, log, &runner, &pod)
+
+	if deleted, err := r.syncDeliveredJITConfig(ctx, &runner, &pod, log); err != nil {
+		log.Error(err, "Failed to sync the just-in-time configuration delivered via vault")
+		return ctrl.Result{}, err
+	} else if deleted {
+		return ctrl.Result{}, nil
+	}
+
+	if runnerRegistered(runner.Status.Conditions, updated.Status.Conditions) {
+		emitCloudEvent(ctx, log, r.CloudEvents, cloudevents.TypeRunnerRegistered, runnerCloudEventSubject(runner.Namespace, runner.Name), runnerCloudEventData(&runner, &pod))
+	}
+
+	if r.RunnerPodDefaults.UseRunnerStatusUpdateHook {
+		if err := syncRunnerPodBusyLabel(ctx, r.Client, log, &runner, &pod); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := syncRunnerPodWorkflowAnnotations(ctx, r.Client, log, &runner, &pod); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// setRunnerPodConditions sets the conditions of the runner that has the runner pod.
+// The runner is registered once ARC sees it in GitHub, or generates its just-in-time configuration.
+func setRunnerPodConditions(conditions *[]metav1.Condition, generation int64, pod *corev1.Pod, ready bool) {
+	setCondition(conditions, generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+
+	if ready {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonPodReady, "")
+	} else {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonPodNotReady, fmt.Sprintf("Runner pod is %s", podPhaseOrCreated(pod)))
+	}
+
+	if id, ok := getAnnotation(pod, AnnotationKeyRunnerID); ok {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeGitHubRegistered, true, v1alpha1.ConditionReasonRegistered, fmt.Sprintf("Runner ID is %s", id))
+	} else {
+		setCondition(conditions, generation, v1alpha1.ConditionTypeGitHubRegistered, false, v1alpha1.ConditionReasonWaitingForRegistration, "")
+	}
+}
+
+// setRunnerFailedConditions patches the conditions of the runner that failed to be reconciled for the reason.
+func (r *RunnerReconciler) setRunnerFailedConditions(ctx context.Context, log logr.Logger, runner *v1alpha1.Runner, reason string, err error) {
+	if err := patchConditions(ctx, r.Client, runner, func(obj *v1alpha1.Runner) *[]metav1.Condition { return &obj.Status.Conditions }, func(conditions *[]metav1.Condition) {
+		setCondition(conditions, runner.Generation, v1alpha1.ConditionTypeSynced, false, reason, err.Error())
+
+		if reason == v1alpha1.ConditionReasonRegistrationFailed || reason == v1alpha1.ConditionReasonPolicyDenied {
+			setCondition(conditions, runner.Generation, v1alpha1.ConditionTypeGitHubRegistered, false, reason, err.Error())
+		}
+	}); err != nil {
+		log.Error(err, "Failed to update runner status conditions")
+	}
+}
+
+// decideRegistration asks the policy whether the runner may be registered.
+func (r *RunnerReconciler) decideRegistration(ctx context.Context, runner v1alpha1.Runner, log logr.Logger) policyhook.Decision {
+	input := registerPolicyInput(runner)
+
+	if runner.Spec.Repository != "" {
+		ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+		if err != nil {
+			log.Error(err, "Could not get the GitHub client for the policy hook")
+		} else {
+			input.RepositoryInfo = policyRepositoryInfo(ctx, log, r.Policy, ghc, runner.Spec.Repository)
+		}
+	}
+
+	return r.Policy.Decide(ctx, input)
+}
+
+func podPhaseOrCreated(pod *corev1.Pod) string {
+	if pod.Status.Phase == "" {
+		return "Created"
+	}
+
+	return string(pod.Status.Phase)
+}
+
 func runnerPodReady(pod *corev1.Pod) bool {
 	for _, c := range pod.Status.Conditions {
 		if c.Type != corev1.PodReady {
@@ -274,6 +421,11 @@ func (r *RunnerReconciler) processRunnerDeletion(runner v1alpha1.Runner, ctx con
 	finalizers, removed := removeFinalizer(runner.ObjectMeta.Finalizers, finalizerName)
 
 	if removed {
+		if err := r.destroyJITConfig(ctx, &runner); err != nil {
+			log.Error(err, "Failed to destroy the just-in-time configuration in vault")
+			return ctrl.Result{}, err
+		}
+
 		newRunner := runner.DeepCopy()
 		newRunner.ObjectMeta.Finalizers = finalizers
 
@@ -283,13 +435,44 @@ func (r *RunnerReconciler) processRunnerDeletion(runner v1alpha1.Runner, ctx con
 		}
 
 		log.Info("Removed finalizer")
+
+		emitCloudEvent(ctx, log, r.CloudEvents, cloudevents.TypeRunnerDeleted, runnerCloudEventSubject(runner.Namespace, runner.Name), runnerCloudEventData(&runner, pod))
 	}
 
 	return ctrl.Result{}, nil
 }
 
 func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1alpha1.Runner, log logr.Logger) (reconcile.Result, error) {
-	if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+	if r.Policy != nil {
+		if d := r.decideRegistration(ctx, runner, log); !d.Allowed {
+			err := fmt.Errorf("registration denied by the policy: %s", d.Reason)
+
+			// The event is emitted only when the policy starts denying the registration, not on every retry
+			if c := meta.FindStatusCondition(runner.Status.Conditions, v1alpha1.ConditionTypeSynced); c == nil || c.Reason != v1alpha1.ConditionReasonPolicyDenied {
+				r.Recorder.Event(&runner, corev1.EventTypeWarning, EventReasonPolicyDenied, err.Error())
+			}
+
+			log.Info("Runner registration denied by the policy", "reason", d.Reason)
+			r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonPolicyDenied, err)
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		}
+	}
+
+	var jitConfigSecret *corev1.Secret
+
+	if runner.Spec.JITConfig {
+		// With Vault, the just-in-time configuration is delivered right before the pod is created below
+		if !r.JITConfigDelivery.fromVault(&runner) {
+			secret, err := r.ensureJITConfigSecret(ctx, runner, log)
+			if err != nil {
+				r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonRegistrationFailed, err)
+				return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+			}
+
+			jitConfigSecret = secret
+		}
+	} else if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+		r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonRegistrationFailed, err)
 		return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
 	} else if updated {
 		return ctrl.Result{Requeue: true}, nil
@@ -298,9 +481,34 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 	newPod, err := r.newPod(runner)
 	if err != nil {
 		log.Error(err, "Could not create pod")
+		r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonReconcileFailed, err)
 		return ctrl.Result{}, err
 	}
 
+	if err := validateContainerModePrerequisites(ctx, r.Client, runner.Spec.ContainerMode, &newPod); err != nil {
+		log.Error(err, "Container mode prerequisites are missing")
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "ContainerModePrerequisitesMissing", err.Error())
+		r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonReconcileFailed, err)
+		return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+	}
+
+	if runner.Spec.ContainerHookTemplate != nil {
+		if err := applyContainerHookTemplate(ctx, r.Client, &runner, &newPod); err != nil {
+			log.Error(err, "Could not render the container hook template")
+			r.Recorder.Event(&runner, corev1.EventTypeWarning, "ContainerHookTemplateFailed", err.Error())
+			r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonReconcileFailed, err)
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		}
+	}
+
+	if jitConfigSecret != nil {
+		// The runner is registered along with the just-in-time configuration,
+		// so ARC can unregister it by the ID without waiting for it to show up in the runners list.
+		if id, ok := getAnnotation(jitConfigSecret, AnnotationKeyRunnerID); ok {
+			newPod.Annotations = CloneAndAddLabel(newPod.Annotations, AnnotationKeyRunnerID, id)
+		}
+	}
+
 	needsServiceAccount := runner.Spec.ServiceAccountName == "" && (r.RunnerPodDefaults.UseRunnerStatusUpdateHook || runner.Spec.ContainerMode == "kubernetes")
 	if needsServiceAccount {
 		serviceAccount := &corev1.ServiceAccount{
@@ -323,6 +531,13 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 					Verbs:         []string{"get", "update", "patch"},
 					ResourceNames: []string{runner.ObjectMeta.Name},
 				},
+				{
+					// The runner reports its state onto its own pod
+					APIGroups:     []string{""},
+					Resources:     []string{"pods"},
+					Verbs:         []string{"get", "patch"},
+					ResourceNames: []string{runner.ObjectMeta.Name},
+				},
 			}...)
 		}
 
@@ -391,6 +606,20 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 		}
 	}
 
+	if h := runner.Spec.NodeAutoscalerHints; h != nil && h.ProvisioningRequest != nil {
+		if res := r.ensureProvisioningRequest(ctx, &runner, &newPod, log); res != nil {
+			return *res, nil
+		}
+	}
+
+	if runner.Spec.JITConfig && r.JITConfigDelivery.fromVault(&runner) {
+		if err := r.deliverJITConfig(ctx, &runner, &newPod, log); err != nil {
+			log.Error(err, "Failed to deliver the just-in-time configuration via vault")
+			r.setRunnerFailedConditions(ctx, log, &runner, v1alpha1.ConditionReasonRegistrationFailed, err)
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		}
+	}
+
 	if err := r.Create(ctx, &newPod); err != nil {
 		if kerrors.IsAlreadyExists(err) {
 			// Gracefully handle pod-already-exists errors due to informer cache delay.
@@ -410,6 +639,8 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 	r.Recorder.Event(&runner, corev1.EventTypeNormal, "PodCreated", fmt.Sprintf("Created pod '%s'", newPod.Name))
 	log.Info("Created runner pod", "repository", runner.Spec.Repository)
 
+	observeRunnerPodResourceEnvelope(&runner, &newPod)
+
 	return ctrl.Result{}, nil
 }
 
@@ -643,7 +874,15 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 	}
 
 	if runnerSpec.NodeSelector != nil {
-		pod.Spec.NodeSelector = runnerSpec.NodeSelector
+		// Overrides the node selectors mapped from the runner labels
+		nodeSelector := map[string]string{}
+		for k, v := range pod.Spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		for k, v := range runnerSpec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		pod.Spec.NodeSelector = nodeSelector
 	}
 
 	if runnerSpec.ServiceAccountName != "" {
@@ -704,10 +943,31 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		pod.Spec.RuntimeClassName = runnerSpec.RuntimeClassName
 	}
 
+	if runnerSpec.Identity != nil {
+		applyRunnerIdentity(&pod, runnerSpec.Identity)
+	}
+
+	if len(runnerSpec.SecretMounts) != 0 {
+		applySecretMounts(&pod, runnerSpec.SecretMounts)
+	}
+
+	if isQuarantined(runner.Labels) {
+		applyQuarantineToPod(&pod)
+	}
+
 	pod.ObjectMeta.Name = runner.ObjectMeta.Name
 
-	// Inject the registration token and the runner name
-	updated := mutatePod(&pod, runner.Status.Registration.Token)
+	// Inject the registration token or the just-in-time configuration, and the runner name
+	var updated *corev1.Pod
+	switch {
+	case runner.Spec.JITConfig && r.JITConfigDelivery.fromVault(&runner):
+		// deliverJITConfig hands the just-in-time configuration over via Vault right before the pod is created
+		updated = pod.DeepCopy()
+	case runner.Spec.JITConfig:
+		updated = mutatePodForJITConfig(&pod, jitConfigSecretName(runner.Name))
+	default:
+		updated = mutatePod(&pod, runner.Status.Registration.Token)
+	}
 
 	if err := ctrl.SetControllerReference(&runner, updated, r.Scheme); err != nil {
 		return pod, err
@@ -788,10 +1048,32 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		varRunVolumeMountPath = "/run"
 	)
 
-	if containerMode == "kubernetes" {
+	if err := validateContainerMode(containerMode); err != nil {
+		return corev1.Pod{}, err
+	}
+
+	switch containerMode {
+	case containerModeKubernetes, containerModeBuildkit:
 		dockerdInRunner = false
 		dockerEnabled = false
 		dockerdInRunnerPrivileged = false
+	case containerModeSysbox:
+		// sysbox-runc lets dockerd run within the runner container without it being privileged
+		dockerdInRunner = true
+		dockerdInRunnerPrivileged = false
+	}
+
+	windows := runnerSpec.OS == "windows"
+
+	// Windows containers can neither run dockerd in a sidecar nor run privileged
+	if windows {
+		dockerdInRunner = false
+		dockerEnabled = false
+		dockerdInRunnerPrivileged = false
+
+		if d.WindowsRunnerImage != "" {
+			defaultRunnerImage = d.WindowsRunnerImage
+		}
 	}
 
 	template = *template.DeepCopy()
@@ -802,10 +1084,30 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 	if runnerSpec.GitHubAPICredentialsFrom != nil {
 		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, annotationKeyGitHubAPICredsSecret, runnerSpec.GitHubAPICredentialsFrom.SecretRef.Name)
 	}
+	if runnerSpec.GitHubAPITokenScope != nil {
+		scope, err := json.Marshal(runnerSpec.GitHubAPITokenScope)
+		if err != nil {
+			return corev1.Pod{}, err
+		}
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, annotationKeyGitHubAPITokenScope, string(scope))
+	}
+	if runnerSpec.DrainTimeout != nil {
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, AnnotationKeyDrainTimeout, runnerSpec.DrainTimeout.Duration.String())
+	}
+	if hooks := runnerSpec.LifecycleHooks; hooks != nil && hooks.PreDestroy != nil && hooks.PreDestroy.HTTP != nil {
+		hook, err := json.Marshal(hooks.PreDestroy.HTTP)
+		if err != nil {
+			return corev1.Pod{}, err
+		}
+		template.ObjectMeta.Annotations = CloneAndAddLabel(template.ObjectMeta.Annotations, AnnotationKeyPreDestroyHook, string(hook))
+	}
 
 	workDir := runnerSpec.WorkDir
 	if workDir == "" {
 		workDir = "/runner/_work"
+		if windows {
+			workDir = windowsRunnerWorkDir
+		}
 	}
 
 	var dockerRegistryMirror string
@@ -896,12 +1198,13 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}
 	}
 
-	if containerMode == "kubernetes" {
+	if containerMode == containerModeKubernetes || containerMode == containerModeSysbox || containerMode == containerModeBuildkit || windows {
 		if dockerdContainer != nil {
 			template.Spec.Containers = append(template.Spec.Containers[:dockerdContainerIndex], template.Spec.Containers[dockerdContainerIndex+1:]...)
-		}
-		if dockerdContainerIndex < runnerContainerIndex {
-			runnerContainerIndex--
+
+			if dockerdContainerIndex < runnerContainerIndex {
+				runnerContainerIndex--
+			}
 		}
 		dockerdContainer = nil
 		dockerdContainerIndex = -1
@@ -933,6 +1236,11 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 	}
 
 	runnerContainer.Env = append(runnerContainer.Env, env...)
+	lifecycleHookEnvs, err := runnerLifecycleHookEnvs(runnerSpec.LifecycleHooks)
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+	runnerContainer.Env = append(runnerContainer.Env, lifecycleHookEnvs...)
 	if containerMode == "kubernetes" {
 		hookEnvs, err := runnerHookEnvs(&template)
 		if err != nil {
@@ -983,6 +1291,15 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}...)
 	}
 
+	// The env of the runner container in the template takes precedence, so that a runner can opt out of the cache server
+	if d.ActionsCacheURL != "" {
+		for _, name := range []string{EnvVarActionsCacheURL, EnvVarActionsResultsURL} {
+			if ok, _ := envVarPresent(name, runnerContainer.Env); !ok {
+				runnerContainer.Env = append(runnerContainer.Env, corev1.EnvVar{Name: name, Value: d.ActionsCacheURL})
+			}
+		}
+	}
+
 	//
 	// /runner must be generated on runtime from /runnertmp embedded in the container image.
 	//
@@ -996,6 +1313,9 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 
 	runnerVolumeName := "runner"
 	runnerVolumeMountPath := "/runner"
+	if windows {
+		runnerVolumeMountPath = windowsRunnerVolumeMountPath
+	}
 	runnerVolumeEmptyDir := &corev1.EmptyDirVolumeSource{}
 
 	if runnerSpec.VolumeStorageMedium != nil {
@@ -1261,6 +1581,18 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}
 	}
 
+	if containerMode == containerModeBuildkit {
+		applyBuildkitContainerMode(pod, runnerContainer, d.BuildkitImage)
+	}
+
+	if name := containerModeRuntimeClassName(containerMode); name != "" && pod.Spec.RuntimeClassName == nil {
+		pod.Spec.RuntimeClassName = &name
+	}
+
+	if windows {
+		applyWindowsRunnerPodSettings(pod, runnerContainer)
+	}
+
 	if runnerContainerIndex == -1 {
 		pod.Spec.Containers = append([]corev1.Container{*runnerContainer}, pod.Spec.Containers...)
 
@@ -1279,9 +1611,55 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		}
 	}
 
+	applyLabelNodeSelectors(pod, runnerSpec.Labels, d)
+
+	applyNodeAutoscalerHints(pod, runnerSpec.NodeAutoscalerHints)
+
+	// Native sidecars are started before the runner container, and terminated by the kubelet after the runner container
+	// once ARC deletes the pod of the stopped runner.
+	for _, c := range runnerSpec.Sidecars {
+		c := *c.DeepCopy()
+		c.RestartPolicy = &containerRestartPolicyAlways
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, c)
+	}
+
+	applyRunnerGPU(pod, runnerSpec.GPU)
+
+	if runnerSpec.PodTemplatePatch != nil {
+		patched, err := applyPodTemplatePatch(*pod, runnerSpec.PodTemplatePatch.Raw)
+		if err != nil {
+			return corev1.Pod{}, fmt.Errorf("applying podTemplatePatch: %w", err)
+		}
+
+		return patched, nil
+	}
+
 	return *pod, nil
 }
 
+var containerRestartPolicyAlways = corev1.ContainerRestartPolicyAlways
+
+// applyPodTemplatePatch applies the strategic merge patch in the form of a PodTemplateSpec to the pod.
+// It works because a Pod and a PodTemplateSpec share the same metadata and spec fields.
+func applyPodTemplatePatch(pod corev1.Pod, patch []byte) (corev1.Pod, error) {
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, corev1.Pod{})
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+
+	var result corev1.Pod
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return corev1.Pod{}, err
+	}
+
+	return result, nil
+}
+
 func (r *RunnerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	name := "runner-controller"
 	if r.Name != "" {
@@ -1294,7 +1672,7 @@ func (r *RunnerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.Runner{}).
 		Owns(&corev1.Pod{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Observe(name, "Runner", r))
 }
 
 func addFinalizer(finalizers []string, finalizerName string) ([]string, bool) {
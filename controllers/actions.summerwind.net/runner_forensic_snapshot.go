@@ -0,0 +1,317 @@
+package actionssummerwindnet
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsmetrics"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	EventReasonForensicSnapshotCaptured = "ForensicSnapshotCaptured"
+	EventReasonForensicSnapshotFailed   = "ForensicSnapshotFailed"
+
+	// AnnotationKeyForensicSnapshot is the annotation on the runner that contains the URL of the forensic snapshot of its runner pod,
+	// or "failed" when the snapshot couldn't be uploaded, so that the snapshot is captured at most once per runner.
+	AnnotationKeyForensicSnapshot = annotationKeyPrefix + "forensic-snapshot"
+
+	forensicSnapshotFailed = "failed"
+
+	// forensicSnapshotTimeout bounds the capture and the upload of a snapshot, as they block the reconciliation of the runner.
+	forensicSnapshotTimeout = 2 * time.Minute
+
+	// forensicSnapshotLogLimitBytes is the maximum size of the logs of each container in the snapshot.
+	forensicSnapshotLogLimitBytes = 10 << 20
+)
+
+// forensicSnapshotCommands are the diagnostic commands run in a container of the runner pod that is still running.
+// Each command falls back to the tools available in the minimal images like busybox.
+// The containers of a pod share the network namespace, so the connections of the runner container are seen from any container.
+var forensicSnapshotCommands = []struct {
+	file    string
+	command []string
+}{
+	{file: "dmesg.txt", command: []string{"sh", "-c", "dmesg -T 2>/dev/null || dmesg"}},
+	{file: "ps.txt", command: []string{"sh", "-c", "ps auxww 2>/dev/null || ps -ef 2>/dev/null || ps"}},
+	{file: "network.txt", command: []string{"sh", "-c", "ss -tunap 2>/dev/null || netstat -tunap 2>/dev/null || cat /proc/net/tcp /proc/net/tcp6 /proc/net/udp /proc/net/udp6"}},
+}
+
+// PodDiagnostics reads the logs of the containers of the runner pods and runs the diagnostic commands in them.
+type PodDiagnostics interface {
+	Logs(ctx context.Context, namespace, pod, container string) ([]byte, error)
+	Exec(ctx context.Context, namespace, pod, container string, command []string) ([]byte, error)
+}
+
+// KubePodDiagnostics is the PodDiagnostics backed by the pods/log and the pods/exec subresources of the Kubernetes API.
+type KubePodDiagnostics struct {
+	Clientset kubernetes.Interface
+	Config    *rest.Config
+}
+
+func NewKubePodDiagnostics(config *rest.Config) (*KubePodDiagnostics, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubePodDiagnostics{Clientset: clientset, Config: config}, nil
+}
+
+func (d *KubePodDiagnostics) Logs(ctx context.Context, namespace, pod, container string) ([]byte, error) {
+	limitBytes := int64(forensicSnapshotLogLimitBytes)
+
+	return d.Clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:  container,
+		Timestamps: true,
+		LimitBytes: &limitBytes,
+	}).DoRaw(ctx)
+}
+
+func (d *KubePodDiagnostics) Exec(ctx context.Context, namespace, pod, container string, command []string) ([]byte, error) {
+	req := d.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(d.Config, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &out, Stderr: &out})
+
+	return out.Bytes(), err
+}
+
+// ForensicSnapshots uploads the forensic snapshots of the runner pods to an object storage.
+type ForensicSnapshots struct {
+	Store actionsmetrics.LogStore
+	// Prefix is prepended to the keys of the snapshots.
+	Prefix string
+	// URL is the URL of the object storage, which the links to the snapshots in the events are built from.
+	URL string
+
+	Pods PodDiagnostics
+}
+
+// NewForensicSnapshots returns the ForensicSnapshots uploading to the object storage of the URL, which is any URL supported by actionsmetrics.NewLogStore.
+func NewForensicSnapshots(ctx context.Context, rawURL, s3Endpoint string, config *rest.Config) (*ForensicSnapshots, error) {
+	store, prefix, err := actionsmetrics.NewLogStore(ctx, rawURL, s3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := NewKubePodDiagnostics(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForensicSnapshots{Store: store, Prefix: prefix, URL: rawURL, Pods: pods}, nil
+}
+
+// link returns the URL of the snapshot stored with the key.
+func (s *ForensicSnapshots) link(key string) string {
+	return strings.TrimSuffix(s.URL, "/") + "/" + strings.TrimPrefix(strings.TrimPrefix(key, s.Prefix), "/")
+}
+
+// forensicSnapshotContainer returns the container the diagnostic commands are run in, or an empty string when no container is running.
+func forensicSnapshotContainer(pod *corev1.Pod, container string) string {
+	running := map[string]bool{}
+	for _, status := range pod.Status.ContainerStatuses {
+		running[status.Name] = status.State.Running != nil
+	}
+
+	if container != "" {
+		if running[container] {
+			return container
+		}
+
+		return ""
+	}
+
+	if running["docker"] {
+		return "docker"
+	}
+
+	for _, c := range pod.Spec.Containers {
+		if running[c.Name] {
+			return c.Name
+		}
+	}
+
+	return ""
+}
+
+// redactPodForSnapshot returns the copy of the pod without the values of the environment variables,
+// which include the registration token of the runner, and without the managed fields.
+func redactPodForSnapshot(pod *corev1.Pod) *corev1.Pod {
+	redacted := pod.DeepCopy()
+	redacted.ManagedFields = nil
+
+	for _, containers := range [][]corev1.Container{redacted.Spec.InitContainers, redacted.Spec.Containers} {
+		for i := range containers {
+			for j := range containers[i].Env {
+				if containers[i].Env[j].Value != "" {
+					containers[i].Env[j].Value = "REDACTED"
+				}
+			}
+		}
+	}
+
+	return redacted
+}
+
+// captureForensicSnapshot archives the status of the runner pod, the logs of its containers, and the outputs of the diagnostic commands.
+// The failures to collect any of them are recorded in errors.txt of the archive instead of failing the snapshot.
+func captureForensicSnapshot(ctx context.Context, pods PodDiagnostics, pod *corev1.Pod, container string, now time.Time) ([]byte, error) {
+	var (
+		buf  bytes.Buffer
+		errs []string
+	)
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: now}); err != nil {
+			return err
+		}
+
+		_, err := tw.Write(content)
+
+		return err
+	}
+
+	podYAML, err := yaml.Marshal(redactPodForSnapshot(pod))
+	if err != nil {
+		return nil, fmt.Errorf("marshaling runner pod: %w", err)
+	}
+
+	if err := add("pod.yaml", podYAML); err != nil {
+		return nil, err
+	}
+
+	for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		logs, err := pods.Logs(ctx, pod.Namespace, pod.Name, c.Name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("logs of %s: %v", c.Name, err))
+			continue
+		}
+
+		if err := add(path.Join("logs", c.Name+".log"), logs); err != nil {
+			return nil, err
+		}
+	}
+
+	if exec := forensicSnapshotContainer(pod, container); exec == "" {
+		errs = append(errs, "no container of the runner pod is running to run the diagnostic commands in")
+	} else {
+		for _, cmd := range forensicSnapshotCommands {
+			out, err := pods.Exec(ctx, pod.Namespace, pod.Name, exec, cmd.command)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s in %s: %v", strings.Join(cmd.command, " "), exec, err))
+			}
+
+			if len(out) == 0 {
+				continue
+			}
+
+			if err := add(path.Join(exec, cmd.file), out); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		if err := add("errors.txt", []byte(strings.Join(errs, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// syncForensicSnapshot captures the forensic snapshot of the runner pod once its runner container exited with any of the exit codes
+// configured for the runner, uploads it, and links it from an event on the runner.
+// It's done at most once per runner, and a failure is reported with an event instead of failing the reconciliation,
+// as the runner pod is about to be replaced anyway.
+func (r *RunnerReconciler) syncForensicSnapshot(ctx context.Context, log logr.Logger, runner *v1alpha1.Runner, pod *corev1.Pod) {
+	s := r.ForensicSnapshots
+	if s == nil || runner.Spec.ForensicSnapshot == nil || pod == nil {
+		return
+	}
+
+	if _, ok := runner.Annotations[AnnotationKeyForensicSnapshot]; ok {
+		return
+	}
+
+	code := runnerContainerExitCode(pod)
+	if code == nil || !runner.Spec.ForensicSnapshot.Captures(*code) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, forensicSnapshotTimeout)
+	defer cancel()
+
+	now := time.Now()
+	key := path.Join(s.Prefix, runner.Namespace, runner.Name, now.UTC().Format("20060102T150405Z")+".tar.gz")
+
+	archive, err := captureForensicSnapshot(ctx, s.Pods, pod, runner.Spec.ForensicSnapshot.Container, now)
+	if err == nil {
+		err = s.Store.Put(ctx, key, "application/gzip", archive)
+	}
+
+	value := s.link(key)
+
+	if err != nil {
+		log.Error(err, "Failed to capture the forensic snapshot of the runner pod", "exitCode", *code)
+		r.Recorder.Eventf(runner, corev1.EventTypeWarning, EventReasonForensicSnapshotFailed, "Failed to capture the forensic snapshot of the runner pod whose runner container exited with %d: %v", *code, err)
+
+		value = forensicSnapshotFailed
+	} else {
+		log.Info("Captured the forensic snapshot of the runner pod", "exitCode", *code, "url", value)
+		r.Recorder.Eventf(runner, corev1.EventTypeNormal, EventReasonForensicSnapshotCaptured, "Runner container exited with %d. Forensic snapshot of the runner pod: %s", *code, value)
+	}
+
+	updated := runner.DeepCopy()
+	updated.Annotations = CloneAndAddLabel(runner.Annotations, AnnotationKeyForensicSnapshot, value)
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(runner)); err != nil {
+		log.Error(err, "Failed to annotate the runner with its forensic snapshot")
+		return
+	}
+
+	*runner = *updated
+}
@@ -0,0 +1,205 @@
+package actionssummerwindnet
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsmetrics"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakePodDiagnostics struct {
+	execs []string
+}
+
+func (d *fakePodDiagnostics) Logs(ctx context.Context, namespace, pod, container string) ([]byte, error) {
+	if container == "docker" {
+		return nil, errors.New("container not found")
+	}
+
+	return []byte(container + " logs\n"), nil
+}
+
+func (d *fakePodDiagnostics) Exec(ctx context.Context, namespace, pod, container string, command []string) ([]byte, error) {
+	d.execs = append(d.execs, container)
+
+	return []byte(strings.Join(command, " ") + "\n"), nil
+}
+
+type memorySnapshotStore map[string][]byte
+
+func (s memorySnapshotStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	s[key] = body
+	return nil
+}
+
+func (s memorySnapshotStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func (s memorySnapshotStore) List(ctx context.Context, prefix string) ([]actionsmetrics.StoredLog, error) {
+	return nil, nil
+}
+
+func (s memorySnapshotStore) Delete(ctx context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func untarSnapshot(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		files[h.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestSyncForensicSnapshot(t *testing.T) {
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-runner", Namespace: "default"},
+		Spec: v1alpha1.RunnerSpec{
+			RunnerPodSpec: v1alpha1.RunnerPodSpec{
+				ForensicSnapshot: &v1alpha1.ForensicSnapshot{ExitCodes: []int32{137}},
+			},
+		},
+	}
+
+	newPod := func(exitCode int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-runner", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "runner", Env: []corev1.EnvVar{{Name: "RUNNER_TOKEN", Value: "registration-token"}}},
+					{Name: "docker"},
+				},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "runner", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}}},
+					{Name: "docker", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				},
+			},
+		}
+	}
+
+	store := memorySnapshotStore{}
+	pods := &fakePodDiagnostics{}
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerReconciler{
+		Client:   clientfake.NewClientBuilder().WithScheme(sc).WithObjects(runner.DeepCopy()).Build(),
+		Recorder: recorder,
+		ForensicSnapshots: &ForensicSnapshots{
+			Store:  store,
+			Prefix: "arc",
+			URL:    "s3://ci-forensics/arc",
+			Pods:   pods,
+		},
+	}
+
+	ctx := context.Background()
+
+	var got v1alpha1.Runner
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &got))
+
+	// The runner container exited with an exit code not configured for the snapshot
+	r.syncForensicSnapshot(ctx, logr.Discard(), &got, newPod(1))
+	require.Empty(t, store)
+
+	r.syncForensicSnapshot(ctx, logr.Discard(), &got, newPod(137))
+	require.Len(t, store, 1)
+
+	var key string
+	for k := range store {
+		key = k
+	}
+	require.True(t, strings.HasPrefix(key, "arc/default/example-runner/"), key)
+	require.True(t, strings.HasSuffix(key, ".tar.gz"), key)
+
+	link := "s3://ci-forensics/arc/" + strings.TrimPrefix(key, "arc/")
+	require.Equal(t, link, got.Annotations[AnnotationKeyForensicSnapshot])
+	require.Contains(t, <-recorder.Events, link)
+
+	files := untarSnapshot(t, store[key])
+	require.Equal(t, "runner logs\n", files["logs/runner.log"])
+	require.Contains(t, files["docker/dmesg.txt"], "dmesg")
+	require.Contains(t, files["docker/ps.txt"], "ps aux")
+	require.Contains(t, files["docker/network.txt"], "netstat")
+	require.Contains(t, files["errors.txt"], "logs of docker: container not found")
+	require.Contains(t, files["pod.yaml"], "exitCode: 137")
+	require.NotContains(t, files["pod.yaml"], "registration-token")
+	require.Equal(t, []string{"docker", "docker", "docker"}, pods.execs)
+
+	// The snapshot is captured at most once per runner
+	r.syncForensicSnapshot(ctx, logr.Discard(), &got, newPod(137))
+	require.Len(t, store, 1)
+
+	var persisted v1alpha1.Runner
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &persisted))
+	require.Equal(t, link, persisted.Annotations[AnnotationKeyForensicSnapshot])
+}
+
+func TestForensicSnapshotContainer(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "runner"}, {Name: "sidecar"}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "runner", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137}}},
+				{Name: "sidecar", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+
+	require.Equal(t, "sidecar", forensicSnapshotContainer(pod, ""))
+	require.Equal(t, "", forensicSnapshotContainer(pod, "runner"), "the commands can't be run in the terminated container")
+	require.Equal(t, "sidecar", forensicSnapshotContainer(pod, "sidecar"))
+}
+
+func TestForensicSnapshotValidate(t *testing.T) {
+	spec := &v1alpha1.RunnerSpec{
+		RunnerConfig: v1alpha1.RunnerConfig{Repository: "test/valid"},
+		RunnerPodSpec: v1alpha1.RunnerPodSpec{
+			ForensicSnapshot: &v1alpha1.ForensicSnapshot{ExitCodes: []int32{0, 137, 256}},
+		},
+	}
+
+	errs := spec.Validate(nil)
+	require.Len(t, errs, 2)
+	require.Equal(t, "forensicSnapshot.exitCodes[0]", errs[0].Field)
+	require.Equal(t, "forensicSnapshot.exitCodes[2]", errs[1].Field)
+
+	require.True(t, spec.ForensicSnapshot.Captures(137))
+	require.False(t, spec.ForensicSnapshot.Captures(1))
+}
@@ -0,0 +1,134 @@
+package actionssummerwindnet
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// EnvVarRunnerGPUDrainTimeout is the number of seconds the graceful stop of the runner container waits
+	// for the processes using the GPUs to exit.
+	EnvVarRunnerGPUDrainTimeout = "RUNNER_GPU_DRAIN_TIMEOUT"
+
+	envVarNVIDIAVisibleDevices     = "NVIDIA_VISIBLE_DEVICES"
+	envVarNVIDIADriverCapabilities = "NVIDIA_DRIVER_CAPABILITIES"
+
+	defaultGPURuntimeClassName = "nvidia"
+	DefaultGPUDrainTimeout     = 5 * time.Minute
+
+	// gpuTerminationGracePeriodMargin is added to the GPU drain timeout for the rest of the graceful stop of the runner.
+	gpuTerminationGracePeriodMargin = 60 * time.Second
+)
+
+// applyRunnerGPU requests the GPUs for the runner container, and sets up the runtime class, the tolerations,
+// and the environment variables of the runner pod for the GPUs.
+func applyRunnerGPU(pod *corev1.Pod, g *v1alpha1.RunnerGPU) {
+	if g == nil {
+		return
+	}
+
+	nvidia := g.Vendor == "" || g.Vendor == v1alpha1.GPUVendorNVIDIA
+
+	resourceName := g.ResourceName()
+	count := *resource.NewQuantity(int64(g.Count), resource.DecimalSI)
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+
+		if c.Name != containerName {
+			// The other containers would see all the GPUs of the node with the nvidia runtime and a CUDA image
+			if nvidia && !requestsGPU(c) {
+				if ok, _ := envVarPresent(envVarNVIDIAVisibleDevices, c.Env); !ok {
+					c.Env = append(c.Env, corev1.EnvVar{Name: envVarNVIDIAVisibleDevices, Value: "void"})
+				}
+			}
+			continue
+		}
+
+		// Extended resources default the requests to the limits
+		c.Resources.Limits = c.Resources.Limits.DeepCopy()
+		if c.Resources.Limits == nil {
+			c.Resources.Limits = corev1.ResourceList{}
+		}
+		c.Resources.Limits[resourceName] = count
+		if _, ok := c.Resources.Requests[resourceName]; ok {
+			c.Resources.Requests = c.Resources.Requests.DeepCopy()
+			c.Resources.Requests[resourceName] = count
+		}
+
+		if nvidia {
+			// nvidia-smi needs the utility capability, which the graceful stop uses to wait for the processes using the GPUs
+			if ok, _ := envVarPresent(envVarNVIDIADriverCapabilities, c.Env); !ok {
+				c.Env = append(c.Env, corev1.EnvVar{Name: envVarNVIDIADriverCapabilities, Value: "compute,utility"})
+			}
+
+			if ok, _ := envVarPresent(EnvVarRunnerGPUDrainTimeout, c.Env); !ok {
+				c.Env = append(c.Env, corev1.EnvVar{Name: EnvVarRunnerGPUDrainTimeout, Value: strconv.Itoa(int(gpuDrainTimeout(g).Seconds()))})
+			}
+		}
+	}
+
+	switch {
+	case g.RuntimeClassName != nil:
+		if *g.RuntimeClassName != "" {
+			name := *g.RuntimeClassName
+			pod.Spec.RuntimeClassName = &name
+		}
+	case nvidia && pod.Spec.RuntimeClassName == nil:
+		name := defaultGPURuntimeClassName
+		pod.Spec.RuntimeClassName = &name
+	}
+
+	// The GPU nodes are usually tainted with the resource name of the GPUs, like nvidia.com/gpu
+	taintKey := string(resourceName)
+	if nvidia {
+		taintKey = "nvidia.com/gpu"
+	}
+
+	var tolerated bool
+	for _, t := range pod.Spec.Tolerations {
+		if t.Key == taintKey {
+			tolerated = true
+			break
+		}
+	}
+	if !tolerated {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, corev1.Toleration{
+			Key:      taintKey,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		})
+	}
+
+	if nvidia && pod.Spec.TerminationGracePeriodSeconds == nil {
+		seconds := int64((gpuDrainTimeout(g) + gpuTerminationGracePeriodMargin).Seconds())
+		pod.Spec.TerminationGracePeriodSeconds = &seconds
+	}
+}
+
+func gpuDrainTimeout(g *v1alpha1.RunnerGPU) time.Duration {
+	if g.DrainTimeout != nil {
+		return g.DrainTimeout.Duration
+	}
+
+	return DefaultGPUDrainTimeout
+}
+
+// requestsGPU returns true if the container requests any of the GPUs by itself.
+func requestsGPU(c *corev1.Container) bool {
+	for name := range c.Resources.Limits {
+		switch {
+		case name == "nvidia.com/gpu", name == "amd.com/gpu", name == "gpu.intel.com/i915":
+			return true
+		case strings.HasPrefix(string(name), "nvidia.com/mig-"):
+			return true
+		}
+	}
+
+	return false
+}
@@ -41,6 +41,14 @@ func tickRunnerGracefulStop(ctx context.Context, retryDelay time.Duration, log l
 		return nil, &ctrl.Result{}, err
 	}
 
+	// The runner no longer runs jobs once unregistered, so the preDestroy hook sees the final state of the runner
+	updated, err := ensurePreDestroyHookCompleted(ctx, log, c, pod)
+	if err != nil {
+		log.Error(err, "Runner preDestroy hook failed. Retrying later before deleting the runner pod", "retryDelay", retryDelay)
+		return nil, &ctrl.Result{RequeueAfter: retryDelay}, nil
+	}
+	pod = updated
+
 	return pod, nil, nil
 }
 
@@ -158,6 +166,12 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 		// Happens e.g. when dind is in runner and run completes
 		log.Info("Runner pod has been stopped with a successful status.")
 	} else if pod != nil && pod.Annotations[AnnotationKeyRunnerCompletionWaitStartTimestamp] != "" {
+		if drainTimedOut(log, pod) {
+			logDrainTimeout(log, pod)
+
+			return nil, nil
+		}
+
 		ct := ephemeralRunnerContainerStatus(pod)
 		if ct == nil {
 			log.Info("Runner pod is annotated to wait for completion, and the runner container is not ephemeral")
@@ -241,6 +255,17 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 				return &ctrl.Result{}, err
 			}
 
+			drainDeadline, err := drainRunner(ctx, log, ghClient, c, enterprise, organization, repository, *runnerID, pod)
+			if err != nil {
+				return &ctrl.Result{}, err
+			}
+
+			if drainDeadline != nil && !time.Now().Before(*drainDeadline) {
+				logDrainTimeout(log, pod)
+
+				return nil, nil
+			}
+
 			// We want to prevent spamming the deletion attemps but returning ctrl.Result with RequeueAfter doesn't
 			// work as the reconcilation can happen earlier due to pod status update.
 			// For ephemeral runners, we can expect it to stop and unregister itself on completion.
@@ -252,6 +277,11 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 					return &ctrl.Result{}, err
 				}
 
+				// Come back on the drain deadline in case the runner doesn't complete by then.
+				if drainDeadline != nil {
+					return &ctrl.Result{RequeueAfter: time.Until(*drainDeadline)}, nil
+				}
+
 				return &ctrl.Result{}, nil
 			}
 
@@ -259,6 +289,9 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 			// Otherwise we may end up spamming 422 errors,
 			// each call consuming GitHub API rate limit
 			// https://github.com/actions/actions-runner-controller/pull/1167#issuecomment-1064213271
+			if drainDeadline != nil && time.Until(*drainDeadline) < retryDelay {
+				return &ctrl.Result{RequeueAfter: time.Until(*drainDeadline)}, nil
+			}
 			return &ctrl.Result{RequeueAfter: retryDelay}, nil
 		}
 
@@ -294,6 +327,83 @@ func ensureRunnerUnregistration(ctx context.Context, retryDelay time.Duration, l
 	return nil, nil
 }
 
+// drainRunner removes the custom labels of the busy runner when the runner pod has a drain timeout,
+// so that the runner isn't assigned another job while ARC waits for the current one to complete.
+// It returns the time until which ARC waits for the job, or nil when the runner pod has no drain timeout.
+func drainRunner(ctx context.Context, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository string, runnerID int64, pod *corev1.Pod) (*time.Time, error) {
+	timeout, ok := podDrainTimeout(log, pod)
+	if !ok {
+		return nil, nil
+	}
+
+	if _, ok := getAnnotation(pod, AnnotationKeyDrainStartTimestamp); !ok {
+		// This is best-effort. The runner can still complete its job and get unregistered without its labels removed.
+		if err := ghClient.RemoveRunnerCustomLabels(ctx, enterprise, organization, repository, runnerID); err != nil {
+			log.Error(err, "Failed to remove the custom labels of the busy runner. It may be assigned another job while draining")
+		}
+
+		updated, err := annotatePodOnce(ctx, c, log, pod, AnnotationKeyDrainStartTimestamp, time.Now().Format(time.RFC3339))
+		if err != nil {
+			return nil, err
+		}
+		pod = updated
+
+		log.Info("Draining busy runner. The runner pod is deleted once the runner completes its job, or the drain timeout elapses", "drainTimeout", timeout)
+	}
+
+	start, err := time.Parse(time.RFC3339, pod.Annotations[AnnotationKeyDrainStartTimestamp])
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := start.Add(timeout)
+
+	return &deadline, nil
+}
+
+// drainTimedOut returns true when the runner has been draining for longer than the drain timeout of the runner pod.
+func drainTimedOut(log logr.Logger, pod *corev1.Pod) bool {
+	timeout, ok := podDrainTimeout(log, pod)
+	if !ok {
+		return false
+	}
+
+	ts, ok := getAnnotation(pod, AnnotationKeyDrainStartTimestamp)
+	if !ok {
+		return false
+	}
+
+	start, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		log.Error(err, "Ignoring the invalid drain start timestamp", "drainStartTimestamp", ts)
+		return false
+	}
+
+	return time.Since(start) >= timeout
+}
+
+func logDrainTimeout(log logr.Logger, pod *corev1.Pod) {
+	log.Info("Deleting runner pod anyway because the runner did not complete its job within the drain timeout. "+
+		"The job is going to fail. You may need to remove the runner on GitHub UI.",
+		"drainTimeout", pod.Annotations[AnnotationKeyDrainTimeout],
+	)
+}
+
+func podDrainTimeout(log logr.Logger, pod *corev1.Pod) (time.Duration, bool) {
+	v, ok := getAnnotation(pod, AnnotationKeyDrainTimeout)
+	if !ok {
+		return 0, false
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil {
+		log.Error(err, "Ignoring the invalid drain timeout", "drainTimeout", v)
+		return 0, false
+	}
+
+	return timeout, true
+}
+
 func ensureRunnerPodRegistered(ctx context.Context, log logr.Logger, ghClient *github.Client, c client.Client, enterprise, organization, repository, runner string, pod *corev1.Pod) (*corev1.Pod, *ctrl.Result, error) {
 	_, hasRunnerID := getAnnotation(pod, AnnotationKeyRunnerID)
 	if runnerPodOrContainerIsStopped(pod) || hasRunnerID {
@@ -0,0 +1,117 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	identityVolumeName = "identity-token"
+
+	// identityTokenDir is where the projected service account token of the runner identity is mounted in the runner container.
+	identityTokenDir  = "/var/run/secrets/actions-runner-controller/identity"
+	identityTokenPath = "token"
+
+	// EnvVarIdentityTokenFile is the environment variable of the runner container pointing to the OIDC identity token of the runner pod.
+	EnvVarIdentityTokenFile = "ACTIONS_RUNNER_IDENTITY_TOKEN_FILE"
+)
+
+// The annotations on the runner pod with an identity that bind the pod, and so its identity token, to the workflow job it runs.
+// They are added once the runner status update hook reports the workflow of the job assigned to the runner.
+const (
+	AnnotationKeyWorkflowRepository = annotationKeyPrefix + "workflow-repository"
+	AnnotationKeyWorkflowName       = annotationKeyPrefix + "workflow-name"
+	AnnotationKeyWorkflowRunID      = annotationKeyPrefix + "workflow-run-id"
+	AnnotationKeyWorkflowJob        = annotationKeyPrefix + "workflow-job"
+)
+
+// applyRunnerIdentity adds the projected service account token of the identity to the runner container.
+// The kubelet binds the token to the pod and rotates it before it expires.
+func applyRunnerIdentity(pod *corev1.Pod, identity *v1alpha1.RunnerIdentity) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: identityVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+							Audience:          identity.Audience,
+							ExpirationSeconds: identity.ExpirationSeconds,
+							Path:              identityTokenPath,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+
+		if c.Name != containerName {
+			continue
+		}
+
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      identityVolumeName,
+			MountPath: identityTokenDir,
+			ReadOnly:  true,
+		})
+
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  EnvVarIdentityTokenFile,
+			Value: filepath.Join(identityTokenDir, identityTokenPath),
+		})
+	}
+}
+
+// workflowAnnotations returns the annotations binding the runner pod to the workflow job reported by the runner status update hook.
+func workflowAnnotations(status *v1alpha1.WorkflowStatus) map[string]string {
+	return map[string]string{
+		AnnotationKeyWorkflowRepository: status.Repository,
+		AnnotationKeyWorkflowName:       status.Name,
+		AnnotationKeyWorkflowRunID:      status.RunID,
+		AnnotationKeyWorkflowJob:        status.Job,
+	}
+}
+
+// syncRunnerPodWorkflowAnnotations annotates the runner pod with an identity with the workflow job assigned to the runner,
+// so that the services verifying its identity token can tell which repository and workflow the token is used for.
+// The annotations are updated on every job the pod of a non-ephemeral runner runs.
+func syncRunnerPodWorkflowAnnotations(ctx context.Context, c client.Client, log logr.Logger, runner *v1alpha1.Runner, pod *corev1.Pod) error {
+	if runner.Spec.Identity == nil || runner.Status.WorkflowStatus == nil || runner.Status.WorkflowStatus.Repository == "" {
+		return nil
+	}
+
+	var updated *corev1.Pod
+
+	for k, v := range workflowAnnotations(runner.Status.WorkflowStatus) {
+		if cur, ok := pod.Annotations[k]; ok && cur == v {
+			continue
+		}
+
+		if updated == nil {
+			updated = pod.DeepCopy()
+		}
+
+		setAnnotation(&updated.ObjectMeta, k, v)
+	}
+
+	if updated == nil {
+		return nil
+	}
+
+	if err := c.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		log.Error(err, "Failed to annotate the runner pod with its workflow")
+		return err
+	}
+
+	log.V(2).Info("Annotated the runner pod with its workflow", "repository", runner.Status.WorkflowStatus.Repository, "workflow", runner.Status.WorkflowStatus.Name)
+
+	return nil
+}
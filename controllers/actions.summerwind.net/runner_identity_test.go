@@ -0,0 +1,93 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyRunnerIdentity(t *testing.T) {
+	expirationSeconds := int64(900)
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "docker"},
+			},
+		},
+	}
+
+	applyRunnerIdentity(pod, &v1alpha1.RunnerIdentity{Audience: "sts.amazonaws.com", ExpirationSeconds: &expirationSeconds})
+
+	require.Len(t, pod.Spec.Volumes, 1)
+	token := pod.Spec.Volumes[0].Projected.Sources[0].ServiceAccountToken
+	require.Equal(t, "sts.amazonaws.com", token.Audience)
+	require.Equal(t, &expirationSeconds, token.ExpirationSeconds)
+
+	runner := pod.Spec.Containers[0]
+	require.Equal(t, []corev1.VolumeMount{{Name: "identity-token", MountPath: "/var/run/secrets/actions-runner-controller/identity", ReadOnly: true}}, runner.VolumeMounts)
+	require.Equal(t, []corev1.EnvVar{{Name: "ACTIONS_RUNNER_IDENTITY_TOKEN_FILE", Value: "/var/run/secrets/actions-runner-controller/identity/token"}}, runner.Env)
+
+	// The token isn't exposed to the sidecars
+	require.Empty(t, pod.Spec.Containers[1].VolumeMounts)
+	require.Empty(t, pod.Spec.Containers[1].Env)
+}
+
+func TestSyncRunnerPodWorkflowAnnotations(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner"}}
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+
+	runner := &v1alpha1.Runner{
+		Spec: v1alpha1.RunnerSpec{
+			RunnerPodSpec: v1alpha1.RunnerPodSpec{Identity: &v1alpha1.RunnerIdentity{Audience: "vault"}},
+		},
+	}
+
+	get := func() *corev1.Pod {
+		var p corev1.Pod
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "runner"}, &p))
+		return &p
+	}
+
+	// No job is assigned yet
+	require.NoError(t, syncRunnerPodWorkflowAnnotations(ctx, c, logr.Discard(), runner, get()))
+	require.Empty(t, get().Annotations)
+
+	runner.Status.WorkflowStatus = &v1alpha1.WorkflowStatus{Repository: "owner/repo", Name: "CI", RunID: "123", Job: "build"}
+
+	require.NoError(t, syncRunnerPodWorkflowAnnotations(ctx, c, logr.Discard(), runner, get()))
+	require.Equal(t, map[string]string{
+		"actions-runner/workflow-repository": "owner/repo",
+		"actions-runner/workflow-name":       "CI",
+		"actions-runner/workflow-run-id":     "123",
+		"actions-runner/workflow-job":        "build",
+	}, get().Annotations)
+
+	// The next job of a non-ephemeral runner
+	runner.Status.WorkflowStatus.RunID = "124"
+
+	require.NoError(t, syncRunnerPodWorkflowAnnotations(ctx, c, logr.Discard(), runner, get()))
+	require.Equal(t, "124", get().Annotations[AnnotationKeyWorkflowRunID])
+
+	// The runners without an identity aren't annotated
+	runner.Spec.Identity = nil
+	runner.Status.WorkflowStatus.RunID = "125"
+
+	require.NoError(t, syncRunnerPodWorkflowAnnotations(ctx, c, logr.Discard(), runner, get()))
+	require.Equal(t, "124", get().Annotations[AnnotationKeyWorkflowRunID])
+}
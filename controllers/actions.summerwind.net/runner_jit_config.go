@@ -0,0 +1,111 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// jitConfigSecretKey is the key of the just-in-time configuration in the secret created per runner.
+const jitConfigSecretKey = "jitConfig"
+
+func jitConfigSecretName(runnerName string) string {
+	return runnerName + "-jitconfig"
+}
+
+// ensureJITConfigSecret returns the secret containing the just-in-time configuration of the runner.
+// The configuration is generated and the runner is registered to GitHub when the secret is missing.
+// The secret is owned by the runner so that it's garbage-collected along with the runner.
+func (r *RunnerReconciler) ensureJITConfigSecret(ctx context.Context, runner v1alpha1.Runner, log logr.Logger) (*corev1.Secret, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: jitConfigSecretName(runner.Name)}, &secret); err == nil {
+		return &secret, nil
+	} else if !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ghc.GenerateJITConfig(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name, runner.Spec.Group, runner.Spec.WorkDir, runner.Spec.Labels)
+	if err != nil {
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedGenerateJITConfig", "Generating just-in-time configuration failed")
+		log.Error(err, "Failed to generate just-in-time configuration")
+		return nil, err
+	}
+
+	runnerID := config.Runner.GetID()
+
+	secret = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jitConfigSecretName(runner.Name),
+			Namespace: runner.Namespace,
+			Annotations: map[string]string{
+				AnnotationKeyRunnerID: strconv.FormatInt(runnerID, 10),
+			},
+		},
+		Data: map[string][]byte{
+			jitConfigSecretKey: []byte(config.EncodedJITConfig),
+		},
+	}
+
+	if err := ctrl.SetControllerReference(&runner, &secret, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	if err := r.Create(ctx, &secret); err != nil {
+		// Otherwise the runner with the same name can't be registered again until GitHub removes the offline runner
+		if err := ghc.RemoveRunner(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runnerID); err != nil {
+			log.Error(err, "Failed to remove the runner registered with the just-in-time configuration that couldn't be saved", "runnerId", runnerID)
+		}
+
+		return nil, err
+	}
+
+	// The runner is registered to GitHub along with the generation of the configuration
+	metrics.ObserveRunnerRegistrationDuration(runner.Namespace, runner.Labels[LabelKeyRunnerDeploymentName], "", runner.CreationTimestamp.Time)
+
+	r.Recorder.Event(&runner, corev1.EventTypeNormal, "JITConfigGenerated", "Successfully generated just-in-time configuration")
+	log.Info("Generated just-in-time configuration", "runnerId", runnerID)
+
+	return &secret, nil
+}
+
+// mutatePodForJITConfig is the counterpart of mutatePod for the runners using the just-in-time configurations.
+// The runner reads the configuration from the secret instead of registering itself with the registration token.
+func mutatePodForJITConfig(pod *corev1.Pod, secretName string) *corev1.Pod {
+	updated := pod.DeepCopy()
+
+	if getRunnerEnv(pod, EnvVarRunnerName) == "" {
+		setRunnerEnv(updated, EnvVarRunnerName, pod.ObjectMeta.Name)
+	}
+
+	for i := range updated.Spec.Containers {
+		c := &updated.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name: EnvVarRunnerJITConfig,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  jitConfigSecretKey,
+				},
+			},
+		})
+	}
+
+	return updated
+}
@@ -0,0 +1,454 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/pkg/vault"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// JITConfigDeliverySecret delivers the just-in-time configurations via the Kubernetes Secrets created per runner.
+	JITConfigDeliverySecret = "secret"
+	// JITConfigDeliveryVault delivers the just-in-time configurations via the single-use wrapping tokens of Vault,
+	// which an init container of the runner pods unwraps into an in-memory volume.
+	JITConfigDeliveryVault = "vault"
+	// JITConfigDeliverySecretsStoreCSI delivers the just-in-time configurations via the volumes of the Secrets Store CSI driver,
+	// which its Vault provider reads from Vault with the service accounts of the runner pods.
+	JITConfigDeliverySecretsStoreCSI = "secrets-store-csi"
+
+	DefaultJITConfigDeliveryTTL  = 15 * time.Minute
+	DefaultJITConfigVaultKVMount = "secret"
+
+	// jitConfigVaultPathPrefix is the path under the KV mount the just-in-time configurations are kept in, per namespace and runner.
+	jitConfigVaultPathPrefix = "actions-runner-controller"
+
+	// JITConfigCSIRoleNamespacePlaceholder is replaced with the namespace of the runner in the Vault role of the runner pods,
+	// so that the runner pods of each namespace log in as the role that can only read the configurations of the namespace.
+	JITConfigCSIRoleNamespacePlaceholder = "{namespace}"
+
+	jitConfigUnwrapContainerName = "jitconfig-unwrap"
+	jitConfigVolumeName          = "jitconfig"
+	jitConfigMountPath           = "/var/run/secrets/actions-runner-jitconfig"
+	jitConfigFileName            = "jitconfig"
+
+	// annotationKeyJITConfigVaultPath is the annotation on the runner pod that contains the Vault path of its just-in-time configuration,
+	// removed once the configuration is destroyed after the runner container started.
+	annotationKeyJITConfigVaultPath = annotationKeyPrefix + "jit-config-vault-path"
+
+	secretProviderClassAPIVersion = "secrets-store.csi.x-k8s.io/v1"
+	secretProviderClassKind       = "SecretProviderClass"
+)
+
+// jitConfigUnwrapScript unwraps the just-in-time configuration with the wrapping token into the in-memory volume the runner container reads it from.
+// The wrapping token can't be used again once unwrapped, so a leaked token is useless, and a stolen one fails the runner pod.
+const jitConfigUnwrapScript = `set -euo pipefail
+umask 077
+
+curl -fsS -X POST -H "X-Vault-Token: ${VAULT_WRAPPING_TOKEN}" "${VAULT_ADDR%/}/v1/sys/wrapping/unwrap" \
+  | jq -j .data.jitConfig > ` + jitConfigMountPath + `/` + jitConfigFileName + `
+`
+
+// JITConfigDelivery configures how the just-in-time configurations of the runners are handed over to the runner pods.
+//
+// With Vault, the configuration generated for a runner is kept in the KV version 2 secrets engine, set to be deleted after TTL,
+// instead of a Kubernetes Secret, so that it never lands in etcd. It's destroyed once the runner container started,
+// and the runner pod not started within TTL is replaced with a new one along with a new configuration.
+type JITConfigDelivery struct {
+	// Mechanism is one of JITConfigDeliverySecret, JITConfigDeliveryVault, and JITConfigDeliverySecretsStoreCSI.
+	// Defaults to JITConfigDeliverySecret. The runners with jitConfigDelivery use theirs instead.
+	Mechanism string
+
+	// Vault is the client of Vault to keep the configurations in. Required unless Mechanism is JITConfigDeliverySecret.
+	Vault *vault.Client
+	// KVMount is the mount path of the KV version 2 secrets engine. Defaults to DefaultJITConfigVaultKVMount.
+	KVMount string
+	// TTL is how long the configurations can be read. Defaults to DefaultJITConfigDeliveryTTL.
+	TTL time.Duration
+
+	// CSIRole is the Vault role the Vault provider of the Secrets Store CSI driver logs in as, via the Kubernetes auth method,
+	// with the service accounts of the runner pods. Required by JITConfigDeliverySecretsStoreCSI.
+	// JITConfigCSIRoleNamespacePlaceholder in it is replaced with the namespace of the runner.
+	CSIRole string
+	// CSIAuthPath is the mount path of the Kubernetes auth method the provider logs in via. Defaults to the default of the provider.
+	CSIAuthPath string
+}
+
+// Validate returns an error when the default mechanism is unknown, or its requirements are missing.
+func (d JITConfigDelivery) Validate() error {
+	return d.validate(d.Mechanism)
+}
+
+func (d JITConfigDelivery) validate(mechanism string) error {
+	switch mechanism {
+	case "", JITConfigDeliverySecret:
+		return nil
+	case JITConfigDeliveryVault, JITConfigDeliverySecretsStoreCSI:
+	default:
+		return fmt.Errorf("unsupported just-in-time configuration delivery %q: must be one of %s, %s, or %s", mechanism, JITConfigDeliverySecret, JITConfigDeliveryVault, JITConfigDeliverySecretsStoreCSI)
+	}
+
+	if d.Vault == nil || d.Vault.Address == "" {
+		return fmt.Errorf("vault address is required to deliver just-in-time configurations via %s", mechanism)
+	}
+
+	if mechanism == JITConfigDeliverySecretsStoreCSI && d.CSIRole == "" {
+		return fmt.Errorf("vault role of the runner pods is required to deliver just-in-time configurations via %s", mechanism)
+	}
+
+	return nil
+}
+
+// mechanism returns how the just-in-time configuration of the runner is delivered, which is either of the runner or the default.
+func (d JITConfigDelivery) mechanism(runner *v1alpha1.Runner) string {
+	if runner.Spec.JITConfigDelivery != "" {
+		return runner.Spec.JITConfigDelivery
+	}
+
+	return d.Mechanism
+}
+
+// fromVault returns true when the configuration of the runner is kept in Vault rather than a Kubernetes Secret.
+func (d JITConfigDelivery) fromVault(runner *v1alpha1.Runner) bool {
+	m := d.mechanism(runner)
+
+	return m == JITConfigDeliveryVault || m == JITConfigDeliverySecretsStoreCSI
+}
+
+func (d JITConfigDelivery) ttl() time.Duration {
+	if d.TTL <= 0 {
+		return DefaultJITConfigDeliveryTTL
+	}
+
+	return d.TTL
+}
+
+// csiRole returns the Vault role the runner pods of the runner log in as.
+func (d JITConfigDelivery) csiRole(runner *v1alpha1.Runner) string {
+	return strings.ReplaceAll(d.CSIRole, JITConfigCSIRoleNamespacePlaceholder, runner.Namespace)
+}
+
+// vaultPath returns the path of the configuration of the runner under the data or the metadata of the KV mount.
+func (d JITConfigDelivery) vaultPath(kind string, runner *v1alpha1.Runner) string {
+	mount := d.KVMount
+	if mount == "" {
+		mount = DefaultJITConfigVaultKVMount
+	}
+
+	return strings.Join([]string{strings.Trim(mount, "/"), kind, jitConfigVaultPathPrefix, runner.Namespace, runner.Name}, "/")
+}
+
+// deliverJITConfig hands the just-in-time configuration of the runner over to the runner pod about to be created via Vault.
+// The configuration kept in Vault is reused, so that the runner isn't registered again for the pod recreated before it started.
+func (r *RunnerReconciler) deliverJITConfig(ctx context.Context, runner *v1alpha1.Runner, pod *corev1.Pod, log logr.Logger) error {
+	d := r.JITConfigDelivery
+
+	mechanism := d.mechanism(runner)
+	if err := d.validate(mechanism); err != nil {
+		return err
+	}
+
+	config, runnerID, err := r.readJITConfig(ctx, runner)
+	if errors.Is(err, vault.ErrNotFound) {
+		config, runnerID, err = r.generateJITConfigIntoVault(ctx, runner, log)
+	}
+	if err != nil {
+		return err
+	}
+
+	if getRunnerEnv(pod, EnvVarRunnerName) == "" {
+		setRunnerEnv(pod, EnvVarRunnerName, pod.Name)
+	}
+
+	switch mechanism {
+	case JITConfigDeliveryVault:
+		token, err := d.Vault.Wrap(ctx, map[string]interface{}{"jitConfig": config}, d.ttl())
+		if err != nil {
+			return err
+		}
+
+		if err := applyJITConfigUnwrap(pod, d.Vault.Address, token); err != nil {
+			return err
+		}
+	case JITConfigDeliverySecretsStoreCSI:
+		spc, err := d.newSecretProviderClass(runner)
+		if err != nil {
+			return err
+		}
+
+		if err := ctrl.SetControllerReference(runner, spc, r.Scheme); err != nil {
+			return err
+		}
+
+		if err := r.Create(ctx, spc); err != nil && !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating SecretProviderClass %s: %w", spc.GetName(), err)
+		}
+
+		applyJITConfigCSIVolume(pod, spc.GetName())
+	}
+
+	pod.Annotations = CloneAndAddLabel(pod.Annotations, AnnotationKeyRunnerID, runnerID)
+	pod.Annotations = CloneAndAddLabel(pod.Annotations, annotationKeyJITConfigVaultPath, d.vaultPath("data", runner))
+
+	return nil
+}
+
+func (r *RunnerReconciler) readJITConfig(ctx context.Context, runner *v1alpha1.Runner) (string, string, error) {
+	data, err := r.JITConfigDelivery.Vault.Read(ctx, r.JITConfigDelivery.vaultPath("data", runner))
+	if err != nil {
+		return "", "", err
+	}
+
+	config, _ := data["jitConfig"].(string)
+	runnerID, _ := data["runnerId"].(string)
+
+	if config == "" || runnerID == "" {
+		return "", "", fmt.Errorf("just-in-time configuration in vault is missing jitConfig or runnerId")
+	}
+
+	return config, runnerID, nil
+}
+
+// generateJITConfigIntoVault generates the just-in-time configuration of the runner, registering the runner to GitHub,
+// and keeps it in Vault until it's read, or TTL elapses.
+func (r *RunnerReconciler) generateJITConfigIntoVault(ctx context.Context, runner *v1alpha1.Runner, log logr.Logger) (string, string, error) {
+	d := r.JITConfigDelivery
+
+	ghc, err := r.GitHubClient.InitForRunner(ctx, runner)
+	if err != nil {
+		return "", "", err
+	}
+
+	config, err := ghc.GenerateJITConfig(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name, runner.Spec.Group, runner.Spec.WorkDir, runner.Spec.Labels)
+	if err != nil {
+		r.Recorder.Event(runner, corev1.EventTypeWarning, "FailedGenerateJITConfig", "Generating just-in-time configuration failed")
+		log.Error(err, "Failed to generate just-in-time configuration")
+		return "", "", err
+	}
+
+	runnerID := strconv.FormatInt(config.Runner.GetID(), 10)
+
+	// Vault deletes the configuration after TTL even when ARC fails to destroy it
+	err = d.Vault.Write(ctx, d.vaultPath("metadata", runner), map[string]interface{}{
+		"delete_version_after": d.ttl().String(),
+	})
+	if err == nil {
+		err = d.Vault.Write(ctx, d.vaultPath("data", runner), map[string]interface{}{
+			"data": map[string]interface{}{
+				"jitConfig": config.EncodedJITConfig,
+				"runnerId":  runnerID,
+			},
+		})
+	}
+	if err != nil {
+		// Otherwise the runner with the same name can't be registered again until GitHub removes the offline runner
+		if err := ghc.RemoveRunner(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, config.Runner.GetID()); err != nil {
+			log.Error(err, "Failed to remove the runner registered with the just-in-time configuration that couldn't be saved", "runnerId", runnerID)
+		}
+
+		return "", "", err
+	}
+
+	// The runner is registered to GitHub along with the generation of the configuration
+	metrics.ObserveRunnerRegistrationDuration(runner.Namespace, runner.Labels[LabelKeyRunnerDeploymentName], "", runner.CreationTimestamp.Time)
+
+	r.Recorder.Event(runner, corev1.EventTypeNormal, "JITConfigGenerated", "Successfully generated just-in-time configuration")
+	log.Info("Generated just-in-time configuration into vault", "runnerId", runnerID)
+
+	return config.EncodedJITConfig, runnerID, nil
+}
+
+// syncDeliveredJITConfig destroys the just-in-time configuration in Vault once the runner container of the pod started,
+// as the runner has read it. The pod not started within TTL can't read the configuration anymore,
+// so it's deleted to be recreated with a new configuration, which unregisters the runner of the expired one.
+// It returns true when the pod is deleted.
+func (r *RunnerReconciler) syncDeliveredJITConfig(ctx context.Context, runner *v1alpha1.Runner, pod *corev1.Pod, log logr.Logger) (bool, error) {
+	path, ok := getAnnotation(pod, annotationKeyJITConfigVaultPath)
+	if !ok || !r.JITConfigDelivery.fromVault(runner) {
+		return false, nil
+	}
+
+	d := r.JITConfigDelivery
+
+	if !runnerContainerStarted(pod) {
+		if pod.DeletionTimestamp != nil || time.Since(pod.CreationTimestamp.Time) < d.ttl() {
+			return false, nil
+		}
+
+		if err := d.Vault.Delete(ctx, d.vaultPath("metadata", runner)); err != nil {
+			return false, err
+		}
+
+		if err := r.Delete(ctx, pod); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		log.Info("Deleted the runner pod that didn't start before the just-in-time configuration expired", "ttl", d.ttl())
+		r.Recorder.Event(runner, corev1.EventTypeWarning, "JITConfigExpired", fmt.Sprintf("Runner pod didn't start within %s after the just-in-time configuration was delivered", d.ttl()))
+
+		return true, nil
+	}
+
+	if err := d.Vault.Delete(ctx, d.vaultPath("metadata", runner)); err != nil {
+		return false, err
+	}
+
+	updated := pod.DeepCopy()
+	delete(updated.Annotations, annotationKeyJITConfigVaultPath)
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	log.V(1).Info("Destroyed the just-in-time configuration read by the runner", "path", path)
+
+	return false, nil
+}
+
+// destroyJITConfig destroys the just-in-time configuration of the deleted runner that may have been left unread in Vault.
+func (r *RunnerReconciler) destroyJITConfig(ctx context.Context, runner *v1alpha1.Runner) error {
+	if !runner.Spec.JITConfig || !r.JITConfigDelivery.fromVault(runner) {
+		return nil
+	}
+
+	return r.JITConfigDelivery.Vault.Delete(ctx, r.JITConfigDelivery.vaultPath("metadata", runner))
+}
+
+func runnerContainerStarted(pod *corev1.Pod) bool {
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Name == containerName {
+			return s.State.Running != nil || s.State.Terminated != nil || s.RestartCount > 0
+		}
+	}
+
+	return false
+}
+
+// applyJITConfigUnwrap adds the init container that unwraps the just-in-time configuration with the wrapping token
+// into the in-memory volume, and makes the runner container read it from there.
+// The init container runs with the runner image, which needs bash, curl, and jq like the ones ARC publishes.
+func applyJITConfigUnwrap(pod *corev1.Pod, vaultAddress, token string) error {
+	runner := runnerContainer(pod)
+	if runner == nil {
+		return fmt.Errorf("runner container %q is missing in the pod", containerName)
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      jitConfigVolumeName,
+		MountPath: jitConfigMountPath,
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: jitConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+		},
+	})
+
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+		Name:            jitConfigUnwrapContainerName,
+		Image:           runner.Image,
+		ImagePullPolicy: runner.ImagePullPolicy,
+		SecurityContext: runner.SecurityContext,
+		Command:         []string{"bash", "-c", jitConfigUnwrapScript},
+		Env: []corev1.EnvVar{
+			{Name: "VAULT_ADDR", Value: vaultAddress},
+			{Name: "VAULT_WRAPPING_TOKEN", Value: token},
+		},
+		VolumeMounts: []corev1.VolumeMount{mount},
+	})
+
+	runner.VolumeMounts = append(runner.VolumeMounts, mount)
+	runner.Env = append(runner.Env, corev1.EnvVar{Name: EnvVarRunnerJITConfigFile, Value: jitConfigMountPath + "/" + jitConfigFileName})
+
+	return nil
+}
+
+// applyJITConfigCSIVolume mounts the volume of the SecretProviderClass into the runner container, which reads the just-in-time configuration from it.
+func applyJITConfigCSIVolume(pod *corev1.Pod, secretProviderClass string) {
+	readOnly := true
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: jitConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			CSI: &corev1.CSIVolumeSource{
+				Driver:   secretsStoreCSIDriver,
+				ReadOnly: &readOnly,
+				VolumeAttributes: map[string]string{
+					"secretProviderClass": secretProviderClass,
+				},
+			},
+		},
+	})
+
+	if runner := runnerContainer(pod); runner != nil {
+		runner.VolumeMounts = append(runner.VolumeMounts, corev1.VolumeMount{
+			Name:      jitConfigVolumeName,
+			MountPath: jitConfigMountPath,
+			ReadOnly:  true,
+		})
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: EnvVarRunnerJITConfigFile, Value: jitConfigMountPath + "/" + jitConfigFileName})
+	}
+}
+
+// newSecretProviderClass returns the SecretProviderClass of the Vault provider reading the just-in-time configuration of the runner.
+// It's unstructured as ARC doesn't depend on the Secrets Store CSI driver API.
+func (d JITConfigDelivery) newSecretProviderClass(runner *v1alpha1.Runner) (*unstructured.Unstructured, error) {
+	objects, err := yaml.Marshal([]map[string]string{{
+		"objectName": jitConfigFileName,
+		"secretPath": d.vaultPath("data", runner),
+		"secretKey":  "jitConfig",
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := map[string]interface{}{
+		"vaultAddress": d.Vault.Address,
+		"roleName":     d.csiRole(runner),
+		"objects":      string(objects),
+	}
+
+	if d.CSIAuthPath != "" {
+		parameters["vaultKubernetesMountPath"] = d.CSIAuthPath
+	}
+
+	spc := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"provider":   "vault",
+				"parameters": parameters,
+			},
+		},
+	}
+
+	spc.SetAPIVersion(secretProviderClassAPIVersion)
+	spc.SetKind(secretProviderClassKind)
+	spc.SetName(jitConfigSecretName(runner.Name))
+	spc.SetNamespace(runner.Namespace)
+
+	return spc, nil
+}
+
+func runnerContainer(pod *corev1.Pod) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			return &pod.Spec.Containers[i]
+		}
+	}
+
+	return nil
+}
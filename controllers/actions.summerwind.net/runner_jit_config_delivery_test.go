@@ -0,0 +1,283 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/actions/actions-runner-controller/pkg/vault"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeVault serves the KV version 2 secrets engine mounted at secret/ and the response wrapping of Vault.
+type fakeVault struct {
+	mu       sync.Mutex
+	data     map[string]map[string]interface{}
+	metadata map[string]map[string]interface{}
+	wrapped  []map[string]interface{}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "vault-token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	switch {
+	case path == "sys/wrapping/wrap":
+		v.wrapped = append(v.wrapped, body)
+		_, _ = w.Write([]byte(`{"wrap_info": {"token": "wrapping-token"}}`))
+	case strings.HasPrefix(path, "secret/data/"):
+		key := strings.TrimPrefix(path, "secret/data/")
+
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := v.data[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		case http.MethodPost:
+			v.data[key] = body["data"].(map[string]interface{})
+			_, _ = w.Write([]byte(`{"data": {"version": 1}}`))
+		}
+	case strings.HasPrefix(path, "secret/metadata/"):
+		key := strings.TrimPrefix(path, "secret/metadata/")
+
+		switch r.Method {
+		case http.MethodPost:
+			v.metadata[key] = body
+		case http.MethodDelete:
+			delete(v.metadata, key)
+			delete(v.data, key)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDeliverJITConfig(t *testing.T) {
+	ctx := context.Background()
+
+	server := fake.NewServer(
+		fake.WithListRepositoryWorkflowRunsResponse(200, "", "", ""),
+		fake.WithListWorkflowJobsResponse(200, nil),
+		fake.WithListRunnersResponse(200, fake.RunnersListBody),
+	)
+	defer server.Close()
+
+	v := &fakeVault{data: map[string]map[string]interface{}{}, metadata: map[string]map[string]interface{}{}}
+	vaultServer := httptest.NewServer(v)
+	defer vaultServer.Close()
+
+	runner := &arcv1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Name: "test3", Namespace: "default", UID: "uid"},
+		Spec: arcv1alpha1.RunnerSpec{
+			RunnerConfig: arcv1alpha1.RunnerConfig{Repository: "test/valid", JITConfig: true},
+		},
+	}
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test3", Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "runner", Image: "summerwind/actions-runner:latest"}}},
+		}
+	}
+
+	spcGVK := schema.FromAPIVersionAndKind(secretProviderClassAPIVersion, secretProviderClassKind)
+
+	newReconciler := func(mechanism string) *RunnerReconciler {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(spcGVK, meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+
+		return &RunnerReconciler{
+			Client:       clientfake.NewClientBuilder().WithScheme(sc).WithRESTMapper(mapper).WithObjects(runner).Build(),
+			Scheme:       sc,
+			Recorder:     record.NewFakeRecorder(10),
+			GitHubClient: NewMultiGitHubClient(&testResourceReader{objects: map[types.NamespacedName]client.Object{}}, newGithubClient(server)),
+			JITConfigDelivery: JITConfigDelivery{
+				Mechanism: mechanism,
+				Vault:     &vault.Client{Address: vaultServer.URL, Token: "vault-token"},
+				TTL:       10 * time.Minute,
+				CSIRole:   "runners-{namespace}",
+			},
+		}
+	}
+
+	const key = "actions-runner-controller/default/test3"
+
+	t.Run("vault", func(t *testing.T) {
+		r := newReconciler(JITConfigDeliveryVault)
+
+		observed := runnerRegistrationObservations(t, "default", "", "")
+
+		pod := newPod()
+		require.NoError(t, r.deliverJITConfig(ctx, runner, pod, logr.Discard()))
+		require.Equal(t, observed+1, runnerRegistrationObservations(t, "default", "", ""))
+
+		// Kept in vault rather than a Kubernetes secret until the runner reads it
+		require.Equal(t, map[string]interface{}{"jitConfig": "fake-encoded-jit-config", "runnerId": "3"}, v.data[key])
+		require.Equal(t, map[string]interface{}{"delete_version_after": "10m0s"}, v.metadata[key])
+		require.Equal(t, map[string]interface{}{"jitConfig": "fake-encoded-jit-config"}, v.wrapped[0])
+
+		require.Equal(t, "3", pod.Annotations[AnnotationKeyRunnerID])
+		require.Equal(t, "secret/data/"+key, pod.Annotations[annotationKeyJITConfigVaultPath])
+
+		require.Len(t, pod.Spec.InitContainers, 1)
+		unwrap := pod.Spec.InitContainers[0]
+		require.Equal(t, jitConfigUnwrapContainerName, unwrap.Name)
+		require.Equal(t, "summerwind/actions-runner:latest", unwrap.Image)
+		require.Contains(t, unwrap.Env, corev1.EnvVar{Name: "VAULT_WRAPPING_TOKEN", Value: "wrapping-token"})
+		require.Equal(t, corev1.StorageMediumMemory, pod.Spec.Volumes[0].EmptyDir.Medium)
+
+		require.Equal(t, "test3", getRunnerEnv(pod, EnvVarRunnerName))
+		require.Equal(t, jitConfigMountPath+"/jitconfig", getRunnerEnv(pod, EnvVarRunnerJITConfigFile))
+		require.Empty(t, getRunnerEnv(pod, EnvVarRunnerJITConfig))
+
+		// The configuration kept in vault is reused rather than registering the runner again
+		v.data[key]["jitConfig"] = "saved"
+
+		require.NoError(t, r.deliverJITConfig(ctx, runner, newPod(), logr.Discard()))
+		require.Equal(t, map[string]interface{}{"jitConfig": "saved"}, v.wrapped[1])
+		require.Equal(t, observed+1, runnerRegistrationObservations(t, "default", "", ""))
+	})
+
+	t.Run("secrets-store-csi", func(t *testing.T) {
+		delete(v.data, key)
+
+		r := newReconciler(JITConfigDeliverySecretsStoreCSI)
+
+		pod := newPod()
+		require.NoError(t, r.deliverJITConfig(ctx, runner, pod, logr.Discard()))
+
+		spc := &unstructured.Unstructured{}
+		spc.SetGroupVersionKind(spcGVK)
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test3-jitconfig"}, spc))
+		require.Equal(t, "Runner", spc.GetOwnerReferences()[0].Kind)
+
+		parameters, _, _ := unstructured.NestedStringMap(spc.Object, "spec", "parameters")
+		require.Equal(t, vaultServer.URL, parameters["vaultAddress"])
+		require.Equal(t, "runners-default", parameters["roleName"], "the runner pods log in as the role of their namespace")
+		require.Contains(t, parameters["objects"], "secretPath: secret/data/"+key)
+
+		require.Empty(t, pod.Spec.InitContainers)
+		require.Equal(t, "test3-jitconfig", pod.Spec.Volumes[0].CSI.VolumeAttributes["secretProviderClass"])
+		require.Equal(t, jitConfigMountPath+"/jitconfig", getRunnerEnv(pod, EnvVarRunnerJITConfigFile))
+	})
+
+	t.Run("mechanism of the runner", func(t *testing.T) {
+		delete(v.data, key)
+
+		r := newReconciler(JITConfigDeliverySecret)
+
+		vaultRunner := runner.DeepCopy()
+		vaultRunner.Spec.JITConfigDelivery = JITConfigDeliveryVault
+
+		require.False(t, r.JITConfigDelivery.fromVault(runner))
+		require.True(t, r.JITConfigDelivery.fromVault(vaultRunner))
+
+		pod := newPod()
+		require.NoError(t, r.deliverJITConfig(ctx, vaultRunner, pod, logr.Discard()))
+		require.Equal(t, jitConfigUnwrapContainerName, pod.Spec.InitContainers[0].Name)
+
+		r.JITConfigDelivery.Vault = nil
+		require.ErrorContains(t, r.deliverJITConfig(ctx, vaultRunner, newPod(), logr.Discard()), "vault address is required")
+	})
+
+	t.Run("destroyed once the runner started", func(t *testing.T) {
+		r := newReconciler(JITConfigDeliveryVault)
+
+		pod := newPod()
+		require.NoError(t, r.deliverJITConfig(ctx, runner, pod, logr.Discard()))
+		pod.CreationTimestamp = metav1.Now()
+		require.NoError(t, r.Create(ctx, pod))
+
+		deleted, err := r.syncDeliveredJITConfig(ctx, runner, pod, logr.Discard())
+		require.NoError(t, err)
+		require.False(t, deleted)
+		require.Contains(t, v.data, key, "the configuration is kept until the runner reads it")
+
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "runner", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+
+		deleted, err = r.syncDeliveredJITConfig(ctx, runner, pod, logr.Discard())
+		require.NoError(t, err)
+		require.False(t, deleted)
+		require.NotContains(t, v.data, key)
+
+		var updated corev1.Pod
+		require.NoError(t, r.Get(ctx, client.ObjectKeyFromObject(pod), &updated))
+		require.NotContains(t, updated.Annotations, annotationKeyJITConfigVaultPath)
+		require.Equal(t, "3", updated.Annotations[AnnotationKeyRunnerID])
+	})
+
+	t.Run("expired before the runner started", func(t *testing.T) {
+		r := newReconciler(JITConfigDeliveryVault)
+
+		pod := newPod()
+		require.NoError(t, r.deliverJITConfig(ctx, runner, pod, logr.Discard()))
+		pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-11 * time.Minute))
+		require.NoError(t, r.Create(ctx, pod))
+
+		deleted, err := r.syncDeliveredJITConfig(ctx, runner, pod, logr.Discard())
+		require.NoError(t, err)
+		require.True(t, deleted)
+		require.NotContains(t, v.data, key)
+
+		var gone corev1.Pod
+		err = r.Get(ctx, client.ObjectKeyFromObject(pod), &gone)
+		require.True(t, kerrors.IsNotFound(err), "the pod is recreated with a new configuration")
+	})
+}
+
+func TestJITConfigDeliveryValidate(t *testing.T) {
+	require.NoError(t, JITConfigDelivery{}.Validate())
+	require.ErrorContains(t, JITConfigDelivery{Mechanism: "etcd"}.Validate(), "unsupported")
+	require.ErrorContains(t, JITConfigDelivery{Mechanism: JITConfigDeliveryVault}.Validate(), "vault address is required")
+
+	v := &vault.Client{Address: "https://vault.example.com:8200"}
+	require.NoError(t, JITConfigDelivery{Mechanism: JITConfigDeliveryVault, Vault: v}.Validate())
+	require.ErrorContains(t, JITConfigDelivery{Mechanism: JITConfigDeliverySecretsStoreCSI, Vault: v}.Validate(), "vault role of the runner pods is required")
+
+	spec := &arcv1alpha1.RunnerSpec{
+		RunnerConfig: arcv1alpha1.RunnerConfig{Repository: "test/valid", JITConfigDelivery: JITConfigDeliveryVault},
+	}
+
+	errs := spec.Validate(nil)
+	require.Len(t, errs, 1)
+	require.Equal(t, "jitConfigDelivery", errs[0].Field)
+
+	spec.JITConfig = true
+	require.Empty(t, spec.Validate(nil))
+}
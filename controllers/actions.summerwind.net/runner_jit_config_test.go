@@ -0,0 +1,99 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureJITConfigSecret(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, arcv1alpha1.AddToScheme(sc))
+
+	server := fake.NewServer(
+		fake.WithListRepositoryWorkflowRunsResponse(200, "", "", ""),
+		fake.WithListWorkflowJobsResponse(200, nil),
+		fake.WithListRunnersResponse(200, fake.RunnersListBody),
+	)
+	defer server.Close()
+
+	runner := arcv1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test3",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: arcv1alpha1.RunnerSpec{
+			RunnerConfig: arcv1alpha1.RunnerConfig{
+				Repository: "test/valid",
+				JITConfig:  true,
+			},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(&runner).Build()
+
+	r := &RunnerReconciler{
+		Client:       c,
+		Scheme:       sc,
+		Recorder:     record.NewFakeRecorder(10),
+		GitHubClient: NewMultiGitHubClient(&testResourceReader{objects: map[types.NamespacedName]client.Object{}}, newGithubClient(server)),
+	}
+
+	observed := runnerRegistrationObservations(t, "default", "", "")
+
+	secret, err := r.ensureJITConfigSecret(ctx, runner, logr.Discard())
+	require.NoError(t, err)
+	require.Equal(t, "test3-jitconfig", secret.Name)
+	require.Equal(t, "fake-encoded-jit-config", string(secret.Data[jitConfigSecretKey]))
+	require.Equal(t, "3", secret.Annotations[AnnotationKeyRunnerID])
+	require.Len(t, secret.OwnerReferences, 1)
+
+	// The existing configuration is reused rather than registering the runner again
+	var saved corev1.Secret
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test3-jitconfig"}, &saved))
+	saved.Data[jitConfigSecretKey] = []byte("saved")
+	require.NoError(t, c.Update(ctx, &saved))
+
+	secret, err = r.ensureJITConfigSecret(ctx, runner, logr.Discard())
+	require.NoError(t, err)
+	require.Equal(t, "saved", string(secret.Data[jitConfigSecretKey]))
+
+	// The registration is observed along with the generation of the configuration, but not along with its reuse
+	require.Equal(t, observed+1, runnerRegistrationObservations(t, "default", "", ""))
+
+	pod := mutatePodForJITConfig(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test3"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "runner"}, {Name: "docker"}},
+		},
+	}, secret.Name)
+
+	require.Equal(t, "test3", getRunnerEnv(pod, EnvVarRunnerName))
+	require.Empty(t, getRunnerEnv(pod, EnvVarRunnerToken))
+
+	var jitConfigEnv *corev1.EnvVar
+	for i, e := range pod.Spec.Containers[0].Env {
+		if e.Name == EnvVarRunnerJITConfig {
+			jitConfigEnv = &pod.Spec.Containers[0].Env[i]
+		}
+	}
+	require.NotNil(t, jitConfigEnv)
+	require.Equal(t, "test3-jitconfig", jitConfigEnv.ValueFrom.SecretKeyRef.Name)
+	require.Empty(t, pod.Spec.Containers[1].Env)
+}
@@ -0,0 +1,139 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	runnerLifecycleHookPreDestroy = "preDestroy"
+
+	// runnerLifecycleHTTPHookTimeout is the timeout of an HTTP call made by ARC for a lifecycle hook.
+	runnerLifecycleHTTPHookTimeout = 30 * time.Second
+)
+
+// runnerLifecycleHookPayload is the request body of an HTTP lifecycle hook.
+// The runner entrypoint sends the same payload for the hooks it runs.
+type runnerLifecycleHookPayload struct {
+	Hook      string `json:"hook"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// runnerLifecycleHookEnvs returns the environment variables of the runner container that let the runner entrypoint
+// run the preRegister and postJob lifecycle hooks.
+func runnerLifecycleHookEnvs(hooks *v1alpha1.RunnerLifecycleHooks) ([]corev1.EnvVar, error) {
+	if hooks == nil || (hooks.PreRegister == nil && hooks.PostJob == nil) {
+		return nil, nil
+	}
+
+	envs := []corev1.EnvVar{
+		{
+			Name: "RUNNER_POD_NAMESPACE",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.namespace",
+				},
+			},
+		},
+	}
+
+	for _, h := range []struct {
+		name string
+		hook *v1alpha1.RunnerLifecycleHook
+	}{
+		{EnvVarRunnerPreRegisterHook, hooks.PreRegister},
+		{EnvVarRunnerPostJobHook, hooks.PostJob},
+	} {
+		if h.hook == nil {
+			continue
+		}
+
+		v, err := json.Marshal(h.hook)
+		if err != nil {
+			return nil, err
+		}
+
+		envs = append(envs, corev1.EnvVar{Name: h.name, Value: string(v)})
+	}
+
+	return envs, nil
+}
+
+// ensurePreDestroyHookCompleted calls the preDestroy lifecycle hook of the runner pod, if any, until it succeeds.
+// It returns the updated pod once the hook succeeded, and the pod as-is when the pod has no hook or the hook has already succeeded.
+func ensurePreDestroyHookCompleted(ctx context.Context, log logr.Logger, c client.Client, pod *corev1.Pod) (*corev1.Pod, error) {
+	if pod == nil {
+		return nil, nil
+	}
+
+	v, ok := getAnnotation(pod, AnnotationKeyPreDestroyHook)
+	if !ok {
+		return pod, nil
+	}
+
+	if _, ok := getAnnotation(pod, AnnotationKeyPreDestroyHookCompleteTimestamp); ok {
+		return pod, nil
+	}
+
+	var hook v1alpha1.RunnerLifecycleHTTPHook
+	if err := json.Unmarshal([]byte(v), &hook); err != nil {
+		return nil, fmt.Errorf("parsing %s annotation: %w", AnnotationKeyPreDestroyHook, err)
+	}
+
+	payload := runnerLifecycleHookPayload{
+		Hook:      runnerLifecycleHookPreDestroy,
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+	}
+
+	if err := callRunnerLifecycleHTTPHook(ctx, &hook, payload); err != nil {
+		return nil, err
+	}
+
+	log.Info("Runner preDestroy hook succeeded", "url", hook.URL)
+
+	return annotatePodOnce(ctx, c, log, pod, AnnotationKeyPreDestroyHookCompleteTimestamp, time.Now().Format(time.RFC3339))
+}
+
+func callRunnerLifecycleHTTPHook(ctx context.Context, hook *v1alpha1.RunnerLifecycleHTTPHook, payload runnerLifecycleHookPayload) error {
+	method := hook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, runnerLifecycleHTTPHookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s hook %s %s responded with %s", payload.Hook, method, hook.URL, res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsurePreDestroyHookCompleted(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	var (
+		payloads []runnerLifecycleHookPayload
+		status   = http.StatusServiceUnavailable
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+
+		var p runnerLifecycleHookPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		payloads = append(payloads, p)
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKeyPreDestroyHook: `{"url":"` + server.URL + `","method":"PUT"}`,
+			},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+
+	_, err := ensurePreDestroyHookCompleted(ctx, logr.Discard(), c, pod)
+	require.Error(t, err, "the pod must not be deleted until the hook succeeds")
+
+	status = http.StatusOK
+
+	updated, err := ensurePreDestroyHookCompleted(ctx, logr.Discard(), c, pod)
+	require.NoError(t, err)
+	require.Contains(t, updated.Annotations, AnnotationKeyPreDestroyHookCompleteTimestamp)
+
+	// The hook isn't called again once it succeeded
+	_, err = ensurePreDestroyHookCompleted(ctx, logr.Discard(), c, updated)
+	require.NoError(t, err)
+
+	want := runnerLifecycleHookPayload{Hook: "preDestroy", Name: "example-abcde", Namespace: "default"}
+	require.Equal(t, []runnerLifecycleHookPayload{want, want}, payloads)
+}
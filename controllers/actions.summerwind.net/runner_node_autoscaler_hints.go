@@ -0,0 +1,198 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// AnnotationKeyDoNotDisruptWhenBusy is the annotation on the runner pod that tells the github webhook server to annotate the pod
+	// with the do-not-disrupt annotations of the node autoscalers while the runner is busy.
+	AnnotationKeyDoNotDisruptWhenBusy = annotationKeyPrefix + "do-not-disrupt-when-busy"
+
+	annotationKeyKarpenterDoNotDisrupt        = "karpenter.sh/do-not-disrupt"
+	annotationKeyClusterAutoscalerSafeToEvict = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	annotationKeyConsumeProvisioningRequest   = "autoscaling.x-k8s.io/consume-provisioning-request"
+	annotationKeyProvisioningRequestClassName = "autoscaling.x-k8s.io/provisioning-class-name"
+	defaultNodePoolLabelKey                   = "karpenter.sh/nodepool"
+	DefaultProvisioningRequestClassName       = "best-effort-atomic-scale-up.autoscaling.x-k8s.io"
+	provisioningRequestAPIVersion             = "autoscaling.x-k8s.io/v1"
+	provisioningRequestKind                   = "ProvisioningRequest"
+)
+
+// applyNodeAutoscalerHints adds the node pool node selector, and the marker of the do-not-disrupt annotations, to the runner pod.
+func applyNodeAutoscalerHints(pod *corev1.Pod, h *v1alpha1.NodeAutoscalerHints) {
+	if h == nil {
+		return
+	}
+
+	if h.NodePool != "" {
+		key := h.NodePoolLabelKey
+		if key == "" {
+			key = defaultNodePoolLabelKey
+		}
+
+		// The node selector of the runner pod template takes precedence
+		if _, ok := pod.Spec.NodeSelector[key]; !ok {
+			pod.Spec.NodeSelector = CloneAndAddLabel(pod.Spec.NodeSelector, key, h.NodePool)
+		}
+	}
+
+	if h.DoNotDisruptWhenBusy {
+		pod.Annotations = CloneAndAddLabel(pod.Annotations, AnnotationKeyDoNotDisruptWhenBusy, "true")
+	}
+}
+
+// setDoNotDisruptAnnotations sets the annotations that keep Karpenter and Cluster Autoscaler from disrupting the node of the busy runner pod.
+// The idle runner pod is marked safe to evict, as ARC recreates it anyway.
+func setDoNotDisruptAnnotations(annotations map[string]string, busy bool) {
+	if busy {
+		annotations[annotationKeyKarpenterDoNotDisrupt] = "true"
+		annotations[annotationKeyClusterAutoscalerSafeToEvict] = "false"
+	} else {
+		delete(annotations, annotationKeyKarpenterDoNotDisrupt)
+		annotations[annotationKeyClusterAutoscalerSafeToEvict] = "true"
+	}
+}
+
+// ensureProvisioningRequest creates the ProvisioningRequest of the runner pod, along with the PodTemplate it refers to,
+// and annotates the pod to consume it. Both are owned by the runner, so that they are garbage-collected along with the runner.
+// The pod is created without the ProvisioningRequest when the cluster doesn't serve the ProvisioningRequest API.
+func (r *RunnerReconciler) ensureProvisioningRequest(ctx context.Context, runner *v1alpha1.Runner, pod *corev1.Pod, log logr.Logger) *ctrl.Result {
+	className := runner.Spec.NodeAutoscalerHints.ProvisioningRequest.ClassName
+	if className == "" {
+		className = DefaultProvisioningRequestClassName
+	}
+
+	podTemplate := &corev1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runner.Name,
+			Namespace: runner.Namespace,
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: pod.Labels,
+			},
+			Spec: *pod.Spec.DeepCopy(),
+		},
+	}
+
+	provisioningRequest := newProvisioningRequest(runner.Name, runner.Namespace, className)
+
+	if err := ctrl.SetControllerReference(runner, provisioningRequest, r.Scheme); err != nil {
+		log.Error(err, "Could not add owner reference to ProvisioningRequest")
+		return &ctrl.Result{Requeue: true}
+	}
+
+	if err := r.Create(ctx, provisioningRequest); err != nil && !kerrors.IsAlreadyExists(err) {
+		if meta.IsNoMatchError(err) {
+			log.Info("Creating the runner pod without the ProvisioningRequest as the cluster doesn't serve the ProvisioningRequest API", "error", err.Error())
+			r.Recorder.Event(runner, corev1.EventTypeWarning, "ProvisioningRequestUnsupported", "The cluster doesn't serve the ProvisioningRequest API of Cluster Autoscaler")
+			return nil
+		}
+
+		log.Error(err, "Retrying as failed to create ProvisioningRequest")
+		return &ctrl.Result{Requeue: true}
+	}
+
+	if res := r.createObject(ctx, podTemplate, podTemplate.ObjectMeta, runner, log); res != nil {
+		return res
+	}
+
+	pod.Annotations = CloneAndAddLabel(pod.Annotations, annotationKeyConsumeProvisioningRequest, provisioningRequest.GetName())
+	pod.Annotations = CloneAndAddLabel(pod.Annotations, annotationKeyProvisioningRequestClassName, className)
+
+	return nil
+}
+
+// newProvisioningRequest returns the ProvisioningRequest of a single pod of the PodTemplate of the same name.
+// It's unstructured as ARC doesn't depend on the Cluster Autoscaler API.
+func newProvisioningRequest(name, namespace, className string) *unstructured.Unstructured {
+	pr := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"provisioningClassName": className,
+				"podSets": []interface{}{
+					map[string]interface{}{
+						"count": int64(1),
+						"podTemplateRef": map[string]interface{}{
+							"name": name,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	pr.SetAPIVersion(provisioningRequestAPIVersion)
+	pr.SetKind(provisioningRequestKind)
+	pr.SetName(name)
+	pr.SetNamespace(namespace)
+
+	return pr
+}
+
+// podResourceEnvelope returns the resources the scheduler reserves for the pod,
+// which is the larger of the sum of the containers and native sidecars, and each of the other init containers along with the sidecars started before it.
+func podResourceEnvelope(pod *corev1.Pod, resources func(corev1.ResourceRequirements) corev1.ResourceList) corev1.ResourceList {
+	envelope := corev1.ResourceList{}
+
+	for _, c := range pod.Spec.Containers {
+		addResourceList(envelope, resources(c.Resources))
+	}
+
+	sidecars := corev1.ResourceList{}
+
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResourceList(envelope, resources(c.Resources))
+			addResourceList(sidecars, resources(c.Resources))
+			continue
+		}
+
+		init := sidecars.DeepCopy()
+		addResourceList(init, resources(c.Resources))
+
+		for name, q := range init {
+			if cur, ok := envelope[name]; !ok || q.Cmp(cur) > 0 {
+				envelope[name] = q
+			}
+		}
+	}
+
+	return envelope
+}
+
+func addResourceList(list, add corev1.ResourceList) {
+	for name, q := range add {
+		if cur, ok := list[name]; ok {
+			cur.Add(q)
+			list[name] = cur
+		} else {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
+
+// observeRunnerPodResourceEnvelope exports the resources of the runner pod per the runs-on labels of the runner,
+// so that the node autoscalers and the capacity planning can tell the size of the nodes the queued jobs need.
+func observeRunnerPodResourceEnvelope(runner *v1alpha1.Runner, pod *corev1.Pod) {
+	runsOn := append([]string{"self-hosted"}, runner.Spec.Labels...)
+
+	metrics.SetRunnerPodResourceEnvelope(
+		runner.Namespace,
+		strings.Join(runsOn, ","),
+		podResourceEnvelope(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Requests }),
+		podResourceEnvelope(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Limits }),
+	)
+}
@@ -0,0 +1,183 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestApplyNodeAutoscalerHints(t *testing.T) {
+	pod := &corev1.Pod{}
+
+	applyNodeAutoscalerHints(pod, &v1alpha1.NodeAutoscalerHints{NodePool: "runners", DoNotDisruptWhenBusy: true})
+
+	require.Equal(t, map[string]string{"karpenter.sh/nodepool": "runners"}, pod.Spec.NodeSelector)
+	require.Equal(t, map[string]string{AnnotationKeyDoNotDisruptWhenBusy: "true"}, pod.Annotations)
+
+	// The node selector of the runner pod template takes precedence
+	pod = &corev1.Pod{}
+	pod.Spec.NodeSelector = map[string]string{"cloud.google.com/gke-nodepool": "explicit"}
+
+	applyNodeAutoscalerHints(pod, &v1alpha1.NodeAutoscalerHints{NodePool: "runners", NodePoolLabelKey: "cloud.google.com/gke-nodepool"})
+
+	require.Equal(t, map[string]string{"cloud.google.com/gke-nodepool": "explicit"}, pod.Spec.NodeSelector)
+	require.Empty(t, pod.Annotations)
+}
+
+func TestPatchRunnerBusyAnnotations_DoNotDisruptWhenBusy(t *testing.T) {
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example-abcde-fghij",
+			Namespace:   "runners",
+			Annotations: map[string]string{AnnotationKeyDoNotDisruptWhenBusy: "true"},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+
+	key := types.NamespacedName{Namespace: "runners", Name: "example-abcde-fghij"}
+
+	require.NoError(t, patchRunnerBusyAnnotations(ctx, c, pod, true, "https://github.com/test/valid/actions/runs/1/job/1"))
+	require.NoError(t, c.Get(ctx, key, pod))
+	require.Equal(t, map[string]string{
+		AnnotationKeyDoNotDisruptWhenBusy:         "true",
+		AnnotationKeyRunnerBusy:                   "true",
+		AnnotationKeyRunnerJobURL:                 "https://github.com/test/valid/actions/runs/1/job/1",
+		annotationKeyKarpenterDoNotDisrupt:        "true",
+		annotationKeyClusterAutoscalerSafeToEvict: "false",
+	}, pod.Annotations)
+
+	require.NoError(t, patchRunnerBusyAnnotations(ctx, c, pod, false, "https://github.com/test/valid/actions/runs/1/job/1"))
+	require.NoError(t, c.Get(ctx, key, pod))
+	require.Equal(t, map[string]string{
+		AnnotationKeyDoNotDisruptWhenBusy:         "true",
+		AnnotationKeyRunnerBusy:                   "false",
+		annotationKeyClusterAutoscalerSafeToEvict: "true",
+	}, pod.Annotations)
+}
+
+func TestPodResourceEnvelope(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+
+	requests := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "sidecar", RestartPolicy: &always, Resources: requests("100m", "64Mi")},
+				{Name: "setup", Resources: requests("4", "128Mi")},
+			},
+			Containers: []corev1.Container{
+				{Name: "runner", Resources: requests("1", "2Gi")},
+				{Name: "docker", Resources: requests("500m", "1Gi")},
+			},
+		},
+	}
+
+	envelope := podResourceEnvelope(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Requests })
+
+	cpu := envelope[corev1.ResourceCPU]
+	memory := envelope[corev1.ResourceMemory]
+
+	// The init container after the sidecar requires more CPU than the containers along with the sidecar
+	require.Equal(t, "4100m", cpu.String())
+	require.Equal(t, int64(3136*1024*1024), memory.Value())
+}
+
+func TestEnsureProvisioningRequest(t *testing.T) {
+	ctx := context.Background()
+
+	gvk := schema.GroupVersionKind{Group: "autoscaling.x-k8s.io", Version: "v1", Kind: "ProvisioningRequest"}
+
+	newRunner := func() *v1alpha1.Runner {
+		return &v1alpha1.Runner{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "Runner"},
+			ObjectMeta: metav1.ObjectMeta{Name: "example-abcde-fghij", Namespace: "runners", UID: "uid"},
+			Spec: v1alpha1.RunnerSpec{
+				RunnerConfig: v1alpha1.RunnerConfig{
+					NodeAutoscalerHints: &v1alpha1.NodeAutoscalerHints{ProvisioningRequest: &v1alpha1.ProvisioningRequestHint{}},
+				},
+			},
+		}
+	}
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-abcde-fghij", Namespace: "runners"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "runner"}}},
+		}
+	}
+
+	t.Run("provisioned", func(t *testing.T) {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("PodTemplate"), meta.RESTScopeNamespace)
+
+		c := clientfake.NewClientBuilder().WithScheme(sc).WithRESTMapper(mapper).Build()
+
+		r := &RunnerReconciler{Client: c, Scheme: sc, Recorder: record.NewFakeRecorder(10)}
+
+		runner, pod := newRunner(), newPod()
+
+		require.Nil(t, r.ensureProvisioningRequest(ctx, runner, pod, logr.Discard()))
+		require.Equal(t, map[string]string{
+			annotationKeyConsumeProvisioningRequest:   "example-abcde-fghij",
+			annotationKeyProvisioningRequestClassName: DefaultProvisioningRequestClassName,
+		}, pod.Annotations)
+
+		key := types.NamespacedName{Namespace: "runners", Name: "example-abcde-fghij"}
+
+		var podTemplate corev1.PodTemplate
+		require.NoError(t, c.Get(ctx, key, &podTemplate))
+		require.Equal(t, pod.Spec, podTemplate.Template.Spec)
+
+		pr := &unstructured.Unstructured{}
+		pr.SetGroupVersionKind(gvk)
+		require.NoError(t, c.Get(ctx, key, pr))
+
+		className, _, _ := unstructured.NestedString(pr.Object, "spec", "provisioningClassName")
+		require.Equal(t, DefaultProvisioningRequestClassName, className)
+		require.Equal(t, "Runner", pr.GetOwnerReferences()[0].Kind)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		c := clientfake.NewClientBuilder().WithScheme(sc).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*unstructured.Unstructured); ok {
+					return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+
+		recorder := record.NewFakeRecorder(10)
+		r := &RunnerReconciler{Client: c, Scheme: sc, Recorder: recorder}
+
+		pod := newPod()
+
+		require.Nil(t, r.ensureProvisioningRequest(ctx, newRunner(), pod, logr.Discard()))
+		require.Empty(t, pod.Annotations, "the pod is created without consuming the ProvisioningRequest")
+		require.Len(t, recorder.Events, 1)
+	})
+}
@@ -0,0 +1,68 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// wellKnownLabelNodeSelectors is the node selectors for the default labels GitHub applies to self-hosted runners.
+//
+// https://docs.github.com/en/actions/hosting-your-own-runners/managing-self-hosted-runners/using-self-hosted-runners-in-a-workflow#using-default-labels-to-route-jobs
+var wellKnownLabelNodeSelectors = map[string]map[string]string{
+	"linux":   {corev1.LabelOSStable: "linux"},
+	"windows": {corev1.LabelOSStable: "windows"},
+	"x64":     {corev1.LabelArchStable: "amd64"},
+	"arm64":   {corev1.LabelArchStable: "arm64"},
+	"arm":     {corev1.LabelArchStable: "arm"},
+}
+
+// ParseLabelNodeSelector parses the runner label to node selector mapping in the LABEL:KEY=VALUE format.
+// The label is lower-cased to be used as a key of RunnerPodDefaults.LabelNodeSelectors.
+func ParseLabelNodeSelector(s string) (label, key, value string, err error) {
+	label, selector, ok := strings.Cut(s, ":")
+	if ok {
+		key, value, ok = strings.Cut(selector, "=")
+	}
+
+	if !ok || label == "" || key == "" {
+		return "", "", "", fmt.Errorf("invalid label node selector %q: it must be in the LABEL:KEY=VALUE format", s)
+	}
+
+	return strings.ToLower(label), key, value, nil
+}
+
+// applyLabelNodeSelectors adds the node selectors mapped from the runner labels to the pod, so that the runners
+// with different labels can be scheduled onto the different kinds of nodes.
+// The node selectors explicitly set on the pod take precedence, and so do the ones mapped from the earlier labels.
+// Labels are matched case-insensitively, as GitHub does when routing jobs to runners.
+func applyLabelNodeSelectors(pod *corev1.Pod, labels []string, d RunnerPodDefaults) {
+	for _, l := range labels {
+		var selectors []map[string]string
+
+		if s, ok := d.LabelNodeSelectors[strings.ToLower(l)]; ok {
+			selectors = append(selectors, s)
+		}
+
+		if d.DeriveNodeSelectorFromLabels {
+			if s, ok := wellKnownLabelNodeSelectors[strings.ToLower(l)]; ok {
+				selectors = append(selectors, s)
+			}
+		}
+
+		for _, s := range selectors {
+			for k, v := range s {
+				if _, ok := pod.Spec.NodeSelector[k]; ok {
+					continue
+				}
+
+				if pod.Spec.NodeSelector == nil {
+					pod.Spec.NodeSelector = map[string]string{}
+				}
+
+				pod.Spec.NodeSelector[k] = v
+			}
+		}
+	}
+}
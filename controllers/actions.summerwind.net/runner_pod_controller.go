@@ -33,6 +33,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -49,6 +52,9 @@ type RunnerPodReconciler struct {
 	RegistrationRecheckJitter   time.Duration
 
 	UnregistrationRetryDelay time.Duration
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
@@ -57,6 +63,7 @@ type RunnerPodReconciler struct {
 
 func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("runnerpod", req.NamespacedName)
+	ctx = audit.WithActor(ctx, "Pod", req.NamespacedName)
 
 	var runnerPod corev1.Pod
 	if err := r.Get(ctx, req.NamespacedName, &runnerPod); err != nil {
@@ -98,6 +105,10 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
+	if !r.Sharder.Owns(runnerPod.Namespace, sharding.Owner(enterprise, org, repo)) {
+		return ctrl.Result{}, nil
+	}
+
 	ghc, err := r.GitHubClient.InitForRunnerPod(ctx, &runnerPod)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -227,9 +238,20 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	po, res, err := ensureRunnerPodRegistered(ctx, log, ghc, r.Client, enterprise, org, repo, runnerPod.Name, &runnerPod)
 	if res != nil {
+		// An error means that ARC failed to list the runners, which isn't enough to tell the runner is unregistered
+		if _, unregistrationRequested := getAnnotation(&runnerPod, AnnotationKeyUnregistrationRequestTimestamp); err == nil && !unregistrationRequested && runnerPodRegistrationTimedOut(&runnerPod) {
+			return recreateUnregisteredRunnerPod(ctx, r.Client, log, r.Recorder, enterprise, org, repo, &runnerPod)
+		}
+
 		return *res, err
 	}
 
+	if _, registered := getAnnotation(&runnerPod, AnnotationKeyRunnerID); !registered {
+		if _, registered := getAnnotation(po, AnnotationKeyRunnerID); registered {
+			r.observeRunnerRegistration(ctx, po)
+		}
+	}
+
 	runnerPod = *po
 
 	if _, unregistrationRequested := getAnnotation(&runnerPod, AnnotationKeyUnregistrationRequestTimestamp); unregistrationRequested {
@@ -257,6 +279,21 @@ func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// observeRunnerRegistration observes the time taken by the runner of the pod to register itself to GitHub,
+// from the creation of the parent Runner, or the pod itself for a RunnerSet.
+func (r *RunnerPodReconciler) observeRunnerRegistration(ctx context.Context, pod *corev1.Pod) {
+	created := pod.CreationTimestamp.Time
+
+	if _, ok := pod.Labels[LabelKeyRunner]; ok {
+		var runner arcv1alpha1.Runner
+		if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &runner); err == nil {
+			created = runner.CreationTimestamp.Time
+		}
+	}
+
+	metrics.ObserveRunnerRegistrationDuration(pod.Namespace, pod.Labels[LabelKeyRunnerDeploymentName], pod.Labels[LabelKeyRunnerSetName], created)
+}
+
 func (r *RunnerPodReconciler) unregistrationRetryDelay() time.Duration {
 	retryDelay := DefaultUnregistrationRetryDelay
 
@@ -0,0 +1,462 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// AnnotationKeyPreemptionReason is the annotation that is added onto the busy runner pod once ARC found it preempted,
+	// like by the termination of its spot instance. It contains the reason of the preemption.
+	AnnotationKeyPreemptionReason = annotationKeyPrefix + "preemption-reason"
+
+	DefaultPreemptedJobRerunTimeout = 24 * time.Hour
+
+	// preemptionReasonNodeTermination is the reason of the preemption of the runner pods on the nodes having one of the node termination taints.
+	preemptionReasonNodeTermination = "NodeTermination"
+
+	preemptedJobRerunCheckInterval = time.Minute
+
+	runnerPodNodeNameKey = "spec.nodeName"
+)
+
+// DefaultPreemptionNodeTaints are the keys of the taints the common node termination handlers add onto the nodes about to be terminated.
+var DefaultPreemptionNodeTaints = []string{
+	// aws-node-termination-handler on the spot interruption notice
+	"aws-node-termination-handler/spot-itn",
+	// aws-node-termination-handler on the EC2 instance rebalance recommendation
+	"aws-node-termination-handler/rebalance-recommendation",
+	// GKE on the preemption of the spot VMs
+	"cloud.google.com/impending-node-termination",
+	// Karpenter on the disruption of the node, including the spot interruption
+	"karpenter.sh/disrupted",
+}
+
+// workflowJobURLRegexp matches the html URL of a workflow job, like https://github.com/OWNER/REPO/actions/runs/RUN_ID/job/JOB_ID
+var workflowJobURLRegexp = regexp.MustCompile(`/([^/]+)/([^/]+)/actions/runs/(\d+)/job/(\d+)$`)
+
+// RunnerPreemptionReconciler notices the busy runner pods being preempted, like by the termination of spot instances,
+// so that the jobs lost to the preemption are told apart from the ordinary job failures and optionally rerun.
+//
+// A runner pod is considered preempted when it has the DisruptionTarget condition, which Kubernetes adds onto the pods
+// about to be deleted due to the preemption, the eviction, the taint-based deletion, or the graceful node shutdown,
+// or when its node has one of the NodeTaints the node termination handlers add onto the nodes about to be terminated.
+//
+// It relies on the busy and job-url annotations the github webhook server adds onto the runner pods on the workflow_job events.
+type RunnerPreemptionReconciler struct {
+	client.Client
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+	Scheme       *runtime.Scheme
+	GitHubClient *MultiGitHubClient
+	Name         string
+
+	// NodeTaints are the keys of the taints added onto the nodes about to be terminated.
+	// DefaultPreemptionNodeTaints are used when empty.
+	NodeTaints []string
+
+	// RerunJobs reruns the preempted jobs once their workflow runs complete.
+	RerunJobs bool
+
+	// RerunTimeout is how long the completion of the workflow run of a preempted job is waited for before giving up on its rerun.
+	RerunTimeout time.Duration
+
+	reruns *preemptedJobReruns
+}
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *RunnerPreemptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("runnerpod", req.NamespacedName)
+
+	var pod corev1.Pod
+	if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, ok := pod.Labels[LabelKeyRunner]; !ok {
+		return ctrl.Result{}, nil
+	}
+
+	// The job of the runner pod has already been handled, or there's no job to lose
+	if _, ok := getAnnotation(&pod, AnnotationKeyPreemptionReason); ok || pod.Annotations[AnnotationKeyRunnerBusy] != "true" {
+		return ctrl.Result{}, nil
+	}
+
+	var node *corev1.Node
+	if pod.Spec.NodeName != "" {
+		var n corev1.Node
+		if err := r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, &n); err == nil {
+			node = &n
+		} else if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	reason, message := runnerPodPreemptionReason(&pod, node, r.nodeTaints())
+	if reason == "" {
+		return ctrl.Result{}, nil
+	}
+
+	jobURL := pod.Annotations[AnnotationKeyRunnerJobURL]
+
+	updated := pod.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, AnnotationKeyPreemptionReason, reason)
+
+	// Annotate the pod first so that the job is counted only once even when the reconciliation is retried
+	if err := r.Patch(ctx, updated, client.MergeFrom(&pod)); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	log.Info("Runner pod preempted while running a job", "reason", reason, "message", message, "jobURL", jobURL)
+
+	r.Recorder.Event(&pod, corev1.EventTypeWarning, "RunnerPreempted", fmt.Sprintf("Runner preempted (%s) while running the job %s: %s", reason, jobURL, message))
+
+	metrics.IncRunnerJobsPreempted(pod.Namespace, pod.Labels[LabelKeyRunnerDeploymentName], pod.Labels[LabelKeyRunnerSetName], reason)
+
+	if !r.RerunJobs {
+		return ctrl.Result{}, nil
+	}
+
+	owner, repo, runID, jobID, ok := parseWorkflowJobURL(jobURL)
+	if !ok {
+		log.Info("Unable to rerun the preempted job of the unknown URL", "jobURL", jobURL)
+		return ctrl.Result{}, nil
+	}
+
+	ghc, err := r.GitHubClient.InitForRunnerPod(ctx, &pod)
+	if err != nil {
+		log.Error(err, "Unable to rerun the preempted job", "jobURL", jobURL)
+		return ctrl.Result{}, nil
+	}
+
+	r.reruns.add(ghc, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, owner, repo, runID, jobID, time.Now())
+
+	return ctrl.Result{}, nil
+}
+
+func (r *RunnerPreemptionReconciler) nodeTaints() []string {
+	if len(r.NodeTaints) > 0 {
+		return r.NodeTaints
+	}
+
+	return DefaultPreemptionNodeTaints
+}
+
+// runnerPodPreemptionReason returns the reason of the preemption of the runner pod along with the human-readable message,
+// or the empty reason when the pod isn't being preempted.
+func runnerPodPreemptionReason(pod *corev1.Pod, node *corev1.Node, nodeTaints []string) (string, string) {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.DisruptionTarget && c.Status == corev1.ConditionTrue {
+			// The reason is one of PreemptionByScheduler, DeletionByTaintManager, EvictionByEvictionAPI, DeletionByPodGC, and TerminationByKubelet
+			reason := c.Reason
+			if reason == "" {
+				reason = string(corev1.DisruptionTarget)
+			}
+
+			return reason, c.Message
+		}
+	}
+
+	if node == nil {
+		return "", ""
+	}
+
+	for _, t := range node.Spec.Taints {
+		for _, key := range nodeTaints {
+			if t.Key == key {
+				return preemptionReasonNodeTermination, fmt.Sprintf("Node %s has the taint %s", node.Name, t.ToString())
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// parseWorkflowJobURL returns the repository, the run ID, and the job ID of the html URL of a workflow job.
+func parseWorkflowJobURL(jobURL string) (string, string, int64, int64, bool) {
+	m := workflowJobURLRegexp.FindStringSubmatch(jobURL)
+	if m == nil {
+		return "", "", 0, 0, false
+	}
+
+	runID, err := strconv.ParseInt(m[3], 10, 64)
+	if err != nil {
+		return "", "", 0, 0, false
+	}
+
+	jobID, err := strconv.ParseInt(m[4], 10, 64)
+	if err != nil {
+		return "", "", 0, 0, false
+	}
+
+	return m[1], m[2], runID, jobID, true
+}
+
+func (r *RunnerPreemptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "runnerpreemption-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	if r.RerunJobs {
+		timeout := r.RerunTimeout
+		if timeout <= 0 {
+			timeout = DefaultPreemptedJobRerunTimeout
+		}
+
+		r.reruns = &preemptedJobReruns{
+			Log:     r.Log.WithName("reruns"),
+			Timeout: timeout,
+		}
+
+		if err := mgr.Add(r.reruns); err != nil {
+			return err
+		}
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, runnerPodNodeNameKey, func(rawObj client.Object) []string {
+		pod := rawObj.(*corev1.Pod)
+		if _, ok := pod.Labels[LabelKeyRunner]; !ok || pod.Spec.NodeName == "" {
+			return nil
+		}
+
+		return []string{pod.Spec.NodeName}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.runnerPodsOnNode)).
+		Named(name).
+		Complete(r)
+}
+
+// runnerPodsOnNode enqueues the runner pods on the node, so that they are reconciled once the node is tainted for termination.
+func (r *RunnerPreemptionReconciler) runnerPodsOnNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	node := obj.(*corev1.Node)
+
+	var tainted bool
+	for _, t := range node.Spec.Taints {
+		for _, key := range r.nodeTaints() {
+			if t.Key == key {
+				tainted = true
+			}
+		}
+	}
+
+	if !tainted {
+		return nil
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{runnerPodNodeNameKey: node.Name}); err != nil {
+		r.Log.Error(err, "Failed to list runner pods on node", "node", node.Name)
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, pod := range pods.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}})
+	}
+
+	return reqs
+}
+
+// preemptedJobReruns reruns the preempted jobs once their workflow runs complete,
+// as GitHub allows rerunning a job only after the whole workflow run completes, and provides no API to cancel a single job.
+type preemptedJobReruns struct {
+	Log     logr.Logger
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	runs map[string]*preemptedRun
+}
+
+// preemptedRun is a workflow run some of whose jobs were preempted.
+type preemptedRun struct {
+	ghc *github.Client
+
+	// pod is the runner pod that lost the first of the jobs, recorded as the actor in the audit log.
+	pod types.NamespacedName
+
+	owner, repo string
+	runID       int64
+	jobIDs      map[int64]struct{}
+
+	since time.Time
+}
+
+func (p *preemptedJobReruns) add(ghc *github.Client, pod types.NamespacedName, owner, repo string, runID, jobID int64, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.runs == nil {
+		p.runs = map[string]*preemptedRun{}
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%d", ghc.GithubBaseURL, owner, repo, runID)
+
+	run, ok := p.runs[key]
+	if !ok {
+		run = &preemptedRun{ghc: ghc, pod: pod, owner: owner, repo: repo, runID: runID, jobIDs: map[int64]struct{}{}, since: now}
+		p.runs[key] = run
+	}
+
+	run.jobIDs[jobID] = struct{}{}
+}
+
+// Start checks the workflow runs of the preempted jobs every minute until the context is canceled.
+// It runs only on the leader, as the controller-runtime manager runs runnables that don't opt out of the leader election only on the leader.
+func (p *preemptedJobReruns) Start(ctx context.Context) error {
+	ticker := time.NewTicker(preemptedJobRerunCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.check(ctx, time.Now())
+		}
+	}
+}
+
+func (p *preemptedJobReruns) check(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	runs := make(map[string]*preemptedRun, len(p.runs))
+	for k, run := range p.runs {
+		runs[k] = run
+	}
+	p.mu.Unlock()
+
+	for key, run := range runs {
+		log := p.Log.WithValues("repository", run.owner+"/"+run.repo, "runID", run.runID)
+
+		done, err := p.rerun(ctx, log, run)
+		if err != nil {
+			log.Error(err, "Failed to rerun preempted jobs")
+		}
+
+		if !done && now.Sub(run.since) > p.Timeout {
+			log.Info("Gave up rerunning preempted jobs as the workflow run didn't complete in time", "timeout", p.Timeout)
+			done = true
+		}
+
+		if done {
+			p.mu.Lock()
+			delete(p.runs, key)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// rerun reruns the preempted jobs of the workflow run if it has completed, and returns true once nothing is left to be done for the run.
+func (p *preemptedJobReruns) rerun(ctx context.Context, log logr.Logger, run *preemptedRun) (bool, error) {
+	r, _, err := run.ghc.Actions.GetWorkflowRunByID(ctx, run.owner, run.repo, run.runID)
+	if err != nil {
+		return false, err
+	}
+
+	if r.GetStatus() != "completed" {
+		return false, nil
+	}
+
+	failed, err := listFailedWorkflowJobs(ctx, run.ghc, run.owner, run.repo, run.runID)
+	if err != nil {
+		return false, err
+	}
+
+	jobIDs, rerunFailed := preemptedJobsToRerun(run.jobIDs, failed)
+
+	ctx = audit.WithActor(ctx, "Pod", run.pod)
+
+	switch {
+	case rerunFailed:
+		if _, err := run.ghc.Actions.RerunFailedJobsByID(ctx, run.owner, run.repo, run.runID); err != nil {
+			return false, err
+		}
+
+		log.Info("Rerun the failed jobs of the workflow run whose jobs were preempted", "jobIDs", jobIDs)
+	case len(jobIDs) == 1:
+		if _, err := run.ghc.Actions.RerunJobByID(ctx, run.owner, run.repo, jobIDs[0]); err != nil {
+			return false, err
+		}
+
+		log.Info("Rerun the preempted job", "jobID", jobIDs[0])
+	case len(jobIDs) > 1:
+		// Rerunning one of the jobs starts a new attempt of the run, which makes the other jobs no longer rerunnable on their own
+		log.Info("Skipped rerunning the preempted jobs, as the workflow run has other failed jobs too", "jobIDs", jobIDs)
+	default:
+		log.V(1).Info("No preempted jobs of the workflow run need rerunning")
+	}
+
+	return true, nil
+}
+
+// preemptedJobsToRerun returns the preempted jobs among the failed jobs of the latest attempt of the workflow run.
+// rerunFailed is true when all the failed jobs were preempted and more than one of them need rerunning,
+// in which case the failed jobs of the run are rerun at once.
+func preemptedJobsToRerun(preempted map[int64]struct{}, failed []int64) (jobIDs []int64, rerunFailed bool) {
+	for _, id := range failed {
+		if _, ok := preempted[id]; ok {
+			jobIDs = append(jobIDs, id)
+		}
+	}
+
+	return jobIDs, len(jobIDs) > 1 && len(jobIDs) == len(failed)
+}
+
+// listFailedWorkflowJobs returns the IDs of the jobs of the latest attempt of the workflow run that either failed or were cancelled.
+func listFailedWorkflowJobs(ctx context.Context, ghc *github.Client, owner, repo string, runID int64) ([]int64, error) {
+	opts := &gogithub.ListWorkflowJobsOptions{
+		Filter:      "latest",
+		ListOptions: gogithub.ListOptions{PerPage: 100},
+	}
+
+	var failed []int64
+
+	for {
+		jobs, res, err := ghc.Actions.ListWorkflowJobs(ctx, owner, repo, runID, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, j := range jobs.Jobs {
+			switch strings.ToLower(j.GetConclusion()) {
+			case "failure", "cancelled":
+				failed = append(failed, j.GetID())
+			}
+		}
+
+		if res.NextPage == 0 {
+			return failed, nil
+		}
+
+		opts.Page = res.NextPage
+	}
+}
@@ -0,0 +1,178 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerPreemptionReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	newPod := func(name, nodeName string, busy bool, conditions ...corev1.PodCondition) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					LabelKeyRunner:               "",
+					LabelKeyRunnerDeploymentName: "example",
+				},
+				Annotations: map[string]string{},
+			},
+			Spec:   corev1.PodSpec{NodeName: nodeName},
+			Status: corev1.PodStatus{Conditions: conditions},
+		}
+		if busy {
+			pod.Annotations[AnnotationKeyRunnerBusy] = "true"
+			pod.Annotations[AnnotationKeyRunnerJobURL] = "https://github.com/test/valid/actions/runs/1/job/" + name
+		}
+		return pod
+	}
+
+	evicted := corev1.PodCondition{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: "EvictionByEvictionAPI", Message: "Eviction API: evicting"}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "spot"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "aws-node-termination-handler/spot-itn", Effect: corev1.TaintEffectNoSchedule}}},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ondemand"}},
+		newPod("evicted", "ondemand", true, evicted),
+		newPod("terminating", "spot", true),
+		newPod("idle", "spot", false),
+		newPod("running", "ondemand", true),
+	).Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerPreemptionReconciler{
+		Client:   c,
+		Log:      logr.Discard(),
+		Recorder: recorder,
+	}
+
+	reason := func(name string) string {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+		require.NoError(t, err)
+
+		var pod corev1.Pod
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &pod))
+		return pod.Annotations[AnnotationKeyPreemptionReason]
+	}
+
+	require.Equal(t, "EvictionByEvictionAPI", reason("evicted"))
+	require.Equal(t, preemptionReasonNodeTermination, reason("terminating"))
+	require.Empty(t, reason("idle"), "the idle runner loses no job")
+	require.Empty(t, reason("running"))
+
+	require.Len(t, recorder.Events, 2)
+
+	// The job is counted only once
+	require.Equal(t, "EvictionByEvictionAPI", reason("evicted"))
+	require.Len(t, recorder.Events, 2)
+}
+
+func TestParseWorkflowJobURL(t *testing.T) {
+	owner, repo, runID, jobID, ok := parseWorkflowJobURL("https://ghes.example.com/owner/repo/actions/runs/123/job/456")
+	require.True(t, ok)
+	require.Equal(t, "owner", owner)
+	require.Equal(t, "repo", repo)
+	require.Equal(t, int64(123), runID)
+	require.Equal(t, int64(456), jobID)
+
+	_, _, _, _, ok = parseWorkflowJobURL("https://github.com/owner/repo/actions/runs/123")
+	require.False(t, ok)
+}
+
+func TestPreemptedJobsToRerun(t *testing.T) {
+	preempted := map[int64]struct{}{1: {}, 2: {}}
+
+	tests := []struct {
+		name            string
+		failed          []int64
+		wantJobIDs      []int64
+		wantRerunFailed bool
+	}{
+		{"succeeded despite the preemption", nil, nil, false},
+		{"one preempted job failed", []int64{1}, []int64{1}, false},
+		{"one preempted job failed along with another job", []int64{1, 3}, []int64{1}, false},
+		{"all failed jobs were preempted", []int64{1, 2}, []int64{1, 2}, true},
+		{"the preempted jobs failed along with another job", []int64{1, 2, 3}, []int64{1, 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobIDs, rerunFailed := preemptedJobsToRerun(preempted, tt.failed)
+			require.Equal(t, tt.wantJobIDs, jobIDs)
+			require.Equal(t, tt.wantRerunFailed, rerunFailed)
+		})
+	}
+}
+
+func TestPreemptedJobReruns(t *testing.T) {
+	ctx := context.Background()
+
+	status := "in_progress"
+	var reruns []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/test/valid/actions/runs/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1, "status": "` + status + `"}`))
+	})
+	mux.HandleFunc("/repos/test/valid/actions/runs/1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "latest", r.URL.Query().Get("filter"))
+		w.Write([]byte(`{"total_count": 3, "jobs": [{"id": 10, "conclusion": "failure"}, {"id": 11, "conclusion": "success"}, {"id": 12, "conclusion": "cancelled"}]}`))
+	})
+	mux.HandleFunc("/repos/test/valid/actions/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		reruns = append(reruns, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	p := &preemptedJobReruns{Log: logr.Discard(), Timeout: time.Hour}
+
+	now := time.Now()
+	pod := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	p.add(ghc, pod, "test", "valid", 1, 10, now)
+
+	p.check(ctx, now.Add(time.Minute))
+	require.Empty(t, reruns, "the job is rerun only after the run completes")
+	require.Len(t, p.runs, 1)
+
+	status = "completed"
+
+	p.check(ctx, now.Add(2*time.Minute))
+	require.Equal(t, []string{"/repos/test/valid/actions/jobs/10/rerun"}, reruns)
+	require.Empty(t, p.runs)
+
+	// Gives up once the run doesn't complete within the timeout
+	status = "in_progress"
+
+	p.add(ghc, pod, "test", "valid", 1, 12, now)
+	p.check(ctx, now.Add(2*time.Hour))
+	require.Len(t, reruns, 1)
+	require.Empty(t, p.runs)
+}
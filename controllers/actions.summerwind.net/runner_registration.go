@@ -0,0 +1,61 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runnerPodRegistrationTimedOut returns true when the runner pod has been running and ready for longer than the registration timeout
+// without the runner being seen registered to GitHub.
+func runnerPodRegistrationTimedOut(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning &&
+		!runnerPodOrContainerIsStopped(pod) &&
+		podRunnerID(pod) == "" &&
+		podConditionTransitionTimeAfter(pod, corev1.PodReady, registrationTimeout)
+}
+
+// recreateUnregisteredRunnerPod marks the runner pod that failed to register itself to GitHub and deletes it,
+// so that it is recreated by the parent Runner or StatefulSet and the new runner retries the registration with a fresh token.
+//
+// The caller must have confirmed via the ListRunners API that the runner isn't registered.
+// Deleting the pod is safe as GitHub can't have assigned any job to the unregistered runner.
+func recreateUnregisteredRunnerPod(ctx context.Context, c client.Client, log logr.Logger, recorder record.EventRecorder, enterprise, organization, repository string, pod *corev1.Pod) (ctrl.Result, error) {
+	if _, marked := getAnnotation(pod, AnnotationKeyRegistrationTimeoutTimestamp); !marked {
+		log.Info(
+			"Runner failed to register itself to GitHub in timely manner. "+
+				"Recreating the pod to see if it resolves the issue. "+
+				"CAUTION: If you see this a lot, you should investigate the root cause. "+
+				"See https://github.com/actions/actions-runner-controller/issues/288",
+			"creationTimestamp", pod.CreationTimestamp,
+			"readyTransitionTime", podConditionTransitionTime(pod, corev1.PodReady, corev1.ConditionTrue),
+			"configuredRegistrationTimeout", registrationTimeout,
+		)
+
+		updated, err := annotatePodOnce(ctx, c, log, pod, AnnotationKeyRegistrationTimeoutTimestamp, time.Now().Format(time.RFC3339))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		pod = updated
+
+		metrics.IncRunnerRegistrationFailures(pod.Namespace, enterprise, organization, repository)
+
+		recorder.Event(pod, corev1.EventTypeWarning, "RegistrationTimeout", fmt.Sprintf("Runner failed to register itself to GitHub within %s. Recreating the pod", registrationTimeout))
+	}
+
+	if err := c.Delete(ctx, pod); err != nil && !kerrors.IsNotFound(err) {
+		log.Error(err, "Failed to delete the runner pod that failed to register")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
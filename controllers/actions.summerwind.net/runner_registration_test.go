@@ -0,0 +1,164 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	arcv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestRecreateUnregisteredRunnerPod(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	newPod := func(readyFor time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "runner",
+				Namespace:  "default",
+				Finalizers: []string{runnerPodFinalizerName},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodReady,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-readyFor)),
+				}},
+			},
+		}
+	}
+
+	require.False(t, runnerPodRegistrationTimedOut(newPod(time.Minute)))
+	require.True(t, runnerPodRegistrationTimedOut(newPod(registrationTimeout+time.Minute)))
+
+	registered := newPod(registrationTimeout + time.Minute)
+	registered.Annotations = map[string]string{AnnotationKeyRunnerID: "1"}
+	require.False(t, runnerPodRegistrationTimedOut(registered))
+
+	pod := newPod(registrationTimeout + time.Minute)
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	res, err := recreateUnregisteredRunnerPod(ctx, c, logr.Discard(), recorder, "", "test", "", pod)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), res.RequeueAfter)
+
+	var updated corev1.Pod
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "runner"}, &updated))
+	require.NotEmpty(t, updated.Annotations[AnnotationKeyRegistrationTimeoutTimestamp])
+	require.NotNil(t, updated.DeletionTimestamp, "the runner pod must be deleted to be recreated")
+	require.Len(t, recorder.Events, 1)
+}
+
+// runnerRegistrationObservations returns the number of the registration durations observed for the runners
+// of the namespace, the RunnerDeployment and the RunnerSet.
+func runnerRegistrationObservations(t *testing.T, namespace, runnerDeployment, runnerSet string) uint64 {
+	t.Helper()
+
+	families, err := crmetrics.Registry.Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != "runner_registration_duration_seconds" {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			if labels["namespace"] == namespace && labels["runnerdeployment"] == runnerDeployment && labels["runnerset"] == runnerSet {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestRunnerPodReconcilerObservesRunnerRegistration(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, arcv1alpha1.AddToScheme(sc))
+
+	server := fake.NewServer(
+		fake.WithListRepositoryWorkflowRunsResponse(200, "", "", ""),
+		fake.WithListWorkflowJobsResponse(200, nil),
+		fake.WithListRunnersResponse(200, fake.RunnersListBody),
+	)
+	defer server.Close()
+
+	runner := &arcv1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test1",
+			Namespace:         "registration",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+		},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test1",
+			Namespace: "registration",
+			Labels: map[string]string{
+				LabelKeyRunner:               "",
+				LabelKeyRunnerDeploymentName: "example-runnerdeploy",
+			},
+			Finalizers: []string{runnerPodFinalizerName},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "runner",
+				Env:  []corev1.EnvVar{{Name: EnvVarRepo, Value: "test/valid"}},
+			}},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(runner, pod).Build()
+
+	r := &RunnerPodReconciler{
+		Client:       c,
+		Log:          logr.Discard(),
+		Recorder:     record.NewFakeRecorder(10),
+		Scheme:       sc,
+		GitHubClient: NewMultiGitHubClient(&testResourceReader{objects: map[types.NamespacedName]client.Object{}}, newGithubClient(server)),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "registration", Name: "test1"}}
+
+	// Observed once the runner ID annotation is added to the pod of the runner seen registered
+	_, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), runnerRegistrationObservations(t, "registration", "example-runnerdeploy", ""))
+
+	var updated corev1.Pod
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	require.Equal(t, "1", updated.Annotations[AnnotationKeyRunnerID])
+
+	// Not observed again for the pod already annotated
+	_, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), runnerRegistrationObservations(t, "registration", "example-runnerdeploy", ""))
+}
@@ -0,0 +1,78 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// secretMountsDir is the directory the secret mounts without a mount path are mounted under.
+	secretMountsDir = "/var/run/secrets/actions-runner"
+
+	secretsStoreCSIDriver = "secrets-store.csi.k8s.io"
+)
+
+func secretMountVolumeName(m v1alpha1.RunnerSecretMount) string {
+	return "secrets-" + m.Name
+}
+
+// newSecretMountVolume returns the volume of the secret mount.
+// Neither of the volumes is mounted with subPath, so that the kubelet, or the Secrets Store CSI Driver with the rotation enabled,
+// updates the files when the secrets change.
+func newSecretMountVolume(m v1alpha1.RunnerSecretMount) corev1.Volume {
+	v := corev1.Volume{Name: secretMountVolumeName(m)}
+
+	if m.SecretProviderClass != "" {
+		readOnly := true
+
+		v.CSI = &corev1.CSIVolumeSource{
+			Driver:   secretsStoreCSIDriver,
+			ReadOnly: &readOnly,
+			VolumeAttributes: map[string]string{
+				"secretProviderClass": m.SecretProviderClass,
+			},
+		}
+
+		return v
+	}
+
+	var sources []corev1.VolumeProjection
+
+	for _, name := range m.SecretNames {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+
+	v.Projected = &corev1.ProjectedVolumeSource{Sources: sources}
+
+	return v
+}
+
+// applySecretMounts mounts the secret mounts into the runner container.
+func applySecretMounts(pod *corev1.Pod, mounts []v1alpha1.RunnerSecretMount) {
+	for _, m := range mounts {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, newSecretMountVolume(m))
+
+		mountPath := m.MountPath
+		if mountPath == "" {
+			mountPath = secretMountsDir + "/" + m.Name
+		}
+
+		for i := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[i]
+
+			if c.Name != containerName {
+				continue
+			}
+
+			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+				Name:      secretMountVolumeName(m),
+				MountPath: mountPath,
+				ReadOnly:  true,
+			})
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplySecretMounts(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "docker"},
+			},
+		},
+	}
+
+	applySecretMounts(pod, []v1alpha1.RunnerSecretMount{
+		{Name: "npm", SecretNames: []string{"npm-token", "npm-registry"}},
+		{Name: "vault", MountPath: "/etc/vault", SecretProviderClass: "ci-secrets"},
+	})
+
+	require.Len(t, pod.Spec.Volumes, 2)
+
+	npm := pod.Spec.Volumes[0]
+	require.Equal(t, "secrets-npm", npm.Name)
+	require.Len(t, npm.Projected.Sources, 2)
+	require.Equal(t, "npm-token", npm.Projected.Sources[0].Secret.Name)
+	require.Equal(t, "npm-registry", npm.Projected.Sources[1].Secret.Name)
+
+	vault := pod.Spec.Volumes[1]
+	require.Equal(t, "secrets-store.csi.k8s.io", vault.CSI.Driver)
+	require.Equal(t, map[string]string{"secretProviderClass": "ci-secrets"}, vault.CSI.VolumeAttributes)
+
+	require.Equal(t, []corev1.VolumeMount{
+		{Name: "secrets-npm", MountPath: "/var/run/secrets/actions-runner/npm", ReadOnly: true},
+		{Name: "secrets-vault", MountPath: "/etc/vault", ReadOnly: true},
+	}, pod.Spec.Containers[0].VolumeMounts)
+
+	// The secrets aren't exposed to the sidecars
+	require.Empty(t, pod.Spec.Containers[1].VolumeMounts)
+}
+
+func TestValidateSecretMounts(t *testing.T) {
+	spec := v1alpha1.RunnerSpec{
+		RunnerConfig: v1alpha1.RunnerConfig{Repository: "owner/repo"},
+		RunnerPodSpec: v1alpha1.RunnerPodSpec{
+			SecretMounts: []v1alpha1.RunnerSecretMount{
+				{Name: "npm", SecretNames: []string{"npm-token"}},
+				{Name: "npm", SecretProviderClass: "ci-secrets"},
+				{Name: "both", SecretNames: []string{"npm-token"}, SecretProviderClass: "ci-secrets"},
+				{Name: "neither"},
+			},
+		},
+	}
+
+	errs := spec.Validate(nil)
+	require.Len(t, errs, 3)
+	require.Equal(t, "secretMounts[1].name", errs[0].Field)
+	require.Equal(t, "secretMounts[2]", errs[1].Field)
+	require.Equal(t, "secretMounts[3]", errs[2].Field)
+}
@@ -0,0 +1,66 @@
+package actionssummerwindnet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// runnerStateReport is the JSON the runner entrypoint and the job hooks put onto the runner pod in the runner state annotation.
+// It is the contract between the runner images and the controller, so the fields must only be added, never renamed.
+type runnerStateReport struct {
+	v1alpha1.RunnerState `json:",inline"`
+
+	// Workflow is the workflow run of the job, reported with JobStarted and JobCompleted.
+	Workflow *v1alpha1.WorkflowStatus `json:"workflow,omitempty"`
+}
+
+// runnerPodState returns the state reported onto the runner pod, or nil when the runner hasn't reported any.
+func runnerPodState(pod *corev1.Pod) (*runnerStateReport, error) {
+	v, ok := getAnnotation(pod, AnnotationKeyRunnerState)
+	if !ok {
+		return nil, nil
+	}
+
+	var report runnerStateReport
+	if err := json.Unmarshal([]byte(v), &report); err != nil {
+		return nil, fmt.Errorf("parsing the %s annotation: %w", AnnotationKeyRunnerState, err)
+	}
+
+	switch report.State {
+	case v1alpha1.RunnerStateRegistering, v1alpha1.RunnerStateIdle, v1alpha1.RunnerStateJobStarted, v1alpha1.RunnerStateJobCompleted:
+	default:
+		return nil, fmt.Errorf("unknown runner state %q in the %s annotation", report.State, AnnotationKeyRunnerState)
+	}
+
+	return &report, nil
+}
+
+// applyRunnerState updates the status of the runner with the reported state.
+// The phase follows the state only when the runner status update hook is enabled, as it's derived from the pod phase otherwise.
+func applyRunnerState(status *v1alpha1.RunnerStatus, report *runnerStateReport, updatePhase bool) {
+	state := report.RunnerState
+	status.State = &state
+
+	if report.Workflow != nil {
+		workflow := *report.Workflow
+		status.WorkflowStatus = &workflow
+	}
+
+	if !updatePhase {
+		return
+	}
+
+	switch report.State {
+	case v1alpha1.RunnerStateRegistering:
+		status.Phase = "Registering"
+	case v1alpha1.RunnerStateJobStarted:
+		status.Phase = runnerPhaseRunning
+	default:
+		status.Phase = "Idle"
+	}
+
+	status.Message = report.Message
+}
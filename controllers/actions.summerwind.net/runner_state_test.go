@@ -0,0 +1,52 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunnerPodState(t *testing.T) {
+	pod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	report, err := runnerPodState(pod(nil))
+	require.NoError(t, err)
+	require.Nil(t, report)
+
+	_, err = runnerPodState(pod(map[string]string{AnnotationKeyRunnerState: `{"state":"Busy"}`}))
+	require.Error(t, err)
+
+	_, err = runnerPodState(pod(map[string]string{AnnotationKeyRunnerState: `not json`}))
+	require.Error(t, err)
+
+	report, err = runnerPodState(pod(map[string]string{
+		AnnotationKeyRunnerState: `{"state":"JobStarted","message":"Run 123 from owner/repo","time":"2023-01-02T15:04:05Z","workflow":{"repository":"owner/repo","runID":"123","job":"build"}}`,
+	}))
+	require.NoError(t, err)
+
+	var status v1alpha1.RunnerStatus
+
+	applyRunnerState(&status, report, false)
+	require.Equal(t, v1alpha1.RunnerStateJobStarted, status.State.State)
+	require.Equal(t, "owner/repo", status.WorkflowStatus.Repository)
+	require.Empty(t, status.Phase, "the phase follows the pod phase without the runner status update hook")
+
+	applyRunnerState(&status, report, true)
+	require.Equal(t, "Running", status.Phase)
+	require.Equal(t, "Run 123 from owner/repo", status.Message)
+
+	report, err = runnerPodState(pod(map[string]string{
+		AnnotationKeyRunnerState: `{"state":"JobCompleted","conclusion":"failed"}`,
+	}))
+	require.NoError(t, err)
+
+	applyRunnerState(&status, report, true)
+	require.Equal(t, "Idle", status.Phase)
+	require.Equal(t, "failed", status.State.Conclusion)
+	require.Equal(t, "build", status.WorkflowStatus.Job, "the workflow is kept when the state doesn't report it")
+}
@@ -0,0 +1,182 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/capacityplacement"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LabelKeyCapacityType is the label on the Runner and the runner pod that tells which capacity type,
+// either spot or on-demand, spec.capacityDistribution put the runner on.
+const LabelKeyCapacityType = "actions-runner/capacity-type"
+
+// newCapacityPlacement returns the placement of the runners of the RunnerReplicaSet,
+// which counts the runners already on each capacity type.
+func newCapacityPlacement(rs *v1alpha1.RunnerReplicaSet, runners []v1alpha1.Runner, replicas int, now time.Time) *capacityplacement.Placement {
+	d := rs.Spec.CapacityDistribution
+
+	var spotUnavailableSince *metav1.Time
+	if s := rs.Status.CapacityDistribution; s != nil {
+		spotUnavailableSince = s.SpotUnavailableSince
+	}
+
+	p := capacityplacement.New(capacityplacement.Distribution{
+		SpotFirst:         d.Policy == v1alpha1.CapacityDistributionPolicySpotFirst,
+		SpotPercentage:    d.SpotPercentage,
+		FallbackAfter:     d.FallbackAfter,
+		SpotRetryInterval: d.SpotRetryInterval,
+	}, replicas, spotUnavailableSince, now)
+
+	for _, r := range runners {
+		if !r.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		p.Add(r.Labels[LabelKeyCapacityType], 1)
+	}
+
+	return p
+}
+
+func capacityDistributionStatus(p *capacityplacement.Placement) *v1alpha1.CapacityDistributionStatus {
+	return &v1alpha1.CapacityDistributionStatus{
+		Spot:                 p.Spot,
+		OnDemand:             p.OnDemand,
+		SpotUnavailableSince: p.SpotUnavailableSince,
+	}
+}
+
+// applyCapacityType labels the runner with the capacity type,
+// and adds the node selector and the tolerations of the capacity type to the runner pod.
+func applyCapacityType(runner *v1alpha1.Runner, d *v1alpha1.CapacityDistribution, capacityType string) {
+	subset := d.OnDemand
+	if capacityType == v1alpha1.CapacityTypeSpot {
+		subset = d.Spot
+	}
+
+	runner.Labels = CloneAndAddLabel(runner.Labels, LabelKeyCapacityType, capacityType)
+
+	if len(subset.NodeSelector) > 0 {
+		nodeSelector := make(map[string]string, len(runner.Spec.NodeSelector)+len(subset.NodeSelector))
+		for k, v := range runner.Spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		for k, v := range subset.NodeSelector {
+			nodeSelector[k] = v
+		}
+		runner.Spec.NodeSelector = nodeSelector
+	}
+
+	runner.Spec.Tolerations = append(append([]corev1.Toleration{}, runner.Spec.Tolerations...), subset.Tolerations...)
+}
+
+// fallBackToOnDemand deletes the spot runners of the SpotFirst policy whose pods have been unschedulable for longer than fallbackAfter,
+// so that they are recreated on the on-demand capacity. It returns true once it marked the spot capacity unavailable.
+func (r *RunnerReplicaSetReconciler) fallBackToOnDemand(ctx context.Context, log logr.Logger, rs *v1alpha1.RunnerReplicaSet, p *capacityplacement.Placement, runners []v1alpha1.Runner, now time.Time) (bool, error) {
+	if !p.FallsBack() {
+		return false, nil
+	}
+
+	var unschedulable []v1alpha1.Runner
+
+	for _, runner := range runners {
+		if !runner.DeletionTimestamp.IsZero() || runner.Labels[LabelKeyCapacityType] != v1alpha1.CapacityTypeSpot {
+			continue
+		}
+
+		var pod corev1.Pod
+		if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, &pod); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		if p.Unschedulable(&pod, now) {
+			unschedulable = append(unschedulable, runner)
+		}
+	}
+
+	if len(unschedulable) == 0 {
+		return false, nil
+	}
+
+	p.FallBack(now)
+
+	for i := range unschedulable {
+		runner := unschedulable[i]
+
+		if err := r.Delete(ctx, &runner); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		log.Info("Deleted the unschedulable spot runner to recreate it on the on-demand capacity", "runner", runner.Name)
+	}
+
+	r.Recorder.Eventf(rs, corev1.EventTypeWarning, "SpotCapacityUnavailable",
+		"Falling back to the on-demand capacity for %s as %d spot runner(s) were unschedulable for %s", p.SpotRetryInterval(), len(unschedulable), p.FallbackAfter())
+
+	return true, nil
+}
+
+// rebalanceCapacity deletes one idle runner that is on the surplus capacity type, or has no capacity type yet,
+// so that it's recreated on the capacity type that has fewer runners than the target.
+// The runners are replaced one at a time, only while all the runners are running.
+func (r *RunnerReplicaSetReconciler) rebalanceCapacity(ctx context.Context, log logr.Logger, p *capacityplacement.Placement, runners []v1alpha1.Runner) error {
+	deficit := p.Deficit()
+	if deficit == "" {
+		return nil
+	}
+
+	for i := range runners {
+		runner := runners[i]
+
+		if !runner.DeletionTimestamp.IsZero() || runner.Labels[LabelKeyCapacityType] == deficit {
+			continue
+		}
+
+		if runner.Annotations[AnnotationKeyRunnerBusy] == "true" {
+			continue
+		}
+
+		if err := r.Delete(ctx, &runner); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		log.Info("Deleted the idle runner to rebalance the capacity types", "runner", runner.Name, "capacityType", runner.Labels[LabelKeyCapacityType], "deficit", deficit)
+
+		return nil
+	}
+
+	return nil
+}
+
+// sumCapacityDistributionStatus sums the runners on each capacity type across the runnerreplicasets of a RunnerDeployment.
+func sumCapacityDistributionStatus(d *v1alpha1.CapacityDistribution, newestSet *v1alpha1.RunnerReplicaSet, oldSets []v1alpha1.RunnerReplicaSet) *v1alpha1.CapacityDistributionStatus {
+	if d == nil {
+		return nil
+	}
+
+	var status v1alpha1.CapacityDistributionStatus
+
+	for _, rs := range append([]v1alpha1.RunnerReplicaSet{*newestSet}, oldSets...) {
+		if s := rs.Status.CapacityDistribution; s != nil {
+			status.Spot += s.Spot
+			status.OnDemand += s.OnDemand
+		}
+	}
+
+	if s := newestSet.Status.CapacityDistribution; s != nil {
+		status.SpotUnavailableSince = s.SpotUnavailableSince
+	}
+
+	return &status
+}
@@ -0,0 +1,157 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCapacityPlacement(t *testing.T) {
+	now := time.Now()
+
+	newRunner := func(capacityType string) v1alpha1.Runner {
+		r := v1alpha1.Runner{}
+		if capacityType != "" {
+			r.Labels = map[string]string{LabelKeyCapacityType: capacityType}
+		}
+		return r
+	}
+
+	weighted := &v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{SpotPercentage: 80},
+		},
+	}
+
+	p := newCapacityPlacement(weighted, nil, 5, now)
+
+	var types []string
+	for i := 0; i < 5; i++ {
+		types = append(types, p.Next())
+	}
+	require.Equal(t, []string{"spot", "spot", "spot", "spot", "on-demand"}, types)
+	require.Empty(t, p.Deficit())
+
+	// The runners without the capacity type label are replaced to fill the deficit
+	p = newCapacityPlacement(weighted, []v1alpha1.Runner{newRunner("spot"), newRunner("on-demand"), newRunner("")}, 3, now)
+	require.Equal(t, v1alpha1.CapacityTypeSpot, p.Deficit())
+
+	p = newCapacityPlacement(weighted, []v1alpha1.Runner{newRunner("spot"), newRunner("spot"), newRunner("spot")}, 3, now)
+	require.Equal(t, v1alpha1.CapacityTypeOnDemand, p.Deficit())
+
+	spotFirst := &v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{Policy: v1alpha1.CapacityDistributionPolicySpotFirst},
+		},
+		Status: v1alpha1.RunnerReplicaSetStatus{
+			CapacityDistribution: &v1alpha1.CapacityDistributionStatus{
+				SpotUnavailableSince: &metav1.Time{Time: now.Add(-10 * time.Minute)},
+			},
+		},
+	}
+
+	p = newCapacityPlacement(spotFirst, []v1alpha1.Runner{newRunner("on-demand")}, 2, now)
+	require.Equal(t, v1alpha1.CapacityTypeOnDemand, p.Next())
+	require.Empty(t, p.Deficit(), "the spot capacity is unavailable")
+
+	// The spot capacity is retried after the retry interval, moving the on-demand runners back
+	p = newCapacityPlacement(spotFirst, []v1alpha1.Runner{newRunner("on-demand")}, 2, now.Add(6*time.Minute))
+	require.Nil(t, p.SpotUnavailableSince)
+	require.Equal(t, v1alpha1.CapacityTypeSpot, p.Next())
+	require.Equal(t, v1alpha1.CapacityTypeSpot, p.Deficit())
+}
+
+func TestApplyCapacityType(t *testing.T) {
+	d := &v1alpha1.CapacityDistribution{
+		Spot: v1alpha1.CapacitySubset{
+			NodeSelector: map[string]string{"karpenter.sh/capacity-type": "spot"},
+			Tolerations:  []corev1.Toleration{{Key: "spot", Operator: corev1.TolerationOpExists}},
+		},
+	}
+
+	runner := &v1alpha1.Runner{}
+	runner.Spec.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+
+	applyCapacityType(runner, d, v1alpha1.CapacityTypeSpot)
+
+	require.Equal(t, "spot", runner.Labels[LabelKeyCapacityType])
+	require.Equal(t, map[string]string{"kubernetes.io/arch": "amd64", "karpenter.sh/capacity-type": "spot"}, runner.Spec.NodeSelector)
+	require.Equal(t, d.Spot.Tolerations, runner.Spec.Tolerations)
+}
+
+func TestFallBackToOnDemand(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	newRunner := func(name string) *v1alpha1.Runner {
+		return &v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{LabelKeyCapacityType: v1alpha1.CapacityTypeSpot},
+			},
+		}
+	}
+
+	newPod := func(name string, unschedulableFor time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					Reason:             corev1.PodReasonUnschedulable,
+					LastTransitionTime: metav1.Time{Time: now.Add(-unschedulableFor)},
+				}},
+			},
+		}
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		newRunner("pending"), newPod("pending", time.Minute),
+		newRunner("unschedulable"), newPod("unschedulable", 5*time.Minute),
+	).Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerReplicaSetReconciler{Client: c, Recorder: recorder}
+
+	rs := &v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			CapacityDistribution: &v1alpha1.CapacityDistribution{Policy: v1alpha1.CapacityDistributionPolicySpotFirst},
+		},
+	}
+
+	var runners v1alpha1.RunnerList
+	require.NoError(t, c.List(ctx, &runners))
+
+	p := newCapacityPlacement(rs, runners.Items, 2, now)
+
+	fellBack, err := r.fallBackToOnDemand(ctx, logr.Discard(), rs, p, runners.Items, now)
+	require.NoError(t, err)
+	require.True(t, fellBack)
+	require.Equal(t, now, p.SpotUnavailableSince.Time)
+	require.Len(t, recorder.Events, 1)
+
+	require.NoError(t, c.List(ctx, &runners))
+	require.Len(t, runners.Items, 1)
+	require.Equal(t, "pending", runners.Items[0].Name, "the runner pending for less than fallbackAfter is kept")
+
+	// The replacement goes to the on-demand capacity
+	require.Equal(t, v1alpha1.CapacityTypeOnDemand, p.Next())
+}
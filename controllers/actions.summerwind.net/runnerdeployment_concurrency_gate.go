@@ -0,0 +1,178 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	DefaultConcurrencyGateCheckInterval = 30 * time.Second
+	DefaultConcurrencyGateHTTPTimeout   = 10 * time.Second
+)
+
+// checkConcurrencyGate returns the state of the concurrency gate of the RunnerDeployment, which is nil when it has no concurrency gate.
+// The conditions are checked at most once per checkInterval, and the result of the last check is reused in the meantime.
+func (r *RunnerDeploymentReconciler) checkConcurrencyGate(ctx context.Context, rd *v1alpha1.RunnerDeployment, now time.Time) *v1alpha1.ConcurrencyGateStatus {
+	gate := rd.Spec.ConcurrencyGate
+	if gate == nil {
+		return nil
+	}
+
+	if last := rd.Status.ConcurrencyGate; last != nil && last.LastCheckTime != nil && now.Sub(last.LastCheckTime.Time) < concurrencyGateCheckInterval(gate) {
+		return &v1alpha1.ConcurrencyGateStatus{
+			Open:          last.Open,
+			Reason:        last.Reason,
+			LastCheckTime: last.LastCheckTime,
+		}
+	}
+
+	status := &v1alpha1.ConcurrencyGateStatus{
+		Open:          true,
+		LastCheckTime: &metav1.Time{Time: now},
+	}
+
+	for _, check := range []func() (bool, string, error){
+		func() (bool, string, error) {
+			return r.checkConcurrencyGateConfigMap(ctx, rd.Namespace, gate.ConfigMap)
+		},
+		func() (bool, string, error) {
+			return checkConcurrencyGateHTTP(ctx, gate.HTTP)
+		},
+		func() (bool, string, error) {
+			return r.checkConcurrencyGateLease(ctx, rd.Namespace, gate.Lease, now)
+		},
+	} {
+		holds, reason, err := check()
+		if err != nil {
+			if gate.FailurePolicy == v1alpha1.ConcurrencyGateFailurePolicyClosed {
+				status.Open = false
+				status.Reason = err.Error()
+				return status
+			}
+
+			continue
+		}
+
+		if !holds {
+			status.Open = false
+			status.Reason = reason
+			return status
+		}
+	}
+
+	return status
+}
+
+func concurrencyGateCheckInterval(gate *v1alpha1.ConcurrencyGate) time.Duration {
+	if gate.CheckInterval != nil {
+		return gate.CheckInterval.Duration
+	}
+
+	return DefaultConcurrencyGateCheckInterval
+}
+
+func (r *RunnerDeploymentReconciler) checkConcurrencyGateConfigMap(ctx context.Context, namespace string, c *v1alpha1.ConcurrencyGateConfigMap) (bool, string, error) {
+	if c == nil {
+		return true, "", nil
+	}
+
+	want := c.Value
+	if want == "" {
+		want = "true"
+	}
+
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: c.Name}, &cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, fmt.Sprintf("ConfigMap %s not found", c.Name), nil
+		}
+		return false, "", fmt.Errorf("getting ConfigMap %s: %w", c.Name, err)
+	}
+
+	if v := cm.Data[c.Key]; v != want {
+		return false, fmt.Sprintf("ConfigMap %s has %s=%q instead of %q", c.Name, c.Key, v, want), nil
+	}
+
+	return true, "", nil
+}
+
+func checkConcurrencyGateHTTP(ctx context.Context, h *v1alpha1.ConcurrencyGateHTTP) (bool, string, error) {
+	if h == nil {
+		return true, "", nil
+	}
+
+	timeout := DefaultConcurrencyGateHTTPTimeout
+	if h.Timeout != nil {
+		timeout = h.Timeout.Duration
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return false, "", err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("checking %s: %w", h.URL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return false, fmt.Sprintf("GET %s returned %d", h.URL, res.StatusCode), nil
+	}
+
+	return true, "", nil
+}
+
+func (r *RunnerDeploymentReconciler) checkConcurrencyGateLease(ctx context.Context, namespace string, l *v1alpha1.ConcurrencyGateLease, now time.Time) (bool, string, error) {
+	if l == nil {
+		return true, "", nil
+	}
+
+	var lease coordinationv1.Lease
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: l.Name}, &lease); err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, "", nil
+		}
+		return false, "", fmt.Errorf("getting Lease %s: %w", l.Name, err)
+	}
+
+	holder := lease.Spec.HolderIdentity
+	if holder == nil || *holder == "" {
+		return true, "", nil
+	}
+
+	// The lease is released once its holder stops renewing it
+	if lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil {
+		expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if !now.Before(expiry) {
+			return true, "", nil
+		}
+	}
+
+	return false, fmt.Sprintf("Lease %s is held by %s", l.Name, *holder), nil
+}
+
+// applyConcurrencyGate keeps the desired replicas of the newest runner replica set from exceeding its current replicas while the gate is closed.
+// It records the withheld replicas into the gate status.
+func applyConcurrencyGate(gate *v1alpha1.ConcurrencyGateStatus, desiredReplicas, currentReplicas int) int {
+	if gate == nil || gate.Open || desiredReplicas <= currentReplicas {
+		return desiredReplicas
+	}
+
+	gate.WithheldReplicas = desiredReplicas - currentReplicas
+
+	return currentReplicas
+}
@@ -0,0 +1,122 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckConcurrencyGate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	newRunnerDeployment := func(gate v1alpha1.ConcurrencyGate) *v1alpha1.RunnerDeployment {
+		return &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+			Spec:       v1alpha1.RunnerDeploymentSpec{ConcurrencyGate: &gate},
+		}
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci-capacity", Namespace: "default"},
+		Data:       map[string]string{"open": "false"},
+	}
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "maintenance", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr("oncall"),
+			RenewTime:            &metav1.MicroTime{Time: now.Add(-time.Minute)},
+			LeaseDurationSeconds: ptr[int32](3600),
+		},
+	}
+
+	r := &RunnerDeploymentReconciler{
+		Client: clientfake.NewClientBuilder().WithScheme(sc).WithObjects(configMap, lease).Build(),
+	}
+
+	status := r.checkConcurrencyGate(ctx, newRunnerDeployment(v1alpha1.ConcurrencyGate{
+		ConfigMap: &v1alpha1.ConcurrencyGateConfigMap{Name: "ci-capacity", Key: "open"},
+	}), now)
+	require.False(t, status.Open)
+	require.Equal(t, `ConfigMap ci-capacity has open="false" instead of "true"`, status.Reason)
+
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(v1alpha1.ConcurrencyGate{
+		ConfigMap: &v1alpha1.ConcurrencyGateConfigMap{Name: "ci-capacity", Key: "open", Value: "false"},
+	}), now)
+	require.True(t, status.Open)
+
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(v1alpha1.ConcurrencyGate{
+		Lease: &v1alpha1.ConcurrencyGateLease{Name: "maintenance"},
+	}), now)
+	require.False(t, status.Open)
+	require.Equal(t, "Lease maintenance is held by oncall", status.Reason)
+
+	// The lease is released once its holder stops renewing it
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(v1alpha1.ConcurrencyGate{
+		Lease: &v1alpha1.ConcurrencyGateLease{Name: "maintenance"},
+	}), now.Add(2*time.Hour))
+	require.True(t, status.Open)
+
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(v1alpha1.ConcurrencyGate{
+		Lease: &v1alpha1.ConcurrencyGateLease{Name: "missing"},
+	}), now)
+	require.True(t, status.Open)
+
+	code := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	rd := newRunnerDeployment(v1alpha1.ConcurrencyGate{HTTP: &v1alpha1.ConcurrencyGateHTTP{URL: server.URL}})
+
+	status = r.checkConcurrencyGate(ctx, rd, now)
+	require.False(t, status.Open)
+	require.Equal(t, "GET "+server.URL+" returned 503", status.Reason)
+
+	// The result of the last check is reused until the check interval elapses
+	rd.Status.ConcurrencyGate = status
+	code = http.StatusOK
+
+	status = r.checkConcurrencyGate(ctx, rd, now.Add(10*time.Second))
+	require.False(t, status.Open)
+
+	status = r.checkConcurrencyGate(ctx, rd, now.Add(time.Minute))
+	require.True(t, status.Open)
+
+	// The unreachable endpoint opens the gate by default
+	unreachable := v1alpha1.ConcurrencyGate{HTTP: &v1alpha1.ConcurrencyGateHTTP{URL: "http://127.0.0.1:1"}}
+
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(unreachable), now)
+	require.True(t, status.Open)
+
+	unreachable.FailurePolicy = v1alpha1.ConcurrencyGateFailurePolicyClosed
+
+	status = r.checkConcurrencyGate(ctx, newRunnerDeployment(unreachable), now)
+	require.False(t, status.Open)
+	require.Contains(t, status.Reason, "checking http://127.0.0.1:1")
+}
+
+func TestApplyConcurrencyGate(t *testing.T) {
+	require.Equal(t, 5, applyConcurrencyGate(nil, 5, 3))
+	require.Equal(t, 5, applyConcurrencyGate(&v1alpha1.ConcurrencyGateStatus{Open: true}, 5, 3))
+
+	closed := &v1alpha1.ConcurrencyGateStatus{}
+	require.Equal(t, 3, applyConcurrencyGate(closed, 5, 3))
+	require.Equal(t, 2, closed.WithheldReplicas)
+
+	// Scale down isn't blocked
+	closed = &v1alpha1.ConcurrencyGateStatus{}
+	require.Equal(t, 1, applyConcurrencyGate(closed, 1, 3))
+	require.Zero(t, closed.WithheldReplicas)
+}
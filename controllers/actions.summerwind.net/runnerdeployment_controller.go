@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"hash/fnv"
+	"net/http"
 	"reflect"
 	"sort"
 	"time"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/tools/record"
@@ -35,10 +37,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 )
 
 const (
@@ -56,14 +65,41 @@ type RunnerDeploymentReconciler struct {
 	Scheme             *runtime.Scheme
 	CommonRunnerLabels []string
 	Name               string
+
+	// EgressPolicyGitHubFQDNs are the hostnames of GitHub allowed by the Cilium egress policies, like the host of GitHub Enterprise Server.
+	// Defaults to DefaultEgressPolicyGitHubFQDNs.
+	EgressPolicyGitHubFQDNs []string
+
+	// ImageRegistryHTTPClient is the HTTP client of the registries of the image policies. Defaults to the client of imageregistry.NewClient.
+	ImageRegistryHTTPClient *http.Client
+
+	// BuildkitImage is the rootless buildkit image of the jobs building the runner images of the RunnerDeployments with imageBuild.
+	// Defaults to DefaultImageBuildBuildkitImage.
+	BuildkitImage string
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+
+	// ciliumAvailable is true when the CiliumNetworkPolicy CRD was found on setup.
+	ciliumAvailable bool
 }
 
+// DefaultImageBuildBuildkitImage is the default image of the jobs building the runner images.
+const DefaultImageBuildBuildkitImage = "moby/buildkit:rootless"
+
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerreplicasets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerreplicasets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get
 
 func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("runnerdeployment", req.NamespacedName)
@@ -73,12 +109,111 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(rd.Namespace, sharding.Owner(rd.Spec.Template.Spec.Enterprise, rd.Spec.Template.Spec.Organization, rd.Spec.Template.Spec.Repository)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !rd.ObjectMeta.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
 
 	metrics.SetRunnerDeployment(rd)
 
+	// The replicas, the profile replicas and the effective time are set by HRA
+	if suspendedOnDrift(ctx, r.Client, r.Recorder, log, &rd, runnerDeploymentConditions, []string{"replicas", "profileReplicas", "effectiveTime"}) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.syncBusyRunnerPodDisruptionBudget(ctx, log, &rd); err != nil {
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	if err := r.syncEgressPolicy(ctx, log, &rd); err != nil {
+		r.Recorder.Event(&rd, corev1.EventTypeWarning, "EgressPolicyFailed", err.Error())
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	image, err := r.syncImageBuild(ctx, log, &rd)
+	if err != nil {
+		log.Error(err, "Failed to sync the build of the runner image")
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	if rd.Spec.ImageBuild != nil {
+		if image == "" {
+			log.V(1).Info("Waiting for the first build of the runner image to succeed")
+			if err := patchConditions(ctx, r.Client, &rd, runnerDeploymentConditions, func(conditions *[]metav1.Condition) {
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonWaitingForImageBuild, "Waiting for the first build of the runner image to succeed")
+			}); err != nil {
+				log.Error(err, "Failed to update runnerdeployment status conditions")
+			}
+			return ctrl.Result{}, nil
+		}
+
+		// The digest of the image is part of the template hash, so a new digest rolls the runners out
+		rd.Spec.Template.Spec.Image = image
+	}
+
+	image, imagePolicyRequeueAfter, err := r.syncImagePolicy(ctx, log, &rd, time.Now())
+	if err != nil {
+		log.Error(err, "Failed to sync the image policy")
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	if rd.Spec.ImagePolicy != nil {
+		if image == "" {
+			log.V(1).Info("Waiting for the digest of the runner image to be verified")
+			if err := patchConditions(ctx, r.Client, &rd, runnerDeploymentConditions, func(conditions *[]metav1.Condition) {
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonWaitingForImagePolicy, imagePolicyWaitingMessage(rd.Status.ImagePolicy))
+			}); err != nil {
+				log.Error(err, "Failed to update runnerdeployment status conditions")
+			}
+			return ctrl.Result{RequeueAfter: imagePolicyRequeueAfter}, nil
+		}
+
+		// The runners are pinned to the digest, which is part of the template hash
+		rd.Spec.Template.Spec.Image = image
+	}
+
+	image, imageVerificationRequeueAfter, err := r.syncImageVerification(ctx, log, &rd, time.Now())
+	if err != nil {
+		log.Error(err, "Failed to sync the image verification")
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	if rd.Spec.ImageVerification != nil {
+		if image == "" {
+			log.V(1).Info("Waiting for the runner image to be verified")
+			if err := patchConditions(ctx, r.Client, &rd, runnerDeploymentConditions, func(conditions *[]metav1.Condition) {
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+				setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonVerificationFailed, imageVerificationWaitingMessage(rd.Status.ImageVerification))
+			}); err != nil {
+				log.Error(err, "Failed to update runnerdeployment status conditions")
+			}
+			return ctrl.Result{RequeueAfter: imageVerificationRequeueAfter}, nil
+		}
+
+		// A refused image leaves the runners on the digest verified last, as the template hash stays the same
+		rd.Spec.Template.Spec.Image = image
+	}
+
+	if err := r.syncToolCache(ctx, log, &rd); err != nil {
+		log.Error(err, "Failed to sync the tool cache")
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+		return ctrl.Result{}, err
+	}
+
+	if rd.Spec.ToolCache != nil {
+		applyToolCache(&rd.Spec.Template, rd.Spec.ToolCache)
+	}
+
 	var myRunnerReplicaSetList v1alpha1.RunnerReplicaSetList
 	if err := r.List(ctx, &myRunnerReplicaSetList, client.InNamespace(req.Namespace), client.MatchingFields{runnerSetOwnerKey: req.Name}); err != nil {
 		return ctrl.Result{}, err
@@ -108,10 +243,30 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 		log.Error(err, "Could not create runnerreplicaset")
 
+		r.setRunnerDeploymentFailedConditions(ctx, log, &rd, err)
+
 		return ctrl.Result{}, err
 	}
 
+	concurrencyGate := r.checkConcurrencyGate(ctx, &rd, time.Now())
+	if concurrencyGate != nil {
+		if prev := rd.Status.ConcurrencyGate; prev == nil || prev.Open != concurrencyGate.Open {
+			if concurrencyGate.Open {
+				r.Recorder.Event(&rd, corev1.EventTypeNormal, "ConcurrencyGateOpened", "Resumed the scale up and the rollout")
+			} else {
+				r.Recorder.Event(&rd, corev1.EventTypeWarning, "ConcurrencyGateClosed", fmt.Sprintf("Paused the scale up and the rollout: %s", concurrencyGate.Reason))
+			}
+		}
+	}
+
+	gateClosed := concurrencyGate != nil && !concurrencyGate.Open
+
 	if newestSet == nil {
+		replicas, _ := applySchedulingBudget(rd, getIntOrDefault(desiredRS.Spec.Replicas, 1), nil)
+		replicas = applyConcurrencyGate(concurrencyGate, replicas, 0)
+		replicas, scaleUpWave, requeueAfter := applyScaleUpWaves(rd.Spec.ScaleUpWaves, nil, "", replicas, 0, 0, time.Now())
+		desiredRS.Spec.Replicas = &replicas
+
 		if err := r.Client.Create(ctx, desiredRS); err != nil {
 			log.Error(err, "Failed to create runnerreplicaset resource")
 
@@ -120,7 +275,11 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 		log.Info("Created runnerreplicaset", "runnerreplicaset", desiredRS.Name)
 
-		return ctrl.Result{}, nil
+		if err := r.createScaleUpWave(ctx, log, &rd, scaleUpWave, desiredRS.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	newestTemplateHash, ok := getTemplateHash(newestSet)
@@ -137,7 +296,21 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
-	if newestTemplateHash != desiredTemplateHash {
+	if newestTemplateHash != desiredTemplateHash && gateClosed {
+		// The newest runnerreplicaset keeps its template until the gate opens, while its replicas can still be scaled down
+		log.V(1).Info("Deferring the rollout as the concurrency gate is closed", "reason", concurrencyGate.Reason)
+	} else if newestTemplateHash != desiredTemplateHash {
+		if rd.Spec.Strategy != nil {
+			// The runners of the updated template are created step by step along with the scale down of the outdated ones
+			plan := planRollout(*rd.Spec.Strategy, canaryPromoted(rd), getIntOrDefault(desiredRS.Spec.Replicas, 1), nil, myRunnerReplicaSets)
+			desiredRS.Spec.Replicas = &plan.newReplicas
+		}
+
+		// The runners of the outdated templates count towards the scheduling budget until they are gone
+		replicas, _ := applySchedulingBudget(rd, getIntOrDefault(desiredRS.Spec.Replicas, 1), myRunnerReplicaSets)
+		replicas, scaleUpWave, _ := applyScaleUpWaves(rd.Spec.ScaleUpWaves, nil, "", replicas, 0, 0, time.Now())
+		desiredRS.Spec.Replicas = &replicas
+
 		if err := r.Client.Create(ctx, desiredRS); err != nil {
 			log.Error(err, "Failed to create runnerreplicaset resource")
 
@@ -146,12 +319,16 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 		log.Info("Created runnerreplicaset", "runnerreplicaset", desiredRS.Name)
 
+		if err := r.createScaleUpWave(ctx, log, &rd, scaleUpWave, desiredRS.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+
 		// We requeue in order to clean up old runner replica sets later.
 		// Otherwise, they aren't cleaned up until the next re-sync interval.
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
-	if !reflect.DeepEqual(newestSet.Spec.Selector, desiredRS.Spec.Selector) {
+	if newestTemplateHash == desiredTemplateHash && !reflect.DeepEqual(newestSet.Spec.Selector, desiredRS.Spec.Selector) {
 		updateSet := newestSet.DeepCopy()
 		updateSet.Spec = *desiredRS.Spec.DeepCopy()
 
@@ -180,6 +357,47 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	currentDesiredReplicas := getIntOrDefault(newestSet.Spec.Replicas, defaultReplicas)
 	newDesiredReplicas := getIntOrDefault(desiredRS.Spec.Replicas, defaultReplicas)
 
+	// With the update strategy, the newest runnerreplicaset is scaled up step by step while the old ones are scaled down
+	newestSetReplicas := newDesiredReplicas
+
+	var plan *rolloutPlan
+	if rd.Spec.Strategy != nil && len(oldSets) > 0 {
+		p := planRollout(*rd.Spec.Strategy, canaryPromoted(rd), newDesiredReplicas, newestSet, oldSets)
+		plan = &p
+		newestSetReplicas = p.newReplicas
+	}
+
+	newestSetReplicas, schedulingBudget := applySchedulingBudget(rd, newestSetReplicas, oldSets)
+	if schedulingBudget != nil && schedulingBudget.WithheldReplicas > 0 {
+		log.V(1).Info("Withholding replicas due to the scheduling budget",
+			"withheldReplicas", schedulingBudget.WithheldReplicas,
+			"limitedBy", schedulingBudget.LimitedBy,
+		)
+	}
+
+	newestSetReplicas = applyConcurrencyGate(concurrencyGate, newestSetReplicas, currentDesiredReplicas)
+	if gateClosed && concurrencyGate.WithheldReplicas > 0 {
+		log.V(1).Info("Withholding replicas as the concurrency gate is closed",
+			"withheldReplicas", concurrencyGate.WithheldReplicas,
+			"reason", concurrencyGate.Reason,
+		)
+	}
+
+	var newestSetReadyReplicas int
+	if newestSet.Status.ReadyReplicas != nil {
+		newestSetReadyReplicas = *newestSet.Status.ReadyReplicas
+	}
+
+	newestSetReplicas, scaleUpWave, scaleUpWaveRequeueAfter := applyScaleUpWaves(rd.Spec.ScaleUpWaves, rd.Status.ScaleUpWave, newestSet.Name, newestSetReplicas, currentDesiredReplicas, newestSetReadyReplicas, time.Now())
+	if scaleUpWave != nil && scaleUpWave.Replicas < scaleUpWave.DesiredReplicas {
+		log.V(1).Info("Scaling up in waves",
+			"wave", scaleUpWave.Number,
+			"replicas", scaleUpWave.Replicas,
+			"readyReplicas", scaleUpWave.ReadyReplicas,
+			"desiredReplicas", scaleUpWave.DesiredReplicas,
+		)
+	}
+
 	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
 	//
 	// If we missed taking the EffectiveTime diff into account, you might end up experiencing scale-ups being delayed scale-down.
@@ -191,9 +409,24 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	if rd.Spec.EffectiveTime != nil {
 		et2 = rd.Spec.EffectiveTime.Time
 	}
-	if currentDesiredReplicas != newDesiredReplicas || et1 != et2 {
-		newestSet.Spec.Replicas = &newDesiredReplicas
+	capacityDistributionChanged := !equality.Semantic.DeepEqual(newestSet.Spec.CapacityDistribution, rd.Spec.CapacityDistribution)
+	labelResourceProfilesChanged := !equality.Semantic.DeepEqual(newestSet.Spec.LabelResourceProfiles, rd.Spec.LabelResourceProfiles) ||
+		!equality.Semantic.DeepEqual(newestSet.Spec.ProfileReplicas, rd.Spec.ProfileReplicas) ||
+		!equality.Semantic.DeepEqual(newestSet.Spec.Quarantine, rd.Spec.Quarantine)
+	if currentDesiredReplicas != newestSetReplicas || et1 != et2 || capacityDistributionChanged || labelResourceProfilesChanged {
+		newestSet.Spec.Replicas = &newestSetReplicas
 		newestSet.Spec.EffectiveTime = rd.Spec.EffectiveTime
+		newestSet.Spec.CapacityDistribution = rd.Spec.CapacityDistribution
+		newestSet.Spec.LabelResourceProfiles = rd.Spec.LabelResourceProfiles
+		newestSet.Spec.ProfileReplicas = rd.Spec.ProfileReplicas
+		newestSet.Spec.Quarantine = rd.Spec.Quarantine
+
+		// The wave is saved before the runner replica set is scaled, as the status isn't patched until the next reconciliation
+		if err := r.patchScaleUpWave(ctx, &rd, scaleUpWave); err != nil {
+			log.Error(err, "Failed to patch the scale up wave of runnerdeployment status")
+
+			return ctrl.Result{}, err
+		}
 
 		if err := r.Client.Update(ctx, newestSet); err != nil {
 			log.Error(err, "Failed to update runnerreplicaset resource")
@@ -203,16 +436,39 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 		log.V(1).Info("Updated runnerreplicaset due to spec change",
 			"currentDesiredReplicas", currentDesiredReplicas,
-			"newDesiredReplicas", newDesiredReplicas,
+			"newDesiredReplicas", newestSetReplicas,
 			"currentEffectiveTime", newestSet.Spec.EffectiveTime,
 			"newEffectiveTime", rd.Spec.EffectiveTime,
+			"capacityDistributionChanged", capacityDistributionChanged,
+			"labelResourceProfilesChanged", labelResourceProfilesChanged,
 		)
 
-		return ctrl.Result{}, err
+		return ctrl.Result{RequeueAfter: scaleUpWaveRequeueAfter}, nil
 	}
 
-	// Do we have old runner replica sets that should eventually deleted?
-	if len(oldSets) > 0 {
+	if gateClosed && len(oldSets) > 0 {
+		// The outdated runners stay until the gate opens, so that the rollout doesn't reduce the capacity while the scale up is blocked
+		log.V(1).Info("Pausing the rollout as the concurrency gate is closed", "reason", concurrencyGate.Reason)
+	} else if plan != nil {
+		if err := r.scaleDownOldRunnerReplicaSets(ctx, log, &rd, oldSets, *plan); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if rd.Spec.Strategy != nil && len(oldSets) == 0 && rd.Annotations[AnnotationKeyPromoteCanary] != "" {
+		// The promotion is for the completed update. The next update needs to be promoted again.
+		updated := rd.DeepCopy()
+		delete(updated.Annotations, AnnotationKeyPromoteCanary)
+
+		if err := r.Client.Patch(ctx, updated, client.MergeFrom(&rd)); err != nil {
+			log.Error(err, "Failed to remove the canary promotion annotation")
+
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Completed the promoted update")
+
+		return ctrl.Result{}, nil
+	} else if len(oldSets) > 0 {
+		// Do we have old runner replica sets that should eventually deleted?
 		var readyReplicas int
 		if newestSet.Status.ReadyReplicas != nil {
 			readyReplicas = *newestSet.Status.ReadyReplicas
@@ -234,7 +490,7 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			logWithDebugInfo.
 				Info("Waiting until the newest runnerreplicaset to be 100% available")
 
-			return ctrl.Result{}, nil
+			return ctrl.Result{RequeueAfter: scaleUpWaveRequeueAfter}, nil
 		}
 
 		if oldSetsCount > 0 {
@@ -313,6 +569,17 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	status.DesiredReplicas = &newDesiredReplicas
 	status.Replicas = &totalCurrentReplicas
 	status.UpdatedReplicas = &updatedReplicas
+	status.SchedulingBudget = schedulingBudget
+	status.ImageBuild = rd.Status.ImageBuild
+	status.ImagePolicy = rd.Status.ImagePolicy
+	status.ImageVerification = rd.Status.ImageVerification
+	status.CapacityDistribution = sumCapacityDistributionStatus(rd.Spec.CapacityDistribution, newestSet, oldSets)
+	status.ConcurrencyGate = concurrencyGate
+	status.ScaleUpWave = scaleUpWave
+	status.ObservedGeneration = rd.Generation
+	status.Conditions = runnerDeploymentStatusConditions(&rd, newDesiredReplicas, totalStatusAvailableReplicas)
+
+	metrics.SetRunnerDeploymentStatus(rd.ObjectMeta, status)
 
 	if !reflect.DeepEqual(rd.Status, status) {
 		updated := rd.DeepCopy()
@@ -326,7 +593,102 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}
 	}
 
-	return ctrl.Result{}, nil
+	requeueAfter := scaleUpWaveRequeueAfter
+
+	if imagePolicyRequeueAfter > 0 && (requeueAfter == 0 || imagePolicyRequeueAfter < requeueAfter) {
+		requeueAfter = imagePolicyRequeueAfter
+	}
+
+	if imageVerificationRequeueAfter > 0 && (requeueAfter == 0 || imageVerificationRequeueAfter < requeueAfter) {
+		requeueAfter = imageVerificationRequeueAfter
+	}
+
+	if rd.Spec.ConcurrencyGate != nil {
+		// The conditions are external, so they are polled
+		if interval := concurrencyGateCheckInterval(rd.Spec.ConcurrencyGate); requeueAfter == 0 || interval < requeueAfter {
+			requeueAfter = interval
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// runnerDeploymentStatusConditions returns the conditions of the runner deployment that has been reconciled.
+// It is ready once the desired number of the runners are available.
+func runnerDeploymentStatusConditions(rd *v1alpha1.RunnerDeployment, desired, available int) []metav1.Condition {
+	conditions := append([]metav1.Condition{}, rd.Status.Conditions...)
+
+	setCondition(&conditions, rd.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+
+	if available >= desired {
+		setCondition(&conditions, rd.Generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonReplicasAvailable, fmt.Sprintf("%d of %d runners available", available, desired))
+	} else {
+		setCondition(&conditions, rd.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonReplicasUnavailable, fmt.Sprintf("%d of %d runners available", available, desired))
+	}
+
+	return conditions
+}
+
+func runnerDeploymentConditions(rd *v1alpha1.RunnerDeployment) *[]metav1.Condition {
+	return &rd.Status.Conditions
+}
+
+// setRunnerDeploymentFailedConditions patches the conditions of the runner deployment that failed to be reconciled.
+func (r *RunnerDeploymentReconciler) setRunnerDeploymentFailedConditions(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, err error) {
+	if err := patchConditions(ctx, r.Client, rd, runnerDeploymentConditions, func(conditions *[]metav1.Condition) {
+		setCondition(conditions, rd.Generation, v1alpha1.ConditionTypeSynced, false, v1alpha1.ConditionReasonReconcileFailed, err.Error())
+	}); err != nil {
+		log.Error(err, "Failed to update runnerdeployment status conditions")
+	}
+}
+
+// scaleDownOldRunnerReplicaSets scales down the old runnerreplicasets according to the plan,
+// and deletes the ones that have been scaled to zero.
+func (r *RunnerDeploymentReconciler) scaleDownOldRunnerReplicaSets(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, oldSets []v1alpha1.RunnerReplicaSet, plan rolloutPlan) error {
+	if plan.waitingForPromotion {
+		log.Info(fmt.Sprintf("The canary runners are available. Annotate the runnerdeployment with %s=true to promote the update", AnnotationKeyPromoteCanary))
+	}
+
+	for i := range oldSets {
+		rs := oldSets[i]
+		replicas := plan.oldReplicas[i]
+
+		rslog := log.WithValues("runnerreplicaset", rs.Name)
+
+		if replicas == 0 && getIntOrDefault(rs.Status.Replicas, 0) == 0 && getIntOrDefault(rs.Spec.Replicas, 0) == 0 {
+			if err := r.Client.Delete(ctx, &rs); err != nil {
+				rslog.Error(err, "Failed to delete runnerreplicaset resource")
+
+				return err
+			}
+
+			r.Recorder.Event(rd, corev1.EventTypeNormal, "RunnerReplicaSetDeleted", fmt.Sprintf("Deleted runnerreplicaset '%s'", rs.Name))
+
+			rslog.Info("Deleted runnerreplicaset")
+
+			continue
+		}
+
+		if getIntOrDefault(rs.Spec.Replicas, 0) == replicas {
+			continue
+		}
+
+		updated := rs.DeepCopy()
+		updated.Spec.Replicas = &replicas
+		if err := r.Client.Update(ctx, updated); err != nil {
+			rslog.Error(err, "Failed to scale down runnerreplicaset")
+
+			return err
+		}
+
+		rslog.Info("Scaled down runnerreplicaset", "replicas", replicas)
+	}
+
+	return nil
+}
+
+func canaryPromoted(rd v1alpha1.RunnerDeployment) bool {
+	return rd.Annotations[AnnotationKeyPromoteCanary] == "true"
 }
 
 func getIntOrDefault(p *int, d int) int {
@@ -466,6 +828,12 @@ func newRunnerReplicaSet(rd *v1alpha1.RunnerDeployment, commonRunnerLabels []str
 			Selector:      newRSSelector,
 			Template:      newRSTemplate,
 			EffectiveTime: rd.Spec.EffectiveTime,
+			// The capacity distribution is updated in-place without replacing the runners
+			CapacityDistribution: rd.Spec.CapacityDistribution,
+			// The profiles only apply to the runners created afterwards, so they are updated in-place too
+			LabelResourceProfiles: rd.Spec.LabelResourceProfiles,
+			ProfileReplicas:       rd.Spec.ProfileReplicas,
+			Quarantine:            rd.Spec.Quarantine,
 		},
 	}
 
@@ -500,9 +868,24 @@ func (r *RunnerDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	_, err := mgr.GetRESTMapper().RESTMapping(ciliumNetworkPolicyGVK.GroupKind(), ciliumNetworkPolicyGVK.Version)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return err
+	}
+	r.ciliumAvailable = err == nil
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.RunnerDeployment{}).
 		Owns(&v1alpha1.RunnerReplicaSet{}).
-		Named(name).
-		Complete(r)
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&batchv1.Job{})
+
+	if r.ciliumAvailable {
+		cnp := &unstructured.Unstructured{}
+		cnp.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+		b = b.Owns(cnp)
+	}
+
+	return b.Named(name).Complete(reconcilemetrics.Observe(name, "RunnerDeployment", r))
 }
@@ -0,0 +1,488 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var ciliumNetworkPolicyGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2", Kind: "CiliumNetworkPolicy"}
+
+// cloudMetadataCIDRs are the instance metadata endpoints of the cloud providers, which hand the credentials of the node,
+// or of the pod identity, out to any pod that can reach them.
+var cloudMetadataCIDRs = []string{
+	// AWS, Azure, GCP, OCI, and DigitalOcean
+	"169.254.169.254/32",
+	"fd00:ec2::254/128",
+	// EKS Pod Identity
+	"169.254.170.23/32",
+	"fd00:ec2::23/128",
+	// Alibaba Cloud
+	"100.100.100.200/32",
+}
+
+// DefaultEgressPolicyGitHubFQDNs are the hostnames of GitHub.com the runners connect to,
+// per https://docs.github.com/en/actions/hosting-your-own-runners/managing-self-hosted-runners/about-self-hosted-runners#communication-requirements.
+var DefaultEgressPolicyGitHubFQDNs = []string{
+	"github.com",
+	"api.github.com",
+	"codeload.github.com",
+	"*.actions.githubusercontent.com",
+	"objects.githubusercontent.com",
+	"objects-origin.githubusercontent.com",
+	"github-releases.githubusercontent.com",
+	"github-registry-files.githubusercontent.com",
+	"release-assets.githubusercontent.com",
+	"ghcr.io",
+	"*.pkg.github.com",
+	"pkg-containers.githubusercontent.com",
+	// The job logs, artifacts, and caches are uploaded to Azure Blob Storage
+	"*.blob.core.windows.net",
+}
+
+func egressPolicyName(rd *v1alpha1.RunnerDeployment) string {
+	return rd.Name + "-runner-egress"
+}
+
+func quarantineEgressPolicyName(rd *v1alpha1.RunnerDeployment) string {
+	return rd.Name + "-runner-quarantine-egress"
+}
+
+func quarantineEgressPolicyProvider(rd *v1alpha1.RunnerDeployment) string {
+	if rd.Spec.Quarantine == nil {
+		return ""
+	}
+	if rd.Spec.Quarantine.EgressProvider == "" {
+		return v1alpha1.EgressPolicyProviderNetworkPolicy
+	}
+	return rd.Spec.Quarantine.EgressProvider
+}
+
+// quarantineEgressPolicy is the egress policy of the quarantined runner pods, which allows nothing but DNS and GitHub.
+var quarantineEgressPolicy = &v1alpha1.EgressPolicy{}
+
+// egressPodSelector selects the runner pods of the RunnerDeployment the egress policy applies to.
+// The egress policy of the RunnerDeployment doesn't apply to the quarantined runner pods, as the network policies are additive.
+func egressPodSelector(rd *v1alpha1.RunnerDeployment, quarantined bool) metav1.LabelSelector {
+	selector := metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelKeyRunnerDeploymentName: rd.Name,
+		},
+	}
+
+	if quarantined {
+		selector.MatchLabels[LabelKeyLabelResourceProfile] = v1alpha1.QuarantineProfileName
+	} else if rd.Spec.Quarantine != nil {
+		selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{Key: LabelKeyLabelResourceProfile, Operator: metav1.LabelSelectorOpNotIn, Values: []string{v1alpha1.QuarantineProfileName}},
+		}
+	}
+
+	return selector
+}
+
+func egressPolicyProvider(rd *v1alpha1.RunnerDeployment) string {
+	if rd.Spec.EgressPolicy == nil {
+		return ""
+	}
+	if rd.Spec.EgressPolicy.Provider == "" {
+		return v1alpha1.EgressPolicyProviderNetworkPolicy
+	}
+	return rd.Spec.EgressPolicy.Provider
+}
+
+// blockedCIDRs returns the IPv4 and IPv6 CIDRs the runner pods of the RunnerDeployment can't connect to.
+func blockedCIDRs(p *v1alpha1.EgressPolicy) (v4, v6 []string) {
+	if p.AllowCloudMetadata {
+		return nil, nil
+	}
+
+	for _, cidr := range cloudMetadataCIDRs {
+		if strings.Contains(cidr, ":") {
+			v6 = append(v6, cidr)
+		} else {
+			v4 = append(v4, cidr)
+		}
+	}
+
+	return v4, v6
+}
+
+// newEgressNetworkPolicy returns the NetworkPolicy that allows the runner pods of the RunnerDeployment to resolve names,
+// to connect to any address over HTTP and HTTPS except the cloud metadata endpoints, and to connect to the allowed CIDRs on any port.
+// NetworkPolicies can't match hostnames, so GitHub can't be singled out by it.
+func newEgressNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme) (*networkingv1.NetworkPolicy, error) {
+	return buildEgressNetworkPolicy(rd, scheme, egressPolicyName(rd), rd.Spec.EgressPolicy, false)
+}
+
+// newQuarantineEgressNetworkPolicy returns the NetworkPolicy that allows the quarantined runner pods of the RunnerDeployment
+// to resolve names and to connect to any address over HTTP and HTTPS except the cloud metadata endpoints.
+func newQuarantineEgressNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme) (*networkingv1.NetworkPolicy, error) {
+	return buildEgressNetworkPolicy(rd, scheme, quarantineEgressPolicyName(rd), quarantineEgressPolicy, true)
+}
+
+func buildEgressNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme, name string, p *v1alpha1.EgressPolicy, quarantined bool) (*networkingv1.NetworkPolicy, error) {
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	port := func(protocol *corev1.Protocol, p int) networkingv1.NetworkPolicyPort {
+		port := intstr.FromInt(p)
+		return networkingv1.NetworkPolicyPort{Protocol: protocol, Port: &port}
+	}
+
+	v4, v6 := blockedCIDRs(p)
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{port(&udp, 53), port(&tcp, 53)},
+		},
+		{
+			To: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: v4}},
+				{IPBlock: &networkingv1.IPBlock{CIDR: "::/0", Except: v6}},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{port(&tcp, 443), port(&tcp, 80)},
+		},
+	}
+
+	if len(p.AllowedCIDRs) > 0 {
+		var to []networkingv1.NetworkPolicyPeer
+		for _, cidr := range p.AllowedCIDRs {
+			to = append(to, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: to})
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: rd.Namespace,
+			Labels: map[string]string{
+				LabelKeyRunnerDeploymentName: rd.Name,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: egressPodSelector(rd, quarantined),
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(rd, np, scheme); err != nil {
+		return nil, err
+	}
+
+	return np, nil
+}
+
+// newEgressCiliumNetworkPolicy returns the CiliumNetworkPolicy that allows the runner pods of the RunnerDeployment to resolve names
+// with kube-dns, to connect to GitHub and the allowed FQDNs over HTTP and HTTPS, to connect to the allowed CIDRs on any port,
+// and to connect to the Kubernetes API server used by the runner status update hook and the kubernetes container mode.
+// Anything else, including the cloud metadata endpoints unless allowed, is denied.
+func newEgressCiliumNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme, githubFQDNs []string) (*unstructured.Unstructured, error) {
+	return buildEgressCiliumNetworkPolicy(rd, scheme, egressPolicyName(rd), rd.Spec.EgressPolicy, githubFQDNs, false)
+}
+
+// newQuarantineEgressCiliumNetworkPolicy returns the CiliumNetworkPolicy that allows the quarantined runner pods of the RunnerDeployment
+// to resolve names with kube-dns and to connect to GitHub over HTTP and HTTPS. Unlike the other runner pods, they can't connect to the Kubernetes API server.
+func newQuarantineEgressCiliumNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme, githubFQDNs []string) (*unstructured.Unstructured, error) {
+	return buildEgressCiliumNetworkPolicy(rd, scheme, quarantineEgressPolicyName(rd), quarantineEgressPolicy, githubFQDNs, true)
+}
+
+func buildEgressCiliumNetworkPolicy(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme, name string, p *v1alpha1.EgressPolicy, githubFQDNs []string, quarantined bool) (*unstructured.Unstructured, error) {
+	var fqdns []interface{}
+	for _, fqdn := range append(append([]string{}, githubFQDNs...), p.AllowedFQDNs...) {
+		if strings.Contains(fqdn, "*") {
+			fqdns = append(fqdns, map[string]interface{}{"matchPattern": fqdn})
+		} else {
+			fqdns = append(fqdns, map[string]interface{}{"matchName": fqdn})
+		}
+	}
+
+	egress := []interface{}{
+		map[string]interface{}{
+			"toEndpoints": []interface{}{
+				map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"k8s:io.kubernetes.pod.namespace": "kube-system",
+						"k8s:k8s-app":                     "kube-dns",
+					},
+				},
+			},
+			"toPorts": []interface{}{
+				map[string]interface{}{
+					"ports": []interface{}{
+						map[string]interface{}{"port": "53", "protocol": "ANY"},
+					},
+					// The DNS proxy learns the IPs of the FQDNs from the responses
+					"rules": map[string]interface{}{
+						"dns": []interface{}{
+							map[string]interface{}{"matchPattern": "*"},
+						},
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"toFQDNs": fqdns,
+			"toPorts": []interface{}{
+				map[string]interface{}{
+					"ports": []interface{}{
+						map[string]interface{}{"port": "443", "protocol": "TCP"},
+						map[string]interface{}{"port": "80", "protocol": "TCP"},
+					},
+				},
+			},
+		},
+	}
+
+	if !quarantined {
+		egress = append(egress, map[string]interface{}{
+			"toEntities": []interface{}{"kube-apiserver"},
+		})
+	}
+
+	cidrs := append([]string{}, p.AllowedCIDRs...)
+	if p.AllowCloudMetadata {
+		cidrs = append(cidrs, cloudMetadataCIDRs...)
+	}
+	if len(cidrs) > 0 {
+		var toCIDR []interface{}
+		for _, cidr := range cidrs {
+			toCIDR = append(toCIDR, cidr)
+		}
+		egress = append(egress, map[string]interface{}{"toCIDR": toCIDR})
+	}
+
+	cnp := &unstructured.Unstructured{}
+	cnp.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	cnp.SetName(name)
+	cnp.SetNamespace(rd.Namespace)
+	cnp.SetLabels(map[string]string{
+		LabelKeyRunnerDeploymentName: rd.Name,
+	})
+
+	podSelector := egressPodSelector(rd, quarantined)
+
+	matchLabels := map[string]interface{}{}
+	for k, v := range podSelector.MatchLabels {
+		matchLabels[k] = v
+	}
+
+	endpointSelector := map[string]interface{}{
+		"matchLabels": matchLabels,
+	}
+
+	if len(podSelector.MatchExpressions) > 0 {
+		var matchExpressions []interface{}
+		for _, e := range podSelector.MatchExpressions {
+			var values []interface{}
+			for _, v := range e.Values {
+				values = append(values, v)
+			}
+			matchExpressions = append(matchExpressions, map[string]interface{}{
+				"key":      e.Key,
+				"operator": string(e.Operator),
+				"values":   values,
+			})
+		}
+		endpointSelector["matchExpressions"] = matchExpressions
+	}
+
+	cnp.Object["spec"] = map[string]interface{}{
+		"endpointSelector": endpointSelector,
+		"egress":           egress,
+	}
+
+	if err := ctrl.SetControllerReference(rd, cnp, scheme); err != nil {
+		return nil, err
+	}
+
+	return cnp, nil
+}
+
+// syncEgressPolicy creates or updates the NetworkPolicy or the CiliumNetworkPolicy restricting the egress of the runner pods
+// when the RunnerDeployment requests it, and deletes the one of the other provider, or both when it doesn't.
+// The quarantined runner pods get their own policy of the provider of the quarantine, allowing nothing but DNS and GitHub.
+func (r *RunnerDeploymentReconciler) syncEgressPolicy(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment) error {
+	provider := egressPolicyProvider(rd)
+	quarantineProvider := quarantineEgressPolicyProvider(rd)
+
+	githubFQDNs := r.EgressPolicyGitHubFQDNs
+	if len(githubFQDNs) == 0 {
+		githubFQDNs = DefaultEgressPolicyGitHubFQDNs
+	}
+
+	var np, quarantineNP *networkingv1.NetworkPolicy
+	var err error
+
+	if provider == v1alpha1.EgressPolicyProviderNetworkPolicy {
+		if np, err = newEgressNetworkPolicy(rd, r.Scheme); err != nil {
+			return err
+		}
+	}
+
+	if quarantineProvider == v1alpha1.EgressPolicyProviderNetworkPolicy {
+		if quarantineNP, err = newQuarantineEgressNetworkPolicy(rd, r.Scheme); err != nil {
+			return err
+		}
+	}
+
+	if err := r.syncEgressNetworkPolicy(ctx, log, rd, egressPolicyName(rd), np); err != nil {
+		return err
+	}
+
+	if err := r.syncEgressNetworkPolicy(ctx, log, rd, quarantineEgressPolicyName(rd), quarantineNP); err != nil {
+		return err
+	}
+
+	// The CiliumNetworkPolicies aren't looked up on the clusters without Cilium,
+	// as every lookup of an unknown kind makes the client rediscover the API groups.
+	if !r.ciliumAvailable {
+		if provider == v1alpha1.EgressPolicyProviderCilium || quarantineProvider == v1alpha1.EgressPolicyProviderCilium {
+			return fmt.Errorf("egress policy provider %s requires the CiliumNetworkPolicy CRD, which wasn't found when the controller started", v1alpha1.EgressPolicyProviderCilium)
+		}
+		return nil
+	}
+
+	var cnp, quarantineCNP *unstructured.Unstructured
+
+	if provider == v1alpha1.EgressPolicyProviderCilium {
+		if cnp, err = newEgressCiliumNetworkPolicy(rd, r.Scheme, githubFQDNs); err != nil {
+			return err
+		}
+	}
+
+	if quarantineProvider == v1alpha1.EgressPolicyProviderCilium {
+		if quarantineCNP, err = newQuarantineEgressCiliumNetworkPolicy(rd, r.Scheme, githubFQDNs); err != nil {
+			return err
+		}
+	}
+
+	if err := r.syncEgressCiliumNetworkPolicy(ctx, log, rd, egressPolicyName(rd), cnp); err != nil {
+		return err
+	}
+
+	return r.syncEgressCiliumNetworkPolicy(ctx, log, rd, quarantineEgressPolicyName(rd), quarantineCNP)
+}
+
+// syncEgressNetworkPolicy creates or updates the NetworkPolicy of the name to the desired one, or deletes it when desired is nil.
+func (r *RunnerDeploymentReconciler) syncEgressNetworkPolicy(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, name string, desired *networkingv1.NetworkPolicy) error {
+	var current networkingv1.NetworkPolicy
+
+	err := r.Get(ctx, types.NamespacedName{Namespace: rd.Namespace, Name: name}, &current)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	exists := err == nil
+
+	if desired == nil {
+		if !exists || !metav1.IsControlledBy(&current, rd) {
+			return nil
+		}
+
+		if err := r.Delete(ctx, &current); err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete networkpolicy")
+			return err
+		}
+
+		log.Info("Deleted networkpolicy", "networkpolicy", current.Name)
+
+		return nil
+	}
+
+	if !exists {
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create networkpolicy")
+			return err
+		}
+
+		log.Info("Created networkpolicy", "networkpolicy", desired.Name)
+
+		return nil
+	}
+
+	if reflect.DeepEqual(current.Spec, desired.Spec) {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	updated.Spec = desired.Spec
+
+	if err := r.Update(ctx, updated); err != nil {
+		log.Error(err, "Failed to update networkpolicy")
+		return err
+	}
+
+	log.Info("Updated networkpolicy", "networkpolicy", updated.Name)
+
+	return nil
+}
+
+// syncEgressCiliumNetworkPolicy creates or updates the CiliumNetworkPolicy of the name to the desired one, or deletes it when desired is nil.
+func (r *RunnerDeploymentReconciler) syncEgressCiliumNetworkPolicy(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, name string, desired *unstructured.Unstructured) error {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+
+	err := r.Get(ctx, types.NamespacedName{Namespace: rd.Namespace, Name: name}, current)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	exists := err == nil
+
+	if desired == nil {
+		if !exists || !metav1.IsControlledBy(current, rd) {
+			return nil
+		}
+
+		if err := r.Delete(ctx, current); err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete ciliumnetworkpolicy")
+			return err
+		}
+
+		log.Info("Deleted ciliumnetworkpolicy", "ciliumnetworkpolicy", current.GetName())
+
+		return nil
+	}
+
+	if !exists {
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create ciliumnetworkpolicy")
+			return err
+		}
+
+		log.Info("Created ciliumnetworkpolicy", "ciliumnetworkpolicy", desired.GetName())
+
+		return nil
+	}
+
+	if reflect.DeepEqual(current.Object["spec"], desired.Object["spec"]) {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	updated.Object["spec"] = desired.Object["spec"]
+
+	if err := r.Update(ctx, updated); err != nil {
+		log.Error(err, "Failed to update ciliumnetworkpolicy")
+		return err
+	}
+
+	log.Info("Updated ciliumnetworkpolicy", "ciliumnetworkpolicy", updated.GetName())
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newEgressPolicyTestReconciler(t *testing.T, rd *v1alpha1.RunnerDeployment) *RunnerDeploymentReconciler {
+	t.Helper()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	return &RunnerDeploymentReconciler{
+		Client: clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd).Build(),
+		Scheme: sc,
+	}
+}
+
+func TestSyncEgressNetworkPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			EgressPolicy: &v1alpha1.EgressPolicy{},
+		},
+	}
+
+	r := newEgressPolicyTestReconciler(t, rd)
+
+	key := types.NamespacedName{Namespace: "default", Name: "example-runner-egress"}
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+
+	var np networkingv1.NetworkPolicy
+	require.NoError(t, r.Get(ctx, key, &np))
+	require.True(t, metav1.IsControlledBy(&np, rd))
+	require.Equal(t, map[string]string{LabelKeyRunnerDeploymentName: "example"}, np.Spec.PodSelector.MatchLabels)
+	require.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, np.Spec.PolicyTypes)
+	require.Len(t, np.Spec.Egress, 2)
+
+	// DNS
+	require.Empty(t, np.Spec.Egress[0].To)
+	require.Equal(t, 53, np.Spec.Egress[0].Ports[0].Port.IntValue())
+
+	// HTTP and HTTPS to anywhere but the cloud metadata endpoints
+	web := np.Spec.Egress[1]
+	require.Equal(t, "0.0.0.0/0", web.To[0].IPBlock.CIDR)
+	require.Contains(t, web.To[0].IPBlock.Except, "169.254.169.254/32")
+	require.NotContains(t, web.To[0].IPBlock.Except, "fd00:ec2::254/128")
+	require.Equal(t, "::/0", web.To[1].IPBlock.CIDR)
+	require.Contains(t, web.To[1].IPBlock.Except, "fd00:ec2::254/128")
+	require.Equal(t, 443, web.Ports[0].Port.IntValue())
+
+	// Idempotent
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+
+	rd.Spec.EgressPolicy.AllowedCIDRs = []string{"10.0.0.0/8"}
+	rd.Spec.EgressPolicy.AllowCloudMetadata = true
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+	require.NoError(t, r.Get(ctx, key, &np))
+	require.Len(t, np.Spec.Egress, 3)
+	require.Empty(t, np.Spec.Egress[1].To[0].IPBlock.Except)
+	require.Equal(t, "10.0.0.0/8", np.Spec.Egress[2].To[0].IPBlock.CIDR)
+	require.Empty(t, np.Spec.Egress[2].Ports)
+
+	rd.Spec.EgressPolicy = nil
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, key, &np)))
+}
+
+func TestSyncEgressCiliumNetworkPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			EgressPolicy: &v1alpha1.EgressPolicy{
+				Provider:     v1alpha1.EgressPolicyProviderCilium,
+				AllowedFQDNs: []string{"registry.npmjs.org", "*.docker.io"},
+				AllowedCIDRs: []string{"10.0.0.0/8"},
+			},
+		},
+	}
+
+	r := newEgressPolicyTestReconciler(t, rd)
+
+	// Cilium isn't installed
+	require.ErrorContains(t, r.syncEgressPolicy(ctx, logr.Discard(), rd), "CiliumNetworkPolicy CRD")
+
+	r.ciliumAvailable = true
+	r.EgressPolicyGitHubFQDNs = []string{"ghes.example.com", "*.ghes.example.com"}
+
+	key := types.NamespacedName{Namespace: "default", Name: "example-runner-egress"}
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+
+	// No NetworkPolicy for the Cilium provider
+	var np networkingv1.NetworkPolicy
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, key, &np)))
+
+	cnp := &unstructured.Unstructured{}
+	cnp.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	require.NoError(t, r.Get(ctx, key, cnp))
+	require.True(t, metav1.IsControlledBy(cnp, rd))
+
+	selector, _, _ := unstructured.NestedStringMap(cnp.Object, "spec", "endpointSelector", "matchLabels")
+	require.Equal(t, map[string]string{LabelKeyRunnerDeploymentName: "example"}, selector)
+
+	egress, _, _ := unstructured.NestedSlice(cnp.Object, "spec", "egress")
+	require.Len(t, egress, 4)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"matchName": "ghes.example.com"},
+		map[string]interface{}{"matchPattern": "*.ghes.example.com"},
+		map[string]interface{}{"matchName": "registry.npmjs.org"},
+		map[string]interface{}{"matchPattern": "*.docker.io"},
+	}, egress[1].(map[string]interface{})["toFQDNs"])
+	// The cloud metadata endpoints are denied as they aren't allowed
+	require.Equal(t, []interface{}{"10.0.0.0/8"}, egress[3].(map[string]interface{})["toCIDR"])
+
+	// Idempotent
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+
+	// Switching the provider replaces the CiliumNetworkPolicy with a NetworkPolicy
+	rd.Spec.EgressPolicy = &v1alpha1.EgressPolicy{}
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+	require.NoError(t, r.Get(ctx, key, &np))
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, key, cnp)))
+}
+
+func TestSyncQuarantineEgressPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			EgressPolicy: &v1alpha1.EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/8"}},
+			Quarantine:   &v1alpha1.Quarantine{FirstTimeContributor: true},
+		},
+	}
+
+	r := newEgressPolicyTestReconciler(t, rd)
+
+	key := types.NamespacedName{Namespace: "default", Name: "example-runner-egress"}
+	quarantineKey := types.NamespacedName{Namespace: "default", Name: "example-runner-quarantine-egress"}
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+
+	// The egress policy of the RunnerDeployment doesn't apply to the quarantined runner pods
+	var np networkingv1.NetworkPolicy
+	require.NoError(t, r.Get(ctx, key, &np))
+	require.Equal(t, []metav1.LabelSelectorRequirement{
+		{Key: LabelKeyLabelResourceProfile, Operator: metav1.LabelSelectorOpNotIn, Values: []string{v1alpha1.QuarantineProfileName}},
+	}, np.Spec.PodSelector.MatchExpressions)
+
+	// Nor does the allowlist
+	var quarantine networkingv1.NetworkPolicy
+	require.NoError(t, r.Get(ctx, quarantineKey, &quarantine))
+	require.True(t, metav1.IsControlledBy(&quarantine, rd))
+	require.Equal(t, map[string]string{
+		LabelKeyRunnerDeploymentName: "example",
+		LabelKeyLabelResourceProfile: v1alpha1.QuarantineProfileName,
+	}, quarantine.Spec.PodSelector.MatchLabels)
+	require.Len(t, quarantine.Spec.Egress, 2)
+	require.Contains(t, quarantine.Spec.Egress[1].To[0].IPBlock.Except, "169.254.169.254/32")
+
+	// Cilium restricts the quarantined runner pods to GitHub
+	r.ciliumAvailable = true
+	rd.Spec.Quarantine.EgressProvider = v1alpha1.EgressPolicyProviderCilium
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, quarantineKey, &quarantine)))
+
+	cnp := &unstructured.Unstructured{}
+	cnp.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	require.NoError(t, r.Get(ctx, quarantineKey, cnp))
+
+	egress, _, _ := unstructured.NestedSlice(cnp.Object, "spec", "egress")
+	require.Len(t, egress, 2, "neither the kube-apiserver nor the CIDRs are allowed")
+	require.Len(t, egress[1].(map[string]interface{})["toFQDNs"], len(DefaultEgressPolicyGitHubFQDNs))
+
+	rd.Spec.Quarantine = nil
+
+	require.NoError(t, r.syncEgressPolicy(ctx, logr.Discard(), rd))
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, quarantineKey, cnp)))
+	require.NoError(t, r.Get(ctx, key, &np))
+	require.Empty(t, np.Spec.PodSelector.MatchExpressions)
+}
+
+func TestEgressPolicyValidate(t *testing.T) {
+	p := &v1alpha1.EgressPolicy{
+		AllowedFQDNs: []string{"registry.npmjs.org"},
+		AllowedCIDRs: []string{"10.0.0.0/8", "10.0.0.1"},
+	}
+
+	errs := p.Validate(nil)
+	require.Len(t, errs, 2)
+	require.Equal(t, "allowedFQDNs", errs[0].Field)
+	require.Equal(t, "allowedCIDRs[1]", errs[1].Field)
+
+	p.Provider = v1alpha1.EgressPolicyProviderCilium
+	p.AllowedCIDRs = []string{"10.0.0.0/8"}
+	require.Empty(t, p.Validate(nil))
+
+	p.Provider = "Calico"
+	require.Len(t, p.Validate(nil), 1)
+}
@@ -0,0 +1,286 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	imageBuildContainerName = "build"
+
+	// imageBuildMetadataKeyDigest is the key of the digest of the pushed image in the metadata file written by buildctl.
+	imageBuildMetadataKeyDigest = "containerimage.digest"
+
+	// imageBuildJobTTL is how long the finished build jobs are kept for troubleshooting.
+	imageBuildJobTTL int32 = 24 * 60 * 60
+
+	imageBuildDockerConfigDir = "/home/user/.docker"
+)
+
+// imageBuildJobName returns the name of the build job of the image build at the time.
+// The name changes when the build changes, and every rebuild interval, so that a new job is created for each build.
+func imageBuildJobName(rd *v1alpha1.RunnerDeployment, now time.Time) string {
+	b := rd.Spec.ImageBuild
+
+	var epoch int64
+	if b.RebuildInterval != nil && b.RebuildInterval.Duration > 0 {
+		epoch = now.Unix() / int64(b.RebuildInterval.Duration.Seconds())
+	}
+
+	name := rd.Name
+	// The job name is used as the value of the job-name label of its pods, limited to 63 characters
+	if len(name) > 40 {
+		name = name[:40]
+	}
+
+	return fmt.Sprintf("%s-image-%s", name, ComputeHash(struct {
+		Build *v1alpha1.RunnerImageBuild
+		Epoch int64
+	}{b, epoch}))
+}
+
+// imageBuildContext returns the git context of buildctl, in the URL#REF:DIR format.
+func imageBuildContext(b *v1alpha1.RunnerImageBuild) string {
+	c := b.Git
+
+	if b.Ref != "" || b.ContextDir != "" {
+		c += "#" + b.Ref
+	}
+
+	if b.ContextDir != "" {
+		c += ":" + b.ContextDir
+	}
+
+	return c
+}
+
+// newImageBuildJob returns the job that builds the image with the rootless buildkit and pushes it to the registry.
+// buildctl writes the metadata of the build, including the digest of the pushed image, to the termination message of the container.
+func (r *RunnerDeploymentReconciler) newImageBuildJob(rd *v1alpha1.RunnerDeployment, name string) (*batchv1.Job, error) {
+	b := rd.Spec.ImageBuild
+
+	dockerfile := b.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	image := r.BuildkitImage
+	if image == "" {
+		image = DefaultImageBuildBuildkitImage
+	}
+
+	// The rootless buildkit runs in the same unprivileged setup as the buildkitd sidecar of the buildkit container mode
+	container := newBuildkitContainer(image)
+	container.Name = imageBuildContainerName
+	container.Command = []string{"buildctl-daemonless.sh"}
+	container.Args = []string{
+		"build",
+		"--frontend", "dockerfile.v0",
+		"--opt", "context=" + imageBuildContext(b),
+		"--opt", "filename=" + dockerfile,
+		"--output", fmt.Sprintf("type=image,name=%s:%s,push=true", b.Image, name),
+		"--metadata-file", corev1.TerminationMessagePathDefault,
+	}
+	container.Env = []corev1.EnvVar{
+		{
+			Name:  "BUILDKITD_FLAGS",
+			Value: "--oci-worker-no-process-sandbox",
+		},
+	}
+
+	var volumes []corev1.Volume
+
+	if b.PushSecretRef != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "docker-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: b.PushSecretRef.Name,
+					Items: []corev1.KeyToPath{
+						{Key: corev1.DockerConfigJsonKey, Path: "config.json"},
+					},
+				},
+			},
+		})
+
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "docker-config",
+			MountPath: imageBuildDockerConfigDir,
+			ReadOnly:  true,
+		})
+
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "DOCKER_CONFIG",
+			Value: imageBuildDockerConfigDir,
+		})
+	}
+
+	var backoffLimit int32
+	ttl := imageBuildJobTTL
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: rd.Namespace,
+			Labels: map[string]string{
+				LabelKeyRunnerDeploymentName: rd.Name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{container},
+					Volumes:       volumes,
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(rd, job, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// imageBuildDigest returns the digest of the image pushed by the succeeded build job, read from the termination message of its pod.
+func (r *RunnerDeploymentReconciler) imageBuildDigest(ctx context.Context, job *batchv1.Job) (string, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+
+		for _, s := range pod.Status.ContainerStatuses {
+			if s.Name != imageBuildContainerName || s.State.Terminated == nil {
+				continue
+			}
+
+			var metadata map[string]interface{}
+			if err := json.Unmarshal([]byte(s.State.Terminated.Message), &metadata); err != nil {
+				return "", fmt.Errorf("parsing the build metadata of %s: %w", pod.Name, err)
+			}
+
+			digest, _ := metadata[imageBuildMetadataKeyDigest].(string)
+			if digest == "" {
+				return "", fmt.Errorf("the build metadata of %s has no %s", pod.Name, imageBuildMetadataKeyDigest)
+			}
+
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("no succeeded pod of the build job %s found", job.Name)
+}
+
+// syncImageBuild starts the build job of the runner image when it's due, and records the image of the succeeded build in the status.
+// It returns the image the runners use, which is empty until the first build succeeds.
+func (r *RunnerDeploymentReconciler) syncImageBuild(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment) (string, error) {
+	if rd.Spec.ImageBuild == nil {
+		if rd.Status.ImageBuild != nil {
+			updated := rd.DeepCopy()
+			updated.Status.ImageBuild = nil
+
+			if err := r.Status().Patch(ctx, updated, client.MergeFrom(rd)); err != nil {
+				return "", err
+			}
+
+			rd.Status.ImageBuild = nil
+		}
+
+		return "", nil
+	}
+
+	status := v1alpha1.RunnerImageBuildStatus{}
+	if rd.Status.ImageBuild != nil {
+		status = *rd.Status.ImageBuild
+	}
+
+	name := imageBuildJobName(rd, time.Now())
+
+	// The build is done. A failed build isn't retried until the build changes or the rebuild interval elapses
+	if status.Job == name && status.Phase != v1alpha1.RunnerImageBuildPhaseBuilding {
+		return status.Image, nil
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: rd.Namespace, Name: name}, &job); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return "", err
+		}
+
+		newJob, err := r.newImageBuildJob(rd, name)
+		if err != nil {
+			return "", err
+		}
+
+		if err := r.Create(ctx, newJob); err != nil {
+			return "", err
+		}
+
+		log.Info("Started building the runner image", "job", name)
+		r.Recorder.Event(rd, corev1.EventTypeNormal, "ImageBuildStarted", fmt.Sprintf("Started building the runner image with the job %s", name))
+
+		return status.Image, r.patchImageBuildStatus(ctx, rd, v1alpha1.RunnerImageBuildStatus{Image: status.Image, Job: name, Phase: v1alpha1.RunnerImageBuildPhaseBuilding})
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		digest, err := r.imageBuildDigest(ctx, &job)
+		if err != nil {
+			return status.Image, err
+		}
+
+		image := rd.Spec.ImageBuild.Image + "@" + digest
+
+		if image != status.Image {
+			log.Info("Built the runner image", "job", name, "image", image)
+			r.Recorder.Event(rd, corev1.EventTypeNormal, "ImageBuildSucceeded", fmt.Sprintf("Built the runner image %s", image))
+		}
+
+		return image, r.patchImageBuildStatus(ctx, rd, v1alpha1.RunnerImageBuildStatus{Image: image, Job: name, Phase: v1alpha1.RunnerImageBuildPhaseSucceeded})
+	case job.Status.Failed > 0:
+		log.Info("Failed to build the runner image", "job", name)
+		r.Recorder.Event(rd, corev1.EventTypeWarning, "ImageBuildFailed", fmt.Sprintf("The build job %s failed. The runners keep using the image of the last successful build", name))
+
+		return status.Image, r.patchImageBuildStatus(ctx, rd, v1alpha1.RunnerImageBuildStatus{Image: status.Image, Job: name, Phase: v1alpha1.RunnerImageBuildPhaseFailed})
+	}
+
+	return status.Image, r.patchImageBuildStatus(ctx, rd, v1alpha1.RunnerImageBuildStatus{Image: status.Image, Job: name, Phase: v1alpha1.RunnerImageBuildPhaseBuilding})
+}
+
+func (r *RunnerDeploymentReconciler) patchImageBuildStatus(ctx context.Context, rd *v1alpha1.RunnerDeployment, status v1alpha1.RunnerImageBuildStatus) error {
+	if rd.Status.ImageBuild != nil && *rd.Status.ImageBuild == status {
+		return nil
+	}
+
+	updated := rd.DeepCopy()
+	updated.Status.ImageBuild = &status
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(rd)); err != nil {
+		return err
+	}
+
+	rd.Status.ImageBuild = &status
+
+	return nil
+}
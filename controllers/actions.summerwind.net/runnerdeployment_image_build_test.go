@@ -0,0 +1,164 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncImageBuild(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			ImageBuild: &v1alpha1.RunnerImageBuild{
+				Git:           "https://github.com/example/runner-images.git",
+				ContextDir:    "ci",
+				Image:         "registry.example.com/runners/ci",
+				PushSecretRef: &v1alpha1.SecretReference{Name: "registry"},
+			},
+		},
+	}
+
+	r := &RunnerDeploymentReconciler{
+		Client:   clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd).WithStatusSubresource(rd).Build(),
+		Scheme:   sc,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	getJob := func(name string) *batchv1.Job {
+		var job batchv1.Job
+		require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &job))
+		return &job
+	}
+
+	// The first build starts
+	image, err := r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+	require.Empty(t, image)
+
+	name := rd.Status.ImageBuild.Job
+	require.Equal(t, imageBuildJobName(rd, time.Now()), name)
+
+	job := getJob(name)
+	require.True(t, metav1.IsControlledBy(job, rd))
+
+	build := job.Spec.Template.Spec.Containers[0]
+	require.Equal(t, DefaultImageBuildBuildkitImage, build.Image)
+	require.Contains(t, build.Args, "context=https://github.com/example/runner-images.git#:ci")
+	require.Contains(t, build.Args, "filename=Dockerfile")
+	require.Contains(t, build.Args, "type=image,name=registry.example.com/runners/ci:"+name+",push=true")
+	require.Equal(t, "registry", job.Spec.Template.Spec.Volumes[0].Secret.SecretName)
+
+	// In progress
+	image, err = r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+	require.Empty(t, image)
+
+	// Succeeded
+	job.Status.Succeeded = 1
+	require.NoError(t, r.Status().Update(ctx, job))
+	require.NoError(t, r.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{"job-name": name},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "build",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							Message: `{"containerimage.digest": "sha256:1234"}`,
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	image, err = r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com/runners/ci@sha256:1234", image)
+	require.Equal(t, &v1alpha1.RunnerImageBuildStatus{Image: image, Job: name, Phase: v1alpha1.RunnerImageBuildPhaseSucceeded}, rd.Status.ImageBuild)
+
+	// A change of the build starts a new job while the runners keep using the last image
+	rd.Spec.ImageBuild.Ref = "v2"
+
+	image, err = r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com/runners/ci@sha256:1234", image)
+
+	newName := rd.Status.ImageBuild.Job
+	require.NotEqual(t, name, newName)
+
+	newJob := getJob(newName)
+	newJob.Status.Failed = 1
+	require.NoError(t, r.Status().Update(ctx, newJob))
+
+	image, err = r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com/runners/ci@sha256:1234", image)
+	require.Equal(t, v1alpha1.RunnerImageBuildPhaseFailed, rd.Status.ImageBuild.Phase)
+
+	// The failed build isn't retried until the build changes
+	require.NoError(t, r.Delete(ctx, newJob))
+
+	_, err = r.syncImageBuild(ctx, logr.Discard(), rd)
+	require.NoError(t, err)
+
+	var jobs batchv1.JobList
+	require.NoError(t, r.List(ctx, &jobs))
+	require.Len(t, jobs.Items, 1)
+}
+
+func TestImageBuildJobName(t *testing.T) {
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			ImageBuild: &v1alpha1.RunnerImageBuild{Git: "https://github.com/example/runner-images.git", Image: "registry.example.com/runners/ci"},
+		},
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Without the rebuild interval, the name changes only with the build
+	require.Equal(t, imageBuildJobName(rd, now), imageBuildJobName(rd, now.Add(30*24*time.Hour)))
+
+	rd.Spec.ImageBuild.RebuildInterval = &metav1.Duration{Duration: 24 * time.Hour}
+
+	require.Equal(t, imageBuildJobName(rd, now), imageBuildJobName(rd, now.Add(23*time.Hour)))
+	require.NotEqual(t, imageBuildJobName(rd, now), imageBuildJobName(rd, now.Add(24*time.Hour)))
+}
+
+func TestImageBuildContext(t *testing.T) {
+	git := "https://github.com/example/runner-images.git"
+
+	require.Equal(t, git, imageBuildContext(&v1alpha1.RunnerImageBuild{Git: git}))
+	require.Equal(t, git+"#main", imageBuildContext(&v1alpha1.RunnerImageBuild{Git: git, Ref: "main"}))
+	require.Equal(t, git+"#main:ci", imageBuildContext(&v1alpha1.RunnerImageBuild{Git: git, Ref: "main", ContextDir: "ci"}))
+	require.Equal(t, git+"#:ci", imageBuildContext(&v1alpha1.RunnerImageBuild{Git: git, ContextDir: "ci"}))
+}
@@ -0,0 +1,220 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/imageregistry"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultImagePolicyImage is the upstream runner image tracked by default.
+	DefaultImagePolicyImage = "summerwind/actions-runner"
+	DefaultImagePolicyTag   = "latest"
+
+	DefaultImagePolicyCheckInterval = time.Hour
+
+	// imagePolicyCosignPublicKeyKey is the key of the public key in the secret created by cosign generate-key-pair k8s://.
+	imagePolicyCosignPublicKeyKey = "cosign.pub"
+
+	// maxImageRollouts is the number of the recent rollouts kept in the status.
+	maxImageRollouts = 10
+)
+
+func imagePolicyTracked(p *v1alpha1.ImagePolicy) string {
+	image, tag := p.Image, p.Tag
+	if image == "" {
+		image = DefaultImagePolicyImage
+	}
+	if tag == "" {
+		tag = DefaultImagePolicyTag
+	}
+
+	return image + ":" + tag
+}
+
+func imagePolicyCheckInterval(p *v1alpha1.ImagePolicy) time.Duration {
+	if p.CheckInterval != nil {
+		return p.CheckInterval.Duration
+	}
+
+	return DefaultImagePolicyCheckInterval
+}
+
+// syncImagePolicy resolves the tracked tag of the runner image when it's due, verifies its digest,
+// and rolls the runners out to the verified digest within the rollout windows, recording the rollout in the status.
+// It returns the image the runners use, which is empty until the first digest is verified,
+// and the duration after which the tag is checked again or the pending digest is rolled out.
+func (r *RunnerDeploymentReconciler) syncImagePolicy(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, now time.Time) (string, time.Duration, error) {
+	p := rd.Spec.ImagePolicy
+	if p == nil {
+		if rd.Status.ImagePolicy != nil {
+			return "", 0, r.patchImagePolicyStatus(ctx, rd, nil)
+		}
+
+		return "", 0, nil
+	}
+
+	status := v1alpha1.ImagePolicyStatus{}
+	if rd.Status.ImagePolicy != nil {
+		status = *rd.Status.ImagePolicy.DeepCopy()
+	}
+
+	tracked := imagePolicyTracked(p)
+	interval := imagePolicyCheckInterval(p)
+
+	ref, err := imageregistry.ParseReference(tracked)
+	if err != nil {
+		return status.Image, 0, err
+	}
+
+	if status.Tracked != tracked {
+		// A digest of the previously tracked image is never rolled out
+		status.Tracked = tracked
+		status.LatestDigest = ""
+		status.LastCheckTime = nil
+	}
+
+	if status.LastCheckTime == nil || now.Sub(status.LastCheckTime.Time) >= interval {
+		status.LastCheckTime = &metav1.Time{Time: now}
+		status.Message = ""
+
+		digest, err := r.resolveImagePolicyDigest(ctx, rd, ref)
+		if err != nil {
+			log.Error(err, "Failed to check the runner image", "image", tracked)
+			r.Recorder.Event(rd, corev1.EventTypeWarning, "ImagePolicyCheckFailed", err.Error())
+
+			status.Message = err.Error()
+		} else {
+			if digest != status.LatestDigest {
+				log.Info("Found the new digest of the runner image", "image", tracked, "digest", digest)
+			}
+
+			status.LatestDigest = digest
+		}
+	}
+
+	requeueAfter := interval - now.Sub(status.LastCheckTime.Time)
+
+	if status.LatestDigest != "" && status.LatestDigest != status.Digest {
+		inWindow := true
+
+		if status.Digest != "" && len(p.RolloutWindows) > 0 {
+			current, next, err := maintenancePeriods(now, p.RolloutWindows)
+			if err != nil {
+				return status.Image, 0, err
+			}
+
+			inWindow = current != nil
+
+			if !inWindow && next != nil {
+				if d := next.StartTime.Sub(now); d < requeueAfter {
+					requeueAfter = d
+				}
+			}
+		}
+
+		if inWindow {
+			image := ref.Name + "@" + status.LatestDigest
+
+			status.Rollouts = append(status.Rollouts, v1alpha1.ImageRollout{
+				OldDigest: status.Digest,
+				NewDigest: status.LatestDigest,
+				Time:      metav1.Time{Time: now},
+			})
+			if len(status.Rollouts) > maxImageRollouts {
+				status.Rollouts = status.Rollouts[len(status.Rollouts)-maxImageRollouts:]
+			}
+
+			log.Info("Rolling out the runner image", "image", image, "oldDigest", status.Digest)
+			r.Recorder.Event(rd, corev1.EventTypeNormal, "ImagePolicyRolledOut", fmt.Sprintf("Rolling out the runner image %s", image))
+
+			status.Image = image
+			status.Digest = status.LatestDigest
+		} else {
+			log.V(1).Info("Waiting for the rollout window to roll out the runner image", "digest", status.LatestDigest)
+		}
+	}
+
+	return status.Image, requeueAfter, r.patchImagePolicyStatus(ctx, rd, &status)
+}
+
+// resolveImagePolicyDigest returns the digest of the tracked tag, verified with the verification of the image policy.
+func (r *RunnerDeploymentReconciler) resolveImagePolicyDigest(ctx context.Context, rd *v1alpha1.RunnerDeployment, ref imageregistry.Reference) (string, error) {
+	p := rd.Spec.ImagePolicy
+
+	c := r.newImageRegistryClient()
+
+	if p.PullSecretRef != nil {
+		data, err := r.imagePolicySecretData(ctx, rd.Namespace, p.PullSecretRef.Name, corev1.DockerConfigJsonKey)
+		if err != nil {
+			return "", err
+		}
+
+		c.Username, c.Password, err = imageregistry.DockerConfigCredentials(data, ref.Registry)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", p.PullSecretRef.Name, err)
+		}
+	}
+
+	digest, err := c.Digest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if p.Verification != nil {
+		if err := r.verifyImageDigest(ctx, c, rd.Namespace, ref, digest, p.Verification); err != nil {
+			return "", err
+		}
+	}
+
+	return digest, nil
+}
+
+func (r *RunnerDeploymentReconciler) imagePolicySecretData(ctx context.Context, namespace, name, key string) ([]byte, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no %s", name, key)
+	}
+
+	return data, nil
+}
+
+func (r *RunnerDeploymentReconciler) patchImagePolicyStatus(ctx context.Context, rd *v1alpha1.RunnerDeployment, status *v1alpha1.ImagePolicyStatus) error {
+	if reflect.DeepEqual(rd.Status.ImagePolicy, status) {
+		return nil
+	}
+
+	updated := rd.DeepCopy()
+	updated.Status.ImagePolicy = status
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(rd)); err != nil {
+		return err
+	}
+
+	rd.Status.ImagePolicy = status
+
+	return nil
+}
+
+func imagePolicyWaitingMessage(status *v1alpha1.ImagePolicyStatus) string {
+	msg := "Waiting for the digest of the runner image to be verified"
+	if status != nil && status.Message != "" {
+		msg += ": " + status.Message
+	}
+
+	return msg
+}
@@ -0,0 +1,136 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncImagePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu       sync.Mutex
+		manifest = []byte(`{"schemaVersion":2,"tag":"v1"}`)
+	)
+
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/v2/runners/ci/manifests/stable" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write(manifest)
+	}))
+	defer registry.Close()
+
+	digestOf := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	v1Digest := digestOf(manifest)
+	v2Manifest := []byte(`{"schemaVersion":2,"tag":"v2"}`)
+	v2Digest := digestOf(v2Manifest)
+
+	image := strings.TrimPrefix(registry.URL, "https://") + "/runners/ci"
+	now := time.Now().Truncate(time.Second)
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			ImagePolicy: &v1alpha1.ImagePolicy{
+				Image: image,
+				Tag:   "stable",
+				RolloutWindows: []v1alpha1.MaintenanceSchedule{{
+					StartTime: metav1.Time{Time: now.Add(90 * time.Minute)},
+					EndTime:   metav1.Time{Time: now.Add(150 * time.Minute)},
+				}},
+			},
+		},
+	}
+
+	r := &RunnerDeploymentReconciler{
+		Client:                  clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd).WithStatusSubresource(rd).Build(),
+		Scheme:                  sc,
+		Recorder:                record.NewFakeRecorder(10),
+		ImageRegistryHTTPClient: registry.Client(),
+	}
+
+	// The first digest is pinned right away
+	got, requeueAfter, err := r.syncImagePolicy(ctx, logr.Discard(), rd, now)
+	require.NoError(t, err)
+	require.Equal(t, image+"@"+v1Digest, got)
+	require.Equal(t, DefaultImagePolicyCheckInterval, requeueAfter)
+	require.Equal(t, []v1alpha1.ImageRollout{{NewDigest: v1Digest, Time: metav1.Time{Time: now}}}, rd.Status.ImagePolicy.Rollouts)
+
+	mu.Lock()
+	manifest = v2Manifest
+	mu.Unlock()
+
+	// The tag isn't checked until the check interval elapses
+	got, requeueAfter, err = r.syncImagePolicy(ctx, logr.Discard(), rd, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, image+"@"+v1Digest, got)
+	require.Equal(t, 30*time.Minute, requeueAfter)
+
+	// The new digest waits for the rollout window
+	got, requeueAfter, err = r.syncImagePolicy(ctx, logr.Discard(), rd, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, image+"@"+v1Digest, got)
+	require.Equal(t, v2Digest, rd.Status.ImagePolicy.LatestDigest)
+	require.Equal(t, 30*time.Minute, requeueAfter)
+
+	got, _, err = r.syncImagePolicy(ctx, logr.Discard(), rd, now.Add(100*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, image+"@"+v2Digest, got)
+	require.Len(t, rd.Status.ImagePolicy.Rollouts, 2)
+	require.Equal(t, v1alpha1.ImageRollout{OldDigest: v1Digest, NewDigest: v2Digest, Time: metav1.Time{Time: now.Add(100 * time.Minute)}}, rd.Status.ImagePolicy.Rollouts[1])
+
+	// The unsigned digest is refused once the signature is required, and the runners stay on the verified digest
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, r.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cosign"},
+		Data:       map[string][]byte{"cosign.pub": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+	}))
+
+	rd.Spec.ImagePolicy.Verification = &v1alpha1.ImageVerification{CosignPublicKeySecretRef: &v1alpha1.SecretReference{Name: "cosign"}}
+
+	got, _, err = r.syncImagePolicy(ctx, logr.Discard(), rd, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, image+"@"+v2Digest, got)
+	require.Contains(t, rd.Status.ImagePolicy.Message, "cosign signatures")
+
+	// The status is removed with the image policy
+	rd.Spec.ImagePolicy = nil
+
+	got, _, err = r.syncImagePolicy(ctx, logr.Discard(), rd, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, got)
+	require.Nil(t, rd.Status.ImagePolicy)
+}
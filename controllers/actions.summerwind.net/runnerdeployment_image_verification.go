@@ -0,0 +1,205 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/imageregistry"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultImageVerificationCheckInterval is how often the tag of template.spec.image is resolved and verified again,
+	// so that the runners follow the tag once its new digest is verified.
+	DefaultImageVerificationCheckInterval = time.Hour
+
+	// imageVerificationRetryInterval is how soon the verification of template.spec.image is retried after a failure.
+	imageVerificationRetryInterval = time.Minute
+)
+
+// syncImageVerification verifies the digest of template.spec.image when the image changes or the last verification is due,
+// and records the outcome in the status and the Verified condition.
+// It returns the image, by the digest verified last, the runners use, which is empty until a digest is verified,
+// and the duration after which the image is verified again.
+func (r *RunnerDeploymentReconciler) syncImageVerification(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, now time.Time) (string, time.Duration, error) {
+	v := rd.Spec.ImageVerification
+	if v == nil {
+		if rd.Status.ImageVerification != nil {
+			return "", 0, r.patchImageVerificationStatus(ctx, rd, nil)
+		}
+
+		return "", 0, nil
+	}
+
+	status := v1alpha1.ImageVerificationStatus{}
+	if rd.Status.ImageVerification != nil {
+		status = *rd.Status.ImageVerification.DeepCopy()
+	}
+
+	image := rd.Spec.Template.Spec.Image
+
+	interval := DefaultImageVerificationCheckInterval
+	if status.Message != "" {
+		interval = imageVerificationRetryInterval
+	}
+
+	if status.Image != image || status.LastCheckTime == nil || now.Sub(status.LastCheckTime.Time) >= interval {
+		if status.Image != image {
+			// The verified digest of the previous image is kept in VerifiedImage until the new image is verified
+			status.Image = image
+			status.Digest = ""
+		}
+
+		status.LastCheckTime = &metav1.Time{Time: now}
+		status.Message = ""
+		interval = DefaultImageVerificationCheckInterval
+
+		ref, digest, err := r.verifyTemplateImage(ctx, rd, image)
+		if err != nil {
+			log.Error(err, "Refused the runner image that failed the verification", "image", image, "verifiedImage", status.VerifiedImage)
+			r.Recorder.Event(rd, corev1.EventTypeWarning, "ImageVerificationFailed", err.Error())
+
+			status.Message = err.Error()
+			interval = imageVerificationRetryInterval
+		} else {
+			verified := ref.Name + "@" + digest
+
+			if verified != status.VerifiedImage {
+				log.Info("Verified the runner image", "image", image, "digest", digest)
+				r.Recorder.Event(rd, corev1.EventTypeNormal, "ImageVerified", fmt.Sprintf("Verified the runner image %s", verified))
+			}
+
+			status.Digest = digest
+			status.VerifiedImage = verified
+		}
+	}
+
+	return status.VerifiedImage, interval - now.Sub(status.LastCheckTime.Time), r.patchImageVerificationStatus(ctx, rd, &status)
+}
+
+// verifyTemplateImage resolves the digest of the image, unless it's already by digest, and verifies it,
+// pulling the signatures with the credentials of the image pull secrets of the runner template.
+func (r *RunnerDeploymentReconciler) verifyTemplateImage(ctx context.Context, rd *v1alpha1.RunnerDeployment, image string) (imageregistry.Reference, string, error) {
+	ref, err := imageregistry.ParseReference(image)
+	if err != nil {
+		return ref, "", err
+	}
+
+	c := r.newImageRegistryClient()
+
+	for _, s := range rd.Spec.Template.Spec.ImagePullSecrets {
+		data, err := r.imagePolicySecretData(ctx, rd.Namespace, s.Name, corev1.DockerConfigJsonKey)
+		if err != nil {
+			continue
+		}
+
+		if c.Username, c.Password, err = imageregistry.DockerConfigCredentials(data, ref.Registry); err == nil {
+			break
+		}
+	}
+
+	digest := ref.Digest
+	if digest == "" {
+		digest, err = c.Digest(ctx, ref)
+		if err != nil {
+			return ref, "", err
+		}
+	}
+
+	return ref, digest, r.verifyImageDigest(ctx, c, rd.Namespace, ref, digest, rd.Spec.ImageVerification)
+}
+
+func (r *RunnerDeploymentReconciler) newImageRegistryClient() *imageregistry.Client {
+	c := imageregistry.NewClient("", "")
+	if r.ImageRegistryHTTPClient != nil {
+		c.HTTPClient = r.ImageRegistryHTTPClient
+	}
+
+	return c
+}
+
+// verifyImageDigest verifies the cosign signature of the digest, and its attestations of the predicate types,
+// with the public key or the identities of the verification.
+func (r *RunnerDeploymentReconciler) verifyImageDigest(ctx context.Context, c *imageregistry.Client, namespace string, ref imageregistry.Reference, digest string, v *v1alpha1.ImageVerification) error {
+	var verifier imageregistry.Verifier
+
+	switch {
+	case v.CosignPublicKeySecretRef != nil:
+		data, err := r.imagePolicySecretData(ctx, namespace, v.CosignPublicKeySecretRef.Name, imagePolicyCosignPublicKeyKey)
+		if err != nil {
+			return err
+		}
+
+		verifier.Key, err = imageregistry.ParsePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("secret %s: %w", v.CosignPublicKeySecretRef.Name, err)
+		}
+	case v.Keyless != nil:
+		var err error
+
+		verifier.Keyless, err = newSigstoreVerifier(ctx, r.Client, namespace, v.Keyless, nil)
+		if err != nil {
+			return err
+		}
+	default:
+		// The verification rejected by the webhook, or the one of the resources created before the webhook rejected it
+		return errors.New("either cosignPublicKeySecretRef or keyless must be set to verify the runner image")
+	}
+
+	if err := c.VerifyCosignSignature(ctx, ref, digest, verifier); err != nil {
+		return err
+	}
+
+	for _, predicateType := range v.Attestations {
+		if err := c.VerifyCosignAttestation(ctx, ref, digest, predicateType, verifier); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patchImageVerificationStatus patches the status of the image verification along with the Verified condition,
+// which is removed with the status.
+func (r *RunnerDeploymentReconciler) patchImageVerificationStatus(ctx context.Context, rd *v1alpha1.RunnerDeployment, status *v1alpha1.ImageVerificationStatus) error {
+	updated := rd.DeepCopy()
+	updated.Status.ImageVerification = status
+
+	switch {
+	case status == nil:
+		meta.RemoveStatusCondition(&updated.Status.Conditions, v1alpha1.ConditionTypeVerified)
+	case status.Message != "":
+		setCondition(&updated.Status.Conditions, rd.Generation, v1alpha1.ConditionTypeVerified, false, v1alpha1.ConditionReasonVerificationFailed, status.Message)
+	default:
+		setCondition(&updated.Status.Conditions, rd.Generation, v1alpha1.ConditionTypeVerified, true, v1alpha1.ConditionReasonVerificationSucceeded, fmt.Sprintf("Verified %s", status.VerifiedImage))
+	}
+
+	if equality.Semantic.DeepEqual(rd.Status, updated.Status) {
+		return nil
+	}
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(rd)); err != nil {
+		return err
+	}
+
+	rd.Status = updated.Status
+
+	return nil
+}
+
+func imageVerificationWaitingMessage(status *v1alpha1.ImageVerificationStatus) string {
+	msg := "Waiting for the runner image to be verified"
+	if status != nil && status.Message != "" {
+		msg += ": " + status.Message
+	}
+
+	return msg
+}
@@ -0,0 +1,168 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/imageregistry"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncImageVerification(t *testing.T) {
+	ctx := context.Background()
+
+	digestOf := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	var (
+		mu      sync.Mutex
+		content = map[string][]byte{}
+	)
+
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ref := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		body, ok := content[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write(body)
+	}))
+	defer registry.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// tag points the tag to the manifest, signed with the key like cosign sign --key does when signed
+	tag := func(tag string, manifest []byte, signed bool) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		digest := digestOf(manifest)
+		content[tag] = manifest
+
+		if signed {
+			payload := []byte(fmt.Sprintf(`{"critical":{"image":{"docker-manifest-digest":%q}}}`, digest))
+			sum := sha256.Sum256(payload)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+			require.NoError(t, err)
+
+			content[digestOf(payload)] = payload
+			content[strings.Replace(digest, ":", "-", 1)+".sig"], err = json.Marshal(map[string]any{
+				"layers": []map[string]any{{
+					"digest":      digestOf(payload),
+					"annotations": map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig)},
+				}},
+			})
+			require.NoError(t, err)
+		}
+
+		return digest
+	}
+
+	v1Digest := tag("stable", []byte(`{"schemaVersion":2,"tag":"v1"}`), true)
+
+	name := strings.TrimPrefix(registry.URL, "https://") + "/runners/ci"
+	now := time.Now().Truncate(time.Second)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	cosignKey := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cosign"},
+		Data:       map[string][]byte{"cosign.pub": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+	}
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			ImageVerification: &v1alpha1.ImageVerification{CosignPublicKeySecretRef: &v1alpha1.SecretReference{Name: "cosign"}},
+		},
+	}
+	rd.Spec.Template.Spec.Image = name + ":stable"
+
+	r := &RunnerDeploymentReconciler{
+		Client:                  clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd, cosignKey).WithStatusSubresource(rd).Build(),
+		Scheme:                  sc,
+		Recorder:                record.NewFakeRecorder(10),
+		ImageRegistryHTTPClient: registry.Client(),
+	}
+
+	// The runners are pinned to the verified digest
+	got, requeueAfter, err := r.syncImageVerification(ctx, logr.Discard(), rd, now)
+	require.NoError(t, err)
+	require.Equal(t, name+"@"+v1Digest, got)
+	require.Equal(t, DefaultImageVerificationCheckInterval, requeueAfter)
+	require.True(t, meta.IsStatusConditionTrue(rd.Status.Conditions, v1alpha1.ConditionTypeVerified))
+
+	// The unsigned digest the tag moves to is refused, and the runners stay on the digest verified last
+	tag("stable", []byte(`{"schemaVersion":2,"tag":"v2"}`), false)
+
+	got, _, err = r.syncImageVerification(ctx, logr.Discard(), rd, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, name+"@"+v1Digest, got, "the tag isn't resolved again until the check interval elapses")
+
+	got, requeueAfter, err = r.syncImageVerification(ctx, logr.Discard(), rd, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, name+"@"+v1Digest, got)
+	require.Equal(t, imageVerificationRetryInterval, requeueAfter)
+
+	cond := meta.FindStatusCondition(rd.Status.Conditions, v1alpha1.ConditionTypeVerified)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, v1alpha1.ConditionReasonVerificationFailed, cond.Reason)
+	require.Contains(t, cond.Message, "cosign signatures")
+
+	// A new image is rolled out once verified
+	v3Digest := tag("v3", []byte(`{"schemaVersion":2,"tag":"v3"}`), true)
+	rd.Spec.Template.Spec.Image = name + ":v3"
+
+	got, _, err = r.syncImageVerification(ctx, logr.Discard(), rd, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, name+"@"+v3Digest, got)
+	require.True(t, meta.IsStatusConditionTrue(rd.Status.Conditions, v1alpha1.ConditionTypeVerified))
+
+	// The verification without a public key nor identities never passes
+	empty := &v1alpha1.ImageVerification{Attestations: []string{"https://slsa.dev/provenance/v1"}}
+	require.Len(t, empty.Validate(nil), 1)
+
+	ref, err := imageregistry.ParseReference(name + "@" + v3Digest)
+	require.NoError(t, err)
+	require.ErrorContains(t, r.verifyImageDigest(ctx, r.newImageRegistryClient(), "default", ref, v3Digest, empty), "either cosignPublicKeySecretRef or keyless must be set")
+
+	// The status and the condition are removed with the image verification
+	rd.Spec.ImageVerification = nil
+
+	got, _, err = r.syncImageVerification(ctx, logr.Discard(), rd, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, got)
+	require.Nil(t, rd.Status.ImageVerification)
+	require.Nil(t, meta.FindStatusCondition(rd.Status.Conditions, v1alpha1.ConditionTypeVerified))
+}
@@ -0,0 +1,194 @@
+package actionssummerwindnet
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+// LabelKeyLabelResourceProfile is the label on the Runner and the runner pod that tells which of spec.labelResourceProfiles
+// the runner was created with.
+const LabelKeyLabelResourceProfile = "actions-runner/label-resource-profile"
+
+// matchLabelResourceProfile returns the name of the label resource profile that serves the workflow job requesting the labels,
+// or an empty string when the runners without a profile serve the job.
+// ok is false when neither the runners without a profile nor any of the profiles have all the labels.
+//
+// A profile serves the job only when the job requests all the labels of the profile,
+// so that the jobs that don't ask for a larger runner don't take one.
+// The profile with the most labels wins, and the ties are broken by the profile name.
+func matchLabelResourceProfile(runnerLabels []string, profiles map[string]v1alpha1.LabelResourceProfile, jobLabels []string) (string, bool) {
+	has := func(labels []string, l string) bool {
+		for _, l2 := range labels {
+			if strings.EqualFold(l, l2) {
+				return true
+			}
+		}
+		return false
+	}
+
+	covers := func(profileLabels []string) bool {
+		for _, l := range jobLabels {
+			// ignore "self-hosted" label as all instance here are self-hosted
+			if l == "self-hosted" {
+				continue
+			}
+
+			if !has(runnerLabels, l) && !has(profileLabels, l) {
+				return false
+			}
+		}
+		return true
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := profiles[names[i]], profiles[names[j]]
+		if len(a.Labels) != len(b.Labels) {
+			return len(a.Labels) > len(b.Labels)
+		}
+		return names[i] < names[j]
+	})
+
+NAMES:
+	for _, name := range names {
+		p := profiles[name]
+
+		for _, l := range p.Labels {
+			if !has(jobLabels, l) {
+				continue NAMES
+			}
+		}
+
+		if covers(p.Labels) {
+			return name, true
+		}
+	}
+
+	if covers(nil) {
+		return "", true
+	}
+
+	return "", false
+}
+
+// profileReplicasFromReservations counts the valid capacity reservations per label resource profile.
+// It returns nil when none of the reservations are for a profile.
+func profileReplicasFromReservations(reservations []v1alpha1.CapacityReservation) map[string]int {
+	var replicas map[string]int
+
+	for _, r := range reservations {
+		if r.Profile == "" {
+			continue
+		}
+
+		if replicas == nil {
+			replicas = map[string]int{}
+		}
+
+		replicas[r.Profile] += r.Replicas
+	}
+
+	return replicas
+}
+
+// profilePlacement decides the label resource profiles of the runners of a RunnerReplicaSet,
+// by counting the runners already created with each profile.
+type profilePlacement struct {
+	profiles map[string]v1alpha1.LabelResourceProfile
+	replicas map[string]int
+
+	current map[string]int
+}
+
+func newProfilePlacement(rs *v1alpha1.RunnerReplicaSet, runners []v1alpha1.Runner) *profilePlacement {
+	p := &profilePlacement{
+		profiles: rs.Spec.LabelResourceProfiles,
+		replicas: rs.Spec.ProfileReplicas,
+		current:  map[string]int{},
+	}
+
+	// The quarantined runners are placed like the runners of a profile
+	if rs.Spec.Quarantine != nil {
+		p.profiles = make(map[string]v1alpha1.LabelResourceProfile, len(rs.Spec.LabelResourceProfiles)+1)
+		for name, profile := range rs.Spec.LabelResourceProfiles {
+			p.profiles[name] = profile
+		}
+		p.profiles[v1alpha1.QuarantineProfileName] = v1alpha1.LabelResourceProfile{}
+	}
+
+	for _, r := range runners {
+		if !r.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if name := r.Labels[LabelKeyLabelResourceProfile]; name != "" {
+			p.current[name]++
+		}
+	}
+
+	return p
+}
+
+// next returns the name of the profile of the next runner to create and counts the runner,
+// or an empty string when every profile already has the requested runners.
+func (p *profilePlacement) next() string {
+	names := make([]string, 0, len(p.replicas))
+	for name := range p.replicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := p.profiles[name]; !ok {
+			continue
+		}
+
+		if p.current[name] < p.replicas[name] {
+			p.current[name]++
+			return name
+		}
+	}
+
+	return ""
+}
+
+// applyLabelResourceProfile labels the runner with the profile name, registers the runner with the labels of the profile,
+// and overrides the resources and the node selector of the runner pod with the ones of the profile.
+func applyLabelResourceProfile(runner *v1alpha1.Runner, name string, p v1alpha1.LabelResourceProfile) {
+	runner.Labels = CloneAndAddLabel(runner.Labels, LabelKeyLabelResourceProfile, name)
+
+	labels := append([]string{}, runner.Spec.Labels...)
+	for _, l := range p.Labels {
+		var found bool
+		for _, l2 := range labels {
+			if strings.EqualFold(l, l2) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			labels = append(labels, l)
+		}
+	}
+	runner.Spec.Labels = labels
+
+	if p.Resources != nil {
+		runner.Spec.Resources = *p.Resources.DeepCopy()
+	}
+
+	if len(p.NodeSelector) > 0 {
+		nodeSelector := make(map[string]string, len(runner.Spec.NodeSelector)+len(p.NodeSelector))
+		for k, v := range runner.Spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		for k, v := range p.NodeSelector {
+			nodeSelector[k] = v
+		}
+		runner.Spec.NodeSelector = nodeSelector
+	}
+}
@@ -0,0 +1,101 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestMatchLabelResourceProfile(t *testing.T) {
+	runnerLabels := []string{"linux"}
+	profiles := map[string]v1alpha1.LabelResourceProfile{
+		"large":  {Labels: []string{"large"}},
+		"xlarge": {Labels: []string{"xlarge"}},
+		"gpu":    {Labels: []string{"xlarge", "gpu"}},
+	}
+
+	for _, tc := range []struct {
+		jobLabels []string
+		profile   string
+		ok        bool
+	}{
+		{jobLabels: []string{"self-hosted", "linux"}, profile: "", ok: true},
+		{jobLabels: []string{"self-hosted", "linux", "XLarge"}, profile: "xlarge", ok: true},
+		// The profile with the most labels wins
+		{jobLabels: []string{"self-hosted", "xlarge", "gpu"}, profile: "gpu", ok: true},
+		{jobLabels: []string{"self-hosted", "large", "xlarge"}, ok: false},
+		{jobLabels: []string{"self-hosted", "windows"}, ok: false},
+	} {
+		profile, ok := matchLabelResourceProfile(runnerLabels, profiles, tc.jobLabels)
+		require.Equal(t, tc.ok, ok, "%v", tc.jobLabels)
+		require.Equal(t, tc.profile, profile, "%v", tc.jobLabels)
+	}
+}
+
+func TestProfilePlacement(t *testing.T) {
+	xlarge := v1alpha1.LabelResourceProfile{
+		Labels: []string{"xlarge"},
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+		},
+		NodeSelector: map[string]string{"nodepool": "xlarge"},
+	}
+
+	rs := &v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			LabelResourceProfiles: map[string]v1alpha1.LabelResourceProfile{"xlarge": xlarge},
+			ProfileReplicas:       map[string]int{"xlarge": 2, "removed": 1},
+		},
+	}
+
+	existing := v1alpha1.Runner{}
+	existing.Labels = map[string]string{LabelKeyLabelResourceProfile: "xlarge"}
+
+	p := newProfilePlacement(rs, []v1alpha1.Runner{existing})
+
+	var profiles []string
+	for i := 0; i < 3; i++ {
+		profiles = append(profiles, p.next())
+	}
+	require.Equal(t, []string{"xlarge", "", ""}, profiles)
+
+	runner := &v1alpha1.Runner{}
+	runner.Spec.Labels = []string{"linux"}
+	runner.Spec.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+
+	applyLabelResourceProfile(runner, "xlarge", xlarge)
+
+	require.Equal(t, "xlarge", runner.Labels[LabelKeyLabelResourceProfile])
+	require.Equal(t, []string{"linux", "xlarge"}, runner.Spec.Labels)
+	require.Equal(t, resource.MustParse("8"), runner.Spec.Resources.Requests[corev1.ResourceCPU])
+	require.Equal(t, map[string]string{"kubernetes.io/os": "linux", "nodepool": "xlarge"}, runner.Spec.NodeSelector)
+}
+
+func TestRemoveProfileCapacityReservations(t *testing.T) {
+	reservations := []v1alpha1.CapacityReservation{
+		{Name: "a", Replicas: 1},
+		{Name: "b", Replicas: 1, Profile: "xlarge"},
+		{Name: "c", Replicas: 1, Profile: "large"},
+		{Name: "d", Replicas: 1, Profile: "xlarge"},
+	}
+
+	require.Equal(t, map[string]int{"xlarge": 2, "large": 1}, profileReplicasFromReservations(reservations))
+
+	remaining := removeProfileCapacityReservations(reservations, "xlarge", 1)
+	require.Equal(t, []string{"a", "c", "d"}, reservationNames(remaining))
+
+	// The reservations without a profile are removed once the ones for the profile run out
+	remaining = removeProfileCapacityReservations(reservations, "large", 2)
+	require.Equal(t, []string{"b", "d"}, reservationNames(remaining))
+}
+
+func reservationNames(reservations []v1alpha1.CapacityReservation) []string {
+	var names []string
+	for _, r := range reservations {
+		names = append(names, r.Name)
+	}
+	return names
+}
@@ -0,0 +1,141 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runnerPhaseRunning is the runner phase the job-started hook of the runner status update hook sets while the runner runs a job.
+const runnerPhaseRunning = "Running"
+
+// syncRunnerPodBusyLabel adds the busy label onto the runner pod while the runner is running a job, and removes it afterwards.
+// The runner phase reflects the job only when the runner status update hook is enabled.
+func syncRunnerPodBusyLabel(ctx context.Context, c client.Client, log logr.Logger, runner *v1alpha1.Runner, pod *corev1.Pod) error {
+	busy := runner.Status.Phase == runnerPhaseRunning
+
+	if _, labeled := pod.Labels[LabelKeyRunnerBusy]; labeled == busy {
+		return nil
+	}
+
+	updated := pod.DeepCopy()
+	if busy {
+		updated.Labels = CloneAndAddLabel(updated.Labels, LabelKeyRunnerBusy, "true")
+	} else {
+		delete(updated.Labels, LabelKeyRunnerBusy)
+	}
+
+	if err := c.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		log.Error(err, "Failed to update the busy label of the runner pod")
+		return err
+	}
+
+	log.V(2).Info("Updated the busy label of the runner pod", "busy", busy)
+
+	return nil
+}
+
+func busyRunnerPodDisruptionBudgetName(rd *v1alpha1.RunnerDeployment) string {
+	return rd.Name + "-busy-runners"
+}
+
+// newBusyRunnerPodDisruptionBudget returns the PodDisruptionBudget that allows none of the busy runner pods of the RunnerDeployment to be evicted.
+func newBusyRunnerPodDisruptionBudget(rd *v1alpha1.RunnerDeployment, scheme *runtime.Scheme) (*policyv1.PodDisruptionBudget, error) {
+	maxUnavailable := intstr.FromInt(0)
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      busyRunnerPodDisruptionBudgetName(rd),
+			Namespace: rd.Namespace,
+			Labels: map[string]string{
+				LabelKeyRunnerDeploymentName: rd.Name,
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					LabelKeyRunnerDeploymentName: rd.Name,
+					LabelKeyRunnerBusy:           "true",
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(rd, pdb, scheme); err != nil {
+		return nil, err
+	}
+
+	return pdb, nil
+}
+
+// syncBusyRunnerPodDisruptionBudget creates or updates the PodDisruptionBudget of the busy runner pods when the RunnerDeployment requests it,
+// and deletes it otherwise.
+func (r *RunnerDeploymentReconciler) syncBusyRunnerPodDisruptionBudget(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment) error {
+	var current policyv1.PodDisruptionBudget
+
+	err := r.Get(ctx, types.NamespacedName{Namespace: rd.Namespace, Name: busyRunnerPodDisruptionBudgetName(rd)}, &current)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	exists := err == nil
+
+	if !rd.Spec.BusyRunnerPodDisruptionBudget {
+		if !exists || !metav1.IsControlledBy(&current, rd) {
+			return nil
+		}
+
+		if err := r.Delete(ctx, &current); err != nil && !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete poddisruptionbudget")
+			return err
+		}
+
+		log.Info("Deleted poddisruptionbudget", "poddisruptionbudget", current.Name)
+
+		return nil
+	}
+
+	desired, err := newBusyRunnerPodDisruptionBudget(rd, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create poddisruptionbudget")
+			return err
+		}
+
+		log.Info("Created poddisruptionbudget", "poddisruptionbudget", desired.Name)
+
+		return nil
+	}
+
+	if reflect.DeepEqual(current.Spec.Selector, desired.Spec.Selector) && reflect.DeepEqual(current.Spec.MaxUnavailable, desired.Spec.MaxUnavailable) && current.Spec.MinAvailable == nil {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	updated.Spec = desired.Spec
+
+	if err := r.Update(ctx, updated); err != nil {
+		log.Error(err, "Failed to update poddisruptionbudget")
+		return err
+	}
+
+	log.Info("Updated poddisruptionbudget", "poddisruptionbudget", updated.Name)
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncBusyRunnerPodDisruptionBudget(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			BusyRunnerPodDisruptionBudget: true,
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd).Build()
+
+	r := &RunnerDeploymentReconciler{
+		Client: c,
+		Scheme: sc,
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "example-busy-runners"}
+
+	require.NoError(t, r.syncBusyRunnerPodDisruptionBudget(ctx, logr.Discard(), rd))
+
+	var pdb policyv1.PodDisruptionBudget
+	require.NoError(t, c.Get(ctx, key, &pdb))
+	require.Equal(t, 0, pdb.Spec.MaxUnavailable.IntValue())
+	require.Equal(t, map[string]string{LabelKeyRunnerDeploymentName: "example", LabelKeyRunnerBusy: "true"}, pdb.Spec.Selector.MatchLabels)
+	require.True(t, metav1.IsControlledBy(&pdb, rd))
+
+	// Idempotent
+	require.NoError(t, r.syncBusyRunnerPodDisruptionBudget(ctx, logr.Discard(), rd))
+
+	rd.Spec.BusyRunnerPodDisruptionBudget = false
+
+	require.NoError(t, r.syncBusyRunnerPodDisruptionBudget(ctx, logr.Discard(), rd))
+	require.True(t, kerrors.IsNotFound(c.Get(ctx, key, &pdb)))
+}
+
+func TestSyncRunnerPodBusyLabel(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde-fghij",
+			Namespace: "default",
+			Labels:    map[string]string{LabelKeyRunnerDeploymentName: "example"},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+
+	var runner v1alpha1.Runner
+
+	getPod := func() *corev1.Pod {
+		var updated corev1.Pod
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-abcde-fghij"}, &updated))
+		return &updated
+	}
+
+	runner.Status.Phase = runnerPhaseRunning
+	require.NoError(t, syncRunnerPodBusyLabel(ctx, c, logr.Discard(), &runner, getPod()))
+	require.Equal(t, "true", getPod().Labels[LabelKeyRunnerBusy])
+
+	runner.Status.Phase = "Idle"
+	require.NoError(t, syncRunnerPodBusyLabel(ctx, c, logr.Discard(), &runner, getPod()))
+	require.NotContains(t, getPod().Labels, LabelKeyRunnerBusy)
+	require.Equal(t, "example", getPod().Labels[LabelKeyRunnerDeploymentName])
+}
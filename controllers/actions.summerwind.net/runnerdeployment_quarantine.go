@@ -0,0 +1,97 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// quarantineWritableDirs are the directories of the runner container of a quarantined runner pod that are backed by emptyDirs,
+// as the root filesystem is read-only. /runner and the work directory are emptyDirs of any runner pod.
+var quarantineWritableDirs = []struct {
+	volume, path string
+}{
+	{volume: "quarantine-tmp", path: "/tmp"},
+	{volume: "quarantine-home", path: "/home/runner"},
+	{volume: "quarantine-tool-cache", path: "/opt/hostedtoolcache"},
+}
+
+// isQuarantined returns true when the Runner or the runner pod is a quarantined runner.
+func isQuarantined(labels map[string]string) bool {
+	return labels[LabelKeyLabelResourceProfile] == v1alpha1.QuarantineProfileName
+}
+
+// applyQuarantine labels the runner as a quarantined runner, and strips the runner of anything a suspicious job could abuse,
+// that is docker, the service account token, the secret mounts, and the identity.
+// The runner keeps the labels of the template, so that it can pick up the job it's created for.
+func applyQuarantine(runner *v1alpha1.Runner, q *v1alpha1.Quarantine) {
+	runner.Labels = CloneAndAddLabel(runner.Labels, LabelKeyLabelResourceProfile, v1alpha1.QuarantineProfileName)
+
+	runtimeClassName := q.RuntimeClassName
+	if runtimeClassName == "" {
+		runtimeClassName = v1alpha1.DefaultQuarantineRuntimeClassName
+	}
+	runner.Spec.RuntimeClassName = &runtimeClassName
+
+	dockerEnabled, dockerdWithinRunnerContainer, automountServiceAccountToken := false, false, false
+	runner.Spec.DockerEnabled = &dockerEnabled
+	runner.Spec.DockerdWithinRunnerContainer = &dockerdWithinRunnerContainer
+	runner.Spec.AutomountServiceAccountToken = &automountServiceAccountToken
+	runner.Spec.ServiceAccountName = ""
+	runner.Spec.SecretMounts = nil
+	runner.Spec.Identity = nil
+
+	var containers []corev1.Container
+	for _, c := range runner.Spec.Containers {
+		if c.Name != "docker" {
+			containers = append(containers, c)
+		}
+	}
+	runner.Spec.Containers = containers
+
+	// The runner writes its diagnostic logs, which tell the steps of the job, to the container logs collected by the cluster
+	env := append([]corev1.EnvVar{}, runner.Spec.Env...)
+	if ok, i := envVarPresent("ACTIONS_RUNNER_PRINT_LOG_TO_STDOUT", env); ok {
+		env[i].Value = "true"
+	} else {
+		env = append(env, corev1.EnvVar{Name: "ACTIONS_RUNNER_PRINT_LOG_TO_STDOUT", Value: "true"})
+	}
+	runner.Spec.Env = env
+}
+
+// applyQuarantineToPod makes the root filesystem of the runner container of the quarantined runner pod read-only and unprivileged,
+// and mounts emptyDirs onto the directories the runner and the actions write to, unless they're already mounted.
+func applyQuarantineToPod(pod *corev1.Pod) {
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != "runner" {
+			continue
+		}
+
+		if c.SecurityContext == nil {
+			c.SecurityContext = &corev1.SecurityContext{}
+		}
+
+		readOnlyRootFilesystem, privileged := true, false
+		c.SecurityContext.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+		c.SecurityContext.Privileged = &privileged
+
+		for _, d := range quarantineWritableDirs {
+			var mounted bool
+			for _, m := range c.VolumeMounts {
+				if m.MountPath == d.path {
+					mounted = true
+					break
+				}
+			}
+			if mounted {
+				continue
+			}
+
+			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: d.volume, MountPath: d.path})
+			pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+				Name:         d.volume,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestQuarantinePlacement(t *testing.T) {
+	rs := &v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			ProfileReplicas: map[string]int{v1alpha1.QuarantineProfileName: 1},
+		},
+	}
+
+	// The quarantine profile is placed only while the quarantine is enabled
+	require.Equal(t, "", newProfilePlacement(rs, nil).next())
+
+	rs.Spec.Quarantine = &v1alpha1.Quarantine{WorkflowFileModified: true}
+
+	p := newProfilePlacement(rs, nil)
+	require.Equal(t, v1alpha1.QuarantineProfileName, p.next())
+	require.Equal(t, "", p.next())
+
+	dockerEnabled := true
+	runner := &v1alpha1.Runner{}
+	runner.Spec.Labels = []string{"linux"}
+	runner.Spec.DockerEnabled = &dockerEnabled
+	runner.Spec.ServiceAccountName = "deployer"
+	runner.Spec.SecretMounts = []v1alpha1.RunnerSecretMount{{Name: "npm", SecretNames: []string{"npm-token"}}}
+	runner.Spec.Containers = []corev1.Container{{Name: "runner"}, {Name: "docker"}}
+
+	applyQuarantine(runner, rs.Spec.Quarantine)
+
+	require.True(t, isQuarantined(runner.Labels))
+	require.Equal(t, []string{"linux"}, runner.Spec.Labels, "the quarantined runner can pick up the job it's created for")
+	require.Equal(t, "gvisor", *runner.Spec.RuntimeClassName)
+	require.False(t, *runner.Spec.DockerEnabled)
+	require.False(t, *runner.Spec.AutomountServiceAccountToken)
+	require.Empty(t, runner.Spec.ServiceAccountName)
+	require.Empty(t, runner.Spec.SecretMounts)
+	require.Equal(t, []corev1.Container{{Name: "runner"}}, runner.Spec.Containers)
+	require.Equal(t, []corev1.EnvVar{{Name: "ACTIONS_RUNNER_PRINT_LOG_TO_STDOUT", Value: "true"}}, runner.Spec.Env)
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "runner",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "runner", MountPath: "/runner"},
+						{Name: "tool-cache", MountPath: "/opt/hostedtoolcache"},
+					},
+				},
+			},
+		},
+	}
+
+	applyQuarantineToPod(pod)
+
+	c := pod.Spec.Containers[0]
+	require.True(t, *c.SecurityContext.ReadOnlyRootFilesystem)
+	require.False(t, *c.SecurityContext.Privileged)
+	require.Equal(t, []corev1.VolumeMount{
+		{Name: "runner", MountPath: "/runner"},
+		{Name: "tool-cache", MountPath: "/opt/hostedtoolcache"},
+		{Name: "quarantine-tmp", MountPath: "/tmp"},
+		{Name: "quarantine-home", MountPath: "/home/runner"},
+	}, c.VolumeMounts)
+	require.Len(t, pod.Spec.Volumes, 2)
+	require.NotNil(t, pod.Spec.Volumes[0].EmptyDir)
+}
+
+func TestQuarantineValidate(t *testing.T) {
+	spec := &v1alpha1.RunnerDeploymentSpec{
+		LabelResourceProfiles: map[string]v1alpha1.LabelResourceProfile{
+			v1alpha1.QuarantineProfileName: {Labels: []string{"quarantine"}},
+		},
+		Template: v1alpha1.RunnerTemplate{
+			Spec: v1alpha1.RunnerSpec{
+				RunnerConfig: v1alpha1.RunnerConfig{ContainerMode: "kubernetes"},
+			},
+		},
+	}
+
+	q := &v1alpha1.Quarantine{EgressProvider: "Calico"}
+
+	errs := q.Validate(spec, field.NewPath("spec", "quarantine"))
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	require.Equal(t, []string{
+		"spec.quarantine",
+		"spec.quarantine.egressProvider",
+		"spec.labelResourceProfiles[quarantine]",
+		"spec.template.spec.containerMode",
+	}, fields)
+
+	spec.LabelResourceProfiles = nil
+	spec.Template.Spec.ContainerMode = ""
+
+	require.Empty(t, (&v1alpha1.Quarantine{FirstTimeContributor: true}).Validate(spec, field.NewPath("spec", "quarantine")))
+}
@@ -0,0 +1,112 @@
+package actionssummerwindnet
+
+import (
+	"math"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	defaultMaxSurge       = intstr.FromString("25%")
+	defaultMaxUnavailable = intstr.FromString("25%")
+)
+
+// rolloutPlan is the desired replicas of the runner replica sets of a RunnerDeployment for the next step of its rolling update.
+type rolloutPlan struct {
+	// newReplicas is the desired replicas of the newest runner replica set.
+	newReplicas int
+	// oldReplicas is the desired replicas of the old runner replica sets, in the same order as the old runner replica sets given to planRollout.
+	oldReplicas []int
+	// waitingForPromotion is true when the canary runners are up and the rolling update doesn't proceed until promoted.
+	waitingForPromotion bool
+}
+
+// planRollout computes the next step of the rolling update of a RunnerDeployment with the strategy, the way the Deployment controller does.
+// The newest runner replica set is scaled up as long as the total number of runners doesn't exceed the desired replicas plus maxSurge,
+// and the old ones are scaled down, oldest first, as long as the number of available runners doesn't fall below the desired replicas minus maxUnavailable.
+//
+// newest is nil when the newest runner replica set is yet to be created.
+func planRollout(strategy v1alpha1.RunnerDeploymentStrategy, promoted bool, desired int, newest *v1alpha1.RunnerReplicaSet, oldSets []v1alpha1.RunnerReplicaSet) rolloutPlan {
+	maxSurge := defaultMaxSurge
+	if strategy.MaxSurge != nil {
+		maxSurge = *strategy.MaxSurge
+	}
+
+	maxUnavailable := defaultMaxUnavailable
+	if strategy.MaxUnavailable != nil {
+		maxUnavailable = *strategy.MaxUnavailable
+	}
+
+	// The values are validated to be non-negative integers or percentages by the webhook
+	surge, _ := intstr.GetScaledValueFromIntOrPercent(&maxSurge, desired, true)
+	unavailable, _ := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, desired, false)
+
+	// Otherwise the rolling update never proceeds
+	if surge == 0 && unavailable == 0 {
+		surge = 1
+	}
+
+	newCap := desired
+
+	var plan rolloutPlan
+
+	if c := strategy.Canary; c != nil && !promoted && desired > 0 {
+		newCap = int(math.Ceil(float64(desired) * float64(c.Percentage) / 100))
+		if newCap > desired {
+			newCap = desired
+		}
+	}
+
+	var newReplicas, newAvailable int
+
+	if newest != nil {
+		newReplicas = getIntOrDefault(newest.Spec.Replicas, 0)
+		newAvailable = getIntOrDefault(newest.Status.AvailableReplicas, 0)
+	}
+
+	available := newAvailable
+
+	var oldTotal int
+
+	for _, rs := range oldSets {
+		oldTotal += getIntOrDefault(rs.Spec.Replicas, 0)
+		available += getIntOrDefault(rs.Status.AvailableReplicas, 0)
+	}
+
+	// Scale up the newest runner replica set within the surge
+	plan.newReplicas = newReplicas
+	if room := desired + surge - newReplicas - oldTotal; room > 0 {
+		plan.newReplicas = newReplicas + room
+	}
+	if plan.newReplicas > newCap {
+		plan.newReplicas = newCap
+	}
+
+	// Scale down the old runner replica sets within the unavailability,
+	// keeping the rest of the runners outdated while waiting for the canary to be promoted
+	scaleDown := oldTotal - (desired - newCap)
+	if s := available - (desired - unavailable); s < scaleDown {
+		scaleDown = s
+	}
+
+	plan.oldReplicas = make([]int, len(oldSets))
+	for i := len(oldSets) - 1; i >= 0; i-- {
+		replicas := getIntOrDefault(oldSets[i].Spec.Replicas, 0)
+
+		if scaleDown > 0 {
+			d := scaleDown
+			if d > replicas {
+				d = replicas
+			}
+			replicas -= d
+			scaleDown -= d
+		}
+
+		plan.oldReplicas[i] = replicas
+	}
+
+	plan.waitingForPromotion = newCap < desired && plan.newReplicas == newCap && newAvailable >= newCap
+
+	return plan
+}
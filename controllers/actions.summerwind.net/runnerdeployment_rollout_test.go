@@ -0,0 +1,105 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newTestRunnerReplicaSet(replicas, available int) v1alpha1.RunnerReplicaSet {
+	return v1alpha1.RunnerReplicaSet{
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			Replicas: &replicas,
+		},
+		Status: v1alpha1.RunnerReplicaSetStatus{
+			AvailableReplicas: &available,
+		},
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	one := intstr.FromInt(1)
+	zero := intstr.FromInt(0)
+
+	rollingUpdate := v1alpha1.RunnerDeploymentStrategy{}
+	canary := v1alpha1.RunnerDeploymentStrategy{Canary: &v1alpha1.RunnerDeploymentCanary{Percentage: 25}}
+
+	tests := []struct {
+		name     string
+		strategy v1alpha1.RunnerDeploymentStrategy
+		promoted bool
+		desired  int
+		newest   *v1alpha1.RunnerReplicaSet
+		old      []v1alpha1.RunnerReplicaSet
+		want     rolloutPlan
+	}{
+		{
+			name:     "create newest within the surge",
+			strategy: rollingUpdate,
+			desired:  4,
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(4, 4)},
+			want:     rolloutPlan{newReplicas: 1, oldReplicas: []int{3}},
+		},
+		{
+			name:     "wait for the newest to be available",
+			strategy: v1alpha1.RunnerDeploymentStrategy{MaxUnavailable: &zero},
+			desired:  4,
+			newest:   ptr(newTestRunnerReplicaSet(1, 0)),
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(4, 4)},
+			want:     rolloutPlan{newReplicas: 1, oldReplicas: []int{4}},
+		},
+		{
+			name:     "scale down the oldest first",
+			strategy: v1alpha1.RunnerDeploymentStrategy{MaxSurge: &one, MaxUnavailable: &one},
+			desired:  4,
+			newest:   ptr(newTestRunnerReplicaSet(2, 2)),
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(2, 2), newTestRunnerReplicaSet(1, 1)},
+			want:     rolloutPlan{newReplicas: 2, oldReplicas: []int{1, 0}},
+		},
+		{
+			name:     "never proceeds without surge and unavailability",
+			strategy: v1alpha1.RunnerDeploymentStrategy{MaxSurge: &zero, MaxUnavailable: &zero},
+			desired:  4,
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(4, 4)},
+			want:     rolloutPlan{newReplicas: 1, oldReplicas: []int{4}},
+		},
+		{
+			name:     "canary waits for promotion",
+			strategy: canary,
+			desired:  4,
+			newest:   ptr(newTestRunnerReplicaSet(1, 1)),
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(3, 3)},
+			want:     rolloutPlan{newReplicas: 1, oldReplicas: []int{3}, waitingForPromotion: true},
+		},
+		{
+			name:     "canary replaces the outdated runners",
+			strategy: canary,
+			desired:  4,
+			newest:   ptr(newTestRunnerReplicaSet(1, 1)),
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(4, 4)},
+			want:     rolloutPlan{newReplicas: 1, oldReplicas: []int{3}, waitingForPromotion: true},
+		},
+		{
+			name:     "promoted canary",
+			strategy: canary,
+			promoted: true,
+			desired:  4,
+			newest:   ptr(newTestRunnerReplicaSet(1, 1)),
+			old:      []v1alpha1.RunnerReplicaSet{newTestRunnerReplicaSet(3, 3)},
+			want:     rolloutPlan{newReplicas: 2, oldReplicas: []int{2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planRollout(tt.strategy, tt.promoted, tt.desired, tt.newest, tt.old)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,135 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DefaultScaleUpWaveInterval = 2 * time.Minute
+
+// applyScaleUpWaves limits the desired replicas of the runner replica set named rs to its current replicas plus the size of a wave.
+// The next wave starts once the runners of the previous one are ready or the wave interval elapses, whichever comes first.
+// The size of the next wave depends on how quickly the previous one became ready,
+// so that the scale up speeds up while the images are cached and the nodes are available, and slows down while they aren't.
+// It returns the limited desired replicas, the wave status to be saved into the RunnerDeployment status,
+// which is nil unless a scale up is in progress, and the duration after which the wave is checked again.
+func applyScaleUpWaves(waves *v1alpha1.ScaleUpWaves, prev *v1alpha1.ScaleUpWaveStatus, rs string, desiredReplicas, currentReplicas, readyReplicas int, now time.Time) (int, *v1alpha1.ScaleUpWaveStatus, time.Duration) {
+	if waves == nil {
+		return desiredReplicas, nil, 0
+	}
+
+	if prev != nil && prev.RunnerReplicaSet != rs {
+		prev = nil
+	}
+
+	if desiredReplicas <= currentReplicas {
+		// The last wave is reported until its runners are ready
+		if prev != nil && prev.Replicas == currentReplicas && desiredReplicas == currentReplicas && readyReplicas < currentReplicas {
+			wave := *prev
+			wave.ReadyReplicas = readyReplicas
+			wave.DesiredReplicas = desiredReplicas
+
+			return desiredReplicas, &wave, 0
+		}
+
+		return desiredReplicas, nil, 0
+	}
+
+	interval := scaleUpWaveInterval(waves)
+
+	number, size := 1, waves.WaveSize
+
+	if prev != nil && prev.Replicas == currentReplicas {
+		elapsed := now.Sub(prev.StartTime.Time)
+
+		if readyReplicas < currentReplicas && elapsed < interval {
+			wave := *prev
+			wave.ReadyReplicas = readyReplicas
+			wave.DesiredReplicas = desiredReplicas
+
+			return currentReplicas, &wave, interval - elapsed
+		}
+
+		number, size = prev.Number+1, prev.Size
+
+		switch {
+		case readyReplicas < currentReplicas:
+			size /= 2
+		case elapsed <= interval/2:
+			size *= 2
+		}
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	if waves.MaxWaveSize != nil && size > *waves.MaxWaveSize {
+		size = *waves.MaxWaveSize
+	}
+
+	replicas := currentReplicas + size
+	if replicas > desiredReplicas {
+		replicas = desiredReplicas
+	}
+
+	wave := &v1alpha1.ScaleUpWaveStatus{
+		RunnerReplicaSet: rs,
+		Number:           number,
+		Size:             size,
+		Replicas:         replicas,
+		ReadyReplicas:    readyReplicas,
+		DesiredReplicas:  desiredReplicas,
+		StartTime:        metav1.Time{Time: now},
+	}
+
+	return replicas, wave, interval
+}
+
+func scaleUpWaveInterval(waves *v1alpha1.ScaleUpWaves) time.Duration {
+	if waves.WaveInterval != nil {
+		return waves.WaveInterval.Duration
+	}
+
+	return DefaultScaleUpWaveInterval
+}
+
+// patchScaleUpWave saves the wave status before the runner replica set is scaled,
+// so that the next reconciliation knows which wave the new replicas belong to.
+func (r *RunnerDeploymentReconciler) patchScaleUpWave(ctx context.Context, rd *v1alpha1.RunnerDeployment, wave *v1alpha1.ScaleUpWaveStatus) error {
+	if reflect.DeepEqual(rd.Status.ScaleUpWave, wave) {
+		return nil
+	}
+
+	updated := rd.DeepCopy()
+	updated.Status.ScaleUpWave = wave
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(rd)); err != nil {
+		return err
+	}
+
+	rd.Status.ScaleUpWave = wave
+
+	return nil
+}
+
+// createScaleUpWave saves the first wave of the runner replica set that has just been created with its generated name.
+func (r *RunnerDeploymentReconciler) createScaleUpWave(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, wave *v1alpha1.ScaleUpWaveStatus, rs string) error {
+	if wave != nil {
+		wave.RunnerReplicaSet = rs
+	}
+
+	if err := r.patchScaleUpWave(ctx, rd, wave); err != nil {
+		log.Error(err, "Failed to patch the scale up wave of runnerdeployment status")
+
+		return err
+	}
+
+	return nil
+}
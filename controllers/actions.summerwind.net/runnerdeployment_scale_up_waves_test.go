@@ -0,0 +1,274 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyScaleUpWaves(t *testing.T) {
+	now := time.Now()
+
+	waves := &v1alpha1.ScaleUpWaves{
+		WaveSize:     5,
+		MaxWaveSize:  intPtr(20),
+		WaveInterval: &metav1.Duration{Duration: 2 * time.Minute},
+	}
+
+	wave := func(number, size, replicas int, started time.Duration) *v1alpha1.ScaleUpWaveStatus {
+		return &v1alpha1.ScaleUpWaveStatus{
+			RunnerReplicaSet: "example-abc",
+			Number:           number,
+			Size:             size,
+			Replicas:         replicas,
+			DesiredReplicas:  100,
+			StartTime:        metav1.Time{Time: now.Add(-started)},
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		waves    *v1alpha1.ScaleUpWaves
+		prev     *v1alpha1.ScaleUpWaveStatus
+		rs       string
+		desired  int
+		current  int
+		ready    int
+		replicas int
+		wave     *v1alpha1.ScaleUpWaveStatus
+		requeue  time.Duration
+	}{
+		{
+			name:     "no waves",
+			desired:  100,
+			current:  10,
+			replicas: 100,
+		},
+		{
+			name:     "first wave",
+			waves:    waves,
+			rs:       "example-abc",
+			desired:  100,
+			current:  10,
+			ready:    10,
+			replicas: 15,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 1, Size: 5, Replicas: 15, ReadyReplicas: 10, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+		{
+			name:     "waiting for the wave to become ready",
+			waves:    waves,
+			prev:     wave(1, 5, 15, 30*time.Second),
+			rs:       "example-abc",
+			desired:  100,
+			current:  15,
+			ready:    12,
+			replicas: 15,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 1, Size: 5, Replicas: 15, ReadyReplicas: 12, DesiredReplicas: 100, StartTime: metav1.Time{Time: now.Add(-30 * time.Second)}},
+			requeue:  90 * time.Second,
+		},
+		{
+			name:     "quickly ready wave doubles the next one",
+			waves:    waves,
+			prev:     wave(1, 5, 15, 30*time.Second),
+			rs:       "example-abc",
+			desired:  100,
+			current:  15,
+			ready:    15,
+			replicas: 25,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 2, Size: 10, Replicas: 25, ReadyReplicas: 15, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+		{
+			name:     "slowly ready wave keeps the size of the next one",
+			waves:    waves,
+			prev:     wave(2, 10, 25, 90*time.Second),
+			rs:       "example-abc",
+			desired:  100,
+			current:  25,
+			ready:    25,
+			replicas: 35,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 3, Size: 10, Replicas: 35, ReadyReplicas: 25, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+		{
+			name:     "timed out wave halves the next one",
+			waves:    waves,
+			prev:     wave(3, 10, 35, 3*time.Minute),
+			rs:       "example-abc",
+			desired:  100,
+			current:  35,
+			ready:    30,
+			replicas: 40,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 4, Size: 5, Replicas: 40, ReadyReplicas: 30, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+		{
+			name:     "wave size is capped",
+			waves:    waves,
+			prev:     wave(4, 16, 56, 10*time.Second),
+			rs:       "example-abc",
+			desired:  100,
+			current:  56,
+			ready:    56,
+			replicas: 76,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 5, Size: 20, Replicas: 76, ReadyReplicas: 56, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+		{
+			name:     "last wave is reported until ready",
+			waves:    waves,
+			prev:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 6, Size: 20, Replicas: 100, DesiredReplicas: 100},
+			rs:       "example-abc",
+			desired:  100,
+			current:  100,
+			ready:    90,
+			replicas: 100,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 6, Size: 20, Replicas: 100, ReadyReplicas: 90, DesiredReplicas: 100},
+		},
+		{
+			name:     "last wave is ready",
+			waves:    waves,
+			prev:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-abc", Number: 6, Size: 20, Replicas: 100, DesiredReplicas: 100},
+			rs:       "example-abc",
+			desired:  100,
+			current:  100,
+			ready:    100,
+			replicas: 100,
+		},
+		{
+			name:     "scale down ends the scale up",
+			waves:    waves,
+			prev:     wave(2, 10, 25, 30*time.Second),
+			rs:       "example-abc",
+			desired:  20,
+			current:  25,
+			ready:    20,
+			replicas: 20,
+		},
+		{
+			name:     "wave of another runner replica set is ignored",
+			waves:    waves,
+			prev:     wave(3, 10, 35, 30*time.Second),
+			rs:       "example-def",
+			desired:  100,
+			current:  35,
+			ready:    0,
+			replicas: 40,
+			wave:     &v1alpha1.ScaleUpWaveStatus{RunnerReplicaSet: "example-def", Number: 1, Size: 5, Replicas: 40, ReadyReplicas: 0, DesiredReplicas: 100, StartTime: metav1.Time{Time: now}},
+			requeue:  2 * time.Minute,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			replicas, wave, requeue := applyScaleUpWaves(tc.waves, tc.prev, tc.rs, tc.desired, tc.current, tc.ready, now)
+			require.Equal(t, tc.replicas, replicas)
+			require.Equal(t, tc.wave, wave)
+			require.Equal(t, tc.requeue, requeue)
+		})
+	}
+}
+
+func TestRunnerDeploymentScaleUpWaves(t *testing.T) {
+	ctx := context.Background()
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			Replicas: intPtr(10),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+			Template: v1alpha1.RunnerTemplate{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+				Spec: v1alpha1.RunnerSpec{
+					RunnerConfig: v1alpha1.RunnerConfig{Repository: "test/valid"},
+				},
+			},
+			ScaleUpWaves: &v1alpha1.ScaleUpWaves{WaveSize: 3},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithStatusSubresource(&v1alpha1.RunnerDeployment{}, &v1alpha1.RunnerReplicaSet{}).
+		WithObjects(rd).
+		WithIndex(&v1alpha1.RunnerReplicaSet{}, runnerSetOwnerKey, func(obj client.Object) []string {
+			owner := metav1.GetControllerOf(obj)
+			if owner == nil {
+				return nil
+			}
+			return []string{owner.Name}
+		}).
+		Build()
+
+	r := &RunnerDeploymentReconciler{
+		Client:   c,
+		Scheme:   sc,
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	reconcile := func() (ctrl.Result, v1alpha1.RunnerDeployment, v1alpha1.RunnerReplicaSet) {
+		t.Helper()
+
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		require.NoError(t, err)
+
+		var got v1alpha1.RunnerDeployment
+		require.NoError(t, c.Get(ctx, key, &got))
+
+		var rsList v1alpha1.RunnerReplicaSetList
+		require.NoError(t, c.List(ctx, &rsList))
+		require.Len(t, rsList.Items, 1)
+
+		return res, got, rsList.Items[0]
+	}
+
+	// The runner replica set is created with the first wave
+	res, got, rs := reconcile()
+	require.Equal(t, 3, *rs.Spec.Replicas)
+	require.Equal(t, DefaultScaleUpWaveInterval, res.RequeueAfter)
+	require.NotNil(t, got.Status.ScaleUpWave)
+	require.Equal(t, rs.Name, got.Status.ScaleUpWave.RunnerReplicaSet)
+	require.Equal(t, 1, got.Status.ScaleUpWave.Number)
+
+	// The next wave waits for the first one to become ready
+	_, got, rs = reconcile()
+	require.Equal(t, 3, *rs.Spec.Replicas)
+	require.Equal(t, 1, got.Status.ScaleUpWave.Number)
+
+	rs.Status.ReadyReplicas = intPtr(3)
+	require.NoError(t, c.Status().Update(ctx, &rs))
+
+	// The first wave became ready right away, so the second one is twice as large
+	_, got, rs = reconcile()
+	require.Equal(t, 9, *rs.Spec.Replicas)
+	require.Equal(t, 2, got.Status.ScaleUpWave.Number)
+	require.Equal(t, 6, got.Status.ScaleUpWave.Size)
+
+	rs.Status.ReadyReplicas = intPtr(9)
+	require.NoError(t, c.Status().Update(ctx, &rs))
+
+	_, got, rs = reconcile()
+	require.Equal(t, 10, *rs.Spec.Replicas)
+	require.Equal(t, 3, got.Status.ScaleUpWave.Number)
+
+	rs.Status.ReadyReplicas = intPtr(10)
+	require.NoError(t, c.Status().Update(ctx, &rs))
+
+	// The scale up is complete
+	_, got, _ = reconcile()
+	require.Nil(t, got.Status.ScaleUpWave)
+}
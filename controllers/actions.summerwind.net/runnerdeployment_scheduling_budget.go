@@ -0,0 +1,152 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// runnerPodRequests estimates the resource requests of a runner pod of the runner spec, the way newPod builds the containers.
+// The limits are used for the containers that omit the requests, as Kubernetes defaults the requests to the limits.
+func runnerPodRequests(spec v1alpha1.RunnerSpec) corev1.ResourceList {
+	var containers []corev1.Container
+
+	if len(spec.Containers) == 0 {
+		containers = append(containers, corev1.Container{Name: containerName})
+
+		dockerdInRunner := spec.DockerdWithinRunnerContainer != nil && *spec.DockerdWithinRunnerContainer
+		dockerEnabled := spec.DockerEnabled == nil || *spec.DockerEnabled
+
+		switch {
+		case spec.OS == "windows", spec.ContainerMode == containerModeKubernetes, spec.ContainerMode == containerModeSysbox, spec.ContainerMode == containerModeBuildkit:
+		case dockerEnabled && !dockerdInRunner:
+			containers = append(containers, corev1.Container{Name: "docker"})
+		}
+	} else {
+		containers = append(containers, spec.Containers...)
+	}
+
+	for i, c := range containers {
+		switch c.Name {
+		case containerName:
+			if len(c.Resources.Requests) == 0 {
+				containers[i].Resources.Requests = spec.Resources.Requests
+			}
+			if len(c.Resources.Limits) == 0 {
+				containers[i].Resources.Limits = spec.Resources.Limits
+			}
+		case "docker":
+			if len(c.Resources.Requests) == 0 {
+				containers[i].Resources.Requests = spec.DockerdContainerResources.Requests
+			}
+			if len(c.Resources.Limits) == 0 {
+				containers[i].Resources.Limits = spec.DockerdContainerResources.Limits
+			}
+		}
+	}
+
+	containers = append(containers, spec.SidecarContainers...)
+	containers = append(containers, spec.Sidecars...)
+
+	requests := corev1.ResourceList{}
+
+	for _, c := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			q, ok := c.Resources.Requests[name]
+			if !ok {
+				q, ok = c.Resources.Limits[name]
+			}
+			if !ok {
+				continue
+			}
+
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+	}
+
+	return requests
+}
+
+// applySchedulingBudget clamps the desired replicas of the newest runner replica set so that the total resource requests
+// of the runner pods of all the runner replica sets don't exceed the scheduling budget of the RunnerDeployment.
+// The runner pods of the old runner replica sets are accounted as long as they exist, so that a rolling update doesn't exceed the budget either.
+// It returns the clamped desired replicas and the scheduling budget status to be saved into the RunnerDeployment status,
+// which is nil when the RunnerDeployment has no scheduling budget.
+func applySchedulingBudget(rd v1alpha1.RunnerDeployment, desiredReplicas int, oldSets []v1alpha1.RunnerReplicaSet) (int, *v1alpha1.SchedulingBudgetStatus) {
+	budget := rd.Spec.SchedulingBudget
+	if budget == nil {
+		return desiredReplicas, nil
+	}
+
+	used := corev1.ResourceList{}
+
+	for _, rs := range oldSets {
+		replicas := getIntOrDefault(rs.Spec.Replicas, 0)
+		if current := getIntOrDefault(rs.Status.Replicas, 0); current > replicas {
+			replicas = current
+		}
+
+		for name, q := range runnerPodRequests(rs.Spec.Template.Spec) {
+			sum := used[name]
+			sum.Add(multiplyQuantity(q, replicas))
+			used[name] = sum
+		}
+	}
+
+	requests := runnerPodRequests(rd.Spec.Template.Spec)
+
+	replicas := desiredReplicas
+
+	var limitedBy string
+
+	for _, l := range []struct {
+		name  corev1.ResourceName
+		limit *resource.Quantity
+	}{
+		{corev1.ResourceCPU, budget.CPU},
+		{corev1.ResourceMemory, budget.Memory},
+	} {
+		perPod, ok := requests[l.name]
+		if l.limit == nil || !ok || perPod.IsZero() {
+			continue
+		}
+
+		remaining := l.limit.DeepCopy()
+		remaining.Sub(used[l.name])
+
+		allowed := 0
+		if remaining.Sign() > 0 {
+			allowed = int(remaining.MilliValue() / perPod.MilliValue())
+		}
+
+		if allowed < replicas {
+			replicas = allowed
+			limitedBy = string(l.name)
+		}
+	}
+
+	status := &v1alpha1.SchedulingBudgetStatus{
+		WithheldReplicas: desiredReplicas - replicas,
+		LimitedBy:        limitedBy,
+	}
+
+	for name, q := range requests {
+		total := used[name]
+		total.Add(multiplyQuantity(q, replicas))
+
+		switch name {
+		case corev1.ResourceCPU:
+			status.CPU = &total
+		case corev1.ResourceMemory:
+			status.Memory = &total
+		}
+	}
+
+	return replicas, status
+}
+
+func multiplyQuantity(q resource.Quantity, n int) resource.Quantity {
+	return *resource.NewMilliQuantity(q.MilliValue()*int64(n), q.Format)
+}
@@ -0,0 +1,152 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newTestRunnerSpec(cpu, memory string) v1alpha1.RunnerSpec {
+	var spec v1alpha1.RunnerSpec
+	spec.DockerEnabled = ptr(false)
+	spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return spec
+}
+
+func TestRunnerPodRequests(t *testing.T) {
+	spec := newTestRunnerSpec("1", "2Gi")
+	spec.DockerEnabled = nil
+	spec.DockerdContainerResources.Limits = corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("500m"),
+	}
+	spec.SidecarContainers = []corev1.Container{{
+		Name: "cache",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}}
+
+	requests := runnerPodRequests(spec)
+
+	cpu := requests[corev1.ResourceCPU]
+	memory := requests[corev1.ResourceMemory]
+	require.Equal(t, int64(1500), cpu.MilliValue(), "the limits of the docker sidecar count as its requests")
+	require.Zero(t, memory.Cmp(resource.MustParse("3Gi")))
+
+	spec.DockerdWithinRunnerContainer = ptr(true)
+
+	requests = runnerPodRequests(spec)
+
+	cpu = requests[corev1.ResourceCPU]
+	require.Equal(t, int64(1000), cpu.MilliValue(), "no docker sidecar runs along with dockerd within the runner container")
+}
+
+func TestApplySchedulingBudget(t *testing.T) {
+	quantity := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+
+	newRunnerDeployment := func(budget *v1alpha1.SchedulingBudget) v1alpha1.RunnerDeployment {
+		var rd v1alpha1.RunnerDeployment
+		rd.Spec.Template.Spec = newTestRunnerSpec("1", "2Gi")
+		rd.Spec.SchedulingBudget = budget
+		return rd
+	}
+
+	newOldSet := func(specReplicas, statusReplicas int) v1alpha1.RunnerReplicaSet {
+		rs := v1alpha1.RunnerReplicaSet{}
+		rs.Spec.Replicas = &specReplicas
+		rs.Status.Replicas = &statusReplicas
+		rs.Spec.Template.Spec = newTestRunnerSpec("2", "1Gi")
+		return rs
+	}
+
+	tests := []struct {
+		name         string
+		budget       *v1alpha1.SchedulingBudget
+		desired      int
+		old          []v1alpha1.RunnerReplicaSet
+		wantReplicas int
+		wantStatus   *v1alpha1.SchedulingBudgetStatus
+	}{
+		{
+			name:         "no budget",
+			desired:      10,
+			wantReplicas: 10,
+		},
+		{
+			name:         "within the budget",
+			budget:       &v1alpha1.SchedulingBudget{CPU: quantity("4")},
+			desired:      3,
+			wantReplicas: 3,
+			wantStatus: &v1alpha1.SchedulingBudgetStatus{
+				CPU:    quantity("3"),
+				Memory: quantity("6Gi"),
+			},
+		},
+		{
+			name:         "limited by memory",
+			budget:       &v1alpha1.SchedulingBudget{CPU: quantity("8"), Memory: quantity("5Gi")},
+			desired:      5,
+			wantReplicas: 2,
+			wantStatus: &v1alpha1.SchedulingBudgetStatus{
+				CPU:              quantity("2"),
+				Memory:           quantity("4Gi"),
+				WithheldReplicas: 3,
+				LimitedBy:        "memory",
+			},
+		},
+		{
+			name:   "old runners count until they are gone",
+			budget: &v1alpha1.SchedulingBudget{CPU: quantity("6")},
+			// The old runner replica set is being scaled down to 1 but still has 2 runners
+			old:          []v1alpha1.RunnerReplicaSet{newOldSet(1, 2)},
+			desired:      4,
+			wantReplicas: 2,
+			wantStatus: &v1alpha1.SchedulingBudgetStatus{
+				CPU:              quantity("6"),
+				Memory:           quantity("6Gi"),
+				WithheldReplicas: 2,
+				LimitedBy:        "cpu",
+			},
+		},
+		{
+			name:         "old runners exceeding the budget",
+			budget:       &v1alpha1.SchedulingBudget{CPU: quantity("2")},
+			old:          []v1alpha1.RunnerReplicaSet{newOldSet(2, 2)},
+			desired:      2,
+			wantReplicas: 0,
+			wantStatus: &v1alpha1.SchedulingBudgetStatus{
+				CPU:              quantity("4"),
+				Memory:           quantity("2Gi"),
+				WithheldReplicas: 2,
+				LimitedBy:        "cpu",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replicas, status := applySchedulingBudget(newRunnerDeployment(tt.budget), tt.desired, tt.old)
+			require.Equal(t, tt.wantReplicas, replicas)
+
+			if tt.wantStatus == nil {
+				require.Nil(t, status)
+				return
+			}
+
+			require.NotNil(t, status)
+			require.Equal(t, tt.wantStatus.WithheldReplicas, status.WithheldReplicas)
+			require.Equal(t, tt.wantStatus.LimitedBy, status.LimitedBy)
+			require.Zero(t, tt.wantStatus.CPU.Cmp(*status.CPU), "cpu: %s", status.CPU)
+			require.Zero(t, tt.wantStatus.Memory.Cmp(*status.Memory), "memory: %s", status.Memory)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package actionssummerwindnet
+
+import (
+	"context"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	toolCacheVolumeName = "tool-cache"
+
+	// toolCacheDir is where the tool cache is mounted in the runner container, the same as the GitHub-hosted runners and the runner images.
+	toolCacheDir = "/opt/hostedtoolcache"
+
+	// toolCacheHostPathDir is the directory on the nodes the HostPath tool caches are created under by default.
+	toolCacheHostPathDir = "/var/lib/actions-runner/tool-cache"
+
+	// labelKeyToolCache is the label on the persistent volume claims of the tool caches that contains the name of the tool cache.
+	labelKeyToolCache = "actions-runner/tool-cache"
+)
+
+var defaultToolCacheSize = resource.MustParse("10Gi")
+
+func toolCacheClaimName(name string) string {
+	return "runner-tool-cache-" + name
+}
+
+// applyToolCache mounts the tool cache into the runner container of the template,
+// and points the setup actions to it via RUNNER_TOOL_CACHE and AGENT_TOOLSDIRECTORY.
+func applyToolCache(template *v1alpha1.RunnerTemplate, c *v1alpha1.ToolCache) {
+	v := corev1.Volume{Name: toolCacheVolumeName}
+
+	if c.Type == v1alpha1.ToolCacheTypeHostPath {
+		path := c.HostPath
+		if path == "" {
+			path = toolCacheHostPathDir + "/" + c.ToolCacheName()
+		}
+
+		hostPathType := corev1.HostPathDirectoryOrCreate
+
+		v.HostPath = &corev1.HostPathVolumeSource{
+			Path: path,
+			Type: &hostPathType,
+		}
+	} else {
+		v.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: toolCacheClaimName(c.ToolCacheName()),
+		}
+	}
+
+	spec := &template.Spec
+
+	spec.Volumes = append(spec.Volumes, v)
+	spec.VolumeMounts = append(spec.VolumeMounts, corev1.VolumeMount{
+		Name:      toolCacheVolumeName,
+		MountPath: toolCacheDir,
+	})
+	spec.Env = append(spec.Env,
+		corev1.EnvVar{Name: "RUNNER_TOOL_CACHE", Value: toolCacheDir},
+		// actions/setup-python reads the tool cache from the environment variable of Azure Pipelines
+		corev1.EnvVar{Name: "AGENT_TOOLSDIRECTORY", Value: toolCacheDir},
+	)
+}
+
+// syncToolCache creates the ReadWriteMany persistent volume claim of the tool cache of the RunnerDeployment, if any, shared with
+// the other RunnerDeployments in the namespace using the tool cache of the same name.
+// Every RunnerDeployment using the claim is its owner, so that the claim is garbage-collected once none uses it.
+func (r *RunnerDeploymentReconciler) syncToolCache(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment) error {
+	var current string
+	if c := rd.Spec.ToolCache; c != nil && c.Type != v1alpha1.ToolCacheTypeHostPath {
+		current = toolCacheClaimName(c.ToolCacheName())
+	}
+
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &pvcList, client.InNamespace(rd.Namespace), client.HasLabels{labelKeyToolCache}); err != nil {
+		return err
+	}
+
+	// The RunnerDeployment no longer uses the tool caches it used to
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+
+		if pvc.Name == current || !isOwnedBy(pvc, rd) {
+			continue
+		}
+
+		if err := r.releaseToolCache(ctx, log, rd, pvc); err != nil {
+			return err
+		}
+	}
+
+	if current == "" {
+		return nil
+	}
+
+	var pvc corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, types.NamespacedName{Namespace: rd.Namespace, Name: current}, &pvc); err == nil {
+		if isOwnedBy(&pvc, rd) {
+			return nil
+		}
+
+		updated := pvc.DeepCopy()
+		updated.OwnerReferences = append(updated.OwnerReferences, toolCacheOwnerReference(rd))
+
+		return r.Patch(ctx, updated, client.MergeFrom(&pvc))
+	} else if !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	c := rd.Spec.ToolCache
+
+	size := defaultToolCacheSize
+	if c.Size != nil {
+		size = *c.Size
+	}
+
+	pvc = corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      current,
+			Namespace: rd.Namespace,
+			Labels: map[string]string{
+				labelKeyToolCache: c.ToolCacheName(),
+			},
+			OwnerReferences: []metav1.OwnerReference{toolCacheOwnerReference(rd)},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: c.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+
+	if err := r.Create(ctx, &pvc); err != nil {
+		return err
+	}
+
+	log.Info("Created the tool cache", "pvc", current)
+
+	return nil
+}
+
+// releaseToolCache removes the RunnerDeployment from the owners of the tool cache, and deletes the tool cache when it was the last owner.
+func (r *RunnerDeploymentReconciler) releaseToolCache(ctx context.Context, log logr.Logger, rd *v1alpha1.RunnerDeployment, pvc *corev1.PersistentVolumeClaim) error {
+	var owners []metav1.OwnerReference
+	for _, o := range pvc.OwnerReferences {
+		if o.UID != rd.UID {
+			owners = append(owners, o)
+		}
+	}
+
+	if len(owners) == 0 {
+		if err := r.Delete(ctx, pvc); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		log.Info("Deleted the tool cache no longer used", "pvc", pvc.Name)
+
+		return nil
+	}
+
+	updated := pvc.DeepCopy()
+	updated.OwnerReferences = owners
+
+	return r.Patch(ctx, updated, client.MergeFrom(pvc))
+}
+
+func toolCacheOwnerReference(rd *v1alpha1.RunnerDeployment) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "RunnerDeployment",
+		Name:       rd.Name,
+		UID:        rd.UID,
+	}
+}
+
+func isOwnedBy(obj metav1.Object, rd *v1alpha1.RunnerDeployment) bool {
+	for _, o := range obj.GetOwnerReferences() {
+		if o.UID == rd.UID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncToolCache(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	newRD := func(name string) *v1alpha1.RunnerDeployment {
+		return &v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID(name),
+			},
+			Spec: v1alpha1.RunnerDeploymentSpec{
+				ToolCache: &v1alpha1.ToolCache{},
+			},
+		}
+	}
+
+	rd1, rd2 := newRD("rd1"), newRD("rd2")
+
+	r := &RunnerDeploymentReconciler{
+		Client: clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rd1, rd2).Build(),
+		Scheme: sc,
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "runner-tool-cache-default"}
+
+	require.NoError(t, r.syncToolCache(ctx, logr.Discard(), rd1))
+
+	var pvc corev1.PersistentVolumeClaim
+	require.NoError(t, r.Get(ctx, key, &pvc))
+	require.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, pvc.Spec.AccessModes)
+	require.True(t, resource.MustParse("10Gi").Equal(pvc.Spec.Resources.Requests[corev1.ResourceStorage]))
+	require.Len(t, pvc.OwnerReferences, 1)
+
+	// Shared with the other RunnerDeployment using the tool cache of the same name
+	require.NoError(t, r.syncToolCache(ctx, logr.Discard(), rd2))
+	require.NoError(t, r.syncToolCache(ctx, logr.Discard(), rd2))
+	require.NoError(t, r.Get(ctx, key, &pvc))
+	require.Len(t, pvc.OwnerReferences, 2)
+
+	// Released by the RunnerDeployments no longer using it, and deleted once none uses it
+	rd1.Spec.ToolCache = nil
+	require.NoError(t, r.syncToolCache(ctx, logr.Discard(), rd1))
+	require.NoError(t, r.Get(ctx, key, &pvc))
+	require.Len(t, pvc.OwnerReferences, 1)
+	require.Equal(t, "rd2", pvc.OwnerReferences[0].Name)
+
+	rd2.Spec.ToolCache.Type = v1alpha1.ToolCacheTypeHostPath
+	require.NoError(t, r.syncToolCache(ctx, logr.Discard(), rd2))
+	require.True(t, kerrors.IsNotFound(r.Get(ctx, key, &pvc)))
+}
+
+func TestApplyToolCache(t *testing.T) {
+	var template v1alpha1.RunnerTemplate
+
+	applyToolCache(&template, &v1alpha1.ToolCache{Name: "node"})
+
+	require.Equal(t, "runner-tool-cache-node", template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	require.Equal(t, []corev1.VolumeMount{{Name: "tool-cache", MountPath: "/opt/hostedtoolcache"}}, template.Spec.VolumeMounts)
+	require.Equal(t, []corev1.EnvVar{
+		{Name: "RUNNER_TOOL_CACHE", Value: "/opt/hostedtoolcache"},
+		{Name: "AGENT_TOOLSDIRECTORY", Value: "/opt/hostedtoolcache"},
+	}, template.Spec.Env)
+
+	template = v1alpha1.RunnerTemplate{}
+
+	applyToolCache(&template, &v1alpha1.ToolCache{Type: v1alpha1.ToolCacheTypeHostPath})
+
+	require.Equal(t, "/var/lib/actions-runner/tool-cache/default", template.Spec.Volumes[0].HostPath.Path)
+}
+
+func TestToolCacheValidate(t *testing.T) {
+	size := resource.MustParse("20Gi")
+
+	require.Empty(t, (&v1alpha1.ToolCache{Size: &size}).Validate(nil))
+	require.Empty(t, (&v1alpha1.ToolCache{Type: v1alpha1.ToolCacheTypeHostPath, HostPath: "/mnt/cache"}).Validate(nil))
+
+	require.Len(t, (&v1alpha1.ToolCache{Name: "Not_A_Label"}).Validate(nil), 1)
+	require.Len(t, (&v1alpha1.ToolCache{HostPath: "/mnt/cache"}).Validate(nil), 1)
+	require.Len(t, (&v1alpha1.ToolCache{Type: v1alpha1.ToolCacheTypeHostPath, Size: &size}).Validate(nil), 1)
+}
@@ -0,0 +1,192 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// RunnerPoolPolicyConfigMapKey is the key of the admin policy of the RunnerPoolClaims in its ConfigMap.
+const RunnerPoolPolicyConfigMapKey = "policy.yaml"
+
+// RunnerPoolPolicy is the admin policy of the RunnerPoolClaims, kept in a ConfigMap under the "policy.yaml" key, like:
+//
+//	data:
+//	  policy.yaml: |
+//	    namespace: arc-runner-pools
+//	    tenants:
+//	    - namespaces: [team-a]
+//	      organizations: [my-org]
+//	      labels: [linux, gpu]
+//	      groups: [team-a]
+//	      maxReplicas: 10
+//	      template:
+//	        spec:
+//	          image: my-registry/actions-runner:latest
+//
+// The claims in the namespaces no tenant lists are denied.
+type RunnerPoolPolicy struct {
+	// Namespace is the namespace the RunnerDeployments and the HorizontalRunnerAutoscalers of the pools are created in.
+	// The teams aren't expected to have any access to it.
+	Namespace string `json:"namespace"`
+
+	// Tenants are the namespaces allowed to claim runner pools, along with what their claims are allowed to request.
+	// The first tenant listing the namespace of a claim applies.
+	Tenants []RunnerPoolTenant `json:"tenants"`
+}
+
+type RunnerPoolTenant struct {
+	// Namespaces are the namespaces of the claims of the tenant. "*" matches any namespace.
+	Namespaces []string `json:"namespaces"`
+
+	// Organizations are the organizations the runners of the tenant may be registered to,
+	// along with the repositories of the organizations.
+	Organizations []string `json:"organizations,omitempty"`
+
+	// Repositories are the repositories, in the OWNER/REPO format, the runners of the tenant may be registered to.
+	Repositories []string `json:"repositories,omitempty"`
+
+	// Labels are the runner labels the claims may request. Any label is allowed when omitted.
+	Labels []string `json:"labels,omitempty"`
+
+	// Groups are the runner groups the claims may register the runners to.
+	// The claims may only use the default runner group when omitted.
+	Groups []string `json:"groups,omitempty"`
+
+	// MaxReplicas caps spec.maxReplicas of each claim. Unlimited when omitted.
+	MaxReplicas int `json:"maxReplicas,omitempty"`
+
+	// Template is the runner template of the RunnerDeployments of the pools, on top of which
+	// the organization, the repository, the labels, and the group of the claims are set.
+	Template v1alpha1.RunnerTemplate `json:"template,omitempty"`
+
+	// Metrics are the metrics of the HorizontalRunnerAutoscalers of the pools. Defaults to PercentageRunnersBusy.
+	Metrics []v1alpha1.MetricSpec `json:"metrics,omitempty"`
+}
+
+// ParseRunnerPoolPolicy parses and validates the admin policy of the RunnerPoolClaims.
+func ParseRunnerPoolPolicy(data []byte) (*RunnerPoolPolicy, error) {
+	var p RunnerPoolPolicy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing runner pool policy: %w", err)
+	}
+
+	if p.Namespace == "" {
+		return nil, errors.New("runner pool policy has no namespace")
+	}
+
+	if errs := validation.IsDNS1123Label(p.Namespace); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid namespace %q of runner pool policy: %s", p.Namespace, strings.Join(errs, ", "))
+	}
+
+	for i, t := range p.Tenants {
+		if len(t.Namespaces) == 0 {
+			return nil, fmt.Errorf("tenant %d of runner pool policy has no namespaces", i)
+		}
+
+		for _, ns := range t.Namespaces {
+			// The pools must not be claimed from the namespace they're provisioned in,
+			// or the claims could be made to collide with the pools of the others
+			if ns == p.Namespace {
+				return nil, fmt.Errorf("tenant %d of runner pool policy lists the namespace %s of the pools", i, ns)
+			}
+		}
+
+		if t.MaxReplicas < 0 {
+			return nil, fmt.Errorf("tenant %d of runner pool policy has negative maxReplicas", i)
+		}
+	}
+
+	return &p, nil
+}
+
+// LoadRunnerPoolPolicy reads the admin policy of the RunnerPoolClaims from the ConfigMap.
+func LoadRunnerPoolPolicy(ctx context.Context, c client.Reader, key types.NamespacedName) (*RunnerPoolPolicy, error) {
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, key, &cm); err != nil {
+		return nil, fmt.Errorf("getting runner pool policy ConfigMap %s: %w", key, err)
+	}
+
+	data, ok := cm.Data[RunnerPoolPolicyConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("runner pool policy ConfigMap %s has no %s key", key, RunnerPoolPolicyConfigMapKey)
+	}
+
+	return ParseRunnerPoolPolicy([]byte(data))
+}
+
+// Tenant returns the tenant of the claims in the namespace, or nil when the namespace may not claim runner pools.
+func (p *RunnerPoolPolicy) Tenant(namespace string) *RunnerPoolTenant {
+	for i, t := range p.Tenants {
+		for _, ns := range t.Namespaces {
+			if ns == namespace || ns == "*" {
+				return &p.Tenants[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// Admit returns the tenant of the claim, or an error telling why the policy denies the claim.
+func (p *RunnerPoolPolicy) Admit(claim v1alpha1.RunnerPoolClaim) (*RunnerPoolTenant, error) {
+	t := p.Tenant(claim.Namespace)
+	if t == nil {
+		return nil, fmt.Errorf("namespace %s may not claim runner pools", claim.Namespace)
+	}
+
+	spec := claim.Spec
+
+	if (spec.Organization == "") == (spec.Repository == "") {
+		return nil, errors.New("exactly one of organization and repository is required")
+	}
+
+	allowed := v1alpha1.GitHubCredentialSpec{Organizations: t.Organizations, Repositories: t.Repositories}
+	if !allowed.Allows("", spec.Organization, spec.Repository) {
+		if spec.Organization != "" {
+			return nil, fmt.Errorf("organization %s is not allowed for namespace %s", spec.Organization, claim.Namespace)
+		}
+		return nil, fmt.Errorf("repository %s is not allowed for namespace %s", spec.Repository, claim.Namespace)
+	}
+
+	if len(t.Labels) > 0 {
+		for _, l := range spec.Labels {
+			if !containsFold(t.Labels, l) {
+				return nil, fmt.Errorf("label %s is not allowed for namespace %s", l, claim.Namespace)
+			}
+		}
+	}
+
+	// The runner groups gate which repositories can use the runners, so a claim may not pick any group it likes
+	if spec.Group != "" && !containsFold(t.Groups, spec.Group) {
+		return nil, fmt.Errorf("runner group %s is not allowed for namespace %s", spec.Group, claim.Namespace)
+	}
+
+	if t.MaxReplicas > 0 && spec.MaxReplicas > t.MaxReplicas {
+		return nil, fmt.Errorf("maxReplicas %d exceeds the limit %d for namespace %s", spec.MaxReplicas, t.MaxReplicas, claim.Namespace)
+	}
+
+	if spec.MinReplicas != nil && *spec.MinReplicas > spec.MaxReplicas {
+		return nil, fmt.Errorf("minReplicas %d exceeds maxReplicas %d", *spec.MinReplicas, spec.MaxReplicas)
+	}
+
+	return t, nil
+}
+
+func containsFold(list []string, v string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, v) {
+			return true
+		}
+	}
+
+	return false
+}
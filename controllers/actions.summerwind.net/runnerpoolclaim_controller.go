@@ -0,0 +1,393 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+)
+
+const (
+	// LabelKeyRunnerPoolClaimNamespace and LabelKeyRunnerPoolClaimName are the labels on the RunnerDeployment and
+	// the HorizontalRunnerAutoscaler of a runner pool that tell the RunnerPoolClaim it was provisioned for.
+	LabelKeyRunnerPoolClaimNamespace = "actions-runner-controller/pool-claim-namespace"
+	LabelKeyRunnerPoolClaimName      = "actions-runner-controller/pool-claim-name"
+
+	// annotationKeyRunnerPoolSpecHash is the annotation on the RunnerDeployment and the HorizontalRunnerAutoscaler of a runner pool
+	// that contains the hash of the spec last applied, so that the defaults set by the webhooks don't look like changes.
+	annotationKeyRunnerPoolSpecHash = "actions-runner-controller/pool-spec-hash"
+
+	runnerPoolClaimFinalizerName = "actions.summerwind.dev/runner-pool-claim"
+
+	// maxRunnerPoolNameLength keeps the names of the runners, prefixed by the name of the pool, short enough for the pod names.
+	maxRunnerPoolNameLength = 40
+)
+
+// RunnerPoolClaimReconciler reconciles a RunnerPoolClaim object.
+// It provisions the runner pool claimed by a team as a RunnerDeployment and a HorizontalRunnerAutoscaler
+// in the namespace managed by the platform administrators, as far as their policy allows.
+// As the pool lives in another namespace than the claim, it is deleted via the finalizer of the claim rather than the owner references.
+type RunnerPoolClaimReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	Name     string
+
+	// PolicyConfigMap is the ConfigMap holding the admin policy. It's read on every reconciliation,
+	// so that the change of the policy applies to the claims on their next sync.
+	PolicyConfigMap types.NamespacedName
+	// PolicyReader reads the ConfigMap of the policy. It should be an uncached reader like the manager's APIReader,
+	// so that the controller doesn't need to watch all the ConfigMaps.
+	PolicyReader client.Reader
+}
+
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerpoolclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerpoolclaims/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+func (r *RunnerPoolClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("runnerpoolclaim", req.NamespacedName)
+
+	var claim v1alpha1.RunnerPoolClaim
+	if err := r.Get(ctx, req.NamespacedName, &claim); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	policy, err := LoadRunnerPoolPolicy(ctx, r.policyReader(), r.PolicyConfigMap)
+	if err != nil {
+		log.Error(err, "Failed to load runner pool policy")
+
+		r.patchFailedConditions(ctx, log, &claim, v1alpha1.ConditionReasonPolicyNotFound, err)
+
+		return ctrl.Result{}, err
+	}
+
+	if !claim.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.processDeletion(ctx, log, claim, policy.Namespace)
+	}
+
+	if finalizers, added := addFinalizer(claim.ObjectMeta.Finalizers, runnerPoolClaimFinalizerName); added {
+		updated := claim.DeepCopy()
+		updated.ObjectMeta.Finalizers = finalizers
+
+		if err := r.Patch(ctx, updated, client.MergeFrom(&claim)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("adding finalizer to runnerpoolclaim: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	tenant, err := policy.Admit(claim)
+	if err != nil {
+		// The pool provisioned for the previous spec, if any, is kept as is until the claim complies again
+		log.Info("Runner pool claim denied by policy", "reason", err.Error())
+		r.Recorder.Event(&claim, corev1.EventTypeWarning, v1alpha1.ConditionReasonPolicyViolation, err.Error())
+
+		r.patchFailedConditions(ctx, log, &claim, v1alpha1.ConditionReasonPolicyViolation, err)
+
+		// Fixing the spec triggers the next reconciliation, and the change of the policy applies on the next sync
+		return ctrl.Result{}, nil
+	}
+
+	rd, err := r.applyRunnerPool(ctx, log, claim, policy.Namespace, tenant)
+	if err != nil {
+		r.Recorder.Event(&claim, corev1.EventTypeWarning, v1alpha1.ConditionReasonReconcileFailed, err.Error())
+
+		r.patchFailedConditions(ctx, log, &claim, v1alpha1.ConditionReasonReconcileFailed, err)
+
+		return ctrl.Result{}, err
+	}
+
+	updated := claim.DeepCopy()
+	updated.Status.ObservedGeneration = claim.Generation
+	updated.Status.Namespace = rd.Namespace
+	updated.Status.RunnerDeployment = rd.Name
+	updated.Status.Replicas = getIntOrDefault(rd.Status.Replicas, 0)
+	updated.Status.ReadyReplicas = getIntOrDefault(rd.Status.ReadyReplicas, 0)
+
+	// The claim is as ready as its pool
+	if c := meta.FindStatusCondition(rd.Status.Conditions, v1alpha1.ConditionTypeReady); c != nil {
+		setCondition(&updated.Status.Conditions, claim.Generation, v1alpha1.ConditionTypeReady, c.Status == metav1.ConditionTrue, c.Reason, c.Message)
+	} else {
+		setCondition(&updated.Status.Conditions, claim.Generation, v1alpha1.ConditionTypeReady, false, v1alpha1.ConditionReasonReplicasUnavailable, "")
+	}
+	setCondition(&updated.Status.Conditions, claim.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+
+	if !reflect.DeepEqual(claim.Status, updated.Status) {
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&claim)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("patching runnerpoolclaim status: %w", err)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *RunnerPoolClaimReconciler) policyReader() client.Reader {
+	if r.PolicyReader != nil {
+		return r.PolicyReader
+	}
+
+	return r.Client
+}
+
+func (r *RunnerPoolClaimReconciler) patchFailedConditions(ctx context.Context, log logr.Logger, claim *v1alpha1.RunnerPoolClaim, reason string, err error) {
+	if err := patchConditions(ctx, r.Client, claim, func(obj *v1alpha1.RunnerPoolClaim) *[]metav1.Condition { return &obj.Status.Conditions }, func(conditions *[]metav1.Condition) {
+		if reason == v1alpha1.ConditionReasonPolicyViolation {
+			setCondition(conditions, claim.Generation, v1alpha1.ConditionTypeReady, false, reason, err.Error())
+		}
+		setCondition(conditions, claim.Generation, v1alpha1.ConditionTypeSynced, false, reason, err.Error())
+	}); err != nil {
+		log.Error(err, "Failed to update runnerpoolclaim status conditions")
+	}
+}
+
+// applyRunnerPool creates or updates the RunnerDeployment and the HorizontalRunnerAutoscaler of the pool.
+// The replicas of the RunnerDeployment are left to the HorizontalRunnerAutoscaler.
+func (r *RunnerPoolClaimReconciler) applyRunnerPool(ctx context.Context, log logr.Logger, claim v1alpha1.RunnerPoolClaim, namespace string, tenant *RunnerPoolTenant) (*v1alpha1.RunnerDeployment, error) {
+	desiredRD, desiredHRA := newRunnerPool(claim, namespace, tenant)
+
+	var rd v1alpha1.RunnerDeployment
+	if err := r.Get(ctx, client.ObjectKeyFromObject(desiredRD), &rd); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if err := r.Create(ctx, desiredRD); err != nil {
+			return nil, fmt.Errorf("creating runnerdeployment: %w", err)
+		}
+
+		log.Info("Created runnerdeployment of runner pool", "runnerdeployment", types.NamespacedName{Namespace: namespace, Name: desiredRD.Name})
+		r.Recorder.Event(&claim, corev1.EventTypeNormal, "RunnerPoolCreated", fmt.Sprintf("Created runnerdeployment %s/%s", namespace, desiredRD.Name))
+
+		rd = *desiredRD
+	} else {
+		if !isRunnerPoolOf(&rd, claim) {
+			return nil, fmt.Errorf("runnerdeployment %s/%s isn't the runner pool of the claim", namespace, rd.Name)
+		}
+
+		if rd.Annotations[annotationKeyRunnerPoolSpecHash] != desiredRD.Annotations[annotationKeyRunnerPoolSpecHash] {
+			updated := rd.DeepCopy()
+			metav1.SetMetaDataAnnotation(&updated.ObjectMeta, annotationKeyRunnerPoolSpecHash, desiredRD.Annotations[annotationKeyRunnerPoolSpecHash])
+			updated.Spec.Template = desiredRD.Spec.Template
+
+			if err := r.Patch(ctx, updated, client.MergeFrom(&rd)); err != nil {
+				return nil, fmt.Errorf("patching runnerdeployment: %w", err)
+			}
+
+			log.Info("Updated runnerdeployment of runner pool", "runnerdeployment", types.NamespacedName{Namespace: namespace, Name: rd.Name})
+
+			rd = *updated
+		}
+	}
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	if err := r.Get(ctx, client.ObjectKeyFromObject(desiredHRA), &hra); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if err := r.Create(ctx, desiredHRA); err != nil {
+			return nil, fmt.Errorf("creating horizontalrunnerautoscaler: %w", err)
+		}
+
+		return &rd, nil
+	}
+
+	if !isRunnerPoolOf(&hra, claim) {
+		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s isn't the runner pool of the claim", namespace, hra.Name)
+	}
+
+	if hra.Annotations[annotationKeyRunnerPoolSpecHash] != desiredHRA.Annotations[annotationKeyRunnerPoolSpecHash] {
+		updated := hra.DeepCopy()
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, annotationKeyRunnerPoolSpecHash, desiredHRA.Annotations[annotationKeyRunnerPoolSpecHash])
+		updated.Spec = desiredHRA.Spec
+
+		if err := r.Patch(ctx, updated, client.MergeFrom(&hra)); err != nil {
+			return nil, fmt.Errorf("patching horizontalrunnerautoscaler: %w", err)
+		}
+	}
+
+	return &rd, nil
+}
+
+// processDeletion deletes the RunnerDeployment and the HorizontalRunnerAutoscaler of the pool before removing the finalizer.
+// The runners are unregistered as the RunnerDeployment is deleted.
+func (r *RunnerPoolClaimReconciler) processDeletion(ctx context.Context, log logr.Logger, claim v1alpha1.RunnerPoolClaim, namespace string) (ctrl.Result, error) {
+	name := runnerPoolName(claim)
+
+	for _, obj := range []client.Object{&v1alpha1.HorizontalRunnerAutoscaler{}, &v1alpha1.RunnerDeployment{}} {
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+
+		if !isRunnerPoolOf(obj, claim) {
+			continue
+		}
+
+		if err := r.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("deleting runner pool: %w", err)
+		}
+
+		log.Info("Deleted runner pool", "kind", reflect.TypeOf(obj).Elem().Name(), "name", types.NamespacedName{Namespace: namespace, Name: name})
+	}
+
+	if finalizers, removed := removeFinalizer(claim.ObjectMeta.Finalizers, runnerPoolClaimFinalizerName); removed {
+		updated := claim.DeepCopy()
+		updated.ObjectMeta.Finalizers = finalizers
+
+		if err := r.Patch(ctx, updated, client.MergeFrom(&claim)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("removing finalizer from runnerpoolclaim: %w", err)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// runnerPoolName returns the name of the RunnerDeployment and the HorizontalRunnerAutoscaler of the claim.
+// It always ends with the hash of the namespace and the name of the claim, as joining them alone can collide,
+// like the claim b-c in the namespace a and the claim c in the namespace a-b, and the name is truncated to keep the runner names short.
+func runnerPoolName(claim v1alpha1.RunnerPoolClaim) string {
+	hash := ComputeHash(claim.Namespace + "/" + claim.Name)
+	if len(hash) > 8 {
+		hash = hash[:8]
+	}
+
+	name := claim.Namespace + "-" + claim.Name
+	if max := maxRunnerPoolNameLength - len(hash) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-.")
+	}
+
+	return name + "-" + hash
+}
+
+func runnerPoolLabels(claim v1alpha1.RunnerPoolClaim) map[string]string {
+	return map[string]string{
+		LabelKeyRunnerPoolClaimNamespace: claim.Namespace,
+		LabelKeyRunnerPoolClaimName:      claim.Name,
+	}
+}
+
+// isRunnerPoolOf tells if the object was provisioned for the claim, so that a claim never takes over the objects of another.
+func isRunnerPoolOf(obj client.Object, claim v1alpha1.RunnerPoolClaim) bool {
+	l := obj.GetLabels()
+
+	return l[LabelKeyRunnerPoolClaimNamespace] == claim.Namespace && l[LabelKeyRunnerPoolClaimName] == claim.Name
+}
+
+// newRunnerPool returns the RunnerDeployment and the HorizontalRunnerAutoscaler of the claim in the namespace,
+// built from the template and the metrics of the tenant.
+func newRunnerPool(claim v1alpha1.RunnerPoolClaim, namespace string, tenant *RunnerPoolTenant) (*v1alpha1.RunnerDeployment, *v1alpha1.HorizontalRunnerAutoscaler) {
+	name := runnerPoolName(claim)
+
+	template := *tenant.Template.DeepCopy()
+	template.Spec.Enterprise = ""
+	template.Spec.Organization = claim.Spec.Organization
+	template.Spec.Repository = claim.Spec.Repository
+	template.Spec.Labels = append([]string(nil), claim.Spec.Labels...)
+	template.Spec.Group = claim.Spec.Group
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Labels:      runnerPoolLabels(claim),
+			Annotations: map[string]string{annotationKeyRunnerPoolSpecHash: ComputeHash(template)},
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			Template: template,
+		},
+	}
+
+	minReplicas := getIntOrDefault(claim.Spec.MinReplicas, 0)
+	maxReplicas := claim.Spec.MaxReplicas
+
+	metrics := tenant.Metrics
+	if len(metrics) == 0 {
+		metrics = []v1alpha1.MetricSpec{{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy}}
+	}
+
+	hraSpec := v1alpha1.HorizontalRunnerAutoscalerSpec{
+		ScaleTargetRef: v1alpha1.ScaleTargetRef{Kind: "RunnerDeployment", Name: name},
+		MinReplicas:    &minReplicas,
+		MaxReplicas:    &maxReplicas,
+		Metrics:        append([]v1alpha1.MetricSpec(nil), metrics...),
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Labels:      runnerPoolLabels(claim),
+			Annotations: map[string]string{annotationKeyRunnerPoolSpecHash: ComputeHash(hraSpec)},
+		},
+		Spec: hraSpec,
+	}
+
+	return rd, hra
+}
+
+func (r *RunnerPoolClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "runnerpoolclaim-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.RunnerPoolClaim{}).
+		Watches(&v1alpha1.RunnerDeployment{}, handler.EnqueueRequestsFromMapFunc(claimForRunnerPool)).
+		Named(name).
+		Complete(reconcilemetrics.Observe(name, "RunnerPoolClaim", r))
+}
+
+// claimForRunnerPool enqueues the claim of the RunnerDeployment of a runner pool, so that the status of the claim follows the pool.
+func claimForRunnerPool(_ context.Context, obj client.Object) []reconcile.Request {
+	l := obj.GetLabels()
+
+	ns, name := l[LabelKeyRunnerPoolClaimNamespace], l[LabelKeyRunnerPoolClaimName]
+	if ns == "" || name == "" {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: ns, Name: name}}}
+}
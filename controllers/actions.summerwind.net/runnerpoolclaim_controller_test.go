@@ -0,0 +1,222 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testRunnerPoolPolicy = `
+namespace: arc-pools
+tenants:
+- namespaces: [team-a]
+  organizations: [my-org]
+  repositories: [other-org/app]
+  labels: [linux, gpu]
+  groups: [team-a]
+  maxReplicas: 10
+  template:
+    spec:
+      image: my-registry/actions-runner:latest
+      organization: not-overridable
+`
+
+func TestRunnerPoolPolicyAdmit(t *testing.T) {
+	policy, err := ParseRunnerPoolPolicy([]byte(testRunnerPoolPolicy))
+	require.NoError(t, err)
+
+	claim := func(namespace string, spec v1alpha1.RunnerPoolClaimSpec) v1alpha1.RunnerPoolClaim {
+		return v1alpha1.RunnerPoolClaim{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "pool"}, Spec: spec}
+	}
+
+	two := 2
+	twenty := 20
+
+	for _, tc := range []struct {
+		name  string
+		claim v1alpha1.RunnerPoolClaim
+		err   string
+	}{
+		{name: "organization", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "My-Org", Labels: []string{"gpu"}, MaxReplicas: 10})},
+		{name: "repository of allowed organization", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Repository: "my-org/web", MaxReplicas: 1})},
+		{name: "allowed repository", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Repository: "other-org/app", MinReplicas: &two, MaxReplicas: 2})},
+		{name: "unlisted namespace", claim: claim("team-b", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", MaxReplicas: 1}), err: "namespace team-b may not claim runner pools"},
+		{name: "both organization and repository", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", Repository: "my-org/web", MaxReplicas: 1}), err: "exactly one of"},
+		{name: "other organization", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "other-org", MaxReplicas: 1}), err: "organization other-org is not allowed"},
+		{name: "other repository", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Repository: "other-org/web", MaxReplicas: 1}), err: "repository other-org/web is not allowed"},
+		{name: "label", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", Labels: []string{"windows"}, MaxReplicas: 1}), err: "label windows is not allowed"},
+		{name: "group", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", Group: "Team-A", MaxReplicas: 1})},
+		{name: "other group", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", Group: "team-b", MaxReplicas: 1}), err: "runner group team-b is not allowed"},
+		{name: "maxReplicas", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", MaxReplicas: 11}), err: "exceeds the limit 10"},
+		{name: "minReplicas", claim: claim("team-a", v1alpha1.RunnerPoolClaimSpec{Organization: "my-org", MinReplicas: &twenty, MaxReplicas: 10}), err: "minReplicas 20 exceeds maxReplicas 10"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tenant, err := policy.Admit(tc.claim)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, &policy.Tenants[0], tenant)
+		})
+	}
+
+	_, err = ParseRunnerPoolPolicy([]byte("namespace: arc-pools\ntenants:\n- namespaces: [arc-pools]\n"))
+	require.ErrorContains(t, err, "lists the namespace arc-pools of the pools")
+
+	_, err = ParseRunnerPoolPolicy([]byte("tenants: []\n"))
+	require.ErrorContains(t, err, "has no namespace")
+}
+
+func TestRunnerPoolName(t *testing.T) {
+	short := v1alpha1.RunnerPoolClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "gpu"}}
+	require.Regexp(t, "^team-a-gpu-[a-z0-9]{8}$", runnerPoolName(short))
+
+	// The namespaces and the names joined with the hyphens alone would collide
+	ambiguous := v1alpha1.RunnerPoolClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "a-gpu"}}
+	require.True(t, strings.HasPrefix(runnerPoolName(ambiguous), "team-a-gpu-"))
+	require.NotEqual(t, runnerPoolName(short), runnerPoolName(ambiguous))
+
+	long := v1alpha1.RunnerPoolClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "team-with-a-very-long-name", Name: "pool-with-a-very-long-name"}}
+	name := runnerPoolName(long)
+	require.LessOrEqual(t, len(name), maxRunnerPoolNameLength)
+	require.True(t, strings.HasPrefix(name, "team-with-a-very-long-name-pool-"))
+
+	other := long
+	other.Name = "pool-with-a-very-long-name-2"
+	require.NotEqual(t, name, runnerPoolName(other), "the names sharing the prefix don't collide")
+}
+
+func TestRunnerPoolClaimReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	policy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "runner-pool-policy"},
+		Data:       map[string]string{RunnerPoolPolicyConfigMapKey: testRunnerPoolPolicy},
+	}
+
+	claim := &v1alpha1.RunnerPoolClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "gpu", Generation: 1},
+		Spec: v1alpha1.RunnerPoolClaimSpec{
+			Organization: "my-org",
+			Labels:       []string{"gpu"},
+			Group:        "team-a",
+			MaxReplicas:  5,
+		},
+	}
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithStatusSubresource(&v1alpha1.RunnerPoolClaim{}).
+		WithObjects(policy, claim).
+		Build()
+
+	r := &RunnerPoolClaimReconciler{
+		Client:          c,
+		Log:             logr.Discard(),
+		Recorder:        record.NewFakeRecorder(10),
+		Scheme:          sc,
+		PolicyConfigMap: types.NamespacedName{Namespace: "actions-runner-system", Name: "runner-pool-policy"},
+	}
+
+	key := types.NamespacedName{Namespace: "team-a", Name: "gpu"}
+	poolKey := types.NamespacedName{Namespace: "arc-pools", Name: runnerPoolName(*claim)}
+
+	reconcile := func() v1alpha1.RunnerPoolClaim {
+		t.Helper()
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		require.NoError(t, err)
+
+		var got v1alpha1.RunnerPoolClaim
+		require.NoError(t, c.Get(ctx, key, &got))
+		return got
+	}
+
+	// The finalizer is added first, so that the pool never outlives the claim
+	got := reconcile()
+	require.Contains(t, got.Finalizers, runnerPoolClaimFinalizerName)
+
+	got = reconcile()
+
+	var rd v1alpha1.RunnerDeployment
+	require.NoError(t, c.Get(ctx, poolKey, &rd))
+	require.Equal(t, "my-registry/actions-runner:latest", rd.Spec.Template.Spec.Image)
+	require.Equal(t, "my-org", rd.Spec.Template.Spec.Organization, "the claim decides the organization rather than the template")
+	require.Equal(t, []string{"gpu"}, rd.Spec.Template.Spec.Labels)
+	require.Equal(t, "team-a", rd.Spec.Template.Spec.Group)
+	require.Equal(t, "team-a", rd.Labels[LabelKeyRunnerPoolClaimNamespace])
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, poolKey, &hra))
+	require.Equal(t, poolKey.Name, hra.Spec.ScaleTargetRef.Name)
+	require.Equal(t, 0, *hra.Spec.MinReplicas)
+	require.Equal(t, 5, *hra.Spec.MaxReplicas)
+	require.Equal(t, v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, hra.Spec.Metrics[0].Type)
+
+	require.Equal(t, "arc-pools", got.Status.Namespace)
+	require.Equal(t, poolKey.Name, got.Status.RunnerDeployment)
+	require.True(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeSynced))
+	require.False(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+
+	// The status follows the pool
+	rdReady := rd.DeepCopy()
+	three := 3
+	rdReady.Status.Replicas = &three
+	rdReady.Status.ReadyReplicas = &three
+	setCondition(&rdReady.Status.Conditions, rd.Generation, v1alpha1.ConditionTypeReady, true, v1alpha1.ConditionReasonReplicasAvailable, "")
+	require.NoError(t, c.Update(ctx, rdReady))
+
+	got = reconcile()
+	require.Equal(t, 3, got.Status.ReadyReplicas)
+	require.True(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeReady))
+
+	// The change of the claim applies to the pool
+	got.Spec.MaxReplicas = 8
+	got.Spec.Labels = []string{"linux"}
+	got.Generation = 2
+	require.NoError(t, c.Update(ctx, &got))
+
+	reconcile()
+
+	require.NoError(t, c.Get(ctx, poolKey, &rd))
+	require.Equal(t, []string{"linux"}, rd.Spec.Template.Spec.Labels)
+	require.NoError(t, c.Get(ctx, poolKey, &hra))
+	require.Equal(t, 8, *hra.Spec.MaxReplicas)
+
+	// The claim exceeding the policy is denied, and the pool is kept as is
+	require.NoError(t, c.Get(ctx, key, &got))
+	got.Spec.MaxReplicas = 50
+	require.NoError(t, c.Update(ctx, &got))
+
+	got = reconcile()
+	ready := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeReady)
+	require.Equal(t, metav1.ConditionFalse, ready.Status)
+	require.Equal(t, v1alpha1.ConditionReasonPolicyViolation, ready.Reason)
+	require.Contains(t, ready.Message, "exceeds the limit 10")
+
+	require.NoError(t, c.Get(ctx, poolKey, &hra))
+	require.Equal(t, 8, *hra.Spec.MaxReplicas)
+
+	// Deleting the claim deletes the pool in the other namespace
+	require.NoError(t, c.Delete(ctx, &got))
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	require.True(t, kerrors.IsNotFound(c.Get(ctx, poolKey, &v1alpha1.RunnerDeployment{})))
+	require.True(t, kerrors.IsNotFound(c.Get(ctx, poolKey, &v1alpha1.HorizontalRunnerAutoscaler{})))
+	require.True(t, kerrors.IsNotFound(c.Get(ctx, key, &v1alpha1.RunnerPoolClaim{})))
+}
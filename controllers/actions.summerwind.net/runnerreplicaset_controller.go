@@ -32,6 +32,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/capacityplacement"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 )
 
 // RunnerReplicaSetReconciler reconciles a Runner object
@@ -41,6 +44,9 @@ type RunnerReplicaSetReconciler struct {
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
 	Name     string
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
 }
 
 const (
@@ -62,6 +68,10 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.Sharder.Owns(rs.Namespace, sharding.Owner(rs.Spec.Template.Spec.Enterprise, rs.Spec.Template.Spec.Organization, rs.Spec.Template.Spec.Repository)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !rs.ObjectMeta.DeletionTimestamp.IsZero() {
 		// RunnerReplicaSet cannot be gracefuly removed.
 		// That means any runner that is running a job can be prematurely terminated.
@@ -70,6 +80,11 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
+	// The replicas and the fields copied from the RunnerDeployment on scaling are set by the RunnerDeployment controller
+	if suspendedOnDrift(ctx, r.Client, r.Recorder, log, &rs, runnerReplicaSetConditions, []string{"replicas", "effectiveTime", "capacityDistribution", "labelResourceProfiles", "profileReplicas", "quarantine"}) {
+		return ctrl.Result{}, nil
+	}
+
 	if rs.ObjectMeta.Labels == nil {
 		rs.ObjectMeta.Labels = map[string]string{}
 	}
@@ -81,6 +96,10 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		template := rs.Spec.DeepCopy()
 		template.Replicas = nil
 		template.EffectiveTime = nil
+		template.CapacityDistribution = nil
+		template.LabelResourceProfiles = nil
+		template.ProfileReplicas = nil
+		template.Quarantine = nil
 		templateHash := ComputeHash(template)
 
 		log.Info("Using auto-generated template hash", "value", templateHash)
@@ -122,19 +141,72 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
+	create := func() client.Object { return desired.DeepCopy() }
+
+	var capacity *capacityplacement.Placement
+	var capacityStatus *v1alpha1.CapacityDistributionStatus
+
+	if rs.Spec.CapacityDistribution != nil {
+		now := time.Now()
+
+		capacity = newCapacityPlacement(&rs, runnerList.Items, replicas, now)
+
+		fellBack, err := r.fallBackToOnDemand(ctx, log, &rs, capacity, runnerList.Items, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		capacityStatus = capacityDistributionStatus(capacity)
+
+		if fellBack {
+			// Remember when the spot capacity became unavailable before recreating the runners on the on-demand capacity
+			updated := rs.DeepCopy()
+			updated.Status.CapacityDistribution = capacityStatus
+
+			if err := r.Status().Patch(ctx, updated, client.MergeFrom(&rs)); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		create = func() client.Object {
+			runner := desired.DeepCopy()
+			applyCapacityType(runner, rs.Spec.CapacityDistribution, capacity.Next())
+			return runner
+		}
+	}
+
+	if len(rs.Spec.ProfileReplicas) > 0 {
+		profiles := newProfilePlacement(&rs, runnerList.Items)
+		createWithoutProfile := create
+
+		create = func() client.Object {
+			runner := createWithoutProfile().(*v1alpha1.Runner)
+			if name := profiles.next(); name == v1alpha1.QuarantineProfileName {
+				applyQuarantine(runner, rs.Spec.Quarantine)
+			} else if name != "" {
+				applyLabelResourceProfile(runner, name, rs.Spec.LabelResourceProfiles[name])
+			}
+			return runner
+		}
+	}
+
 	var live []client.Object
 	for _, r := range runnerList.Items {
 		r := r
 		live = append(live, &r)
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, replicas, func() client.Object { return desired.DeepCopy() }, ephemeral, live)
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, replicas, &desired, create, ephemeral, live)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
 
 	var (
-		status v1alpha1.RunnerReplicaSetStatus
+		status = v1alpha1.RunnerReplicaSetStatus{
+			Conditions: append([]metav1.Condition{}, rs.Status.Conditions...),
+		}
 
 		current, available, ready int
 	)
@@ -148,6 +220,26 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	status.Replicas = &current
 	status.AvailableReplicas = &available
 	status.ReadyReplicas = &ready
+	status.CapacityDistribution = capacityStatus
+	status.ObservedGeneration = rs.Generation
+	setCondition(&status.Conditions, rs.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
+
+	var result ctrl.Result
+
+	if capacity != nil {
+		if available == replicas && current == replicas {
+			if err := r.rebalanceCapacity(ctx, log, capacity, runnerList.Items); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		// The pods pending on the spot capacity are checked for the fallback, and the spot capacity is retried after the interval
+		if since := capacity.SpotUnavailableSince; since != nil {
+			result.RequeueAfter = time.Until(since.Add(capacity.SpotRetryInterval()))
+		} else if capacity.SpotFirst() {
+			result.RequeueAfter = capacity.FallbackAfter()
+		}
+	}
 
 	if !reflect.DeepEqual(rs.Status, status) {
 		updated := rs.DeepCopy()
@@ -161,7 +253,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func (r *RunnerReplicaSetReconciler) newRunner(rs v1alpha1.RunnerReplicaSet) (v1alpha1.Runner, error) {
@@ -201,5 +293,9 @@ func (r *RunnerReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.RunnerReplicaSet{}).
 		Owns(&v1alpha1.Runner{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Observe(name, "RunnerReplicaSet", r))
+}
+
+func runnerReplicaSetConditions(rs *v1alpha1.RunnerReplicaSet) *[]metav1.Condition {
+	return &rs.Status.Conditions
 }
@@ -0,0 +1,265 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// cacheVolumeName is the name of the pod volume of the cache volume claim attached to a runner pod of a RunnerSet.
+	cacheVolumeName = "cache"
+
+	// labelKeyCacheKey is the label on the cache volume claims that contains the hash of the repository and labels
+	// of the runners the caches were built by.
+	labelKeyCacheKey = "runner-cache-key"
+
+	// annotationKeyCacheLastUsedTimestamp is the annotation on the cache volume claims that contains when the cache volume was
+	// last seen attached to a runner pod.
+	annotationKeyCacheLastUsedTimestamp = annotationKeyPrefix + "cache-last-used-timestamp"
+
+	defaultCacheVolumeTTL = 7 * 24 * time.Hour
+
+	// cacheLastUsedUpdateInterval limits how often the last used timestamp of the cache volumes in use are updated.
+	cacheLastUsedUpdateInterval = 5 * time.Minute
+
+	// cacheVolumeAttachGracePeriod is how long a cache volume isn't handed out again after it was attached to a statefulset,
+	// in case the statefulset isn't in the informer cache yet.
+	cacheVolumeAttachGracePeriod = time.Minute
+)
+
+// cacheKey returns the key of the cache volumes shared among the runners of a RunnerSet for the same repository and labels.
+func cacheKey(runnerSet *v1alpha1.RunnerSet) string {
+	labels := append([]string{}, runnerSet.Spec.Labels...)
+	sort.Strings(labels)
+
+	return ComputeHash(struct {
+		Enterprise   string
+		Organization string
+		Repository   string
+		Group        string
+		Labels       []string
+	}{
+		Enterprise:   runnerSet.Spec.Enterprise,
+		Organization: runnerSet.Spec.Organization,
+		Repository:   runnerSet.Spec.Repository,
+		Group:        runnerSet.Spec.Group,
+		Labels:       labels,
+	})
+}
+
+// cacheVolumeClaimName returns the name of the cache volume claim attached to the pod template of the statefulset, if any.
+func cacheVolumeClaimName(sts *appsv1.StatefulSet) string {
+	for _, v := range sts.Spec.Template.Spec.Volumes {
+		if v.Name == cacheVolumeName && v.PersistentVolumeClaim != nil {
+			return v.PersistentVolumeClaim.ClaimName
+		}
+	}
+
+	return ""
+}
+
+// cacheVolumePool hands out the cache volumes of a RunnerSet to the statefulsets being created.
+type cacheVolumePool struct {
+	client.Client
+	log    logr.Logger
+	scheme *runtime.Scheme
+
+	runnerSet *v1alpha1.RunnerSet
+	key       string
+	now       time.Time
+
+	// idle is the cache volume claims not attached to any runner pod, the most recently used first.
+	idle []corev1.PersistentVolumeClaim
+}
+
+// attach adds the most recently used idle cache volume, or a new one when there's none, to the pod template of the statefulset.
+func (p *cacheVolumePool) attach(ctx context.Context, sts *appsv1.StatefulSet) {
+	var claimName string
+
+	if len(p.idle) > 0 {
+		pvc := p.idle[0]
+		p.idle = p.idle[1:]
+
+		claimName = pvc.Name
+
+		updated := pvc.DeepCopy()
+		updated.Annotations = CloneAndAddLabel(updated.Annotations, annotationKeyCacheLastUsedTimestamp, p.now.Format(time.RFC3339))
+		if err := p.Patch(ctx, updated, client.MergeFrom(&pvc)); err != nil {
+			p.log.Error(err, "Failed to update the last used timestamp of the cache volume", "pvc", claimName)
+		}
+
+		p.log.V(1).Info("Reusing cache volume", "pvc", claimName)
+	} else {
+		claimName = p.runnerSet.Name + "-cache-" + rand.String(5)
+
+		// Failures are retried by syncCacheVolumes in the next reconciliation
+		if err := p.create(ctx, claimName); err != nil && !kerrors.IsAlreadyExists(err) {
+			p.log.Error(err, "Failed to create cache volume", "pvc", claimName)
+		}
+	}
+
+	sts.Spec.Template.Spec.Volumes = append(sts.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name: cacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		},
+	})
+}
+
+func (p *cacheVolumePool) create(ctx context.Context, name string) error {
+	t := p.runnerSet.Spec.CacheVolumeClaimTemplate
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: p.runnerSet.Namespace,
+			Labels: map[string]string{
+				LabelKeyRunnerSetName: p.runnerSet.Name,
+				labelKeyCacheKey:      p.key,
+			},
+			Annotations: map[string]string{
+				annotationKeyCacheLastUsedTimestamp: p.now.Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      t.AccessModes,
+			StorageClassName: &t.StorageClassName,
+			Resources:        t.Resources,
+		},
+	}
+
+	// So that the cache volumes are garbage-collected along with the RunnerSet
+	if err := ctrl.SetControllerReference(p.runnerSet, pvc, p.scheme); err != nil {
+		return err
+	}
+
+	if err := p.Create(ctx, pvc); err != nil {
+		return err
+	}
+
+	p.log.Info("Created cache volume", "pvc", name)
+
+	return nil
+}
+
+// syncCacheVolumes creates the cache volumes attached to the statefulsets but missing, keeps the last used timestamps of the ones in use up to date,
+// and deletes the ones left idle for longer than the TTL.
+// It returns the pool of the idle cache volumes to be attached to new statefulsets, and how long to wait until the next idle cache volume expires.
+func syncCacheVolumes(ctx context.Context, c client.Client, log logr.Logger, scheme *runtime.Scheme, runnerSet *v1alpha1.RunnerSet, statefulsets []appsv1.StatefulSet) (*cacheVolumePool, time.Duration, error) {
+	t := runnerSet.Spec.CacheVolumeClaimTemplate
+	if t == nil {
+		return nil, 0, nil
+	}
+
+	ttl := defaultCacheVolumeTTL
+	if t.TTL != nil {
+		ttl = t.TTL.Duration
+	}
+
+	pool := &cacheVolumePool{
+		Client:    c,
+		log:       log.WithValues("runnerset", runnerSet.Name),
+		scheme:    scheme,
+		runnerSet: runnerSet,
+		key:       cacheKey(runnerSet),
+		now:       time.Now(),
+	}
+
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := c.List(ctx, &pvcList, client.InNamespace(runnerSet.Namespace), client.MatchingLabels{LabelKeyRunnerSetName: runnerSet.Name}); err != nil {
+		return nil, 0, err
+	}
+
+	existing := map[string]*corev1.PersistentVolumeClaim{}
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if _, ok := pvc.Labels[labelKeyCacheKey]; !ok {
+			continue
+		}
+		existing[pvc.Name] = pvc
+	}
+
+	inUse := map[string]bool{}
+	for i := range statefulsets {
+		name := cacheVolumeClaimName(&statefulsets[i])
+		if name == "" {
+			continue
+		}
+
+		inUse[name] = true
+
+		if _, ok := existing[name]; !ok {
+			if err := pool.create(ctx, name); err != nil && !kerrors.IsAlreadyExists(err) {
+				return nil, 0, err
+			}
+		}
+	}
+
+	var requeueAfter time.Duration
+
+	lastUsed := map[string]time.Time{}
+
+	for name, pvc := range existing {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+
+		used := pvc.CreationTimestamp.Time
+		if v, ok := pvc.Annotations[annotationKeyCacheLastUsedTimestamp]; ok {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				used = t
+			}
+		}
+
+		if inUse[name] {
+			if pool.now.Sub(used) >= cacheLastUsedUpdateInterval {
+				updated := pvc.DeepCopy()
+				updated.Annotations = CloneAndAddLabel(updated.Annotations, annotationKeyCacheLastUsedTimestamp, pool.now.Format(time.RFC3339))
+				if err := c.Patch(ctx, updated, client.MergeFrom(pvc)); err != nil {
+					return nil, 0, err
+				}
+			}
+			continue
+		}
+
+		if expiry := used.Add(ttl).Sub(pool.now); expiry <= 0 {
+			if err := c.Delete(ctx, pvc); err != nil && !kerrors.IsNotFound(err) {
+				return nil, 0, err
+			}
+
+			log.Info("Deleted expired cache volume", "pvc", name, "lastUsed", used)
+
+			continue
+		} else if requeueAfter == 0 || expiry < requeueAfter {
+			requeueAfter = expiry
+		}
+
+		// The caches built by the runners for other repositories or labels are left to expire
+		if pvc.Labels[labelKeyCacheKey] != pool.key || pool.now.Sub(used) < cacheVolumeAttachGracePeriod {
+			continue
+		}
+
+		lastUsed[name] = used
+		pool.idle = append(pool.idle, *pvc)
+	}
+
+	sort.SliceStable(pool.idle, func(i, j int) bool {
+		return lastUsed[pool.idle[i].Name].After(lastUsed[pool.idle[j].Name])
+	})
+
+	return pool, requeueAfter, nil
+}
@@ -0,0 +1,139 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncCacheVolumes(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	runnerSet := &v1alpha1.RunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: v1alpha1.RunnerSetSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{
+				Repository: "test/valid",
+				Labels:     []string{"b", "a"},
+			},
+			CacheVolumeClaimTemplate: &v1alpha1.CacheVolumeClaimTemplate{
+				WorkVolumeClaimTemplate: v1alpha1.WorkVolumeClaimTemplate{
+					StorageClassName: "standard",
+					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				},
+				MountPath: "/cache",
+				TTL:       &metav1.Duration{Duration: time.Hour},
+			},
+		},
+	}
+
+	key := cacheKey(runnerSet)
+
+	other := runnerSet.DeepCopy()
+	other.Spec.Labels = []string{"a", "b"}
+	require.Equal(t, key, cacheKey(other), "the cache key must not depend on the order of the labels")
+
+	other.Spec.Repository = "test/other"
+	require.NotEqual(t, key, cacheKey(other))
+
+	now := time.Now()
+
+	pvc := func(name, key string, lastUsed time.Time) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					LabelKeyRunnerSetName: runnerSet.Name,
+					labelKeyCacheKey:      key,
+				},
+				Annotations: map[string]string{
+					annotationKeyCacheLastUsedTimestamp: lastUsed.Format(time.RFC3339),
+				},
+			},
+		}
+	}
+
+	sts := func(claimName string) appsv1.StatefulSet {
+		var s appsv1.StatefulSet
+		s.Spec.Template.Spec.Volumes = []corev1.Volume{{
+			Name: cacheVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		}}
+		return s
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		pvc("in-use", key, now.Add(-30*time.Minute)),
+		pvc("idle-old", key, now.Add(-40*time.Minute)),
+		pvc("idle-recent", key, now.Add(-10*time.Minute)),
+		pvc("just-attached", key, now.Add(-10*time.Second)),
+		pvc("other-key", "other", now.Add(-20*time.Minute)),
+		pvc("expired", key, now.Add(-2*time.Hour)),
+	).Build()
+
+	pool, requeueAfter, err := syncCacheVolumes(ctx, c, logr.Discard(), sc, runnerSet, []appsv1.StatefulSet{sts("in-use"), sts("missing")})
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	// idle-old expires first
+	require.InDelta(t, (20 * time.Minute).Seconds(), requeueAfter.Seconds(), 5)
+
+	var expired corev1.PersistentVolumeClaim
+	err = c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "expired"}, &expired)
+	require.True(t, kerrors.IsNotFound(err), "expected the expired cache volume to be deleted, got %v", err)
+
+	var missing corev1.PersistentVolumeClaim
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "missing"}, &missing))
+	require.Equal(t, key, missing.Labels[labelKeyCacheKey])
+	require.Equal(t, "standard", *missing.Spec.StorageClassName)
+	require.Len(t, missing.OwnerReferences, 1)
+
+	var inUse corev1.PersistentVolumeClaim
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "in-use"}, &inUse))
+	lastUsed, err := time.Parse(time.RFC3339, inUse.Annotations[annotationKeyCacheLastUsedTimestamp])
+	require.NoError(t, err)
+	require.WithinDuration(t, now, lastUsed, 5*time.Second)
+
+	var idle []string
+	for _, p := range pool.idle {
+		idle = append(idle, p.Name)
+	}
+	require.Equal(t, []string{"idle-recent", "idle-old"}, idle)
+
+	// The most recently used cache volume is attached first, then a new one once the pool runs out of idle ones
+	var claimNames []string
+	for i := 0; i < 3; i++ {
+		var s appsv1.StatefulSet
+		pool.attach(ctx, &s)
+		claimNames = append(claimNames, cacheVolumeClaimName(&s))
+	}
+	require.Equal(t, "idle-recent", claimNames[0])
+	require.Equal(t, "idle-old", claimNames[1])
+	require.Regexp(t, "^example-cache-", claimNames[2])
+
+	var created corev1.PersistentVolumeClaim
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: claimNames[2]}, &created))
+}
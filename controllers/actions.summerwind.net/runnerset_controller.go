@@ -33,6 +33,8 @@ import (
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	"github.com/actions/actions-runner-controller/pkg/reconcilemetrics"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
 	"github.com/go-logr/logr"
 )
 
@@ -49,6 +51,11 @@ type RunnerSetReconciler struct {
 	GitHubClient       *MultiGitHubClient
 
 	RunnerPodDefaults RunnerPodDefaults
+
+	// Sharder skips the resources claimed by the other shards. Nil when sharding is disabled.
+	Sharder *sharding.Sharder
+
+	runnerVersions runnerVersionCache
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnersets,verbs=get;list;watch;create;update;patch;delete
@@ -77,6 +84,10 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	if !r.Sharder.Owns(runnerSet.Namespace, sharding.Owner(runnerSet.Spec.Enterprise, runnerSet.Spec.Organization, runnerSet.Spec.Repository)) {
+		return ctrl.Result{}, nil
+	}
+
 	if !runnerSet.ObjectMeta.DeletionTimestamp.IsZero() {
 		r.GitHubClient.DeinitForRunnerSet(runnerSet)
 
@@ -85,6 +96,11 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	metrics.SetRunnerSet(*runnerSet)
 
+	// The replicas and the effective time are set by HRA
+	if suspendedOnDrift(ctx, r.Client, r.Recorder, log, runnerSet, runnerSetConditions, []string{"replicas", "effectiveTime"}) {
+		return ctrl.Result{}, nil
+	}
+
 	var statefulsetList appsv1.StatefulSetList
 	if err := r.List(ctx, &statefulsetList, client.InNamespace(req.Namespace), client.MatchingFields{runnerSetOwnerKey: req.Name}); err != nil {
 		return ctrl.Result{}, err
@@ -97,7 +113,7 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	desiredStatefulSet, err := r.newStatefulSet(ctx, runnerSet)
+	desiredStatefulSet, err := r.newStatefulSet(ctx, log, runnerSet)
 	if err != nil {
 		r.Recorder.Event(runnerSet, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
@@ -136,11 +152,32 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return *res, nil
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, newDesiredReplicas, func() client.Object { return create.DeepCopy() }, ephemeral, owners)
+	cacheVolumes, cacheRequeueAfter, err := syncCacheVolumes(ctx, r.Client, log, r.Scheme, runnerSet, statefulsets)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	newStatefulSet := func() client.Object { return create.DeepCopy() }
+	if cacheVolumes != nil {
+		newStatefulSet = func() client.Object {
+			sts := create.DeepCopy()
+			cacheVolumes.attach(ctx, sts)
+			return sts
+		}
+	}
+
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, newDesiredReplicas, create, newStatefulSet, ephemeral, owners)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
 
+	runnerVersion, _ := getAnnotation(desiredStatefulSet, annotationKeyRunnerVersion)
+
+	upgradeRequeueAfter, err := rollOutRunnerUpgrade(ctx, r.Client, log, runnerSet, runnerVersion, res.currentObjects)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	var statusReplicas, statusReadyReplicas, totalCurrentReplicas, updatedReplicas int
 
 	for _, ss := range res.currentObjects {
@@ -157,6 +194,9 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	status.DesiredReplicas = &newDesiredReplicas
 	status.Replicas = &statusReplicas
 	status.UpdatedReplicas = &updatedReplicas
+	status.RunnerVersion = runnerVersion
+	status.ObservedGeneration = runnerSet.Generation
+	setCondition(&status.Conditions, runnerSet.Generation, v1alpha1.ConditionTypeSynced, true, v1alpha1.ConditionReasonReconciled, "")
 
 	if !reflect.DeepEqual(runnerSet.Status, status) {
 		updated := runnerSet.DeepCopy()
@@ -170,7 +210,13 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// Requeue so that the idle cache volumes are deleted once expired, and the next batch of outdated runners are replaced
+	requeueAfter := cacheRequeueAfter
+	if upgradeRequeueAfter > 0 && (requeueAfter == 0 || upgradeRequeueAfter < requeueAfter) {
+		requeueAfter = upgradeRequeueAfter
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func getRunnerSetSelector(runnerSet *v1alpha1.RunnerSet) *metav1.LabelSelector {
@@ -185,7 +231,7 @@ func getRunnerSetSelector(runnerSet *v1alpha1.RunnerSet) *metav1.LabelSelector {
 var LabelKeyPodMutation = "actions-runner-controller/inject-registration-token"
 var LabelValuePodMutation = "true"
 
-func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, runnerSet *v1alpha1.RunnerSet) (*appsv1.StatefulSet, error) {
+func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, log logr.Logger, runnerSet *v1alpha1.RunnerSet) (*appsv1.StatefulSet, error) {
 	runnerSetWithOverrides := *runnerSet.Spec.DeepCopy()
 
 	runnerSetWithOverrides.Labels = append(runnerSetWithOverrides.Labels, r.CommonRunnerLabels...)
@@ -228,11 +274,41 @@ func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, runnerSet *v1a
 
 	githubBaseURL := ghc.GithubBaseURL
 
+	if err := runnerSet.Spec.ValidateSidecars(); err != nil {
+		return nil, err
+	}
+
 	pod, err := newRunnerPodWithContainerMode(runnerSet.Spec.RunnerConfig.ContainerMode, template, runnerSet.Spec.RunnerConfig, githubBaseURL, r.RunnerPodDefaults)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := validateContainerModePrerequisites(ctx, r.Client, runnerSet.Spec.ContainerMode, &pod); err != nil {
+		return nil, err
+	}
+
+	if c := runnerSet.Spec.CacheVolumeClaimTemplate; c != nil {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+
+		// The volume itself is added per statefulset, as each runner pod gets its own cache volume
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].Name == containerName {
+				pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+					Name:      cacheVolumeName,
+					MountPath: c.MountPath,
+				})
+			}
+		}
+	}
+
+	if runnerSet.Spec.RunnerUpgrade != nil {
+		if err := validateRunnerUpgrade(runnerSet); err != nil {
+			return nil, err
+		}
+	}
+
 	runnerSetWithOverrides.StatefulSetSpec.Template.ObjectMeta = pod.ObjectMeta
 	runnerSetWithOverrides.StatefulSetSpec.Template.Spec = pod.Spec
 	// NOTE: Seems like the only supported restart policy for statefulset is "Always"?
@@ -267,6 +343,16 @@ func (r *RunnerSetReconciler) newStatefulSet(ctx context.Context, runnerSet *v1a
 		Spec: runnerSetWithOverrides.StatefulSetSpec,
 	}
 
+	// Applied after computing the template hash, so that a new version replaces the runners at the pace of rollOutRunnerUpgrade
+	// rather than all at once
+	if runnerSet.Spec.RunnerUpgrade != nil {
+		if release := r.resolveRunnerVersion(ctx, log, ghc, runnerSet); release.Version != "" {
+			if err := applyRunnerUpgrade(&rs, release); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if err := ctrl.SetControllerReference(runnerSet, &rs, r.Scheme); err != nil {
 		return &rs, err
 	}
@@ -302,5 +388,5 @@ func (r *RunnerSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&v1alpha1.RunnerSet{}).
 		Owns(&appsv1.StatefulSet{}).
 		Named(name).
-		Complete(r)
+		Complete(reconcilemetrics.Observe(name, "RunnerSet", r))
 }
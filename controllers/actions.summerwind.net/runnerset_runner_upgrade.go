@@ -0,0 +1,452 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// runnerUpgradeContainerName is the name of the init container that downloads the actions/runner release into the runner assets volume.
+	runnerUpgradeContainerName = "runner-upgrade"
+
+	// runnerAssetsVolumeName is the name of the pod volume the runner container starts the downloaded actions/runner release from.
+	runnerAssetsVolumeName = "runner-assets"
+	runnerAssetsMountPath  = "/runner-assets"
+
+	// annotationKeyRunnerVersion is the annotation on the statefulsets of a RunnerSet that contains the version of actions/runner
+	// the runner pods download on start.
+	annotationKeyRunnerVersion = annotationKeyPrefix + "runner-version"
+
+	// annotationKeyRunnerUpgradeTimestamp is the annotation on a RunnerSet that contains when ARC last started replacing a batch
+	// of the runners of outdated versions.
+	annotationKeyRunnerUpgradeTimestamp = annotationKeyPrefix + "runner-upgrade-timestamp"
+
+	defaultRunnerUpgradeCheckInterval = time.Hour
+	defaultRunnerUpgradeBatchSize     = 1
+	defaultRunnerUpgradeInterval      = 5 * time.Minute
+)
+
+// runnerUpgradeScript copies the runner assets built into the runner image and overwrites them with the actions/runner release of RUNNER_VERSION,
+// so that the runner container starts the release without the image being rebuilt.
+const runnerUpgradeScript = `set -euo pipefail
+
+cp -a "${RUNNER_ASSETS_DIR:-/runnertmp}/." ` + runnerAssetsMountPath + `/
+
+case "$(uname -m)" in
+  x86_64) arch=x64 ;;
+  aarch64) arch=arm64 ;;
+  armv7l) arch=arm ;;
+  *) echo "Unsupported architecture: $(uname -m)" >&2; exit 1 ;;
+esac
+
+tarball="$(mktemp)"
+curl -fsSL -o "${tarball}" "https://github.com/actions/runner/releases/download/v${RUNNER_VERSION}/actions-runner-linux-${arch}-${RUNNER_VERSION}.tar.gz"
+
+# Set when the release is verified, so that only the attested binaries run
+checksum="RUNNER_SHA256_${arch^^}"
+if [ -n "${!checksum:-}" ]; then
+  echo "${!checksum}  ${tarball}" | sha256sum -c -
+fi
+
+tar xzf "${tarball}" -C ` + runnerAssetsMountPath + `
+rm -f "${tarball}"
+`
+
+// defaultRunnerReleaseIdentities are the identities the actions/runner releases are attested by, the release workflow of actions/runner.
+var defaultRunnerReleaseIdentities = []sigstore.Identity{
+	{
+		Issuer:        "https://token.actions.githubusercontent.com",
+		SubjectRegExp: regexp.MustCompile(`^https://github\.com/actions/runner/\.github/workflows/release\.yml@refs/tags/v`),
+	},
+}
+
+// runnerRelease is the actions/runner release the runner pods download, with the SHA-256 checksums of its Linux binaries by the architecture
+// when verified.
+type runnerRelease struct {
+	Version   string
+	Checksums map[string]string
+}
+
+// runnerVersionCache caches the latest versions of actions/runner per GitHub instance,
+// so that the releases aren't looked up on every reconciliation of every RunnerSet.
+type runnerVersionCache struct {
+	mu            sync.Mutex
+	versions      map[string]cachedRunnerVersion
+	verifications map[string]cachedRunnerVerification
+}
+
+type cachedRunnerVersion struct {
+	version   string
+	checkedAt time.Time
+}
+
+type cachedRunnerVerification struct {
+	checksums map[string]string
+	err       error
+	checkedAt time.Time
+}
+
+// verified returns the checksums of the release verified by verify, caching the outcome by the key for checkInterval,
+// so that the attestations aren't looked up on every reconciliation of every RunnerSet.
+func (c *runnerVersionCache) verified(key string, checkInterval time.Duration, verify func() (map[string]string, error)) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.verifications[key]; ok && time.Since(cached.checkedAt) < checkInterval {
+		return cached.checksums, cached.err
+	}
+
+	checksums, err := verify()
+
+	if c.verifications == nil {
+		c.verifications = map[string]cachedRunnerVerification{}
+	}
+
+	c.verifications[key] = cachedRunnerVerification{checksums: checksums, err: err, checkedAt: time.Now()}
+
+	return checksums, err
+}
+
+// latest returns the cached latest version of actions/runner, looking it up again once checkInterval has passed since the last check.
+// The previously cached version is returned along with the error when the lookup fails.
+func (c *runnerVersionCache) latest(ctx context.Context, ghc *github.Client, checkInterval time.Duration) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ghc.GithubBaseURL
+
+	cached, ok := c.versions[key]
+	if ok && time.Since(cached.checkedAt) < checkInterval {
+		return cached.version, nil
+	}
+
+	version, err := ghc.GetLatestRunnerVersion(ctx)
+
+	if c.versions == nil {
+		c.versions = map[string]cachedRunnerVersion{}
+	}
+
+	// Failed lookups are retried after checkInterval too, so that ARC doesn't hammer the API while it's unavailable
+	if err != nil {
+		c.versions[key] = cachedRunnerVersion{version: cached.version, checkedAt: time.Now()}
+
+		return cached.version, err
+	}
+
+	c.versions[key] = cachedRunnerVersion{version: version, checkedAt: time.Now()}
+
+	return version, nil
+}
+
+func validateRunnerUpgrade(runnerSet *v1alpha1.RunnerSet) error {
+	u := runnerSet.Spec.RunnerUpgrade
+
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	if runnerSet.Spec.OS == "windows" {
+		return errors.New("runnerUpgrade is not supported for windows runners")
+	}
+
+	return nil
+}
+
+// resolveRunnerVersion returns the release of actions/runner the new runner pods of the RunnerSet download on start.
+// It returns an empty version when the latest version is yet to be known, in which case the runner pods run the version built into the runner image.
+// When runnerUpgrade.verification is set, a release failing the verification is refused with the Verified condition,
+// and the release verified last, if any, is returned instead.
+func (r *RunnerSetReconciler) resolveRunnerVersion(ctx context.Context, log logr.Logger, ghc *github.Client, runnerSet *v1alpha1.RunnerSet) runnerRelease {
+	u := runnerSet.Spec.RunnerUpgrade
+
+	checkInterval := defaultRunnerUpgradeCheckInterval
+	if u.CheckInterval != nil {
+		checkInterval = u.CheckInterval.Duration
+	}
+
+	version := u.Version
+
+	if version == "" || version == "latest" {
+		var err error
+
+		version, err = r.runnerVersions.latest(ctx, ghc, checkInterval)
+		if err != nil {
+			log.Error(err, "Failed to check for the latest version of actions/runner", "cachedVersion", version)
+		}
+	}
+
+	if u.Verification == nil || version == "" {
+		return runnerRelease{Version: version}
+	}
+
+	checksums, err := r.verifyRunnerRelease(ctx, ghc, runnerSet, version, checkInterval)
+	if err == nil {
+		r.setRunnerSetVerifiedCondition(ctx, log, runnerSet, true, v1alpha1.ConditionReasonVerificationSucceeded, fmt.Sprintf("Verified actions/runner %s", version))
+
+		return runnerRelease{Version: version, Checksums: checksums}
+	}
+
+	log.Error(err, "Refused the actions/runner release that failed the verification", "runnerVersion", version, "verifiedVersion", runnerSet.Status.RunnerVersion)
+	r.Recorder.Event(runnerSet, corev1.EventTypeWarning, "RunnerReleaseVerificationFailed", err.Error())
+	r.setRunnerSetVerifiedCondition(ctx, log, runnerSet, false, v1alpha1.ConditionReasonVerificationFailed, err.Error())
+
+	// The runners stay on the release verified last
+	if prev := runnerSet.Status.RunnerVersion; prev != "" && prev != version {
+		if checksums, err := r.verifyRunnerRelease(ctx, ghc, runnerSet, prev, checkInterval); err == nil {
+			return runnerRelease{Version: prev, Checksums: checksums}
+		}
+	}
+
+	return runnerRelease{}
+}
+
+// verifyRunnerRelease verifies that the Linux binaries of the actions/runner release of the version have the GitHub artifact attestations
+// made by the identities of the verification, and returns their SHA-256 checksums by the architecture.
+func (r *RunnerSetReconciler) verifyRunnerRelease(ctx context.Context, ghc *github.Client, runnerSet *v1alpha1.RunnerSet, version string, checkInterval time.Duration) (map[string]string, error) {
+	v := runnerSet.Spec.RunnerUpgrade.Verification
+
+	key := strings.Join([]string{ghc.GithubBaseURL, runnerSet.Namespace, version, ComputeHash(v)}, "/")
+
+	return r.runnerVersions.verified(key, checkInterval, func() (map[string]string, error) {
+		verifier, err := newSigstoreVerifier(ctx, r.Client, runnerSet.Namespace, v, defaultRunnerReleaseIdentities)
+		if err != nil {
+			return nil, err
+		}
+
+		checksums, err := ghc.GetRunnerReleaseChecksums(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, arch := range []string{"x64", "arm64", "arm"} {
+			sum, ok := checksums[arch]
+			if !ok {
+				continue
+			}
+
+			digest := "sha256:" + sum
+
+			bundles, err := ghc.ListAttestations(ctx, "actions", "runner", digest)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := verifyAttestationBundles(verifier, bundles, digest); err != nil {
+				return nil, fmt.Errorf("actions-runner-linux-%s-%s.tar.gz: %w", arch, version, err)
+			}
+		}
+
+		return checksums, nil
+	})
+}
+
+// verifyAttestationBundles verifies that any of the Sigstore bundles of the attestations is of a statement about the artifact of the digest,
+// attested by the identities of the verifier.
+func verifyAttestationBundles(verifier *sigstore.Verifier, bundles []json.RawMessage, digest string) error {
+	var errs []error
+
+	for _, b := range bundles {
+		statement, err := verifier.VerifyBundle(b)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !statement.HasSubject(digest) {
+			errs = append(errs, fmt.Errorf("attestation of %s is about another artifact", statement.PredicateType))
+			continue
+		}
+
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("no attestation of %s found", digest)
+	}
+
+	return fmt.Errorf("no attestation of %s is verified: %w", digest, errors.Join(errs...))
+}
+
+func (r *RunnerSetReconciler) setRunnerSetVerifiedCondition(ctx context.Context, log logr.Logger, runnerSet *v1alpha1.RunnerSet, ok bool, reason, message string) {
+	set := func(conditions *[]metav1.Condition) {
+		setCondition(conditions, runnerSet.Generation, v1alpha1.ConditionTypeVerified, ok, reason, message)
+	}
+
+	if err := patchConditions(ctx, r.Client, runnerSet, runnerSetConditions, set); err != nil {
+		log.Error(err, "Failed to update runnerset status conditions")
+		return
+	}
+
+	// The status is patched again at the end of the reconciliation, with the conditions in it
+	set(&runnerSet.Status.Conditions)
+}
+
+func runnerSetConditions(runnerSet *v1alpha1.RunnerSet) *[]metav1.Condition {
+	return &runnerSet.Status.Conditions
+}
+
+// applyRunnerUpgrade makes the runner pods of the statefulset download the actions/runner release of the version
+// via an init container, and start the runner from it.
+func applyRunnerUpgrade(sts *appsv1.StatefulSet, release runnerRelease) error {
+	spec := &sts.Spec.Template.Spec
+
+	var runner *corev1.Container
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == containerName {
+			runner = &spec.Containers[i]
+		}
+	}
+
+	if runner == nil {
+		return fmt.Errorf("runner container %q is missing in the pod template", containerName)
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      runnerAssetsVolumeName,
+		MountPath: runnerAssetsMountPath,
+	}
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: runnerAssetsVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+
+	env := []corev1.EnvVar{
+		{
+			Name:  "RUNNER_VERSION",
+			Value: release.Version,
+		},
+	}
+
+	for _, arch := range []string{"x64", "arm64", "arm"} {
+		if sum, ok := release.Checksums[arch]; ok {
+			env = append(env, corev1.EnvVar{Name: "RUNNER_SHA256_" + strings.ToUpper(arch), Value: sum})
+		}
+	}
+
+	// Runs with the runner image so that the assets built into the image, like the hooks, are carried over
+	spec.InitContainers = append([]corev1.Container{{
+		Name:            runnerUpgradeContainerName,
+		Image:           runner.Image,
+		ImagePullPolicy: runner.ImagePullPolicy,
+		SecurityContext: runner.SecurityContext,
+		Command:         []string{"bash", "-c", runnerUpgradeScript},
+		Env:             env,
+		VolumeMounts:    []corev1.VolumeMount{mount},
+	}}, spec.InitContainers...)
+
+	runner.Env = append(runner.Env, corev1.EnvVar{
+		Name:  "RUNNER_ASSETS_DIR",
+		Value: runnerAssetsMountPath,
+	})
+	runner.VolumeMounts = append(runner.VolumeMounts, mount)
+
+	sts.Annotations = CloneAndAddLabel(sts.Annotations, annotationKeyRunnerVersion, release.Version)
+
+	return nil
+}
+
+// rollOutRunnerUpgrade replaces the runners of versions other than the desired one, batchSize statefulsets every interval, oldest first.
+// The outdated statefulsets go through the same unregistration process as the redundant ones do on scale down,
+// and syncRunnerPodsOwners creates the replacements of the desired version once they are gone.
+// It returns how long to wait until the next batch.
+func rollOutRunnerUpgrade(ctx context.Context, c client.Client, log logr.Logger, runnerSet *v1alpha1.RunnerSet, version string, currentObjects []*podsForOwner) (time.Duration, error) {
+	u := runnerSet.Spec.RunnerUpgrade
+	if u == nil || version == "" {
+		return 0, nil
+	}
+
+	var outdated []*podsForOwner
+
+	for _, ss := range currentObjects {
+		if !ss.owner.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		if _, ok := getAnnotation(ss.owner, AnnotationKeyUnregistrationRequestTimestamp); ok {
+			continue
+		}
+
+		if v, _ := getAnnotation(ss.owner, annotationKeyRunnerVersion); v == version {
+			continue
+		}
+
+		outdated = append(outdated, ss)
+	}
+
+	if len(outdated) == 0 {
+		return 0, nil
+	}
+
+	batchSize := defaultRunnerUpgradeBatchSize
+	if u.BatchSize != nil {
+		batchSize = *u.BatchSize
+	}
+
+	interval := defaultRunnerUpgradeInterval
+	if u.Interval != nil {
+		interval = u.Interval.Duration
+	}
+
+	now := time.Now()
+
+	if v, ok := getAnnotation(runnerSet, annotationKeyRunnerUpgradeTimestamp); ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			if wait := t.Add(interval).Sub(now); wait > 0 {
+				log.V(2).Info("Waiting for the interval to replace the next batch of outdated runners", "outdated", len(outdated), "wait", wait)
+
+				return wait, nil
+			}
+		}
+	}
+
+	// currentObjects is sorted by creation timestamp, oldest first
+	if len(outdated) > batchSize {
+		outdated = outdated[:batchSize]
+	}
+
+	var names []string
+
+	for _, ss := range outdated {
+		for _, po := range ss.pods {
+			if _, err := annotatePodOnce(ctx, c, log, &po, AnnotationKeyUnregistrationRequestTimestamp, now.Format(time.RFC3339)); err != nil {
+				return 0, err
+			}
+		}
+
+		updated := ss.owner.withAnnotation(AnnotationKeyUnregistrationRequestTimestamp, now.Format(time.RFC3339))
+		if err := c.Patch(ctx, updated, client.MergeFrom(ss.owner)); err != nil {
+			log.Error(err, fmt.Sprintf("Failed to patch owner to have %s annotation", AnnotationKeyUnregistrationRequestTimestamp))
+			return 0, err
+		}
+
+		names = append(names, ss.owner.GetName())
+	}
+
+	updated := runnerSet.DeepCopy()
+	setAnnotation(&updated.ObjectMeta, annotationKeyRunnerUpgradeTimestamp, now.Format(time.RFC3339))
+	if err := c.Patch(ctx, updated, client.MergeFrom(runnerSet)); err != nil {
+		return 0, err
+	}
+
+	log.Info("Replacing outdated runners", "runnerVersion", version, "statefulsets", names)
+
+	return interval, nil
+}
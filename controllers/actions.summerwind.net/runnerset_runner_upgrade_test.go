@@ -0,0 +1,261 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	sigstorefake "github.com/actions/actions-runner-controller/pkg/sigstore/fake"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyRunnerUpgrade(t *testing.T) {
+	var sts appsv1.StatefulSet
+	sts.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+	sts.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "docker"},
+		{Name: containerName, Image: "summerwind/actions-runner:latest"},
+	}
+
+	require.NoError(t, applyRunnerUpgrade(&sts, runnerRelease{Version: "2.311.0"}))
+
+	spec := sts.Spec.Template.Spec
+
+	require.Len(t, spec.InitContainers, 2)
+	require.Equal(t, runnerUpgradeContainerName, spec.InitContainers[0].Name, "the runner assets must be ready before other init containers run")
+	require.Equal(t, "summerwind/actions-runner:latest", spec.InitContainers[0].Image)
+	require.Contains(t, spec.InitContainers[0].Env, corev1.EnvVar{Name: "RUNNER_VERSION", Value: "2.311.0"})
+
+	require.Contains(t, spec.Containers[1].Env, corev1.EnvVar{Name: "RUNNER_ASSETS_DIR", Value: runnerAssetsMountPath})
+	require.Contains(t, spec.Containers[1].VolumeMounts, corev1.VolumeMount{Name: runnerAssetsVolumeName, MountPath: runnerAssetsMountPath})
+	require.Empty(t, spec.Containers[0].VolumeMounts)
+
+	require.Len(t, spec.Volumes, 1)
+	require.NotNil(t, spec.Volumes[0].EmptyDir)
+
+	require.Equal(t, "2.311.0", sts.Annotations[annotationKeyRunnerVersion])
+
+	// The verified checksums are passed to be checked against the downloaded binaries
+	var verified appsv1.StatefulSet
+	verified.Spec.Template.Spec.Containers = []corev1.Container{{Name: containerName}}
+
+	require.NoError(t, applyRunnerUpgrade(&verified, runnerRelease{Version: "2.311.0", Checksums: map[string]string{"x64": "abc", "arm64": "def"}}))
+	require.Equal(t, []corev1.EnvVar{
+		{Name: "RUNNER_VERSION", Value: "2.311.0"},
+		{Name: "RUNNER_SHA256_X64", Value: "abc"},
+		{Name: "RUNNER_SHA256_ARM64", Value: "def"},
+	}, verified.Spec.Template.Spec.InitContainers[0].Env)
+}
+
+func TestResolveRunnerVersionVerification(t *testing.T) {
+	ctx := context.Background()
+
+	const issuer = "https://token.actions.githubusercontent.com"
+
+	s, err := sigstorefake.New()
+	require.NoError(t, err)
+
+	release, err := s.NewSigner(issuer, "https://github.com/actions/runner/.github/workflows/release.yml@refs/tags/v2.310.0")
+	require.NoError(t, err)
+	stranger, err := s.NewSigner(issuer, "https://github.com/stranger/runner/.github/workflows/release.yml@refs/tags/v2.311.0")
+	require.NoError(t, err)
+
+	checksums := map[string]string{
+		"2.310.0": "1111111111111111111111111111111111111111111111111111111111111111",
+		"2.311.0": "2222222222222222222222222222222222222222222222222222222222222222",
+	}
+
+	attestations := map[string][]byte{}
+	for version, signer := range map[string]*sigstorefake.Signer{"2.310.0": release, "2.311.0": stranger} {
+		bundle, err := signer.Bundle("https://slsa.dev/provenance/v1", "sha256:"+checksums[version])
+		require.NoError(t, err)
+		attestations["sha256:"+checksums[version]] = bundle
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/actions/runner/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v2.311.0"}`)
+	})
+	mux.HandleFunc("/repos/actions/runner/releases/tags/", func(w http.ResponseWriter, r *http.Request) {
+		version := strings.TrimPrefix(r.URL.Path, "/repos/actions/runner/releases/tags/v")
+		body := fmt.Sprintf("<!-- BEGIN SHA linux-x64 -->%s<!-- END SHA linux-x64 -->", checksums[version])
+		_ = json.NewEncoder(w).Encode(map[string]string{"tag_name": "v" + version, "body": body})
+	})
+	mux.HandleFunc("/repos/actions/runner/attestations/", func(w http.ResponseWriter, r *http.Request) {
+		digest := strings.TrimPrefix(r.URL.Path, "/repos/actions/runner/attestations/")
+		fmt.Fprintf(w, `{"attestations":[{"bundle":%s}]}`, attestations[digest])
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	runnerSet := &v1alpha1.RunnerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"},
+		Spec: v1alpha1.RunnerSetSpec{
+			RunnerUpgrade: &v1alpha1.RunnerUpgrade{
+				Verification: &v1alpha1.SigstoreVerification{
+					TrustedRootConfigMapRef: v1alpha1.ConfigMapReference{Name: "sigstore"},
+				},
+			},
+		},
+		Status: v1alpha1.RunnerSetStatus{RunnerVersion: "2.310.0"},
+	}
+
+	trustedRoot := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sigstore", Namespace: "default"},
+		Data: map[string]string{
+			sigstoreFulcioCertificatesKey: string(s.FulcioCertificates()),
+			sigstoreRekorPublicKeysKey:    string(s.RekorPublicKeys()),
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &RunnerSetReconciler{
+		Client:   clientfake.NewClientBuilder().WithScheme(sc).WithObjects(runnerSet, trustedRoot).WithStatusSubresource(runnerSet).Build(),
+		Recorder: recorder,
+	}
+
+	// The latest release attested by another identity is refused, and the runners stay on the release verified last
+	got := r.resolveRunnerVersion(ctx, logr.Discard(), newGithubClient(server), runnerSet)
+	require.Equal(t, runnerRelease{Version: "2.310.0", Checksums: map[string]string{"x64": checksums["2.310.0"]}}, got)
+	require.Contains(t, <-recorder.Events, "RunnerReleaseVerificationFailed")
+
+	var updated v1alpha1.RunnerSet
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updated))
+	cond := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ConditionTypeVerified)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Contains(t, cond.Message, "matches none of the identities")
+
+	// The release is verified once attested by the release workflow
+	attestations["sha256:"+checksums["2.311.0"]], err = release.Bundle("https://slsa.dev/provenance/v1", "sha256:"+checksums["2.311.0"])
+	require.NoError(t, err)
+	r.runnerVersions.verifications = nil
+
+	got = r.resolveRunnerVersion(ctx, logr.Discard(), newGithubClient(server), runnerSet)
+	require.Equal(t, "2.311.0", got.Version)
+	require.True(t, meta.IsStatusConditionTrue(runnerSet.Status.Conditions, v1alpha1.ConditionTypeVerified))
+}
+
+func TestRollOutRunnerUpgrade(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+	require.NoError(t, v1alpha1.AddToScheme(sc))
+
+	runnerSet := &v1alpha1.RunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RunnerSetSpec{
+			RunnerUpgrade: &v1alpha1.RunnerUpgrade{
+				BatchSize: intPtr(2),
+				Interval:  &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	}
+
+	now := time.Now()
+
+	newStatefulSet := func(name, version string, created time.Time) *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+		}
+		if version != "" {
+			sts.Annotations = map[string]string{annotationKeyRunnerVersion: version}
+		}
+		return sts
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oldest-0",
+			Namespace: "default",
+		},
+	}
+
+	statefulsets := []*appsv1.StatefulSet{
+		newStatefulSet("oldest", "", now.Add(-3*time.Hour)),
+		newStatefulSet("older", "2.310.0", now.Add(-2*time.Hour)),
+		newStatefulSet("old", "2.310.0", now.Add(-time.Hour)),
+		newStatefulSet("current", "2.311.0", now),
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(runnerSet, pod)
+
+	var currentObjects []*podsForOwner
+	for _, sts := range statefulsets {
+		c = c.WithObjects(sts)
+
+		ss := &podsForOwner{
+			statefulSet: sts,
+			owner:       &ownerStatefulSet{Object: sts, StatefulSet: sts, Log: logr.Discard()},
+			object:      sts,
+		}
+		if sts.Name == "oldest" {
+			ss.pods = []corev1.Pod{*pod}
+		}
+
+		currentObjects = append(currentObjects, ss)
+	}
+
+	cl := c.Build()
+
+	requeueAfter, err := rollOutRunnerUpgrade(ctx, cl, logr.Discard(), runnerSet, "2.311.0", currentObjects)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, requeueAfter)
+
+	// The oldest batch of the outdated statefulsets, including the one that predates the runner upgrade, are unregistered
+	for name, requested := range map[string]bool{"oldest": true, "older": true, "old": false, "current": false} {
+		var sts appsv1.StatefulSet
+		require.NoError(t, cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &sts))
+
+		_, ok := getAnnotation(&sts, AnnotationKeyUnregistrationRequestTimestamp)
+		require.Equal(t, requested, ok, "statefulset %s", name)
+	}
+
+	var updatedPod corev1.Pod
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "oldest-0"}, &updatedPod))
+	_, ok := getAnnotation(&updatedPod, AnnotationKeyUnregistrationRequestTimestamp)
+	require.True(t, ok)
+
+	var updatedRunnerSet v1alpha1.RunnerSet
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updatedRunnerSet))
+	_, ok = getAnnotation(&updatedRunnerSet, annotationKeyRunnerUpgradeTimestamp)
+	require.True(t, ok)
+
+	// The next batch waits for the interval
+	requeueAfter, err = rollOutRunnerUpgrade(ctx, cl, logr.Discard(), &updatedRunnerSet, "2.311.0", currentObjects[2:])
+	require.NoError(t, err)
+	require.InDelta(t, (10 * time.Minute).Seconds(), requeueAfter.Seconds(), 5)
+
+	var old appsv1.StatefulSet
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "old"}, &old))
+	_, ok = getAnnotation(&old, AnnotationKeyUnregistrationRequestTimestamp)
+	require.False(t, ok)
+}
@@ -10,6 +10,7 @@ import (
 type RecurrenceRule struct {
 	Frequency string
 	UntilTime time.Time
+	Weekdays  []time.Weekday
 }
 
 type Period struct {
@@ -32,10 +33,25 @@ func MatchSchedule(now time.Time, startTime, endTime time.Time, recurrenceRule R
 		endTime,
 		recurrenceRule.Frequency,
 		recurrenceRule.UntilTime,
+		recurrenceRule.Weekdays,
 	)
 }
 
-func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Time, frequency string, untilTime time.Time) (*Period, *Period, error) {
+var rruleWeekdays = map[time.Weekday]rrule.Weekday{
+	time.Monday:    rrule.MO,
+	time.Tuesday:   rrule.TU,
+	time.Wednesday: rrule.WE,
+	time.Thursday:  rrule.TH,
+	time.Friday:    rrule.FR,
+	time.Saturday:  rrule.SA,
+	time.Sunday:    rrule.SU,
+}
+
+func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Time, frequency string, untilTime time.Time, weekdays []time.Weekday) (*Period, *Period, error) {
+	if len(weekdays) > 0 && frequency != "Daily" {
+		return nil, nil, fmt.Errorf(`invalid freq %q: weekdays can be specified only with "Daily"`, frequency)
+	}
+
 	var freqValue rrule.Frequency
 
 	var freqDurationDay int
@@ -83,10 +99,24 @@ func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Tim
 		return nil, nil, fmt.Errorf("override's duration %s must be equal to sor shorter than the duration implied by freq %q (%s)", overrideDuration, frequency, freqDuration)
 	}
 
+	var byWeekday []rrule.Weekday
+
+	for _, d := range weekdays {
+		byWeekday = append(byWeekday, rruleWeekdays[d])
+	}
+
+	// A daily recurrence limited to weekdays can skip a few days, like the weekend,
+	// so we look a week ahead for the upcoming recurrence.
+	upcomingUntil := freqDurationLater
+	if len(byWeekday) > 0 {
+		upcomingUntil = now.AddDate(0, 0, 7)
+	}
+
 	rrule, err := rrule.NewRRule(rrule.ROption{
-		Freq:    freqValue,
-		Dtstart: startTime,
-		Until:   untilTime,
+		Freq:      freqValue,
+		Dtstart:   startTime,
+		Until:     untilTime,
+		Byweekday: byWeekday,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -107,7 +137,7 @@ func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Tim
 	}
 
 	oneSecondLater := now.Add(1)
-	upcomingOverrideStarts := rrule.Between(oneSecondLater, freqDurationLater, true)
+	upcomingOverrideStarts := rrule.Between(oneSecondLater, upcomingUntil, true)
 
 	var next *Period
 
@@ -615,3 +615,90 @@ func FuzzMatchSchedule(f *testing.F) {
 		_, _, _ = MatchSchedule(now, start, end, RecurrenceRule{Frequency: freq})
 	})
 }
+
+func TestMatchScheduleInTimeZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A daily 09:00-18:00 override on weekdays in Berlin, which switches to CEST on 2021-03-28
+	start := time.Date(2021, 3, 22, 9, 0, 0, 0, berlin)
+	end := time.Date(2021, 3, 22, 18, 0, 0, 0, berlin)
+	rule := RecurrenceRule{
+		Frequency: "Daily",
+		Weekdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	}
+
+	type testcase struct {
+		now string
+
+		wantActive   string
+		wantUpcoming string
+	}
+
+	check := func(t *testing.T, tc testcase) {
+		t.Helper()
+
+		now, err := time.Parse(time.RFC3339, tc.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		active, upcoming, err := MatchSchedule(now, start, end, rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if active.String() != tc.wantActive {
+			t.Errorf("unexpected active: want %q, got %q", tc.wantActive, active)
+		}
+
+		if upcoming.String() != tc.wantUpcoming {
+			t.Errorf("unexpected upcoming: want %q, got %q", tc.wantUpcoming, upcoming)
+		}
+	}
+
+	t.Run("active before DST", func(t *testing.T) {
+		check(t, testcase{
+			now: "2021-03-26T09:30:00+01:00",
+
+			wantActive:   "2021-03-26T09:00:00+01:00-2021-03-26T18:00:00+01:00",
+			wantUpcoming: "2021-03-29T09:00:00+02:00-2021-03-29T18:00:00+02:00",
+		})
+	})
+
+	t.Run("weekend", func(t *testing.T) {
+		check(t, testcase{
+			now: "2021-03-27T10:00:00+01:00",
+
+			wantActive:   "",
+			wantUpcoming: "2021-03-29T09:00:00+02:00-2021-03-29T18:00:00+02:00",
+		})
+	})
+
+	t.Run("not yet active after DST", func(t *testing.T) {
+		check(t, testcase{
+			now: "2021-03-29T08:30:00+02:00",
+
+			wantActive:   "",
+			wantUpcoming: "2021-03-29T09:00:00+02:00-2021-03-29T18:00:00+02:00",
+		})
+	})
+
+	t.Run("active after DST", func(t *testing.T) {
+		check(t, testcase{
+			now: "2021-03-29T17:30:00+02:00",
+
+			wantActive:   "2021-03-29T09:00:00+02:00-2021-03-29T18:00:00+02:00",
+			wantUpcoming: "2021-03-30T09:00:00+02:00-2021-03-30T18:00:00+02:00",
+		})
+	})
+
+	t.Run("weekdays with non-daily frequency", func(t *testing.T) {
+		_, _, err := MatchSchedule(start, start, end, RecurrenceRule{Frequency: "Weekly", Weekdays: []time.Weekday{time.Monday}})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// The keys of the trusted root in the ConfigMap, named after the targets of the TUF repository of Sigstore.
+	sigstoreFulcioCertificatesKey = "fulcio_v1.crt.pem"
+	sigstoreRekorPublicKeysKey    = "rekor.pub"
+)
+
+// newSigstoreVerifier returns the verifier of the keyless signatures made by the identities of the verification,
+// or the default identities when none is set, against the trusted root in the ConfigMap in the namespace.
+func newSigstoreVerifier(ctx context.Context, c client.Client, namespace string, v *v1alpha1.SigstoreVerification, defaultIdentities []sigstore.Identity) (*sigstore.Verifier, error) {
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: v.TrustedRootConfigMapRef.Name}, &cm); err != nil {
+		return nil, err
+	}
+
+	root, err := sigstore.NewTrustedRoot([]byte(cm.Data[sigstoreFulcioCertificatesKey]), []byte(cm.Data[sigstoreRekorPublicKeysKey]))
+	if err != nil {
+		return nil, fmt.Errorf("configmap %s: %w", cm.Name, err)
+	}
+
+	identities := defaultIdentities
+
+	if len(v.Identities) > 0 {
+		identities = nil
+
+		for _, id := range v.Identities {
+			i := sigstore.Identity{Issuer: id.Issuer, Subject: id.Subject}
+
+			if id.SubjectRegExp != "" {
+				i.SubjectRegExp, err = regexp.Compile(id.SubjectRegExp)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			identities = append(identities, i)
+		}
+	}
+
+	return &sigstore.Verifier{TrustedRoot: root, Identities: identities}, nil
+}
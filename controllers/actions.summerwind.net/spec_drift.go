@@ -0,0 +1,68 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/specdrift"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// specDrift tells how the spec of the object has been changed outside of the GitOps tools listed in the suspend-on-drift annotation.
+// It returns an empty message when the object has no such annotation, or has not drifted.
+// ignoredFields are the top-level fields of the spec that ARC changes by itself, like the replicas set by HRA.
+func specDrift(obj client.Object, ignoredFields ...string) (string, error) {
+	v, ok := getAnnotation(obj, AnnotationKeySuspendOnDrift)
+	if !ok {
+		return "", nil
+	}
+
+	drifts, err := specdrift.Detect(obj, specdrift.ParseManagers(v), ignoredFields...)
+	if err != nil {
+		return "", fmt.Errorf("detecting the drift of the spec: %w", err)
+	}
+
+	var changes []string
+	for _, d := range drifts {
+		changes = append(changes, d.String())
+	}
+
+	return strings.Join(changes, "; "), nil
+}
+
+// suspendedOnDrift returns true when the reconciliation of the object should be suspended as its spec has drifted.
+// The drift is recorded as an event, and as the reason of the Synced condition and the extra conditionTypes set to false.
+// conditions returns the conditions in the status of the object.
+//
+// The suspended object isn't requeued. Syncing the spec back bumps the generation of the object, which triggers
+// the next reconciliation, and the Synced condition is set back to true once that reconciliation succeeds.
+func suspendedOnDrift[T client.Object](ctx context.Context, c client.Client, recorder record.EventRecorder, log logr.Logger, obj T, conditions func(T) *[]metav1.Condition, ignoredFields []string, conditionTypes ...string) bool {
+	drift, err := specDrift(obj, ignoredFields...)
+	if err != nil {
+		log.Error(err, "Ignoring the suspend-on-drift annotation")
+		return false
+	}
+
+	if drift == "" {
+		return false
+	}
+
+	log.Info("Suspended the reconciliation as the spec has drifted", "drift", drift)
+	recorder.Event(obj, corev1.EventTypeWarning, v1alpha1.ConditionReasonSpecDrifted, drift)
+
+	if err := patchConditions(ctx, c, obj, conditions, func(conditions *[]metav1.Condition) {
+		for _, t := range append([]string{v1alpha1.ConditionTypeSynced}, conditionTypes...) {
+			setCondition(conditions, obj.GetGeneration(), t, false, v1alpha1.ConditionReasonSpecDrifted, drift)
+		}
+	}); err != nil {
+		log.Error(err, "Failed to update status conditions of the drifted spec")
+	}
+
+	return true
+}
@@ -0,0 +1,83 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunnerReplicaSetSuspendedOnDrift(t *testing.T) {
+	ctx := context.Background()
+
+	rs := &v1alpha1.RunnerReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "example",
+			Generation:  2,
+			Annotations: map[string]string{AnnotationKeySuspendOnDrift: "argocd-controller"},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:   "argocd-controller",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:selector":{},"f:template":{}}}`)},
+				},
+				{
+					// The RunnerDeployment controller scales the replica set
+					Manager:   "manager",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:effectiveTime":{}}}`)},
+				},
+			},
+		},
+		Spec: v1alpha1.RunnerReplicaSetSpec{
+			Replicas: intPtr(0),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "example"}},
+		},
+	}
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(rs).WithStatusSubresource(rs).Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerReplicaSetReconciler{
+		Client:   c,
+		Log:      logr.Discard(),
+		Recorder: recorder,
+		Scheme:   sc,
+	}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(rs)})
+	require.NoError(t, err)
+	require.Empty(t, recorder.Events, "the replicas scaled by the controller aren't counted as drift")
+
+	var got v1alpha1.RunnerReplicaSet
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(rs), &got))
+	require.True(t, meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionTypeSynced))
+
+	got.ManagedFields = append(got.ManagedFields, metav1.ManagedFieldsEntry{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:image":{}}}}}`)},
+	})
+	require.NoError(t, c.Update(ctx, &got))
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(rs)})
+	require.NoError(t, err)
+	require.Equal(t, "Warning SpecDrifted kubectl-edit changed spec.template", <-recorder.Events)
+
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(rs), &got))
+	synced := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionTypeSynced)
+	require.NotNil(t, synced)
+	require.Equal(t, metav1.ConditionFalse, synced.Status)
+	require.Equal(t, v1alpha1.ConditionReasonSpecDrifted, synced.Reason)
+	require.Equal(t, "kubectl-edit changed spec.template", synced.Message)
+}
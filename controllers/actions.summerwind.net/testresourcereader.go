@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,3 +31,27 @@ func (r *testResourceReader) Get(_ context.Context, key client.ObjectKey, obj cl
 
 	return nil
 }
+
+func (r *testResourceReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	var listOpts client.ListOptions
+	listOpts.ApplyOptions(opts)
+
+	itemsPtr, err := meta.GetItemsPtr(list)
+	if err != nil {
+		return err
+	}
+
+	items := reflect.ValueOf(itemsPtr).Elem()
+
+	for nsName, obj := range r.objects {
+		if listOpts.Namespace != "" && nsName.Namespace != listOpts.Namespace {
+			continue
+		}
+
+		if v := reflect.ValueOf(obj).Elem(); v.Type() == items.Type().Elem() {
+			items.Set(reflect.Append(items, v))
+		}
+	}
+
+	return nil
+}
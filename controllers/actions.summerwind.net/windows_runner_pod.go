@@ -0,0 +1,46 @@
+package actionssummerwindnet
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// windowsRunnerVolumeMountPath is where the runner volume is mounted in windows runner containers,
+	// to which the startup script copies the runner assets from C:\runnertmp, like the one of the linux runner image does to /runner.
+	windowsRunnerVolumeMountPath = `C:\runner`
+	windowsRunnerWorkDir         = `C:\runner\_work`
+
+	// windowsRunnerContainerUser is the non-administrator user built into Windows Server Core and Nano Server images.
+	windowsRunnerContainerUser = "ContainerUser"
+)
+
+// windowsRunnerCommand is the command of windows runner containers, the PowerShell counterpart of the entrypoint of the linux runner image.
+// The script is included in the default windows runner image built from runner/actions-runner.windows-ltsc2022.dockerfile.
+var windowsRunnerCommand = []string{"pwsh", "-NoLogo", "-NoProfile", "-File", `C:\arc\startup.ps1`}
+
+// applyWindowsRunnerPodSettings makes the runner pod runnable on Windows nodes.
+// Settings explicitly specified in the runner pod template are left as is.
+func applyWindowsRunnerPodSettings(pod *corev1.Pod, runner *corev1.Container) {
+	pod.Spec.OS = &corev1.PodOS{Name: corev1.Windows}
+
+	if _, ok := pod.Spec.NodeSelector[corev1.LabelOSStable]; !ok {
+		pod.Spec.NodeSelector = CloneAndAddLabel(pod.Spec.NodeSelector, corev1.LabelOSStable, "windows")
+	}
+
+	if len(runner.Command) == 0 {
+		runner.Command = windowsRunnerCommand
+	}
+
+	if runner.SecurityContext == nil {
+		runner.SecurityContext = &corev1.SecurityContext{}
+	}
+
+	if runner.SecurityContext.WindowsOptions == nil {
+		runner.SecurityContext.WindowsOptions = &corev1.WindowsSecurityContextOptions{}
+	}
+
+	if runner.SecurityContext.WindowsOptions.RunAsUserName == nil {
+		user := windowsRunnerContainerUser
+		runner.SecurityContext.WindowsOptions.RunAsUserName = &user
+	}
+}
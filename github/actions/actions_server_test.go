@@ -13,8 +13,8 @@ import (
 
 // newActionsServer returns a new httptest.Server that handles the
 // authentication requests neeeded to create a new client. Any requests not
-// made to the /actions/runners/registration-token or
-// /actions/runner-registration endpoints will be handled by the provided
+// made to the /actions/runners/registration-token, /actions/runner-registration,
+// or /meta endpoints will be handled by the provided
 // handler. The returned server is started and will be automatically closed
 // when the test ends.
 func newActionsServer(t *testing.T, handler http.Handler, options ...actionsServerOption) *actionsServer {
@@ -48,6 +48,12 @@ func newActionsServer(t *testing.T, handler http.Handler, options ...actionsServ
 			return
 		}
 
+		// handle the GitHub Enterprise Server version detection
+		if strings.HasSuffix(r.URL.Path, "/api/v3/meta") {
+			w.Write([]byte(`{"installed_version":"3.9.0"}`))
+			return
+		}
+
 		handler.ServeHTTP(w, r)
 	})
 
@@ -15,10 +15,14 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/actions/actions-runner-controller/build"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/github/ghes"
+	"github.com/actions/actions-runner-controller/github/httptransport"
 	"github.com/go-logr/logr"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -59,6 +63,12 @@ type ActionsService interface {
 	GetRunnerByName(ctx context.Context, runnerName string) (*RunnerReference, error)
 	RemoveRunner(ctx context.Context, runnerId int64) error
 
+	GetOrganizationRunnerGroupByName(ctx context.Context, name string) (*OrganizationRunnerGroup, error)
+	CreateOrganizationRunnerGroup(ctx context.Context, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error)
+	UpdateOrganizationRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error)
+	SetOrganizationRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositoryIds []int64) error
+	GetRepository(ctx context.Context, owner, name string) (*Repository, error)
+
 	SetUserAgent(info UserAgentInfo)
 }
 
@@ -107,6 +117,13 @@ type Client struct {
 	tlsInsecureSkipVerify bool
 
 	proxyFunc ProxyFunc
+
+	transportConfig httptransport.Config
+
+	auditSink audit.Sink
+
+	// serverInfo is the version of GitHub Enterprise Server detected on the first token refresh
+	serverInfo *ghes.Info
 }
 
 var _ ActionsService = &Client{}
@@ -173,6 +190,21 @@ func WithoutTLSVerify() ClientOption {
 	}
 }
 
+// WithTransportConfig applies the proxy and TLS settings shared with the other GitHub clients.
+// WithRootCAs and WithProxy take precedence over the settings in the config.
+func WithTransportConfig(config httptransport.Config) ClientOption {
+	return func(c *Client) {
+		c.transportConfig = config
+	}
+}
+
+// WithAuditSink records the mutating API calls of the client, like generating JIT configs and removing runners, to the sink.
+func WithAuditSink(sink audit.Sink) ClientOption {
+	return func(c *Client) {
+		c.auditSink = sink
+	}
+}
+
 func WithProxy(proxyFunc ProxyFunc) ClientOption {
 	return func(c *Client) {
 		c.proxyFunc = proxyFunc
@@ -222,6 +254,10 @@ func NewClient(githubConfigURL string, creds *ActionsAuth, options ...ClientOpti
 		transport.TLSClientConfig = &tls.Config{}
 	}
 
+	if err := ac.transportConfig.Apply(transport); err != nil {
+		return nil, fmt.Errorf("failed to apply transport config: %w", err)
+	}
+
 	if ac.rootCAs != nil {
 		transport.TLSClientConfig.RootCAs = ac.rootCAs
 	}
@@ -230,9 +266,14 @@ func NewClient(githubConfigURL string, creds *ActionsAuth, options ...ClientOpti
 		transport.TLSClientConfig.InsecureSkipVerify = true
 	}
 
-	transport.Proxy = ac.proxyFunc
+	if ac.proxyFunc != nil || ac.transportConfig.ProxyURL == "" {
+		transport.Proxy = ac.proxyFunc
+	}
 
 	retryClient.HTTPClient.Transport = transport
+	if ac.auditSink != nil {
+		retryClient.HTTPClient.Transport = audit.Transport{Transport: transport, Sink: ac.auditSink, Client: "actions"}
+	}
 	ac.Client = retryClient.StandardClient()
 
 	return ac, nil
@@ -268,6 +309,10 @@ func (c *Client) Identifier() string {
 		identifier += fmt.Sprintf("rootCAs:%q", c.rootCAs.Subjects())
 	}
 
+	if !c.transportConfig.IsZero() {
+		identifier += fmt.Sprintf("transport:%+v", c.transportConfig)
+	}
+
 	return uuid.NewHash(sha256.New(), uuid.NameSpaceOID, []byte(identifier), 6).String()
 }
 
@@ -982,17 +1027,9 @@ func (c *Client) getRunnerRegistrationToken(ctx context.Context) (*registrationT
 		return nil, fmt.Errorf("failed to create new GitHub API request: %w", err)
 	}
 
-	bearerToken := ""
-
-	if c.creds.Token != "" {
-		bearerToken = fmt.Sprintf("Bearer %v", c.creds.Token)
-	} else {
-		accessToken, err := c.fetchAccessToken(ctx, c.config.ConfigURL.String(), c.creds.AppCreds)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch access token: %w", err)
-		}
-
-		bearerToken = fmt.Sprintf("Bearer %v", accessToken.Token)
+	bearerToken, err := c.gitHubAPIBearerToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/vnd.github.v3+json")
@@ -1030,6 +1067,182 @@ func (c *Client) getRunnerRegistrationToken(ctx context.Context) (*registrationT
 	return registrationToken, nil
 }
 
+// gitHubAPIBearerToken returns the authorization header of the GitHub API requests,
+// either from the personal access token or from an installation access token of the GitHub App.
+func (c *Client) gitHubAPIBearerToken(ctx context.Context) (string, error) {
+	if c.creds.Token != "" {
+		return fmt.Sprintf("Bearer %v", c.creds.Token), nil
+	}
+
+	accessToken, err := c.fetchAccessToken(ctx, c.config.ConfigURL.String(), c.creds.AppCreds)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch access token: %w", err)
+	}
+
+	return fmt.Sprintf("Bearer %v", accessToken.Token), nil
+}
+
+// doGitHubAPIRequest issues an authenticated GitHub API request,
+// and decodes the response into responseUnmarshalTarget when it isn't nil.
+func (c *Client) doGitHubAPIRequest(ctx context.Context, method, path string, requestData any, expectedResponseStatusCode int, responseUnmarshalTarget any) error {
+	var body io.Reader
+	if requestData != nil {
+		b, err := json.Marshal(requestData)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the request body: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	parsedPath, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse path %q: %w", path, err)
+	}
+
+	req, err := c.NewGitHubAPIRequest(ctx, method, parsedPath.Path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create new GitHub API request: %w", err)
+	}
+	req.URL.RawQuery = parsedPath.RawQuery
+
+	bearerToken, err := c.gitHubAPIBearerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearerToken)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to issue the request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedResponseStatusCode {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			body = []byte(err.Error())
+		}
+		return &GitHubAPIError{
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get(HeaderGitHubRequestID),
+			Err:        errors.New(string(body)),
+		}
+	}
+
+	if responseUnmarshalTarget == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(responseUnmarshalTarget); err != nil {
+		return &GitHubAPIError{
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get(HeaderGitHubRequestID),
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) organizationRunnerGroupsPath() (string, error) {
+	if c.config.Scope != GitHubScopeOrganization {
+		return "", fmt.Errorf("runner groups can only be managed for an organization, got config url: %s", c.config.ConfigURL)
+	}
+	return fmt.Sprintf("/orgs/%s/actions/runner-groups", c.config.Organization), nil
+}
+
+// GetOrganizationRunnerGroupByName returns the runner group of the organization with the given name,
+// or nil if the organization doesn't have it.
+func (c *Client) GetOrganizationRunnerGroupByName(ctx context.Context, name string) (*OrganizationRunnerGroup, error) {
+	path, err := c.organizationRunnerGroupsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	const perPage = 100
+	for page := 1; ; page++ {
+		var runnerGroupList OrganizationRunnerGroupList
+		if err := c.doGitHubAPIRequest(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=%d&page=%d", path, perPage, page), nil, http.StatusOK, &runnerGroupList); err != nil {
+			return nil, err
+		}
+
+		for i := range runnerGroupList.RunnerGroups {
+			if strings.EqualFold(runnerGroupList.RunnerGroups[i].Name, name) {
+				return &runnerGroupList.RunnerGroups[i], nil
+			}
+		}
+
+		if len(runnerGroupList.RunnerGroups) < perPage {
+			return nil, nil
+		}
+	}
+}
+
+func (c *Client) CreateOrganizationRunnerGroup(ctx context.Context, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error) {
+	path, err := c.organizationRunnerGroupsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var createdRunnerGroup *OrganizationRunnerGroup
+	if err := c.doGitHubAPIRequest(ctx, http.MethodPost, path, runnerGroup, http.StatusCreated, &createdRunnerGroup); err != nil {
+		return nil, err
+	}
+
+	return createdRunnerGroup, nil
+}
+
+// UpdateOrganizationRunnerGroup updates the runner group, except its repositories which are set by SetOrganizationRunnerGroupRepositories.
+func (c *Client) UpdateOrganizationRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error) {
+	path, err := c.organizationRunnerGroupsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	update := *runnerGroup
+	update.ID = 0
+	update.SelectedRepositoryIDs = nil
+
+	var updatedRunnerGroup *OrganizationRunnerGroup
+	if err := c.doGitHubAPIRequest(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", path, runnerGroupId), &update, http.StatusOK, &updatedRunnerGroup); err != nil {
+		return nil, err
+	}
+
+	return updatedRunnerGroup, nil
+}
+
+// SetOrganizationRunnerGroupRepositories replaces the repositories allowed to use the runner group with selected visibility.
+func (c *Client) SetOrganizationRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositoryIds []int64) error {
+	path, err := c.organizationRunnerGroupsPath()
+	if err != nil {
+		return err
+	}
+
+	if repositoryIds == nil {
+		repositoryIds = []int64{}
+	}
+
+	body := struct {
+		SelectedRepositoryIDs []int64 `json:"selected_repository_ids"`
+	}{
+		SelectedRepositoryIDs: repositoryIds,
+	}
+
+	return c.doGitHubAPIRequest(ctx, http.MethodPut, fmt.Sprintf("%s/%d/repositories", path, runnerGroupId), &body, http.StatusNoContent, nil)
+}
+
+func (c *Client) GetRepository(ctx context.Context, owner, name string) (*Repository, error) {
+	var repository *Repository
+	if err := c.doGitHubAPIRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s", owner, name), nil, http.StatusOK, &repository); err != nil {
+		return nil, err
+	}
+
+	return repository, nil
+}
+
 // Format: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
 type accessToken struct {
 	Token     string    `json:"token"`
@@ -1247,6 +1460,26 @@ func actionsServiceAdminTokenExpiresAt(jwtToken string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("failed to parse token claims to get expire at")
 }
 
+// checkRunnerScaleSetsSupported returns ghes.UnsupportedError when the GitHub Enterprise Server is too old for runner scale sets,
+// instead of letting the runner registration fail with a 404. A failure to detect the version is logged and ignored.
+func (c *Client) checkRunnerScaleSetsSupported(ctx context.Context) error {
+	if c.config.IsHosted {
+		return nil
+	}
+
+	if c.serverInfo == nil {
+		info, err := ghes.Detect(ctx, c.Client, c.config.GitHubAPIURL("/").String())
+		if err != nil {
+			c.logger.Info("unable to detect the GitHub Enterprise Server version", "error", err.Error())
+			return nil
+		}
+
+		c.serverInfo = &info
+	}
+
+	return c.serverInfo.UnsupportedError(ghes.CapabilityRunnerScaleSets)
+}
+
 func (c *Client) updateTokenIfNeeded(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1256,6 +1489,10 @@ func (c *Client) updateTokenIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
+	if err := c.checkRunnerScaleSetsSupported(ctx); err != nil {
+		return err
+	}
+
 	c.logger.Info("refreshing token", "githubConfigUrl", c.config.ConfigURL.String())
 	rt, err := c.getRunnerRegistrationToken(ctx)
 	if err != nil {
@@ -7,10 +7,20 @@ import (
 	"time"
 
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+type auditRecorder struct {
+	events []audit.Event
+}
+
+func (r *auditRecorder) Record(_ context.Context, e audit.Event) {
+	r.events = append(r.events, e)
+}
+
 func TestGenerateJitRunnerConfig(t *testing.T) {
 	ctx := context.Background()
 	auth := &actions.ActionsAuth{
@@ -34,6 +44,28 @@ func TestGenerateJitRunnerConfig(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 
+	t.Run("Records the call to the audit sink", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{}`))
+		}))
+
+		rec := &auditRecorder{}
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth, actions.WithAuditSink(rec))
+		require.NoError(t, err)
+
+		ctx := audit.WithActor(ctx, "EphemeralRunner", types.NamespacedName{Namespace: "default", Name: "runner"})
+		_, err = client.GenerateJitRunnerConfig(ctx, &actions.RunnerScaleSetJitRunnerSetting{}, 1)
+		require.NoError(t, err)
+
+		var operations []string
+		for _, e := range rec.events {
+			assert.Equal(t, "actions", e.Client)
+			assert.Equal(t, "EphemeralRunner/default/runner", e.Actor.String())
+			operations = append(operations, e.Operation)
+		}
+		assert.Contains(t, operations, "generate-jit-config")
+	})
+
 	t.Run("Default retries on server error", func(t *testing.T) {
 		runnerSettings := &actions.RunnerScaleSetJitRunnerSetting{}
 
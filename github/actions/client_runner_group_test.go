@@ -0,0 +1,133 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrganizationRunnerGroupByName(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	t.Run("Get runner group across pages", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v3/orgs/my-org/actions/runner-groups", r.URL.Path)
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+			var list actions.OrganizationRunnerGroupList
+			if r.URL.Query().Get("page") == "1" {
+				for i := range 100 {
+					list.RunnerGroups = append(list.RunnerGroups, actions.OrganizationRunnerGroup{ID: int64(i + 1), Name: fmt.Sprintf("group-%d", i)})
+				}
+			} else {
+				list.RunnerGroups = []actions.OrganizationRunnerGroup{{ID: 101, Name: "Linux", Visibility: "selected"}}
+			}
+			list.TotalCount = 101
+			json.NewEncoder(w).Encode(list)
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+		require.NoError(t, err)
+
+		got, err := client.GetOrganizationRunnerGroupByName(ctx, "linux")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(101), got.ID)
+		assert.Equal(t, "selected", got.Visibility)
+
+		got, err = client.GetOrganizationRunnerGroupByName(ctx, "missing")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Repository config url", func(t *testing.T) {
+		server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("unexpected request")
+		}))
+
+		client, err := actions.NewClient(server.configURLForOrg("my-org")+"/my-repo", auth)
+		require.NoError(t, err)
+
+		_, err = client.GetOrganizationRunnerGroupByName(ctx, "linux")
+		assert.ErrorContains(t, err, "runner groups can only be managed for an organization")
+	})
+}
+
+func TestManageOrganizationRunnerGroup(t *testing.T) {
+	ctx := context.Background()
+	auth := &actions.ActionsAuth{
+		Token: "token",
+	}
+
+	var requests []string
+	var bodies []map[string]any
+	server := newActionsServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		var body map[string]any
+		if r.Body != nil && r.ContentLength != 0 {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+		bodies = append(bodies, body)
+
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 5, "name": "linux", "visibility": "selected"}`))
+		case r.Method == http.MethodPatch:
+			w.Write([]byte(`{"id": 5, "name": "linux", "visibility": "all"}`))
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"id": 42, "name": "my-repo", "full_name": "my-org/my-repo"}`))
+		}
+	}))
+
+	client, err := actions.NewClient(server.configURLForOrg("my-org"), auth)
+	require.NoError(t, err)
+
+	created, err := client.CreateOrganizationRunnerGroup(ctx, &actions.OrganizationRunnerGroup{
+		Name:                  "linux",
+		Visibility:            "selected",
+		SelectedWorkflows:     []string{},
+		SelectedRepositoryIDs: []int64{42},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), created.ID)
+
+	updated, err := client.UpdateOrganizationRunnerGroup(ctx, 5, &actions.OrganizationRunnerGroup{
+		ID:                    5,
+		Name:                  "linux",
+		Visibility:            "all",
+		SelectedWorkflows:     []string{},
+		SelectedRepositoryIDs: []int64{42},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "all", updated.Visibility)
+
+	require.NoError(t, client.SetOrganizationRunnerGroupRepositories(ctx, 5, []int64{42}))
+
+	repository, err := client.GetRepository(ctx, "my-org", "my-repo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), repository.ID)
+
+	assert.Equal(t, []string{
+		"POST /api/v3/orgs/my-org/actions/runner-groups",
+		"PATCH /api/v3/orgs/my-org/actions/runner-groups/5",
+		"PUT /api/v3/orgs/my-org/actions/runner-groups/5/repositories",
+		"GET /api/v3/repos/my-org/my-repo",
+	}, requests)
+	assert.Equal(t, []any{float64(42)}, bodies[0]["selected_repository_ids"])
+	assert.NotContains(t, bodies[1], "selected_repository_ids")
+	assert.NotContains(t, bodies[1], "id")
+	assert.Equal(t, []any{float64(42)}, bodies[2]["selected_repository_ids"])
+}
@@ -76,6 +76,18 @@ var defaultRunnerGroup = &actions.RunnerGroup{
 	IsDefault: true,
 }
 
+var defaultOrganizationRunnerGroup = &actions.OrganizationRunnerGroup{
+	ID:         1,
+	Name:       "testgroup",
+	Visibility: "all",
+}
+
+var defaultRepository = &actions.Repository{
+	ID:       1,
+	Name:     "testrepo",
+	FullName: "testowner/testrepo",
+}
+
 var sessionID = uuid.New()
 
 var defaultRunnerScaleSetSession = &actions.RunnerScaleSetSession{
@@ -188,6 +200,25 @@ type FakeClient struct {
 	removeRunnerResult struct {
 		err error
 	}
+	getOrganizationRunnerGroupByNameResult struct {
+		*actions.OrganizationRunnerGroup
+		err error
+	}
+	createOrganizationRunnerGroupResult struct {
+		*actions.OrganizationRunnerGroup
+		err error
+	}
+	updateOrganizationRunnerGroupResult struct {
+		*actions.OrganizationRunnerGroup
+		err error
+	}
+	setOrganizationRunnerGroupRepositoriesResult struct {
+		err error
+	}
+	getRepositoryResult struct {
+		*actions.Repository
+		err error
+	}
 }
 
 func NewFakeClient(options ...Option) actions.ActionsService {
@@ -213,6 +244,10 @@ func (f *FakeClient) applyDefaults() {
 	f.generateJitRunnerConfigResult.RunnerScaleSetJitRunnerConfig = defaultRunnerScaleSetJitRunnerConfig
 	f.getRunnerResult.RunnerReference = defaultRunnerReference
 	f.getRunnerByNameResult.RunnerReference = defaultRunnerReference
+	f.getOrganizationRunnerGroupByNameResult.OrganizationRunnerGroup = defaultOrganizationRunnerGroup
+	f.createOrganizationRunnerGroupResult.OrganizationRunnerGroup = defaultOrganizationRunnerGroup
+	f.updateOrganizationRunnerGroupResult.OrganizationRunnerGroup = defaultOrganizationRunnerGroup
+	f.getRepositoryResult.Repository = defaultRepository
 }
 
 func (f *FakeClient) GetRunnerScaleSet(ctx context.Context, runnerGroupId int, runnerScaleSetName string) (*actions.RunnerScaleSet, error) {
@@ -283,4 +318,24 @@ func (f *FakeClient) RemoveRunner(ctx context.Context, runnerId int64) error {
 	return f.removeRunnerResult.err
 }
 
+func (f *FakeClient) GetOrganizationRunnerGroupByName(ctx context.Context, name string) (*actions.OrganizationRunnerGroup, error) {
+	return f.getOrganizationRunnerGroupByNameResult.OrganizationRunnerGroup, f.getOrganizationRunnerGroupByNameResult.err
+}
+
+func (f *FakeClient) CreateOrganizationRunnerGroup(ctx context.Context, runnerGroup *actions.OrganizationRunnerGroup) (*actions.OrganizationRunnerGroup, error) {
+	return f.createOrganizationRunnerGroupResult.OrganizationRunnerGroup, f.createOrganizationRunnerGroupResult.err
+}
+
+func (f *FakeClient) UpdateOrganizationRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *actions.OrganizationRunnerGroup) (*actions.OrganizationRunnerGroup, error) {
+	return f.updateOrganizationRunnerGroupResult.OrganizationRunnerGroup, f.updateOrganizationRunnerGroupResult.err
+}
+
+func (f *FakeClient) SetOrganizationRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositoryIds []int64) error {
+	return f.setOrganizationRunnerGroupRepositoriesResult.err
+}
+
+func (f *FakeClient) GetRepository(ctx context.Context, owner, name string) (*actions.Repository, error) {
+	return f.getRepositoryResult.Repository, f.getRepositoryResult.err
+}
+
 func (f *FakeClient) SetUserAgent(_ actions.UserAgentInfo) {}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/actions/actions-runner-controller/github/actions"
 	"github.com/actions/actions-runner-controller/github/actions/testserver"
+	"github.com/actions/actions-runner-controller/github/ghes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -131,6 +132,26 @@ func TestNewActionsServiceRequest(t *testing.T) {
 			assert.Equal(t, "Bearer "+newToken, req.Header.Get("Authorization"))
 		})
 
+		t.Run("github enterprise server is too old for runner scale sets", func(t *testing.T) {
+			server := testserver.New(
+				t,
+				nil,
+				testserver.WithMetaHandler(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(`{"installed_version":"3.8.2"}`))
+				}),
+			)
+
+			client, err := actions.NewClient(server.ConfigURLForOrg("my-org"), defaultCreds)
+			require.NoError(t, err)
+
+			_, err = client.NewActionsServiceRequest(ctx, http.MethodGet, "my-path", nil)
+			require.Error(t, err)
+
+			var unsupported *ghes.UnsupportedError
+			require.ErrorAs(t, err, &unsupported)
+			assert.Equal(t, ghes.CapabilityRunnerScaleSets, unsupported.Capability)
+		})
+
 		t.Run("admin token refresh failure", func(t *testing.T) {
 			newToken := defaultActionsToken(t)
 			errMessage := `{"message":"test"}`
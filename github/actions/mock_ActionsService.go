@@ -66,6 +66,32 @@ func (_m *MockActionsService) CreateMessageSession(ctx context.Context, runnerSc
 	return r0, r1
 }
 
+// CreateOrganizationRunnerGroup provides a mock function with given fields: ctx, runnerGroup
+func (_m *MockActionsService) CreateOrganizationRunnerGroup(ctx context.Context, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error) {
+	ret := _m.Called(ctx, runnerGroup)
+
+	var r0 *OrganizationRunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error)); ok {
+		return rf(ctx, runnerGroup)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *OrganizationRunnerGroup) *OrganizationRunnerGroup); ok {
+		r0 = rf(ctx, runnerGroup)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*OrganizationRunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *OrganizationRunnerGroup) error); ok {
+		r1 = rf(ctx, runnerGroup)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateRunnerScaleSet provides a mock function with given fields: ctx, runnerScaleSet
 func (_m *MockActionsService) CreateRunnerScaleSet(ctx context.Context, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
 	ret := _m.Called(ctx, runnerScaleSet)
@@ -212,6 +238,58 @@ func (_m *MockActionsService) GetMessage(ctx context.Context, messageQueueUrl st
 	return r0, r1
 }
 
+// GetOrganizationRunnerGroupByName provides a mock function with given fields: ctx, name
+func (_m *MockActionsService) GetOrganizationRunnerGroupByName(ctx context.Context, name string) (*OrganizationRunnerGroup, error) {
+	ret := _m.Called(ctx, name)
+
+	var r0 *OrganizationRunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*OrganizationRunnerGroup, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *OrganizationRunnerGroup); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*OrganizationRunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRepository provides a mock function with given fields: ctx, owner, name
+func (_m *MockActionsService) GetRepository(ctx context.Context, owner string, name string) (*Repository, error) {
+	ret := _m.Called(ctx, owner, name)
+
+	var r0 *Repository
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Repository, error)); ok {
+		return rf(ctx, owner, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Repository); ok {
+		r0 = rf(ctx, owner, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Repository)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, owner, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRunner provides a mock function with given fields: ctx, runnerId
 func (_m *MockActionsService) GetRunner(ctx context.Context, runnerId int64) (*RunnerReference, error) {
 	ret := _m.Called(ctx, runnerId)
@@ -382,11 +460,51 @@ func (_m *MockActionsService) RemoveRunner(ctx context.Context, runnerId int64)
 	return r0
 }
 
+// SetOrganizationRunnerGroupRepositories provides a mock function with given fields: ctx, runnerGroupId, repositoryIds
+func (_m *MockActionsService) SetOrganizationRunnerGroupRepositories(ctx context.Context, runnerGroupId int64, repositoryIds []int64) error {
+	ret := _m.Called(ctx, runnerGroupId, repositoryIds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) error); ok {
+		r0 = rf(ctx, runnerGroupId, repositoryIds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetUserAgent provides a mock function with given fields: info
 func (_m *MockActionsService) SetUserAgent(info UserAgentInfo) {
 	_m.Called(info)
 }
 
+// UpdateOrganizationRunnerGroup provides a mock function with given fields: ctx, runnerGroupId, runnerGroup
+func (_m *MockActionsService) UpdateOrganizationRunnerGroup(ctx context.Context, runnerGroupId int64, runnerGroup *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error) {
+	ret := _m.Called(ctx, runnerGroupId, runnerGroup)
+
+	var r0 *OrganizationRunnerGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *OrganizationRunnerGroup) (*OrganizationRunnerGroup, error)); ok {
+		return rf(ctx, runnerGroupId, runnerGroup)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *OrganizationRunnerGroup) *OrganizationRunnerGroup); ok {
+		r0 = rf(ctx, runnerGroupId, runnerGroup)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*OrganizationRunnerGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *OrganizationRunnerGroup) error); ok {
+		r1 = rf(ctx, runnerGroupId, runnerGroup)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateRunnerScaleSet provides a mock function with given fields: ctx, runnerScaleSetId, runnerScaleSet
 func (_m *MockActionsService) UpdateRunnerScaleSet(ctx context.Context, runnerScaleSetId int, runnerScaleSet *RunnerScaleSet) (*RunnerScaleSet, error) {
 	ret := _m.Called(ctx, runnerScaleSetId, runnerScaleSet)
@@ -20,6 +20,9 @@ type multiClient struct {
 	clients map[ActionsClientKey]*Client
 
 	logger logr.Logger
+
+	// options are applied to every client before the options passed to GetClientFor
+	options []ClientOption
 }
 
 type GitHubAppAuth struct {
@@ -41,11 +44,14 @@ type ActionsClientKey struct {
 	Namespace  string
 }
 
-func NewMultiClient(logger logr.Logger) MultiClient {
+// NewMultiClient returns the MultiClient whose clients are created with the options,
+// like WithTransportConfig for the controller-wide proxy and TLS settings.
+func NewMultiClient(logger logr.Logger, options ...ClientOption) MultiClient {
 	return &multiClient{
 		mu:      sync.Mutex{},
 		clients: make(map[ActionsClientKey]*Client),
 		logger:  logger,
+		options: options,
 	}
 }
 
@@ -63,9 +69,9 @@ func (m *multiClient) GetClientFor(ctx context.Context, githubConfigURL string,
 	client, err := NewClient(
 		githubConfigURL,
 		&creds,
-		append([]ClientOption{
+		append(append([]ClientOption{
 			WithLogger(m.logger),
-		}, options...)...,
+		}, m.options...), options...)...,
 	)
 	if err != nil {
 		return nil, err
@@ -13,8 +13,8 @@ import (
 
 // New returns a new httptest.Server that handles the
 // authentication requests neeeded to create a new client. Any requests not
-// made to the /actions/runners/registration-token or
-// /actions/runner-registration endpoints will be handled by the provided
+// made to the /actions/runners/registration-token, /actions/runner-registration,
+// or /meta endpoints will be handled by the provided
 // handler. The returned server is started and will be automatically closed
 // when the test ends.
 //
@@ -54,6 +54,12 @@ func NewUnstarted(t ginkgo.GinkgoTInterface, handler http.Handler, options ...ac
 			return
 		}
 
+		// handle the GitHub Enterprise Server version detection
+		if strings.HasSuffix(r.URL.Path, "/api/v3/meta") {
+			server.metaHandler(w, r)
+			return
+		}
+
 		handler.ServeHTTP(w, r)
 	})
 
@@ -82,12 +88,19 @@ func WithActionsRegistrationTokenHandler(h http.HandlerFunc) actionsServerOption
 	}
 }
 
+func WithMetaHandler(h http.HandlerFunc) actionsServerOption {
+	return func(s *actionsServer) {
+		s.metaHandler = h
+	}
+}
+
 type actionsServer struct {
 	*httptest.Server
 
 	token                          string
 	runnerRegistrationTokenHandler http.HandlerFunc
 	actionRegistrationTokenHandler http.HandlerFunc
+	metaHandler                    http.HandlerFunc
 }
 
 func (s *actionsServer) setDefaults(t ginkgo.GinkgoTInterface) {
@@ -108,6 +121,13 @@ func (s *actionsServer) setDefaults(t ginkgo.GinkgoTInterface) {
 			w.Write([]byte(`{"url":"` + s.URL + `/tenant/123/","token":"` + s.token + `"}`))
 		}
 	}
+
+	if s.metaHandler == nil {
+		s.metaHandler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"installed_version":"3.9.0"}`))
+		}
+	}
 }
 
 func (s *actionsServer) ConfigURLForOrg(org string) string {
@@ -86,6 +86,30 @@ type RunnerGroupList struct {
 	RunnerGroups []RunnerGroup `json:"value"`
 }
 
+// OrganizationRunnerGroup is a runner group of an organization in the GitHub API.
+// Format: https://docs.github.com/en/rest/actions/self-hosted-runner-groups
+type OrganizationRunnerGroup struct {
+	ID                       int64    `json:"id,omitempty"`
+	Name                     string   `json:"name"`
+	Visibility               string   `json:"visibility,omitempty"`
+	AllowsPublicRepositories bool     `json:"allows_public_repositories"`
+	RestrictedToWorkflows    bool     `json:"restricted_to_workflows"`
+	SelectedWorkflows        []string `json:"selected_workflows"`
+	// SelectedRepositoryIDs is only sent when creating a runner group
+	SelectedRepositoryIDs []int64 `json:"selected_repository_ids,omitempty"`
+}
+
+type OrganizationRunnerGroupList struct {
+	TotalCount   int                       `json:"total_count"`
+	RunnerGroups []OrganizationRunnerGroup `json:"runner_groups"`
+}
+
+type Repository struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+}
+
 type RunnerScaleSet struct {
 	Id                 int                      `json:"id,omitempty"`
 	Name               string                   `json:"name,omitempty"`
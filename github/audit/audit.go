@@ -0,0 +1,194 @@
+// Package audit records the mutating GitHub API calls made by the controller, like minting tokens and removing runners,
+// for the compliance review of what the controller did to GitHub on behalf of which resource.
+//
+// Only the method, the host, and the path of the requests are recorded. The query strings, the headers, and the bodies,
+// which may carry the credentials and the minted tokens, never are.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	// OperationOther is the operation of the mutating calls not known to the classifier.
+	OperationOther = "other"
+)
+
+// Actor is the Kubernetes resource whose reconciliation made the GitHub API call.
+type Actor struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (a Actor) String() string {
+	if a.Namespace == "" {
+		return fmt.Sprintf("%s/%s", a.Kind, a.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", a.Kind, a.Namespace, a.Name)
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes the GitHub API calls made with it to the resource of the kind.
+func WithActor(ctx context.Context, kind string, key types.NamespacedName) context.Context {
+	return context.WithValue(ctx, actorKey{}, Actor{Kind: kind, Namespace: key.Namespace, Name: key.Name})
+}
+
+// ActorFrom returns the actor set on the context by WithActor.
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	a, ok := ctx.Value(actorKey{}).(Actor)
+	return a, ok
+}
+
+// Event is the record of a mutating GitHub API call.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Client is the GitHub client that made the call, either github for the client of the legacy controllers
+	// or actions for the client of the runner scale sets.
+	Client    string `json:"client"`
+	Operation string `json:"operation"`
+	Method    string `json:"method"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	// Actor is nil when the call was not made while reconciling a resource, like the calls of the github webhook server.
+	Actor          *Actor  `json:"actor,omitempty"`
+	Outcome        string  `json:"outcome"`
+	StatusCode     int     `json:"statusCode,omitempty"`
+	Error          string  `json:"error,omitempty"`
+	LatencySeconds float64 `json:"latencySeconds"`
+}
+
+// Sink records the events. Record must not block the API call for long, so sinks that deliver the events remotely
+// should queue them.
+type Sink interface {
+	Record(ctx context.Context, e Event)
+}
+
+// Sinks records the events to every sink.
+type Sinks []Sink
+
+func (s Sinks) Record(ctx context.Context, e Event) {
+	for _, sink := range s {
+		sink.Record(ctx, e)
+	}
+}
+
+// Transport wraps a transport with the recording of the mutating requests to the sink.
+type Transport struct {
+	Transport http.RoundTripper
+	Sink      Sink
+	// Client is recorded as the client of the events.
+	Client string
+}
+
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isMutation(req.Method) {
+		return t.Transport.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.Transport.RoundTrip(req)
+
+	e := Event{
+		Time:           start.UTC(),
+		Client:         t.Client,
+		Operation:      Operation(req.Method, req.URL.Path),
+		Method:         req.Method,
+		Host:           req.URL.Host,
+		Path:           req.URL.Path,
+		Outcome:        OutcomeSuccess,
+		LatencySeconds: time.Since(start).Seconds(),
+	}
+	if a, ok := ActorFrom(req.Context()); ok {
+		e.Actor = &a
+	}
+	if resp != nil {
+		e.StatusCode = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			e.Outcome = OutcomeFailure
+		}
+	}
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	}
+
+	t.Sink.Record(req.Context(), e)
+
+	return resp, err
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+type operation struct {
+	method string
+	path   *regexp.Regexp
+	name   string
+}
+
+// operations are matched against the end of the paths, as GitHub Enterprise Server prefixes the REST API paths
+// with /api/v3 and the Actions service prefixes its paths with the tenant.
+var operations = []operation{
+	// GitHub REST API
+	{http.MethodPost, regexp.MustCompile(`/app/installations/\d+/access_tokens$`), "create-installation-token"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runners/registration-token$`), "create-registration-token"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runners/remove-token$`), "create-remove-token"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runners/generate-jitconfig$`), "generate-jit-config"},
+	{http.MethodDelete, regexp.MustCompile(`/actions/runners/\d+$`), "remove-runner"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runners/\d+/labels$`), "add-runner-labels"},
+	{http.MethodPut, regexp.MustCompile(`/actions/runners/\d+/labels$`), "set-runner-labels"},
+	{http.MethodDelete, regexp.MustCompile(`/actions/runners/\d+/labels(/[^/]+)?$`), "remove-runner-labels"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runner-groups$`), "create-runner-group"},
+	{http.MethodPatch, regexp.MustCompile(`/actions/runner-groups/\d+$`), "update-runner-group"},
+	{http.MethodDelete, regexp.MustCompile(`/actions/runner-groups/\d+$`), "delete-runner-group"},
+	{http.MethodPut, regexp.MustCompile(`/actions/runner-groups/\d+/repositories$`), "set-runner-group-repositories"},
+	{http.MethodPut, regexp.MustCompile(`/actions/runner-groups/\d+/repositories/\d+$`), "add-runner-group-repository"},
+	{http.MethodDelete, regexp.MustCompile(`/actions/runner-groups/\d+/repositories/\d+$`), "remove-runner-group-repository"},
+	{http.MethodPut, regexp.MustCompile(`/actions/runner-groups/\d+/runners$`), "set-runner-group-runners"},
+	{http.MethodPut, regexp.MustCompile(`/actions/runner-groups/\d+/runners/\d+$`), "add-runner-group-runner"},
+	{http.MethodDelete, regexp.MustCompile(`/actions/runner-groups/\d+/runners/\d+$`), "remove-runner-group-runner"},
+	{http.MethodPost, regexp.MustCompile(`/hooks/\d+/deliveries/\d+/attempts$`), "redeliver-hook-delivery"},
+	{http.MethodPost, regexp.MustCompile(`/actions/jobs/\d+/rerun$`), "rerun-workflow-job"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runs/\d+/rerun-failed-jobs$`), "rerun-failed-workflow-jobs"},
+	{http.MethodPost, regexp.MustCompile(`/actions/runner-registration$`), "create-actions-service-token"},
+	{http.MethodPost, regexp.MustCompile(`/repos/[^/]+/[^/]+/statuses/[0-9a-f]+$`), "create-commit-status"},
+
+	// Actions service
+	{http.MethodPost, regexp.MustCompile(`/_apis/runtime/runnerscalesets$`), "create-runner-scale-set"},
+	{http.MethodPatch, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+$`), "update-runner-scale-set"},
+	{http.MethodDelete, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+$`), "delete-runner-scale-set"},
+	{http.MethodPost, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+/generatejitconfig$`), "generate-jit-config"},
+	{http.MethodPost, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+/acquirejobs$`), "acquire-jobs"},
+	{http.MethodPost, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+/sessions$`), "create-message-session"},
+	{http.MethodPatch, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+/sessions/[^/]+$`), "refresh-message-session"},
+	{http.MethodDelete, regexp.MustCompile(`/_apis/runtime/runnerscalesets/\d+/sessions/[^/]+$`), "delete-message-session"},
+	{http.MethodDelete, regexp.MustCompile(`/_apis/distributedtask/pools/\d+/agents/\d+$`), "remove-runner"},
+}
+
+// Operation returns the name of the mutating GitHub API operation of the method and the path,
+// like create-registration-token or remove-runner, or OperationOther for the unknown ones.
+func Operation(method, path string) string {
+	for _, op := range operations {
+		if op.method == method && op.path.MatchString(path) {
+			return op.name
+		}
+	}
+	return OperationOther
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Record(_ context.Context, e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodPost, "/app/installations/123/access_tokens", "create-installation-token"},
+		{http.MethodPost, "/api/v3/app/installations/123/access_tokens", "create-installation-token"},
+		{http.MethodPost, "/repos/owner/repo/actions/runners/registration-token", "create-registration-token"},
+		{http.MethodPost, "/orgs/org/actions/runners/remove-token", "create-remove-token"},
+		{http.MethodPost, "/enterprises/ent/actions/runners/generate-jitconfig", "generate-jit-config"},
+		{http.MethodDelete, "/orgs/org/actions/runners/42", "remove-runner"},
+		{http.MethodDelete, "/orgs/org/actions/runners/42/labels/gpu", "remove-runner-labels"},
+		{http.MethodPatch, "/orgs/org/actions/runner-groups/3", "update-runner-group"},
+		{http.MethodPut, "/orgs/org/actions/runner-groups/3/repositories", "set-runner-group-repositories"},
+		{http.MethodPost, "/repos/owner/repo/actions/jobs/42/rerun", "rerun-workflow-job"},
+		{http.MethodPost, "/repos/owner/repo/actions/runs/7/rerun-failed-jobs", "rerun-failed-workflow-jobs"},
+		{http.MethodPost, "/actions/runner-registration", "create-actions-service-token"},
+		{http.MethodPost, "/repos/owner/.github/statuses/0a1b2c3d", "create-commit-status"},
+		{http.MethodPost, "/tenant/_apis/runtime/runnerscalesets", "create-runner-scale-set"},
+		{http.MethodDelete, "/tenant/_apis/runtime/runnerscalesets/7", "delete-runner-scale-set"},
+		{http.MethodPost, "/tenant/_apis/runtime/runnerscalesets/7/generatejitconfig", "generate-jit-config"},
+		{http.MethodPatch, "/tenant/_apis/runtime/runnerscalesets/7/sessions/4b7bd0ab-0f5d-4f6d-8c2e-1b5e5a1b0c7f", "refresh-message-session"},
+		{http.MethodDelete, "/tenant/_apis/distributedtask/pools/0/agents/42", "remove-runner"},
+		{http.MethodPost, "/repos/owner/repo/issues", OperationOther},
+		// A GET of the runner is not its removal
+		{http.MethodGet, "/orgs/org/actions/runners/42", OperationOther},
+	}
+
+	for _, tt := range tests {
+		if got := Operation(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgs/org/actions/runners/404" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	client := &http.Client{Transport: Transport{Transport: http.DefaultTransport, Sink: rec, Client: "github"}}
+
+	ctx := WithActor(context.Background(), "Runner", types.NamespacedName{Namespace: "default", Name: "example-runner"})
+
+	do := func(ctx context.Context, method, path string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	do(ctx, http.MethodGet, "/orgs/org/actions/runners")
+	do(ctx, http.MethodDelete, "/orgs/org/actions/runners/1?secret=token")
+	do(context.Background(), http.MethodDelete, "/orgs/org/actions/runners/404")
+
+	if len(rec.events) != 2 {
+		t.Fatalf("expected the 2 mutations to be recorded, got %+v", rec.events)
+	}
+
+	e := rec.events[0]
+	if e.Client != "github" || e.Operation != "remove-runner" || e.Method != http.MethodDelete || e.Path != "/orgs/org/actions/runners/1" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.Outcome != OutcomeSuccess || e.StatusCode != http.StatusNoContent {
+		t.Errorf("expected a successful outcome, got %+v", e)
+	}
+	if e.Actor == nil || e.Actor.String() != "Runner/default/example-runner" {
+		t.Errorf("expected the actor Runner/default/example-runner, got %v", e.Actor)
+	}
+	if e.LatencySeconds <= 0 || e.Time.IsZero() {
+		t.Errorf("expected the time and the latency, got %+v", e)
+	}
+
+	e = rec.events[1]
+	if e.Outcome != OutcomeFailure || e.StatusCode != http.StatusNotFound || e.Actor != nil {
+		t.Errorf("expected a failed outcome without actor, got %+v", e)
+	}
+}
+
+type errorTransport struct{}
+
+func (errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransportError(t *testing.T) {
+	rec := &recorder{}
+	client := &http.Client{Transport: Transport{Transport: errorTransport{}, Sink: rec, Client: "actions"}}
+
+	resp, err := client.Post("https://pipelines.actions.githubusercontent.com/tenant/_apis/runtime/runnerscalesets/1/generatejitconfig", "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected an error")
+	}
+
+	if len(rec.events) != 1 {
+		t.Fatalf("expected 1 event, got %+v", rec.events)
+	}
+	e := rec.events[0]
+	if e.Operation != "generate-jit-config" || e.Outcome != OutcomeFailure || e.Error != "connection refused" || e.Host != "pipelines.actions.githubusercontent.com" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	a, b := &recorder{}, &recorder{}
+
+	Sinks{a, b}.Record(context.Background(), Event{Operation: "remove-runner"})
+
+	if len(a.events) != 1 || len(b.events) != 1 {
+		t.Errorf("expected the event to be recorded to both sinks, got %v and %v", a.events, b.events)
+	}
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// LogSink writes the events to the structured log of the controller.
+type LogSink struct {
+	Log logr.Logger
+}
+
+func (s LogSink) Record(_ context.Context, e Event) {
+	args := []interface{}{
+		"client", e.Client,
+		"operation", e.Operation,
+		"method", e.Method,
+		"host", e.Host,
+		"path", e.Path,
+		"outcome", e.Outcome,
+		"statusCode", e.StatusCode,
+		"latencySeconds", e.LatencySeconds,
+	}
+	if e.Actor != nil {
+		args = append(args, "actor", e.Actor.String())
+	}
+	if e.Error != "" {
+		args = append(args, "error", e.Error)
+	}
+
+	s.Log.Info("GitHub API mutation", args...)
+}
+
+const (
+	defaultWebhookQueueSize = 1000
+	webhookAttempts         = 3
+	webhookTimeout          = 10 * time.Second
+)
+
+// WebhookSink POSTs the events as JSON to a URL, like the HTTP collector of a SIEM.
+//
+// The events are queued and delivered by Start in the background, so that the API calls don't wait for the webhook.
+// The events are dropped, with an error logged, when the queue is full or the delivery fails after retries.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+	Log    logr.Logger
+
+	events chan Event
+	// backoff is the wait before the first retry, doubled on every retry.
+	backoff time.Duration
+}
+
+// NewWebhookSink returns the WebhookSink delivering the events to the URL.
+// Add it to the manager, which runs Start, to deliver the events.
+func NewWebhookSink(url string, log logr.Logger) *WebhookSink {
+	return &WebhookSink{
+		URL:     url,
+		Client:  &http.Client{Timeout: webhookTimeout},
+		Log:     log,
+		events:  make(chan Event, defaultWebhookQueueSize),
+		backoff: time.Second,
+	}
+}
+
+func (s *WebhookSink) Record(_ context.Context, e Event) {
+	select {
+	case s.events <- e:
+	default:
+		s.Log.Error(fmt.Errorf("queue of %d events is full", cap(s.events)), "Dropped audit event", "operation", e.Operation, "path", e.Path)
+	}
+}
+
+// Start delivers the queued events until the context is done, and then the events left in the queue
+// for up to 10 seconds.
+func (s *WebhookSink) Start(ctx context.Context) error {
+	for {
+		select {
+		case e := <-s.events:
+			s.deliver(ctx, e)
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+			defer cancel()
+			for flushCtx.Err() == nil {
+				select {
+				case e := <-s.events:
+					s.deliver(flushCtx, e)
+				default:
+					return nil
+				}
+			}
+			if n := len(s.events); n > 0 {
+				s.Log.Error(flushCtx.Err(), "Dropped audit events left in the queue on stop", "count", n)
+			}
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica deliver its own events, as the replicas not elected as the leader
+// still serve the admission webhooks minting registration tokens.
+func (s *WebhookSink) NeedLeaderElection() bool {
+	return false
+}
+
+// deliver POSTs the event, retrying the failures until the context is done.
+// The requests don't use the context, so that the events dequeued as the manager stops are still delivered.
+func (s *WebhookSink) deliver(ctx context.Context, e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		s.Log.Error(err, "Dropped audit event", "operation", e.Operation, "path", e.Path)
+		return
+	}
+
+	backoff := s.backoff
+	for attempt := 1; ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	s.Log.Error(err, "Dropped audit event", "operation", e.Operation, "path", e.Path, "url", s.URL)
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		// Fail the first delivery to be retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("unable to decode event: %v", err)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, logr.Discard())
+	sink.backoff = time.Millisecond
+
+	sink.Record(context.Background(), Event{Operation: "create-registration-token", Actor: &Actor{Kind: "Runner", Namespace: "default", Name: "example"}})
+	sink.Record(context.Background(), Event{Operation: "remove-runner"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- sink.Start(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 delivered events, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if received[0].Operation != "create-registration-token" || received[0].Actor == nil || received[0].Actor.Name != "example" {
+		t.Errorf("unexpected first event: %+v", received[0])
+	}
+	if received[1].Operation != "remove-runner" {
+		t.Errorf("unexpected second event: %+v", received[1])
+	}
+}
+
+func TestWebhookSinkFlushesOnStop(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		delivered int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered++
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, logr.Discard())
+	for i := 0; i < 3; i++ {
+		sink.Record(context.Background(), Event{Operation: "remove-runner"})
+	}
+
+	// The events queued before the manager stops are delivered on stop
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sink.Start(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if delivered != 3 {
+		t.Errorf("expected 3 delivered events, got %d", delivered)
+	}
+}
+
+func TestWebhookSinkDropsWhenFull(t *testing.T) {
+	sink := NewWebhookSink("http://audit.example.com", logr.Discard())
+	sink.events = make(chan Event, 1)
+
+	sink.Record(context.Background(), Event{Operation: "create-registration-token"})
+	// Doesn't block the API call
+	sink.Record(context.Background(), Event{Operation: "remove-runner"})
+
+	if len(sink.events) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(sink.events))
+	}
+	if e := <-sink.events; e.Operation != "create-registration-token" {
+		t.Errorf("expected the first event to be kept, got %+v", e)
+	}
+}
@@ -0,0 +1,305 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/vault"
+	githubv69 "github.com/google/go-github/v69/github"
+	"golang.org/x/oauth2"
+)
+
+// Credentials is the set of credentials used to authenticate to the GitHub API.
+// Either Token, or all of AppID, AppInstallationID, and AppPrivateKey are set.
+type Credentials struct {
+	Token string
+
+	AppID             int64
+	AppInstallationID int64
+	// AppPrivateKey is the PEM-encoded private key of the GitHub App.
+	AppPrivateKey string
+}
+
+// IsApp returns true if the credentials are for a GitHub App.
+func (c *Credentials) IsApp() bool {
+	return c.Token == "" && c.AppID != 0
+}
+
+func (c *Credentials) validate() error {
+	if c.Token != "" {
+		return nil
+	}
+
+	if c.AppID == 0 || c.AppInstallationID == 0 || c.AppPrivateKey == "" {
+		return errors.New("credentials must contain either github_token, or all of github_app_id, github_app_installation_id, and github_app_private_key")
+	}
+
+	return nil
+}
+
+// CredentialProvider provides the credentials to authenticate to the GitHub API.
+// The client calls Credentials before every request, so implementations that fetch
+// the credentials from an external source should be wrapped with NewRefreshingCredentialProvider.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+// StaticTokenProvider provides a fixed personal access token.
+type StaticTokenProvider struct {
+	Token string
+}
+
+func (p *StaticTokenProvider) Credentials(_ context.Context) (*Credentials, error) {
+	return &Credentials{Token: p.Token}, nil
+}
+
+// AppProvider provides a fixed set of GitHub App credentials.
+type AppProvider struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     string
+}
+
+func (p *AppProvider) Credentials(_ context.Context) (*Credentials, error) {
+	return &Credentials{
+		AppID:             p.AppID,
+		AppInstallationID: p.InstallationID,
+		AppPrivateKey:     p.PrivateKey,
+	}, nil
+}
+
+// RefreshingCredentialProvider caches the credentials obtained from the source for the interval,
+// so that the credentials rotated in the source are picked up without restarting the process.
+type RefreshingCredentialProvider struct {
+	source   CredentialProvider
+	interval time.Duration
+
+	// now is overridden in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	creds     *Credentials
+	fetchedAt time.Time
+}
+
+// NewRefreshingCredentialProvider returns the provider that fetches the credentials from the source at most once per the interval.
+func NewRefreshingCredentialProvider(source CredentialProvider, interval time.Duration) *RefreshingCredentialProvider {
+	return &RefreshingCredentialProvider{
+		source:   source,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Credentials returns the cached credentials, fetching them from the source when they are older than the interval.
+// The stale credentials are returned when the refresh fails, so that a transient outage of the source
+// doesn't break the client until the credentials are actually revoked.
+func (p *RefreshingCredentialProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.creds != nil && p.now().Sub(p.fetchedAt) < p.interval {
+		return p.creds, nil
+	}
+
+	creds, err := p.source.Credentials(ctx)
+	if err == nil {
+		err = creds.validate()
+	}
+	if err != nil {
+		if p.creds != nil {
+			return p.creds, nil
+		}
+
+		return nil, fmt.Errorf("fetching github credentials: %w", err)
+	}
+
+	p.creds = creds
+	p.fetchedAt = p.now()
+
+	return creds, nil
+}
+
+// credentialsFromJSON decodes the credentials stored in an external secret store as a JSON object,
+// whose keys are the same as the keys of the Kubernetes secret used by githubAPICredentialsFrom.
+func credentialsFromJSON(data []byte) (*Credentials, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("decoding github credentials: %w", err)
+	}
+
+	return credentialsFromMap(m)
+}
+
+// credentialsFromMap reads the credentials from the keys of the Kubernetes secret used by githubAPICredentialsFrom.
+// The IDs can be either strings or numbers, as secret stores like Vault keep the types of JSON values.
+func credentialsFromMap(m map[string]interface{}) (*Credentials, error) {
+	get := func(k string) string {
+		v, ok := m[k]
+		if !ok || v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+
+	creds := Credentials{
+		Token:         get("github_token"),
+		AppPrivateKey: get("github_app_private_key"),
+	}
+
+	var err error
+
+	if v := get("github_app_id"); v != "" {
+		creds.AppID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing github_app_id: %w", err)
+		}
+	}
+
+	if v := get("github_app_installation_id"); v != "" {
+		creds.AppInstallationID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing github_app_installation_id: %w", err)
+		}
+	}
+
+	return &creds, nil
+}
+
+// credentialProviderTransport authenticates each request with the credentials obtained from the provider.
+// The underlying transport is recreated only when the credentials change, so that the installation token
+// minted for a GitHub App is reused until it expires.
+type credentialProviderTransport struct {
+	provider     CredentialProvider
+	config       Config
+	tokenOptions *githubv69.InstallationTokenOptions
+	// base is the unauthenticated transport. Defaults to http.DefaultTransport.
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	creds     Credentials
+	transport http.RoundTripper
+}
+
+func (t *credentialProviderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tr, err := t.transportFor(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.RoundTrip(req)
+}
+
+func (t *credentialProviderTransport) transportFor(ctx context.Context) (http.RoundTripper, error) {
+	creds, err := t.provider.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transport != nil && t.creds == *creds {
+		return t.transport, nil
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var tr http.RoundTripper
+
+	if creds.IsApp() {
+		tr, err = t.config.newAppTransport(base, creds.AppID, creds.AppInstallationID, creds.AppPrivateKey, t.tokenOptions)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if t.tokenOptions != nil {
+			return nil, errors.New("scoping the github api token requires github app credentials")
+		}
+
+		tr = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: creds.Token}),
+			Base:   base,
+		}
+	}
+
+	t.creds = *creds
+	t.transport = tr
+
+	return tr, nil
+}
+
+const (
+	CredentialSourceVault             = "vault"
+	CredentialSourceAWSSecretsManager = "aws-secrets-manager"
+	CredentialSourceGCPSecretManager  = "gcp-secret-manager"
+)
+
+// CredentialSourceConfig configures the external secret store to source the credentials from.
+type CredentialSourceConfig struct {
+	// Source is one of vault, aws-secrets-manager, and gcp-secret-manager.
+	Source string
+	// Secret is the path of the Vault secret, the name or the ARN of the AWS secret, or the resource name of the GCP secret version.
+	Secret string
+	// RefreshInterval is the interval to re-read the secret to pick up rotated credentials.
+	RefreshInterval time.Duration
+
+	VaultAddress  string
+	VaultToken    string
+	VaultRole     string
+	VaultAuthPath string
+}
+
+// NewCredentialProvider returns the refreshing provider for the source, or nil when no source is configured.
+func (c *CredentialSourceConfig) NewCredentialProvider() (CredentialProvider, error) {
+	var source CredentialProvider
+
+	switch c.Source {
+	case "":
+		return nil, nil
+	case CredentialSourceVault:
+		if c.VaultAddress == "" {
+			return nil, errors.New("vault address is required to source github credentials from vault")
+		}
+
+		source = &VaultProvider{
+			Vault: &vault.Client{
+				Address:  c.VaultAddress,
+				Token:    c.VaultToken,
+				Role:     c.VaultRole,
+				AuthPath: c.VaultAuthPath,
+			},
+			Path: c.Secret,
+		}
+	case CredentialSourceAWSSecretsManager:
+		source = &AWSSecretsManagerProvider{SecretID: c.Secret}
+	case CredentialSourceGCPSecretManager:
+		source = &GCPSecretManagerProvider{Name: c.Secret}
+	default:
+		return nil, fmt.Errorf("unsupported github credentials source %q: must be one of %s, %s, or %s", c.Source, CredentialSourceVault, CredentialSourceAWSSecretsManager, CredentialSourceGCPSecretManager)
+	}
+
+	if c.Secret == "" {
+		return nil, fmt.Errorf("secret is required to source github credentials from %s", c.Source)
+	}
+
+	interval := c.RefreshInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	return NewRefreshingCredentialProvider(source, interval), nil
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// AWSSecretsManagerProvider reads the credentials from a secret in AWS Secrets Manager.
+type AWSSecretsManagerProvider struct {
+	// SecretID is either the name or the ARN of the secret.
+	SecretID string
+
+	// Client defaults to the client configured via the default credential chain, like IRSA or the instance profile.
+	Client *secretsmanager.Client
+}
+
+func (p *AWSSecretsManagerProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	if p.Client == nil {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading aws config: %w", err)
+		}
+
+		p.Client = secretsmanager.NewFromConfig(cfg)
+	}
+
+	out, err := p.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.SecretID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting aws secret %s: %w", p.SecretID, err)
+	}
+
+	if out.SecretString != nil {
+		return credentialsFromJSON([]byte(*out.SecretString))
+	}
+
+	return credentialsFromJSON(out.SecretBinary)
+}
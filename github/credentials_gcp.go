@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	gcpMetadataTokenURL    = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpSecretManagerAPIURL = "https://secretmanager.googleapis.com/v1/"
+)
+
+// GCPSecretManagerProvider reads the credentials from a secret version in Google Cloud Secret Manager.
+// It authenticates as the service account of the node, or the one bound via Workload Identity,
+// using the access token served by the metadata server.
+type GCPSecretManagerProvider struct {
+	// Name is the resource name of the secret version like projects/my-project/secrets/arc-github/versions/latest.
+	Name string
+
+	HTTPClient *http.Client
+
+	// metadataTokenURL and apiURL are overridden in tests
+	metadataTokenURL string
+	apiURL           string
+}
+
+func (p *GCPSecretManagerProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("obtaining gcp access token: %w", err)
+	}
+
+	apiURL := p.apiURL
+	if apiURL == "" {
+		apiURL = gcpSecretManagerAPIURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+strings.TrimPrefix(p.Name, "/")+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+
+	if err := doJSON(p.HTTPClient, req, &res); err != nil {
+		return nil, fmt.Errorf("accessing gcp secret %s: %w", p.Name, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding gcp secret %s: %w", p.Name, err)
+	}
+
+	return credentialsFromJSON(data)
+}
+
+func (p *GCPSecretManagerProvider) accessToken(ctx context.Context) (string, error) {
+	tokenURL := p.metadataTokenURL
+	if tokenURL == "" {
+		tokenURL = gcpMetadataTokenURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := doJSON(p.HTTPClient, req, &res); err != nil {
+		return "", err
+	}
+
+	return res.AccessToken, nil
+}
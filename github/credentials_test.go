@@ -0,0 +1,222 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/vault"
+)
+
+type countingProvider struct {
+	calls int
+	creds *Credentials
+	err   error
+}
+
+func (p *countingProvider) Credentials(_ context.Context) (*Credentials, error) {
+	p.calls++
+	return p.creds, p.err
+}
+
+func TestRefreshingCredentialProvider(t *testing.T) {
+	source := &countingProvider{creds: &Credentials{Token: "token1"}}
+	p := NewRefreshingCredentialProvider(source, time.Minute)
+
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		creds, err := p.Credentials(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if creds.Token != "token1" {
+			t.Errorf("unexpected token: %s", creds.Token)
+		}
+	}
+	if source.calls != 1 {
+		t.Errorf("expected the credentials to be cached, but the source was called %d times", source.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	source.creds = &Credentials{Token: "token2"}
+
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Token != "token2" {
+		t.Errorf("expected the rotated token, got %s", creds.Token)
+	}
+
+	now = now.Add(2 * time.Minute)
+	source.err = errors.New("unavailable")
+
+	creds, err = p.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("expected the stale credentials on refresh failure, got error: %v", err)
+	}
+	if creds.Token != "token2" {
+		t.Errorf("expected the stale token, got %s", creds.Token)
+	}
+}
+
+func TestRefreshingCredentialProviderInvalidCredentials(t *testing.T) {
+	p := NewRefreshingCredentialProvider(&countingProvider{creds: &Credentials{AppID: 1}}, time.Minute)
+
+	if _, err := p.Credentials(context.Background()); err == nil {
+		t.Errorf("expected an error for incomplete github app credentials")
+	}
+}
+
+func TestCredentialsFromJSON(t *testing.T) {
+	creds, err := credentialsFromJSON([]byte(`{"github_app_id": 123, "github_app_installation_id": "456", "github_app_private_key": "key"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Credentials{AppID: 123, AppInstallationID: 456, AppPrivateKey: "key"}
+	if *creds != want {
+		t.Errorf("unexpected credentials: want %+v, got %+v", want, *creds)
+	}
+	if !creds.IsApp() {
+		t.Errorf("expected github app credentials")
+	}
+
+	if _, err := credentialsFromJSON([]byte(`{"github_app_id": "abc"}`)); err == nil {
+		t.Errorf("expected an error for a non-numeric app id")
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			w.Write([]byte(`{"auth": {"client_token": "vault-token"}}`))
+		case "/v1/secret/data/arc":
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data": {"data": {"github_token": "pat"}, "metadata": {"version": 1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	saToken := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(saToken, []byte("jwt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &VaultProvider{
+		Vault: &vault.Client{
+			Address:                 srv.URL,
+			Role:                    "arc",
+			ServiceAccountTokenPath: saToken,
+		},
+		Path: "secret/data/arc",
+	}
+
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Token != "pat" {
+		t.Errorf("unexpected token: %s", creds.Token)
+	}
+}
+
+func TestGCPSecretManagerProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "gcp-token"}`))
+		case "/v1/projects/p/secrets/arc/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer gcp-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			data := base64.StdEncoding.EncodeToString([]byte(`{"github_token": "pat"}`))
+			w.Write([]byte(`{"payload": {"data": "` + data + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := &GCPSecretManagerProvider{
+		Name:             "projects/p/secrets/arc/versions/latest",
+		metadataTokenURL: srv.URL + "/token",
+		apiURL:           srv.URL + "/v1/",
+	}
+
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Token != "pat" {
+		t.Errorf("unexpected token: %s", creds.Token)
+	}
+}
+
+func TestCredentialProviderTransport(t *testing.T) {
+	var got string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	provider := &StaticTokenProvider{Token: "token1"}
+	tr := &credentialProviderTransport{provider: provider}
+
+	for _, token := range []string{"token1", "token2"} {
+		provider.Token = token
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		if want := "Bearer " + token; got != want {
+			t.Errorf("unexpected authorization header: want %q, got %q", want, got)
+		}
+	}
+}
+
+func TestCredentialSourceConfig(t *testing.T) {
+	c := CredentialSourceConfig{}
+	if p, err := c.NewCredentialProvider(); err != nil || p != nil {
+		t.Errorf("expected no provider without source, got %v, %v", p, err)
+	}
+
+	c = CredentialSourceConfig{Source: "unknown", Secret: "s"}
+	if _, err := c.NewCredentialProvider(); err == nil {
+		t.Errorf("expected an error for an unknown source")
+	}
+
+	c = CredentialSourceConfig{Source: CredentialSourceAWSSecretsManager}
+	if _, err := c.NewCredentialProvider(); err == nil {
+		t.Errorf("expected an error without secret")
+	}
+
+	c = CredentialSourceConfig{Source: CredentialSourceGCPSecretManager, Secret: "projects/p/secrets/s/versions/latest"}
+	if _, err := c.NewCredentialProvider(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
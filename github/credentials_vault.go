@@ -0,0 +1,50 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/vault"
+)
+
+// VaultProvider reads the credentials from a secret in HashiCorp Vault.
+type VaultProvider struct {
+	Vault *vault.Client
+
+	// Path is the path to read the secret from, including the mount.
+	// For a KV version 2 secrets engine mounted at secret/, it looks like secret/data/arc/github.
+	Path string
+}
+
+func (p *VaultProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	data, err := p.Vault.Read(ctx, p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentialsFromMap(data)
+}
+
+// doJSON sends the request and decodes the JSON response into out.
+func doJSON(c *http.Client, req *http.Request, out interface{}) error {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -21,6 +21,50 @@ const (
     {"id": 2, "name": "test2", "os": "linux", "status": "offline", "busy": false}
   ]
 }
+`
+
+	JITConfigBody = `
+{
+  "runner": {"id": 3, "name": "test3", "os": "unknown", "status": "offline", "busy": false},
+  "encoded_jit_config": "fake-encoded-jit-config"
+}
+`
+
+	RunnerGroupsBody = `
+{
+  "total_count": 2,
+  "runner_groups": [
+    {"id": 1, "name": "Default", "default": true},
+    {"id": 2, "name": "custom", "default": false}
+  ]
+}
+`
+
+	RunnerReleaseBody = `
+{
+  "id": 1,
+  "tag_name": "v2.311.0",
+  "name": "v2.311.0"
+}
+`
+
+	RunnerReleaseByTagBody = `
+{
+  "id": 1,
+  "tag_name": "v2.311.0",
+  "name": "v2.311.0",
+  "body": "## SHA-256 Checksums\n\n- actions-runner-linux-x64-2.311.0.tar.gz <!-- BEGIN SHA linux-x64 -->29fc8cf2dab4c195bb147384e7e2c94cfd4d4022c793b346a6175435265aa278<!-- END SHA linux-x64 -->\n- actions-runner-linux-arm64-2.311.0.tar.gz <!-- BEGIN SHA linux-arm64 -->5d13b77e0aa5306b6c03e234ad1da4d9c6aa7831d26fd7e37a3656e77153611e<!-- END SHA linux-arm64 -->\n- actions-runner-win-x64-2.311.0.zip <!-- BEGIN SHA win-x64 -->e629628ea2b5efa2ba1eb9e8a0e1f0f1fa5fd5f40e2d3d0e1a1b6bb2d30c0e1a<!-- END SHA win-x64 -->\n"
+}
+`
+
+	RunnerLabelsBody = `
+{
+  "total_count": 2,
+  "labels": [
+    {"id": 1, "name": "self-hosted", "type": "read-only"},
+    {"id": 2, "name": "Linux", "type": "read-only"}
+  ]
+}
 `
 )
 
@@ -203,6 +247,64 @@ func NewServer(opts ...Option) *httptest.Server {
 			Body:   "",
 		},
 
+		// For RemoveRunnerCustomLabels
+		"/repos/test/valid/actions/runners/1/labels": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerLabelsBody,
+		},
+		"/repos/test/error/actions/runners/1/labels": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+		"/orgs/test/actions/runners/1/labels": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerLabelsBody,
+		},
+		"/orgs/error/actions/runners/1/labels": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+		"/enterprises/test/actions/runners/1/labels": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerLabelsBody,
+		},
+		"/enterprises/error/actions/runners/1/labels": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+
+		// For GenerateJITConfig
+		"/repos/test/valid/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+		"/repos/test/error/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+		"/orgs/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+		"/orgs/test/actions/runner-groups": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerGroupsBody,
+		},
+		"/enterprises/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+
+		// For GetLatestRunnerVersion
+		"/repos/actions/runner/releases/latest": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerReleaseBody,
+		},
+		"/repos/actions/runner/releases/tags/v2.311.0": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerReleaseByTagBody,
+		},
+
 		// For auto-scaling based on the number of queued(pending) workflow runs
 		"/repos/test/valid/actions/runs": config.FixedResponses.ListRepositoryWorkflowRuns,
 
@@ -62,7 +62,7 @@ func (r *RunnersList) handleRemove() http.HandlerFunc {
 				r.runners = append(r.runners[:i], r.runners[i+1:]...)
 			}
 		}
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
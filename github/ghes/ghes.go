@@ -0,0 +1,160 @@
+// Package ghes detects the version of GitHub Enterprise Server and tells which API features it supports,
+// so that ARC can skip or reject features unavailable on older versions instead of failing with 404s.
+package ghes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Capability is a feature of the GitHub API that is unavailable on older GitHub Enterprise Server versions.
+type Capability string
+
+const (
+	// CapabilityRunnerGroups is the API to list runner groups and the repositories that can access them.
+	CapabilityRunnerGroups Capability = "runner-groups"
+	// CapabilityWorkflowJobLogs is the API to download the logs of a workflow job.
+	CapabilityWorkflowJobLogs Capability = "workflow-job-logs"
+	// CapabilityRunnerScaleSets is the Actions service API used by runner scale sets.
+	CapabilityRunnerScaleSets Capability = "runner-scale-sets"
+)
+
+// minVersions is the earliest GitHub Enterprise Server version that supports each capability.
+var minVersions = map[Capability]Version{
+	CapabilityRunnerGroups:    {Major: 3, Minor: 0},
+	CapabilityWorkflowJobLogs: {Major: 3, Minor: 0},
+	CapabilityRunnerScaleSets: {Major: 3, Minor: 9},
+}
+
+// Version is a GitHub Enterprise Server version.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less returns true if v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+// ParseVersion parses the version like 3.9.0 reported as installed_version by the /meta API.
+// A missing patch version is treated as 0 and any pre-release or build suffix is ignored.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid github enterprise server version %q", s)
+	}
+
+	var nums [3]int
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid github enterprise server version %q: %w", s, err)
+		}
+		nums[i] = n
+	}
+
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// Info is the result of the version detection.
+type Info struct {
+	// Version is nil for GitHub.com and GitHub Enterprise Cloud, whose APIs support every capability.
+	Version *Version
+}
+
+// Supports returns true if the GitHub instance supports the capability.
+func (i Info) Supports(c Capability) bool {
+	if i.Version == nil {
+		return true
+	}
+
+	min, ok := minVersions[c]
+	if !ok {
+		return true
+	}
+
+	return !i.Version.Less(min)
+}
+
+// UnsupportedError returns the error telling the capability requires a newer version, or nil if it is supported.
+func (i Info) UnsupportedError(c Capability) error {
+	if i.Supports(c) {
+		return nil
+	}
+
+	return &UnsupportedError{Capability: c, Version: *i.Version, MinVersion: minVersions[c]}
+}
+
+// UnsupportedError is returned when the capability is unavailable on the detected GitHub Enterprise Server version.
+type UnsupportedError struct {
+	Capability Capability
+	Version    Version
+	MinVersion Version
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s requires GitHub Enterprise Server %d.%d or later, but the server is running %s", e.Capability, e.MinVersion.Major, e.MinVersion.Minor, e.Version)
+}
+
+// Detect reads the version from the /meta API of the GitHub instance at apiURL, like https://ghes.example.com/api/v3/.
+// The client is expected to authenticate the request when the instance runs in private mode.
+func Detect(ctx context.Context, client *http.Client, apiURL string) (Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/meta", nil)
+	if err != nil {
+		return Info{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("fetching github meta: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Info{}, fmt.Errorf("fetching github meta: unexpected status %d", res.StatusCode)
+	}
+
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&meta); err != nil {
+		return Info{}, fmt.Errorf("decoding github meta: %w", err)
+	}
+
+	if meta.InstalledVersion == "" {
+		return Info{}, nil
+	}
+
+	v, err := ParseVersion(meta.InstalledVersion)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{Version: &v}, nil
+}
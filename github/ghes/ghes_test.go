@@ -0,0 +1,90 @@
+package ghes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Version
+		wantErr bool
+	}{
+		{in: "3.9.0", want: Version{3, 9, 0}},
+		{in: "3.10", want: Version{3, 10, 0}},
+		{in: "v3.12.1-rc.1", want: Version{3, 12, 1}},
+		{in: "3", wantErr: true},
+		{in: "3.x.0", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseVersion(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestInfoSupports(t *testing.T) {
+	if !(Info{}).Supports(CapabilityRunnerScaleSets) {
+		t.Errorf("github.com should support every capability")
+	}
+
+	old := Info{Version: &Version{Major: 3, Minor: 8, Patch: 5}}
+	if old.Supports(CapabilityRunnerScaleSets) {
+		t.Errorf("3.8.5 should not support runner scale sets")
+	}
+	if !old.Supports(CapabilityRunnerGroups) {
+		t.Errorf("3.8.5 should support runner groups")
+	}
+	if err := old.UnsupportedError(CapabilityRunnerScaleSets); err == nil {
+		t.Errorf("expected an error for runner scale sets on 3.8.5")
+	}
+
+	recent := Info{Version: &Version{Major: 3, Minor: 10}}
+	if !recent.Supports(CapabilityRunnerScaleSets) {
+		t.Errorf("3.10.0 should support runner scale sets")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want *Version
+	}{
+		{body: `{"installed_version":"3.9.2","verifiable_password_authentication":true}`, want: &Version{3, 9, 2}},
+		{body: `{"verifiable_password_authentication":true}`, want: nil},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/meta" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(tc.body))
+		}))
+
+		info, err := Detect(context.Background(), srv.Client(), srv.URL+"/api/v3/")
+		srv.Close()
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if (info.Version == nil) != (tc.want == nil) || (info.Version != nil && *info.Version != *tc.want) {
+			t.Errorf("unexpected version: want %v, got %v", tc.want, info.Version)
+		}
+	}
+}
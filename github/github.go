@@ -1,21 +1,30 @@
 package github
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/actions/actions-runner-controller/build"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/github/ghes"
+	"github.com/actions/actions-runner-controller/github/httptransport"
 	"github.com/actions/actions-runner-controller/github/metrics"
 	"github.com/actions/actions-runner-controller/logging"
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v52/github"
+	githubv69 "github.com/google/go-github/v69/github"
 	"github.com/gregjones/httpcache"
 	"golang.org/x/oauth2"
 )
@@ -33,65 +42,116 @@ type Config struct {
 	BasicauthPassword string `split_words:"true"`
 	RunnerGitHubURL   string `split_words:"true"`
 
+	// AppInstallationRepositories and AppInstallationPermissions restrict the installation tokens minted for the GitHub App
+	// to the repositories and the permissions. Both are ignored unless the client authenticates as a GitHub App.
+	AppInstallationRepositories []string          `split_words:"true"`
+	AppInstallationPermissions  map[string]string `split_words:"true"`
+
+	// ProxyURL, RootCAsFile, and TLSMinVersion configure the transport shared by the API calls and the downloads
+	// from the URLs the API redirects to, like workflow job logs. See httptransport.Config for details.
+	ProxyURL      string `split_words:"true"`
+	RootCAsFile   string `split_words:"true"`
+	TLSMinVersion string `split_words:"true"`
+
+	// CredentialProvider provides the credentials in place of Token, AppID, AppInstallationID, and AppPrivateKey,
+	// so that the credentials can be sourced from an external secret store and refreshed without restarting.
+	CredentialProvider CredentialProvider `ignored:"true"`
+
+	// AuditSink records the mutating API calls, including the installation token mints, when set.
+	AuditSink audit.Sink `ignored:"true"`
+
+	// Tenant partitions the registration and remove tokens cached across clients, so that a client never gets the tokens
+	// minted with the credentials of another tenant. Empty for the controller-wide credentials.
+	Tenant string `ignored:"true"`
+
 	Log *logr.Logger
 }
 
 // Client wraps GitHub client with some additional
 type Client struct {
 	*github.Client
-	regTokens map[string]*github.RegistrationToken
-	mu        sync.Mutex
+	mu sync.Mutex
 	// GithubBaseURL to Github without API suffix.
 	GithubBaseURL string
 	IsEnterprise  bool
+
+	// config is the configuration the client was created from, used to derive scoped clients.
+	config Config
+	// serverInfo is the version of GitHub Enterprise Server detected by ServerInfo, guarded by serverInfoMu.
+	serverInfo   *ghes.Info
+	serverInfoMu sync.Mutex
+
+	// transport is the unauthenticated transport with the proxy and TLS settings of the config.
+	transport http.RoundTripper
+
+	// scopedClients caches the clients derived via WithTokenScope by their scopes,
+	// so that each of them keeps its installation token across calls.
+	scopedClients map[string]*Client
 }
 
 type BasicAuthTransport struct {
 	Username string
 	Password string
+
+	// Transport defaults to http.DefaultTransport.
+	Transport http.RoundTripper
 }
 
 func (p BasicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.SetBasicAuth(p.Username, p.Password)
-	return http.DefaultTransport.RoundTrip(req)
+
+	tr := p.Transport
+	if tr == nil {
+		tr = http.DefaultTransport
+	}
+
+	return tr.RoundTrip(req)
 }
 
 // NewClient creates a Github Client
 func (c *Config) NewClient() (*Client, error) {
+	base, err := c.transportConfig().NewTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	// The audit transport sits below the authentication so that it also sees the installation tokens minted by it.
+	// base is kept unaudited for the unauthenticated downloads, which don't mutate anything.
+	var api http.RoundTripper = base
+	if c.AuditSink != nil {
+		api = audit.Transport{Transport: base, Sink: c.AuditSink, Client: "github"}
+	}
+
 	var transport http.RoundTripper
 	if len(c.BasicauthUsername) > 0 && len(c.BasicauthPassword) > 0 {
-		transport = BasicAuthTransport{Username: c.BasicauthUsername, Password: c.BasicauthPassword}
+		transport = BasicAuthTransport{Username: c.BasicauthUsername, Password: c.BasicauthPassword, Transport: api}
 	} else if len(c.Token) > 0 {
-		transport = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})).Transport
-	} else {
-		var tr *ghinstallation.Transport
+		transport = &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token}), Base: api}
+	} else if c.CredentialProvider != nil {
+		opts, err := c.installationTokenOptions()
+		if err != nil {
+			return nil, err
+		}
 
-		if _, err := os.Stat(c.AppPrivateKey); err == nil {
-			tr, err = ghinstallation.NewKeyFromFile(http.DefaultTransport, c.AppID, c.AppInstallationID, c.AppPrivateKey)
-			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key at %s: %v", c.AppPrivateKey, err)
-			}
-		} else {
-			tr, err = ghinstallation.New(http.DefaultTransport, c.AppID, c.AppInstallationID, []byte(c.AppPrivateKey))
-			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(c.AppPrivateKey), strings.Split(c.AppPrivateKey, "\n")[0], err)
-			}
+		transport = &credentialProviderTransport{provider: c.CredentialProvider, config: *c, tokenOptions: opts, base: api}
+	} else {
+		opts, err := c.installationTokenOptions()
+		if err != nil {
+			return nil, err
 		}
 
-		if len(c.EnterpriseURL) > 0 {
-			githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
-			if err != nil {
-				return nil, fmt.Errorf("enterprise url incorrect: %v", err)
-			}
-			tr.BaseURL = githubAPIURL
-		} else if c.URL != "" && tr.BaseURL != c.URL {
-			tr.BaseURL = c.URL
+		tr, err := c.newAppTransport(api, c.AppID, c.AppInstallationID, c.AppPrivateKey, opts)
+		if err != nil {
+			return nil, err
 		}
+
 		transport = tr
 	}
 
+	// The cache revalidates the stale responses of the list endpoints polled for autoscaling, like runners and workflow runs,
+	// with their ETags. The resulting 304 Not Modified responses don't count against the rate limit.
 	cached := httpcache.NewTransport(httpcache.NewMemoryCache())
-	cached.Transport = transport
+	cached.Transport = rateLimitTransport{limiter: newRateLimiter(), Transport: transport}
 	loggingTransport := logging.Transport{Transport: cached, Log: c.Log}
 	metricsTransport := metrics.Transport{Transport: loggingTransport}
 	httpClient := &http.Client{Transport: metricsTransport}
@@ -145,60 +205,243 @@ func (c *Config) NewClient() (*Client, error) {
 	client.UserAgent = "actions-runner-controller/" + build.Version
 	return &Client{
 		Client:        client,
-		regTokens:     map[string]*github.RegistrationToken{},
 		mu:            sync.Mutex{},
 		GithubBaseURL: githubBaseURL,
 		IsEnterprise:  isEnterprise,
+		config:        *c,
+		scopedClients: map[string]*Client{},
+		transport:     base,
 	}, nil
 }
 
-// GetRegistrationToken returns a registration token tied with the name of repository and runner.
-func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
+func (c *Config) transportConfig() httptransport.Config {
+	return httptransport.Config{
+		ProxyURL:      c.ProxyURL,
+		RootCAsFile:   c.RootCAsFile,
+		TLSMinVersion: c.TLSMinVersion,
+	}
+}
+
+// UnauthenticatedHTTPClient returns the HTTP client that shares the proxy and TLS settings with the client
+// but doesn't authenticate requests, used to download from the URLs the API redirects to, like workflow job logs.
+// AuditSink returns the sink recording the mutating API calls of the client, so that the clients created
+// from the credentials of the other secrets can record theirs to the same sink. It is nil when auditing is disabled.
+func (c *Client) AuditSink() audit.Sink {
+	return c.config.AuditSink
+}
+
+func (c *Client) UnauthenticatedHTTPClient() *http.Client {
+	return &http.Client{Transport: c.transport}
+}
+
+// newAppTransport returns the transport that authenticates as the installation of the GitHub App.
+// The private key is either the path to the key file or the content of the key.
+func (c *Config) newAppTransport(base http.RoundTripper, appID, installationID int64, privateKey string, opts *githubv69.InstallationTokenOptions) (*ghinstallation.Transport, error) {
+	var tr *ghinstallation.Transport
+
+	if _, err := os.Stat(privateKey); err == nil {
+		tr, err = ghinstallation.NewKeyFromFile(base, appID, installationID, privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key at %s: %v", privateKey, err)
+		}
+	} else {
+		tr, err = ghinstallation.New(base, appID, installationID, []byte(privateKey))
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(privateKey), strings.Split(privateKey, "\n")[0], err)
+		}
+	}
+
+	if len(c.EnterpriseURL) > 0 {
+		githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+		}
+		tr.BaseURL = githubAPIURL
+	} else if c.URL != "" && tr.BaseURL != c.URL {
+		tr.BaseURL = c.URL
+	}
+
+	tr.InstallationTokenOptions = opts
+
+	return tr, nil
+}
+
+// installationTokenOptions returns the parameters of the installation token request that restricts the token
+// to AppInstallationRepositories and AppInstallationPermissions, or nil when neither is set.
+func (c *Config) installationTokenOptions() (*githubv69.InstallationTokenOptions, error) {
+	if len(c.AppInstallationRepositories) == 0 && len(c.AppInstallationPermissions) == 0 {
+		return nil, nil
+	}
+
+	var opts githubv69.InstallationTokenOptions
+
+	for _, r := range c.AppInstallationRepositories {
+		// The API accepts repository names without the owner, as an installation is bound to a single owner
+		if i := strings.LastIndex(r, "/"); i >= 0 {
+			r = r[i+1:]
+		}
+
+		opts.Repositories = append(opts.Repositories, r)
+	}
+
+	if len(c.AppInstallationPermissions) > 0 {
+		data, err := json.Marshal(c.AppInstallationPermissions)
+		if err != nil {
+			return nil, err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		var perms githubv69.InstallationPermissions
+
+		if err := dec.Decode(&perms); err != nil {
+			return nil, fmt.Errorf("invalid github app installation permissions: %v", err)
+		}
+
+		opts.Permissions = &perms
+	}
+
+	return &opts, nil
+}
+
+// WithTokenScope returns a client whose GitHub App installation tokens are restricted to the repositories and the permissions,
+// so that a runner that only needs to act on a repository cannot obtain a token capable of acting on another one.
+// It returns an error when the client doesn't authenticate as a GitHub App, as only installation tokens can be scoped.
+func (c *Client) WithTokenScope(repositories []string, permissions map[string]string) (*Client, error) {
+	if c.config.AppID == 0 && c.config.CredentialProvider == nil {
+		return nil, errors.New("scoping the github api token requires github app credentials")
+	}
+
+	repos := append([]string{}, repositories...)
+	sort.Strings(repos)
+
+	// encoding/json sorts map keys so that the key is stable regardless of the order of the permissions
+	key, err := json.Marshal([]interface{}{repos, permissions})
+	if err != nil {
+		return nil, err
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	key := getRegistrationKey(org, repo, enterprise)
-	rt, ok := c.regTokens[key]
+	if cli, ok := c.scopedClients[string(key)]; ok {
+		return cli, nil
+	}
+
+	conf := c.config
+	conf.AppInstallationRepositories = repos
+	conf.AppInstallationPermissions = permissions
+
+	cli, err := conf.NewClient()
+	if err != nil {
+		return nil, err
+	}
 
-	// We'd like to allow the runner just starting up to miss the expiration date by a bit.
-	// Note that this means that we're going to cache Creation Registraion Token API response longer than the
-	// recommended cache duration.
-	//
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-a-repository
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-organization
-	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-enterprise
-	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
-	//
-	// This is currently set to 30 minutes as the result of the discussion took place at the following issue:
-	// https://github.com/actions/actions-runner-controller/issues/1295
-	runnerStartupTimeout := 30 * time.Minute
+	c.scopedClients[string(key)] = cli
 
-	if ok && rt.GetExpiresAt().After(time.Now().Add(runnerStartupTimeout)) {
-		return rt, nil
+	return cli, nil
+}
+
+// ServerInfo returns the version of GitHub Enterprise Server the client talks to, detected via the /meta API on the first call.
+// It returns the zero Info, which supports every capability, for GitHub.com.
+func (c *Client) ServerInfo(ctx context.Context) (ghes.Info, error) {
+	if !c.IsEnterprise {
+		return ghes.Info{}, nil
 	}
 
-	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+	c.serverInfoMu.Lock()
+	defer c.serverInfoMu.Unlock()
 
+	if c.serverInfo != nil {
+		return *c.serverInfo, nil
+	}
+
+	info, err := ghes.Detect(ctx, c.Client.Client(), c.Client.BaseURL.String())
 	if err != nil {
-		return rt, err
+		return ghes.Info{}, err
 	}
 
-	rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
+	c.serverInfo = &info
+
+	return info, nil
+}
 
+// Supports returns false when the GitHub Enterprise Server is known to lack the capability.
+// A failure to detect the version is treated as supported, so that the caller behaves as if the detection didn't exist.
+func (c *Client) Supports(ctx context.Context, capability ghes.Capability) bool {
+	info, err := c.ServerInfo(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create registration token: %v", err)
+		return true
 	}
 
-	if res.StatusCode != 201 {
-		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	return info.Supports(capability)
+}
+
+// GetRegistrationToken returns a registration token tied with the name of repository and runner.
+// Tokens are cached across the clients of the same tenant and refreshed in the background before they expire.
+func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
+	t, err := tokens.get(ctx, tokenKindRegistration, c.tokenCacheKey(enterprise, org, repo), func(ctx context.Context) (*cachedToken, error) {
+		enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registration token: %v", err)
+		}
+
+		if res.StatusCode != 201 {
+			return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+		}
+
+		return &cachedToken{token: rt.GetToken(), expiresAt: rt.GetExpiresAt().Time}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	c.regTokens[key] = rt
-	go func() {
-		c.cleanup()
-	}()
+	return &github.RegistrationToken{
+		Token:     github.String(t.token),
+		ExpiresAt: &github.Timestamp{Time: t.expiresAt},
+	}, nil
+}
+
+// GetRemoveToken returns a token to remove a runner from the enterprise, organization, or repository
+// using the config script of the runner. Tokens are cached in the same way as registration tokens.
+func (c *Client) GetRemoveToken(ctx context.Context, enterprise, org, repo string) (*github.RemoveToken, error) {
+	t, err := tokens.get(ctx, tokenKindRemove, c.tokenCacheKey(enterprise, org, repo), func(ctx context.Context) (*cachedToken, error) {
+		enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		rt, res, err := c.createRemoveToken(ctx, enterprise, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remove token: %v", err)
+		}
+
+		if res.StatusCode != 201 {
+			return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+		}
 
-	return rt, nil
+		return &cachedToken{token: rt.GetToken(), expiresAt: rt.GetExpiresAt().Time}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.RemoveToken{
+		Token:     github.String(t.token),
+		ExpiresAt: &github.Timestamp{Time: t.expiresAt},
+	}, nil
+}
+
+// tokenCacheKey returns the key of the tokens for the enterprise, organization, or repository on the GitHub instance the client talks to,
+// within the tenant of the client.
+func (c *Client) tokenCacheKey(enterprise, org, repo string) string {
+	return fmt.Sprintf("tenant=%s,url=%s,%s", c.config.Tenant, c.Client.BaseURL, getRegistrationKey(org, repo, enterprise))
 }
 
 // RemoveRunner removes a runner with specified runner ID from repository.
@@ -222,6 +465,151 @@ func (c *Client) RemoveRunner(ctx context.Context, enterprise, org, repo string,
 	return nil
 }
 
+// defaultRunnerGroupID is the ID of the Default runner group that every organization and enterprise has,
+// and the repository runners always belong to.
+const defaultRunnerGroupID = 1
+
+// JITRunnerConfig is the just-in-time configuration of a runner.
+type JITRunnerConfig struct {
+	// Runner is the runner registered along with the configuration.
+	Runner *github.Runner `json:"runner,omitempty"`
+	// EncodedJITConfig is the single-use configuration passed to the runner via the --jitconfig flag.
+	EncodedJITConfig string `json:"encoded_jit_config"`
+}
+
+// GenerateJITConfig registers a runner with the name and labels to the enterprise, organization, or repository, and returns
+// its just-in-time configuration. The runner starts with the configuration without the registration and remove tokens,
+// and is removed from GitHub by itself once it completes a job.
+func (c *Client) GenerateJITConfig(ctx context.Context, enterprise, org, repo, name, group, workFolder string, labels []string) (*JITRunnerConfig, error) {
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID, err := c.getRunnerGroupID(ctx, enterprise, owner, repo, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	if len(repo) > 0 {
+		path = fmt.Sprintf("repos/%v/%v/actions/runners/generate-jitconfig", owner, repo)
+	} else if len(owner) > 0 {
+		path = fmt.Sprintf("orgs/%v/actions/runners/generate-jitconfig", owner)
+	} else {
+		path = fmt.Sprintf("enterprises/%v/actions/runners/generate-jitconfig", enterprise)
+	}
+
+	body := struct {
+		Name          string   `json:"name"`
+		RunnerGroupID int64    `json:"runner_group_id"`
+		Labels        []string `json:"labels"`
+		WorkFolder    string   `json:"work_folder,omitempty"`
+	}{
+		Name:          name,
+		RunnerGroupID: groupID,
+		// The API requires at least one label
+		Labels:     append([]string{"self-hosted"}, labels...),
+		WorkFolder: workFolder,
+	}
+
+	// go-github v52 doesn't cover the JIT runner configuration API yet
+	req, err := c.Client.NewRequest(http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Runners can't be registered without the configurations, so these are the last requests to shed, as the tokens are.
+	var config JITRunnerConfig
+	res, err := c.Client.Do(WithRequestPriority(ctx, PriorityHigh), req, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jit config: %w", err)
+	}
+
+	if res.StatusCode != 201 {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	return &config, nil
+}
+
+// getRunnerGroupID returns the ID of the runner group with the name in the enterprise or organization.
+func (c *Client) getRunnerGroupID(ctx context.Context, enterprise, org, repo, group string) (int64, error) {
+	if group == "" || len(repo) > 0 {
+		return defaultRunnerGroupID, nil
+	}
+
+	var path string
+	if len(org) > 0 {
+		path = fmt.Sprintf("orgs/%v/actions/runner-groups", org)
+	} else {
+		path = fmt.Sprintf("enterprises/%v/actions/runner-groups", enterprise)
+	}
+
+	page := 1
+	for {
+		req, err := c.Client.NewRequest(http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", path, page), nil)
+		if err != nil {
+			return 0, err
+		}
+
+		var list github.RunnerGroups
+		res, err := c.Client.Do(ctx, req, &list)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list runner groups: %w", err)
+		}
+
+		for _, g := range list.RunnerGroups {
+			if g.GetName() == group {
+				return g.GetID(), nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return 0, fmt.Errorf("runner group %q not found", group)
+}
+
+// RemoveRunnerCustomLabels removes all the custom labels from the runner, so that it is no longer assigned the jobs
+// targeting them. The read-only labels like self-hosted and the OS remain.
+func (c *Client) RemoveRunnerCustomLabels(ctx context.Context, enterprise, org, repo string, runnerID int64) error {
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+
+	if err != nil {
+		return err
+	}
+
+	var path string
+	if len(repo) > 0 {
+		path = fmt.Sprintf("repos/%v/%v/actions/runners/%v/labels", owner, repo, runnerID)
+	} else if len(owner) > 0 {
+		path = fmt.Sprintf("orgs/%v/actions/runners/%v/labels", owner, runnerID)
+	} else {
+		path = fmt.Sprintf("enterprises/%v/actions/runners/%v/labels", enterprise, runnerID)
+	}
+
+	// go-github doesn't cover the runner labels API yet
+	req, err := c.Client.NewRequest(http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Client.Do(WithRequestPriority(ctx, PriorityHigh), req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove runner labels: %w", err)
+	}
+
+	if res.StatusCode != 200 {
+		return fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	return nil
+}
+
 // ListRunners returns a list of runners of specified owner/repository name.
 func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string) ([]*github.Runner, error) {
 	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
@@ -306,22 +694,13 @@ func (c *Client) ListRunnerGroupRepositoryAccesses(ctx context.Context, org stri
 	return repos, nil
 }
 
-// cleanup removes expired registration tokens.
-func (c *Client) cleanup() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	for key, rt := range c.regTokens {
-		if rt.GetExpiresAt().Before(time.Now()) {
-			delete(c.regTokens, key)
-		}
-	}
-}
-
 // wrappers for github functions (switch between enterprise/organization/repository mode)
 // so the calling functions don't need to switch and their code is a bit cleaner
 
 func (c *Client) createRegistrationToken(ctx context.Context, enterprise, org, repo string) (*github.RegistrationToken, *github.Response, error) {
+	// Runners can't be registered or unregistered without the tokens, so these are the last requests to shed.
+	ctx = WithRequestPriority(ctx, PriorityHigh)
+
 	if len(repo) > 0 {
 		return c.Client.Actions.CreateRegistrationToken(ctx, org, repo)
 	}
@@ -331,6 +710,32 @@ func (c *Client) createRegistrationToken(ctx context.Context, enterprise, org, r
 	return c.Client.Enterprise.CreateRegistrationToken(ctx, enterprise)
 }
 
+func (c *Client) createRemoveToken(ctx context.Context, enterprise, org, repo string) (*github.RemoveToken, *github.Response, error) {
+	// Runners can't be registered or unregistered without the tokens, so these are the last requests to shed.
+	ctx = WithRequestPriority(ctx, PriorityHigh)
+
+	if len(repo) > 0 {
+		return c.Client.Actions.CreateRemoveToken(ctx, org, repo)
+	}
+	if len(org) > 0 {
+		return c.Client.Actions.CreateOrganizationRemoveToken(ctx, org)
+	}
+
+	// go-github doesn't cover the enterprise remove token API yet
+	req, err := c.Client.NewRequest(http.MethodPost, fmt.Sprintf("enterprises/%v/actions/runners/remove-token", enterprise), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rt := new(github.RemoveToken)
+	res, err := c.Client.Do(ctx, req, rt)
+	if err != nil {
+		return nil, res, err
+	}
+
+	return rt, res, nil
+}
+
 func (c *Client) removeRunner(ctx context.Context, enterprise, org, repo string, runnerID int64) (*github.Response, error) {
 	if len(repo) > 0 {
 		return c.Client.Actions.RemoveRunner(ctx, org, repo, runnerID)
@@ -397,6 +802,318 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// ListWorkflowJobs returns all the jobs of the latest attempt of the specified workflow run.
+func (c *Client) ListWorkflowJobs(ctx context.Context, owner, repo string, runID int64) ([]*github.WorkflowJob, error) {
+	var jobs []*github.WorkflowJob
+
+	opts := github.ListWorkflowJobsOptions{
+		Filter: "latest",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		list, res, err := c.Client.Actions.ListWorkflowJobs(ctx, owner, repo, runID, &opts)
+		if err != nil {
+			return jobs, fmt.Errorf("failed to list workflow jobs: %w", err)
+		}
+
+		jobs = append(jobs, list.Jobs...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return jobs, nil
+}
+
+// GetWorkflowRun returns the workflow run, which tells the event that triggered it and its head repository.
+func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo string, runID int64) (*github.WorkflowRun, error) {
+	run, _, err := c.Client.Actions.GetWorkflowRunByID(ctx, owner, repo, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow run: %w", err)
+	}
+
+	return run, nil
+}
+
+// GetWorkflowRunPullRequest returns the pull request that triggered the workflow run, or nil when there's none.
+// The pull requests from forks aren't listed in the workflow run, so they're looked up by the head repository and branch of the run.
+func (c *Client) GetWorkflowRunPullRequest(ctx context.Context, owner, repo string, run *github.WorkflowRun) (*github.PullRequest, error) {
+	number := 0
+
+	if len(run.PullRequests) > 0 {
+		number = run.PullRequests[0].GetNumber()
+	} else {
+		head := run.GetHeadRepository().GetOwner().GetLogin() + ":" + run.GetHeadBranch()
+
+		prs, _, err := c.Client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+			State:       "open",
+			Head:        head,
+			ListOptions: github.ListOptions{PerPage: 10},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pull requests with head %s: %w", head, err)
+		}
+
+		for _, pr := range prs {
+			if pr.GetHead().GetSHA() == run.GetHeadSHA() {
+				number = pr.GetNumber()
+				break
+			}
+		}
+	}
+
+	if number == 0 {
+		return nil, nil
+	}
+
+	// The pull requests in the lists lack some fields, like the author association
+	pr, _, err := c.Client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request %d: %w", number, err)
+	}
+
+	return pr, nil
+}
+
+// ListPullRequestFiles returns the files changed by the pull request, up to the 3000 files the API lists.
+func (c *Client) ListPullRequestFiles(ctx context.Context, owner, repo string, number int) ([]*github.CommitFile, error) {
+	var files []*github.CommitFile
+
+	opts := github.ListOptions{PerPage: 100}
+
+	for {
+		list, res, err := c.Client.PullRequests.ListFiles(ctx, owner, repo, number, &opts)
+		if err != nil {
+			return files, fmt.Errorf("failed to list files of pull request %d: %w", number, err)
+		}
+
+		files = append(files, list...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return files, nil
+}
+
+// GetWorkflowFile returns the path and the content at the ref of the workflow file of the workflow.
+func (c *Client) GetWorkflowFile(ctx context.Context, owner, repo string, workflowID int64, ref string) (string, []byte, error) {
+	wf, _, err := c.Client.Actions.GetWorkflowByID(ctx, owner, repo, workflowID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get workflow %d: %w", workflowID, err)
+	}
+
+	file, _, _, err := c.Client.Repositories.GetContents(ctx, owner, repo, wf.GetPath(), &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get workflow file %s at %s: %w", wf.GetPath(), ref, err)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode workflow file %s: %w", wf.GetPath(), err)
+	}
+
+	return wf.GetPath(), []byte(content), nil
+}
+
+// ListHookDeliveries returns the deliveries of the repository webhook when repo is specified,
+// or the organization webhook otherwise, newest first, until the first one delivered before since.
+func (c *Client) ListHookDeliveries(ctx context.Context, org, repo string, hookID int64, since time.Time) ([]*github.HookDelivery, error) {
+	var owner, repoName string
+
+	if len(repo) > 0 {
+		var err error
+
+		owner, repoName, err = splitOwnerAndRepo(repo)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(org) == 0 {
+		return nil, fmt.Errorf("either org or repo must be specified to list webhook deliveries")
+	}
+
+	var deliveries []*github.HookDelivery
+
+	opts := github.ListCursorOptions{
+		PerPage: 100,
+	}
+
+	for {
+		var (
+			list []*github.HookDelivery
+			res  *github.Response
+			err  error
+		)
+
+		if len(repo) > 0 {
+			list, res, err = c.Client.Repositories.ListHookDeliveries(ctx, owner, repoName, hookID, &opts)
+		} else {
+			list, res, err = c.Client.Organizations.ListHookDeliveries(ctx, org, hookID, &opts)
+		}
+		if err != nil {
+			return deliveries, fmt.Errorf("failed to list webhook deliveries: %w", err)
+		}
+
+		for _, d := range list {
+			if d.DeliveredAt != nil && d.DeliveredAt.Before(since) {
+				return deliveries, nil
+			}
+
+			deliveries = append(deliveries, d)
+		}
+
+		if res.Cursor == "" {
+			break
+		}
+		opts.Cursor = res.Cursor
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverHookDelivery asks GitHub to redeliver the webhook delivery.
+// See ListHookDeliveries for how the webhook is determined from org and repo.
+func (c *Client) RedeliverHookDelivery(ctx context.Context, org, repo string, hookID, deliveryID int64) error {
+	var err error
+
+	if len(repo) > 0 {
+		owner, repoName, splitErr := splitOwnerAndRepo(repo)
+		if splitErr != nil {
+			return splitErr
+		}
+
+		_, _, err = c.Client.Repositories.RedeliverHookDelivery(ctx, owner, repoName, hookID, deliveryID)
+	} else {
+		_, _, err = c.Client.Organizations.RedeliverHookDelivery(ctx, org, hookID, deliveryID)
+	}
+
+	// GitHub responds to a redelivery request with 202 Accepted, which go-github reports as an AcceptedError.
+	var acceptedErr *github.AcceptedError
+	if err != nil && !errors.As(err, &acceptedErr) {
+		return fmt.Errorf("failed to redeliver webhook delivery %d: %w", deliveryID, err)
+	}
+
+	return nil
+}
+
+// GetLatestRunnerVersion returns the version of the latest release of actions/runner, like 2.311.0.
+// The releases are looked up on the GitHub instance the client talks to, which doesn't host actions/runner in case of GitHub Enterprise Server.
+func (c *Client) GetLatestRunnerVersion(ctx context.Context) (string, error) {
+	release, _, err := c.Client.Repositories.GetLatestRelease(WithRequestPriority(ctx, PriorityLow), "actions", "runner")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the latest release of actions/runner: %w", err)
+	}
+
+	return strings.TrimPrefix(release.GetTagName(), "v"), nil
+}
+
+// runnerReleaseChecksumPattern matches the SHA-256 checksums of the Linux binaries in the notes of the actions/runner releases,
+// like <!-- BEGIN SHA linux-x64 -->HEX<!-- END SHA linux-x64 -->.
+var runnerReleaseChecksumPattern = regexp.MustCompile(`<!-- BEGIN SHA linux-(x64|arm64|arm) -->([0-9a-f]{64})<!-- END SHA linux-(?:x64|arm64|arm) -->`)
+
+// GetRunnerReleaseChecksums returns the SHA-256 checksums of the Linux binaries of the actions/runner release of the version,
+// like 2.311.0, by the architecture of the binaries, either x64, arm64 or arm.
+func (c *Client) GetRunnerReleaseChecksums(ctx context.Context, version string) (map[string]string, error) {
+	release, _, err := c.Client.Repositories.GetReleaseByTag(WithRequestPriority(ctx, PriorityLow), "actions", "runner", "v"+version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the release %s of actions/runner: %w", version, err)
+	}
+
+	checksums := map[string]string{}
+
+	for _, m := range runnerReleaseChecksumPattern.FindAllStringSubmatch(release.GetBody(), -1) {
+		checksums[m[1]] = m[2]
+	}
+
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("no checksum of the binaries found in the notes of the release %s of actions/runner", version)
+	}
+
+	return checksums, nil
+}
+
+// ListAttestations returns the Sigstore bundles of the artifact attestations of the artifact of the digest, like sha256:HEX,
+// made in the repository.
+func (c *Client) ListAttestations(ctx context.Context, owner, repo, digest string) ([]json.RawMessage, error) {
+	req, err := c.Client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/attestations/%s?per_page=100", owner, repo, digest), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Attestations []struct {
+			Bundle json.RawMessage `json:"bundle"`
+		} `json:"attestations"`
+	}
+
+	if _, err := c.Client.Do(WithRequestPriority(ctx, PriorityLow), req, &list); err != nil {
+		return nil, fmt.Errorf("failed to list the attestations of %s in %s/%s: %w", digest, owner, repo, err)
+	}
+
+	var bundles []json.RawMessage
+	for _, a := range list.Attestations {
+		bundles = append(bundles, a.Bundle)
+	}
+
+	return bundles, nil
+}
+
+// CreateDefaultBranchStatus sets the commit status of the context on the head of the default branch of the repository,
+// like the status of the maintenance of the runners that the workflows can check.
+func (c *Client) CreateDefaultBranchStatus(ctx context.Context, repo, state, statusContext, description string) error {
+	owner, repoName, err := splitOwnerAndRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := c.Client.Repositories.Get(ctx, owner, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to get repository %s: %w", repo, err)
+	}
+
+	branch, _, err := c.Client.Repositories.GetBranch(ctx, owner, repoName, r.GetDefaultBranch(), true)
+	if err != nil {
+		return fmt.Errorf("failed to get default branch of repository %s: %w", repo, err)
+	}
+
+	_, _, err = c.Client.Repositories.CreateStatus(ctx, owner, repoName, branch.GetCommit().GetSHA(), &github.RepoStatus{
+		State:       github.String(state),
+		Context:     github.String(statusContext),
+		Description: github.String(description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create status of repository %s: %w", repo, err)
+	}
+
+	return nil
+}
+
+// GetRepositoryWithDefaultBranch returns the repository in the OWNER/REPO format, along with its default branch,
+// which tells whether the branch is protected.
+func (c *Client) GetRepositoryWithDefaultBranch(ctx context.Context, repo string) (*github.Repository, *github.Branch, error) {
+	owner, repoName, err := splitOwnerAndRepo(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, _, err := c.Client.Repositories.Get(ctx, owner, repoName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get repository %s: %w", repo, err)
+	}
+
+	branch, _, err := c.Client.Repositories.GetBranch(ctx, owner, repoName, r.GetDefaultBranch(), true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get default branch of repository %s: %w", repo, err)
+	}
+
+	return r, branch, nil
+}
+
 // Validates enterprise, organization and repo arguments. Both are optional, but at least one should be specified
 func getEnterpriseOrganizationAndRepo(enterprise, org, repo string) (string, string, string, error) {
 	if len(repo) > 0 {
@@ -2,13 +2,18 @@ package github
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/actions/actions-runner-controller/github/audit"
 	"github.com/actions/actions-runner-controller/github/fake"
-	"github.com/google/go-github/v52/github"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var server *httptest.Server
@@ -129,26 +134,115 @@ func TestRemoveRunner(t *testing.T) {
 	}
 }
 
-func TestCleanup(t *testing.T) {
-	token := "token"
+func TestRemoveRunnerCustomLabels(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		org        string
+		repo       string
+		err        bool
+	}{
+		{enterprise: "", org: "", repo: "test/valid", err: false},
+		{enterprise: "", org: "", repo: "test/error", err: true},
+		{enterprise: "", org: "test", repo: "", err: false},
+		{enterprise: "", org: "error", repo: "", err: true},
+		{enterprise: "test", org: "", repo: "", err: false},
+		{enterprise: "error", org: "", repo: "", err: true},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		err := client.RemoveRunnerCustomLabels(context.Background(), tt.enterprise, tt.org, tt.repo, int64(1))
+		if !tt.err && err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.err && err == nil {
+			t.Errorf("[%d] expected an error", i)
+		}
+	}
+}
+
+func TestGenerateJITConfig(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		org        string
+		repo       string
+		group      string
+		err        bool
+	}{
+		{enterprise: "", org: "", repo: "test/valid", group: "", err: false},
+		{enterprise: "", org: "", repo: "test/valid", group: "custom", err: false},
+		{enterprise: "", org: "", repo: "test/error", group: "", err: true},
+		{enterprise: "", org: "test", repo: "", group: "custom", err: false},
+		{enterprise: "", org: "test", repo: "", group: "missing", err: true},
+		{enterprise: "test", org: "", repo: "", group: "", err: false},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		config, err := client.GenerateJITConfig(context.Background(), tt.enterprise, tt.org, tt.repo, "test3", tt.group, "", []string{"custom"})
+		if !tt.err && err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.err && err == nil {
+			t.Errorf("[%d] expected an error", i)
+		}
+		if !tt.err && err == nil && (config.EncodedJITConfig != "fake-encoded-jit-config" || config.Runner.GetID() != 3) {
+			t.Errorf("[%d] unexpected jit config: %+v", i, config)
+		}
+	}
+}
 
+func TestGetLatestRunnerVersion(t *testing.T) {
 	client := newTestClient()
-	client.regTokens = map[string]*github.RegistrationToken{
-		"active": &github.RegistrationToken{
-			Token:     &token,
-			ExpiresAt: &github.Timestamp{Time: time.Now().Add(time.Hour * 1)},
+
+	version, err := client.GetLatestRunnerVersion(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != "2.311.0" {
+		t.Errorf("unexpected version: %s", version)
+	}
+}
+
+func TestGetRunnerReleaseChecksums(t *testing.T) {
+	client := newTestClient()
+
+	checksums, err := client.GetRunnerReleaseChecksums(context.Background(), "2.311.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"x64":   "29fc8cf2dab4c195bb147384e7e2c94cfd4d4022c793b346a6175435265aa278",
+		"arm64": "5d13b77e0aa5306b6c03e234ad1da4d9c6aa7831d26fd7e37a3656e77153611e",
+	}
+	if !reflect.DeepEqual(checksums, want) {
+		t.Errorf("unexpected checksums: %v", checksums)
+	}
+
+	if _, err := client.GetRunnerReleaseChecksums(context.Background(), "2.310.0"); err == nil {
+		t.Error("expected an error for the missing release")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	tc := newTokenCache()
+	tc.tokens = map[string]*cachedToken{
+		"active": {
+			token:     "token",
+			expiresAt: time.Now().Add(time.Hour * 1),
 		},
-		"expired": &github.RegistrationToken{
-			Token:     &token,
-			ExpiresAt: &github.Timestamp{Time: time.Now().Add(-time.Hour * 1)},
+		"expired": {
+			token:     "token",
+			expiresAt: time.Now().Add(-time.Hour * 1),
 		},
 	}
 
-	client.cleanup()
-	if _, ok := client.regTokens["active"]; !ok {
+	tc.cleanup()
+	if _, ok := tc.tokens["active"]; !ok {
 		t.Errorf("active token was accidentally removed")
 	}
-	if _, ok := client.regTokens["expired"]; ok {
+	if _, ok := tc.tokens["expired"]; ok {
 		t.Errorf("expired token still exists")
 	}
 }
@@ -159,3 +253,170 @@ func TestUserAgent(t *testing.T) {
 		t.Errorf("UserAgent should be set to actions-runner-controller/NA")
 	}
 }
+
+func TestInstallationTokenOptions(t *testing.T) {
+	c := Config{
+		AppInstallationRepositories: []string{"owner/repo-a", "repo-b"},
+		AppInstallationPermissions:  map[string]string{"administration": "write", "metadata": "read"},
+	}
+
+	opts, err := c.installationTokenOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"repo-a", "repo-b"}; !reflect.DeepEqual(opts.Repositories, want) {
+		t.Errorf("unexpected repositories: want %v, got %v", want, opts.Repositories)
+	}
+	if got := opts.Permissions.GetAdministration(); got != "write" {
+		t.Errorf("unexpected administration permission: want write, got %s", got)
+	}
+	if got := opts.Permissions.GetMetadata(); got != "read" {
+		t.Errorf("unexpected metadata permission: want read, got %s", got)
+	}
+
+	c.AppInstallationPermissions = map[string]string{"no_such_permission": "write"}
+	if _, err := c.installationTokenOptions(); err == nil {
+		t.Errorf("expected an error for an unknown permission")
+	}
+
+	c = Config{}
+	if opts, err := c.installationTokenOptions(); err != nil || opts != nil {
+		t.Errorf("expected no options without scope, got %v, %v", opts, err)
+	}
+}
+
+func TestWithTokenScopeRequiresGitHubApp(t *testing.T) {
+	client := newTestClient()
+	if _, err := client.WithTokenScope([]string{"repo"}, nil); err == nil {
+		t.Errorf("expected an error for a client authenticating with a token")
+	}
+}
+
+func TestListRunnersConditionalRequests(t *testing.T) {
+	var requests, notModified int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		w.Header().Set("ETag", `"runners"`)
+		w.Header().Set("Cache-Control", "private, max-age=0")
+
+		if r.Header.Get("If-None-Match") == `"runners"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		fmt.Fprint(w, `{"total_count": 1, "runners": [{"id": 1, "name": "test1", "os": "linux", "status": "online", "busy": true}]}`)
+	}))
+	defer srv.Close()
+
+	client := newTestClient()
+	client.Client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	for i := 0; i < 3; i++ {
+		runners, err := client.ListRunners(WithRequestPriority(context.Background(), PriorityLow), "", "", "test/valid")
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		if len(runners) != 1 || !runners[0].GetBusy() {
+			t.Errorf("[%d] unexpected runners list: %v", i, runners)
+		}
+	}
+
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+	if notModified != 2 {
+		t.Errorf("expected the cached runners list to be revalidated 2 times, got %d", notModified)
+	}
+}
+
+type auditRecorder struct {
+	events []audit.Event
+}
+
+func (r *auditRecorder) Record(_ context.Context, e audit.Event) {
+	r.events = append(r.events, e)
+}
+
+func TestAuditSink(t *testing.T) {
+	rec := &auditRecorder{}
+	c := Config{
+		Token:     "token",
+		AuditSink: rec,
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	ctx := audit.WithActor(context.Background(), "Runner", types.NamespacedName{Namespace: "default", Name: "example"})
+
+	if _, err := client.ListRunners(ctx, "", "", "test/valid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.RemoveRunner(ctx, "", "", "test/valid", int64(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rec.events) != 1 {
+		t.Fatalf("expected only the runner removal to be recorded, got %+v", rec.events)
+	}
+	e := rec.events[0]
+	if e.Client != "github" || e.Operation != "remove-runner" || e.Outcome != audit.OutcomeSuccess {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.Actor == nil || e.Actor.String() != "Runner/default/example" {
+		t.Errorf("expected the actor Runner/default/example, got %v", e.Actor)
+	}
+}
+
+func TestGetRegistrationTokenPerTenant(t *testing.T) {
+	var mints int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&mints, 1)
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token-%d", "expires_at": "%s"}`, n, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	newTenantClient := func(tenant string) *Client {
+		c := Config{
+			Token:  "token",
+			Tenant: tenant,
+		}
+		client, err := c.NewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+		return client
+	}
+
+	get := func(client *Client) string {
+		rt, err := client.GetRegistrationToken(context.Background(), "", "tenants", "", "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return rt.GetToken()
+	}
+
+	a := get(newTenantClient("a"))
+	b := get(newTenantClient("b"))
+
+	if a == b {
+		t.Errorf("expected the tenants to have their own tokens, got %q for both", a)
+	}
+	if got := get(newTenantClient("a")); got != a {
+		t.Errorf("expected the clients of the same tenant to share the token %q, got %q", a, got)
+	}
+	if mints != 2 {
+		t.Errorf("expected 2 tokens to be minted, got %d", mints)
+	}
+}
@@ -0,0 +1,155 @@
+// Package httptransport builds the HTTP transports shared by the clients that talk to GitHub,
+// so that the proxy and TLS settings apply equally to the API calls, the log downloads, and the scale set listener.
+package httptransport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// dialTimeout is the connect timeout of http.DefaultTransport, kept when KeepAlive replaces its dialer.
+const dialTimeout = 30 * time.Second
+
+// Config is the proxy and TLS settings of the HTTP clients that talk to GitHub.
+// The zero Config keeps the defaults of http.DefaultTransport, that is the system root CAs
+// and the proxy configured via the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+type Config struct {
+	// ProxyURL is the URL of the proxy used for all the requests, overriding the environment variables.
+	ProxyURL string
+	// RootCAs is the PEM-encoded certificates trusted in addition to the system root CAs.
+	RootCAs string
+	// RootCAsFile is the path to the file containing PEM-encoded certificates trusted in addition to the system root CAs.
+	RootCAsFile string
+	// TLSMinVersion is the minimum TLS version, either 1.2 or 1.3.
+	TLSMinVersion string
+	// KeepAlive is the interval of the TCP keep-alive probes of the connections, for the proxies and NATs that drop idle connections.
+	// Defaults to 30s of http.DefaultTransport when zero. Negative disables the probes.
+	KeepAlive time.Duration
+}
+
+// IsZero returns true if the config doesn't change any of the defaults.
+func (c Config) IsZero() bool {
+	return c == Config{}
+}
+
+// CertPool returns the system cert pool extended with RootCAs and RootCAsFile, or nil when neither is set.
+func (c Config) CertPool() (*x509.CertPool, error) {
+	if c.RootCAs == "" && c.RootCAsFile == "" {
+		return nil, nil
+	}
+
+	systemPool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load system cert pool: %w", err)
+	}
+	pool := systemPool.Clone()
+
+	if c.RootCAs != "" {
+		if ok := pool.AppendCertsFromPEM([]byte(c.RootCAs)); !ok {
+			return nil, fmt.Errorf("failed to parse root certificate")
+		}
+	}
+
+	if c.RootCAsFile != "" {
+		pem, err := os.ReadFile(c.RootCAsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root certificate file: %w", err)
+		}
+
+		if ok := pool.AppendCertsFromPEM(pem); !ok {
+			return nil, fmt.Errorf("failed to parse root certificate file %s", c.RootCAsFile)
+		}
+	}
+
+	return pool, nil
+}
+
+// Proxy returns the proxy function for ProxyURL, or nil when it isn't set.
+func (c Config) Proxy() (func(*http.Request) (*url.URL, error), error) {
+	if c.ProxyURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+	}
+
+	return http.ProxyURL(u), nil
+}
+
+// MinVersion returns the tls.VersionTLS* constant for TLSMinVersion, or 0 when it isn't set.
+func (c Config) MinVersion() (uint16, error) {
+	switch c.TLSMinVersion {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls min version %q: must be either 1.2 or 1.3", c.TLSMinVersion)
+	}
+}
+
+// Apply overrides the settings of the transport with the ones set in the config.
+func (c Config) Apply(t *http.Transport) error {
+	pool, err := c.CertPool()
+	if err != nil {
+		return err
+	}
+
+	proxy, err := c.Proxy()
+	if err != nil {
+		return err
+	}
+
+	minVersion, err := c.MinVersion()
+	if err != nil {
+		return err
+	}
+
+	if pool != nil || minVersion != 0 {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+
+		if pool != nil {
+			t.TLSClientConfig.RootCAs = pool
+		}
+
+		if minVersion != 0 {
+			t.TLSClientConfig.MinVersion = minVersion
+		}
+	}
+
+	if proxy != nil {
+		t.Proxy = proxy
+	}
+
+	if c.KeepAlive != 0 {
+		t.DialContext = (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: c.KeepAlive,
+		}).DialContext
+	}
+
+	return nil
+}
+
+// NewTransport returns a clone of http.DefaultTransport with the config applied.
+func (c Config) NewTransport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if err := c.Apply(t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
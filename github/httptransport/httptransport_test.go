@@ -0,0 +1,85 @@
+package httptransport
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigZeroKeepsDefaults(t *testing.T) {
+	tr, err := Config{}.NewTransport()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tr.TLSClientConfig != nil && (tr.TLSClientConfig.RootCAs != nil || tr.TLSClientConfig.MinVersion != 0) {
+		t.Errorf("expected the default tls config, got %+v", tr.TLSClientConfig)
+	}
+
+	if tr.Proxy == nil {
+		t.Errorf("expected the proxy from the environment")
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, srvCertPEM(t, srv), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := Config{
+		ProxyURL:      "http://proxy.example.com:3128",
+		RootCAsFile:   ca,
+		TLSMinVersion: "1.3",
+	}.NewTransport()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("unexpected tls min version: %d", tr.TLSClientConfig.MinVersion)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com", nil)
+	proxy, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proxy == nil || proxy.Host != "proxy.example.com:3128" {
+		t.Errorf("unexpected proxy: %v", proxy)
+	}
+
+	// The server trusted via RootCAsFile is reachable without the proxy
+	tr.Proxy = nil
+
+	res, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the server certificate to be trusted: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestConfigInvalid(t *testing.T) {
+	for name, c := range map[string]Config{
+		"tls version":   {TLSMinVersion: "1.1"},
+		"root cas":      {RootCAs: "not a certificate"},
+		"root cas file": {RootCAsFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, err := c.NewTransport(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func srvCertPEM(t *testing.T, srv *httptest.Server) []byte {
+	t.Helper()
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -17,7 +18,17 @@ var onceRegister sync.Once
 
 func Register() {
 	onceRegister.Do(func() {
-		metrics.Registry.MustRegister(metricRateLimit, metricRateLimitRemaining)
+		metrics.Registry.MustRegister(
+			metricRateLimit,
+			metricRateLimitRemaining,
+			metricTokenCacheHits,
+			metricTokenCacheMisses,
+			metricTokenMintDuration,
+			metricRateLimiterRequests,
+			metricRateLimiterShed,
+			metricRateLimiterDelay,
+			metricConditionalRequests,
+		)
 	})
 }
 
@@ -35,8 +46,99 @@ var (
 			Help: "The number of requests remaining in the current rate limit window",
 		},
 	)
+	metricTokenCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_token_cache_hits_total",
+			Help: "The number of registration and remove token requests served from the token cache",
+		},
+		[]string{"kind"},
+	)
+	metricTokenCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_token_cache_misses_total",
+			Help: "The number of registration and remove token requests that had to mint a new token",
+		},
+		[]string{"kind"},
+	)
+	metricTokenMintDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_token_mint_duration_seconds",
+			Help:    "The latency of the GitHub API calls minting registration and remove tokens",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"kind", "result"},
+	)
+	metricRateLimiterRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_rate_limiter_requests_total",
+			Help: "The number of GitHub API requests the client-side rate limiter let through, by endpoint category and priority",
+		},
+		[]string{"category", "priority"},
+	)
+	metricRateLimiterShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_rate_limiter_shed_total",
+			Help: "The number of GitHub API requests the client-side rate limiter shed to save the rate limit, by endpoint category and priority",
+		},
+		[]string{"category", "priority"},
+	)
+	metricRateLimiterDelay = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_rate_limiter_delay_seconds",
+			Help:    "How long the client-side rate limiter held back GitHub API requests until the rate limit reset, by endpoint category and priority",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"category", "priority"},
+	)
+	metricConditionalRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_conditional_requests_total",
+			Help: "The number of GitHub API requests revalidating cached responses with ETags, by endpoint category and whether the resource was modified",
+		},
+		[]string{"category", "result"},
+	)
 )
 
+// ObserveTokenCacheHit counts a token request of the kind served from the cache.
+func ObserveTokenCacheHit(kind string) {
+	metricTokenCacheHits.WithLabelValues(kind).Inc()
+}
+
+// ObserveTokenCacheMiss counts a token request of the kind that had to mint a new token.
+func ObserveTokenCacheMiss(kind string) {
+	metricTokenCacheMisses.WithLabelValues(kind).Inc()
+}
+
+// ObserveTokenMint records the latency of minting a token of the kind. The result is either success or error.
+func ObserveTokenMint(kind, result string, d time.Duration) {
+	metricTokenMintDuration.WithLabelValues(kind, result).Observe(d.Seconds())
+}
+
+// ObserveRateLimiterRequest counts a request of the category and priority the rate limiter let through.
+func ObserveRateLimiterRequest(category, priority string) {
+	metricRateLimiterRequests.WithLabelValues(category, priority).Inc()
+}
+
+// ObserveRateLimiterShed counts a request of the category and priority the rate limiter shed.
+func ObserveRateLimiterShed(category, priority string) {
+	metricRateLimiterShed.WithLabelValues(category, priority).Inc()
+}
+
+// ObserveRateLimiterDelay records how long the rate limiter held back a request of the category and priority.
+func ObserveRateLimiterDelay(category, priority string, d time.Duration) {
+	metricRateLimiterDelay.WithLabelValues(category, priority).Observe(d.Seconds())
+}
+
+// ObserveConditionalRequest counts a request of the category revalidating a cached response.
+// The result is not_modified when the cached response was reused without counting against the rate limit, or modified otherwise.
+func ObserveConditionalRequest(category string, notModified bool) {
+	result := "modified"
+	if notModified {
+		result = "not_modified"
+	}
+	metricConditionalRequests.WithLabelValues(category, result).Inc()
+}
+
 const (
 	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
 	headerRateLimit          = "X-RateLimit-Limit"
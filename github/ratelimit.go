@@ -0,0 +1,283 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+)
+
+// RequestPriority tells the client-side rate limiter which requests to shed first when the GitHub API rate limit runs low.
+type RequestPriority int
+
+const (
+	// PriorityLow is for requests ARC can live without for a while, like fetching workflow job logs
+	// or polling runners for PercentageRunnersBusy.
+	PriorityLow RequestPriority = iota - 1
+	// PriorityNormal is the priority of requests whose context has no priority set.
+	PriorityNormal
+	// PriorityHigh is for requests that runners can't start or stop without, like minting registration tokens.
+	PriorityHigh
+)
+
+func (p RequestPriority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a context that makes the GitHub API requests made with it have the priority.
+func WithRequestPriority(ctx context.Context, p RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, p)
+}
+
+func requestPriorityFrom(ctx context.Context) RequestPriority {
+	if p, ok := ctx.Value(requestPriorityKey{}).(RequestPriority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+const (
+	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+	headerRateLimit          = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRateLimitResource  = "X-RateLimit-Resource"
+	headerRetryAfter         = "Retry-After"
+
+	// defaultSecondaryRateLimitBackoff is how long to back off after hitting a secondary rate limit without Retry-After.
+	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#secondary-rate-limits
+	defaultSecondaryRateLimitBackoff = time.Minute
+
+	// lowPriorityReserve and normalPriorityReserve are the fractions of the primary rate limit kept for the requests
+	// of the higher priorities. Low priority requests are shed first, then normal priority ones.
+	lowPriorityReserve    = 0.2
+	normalPriorityReserve = 0.05
+
+	// maxRateLimitWait is the longest a high priority request waits for the rate limit to reset before it is shed.
+	maxRateLimitWait = 30 * time.Second
+)
+
+// RateLimitShedError is returned for the GitHub API requests the client-side rate limiter refused to send.
+type RateLimitShedError struct {
+	Category string
+	Priority RequestPriority
+	// Reason is either primary or secondary, the kind of the rate limit the request was shed for.
+	Reason string
+	// Until is when the rate limit is expected to allow the request.
+	Until time.Time
+}
+
+func (e *RateLimitShedError) Error() string {
+	return fmt.Sprintf("%s priority %s request shed to save the GitHub API %s rate limit until %s", e.Priority, e.Category, e.Reason, e.Until.Format(time.RFC3339))
+}
+
+// IsRateLimitShed returns true when the error is caused by the client-side rate limiter shedding the request.
+func IsRateLimitShed(err error) bool {
+	var shed *RateLimitShedError
+	return errors.As(err, &shed)
+}
+
+// requestCategory groups the GitHub API requests by the endpoints ARC calls, for metrics.
+func requestCategory(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/logs"):
+		return "logs"
+	case strings.HasSuffix(path, "/registration-token"), strings.HasSuffix(path, "/remove-token"):
+		return "tokens"
+	case strings.Contains(path, "/actions/runner-groups"):
+		return "runner-groups"
+	case strings.Contains(path, "/actions/runners"):
+		return "runners"
+	case strings.Contains(path, "/actions/jobs"), strings.HasSuffix(path, "/jobs"):
+		return "workflow-jobs"
+	case strings.Contains(path, "/actions/runs"):
+		return "workflow-runs"
+	case strings.HasSuffix(path, "/meta"):
+		return "meta"
+	default:
+		return "other"
+	}
+}
+
+// rateLimiter tracks the rate limit of the credentials a client uses, from the headers of the responses.
+// It counts down the remaining requests as they are sent, so that a burst of concurrent requests
+// doesn't overrun the rate limit before the responses tell the actual remaining count.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	limit     int
+	remaining int
+	reset     time.Time
+
+	// retryAfter is until when requests are held back after hitting a secondary rate limit.
+	retryAfter time.Time
+
+	// now and sleep are overridden in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// wait returns how long the request needs to wait before it is sent, or an error when it has to be shed.
+// Conditional requests are let through regardless of the primary rate limit, as GitHub doesn't count 304 Not Modified
+// responses against it. When the resource has changed, the rate limit of the response counts the request anyway.
+func (l *rateLimiter) wait(p RequestPriority, category string, conditional bool) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	shed := func(reason string, until time.Time) error {
+		return &RateLimitShedError{Category: category, Priority: p, Reason: reason, Until: until}
+	}
+
+	if now.Before(l.retryAfter) {
+		d := l.retryAfter.Sub(now)
+		if p < PriorityHigh || d > maxRateLimitWait {
+			return 0, shed("secondary", l.retryAfter)
+		}
+		return d, nil
+	}
+
+	if conditional || l.limit <= 0 || !now.Before(l.reset) {
+		return 0, nil
+	}
+
+	left := float64(l.remaining) / float64(l.limit)
+
+	switch {
+	case p == PriorityLow && left <= lowPriorityReserve,
+		p == PriorityNormal && left <= normalPriorityReserve:
+		return 0, shed("primary", l.reset)
+	case l.remaining <= 0:
+		d := l.reset.Sub(now)
+		if d > maxRateLimitWait {
+			return 0, shed("primary", l.reset)
+		}
+		return d, nil
+	}
+
+	l.remaining--
+
+	return 0, nil
+}
+
+// observe updates the rate limit from the response.
+func (l *rateLimiter) observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden && resp.Header.Get(headerRetryAfter) != "" {
+		backoff := defaultSecondaryRateLimitBackoff
+		if secs, err := strconv.Atoi(resp.Header.Get(headerRetryAfter)); err == nil {
+			backoff = time.Duration(secs) * time.Second
+		}
+		if until := now.Add(backoff); until.After(l.retryAfter) {
+			l.retryAfter = until
+		}
+	}
+
+	// Search and other resources have their own rate limits that don't tell anything about the core one.
+	if r := resp.Header.Get(headerRateLimitResource); r != "" && r != "core" {
+		return
+	}
+
+	limit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return
+	}
+
+	l.limit = limit
+	l.remaining = remaining
+	l.reset = time.Unix(reset, 0)
+}
+
+// rateLimitTransport holds back and sheds the requests according to the rate limiter before they are sent.
+type rateLimitTransport struct {
+	limiter   *rateLimiter
+	Transport http.RoundTripper
+}
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	p := requestPriorityFrom(ctx)
+	category := requestCategory(req.URL.Path)
+
+	conditional := isConditionalRequest(req)
+
+	d, err := t.limiter.wait(p, category, conditional)
+	if err != nil {
+		metrics.ObserveRateLimiterShed(category, p.String())
+		return nil, err
+	}
+
+	if d > 0 {
+		metrics.ObserveRateLimiterDelay(category, p.String(), d)
+		if err := t.limiter.sleep(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics.ObserveRateLimiterRequest(category, p.String())
+
+	resp, err := t.Transport.RoundTrip(req)
+	if resp != nil {
+		t.limiter.observe(resp)
+
+		if conditional {
+			metrics.ObserveConditionalRequest(category, resp.StatusCode == http.StatusNotModified)
+		}
+	}
+
+	return resp, err
+}
+
+// isConditionalRequest returns true for the requests the HTTP cache revalidates with the ETag or the Last-Modified of the cached response.
+//
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
+func isConditionalRequest(req *http.Request) bool {
+	return req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+}
@@ -0,0 +1,241 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func rateLimitResponse(limit, remaining int, reset time.Time) *http.Response {
+	h := http.Header{}
+	h.Set(headerRateLimit, strconv.Itoa(limit))
+	h.Set(headerRateLimitRemaining, strconv.Itoa(remaining))
+	h.Set(headerRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+	return &http.Response{StatusCode: http.StatusOK, Header: h}
+}
+
+func TestRateLimiterShedsByPriority(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	reset := now.Add(10 * time.Minute)
+
+	l.observe(rateLimitResponse(5000, 4000, reset))
+
+	for _, p := range []RequestPriority{PriorityLow, PriorityNormal, PriorityHigh} {
+		if _, err := l.wait(p, "runners", false); err != nil {
+			t.Errorf("%s priority: unexpected error with plenty of rate limit left: %v", p, err)
+		}
+	}
+
+	l.observe(rateLimitResponse(5000, 500, reset))
+
+	if _, err := l.wait(PriorityLow, "runners", false); !IsRateLimitShed(err) {
+		t.Errorf("expected low priority request to be shed, got %v", err)
+	}
+	if _, err := l.wait(PriorityNormal, "runners", false); err != nil {
+		t.Errorf("unexpected error for normal priority request: %v", err)
+	}
+
+	l.observe(rateLimitResponse(5000, 100, reset))
+
+	if _, err := l.wait(PriorityNormal, "runners", false); !IsRateLimitShed(err) {
+		t.Errorf("expected normal priority request to be shed, got %v", err)
+	}
+	if _, err := l.wait(PriorityHigh, "tokens", false); err != nil {
+		t.Errorf("unexpected error for high priority request: %v", err)
+	}
+
+	// The rate limit has reset
+	now = reset.Add(time.Second)
+
+	if _, err := l.wait(PriorityLow, "runners", false); err != nil {
+		t.Errorf("unexpected error after the rate limit reset: %v", err)
+	}
+}
+
+func TestRateLimiterCountsDownBursts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	l.observe(rateLimitResponse(100, 25, now.Add(time.Minute)))
+
+	var sent int
+	for i := 0; i < 10; i++ {
+		if _, err := l.wait(PriorityLow, "runners", false); err == nil {
+			sent++
+		}
+	}
+
+	// 25 remaining minus 20 reserved for normal and high priority requests
+	if sent != 5 {
+		t.Errorf("expected 5 low priority requests in the burst to be sent, got %d", sent)
+	}
+}
+
+func TestRateLimiterHighPriorityWaitsForReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	l.observe(rateLimitResponse(5000, 0, now.Add(10*time.Second)))
+
+	d, err := l.wait(PriorityHigh, "tokens", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d != 10*time.Second {
+		t.Errorf("unexpected wait: %s", d)
+	}
+
+	l.observe(rateLimitResponse(5000, 0, now.Add(10*time.Minute)))
+
+	if _, err := l.wait(PriorityHigh, "tokens", false); !IsRateLimitShed(err) {
+		t.Errorf("expected the request to be shed rather than waiting for %s, got %v", 10*time.Minute, err)
+	}
+}
+
+func TestRateLimiterSecondaryRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	res := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	res.Header.Set(headerRetryAfter, "20")
+	l.observe(res)
+
+	if _, err := l.wait(PriorityNormal, "workflow-runs", false); !IsRateLimitShed(err) {
+		t.Errorf("expected normal priority request to be shed, got %v", err)
+	}
+
+	d, err := l.wait(PriorityHigh, "tokens", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d != 20*time.Second {
+		t.Errorf("unexpected wait: %s", d)
+	}
+
+	now = now.Add(21 * time.Second)
+
+	if _, err := l.wait(PriorityNormal, "workflow-runs", false); err != nil {
+		t.Errorf("unexpected error after Retry-After: %v", err)
+	}
+
+	// 429 without Retry-After backs off for a minute
+	l.observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+
+	if _, err := l.wait(PriorityHigh, "tokens", false); !IsRateLimitShed(err) {
+		t.Errorf("expected the request to be shed rather than waiting for the default backoff, got %v", err)
+	}
+}
+
+func TestRateLimiterIgnoresOtherResources(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	res := rateLimitResponse(30, 0, now.Add(time.Minute))
+	res.Header.Set(headerRateLimitResource, "search")
+	l.observe(res)
+
+	if _, err := l.wait(PriorityLow, "runners", false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRequestCategory(t *testing.T) {
+	tests := map[string]string{
+		"/repos/o/r/actions/runners":                   "runners",
+		"/orgs/o/actions/runners/registration-token":   "tokens",
+		"/enterprises/e/actions/runners/remove-token":  "tokens",
+		"/orgs/o/actions/runner-groups/1/repositories": "runner-groups",
+		"/repos/o/r/actions/runs":                      "workflow-runs",
+		"/repos/o/r/actions/runs/1/jobs":               "workflow-jobs",
+		"/repos/o/r/actions/jobs/1/logs":               "logs",
+		"/api/v3/meta":                                 "meta",
+		"/app/installations/1/access_tokens":           "other",
+	}
+
+	for path, want := range tests {
+		if got := requestCategory(path); got != want {
+			t.Errorf("%s: want %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	var calls int32
+
+	reset := time.Now().Add(time.Hour)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set(headerRateLimit, "5000")
+		w.Header().Set(headerRateLimitRemaining, "10")
+		w.Header().Set(headerRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+		fmt.Fprint(w, "[]")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: rateLimitTransport{limiter: newRateLimiter(), Transport: http.DefaultTransport}}
+
+	get := func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/repos/o/r/actions/runners", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	if err := get(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := get(WithRequestPriority(context.Background(), PriorityLow)); !IsRateLimitShed(err) {
+		t.Errorf("expected the low priority request to be shed, got %v", err)
+	}
+
+	if err := get(WithRequestPriority(context.Background(), PriorityHigh)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 requests to reach the server, got %d", calls)
+	}
+}
+
+func TestRateLimiterLetsConditionalRequestsThrough(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	l.observe(rateLimitResponse(5000, 10, now.Add(time.Minute)))
+
+	if _, err := l.wait(PriorityLow, "runners", false); !IsRateLimitShed(err) {
+		t.Errorf("expected low priority request to be shed, got %v", err)
+	}
+	if _, err := l.wait(PriorityLow, "runners", true); err != nil {
+		t.Errorf("unexpected error for conditional request: %v", err)
+	}
+	if l.remaining != 10 {
+		t.Errorf("expected conditional requests not to count down the rate limit, got %d remaining", l.remaining)
+	}
+}
@@ -0,0 +1,137 @@
+package github
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	tokenKindRegistration = "registration"
+	tokenKindRemove       = "remove"
+
+	// We'd like to allow the runner just starting up to miss the expiration date by a bit.
+	// Note that this means that we're going to cache Creation Registraion Token API response longer than the
+	// recommended cache duration.
+	//
+	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-a-repository
+	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-organization
+	// https://docs.github.com/en/rest/reference/actions#create-a-registration-token-for-an-enterprise
+	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests
+	//
+	// This is currently set to 30 minutes as the result of the discussion took place at the following issue:
+	// https://github.com/actions/actions-runner-controller/issues/1295
+	runnerStartupTimeout = 30 * time.Minute
+
+	// tokenRefreshWindow is how long before a cached token becomes unusable it is refreshed in the background,
+	// so that requests keep being served from the cache instead of waiting for the API.
+	tokenRefreshWindow = 10 * time.Minute
+
+	tokenMintTimeout = 30 * time.Second
+)
+
+// tokens is the token cache shared among all the clients, so that the clients created per githubAPICredentialsFrom secret
+// or per githubAPITokenScope share the tokens for the same enterprise, organization, or repository.
+// The keys include the tenant of the client, so that the tokens are never shared across tenants.
+var tokens = newTokenCache()
+
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+type mintFunc func(ctx context.Context) (*cachedToken, error)
+
+// tokenCache caches registration and remove tokens by their kinds, the GitHub API URL, and the enterprise, organization, and repository.
+// Concurrent requests for the same token that is missing in the cache result in a single API call.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	group  singleflight.Group
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{
+		tokens: map[string]*cachedToken{},
+		now:    time.Now,
+	}
+}
+
+// get returns the cached token for the key, or mints a new one when the cached token expires within runnerStartupTimeout.
+// A cached token that is about to become unusable is served as is while it is refreshed in the background.
+func (tc *tokenCache) get(ctx context.Context, kind, key string, mint mintFunc) (*cachedToken, error) {
+	key = kind + "," + key
+
+	tc.mu.Lock()
+	t, ok := tc.tokens[key]
+	tc.mu.Unlock()
+
+	now := tc.now()
+
+	if ok && t.expiresAt.After(now.Add(runnerStartupTimeout)) {
+		metrics.ObserveTokenCacheHit(kind)
+
+		if t.expiresAt.Before(now.Add(runnerStartupTimeout + tokenRefreshWindow)) {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), tokenMintTimeout)
+				defer cancel()
+
+				// The error is surfaced by the next request that misses the cache
+				_, _ = tc.mint(ctx, kind, key, mint)
+			}()
+		}
+
+		return t, nil
+	}
+
+	metrics.ObserveTokenCacheMiss(kind)
+
+	return tc.mint(ctx, kind, key, mint)
+}
+
+func (tc *tokenCache) mint(ctx context.Context, kind, key string, mint mintFunc) (*cachedToken, error) {
+	v, err, _ := tc.group.Do(key, func() (interface{}, error) {
+		start := time.Now()
+
+		t, err := mint(ctx)
+		if err != nil {
+			metrics.ObserveTokenMint(kind, "error", time.Since(start))
+			return nil, err
+		}
+
+		metrics.ObserveTokenMint(kind, "success", time.Since(start))
+
+		tc.mu.Lock()
+		tc.tokens[key] = t
+		tc.mu.Unlock()
+
+		go tc.cleanup()
+
+		return t, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*cachedToken), nil
+}
+
+// cleanup removes expired tokens.
+func (tc *tokenCache) cleanup() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := tc.now()
+
+	for key, t := range tc.tokens {
+		if t.expiresAt.Before(now) {
+			delete(tc.tokens, key)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenCacheGet(t *testing.T) {
+	tc := newTokenCache()
+
+	now := time.Now()
+	tc.now = func() time.Time { return now }
+
+	var calls int32
+
+	mint := func(context.Context) (*cachedToken, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return &cachedToken{token: string(rune('a' + n - 1)), expiresAt: now.Add(time.Hour)}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		tok, err := tc.get(context.Background(), tokenKindRegistration, "org=o", mint)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok.token != "a" {
+			t.Errorf("unexpected token: %s", tok.token)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the token to be minted once, but minted %d times", calls)
+	}
+
+	if _, err := tc.get(context.Background(), tokenKindRemove, "org=o", mint); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the remove token to be cached separately from the registration token")
+	}
+
+	// The token expires within runnerStartupTimeout and cannot be served anymore
+	now = now.Add(45 * time.Minute)
+
+	tok, err := tc.get(context.Background(), tokenKindRegistration, "org=o", mint)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.token != "c" {
+		t.Errorf("expected a newly minted token, got %s", tok.token)
+	}
+}
+
+func TestTokenCacheProactiveRefresh(t *testing.T) {
+	tc := newTokenCache()
+
+	now := time.Now()
+	tc.now = func() time.Time { return now }
+
+	tc.tokens[tokenKindRegistration+",org=o"] = &cachedToken{token: "old", expiresAt: now.Add(runnerStartupTimeout + tokenRefreshWindow/2)}
+
+	refreshed := make(chan struct{})
+
+	tok, err := tc.get(context.Background(), tokenKindRegistration, "org=o", func(context.Context) (*cachedToken, error) {
+		defer close(refreshed)
+		return &cachedToken{token: "new", expiresAt: now.Add(time.Hour)}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.token != "old" {
+		t.Errorf("expected the cached token to be served while refreshing, got %s", tok.token)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the token was not refreshed in the background")
+	}
+
+	// Wait for the refreshed token to be stored
+	for i := 0; i < 100; i++ {
+		tc.mu.Lock()
+		got := tc.tokens[tokenKindRegistration+",org=o"].token
+		tc.mu.Unlock()
+
+		if got == "new" {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Error("the refreshed token was not stored")
+}
+
+func TestTokenCacheDeduplicatesMints(t *testing.T) {
+	tc := newTokenCache()
+
+	var calls int32
+
+	release := make(chan struct{})
+
+	mint := func(context.Context) (*cachedToken, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &cachedToken{token: "t", expiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tc.get(context.Background(), tokenKindRegistration, "repo=o/r", mint); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected concurrent requests to mint a single token, but minted %d times", calls)
+	}
+}
+
+func TestTokenCacheMintError(t *testing.T) {
+	tc := newTokenCache()
+
+	_, err := tc.get(context.Background(), tokenKindRegistration, "org=o", func(context.Context) (*cachedToken, error) {
+		return nil, errors.New("rate limited")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(tc.tokens) != 0 {
+		t.Errorf("expected no token to be cached on error")
+	}
+}
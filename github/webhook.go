@@ -0,0 +1,59 @@
+package github
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v52/github"
+)
+
+// ValidateWebhookPayload validates the signature of the webhook request against each of the secret keys,
+// so that the webhook secret can be rotated without downtime by accepting both the old and the new secrets for a while.
+// It returns the payload and the index of the secret key that matched.
+func ValidateWebhookPayload(r *http.Request, secretKeys [][]byte) ([]byte, int, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, -1, fmt.Errorf("reading request body: %w", err)
+	}
+
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if signature == "" {
+		signature = r.Header.Get(github.SHA1SignatureHeader)
+	}
+
+	contentType := r.Header.Get("Content-Type")
+
+	var errs []error
+
+	for i, key := range secretKeys {
+		payload, err := github.ValidatePayloadFromBody(contentType, bytes.NewReader(body), signature, key)
+		if err == nil {
+			return payload, i, nil
+		}
+
+		errs = append(errs, fmt.Errorf("secret #%d: %w", i, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, -1, errors.New("no webhook secret is configured")
+	}
+
+	return nil, -1, errors.Join(errs...)
+}
+
+// ParseWebhookSecretTokens parses the list of webhook secret tokens separated by commas or newlines.
+func ParseWebhookSecretTokens(s string) []string {
+	var tokens []string
+
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens
+}
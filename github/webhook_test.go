@@ -0,0 +1,52 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignedWebhookRequest(t *testing.T, payload, secret string) *http.Request {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(github.SHA256SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	return req
+}
+
+func TestValidateWebhookPayload(t *testing.T) {
+	const payload = `{"action":"queued"}`
+
+	secrets := [][]byte{[]byte("new"), []byte("old")}
+
+	got, matched, err := ValidateWebhookPayload(newSignedWebhookRequest(t, payload, "new"), secrets)
+	require.NoError(t, err)
+	require.Equal(t, 0, matched)
+	require.Equal(t, payload, string(got))
+
+	got, matched, err = ValidateWebhookPayload(newSignedWebhookRequest(t, payload, "old"), secrets)
+	require.NoError(t, err)
+	require.Equal(t, 1, matched)
+	require.Equal(t, payload, string(got))
+
+	_, matched, err = ValidateWebhookPayload(newSignedWebhookRequest(t, payload, "unknown"), secrets)
+	require.Error(t, err)
+	require.Equal(t, -1, matched)
+}
+
+func TestParseWebhookSecretTokens(t *testing.T) {
+	require.Equal(t, []string{"a", "b", "c"}, ParseWebhookSecretTokens("a, b\nc\n"))
+	require.Nil(t, ParseWebhookSecretTokens(""))
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -29,12 +32,24 @@ import (
 	actionsgithubcom "github.com/actions/actions-runner-controller/controllers/actions.github.com"
 	actionsgithubcommetrics "github.com/actions/actions-runner-controller/controllers/actions.github.com/metrics"
 	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
+	summerwindmetrics "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
 	"github.com/actions/actions-runner-controller/github"
 	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/actions/actions-runner-controller/github/audit"
+	"github.com/actions/actions-runner-controller/github/httptransport"
 	"github.com/actions/actions-runner-controller/logging"
+	"github.com/actions/actions-runner-controller/pkg/cloudevents"
+	"github.com/actions/actions-runner-controller/pkg/policyhook"
+	"github.com/actions/actions-runner-controller/pkg/sharding"
+	"github.com/actions/actions-runner-controller/pkg/standby"
+	"github.com/actions/actions-runner-controller/pkg/vault"
 	"github.com/kelseyhightower/envconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -49,6 +64,12 @@ const (
 	defaultRunnerImage = "summerwind/actions-runner:latest"
 	defaultDockerImage = "docker:dind"
 	defaultDockerGID   = "1001"
+
+	// defaultBuildkitImage is the image of the buildkitd sidecar of the runners with containerMode: buildkit
+	defaultBuildkitImage = "moby/buildkit:rootless"
+
+	// defaultWindowsRunnerImage is built from runner/actions-runner.windows-ltsc2022.dockerfile
+	defaultWindowsRunnerImage = "summerwind/actions-runner:windows-ltsc2022"
 )
 
 var scheme = runtime.NewScheme()
@@ -80,19 +101,36 @@ func main() {
 		listenerMetricsAddr     string
 		listenerMetricsEndpoint string
 
-		metricsAddr              string
-		autoScalingRunnerSetOnly bool
-		enableLeaderElection     bool
-		disableAdmissionWebhook  bool
-		updateStrategy           string
-		leaderElectionId         string
-		port                     int
-		syncPeriod               time.Duration
+		metricsAddr                       string
+		autoScalingRunnerSetOnly          bool
+		enableLeaderElection              bool
+		disableAdmissionWebhook           bool
+		enableAutoscalingRunnerSetWebhook bool
+		updateStrategy                    string
+		leaderElectionId                  string
+		leaderElection                    standby.LeaderElectionConfig
+		leaderElectionReleaseOnCancel     bool
+		warmStandbyCaches                 bool
+		port                              int
+		syncPeriod                        time.Duration
 
 		defaultScaleDownDelay time.Duration
+		hraDryRun             bool
+		actionsMetricsURL     string
+
+		offlineRunnerGracePeriod        time.Duration
+		offlineRunnerCollectionInterval time.Duration
+
+		orphanedWorkflowResourceGracePeriod        time.Duration
+		orphanedWorkflowResourceCollectionInterval time.Duration
+
+		preemptionNodeTaints     stringSlice
+		rerunPreemptedJobs       bool
+		preemptedJobRerunTimeout time.Duration
 
 		runnerImagePullSecrets stringSlice
 		runnerPodDefaults      actionssummerwindnet.RunnerPodDefaults
+		runnerSecurityDefaults bool
 
 		namespace                       string
 		logLevel                        string
@@ -106,8 +144,38 @@ func main() {
 
 		commonRunnerLabels commaSeparatedStringSlice
 
+		labelNodeSelectors = labelNodeSelectorMap{}
+
 		k8sClientRateLimiterQPS   int
 		k8sClientRateLimiterBurst int
+
+		credentialSource github.CredentialSourceConfig
+
+		auditLog        bool
+		auditWebhookURL string
+
+		requireGitHubCredential bool
+
+		shardName            string
+		shardConfigMap       string
+		shardRefreshInterval time.Duration
+
+		grafanaDashboardsConfigMap string
+
+		cloudEventsSinkURL string
+		cloudEventsSource  string
+		cloudEventsMode    string
+
+		jitConfigDelivery actionssummerwindnet.JITConfigDelivery
+
+		runnerPoolPolicyConfigMap string
+
+		policyHookURL      string
+		policyHookCELRules string
+		policyHookFailOpen bool
+
+		forensicSnapshotURL        string
+		forensicSnapshotS3Endpoint string
 	)
 	var c github.Config
 	err = envconfig.Process("github", &c)
@@ -122,11 +190,19 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&leaderElectionId, "leader-election-id", "actions-runner-controller", "Controller id for leader election.")
+	flag.DurationVar(&leaderElection.LeaseDuration, "leader-election-lease-duration", 15*time.Second, "How long a standby replica waits since the leader last renewed the lease before taking over the leadership. Shorten this along with -leader-election-renew-deadline for faster failovers when the leader is lost with its node.")
+	flag.DurationVar(&leaderElection.RenewDeadline, "leader-election-renew-deadline", 10*time.Second, "How long the leader keeps retrying to renew the lease before stepping down. Must be shorter than -leader-election-lease-duration.")
+	flag.DurationVar(&leaderElection.RetryPeriod, "leader-election-retry-period", 2*time.Second, "The interval between the attempts to acquire or renew the lease.")
+	flag.BoolVar(&leaderElectionReleaseOnCancel, "leader-election-release-on-cancel", true, "Release the lease when the leader shuts down, like on rollouts and node drains, so that a standby replica takes over without waiting for -leader-election-lease-duration.")
+	flag.BoolVar(&warmStandbyCaches, "warm-standby-caches", true, "Keep the informer caches of the standby replicas synced while they wait for the leadership, so that a new leader reconciles as soon as it takes over. Costs the standby replicas as much memory as the leader.")
 	flag.StringVar(&runnerPodDefaults.RunnerImage, "runner-image", defaultRunnerImage, "The image name of self-hosted runner container to use by default if one isn't defined in yaml.")
+	flag.StringVar(&runnerPodDefaults.WindowsRunnerImage, "windows-runner-image", defaultWindowsRunnerImage, "The image name of self-hosted runner container to use by default for runners with os: windows if one isn't defined in yaml.")
 	flag.StringVar(&runnerPodDefaults.DockerImage, "docker-image", defaultDockerImage, "The image name of docker sidecar container to use by default if one isn't defined in yaml.")
+	flag.StringVar(&runnerPodDefaults.BuildkitImage, "buildkit-image", defaultBuildkitImage, "The image name of buildkitd sidecar container of the runners with containerMode: buildkit to use by default if one isn't defined in yaml, and of the jobs building the runner images of RunnerDeployments with imageBuild.")
 	flag.StringVar(&runnerPodDefaults.DockerGID, "docker-gid", defaultDockerGID, "The default GID of docker group in the docker sidecar container. Use 1001 for dockerd sidecars of Ubuntu 20.04 runners 121 for Ubuntu 22.04.")
 	flag.Var(&runnerImagePullSecrets, "runner-image-pull-secret", "The default image-pull secret name for self-hosted runner container.")
 	flag.StringVar(&runnerPodDefaults.DockerRegistryMirror, "docker-registry-mirror", "", "The default Docker Registry Mirror used by runners.")
+	flag.StringVar(&runnerPodDefaults.ActionsCacheURL, "actions-cache-url", "", "The URL of the self-hosted Actions cache server set to ACTIONS_CACHE_URL and ACTIONS_RESULTS_URL of the runners. The runners use the cache service of GitHub if empty.")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.StringVar(&c.EnterpriseURL, "github-enterprise-url", c.EnterpriseURL, "Enterprise URL to be used for your GitHub API calls")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
@@ -137,8 +213,34 @@ func main() {
 	flag.StringVar(&c.BasicauthUsername, "github-basicauth-username", c.BasicauthUsername, "Username for GitHub basic auth to use instead of PAT or GitHub APP in case it's running behind a proxy API")
 	flag.StringVar(&c.BasicauthPassword, "github-basicauth-password", c.BasicauthPassword, "Password for GitHub basic auth to use instead of PAT or GitHub APP in case it's running behind a proxy API")
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
+	flag.StringVar(&c.ProxyURL, "github-proxy-url", c.ProxyURL, "The URL of the proxy for all the requests to GitHub. Defaults to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.")
+	flag.StringVar(&c.RootCAsFile, "github-root-cas-file", c.RootCAsFile, "The path of the file containing PEM-encoded certificates to trust in addition to the system root CAs when talking to GitHub.")
+	flag.StringVar(&c.TLSMinVersion, "github-tls-min-version", c.TLSMinVersion, `The minimum TLS version to talk to GitHub, including the scale set listeners. Valid values are "1.2" and "1.3".`)
+	flag.StringVar(&credentialSource.Source, "github-credentials-source", "", `The external secret store to read the GitHub API credentials from, instead of the github-token or github-app-* flags. Valid values are "vault", "aws-secrets-manager", and "gcp-secret-manager".`)
+	flag.StringVar(&credentialSource.Secret, "github-credentials-secret", "", "The Vault secret path, the AWS Secrets Manager secret name or ARN, or the GCP Secret Manager secret version name that contains the GitHub API credentials.")
+	flag.DurationVar(&credentialSource.RefreshInterval, "github-credentials-refresh-interval", 5*time.Minute, "The interval to re-read the GitHub API credentials from the external secret store.")
+	flag.StringVar(&credentialSource.VaultAddress, "vault-address", os.Getenv("VAULT_ADDR"), "The address of the Vault server to read the GitHub API credentials from, and to deliver the just-in-time configurations of the runners via.")
+	flag.StringVar(&credentialSource.VaultRole, "vault-role", "", "The role to log in to Vault via the Kubernetes auth method. Required unless VAULT_TOKEN is set.")
+	flag.StringVar(&credentialSource.VaultAuthPath, "vault-auth-path", "kubernetes", "The mount path of the Kubernetes auth method of Vault.")
+	flag.StringVar(&jitConfigDelivery.Mechanism, "runner-jit-config-delivery", actionssummerwindnet.JITConfigDeliverySecret, `How the just-in-time configurations of the runners with jitConfig are delivered to the runner pods. Valid values are "secret" for the Kubernetes Secrets, "vault" for the single-use wrapping tokens of Vault unwrapped by an init container, and "secrets-store-csi" for the volumes of the Secrets Store CSI driver reading them from Vault. The latter two keep the configurations out of etcd, and require vault-address. The runners with jitConfigDelivery use theirs instead.`)
+	flag.DurationVar(&jitConfigDelivery.TTL, "runner-jit-config-ttl", actionssummerwindnet.DefaultJITConfigDeliveryTTL, "How long the just-in-time configurations delivered via Vault can be read. The runner pods not started within it are recreated with new configurations.")
+	flag.StringVar(&jitConfigDelivery.KVMount, "runner-jit-config-vault-kv-mount", actionssummerwindnet.DefaultJITConfigVaultKVMount, "The mount path of the KV version 2 secrets engine of Vault the just-in-time configurations are kept in until read.")
+	flag.StringVar(&jitConfigDelivery.CSIRole, "runner-jit-config-csi-vault-role", "", `The Vault role the Vault provider of the Secrets Store CSI driver logs in as with the service accounts of the runner pods. Required by the "secrets-store-csi" delivery. "{namespace}" in it is replaced with the namespace of the runner, so that the runner pods of each namespace log in as a role that can only read the configurations of the namespace.`)
+	flag.StringVar(&jitConfigDelivery.CSIAuthPath, "runner-jit-config-csi-vault-auth-path", "", "The mount path of the Kubernetes auth method of Vault the Vault provider of the Secrets Store CSI driver logs in via. Defaults to the default of the provider.")
+	flag.Var(&labelNodeSelectors, "runner-label-node-selector", "The node selector in the LABEL:KEY=VALUE format added to the runner pods having the runner label, like gpu:nodepool=gpu. Can be specified multiple times.")
+	flag.BoolVar(&runnerPodDefaults.DeriveNodeSelectorFromLabels, "derive-runner-node-selector-from-labels", false, "Add the node selectors for the default runner labels linux, windows, x64, arm64, and arm to the runner pods having the labels.")
+	flag.BoolVar(&runnerSecurityDefaults, "runner-pod-security-defaults", false, `Serve the mutating admission webhook that applies a security baseline to the runner pods, like the RuntimeDefault seccomp profile and dropping all the capabilities. Runner pods annotated with "actions-runner-controller/security-defaults: false" are left as is.`)
 	flag.BoolVar(&runnerPodDefaults.UseRunnerStatusUpdateHook, "runner-status-update-hook", false, "Use custom RBAC for runners (role, role binding and service account).")
 	flag.DurationVar(&defaultScaleDownDelay, "default-scale-down-delay", actionssummerwindnet.DefaultScaleDownDelay, "The approximate delay for a scale down followed by a scale up, used to prevent flapping (down->up->down->... loop)")
+	flag.BoolVar(&hraDryRun, "hra-dry-run", false, "Make every HorizontalRunnerAutoscaler without spec.dryRun compute the desired replicas into its status, events, and metrics without changing the replicas of its scale target.")
+	flag.DurationVar(&offlineRunnerGracePeriod, "offline-runner-grace-period", 0, "How long a runner of a RunnerDeployment or a RunnerSet needs to be seen offline in GitHub without its runner pod before it's removed from GitHub. Set to a non-zero value like 1h to remove the runners that disappeared uncleanly, like on node loss or OOMKill.")
+	flag.DurationVar(&offlineRunnerCollectionInterval, "offline-runner-collection-interval", actionssummerwindnet.DefaultOfflineRunnerCollectionInterval, "The interval to look for the offline runners to be removed from GitHub. Used only when offline-runner-grace-period is set.")
+	flag.DurationVar(&orphanedWorkflowResourceGracePeriod, "orphaned-workflow-resource-grace-period", 0, `How long a workflow pod, service, secret, or persistent volume claim labeled with "runner-pod" or "actions-runner/runner-pod" needs to be seen without its runner pod before it's deleted. Set to a non-zero value like 1h to clean up the resources the container hooks and the jobs leaked on crashes.`)
+	flag.DurationVar(&orphanedWorkflowResourceCollectionInterval, "orphaned-workflow-resource-collection-interval", actionssummerwindnet.DefaultOrphanedWorkflowResourceCollectionInterval, "The interval to look for the orphaned workflow resources to be deleted. Used only when orphaned-workflow-resource-grace-period is set.")
+	flag.Var(&preemptionNodeTaints, "runner-preemption-node-taint", "The key of the taint the node termination handler adds onto the nodes about to be terminated, like aws-node-termination-handler/spot-itn. The busy runner pods on such nodes are considered preempted. Can be specified multiple times. Defaults to the taints of aws-node-termination-handler, GKE, and Karpenter.")
+	flag.BoolVar(&rerunPreemptedJobs, "rerun-preempted-jobs", false, "Rerun the workflow jobs lost to the preemption of their runner pods, like on spot instance termination, once their workflow runs complete. Requires the actions:write permission of the GitHub API credentials.")
+	flag.DurationVar(&preemptedJobRerunTimeout, "preempted-job-rerun-timeout", actionssummerwindnet.DefaultPreemptedJobRerunTimeout, "How long the workflow run of a preempted job is waited for to complete before giving up on rerunning the job. Used only when rerun-preempted-jobs is set.")
+	flag.StringVar(&actionsMetricsURL, "actions-metrics-url", "", "The URL of the actions-metrics-server's metrics endpoint, like http://actions-metrics-server:8080/metrics. Required by HorizontalRunnerAutoscalers with scaleDownDelayFromJobRunDuration or serviceLevelObjectives.")
 	flag.IntVar(&port, "port", 9443, "The port to which the admission webhook endpoint should bind")
 	flag.DurationVar(&syncPeriod, "sync-period", 1*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled.")
 	flag.IntVar(&opts.RunnerMaxConcurrentReconciles, "runner-max-concurrent-reconciles", opts.RunnerMaxConcurrentReconciles, "The maximum number of concurrent reconciles which can be run by the EphemeralRunner controller. Increase this value to improve the throughput of the controller, but it may also increase the load on the API server and the external service (e.g. GitHub API).")
@@ -149,13 +251,31 @@ func main() {
 	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
 	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
 	flag.BoolVar(&autoScalingRunnerSetOnly, "auto-scaling-runner-set-only", false, "Make controller only reconcile AutoRunnerScaleSet object.")
+	flag.BoolVar(&enableAutoscalingRunnerSetWebhook, "enable-autoscaling-runner-set-webhook", false, "Serve the validating admission webhook of the AutoscalingRunnerSets, rejecting duplicate runner scale set names in the same runner group. Requires a ValidatingWebhookConfiguration and a serving certificate for the webhook port.")
 	flag.StringVar(&updateStrategy, "update-strategy", "immediate", `Resources reconciliation strategy on upgrade with running/pending jobs. Valid values are: "immediate", "eventual". Defaults to "immediate".`)
 	flag.Var(&autoScalerImagePullSecrets, "auto-scaler-image-pull-secrets", "The default image-pull secret name for auto-scaler listener container.")
 	flag.IntVar(&k8sClientRateLimiterQPS, "k8s-client-rate-limiter-qps", 20, "The QPS value of the K8s client rate limiter.")
 	flag.IntVar(&k8sClientRateLimiterBurst, "k8s-client-rate-limiter-burst", 30, "The burst value of the K8s client rate limiter.")
+	flag.BoolVar(&auditLog, "audit-log", false, `Log every mutating GitHub API call made by the controller, like minting tokens and removing runners, with the resource it was made for, the outcome, and the latency, to the "audit" logger. Requires the "info" or "debug" log level.`)
+	flag.StringVar(&auditWebhookURL, "audit-webhook-url", "", "The URL to POST every mutating GitHub API call made by the controller to as a JSON event, like the HTTP collector of a SIEM.")
+	flag.BoolVar(&requireGitHubCredential, "require-github-credential", false, "Refuse to use the controller-wide GitHub API credentials for the runners, runner sets, and autoscalers in the namespaces without a GitHubCredential, so that every namespace uses its own credentials.")
+	flag.StringVar(&shardName, "shard-name", "", "The shard key of the controller deployment. The controller reconciles only the resources of the namespaces and the organizations the shard claims in -shard-config-map. Set to empty for disabling sharding.")
+	flag.StringVar(&shardConfigMap, "shard-config-map", "", "The coordination ConfigMap in the NAMESPACE/NAME format that lists the shards and the namespaces and the organizations each of them claims. Required by -shard-name.")
+	flag.DurationVar(&shardRefreshInterval, "shard-refresh-interval", sharding.DefaultRefreshInterval, "The interval to re-read -shard-config-map. The controller restarts when the shards change.")
+	flag.StringVar(&grafanaDashboardsConfigMap, "grafana-dashboards-config-map", "", "The ConfigMap in the NAMESPACE/NAME format the Grafana dashboards over the metrics of the controller are written to, labeled for the dashboard sidecar of Grafana. The dashboards are only served under /dashboards/ on the metrics endpoint if empty.")
+	flag.StringVar(&cloudEventsSinkURL, "cloudevents-sink-url", "", "The URL to POST the registrations and the deletions of the runners and the scale decisions of the HorizontalRunnerAutoscalers to as CloudEvents, like a Knative broker. Set to empty for disabling the CloudEvents.")
+	flag.StringVar(&cloudEventsSource, "cloudevents-source", cloudevents.DefaultSource, "The source of the CloudEvents, like the name of the cluster, which tells the events of the installations apart.")
+	flag.StringVar(&cloudEventsMode, "cloudevents-mode", cloudevents.ModeStructured, `The content mode of the CloudEvents, either "structured" for the whole events as the JSON body, or "binary" for the data as the body and the attributes as the ce- headers.`)
+	flag.StringVar(&runnerPoolPolicyConfigMap, "runner-pool-policy-config-map", "", "The ConfigMap in the NAMESPACE/NAME format holding the admin policy of the RunnerPoolClaims, which tells the namespace the runner pools are provisioned in and what the claims of each namespace may request. Set to empty for disabling the RunnerPoolClaims.")
+	flag.StringVar(&policyHookURL, "policy-hook-url", "", `The URL of the policy endpoint, like the Data API of Open Policy Agent, asked whether the HorizontalRunnerAutoscalers may scale up and whether the runners may be registered. Set to empty for not asking the endpoint.`)
+	flag.StringVar(&policyHookCELRules, "policy-hook-cel-rules", "", "The path to the YAML file of the CEL rules that must all hold for the HorizontalRunnerAutoscalers to scale up and for the runners to be registered. Set to empty for not evaluating the rules.")
+	flag.BoolVar(&policyHookFailOpen, "policy-hook-fail-open", false, "Allow the scale ups and the registrations when the policy can't be evaluated, like when the policy endpoint is down. They are denied otherwise.")
+	flag.StringVar(&forensicSnapshotURL, "forensic-snapshot-url", "", "The URL of the object storage the forensic snapshots of the runner pods whose runner containers exited with the exit codes of their forensicSnapshot are uploaded to, one of s3://BUCKET/PREFIX, gs://BUCKET/PREFIX, and https://ACCOUNT.blob.core.windows.net/CONTAINER/PREFIX. The snapshots aren't captured if empty.")
+	flag.StringVar(&forensicSnapshotS3Endpoint, "forensic-snapshot-s3-endpoint", "", "The endpoint of the S3-compatible storage like MinIO the s3:// forensic snapshot url points to.")
 	flag.Parse()
 
 	runnerPodDefaults.RunnerImagePullSecrets = runnerImagePullSecrets
+	runnerPodDefaults.LabelNodeSelectors = labelNodeSelectors
 
 	log, err := logging.NewLogger(logLevel, logFormat)
 	if err != nil {
@@ -164,8 +284,44 @@ func main() {
 	}
 	c.Log = &log
 
+	var auditSinks audit.Sinks
+	if auditLog {
+		auditSinks = append(auditSinks, audit.LogSink{Log: log.WithName("audit")})
+	}
+	var auditWebhookSink *audit.WebhookSink
+	if auditWebhookURL != "" {
+		auditWebhookSink = audit.NewWebhookSink(auditWebhookURL, log.WithName("audit-webhook"))
+		auditSinks = append(auditSinks, auditWebhookSink)
+	}
+	if len(auditSinks) > 0 {
+		c.AuditSink = auditSinks
+	}
+
 	log.Info("Using options", "runner-max-concurrent-reconciles", opts.RunnerMaxConcurrentReconciles)
 
+	credentialSource.VaultToken = os.Getenv("VAULT_TOKEN")
+
+	// The runners with jitConfigDelivery can use Vault even when the configurations are delivered via the Kubernetes Secrets by default
+	if jitConfigDelivery.Mechanism != actionssummerwindnet.JITConfigDeliverySecret || credentialSource.VaultAddress != "" {
+		jitConfigDelivery.Vault = &vault.Client{
+			Address:  credentialSource.VaultAddress,
+			Token:    credentialSource.VaultToken,
+			Role:     credentialSource.VaultRole,
+			AuthPath: credentialSource.VaultAuthPath,
+		}
+	}
+
+	if err := jitConfigDelivery.Validate(); err != nil {
+		log.Error(err, "unable to configure the delivery of just-in-time configurations")
+		os.Exit(1)
+	}
+
+	c.CredentialProvider, err = credentialSource.NewCredentialProvider()
+	if err != nil {
+		log.Error(err, "unable to configure github credentials source")
+		os.Exit(1)
+	}
+
 	if !autoScalingRunnerSetOnly {
 		ghClient, err = c.NewClient()
 		if err != nil {
@@ -228,23 +384,100 @@ func main() {
 	cfg.QPS = float32(k8sClientRateLimiterQPS)
 	cfg.Burst = k8sClientRateLimiterBurst
 
+	var (
+		sharder      *sharding.Sharder
+		shardWatcher *sharding.Watcher
+	)
+	if shardName != "" {
+		ns, name, ok := strings.Cut(shardConfigMap, "/")
+		if !ok || ns == "" || name == "" {
+			log.Error(fmt.Errorf("invalid -shard-config-map %q", shardConfigMap), "-shard-config-map must be in the NAMESPACE/NAME format")
+			os.Exit(1)
+		}
+		shardConfigMapKey := types.NamespacedName{Namespace: ns, Name: name}
+
+		// The manager isn't created yet, as the namespaces of the shard decide the namespaces of its cache
+		reader, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
+			log.Error(err, "unable to create client for reading shards")
+			os.Exit(1)
+		}
+
+		shards, err := sharding.Load(context.Background(), reader, shardConfigMapKey)
+		if err != nil {
+			log.Error(err, "unable to read shards")
+			os.Exit(1)
+		}
+
+		sharder, err = sharding.New(shardName, shards)
+		if err != nil {
+			log.Error(err, "unable to configure shard")
+			os.Exit(1)
+		}
+
+		shardWatcher = &sharding.Watcher{
+			Client:   reader,
+			Key:      shardConfigMapKey,
+			Interval: shardRefreshInterval,
+			Log:      log.WithName("sharding"),
+			Shards:   shards,
+		}
+
+		if shardNamespaces := sharder.Namespaces(); namespace == "" && len(watchSingleNamespace) == 0 && shardNamespaces != nil {
+			defaultNamespaces = map[string]cache.Config{}
+			for _, ns := range shardNamespaces {
+				defaultNamespaces[ns] = cache.Config{}
+			}
+			if managerNamespace != "" {
+				defaultNamespaces[managerNamespace] = cache.Config{}
+			}
+		}
+
+		// The shards elect their leaders independently
+		leaderElectionId = leaderElectionId + "-" + shardName
+
+		log.Info("Sharding enabled", "shard", shardName, "configMap", shardConfigMap, "namespaces", sharder.Namespaces())
+	}
+
+	if enableLeaderElection {
+		if err := leaderElection.Validate(); err != nil {
+			log.Error(err, "invalid leader election durations")
+			os.Exit(1)
+		}
+	}
+
+	metricsOptions := metricsserver.Options{
+		BindAddress: metricsAddr,
+	}
+
+	// The dashboards and the alerts are over the metrics of the legacy controllers
+	if !autoScalingRunnerSetOnly {
+		metricsOptions.ExtraHandlers = map[string]http.Handler{
+			summerwindmetrics.DashboardsPath: summerwindmetrics.DashboardsHandler(),
+		}
+	}
+
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
-		},
+		Scheme:  scheme,
+		Metrics: metricsOptions,
 		Cache: cache.Options{
 			SyncPeriod:        &syncPeriod,
 			DefaultNamespaces: defaultNamespaces,
 		},
-		WebhookServer:    webhookServer,
-		LeaderElection:   enableLeaderElection,
-		LeaderElectionID: leaderElectionId,
+		WebhookServer:                 webhookServer,
+		LeaderElection:                enableLeaderElection,
+		LeaderElectionID:              leaderElectionId,
+		LeaderElectionReleaseOnCancel: leaderElectionReleaseOnCancel,
+		LeaseDuration:                 &leaderElection.LeaseDuration,
+		RenewDeadline:                 &leaderElection.RenewDeadline,
+		RetryPeriod:                   &leaderElection.RetryPeriod,
 		Client: client.Options{
 			Cache: &client.CacheOptions{
 				DisableFor: []client.Object{
 					&corev1.Secret{},
 					&corev1.ConfigMap{},
+					// Leases are only read by the concurrency gates of RunnerDeployments
+					&coordinationv1.Lease{},
 				},
 			},
 		},
@@ -254,6 +487,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	if shardWatcher != nil {
+		if err := mgr.Add(shardWatcher); err != nil {
+			log.Error(err, "unable to add the shard watcher to the manager")
+			os.Exit(1)
+		}
+	}
+
+	if auditWebhookSink != nil {
+		if err := mgr.Add(auditWebhookSink); err != nil {
+			log.Error(err, "unable to add the audit webhook sink to the manager")
+			os.Exit(1)
+		}
+	}
+
 	if autoScalingRunnerSetOnly {
 		if err := actionsgithubcom.SetupIndexers(mgr); err != nil {
 			log.Error(err, "unable to setup indexers")
@@ -270,12 +517,22 @@ func main() {
 			actionsgithubcommetrics.RegisterMetrics()
 		}
 
-		actionsMultiClient := actions.NewMultiClient(
-			log.WithName("actions-clients"),
-		)
+		actionsClientOptions := []actions.ClientOption{
+			actions.WithTransportConfig(httptransport.Config{
+				ProxyURL:      c.ProxyURL,
+				RootCAsFile:   c.RootCAsFile,
+				TLSMinVersion: c.TLSMinVersion,
+			}),
+		}
+		if c.AuditSink != nil {
+			actionsClientOptions = append(actionsClientOptions, actions.WithAuditSink(c.AuditSink))
+		}
+
+		actionsMultiClient := actions.NewMultiClient(log.WithName("actions-clients"), actionsClientOptions...)
 
 		rb := actionsgithubcom.ResourceBuilder{
 			ExcludeLabelPropagationPrefixes: excludeLabelPropagationPrefixes,
+			TLSMinVersion:                   c.TLSMinVersion,
 		}
 
 		if err = (&actionsgithubcom.AutoscalingRunnerSetReconciler{
@@ -288,6 +545,7 @@ func main() {
 			UpdateStrategy:                     actionsgithubcom.UpdateStrategy(updateStrategy),
 			DefaultRunnerScaleSetListenerImagePullSecrets: autoScalerImagePullSecrets,
 			ResourceBuilder: rb,
+			Sharder:         sharder,
 		}).SetupWithManager(mgr); err != nil {
 			log.Error(err, "unable to create controller", "controller", "AutoscalingRunnerSet")
 			os.Exit(1)
@@ -299,6 +557,7 @@ func main() {
 			Scheme:          mgr.GetScheme(),
 			ActionsClient:   actionsMultiClient,
 			ResourceBuilder: rb,
+			Sharder:         sharder,
 		}).SetupWithManager(mgr, actionsgithubcom.WithMaxConcurrentReconciles(opts.RunnerMaxConcurrentReconciles)); err != nil {
 			log.Error(err, "unable to create controller", "controller", "EphemeralRunner")
 			os.Exit(1)
@@ -311,11 +570,40 @@ func main() {
 			ActionsClient:   actionsMultiClient,
 			PublishMetrics:  metricsAddr != "0",
 			ResourceBuilder: rb,
+			Sharder:         sharder,
 		}).SetupWithManager(mgr); err != nil {
 			log.Error(err, "unable to create controller", "controller", "EphemeralRunnerSet")
 			os.Exit(1)
 		}
 
+		if err = (&actionsgithubcom.RunnerGroupReconciler{
+			Client:        mgr.GetClient(),
+			Log:           log.WithName("RunnerGroup").WithValues("version", build.Version),
+			Scheme:        mgr.GetScheme(),
+			ActionsClient: actionsMultiClient,
+			Sharder:       sharder,
+		}).SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create controller", "controller", "RunnerGroup")
+			os.Exit(1)
+		}
+
+		if err = (&actionsgithubcom.RunnerInventoryReconciler{
+			Client:  mgr.GetClient(),
+			Log:     log.WithName("RunnerInventory").WithValues("version", build.Version),
+			Scheme:  mgr.GetScheme(),
+			Sharder: sharder,
+		}).SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create controller", "controller", "RunnerInventory")
+			os.Exit(1)
+		}
+
+		if enableAutoscalingRunnerSetWebhook {
+			if err = (&githubv1alpha1.AutoscalingRunnerSet{}).SetupWebhookWithManager(mgr); err != nil {
+				log.Error(err, "unable to create webhook", "webhook", "AutoscalingRunnerSet")
+				os.Exit(1)
+			}
+		}
+
 		if err = (&actionsgithubcom.AutoscalingListenerReconciler{
 			Client:                  mgr.GetClient(),
 			Log:                     log.WithName("AutoscalingListener").WithValues("version", build.Version),
@@ -323,6 +611,7 @@ func main() {
 			ListenerMetricsAddr:     listenerMetricsAddr,
 			ListenerMetricsEndpoint: listenerMetricsEndpoint,
 			ResourceBuilder:         rb,
+			Sharder:                 sharder,
 		}).SetupWithManager(mgr); err != nil {
 			log.Error(err, "unable to create controller", "controller", "AutoscalingListener")
 			os.Exit(1)
@@ -332,6 +621,38 @@ func main() {
 			mgr.GetClient(),
 			ghClient,
 		)
+		multiClient.RequireGitHubCredential = requireGitHubCredential
+
+		policyHook, err := policyhook.New(policyHookURL, policyHookCELRules, policyHookFailOpen, log.WithName("policyhook"))
+		if err != nil {
+			log.Error(err, "unable to create the policy hook")
+			os.Exit(1)
+		}
+
+		var cloudEventsEmitter cloudevents.Emitter
+		if cloudEventsSinkURL != "" {
+			sink, err := cloudevents.NewHTTPSink(cloudEventsSinkURL, cloudEventsSource, cloudEventsMode, log.WithName("cloudevents"))
+			if err != nil {
+				log.Error(err, "unable to create the CloudEvents sink")
+				os.Exit(1)
+			}
+
+			if err := mgr.Add(sink); err != nil {
+				log.Error(err, "unable to add the CloudEvents sink to the manager")
+				os.Exit(1)
+			}
+
+			cloudEventsEmitter = sink
+		}
+
+		var forensicSnapshots *actionssummerwindnet.ForensicSnapshots
+		if forensicSnapshotURL != "" {
+			forensicSnapshots, err = actionssummerwindnet.NewForensicSnapshots(context.Background(), forensicSnapshotURL, forensicSnapshotS3Endpoint, cfg)
+			if err != nil {
+				log.Error(err, "unable to create the forensic snapshot store")
+				os.Exit(1)
+			}
+		}
 
 		runnerReconciler := &actionssummerwindnet.RunnerReconciler{
 			Client:            mgr.GetClient(),
@@ -339,6 +660,11 @@ func main() {
 			Scheme:            mgr.GetScheme(),
 			GitHubClient:      multiClient,
 			RunnerPodDefaults: runnerPodDefaults,
+			Sharder:           sharder,
+			CloudEvents:       cloudEventsEmitter,
+			JITConfigDelivery: jitConfigDelivery,
+			Policy:            policyHook,
+			ForensicSnapshots: forensicSnapshots,
 		}
 
 		if err = runnerReconciler.SetupWithManager(mgr); err != nil {
@@ -347,9 +673,10 @@ func main() {
 		}
 
 		runnerReplicaSetReconciler := &actionssummerwindnet.RunnerReplicaSetReconciler{
-			Client: mgr.GetClient(),
-			Log:    log.WithName("runnerreplicaset"),
-			Scheme: mgr.GetScheme(),
+			Client:  mgr.GetClient(),
+			Log:     log.WithName("runnerreplicaset"),
+			Scheme:  mgr.GetScheme(),
+			Sharder: sharder,
 		}
 
 		if err = runnerReplicaSetReconciler.SetupWithManager(mgr); err != nil {
@@ -362,6 +689,15 @@ func main() {
 			Log:                log.WithName("runnerdeployment"),
 			Scheme:             mgr.GetScheme(),
 			CommonRunnerLabels: commonRunnerLabels,
+			BuildkitImage:      runnerPodDefaults.BuildkitImage,
+			Sharder:            sharder,
+		}
+
+		// The runners of GitHub Enterprise Server connect to the server and its subdomains instead of GitHub.com
+		if ghClient.IsEnterprise {
+			if u, err := url.Parse(ghClient.GithubBaseURL); err == nil && u.Hostname() != "" {
+				runnerDeploymentReconciler.EgressPolicyGitHubFQDNs = []string{u.Hostname(), "*." + u.Hostname()}
+			}
 		}
 
 		if err = runnerDeploymentReconciler.SetupWithManager(mgr); err != nil {
@@ -376,6 +712,7 @@ func main() {
 			CommonRunnerLabels: commonRunnerLabels,
 			GitHubClient:       multiClient,
 			RunnerPodDefaults:  runnerPodDefaults,
+			Sharder:            sharder,
 		}
 
 		if err = runnerSetReconciler.SetupWithManager(mgr); err != nil {
@@ -387,9 +724,13 @@ func main() {
 			"Initializing actions-runner-controller",
 			"version", build.Version,
 			"default-scale-down-delay", defaultScaleDownDelay,
+			"hra-dry-run", hraDryRun,
 			"sync-period", syncPeriod,
 			"default-runner-image", runnerPodDefaults.RunnerImage,
+			"default-windows-runner-image", runnerPodDefaults.WindowsRunnerImage,
 			"default-docker-image", runnerPodDefaults.DockerImage,
+			"default-buildkit-image", runnerPodDefaults.BuildkitImage,
+			"actions-cache-url", runnerPodDefaults.ActionsCacheURL,
 			"default-docker-gid", runnerPodDefaults.DockerGID,
 			"common-runnner-labels", commonRunnerLabels,
 			"leader-election-enabled", enableLeaderElection,
@@ -403,6 +744,20 @@ func main() {
 			Scheme:                mgr.GetScheme(),
 			GitHubClient:          multiClient,
 			DefaultScaleDownDelay: defaultScaleDownDelay,
+			DryRun:                hraDryRun,
+			Sharder:               sharder,
+			CloudEvents:           cloudEventsEmitter,
+			Policy:                policyHook,
+		}
+
+		if actionsMetricsURL != "" {
+			// Both share the scraped histograms
+			jobDurations := &actionssummerwindnet.ActionsMetricsJobRunDurations{
+				URL: actionsMetricsURL,
+			}
+
+			horizontalRunnerAutoscaler.JobRunDurations = jobDurations
+			horizontalRunnerAutoscaler.JobDurationHistograms = jobDurations
 		}
 
 		runnerPodReconciler := &actionssummerwindnet.RunnerPodReconciler{
@@ -410,6 +765,7 @@ func main() {
 			Log:          log.WithName("runnerpod"),
 			Scheme:       mgr.GetScheme(),
 			GitHubClient: multiClient,
+			Sharder:      sharder,
 		}
 
 		runnerPersistentVolumeReconciler := &actionssummerwindnet.RunnerPersistentVolumeReconciler{
@@ -434,6 +790,39 @@ func main() {
 			os.Exit(1)
 		}
 
+		maintenanceWindowReconciler := &actionssummerwindnet.MaintenanceWindowReconciler{
+			Client:       mgr.GetClient(),
+			Log:          log.WithName("maintenancewindow"),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: multiClient,
+		}
+
+		if err = maintenanceWindowReconciler.SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create controller", "controller", "MaintenanceWindow")
+			os.Exit(1)
+		}
+
+		if runnerPoolPolicyConfigMap != "" {
+			ns, name, ok := strings.Cut(runnerPoolPolicyConfigMap, "/")
+			if !ok || ns == "" || name == "" {
+				log.Error(fmt.Errorf("invalid -runner-pool-policy-config-map %q", runnerPoolPolicyConfigMap), "-runner-pool-policy-config-map must be in the NAMESPACE/NAME format")
+				os.Exit(1)
+			}
+
+			runnerPoolClaimReconciler := &actionssummerwindnet.RunnerPoolClaimReconciler{
+				Client:          mgr.GetClient(),
+				Log:             log.WithName("runnerpoolclaim"),
+				Scheme:          mgr.GetScheme(),
+				PolicyConfigMap: types.NamespacedName{Namespace: ns, Name: name},
+				PolicyReader:    mgr.GetAPIReader(),
+			}
+
+			if err = runnerPoolClaimReconciler.SetupWithManager(mgr); err != nil {
+				log.Error(err, "unable to create controller", "controller", "RunnerPoolClaim")
+				os.Exit(1)
+			}
+		}
+
 		if err = runnerPersistentVolumeReconciler.SetupWithManager(mgr); err != nil {
 			log.Error(err, "unable to create controller", "controller", "RunnerPersistentVolume")
 			os.Exit(1)
@@ -444,6 +833,47 @@ func main() {
 			os.Exit(1)
 		}
 
+		offlineRunnerCollector := &actionssummerwindnet.OfflineRunnerCollector{
+			Client:       mgr.GetClient(),
+			Log:          log.WithName("offlinerunnercollector"),
+			GitHubClient: multiClient,
+			Interval:     offlineRunnerCollectionInterval,
+			GracePeriod:  offlineRunnerGracePeriod,
+		}
+
+		if err = offlineRunnerCollector.SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create offline runner collector")
+			os.Exit(1)
+		}
+
+		orphanedWorkflowResourceCollector := &actionssummerwindnet.OrphanedWorkflowResourceCollector{
+			Client:      mgr.GetClient(),
+			Log:         log.WithName("orphanedworkflowresourcecollector"),
+			Namespace:   namespace,
+			Interval:    orphanedWorkflowResourceCollectionInterval,
+			GracePeriod: orphanedWorkflowResourceGracePeriod,
+		}
+
+		if err = orphanedWorkflowResourceCollector.SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create orphaned workflow resource collector")
+			os.Exit(1)
+		}
+
+		runnerPreemptionReconciler := &actionssummerwindnet.RunnerPreemptionReconciler{
+			Client:       mgr.GetClient(),
+			Log:          log.WithName("runnerpreemption"),
+			Scheme:       mgr.GetScheme(),
+			GitHubClient: multiClient,
+			NodeTaints:   preemptionNodeTaints,
+			RerunJobs:    rerunPreemptedJobs,
+			RerunTimeout: preemptedJobRerunTimeout,
+		}
+
+		if err = runnerPreemptionReconciler.SetupWithManager(mgr); err != nil {
+			log.Error(err, "unable to create controller", "controller", "RunnerPreemption")
+			os.Exit(1)
+		}
+
 		if !disableAdmissionWebhook {
 			if err = (&summerwindv1alpha1.Runner{}).SetupWebhookWithManager(mgr); err != nil {
 				log.Error(err, "unable to create webhook", "webhook", "Runner")
@@ -457,6 +887,19 @@ func main() {
 				log.Error(err, "unable to create webhook", "webhook", "RunnerReplicaSet")
 				os.Exit(1)
 			}
+			if err = (&summerwindv1alpha1.HorizontalRunnerAutoscaler{}).SetupWebhookWithManager(mgr); err != nil {
+				log.Error(err, "unable to create webhook", "webhook", "HorizontalRunnerAutoscaler")
+				os.Exit(1)
+			}
+			if runnerSecurityDefaults {
+				securityDefaulter := &actionssummerwindnet.PodSecurityDefaulter{
+					Log: ctrl.Log.WithName("webhook").WithName("PodSecurityDefaulter"),
+				}
+				if err = securityDefaulter.SetupWithManager(mgr); err != nil {
+					log.Error(err, "unable to create webhook server", "webhook", "PodSecurityDefaulter")
+					os.Exit(1)
+				}
+			}
 			injector := &actionssummerwindnet.PodRunnerTokenInjector{
 				Client:       mgr.GetClient(),
 				GitHubClient: multiClient,
@@ -469,6 +912,64 @@ func main() {
 		}
 	}
 
+	if grafanaDashboardsConfigMap != "" && !autoScalingRunnerSetOnly {
+		ns, name, ok := strings.Cut(grafanaDashboardsConfigMap, "/")
+		if !ok || ns == "" || name == "" {
+			log.Error(fmt.Errorf("invalid -grafana-dashboards-config-map %q", grafanaDashboardsConfigMap), "-grafana-dashboards-config-map must be in the NAMESPACE/NAME format")
+			os.Exit(1)
+		}
+
+		provisioner := &summerwindmetrics.DashboardProvisioner{
+			Client:    mgr.GetClient(),
+			Log:       log.WithName("dashboards"),
+			ConfigMap: types.NamespacedName{Namespace: ns, Name: name},
+		}
+
+		if err := mgr.Add(provisioner); err != nil {
+			log.Error(err, "unable to add the dashboard provisioner to the manager")
+			os.Exit(1)
+		}
+	}
+
+	if enableLeaderElection && warmStandbyCaches {
+		warmer := &standby.CacheWarmer{
+			Cache: mgr.GetCache(),
+			Log:   log.WithName("standby"),
+		}
+
+		if autoScalingRunnerSetOnly {
+			warmer.Objects = []client.Object{
+				&githubv1alpha1.AutoscalingRunnerSet{},
+				&githubv1alpha1.AutoscalingListener{},
+				&githubv1alpha1.EphemeralRunnerSet{},
+				&githubv1alpha1.EphemeralRunner{},
+				&githubv1alpha1.RunnerGroup{},
+				&githubv1alpha1.RunnerInventory{},
+				&corev1.Pod{},
+				&corev1.ServiceAccount{},
+				&rbacv1.Role{},
+				&rbacv1.RoleBinding{},
+			}
+		} else {
+			warmer.Objects = []client.Object{
+				&summerwindv1alpha1.HorizontalRunnerAutoscaler{},
+				&summerwindv1alpha1.RunnerDeployment{},
+				&summerwindv1alpha1.RunnerReplicaSet{},
+				&summerwindv1alpha1.Runner{},
+				&summerwindv1alpha1.RunnerSet{},
+				&appsv1.StatefulSet{},
+				&corev1.Pod{},
+				&corev1.PersistentVolumeClaim{},
+				&corev1.PersistentVolume{},
+			}
+		}
+
+		if err := mgr.Add(warmer); err != nil {
+			log.Error(err, "unable to add the standby cache warmer to the manager")
+			os.Exit(1)
+		}
+	}
+
 	log.Info("starting manager", "version", build.Version)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		log.Error(err, "problem running manager")
@@ -492,3 +993,23 @@ func (s *commaSeparatedStringSlice) Set(value string) error {
 	}
 	return nil
 }
+
+type labelNodeSelectorMap map[string]map[string]string
+
+func (m labelNodeSelectorMap) String() string {
+	return fmt.Sprintf("%v", map[string]map[string]string(m))
+}
+
+func (m labelNodeSelectorMap) Set(value string) error {
+	label, key, v, err := actionssummerwindnet.ParseLabelNodeSelector(value)
+	if err != nil {
+		return err
+	}
+
+	if m[label] == nil {
+		m[label] = map[string]string{}
+	}
+	m[label][key] = v
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package actionsmetrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AnnotationKeyCostPerHour is the annotation on the runner pods for the hourly cost of a runner, like "0.096".
+	// It takes precedence over the price of the instance type of the node.
+	AnnotationKeyCostPerHour = "actions-runner/cost-per-hour"
+
+	// AnnotationKeyCostCenter is the annotation on the runner pods for the team or the cost center the runner-hours are charged to.
+	AnnotationKeyCostCenter = "actions-runner/cost-center"
+
+	labelKeyInstanceType = "node.kubernetes.io/instance-type"
+
+	costSourceAnnotation   = "annotation"
+	costSourceInstanceType = "instance_type"
+	costSourceDefault      = "default"
+
+	costExpiryInterval = 10 * time.Minute
+)
+
+// CostModel is how much a runner costs per hour.
+type CostModel struct {
+	// DefaultHourlyCost is the hourly cost of the runners with neither the cost annotation nor a known instance type.
+	DefaultHourlyCost float64 `json:"defaultHourlyCost,omitempty"`
+
+	// InstanceTypes are the hourly costs of the runners by the instance types of their nodes, like "m5.large": 0.096,
+	// usually copied from the price list of the cloud provider.
+	// The whole price of the node is charged to every runner on it, so divide it by the number of runners per node when they share nodes.
+	InstanceTypes map[string]float64 `json:"instanceTypes,omitempty"`
+}
+
+// LoadCostModel reads the cost model from the YAML or JSON file.
+func LoadCostModel(path string) (*CostModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m CostModel
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("reading cost model %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// HourlyCost returns the hourly cost of the runner pod on the node, along with where the cost came from.
+// The pod and the node can be nil when they are unknown.
+func (m *CostModel) HourlyCost(pod *corev1.Pod, node *corev1.Node) (float64, string) {
+	if pod != nil {
+		if v, ok := pod.Annotations[AnnotationKeyCostPerHour]; ok {
+			if cost, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && cost >= 0 {
+				return cost, costSourceAnnotation
+			}
+		}
+	}
+
+	if node != nil {
+		if cost, ok := m.InstanceTypes[node.Labels[labelKeyInstanceType]]; ok {
+			return cost, costSourceInstanceType
+		}
+	}
+
+	return m.DefaultHourlyCost, costSourceDefault
+}
+
+// runnerCost is the cost of the runner of an in-progress job
+type runnerCost struct {
+	hourlyCost float64
+	costCenter string
+	seen       time.Time
+}
+
+// CostTracker attributes the runner-hours and their estimated costs to the repositories, the workflows,
+// and the runs-on labels of the completed jobs.
+//
+// The hourly cost of the runner of a job is looked up when the job starts, as the pod of an ephemeral runner is gone
+// soon after the job completes. The runner-hours are the run times of the jobs reported by GitHub,
+// so that they add up with the run durations of the other workflow job metrics.
+type CostTracker struct {
+	Log logr.Logger
+
+	Model *CostModel
+
+	// Client looks up the runner pods and their nodes. Without it, every runner costs the DefaultHourlyCost of the model.
+	Client client.Reader
+
+	Events chan *gogithub.WorkflowJobEvent
+
+	runners     map[int64]runnerCost
+	runnersLock sync.Mutex
+}
+
+// HandleWorkflowJobEvent queues the started and the completed workflow jobs.
+func (t *CostTracker) HandleWorkflowJobEvent(event interface{}) {
+	e, ok := event.(*gogithub.WorkflowJobEvent)
+	if !ok {
+		return
+	}
+
+	switch e.GetAction() {
+	case "in_progress", "completed":
+		t.Events <- e
+	}
+}
+
+// Run records the costs of the runners of the started jobs and counts the costs of the completed ones in a loop.
+//
+// Should be called asynchronously with `go`
+func (t *CostTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(costExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-t.Events:
+			if e.GetAction() == "in_progress" {
+				t.recordRunnerCost(ctx, e)
+				continue
+			}
+
+			t.countJobCost(e)
+		case <-ticker.C:
+			t.expireRunnerCosts(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *CostTracker) recordRunnerCost(ctx context.Context, e *gogithub.WorkflowJobEvent) {
+	job := e.GetWorkflowJob()
+
+	var (
+		pod  *corev1.Pod
+		node *corev1.Node
+	)
+
+	if name := job.GetRunnerName(); name != "" && t.Client != nil {
+		var pods corev1.PodList
+		if err := t.Client.List(ctx, &pods, client.MatchingFields{"metadata.name": name}); err != nil {
+			t.Log.Error(err, "looking up the runner pod of workflow job", "job_id", job.GetID(), "runner", name)
+		} else if len(pods.Items) > 0 {
+			pod = &pods.Items[0]
+		}
+	}
+
+	if pod != nil && pod.Spec.NodeName != "" {
+		var n corev1.Node
+		if err := t.Client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, &n); err != nil {
+			t.Log.V(1).Info("Failed to look up the node of the runner pod. The cost of the instance type isn't applied", "node", pod.Spec.NodeName, "error", err.Error())
+		} else {
+			node = &n
+		}
+	}
+
+	cost, source := t.Model.HourlyCost(pod, node)
+
+	var costCenter string
+	if pod != nil {
+		costCenter = pod.Annotations[AnnotationKeyCostCenter]
+	}
+
+	t.Log.V(1).Info("Recorded the cost of the runner", "job_id", job.GetID(), "runner", job.GetRunnerName(), "hourly_cost", cost, "source", source)
+
+	t.runnersLock.Lock()
+	defer t.runnersLock.Unlock()
+
+	if t.runners == nil {
+		t.runners = map[int64]runnerCost{}
+	}
+
+	t.runners[job.GetID()] = runnerCost{hourlyCost: cost, costCenter: costCenter, seen: time.Now()}
+}
+
+// countJobCost adds the run time of the completed job and its cost to the metrics.
+// A job whose start was missed, like while the server was restarting, costs the DefaultHourlyCost of the model.
+func (t *CostTracker) countJobCost(e *gogithub.WorkflowJobEvent) {
+	job := e.GetWorkflowJob()
+
+	t.runnersLock.Lock()
+	rc, ok := t.runners[job.GetID()]
+	delete(t.runners, job.GetID())
+	t.runnersLock.Unlock()
+
+	// The skipped and the cancelled-before-starting jobs never ran on a runner
+	if job.GetRunnerName() == "" || job.StartedAt == nil || job.CompletedAt == nil {
+		return
+	}
+
+	hours := job.GetCompletedAt().Time.Sub(job.GetStartedAt().Time).Hours()
+	if hours <= 0 {
+		return
+	}
+
+	if !ok {
+		rc.hourlyCost, _ = t.Model.HourlyCost(nil, nil)
+	}
+
+	labels := prometheus.Labels{
+		"repository_full_name": e.GetRepo().GetFullName(),
+		"workflow_name":        job.GetWorkflowName(),
+		"runs_on":              strings.Join(job.Labels, ","),
+		"cost_center":          rc.costCenter,
+	}
+
+	githubWorkflowJobRunnerHoursTotal.With(labels).Add(hours)
+	githubWorkflowJobEstimatedCostTotal.With(labels).Add(hours * rc.hourlyCost)
+}
+
+func (t *CostTracker) expireRunnerCosts(now time.Time) {
+	t.runnersLock.Lock()
+	defer t.runnersLock.Unlock()
+
+	for id, rc := range t.runners {
+		if now.Sub(rc.seen) > runnerNodeTTL {
+			delete(t.runners, id)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package actionsmetrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadCostModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cost-model.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("defaultHourlyCost: 0.05\ninstanceTypes:\n  m5.large: 0.096\n"), 0644))
+
+	m, err := LoadCostModel(path)
+	require.NoError(t, err)
+	require.Equal(t, &CostModel{DefaultHourlyCost: 0.05, InstanceTypes: map[string]float64{"m5.large": 0.096}}, m)
+
+	require.NoError(t, os.WriteFile(path, []byte("defaultCost: 0.05\n"), 0644))
+	_, err = LoadCostModel(path)
+	require.Error(t, err)
+}
+
+func TestCostModelHourlyCost(t *testing.T) {
+	m := &CostModel{DefaultHourlyCost: 0.05, InstanceTypes: map[string]float64{"m5.large": 0.096}}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{labelKeyInstanceType: "m5.large"}}}
+	annotated := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyCostPerHour: "0.5"}}}
+	invalid := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationKeyCostPerHour: "cheap"}}}
+
+	cost, source := m.HourlyCost(annotated, node)
+	require.Equal(t, 0.5, cost)
+	require.Equal(t, costSourceAnnotation, source)
+
+	cost, source = m.HourlyCost(invalid, node)
+	require.Equal(t, 0.096, cost)
+	require.Equal(t, costSourceInstanceType, source)
+
+	cost, source = m.HourlyCost(nil, &corev1.Node{})
+	require.Equal(t, 0.05, cost)
+	require.Equal(t, costSourceDefault, source)
+}
+
+func TestCostTracker(t *testing.T) {
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "runners",
+					Name:        "example-runner-abcde",
+					Annotations: map[string]string{AnnotationKeyCostCenter: "ml-team"},
+				},
+				Spec: corev1.PodSpec{NodeName: "gpu-node-1"},
+			},
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-1", Labels: map[string]string{labelKeyInstanceType: "p3.2xlarge"}},
+			},
+		).
+		WithIndex(&corev1.Pod{}, "metadata.name", func(o client.Object) []string { return []string{o.GetName()} }).
+		Build()
+
+	tr := &CostTracker{
+		Log:    logr.Discard(),
+		Model:  &CostModel{DefaultHourlyCost: 0.1, InstanceTypes: map[string]float64{"p3.2xlarge": 3}},
+		Client: c,
+	}
+
+	startedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	e := &gogithub.WorkflowJobEvent{
+		Action: gogithub.String("in_progress"),
+		Repo:   &gogithub.Repository{FullName: gogithub.String("owner/cost-repo")},
+		WorkflowJob: &gogithub.WorkflowJob{
+			ID:           gogithub.Int64(10),
+			Name:         gogithub.String("train"),
+			WorkflowName: gogithub.String("ml"),
+			Labels:       []string{"self-hosted", "gpu"},
+			RunnerName:   gogithub.String("example-runner-abcde"),
+			StartedAt:    &gogithub.Timestamp{Time: startedAt},
+		},
+	}
+
+	tr.recordRunnerCost(context.Background(), e)
+
+	e.Action = gogithub.String("completed")
+	e.WorkflowJob.CompletedAt = &gogithub.Timestamp{Time: startedAt.Add(30 * time.Minute)}
+
+	tr.countJobCost(e)
+
+	labels := []string{"owner/cost-repo", "ml", "self-hosted,gpu", "ml-team"}
+	require.Equal(t, 0.5, testutil.ToFloat64(githubWorkflowJobRunnerHoursTotal.WithLabelValues(labels...)))
+	require.Equal(t, 1.5, testutil.ToFloat64(githubWorkflowJobEstimatedCostTotal.WithLabelValues(labels...)))
+	require.Empty(t, tr.runners)
+
+	// The job whose start was missed costs the default
+	e.WorkflowJob.ID = gogithub.Int64(11)
+
+	tr.countJobCost(e)
+
+	labels = []string{"owner/cost-repo", "ml", "self-hosted,gpu", ""}
+	require.Equal(t, 0.5, testutil.ToFloat64(githubWorkflowJobRunnerHoursTotal.WithLabelValues(labels...)))
+	require.InDelta(t, 0.05, testutil.ToFloat64(githubWorkflowJobEstimatedCostTotal.WithLabelValues(labels...)), 1e-9)
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/ghes"
 )
 
 const (
@@ -91,59 +93,7 @@ func (reader *EventReader) ProcessWorkflowJobEvent(ctx context.Context, event in
 		return
 	}
 
-	// collect labels
-	var (
-		labels        = make(prometheus.Labels)
-		keysAndValues = []interface{}{"job_id", fmt.Sprint(*e.WorkflowJob.ID)}
-	)
-
-	runsOn := strings.Join(e.WorkflowJob.Labels, `,`)
-	labels["runs_on"] = runsOn
-
-	labels["job_name"] = *e.WorkflowJob.Name
-	keysAndValues = append(keysAndValues, "job_name", *e.WorkflowJob.Name)
-
-	if e.Repo != nil {
-		if n := e.Repo.Name; n != nil {
-			labels["repository"] = *n
-			keysAndValues = append(keysAndValues, "repository", *n)
-		}
-		if n := e.Repo.FullName; n != nil {
-			labels["repository_full_name"] = *n
-			keysAndValues = append(keysAndValues, "repository_full_name", *n)
-		}
-
-		if e.Repo.Owner != nil {
-			if l := e.Repo.Owner.Login; l != nil {
-				labels["owner"] = *l
-				keysAndValues = append(keysAndValues, "owner", *l)
-			}
-		}
-	}
-
-	var org string
-	if e.Org != nil {
-		if n := e.Org.Name; n != nil {
-			org = *n
-			keysAndValues = append(keysAndValues, "organization", *n)
-		}
-	}
-	labels["organization"] = org
-
-	var wn string
-	var hb string
-	if e.WorkflowJob != nil {
-		if n := e.WorkflowJob.WorkflowName; n != nil {
-			wn = *n
-			keysAndValues = append(keysAndValues, "workflow_name", *n)
-		}
-		if n := e.WorkflowJob.HeadBranch; n != nil {
-			hb = *n
-			keysAndValues = append(keysAndValues, "head_branch", *n)
-		}
-	}
-	labels["workflow_name"] = wn
-	labels["head_branch"] = hb
+	labels, keysAndValues := workflowJobLabels(e)
 
 	log := reader.Log.WithValues(keysAndValues...)
 
@@ -169,7 +119,7 @@ func (reader *EventReader) ProcessWorkflowJobEvent(ctx context.Context, event in
 		}
 		reader.inProgressJobsLock.Unlock()
 
-		if reader.GitHubClient == nil {
+		if reader.GitHubClient == nil || !reader.GitHubClient.Supports(ctx, ghes.CapabilityWorkflowJobLogs) {
 			return
 		}
 
@@ -202,7 +152,8 @@ func (reader *EventReader) ProcessWorkflowJobEvent(ctx context.Context, event in
 		// We need to do our best not to fail the whole event processing
 		// when the user provided no GitHub API credentials.
 		// See https://github.com/actions/actions-runner-controller/issues/2424
-		if reader.GitHubClient != nil {
+		// The same applies to GitHub Enterprise Server versions that lack the workflow job logs API.
+		if reader.GitHubClient != nil && reader.GitHubClient.Supports(ctx, ghes.CapabilityWorkflowJobLogs) {
 			parseResult, err := reader.fetchAndParseWorkflowJobLogs(ctx, e)
 			if err != nil {
 				log.Error(err, "reading workflow job log")
@@ -219,29 +170,10 @@ func (reader *EventReader) ProcessWorkflowJobEvent(ctx context.Context, event in
 
 		if *e.WorkflowJob.Conclusion == "failure" {
 			failedStep := "null"
-			for i, step := range e.WorkflowJob.Steps {
-				conclusion := step.Conclusion
-				if conclusion == nil {
-					continue
-				}
-
-				// *step.Conclusion ~
-				// "success",
-				// "failure",
-				// "neutral",
-				// "cancelled",
-				// "skipped",
-				// "timed_out",
-				// "action_required",
-				// null
-				if *conclusion == "failure" {
-					failedStep = fmt.Sprint(i)
-					break
-				}
-				if *conclusion == "timed_out" {
-					failedStep = fmt.Sprint(i)
+			if i, step := findFailedStep(e.WorkflowJob.Steps); step != nil {
+				failedStep = fmt.Sprint(i)
+				if step.GetConclusion() == "timed_out" {
 					exitCode = "timed_out"
-					break
 				}
 			}
 			githubWorkflowJobFailuresTotal.With(
@@ -257,6 +189,85 @@ func (reader *EventReader) ProcessWorkflowJobEvent(ctx context.Context, event in
 	}
 }
 
+// workflowJobLabels returns the common labels of the workflow job metrics of the event, along with the keys and values to log them with.
+func workflowJobLabels(e *gogithub.WorkflowJobEvent) (prometheus.Labels, []interface{}) {
+	var (
+		labels        = make(prometheus.Labels)
+		keysAndValues = []interface{}{"job_id", fmt.Sprint(*e.WorkflowJob.ID)}
+	)
+
+	runsOn := strings.Join(e.WorkflowJob.Labels, `,`)
+	labels["runs_on"] = runsOn
+
+	labels["job_name"] = *e.WorkflowJob.Name
+	keysAndValues = append(keysAndValues, "job_name", *e.WorkflowJob.Name)
+
+	if e.Repo != nil {
+		if n := e.Repo.Name; n != nil {
+			labels["repository"] = *n
+			keysAndValues = append(keysAndValues, "repository", *n)
+		}
+		if n := e.Repo.FullName; n != nil {
+			labels["repository_full_name"] = *n
+			keysAndValues = append(keysAndValues, "repository_full_name", *n)
+		}
+
+		if e.Repo.Owner != nil {
+			if l := e.Repo.Owner.Login; l != nil {
+				labels["owner"] = *l
+				keysAndValues = append(keysAndValues, "owner", *l)
+			}
+		}
+	}
+
+	var org string
+	if e.Org != nil {
+		if n := e.Org.Name; n != nil {
+			org = *n
+			keysAndValues = append(keysAndValues, "organization", *n)
+		}
+	}
+	labels["organization"] = org
+
+	var wn string
+	var hb string
+	if e.WorkflowJob != nil {
+		if n := e.WorkflowJob.WorkflowName; n != nil {
+			wn = *n
+			keysAndValues = append(keysAndValues, "workflow_name", *n)
+		}
+		if n := e.WorkflowJob.HeadBranch; n != nil {
+			hb = *n
+			keysAndValues = append(keysAndValues, "head_branch", *n)
+		}
+	}
+	labels["workflow_name"] = wn
+	labels["head_branch"] = hb
+
+	return labels, keysAndValues
+}
+
+// findFailedStep returns the first step of the job that failed or timed out, along with its index.
+func findFailedStep(steps []*gogithub.TaskStep) (int, *gogithub.TaskStep) {
+	for i, step := range steps {
+		// step.Conclusion ~
+		// "success",
+		// "failure",
+		// "neutral",
+		// "cancelled",
+		// "skipped",
+		// "timed_out",
+		// "action_required",
+		// null
+		switch step.GetConclusion() {
+		case "failure", "timed_out":
+			return i, step
+		}
+	}
+
+	return -1, nil
+}
+
 func extraLabel(key string, value string, labels prometheus.Labels) prometheus.Labels {
 	fixedLabels := make(prometheus.Labels)
 	for k, v := range labels {
@@ -276,19 +287,43 @@ var logLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.\d{7}Z)\
 var exitCodeLine = regexp.MustCompile(`##\[error\]Process completed with exit code (\d)\.`)
 
 func (reader *EventReader) fetchAndParseWorkflowJobLogs(ctx context.Context, e *gogithub.WorkflowJobEvent) (*ParseResult, error) {
+	// The metrics parsed from logs are nice-to-have, so fetching them is shed first when the rate limit runs low.
+	ctx = github.WithRequestPriority(ctx, github.PriorityLow)
+
+	jobLogs, err := fetchWorkflowJobLogs(ctx, reader.GitHubClient, e)
+	if err != nil {
+		return nil, err
+	}
+	defer jobLogs.Close()
+
+	return parseWorkflowJobLogs(jobLogs), nil
+}
 
+// fetchWorkflowJobLogs downloads the logs of the workflow job of the event.
+// The caller is responsible for closing the returned reader.
+func fetchWorkflowJobLogs(ctx context.Context, client *github.Client, e *gogithub.WorkflowJobEvent) (io.ReadCloser, error) {
 	owner := *e.Repo.Owner.Login
 	repo := *e.Repo.Name
 	id := *e.WorkflowJob.ID
-	url, _, err := reader.GitHubClient.Actions.GetWorkflowJobLogs(ctx, owner, repo, id, true)
+
+	url, _, err := client.Actions.GetWorkflowJobLogs(ctx, owner, repo, id, true)
 	if err != nil {
 		return nil, err
 	}
-	jobLogs, err := http.DefaultClient.Get(url.String())
+	res, err := client.UnauthenticatedHTTPClient().Get(url.String())
 	if err != nil {
 		return nil, err
 	}
 
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("downloading the logs of workflow job %d: unexpected status %s", id, res.Status)
+	}
+
+	return res.Body, nil
+}
+
+func parseWorkflowJobLogs(jobLogs io.Reader) *ParseResult {
 	exitCode := "null"
 
 	var (
@@ -298,10 +333,8 @@ func (reader *EventReader) fetchAndParseWorkflowJobLogs(ctx context.Context, e *
 	)
 
 	func() {
-		// Read jobLogs.Body line by line
-
-		defer jobLogs.Body.Close()
-		lines := bufio.NewScanner(jobLogs.Body)
+		// Read jobLogs line by line
+		lines := bufio.NewScanner(jobLogs)
 
 		for lines.Scan() {
 			matches := logLine.FindStringSubmatch(lines.Text())
@@ -339,5 +372,5 @@ func (reader *EventReader) fetchAndParseWorkflowJobLogs(ctx context.Context, e *
 		ExitCode:  exitCode,
 		QueueTime: startedTime.Sub(queuedTime),
 		RunTime:   completedTime.Sub(startedTime),
-	}, nil
+	}
 }
@@ -0,0 +1,250 @@
+package actionsmetrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The causes of the infrastructure failures of the workflow jobs, in the cause label of github_workflow_job_infra_failures_total.
+const (
+	InfraFailureCauseOOMKilled = "oom_killed"
+	InfraFailureCauseEvicted   = "evicted"
+	InfraFailureCausePreempted = "preempted"
+)
+
+const (
+	// labelKeyRunner is the label ARC sets on the pods of the runners of RunnerDeployments and RunnerSets.
+	labelKeyRunner = "actions-runner"
+
+	// labelKeyEphemeralRunner is the label ARC sets on the pods of the EphemeralRunners of the runner scale sets.
+	labelKeyEphemeralRunner = "actions-ephemeral-runner"
+
+	// infraFailureCauseTTL is how long the cause of the death of a runner pod is remembered without the job on it completing.
+	// GitHub concludes the job of a lost runner after several minutes.
+	infraFailureCauseTTL = 24 * time.Hour
+
+	infraFailureExpiryInterval = 10 * time.Minute
+)
+
+type infraFailureCause struct {
+	cause string
+	seen  time.Time
+}
+
+// InfraFailureTracker correlates the runner pods killed by the OOM killer, evicted, or preempted with the workflow jobs
+// that were running on them, so that the jobs failed by the dead runners are told apart from the jobs failed by the tests.
+//
+// The runner pods are watched, as the pod of an ephemeral runner is gone long before GitHub concludes the job of the lost runner.
+// The pods are matched with the jobs by the runner names in the workflow_job events, which are the names of the runner pods,
+// both for the runners of RunnerDeployments and RunnerSets and for the EphemeralRunners of the runner scale sets.
+type InfraFailureTracker struct {
+	Log logr.Logger
+
+	Events chan *gogithub.WorkflowJobEvent
+
+	causes     map[string]infraFailureCause
+	causesLock sync.Mutex
+}
+
+// HandleWorkflowJobEvent queues the started and the completed workflow jobs.
+func (t *InfraFailureTracker) HandleWorkflowJobEvent(event interface{}) {
+	e, ok := event.(*gogithub.WorkflowJobEvent)
+	if !ok {
+		return
+	}
+
+	switch e.GetAction() {
+	case "in_progress", "completed":
+		t.Events <- e
+	}
+}
+
+// Run counts the failed jobs whose runner pods died in a loop.
+//
+// Should be called asynchronously with `go`
+func (t *InfraFailureTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(infraFailureExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-t.Events:
+			t.processWorkflowJobEvent(e)
+		case <-ticker.C:
+			t.expireCauses(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runnerPodLabelKeys are the labels of the runner pods watched by the tracker. Label selectors can't select either of the labels,
+// so the pods of each label are watched separately.
+var runnerPodLabelKeys = []string{labelKeyRunner, labelKeyEphemeralRunner}
+
+// Watch observes the runner pods in the cluster until the context is done.
+//
+// Should be called asynchronously with `go`
+func (t *InfraFailureTracker) Watch(ctx context.Context, cfg *rest.Config, scheme *runtime.Scheme) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, key := range runnerPodLabelKeys {
+		podCache, err := t.newPodCache(ctx, cfg, scheme, key)
+		if err != nil {
+			return err
+		}
+
+		g.Go(func() error {
+			return podCache.Start(ctx)
+		})
+	}
+
+	return g.Wait()
+}
+
+// newPodCache returns the cache of the pods labeled with the key, which observes the updates of the pods.
+func (t *InfraFailureTracker) newPodCache(ctx context.Context, cfg *rest.Config, scheme *runtime.Scheme, key string) (cache.Cache, error) {
+	runnerPods, err := labels.NewRequirement(key, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	podCache, err := cache.New(cfg, cache.Options{
+		Scheme: scheme,
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Pod{}: {Label: labels.NewSelector().Add(*runnerPods)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	informer, err := podCache.GetInformer(ctx, &corev1.Pod{})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+
+			t.ObservePod(oldPod, newPod)
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return podCache, nil
+}
+
+// ObservePod remembers the cause of the death of the runner pod when the pod is updated from the old to the new state.
+// Only the transitions are observed, so that a runner container restarted after an OOM kill isn't blamed for the later jobs.
+func (t *InfraFailureTracker) ObservePod(oldPod, newPod *corev1.Pod) {
+	cause := PodInfraFailureCause(newPod)
+	if cause == "" || cause == PodInfraFailureCause(oldPod) {
+		return
+	}
+
+	t.Log.V(1).Info("Observed the death of the runner pod", "runner", newPod.Name, "namespace", newPod.Namespace, "cause", cause)
+
+	t.causesLock.Lock()
+	defer t.causesLock.Unlock()
+
+	if t.causes == nil {
+		t.causes = map[string]infraFailureCause{}
+	}
+
+	t.causes[newPod.Name] = infraFailureCause{cause: cause, seen: time.Now()}
+}
+
+// PodInfraFailureCause returns why the runner pod died, or an empty string when it didn't die of the infrastructure.
+func PodInfraFailureCause(pod *corev1.Pod) string {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, s := range statuses {
+			if s.State.Terminated != nil && s.State.Terminated.Reason == "OOMKilled" {
+				return InfraFailureCauseOOMKilled
+			}
+
+			if s.LastTerminationState.Terminated != nil && s.LastTerminationState.Terminated.Reason == "OOMKilled" {
+				return InfraFailureCauseOOMKilled
+			}
+		}
+	}
+
+	if pod.Status.Reason == "Evicted" {
+		return InfraFailureCauseEvicted
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type != corev1.DisruptionTarget || c.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch c.Reason {
+		case "PreemptionByScheduler":
+			return InfraFailureCausePreempted
+		case "EvictionByEvictionAPI", "TerminationByKubelet", "DeletionByTaintManager":
+			return InfraFailureCauseEvicted
+		}
+	}
+
+	return ""
+}
+
+func (t *InfraFailureTracker) processWorkflowJobEvent(e *gogithub.WorkflowJobEvent) {
+	job := e.GetWorkflowJob()
+
+	runner := job.GetRunnerName()
+	if runner == "" {
+		return
+	}
+
+	t.causesLock.Lock()
+	c, ok := t.causes[runner]
+	delete(t.causes, runner)
+	t.causesLock.Unlock()
+
+	// A cause observed before the job started on the runner, like for the previous job of a persistent runner, isn't the cause of this job
+	if e.GetAction() != "completed" || !ok || job.GetConclusion() == "success" {
+		return
+	}
+
+	labels, keysAndValues := workflowJobLabels(e)
+
+	t.Log.WithValues(keysAndValues...).Info("The workflow job failed because its runner died", "job_id", fmt.Sprint(job.GetID()), "runner", runner, "cause", c.cause, "job_conclusion", job.GetConclusion())
+
+	githubWorkflowJobInfraFailuresTotal.With(extraLabel("cause", c.cause, labels)).Inc()
+}
+
+func (t *InfraFailureTracker) expireCauses(now time.Time) {
+	t.causesLock.Lock()
+	defer t.causesLock.Unlock()
+
+	for runner, c := range t.causes {
+		if now.Sub(c.seen) > infraFailureCauseTTL {
+			delete(t.causes, runner)
+		}
+	}
+}
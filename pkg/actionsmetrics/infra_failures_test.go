@@ -0,0 +1,145 @@
+package actionsmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodInfraFailureCause(t *testing.T) {
+	oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}}
+
+	for _, tc := range []struct {
+		name   string
+		status corev1.PodStatus
+		want   string
+	}{
+		{
+			name:   "running",
+			status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "runner", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}},
+		},
+		{
+			name:   "completed",
+			status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "runner", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}}}},
+		},
+		{
+			name:   "oom killed",
+			status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "docker", State: oomKilled}}},
+			want:   InfraFailureCauseOOMKilled,
+		},
+		{
+			name:   "restarted after oom killed",
+			status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "runner", LastTerminationState: oomKilled}}},
+			want:   InfraFailureCauseOOMKilled,
+		},
+		{
+			name:   "evicted by kubelet",
+			status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"},
+			want:   InfraFailureCauseEvicted,
+		},
+		{
+			name:   "drained",
+			status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: "EvictionByEvictionAPI"}}},
+			want:   InfraFailureCauseEvicted,
+		},
+		{
+			name:   "preempted",
+			status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: "PreemptionByScheduler"}}},
+			want:   InfraFailureCausePreempted,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, PodInfraFailureCause(&corev1.Pod{Status: tc.status}))
+		})
+	}
+}
+
+func TestInfraFailureTracker(t *testing.T) {
+	tr := &InfraFailureTracker{Log: logr.Discard()}
+
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "runners", Name: "example-runner-abcde"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "runner", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+		},
+	}
+
+	oomKilled := running.DeepCopy()
+	oomKilled.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}}
+
+	newEvent := func(id int64, action, conclusion string) *gogithub.WorkflowJobEvent {
+		return &gogithub.WorkflowJobEvent{
+			Action: gogithub.String(action),
+			Repo: &gogithub.Repository{
+				Name:     gogithub.String("infra-repo"),
+				FullName: gogithub.String("owner/infra-repo"),
+				Owner:    &gogithub.User{Login: gogithub.String("owner")},
+			},
+			WorkflowJob: &gogithub.WorkflowJob{
+				ID:           gogithub.Int64(id),
+				Name:         gogithub.String("build"),
+				WorkflowName: gogithub.String("ci"),
+				HeadBranch:   gogithub.String("main"),
+				Labels:       []string{"self-hosted"},
+				RunnerName:   gogithub.String("example-runner-abcde"),
+				Conclusion:   gogithub.String(conclusion),
+			},
+		}
+	}
+
+	counted := func(cause string) float64 {
+		return testutil.ToFloat64(githubWorkflowJobInfraFailuresTotal.With(prometheus.Labels{
+			"runs_on":              "self-hosted",
+			"job_name":             "build",
+			"organization":         "",
+			"repository":           "infra-repo",
+			"repository_full_name": "owner/infra-repo",
+			"owner":                "owner",
+			"workflow_name":        "ci",
+			"head_branch":          "main",
+			"cause":                cause,
+		}))
+	}
+
+	tr.processWorkflowJobEvent(newEvent(1, "in_progress", ""))
+	tr.ObservePod(running, oomKilled)
+	tr.processWorkflowJobEvent(newEvent(1, "completed", "failure"))
+
+	require.Equal(t, 1.0, counted(InfraFailureCauseOOMKilled))
+	require.Empty(t, tr.causes)
+
+	// The pods not transitioning to the death aren't blamed, like the persistent runner restarted after the OOM kill
+	tr.ObservePod(oomKilled, oomKilled)
+	tr.processWorkflowJobEvent(newEvent(2, "completed", "failure"))
+
+	require.Equal(t, 1.0, counted(InfraFailureCauseOOMKilled))
+
+	// The cause observed before the job started on the runner isn't the cause of the job
+	tr.ObservePod(running, oomKilled)
+	tr.processWorkflowJobEvent(newEvent(3, "in_progress", ""))
+	tr.processWorkflowJobEvent(newEvent(3, "completed", "failure"))
+
+	require.Equal(t, 1.0, counted(InfraFailureCauseOOMKilled))
+
+	// The jobs succeeded despite the runner deaths aren't counted
+	evicted := running.DeepCopy()
+	evicted.Status.Reason = "Evicted"
+
+	tr.ObservePod(running, evicted)
+	tr.processWorkflowJobEvent(newEvent(4, "completed", "success"))
+
+	require.Equal(t, 0.0, counted(InfraFailureCauseEvicted))
+
+	// The causes of the runners whose jobs never complete expire
+	tr.ObservePod(running, evicted)
+	tr.expireCauses(time.Now().Add(infraFailureCauseTTL + time.Minute))
+
+	require.Empty(t, tr.causes)
+}
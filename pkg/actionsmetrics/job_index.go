@@ -0,0 +1,253 @@
+package actionsmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJobQueryLimit = 100
+	maxJobQueryLimit     = 1000
+)
+
+// JobRecord is the metadata of a completed workflow job archived along with its logs.
+type JobRecord struct {
+	Repository      string    `json:"repository"`
+	WorkflowName    string    `json:"workflowName,omitempty"`
+	HeadBranch      string    `json:"headBranch,omitempty"`
+	RunID           int64     `json:"runID"`
+	RunAttempt      int64     `json:"runAttempt,omitempty"`
+	JobID           int64     `json:"jobID"`
+	JobName         string    `json:"jobName"`
+	Labels          []string  `json:"labels,omitempty"`
+	Conclusion      string    `json:"conclusion"`
+	ExitCode        string    `json:"exitCode,omitempty"`
+	FailedStep      string    `json:"failedStep,omitempty"`
+	StartedAt       time.Time `json:"startedAt"`
+	CompletedAt     time.Time `json:"completedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	RunnerName      string    `json:"runnerName,omitempty"`
+	RunnerGroupName string    `json:"runnerGroupName,omitempty"`
+	// Node is the Kubernetes node the runner pod ran on, if known.
+	Node   string `json:"node,omitempty"`
+	URL    string `json:"url,omitempty"`
+	LogKey string `json:"logKey"`
+}
+
+// JobQuery filters the job records. The empty fields match any record.
+type JobQuery struct {
+	Repository   string
+	WorkflowName string
+	JobName      string
+	Conclusion   string
+	ExitCode     string
+	// Labels are the runs-on labels all of which the jobs have.
+	Labels     []string
+	RunnerName string
+	Node       string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// ParseJobQuery parses the query parameters of the job query API.
+// since and until are either RFC 3339 timestamps or durations before now, like 168h for the last week.
+func ParseJobQuery(values url.Values, now time.Time) (*JobQuery, error) {
+	q := &JobQuery{
+		Repository:   values.Get("repository"),
+		WorkflowName: values.Get("workflow"),
+		JobName:      values.Get("job"),
+		Conclusion:   values.Get("conclusion"),
+		ExitCode:     values.Get("exitCode"),
+		Labels:       values["label"],
+		RunnerName:   values.Get("runner"),
+		Node:         values.Get("node"),
+		Limit:        defaultJobQueryLimit,
+	}
+
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"since", &q.Since},
+		{"until", &q.Until},
+	} {
+		v := values.Get(p.name)
+		if v == "" {
+			continue
+		}
+
+		if d, err := time.ParseDuration(v); err == nil {
+			*p.t = now.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			*p.t = t
+		} else {
+			return nil, fmt.Errorf("%s must be either an RFC 3339 timestamp or a duration: %q", p.name, v)
+		}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer: %q", v)
+		}
+
+		if limit > maxJobQueryLimit {
+			limit = maxJobQueryLimit
+		}
+
+		q.Limit = limit
+	}
+
+	return q, nil
+}
+
+func (q *JobQuery) Matches(r *JobRecord) bool {
+	if (q.Repository != "" && !strings.EqualFold(q.Repository, r.Repository)) ||
+		(q.WorkflowName != "" && q.WorkflowName != r.WorkflowName) ||
+		(q.JobName != "" && q.JobName != r.JobName) ||
+		(q.Conclusion != "" && q.Conclusion != r.Conclusion) ||
+		(q.ExitCode != "" && q.ExitCode != r.ExitCode) ||
+		(q.RunnerName != "" && q.RunnerName != r.RunnerName) ||
+		(q.Node != "" && q.Node != r.Node) ||
+		(!q.Since.IsZero() && r.CompletedAt.Before(q.Since)) ||
+		(!q.Until.IsZero() && !r.CompletedAt.Before(q.Until)) {
+		return false
+	}
+
+	for _, l := range q.Labels {
+		var found bool
+		for _, rl := range r.Labels {
+			if strings.EqualFold(l, rl) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// JobIndex is the in-memory index of the job records archived by LogArchiver, served by the job query API.
+type JobIndex struct {
+	mu      sync.RWMutex
+	records map[int64]JobRecord
+}
+
+func NewJobIndex() *JobIndex {
+	return &JobIndex{records: map[int64]JobRecord{}}
+}
+
+func (x *JobIndex) Add(r JobRecord) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.records[r.JobID] = r
+}
+
+// RemoveCompletedBefore removes the records of the jobs completed before the time, whose logs are pruned.
+func (x *JobIndex) RemoveCompletedBefore(t time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for id, r := range x.records {
+		if r.CompletedAt.Before(t) {
+			delete(x.records, id)
+		}
+	}
+}
+
+// Load adds the job records archived under the prefix in the store to the index.
+func (x *JobIndex) Load(ctx context.Context, store LogStore, prefix string) (int, error) {
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var loaded int
+
+	for _, o := range objects {
+		if !strings.HasSuffix(o.Key, jobRecordSuffix) {
+			continue
+		}
+
+		body, err := store.Get(ctx, o.Key)
+		if err != nil {
+			return loaded, err
+		}
+
+		var r JobRecord
+		if err := json.Unmarshal(body, &r); err != nil {
+			return loaded, fmt.Errorf("decoding job record %s: %w", o.Key, err)
+		}
+
+		x.Add(r)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// Query returns the records matching the query, the most recently completed first.
+func (x *JobIndex) Query(q *JobQuery) []JobRecord {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	matched := []JobRecord{}
+	for _, r := range x.records {
+		if q.Matches(&r) {
+			matched = append(matched, r)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CompletedAt.Equal(matched[j].CompletedAt) {
+			return matched[i].CompletedAt.After(matched[j].CompletedAt)
+		}
+		return matched[i].JobID > matched[j].JobID
+	})
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+
+	return matched
+}
+
+// ServeHTTP serves the job query API on GET /jobs, like /jobs?conclusion=timed_out&label=gpu&since=168h.
+func (x *JobIndex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := ParseJobQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Jobs []JobRecord `json:"jobs"`
+	}{x.Query(q)}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
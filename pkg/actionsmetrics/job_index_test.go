@@ -0,0 +1,123 @@
+package actionsmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJobIndexQuery(t *testing.T) {
+	now := time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)
+
+	x := NewJobIndex()
+	x.Add(JobRecord{JobID: 1, Repository: "owner/repo", Conclusion: "timed_out", Labels: []string{"self-hosted", "gpu"}, CompletedAt: now.Add(-2 * 24 * time.Hour)})
+	x.Add(JobRecord{JobID: 2, Repository: "owner/repo", Conclusion: "timed_out", Labels: []string{"self-hosted", "GPU"}, CompletedAt: now.Add(-1 * 24 * time.Hour)})
+	x.Add(JobRecord{JobID: 3, Repository: "owner/repo", Conclusion: "timed_out", Labels: []string{"self-hosted", "gpu"}, CompletedAt: now.Add(-10 * 24 * time.Hour)})
+	x.Add(JobRecord{JobID: 4, Repository: "owner/repo", Conclusion: "success", Labels: []string{"self-hosted", "gpu"}, CompletedAt: now.Add(-1 * 24 * time.Hour)})
+	x.Add(JobRecord{JobID: 5, Repository: "owner/repo", Conclusion: "timed_out", Labels: []string{"self-hosted"}, CompletedAt: now.Add(-1 * 24 * time.Hour)})
+
+	ids := func(records []JobRecord) []int64 {
+		var ids []int64
+		for _, r := range records {
+			ids = append(ids, r.JobID)
+		}
+		return ids
+	}
+
+	// All timed_out jobs on gpu runners last week, the most recent first
+	q, err := ParseJobQuery(url.Values{"conclusion": {"timed_out"}, "label": {"gpu"}, "since": {"168h"}}, now)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 1}, ids(x.Query(q)))
+
+	q, err = ParseJobQuery(url.Values{"until": {"2024-06-06T00:00:00Z"}, "limit": {"1"}}, now)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3}, ids(x.Query(q)))
+
+	x.RemoveCompletedBefore(now.Add(-7 * 24 * time.Hour))
+
+	q, err = ParseJobQuery(url.Values{}, now)
+	require.NoError(t, err)
+	require.Equal(t, []int64{5, 4, 2, 1}, ids(x.Query(q)))
+
+	_, err = ParseJobQuery(url.Values{"since": {"last week"}}, now)
+	require.Error(t, err)
+
+	_, err = ParseJobQuery(url.Values{"limit": {"0"}}, now)
+	require.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	x.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs?conclusion=success", nil))
+	require.Equal(t, 200, rec.Code)
+
+	var res struct {
+		Jobs []JobRecord `json:"jobs"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, []int64{4}, ids(res.Jobs))
+}
+
+func TestLogArchiverJobRecord(t *testing.T) {
+	sc := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(sc))
+
+	c := clientfake.NewClientBuilder().
+		WithScheme(sc).
+		WithObjects(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "runners", Name: "example-runner-abcde"},
+			Spec:       corev1.PodSpec{NodeName: "gpu-node-1"},
+		}).
+		WithIndex(&corev1.Pod{}, "metadata.name", func(o client.Object) []string { return []string{o.GetName()} }).
+		Build()
+
+	a := &LogArchiver{Log: logr.Discard(), Client: c}
+
+	startedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	e := &gogithub.WorkflowJobEvent{
+		Action: gogithub.String("in_progress"),
+		Repo:   &gogithub.Repository{FullName: gogithub.String("owner/repo")},
+		WorkflowJob: &gogithub.WorkflowJob{
+			ID:         gogithub.Int64(10),
+			RunID:      gogithub.Int64(1),
+			Name:       gogithub.String("train"),
+			Labels:     []string{"self-hosted", "gpu"},
+			RunnerName: gogithub.String("example-runner-abcde"),
+			StartedAt:  &gogithub.Timestamp{Time: startedAt},
+		},
+	}
+
+	a.recordRunnerNode(context.Background(), e)
+
+	e.Action = gogithub.String("completed")
+	e.WorkflowJob.Conclusion = gogithub.String("failure")
+	e.WorkflowJob.CompletedAt = &gogithub.Timestamp{Time: startedAt.Add(90 * time.Second)}
+	e.WorkflowJob.Steps = []*gogithub.TaskStep{
+		{Name: gogithub.String("Set up job"), Conclusion: gogithub.String("success")},
+		{Name: gogithub.String("Train"), Conclusion: gogithub.String("failure")},
+	}
+
+	logs := "2024-06-01T00:01:29.0000000Z ##[error]Process completed with exit code 2.\n"
+
+	r := a.newJobRecord(e, []byte(logs), "owner/repo/1/10.log")
+
+	require.Equal(t, "owner/repo", r.Repository)
+	require.Equal(t, "failure", r.Conclusion)
+	require.Equal(t, "2", r.ExitCode)
+	require.Equal(t, "Train", r.FailedStep)
+	require.Equal(t, float64(90), r.DurationSeconds)
+	require.Equal(t, "gpu-node-1", r.Node)
+	require.Equal(t, "owner/repo/1/10.log", r.LogKey)
+}
@@ -0,0 +1,316 @@
+package actionsmetrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions/actions-runner-controller/github"
+)
+
+const (
+	// logArchiveAttempts is how many times archiving the logs of a job is attempted,
+	// as the logs of a job sometimes become available a while after the job completes.
+	logArchiveAttempts   = 3
+	logArchiveRetryDelay = 10 * time.Second
+
+	logArchivePruneInterval = time.Hour
+
+	// runnerNodeTTL is how long the node of the runner of a job is remembered without the job completing,
+	// like when the completed event of the job was missed.
+	runnerNodeTTL = 7 * 24 * time.Hour
+
+	jobRecordSuffix = ".json"
+)
+
+// LogArchiver archives the logs of the completed workflow jobs to an object storage,
+// so that they are retained beyond the log retention period of GitHub.
+type LogArchiver struct {
+	Log logr.Logger
+
+	// GitHub Client to download the logs
+	GitHubClient *github.Client
+
+	Store LogStore
+
+	// Prefix is prepended to the keys of the archived logs.
+	Prefix string
+
+	// Retention is how long the archived logs are kept. The archived logs are kept forever when zero.
+	Retention time.Duration
+
+	// Index is updated with the job records archived along with the logs, if set.
+	Index *JobIndex
+
+	// Client looks up the nodes of the runner pods of the started jobs, if set.
+	Client client.Reader
+
+	// Event queue
+	Events chan *gogithub.WorkflowJobEvent
+
+	// runnerNodes is the nodes of the runner pods by the IDs of the in-progress jobs
+	runnerNodes     map[int64]runnerNode
+	runnerNodesLock sync.Mutex
+}
+
+type runnerNode struct {
+	name string
+	seen time.Time
+}
+
+// HandleWorkflowJobEvent queues the completed workflow jobs for archiving their logs,
+// and the started ones for looking up the nodes of their runner pods.
+func (a *LogArchiver) HandleWorkflowJobEvent(event interface{}) {
+	e, ok := event.(*gogithub.WorkflowJobEvent)
+	if !ok {
+		return
+	}
+
+	switch e.GetAction() {
+	case "completed":
+	case "in_progress":
+		if a.Client == nil {
+			return
+		}
+	default:
+		return
+	}
+
+	a.Events <- e
+}
+
+// Run archives the logs of the queued jobs and prunes the archived logs older than the retention in a loop.
+//
+// Should be called asynchronously with `go`
+func (a *LogArchiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(logArchivePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-a.Events:
+			if e.GetAction() == "in_progress" {
+				a.recordRunnerNode(ctx, e)
+				continue
+			}
+
+			a.archiveWithRetries(ctx, e)
+		case <-ticker.C:
+			a.expireRunnerNodes(time.Now())
+
+			if a.Retention > 0 {
+				if err := a.Prune(ctx, time.Now()); err != nil {
+					a.Log.Error(err, "pruning archived workflow job logs")
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *LogArchiver) archiveWithRetries(ctx context.Context, e *gogithub.WorkflowJobEvent) {
+	log := a.Log.WithValues("job_id", e.GetWorkflowJob().GetID(), "repository_full_name", e.GetRepo().GetFullName())
+
+	defer func() {
+		a.runnerNodesLock.Lock()
+		delete(a.runnerNodes, e.GetWorkflowJob().GetID())
+		a.runnerNodesLock.Unlock()
+	}()
+
+	for attempt := 1; ; attempt++ {
+		key, err := a.Archive(ctx, e)
+		if err == nil {
+			log.V(1).Info("archived workflow job logs", "key", key)
+			githubWorkflowJobLogsArchivedTotal.Inc()
+			return
+		}
+
+		if attempt == logArchiveAttempts {
+			log.Error(err, "archiving workflow job logs", "attempts", attempt)
+			githubWorkflowJobLogArchiveFailuresTotal.Inc()
+			return
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * logArchiveRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Archive downloads the logs of the workflow job and stores them under PREFIX/OWNER/REPO/RUN_ID/JOB_ID.log,
+// along with the job record in JOB_ID.json next to it.
+// The run attempts of a run have their own job IDs, so the logs of the re-run jobs don't overwrite the former ones.
+func (a *LogArchiver) Archive(ctx context.Context, e *gogithub.WorkflowJobEvent) (string, error) {
+	job := e.GetWorkflowJob()
+
+	base := path.Join(a.Prefix, e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName(), fmt.Sprint(job.GetRunID()), fmt.Sprint(job.GetID()))
+	key := base + ".log"
+
+	jobLogs, err := fetchWorkflowJobLogs(ctx, a.GitHubClient, e)
+	if err != nil {
+		return "", err
+	}
+	defer jobLogs.Close()
+
+	body, err := io.ReadAll(jobLogs)
+	if err != nil {
+		return "", fmt.Errorf("reading the logs of workflow job %d: %w", job.GetID(), err)
+	}
+
+	if err := a.Store.Put(ctx, key, "text/plain; charset=utf-8", body); err != nil {
+		return "", err
+	}
+
+	record := a.newJobRecord(e, body, key)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	if err := a.Store.Put(ctx, base+jobRecordSuffix, "application/json", data); err != nil {
+		return "", err
+	}
+
+	if a.Index != nil {
+		a.Index.Add(record)
+	}
+
+	return key, nil
+}
+
+func (a *LogArchiver) newJobRecord(e *gogithub.WorkflowJobEvent, logs []byte, logKey string) JobRecord {
+	job := e.GetWorkflowJob()
+
+	exitCode := parseWorkflowJobLogs(bytes.NewReader(logs)).ExitCode
+	if exitCode == "null" {
+		exitCode = ""
+	}
+
+	var failedStep string
+	if _, step := findFailedStep(job.Steps); step != nil {
+		failedStep = step.GetName()
+		if step.GetConclusion() == "timed_out" {
+			exitCode = "timed_out"
+		}
+	}
+
+	r := JobRecord{
+		Repository:      e.GetRepo().GetFullName(),
+		WorkflowName:    job.GetWorkflowName(),
+		HeadBranch:      job.GetHeadBranch(),
+		RunID:           job.GetRunID(),
+		RunAttempt:      job.GetRunAttempt(),
+		JobID:           job.GetID(),
+		JobName:         job.GetName(),
+		Labels:          job.Labels,
+		Conclusion:      job.GetConclusion(),
+		ExitCode:        exitCode,
+		FailedStep:      failedStep,
+		StartedAt:       job.GetStartedAt().Time,
+		CompletedAt:     job.GetCompletedAt().Time,
+		RunnerName:      job.GetRunnerName(),
+		RunnerGroupName: job.GetRunnerGroupName(),
+		URL:             job.GetHTMLURL(),
+		LogKey:          logKey,
+	}
+
+	if !r.StartedAt.IsZero() && !r.CompletedAt.IsZero() {
+		r.DurationSeconds = r.CompletedAt.Sub(r.StartedAt).Seconds()
+	}
+
+	a.runnerNodesLock.Lock()
+	r.Node = a.runnerNodes[job.GetID()].name
+	a.runnerNodesLock.Unlock()
+
+	return r
+}
+
+// recordRunnerNode remembers the node of the runner pod of the started job, as the pod of an ephemeral runner is gone soon after the job completes.
+// The runner of a job is named after its pod for both the legacy runners and the runner scale sets.
+func (a *LogArchiver) recordRunnerNode(ctx context.Context, e *gogithub.WorkflowJobEvent) {
+	name := e.GetWorkflowJob().GetRunnerName()
+	if name == "" {
+		return
+	}
+
+	var pods corev1.PodList
+	if err := a.Client.List(ctx, &pods, client.MatchingFields{"metadata.name": name}); err != nil {
+		a.Log.Error(err, "looking up the runner pod of workflow job", "job_id", e.GetWorkflowJob().GetID(), "runner", name)
+		return
+	}
+
+	if len(pods.Items) == 0 || pods.Items[0].Spec.NodeName == "" {
+		return
+	}
+
+	a.runnerNodesLock.Lock()
+	defer a.runnerNodesLock.Unlock()
+
+	if a.runnerNodes == nil {
+		a.runnerNodes = map[int64]runnerNode{}
+	}
+
+	a.runnerNodes[e.GetWorkflowJob().GetID()] = runnerNode{name: pods.Items[0].Spec.NodeName, seen: time.Now()}
+}
+
+func (a *LogArchiver) expireRunnerNodes(now time.Time) {
+	a.runnerNodesLock.Lock()
+	defer a.runnerNodesLock.Unlock()
+
+	for id, n := range a.runnerNodes {
+		if now.Sub(n.seen) > runnerNodeTTL {
+			delete(a.runnerNodes, id)
+		}
+	}
+}
+
+// Prune deletes the archived logs older than the retention.
+func (a *LogArchiver) Prune(ctx context.Context, now time.Time) error {
+	prefix := a.Prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	logs, err := a.Store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	var pruned int
+
+	for _, l := range logs {
+		if now.Sub(l.LastModified) <= a.Retention {
+			continue
+		}
+
+		if err := a.Store.Delete(ctx, l.Key); err != nil {
+			return err
+		}
+
+		pruned++
+	}
+
+	if a.Index != nil {
+		a.Index.RemoveCompletedBefore(now.Add(-a.Retention))
+	}
+
+	if pruned > 0 {
+		a.Log.Info("pruned archived workflow job logs", "count", pruned, "retention", a.Retention)
+	}
+
+	return nil
+}
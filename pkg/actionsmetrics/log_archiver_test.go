@@ -0,0 +1,125 @@
+package actionsmetrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryLogStore map[string]StoredLog
+
+func (s memoryLogStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	s[key] = StoredLog{Key: key, LastModified: time.Now()}
+	return nil
+}
+
+func (s memoryLogStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, nil
+}
+
+func (s memoryLogStore) List(ctx context.Context, prefix string) ([]StoredLog, error) {
+	var logs []StoredLog
+	for k, l := range s {
+		if strings.HasPrefix(k, prefix) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (s memoryLogStore) Delete(ctx context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func TestLogArchiverPrune(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	store := memoryLogStore{
+		"logs/owner/repo/1/10.log":  {Key: "logs/owner/repo/1/10.log", LastModified: now.Add(-400 * 24 * time.Hour)},
+		"logs/owner/repo/2/20.log":  {Key: "logs/owner/repo/2/20.log", LastModified: now.Add(-24 * time.Hour)},
+		"other/owner/repo/1/10.log": {Key: "other/owner/repo/1/10.log", LastModified: now.Add(-400 * 24 * time.Hour)},
+	}
+
+	a := &LogArchiver{
+		Log:       logr.Discard(),
+		Store:     store,
+		Prefix:    "logs",
+		Retention: 365 * 24 * time.Hour,
+	}
+
+	require.NoError(t, a.Prune(context.Background(), now))
+
+	var keys []string
+	for k := range store {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// The objects out of the prefix aren't pruned
+	require.Equal(t, []string{"logs/owner/repo/2/20.log", "other/owner/repo/1/10.log"}, keys)
+}
+
+func TestAzureBlobLogStore(t *testing.T) {
+	blobs := map[string]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "sig", r.URL.Query().Get("sv"))
+
+		switch r.Method {
+		case http.MethodPut:
+			require.Equal(t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+			body, _ := io.ReadAll(r.Body)
+			blobs[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			require.Equal(t, "list", r.URL.Query().Get("comp"))
+			require.Equal(t, "logs/", r.URL.Query().Get("prefix"))
+			w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<EnumerationResults>
+  <Blobs>
+    <Blob>
+      <Name>logs/owner/repo/1/10.log</Name>
+      <Properties><Last-Modified>Sat, 01 Jun 2024 00:00:00 GMT</Last-Modified></Properties>
+    </Blob>
+  </Blobs>
+  <NextMarker />
+</EnumerationResults>`))
+		case http.MethodDelete:
+			if _, ok := blobs[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(blobs, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	u.Path = "/container"
+
+	s := &AzureBlobLogStore{ContainerURL: u, SASToken: "sv=sig", HTTPClient: srv.Client()}
+	ctx := context.Background()
+
+	require.NoError(t, s.Put(ctx, "logs/owner/repo/1/10.log", "text/plain", []byte("log")))
+	require.Equal(t, map[string]string{"/container/logs/owner/repo/1/10.log": "log"}, blobs)
+
+	logs, err := s.List(ctx, "logs/")
+	require.NoError(t, err)
+	require.Equal(t, []StoredLog{{Key: "logs/owner/repo/1/10.log", LastModified: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}}, logs)
+
+	require.NoError(t, s.Delete(ctx, "logs/owner/repo/1/10.log"))
+	require.Empty(t, blobs)
+	require.Error(t, s.Delete(ctx, "logs/owner/repo/1/10.log"))
+}
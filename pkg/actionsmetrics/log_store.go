@@ -0,0 +1,309 @@
+package actionsmetrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// gcsInteroperabilityEndpoint is the endpoint of the S3-compatible XML API of Google Cloud Storage.
+	gcsInteroperabilityEndpoint = "https://storage.googleapis.com"
+
+	// azureStorageSASTokenEnvName is the environment variable containing the SAS token of the Azure Blob Storage container.
+	azureStorageSASTokenEnvName = "AZURE_STORAGE_SAS_TOKEN"
+
+	azureStorageAPIVersion = "2021-08-06"
+)
+
+// LogStore is an object storage the workflow job logs are archived to.
+type LogStore interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the objects whose keys start with the prefix.
+	List(ctx context.Context, prefix string) ([]StoredLog, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type StoredLog struct {
+	Key          string
+	LastModified time.Time
+}
+
+// NewLogStore returns the log store of the URL, along with the prefix of the keys in the URL.
+// The URL is one of:
+//
+//   - s3://BUCKET/PREFIX for Amazon S3 and the S3-compatible storages like MinIO, configured by the default AWS credential chain.
+//     s3Endpoint overrides the endpoint of S3 for the S3-compatible storages.
+//   - gs://BUCKET/PREFIX for Google Cloud Storage, accessed via its S3-compatible XML API with the HMAC key in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+//   - https://ACCOUNT.blob.core.windows.net/CONTAINER/PREFIX for Azure Blob Storage, authorized by the SAS token in AZURE_STORAGE_SAS_TOKEN.
+func NewLogStore(ctx context.Context, rawURL, s3Endpoint string) (LogStore, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing log archive url: %w", err)
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+
+	switch {
+	case u.Scheme == "s3" || u.Scheme == "gs":
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("log archive url %s has no bucket", rawURL)
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("loading aws config: %w", err)
+		}
+
+		endpoint := s3Endpoint
+		if u.Scheme == "gs" {
+			endpoint = gcsInteroperabilityEndpoint
+			cfg.Region = "auto"
+		}
+
+		client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+				o.UsePathStyle = true
+				// The S3-compatible storages don't necessarily support the checksums the SDK adds by default
+				o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			}
+		})
+
+		return &S3LogStore{Client: client, Bucket: u.Host}, prefix, nil
+	case u.Scheme == "https" && strings.HasSuffix(u.Host, ".blob.core.windows.net"):
+		container, prefix, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			return nil, "", fmt.Errorf("log archive url %s has no container", rawURL)
+		}
+
+		sasToken := strings.TrimPrefix(os.Getenv(azureStorageSASTokenEnvName), "?")
+		if sasToken == "" {
+			return nil, "", fmt.Errorf("%s is required for archiving logs to azure blob storage", azureStorageSASTokenEnvName)
+		}
+
+		return &AzureBlobLogStore{
+			ContainerURL: &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/" + container},
+			SASToken:     sasToken,
+			HTTPClient:   http.DefaultClient,
+		}, prefix, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported log archive url %s: must be one of s3://, gs://, or https://ACCOUNT.blob.core.windows.net/", rawURL)
+}
+
+// S3LogStore stores the logs in a bucket of Amazon S3 or an S3-compatible storage.
+type S3LogStore struct {
+	Client *s3.Client
+	Bucket string
+}
+
+func (s *S3LogStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	_, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("putting s3://%s/%s: %w", s.Bucket, key, err)
+	}
+
+	return nil
+}
+
+func (s *S3LogStore) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting s3://%s/%s: %w", s.Bucket, key, err)
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
+func (s *S3LogStore) List(ctx context.Context, prefix string) ([]StoredLog, error) {
+	var logs []StoredLog
+
+	p := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %w", s.Bucket, prefix, err)
+		}
+
+		for _, o := range page.Contents {
+			logs = append(logs, StoredLog{Key: aws.ToString(o.Key), LastModified: aws.ToTime(o.LastModified)})
+		}
+	}
+
+	return logs, nil
+}
+
+func (s *S3LogStore) Delete(ctx context.Context, key string) error {
+	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting s3://%s/%s: %w", s.Bucket, key, err)
+	}
+
+	return nil
+}
+
+// AzureBlobLogStore stores the logs in a container of Azure Blob Storage via its REST API.
+type AzureBlobLogStore struct {
+	// ContainerURL is the URL of the container without the SAS token.
+	ContainerURL *url.URL
+	// SASToken is the SAS token of the container, which needs the create, write, list, and delete permissions.
+	SASToken   string
+	HTTPClient *http.Client
+}
+
+func (s *AzureBlobLogStore) url(key string, query url.Values) string {
+	u := *s.ContainerURL
+
+	if key != "" {
+		u.Path += "/" + key
+	}
+
+	u.RawQuery = s.SASToken
+	if len(query) > 0 {
+		u.RawQuery = query.Encode() + "&" + s.SASToken
+	}
+
+	return u.String()
+}
+
+func (s *AzureBlobLogStore) do(ctx context.Context, method, rawURL string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+
+	res, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, msg)
+	}
+
+	return res, nil
+}
+
+func (s *AzureBlobLogStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	res, err := s.do(ctx, http.MethodPut, s.url(key, nil), body, http.Header{
+		"x-ms-blob-type": {"BlockBlob"},
+		"Content-Type":   {contentType},
+	})
+	if err != nil {
+		return fmt.Errorf("putting blob %s: %w", key, err)
+	}
+
+	return res.Body.Close()
+}
+
+func (s *AzureBlobLogStore) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.do(ctx, http.MethodGet, s.url(key, nil), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting blob %s: %w", key, err)
+	}
+	defer res.Body.Close()
+
+	return io.ReadAll(res.Body)
+}
+
+type azureBlobList struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified string `xml:"Last-Modified"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (s *AzureBlobLogStore) List(ctx context.Context, prefix string) ([]StoredLog, error) {
+	var (
+		logs   []StoredLog
+		marker string
+	)
+
+	for {
+		query := url.Values{
+			"restype": {"container"},
+			"comp":    {"list"},
+			"prefix":  {prefix},
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		res, err := s.do(ctx, http.MethodGet, s.url("", query), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("listing blobs %s: %w", prefix, err)
+		}
+
+		var list azureBlobList
+		err = xml.NewDecoder(res.Body).Decode(&list)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding blob list: %w", err)
+		}
+
+		for _, b := range list.Blobs {
+			lastModified, err := time.Parse(http.TimeFormat, b.Properties.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("parsing the last modified time of blob %s: %w", b.Name, err)
+			}
+
+			logs = append(logs, StoredLog{Key: b.Name, LastModified: lastModified})
+		}
+
+		if list.NextMarker == "" {
+			return logs, nil
+		}
+
+		marker = list.NextMarker
+	}
+}
+
+func (s *AzureBlobLogStore) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, s.url(key, nil), nil, nil)
+	if err != nil {
+		return fmt.Errorf("deleting blob %s: %w", key, err)
+	}
+
+	return res.Body.Close()
+}
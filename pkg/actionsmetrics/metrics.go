@@ -19,6 +19,11 @@ func init() {
 		githubWorkflowJobsStartedTotal,
 		githubWorkflowJobsCompletedTotal,
 		githubWorkflowJobFailuresTotal,
+		githubWorkflowJobInfraFailuresTotal,
+		githubWorkflowJobLogsArchivedTotal,
+		githubWorkflowJobLogArchiveFailuresTotal,
+		githubWorkflowJobRunnerHoursTotal,
+		githubWorkflowJobEstimatedCostTotal,
 	)
 }
 
@@ -75,6 +80,7 @@ func metricLabels(extras ...string) []string {
 }
 
 var (
+	costLabels                            = []string{"repository_full_name", "workflow_name", "runs_on", "cost_center"}
 	commonLabels                          = []string{"runs_on", "job_name", "organization", "repository", "repository_full_name", "owner", "workflow_name", "head_branch"}
 	githubWorkflowJobQueueDurationSeconds = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -134,4 +140,37 @@ var (
 		},
 		metricLabels("failed_step", "exit_code"),
 	)
+	githubWorkflowJobInfraFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_workflow_job_infra_failures_total",
+			Help: "Total count of workflow jobs failed because their runner pods were killed by the OOM killer, evicted, or preempted",
+		},
+		metricLabels("cause"),
+	)
+	githubWorkflowJobLogsArchivedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "github_workflow_job_logs_archived_total",
+			Help: "Total count of workflow job logs archived to the object storage",
+		},
+	)
+	githubWorkflowJobLogArchiveFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "github_workflow_job_log_archive_failures_total",
+			Help: "Total count of workflow job logs failed to be archived to the object storage",
+		},
+	)
+	githubWorkflowJobRunnerHoursTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_workflow_job_runner_hours_total",
+			Help: "Total runner-hours of the completed workflow jobs",
+		},
+		costLabels,
+	)
+	githubWorkflowJobEstimatedCostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_workflow_job_estimated_cost_total",
+			Help: "Total estimated cost of the runners of the completed workflow jobs, in the currency of the cost model",
+		},
+		costLabels,
+	)
 )
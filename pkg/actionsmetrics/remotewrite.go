@@ -0,0 +1,333 @@
+package actionsmetrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	DefaultRemoteWriteInterval   = 30 * time.Second
+	DefaultRemoteWriteBatchSize  = 500
+	DefaultRemoteWriteMaxRetries = 5
+
+	remoteWriteMinBackoff = time.Second
+	remoteWriteMaxBackoff = 30 * time.Second
+)
+
+// RemoteWriter pushes the metrics of the server to an endpoint of the Prometheus remote write protocol 1.0, like Prometheus with
+// --web.enable-remote-write-receiver, Grafana Mimir, Thanos Receive, or VictoriaMetrics, for the clusters without a Prometheus scraping the server.
+//
+// Every interval, the metrics are gathered and sent as the samples at the time in the batches of up to BatchSize series.
+// A batch is retried with the exponential backoff on the network errors, 429 and 5xx responses, and is dropped after MaxRetries,
+// as the next push sends the latest values of the same series anyway.
+type RemoteWriter struct {
+	Log logr.Logger
+
+	// URL is the remote write endpoint, like https://prometheus.example.com/api/v1/write.
+	URL string
+
+	Gatherer   prometheus.Gatherer
+	HTTPClient *http.Client
+
+	Interval   time.Duration
+	BatchSize  int
+	MaxRetries int
+
+	// Username and Password authenticate the requests with the basic auth. BearerToken takes precedence over them.
+	Username    string
+	Password    string
+	BearerToken string
+
+	// ExternalLabels are added to every series, like cluster=prod, to tell the servers pushing to the same endpoint apart.
+	// They override the labels of the same names of the metrics.
+	ExternalLabels map[string]string
+}
+
+// Run pushes the metrics every interval until the context is done.
+//
+// Should be called asynchronously with `go`
+func (w *RemoteWriter) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultRemoteWriteInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := w.Push(ctx, now); err != nil {
+				w.Log.Error(err, "pushing metrics to the remote write endpoint", "url", w.URL)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Push sends the current values of the metrics as the samples at now.
+// It returns the error of the last batch that failed, after trying the rest.
+func (w *RemoteWriter) Push(ctx context.Context, now time.Time) error {
+	families, err := w.Gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever it could gather along with the errors
+		w.Log.Error(err, "gathering some of the metrics to push")
+	}
+
+	series := remoteWriteSeries(families, w.ExternalLabels, now.UnixMilli())
+
+	batchSize := w.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultRemoteWriteBatchSize
+	}
+
+	var lastErr error
+
+	for start := 0; start < len(series); start += batchSize {
+		end := start + batchSize
+		if end > len(series) {
+			end = len(series)
+		}
+
+		if err := w.send(ctx, encodeWriteRequest(series[start:end])); err != nil {
+			lastErr = err
+		}
+	}
+
+	if lastErr == nil {
+		w.Log.V(1).Info("Pushed metrics to the remote write endpoint", "series", len(series))
+	}
+
+	return lastErr
+}
+
+func (w *RemoteWriter) send(ctx context.Context, req []byte) error {
+	body := s2.EncodeSnappy(nil, req)
+
+	maxRetries := w.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultRemoteWriteMaxRetries
+	}
+
+	backoff := remoteWriteMinBackoff
+
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable || attempt >= maxRetries {
+			return err
+		}
+
+		w.Log.V(1).Info("Retrying the push to the remote write endpoint", "attempt", attempt+1, "backoff", backoff, "error", err.Error())
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > remoteWriteMaxBackoff {
+			backoff = remoteWriteMaxBackoff
+		}
+	}
+}
+
+// post sends the compressed write request and tells whether it can be retried on failure.
+func (w *RemoteWriter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "actions-metrics-server")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if w.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.BearerToken)
+	} else if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+
+	httpClient := w.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("pushing to %s: %w", w.URL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+	retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode/100 == 5
+
+	return retryable, fmt.Errorf("pushing to %s: unexpected status %s: %s", w.URL, res.Status, bytes.TrimSpace(msg))
+}
+
+type remoteWriteLabel struct {
+	name, value string
+}
+
+type remoteWriteSample struct {
+	labels    []remoteWriteLabel
+	value     float64
+	timestamp int64
+}
+
+// remoteWriteSeries flattens the metric families into the series of the remote write protocol,
+// the histograms and the summaries into the _bucket, _sum, and _count series like the text exposition format.
+func remoteWriteSeries(families []*dto.MetricFamily, externalLabels map[string]string, timestamp int64) []remoteWriteSample {
+	var series []remoteWriteSample
+
+	for _, mf := range families {
+		name := mf.GetName()
+
+		for _, m := range mf.GetMetric() {
+			add := func(name string, value float64, extra ...remoteWriteLabel) {
+				series = append(series, remoteWriteSample{
+					labels:    remoteWriteLabels(name, m.GetLabel(), extra, externalLabels),
+					value:     value,
+					timestamp: timestamp,
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				var inf bool
+				for _, b := range h.GetBucket() {
+					inf = math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", float64(b.GetCumulativeCount()), remoteWriteLabel{"le", formatFloat(b.GetUpperBound())})
+				}
+				// The client library leaves the +Inf bucket implicit
+				if !inf {
+					add(name+"_bucket", float64(h.GetSampleCount()), remoteWriteLabel{"le", "+Inf"})
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), remoteWriteLabel{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+
+	return series
+}
+
+// remoteWriteLabels returns the labels of a series sorted by name, as the remote write protocol requires.
+func remoteWriteLabels(name string, pairs []*dto.LabelPair, extra []remoteWriteLabel, externalLabels map[string]string) []remoteWriteLabel {
+	byName := map[string]string{"__name__": name}
+
+	for _, lp := range pairs {
+		byName[lp.GetName()] = lp.GetValue()
+	}
+
+	for _, l := range extra {
+		byName[l.name] = l.value
+	}
+
+	for n, v := range externalLabels {
+		byName[n] = v
+	}
+
+	labels := make([]remoteWriteLabel, 0, len(byName))
+
+	for n, v := range byName {
+		// The empty labels are the same as the missing ones
+		if v == "" {
+			continue
+		}
+
+		labels = append(labels, remoteWriteLabel{n, v})
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+
+	return labels
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the series into the prometheus.WriteRequest protobuf message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteWriteSample) []byte {
+	var req []byte
+
+	for _, s := range series {
+		var ts []byte
+
+		for _, l := range s.labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+
+	return req
+}
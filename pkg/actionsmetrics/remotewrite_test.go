@@ -0,0 +1,171 @@
+package actionsmetrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes the series of a write request into the lines like `__name__=a,b=c 3 @4`, sorted.
+func decodeWriteRequest(t *testing.T, b []byte) []string {
+	t.Helper()
+
+	fields := func(b []byte, f func(num protowire.Number, typ protowire.Type, v []byte)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			require.GreaterOrEqual(t, n, 0)
+			f(num, typ, b[:n])
+			b = b[n:]
+		}
+	}
+
+	var series []string
+
+	fields(b, func(_ protowire.Number, _ protowire.Type, ts []byte) {
+		ts, _ = protowire.ConsumeBytes(ts)
+
+		var labels []string
+		var sample string
+
+		fields(ts, func(num protowire.Number, _ protowire.Type, v []byte) {
+			v, _ = protowire.ConsumeBytes(v)
+
+			if num == 1 {
+				var name, value string
+				fields(v, func(num protowire.Number, _ protowire.Type, s []byte) {
+					s, _ = protowire.ConsumeBytes(s)
+					if num == 1 {
+						name = string(s)
+					} else {
+						value = string(s)
+					}
+				})
+				labels = append(labels, name+"="+value)
+				return
+			}
+
+			var value float64
+			var timestamp uint64
+			fields(v, func(num protowire.Number, _ protowire.Type, s []byte) {
+				if num == 1 {
+					bits, _ := protowire.ConsumeFixed64(s)
+					value = math.Float64frombits(bits)
+				} else {
+					timestamp, _ = protowire.ConsumeVarint(s)
+				}
+			})
+			sample = fmt.Sprintf("%s @%d", formatFloat(value), timestamp)
+		})
+
+		series = append(series, strings.Join(labels, ",")+" "+sample)
+	})
+
+	sort.Strings(series)
+
+	return series
+}
+
+func TestRemoteWriter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "jobs_total", Help: "jobs"}, []string{"repository"})
+	counter.WithLabelValues("owner/repo").Add(3)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "queue_seconds", Help: "queue", Buckets: []float64{10}})
+	histogram.Observe(5)
+	histogram.Observe(50)
+
+	reg.MustRegister(counter, histogram)
+
+	var (
+		requests []string
+		failures = 1
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "arc", user)
+		require.Equal(t, "secret", pass)
+
+		if failures > 0 {
+			failures--
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		body, err := s2.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		requests = append(requests, strings.Join(decodeWriteRequest(t, body), "\n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	w := &RemoteWriter{
+		Log:            logr.Discard(),
+		URL:            srv.URL,
+		Gatherer:       reg,
+		BatchSize:      4,
+		Username:       "arc",
+		Password:       "secret",
+		ExternalLabels: map[string]string{"cluster": "prod"},
+	}
+
+	now := time.UnixMilli(1700000000000)
+
+	require.NoError(t, w.Push(context.Background(), now))
+
+	// The first batch succeeds on retry, and the 5 series are split into the batches of up to 4
+	require.Equal(t, []string{
+		strings.Join([]string{
+			`__name__=jobs_total,cluster=prod,repository=owner/repo 3 @1700000000000`,
+			`__name__=queue_seconds_bucket,cluster=prod,le=+Inf 2 @1700000000000`,
+			`__name__=queue_seconds_bucket,cluster=prod,le=10 1 @1700000000000`,
+			`__name__=queue_seconds_sum,cluster=prod 55 @1700000000000`,
+		}, "\n"),
+		`__name__=queue_seconds_count,cluster=prod 2 @1700000000000`,
+	}, requests)
+}
+
+func TestRemoteWriterNonRetryableError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "up"}))
+
+	var attempts int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+
+	w := &RemoteWriter{Log: logr.Discard(), URL: srv.URL, Gatherer: reg, BearerToken: "token", Username: "ignored"}
+
+	err := w.Push(context.Background(), time.Now())
+	require.ErrorContains(t, err, "400 Bad Request: out of order sample")
+	require.Equal(t, 1, attempts)
+}
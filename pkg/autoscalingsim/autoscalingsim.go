@@ -0,0 +1,420 @@
+// Package autoscalingsim replays workflow jobs against a HorizontalRunnerAutoscaler scaling by workflow_job webhook events,
+// so that its amount, duration, minReplicas, and maxReplicas can be tuned offline.
+//
+// The simulation follows the webhook-based autoscaler: every queued job reserves the capacity of the scale trigger,
+// every completed job releases it, and the reservations expire after the duration of the trigger.
+// The ephemeral runners of the simulated pool take RunnerStartup to become available, pick up the queued jobs
+// in the order they were queued, and are replaced once their jobs complete.
+// The jobs run as long as they did originally, so the queue latencies and the runner-hours of the simulation
+// are what the candidate configuration would have resulted in.
+package autoscalingsim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const (
+	DefaultRunnerStartup          = time.Minute
+	DefaultStep                   = 10 * time.Second
+	DefaultScaleDownDelay         = 10 * time.Minute
+	DefaultScaleUpTriggerDuration = 10 * time.Minute
+
+	// maxHorizon is how long the simulation runs after the last job was queued at most,
+	// so that the jobs a configuration never runs, like with maxReplicas 0, don't keep it running forever.
+	maxHorizon = 24 * time.Hour
+)
+
+// Config is the candidate configuration the jobs are replayed against.
+type Config struct {
+	// Spec is the spec of the HorizontalRunnerAutoscaler. Its first workflowJob scale trigger is simulated.
+	Spec v1alpha1.HorizontalRunnerAutoscalerSpec
+
+	// RunnerLabels are the labels of the runners. The jobs requiring a label not in the list are ignored,
+	// like the webhook-based autoscaler ignores the jobs the runners can't run.
+	// The "self-hosted" label is implied. Empty to replay all the jobs.
+	RunnerLabels []string
+
+	// RunnerStartup is how long a runner takes to become available after it was created. Defaults to DefaultRunnerStartup.
+	RunnerStartup time.Duration
+
+	// Step is the resolution of the simulation. Defaults to DefaultStep.
+	Step time.Duration
+
+	// DefaultScaleDownDelay is the scale down delay of the HorizontalRunnerAutoscaler without scaleDownDelaySecondsAfterScaleOut,
+	// which is configured on the controller. Defaults to DefaultScaleDownDelay.
+	DefaultScaleDownDelay time.Duration
+}
+
+// Sample is the state of the runner pool at a point in time.
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Desired int       `json:"desired"`
+	Runners int       `json:"runners"`
+	Busy    int       `json:"busy"`
+	Idle    int       `json:"idle"`
+	Queued  int       `json:"queued"`
+}
+
+// LatencyStats are the queue latencies, from the time a job was queued until a runner picked it up, in seconds.
+type LatencyStats struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// RunnerHours is how long the runners existed, split by what they were doing.
+type RunnerHours struct {
+	Total    float64 `json:"total"`
+	Busy     float64 `json:"busy"`
+	Idle     float64 `json:"idle"`
+	Starting float64 `json:"starting"`
+}
+
+// Report is the result of a simulation.
+type Report struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Jobs is the number of the jobs replayed, Ignored the number of the jobs the runners can't run,
+	// and Unfinished the number of the replayed jobs still queued or running when the simulation ended.
+	Jobs       int `json:"jobs"`
+	Ignored    int `json:"ignored"`
+	Unfinished int `json:"unfinished"`
+
+	MaxDesired   int          `json:"maxDesired"`
+	QueueLatency LatencyStats `json:"queueLatencySeconds"`
+	RunnerHours  RunnerHours  `json:"runnerHours"`
+
+	// Timeline has a sample for every change to the runner pool.
+	Timeline []Sample `json:"timeline"`
+}
+
+type runner struct {
+	readyAt time.Time
+	job     *Job
+	doneAt  time.Time
+}
+
+// Simulate replays the jobs against the configuration.
+func Simulate(cfg Config, jobs []Job) (*Report, error) {
+	trigger, err := workflowJobTrigger(cfg.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseAmount, err := scaleDownAmount(trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	runnerStartup := cfg.RunnerStartup
+	if runnerStartup <= 0 {
+		runnerStartup = DefaultRunnerStartup
+	}
+
+	step := cfg.Step
+	if step <= 0 {
+		step = DefaultStep
+	}
+
+	scaleDownDelay := cfg.DefaultScaleDownDelay
+	if cfg.Spec.ScaleDownDelaySecondsAfterScaleUp != nil {
+		scaleDownDelay = time.Duration(*cfg.Spec.ScaleDownDelaySecondsAfterScaleUp) * time.Second
+	} else if scaleDownDelay <= 0 {
+		scaleDownDelay = DefaultScaleDownDelay
+	}
+
+	minReplicas := 0
+	if cfg.Spec.MinReplicas != nil {
+		minReplicas = *cfg.Spec.MinReplicas
+	}
+
+	maxReplicas := -1
+	if cfg.Spec.MaxReplicas != nil {
+		maxReplicas = *cfg.Spec.MaxReplicas
+	}
+
+	clamp := func(n int) int {
+		if maxReplicas >= 0 && n > maxReplicas {
+			n = maxReplicas
+		}
+		if n < minReplicas {
+			n = minReplicas
+		}
+		return n
+	}
+
+	report := &Report{}
+
+	var pending []*Job
+	for i := range jobs {
+		if !runnable(jobs[i].Labels, cfg.RunnerLabels) {
+			report.Ignored++
+			continue
+		}
+		pending = append(pending, &jobs[i])
+	}
+
+	report.Jobs = len(pending)
+
+	if len(pending) == 0 {
+		return report, nil
+	}
+
+	var (
+		now          = pending[0].QueuedAt.Truncate(step)
+		horizon      = pending[len(pending)-1].QueuedAt.Add(maxHorizon)
+		reservations []time.Time // the expiration times of the reservations of a replica each
+		runners      []*runner
+		queue        []*Job
+		latencies    []time.Duration
+		desired      = minReplicas
+		lastScaleOut time.Time
+		running      int
+		last         Sample
+	)
+
+	report.Start = now
+
+	for ; !now.After(horizon); now = now.Add(step) {
+		var batched bool
+
+		// The runners of the completed jobs are deleted, as the runners are ephemeral
+		alive := runners[:0]
+		for _, r := range runners {
+			if r.job != nil && !r.doneAt.After(now) {
+				running--
+				batched = true
+
+				if releaseAmount >= len(reservations) {
+					reservations = nil
+				} else {
+					reservations = reservations[releaseAmount:]
+				}
+
+				continue
+			}
+			alive = append(alive, r)
+		}
+		runners = alive
+
+		for len(pending) > 0 && !pending[0].QueuedAt.After(now) {
+			batched = true
+
+			queue = append(queue, pending[0])
+			pending = pending[1:]
+
+			for i := 0; i < trigger.Amount; i++ {
+				reservations = append(reservations, now.Add(trigger.Duration.Duration))
+			}
+		}
+
+		// The reservations not yet fitting within maxReplicas start expiring only once they fit, see planBatchScale
+		if batched && maxReplicas >= 0 {
+			for i := maxReplicas; i < len(reservations); i++ {
+				reservations[i] = now.Add(trigger.Duration.Duration)
+			}
+		}
+
+		valid := reservations[:0]
+		for _, exp := range reservations {
+			if exp.After(now) {
+				valid = append(valid, exp)
+			}
+		}
+		reservations = valid
+
+		newDesired := clamp(minReplicas + len(reservations))
+		if newDesired > desired {
+			lastScaleOut = now
+			desired = newDesired
+		} else if newDesired < desired && !now.Before(lastScaleOut.Add(scaleDownDelay)) {
+			desired = newDesired
+		}
+
+		if desired > report.MaxDesired {
+			report.MaxDesired = desired
+		}
+
+		runners = resize(runners, desired, now, runnerStartup)
+
+		for _, r := range runners {
+			if len(queue) == 0 {
+				break
+			}
+
+			if r.job != nil || r.readyAt.After(now) {
+				continue
+			}
+
+			r.job = queue[0]
+			r.doneAt = now.Add(r.job.Duration)
+			queue = queue[1:]
+			running++
+
+			latencies = append(latencies, now.Sub(r.job.QueuedAt))
+		}
+
+		sample := Sample{Time: now, Desired: desired, Runners: len(runners), Queued: len(queue)}
+
+		for _, r := range runners {
+			switch {
+			case r.job != nil:
+				sample.Busy++
+				report.RunnerHours.Busy += step.Hours()
+			case r.readyAt.After(now):
+				report.RunnerHours.Starting += step.Hours()
+			default:
+				sample.Idle++
+				report.RunnerHours.Idle += step.Hours()
+			}
+		}
+
+		if len(report.Timeline) == 0 || sample.Desired != last.Desired || sample.Runners != last.Runners ||
+			sample.Busy != last.Busy || sample.Idle != last.Idle || sample.Queued != last.Queued {
+			report.Timeline = append(report.Timeline, sample)
+		}
+		last = sample
+		report.End = now
+
+		// The simulation ends once all the jobs completed and the runner pool settled
+		if len(pending) == 0 && len(queue) == 0 && running == 0 && len(reservations) == 0 &&
+			desired == clamp(minReplicas) && len(runners) == desired && sample.Idle == desired {
+			break
+		}
+	}
+
+	report.Unfinished = len(pending) + len(queue) + running
+	report.RunnerHours.Total = report.RunnerHours.Busy + report.RunnerHours.Idle + report.RunnerHours.Starting
+	report.QueueLatency = latencyStats(latencies)
+
+	return report, nil
+}
+
+// resize creates or deletes the runners to match the desired replicas.
+// The idle runners are deleted first, then the starting ones, and the busy runners are never deleted.
+func resize(runners []*runner, desired int, now time.Time, startup time.Duration) []*runner {
+	for len(runners) < desired {
+		runners = append(runners, &runner{readyAt: now.Add(startup)})
+	}
+
+	excess := len(runners) - desired
+
+	for _, starting := range []bool{false, true} {
+		kept := runners[:0]
+		for _, r := range runners {
+			if excess > 0 && r.job == nil && r.readyAt.After(now) == starting {
+				excess--
+				continue
+			}
+			kept = append(kept, r)
+		}
+		runners = kept
+	}
+
+	return runners
+}
+
+func workflowJobTrigger(spec v1alpha1.HorizontalRunnerAutoscalerSpec) (v1alpha1.ScaleUpTrigger, error) {
+	for _, t := range spec.ScaleUpTriggers {
+		if t.GitHubEvent == nil || t.GitHubEvent.WorkflowJob == nil {
+			continue
+		}
+
+		// The defaults of the webhook-based autoscaler, which applies the amount only along with labels or scaleDownFactor
+		if t.Amount <= 0 || (t.ScaleDownFactor == "" && len(t.GitHubEvent.WorkflowJob.Labels) == 0) {
+			t.Amount = 1
+		}
+
+		if t.Duration.Duration <= 0 {
+			t.Duration.Duration = DefaultScaleUpTriggerDuration
+		}
+
+		return t, nil
+	}
+
+	return v1alpha1.ScaleUpTrigger{}, errors.New("the HorizontalRunnerAutoscaler has no `githubEvent.workflowJob` scale trigger")
+}
+
+// scaleDownAmount is the number of the reservations released on a completed job, like workflowJobScaleDownAmount of the webhook-based autoscaler.
+func scaleDownAmount(trigger v1alpha1.ScaleUpTrigger) (int, error) {
+	if trigger.ScaleDownFactor == "" {
+		return trigger.Amount, nil
+	}
+
+	factor, err := strconv.ParseFloat(trigger.ScaleDownFactor, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse scaleDownFactor %q: %w", trigger.ScaleDownFactor, err)
+	}
+
+	if factor < 0 || factor > 1 {
+		return 0, fmt.Errorf("scaleDownFactor must be between 0 and 1, but got %s", trigger.ScaleDownFactor)
+	}
+
+	return int(math.Ceil(float64(trigger.Amount) * factor)), nil
+}
+
+func runnable(jobLabels, runnerLabels []string) bool {
+	if len(runnerLabels) == 0 {
+		return true
+	}
+
+	for _, l := range jobLabels {
+		if strings.EqualFold(l, "self-hosted") {
+			continue
+		}
+
+		var found bool
+		for _, rl := range runnerLabels {
+			if strings.EqualFold(l, rl) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	percentile := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return latencies[i].Seconds()
+	}
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+
+	return LatencyStats{
+		Mean: (sum / time.Duration(len(latencies))).Seconds(),
+		P50:  percentile(0.5),
+		P90:  percentile(0.9),
+		P99:  percentile(0.99),
+		Max:  latencies[len(latencies)-1].Seconds(),
+	}
+}
@@ -0,0 +1,142 @@
+package autoscalingsim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func testSpec(min, max int) v1alpha1.HorizontalRunnerAutoscalerSpec {
+	return v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas:                       intPtr(min),
+		MaxReplicas:                       intPtr(max),
+		ScaleDownDelaySecondsAfterScaleUp: intPtr(0),
+		ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{
+			{
+				GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &v1alpha1.WorkflowJobSpec{}},
+				Amount:      1,
+				Duration:    metav1.Duration{Duration: 30 * time.Minute},
+			},
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	jobs := []Job{
+		{ID: 1, Labels: []string{"self-hosted", "linux"}, QueuedAt: t0, Duration: 5 * time.Minute},
+		{ID: 2, Labels: []string{"self-hosted", "linux"}, QueuedAt: t0, Duration: 5 * time.Minute},
+		{ID: 3, Labels: []string{"self-hosted", "linux"}, QueuedAt: t0, Duration: 5 * time.Minute},
+		{ID: 4, Labels: []string{"self-hosted", "gpu"}, QueuedAt: t0, Duration: 5 * time.Minute},
+	}
+
+	report, err := Simulate(Config{Spec: testSpec(0, 2), RunnerLabels: []string{"Linux"}}, jobs)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Jobs)
+	assert.Equal(t, 1, report.Ignored)
+	assert.Equal(t, 0, report.Unfinished)
+	assert.Equal(t, 2, report.MaxDesired)
+	assert.Equal(t, t0, report.Start)
+	assert.Equal(t, t0.Add(12*time.Minute), report.End, "the third job runs on a runner created once the first two completed")
+
+	assert.Equal(t, LatencyStats{Mean: 180, P50: 60, P90: 420, P99: 420, Max: 420}, report.QueueLatency)
+
+	assert.InDelta(t, 0.25, report.RunnerHours.Busy, 1e-9)
+	assert.InDelta(t, 0.05, report.RunnerHours.Starting, 1e-9)
+	assert.InDelta(t, 0, report.RunnerHours.Idle, 1e-9)
+	assert.InDelta(t, 0.3, report.RunnerHours.Total, 1e-9)
+
+	assert.Equal(t, Sample{Time: t0, Desired: 2, Runners: 2, Queued: 3}, report.Timeline[0])
+	assert.Equal(t, Sample{Time: t0.Add(12 * time.Minute)}, report.Timeline[len(report.Timeline)-1])
+
+	var csv bytes.Buffer
+	require.NoError(t, report.WriteTimelineCSV(&csv))
+	assert.True(t, strings.HasPrefix(csv.String(), "time,desired,runners,busy,idle,queued\n2024-01-01T00:00:00Z,2,2,0,0,3\n"))
+}
+
+func TestSimulate_ScaleDownDelay(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	spec := testSpec(0, 10)
+	spec.ScaleDownDelaySecondsAfterScaleUp = nil
+
+	report, err := Simulate(Config{Spec: spec}, []Job{{ID: 1, QueuedAt: t0, Duration: time.Minute}})
+	require.NoError(t, err)
+
+	// The runner of the completed job is replaced until the default scale down delay elapses
+	assert.Equal(t, t0.Add(DefaultScaleDownDelay), report.End)
+	assert.InDelta(t, DefaultScaleDownDelay.Hours(), report.RunnerHours.Total, 1e-9)
+}
+
+func TestSimulate_MaxReplicasZero(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report, err := Simulate(Config{Spec: testSpec(0, 0)}, []Job{{ID: 1, QueuedAt: t0, Duration: time.Minute}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Unfinished)
+	assert.Equal(t, t0.Add(maxHorizon), report.End)
+}
+
+func TestSimulate_NoWorkflowJobTrigger(t *testing.T) {
+	_, err := Simulate(Config{Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{}}, nil)
+	assert.Error(t, err)
+
+	spec := testSpec(0, 1)
+	spec.ScaleUpTriggers[0].ScaleDownFactor = "2"
+	_, err = Simulate(Config{Spec: spec}, nil)
+	assert.Error(t, err)
+}
+
+func TestReadDeliveries(t *testing.T) {
+	input := `[
+  {"receivedAt":"2024-01-01T00:00:05Z","event":"workflow_job","payload":{"action":"queued","workflow_job":{"id":1,"labels":["self-hosted"],"created_at":"2024-01-01T00:00:00Z"}}},
+  {"receivedAt":"2024-01-01T00:00:06Z","event":"workflow_run","payload":{"action":"requested"}},
+  {"receivedAt":"2024-01-01T00:00:07Z","event":"workflow_job","payload":{"action":"queued","workflow_job":{"id":3}}}
+]
+{"receivedAt":"2024-01-01T00:05:00Z","event":"workflow_job","payload":{"action":"completed","workflow_job":{"id":1,"name":"build","labels":["self-hosted"],"conclusion":"success","created_at":"2024-01-01T00:00:00Z","started_at":"2024-01-01T00:01:00Z","completed_at":"2024-01-01T00:04:00Z"}}}
+{"receivedAt":"2024-01-01T00:06:00Z","event":"workflow_job","payload":{"action":"completed","workflow_job":{"id":2,"conclusion":"failure","created_at":"2023-12-31T23:59:00Z","started_at":"2024-01-01T00:00:00Z","completed_at":"2024-01-01T00:02:00Z"}}}
+{"receivedAt":"2024-01-01T00:07:00Z","event":"workflow_job","payload":{"action":"completed","workflow_job":{"id":4,"conclusion":"skipped"}}}
+{"receivedAt":"2024-01-01T00:08:00Z","event":"workflow_job","truncated":true}
+`
+
+	jobs, err := ReadDeliveries(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Job{
+		{ID: 2, QueuedAt: time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC), Duration: 2 * time.Minute},
+		{ID: 1, Name: "build", Labels: []string{"self-hosted"}, QueuedAt: time.Date(2024, 1, 1, 0, 0, 5, 0, time.UTC), Duration: 3 * time.Minute},
+	}, jobs)
+
+	_, err = ReadDeliveries(strings.NewReader(`{"receivedAt":`))
+	assert.Error(t, err)
+}
+
+func TestReadJobRecords(t *testing.T) {
+	input := `{"jobs":[
+  {"jobID":2,"jobName":"test","labels":["self-hosted","linux"],"conclusion":"success","startedAt":"2024-01-01T00:10:00Z","durationSeconds":90},
+  {"jobID":3,"conclusion":"skipped","startedAt":"2024-01-01T00:00:00Z"}
+]}
+{"jobID":1,"jobName":"build","conclusion":"failure","startedAt":"2024-01-01T00:00:00Z","durationSeconds":60}
+`
+
+	jobs, err := ReadJobRecords(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Job{
+		{ID: 1, Name: "build", QueuedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Duration: time.Minute},
+		{ID: 2, Name: "test", Labels: []string{"self-hosted", "linux"}, QueuedAt: time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC), Duration: 90 * time.Second},
+	}, jobs)
+}
@@ -0,0 +1,218 @@
+package autoscalingsim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/actionsmetrics"
+)
+
+// Job is a workflow job replayed by the simulator.
+type Job struct {
+	ID     int64
+	Name   string
+	Labels []string
+
+	// QueuedAt is when the job was queued, which is when the webhook-based autoscaler reserves the capacity for it.
+	QueuedAt time.Time
+
+	// Duration is how long the job runs once a runner picks it up.
+	// It's independent of the runner the job ran on, so the simulator replays it as is on the simulated runners.
+	Duration time.Duration
+}
+
+// recordedDelivery is the subset of the webhook deliveries recorded by the github-webhook-server
+// and served on its /debug/deliveries endpoint the simulator reads.
+// See RecordedDelivery in controllers/actions.summerwind.net.
+type recordedDelivery struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+type workflowJobPayload struct {
+	Action      string `json:"action"`
+	WorkflowJob struct {
+		ID          int64      `json:"id"`
+		Name        string     `json:"name"`
+		Labels      []string   `json:"labels"`
+		Conclusion  string     `json:"conclusion"`
+		CreatedAt   *time.Time `json:"created_at"`
+		StartedAt   *time.Time `json:"started_at"`
+		CompletedAt *time.Time `json:"completed_at"`
+	} `json:"workflow_job"`
+}
+
+// ReadDeliveries reads the jobs from the recorded workflow_job deliveries,
+// either as the JSON array served on /debug/deliveries or as one delivery per line.
+//
+// A job is queued when its queued delivery was received, and runs for as long as it took between
+// its started_at and completed_at. The jobs without a completed delivery, the skipped ones,
+// and the ones whose payloads were too large to be recorded are not replayed.
+func ReadDeliveries(r io.Reader) ([]Job, error) {
+	var deliveries []recordedDelivery
+
+	err := decodeEach(r, func(v json.RawMessage) error {
+		if isArray(v) {
+			var ds []recordedDelivery
+			if err := json.Unmarshal(v, &ds); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, ds...)
+			return nil
+		}
+
+		var d recordedDelivery
+		if err := json.Unmarshal(v, &d); err != nil {
+			return err
+		}
+		deliveries = append(deliveries, d)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading deliveries: %w", err)
+	}
+
+	queued := map[int64]time.Time{}
+	jobs := map[int64]*Job{}
+
+	for _, d := range deliveries {
+		if d.Event != "workflow_job" || len(d.Payload) == 0 {
+			continue
+		}
+
+		var p workflowJobPayload
+		if err := json.Unmarshal(d.Payload, &p); err != nil {
+			return nil, fmt.Errorf("reading the payload of the delivery received at %s: %w", d.ReceivedAt, err)
+		}
+
+		wj := p.WorkflowJob
+
+		switch p.Action {
+		case "queued":
+			queued[wj.ID] = d.ReceivedAt
+		case "completed":
+			if wj.Conclusion == "skipped" || wj.StartedAt == nil || wj.CompletedAt == nil {
+				continue
+			}
+
+			job := &Job{
+				ID:       wj.ID,
+				Name:     wj.Name,
+				Labels:   wj.Labels,
+				Duration: wj.CompletedAt.Sub(*wj.StartedAt),
+			}
+
+			// The job created before the deliveries were recorded is queued when GitHub created it
+			if wj.CreatedAt != nil {
+				job.QueuedAt = *wj.CreatedAt
+			} else {
+				job.QueuedAt = *wj.StartedAt
+			}
+
+			jobs[wj.ID] = job
+		}
+	}
+
+	var result []Job
+
+	for id, job := range jobs {
+		if t, ok := queued[id]; ok {
+			job.QueuedAt = t
+		}
+
+		result = append(result, *job)
+	}
+
+	sortJobs(result)
+
+	return result, nil
+}
+
+// ReadJobRecords reads the jobs from the job records exported by the actions-metrics-server,
+// either as the {"jobs": [...]} response of its /jobs endpoint or as one record per line.
+//
+// The records don't tell when the jobs were queued, so they are queued when they started.
+// The queue latencies of the simulation are then relative to the ones observed.
+func ReadJobRecords(r io.Reader) ([]Job, error) {
+	var records []actionsmetrics.JobRecord
+
+	err := decodeEach(r, func(v json.RawMessage) error {
+		var list struct {
+			Jobs []actionsmetrics.JobRecord `json:"jobs"`
+		}
+		if err := json.Unmarshal(v, &list); err != nil {
+			return err
+		}
+
+		if list.Jobs != nil {
+			records = append(records, list.Jobs...)
+			return nil
+		}
+
+		var rec actionsmetrics.JobRecord
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return err
+		}
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading job records: %w", err)
+	}
+
+	var jobs []Job
+
+	for _, rec := range records {
+		if rec.Conclusion == "skipped" || rec.StartedAt.IsZero() {
+			continue
+		}
+
+		jobs = append(jobs, Job{
+			ID:       rec.JobID,
+			Name:     rec.JobName,
+			Labels:   rec.Labels,
+			QueuedAt: rec.StartedAt,
+			Duration: time.Duration(rec.DurationSeconds * float64(time.Second)),
+		})
+	}
+
+	sortJobs(jobs)
+
+	return jobs, nil
+}
+
+func decodeEach(r io.Reader, f func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := f(v); err != nil {
+			return err
+		}
+	}
+}
+
+func isArray(v json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(v), []byte("["))
+}
+
+func sortJobs(jobs []Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].QueuedAt.Equal(jobs[j].QueuedAt) {
+			return jobs[i].ID < jobs[j].ID
+		}
+		return jobs[i].QueuedAt.Before(jobs[j].QueuedAt)
+	})
+}
@@ -0,0 +1,58 @@
+package autoscalingsim
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteText writes the summary of the report for humans.
+func (r *Report) WriteText(w io.Writer) error {
+	seconds := func(s float64) string {
+		return (time.Duration(s * float64(time.Second))).Round(time.Second).String()
+	}
+
+	_, err := fmt.Fprintf(w, `Simulated:      %s - %s
+Jobs:           %d replayed, %d ignored, %d unfinished
+Max desired:    %d
+Queue latency:  mean %s, p50 %s, p90 %s, p99 %s, max %s
+Runner-hours:   %.2f total, %.2f busy, %.2f idle, %.2f starting
+`,
+		r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339),
+		r.Jobs, r.Ignored, r.Unfinished,
+		r.MaxDesired,
+		seconds(r.QueueLatency.Mean), seconds(r.QueueLatency.P50), seconds(r.QueueLatency.P90), seconds(r.QueueLatency.P99), seconds(r.QueueLatency.Max),
+		r.RunnerHours.Total, r.RunnerHours.Busy, r.RunnerHours.Idle, r.RunnerHours.Starting,
+	)
+
+	return err
+}
+
+// WriteTimelineCSV writes the timeline of the report as CSV, for plotting the replicas over time.
+func (r *Report) WriteTimelineCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"time", "desired", "runners", "busy", "idle", "queued"}); err != nil {
+		return err
+	}
+
+	for _, s := range r.Timeline {
+		row := []string{
+			s.Time.Format(time.RFC3339),
+			strconv.Itoa(s.Desired),
+			strconv.Itoa(s.Runners),
+			strconv.Itoa(s.Busy),
+			strconv.Itoa(s.Idle),
+			strconv.Itoa(s.Queued),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
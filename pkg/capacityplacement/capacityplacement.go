@@ -0,0 +1,167 @@
+// Package capacityplacement decides the capacity types, either spot or on-demand, of the runners created by the controllers
+// of RunnerReplicaSets and EphemeralRunnerSets for their capacity distributions.
+//
+// The capacity distributions of both APIs are converted to Distribution, so that the runners of both are placed alike.
+package capacityplacement
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The capacity types, which are the same values as the capacity types of the APIs.
+const (
+	Spot     = "spot"
+	OnDemand = "on-demand"
+)
+
+const (
+	DefaultFallbackAfter     = 3 * time.Minute
+	DefaultSpotRetryInterval = 15 * time.Minute
+)
+
+// Distribution is how the runners are split between the spot and the on-demand capacity.
+type Distribution struct {
+	// SpotFirst puts all the runners on the spot capacity, and falls back to the on-demand capacity while the spot capacity is unavailable.
+	// Otherwise, SpotPercentage of the runners are put on the spot capacity.
+	SpotFirst      bool
+	SpotPercentage int
+
+	// FallbackAfter and SpotRetryInterval default to DefaultFallbackAfter and DefaultSpotRetryInterval when nil.
+	FallbackAfter     *metav1.Duration
+	SpotRetryInterval *metav1.Duration
+}
+
+// Placement decides the capacity types of the runners, by counting the runners already on each capacity type.
+type Placement struct {
+	Spot, OnDemand       int
+	SpotUnavailableSince *metav1.Time
+
+	distribution Distribution
+	replicas     int
+}
+
+// New returns the placement of the replicas. spotUnavailableSince is the last time the SpotFirst distribution fell back to
+// the on-demand capacity, which is forgotten after the retry interval so that the spot capacity is tried again.
+func New(d Distribution, replicas int, spotUnavailableSince *metav1.Time, now time.Time) *Placement {
+	p := &Placement{
+		distribution: d,
+		replicas:     replicas,
+	}
+
+	if spotUnavailableSince != nil && now.Before(spotUnavailableSince.Add(p.SpotRetryInterval())) {
+		p.SpotUnavailableSince = spotUnavailableSince
+	}
+
+	return p
+}
+
+// Add counts n more runners on the capacity type. A negative n uncounts the runners.
+// The runners without any capacity type aren't counted.
+func (p *Placement) Add(capacityType string, n int) {
+	switch capacityType {
+	case Spot:
+		p.Spot += n
+	case OnDemand:
+		p.OnDemand += n
+	}
+}
+
+func (p *Placement) SpotFirst() bool {
+	return p.distribution.SpotFirst
+}
+
+func (p *Placement) FallbackAfter() time.Duration {
+	if p.distribution.FallbackAfter != nil {
+		return p.distribution.FallbackAfter.Duration
+	}
+
+	return DefaultFallbackAfter
+}
+
+func (p *Placement) SpotRetryInterval() time.Duration {
+	if p.distribution.SpotRetryInterval != nil {
+		return p.distribution.SpotRetryInterval.Duration
+	}
+
+	return DefaultSpotRetryInterval
+}
+
+// SpotTarget is the number of the runners that should be on the spot capacity.
+func (p *Placement) SpotTarget() int {
+	if p.SpotFirst() {
+		if p.SpotUnavailableSince != nil {
+			return 0
+		}
+
+		return p.replicas
+	}
+
+	return (p.replicas*p.distribution.SpotPercentage + 50) / 100
+}
+
+// Next returns the capacity type of the next runner to create, and counts the runner.
+func (p *Placement) Next() string {
+	if p.Spot < p.SpotTarget() {
+		p.Spot++
+		return Spot
+	}
+
+	p.OnDemand++
+	return OnDemand
+}
+
+// Deficit returns the capacity type that has fewer runners than the target, or an empty string when the runners are balanced.
+// The SpotFirst policy doesn't move the runners already on the spot capacity while it's falling back to the on-demand capacity.
+func (p *Placement) Deficit() string {
+	if p.SpotFirst() && p.SpotUnavailableSince != nil {
+		return ""
+	}
+
+	target := p.SpotTarget()
+
+	if p.Spot < target {
+		return Spot
+	}
+
+	if p.OnDemand < p.replicas-target {
+		return OnDemand
+	}
+
+	return ""
+}
+
+// FallsBack returns true when the SpotFirst policy should check the spot runners for the fallback to the on-demand capacity,
+// that is, unless it's already falling back.
+func (p *Placement) FallsBack() bool {
+	return p.SpotFirst() && p.SpotUnavailableSince == nil
+}
+
+// Unschedulable returns true when the pod of a spot runner has been unschedulable for fallbackAfter or longer.
+func (p *Placement) Unschedulable(pod *corev1.Pod, now time.Time) bool {
+	since, ok := UnschedulableSince(pod)
+
+	return ok && now.Sub(since) >= p.FallbackAfter()
+}
+
+// FallBack marks the spot capacity unavailable, so that the runners are put on the on-demand capacity until the retry interval passes.
+func (p *Placement) FallBack(now time.Time) {
+	p.SpotUnavailableSince = &metav1.Time{Time: now}
+}
+
+// UnschedulableSince returns when the pod became unschedulable, if it's pending for that.
+func UnschedulableSince(pod *corev1.Pod) (time.Time, bool) {
+	if pod.Status.Phase != corev1.PodPending {
+		return time.Time{}, false
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+
+	return time.Time{}, false
+}
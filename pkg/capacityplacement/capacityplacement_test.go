@@ -0,0 +1,92 @@
+package capacityplacement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlacementWeighted(t *testing.T) {
+	now := time.Now()
+
+	p := New(Distribution{SpotPercentage: 80}, 5, nil, now)
+
+	var types []string
+	for i := 0; i < 5; i++ {
+		types = append(types, p.Next())
+	}
+	require.Equal(t, []string{Spot, Spot, Spot, Spot, OnDemand}, types)
+	require.Empty(t, p.Deficit())
+	require.False(t, p.FallsBack())
+
+	// The runners without any capacity type aren't counted, so that they are replaced to fill the deficit
+	p = New(Distribution{SpotPercentage: 80}, 3, nil, now)
+	p.Add(Spot, 1)
+	p.Add(OnDemand, 1)
+	p.Add("", 1)
+	require.Equal(t, Spot, p.Deficit())
+
+	p.Add(Spot, 2)
+	p.Add(OnDemand, -1)
+	require.Equal(t, 3, p.Spot)
+	require.Equal(t, 0, p.OnDemand)
+	require.Equal(t, OnDemand, p.Deficit())
+}
+
+func TestPlacementSpotFirst(t *testing.T) {
+	now := time.Now()
+	unavailable := &metav1.Time{Time: now.Add(-10 * time.Minute)}
+
+	p := New(Distribution{SpotFirst: true}, 2, nil, now)
+	require.True(t, p.FallsBack())
+	require.Equal(t, 2, p.SpotTarget())
+	require.Equal(t, DefaultFallbackAfter, p.FallbackAfter())
+
+	p.FallBack(now)
+	require.False(t, p.FallsBack())
+	require.Equal(t, now, p.SpotUnavailableSince.Time)
+	require.Equal(t, OnDemand, p.Next())
+
+	p = New(Distribution{SpotFirst: true}, 2, unavailable, now)
+	p.Add(OnDemand, 1)
+	require.Equal(t, OnDemand, p.Next())
+	require.Empty(t, p.Deficit(), "the spot capacity is unavailable")
+
+	// The spot capacity is retried after the retry interval, moving the on-demand runners back
+	p = New(Distribution{SpotFirst: true}, 2, unavailable, now.Add(6*time.Minute))
+	p.Add(OnDemand, 1)
+	require.Nil(t, p.SpotUnavailableSince)
+	require.Equal(t, Spot, p.Next())
+	require.Equal(t, Spot, p.Deficit())
+
+	p = New(Distribution{SpotFirst: true, SpotRetryInterval: &metav1.Duration{Duration: time.Hour}}, 2, unavailable, now.Add(6*time.Minute))
+	require.NotNil(t, p.SpotUnavailableSince)
+}
+
+func TestPlacementUnschedulable(t *testing.T) {
+	now := time.Now()
+
+	newPod := func(phase corev1.PodPhase, unschedulableFor time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			Status: corev1.PodStatus{
+				Phase: phase,
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					Reason:             corev1.PodReasonUnschedulable,
+					LastTransitionTime: metav1.Time{Time: now.Add(-unschedulableFor)},
+				}},
+			},
+		}
+	}
+
+	p := New(Distribution{SpotFirst: true, FallbackAfter: &metav1.Duration{Duration: 2 * time.Minute}}, 1, nil, now)
+
+	require.False(t, p.Unschedulable(newPod(corev1.PodPending, time.Minute), now))
+	require.True(t, p.Unschedulable(newPod(corev1.PodPending, 2*time.Minute), now))
+	require.False(t, p.Unschedulable(newPod(corev1.PodRunning, 5*time.Minute), now))
+	require.False(t, p.Unschedulable(&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}, now))
+}
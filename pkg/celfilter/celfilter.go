@@ -0,0 +1,81 @@
+// Package celfilter evaluates CEL expressions against GitHub webhook payloads,
+// so that scale triggers can select webhook events with arbitrary conditions.
+//
+// See https://github.com/google/cel-spec for the language definition.
+package celfilter
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+const (
+	// VariableEvent is the name of the variable bound to the decoded webhook payload.
+	VariableEvent = "event"
+
+	// VariableEventType is the name of the variable bound to the webhook event type like "workflow_job",
+	// which is the value of the X-GitHub-Event header.
+	VariableEventType = "eventType"
+)
+
+var env *cel.Env
+
+func init() {
+	var err error
+
+	env, err = cel.NewEnv(
+		cel.Variable(VariableEvent, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(VariableEventType, cel.StringType),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("creating cel environment: %v", err))
+	}
+}
+
+// Filter is a compiled CEL expression that evaluates to a bool.
+type Filter struct {
+	expr string
+	prg  cel.Program
+}
+
+// Compile parses and type-checks the expression.
+// It fails when the expression doesn't evaluate to a bool.
+func Compile(expr string) (*Filter, error) {
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("compiling filter %q: %w", expr, iss.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("filter %q must evaluate to bool, but evaluates to %s", expr, ast.OutputType())
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("creating program for filter %q: %w", expr, err)
+	}
+
+	return &Filter{expr: expr, prg: prg}, nil
+}
+
+// Match evaluates the filter against the decoded webhook payload.
+// An evaluation error like accessing a missing field of the payload is returned as an error,
+// which the caller usually treats as not matching. Use has() to test for optional fields.
+func (f *Filter) Match(eventType string, event map[string]interface{}) (bool, error) {
+	out, _, err := f.prg.Eval(map[string]interface{}{
+		VariableEvent:     event,
+		VariableEventType: eventType,
+	})
+	if err != nil {
+		return false, fmt.Errorf("evaluating filter %q: %w", f.expr, err)
+	}
+
+	b, ok := out.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("filter %q must evaluate to bool, but evaluated to %v", f.expr, out.Type())
+	}
+
+	return bool(b), nil
+}
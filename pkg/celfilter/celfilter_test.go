@@ -0,0 +1,84 @@
+package celfilter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	var event map[string]interface{}
+
+	payload := `{
+  "action": "queued",
+  "workflow_job": {"labels": ["self-hosted", "linux", "gpu"]},
+  "repository": {"name": "myrepo", "visibility": "private"}
+}`
+
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{
+			expr: `event.workflow_job.labels.exists(l, l == "gpu") && event.repository.visibility == "private"`,
+			want: true,
+		},
+		{
+			expr: `event.workflow_job.labels.exists(l, l == "arm64")`,
+			want: false,
+		},
+		{
+			expr: `eventType == "workflow_job" && event.action == "queued"`,
+			want: true,
+		},
+		{
+			expr: `has(event.deployment) && event.deployment.environment == "production"`,
+			want: false,
+		},
+		{
+			// Accessing a missing field is an evaluation error
+			expr:    `event.deployment.environment == "production"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.expr, func(t *testing.T) {
+			f, err := Compile(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := f.Match("workflow_job", event)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, expr := range []string{
+		`event.workflow_job.labels.exists(l, `,
+		`"queued"`,
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("want error for %q", expr)
+		}
+	}
+}
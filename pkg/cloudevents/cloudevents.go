@@ -0,0 +1,124 @@
+// Package cloudevents emits the lifecycle transitions of the runners, like a runner registered to GitHub, a job assigned to a runner,
+// and a scale decision of a HorizontalRunnerAutoscaler, as CloudEvents to a sink,
+// so that the platforms downstream, like developer portals, can follow the state of ARC without watching the Kubernetes API.
+//
+// The events conform to the CloudEvents specification 1.0, and their data are the JSON of the types of this package,
+// which are the contract with the consumers, so the fields must only be added, never renamed.
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// DefaultSource is the source of the events unless configured otherwise, like with the name of the cluster.
+	DefaultSource = "actions-runner-controller"
+
+	// TypeRunnerRegistered is emitted when a runner is registered to GitHub, with RunnerData.
+	TypeRunnerRegistered = "dev.summerwind.actions.runner.registered"
+	// TypeRunnerDeleted is emitted when a runner is deleted after it's unregistered from GitHub, with RunnerData.
+	TypeRunnerDeleted = "dev.summerwind.actions.runner.deleted"
+	// TypeRunnerJobAssigned is emitted when a runner picks up a workflow job, with JobAssignedData.
+	TypeRunnerJobAssigned = "dev.summerwind.actions.runner.job.assigned"
+	// TypeScaleDecision is emitted when a HorizontalRunnerAutoscaler changes the desired replicas of its scale target, with ScaleDecisionData.
+	TypeScaleDecision = "dev.summerwind.actions.horizontalrunnerautoscaler.scaled"
+)
+
+// Event is a CloudEvent in the JSON event format.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// NewEvent returns the event of the type about the subject, like namespaces/default/runners/example, with the data encoded as JSON.
+// The source is left to the emitter.
+func NewEvent(typ, subject string, data interface{}) (Event, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Type:            typ,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            b,
+	}, nil
+}
+
+// Emitter sends the events to the sink. Emit must not block the reconciliation for long,
+// so emitters that deliver the events remotely should queue them.
+type Emitter interface {
+	Emit(ctx context.Context, e Event)
+}
+
+// RunnerData is the data of TypeRunnerRegistered and TypeRunnerDeleted.
+type RunnerData struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// RunnerDeployment is the RunnerDeployment the runner belongs to, if any.
+	RunnerDeployment string `json:"runnerDeployment,omitempty"`
+
+	Enterprise   string   `json:"enterprise,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	Group        string   `json:"group,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+
+	// RunnerID is the ID of the runner in GitHub, when known.
+	RunnerID int64 `json:"runnerID,omitempty"`
+	// Node is the node the runner pod is scheduled onto, when known.
+	Node string `json:"node,omitempty"`
+}
+
+// JobData is the workflow job of JobAssignedData.
+type JobData struct {
+	ID           int64    `json:"id"`
+	RunID        int64    `json:"runID"`
+	Name         string   `json:"name"`
+	WorkflowName string   `json:"workflowName,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	URL          string   `json:"url,omitempty"`
+}
+
+// JobAssignedData is the data of TypeRunnerJobAssigned.
+type JobAssignedData struct {
+	Runner RunnerData `json:"runner"`
+	Job    JobData    `json:"job"`
+}
+
+// ScaleDecisionData is the data of TypeScaleDecision.
+type ScaleDecisionData struct {
+	Namespace string `json:"namespace"`
+	// Name is the name of the HorizontalRunnerAutoscaler.
+	Name string `json:"name"`
+
+	// ScaleTargetKind is either RunnerDeployment or RunnerSet.
+	ScaleTargetKind string `json:"scaleTargetKind"`
+	ScaleTargetName string `json:"scaleTargetName"`
+
+	// PreviousReplicas is nil for the first decision of the HorizontalRunnerAutoscaler.
+	PreviousReplicas *int `json:"previousReplicas,omitempty"`
+	DesiredReplicas  int  `json:"desiredReplicas"`
+	MinReplicas      int  `json:"minReplicas"`
+	MaxReplicas      *int `json:"maxReplicas,omitempty"`
+
+	// DryRun is true when the replicas were only computed, without applying them to the scale target.
+	DryRun bool `json:"dryRun,omitempty"`
+}
@@ -0,0 +1,184 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// ModeStructured sends the whole event as the JSON body with the application/cloudevents+json content type.
+	ModeStructured = "structured"
+	// ModeBinary sends the data as the body and the attributes as the ce- headers, like the Knative brokers prefer.
+	ModeBinary = "binary"
+
+	defaultQueueSize = 1000
+	sinkAttempts     = 3
+	sinkTimeout      = 10 * time.Second
+)
+
+// HTTPSink POSTs the events to a URL, like a Knative broker or the webhook of a developer portal.
+//
+// The events are queued and delivered by Start in the background, so that the reconciliations don't wait for the sink.
+// The events are dropped, with an error logged, when the queue is full or the delivery fails after retries.
+type HTTPSink struct {
+	URL string
+	// Source is set as the source of the events.
+	Source string
+	// Mode is either ModeStructured or ModeBinary. Defaults to ModeStructured.
+	Mode   string
+	Client *http.Client
+	Log    logr.Logger
+
+	events chan Event
+	// backoff is the wait before the first retry, doubled on every retry.
+	backoff time.Duration
+}
+
+// NewHTTPSink returns the HTTPSink delivering the events to the URL.
+// Add it to the manager, which runs Start, to deliver the events.
+func NewHTTPSink(url, source, mode string, log logr.Logger) (*HTTPSink, error) {
+	switch mode {
+	case "":
+		mode = ModeStructured
+	case ModeStructured, ModeBinary:
+	default:
+		return nil, fmt.Errorf("unknown CloudEvents mode %q: must be either %q or %q", mode, ModeStructured, ModeBinary)
+	}
+
+	if source == "" {
+		source = DefaultSource
+	}
+
+	return &HTTPSink{
+		URL:     url,
+		Source:  source,
+		Mode:    mode,
+		Client:  &http.Client{Timeout: sinkTimeout},
+		Log:     log,
+		events:  make(chan Event, defaultQueueSize),
+		backoff: time.Second,
+	}, nil
+}
+
+func (s *HTTPSink) Emit(_ context.Context, e Event) {
+	if e.Source == "" {
+		e.Source = s.Source
+	}
+
+	select {
+	case s.events <- e:
+	default:
+		s.Log.Error(fmt.Errorf("queue of %d events is full", cap(s.events)), "Dropped CloudEvent", "type", e.Type, "subject", e.Subject)
+	}
+}
+
+// Start delivers the queued events until the context is done, and then the events left in the queue
+// for up to 10 seconds.
+func (s *HTTPSink) Start(ctx context.Context) error {
+	for {
+		select {
+		case e := <-s.events:
+			s.deliver(ctx, e)
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+			defer cancel()
+			for flushCtx.Err() == nil {
+				select {
+				case e := <-s.events:
+					s.deliver(flushCtx, e)
+				default:
+					return nil
+				}
+			}
+			if n := len(s.events); n > 0 {
+				s.Log.Error(flushCtx.Err(), "Dropped CloudEvents left in the queue on stop", "count", n)
+			}
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection makes every replica deliver its own events. Only the leader emits the events of the reconciliations,
+// while every replica of the github webhook server emits the events of the webhook deliveries it receives.
+func (s *HTTPSink) NeedLeaderElection() bool {
+	return false
+}
+
+// deliver POSTs the event, retrying the failures until the context is done.
+// The requests don't use the context, so that the events dequeued as the manager stops are still delivered.
+func (s *HTTPSink) deliver(ctx context.Context, e Event) {
+	backoff := s.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.post(e)
+		if err == nil {
+			return
+		}
+		if attempt == sinkAttempts || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	s.Log.Error(err, "Dropped CloudEvent", "type", e.Type, "subject", e.Subject, "url", s.URL)
+}
+
+func (s *HTTPSink) post(e Event) error {
+	req, err := s.newRequest(e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *HTTPSink) newRequest(e Event) (*http.Request, error) {
+	if s.Mode == ModeBinary {
+		req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(e.Data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", e.DataContentType)
+		req.Header.Set("ce-specversion", e.SpecVersion)
+		req.Header.Set("ce-id", e.ID)
+		req.Header.Set("ce-source", e.Source)
+		req.Header.Set("ce-type", e.Type)
+		req.Header.Set("ce-time", e.Time.Format(time.RFC3339Nano))
+		if e.Subject != "" {
+			req.Header.Set("ce-subject", e.Subject)
+		}
+		return req, nil
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	return req, nil
+}
@@ -0,0 +1,166 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestHTTPSinkStructured(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		// Fail the first delivery to be retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/cloudevents+json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("unable to decode event: %v", err)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	sink, err := NewHTTPSink(srv.URL, "", "", logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.backoff = time.Millisecond
+
+	e, err := NewEvent(TypeRunnerRegistered, "namespaces/default/runners/example", RunnerData{Namespace: "default", Name: "example", RunnerID: 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.Emit(context.Background(), e)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- sink.Start(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 1 delivered event, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	got := received[0]
+	if got.SpecVersion != "1.0" || got.Source != DefaultSource || got.Type != TypeRunnerRegistered || got.Subject != "namespaces/default/runners/example" || got.ID != e.ID {
+		t.Errorf("unexpected event: %+v", got)
+	}
+
+	var data RunnerData
+	if err := json.Unmarshal(got.Data, &data); err != nil {
+		t.Fatalf("unable to decode data: %v", err)
+	}
+	if data.Name != "example" || data.RunnerID != 42 {
+		t.Errorf("unexpected data: %+v", data)
+	}
+}
+
+func TestHTTPSinkBinary(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sink, err := NewHTTPSink(srv.URL, "prod-cluster", ModeBinary, logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e, err := NewEvent(TypeScaleDecision, "namespaces/default/horizontalrunnerautoscalers/example", ScaleDecisionData{Namespace: "default", Name: "example", DesiredReplicas: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.Emit(context.Background(), e)
+
+	// The events queued before the manager stops are delivered on stop
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sink.Start(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for k, want := range map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-id":          e.ID,
+		"ce-source":      "prod-cluster",
+		"ce-type":        TypeScaleDecision,
+		"ce-subject":     "namespaces/default/horizontalrunnerautoscalers/example",
+	} {
+		if got := header.Get(k); got != want {
+			t.Errorf("unexpected %s header: want %q, got %q", k, want, got)
+		}
+	}
+
+	if want := `{"namespace":"default","name":"example","scaleTargetKind":"","scaleTargetName":"","desiredReplicas":3,"minReplicas":0}`; string(body) != want {
+		t.Errorf("unexpected body: want %s, got %s", want, body)
+	}
+}
+
+func TestHTTPSinkDropsWhenFull(t *testing.T) {
+	sink, err := NewHTTPSink("http://sink.example.com", "", "", logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.events = make(chan Event, 1)
+
+	sink.Emit(context.Background(), Event{Type: TypeRunnerRegistered})
+	// Doesn't block the reconciliation
+	sink.Emit(context.Background(), Event{Type: TypeRunnerDeleted})
+
+	if len(sink.events) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(sink.events))
+	}
+	if e := <-sink.events; e.Type != TypeRunnerRegistered {
+		t.Errorf("expected the first event to be kept, got %+v", e)
+	}
+}
+
+func TestNewHTTPSinkUnknownMode(t *testing.T) {
+	if _, err := NewHTTPSink("http://sink.example.com", "", "batched", logr.Discard()); err == nil {
+		t.Error("expected an error for the unknown mode")
+	}
+}
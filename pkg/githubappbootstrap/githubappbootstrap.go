@@ -0,0 +1,369 @@
+// Package githubappbootstrap creates a GitHub App for actions-runner-controller via the GitHub App manifest flow,
+// and stores the credentials of the app into a Kubernetes secret that the controller reads.
+//
+// See https://docs.github.com/en/apps/sharing-github-apps/registering-a-github-app-from-a-manifest for the flow.
+package githubappbootstrap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v52/github"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultGitHubURL = "https://github.com"
+
+	// The keys of the secret read by the controller. See charts/actions-runner-controller/templates/manager_secrets.yaml
+	SecretKeyAppID             = "github_app_id"
+	SecretKeyAppInstallationID = "github_app_installation_id"
+	SecretKeyAppPrivateKey     = "github_app_private_key"
+	SecretKeyWebhookSecret     = "github_webhook_secret_token"
+
+	AnnotationKeyAppSlug        = "actions-runner-controller/github-app-slug"
+	AnnotationKeyAppURL         = "actions-runner-controller/github-app-url"
+	AnnotationKeyBootstrappedAt = "actions-runner-controller/github-app-bootstrapped-at"
+)
+
+// Bootstrapper serves the pages the user opens in the browser to walk through the GitHub App manifest flow:
+//
+//  1. The index page submits the manifest to GitHub, where the user confirms the creation of the app.
+//  2. GitHub redirects the user back to the callback page with a temporary code,
+//     which is exchanged for the app ID, the private key, and the webhook secret. They are stored into the secret.
+//  3. The user is redirected to GitHub to install the app, and GitHub redirects the user back to the setup page
+//     with the installation ID, which is stored into the secret.
+type Bootstrapper struct {
+	// GitHubURL is the URL of GitHub or GitHub Enterprise Server. Defaults to DefaultGitHubURL.
+	GitHubURL string
+
+	// GitHubClient is used to convert the temporary code into the app credentials.
+	// It doesn't need to be authenticated.
+	GitHubClient *gogithub.Client
+
+	// Organization is the organization that owns the app. The app is owned by the user creating it when empty.
+	Organization string
+
+	// PublicURL is the URL at which the browser reaches this server, like http://localhost:8080 via kubectl port-forward.
+	PublicURL string
+
+	// AppName is the name of the app. GitHub requires it to be unique.
+	AppName string
+
+	// WebhookURL is the URL of the github-webhook-server. The app is created without a webhook when empty.
+	WebhookURL string
+
+	Client          client.Client
+	SecretNamespace string
+	SecretName      string
+
+	Log logr.Logger
+
+	mu    sync.Mutex
+	state string
+	done  chan struct{}
+}
+
+type manifest struct {
+	Name               string            `json:"name,omitempty"`
+	URL                string            `json:"url"`
+	HookAttributes     *hookAttributes   `json:"hook_attributes,omitempty"`
+	RedirectURL        string            `json:"redirect_url"`
+	SetupURL           string            `json:"setup_url"`
+	Public             bool              `json:"public"`
+	DefaultPermissions map[string]string `json:"default_permissions"`
+	DefaultEvents      []string          `json:"default_events,omitempty"`
+}
+
+type hookAttributes struct {
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+}
+
+// Manifest returns the GitHub App manifest with the permissions required by the controller.
+// See docs/authenticating-to-the-github-api.md for the permissions.
+func (b *Bootstrapper) Manifest() ([]byte, error) {
+	publicURL := strings.TrimSuffix(b.PublicURL, "/")
+
+	m := manifest{
+		Name:        b.AppName,
+		URL:         "https://github.com/actions/actions-runner-controller",
+		RedirectURL: publicURL + "/callback",
+		SetupURL:    publicURL + "/installed",
+		Public:      false,
+		DefaultPermissions: map[string]string{
+			"actions":        "read",
+			"administration": "write",
+			"checks":         "read",
+			"metadata":       "read",
+		},
+	}
+
+	if b.Organization != "" {
+		m.DefaultPermissions["organization_self_hosted_runners"] = "write"
+	}
+
+	if b.WebhookURL != "" {
+		m.HookAttributes = &hookAttributes{URL: b.WebhookURL, Active: true}
+		m.DefaultEvents = []string{"workflow_job"}
+	}
+
+	return json.Marshal(m)
+}
+
+// Done is closed once the credentials including the installation ID are stored into the secret.
+func (b *Bootstrapper) Done() <-chan struct{} {
+	b.init()
+
+	return b.done
+}
+
+func (b *Bootstrapper) init() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done != nil {
+		return
+	}
+
+	b.done = make(chan struct{})
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("generating state: %v", err))
+	}
+
+	b.state = hex.EncodeToString(buf)
+}
+
+func (b *Bootstrapper) githubURL() string {
+	if b.GitHubURL == "" {
+		return DefaultGitHubURL
+	}
+
+	return strings.TrimSuffix(b.GitHubURL, "/")
+}
+
+// Handler returns the handler serving the pages of the manifest flow.
+func (b *Bootstrapper) Handler() http.Handler {
+	b.init()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", b.handleIndex)
+	mux.HandleFunc("/callback", b.handleCallback)
+	mux.HandleFunc("/installed", b.handleInstalled)
+
+	return mux
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Create a GitHub App for actions-runner-controller</title></head>
+<body>
+<form action="{{ .Action }}" method="post">
+<p>Create a GitHub App named <strong>{{ .AppName }}</strong> for actions-runner-controller. The credentials of the app will be stored into the secret <strong>{{ .Secret }}</strong>.</p>
+<input type="hidden" name="manifest" value="{{ .Manifest }}">
+<input type="submit" value="Create GitHub App">
+</form>
+</body>
+</html>
+`))
+
+var messageTemplate = template.Must(template.New("message").Parse(`<!DOCTYPE html>
+<html>
+<head><title>actions-runner-controller</title></head>
+<body><p>{{ . }}</p></body>
+</html>
+`))
+
+func (b *Bootstrapper) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	m, err := b.Manifest()
+	if err != nil {
+		b.Log.Error(err, "Could not create the GitHub App manifest")
+		http.Error(w, "could not create the GitHub App manifest", http.StatusInternalServerError)
+		return
+	}
+
+	action := b.githubURL() + "/settings/apps/new"
+	if b.Organization != "" {
+		action = fmt.Sprintf("%s/organizations/%s/settings/apps/new", b.githubURL(), url.PathEscape(b.Organization))
+	}
+
+	action += "?state=" + url.QueryEscape(b.state)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := indexTemplate.Execute(w, map[string]string{
+		"Action":   action,
+		"AppName":  b.AppName,
+		"Secret":   b.SecretNamespace + "/" + b.SecretName,
+		"Manifest": string(m),
+	}); err != nil {
+		b.Log.Error(err, "Could not render the index page")
+	}
+}
+
+func (b *Bootstrapper) handleCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if !b.validState(q.Get("state")) {
+		http.Error(w, "invalid state. Start over from the index page", http.StatusBadRequest)
+		return
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+
+	app, _, err := b.GitHubClient.Apps.CompleteAppManifest(r.Context(), code)
+	if err != nil {
+		b.Log.Error(err, "Could not convert the GitHub App manifest code")
+		http.Error(w, "could not create the GitHub App. Start over from the index page", http.StatusBadGateway)
+		return
+	}
+
+	log := b.Log.WithValues("app", app.GetSlug(), "appID", app.GetID())
+
+	// Store the credentials right away, as GitHub returns the private key only once
+	data := map[string][]byte{
+		SecretKeyAppID:         []byte(strconv.FormatInt(app.GetID(), 10)),
+		SecretKeyAppPrivateKey: []byte(app.GetPEM()),
+	}
+
+	if s := app.GetWebhookSecret(); s != "" {
+		data[SecretKeyWebhookSecret] = []byte(s)
+	}
+
+	annotations := map[string]string{
+		AnnotationKeyAppSlug: app.GetSlug(),
+		AnnotationKeyAppURL:  app.GetHTMLURL(),
+	}
+
+	if err := b.updateSecret(r.Context(), data, annotations); err != nil {
+		log.Error(err, "Could not store the GitHub App credentials")
+		http.Error(w, fmt.Sprintf("created the GitHub App %s but could not store its credentials. Delete the app and start over", app.GetHTMLURL()), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Created the GitHub App and stored its credentials", "secret", b.SecretNamespace+"/"+b.SecretName)
+
+	http.Redirect(w, r, fmt.Sprintf("%s/installations/new?state=%s", strings.TrimSuffix(app.GetHTMLURL(), "/"), url.QueryEscape(b.state)), http.StatusFound)
+}
+
+func (b *Bootstrapper) handleInstalled(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if !b.validState(q.Get("state")) {
+		http.Error(w, "invalid state. Start over from the index page", http.StatusBadRequest)
+		return
+	}
+
+	installationID, err := strconv.ParseInt(q.Get("installation_id"), 10, 64)
+	if err != nil || installationID <= 0 {
+		http.Error(w, "missing or invalid installation_id", http.StatusBadRequest)
+		return
+	}
+
+	data := map[string][]byte{
+		SecretKeyAppInstallationID: []byte(strconv.FormatInt(installationID, 10)),
+	}
+
+	annotations := map[string]string{
+		AnnotationKeyBootstrappedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if err := b.updateSecret(r.Context(), data, annotations); err != nil {
+		b.Log.Error(err, "Could not store the GitHub App installation ID")
+		http.Error(w, "could not store the installation ID. Reload this page to retry", http.StatusInternalServerError)
+		return
+	}
+
+	b.Log.Info("Stored the GitHub App installation ID", "installationID", installationID, "secret", b.SecretNamespace+"/"+b.SecretName)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := messageTemplate.Execute(w, fmt.Sprintf("Stored the GitHub App credentials into the secret %s/%s. You can close this page.", b.SecretNamespace, b.SecretName)); err != nil {
+		b.Log.Error(err, "Could not render the installed page")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.done:
+	default:
+		close(b.done)
+	}
+}
+
+func (b *Bootstrapper) validState(state string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return state != "" && state == b.state
+}
+
+// updateSecret creates the secret or merges the data and the annotations into the existing secret,
+// so that the secret can be shared with other settings like the webhook secret token.
+func (b *Bootstrapper) updateSecret(ctx context.Context, data map[string][]byte, annotations map[string]string) error {
+	var secret corev1.Secret
+
+	err := b.Client.Get(ctx, types.NamespacedName{Namespace: b.SecretNamespace, Name: b.SecretName}, &secret)
+	if kerrors.IsNotFound(err) {
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   b.SecretNamespace,
+				Name:        b.SecretName,
+				Annotations: annotations,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+
+		return b.Client.Create(ctx, &secret)
+	} else if err != nil {
+		return err
+	}
+
+	updated := secret.DeepCopy()
+
+	if updated.Data == nil {
+		updated.Data = map[string][]byte{}
+	}
+
+	for k, v := range data {
+		updated.Data[k] = v
+	}
+
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+
+	for k, v := range annotations {
+		updated.Annotations[k] = v
+	}
+
+	return b.Client.Patch(ctx, updated, client.MergeFrom(&secret))
+}
@@ -0,0 +1,264 @@
+package imageregistry
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+)
+
+const (
+	// cosignSignatureAnnotation is the annotation of the layers of the signature manifests holding the base64 encoded signatures.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// The annotations of the layers of the keyless signatures and attestations holding the PEM encoded Fulcio certificate,
+	// its chain, and the Rekor bundle of the log entry.
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+
+	// cosignPredicateTypeAnnotation is the annotation of the layers of the attestation manifests holding the predicate type of the attestations.
+	cosignPredicateTypeAnnotation = "predicateType"
+)
+
+// ParsePublicKey parses the PEM encoded public key, like the cosign.pub written by cosign generate-key-pair.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Verifier verifies the cosign signatures and attestations either with Key, for the ones made by cosign with --key,
+// or with Keyless, for the ones made with the Fulcio certificates issued to the identities of the signers.
+type Verifier struct {
+	Key     crypto.PublicKey
+	Keyless *sigstore.Verifier
+}
+
+// VerifyCosignSignature verifies that the digest in the repository of the reference is signed by cosign sign.
+// The signatures are read from the sha256-HEX.sig tag of the repository where cosign stores them,
+// and at least one of them must be of a payload pointing to the digest.
+func (c *Client) VerifyCosignSignature(ctx context.Context, ref Reference, digest string, v Verifier) error {
+	layers, err := c.cosignLayers(ctx, ref, digest, "sig")
+	if err != nil {
+		return fmt.Errorf("getting the cosign signatures of %s@%s: %w", ref.Name, digest, err)
+	}
+
+	var errs []error
+
+	for _, l := range layers {
+		encoded, ok := l.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("decoding the signature of %s: %w", l.Digest, err))
+			continue
+		}
+
+		payload, err := c.Blob(ctx, ref, l.Digest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := v.verify(l.Annotations, payload, func(key crypto.PublicKey) ([]byte, error) {
+			return sig, sigstore.VerifySignature(key, payload, sig)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("signature of %s: %w", l.Digest, err))
+			continue
+		}
+
+		if err := verifyPayload(payload, digest); err != nil {
+			errs = append(errs, fmt.Errorf("payload of %s: %w", l.Digest, err))
+			continue
+		}
+
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("no cosign signature of %s@%s found", ref.Name, digest)
+	}
+
+	return fmt.Errorf("no cosign signature of %s@%s is verified: %w", ref.Name, digest, errors.Join(errs...))
+}
+
+// VerifyCosignAttestation verifies that the digest in the repository of the reference has the attestation of the predicate type,
+// like https://slsa.dev/provenance/v1, attested by cosign attest.
+// The attestations are read from the sha256-HEX.att tag of the repository where cosign stores them as DSSE envelopes,
+// and at least one of them must be of an in-toto statement about the digest.
+func (c *Client) VerifyCosignAttestation(ctx context.Context, ref Reference, digest, predicateType string, v Verifier) error {
+	layers, err := c.cosignLayers(ctx, ref, digest, "att")
+	if err != nil {
+		return fmt.Errorf("getting the cosign attestations of %s@%s: %w", ref.Name, digest, err)
+	}
+
+	var errs []error
+
+	for _, l := range layers {
+		if t, ok := l.Annotations[cosignPredicateTypeAnnotation]; ok && t != predicateType {
+			continue
+		}
+
+		blob, err := c.Blob(ctx, ref, l.Digest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var envelope sigstore.Envelope
+		if err := json.Unmarshal(blob, &envelope); err != nil {
+			errs = append(errs, fmt.Errorf("parsing the envelope of %s: %w", l.Digest, err))
+			continue
+		}
+
+		if err := v.verify(l.Annotations, envelope.Payload, envelope.Verify); err != nil {
+			errs = append(errs, fmt.Errorf("attestation %s: %w", l.Digest, err))
+			continue
+		}
+
+		statement, err := sigstore.ParseStatement(&envelope)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attestation %s: %w", l.Digest, err))
+			continue
+		}
+
+		if statement.PredicateType != predicateType || !statement.HasSubject(digest) {
+			errs = append(errs, fmt.Errorf("attestation %s is of %s about another artifact", l.Digest, statement.PredicateType))
+			continue
+		}
+
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("no cosign attestation of %s of %s@%s found", predicateType, ref.Name, digest)
+	}
+
+	return fmt.Errorf("no cosign attestation of %s of %s@%s is verified: %w", predicateType, ref.Name, digest, errors.Join(errs...))
+}
+
+type cosignLayer struct {
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// cosignLayers returns the layers of the manifest cosign stores the signatures or the attestations of the digest in, by the suffix of the tag.
+func (c *Client) cosignLayers(ctx context.Context, ref Reference, digest, suffix string) ([]cosignLayer, error) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("unsupported digest %q", digest)
+	}
+
+	body, _, err := c.Manifest(ctx, ref, strings.Replace(digest, ":", "-", 1)+"."+suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Layers []cosignLayer `json:"layers"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	return manifest.Layers, nil
+}
+
+// cosignBundle is the Rekor bundle cosign annotates the keyless signatures with.
+// It carries only the signed entry timestamp of the log entry, which cosign verifies offline likewise, and no inclusion proof.
+type cosignBundle struct {
+	SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+	Payload              struct {
+		Body           []byte `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogIndex       int64  `json:"logIndex"`
+		LogID          string `json:"logID"`
+	} `json:"Payload"`
+}
+
+// verify verifies the signature of the payload with the signature function, which returns the signature verified with the key.
+// When keyless, the key is of the certificate in the annotations, which must have been valid when the signature was logged in the Rekor bundle,
+// and must be the certificate the log entry records.
+func (v Verifier) verify(annotations map[string]string, payload []byte, verifySignature func(crypto.PublicKey) ([]byte, error)) error {
+	if v.Keyless == nil {
+		_, err := verifySignature(v.Key)
+		return err
+	}
+
+	certs, err := sigstore.ParseCertificates([]byte(annotations[cosignCertificateAnnotation]))
+	if err != nil {
+		return fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	var chain []*x509.Certificate
+	if pem := annotations[cosignChainAnnotation]; pem != "" {
+		chain, err = sigstore.ParseCertificates([]byte(pem))
+		if err != nil {
+			return fmt.Errorf("parsing certificate chain: %w", err)
+		}
+	}
+
+	sig, err := verifySignature(certs[0].PublicKey)
+	if err != nil {
+		return err
+	}
+
+	var bundle cosignBundle
+	if err := json.Unmarshal([]byte(annotations[cosignBundleAnnotation]), &bundle); err != nil {
+		return fmt.Errorf("parsing Rekor bundle: %w", err)
+	}
+
+	signedAt, err := v.Keyless.VerifyLogEntry(sigstore.LogEntry{
+		Body:                 bundle.Payload.Body,
+		IntegratedTime:       bundle.Payload.IntegratedTime,
+		LogIndex:             bundle.Payload.LogIndex,
+		LogID:                bundle.Payload.LogID,
+		SignedEntryTimestamp: bundle.SignedEntryTimestamp,
+	}, certs[0], sig, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = v.Keyless.VerifyCertificate(certs[0], chain, signedAt)
+
+	return err
+}
+
+// verifyPayload verifies that the simple signing payload signed by cosign points to the digest.
+func verifyPayload(payload []byte, digest string) error {
+	var p struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("parsing: %w", err)
+	}
+
+	if got := p.Critical.Image.DockerManifestDigest; got != digest {
+		return fmt.Errorf("signed digest %q doesn't match %s", got, digest)
+	}
+
+	return nil
+}
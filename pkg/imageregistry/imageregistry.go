@@ -0,0 +1,396 @@
+// Package imageregistry resolves the tags of container images to digests and verifies the cosign signatures of the digests,
+// with the OCI distribution API of the registries.
+package imageregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dockerHubName     = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// maxBodySize caps the manifests and the blobs read from the registry, which are small JSON documents.
+	maxBodySize = 4 << 20
+
+	defaultTimeout = 30 * time.Second
+)
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference like ghcr.io/actions/actions-runner:latest.
+type Reference struct {
+	// Name is the image name as written, without the tag and the digest, like summerwind/actions-runner.
+	Name string
+	// Registry is the host of the registry API, like registry-1.docker.io for the images on Docker Hub.
+	Registry string
+	// Repository is the repository in the registry, like library/ubuntu.
+	Repository string
+	// Tag is the tag, empty when omitted.
+	Tag string
+	// Digest is the digest, empty when omitted.
+	Digest string
+}
+
+// ParseReference parses the image reference in the [REGISTRY/]REPOSITORY[:TAG][@DIGEST] format.
+// The images without a registry are on Docker Hub.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("invalid digest in image reference %q", s)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	if name == "" || ref.Tag == "" && strings.HasSuffix(s, ":") {
+		return Reference{}, fmt.Errorf("invalid image reference %q", s)
+	}
+
+	ref.Name = name
+	ref.Registry = dockerHubName
+	ref.Repository = name
+
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry, ref.Repository = host, name[i+1:]
+		}
+	}
+
+	if ref.Registry == dockerHubName || ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHubRegistry
+		if !strings.Contains(ref.Repository, "/") {
+			ref.Repository = "library/" + ref.Repository
+		}
+	}
+
+	return ref, nil
+}
+
+// Client is the client of the OCI distribution API.
+// It authenticates with the bearer tokens issued by the token endpoints of the registries, like Docker Hub and GHCR do,
+// or with the basic authentication.
+type Client struct {
+	HTTPClient *http.Client
+	// Username and Password are the credentials of the registry. The registry is accessed anonymously when empty.
+	Username string
+	Password string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewClient returns the Client authenticating with the credentials, which can be empty.
+func NewClient(username, password string) *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+		Username:   username,
+		Password:   password,
+	}
+}
+
+// Digest returns the digest of the manifest the tag of the reference points to.
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	tag := ref.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	_, digest, err := c.Manifest(ctx, ref, tag)
+
+	return digest, err
+}
+
+// Manifest returns the manifest of the tag or the digest in the repository of the reference, and its digest.
+// The digest is computed from the content, and an error is returned when it doesn't match the digest the registry claims,
+// so that a tampered response isn't trusted.
+func (c *Client) Manifest(ctx context.Context, ref Reference, tagOrDigest string) ([]byte, string, error) {
+	body, claimed, err := c.get(ctx, ref, "manifests/"+tagOrDigest, manifestMediaTypes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := sha256Digest(body)
+
+	if claimed != "" && claimed != digest {
+		return nil, "", fmt.Errorf("digest of the manifest %s of %s is %s, while the registry claims %s", tagOrDigest, ref.Name, digest, claimed)
+	}
+
+	if strings.HasPrefix(tagOrDigest, "sha256:") && tagOrDigest != digest {
+		return nil, "", fmt.Errorf("digest of the manifest %s of %s is %s", tagOrDigest, ref.Name, digest)
+	}
+
+	return body, digest, nil
+}
+
+// Blob returns the blob of the digest in the repository of the reference, verified against the digest.
+func (c *Client) Blob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	body, _, err := c.get(ctx, ref, "blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if got := sha256Digest(body); got != digest {
+		return nil, fmt.Errorf("digest of the blob %s of %s is %s", digest, ref.Name, got)
+	}
+
+	return body, nil
+}
+
+func (c *Client) get(ctx context.Context, ref Reference, path string, accept []string) ([]byte, string, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/%s", ref.Registry, ref.Repository, path)
+
+	res, err := c.do(ctx, ref, u, accept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET %s: unexpected status %s", u, res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("GET %s: %w", u, err)
+	}
+
+	if len(body) > maxBodySize {
+		return nil, "", fmt.Errorf("GET %s: response exceeds %d bytes", u, maxBodySize)
+	}
+
+	return body, res.Header.Get("Docker-Content-Digest"), nil
+}
+
+// do sends the request, authenticating and retrying once when the registry challenges it.
+func (c *Client) do(ctx context.Context, ref Reference, u string, accept []string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", ref.Repository)
+
+	c.mu.Lock()
+	token := c.tokens[ref.Registry+"/"+scope]
+	c.mu.Unlock()
+
+	res, err := c.send(ctx, u, accept, token)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	challenge := res.Header.Get("WWW-Authenticate")
+	res.Body.Close()
+
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		token, err = c.token(ctx, params, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if c.tokens == nil {
+			c.tokens = map[string]string{}
+		}
+		c.tokens[ref.Registry+"/"+scope] = token
+		c.mu.Unlock()
+
+		return c.send(ctx, u, accept, token)
+	case "basic":
+		if c.Username == "" {
+			return nil, fmt.Errorf("GET %s: the registry requires credentials", u)
+		}
+
+		return c.send(ctx, u, accept, "")
+	}
+
+	return nil, fmt.Errorf("GET %s: unsupported authentication challenge %q", u, challenge)
+}
+
+func (c *Client) send(ctx context.Context, u string, accept []string, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	return c.httpClient().Do(req)
+}
+
+// token requests the bearer token from the realm of the challenge, with the credentials if any.
+func (c *Client) token(ctx context.Context, params map[string]string, scope string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("bearer challenge without realm")
+	}
+
+	q := url.Values{}
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	if s := params["scope"]; s != "" {
+		scope = s
+	}
+	q.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: unexpected status %s", realm, res.Status)
+	}
+
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxBodySize)).Decode(&t); err != nil {
+		return "", fmt.Errorf("decoding the token from %s: %w", realm, err)
+	}
+
+	if t.Token != "" {
+		return t.Token, nil
+	}
+
+	if t.AccessToken != "" {
+		return t.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("no token issued by %s", realm)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return http.DefaultClient
+}
+
+// parseChallenge parses the WWW-Authenticate header like `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseChallenge(h string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+
+	params := map[string]string{}
+
+	for rest != "" {
+		var kv string
+		rest = strings.TrimLeft(rest, " ,")
+
+		k, v, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(v, `"`) {
+			end := strings.Index(v[1:], `"`)
+			if end < 0 {
+				break
+			}
+			kv, rest = v[1:end+1], v[end+2:]
+		} else {
+			kv, rest, _ = strings.Cut(v, ",")
+		}
+
+		params[strings.ToLower(strings.TrimSpace(k))] = kv
+	}
+
+	return scheme, params
+}
+
+// DockerConfigCredentials returns the credentials of the registry in the content of a kubernetes.io/dockerconfigjson secret.
+// Empty credentials are returned when the config has none for the registry.
+func DockerConfigCredentials(dockerConfigJSON []byte, registry string) (string, string, error) {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+
+	if err := json.Unmarshal(dockerConfigJSON, &config); err != nil {
+		return "", "", fmt.Errorf("parsing docker config: %w", err)
+	}
+
+	hosts := []string{registry}
+	if registry == dockerHubRegistry {
+		hosts = append(hosts, dockerHubName, "index.docker.io", "https://index.docker.io/v1/")
+	}
+
+	for key, a := range config.Auths {
+		host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		host, _, _ = strings.Cut(host, "/")
+
+		for _, h := range hosts {
+			if key != h && host != h {
+				continue
+			}
+
+			if a.Username != "" {
+				return a.Username, a.Password, nil
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("decoding the auth of %s: %w", key, err)
+			}
+
+			username, password, _ := strings.Cut(string(decoded), ":")
+
+			return username, password, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+func sha256Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
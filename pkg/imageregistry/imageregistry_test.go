@@ -0,0 +1,331 @@
+package imageregistry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+	"github.com/actions/actions-runner-controller/pkg/sigstore/fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want Reference
+	}{
+		{
+			in:   "summerwind/actions-runner:latest",
+			want: Reference{Name: "summerwind/actions-runner", Registry: "registry-1.docker.io", Repository: "summerwind/actions-runner", Tag: "latest"},
+		},
+		{
+			in:   "ubuntu",
+			want: Reference{Name: "ubuntu", Registry: "registry-1.docker.io", Repository: "library/ubuntu"},
+		},
+		{
+			in:   "ghcr.io/actions/actions-runner:2.320.0",
+			want: Reference{Name: "ghcr.io/actions/actions-runner", Registry: "ghcr.io", Repository: "actions/actions-runner", Tag: "2.320.0"},
+		},
+		{
+			in:   "localhost:5000/runner@sha256:abc",
+			want: Reference{Name: "localhost:5000/runner", Registry: "localhost:5000", Repository: "runner", Digest: "sha256:abc"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseReference(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := ParseReference("runner@abc")
+	require.Error(t, err)
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	config := []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("user:pass")) + `"},"ghcr.io":{"username":"bot","password":"token"}}}`)
+
+	username, password, err := DockerConfigCredentials(config, "registry-1.docker.io")
+	require.NoError(t, err)
+	require.Equal(t, "user", username)
+	require.Equal(t, "pass", password)
+
+	username, password, err = DockerConfigCredentials(config, "ghcr.io")
+	require.NoError(t, err)
+	require.Equal(t, "bot", username)
+	require.Equal(t, "token", password)
+
+	username, _, err = DockerConfigCredentials(config, "quay.io")
+	require.NoError(t, err)
+	require.Empty(t, username)
+}
+
+// testRegistry serves the manifests and the blobs of the repository "runner", behind the bearer token authentication.
+type testRegistry struct {
+	*httptest.Server
+
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	// claimed overrides the Docker-Content-Digest of the manifests, by tag.
+	claimed map[string]string
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}, claimed: map[string]string{}}
+
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			require.Equal(t, "repository:runner:pull", req.URL.Query().Get("scope"))
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if ref, ok := strings.CutPrefix(req.URL.Path, "/v2/runner/manifests/"); ok {
+			body, ok := r.manifests[ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			digest := sha256Digest(body)
+			if d, ok := r.claimed[ref]; ok {
+				digest = d
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+			_, _ = w.Write(body)
+			return
+		}
+
+		if digest, ok := strings.CutPrefix(req.URL.Path, "/v2/runner/blobs/"); ok {
+			body, ok := r.blobs[digest]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(body)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *testRegistry) reference(t *testing.T) Reference {
+	ref, err := ParseReference(strings.TrimPrefix(r.URL, "https://") + "/runner:latest")
+	require.NoError(t, err)
+	return ref
+}
+
+// signPayload returns the simple signing payload cosign signs for the digest.
+func signPayload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"runner"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+}
+
+// push stores the blob as the only layer of the manifest of the tag, with the annotations.
+func (r *testRegistry) push(t *testing.T, tag, mediaType string, blob []byte, annotations map[string]string) {
+	blobDigest := sha256Digest(blob)
+	r.blobs[blobDigest] = blob
+
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers": []map[string]any{{
+			"mediaType":   mediaType,
+			"digest":      blobDigest,
+			"size":        len(blob),
+			"annotations": annotations,
+		}},
+	})
+	require.NoError(t, err)
+
+	r.manifests[tag] = manifest
+}
+
+// sign stores the cosign signature of the digest signed with the key, like cosign sign --key does.
+func (r *testRegistry) sign(t *testing.T, key *ecdsa.PrivateKey, digest, signedDigest string) {
+	payload := signPayload(signedDigest)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	require.NoError(t, err)
+
+	r.push(t, strings.Replace(digest, ":", "-", 1)+".sig", "application/vnd.dev.cosign.simplesigning.v1+json", payload, map[string]string{
+		cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+	})
+}
+
+func keylessAnnotations(t *testing.T, signer *fake.Signer, e sigstore.LogEntry) map[string]string {
+	var bundle cosignBundle
+	bundle.SignedEntryTimestamp = e.SignedEntryTimestamp
+	bundle.Payload.Body = e.Body
+	bundle.Payload.IntegratedTime = e.IntegratedTime
+	bundle.Payload.LogIndex = e.LogIndex
+	bundle.Payload.LogID = e.LogID
+
+	b, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	return map[string]string{
+		cosignCertificateAnnotation: string(signer.Certificate),
+		cosignBundleAnnotation:      string(b),
+	}
+}
+
+// signKeyless stores the keyless cosign signature of the digest, like cosign sign does with the certificate of the signer.
+func (r *testRegistry) signKeyless(t *testing.T, signer *fake.Signer, digest string) {
+	payload := signPayload(digest)
+	sig, e, err := signer.SignHashedRekord(payload)
+	require.NoError(t, err)
+
+	annotations := keylessAnnotations(t, signer, e)
+	annotations[cosignSignatureAnnotation] = base64.StdEncoding.EncodeToString(sig)
+
+	r.push(t, strings.Replace(digest, ":", "-", 1)+".sig", "application/vnd.dev.cosign.simplesigning.v1+json", payload, annotations)
+}
+
+// attest stores the keyless cosign attestation of the predicate type about the subject, like cosign attest does.
+func (r *testRegistry) attest(t *testing.T, signer *fake.Signer, digest, subject, predicateType string) {
+	alg, hex, _ := strings.Cut(subject, ":")
+	statement, err := json.Marshal(sigstore.Statement{
+		Type:          "https://in-toto.io/Statement/v1",
+		PredicateType: predicateType,
+		Subject:       []sigstore.Subject{{Name: "runner", Digest: map[string]string{alg: hex}}},
+		Predicate:     json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	envelope, e, err := signer.SignEnvelope(sigstore.InTotoPayloadType, statement)
+	require.NoError(t, err)
+
+	blob, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	annotations := keylessAnnotations(t, signer, e)
+	annotations[cosignPredicateTypeAnnotation] = predicateType
+
+	r.push(t, strings.Replace(digest, ":", "-", 1)+".att", "application/vnd.dsse.envelope.v1+json", blob, annotations)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	r := newTestRegistry(t)
+	r.manifests["latest"] = []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	r.manifests["tampered"] = []byte(`{"schemaVersion":2}`)
+	r.claimed["tampered"] = "sha256:0000"
+
+	c := &Client{HTTPClient: r.Client()}
+	ref := r.reference(t)
+
+	digest, err := c.Digest(ctx, ref)
+	require.NoError(t, err)
+	require.Equal(t, sha256Digest(r.manifests["latest"]), digest)
+
+	ref.Tag = "tampered"
+	_, err = c.Digest(ctx, ref)
+	require.ErrorContains(t, err, "while the registry claims sha256:0000")
+
+	ref.Tag = "missing"
+	_, err = c.Digest(ctx, ref)
+	require.ErrorContains(t, err, "404")
+}
+
+func TestVerifyCosignSignature(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	r := newTestRegistry(t)
+	c := &Client{HTTPClient: r.Client()}
+	ref := r.reference(t)
+
+	signed := sha256Digest([]byte("signed"))
+	r.sign(t, key, signed, signed)
+	require.NoError(t, c.VerifyCosignSignature(ctx, ref, signed, Verifier{Key: pub}))
+
+	// Signed with another key
+	foreign := sha256Digest([]byte("foreign"))
+	r.sign(t, otherKey, foreign, foreign)
+	require.ErrorContains(t, c.VerifyCosignSignature(ctx, ref, foreign, Verifier{Key: pub}), "invalid ECDSA signature")
+
+	// The signature of another digest copied over
+	copied := sha256Digest([]byte("copied"))
+	r.sign(t, key, copied, signed)
+	require.ErrorContains(t, c.VerifyCosignSignature(ctx, ref, copied, Verifier{Key: pub}), "doesn't match")
+
+	require.ErrorContains(t, c.VerifyCosignSignature(ctx, ref, sha256Digest([]byte("unsigned")), Verifier{Key: pub}), "404")
+}
+
+func TestVerifyCosignKeyless(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		issuer   = "https://token.actions.githubusercontent.com"
+		workflow = "https://github.com/example/runner/.github/workflows/release.yml@refs/heads/main"
+		slsa     = "https://slsa.dev/provenance/v1"
+	)
+
+	s, err := fake.New()
+	require.NoError(t, err)
+	root, err := sigstore.NewTrustedRoot(s.FulcioCertificates(), s.RekorPublicKeys())
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	stranger, err := s.NewSigner(issuer, "https://github.com/stranger/runner/.github/workflows/release.yml@refs/heads/main")
+	require.NoError(t, err)
+
+	v := Verifier{Keyless: &sigstore.Verifier{TrustedRoot: root, Identities: []sigstore.Identity{{Issuer: issuer, Subject: workflow}}}}
+
+	r := newTestRegistry(t)
+	c := &Client{HTTPClient: r.Client()}
+	ref := r.reference(t)
+
+	signed := sha256Digest([]byte("signed"))
+	r.signKeyless(t, signer, signed)
+	r.attest(t, signer, signed, signed, slsa)
+	require.NoError(t, c.VerifyCosignSignature(ctx, ref, signed, v))
+	require.NoError(t, c.VerifyCosignAttestation(ctx, ref, signed, slsa, v))
+	require.ErrorContains(t, c.VerifyCosignAttestation(ctx, ref, signed, "https://spdx.dev/Document", v), "no cosign attestation")
+
+	// Signed and attested by another identity
+	foreign := sha256Digest([]byte("foreign"))
+	r.signKeyless(t, stranger, foreign)
+	r.attest(t, stranger, foreign, foreign, slsa)
+	require.ErrorContains(t, c.VerifyCosignSignature(ctx, ref, foreign, v), "matches none of the identities")
+	require.ErrorContains(t, c.VerifyCosignAttestation(ctx, ref, foreign, slsa, v), "matches none of the identities")
+
+	// The attestation of another digest copied over
+	copied := sha256Digest([]byte("copied"))
+	r.attest(t, signer, copied, signed, slsa)
+	require.ErrorContains(t, c.VerifyCosignAttestation(ctx, ref, copied, slsa, v), "about another artifact")
+}
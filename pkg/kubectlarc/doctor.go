@@ -0,0 +1,665 @@
+package kubectlarc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/actions"
+	gogithub "github.com/google/go-github/v52/github"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckStatus is the outcome of a check of the doctor.
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckWarning CheckStatus = "warning"
+	CheckError   CheckStatus = "error"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// Check is the outcome of a check along with the hint to fix it.
+type Check struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+	Hint    string      `json:"hint,omitempty"`
+}
+
+// Report is the outcome of all the checks of the doctor.
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Errors returns the number of failed checks.
+func (r *Report) Errors() int {
+	var n int
+	for _, c := range r.Checks {
+		if c.Status == CheckError {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(name string, status CheckStatus, message, hint string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: message, Hint: hint})
+}
+
+// DoctorOptions configures the checks of the installation of the legacy controller and its github webhook server.
+// The AutoscalingRunnerSets and the admission webhooks are always checked.
+type DoctorOptions struct {
+	// ControllerNamespace and ControllerSecret locate the secret holding the GitHub App or PAT of the legacy controller.
+	ControllerNamespace string
+	ControllerSecret    string
+
+	// GitHubEnterpriseURL is the URL of the GitHub Enterprise Server the legacy controller is connected to.
+	GitHubEnterpriseURL string
+
+	// WebhookURL is the public URL of the github webhook server, checked when set.
+	// WebhookSecret is the secret in ControllerNamespace holding the github_webhook_secret_token of the server.
+	WebhookURL    string
+	WebhookSecret string
+
+	// Organization or Repository, in the owner/name format, is where the webhook of WebhookURL is registered.
+	Organization string
+	Repository   string
+
+	// AllNamespaces checks the AutoscalingRunnerSets of all the namespaces instead of the namespace of the Arc.
+	AllNamespaces bool
+}
+
+const (
+	// rateLimitWarningRatio is the ratio of the remaining API calls under which the rate limit check warns.
+	rateLimitWarningRatio = 0.1
+
+	// certExpiryWarning is how long before its expiry the serving certificate of the admission webhooks is warned about.
+	certExpiryWarning = 14 * 24 * time.Hour
+
+	// servingCertsMountPath is where the controller reads the serving certificate of the admission webhooks from.
+	servingCertsMountPath = "/tmp/k8s-webhook-server/serving-certs"
+)
+
+// Doctor checks the installation for the causes of the most common issues:
+// the validity, scopes and rate limit of the GitHub credentials, the permissions of the runner groups,
+// the TLS of the admission webhooks, and the reachability and the secret of the github webhook server.
+func (a *Arc) Doctor(ctx context.Context, opts DoctorOptions) (*Report, error) {
+	report := &Report{}
+
+	gh, err := a.checkControllerCredentials(ctx, report, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkAutoscalingRunnerSets(ctx, report, opts); err != nil {
+		return nil, err
+	}
+
+	if err := a.checkAdmissionWebhooks(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if err := a.checkWebhookServer(ctx, report, gh, opts); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// WriteReport writes the report as a table, or as JSON when output is "json".
+func (a *Arc) WriteReport(report *Report, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(a.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	w := tabwriter.NewWriter(a.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tCHECK\tMESSAGE")
+	for _, c := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Status, c.Name, c.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, c := range report.Checks {
+		if c.Hint != "" && (c.Status == CheckError || c.Status == CheckWarning) {
+			fmt.Fprintf(a.Out, "\n%s: %s", c.Name, c.Hint)
+		}
+	}
+	fmt.Fprintln(a.Out)
+
+	return nil
+}
+
+// checkControllerCredentials checks the credentials of the legacy controller, and returns the client authenticated with them,
+// or nil when the legacy controller isn't installed or its credentials are invalid.
+func (a *Arc) checkControllerCredentials(ctx context.Context, report *Report, opts DoctorOptions) (*github.Client, error) {
+	const name = "GitHub credentials"
+
+	key := types.NamespacedName{Namespace: opts.ControllerNamespace, Name: opts.ControllerSecret}
+
+	var secret corev1.Secret
+	if err := a.Client.Get(ctx, key, &secret); err != nil {
+		if kerrors.IsNotFound(err) {
+			report.add(name, CheckSkipped, fmt.Sprintf("secret %s of the legacy controller not found", key), "")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get secret %s: %w", key, err)
+	}
+
+	config := github.Config{
+		EnterpriseURL: opts.GitHubEnterpriseURL,
+		Token:         string(secret.Data["github_token"]),
+		AppPrivateKey: string(secret.Data["github_app_private_key"]),
+	}
+	if config.Token == "" {
+		appID, err := strconv.ParseInt(string(secret.Data["github_app_id"]), 10, 64)
+		if err != nil {
+			report.add(name, CheckError, fmt.Sprintf("secret %s holds neither github_token nor a valid github_app_id", key),
+				"Set github_token to a PAT, or github_app_id, github_app_installation_id and github_app_private_key to the GitHub App.")
+			return nil, nil
+		}
+		installationID, err := strconv.ParseInt(string(secret.Data["github_app_installation_id"]), 10, 64)
+		if err != nil {
+			report.add(name, CheckError, fmt.Sprintf("secret %s holds no valid github_app_installation_id", key),
+				"Set github_app_installation_id to the ID of the installation of the GitHub App in the organization or enterprise.")
+			return nil, nil
+		}
+		config.AppID = appID
+		config.AppInstallationID = installationID
+	}
+
+	gh, err := config.NewClient()
+	if err != nil {
+		report.add(name, CheckError, fmt.Sprintf("invalid credentials in secret %s: %v", key, err), "")
+		return nil, nil
+	}
+
+	limits, resp, err := gh.RateLimits(ctx)
+	if err != nil {
+		report.add(name, CheckError, fmt.Sprintf("failed to authenticate with the credentials in secret %s: %v", key, err),
+			"Check that the PAT hasn't expired or been revoked, or that the GitHub App is still installed and its private key is current.")
+		return nil, nil
+	}
+
+	if config.Token != "" {
+		report.add(name, CheckOK, fmt.Sprintf("authenticated with the PAT in secret %s", key), "")
+		// Only the classic PATs are responded with the header, which is empty for a PAT without scopes.
+		checkTokenScopes(report, resp.Header.Get("X-OAuth-Scopes"), len(resp.Header.Values("X-OAuth-Scopes")) > 0)
+	} else {
+		report.add(name, CheckOK, fmt.Sprintf("authenticated as the installation %d of the GitHub App %d", config.AppInstallationID, config.AppID), "")
+	}
+
+	checkRateLimit(report, limits.GetCore())
+
+	return gh, nil
+}
+
+// checkTokenScopes checks that the classic PAT is allowed to register runners.
+// The fine-grained PATs have permissions instead of scopes, which the API doesn't list.
+func checkTokenScopes(report *Report, header string, classic bool) {
+	const name = "Token scopes"
+
+	if !classic {
+		report.add(name, CheckSkipped, "the permissions of a fine-grained PAT can't be listed", "")
+		return
+	}
+
+	scopes := map[string]bool{}
+	for _, s := range strings.Split(header, ",") {
+		scopes[strings.TrimSpace(s)] = true
+	}
+
+	var allowed []string
+	for _, s := range []string{"repo", "admin:org", "manage_runners:enterprise", "admin:enterprise"} {
+		if scopes[s] {
+			allowed = append(allowed, s)
+		}
+	}
+	if len(allowed) == 0 {
+		report.add(name, CheckError, fmt.Sprintf("the PAT has the scopes %q, which don't allow registering runners", header),
+			"Grant the repo scope for repository runners, admin:org for organization runners, or manage_runners:enterprise for enterprise runners.")
+		return
+	}
+
+	report.add(name, CheckOK, fmt.Sprintf("the PAT can register runners with the scopes %s", strings.Join(allowed, ", ")), "")
+}
+
+func checkRateLimit(report *Report, core *gogithub.Rate) {
+	const name = "Rate limit"
+
+	if core == nil || core.Limit == 0 {
+		report.add(name, CheckSkipped, "the API is not rate limited", "")
+		return
+	}
+
+	message := fmt.Sprintf("%d of %d API calls remaining until %s", core.Remaining, core.Limit, core.Reset.Format(time.RFC3339))
+	const hint = "Prefer the webhook-based autoscaling to the pull-based metrics, raise syncPeriod, or authenticate as a GitHub App for a higher rate limit."
+	switch {
+	case core.Remaining == 0:
+		report.add(name, CheckError, message, hint)
+	case float64(core.Remaining) < float64(core.Limit)*rateLimitWarningRatio:
+		report.add(name, CheckWarning, message, hint)
+	default:
+		report.add(name, CheckOK, message, "")
+	}
+}
+
+// checkAutoscalingRunnerSets checks the credentials and the runner group of every AutoscalingRunnerSet.
+func (a *Arc) checkAutoscalingRunnerSets(ctx context.Context, report *Report, opts DoctorOptions) error {
+	var listOpts []client.ListOption
+	if !opts.AllNamespaces {
+		listOpts = append(listOpts, client.InNamespace(a.Namespace))
+	}
+
+	var list githubv1alpha1.AutoscalingRunnerSetList
+	if err := a.Client.List(ctx, &list, listOpts...); err != nil {
+		if isNotInstalled(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list autoscaling runner sets: %w", err)
+	}
+
+	for _, ars := range list.Items {
+		name := fmt.Sprintf("Scale set %s/%s", ars.Namespace, ars.Name)
+		key := types.NamespacedName{Namespace: ars.Namespace, Name: ars.Spec.GitHubConfigSecret}
+
+		var secret corev1.Secret
+		if err := a.Client.Get(ctx, key, &secret); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get secret %s: %w", key, err)
+			}
+			report.add(name, CheckError, fmt.Sprintf("secret %s not found", key), "Create the secret in the namespace of the AutoscalingRunnerSet.")
+			continue
+		}
+
+		actionsClient, err := a.NewActionsClient(ctx, ars.Spec.GitHubConfigUrl, secret.Data)
+		if err != nil {
+			report.add(name, CheckError, fmt.Sprintf("invalid credentials in secret %s: %v", key, err), "")
+			continue
+		}
+
+		runnerGroup := ars.Spec.RunnerGroup
+		if runnerGroup == "" {
+			runnerGroup = defaultRunnerGroup
+		}
+		if _, err := actionsClient.GetRunnerGroupByName(ctx, runnerGroup); err != nil {
+			report.add(name, CheckError, fmt.Sprintf("failed to authenticate to %s with the credentials in secret %s: %v", ars.Spec.GitHubConfigUrl, key, err),
+				"Check that the credentials are current, that the GitHub App is installed on the organization or repository, and that the runner group exists.")
+			continue
+		}
+		report.add(name, CheckOK, fmt.Sprintf("authenticated to %s", ars.Spec.GitHubConfigUrl), "")
+
+		checkRunnerGroup(ctx, report, fmt.Sprintf("Runner group of %s/%s", ars.Namespace, ars.Name), actionsClient, ars.Spec.GitHubConfigUrl, runnerGroup)
+	}
+
+	return nil
+}
+
+// checkRunnerGroup checks that the runner group lets the repositories of the organization run their jobs on the runners.
+func checkRunnerGroup(ctx context.Context, report *Report, name string, actionsClient actions.ActionsService, githubConfigURL, runnerGroup string) {
+	config, err := actions.ParseGitHubConfigFromURL(githubConfigURL)
+	if err != nil || config.Scope != actions.GitHubScopeOrganization {
+		report.add(name, CheckSkipped, "only the runner groups of the organizations have permissions", "")
+		return
+	}
+
+	group, err := actionsClient.GetOrganizationRunnerGroupByName(ctx, runnerGroup)
+	if err != nil {
+		report.add(name, CheckWarning, fmt.Sprintf("failed to read the settings of runner group %q: %v", runnerGroup, err),
+			"Grant the credentials the admin:org scope, or the Self-hosted runners organization permission, to check the runner group.")
+		return
+	}
+	if group == nil {
+		report.add(name, CheckError, fmt.Sprintf("runner group %q does not exist in organization %s", runnerGroup, config.Organization), "Create the runner group, or fix runnerGroup.")
+		return
+	}
+
+	switch {
+	case group.RestrictedToWorkflows:
+		report.add(name, CheckWarning, fmt.Sprintf("runner group %q only runs the workflows %s", runnerGroup, strings.Join(group.SelectedWorkflows, ", ")),
+			"The jobs of the other workflows wait for a runner forever. Allow all the workflows in the settings of the runner group if that's unexpected.")
+	case group.Visibility == "selected":
+		report.add(name, CheckWarning, fmt.Sprintf("runner group %q is only available to the selected repositories", runnerGroup),
+			"The jobs of the other repositories wait for a runner forever. Add the repositories to the runner group if that's unexpected.")
+	case !group.AllowsPublicRepositories && group.Visibility == "all":
+		report.add(name, CheckOK, fmt.Sprintf("runner group %q is available to all the private repositories", runnerGroup), "")
+	default:
+		report.add(name, CheckOK, fmt.Sprintf("runner group %q is available to the %s repositories", runnerGroup, group.Visibility), "")
+	}
+}
+
+// webhookClientConfig is the client config of a mutating or validating webhook.
+type webhookClientConfig struct {
+	name   string
+	config admissionregistrationv1.WebhookClientConfig
+}
+
+// checkAdmissionWebhooks checks that the API server can call the admission webhooks of ARC:
+// the caBundle is injected, a controller pod serves the webhooks, and the CA signs its unexpired serving certificate.
+func (a *Arc) checkAdmissionWebhooks(ctx context.Context, report *Report) error {
+	var mutating admissionregistrationv1.MutatingWebhookConfigurationList
+	if err := a.Client.List(ctx, &mutating); err != nil {
+		return fmt.Errorf("failed to list mutating webhook configurations: %w", err)
+	}
+	var validating admissionregistrationv1.ValidatingWebhookConfigurationList
+	if err := a.Client.List(ctx, &validating); err != nil {
+		return fmt.Errorf("failed to list validating webhook configurations: %w", err)
+	}
+
+	configs := map[string][]webhookClientConfig{}
+	var names []string
+	addConfig := func(configName, webhookName string, config admissionregistrationv1.WebhookClientConfig) {
+		if !strings.HasSuffix(webhookName, ".actions.summerwind.dev") && !strings.HasSuffix(webhookName, ".actions.github.com") {
+			return
+		}
+		if _, ok := configs[configName]; !ok {
+			names = append(names, configName)
+		}
+		configs[configName] = append(configs[configName], webhookClientConfig{name: webhookName, config: config})
+	}
+	for _, c := range mutating.Items {
+		for _, w := range c.Webhooks {
+			addConfig("MutatingWebhookConfiguration "+c.Name, w.Name, w.ClientConfig)
+		}
+	}
+	for _, c := range validating.Items {
+		for _, w := range c.Webhooks {
+			addConfig("ValidatingWebhookConfiguration "+c.Name, w.Name, w.ClientConfig)
+		}
+	}
+
+	for _, name := range names {
+		status, message, hint, err := a.checkWebhookClientConfigs(ctx, configs[name])
+		if err != nil {
+			return err
+		}
+		report.add(name, status, message, hint)
+	}
+
+	return nil
+}
+
+func (a *Arc) checkWebhookClientConfigs(ctx context.Context, webhooks []webhookClientConfig) (CheckStatus, string, string, error) {
+	var svcRef *admissionregistrationv1.ServiceReference
+	var caBundle []byte
+	for _, w := range webhooks {
+		if len(w.config.CABundle) == 0 {
+			return CheckError, fmt.Sprintf("webhook %s has no caBundle", w.name),
+				"Check that cert-manager and its cainjector are running, and that the Certificate of the webhook server is Ready.", nil
+		}
+		if w.config.Service != nil {
+			svcRef = w.config.Service
+			caBundle = w.config.CABundle
+		}
+	}
+	if svcRef == nil {
+		return CheckSkipped, "the webhooks are not served by a service of the cluster", "", nil
+	}
+
+	svcKey := types.NamespacedName{Namespace: svcRef.Namespace, Name: svcRef.Name}
+
+	var svc corev1.Service
+	if err := a.Client.Get(ctx, svcKey, &svc); err != nil {
+		if kerrors.IsNotFound(err) {
+			return CheckError, fmt.Sprintf("service %s of the webhooks not found", svcKey), "Reinstall the chart of the controller.", nil
+		}
+		return "", "", "", fmt.Errorf("failed to get service %s: %w", svcKey, err)
+	}
+
+	var endpoints corev1.Endpoints
+	if err := a.Client.Get(ctx, svcKey, &endpoints); err != nil && !kerrors.IsNotFound(err) {
+		return "", "", "", fmt.Errorf("failed to get endpoints %s: %w", svcKey, err)
+	}
+	var ready int
+	for _, s := range endpoints.Subsets {
+		ready += len(s.Addresses)
+	}
+	if ready == 0 {
+		return CheckError, fmt.Sprintf("no ready pod serves the webhooks of service %s", svcKey),
+			"The resources the webhooks fail closed for can't be created until the controller is ready. Check the pods of the controller.", nil
+	}
+
+	secretName, err := a.servingCertSecret(ctx, &svc)
+	if err != nil {
+		return "", "", "", err
+	}
+	if secretName == "" {
+		return CheckOK, fmt.Sprintf("%d ready pods serve the webhooks of service %s", ready, svcKey), "", nil
+	}
+
+	certKey := types.NamespacedName{Namespace: svc.Namespace, Name: secretName}
+	var secret corev1.Secret
+	if err := a.Client.Get(ctx, certKey, &secret); err != nil {
+		if kerrors.IsNotFound(err) {
+			return CheckError, fmt.Sprintf("serving certificate secret %s not found", certKey),
+				"Check that the Certificate of the webhook server is Ready in cert-manager.", nil
+		}
+		return "", "", "", fmt.Errorf("failed to get secret %s: %w", certKey, err)
+	}
+
+	cert, err := parseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return CheckError, fmt.Sprintf("invalid serving certificate in secret %s: %v", certKey, err), "", nil
+	}
+
+	now := time.Now()
+	if now.After(cert.NotAfter) {
+		return CheckError, fmt.Sprintf("serving certificate in secret %s expired at %s", certKey, cert.NotAfter.Format(time.RFC3339)),
+			"Check that cert-manager renews the Certificate of the webhook server, and restart the controller.", nil
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		return CheckError, "the caBundle of the webhooks holds no valid certificate", "", nil
+	}
+	dnsName := fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: dnsName, CurrentTime: now}); err != nil {
+		return CheckError, fmt.Sprintf("the caBundle of the webhooks doesn't verify the serving certificate in secret %s: %v", certKey, err),
+			"Check that cert-manager's cainjector is running, so that the caBundle is updated when the certificate is renewed.", nil
+	}
+
+	if cert.NotAfter.Sub(now) < certExpiryWarning {
+		return CheckWarning, fmt.Sprintf("serving certificate in secret %s expires at %s", certKey, cert.NotAfter.Format(time.RFC3339)),
+			"Check that cert-manager renews the Certificate of the webhook server.", nil
+	}
+
+	return CheckOK, fmt.Sprintf("%d ready pods serve the webhooks of service %s with a certificate valid until %s", ready, svcKey, cert.NotAfter.Format(time.RFC3339)), "", nil
+}
+
+// servingCertSecret returns the secret mounted as the serving certificate of the admission webhooks
+// by the deployment selected by the service, or an empty string when there's none.
+func (a *Arc) servingCertSecret(ctx context.Context, svc *corev1.Service) (string, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return "", nil
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := a.Client.List(ctx, &deployments, client.InNamespace(svc.Namespace)); err != nil {
+		return "", fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	for _, d := range deployments.Items {
+		if !selector.Matches(labels.Set(d.Spec.Template.Labels)) {
+			continue
+		}
+		for _, c := range d.Spec.Template.Spec.Containers {
+			for _, m := range c.VolumeMounts {
+				if m.MountPath != servingCertsMountPath {
+					continue
+				}
+				for _, v := range d.Spec.Template.Spec.Volumes {
+					if v.Name == m.Name && v.Secret != nil {
+						return v.Secret.SecretName, nil
+					}
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// checkWebhookServer checks that the github webhook server is reachable at the public URL and accepts the deliveries
+// signed with its secret, and that GitHub delivers the events to the URL successfully.
+func (a *Arc) checkWebhookServer(ctx context.Context, report *Report, gh *github.Client, opts DoctorOptions) error {
+	const name = "Webhook server"
+
+	if opts.WebhookURL == "" {
+		report.add(name, CheckSkipped, "no webhook URL to check", "")
+		return nil
+	}
+
+	var secretToken []byte
+	if opts.WebhookSecret != "" {
+		key := types.NamespacedName{Namespace: opts.ControllerNamespace, Name: opts.WebhookSecret}
+
+		var secret corev1.Secret
+		if err := a.Client.Get(ctx, key, &secret); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get secret %s: %w", key, err)
+			}
+			report.add(name, CheckError, fmt.Sprintf("secret %s not found", key), "")
+			return nil
+		}
+		secretToken = secret.Data["github_webhook_secret_token"]
+	}
+
+	status, message, hint := pingWebhookServer(ctx, opts.WebhookURL, secretToken)
+	report.add(name, status, message, hint)
+
+	if opts.Organization == "" && opts.Repository == "" {
+		return nil
+	}
+	if gh == nil {
+		report.add("GitHub webhook", CheckSkipped, "no valid GitHub credentials to read the webhooks with", "")
+		return nil
+	}
+
+	var hooks []*gogithub.Hook
+	var err error
+	if opts.Repository != "" {
+		owner, repo, ok := strings.Cut(opts.Repository, "/")
+		if !ok {
+			return fmt.Errorf("invalid repository %q: expected OWNER/NAME", opts.Repository)
+		}
+		hooks, _, err = gh.Repositories.ListHooks(ctx, owner, repo, &gogithub.ListOptions{PerPage: 100})
+	} else {
+		hooks, _, err = gh.Organizations.ListHooks(ctx, opts.Organization, &gogithub.ListOptions{PerPage: 100})
+	}
+	if err != nil {
+		report.add("GitHub webhook", CheckWarning, fmt.Sprintf("failed to list the webhooks: %v", err),
+			"Grant the credentials the admin:repo_hook or admin:org_hook scope, or the Webhooks permission, to check the webhook.")
+		return nil
+	}
+
+	report.add(checkHooks(hooks, opts.WebhookURL))
+
+	return nil
+}
+
+// pingWebhookServer sends a ping event signed with secretToken, which the server responds to with 200 only when its secret matches.
+func pingWebhookServer(ctx context.Context, webhookURL string, secretToken []byte) (CheckStatus, string, string) {
+	body := []byte(`{"zen":"Keep it logically awesome.","hook_id":0}`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return CheckError, fmt.Sprintf("invalid webhook URL %q: %v", webhookURL, err), ""
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-GitHub-Delivery", "kubectl-arc-doctor")
+	if len(secretToken) > 0 {
+		mac := hmac.New(sha256.New, secretToken)
+		mac.Write(body)
+		req.Header.Set(gogithub.SHA256SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return CheckError, fmt.Sprintf("failed to reach %s: %v", webhookURL, err),
+			"Check the Ingress or Service exposing the github webhook server, and that GitHub can reach it."
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusOK {
+		if len(secretToken) == 0 {
+			return CheckOK, fmt.Sprintf("%s is reachable", webhookURL), ""
+		}
+		return CheckOK, fmt.Sprintf("%s is reachable and its secret matches", webhookURL), ""
+	}
+
+	msg := strings.TrimSpace(string(respBody))
+	if strings.Contains(msg, "signature") || strings.Contains(msg, "secret") {
+		return CheckError, fmt.Sprintf("%s rejected the ping signed with the secret: %s", webhookURL, msg),
+			"Set the same secret to the github_webhook_secret_token of the server and to the webhook in GitHub."
+	}
+
+	return CheckError, fmt.Sprintf("%s responded to a ping with %d: %s", webhookURL, resp.StatusCode, msg),
+		"Check that the URL routes to the github webhook server."
+}
+
+// checkHooks checks that the hook of webhookURL is active and its last delivery succeeded.
+func checkHooks(hooks []*gogithub.Hook, webhookURL string) (string, CheckStatus, string, string) {
+	const name = "GitHub webhook"
+
+	for _, h := range hooks {
+		if u, _ := h.Config["url"].(string); strings.TrimRight(u, "/") != strings.TrimRight(webhookURL, "/") {
+			continue
+		}
+
+		if !h.GetActive() {
+			return name, CheckError, fmt.Sprintf("webhook %d of %s is inactive", h.GetID(), webhookURL), "Activate the webhook in GitHub."
+		}
+
+		code := fmt.Sprint(h.LastResponse["code"])
+		message := fmt.Sprint(h.LastResponse["message"])
+		switch code {
+		case "200", "<nil>":
+			return name, CheckOK, fmt.Sprintf("webhook %d of %s is active", h.GetID(), webhookURL), ""
+		case "500":
+			return name, CheckError, fmt.Sprintf("the last delivery of webhook %d failed with %s: %s", h.GetID(), code, message),
+				"When the server accepts the ping of the doctor, the secret of the webhook in GitHub differs from the github_webhook_secret_token of the server."
+		default:
+			return name, CheckWarning, fmt.Sprintf("the last delivery of webhook %d responded with %s: %s", h.GetID(), code, message), ""
+		}
+	}
+
+	return name, CheckError, fmt.Sprintf("no webhook delivers to %s", webhookURL), "Create the webhook in GitHub, sending the Workflow jobs events."
+}
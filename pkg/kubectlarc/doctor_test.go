@@ -0,0 +1,384 @@
+package kubectlarc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/github/actions"
+	gogithub "github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func findCheck(t *testing.T, report *Report, name string) Check {
+	t.Helper()
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no check %q in %+v", name, report.Checks)
+	return Check{}
+}
+
+func TestCheckTokenScopes(t *testing.T) {
+	tests := []struct {
+		header  string
+		classic bool
+		want    CheckStatus
+	}{
+		{header: "repo, workflow", classic: true, want: CheckOK},
+		{header: "admin:org", classic: true, want: CheckOK},
+		{header: "manage_runners:enterprise", classic: true, want: CheckOK},
+		{header: "read:org, workflow", classic: true, want: CheckError},
+		{header: "", classic: true, want: CheckError},
+		{header: "", classic: false, want: CheckSkipped},
+	}
+
+	for _, tt := range tests {
+		report := &Report{}
+		checkTokenScopes(report, tt.header, tt.classic)
+		assert.Equal(t, tt.want, findCheck(t, report, "Token scopes").Status, "scopes %q", tt.header)
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	tests := []struct {
+		remaining int
+		want      CheckStatus
+	}{
+		{remaining: 4000, want: CheckOK},
+		{remaining: 100, want: CheckWarning},
+		{remaining: 0, want: CheckError},
+	}
+
+	for _, tt := range tests {
+		report := &Report{}
+		checkRateLimit(report, &gogithub.Rate{Limit: 5000, Remaining: tt.remaining})
+		assert.Equal(t, tt.want, findCheck(t, report, "Rate limit").Status, "remaining %d", tt.remaining)
+	}
+}
+
+func TestControllerCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/rate_limit" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Bad credentials"}`)
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo")
+		fmt.Fprint(w, `{"resources":{"core":{"limit":5000,"remaining":200,"reset":1700000000}}}`)
+	}))
+	defer server.Close()
+
+	newSecret := func(token string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "controller-manager"},
+			Data:       map[string][]byte{"github_token": []byte(token)},
+		}
+	}
+	opts := DoctorOptions{ControllerNamespace: "actions-runner-system", ControllerSecret: "controller-manager", GitHubEnterpriseURL: server.URL}
+
+	t.Run("valid token", func(t *testing.T) {
+		a, _ := newArc(t, newSecret("valid"))
+
+		report, err := a.Doctor(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, CheckOK, findCheck(t, report, "GitHub credentials").Status)
+		assert.Equal(t, CheckOK, findCheck(t, report, "Token scopes").Status)
+		assert.Equal(t, CheckWarning, findCheck(t, report, "Rate limit").Status)
+		assert.Equal(t, CheckSkipped, findCheck(t, report, "Webhook server").Status)
+		assert.Equal(t, 0, report.Errors())
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		a, _ := newArc(t, newSecret("revoked"))
+
+		report, err := a.Doctor(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, CheckError, findCheck(t, report, "GitHub credentials").Status)
+		assert.Equal(t, 1, report.Errors())
+	})
+
+	t.Run("legacy controller not installed", func(t *testing.T) {
+		a, _ := newArc(t)
+
+		report, err := a.Doctor(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, CheckSkipped, findCheck(t, report, "GitHub credentials").Status)
+	})
+}
+
+func TestCheckAutoscalingRunnerSets(t *testing.T) {
+	a, _ := newArc(t,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "github-config"},
+			Data:       map[string][]byte{"github_token": []byte("token")},
+		},
+		&githubv1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "arc-runner-set"},
+			Spec: githubv1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "github-config",
+				RunnerGroup:        "gpu",
+			},
+		},
+		&githubv1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "no-secret"},
+			Spec: githubv1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "missing",
+			},
+		},
+	)
+
+	actionsClient := actions.NewMockActionsService(t)
+	actionsClient.On("GetRunnerGroupByName", mock.Anything, "gpu").Return(&actions.RunnerGroup{ID: 2, Name: "gpu"}, nil)
+	actionsClient.On("GetOrganizationRunnerGroupByName", mock.Anything, "gpu").Return(&actions.OrganizationRunnerGroup{ID: 2, Name: "gpu", Visibility: "selected"}, nil)
+	a.NewActionsClient = func(context.Context, string, actions.KubernetesSecretData) (actions.ActionsService, error) {
+		return actionsClient, nil
+	}
+
+	report := &Report{}
+	require.NoError(t, a.checkAutoscalingRunnerSets(context.Background(), report, DoctorOptions{}))
+
+	assert.Equal(t, CheckOK, findCheck(t, report, "Scale set default/arc-runner-set").Status)
+	runnerGroup := findCheck(t, report, "Runner group of default/arc-runner-set")
+	assert.Equal(t, CheckWarning, runnerGroup.Status)
+	assert.Contains(t, runnerGroup.Message, "only available to the selected repositories")
+	noSecret := findCheck(t, report, "Scale set default/no-secret")
+	assert.Equal(t, CheckError, noSecret.Status)
+	assert.Equal(t, "secret default/missing not found", noSecret.Message)
+}
+
+// newCertificate returns the PEM encoded certificate and key for dnsName, signed by the parent, or self-signed when parent is nil.
+func newCertificate(t *testing.T, dnsName string, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		template.DNSNames = []string{dnsName}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, key
+}
+
+func TestCheckAdmissionWebhooks(t *testing.T) {
+	caPEM, ca, caKey := newCertificate(t, "ca", time.Now().Add(365*24*time.Hour), nil, nil)
+	otherCAPEM, _, _ := newCertificate(t, "other-ca", time.Now().Add(365*24*time.Hour), nil, nil)
+
+	newObjects := func(caBundle []byte, servingCert []byte, readyPods int) []client.Object {
+		var addresses []corev1.EndpointAddress
+		for i := 0; i < readyPods; i++ {
+			addresses = append(addresses, corev1.EndpointAddress{IP: fmt.Sprintf("10.0.0.%d", i+1)})
+		}
+		selector := map[string]string{"app.kubernetes.io/name": "actions-runner-controller"}
+
+		return []client.Object{
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "arc-validating-webhook-configuration"},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: "validate.runner.actions.summerwind.dev",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service:  &admissionregistrationv1.ServiceReference{Namespace: "actions-runner-system", Name: "arc-webhook"},
+						CABundle: caBundle,
+					},
+				}},
+			},
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "unrelated"},
+				Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "validate.example.com"}},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "arc-webhook"},
+				Spec:       corev1.ServiceSpec{Selector: selector},
+			},
+			&corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "arc-webhook"},
+				Subsets:    []corev1.EndpointSubset{{Addresses: addresses}},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "arc"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: selector},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name:         "manager",
+								VolumeMounts: []corev1.VolumeMount{{Name: "cert", MountPath: servingCertsMountPath}},
+							}},
+							Volumes: []corev1.Volume{{
+								Name:         "cert",
+								VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "arc-serving-cert"}},
+							}},
+						},
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "actions-runner-system", Name: "arc-serving-cert"},
+				Data:       map[string][]byte{corev1.TLSCertKey: servingCert},
+			},
+		}
+	}
+
+	const name = "ValidatingWebhookConfiguration arc-validating-webhook-configuration"
+	dnsName := "arc-webhook.actions-runner-system.svc"
+
+	t.Run("healthy", func(t *testing.T) {
+		servingCert, _, _ := newCertificate(t, dnsName, time.Now().Add(90*24*time.Hour), ca, caKey)
+		a, _ := newArc(t, newObjects(caPEM, servingCert, 2)...)
+
+		report := &Report{}
+		require.NoError(t, a.checkAdmissionWebhooks(context.Background(), report))
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, CheckOK, findCheck(t, report, name).Status)
+	})
+
+	tests := map[string]struct {
+		caBundle    []byte
+		notAfter    time.Time
+		readyPods   int
+		wantStatus  CheckStatus
+		wantMessage string
+	}{
+		"no caBundle": {
+			notAfter: time.Now().Add(90 * 24 * time.Hour), readyPods: 1,
+			wantStatus: CheckError, wantMessage: "has no caBundle",
+		},
+		"no ready pod": {
+			caBundle: caPEM, notAfter: time.Now().Add(90 * 24 * time.Hour),
+			wantStatus: CheckError, wantMessage: "no ready pod serves the webhooks",
+		},
+		"stale caBundle": {
+			caBundle: otherCAPEM, notAfter: time.Now().Add(90 * 24 * time.Hour), readyPods: 1,
+			wantStatus: CheckError, wantMessage: "doesn't verify the serving certificate",
+		},
+		"expiring certificate": {
+			caBundle: caPEM, notAfter: time.Now().Add(24 * time.Hour), readyPods: 1,
+			wantStatus: CheckWarning, wantMessage: "expires at",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			servingCert, _, _ := newCertificate(t, dnsName, tt.notAfter, ca, caKey)
+			a, _ := newArc(t, newObjects(tt.caBundle, servingCert, tt.readyPods)...)
+
+			report := &Report{}
+			require.NoError(t, a.checkAdmissionWebhooks(context.Background(), report))
+			require.Len(t, report.Checks, 1)
+			assert.Equal(t, tt.wantStatus, report.Checks[0].Status)
+			assert.Contains(t, report.Checks[0].Message, tt.wantMessage)
+		})
+	}
+}
+
+func TestPingWebhookServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := github.ValidateWebhookPayload(r, [][]byte{[]byte("secret")}); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+		fmt.Fprint(w, "pong")
+	}))
+	defer server.Close()
+
+	status, message, _ := pingWebhookServer(context.Background(), server.URL, []byte("secret"))
+	assert.Equal(t, CheckOK, status)
+	assert.Contains(t, message, "its secret matches")
+
+	status, message, _ = pingWebhookServer(context.Background(), server.URL, []byte("other"))
+	assert.Equal(t, CheckError, status)
+	assert.Contains(t, message, "rejected the ping signed with the secret")
+
+	status, _, _ = pingWebhookServer(context.Background(), "http://127.0.0.1:1", []byte("secret"))
+	assert.Equal(t, CheckError, status)
+}
+
+func TestCheckHooks(t *testing.T) {
+	const webhookURL = "https://arc.example.com/"
+
+	hook := func(url string, active bool, code int) *gogithub.Hook {
+		return &gogithub.Hook{
+			ID:           gogithub.Int64(1),
+			Active:       gogithub.Bool(active),
+			Config:       map[string]interface{}{"url": url},
+			LastResponse: map[string]interface{}{"code": float64(code), "message": "Invalid HTTP Response: 500"},
+		}
+	}
+
+	_, status, _, _ := checkHooks([]*gogithub.Hook{hook("https://arc.example.com", true, 200)}, webhookURL)
+	assert.Equal(t, CheckOK, status)
+
+	_, status, _, _ = checkHooks([]*gogithub.Hook{hook(webhookURL, false, 200)}, webhookURL)
+	assert.Equal(t, CheckError, status)
+
+	_, status, _, hint := checkHooks([]*gogithub.Hook{hook(webhookURL, true, 500)}, webhookURL)
+	assert.Equal(t, CheckError, status)
+	assert.Contains(t, hint, "the secret of the webhook in GitHub differs")
+
+	_, status, message, _ := checkHooks([]*gogithub.Hook{hook("https://other.example.com", true, 200)}, webhookURL)
+	assert.Equal(t, CheckError, status)
+	assert.Equal(t, "no webhook delivers to https://arc.example.com/", message)
+}
+
+func TestWriteReport(t *testing.T) {
+	report := &Report{}
+	report.add("Rate limit", CheckWarning, "100 of 5000 API calls remaining", "Prefer the webhook-based autoscaling.")
+	report.add("Token scopes", CheckOK, "the PAT can register runners with the scopes repo", "")
+
+	var out bytes.Buffer
+	a := &Arc{Out: &out}
+
+	require.NoError(t, a.WriteReport(report, "text"))
+	assert.Contains(t, out.String(), "warning  Rate limit    100 of 5000 API calls remaining\n")
+	assert.Contains(t, out.String(), "\nRate limit: Prefer the webhook-based autoscaling.\n")
+
+	out.Reset()
+	require.NoError(t, a.WriteReport(report, "json"))
+	assert.Contains(t, out.String(), `"status": "warning"`)
+	assert.Contains(t, out.String(), `"hint": "Prefer the webhook-based autoscaling."`)
+}
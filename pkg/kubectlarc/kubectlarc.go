@@ -0,0 +1,541 @@
+// Package kubectlarc implements the operations of the kubectl-arc plugin
+// on the runners and autoscalers of actions-runner-controller.
+package kubectlarc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
+	"github.com/actions/actions-runner-controller/github/actions"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReservationName is the name of the capacity reservation added by Scale
+// to the HorizontalRunnerAutoscaler of the scaled RunnerDeployment.
+const ReservationName = "kubectl-arc"
+
+// defaultRunnerGroup is the runner group the runner scale sets are added to when no runner group is set.
+const defaultRunnerGroup = "Default"
+
+// Arc runs the operations in Namespace.
+type Arc struct {
+	Client client.Client
+
+	// Clientset streams the logs of the runner pods, which the controller-runtime client can't.
+	Clientset kubernetes.Interface
+
+	Namespace string
+	Out       io.Writer
+
+	// NewActionsClient returns the client authenticated with the GitHub App or PAT of the secret data.
+	NewActionsClient func(ctx context.Context, githubConfigURL string, secretData actions.KubernetesSecretData) (actions.ActionsService, error)
+}
+
+// runner is either a Runner of a RunnerDeployment or an EphemeralRunner of an AutoscalingRunnerSet.
+type runner struct {
+	namespace string
+	name      string
+	kind      string
+	status    string
+	busy      bool
+	job       string
+}
+
+// ListRunners writes the runners of the namespace, or of all the namespaces, along with the job they are running.
+func (a *Arc) ListRunners(ctx context.Context, allNamespaces bool) error {
+	var opts []client.ListOption
+	if !allNamespaces {
+		opts = append(opts, client.InNamespace(a.Namespace))
+	}
+
+	var runners summerwindv1alpha1.RunnerList
+	if err := a.Client.List(ctx, &runners, opts...); err != nil && !isNotInstalled(err) {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+
+	var ephemeralRunners githubv1alpha1.EphemeralRunnerList
+	if err := a.Client.List(ctx, &ephemeralRunners, opts...); err != nil && !isNotInstalled(err) {
+		return fmt.Errorf("failed to list ephemeral runners: %w", err)
+	}
+
+	var rs []runner
+	for _, r := range runners.Items {
+		rs = append(rs, runner{
+			namespace: r.Namespace,
+			name:      r.Name,
+			kind:      "Runner",
+			status:    r.Status.Phase,
+			busy:      r.Annotations[actionssummerwindnet.AnnotationKeyRunnerBusy] == "true",
+			job:       r.Annotations[actionssummerwindnet.AnnotationKeyRunnerJobURL],
+		})
+	}
+	for _, er := range ephemeralRunners.Items {
+		r := runner{
+			namespace: er.Namespace,
+			name:      er.Name,
+			kind:      "EphemeralRunner",
+			status:    string(er.Status.Phase),
+			busy:      isBusy(&er),
+		}
+		if r.busy {
+			r.job = fmt.Sprintf("%s %q (run %d)", er.Status.JobRepositoryName, er.Status.JobDisplayName, er.Status.WorkflowRunId)
+		}
+		rs = append(rs, r)
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		if rs[i].namespace != rs[j].namespace {
+			return rs[i].namespace < rs[j].namespace
+		}
+		return rs[i].name < rs[j].name
+	})
+
+	w := tabwriter.NewWriter(a.Out, 0, 8, 2, ' ', 0)
+	if allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tKIND\tSTATUS\tBUSY\tJOB")
+	for _, r := range rs {
+		if allNamespaces {
+			fmt.Fprintf(w, "%s\t", r.namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", r.name, r.kind, r.status, r.busy, r.job)
+	}
+
+	return w.Flush()
+}
+
+// DescribeAutoscaler writes the spec and the last decisions of the HorizontalRunnerAutoscaler
+// or the AutoscalingRunnerSet named name.
+func (a *Arc) DescribeAutoscaler(ctx context.Context, name string) error {
+	key := types.NamespacedName{Namespace: a.Namespace, Name: name}
+
+	var hra summerwindv1alpha1.HorizontalRunnerAutoscaler
+	err := a.Client.Get(ctx, key, &hra)
+	if err == nil {
+		return a.describeHorizontalRunnerAutoscaler(&hra)
+	}
+	if !kerrors.IsNotFound(err) && !isNotInstalled(err) {
+		return fmt.Errorf("failed to get horizontal runner autoscaler %s: %w", key, err)
+	}
+
+	var ars githubv1alpha1.AutoscalingRunnerSet
+	err = a.Client.Get(ctx, key, &ars)
+	if err == nil {
+		return a.describeAutoscalingRunnerSet(&ars)
+	}
+	if !kerrors.IsNotFound(err) && !isNotInstalled(err) {
+		return fmt.Errorf("failed to get autoscaling runner set %s: %w", key, err)
+	}
+
+	return fmt.Errorf("no HorizontalRunnerAutoscaler or AutoscalingRunnerSet named %q in namespace %q", name, a.Namespace)
+}
+
+func (a *Arc) describeHorizontalRunnerAutoscaler(hra *summerwindv1alpha1.HorizontalRunnerAutoscaler) error {
+	now := time.Now()
+
+	w := tabwriter.NewWriter(a.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", hra.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", hra.Namespace)
+	kind := hra.Spec.ScaleTargetRef.Kind
+	if kind == "" {
+		kind = "RunnerDeployment"
+	}
+	fmt.Fprintf(w, "Scale target:\t%s/%s\n", kind, hra.Spec.ScaleTargetRef.Name)
+	fmt.Fprintf(w, "Min replicas:\t%s\n", formatInt(hra.Spec.MinReplicas))
+	fmt.Fprintf(w, "Max replicas:\t%s\n", formatInt(hra.Spec.MaxReplicas))
+	fmt.Fprintf(w, "Desired replicas:\t%s\n", formatInt(hra.Status.DesiredReplicas))
+	fmt.Fprintf(w, "Last scale out:\t%s\n", formatTime(hra.Status.LastSuccessfulScaleOutTime))
+
+	if hra.Status.ScheduledOverridesSummary != nil {
+		fmt.Fprintf(w, "Scheduled overrides:\t%s\n", *hra.Status.ScheduledOverridesSummary)
+	}
+	if o := hra.Status.ActiveScheduledOverride; o != nil {
+		fmt.Fprintf(w, "Active scheduled override:\t%s, min replicas %s until %s\n", o.Name, formatInt(o.MinReplicas), o.EndTime.Format(time.RFC3339))
+	}
+	if b := hra.Status.Budget; b != nil {
+		fmt.Fprintf(w, "Budget spent on %s:\t%s\n", b.Date, b.Spent)
+		if b.WithheldReplicas > 0 {
+			fmt.Fprintf(w, "Replicas withheld by the budget:\t%d (%s limit)\n", b.WithheldReplicas, b.LimitedBy)
+		}
+	}
+
+	if len(hra.Spec.Metrics) > 0 {
+		fmt.Fprintln(w, "Metrics:")
+		for _, m := range hra.Spec.Metrics {
+			fmt.Fprintf(w, "  %s\t%s\n", m.Type, strings.Join(m.RepositoryNames, ","))
+		}
+	}
+
+	if len(hra.Spec.ScaleUpTriggers) > 0 {
+		fmt.Fprintln(w, "Scale up triggers:")
+		for _, t := range hra.Spec.ScaleUpTriggers {
+			fmt.Fprintf(w, "  %s\tamount %d for %s\n", gitHubEvent(t.GitHubEvent), t.Amount, t.Duration.Duration)
+		}
+	}
+
+	if len(hra.Spec.CapacityReservations) > 0 {
+		fmt.Fprintln(w, "Capacity reservations:")
+		for _, r := range hra.Spec.CapacityReservations {
+			state := "active"
+			if !r.ExpirationTime.Time.After(now) {
+				state = "expired"
+			}
+			fmt.Fprintf(w, "  %s\t%d replicas until %s (%s)\n", r.Name, r.Replicas, r.ExpirationTime.Format(time.RFC3339), state)
+		}
+	}
+
+	if len(hra.Status.CacheEntries) > 0 {
+		fmt.Fprintln(w, "Cached decisions:")
+		for _, e := range hra.Status.CacheEntries {
+			fmt.Fprintf(w, "  %s\t%d until %s\n", e.Key, e.Value, e.ExpirationTime.Format(time.RFC3339))
+		}
+	}
+
+	return w.Flush()
+}
+
+func (a *Arc) describeAutoscalingRunnerSet(ars *githubv1alpha1.AutoscalingRunnerSet) error {
+	w := tabwriter.NewWriter(a.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", ars.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", ars.Namespace)
+	fmt.Fprintf(w, "GitHub config URL:\t%s\n", ars.Spec.GitHubConfigUrl)
+	runnerScaleSetName := ars.Spec.RunnerScaleSetName
+	if runnerScaleSetName == "" {
+		runnerScaleSetName = ars.Name
+	}
+	fmt.Fprintf(w, "Runner scale set:\t%s\n", runnerScaleSetName)
+	runnerGroup := ars.Spec.RunnerGroup
+	if runnerGroup == "" {
+		runnerGroup = defaultRunnerGroup
+	}
+	fmt.Fprintf(w, "Runner group:\t%s\n", runnerGroup)
+	fmt.Fprintf(w, "Min runners:\t%s\n", formatInt(ars.Spec.MinRunners))
+	fmt.Fprintf(w, "Max runners:\t%s\n", formatInt(ars.Spec.MaxRunners))
+	fmt.Fprintf(w, "Current runners:\t%d\n", ars.Status.CurrentRunners)
+	fmt.Fprintf(w, "Pending runners:\t%d\n", ars.Status.PendingEphemeralRunners)
+	fmt.Fprintf(w, "Running runners:\t%d\n", ars.Status.RunningEphemeralRunners)
+	fmt.Fprintf(w, "Failed runners:\t%d\n", ars.Status.FailedEphemeralRunners)
+	if ars.Status.State != "" {
+		fmt.Fprintf(w, "State:\t%s\n", ars.Status.State)
+	}
+
+	return w.Flush()
+}
+
+// Drain removes the runner named name.
+// A Runner is unregistered from GitHub by the controller once its job, if any, completes, and then replaced.
+// An EphemeralRunner is deleted along with its pod right away, so a busy EphemeralRunner is only drained when force is set.
+func (a *Arc) Drain(ctx context.Context, name string, force bool) error {
+	key := types.NamespacedName{Namespace: a.Namespace, Name: name}
+
+	var r summerwindv1alpha1.Runner
+	err := a.Client.Get(ctx, key, &r)
+	if err == nil {
+		if !r.DeletionTimestamp.IsZero() {
+			fmt.Fprintf(a.Out, "runner/%s is already being drained\n", name)
+			return nil
+		}
+		if err := a.Client.Delete(ctx, &r); err != nil {
+			return fmt.Errorf("failed to delete runner %s: %w", key, err)
+		}
+		fmt.Fprintf(a.Out, "runner/%s drained. It is unregistered and replaced once its running job, if any, completes\n", name)
+		return nil
+	}
+	if !kerrors.IsNotFound(err) && !isNotInstalled(err) {
+		return fmt.Errorf("failed to get runner %s: %w", key, err)
+	}
+
+	var er githubv1alpha1.EphemeralRunner
+	err = a.Client.Get(ctx, key, &er)
+	if err != nil {
+		if kerrors.IsNotFound(err) || isNotInstalled(err) {
+			return fmt.Errorf("no Runner or EphemeralRunner named %q in namespace %q", name, a.Namespace)
+		}
+		return fmt.Errorf("failed to get ephemeral runner %s: %w", key, err)
+	}
+
+	if isBusy(&er) && !force {
+		return fmt.Errorf("ephemeral runner %s is running the job %q of %s, which would be cancelled along with its pod. Wait for the job to complete, or force the drain", name, er.Status.JobDisplayName, er.Status.JobRepositoryName)
+	}
+	if err := a.Client.Delete(ctx, &er); err != nil {
+		return fmt.Errorf("failed to delete ephemeral runner %s: %w", key, err)
+	}
+	fmt.Fprintf(a.Out, "ephemeralrunner/%s drained\n", name)
+
+	return nil
+}
+
+// Scale sets the replicas of the RunnerDeployment named name.
+// The RunnerDeployment scaled by a HorizontalRunnerAutoscaler is scaled up by reserving the missing replicas
+// in the HorizontalRunnerAutoscaler for duration, as the HorizontalRunnerAutoscaler would revert its replicas otherwise.
+func (a *Arc) Scale(ctx context.Context, name string, replicas int, duration time.Duration) error {
+	key := types.NamespacedName{Namespace: a.Namespace, Name: name}
+
+	var rd summerwindv1alpha1.RunnerDeployment
+	if err := a.Client.Get(ctx, key, &rd); err != nil {
+		return fmt.Errorf("failed to get runner deployment %s: %w", key, err)
+	}
+
+	var hras summerwindv1alpha1.HorizontalRunnerAutoscalerList
+	if err := a.Client.List(ctx, &hras, client.InNamespace(a.Namespace)); err != nil {
+		return fmt.Errorf("failed to list horizontal runner autoscalers: %w", err)
+	}
+
+	var hra *summerwindv1alpha1.HorizontalRunnerAutoscaler
+	for i, h := range hras.Items {
+		if (h.Spec.ScaleTargetRef.Kind == "" || h.Spec.ScaleTargetRef.Kind == "RunnerDeployment") && h.Spec.ScaleTargetRef.Name == name {
+			hra = &hras.Items[i]
+			break
+		}
+	}
+
+	if hra == nil {
+		updated := rd.DeepCopy()
+		r := replicas
+		updated.Spec.Replicas = &r
+		if err := a.Client.Patch(ctx, updated, client.MergeFrom(&rd)); err != nil {
+			return fmt.Errorf("failed to patch runner deployment %s: %w", key, err)
+		}
+		fmt.Fprintf(a.Out, "runnerdeployment/%s scaled to %d replicas\n", name, replicas)
+		return nil
+	}
+
+	now := time.Now()
+	updated := hra.DeepCopy()
+
+	// The desired replicas without the previous reservation of kubectl-arc, which is replaced by the new one.
+	var desired int
+	if hra.Status.DesiredReplicas != nil {
+		desired = *hra.Status.DesiredReplicas
+	} else if rd.Spec.Replicas != nil {
+		desired = *rd.Spec.Replicas
+	}
+	var reservations []summerwindv1alpha1.CapacityReservation
+	for _, r := range hra.Spec.CapacityReservations {
+		if r.Name == ReservationName {
+			if r.ExpirationTime.Time.After(now) {
+				desired -= r.Replicas
+			}
+			continue
+		}
+		reservations = append(reservations, r)
+	}
+
+	if hra.Spec.MaxReplicas != nil && replicas > *hra.Spec.MaxReplicas {
+		return fmt.Errorf("runner deployment %s is scaled by horizontal runner autoscaler %s up to its maxReplicas of %d. Raise maxReplicas to scale to %d replicas", name, hra.Name, *hra.Spec.MaxReplicas, replicas)
+	}
+	if replicas <= desired {
+		return fmt.Errorf("runner deployment %s already has %d desired replicas, and can't be scaled down as it is scaled by horizontal runner autoscaler %s. Lower the minReplicas or maxReplicas of %s instead", name, desired, hra.Name, hra.Name)
+	}
+
+	expirationTime := metav1.NewTime(now.Add(duration))
+	updated.Spec.CapacityReservations = append(reservations, summerwindv1alpha1.CapacityReservation{
+		Name:           ReservationName,
+		ExpirationTime: expirationTime,
+		Replicas:       replicas - desired,
+	})
+	if err := a.Client.Patch(ctx, updated, client.MergeFrom(hra)); err != nil {
+		return fmt.Errorf("failed to patch horizontal runner autoscaler %s: %w", hra.Name, err)
+	}
+	fmt.Fprintf(a.Out, "horizontalrunnerautoscaler/%s reserves %d more replicas for runnerdeployment/%s until %s\n", hra.Name, replicas-desired, name, expirationTime.Format(time.RFC3339))
+
+	return nil
+}
+
+// Logs writes the logs of the runner container of the pod running the workflow job of jobURL,
+// like https://github.com/owner/repo/actions/runs/1/job/2.
+func (a *Arc) Logs(ctx context.Context, jobURL string, follow bool) error {
+	pod, err := a.findJobPod(ctx, jobURL)
+	if err != nil {
+		return err
+	}
+
+	stream, err := a.Clientset.CoreV1().Pods(a.Namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: githubv1alpha1.EphemeralRunnerContainerName,
+		Follow:    follow,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stream the logs of pod %s: %w", pod, err)
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(a.Out, stream)
+	return err
+}
+
+// findJobPod returns the name of the runner pod that is running the workflow job of jobURL.
+// Both the Runners and the EphemeralRunners are named after their pods.
+func (a *Arc) findJobPod(ctx context.Context, jobURL string) (string, error) {
+	repo, runID, jobID, err := parseJobURL(jobURL)
+	if err != nil {
+		return "", err
+	}
+
+	var runners summerwindv1alpha1.RunnerList
+	if err := a.Client.List(ctx, &runners, client.InNamespace(a.Namespace)); err != nil && !isNotInstalled(err) {
+		return "", fmt.Errorf("failed to list runners: %w", err)
+	}
+
+	// The job URL annotation is only set by the webhook-based autoscaling.
+	// The other runners are matched by the workflow run, which they can run several jobs of.
+	var exact, candidates []string
+	for _, r := range runners.Items {
+		if u, ok := r.Annotations[actionssummerwindnet.AnnotationKeyRunnerJobURL]; ok {
+			if _, rRunID, rJobID, err := parseJobURL(u); err == nil && rRunID == runID && rJobID == jobID {
+				exact = append(exact, r.Name)
+				continue
+			}
+		}
+		if s := r.Status.WorkflowStatus; s != nil && strings.EqualFold(s.Repository, repo) && s.RunID == strconv.FormatInt(runID, 10) {
+			candidates = append(candidates, r.Name)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	var ephemeralRunners githubv1alpha1.EphemeralRunnerList
+	if err := a.Client.List(ctx, &ephemeralRunners, client.InNamespace(a.Namespace)); err != nil && !isNotInstalled(err) {
+		return "", fmt.Errorf("failed to list ephemeral runners: %w", err)
+	}
+	for _, er := range ephemeralRunners.Items {
+		if strings.EqualFold(er.Status.JobRepositoryName, repo) && er.Status.WorkflowRunId == runID {
+			candidates = append(candidates, er.Name)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no runner in namespace %q is running the job %s. The runners are deleted once their job completes", a.Namespace, jobURL)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("the runners %s are running the jobs of the workflow run %d, and the job %d can't be told apart. Read the logs of the pod of the job with kubectl logs", strings.Join(candidates, ", "), runID, jobID)
+	}
+}
+
+// parseJobURL returns the owner/repo, the workflow run ID and the job ID of the URL of a workflow job.
+func parseJobURL(jobURL string) (string, int64, int64, error) {
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid job URL %q: %w", jobURL, err)
+	}
+
+	// owner/repo/actions/runs/RUN_ID/job/JOB_ID, possibly followed by a step anchor or the attempt
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 7 || parts[2] != "actions" || parts[3] != "runs" || parts[5] != "job" {
+		return "", 0, 0, fmt.Errorf("invalid job URL %q: expected https://HOST/OWNER/REPO/actions/runs/RUN_ID/job/JOB_ID", jobURL)
+	}
+
+	runID, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid workflow run ID in job URL %q: %w", jobURL, err)
+	}
+	jobID, err := strconv.ParseInt(parts[6], 10, 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid job ID in job URL %q: %w", jobURL, err)
+	}
+
+	return parts[0] + "/" + parts[1], runID, jobID, nil
+}
+
+// ValidateCredentials authenticates to githubConfigURL with the GitHub App or PAT of the secret named secretName,
+// and looks up runnerGroup, as the controller does when it creates the runner scale sets.
+func (a *Arc) ValidateCredentials(ctx context.Context, githubConfigURL, secretName, runnerGroup string) error {
+	key := types.NamespacedName{Namespace: a.Namespace, Name: secretName}
+
+	var secret corev1.Secret
+	if err := a.Client.Get(ctx, key, &secret); err != nil {
+		return fmt.Errorf("failed to get secret %s: %w", key, err)
+	}
+
+	actionsClient, err := a.NewActionsClient(ctx, githubConfigURL, secret.Data)
+	if err != nil {
+		return fmt.Errorf("invalid credentials in secret %s: %w", key, err)
+	}
+
+	if runnerGroup == "" {
+		runnerGroup = defaultRunnerGroup
+	}
+	group, err := actionsClient.GetRunnerGroupByName(ctx, runnerGroup)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to %s with the credentials in secret %s: %w", githubConfigURL, key, err)
+	}
+
+	fmt.Fprintf(a.Out, "The credentials in secret %s are valid for %s, runner group %q has ID %d\n", key, githubConfigURL, group.Name, group.ID)
+
+	return nil
+}
+
+// ValidateAutoscalingRunnerSetCredentials validates the credentials of the AutoscalingRunnerSet named name.
+func (a *Arc) ValidateAutoscalingRunnerSetCredentials(ctx context.Context, name string) error {
+	key := types.NamespacedName{Namespace: a.Namespace, Name: name}
+
+	var ars githubv1alpha1.AutoscalingRunnerSet
+	if err := a.Client.Get(ctx, key, &ars); err != nil {
+		return fmt.Errorf("failed to get autoscaling runner set %s: %w", key, err)
+	}
+
+	return a.ValidateCredentials(ctx, ars.Spec.GitHubConfigUrl, ars.Spec.GitHubConfigSecret, ars.Spec.RunnerGroup)
+}
+
+// isBusy returns true when the EphemeralRunner is assigned a job it has not completed yet.
+func isBusy(er *githubv1alpha1.EphemeralRunner) bool {
+	return er.Status.JobRequestId != 0 && !er.IsDone()
+}
+
+// isNotInstalled returns true when the CRD of the listed kind isn't installed,
+// as the legacy and the scale set controllers can be installed separately.
+func isNotInstalled(err error) bool {
+	return kerrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
+
+func gitHubEvent(e *summerwindv1alpha1.GitHubEventScaleUpTriggerSpec) string {
+	switch {
+	case e == nil:
+		return "<none>"
+	case e.WorkflowJob != nil:
+		return "workflowJob"
+	case e.CheckRun != nil:
+		return "checkRun"
+	case e.PullRequest != nil:
+		return "pullRequest"
+	case e.Push != nil:
+		return "push"
+	case e.Deployment != nil:
+		return "deployment"
+	default:
+		return "<none>"
+	}
+}
+
+func formatInt(i *int) string {
+	if i == nil {
+		return "<unset>"
+	}
+	return strconv.Itoa(*i)
+}
+
+func formatTime(t *metav1.Time) string {
+	if t == nil {
+		return "<never>"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,293 @@
+package kubectlarc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newArc(t *testing.T, objs ...client.Object) (*Arc, *bytes.Buffer) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, summerwindv1alpha1.AddToScheme(scheme))
+	require.NoError(t, githubv1alpha1.AddToScheme(scheme))
+
+	var out bytes.Buffer
+	return &Arc{
+		Client:    clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Clientset: kubefake.NewSimpleClientset(),
+		Namespace: "default",
+		Out:       &out,
+	}, &out
+}
+
+func busyEphemeralRunner(name string, runID int64) *githubv1alpha1.EphemeralRunner {
+	return &githubv1alpha1.EphemeralRunner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Status: githubv1alpha1.EphemeralRunnerStatus{
+			Phase:             corev1.PodRunning,
+			JobRequestId:      1,
+			JobRepositoryName: "my-org/my-repo",
+			JobDisplayName:    "build",
+			WorkflowRunId:     runID,
+		},
+	}
+}
+
+func TestParseJobURL(t *testing.T) {
+	repo, runID, jobID, err := parseJobURL("https://github.com/my-org/my-repo/actions/runs/123/job/456")
+	require.NoError(t, err)
+	assert.Equal(t, "my-org/my-repo", repo)
+	assert.Equal(t, int64(123), runID)
+	assert.Equal(t, int64(456), jobID)
+
+	_, _, jobID, err = parseJobURL("https://ghes.example.com/my-org/my-repo/actions/runs/123/job/456#step:2:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(456), jobID)
+
+	_, _, _, err = parseJobURL("https://github.com/my-org/my-repo/actions/runs/123")
+	assert.ErrorContains(t, err, "expected https://HOST/OWNER/REPO/actions/runs/RUN_ID/job/JOB_ID")
+
+	_, _, _, err = parseJobURL("https://github.com/my-org/my-repo/actions/runs/abc/job/456")
+	assert.ErrorContains(t, err, "invalid workflow run ID")
+}
+
+func TestListRunners(t *testing.T) {
+	a, out := newArc(t,
+		&summerwindv1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy", Annotations: map[string]string{
+				"actions-runner/busy":    "true",
+				"actions-runner/job-url": "https://github.com/my-org/my-repo/actions/runs/1/job/2",
+			}},
+			Status: summerwindv1alpha1.RunnerStatus{Phase: "Running"},
+		},
+		busyEphemeralRunner("ephemeral-busy", 3),
+		&githubv1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ephemeral-idle"}},
+		&githubv1alpha1.EphemeralRunner{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "ephemeral-other"}},
+	)
+
+	require.NoError(t, a.ListRunners(context.Background(), false))
+	lines := strings.Split(out.String(), "\n")
+	require.Len(t, lines, 5)
+	assert.Regexp(t, `^NAME\s+KIND\s+STATUS\s+BUSY\s+JOB$`, lines[0])
+	assert.Regexp(t, `^ephemeral-busy\s+EphemeralRunner\s+Running\s+true\s+my-org/my-repo "build" \(run 3\)$`, lines[1])
+	assert.Regexp(t, `^ephemeral-idle\s+EphemeralRunner\s+false\s*$`, lines[2])
+	assert.Regexp(t, `^legacy\s+Runner\s+Running\s+true\s+https://github.com/my-org/my-repo/actions/runs/1/job/2$`, lines[3])
+
+	out.Reset()
+	require.NoError(t, a.ListRunners(context.Background(), true))
+	assert.Contains(t, out.String(), "NAMESPACE")
+	assert.Contains(t, out.String(), "ephemeral-other")
+}
+
+func TestDescribeAutoscaler(t *testing.T) {
+	minReplicas, maxReplicas, desiredReplicas := 1, 10, 3
+	a, out := newArc(t,
+		&summerwindv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+			Spec: summerwindv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: summerwindv1alpha1.ScaleTargetRef{Name: "rd"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    &maxReplicas,
+			},
+			Status: summerwindv1alpha1.HorizontalRunnerAutoscalerStatus{DesiredReplicas: &desiredReplicas},
+		},
+		&githubv1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ars"},
+			Spec:       githubv1alpha1.AutoscalingRunnerSetSpec{GitHubConfigUrl: "https://github.com/my-org"},
+			Status:     githubv1alpha1.AutoscalingRunnerSetStatus{CurrentRunners: 2, RunningEphemeralRunners: 2},
+		},
+	)
+
+	require.NoError(t, a.DescribeAutoscaler(context.Background(), "hra"))
+	assert.Regexp(t, `Scale target:\s+RunnerDeployment/rd\n`, out.String())
+	assert.Regexp(t, `Desired replicas:\s+3\n`, out.String())
+	assert.Regexp(t, `Last scale out:\s+<never>\n`, out.String())
+
+	out.Reset()
+	require.NoError(t, a.DescribeAutoscaler(context.Background(), "ars"))
+	assert.Regexp(t, `Runner scale set:\s+ars\n`, out.String())
+	assert.Regexp(t, `Runner group:\s+Default\n`, out.String())
+	assert.Regexp(t, `Running runners:\s+2\n`, out.String())
+
+	assert.ErrorContains(t, a.DescribeAutoscaler(context.Background(), "missing"), `no HorizontalRunnerAutoscaler or AutoscalingRunnerSet named "missing"`)
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("runner", func(t *testing.T) {
+		a, _ := newArc(t, &summerwindv1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy"}})
+		require.NoError(t, a.Drain(ctx, "legacy", false))
+
+		err := a.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "legacy"}, &summerwindv1alpha1.Runner{})
+		assert.True(t, kerrors.IsNotFound(err))
+	})
+
+	t.Run("busy ephemeral runner", func(t *testing.T) {
+		a, _ := newArc(t, busyEphemeralRunner("ephemeral", 1))
+		assert.ErrorContains(t, a.Drain(ctx, "ephemeral", false), `is running the job "build" of my-org/my-repo`)
+		require.NoError(t, a.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "ephemeral"}, &githubv1alpha1.EphemeralRunner{}))
+
+		require.NoError(t, a.Drain(ctx, "ephemeral", true))
+		err := a.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "ephemeral"}, &githubv1alpha1.EphemeralRunner{})
+		assert.True(t, kerrors.IsNotFound(err))
+	})
+
+	t.Run("missing runner", func(t *testing.T) {
+		a, _ := newArc(t)
+		assert.ErrorContains(t, a.Drain(ctx, "missing", false), `no Runner or EphemeralRunner named "missing"`)
+	})
+}
+
+func TestScale(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "default", Name: "rd"}
+
+	newRunnerDeployment := func() *summerwindv1alpha1.RunnerDeployment {
+		replicas := 1
+		return &summerwindv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rd"},
+			Spec:       summerwindv1alpha1.RunnerDeploymentSpec{Replicas: &replicas},
+		}
+	}
+
+	t.Run("without autoscaler", func(t *testing.T) {
+		a, _ := newArc(t, newRunnerDeployment())
+		require.NoError(t, a.Scale(ctx, "rd", 5, time.Hour))
+
+		var rd summerwindv1alpha1.RunnerDeployment
+		require.NoError(t, a.Client.Get(ctx, key, &rd))
+		assert.Equal(t, 5, *rd.Spec.Replicas)
+	})
+
+	t.Run("with autoscaler", func(t *testing.T) {
+		maxReplicas, desiredReplicas := 10, 3
+		a, _ := newArc(t, newRunnerDeployment(), &summerwindv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+			Spec: summerwindv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: summerwindv1alpha1.ScaleTargetRef{Name: "rd"},
+				MaxReplicas:    &maxReplicas,
+				CapacityReservations: []summerwindv1alpha1.CapacityReservation{
+					{Name: "other", Replicas: 1, ExpirationTime: metav1.NewTime(time.Now().Add(time.Hour))},
+					{Name: ReservationName, Replicas: 1, ExpirationTime: metav1.NewTime(time.Now().Add(time.Hour))},
+				},
+			},
+			Status: summerwindv1alpha1.HorizontalRunnerAutoscalerStatus{DesiredReplicas: &desiredReplicas},
+		})
+
+		assert.ErrorContains(t, a.Scale(ctx, "rd", 11, time.Hour), "up to its maxReplicas of 10")
+		assert.ErrorContains(t, a.Scale(ctx, "rd", 2, time.Hour), "can't be scaled down")
+
+		require.NoError(t, a.Scale(ctx, "rd", 5, 30*time.Minute))
+
+		var hra summerwindv1alpha1.HorizontalRunnerAutoscaler
+		require.NoError(t, a.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "hra"}, &hra))
+		require.Len(t, hra.Spec.CapacityReservations, 2)
+		assert.Equal(t, "other", hra.Spec.CapacityReservations[0].Name)
+		// The previous reservation of 1 replica is replaced, so 3 of the 5 replicas are reserved on top of the 2 others.
+		assert.Equal(t, ReservationName, hra.Spec.CapacityReservations[1].Name)
+		assert.Equal(t, 3, hra.Spec.CapacityReservations[1].Replicas)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), hra.Spec.CapacityReservations[1].ExpirationTime.Time, time.Minute)
+
+		var rd summerwindv1alpha1.RunnerDeployment
+		require.NoError(t, a.Client.Get(ctx, key, &rd))
+		assert.Equal(t, 1, *rd.Spec.Replicas)
+	})
+}
+
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	jobURL := "https://github.com/my-org/my-repo/actions/runs/1/job/2"
+
+	t.Run("legacy runner", func(t *testing.T) {
+		a, out := newArc(t,
+			&summerwindv1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other", Annotations: map[string]string{
+				"actions-runner/job-url": "https://github.com/my-org/my-repo/actions/runs/1/job/3",
+			}}},
+			&summerwindv1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy", Annotations: map[string]string{
+				"actions-runner/job-url": jobURL,
+			}}},
+		)
+
+		pod, err := a.findJobPod(ctx, jobURL)
+		require.NoError(t, err)
+		assert.Equal(t, "legacy", pod)
+
+		require.NoError(t, a.Logs(ctx, jobURL, false))
+		assert.Equal(t, "fake logs", out.String())
+	})
+
+	t.Run("ephemeral runner", func(t *testing.T) {
+		a, _ := newArc(t, busyEphemeralRunner("ephemeral", 1), busyEphemeralRunner("ephemeral-other-run", 2))
+
+		pod, err := a.findJobPod(ctx, jobURL)
+		require.NoError(t, err)
+		assert.Equal(t, "ephemeral", pod)
+	})
+
+	t.Run("jobs of the same run", func(t *testing.T) {
+		a, _ := newArc(t, busyEphemeralRunner("ephemeral-1", 1), busyEphemeralRunner("ephemeral-2", 1))
+
+		_, err := a.findJobPod(ctx, jobURL)
+		assert.ErrorContains(t, err, "the runners ephemeral-1, ephemeral-2 are running the jobs of the workflow run 1")
+	})
+
+	t.Run("completed job", func(t *testing.T) {
+		a, _ := newArc(t)
+
+		_, err := a.findJobPod(ctx, jobURL)
+		assert.ErrorContains(t, err, "The runners are deleted once their job completes")
+	})
+}
+
+func TestValidateCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	a, out := newArc(t,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "github-config"},
+			Data:       map[string][]byte{"github_token": []byte("token")},
+		},
+		&githubv1alpha1.AutoscalingRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ars"},
+			Spec: githubv1alpha1.AutoscalingRunnerSetSpec{
+				GitHubConfigUrl:    "https://github.com/my-org",
+				GitHubConfigSecret: "github-config",
+				RunnerGroup:        "gpu",
+			},
+		},
+	)
+
+	actionsClient := actions.NewMockActionsService(t)
+	actionsClient.On("GetRunnerGroupByName", mock.Anything, "gpu").Return(&actions.RunnerGroup{ID: 2, Name: "gpu"}, nil)
+
+	a.NewActionsClient = func(_ context.Context, githubConfigURL string, secretData actions.KubernetesSecretData) (actions.ActionsService, error) {
+		assert.Equal(t, "https://github.com/my-org", githubConfigURL)
+		assert.Equal(t, "token", string(secretData["github_token"]))
+		return actionsClient, nil
+	}
+
+	require.NoError(t, a.ValidateAutoscalingRunnerSetCredentials(ctx, "ars"))
+	assert.Equal(t, "The credentials in secret default/github-config are valid for https://github.com/my-org, runner group \"gpu\" has ID 2\n", out.String())
+
+	assert.ErrorContains(t, a.ValidateCredentials(ctx, "https://github.com/my-org", "missing", ""), "failed to get secret default/missing")
+}
@@ -0,0 +1,133 @@
+package policyhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"sigs.k8s.io/yaml"
+)
+
+// VariableInput is the name of the CEL variable bound to the Input, with the same field names as its JSON encoding.
+const VariableInput = "input"
+
+// Rule is a CEL expression that must evaluate to true for the action to be allowed.
+type Rule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	// Message is the reason of the decision when the rule denies the action. Defaults to one telling the name of the rule.
+	Message string `json:"message,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	prg cel.Program
+}
+
+// CELEngine allows the action when all the rules evaluate to true, like:
+//
+//	rules:
+//	- name: protected-default-branch
+//	  expression: '!has(input.repositoryInfo) || input.repositoryInfo.defaultBranchProtected'
+//	  message: the default branch of the repository must be protected
+//
+// Accessing a field omitted from the input, like input.repositoryInfo of an organizational runner,
+// is an evaluation error. Use has() to test for the optional fields.
+type CELEngine struct {
+	rules []compiledRule
+}
+
+// LoadCELEngine reads the rules from the YAML file.
+func LoadCELEngine(path string) (*CELEngine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy rules: %w", err)
+	}
+
+	var f struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing policy rules %s: %w", path, err)
+	}
+
+	return NewCELEngine(f.Rules)
+}
+
+// NewCELEngine compiles the rules. It fails when a rule doesn't evaluate to a bool.
+func NewCELEngine(rules []Rule) (*CELEngine, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no policy rules")
+	}
+
+	env, err := cel.NewEnv(cel.Variable(VariableInput, cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, fmt.Errorf("creating cel environment: %w", err)
+	}
+
+	e := &CELEngine{}
+
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("policy rule %q has no name", r.Expression)
+		}
+
+		ast, iss := env.Compile(r.Expression)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("compiling policy rule %s: %w", r.Name, iss.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("policy rule %s must evaluate to bool, but evaluates to %s", r.Name, ast.OutputType())
+		}
+
+		prg, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("creating program for policy rule %s: %w", r.Name, err)
+		}
+
+		e.rules = append(e.rules, compiledRule{Rule: r, prg: prg})
+	}
+
+	return e, nil
+}
+
+func (e *CELEngine) Evaluate(_ context.Context, input Input) (Decision, error) {
+	// The input is bound as the JSON document the HTTP engine receives, so that the rules are portable between the engines
+	data, err := json.Marshal(input)
+	if err != nil {
+		return Decision{}, fmt.Errorf("encoding policy input: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Decision{}, fmt.Errorf("decoding policy input: %w", err)
+	}
+
+	for _, r := range e.rules {
+		out, _, err := r.prg.Eval(map[string]interface{}{VariableInput: doc})
+		if err != nil {
+			return Decision{}, fmt.Errorf("evaluating policy rule %s: %w", r.Name, err)
+		}
+
+		b, ok := out.(types.Bool)
+		if !ok {
+			return Decision{}, fmt.Errorf("policy rule %s must evaluate to bool, but evaluated to %v", r.Name, out.Type())
+		}
+
+		if !b {
+			reason := r.Message
+			if reason == "" {
+				reason = fmt.Sprintf("denied by the policy rule %s", r.Name)
+			}
+
+			return Decision{Reason: reason}, nil
+		}
+	}
+
+	return Decision{Allowed: true}, nil
+}
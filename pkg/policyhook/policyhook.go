@@ -0,0 +1,292 @@
+// Package policyhook asks a policy engine whether ARC may scale up the runners, or register a runner, for a repository or a workflow,
+// so that security teams can enforce rules like "no self-hosted runners for the repositories without branch protection" without changing ARC.
+//
+// The engine is either an HTTP endpoint compatible with the Data API of Open Policy Agent, or a set of CEL rules.
+// See https://www.openpolicyagent.org/docs/latest/rest-api/#data-api and https://github.com/google/cel-spec.
+package policyhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// The actions the policy decides on.
+const (
+	// ActionScaleUp is to increase the desired replicas of a RunnerDeployment or a RunnerSet.
+	ActionScaleUp = "scale_up"
+	// ActionRegister is to register a runner to GitHub, right before the runner pod is created.
+	ActionRegister = "register"
+)
+
+// DefaultRepositoryInfoTTL is how long the RepositoryInfo is reused before it's fetched from GitHub again.
+const DefaultRepositoryInfoTTL = 5 * time.Minute
+
+// Input is what the policy decides on. It's sent as is, as the input document of the policy.
+type Input struct {
+	// Action is either ActionScaleUp or ActionRegister.
+	Action string `json:"action"`
+
+	// Resource is the Kubernetes resource the action is taken for, like the HorizontalRunnerAutoscaler or the Runner.
+	Resource Resource `json:"resource"`
+
+	Enterprise   string `json:"enterprise,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	// Repository is the repository in the OWNER/REPO format. Empty for the organizational and the enterprise runners,
+	// unless the action is taken for a workflow job.
+	Repository string   `json:"repository,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+
+	// Replicas is set for ActionScaleUp.
+	Replicas *Replicas `json:"replicas,omitempty"`
+
+	// RepositoryInfo is set when Repository is set and ARC could get the repository from GitHub.
+	RepositoryInfo *RepositoryInfo `json:"repositoryInfo,omitempty"`
+
+	// Workflow is set when the action is taken for a workflow job.
+	Workflow *Workflow `json:"workflow,omitempty"`
+}
+
+type Resource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type Replicas struct {
+	Current int `json:"current"`
+	Desired int `json:"desired"`
+}
+
+type RepositoryInfo struct {
+	// Visibility is either public, private, or internal.
+	Visibility             string `json:"visibility"`
+	Fork                   bool   `json:"fork"`
+	Archived               bool   `json:"archived"`
+	DefaultBranch          string `json:"defaultBranch"`
+	DefaultBranchProtected bool   `json:"defaultBranchProtected"`
+}
+
+type Workflow struct {
+	Name       string `json:"name,omitempty"`
+	JobName    string `json:"jobName,omitempty"`
+	RunID      int64  `json:"runID,omitempty"`
+	HeadBranch string `json:"headBranch,omitempty"`
+}
+
+// Decision is the outcome of the policy.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Reason tells why the action is denied, and ends up in the events and the conditions of the resources.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Engine evaluates the policy. An error means that the engine couldn't decide, not that the action is denied.
+type Engine interface {
+	Evaluate(ctx context.Context, input Input) (Decision, error)
+}
+
+// Hook evaluates the policy of the engines in order, and the first engine denying the action decides.
+// It's safe for concurrent use.
+type Hook struct {
+	Engines []Engine
+
+	// FailOpen allows the actions the engines fail to decide on. The actions are denied otherwise.
+	FailOpen bool
+
+	// RepositoryInfoTTL defaults to DefaultRepositoryInfoTTL.
+	RepositoryInfoTTL time.Duration
+
+	Log logr.Logger
+
+	mu    sync.Mutex
+	infos map[string]cachedRepositoryInfo
+}
+
+type cachedRepositoryInfo struct {
+	info      *RepositoryInfo
+	expiresAt time.Time
+}
+
+// New returns the hook asking the HTTP endpoint at the URL and then the CEL rules in the file, skipping either when empty.
+// It returns nil when both are empty, which disables the hook.
+func New(url, rulesFile string, failOpen bool, log logr.Logger) (*Hook, error) {
+	var engines []Engine
+
+	if url != "" {
+		engines = append(engines, &HTTPEngine{URL: url})
+	}
+
+	if rulesFile != "" {
+		e, err := LoadCELEngine(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+
+		engines = append(engines, e)
+	}
+
+	if len(engines) == 0 {
+		return nil, nil
+	}
+
+	return &Hook{Engines: engines, FailOpen: failOpen, Log: log}, nil
+}
+
+// Decide returns the decision of the engines on the input.
+func (h *Hook) Decide(ctx context.Context, input Input) Decision {
+	for _, e := range h.Engines {
+		d, err := e.Evaluate(ctx, input)
+		if err != nil {
+			h.Log.Error(err, "Could not evaluate the policy", "action", input.Action, "resource", input.Resource, "failOpen", h.FailOpen)
+
+			if h.FailOpen {
+				continue
+			}
+
+			return Decision{Reason: fmt.Sprintf("policy evaluation failed: %v", err)}
+		}
+
+		if !d.Allowed {
+			if d.Reason == "" {
+				d.Reason = "denied by the policy"
+			}
+
+			return d
+		}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// RepositoryInfo returns the info of the repository fetched by get, reusing the one fetched within the TTL.
+func (h *Hook) RepositoryInfo(ctx context.Context, repository string, get func(context.Context, string) (*RepositoryInfo, error)) (*RepositoryInfo, error) {
+	now := time.Now()
+
+	h.mu.Lock()
+	cached, ok := h.infos[repository]
+	h.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.info, nil
+	}
+
+	info, err := get(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := h.RepositoryInfoTTL
+	if ttl == 0 {
+		ttl = DefaultRepositoryInfoTTL
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.infos == nil {
+		h.infos = map[string]cachedRepositoryInfo{}
+	}
+
+	for k, v := range h.infos {
+		if !now.Before(v.expiresAt) {
+			delete(h.infos, k)
+		}
+	}
+
+	h.infos[repository] = cachedRepositoryInfo{info: info, expiresAt: now.Add(ttl)}
+
+	return info, nil
+}
+
+// HTTPEngine POSTs the input as {"input": INPUT} to the URL, like the Data API of Open Policy Agent, and accepts either
+// {"result": true|false}, {"result": {"allowed": true|false, "reason": "..."}}, or {"allowed": true|false, "reason": "..."}.
+// An undefined result of Open Policy Agent, that is a response without the result, is an error.
+type HTTPEngine struct {
+	// URL is like http://localhost:8181/v1/data/arc/decision.
+	URL string
+
+	// HTTPClient defaults to a client timing out in 10 seconds.
+	HTTPClient *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func (e *HTTPEngine) Evaluate(ctx context.Context, input Input) (Decision, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return Decision{}, fmt.Errorf("encoding policy input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, fmt.Errorf("creating policy request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	c := e.HTTPClient
+	if c == nil {
+		c = defaultHTTPClient
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("requesting policy decision: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return Decision{}, fmt.Errorf("reading policy decision: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("policy endpoint responded with %s: %s", res.Status, bytes.TrimSpace(data))
+	}
+
+	return parseDecision(data)
+}
+
+func parseDecision(data []byte) (Decision, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Decision{}, fmt.Errorf("decoding policy decision: %w", err)
+	}
+
+	if _, ok := doc["allowed"]; !ok {
+		result, ok := doc["result"]
+		if !ok {
+			return Decision{}, errors.New("policy decision is undefined")
+		}
+
+		var allowed bool
+		if err := json.Unmarshal(result, &allowed); err == nil {
+			return Decision{Allowed: allowed}, nil
+		}
+
+		data = result
+	}
+
+	var d struct {
+		Allowed *bool  `json:"allowed"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Decision{}, fmt.Errorf("decoding policy decision: %w", err)
+	}
+
+	if d.Allowed == nil {
+		return Decision{}, errors.New("policy decision has no allowed field")
+	}
+
+	return Decision{Allowed: *d.Allowed, Reason: d.Reason}, nil
+}
@@ -0,0 +1,150 @@
+package policyhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var unprotectedRepoInput = Input{
+	Action:       ActionRegister,
+	Resource:     Resource{Kind: "Runner", Namespace: "default", Name: "example-runner"},
+	Organization: "my-org",
+	Repository:   "my-org/app",
+	RepositoryInfo: &RepositoryInfo{
+		Visibility:    "private",
+		DefaultBranch: "main",
+	},
+}
+
+func TestHTTPEngine(t *testing.T) {
+	var response string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input Input `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, unprotectedRepoInput, body.Input)
+
+		_, _ = w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	e := &HTTPEngine{URL: srv.URL}
+
+	for _, tc := range []struct {
+		response string
+		want     Decision
+		err      string
+	}{
+		{response: `{"result": true}`, want: Decision{Allowed: true}},
+		{response: `{"result": false}`, want: Decision{}},
+		{response: `{"result": {"allowed": false, "reason": "branch protection is required"}}`, want: Decision{Reason: "branch protection is required"}},
+		{response: `{"allowed": true}`, want: Decision{Allowed: true}},
+		{response: `{}`, err: "policy decision is undefined"},
+		{response: `{"result": {"reason": "no decision"}}`, err: "no allowed field"},
+	} {
+		t.Run(tc.response, func(t *testing.T) {
+			response = tc.response
+
+			got, err := e.Evaluate(context.Background(), unprotectedRepoInput)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCELEngine(t *testing.T) {
+	e, err := NewCELEngine([]Rule{
+		{
+			Name:       "public-repositories",
+			Expression: `!has(input.repositoryInfo) || input.repositoryInfo.visibility != "public"`,
+		},
+		{
+			Name:       "protected-default-branch",
+			Expression: `input.action != "register" || !has(input.repositoryInfo) || input.repositoryInfo.defaultBranchProtected`,
+			Message:    "the default branch of the repository must be protected",
+		},
+	})
+	require.NoError(t, err)
+
+	got, err := e.Evaluate(context.Background(), unprotectedRepoInput)
+	require.NoError(t, err)
+	require.Equal(t, Decision{Reason: "the default branch of the repository must be protected"}, got)
+
+	public := unprotectedRepoInput
+	public.RepositoryInfo = &RepositoryInfo{Visibility: "public", DefaultBranchProtected: true}
+	got, err = e.Evaluate(context.Background(), public)
+	require.NoError(t, err)
+	require.Equal(t, Decision{Reason: "denied by the policy rule public-repositories"}, got)
+
+	org := unprotectedRepoInput
+	org.Repository = ""
+	org.RepositoryInfo = nil
+	got, err = e.Evaluate(context.Background(), org)
+	require.NoError(t, err)
+	require.True(t, got.Allowed)
+
+	_, err = NewCELEngine([]Rule{{Name: "dynamic", Expression: `input.action`}})
+	require.NoError(t, err, "the fields of the input are dynamically typed")
+
+	_, err = NewCELEngine([]Rule{{Name: "string", Expression: `"allowed"`}})
+	require.ErrorContains(t, err, "must evaluate to bool")
+}
+
+type engineFunc func(Input) (Decision, error)
+
+func (f engineFunc) Evaluate(_ context.Context, input Input) (Decision, error) {
+	return f(input)
+}
+
+func TestHook(t *testing.T) {
+	allow := engineFunc(func(Input) (Decision, error) { return Decision{Allowed: true}, nil })
+	deny := engineFunc(func(Input) (Decision, error) { return Decision{}, nil })
+	broken := engineFunc(func(Input) (Decision, error) { return Decision{}, errors.New("connection refused") })
+
+	ctx := context.Background()
+
+	require.True(t, (&Hook{Engines: []Engine{allow}}).Decide(ctx, unprotectedRepoInput).Allowed)
+	require.Equal(t, Decision{Reason: "denied by the policy"}, (&Hook{Engines: []Engine{allow, deny}}).Decide(ctx, unprotectedRepoInput))
+
+	require.Equal(t, Decision{Reason: "policy evaluation failed: connection refused"}, (&Hook{Engines: []Engine{broken, allow}}).Decide(ctx, unprotectedRepoInput))
+	require.True(t, (&Hook{Engines: []Engine{broken, allow}, FailOpen: true}).Decide(ctx, unprotectedRepoInput).Allowed)
+	require.False(t, (&Hook{Engines: []Engine{broken, deny}, FailOpen: true}).Decide(ctx, unprotectedRepoInput).Allowed, "fail-open doesn't skip the other engines")
+}
+
+func TestHookRepositoryInfo(t *testing.T) {
+	h := &Hook{}
+
+	var gets int
+	get := func(_ context.Context, repo string) (*RepositoryInfo, error) {
+		gets++
+		if repo == "my-org/missing" {
+			return nil, errors.New("not found")
+		}
+		return &RepositoryInfo{Visibility: "private"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		info, err := h.RepositoryInfo(context.Background(), "my-org/app", get)
+		require.NoError(t, err)
+		require.Equal(t, "private", info.Visibility)
+	}
+	require.Equal(t, 1, gets, "the info is reused within the TTL")
+
+	_, err := h.RepositoryInfo(context.Background(), "my-org/missing", get)
+	require.Error(t, err)
+	_, err = h.RepositoryInfo(context.Background(), "my-org/missing", get)
+	require.Error(t, err)
+	require.Equal(t, 3, gets, "the errors aren't cached")
+}
@@ -0,0 +1,91 @@
+// Package reconcilemetrics observes the reconciliations of the controllers of both the actions.summerwind.dev
+// and the actions.github.com resources, so that the reconcile durations of all the kinds are exported alike.
+//
+// This depends on the metrics exporter of kubebuilder.
+// See https://book.kubebuilder.io/reference/metrics.html for details.
+package reconcilemetrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	controllerName   = "controller"
+	controllerKind   = "kind"
+	reconcileResult  = "result"
+	reconcileSuccess = "success"
+	reconcileRequeue = "requeue"
+	reconcileError   = "error"
+)
+
+// Collectors are the metrics of the reconciliations, registered to the metrics registry of controller-runtime.
+var Collectors = []prometheus.Collector{
+	controllerKindInfo,
+	reconcileDuration,
+}
+
+var (
+	controllerKindInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "controller_kind_info",
+			Help: "Kind of the resources reconciled by the controller, to break down the workqueue_* and controller_runtime_* metrics labeled by the controller name by the kind",
+		},
+		[]string{controllerName, controllerKind},
+	)
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "controller_reconcile_duration_seconds",
+			Help:    "Time taken by the reconciliations of the resources of the kind, by the result of the reconciliation",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{controllerKind, reconcileResult},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(Collectors...)
+}
+
+// Observe wraps the reconciler of the controller to observe the durations of its reconciliations
+// of the resources of the kind.
+func Observe(controller, kind string, r reconcile.Reconciler) reconcile.Reconciler {
+	controllerKindInfo.With(prometheus.Labels{
+		controllerName: controller,
+		controllerKind: kind,
+	}).Set(1)
+
+	return &observedReconciler{kind: kind, Reconciler: r}
+}
+
+type observedReconciler struct {
+	reconcile.Reconciler
+
+	kind string
+}
+
+func (r *observedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+
+	res, err := r.Reconciler.Reconcile(ctx, req)
+
+	result := reconcileSuccess
+	switch {
+	case err != nil:
+		result = reconcileError
+	case res.Requeue || res.RequeueAfter > 0:
+		result = reconcileRequeue
+	}
+
+	reconcileDuration.With(prometheus.Labels{
+		controllerKind:  r.kind,
+		reconcileResult: result,
+	}).Observe(time.Since(start).Seconds())
+
+	return res, err
+}
@@ -0,0 +1,77 @@
+package reconcilemetrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func observations(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	require.NoError(t, o.(prometheus.Metric).Write(&m))
+
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserve(t *testing.T) {
+	testcases := []struct {
+		name   string
+		res    ctrl.Result
+		err    error
+		result string
+	}{
+		{
+			name:   "success",
+			result: reconcileSuccess,
+		},
+		{
+			name:   "requeue",
+			res:    ctrl.Result{Requeue: true},
+			result: reconcileRequeue,
+		},
+		{
+			name:   "requeue after",
+			res:    ctrl.Result{RequeueAfter: time.Minute},
+			result: reconcileRequeue,
+		},
+		{
+			name:   "error",
+			res:    ctrl.Result{RequeueAfter: time.Minute},
+			err:    errors.New("failed"),
+			result: reconcileError,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind := "Test" + tc.name
+
+			r := Observe("test-controller", kind, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+				return tc.res, tc.err
+			}))
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{})
+			require.Equal(t, tc.res, res)
+			require.Equal(t, tc.err, err)
+
+			for _, result := range []string{reconcileSuccess, reconcileRequeue, reconcileError} {
+				want := uint64(0)
+				if result == tc.result {
+					want = 1
+				}
+
+				o := reconcileDuration.With(prometheus.Labels{controllerKind: kind, reconcileResult: result})
+				require.Equal(t, want, observations(t, o), "observations of the %s result", result)
+			}
+		})
+	}
+}
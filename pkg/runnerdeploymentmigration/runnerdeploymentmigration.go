@@ -0,0 +1,566 @@
+// Package runnerdeploymentmigration migrates the RunnerDeployments of the legacy mode, along with their HorizontalRunnerAutoscalers,
+// to AutoscalingRunnerSets.
+//
+// The AutoscalingRunnerSet of a RunnerDeployment can run side by side with it: the capacity of the RunnerDeployment is split
+// between the two by CapacityPercent, so that the jobs are shifted to the runner scale set step by step.
+package runnerdeploymentmigration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultGitHubURL   = "https://github.com"
+	DefaultRunnerImage = "ghcr.io/actions/actions-runner:latest"
+	DefaultDockerImage = "docker:dind"
+
+	// AnnotationKeyMigratedFrom is the annotation on the generated AutoscalingRunnerSet that names the RunnerDeployment it was migrated from.
+	AnnotationKeyMigratedFrom = "actions-runner-controller/migrated-from"
+
+	// labelKeyKubernetesVersion is the label the AutoscalingRunnerSet controller deletes the AutoscalingRunnerSets
+	// of other versions by. See controllers/actions.github.com/constants.go
+	labelKeyKubernetesVersion = "app.kubernetes.io/version"
+
+	// The dind sidecar is rendered like the dind container mode of the gha-runner-scale-set chart
+	runnerContainerName  = "runner"
+	dockerContainerName  = "dind"
+	runnerWorkDir        = "/home/runner/_work"
+	runnerExternalsDir   = "/home/runner/externals"
+	runnerDockerGroupGID = "123"
+)
+
+// Options configures the AutoscalingRunnerSets generated from the RunnerDeployments.
+type Options struct {
+	// GitHubURL is the URL of GitHub or GitHub Enterprise Server the runners are registered at. Defaults to DefaultGitHubURL.
+	GitHubURL string
+
+	// GitHubConfigSecret is the secret in the namespace of the RunnerDeployment holding the GitHub App or PAT
+	// the AutoscalingRunnerSet authenticates with. Required.
+	GitHubConfigSecret string
+
+	// ControllerVersion is the version of the gha-runner-scale-set-controller,
+	// which deletes the AutoscalingRunnerSets labeled with another version. Required.
+	ControllerVersion string
+
+	// RunnerImage is the image of the runner container. Defaults to DefaultRunnerImage.
+	// The images of the legacy runners are not compatible with runner scale sets.
+	RunnerImage string
+
+	// DockerImage is the image of the dind sidecar of the RunnerDeployments with docker enabled. Defaults to DefaultDockerImage.
+	DockerImage string
+
+	// LabelRunnerGroups maps the runner labels to runner groups. The runner scale set of a RunnerDeployment having
+	// one of the labels is added to the runner group, so that label-based routing is replaced with runner groups.
+	LabelRunnerGroups map[string]string
+
+	// CapacityPercent is the percentage of the capacity of the RunnerDeployment moved to the AutoscalingRunnerSet.
+	// The RunnerDeployment keeps the rest, so that both run side by side until it reaches 100.
+	CapacityPercent int
+}
+
+// Result is the migration of a RunnerDeployment.
+type Result struct {
+	AutoscalingRunnerSet *githubv1alpha1.AutoscalingRunnerSet
+
+	// HorizontalRunnerAutoscaler is the HorizontalRunnerAutoscaler of the RunnerDeployment scaled to the remaining capacity, if any.
+	HorizontalRunnerAutoscaler *summerwindv1alpha1.HorizontalRunnerAutoscaler
+
+	// RunnerDeployment is the RunnerDeployment scaled to the remaining capacity when it has no HorizontalRunnerAutoscaler.
+	RunnerDeployment *summerwindv1alpha1.RunnerDeployment
+
+	// Warnings are the settings of the RunnerDeployment that can't be migrated as is.
+	Warnings []string
+}
+
+// Objects returns the resources to apply, the AutoscalingRunnerSet first.
+func (r *Result) Objects() []client.Object {
+	objs := []client.Object{r.AutoscalingRunnerSet}
+	if r.HorizontalRunnerAutoscaler != nil {
+		objs = append(objs, r.HorizontalRunnerAutoscaler)
+	}
+	if r.RunnerDeployment != nil {
+		objs = append(objs, r.RunnerDeployment)
+	}
+	return objs
+}
+
+// Convert generates the AutoscalingRunnerSet of the RunnerDeployment autoscaled by the HorizontalRunnerAutoscaler, which can be nil.
+func Convert(rd *summerwindv1alpha1.RunnerDeployment, hra *summerwindv1alpha1.HorizontalRunnerAutoscaler, opts Options) (*Result, error) {
+	if opts.GitHubConfigSecret == "" {
+		return nil, fmt.Errorf("github config secret is required")
+	}
+	if opts.ControllerVersion == "" {
+		return nil, fmt.Errorf("controller version is required")
+	}
+	if opts.CapacityPercent < 0 || opts.CapacityPercent > 100 {
+		return nil, fmt.Errorf("capacity percent must be between 0 and 100: %d", opts.CapacityPercent)
+	}
+
+	spec := rd.Spec.Template.Spec
+	res := &Result{}
+
+	configURL, err := gitHubConfigURL(opts.GitHubURL, &spec.RunnerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("runner deployment %s/%s: %w", rd.Namespace, rd.Name, err)
+	}
+
+	// Jobs are routed to a runner scale set by its name instead of the runner labels.
+	// A runner deployment with a single label is named after it, so that the workflows running on it don't change.
+	scaleSetName := rd.Name
+	if len(spec.Labels) == 1 {
+		scaleSetName = spec.Labels[0]
+	} else if len(spec.Labels) > 1 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("runner scale sets are routed jobs by their name: update the workflows running on the labels %s to run on %q", strings.Join(spec.Labels, ","), scaleSetName))
+	}
+
+	runnerGroup := spec.Group
+	for _, l := range spec.Labels {
+		if g, ok := opts.LabelRunnerGroups[l]; ok {
+			runnerGroup = g
+			break
+		}
+	}
+
+	// A RunnerDeployment runs a single runner when neither replicas nor the HorizontalRunnerAutoscaler sets the number of runners
+	minRunners, maxRunners := 1, 1
+	if rd.Spec.Replicas != nil {
+		minRunners, maxRunners = *rd.Spec.Replicas, *rd.Spec.Replicas
+	}
+	if hra != nil {
+		if hra.Spec.MinReplicas != nil {
+			minRunners = *hra.Spec.MinReplicas
+		}
+		if hra.Spec.MaxReplicas != nil {
+			maxRunners = *hra.Spec.MaxReplicas
+		}
+		if len(hra.Spec.ScheduledOverrides) > 0 {
+			res.Warnings = append(res.Warnings, "runner scale sets have no scheduled overrides: set minRunners of the AutoscalingRunnerSet instead")
+		}
+	}
+
+	scaleSetMin, scaleSetMax := shiftedCapacity(minRunners, opts.CapacityPercent), shiftedCapacity(maxRunners, opts.CapacityPercent)
+	switch {
+	case hra != nil:
+		remainingMin, remainingMax := minRunners-scaleSetMin, maxRunners-scaleSetMax
+		if remainingMin != minRunners || remainingMax != maxRunners {
+			res.HorizontalRunnerAutoscaler = hra.DeepCopy()
+			res.HorizontalRunnerAutoscaler.Spec.MinReplicas = &remainingMin
+			res.HorizontalRunnerAutoscaler.Spec.MaxReplicas = &remainingMax
+		}
+	default:
+		remaining := maxRunners - scaleSetMax
+		if remaining != maxRunners {
+			res.RunnerDeployment = rd.DeepCopy()
+			res.RunnerDeployment.Spec.Replicas = &remaining
+		}
+	}
+
+	if spec.Ephemeral != nil && !*spec.Ephemeral {
+		res.Warnings = append(res.Warnings, "runner scale sets only run ephemeral runners: the runners are recreated after every job")
+	}
+	if spec.ContainerMode != "" {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("the %s container mode is not migrated: configure the containerMode of the gha-runner-scale-set chart instead", spec.ContainerMode))
+	}
+	if spec.OS == "windows" {
+		res.Warnings = append(res.Warnings, "windows runners are not migrated: set the image and the command of the runner container to a windows runner image")
+	}
+	if spec.DockerdWithinRunnerContainer != nil && *spec.DockerdWithinRunnerContainer {
+		res.Warnings = append(res.Warnings, "dockerd is run in a dind sidecar instead of the runner container")
+	}
+	if spec.WorkVolumeClaimTemplate != nil {
+		res.Warnings = append(res.Warnings, "the work volume claim template is not migrated: add an ephemeral work volume to the runner template instead")
+	}
+
+	runnerImage := opts.RunnerImage
+	if runnerImage == "" {
+		runnerImage = DefaultRunnerImage
+	}
+	if spec.Image != "" {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("the runner image %s is replaced with %s, since runner scale sets require the image of actions/runner", spec.Image, runnerImage))
+	}
+
+	dockerEnabled := spec.DockerEnabled == nil || *spec.DockerEnabled
+	if spec.ContainerMode != "" {
+		dockerEnabled = false
+	}
+
+	ars := &githubv1alpha1.AutoscalingRunnerSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: githubv1alpha1.GroupVersion.String(),
+			Kind:       "AutoscalingRunnerSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: rd.Namespace,
+			Name:      rd.Name,
+			Labels: map[string]string{
+				labelKeyKubernetesVersion: opts.ControllerVersion,
+			},
+			Annotations: map[string]string{
+				AnnotationKeyMigratedFrom: "RunnerDeployment/" + rd.Name,
+			},
+		},
+		Spec: githubv1alpha1.AutoscalingRunnerSetSpec{
+			GitHubConfigUrl:    configURL,
+			GitHubConfigSecret: opts.GitHubConfigSecret,
+			RunnerGroup:        runnerGroup,
+			RunnerScaleSetName: scaleSetName,
+			MinRunners:         &scaleSetMin,
+			MaxRunners:         &scaleSetMax,
+			Template:           runnerPodTemplate(rd, runnerImage, dockerImage(opts), dockerEnabled),
+		},
+	}
+
+	res.AutoscalingRunnerSet = ars
+
+	return res, nil
+}
+
+func dockerImage(opts Options) string {
+	if opts.DockerImage != "" {
+		return opts.DockerImage
+	}
+	return DefaultDockerImage
+}
+
+// shiftedCapacity returns the part of the capacity moved to the runner scale set, rounded up.
+func shiftedCapacity(capacity, percent int) int {
+	return (capacity*percent + 99) / 100
+}
+
+// gitHubConfigURL returns the URL of the enterprise, organization or repository of the runners.
+func gitHubConfigURL(gitHubURL string, config *summerwindv1alpha1.RunnerConfig) (string, error) {
+	if gitHubURL == "" {
+		gitHubURL = DefaultGitHubURL
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(gitHubURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid github url %q: %w", gitHubURL, err)
+	}
+
+	switch {
+	case config.Repository != "":
+		return u.JoinPath(config.Repository).String(), nil
+	case config.Organization != "":
+		return u.JoinPath(config.Organization).String(), nil
+	case config.Enterprise != "":
+		return u.JoinPath("enterprises", config.Enterprise).String(), nil
+	default:
+		return "", fmt.Errorf("none of enterprise, organization and repository is set")
+	}
+}
+
+// runnerPodTemplate returns the pod template of the runners of the runner scale set,
+// with the dind sidecar rendered like the dind container mode of the gha-runner-scale-set chart when docker is enabled.
+func runnerPodTemplate(rd *summerwindv1alpha1.RunnerDeployment, runnerImage, dockerImage string, dockerEnabled bool) corev1.PodTemplateSpec {
+	spec := rd.Spec.Template.Spec
+
+	runner := corev1.Container{
+		Name:            runnerContainerName,
+		Image:           runnerImage,
+		Command:         []string{"/home/runner/run.sh"},
+		ImagePullPolicy: spec.ImagePullPolicy,
+		Env:             append([]corev1.EnvVar(nil), spec.Env...),
+		EnvFrom:         spec.EnvFrom,
+		Resources:       spec.Resources,
+		VolumeMounts:    append([]corev1.VolumeMount(nil), spec.VolumeMounts...),
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      rd.Spec.Template.Labels,
+			Annotations: rd.Spec.Template.Annotations,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			InitContainers:                spec.InitContainers,
+			Volumes:                       append([]corev1.Volume(nil), spec.Volumes...),
+			NodeSelector:                  spec.NodeSelector,
+			ServiceAccountName:            spec.ServiceAccountName,
+			AutomountServiceAccountToken:  spec.AutomountServiceAccountToken,
+			SecurityContext:               spec.SecurityContext,
+			ImagePullSecrets:              spec.ImagePullSecrets,
+			Affinity:                      spec.Affinity,
+			Tolerations:                   spec.Tolerations,
+			PriorityClassName:             spec.PriorityClassName,
+			TerminationGracePeriodSeconds: spec.TerminationGracePeriodSeconds,
+			HostAliases:                   spec.HostAliases,
+			TopologySpreadConstraints:     spec.TopologySpreadConstraints,
+			RuntimeClassName:              spec.RuntimeClassName,
+			DNSPolicy:                     spec.DnsPolicy,
+			DNSConfig:                     spec.DnsConfig,
+			EnableServiceLinks:            spec.EnableServiceLinks,
+		},
+	}
+
+	var sidecars []corev1.Container
+	for _, c := range spec.Containers {
+		// The runner and docker containers of the legacy runners are replaced
+		if c.Name != "runner" && c.Name != "docker" {
+			sidecars = append(sidecars, c)
+		}
+	}
+	sidecars = append(sidecars, spec.SidecarContainers...)
+
+	if dockerEnabled {
+		privileged := true
+
+		runner.Env = append(runner.Env,
+			corev1.EnvVar{Name: "DOCKER_HOST", Value: "unix:///var/run/docker.sock"},
+			corev1.EnvVar{Name: "RUNNER_WAIT_FOR_DOCKER_IN_SECONDS", Value: "120"},
+		)
+		runner.VolumeMounts = append(runner.VolumeMounts,
+			corev1.VolumeMount{Name: "work", MountPath: runnerWorkDir},
+			corev1.VolumeMount{Name: "dind-sock", MountPath: "/var/run"},
+		)
+
+		template.Spec.InitContainers = append(append([]corev1.Container(nil), template.Spec.InitContainers...), corev1.Container{
+			Name:         "init-dind-externals",
+			Image:        runnerImage,
+			Command:      []string{"cp"},
+			Args:         []string{"-r", runnerExternalsDir + "/.", "/home/runner/tmpDir/"},
+			VolumeMounts: []corev1.VolumeMount{{Name: "dind-externals", MountPath: "/home/runner/tmpDir"}},
+		})
+
+		dind := corev1.Container{
+			Name:  dockerContainerName,
+			Image: dockerImage,
+			Args: []string{
+				"dockerd",
+				"--host=unix:///var/run/docker.sock",
+				"--group=$(DOCKER_GROUP_GID)",
+			},
+			Env:       append([]corev1.EnvVar{{Name: "DOCKER_GROUP_GID", Value: runnerDockerGroupGID}}, spec.DockerEnv...),
+			Resources: spec.DockerdContainerResources,
+			SecurityContext: &corev1.SecurityContext{
+				Privileged: &privileged,
+			},
+			VolumeMounts: append([]corev1.VolumeMount{
+				{Name: "work", MountPath: runnerWorkDir},
+				{Name: "dind-sock", MountPath: "/var/run"},
+				{Name: "dind-externals", MountPath: runnerExternalsDir},
+			}, spec.DockerVolumeMounts...),
+		}
+		if spec.DockerRegistryMirror != nil && *spec.DockerRegistryMirror != "" {
+			dind.Args = append(dind.Args, "--registry-mirror="+*spec.DockerRegistryMirror)
+		}
+		if spec.DockerMTU != nil {
+			dind.Args = append(dind.Args, fmt.Sprintf("--mtu=%d", *spec.DockerMTU))
+		}
+		sidecars = append([]corev1.Container{dind}, sidecars...)
+
+		if !hasVolume(template.Spec.Volumes, "work") {
+			template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{Name: "work", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+		}
+		template.Spec.Volumes = append(template.Spec.Volumes,
+			corev1.Volume{Name: "dind-sock", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			corev1.Volume{Name: "dind-externals", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		)
+	}
+
+	template.Spec.Containers = append([]corev1.Container{runner}, sidecars...)
+
+	return template
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Migrator migrates the RunnerDeployments of a namespace, or of all the namespaces when Namespace is empty.
+type Migrator struct {
+	Client    client.Client
+	Namespace string
+	Options   Options
+
+	// Apply creates or updates the AutoscalingRunnerSets, and scales the RunnerDeployments and their HorizontalRunnerAutoscalers
+	// down to the remaining capacity. Otherwise, the resources are only written to Out.
+	Apply bool
+
+	// Out is where the migrated resources are written to in YAML.
+	Out io.Writer
+
+	Log logr.Logger
+}
+
+// Run migrates the RunnerDeployments in the order of their namespaces and names.
+func (m *Migrator) Run(ctx context.Context) error {
+	var rdList summerwindv1alpha1.RunnerDeploymentList
+	if err := m.Client.List(ctx, &rdList, client.InNamespace(m.Namespace)); err != nil {
+		return fmt.Errorf("listing runner deployments: %w", err)
+	}
+
+	var hraList summerwindv1alpha1.HorizontalRunnerAutoscalerList
+	if err := m.Client.List(ctx, &hraList, client.InNamespace(m.Namespace)); err != nil {
+		return fmt.Errorf("listing horizontal runner autoscalers: %w", err)
+	}
+
+	var arsList githubv1alpha1.AutoscalingRunnerSetList
+	if err := m.Client.List(ctx, &arsList, client.InNamespace(m.Namespace)); err != nil {
+		return fmt.Errorf("listing autoscaling runner sets: %w", err)
+	}
+
+	sort.Slice(rdList.Items, func(i, j int) bool {
+		if rdList.Items[i].Namespace != rdList.Items[j].Namespace {
+			return rdList.Items[i].Namespace < rdList.Items[j].Namespace
+		}
+		return rdList.Items[i].Name < rdList.Items[j].Name
+	})
+
+	for i := range rdList.Items {
+		rd := &rdList.Items[i]
+		log := m.Log.WithValues("runnerdeployment", rd.Namespace+"/"+rd.Name)
+
+		hra := horizontalRunnerAutoscalerFor(rd, hraList.Items)
+		if ars := autoscalingRunnerSetFor(rd, arsList.Items); ars != nil {
+			rd, hra = withMigratedCapacity(rd, hra, ars)
+		}
+
+		res, err := Convert(rd, hra, m.Options)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range res.Warnings {
+			log.Info("Warning: " + w)
+		}
+
+		for _, obj := range res.Objects() {
+			if err := m.write(obj); err != nil {
+				return err
+			}
+
+			if !m.Apply {
+				continue
+			}
+
+			if err := m.apply(ctx, obj); err != nil {
+				return err
+			}
+			log.Info("Applied", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) write(obj client.Object) error {
+	if m.Out == nil {
+		return nil
+	}
+
+	switch o := obj.(type) {
+	case *summerwindv1alpha1.HorizontalRunnerAutoscaler:
+		o.TypeMeta = metav1.TypeMeta{APIVersion: summerwindv1alpha1.GroupVersion.String(), Kind: "HorizontalRunnerAutoscaler"}
+		o.ManagedFields = nil
+	case *summerwindv1alpha1.RunnerDeployment:
+		o.TypeMeta = metav1.TypeMeta{APIVersion: summerwindv1alpha1.GroupVersion.String(), Kind: "RunnerDeployment"}
+		o.ManagedFields = nil
+	}
+
+	buf, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", obj.GetName(), err)
+	}
+
+	_, err = fmt.Fprintf(m.Out, "---\n%s", buf)
+	return err
+}
+
+func (m *Migrator) apply(ctx context.Context, obj client.Object) error {
+	ars, ok := obj.(*githubv1alpha1.AutoscalingRunnerSet)
+	if !ok {
+		// The scaled down RunnerDeployment or HorizontalRunnerAutoscaler read from the cluster
+		return m.Client.Update(ctx, obj)
+	}
+
+	var current githubv1alpha1.AutoscalingRunnerSet
+	err := m.Client.Get(ctx, client.ObjectKeyFromObject(ars), &current)
+	if kerrors.IsNotFound(err) {
+		return m.Client.Create(ctx, ars)
+	}
+	if err != nil {
+		return fmt.Errorf("getting autoscaling runner set %s/%s: %w", ars.Namespace, ars.Name, err)
+	}
+
+	if current.Annotations[AnnotationKeyMigratedFrom] != ars.Annotations[AnnotationKeyMigratedFrom] {
+		return fmt.Errorf("autoscaling runner set %s/%s already exists and was not migrated from runner deployment %s", ars.Namespace, ars.Name, ars.Name)
+	}
+
+	current.Spec = ars.Spec
+	return m.Client.Update(ctx, &current)
+}
+
+// horizontalRunnerAutoscalerFor returns the HorizontalRunnerAutoscaler scaling the RunnerDeployment, if any.
+func horizontalRunnerAutoscalerFor(rd *summerwindv1alpha1.RunnerDeployment, hras []summerwindv1alpha1.HorizontalRunnerAutoscaler) *summerwindv1alpha1.HorizontalRunnerAutoscaler {
+	for i := range hras {
+		hra := &hras[i]
+		ref := hra.Spec.ScaleTargetRef
+		if hra.Namespace == rd.Namespace && (ref.Kind == "" || ref.Kind == "RunnerDeployment") && ref.Name == rd.Name {
+			return hra
+		}
+	}
+	return nil
+}
+
+// autoscalingRunnerSetFor returns the AutoscalingRunnerSet previously migrated from the RunnerDeployment, if any.
+func autoscalingRunnerSetFor(rd *summerwindv1alpha1.RunnerDeployment, arss []githubv1alpha1.AutoscalingRunnerSet) *githubv1alpha1.AutoscalingRunnerSet {
+	for i := range arss {
+		ars := &arss[i]
+		if ars.Namespace == rd.Namespace && ars.Annotations[AnnotationKeyMigratedFrom] == "RunnerDeployment/"+rd.Name {
+			return ars
+		}
+	}
+	return nil
+}
+
+// withMigratedCapacity adds the capacity already moved to the AutoscalingRunnerSet back to the RunnerDeployment or its HorizontalRunnerAutoscaler,
+// so that re-running the migration with another CapacityPercent splits the whole capacity again.
+func withMigratedCapacity(rd *summerwindv1alpha1.RunnerDeployment, hra *summerwindv1alpha1.HorizontalRunnerAutoscaler, ars *githubv1alpha1.AutoscalingRunnerSet) (*summerwindv1alpha1.RunnerDeployment, *summerwindv1alpha1.HorizontalRunnerAutoscaler) {
+	var migratedMin, migratedMax int
+	if ars.Spec.MinRunners != nil {
+		migratedMin = *ars.Spec.MinRunners
+	}
+	if ars.Spec.MaxRunners != nil {
+		migratedMax = *ars.Spec.MaxRunners
+	}
+
+	if hra != nil {
+		hra = hra.DeepCopy()
+		if hra.Spec.MinReplicas != nil {
+			minReplicas := *hra.Spec.MinReplicas + migratedMin
+			hra.Spec.MinReplicas = &minReplicas
+		}
+		if hra.Spec.MaxReplicas != nil {
+			maxReplicas := *hra.Spec.MaxReplicas + migratedMax
+			hra.Spec.MaxReplicas = &maxReplicas
+		}
+		return rd, hra
+	}
+
+	if rd.Spec.Replicas != nil {
+		rd = rd.DeepCopy()
+		replicas := *rd.Spec.Replicas + migratedMax
+		rd.Spec.Replicas = &replicas
+	}
+	return rd, hra
+}
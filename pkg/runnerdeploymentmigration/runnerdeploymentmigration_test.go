@@ -0,0 +1,200 @@
+package runnerdeploymentmigration
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	githubv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	summerwindv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func intPtr(v int) *int { return &v }
+
+func newRunnerDeployment(name string, labels ...string) *summerwindv1alpha1.RunnerDeployment {
+	rd := &summerwindv1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+	}
+	rd.Spec.Template.Spec.Organization = "my-org"
+	rd.Spec.Template.Spec.Labels = labels
+	return rd
+}
+
+func newHorizontalRunnerAutoscaler(name, target string, min, max int) *summerwindv1alpha1.HorizontalRunnerAutoscaler {
+	return &summerwindv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: summerwindv1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: summerwindv1alpha1.ScaleTargetRef{Name: target},
+			MinReplicas:    intPtr(min),
+			MaxReplicas:    intPtr(max),
+		},
+	}
+}
+
+var testOptions = Options{
+	GitHubConfigSecret: "github-config",
+	ControllerVersion:  "0.10.1",
+	CapacityPercent:    100,
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("autoscaled with docker", func(t *testing.T) {
+		rd := newRunnerDeployment("example", "gpu")
+		rd.Spec.Template.Spec.Group = "legacy"
+
+		opts := testOptions
+		opts.LabelRunnerGroups = map[string]string{"gpu": "gpu-runners"}
+
+		res, err := Convert(rd, newHorizontalRunnerAutoscaler("example", "example", 1, 10), opts)
+		require.NoError(t, err)
+		assert.Empty(t, res.Warnings)
+
+		ars := res.AutoscalingRunnerSet
+		assert.Equal(t, "example", ars.Name)
+		assert.Equal(t, "0.10.1", ars.Labels["app.kubernetes.io/version"])
+		assert.Equal(t, "RunnerDeployment/example", ars.Annotations[AnnotationKeyMigratedFrom])
+		assert.Equal(t, "https://github.com/my-org", ars.Spec.GitHubConfigUrl)
+		assert.Equal(t, "github-config", ars.Spec.GitHubConfigSecret)
+		assert.Equal(t, "gpu", ars.Spec.RunnerScaleSetName)
+		assert.Equal(t, "gpu-runners", ars.Spec.RunnerGroup)
+		assert.Equal(t, intPtr(1), ars.Spec.MinRunners)
+		assert.Equal(t, intPtr(10), ars.Spec.MaxRunners)
+
+		require.Len(t, ars.Spec.Template.Spec.Containers, 2)
+		assert.Equal(t, "runner", ars.Spec.Template.Spec.Containers[0].Name)
+		assert.Equal(t, DefaultRunnerImage, ars.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, "dind", ars.Spec.Template.Spec.Containers[1].Name)
+		require.Len(t, ars.Spec.Template.Spec.InitContainers, 1)
+		assert.Len(t, ars.Spec.Template.Spec.Volumes, 3)
+
+		require.NotNil(t, res.HorizontalRunnerAutoscaler)
+		assert.Equal(t, intPtr(0), res.HorizontalRunnerAutoscaler.Spec.MinReplicas)
+		assert.Equal(t, intPtr(0), res.HorizontalRunnerAutoscaler.Spec.MaxReplicas)
+		assert.Nil(t, res.RunnerDeployment)
+	})
+
+	t.Run("side by side", func(t *testing.T) {
+		rd := newRunnerDeployment("example", "linux", "gpu")
+		rd.Spec.Template.Spec.DockerEnabled = new(bool)
+
+		opts := testOptions
+		opts.CapacityPercent = 25
+
+		res, err := Convert(rd, newHorizontalRunnerAutoscaler("example", "example", 2, 10), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`runner scale sets are routed jobs by their name: update the workflows running on the labels linux,gpu to run on "example"`}, res.Warnings)
+
+		ars := res.AutoscalingRunnerSet
+		assert.Equal(t, "example", ars.Spec.RunnerScaleSetName)
+		assert.Equal(t, intPtr(1), ars.Spec.MinRunners)
+		assert.Equal(t, intPtr(3), ars.Spec.MaxRunners)
+		assert.Len(t, ars.Spec.Template.Spec.Containers, 1)
+		assert.Empty(t, ars.Spec.Template.Spec.Volumes)
+
+		assert.Equal(t, intPtr(1), res.HorizontalRunnerAutoscaler.Spec.MinReplicas)
+		assert.Equal(t, intPtr(7), res.HorizontalRunnerAutoscaler.Spec.MaxReplicas)
+	})
+
+	t.Run("static replicas", func(t *testing.T) {
+		rd := newRunnerDeployment("example")
+		rd.Spec.Replicas = intPtr(4)
+		rd.Spec.Template.Spec.Organization = ""
+		rd.Spec.Template.Spec.Repository = "my-org/my-repo"
+
+		opts := testOptions
+		opts.GitHubURL = "https://ghes.example.com/"
+		opts.CapacityPercent = 50
+
+		res, err := Convert(rd, nil, opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://ghes.example.com/my-org/my-repo", res.AutoscalingRunnerSet.Spec.GitHubConfigUrl)
+		assert.Equal(t, intPtr(2), res.AutoscalingRunnerSet.Spec.MinRunners)
+		assert.Equal(t, intPtr(2), res.AutoscalingRunnerSet.Spec.MaxRunners)
+		require.NotNil(t, res.RunnerDeployment)
+		assert.Equal(t, intPtr(2), res.RunnerDeployment.Spec.Replicas)
+	})
+
+	t.Run("no capacity shifted", func(t *testing.T) {
+		opts := testOptions
+		opts.CapacityPercent = 0
+
+		res, err := Convert(newRunnerDeployment("example"), newHorizontalRunnerAutoscaler("example", "example", 1, 5), opts)
+		require.NoError(t, err)
+		assert.Equal(t, intPtr(0), res.AutoscalingRunnerSet.Spec.MaxRunners)
+		assert.Nil(t, res.HorizontalRunnerAutoscaler)
+		assert.Len(t, res.Objects(), 1)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := Convert(newRunnerDeployment("example"), nil, Options{ControllerVersion: "0.10.1"})
+		assert.ErrorContains(t, err, "github config secret is required")
+
+		opts := testOptions
+		opts.CapacityPercent = 101
+		_, err = Convert(newRunnerDeployment("example"), nil, opts)
+		assert.ErrorContains(t, err, "capacity percent must be between 0 and 100")
+
+		rd := newRunnerDeployment("example")
+		rd.Spec.Template.Spec.Organization = ""
+		_, err = Convert(rd, nil, testOptions)
+		assert.ErrorContains(t, err, "none of enterprise, organization and repository is set")
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	sc := runtime.NewScheme()
+	require.NoError(t, summerwindv1alpha1.AddToScheme(sc))
+	require.NoError(t, githubv1alpha1.AddToScheme(sc))
+
+	c := clientfake.NewClientBuilder().WithScheme(sc).WithObjects(
+		newRunnerDeployment("example", "gpu"),
+		newHorizontalRunnerAutoscaler("example-autoscaler", "example", 1, 4),
+	).Build()
+
+	var out bytes.Buffer
+
+	opts := testOptions
+	opts.CapacityPercent = 50
+
+	m := &Migrator{Client: c, Options: opts, Out: &out, Log: logr.Discard()}
+
+	require.NoError(t, m.Run(ctx))
+	assert.Contains(t, out.String(), "kind: AutoscalingRunnerSet")
+	assert.Contains(t, out.String(), "kind: HorizontalRunnerAutoscaler")
+
+	var ars githubv1alpha1.AutoscalingRunnerSet
+	err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &ars)
+	require.Error(t, err, "the resources must not be applied without Apply")
+
+	m.Apply = true
+	require.NoError(t, m.Run(ctx))
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &ars))
+	assert.Equal(t, intPtr(2), ars.Spec.MaxRunners)
+
+	var hra summerwindv1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-autoscaler"}, &hra))
+	assert.Equal(t, intPtr(2), hra.Spec.MaxReplicas)
+	assert.Equal(t, intPtr(0), hra.Spec.MinReplicas)
+
+	// Shifting the whole capacity splits the capacity of both again
+	m.Options.CapacityPercent = 100
+	require.NoError(t, m.Run(ctx))
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &ars))
+	assert.Equal(t, intPtr(1), ars.Spec.MinRunners)
+	assert.Equal(t, intPtr(4), ars.Spec.MaxRunners)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-autoscaler"}, &hra))
+	assert.Equal(t, intPtr(0), hra.Spec.MaxReplicas)
+}
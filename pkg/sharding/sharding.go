@@ -0,0 +1,303 @@
+// Package sharding splits the resources of actions-runner-controller across multiple controller deployments,
+// each of which claims a subset of the namespaces or the GitHub organizations listed in a coordination ConfigMap.
+//
+// The ConfigMap has the shards under the "shards.yaml" key, like:
+//
+//	data:
+//	  shards.yaml: |
+//	    - name: shard-a
+//	      namespaces: [team-a, team-b]
+//	    - name: shard-b
+//	      organizations: [my-org]
+//	      webhookURL: http://shard-b-github-webhook-server.actions-runner-system.svc
+//	    - name: shard-c
+//	      default: true
+//
+// A resource belongs to the shard claiming its organization, or to the shard claiming its namespace when no shard claims the organization,
+// or to the default shard when no shard claims either.
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/github/actions"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapKey is the key of the shards in the coordination ConfigMap.
+	ConfigMapKey = "shards.yaml"
+
+	DefaultRefreshInterval = time.Minute
+)
+
+// ErrShardsChanged is returned by Watcher when the shards in the coordination ConfigMap change,
+// so that the controller restarts and claims the resources anew.
+var ErrShardsChanged = errors.New("the shards in the coordination ConfigMap changed")
+
+// Shard is a controller deployment and the resources it claims.
+type Shard struct {
+	// Name is the shard key the controller deployment is started with.
+	Name string `json:"name"`
+
+	// Namespaces are the namespaces of the resources the shard claims.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Organizations are the owners of the runners the shard claims,
+	// which are the organizations, the owners of the repositories, or the enterprises the runners are registered at.
+	Organizations []string `json:"organizations,omitempty"`
+
+	// Default makes the shard claim the resources no other shard claims.
+	Default bool `json:"default,omitempty"`
+
+	// WebhookURL is the URL of the github-webhook-server of the shard,
+	// which the webhook deliveries for the organizations of the shard are forwarded to.
+	WebhookURL string `json:"webhookURL,omitempty"`
+}
+
+// Parse parses and validates the shards in the coordination ConfigMap.
+func Parse(data []byte) ([]Shard, error) {
+	var shards []Shard
+	if err := yaml.UnmarshalStrict(data, &shards); err != nil {
+		return nil, fmt.Errorf("parsing shards: %w", err)
+	}
+
+	names := map[string]bool{}
+	namespaces := map[string]string{}
+	organizations := map[string]string{}
+	var defaultShard string
+
+	for _, s := range shards {
+		if s.Name == "" {
+			return nil, errors.New("a shard has no name")
+		}
+
+		if names[s.Name] {
+			return nil, fmt.Errorf("shard %s is listed twice", s.Name)
+		}
+		names[s.Name] = true
+
+		for _, ns := range s.Namespaces {
+			if other, ok := namespaces[ns]; ok {
+				return nil, fmt.Errorf("namespace %s is claimed by both shard %s and %s", ns, other, s.Name)
+			}
+			namespaces[ns] = s.Name
+		}
+
+		for _, org := range s.Organizations {
+			org = strings.ToLower(org)
+			if other, ok := organizations[org]; ok {
+				return nil, fmt.Errorf("organization %s is claimed by both shard %s and %s", org, other, s.Name)
+			}
+			organizations[org] = s.Name
+		}
+
+		if s.Default {
+			if defaultShard != "" {
+				return nil, fmt.Errorf("both shard %s and %s are default", defaultShard, s.Name)
+			}
+			defaultShard = s.Name
+		}
+	}
+
+	return shards, nil
+}
+
+// Load reads the shards from the coordination ConfigMap.
+func Load(ctx context.Context, c client.Reader, key types.NamespacedName) ([]Shard, error) {
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, key, &cm); err != nil {
+		return nil, fmt.Errorf("getting the coordination ConfigMap %s: %w", key, err)
+	}
+
+	data, ok := cm.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("the coordination ConfigMap %s has no %s key", key, ConfigMapKey)
+	}
+
+	return Parse([]byte(data))
+}
+
+// Lookup returns the shard of the resource in the namespace for the owner, or nil when no shard claims it.
+// owner is empty when the resource tells no owner.
+func Lookup(shards []Shard, namespace, owner string) *Shard {
+	if owner != "" {
+		for i, s := range shards {
+			for _, org := range s.Organizations {
+				if strings.EqualFold(org, owner) {
+					return &shards[i]
+				}
+			}
+		}
+	}
+
+	for i, s := range shards {
+		for _, ns := range s.Namespaces {
+			if ns == namespace {
+				return &shards[i]
+			}
+		}
+	}
+
+	for i, s := range shards {
+		if s.Default {
+			return &shards[i]
+		}
+	}
+
+	return nil
+}
+
+// Owner returns the owner of the runners registered at the enterprise, the organization, or the repository in the OWNER/REPO format.
+func Owner(enterprise, organization, repository string) string {
+	if organization != "" {
+		return organization
+	}
+
+	if owner, _, ok := strings.Cut(repository, "/"); ok {
+		return owner
+	}
+
+	return enterprise
+}
+
+// OwnerOfGitHubConfigURL returns the owner of the runners registered at the GitHub config URL of a runner scale set.
+// It returns an empty string for an invalid URL, whose resource then follows its namespace.
+func OwnerOfGitHubConfigURL(configURL string) string {
+	config, err := actions.ParseGitHubConfigFromURL(configURL)
+	if err != nil {
+		return ""
+	}
+
+	return Owner(config.Enterprise, config.Organization, config.Repository)
+}
+
+// Sharder tells whether the resources belong to the shard of the controller.
+// A nil Sharder owns every resource, which is the case when sharding is disabled.
+type Sharder struct {
+	name   string
+	shards []Shard
+}
+
+// New returns the Sharder of the named shard, which must be in the shards.
+func New(name string, shards []Shard) (*Sharder, error) {
+	for _, s := range shards {
+		if s.Name == name {
+			return &Sharder{name: name, shards: shards}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("shard %s is not in the coordination ConfigMap", name)
+}
+
+// Name returns the name of the shard.
+func (s *Sharder) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// Owns tells whether the resource in the namespace for the owner belongs to the shard.
+func (s *Sharder) Owns(namespace, owner string) bool {
+	if s == nil {
+		return true
+	}
+
+	shard := Lookup(s.shards, namespace, owner)
+
+	return shard != nil && shard.Name == s.name
+}
+
+// ShardOf returns the shard claiming the owner in its organizations, or nil when no shard claims it.
+func (s *Sharder) ShardOf(owner string) *Shard {
+	if s == nil || owner == "" {
+		return nil
+	}
+
+	for i, shard := range s.shards {
+		for _, org := range shard.Organizations {
+			if strings.EqualFold(org, owner) {
+				return &s.shards[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// Namespaces returns the namespaces the controller needs to watch, or nil when it needs to watch all the namespaces.
+// The watch can be limited only when every shard claims namespaces alone and the shard isn't the default one,
+// since the resources of a claimed organization or of the unclaimed namespaces can be anywhere.
+func (s *Sharder) Namespaces() []string {
+	if s == nil {
+		return nil
+	}
+
+	var namespaces []string
+
+	for _, shard := range s.shards {
+		if len(shard.Organizations) > 0 || (shard.Default && shard.Name == s.name) {
+			return nil
+		}
+
+		if shard.Name == s.name {
+			namespaces = append(namespaces, shard.Namespaces...)
+		}
+	}
+
+	return namespaces
+}
+
+// Watcher periodically reads the coordination ConfigMap, and fails with ErrShardsChanged once the shards change.
+// It runs on every replica of the controller, including the ones that aren't the leader.
+type Watcher struct {
+	Client   client.Reader
+	Key      types.NamespacedName
+	Interval time.Duration
+	Log      logr.Logger
+
+	// Shards are the shards the controller started with.
+	Shards []Shard
+}
+
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *Watcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			shards, err := Load(ctx, w.Client, w.Key)
+			if err != nil {
+				w.Log.Error(err, "Failed to read the shards. The shards the controller started with are kept")
+				continue
+			}
+
+			if !reflect.DeepEqual(shards, w.Shards) {
+				w.Log.Info("The shards changed. Restarting to claim the resources anew", "configMap", w.Key.String())
+				return ErrShardsChanged
+			}
+		}
+	}
+}
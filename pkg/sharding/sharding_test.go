@@ -0,0 +1,94 @@
+package sharding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testShards = `
+- name: shard-a
+  namespaces: [team-a, team-b]
+- name: shard-b
+  organizations: [My-Org]
+  webhookURL: http://shard-b
+- name: shard-c
+  default: true
+`
+
+func TestParse(t *testing.T) {
+	shards, err := Parse([]byte(testShards))
+	require.NoError(t, err)
+	require.Len(t, shards, 3)
+	assert.Equal(t, Shard{Name: "shard-b", Organizations: []string{"My-Org"}, WebhookURL: "http://shard-b"}, shards[1])
+
+	for name, data := range map[string]string{
+		"NoName":             "- namespaces: [a]",
+		"DuplicateName":      "- name: a\n- name: a",
+		"DuplicateNamespace": "- name: a\n  namespaces: [x]\n- name: b\n  namespaces: [x]",
+		"DuplicateOrg":       "- name: a\n  organizations: [Org]\n- name: b\n  organizations: [org]",
+		"TwoDefaults":        "- name: a\n  default: true\n- name: b\n  default: true",
+		"UnknownField":       "- name: a\n  namespace: x",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	shards, err := Parse([]byte(testShards))
+	require.NoError(t, err)
+
+	name := func(s *Shard) string {
+		if s == nil {
+			return ""
+		}
+		return s.Name
+	}
+
+	assert.Equal(t, "shard-a", name(Lookup(shards, "team-a", "other-org")))
+	assert.Equal(t, "shard-b", name(Lookup(shards, "team-a", "my-org")), "the organization takes precedence over the namespace")
+	assert.Equal(t, "shard-c", name(Lookup(shards, "team-c", "")))
+	assert.Equal(t, "", name(Lookup(shards[:2], "team-c", "")))
+}
+
+func TestSharder(t *testing.T) {
+	shards, err := Parse([]byte(testShards))
+	require.NoError(t, err)
+
+	_, err = New("shard-d", shards)
+	assert.Error(t, err)
+
+	a, err := New("shard-a", shards)
+	require.NoError(t, err)
+
+	assert.True(t, a.Owns("team-b", ""))
+	assert.False(t, a.Owns("team-b", "my-org"))
+	assert.False(t, a.Owns("team-c", ""))
+	assert.Nil(t, a.Namespaces(), "the resources of my-org can be in any namespace")
+
+	assert.Equal(t, "shard-b", a.ShardOf("MY-ORG").Name)
+	assert.Nil(t, a.ShardOf("other-org"))
+
+	onlyNamespaces, err := New("shard-a", []Shard{shards[0], shards[2]})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, onlyNamespaces.Namespaces())
+
+	var disabled *Sharder
+	assert.True(t, disabled.Owns("team-c", "my-org"))
+	assert.Nil(t, disabled.Namespaces())
+}
+
+func TestOwner(t *testing.T) {
+	assert.Equal(t, "org", Owner("ent", "org", ""))
+	assert.Equal(t, "owner", Owner("ent", "", "owner/repo"))
+	assert.Equal(t, "ent", Owner("ent", "", ""))
+
+	assert.Equal(t, "org", OwnerOfGitHubConfigURL("https://github.com/org"))
+	assert.Equal(t, "owner", OwnerOfGitHubConfigURL("https://github.com/owner/repo"))
+	assert.Equal(t, "ent", OwnerOfGitHubConfigURL("https://github.com/enterprises/ent"))
+	assert.Equal(t, "", OwnerOfGitHubConfigURL("://invalid"))
+}
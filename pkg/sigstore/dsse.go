@@ -0,0 +1,241 @@
+package sigstore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// InTotoPayloadType is the payload type of the DSSE envelopes of the in-toto attestations.
+const InTotoPayloadType = "application/vnd.in-toto+json"
+
+// Envelope is a DSSE envelope. The payload and the signatures are base64 encoded in JSON.
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     []byte              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// PAE returns the pre-authentication encoding of the payload, which is what the signatures of the envelope sign.
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// Verify returns the signature of the envelope verified with the public key.
+func (e *Envelope) Verify(key crypto.PublicKey) ([]byte, error) {
+	if len(e.Signatures) == 0 {
+		return nil, errors.New("envelope has no signature")
+	}
+
+	pae := PAE(e.PayloadType, e.Payload)
+
+	var errs []error
+
+	for _, s := range e.Signatures {
+		err := VerifySignature(key, pae, s.Sig)
+		if err == nil {
+			return s.Sig, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Statement is an in-toto attestation statement.
+type Statement struct {
+	Type          string          `json:"_type"`
+	PredicateType string          `json:"predicateType"`
+	Subject       []Subject       `json:"subject"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// HasSubject returns true when the statement is about the artifact of the digest, in the ALGORITHM:HEX format.
+func (s *Statement) HasSubject(digest string) bool {
+	for _, sub := range s.Subject {
+		for alg, v := range sub.Digest {
+			if alg+":"+v == digest {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ParseStatement parses the in-toto statement in the payload of the envelope.
+func ParseStatement(e *Envelope) (*Statement, error) {
+	if e.PayloadType != InTotoPayloadType {
+		return nil, fmt.Errorf("unsupported payload type %q", e.PayloadType)
+	}
+
+	var s Statement
+	if err := json.Unmarshal(e.Payload, &s); err != nil {
+		return nil, fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+
+	return &s, nil
+}
+
+// protoInt64 is an int64 of the protobuf JSON, encoded as either a string or a number.
+type protoInt64 int64
+
+func (i *protoInt64) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := strconv.ParseInt(s, 10, 64)
+		*i = protoInt64(v)
+		return err
+	}
+
+	var v int64
+	err := json.Unmarshal(b, &v)
+	*i = protoInt64(v)
+
+	return err
+}
+
+// bundleMediaTypeV01 is the media type of the bundles of version 0.1, whose log entries may have only the signed entry timestamps,
+// as they were made before Rekor returned the inclusion proofs. The log entries of the later versions must have the inclusion proofs.
+const bundleMediaTypeV01 = "application/vnd.dev.sigstore.bundle+json;version=0.1"
+
+// bundle is the Sigstore bundle of a DSSE envelope, in the protobuf JSON encoding.
+type bundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		TlogEntries []struct {
+			LogIndex protoInt64 `json:"logIndex"`
+			LogID    struct {
+				KeyID []byte `json:"keyId"`
+			} `json:"logId"`
+			IntegratedTime   protoInt64 `json:"integratedTime"`
+			InclusionPromise *struct {
+				SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+			} `json:"inclusionPromise"`
+			InclusionProof *struct {
+				LogIndex   protoInt64 `json:"logIndex"`
+				RootHash   []byte     `json:"rootHash"`
+				TreeSize   protoInt64 `json:"treeSize"`
+				Hashes     [][]byte   `json:"hashes"`
+				Checkpoint struct {
+					Envelope string `json:"envelope"`
+				} `json:"checkpoint"`
+			} `json:"inclusionProof"`
+			CanonicalizedBody []byte `json:"canonicalizedBody"`
+		} `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *Envelope `json:"dsseEnvelope"`
+}
+
+// VerifyBundle verifies the Sigstore bundle of a DSSE envelope, like an artifact attestation of GitHub,
+// and returns the in-toto statement in it. The envelope must be signed with the key of the Fulcio certificate issued to one of the identities,
+// and logged in the Rekor log while the certificate was valid, with the proof of the inclusion of the log entry unless the bundle is of version 0.1.
+func (v *Verifier) VerifyBundle(data []byte) (*Statement, error) {
+	var b bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parsing bundle: %w", err)
+	}
+
+	if b.DSSEEnvelope == nil {
+		return nil, fmt.Errorf("bundle of %s has no DSSE envelope", b.MediaType)
+	}
+
+	var certs []*x509.Certificate
+
+	switch m := b.VerificationMaterial; {
+	case m.Certificate != nil:
+		c, err := x509.ParseCertificate(m.Certificate.RawBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, c)
+	case m.X509CertificateChain != nil:
+		for _, raw := range m.X509CertificateChain.Certificates {
+			c, err := x509.ParseCertificate(raw.RawBytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			certs = append(certs, c)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("bundle has no certificate")
+	}
+
+	sig, err := b.DSSEEnvelope.Verify(certs[0].PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("verifying envelope: %w", err)
+	}
+
+	var errs []error
+
+	for _, e := range b.VerificationMaterial.TlogEntries {
+		if e.InclusionPromise == nil {
+			errs = append(errs, errors.New("log entry has no signed entry timestamp"))
+			continue
+		}
+
+		var proof *InclusionProof
+
+		if e.InclusionProof != nil {
+			proof = &InclusionProof{
+				LogIndex:   int64(e.InclusionProof.LogIndex),
+				TreeSize:   int64(e.InclusionProof.TreeSize),
+				RootHash:   e.InclusionProof.RootHash,
+				Hashes:     e.InclusionProof.Hashes,
+				Checkpoint: e.InclusionProof.Checkpoint.Envelope,
+			}
+		} else if b.MediaType != bundleMediaTypeV01 {
+			errs = append(errs, errors.New("log entry has no inclusion proof"))
+			continue
+		}
+
+		signedAt, err := v.VerifyLogEntry(LogEntry{
+			Body:                 e.CanonicalizedBody,
+			IntegratedTime:       int64(e.IntegratedTime),
+			LogIndex:             int64(e.LogIndex),
+			LogID:                fmt.Sprintf("%x", e.LogID.KeyID),
+			SignedEntryTimestamp: e.InclusionPromise.SignedEntryTimestamp,
+			InclusionProof:       proof,
+		}, certs[0], sig, b.DSSEEnvelope.Payload)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, err := v.VerifyCertificate(certs[0], certs[1:], signedAt); err != nil {
+			return nil, err
+		}
+
+		return ParseStatement(b.DSSEEnvelope)
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("bundle has no log entry")
+	}
+
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,386 @@
+// Package fake is a fake Sigstore instance for testing the verification of the keyless signatures:
+// the Fulcio CA issuing the certificates to any identity, and the Rekor log promising and proving the inclusion of any entry.
+package fake
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+)
+
+// oidIssuer is the Fulcio extension of the OIDC issuer, as the DER encoded UTF8String.
+var oidIssuer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+
+// Sigstore is the fake Fulcio CA and Rekor log.
+type Sigstore struct {
+	// Now is the time the certificates are issued and the entries are logged at. Defaults to the current time.
+	Now time.Time
+
+	caKey    *ecdsa.PrivateKey
+	ca       *x509.Certificate
+	rekorKey *ecdsa.PrivateKey
+	rekorDER []byte
+	// leaves are the leaf hashes of the entries logged so far.
+	leaves [][]byte
+}
+
+// New returns the fake Sigstore with the new keys of the CA and the log.
+func New() (*Sigstore, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"sigstore.dev"}, CommonName: "sigstore"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	rekorDER, err := x509.MarshalPKIXPublicKey(&rekorKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sigstore{caKey: caKey, ca: ca, rekorKey: rekorKey, rekorDER: rekorDER}, nil
+}
+
+func (s *Sigstore) now() time.Time {
+	if s.Now.IsZero() {
+		return time.Now()
+	}
+
+	return s.Now
+}
+
+// FulcioCertificates returns the PEM encoded root certificate of the CA, like fulcio_v1.crt.pem.
+func (s *Sigstore) FulcioCertificates() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.ca.Raw})
+}
+
+// RekorPublicKeys returns the PEM encoded public key of the log, like rekor.pub.
+func (s *Sigstore) RekorPublicKeys() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: s.rekorDER})
+}
+
+// LogID returns the hex encoded ID of the log.
+func (s *Sigstore) LogID() string {
+	sum := sha256.Sum256(s.rekorDER)
+	return hex.EncodeToString(sum[:])
+}
+
+// LogEntry logs the body, returning the entry with the signed entry timestamp and the inclusion proof in the tree of all the entries so far.
+func (s *Sigstore) LogEntry(body []byte) (sigstore.LogEntry, error) {
+	s.leaves = append(s.leaves, sigstore.LeafHash(body))
+
+	index := int64(len(s.leaves) - 1)
+	root := treeHash(s.leaves)
+
+	checkpoint, err := s.checkpoint(int64(len(s.leaves)), root)
+	if err != nil {
+		return sigstore.LogEntry{}, err
+	}
+
+	e := sigstore.LogEntry{
+		Body:           body,
+		IntegratedTime: s.now().Unix(),
+		LogIndex:       index,
+		LogID:          s.LogID(),
+		InclusionProof: &sigstore.InclusionProof{
+			LogIndex:   index,
+			TreeSize:   int64(len(s.leaves)),
+			RootHash:   root,
+			Hashes:     auditPath(int(index), s.leaves),
+			Checkpoint: checkpoint,
+		},
+	}
+
+	set, err := json.Marshal(map[string]any{
+		"body":           base64.StdEncoding.EncodeToString(e.Body),
+		"integratedTime": e.IntegratedTime,
+		"logID":          e.LogID,
+		"logIndex":       e.LogIndex,
+	})
+	if err != nil {
+		return e, err
+	}
+
+	sum := sha256.Sum256(set)
+	e.SignedEntryTimestamp, err = ecdsa.SignASN1(rand.Reader, s.rekorKey, sum[:])
+
+	return e, err
+}
+
+// checkpoint returns the signed note of the root hash at the tree size, signed with the key hint of the log ID like Rekor does.
+func (s *Sigstore) checkpoint(size int64, root []byte) (string, error) {
+	text := fmt.Sprintf("fake.sigstore.dev - 1\n%d\n%s\n", size, base64.StdEncoding.EncodeToString(root))
+
+	sum := sha256.Sum256([]byte(text))
+	sig, err := ecdsa.SignASN1(rand.Reader, s.rekorKey, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	hint, err := hex.DecodeString(s.LogID()[:8])
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s\n— fake.sigstore.dev %s\n", text, base64.StdEncoding.EncodeToString(append(hint, sig...))), nil
+}
+
+// treeHash returns the RFC 6962 Merkle tree hash of the leaves.
+func treeHash(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+
+	k := splitPoint(len(leaves))
+
+	return sigstore.NodeHash(treeHash(leaves[:k]), treeHash(leaves[k:]))
+}
+
+// auditPath returns the RFC 6962 audit path of the m-th leaf, from the leaf to the root.
+func auditPath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+
+	k := splitPoint(len(leaves))
+
+	if m < k {
+		return append(auditPath(m, leaves[:k]), treeHash(leaves[k:]))
+	}
+
+	return append(auditPath(m-k, leaves[k:]), treeHash(leaves[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n.
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+
+	return k
+}
+
+// Signer is a signer with the short-lived certificate issued to its identity.
+type Signer struct {
+	Key *ecdsa.PrivateKey
+	// Certificate is the PEM encoded certificate of Key.
+	Certificate []byte
+
+	sigstore *Sigstore
+}
+
+// NewSigner returns the signer with the certificate issued to the subjects, each either an email or a URI, by the OIDC issuer.
+func (s *Sigstore) NewSigner(issuer string, subjects ...string) (*Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ext, err := asn1.MarshalWithParams(issuer, "utf8")
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(s.now().UnixNano()),
+		NotBefore:       s.now().Add(-time.Minute),
+		NotAfter:        s.now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuer, Value: ext}},
+	}
+
+	for _, subject := range subjects {
+		if !strings.Contains(subject, "://") {
+			template.EmailAddresses = append(template.EmailAddresses, subject)
+			continue
+		}
+
+		u, err := url.Parse(subject)
+		if err != nil {
+			return nil, err
+		}
+		template.URIs = append(template.URIs, u)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, s.ca, &key.PublicKey, s.caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		Key:         key,
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		sigstore:    s,
+	}, nil
+}
+
+// Sign signs the SHA-256 of the message.
+func (sg *Signer) Sign(message []byte) ([]byte, error) {
+	sum := sha256.Sum256(message)
+	return ecdsa.SignASN1(rand.Reader, sg.Key, sum[:])
+}
+
+// SignHashedRekord signs the payload and logs the signature as a hashedrekord entry, like cosign sign does.
+func (sg *Signer) SignHashedRekord(payload []byte) ([]byte, sigstore.LogEntry, error) {
+	sig, err := sg.Sign(payload)
+	if err != nil {
+		return nil, sigstore.LogEntry{}, err
+	}
+
+	sum := sha256.Sum256(payload)
+
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data": map[string]any{
+				"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+			},
+			"signature": map[string]any{
+				"content":   base64.StdEncoding.EncodeToString(sig),
+				"publicKey": map[string]string{"content": base64.StdEncoding.EncodeToString(sg.Certificate)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, sigstore.LogEntry{}, err
+	}
+
+	e, err := sg.sigstore.LogEntry(body)
+
+	return sig, e, err
+}
+
+// SignEnvelope signs the DSSE envelope of the payload and logs it as a dsse entry, like cosign attest and GitHub do.
+func (sg *Signer) SignEnvelope(payloadType string, payload []byte) (*sigstore.Envelope, sigstore.LogEntry, error) {
+	sig, err := sg.Sign(sigstore.PAE(payloadType, payload))
+	if err != nil {
+		return nil, sigstore.LogEntry{}, err
+	}
+
+	sum := sha256.Sum256(payload)
+
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]any{
+			"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+			"signatures": []map[string]string{{
+				"signature": base64.StdEncoding.EncodeToString(sig),
+				"verifier":  base64.StdEncoding.EncodeToString(sg.Certificate),
+			}},
+		},
+	})
+	if err != nil {
+		return nil, sigstore.LogEntry{}, err
+	}
+
+	e, err := sg.sigstore.LogEntry(body)
+
+	envelope := &sigstore.Envelope{
+		PayloadType: payloadType,
+		Payload:     payload,
+		Signatures:  []sigstore.EnvelopeSignature{{Sig: sig}},
+	}
+
+	return envelope, e, err
+}
+
+// Bundle attests the in-toto statement about the subjects of the digests in the ALGORITHM:HEX format,
+// returning the Sigstore bundle like the ones of the GitHub artifact attestations.
+func (sg *Signer) Bundle(predicateType string, digests ...string) ([]byte, error) {
+	var subjects []sigstore.Subject
+
+	for i, d := range digests {
+		alg, v, ok := strings.Cut(d, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid digest %q", d)
+		}
+
+		subjects = append(subjects, sigstore.Subject{Name: fmt.Sprintf("artifact-%d", i), Digest: map[string]string{alg: v}})
+	}
+
+	statement, err := json.Marshal(sigstore.Statement{
+		Type:          "https://in-toto.io/Statement/v1",
+		PredicateType: predicateType,
+		Subject:       subjects,
+		Predicate:     json.RawMessage(`{}`),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, e, err := sg.SignEnvelope(sigstore.InTotoPayloadType, statement)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(sg.Certificate)
+	logID, err := hex.DecodeString(e.LogID)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]any{"rawBytes": block.Bytes},
+			"tlogEntries": []map[string]any{{
+				"logIndex":         fmt.Sprint(e.LogIndex),
+				"logId":            map[string]any{"keyId": logID},
+				"kindVersion":      map[string]string{"kind": "dsse", "version": "0.0.1"},
+				"integratedTime":   fmt.Sprint(e.IntegratedTime),
+				"inclusionPromise": map[string]any{"signedEntryTimestamp": e.SignedEntryTimestamp},
+				"inclusionProof": map[string]any{
+					"logIndex":   fmt.Sprint(e.InclusionProof.LogIndex),
+					"rootHash":   e.InclusionProof.RootHash,
+					"treeSize":   fmt.Sprint(e.InclusionProof.TreeSize),
+					"hashes":     e.InclusionProof.Hashes,
+					"checkpoint": map[string]string{"envelope": e.InclusionProof.Checkpoint},
+				},
+				"canonicalizedBody": e.Body,
+			}},
+		},
+		"dsseEnvelope": envelope,
+	})
+}
@@ -0,0 +1,127 @@
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// InclusionProof is the proof of the inclusion of a log entry in the Merkle tree of the Rekor log,
+// along with the checkpoint the log signed for the root of the tree.
+type InclusionProof struct {
+	// LogIndex is the index of the entry in the tree, which differs from the index of the entry in the log when the log is sharded.
+	LogIndex int64
+	TreeSize int64
+	RootHash []byte
+	// Hashes are the hashes of the audit path from the leaf to the root.
+	Hashes [][]byte
+	// Checkpoint is the signed note of the log committing to the root hash at the tree size.
+	Checkpoint string
+}
+
+// LeafHash returns the RFC 6962 hash of the leaf of the body of a log entry.
+func LeafHash(body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// NodeHash returns the RFC 6962 hash of the interior node of the children.
+func NodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// verifyInclusionProof verifies that the body is the leaf at the index of the tree of the root hash,
+// and that the log of the key signed the checkpoint of the root hash.
+func verifyInclusionProof(p *InclusionProof, body []byte, logID string, key crypto.PublicKey) error {
+	if p.LogIndex < 0 || p.LogIndex >= p.TreeSize {
+		return fmt.Errorf("index %d out of the tree of size %d", p.LogIndex, p.TreeSize)
+	}
+
+	index, size := uint64(p.LogIndex), uint64(p.TreeSize)
+
+	// The audit path is the siblings below the point the path to the leaf and the path to the last leaf fork,
+	// followed by the roots of the subtrees to the left of the path above it, as in RFC 9162
+	inner := bits.Len64(index ^ (size - 1))
+	border := bits.OnesCount64(index >> inner)
+
+	if len(p.Hashes) != inner+border {
+		return fmt.Errorf("inclusion proof has %d hashes, want %d", len(p.Hashes), inner+border)
+	}
+
+	hash := LeafHash(body)
+
+	for i, h := range p.Hashes[:inner] {
+		if (index>>i)&1 == 0 {
+			hash = NodeHash(hash, h)
+		} else {
+			hash = NodeHash(h, hash)
+		}
+	}
+
+	for _, h := range p.Hashes[inner:] {
+		hash = NodeHash(h, hash)
+	}
+
+	if !bytes.Equal(hash, p.RootHash) {
+		return errors.New("inclusion proof doesn't lead to the root hash")
+	}
+
+	return verifyCheckpoint(p.Checkpoint, p.TreeSize, p.RootHash, logID, key)
+}
+
+// verifyCheckpoint verifies the checkpoint, the signed note of the origin, the tree size and the root hash on the first three lines,
+// and the signature lines each of the name and the base64 encoded key hint and signature, after the empty line ending the text.
+// The key hint of Rekor is the first 4 bytes of the log ID.
+func verifyCheckpoint(checkpoint string, size int64, rootHash []byte, logID string, key crypto.PublicKey) error {
+	text, sigs, ok := strings.Cut(checkpoint, "\n\n")
+	if !ok {
+		return errors.New("checkpoint has no signature")
+	}
+
+	// The signed text ends with the newline
+	text += "\n"
+
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return errors.New("malformed checkpoint")
+	}
+
+	if s, err := strconv.ParseInt(lines[1], 10, 64); err != nil || s != size {
+		return fmt.Errorf("checkpoint is of the tree size %q, want %d", lines[1], size)
+	}
+
+	if lines[2] != base64.StdEncoding.EncodeToString(rootHash) {
+		return errors.New("checkpoint is of another root hash")
+	}
+
+	for _, line := range strings.Split(sigs, "\n") {
+		// Each signature line starts with the em dash
+		_, sig, ok := strings.Cut(strings.TrimPrefix(line, "— "), " ")
+		if !ok {
+			continue
+		}
+
+		b, err := base64.StdEncoding.DecodeString(sig)
+		if err != nil || len(b) < 5 || fmt.Sprintf("%x", b[:4]) != logID[:8] {
+			continue
+		}
+
+		if err := VerifySignature(key, []byte(text), b[4:]); err == nil {
+			return nil
+		}
+	}
+
+	return errors.New("checkpoint isn't signed by the log")
+}
@@ -0,0 +1,401 @@
+// Package sigstore verifies the keyless signatures of Sigstore: the Fulcio certificates issued to the OIDC identities of the signers,
+// the entries of the Rekor transparency log proving when the signatures were made, with their signed entry timestamps and inclusion proofs,
+// the DSSE envelopes of the attestations,
+// and the Sigstore bundles packing them together, like the artifact attestations of GitHub.
+//
+// The Fulcio certificates and the Rekor public keys to trust are given explicitly, like the ones of the public-good instance
+// distributed by the TUF repository of Sigstore, so that nothing is fetched from the network on verification.
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// oidIssuerV1 is the Fulcio extension of the OIDC issuer, as the raw string.
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidIssuerV2 is the Fulcio extension of the OIDC issuer, as the DER encoded UTF8String.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// TrustedRoot is the Fulcio certificates and the Rekor public keys the keyless signatures are verified against.
+type TrustedRoot struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	// rekorKeys are the public keys of the Rekor logs by the log ID, the hex encoded SHA-256 of the DER encoded key.
+	rekorKeys map[string]crypto.PublicKey
+}
+
+// NewTrustedRoot returns the TrustedRoot of the PEM encoded Fulcio certificates, like fulcio_v1.crt.pem of the TUF repository,
+// and the PEM encoded Rekor public keys, like rekor.pub. The self-signed certificates are the roots, and the others are the intermediates.
+func NewTrustedRoot(fulcioCertificates, rekorPublicKeys []byte) (*TrustedRoot, error) {
+	t := &TrustedRoot{
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+		rekorKeys:     map[string]crypto.PublicKey{},
+	}
+
+	certs, err := ParseCertificates(fulcioCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Fulcio certificates: %w", err)
+	}
+
+	var roots int
+
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+			t.roots.AddCert(c)
+			roots++
+		} else {
+			t.intermediates.AddCert(c)
+		}
+	}
+
+	if roots == 0 {
+		return nil, errors.New("no root Fulcio certificate found")
+	}
+
+	for rest := rekorPublicKeys; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Rekor public key: %w", err)
+		}
+
+		sum := sha256.Sum256(block.Bytes)
+		t.rekorKeys[hex.EncodeToString(sum[:])] = key
+	}
+
+	if len(t.rekorKeys) == 0 {
+		return nil, errors.New("no Rekor public key found")
+	}
+
+	return t, nil
+}
+
+// ParseCertificates parses the PEM encoded certificates.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, c)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return certs, nil
+}
+
+// Identity is a signer allowed to make the keyless signatures, the OIDC identity the Fulcio certificate is issued to.
+type Identity struct {
+	// Issuer is the OIDC issuer, like https://token.actions.githubusercontent.com for the GitHub Actions workflows.
+	Issuer string
+	// Subject is the subject, either the email or the URI in the certificate, like the URL of the workflow file at the ref.
+	Subject string
+	// SubjectRegExp matches the subject, instead of Subject.
+	SubjectRegExp *regexp.Regexp
+}
+
+func (i Identity) matches(issuer, subject string) bool {
+	if i.Issuer != issuer {
+		return false
+	}
+
+	if i.SubjectRegExp != nil {
+		return i.SubjectRegExp.MatchString(subject)
+	}
+
+	return i.Subject == subject
+}
+
+// Verifier verifies the keyless signatures made by any of Identities.
+type Verifier struct {
+	TrustedRoot *TrustedRoot
+	Identities  []Identity
+}
+
+// VerifyCertificate verifies that the Fulcio certificate chains up to the trusted root, was valid at the signing time,
+// and was issued to one of the identities. It returns the public key of the certificate.
+func (v *Verifier) VerifyCertificate(cert *x509.Certificate, chain []*x509.Certificate, signedAt time.Time) (crypto.PublicKey, error) {
+	intermediates := v.TrustedRoot.intermediates.Clone()
+	for _, c := range chain {
+		if !bytes.Equal(c.RawIssuer, c.RawSubject) {
+			intermediates.AddCert(c)
+		}
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.TrustedRoot.roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("verifying certificate: %w", err)
+	}
+
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := certificateSubjects(cert)
+
+	for _, i := range v.Identities {
+		for _, subject := range subjects {
+			if i.matches(issuer, subject) {
+				return cert.PublicKey, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("certificate issued to %s by %s matches none of the identities", strings.Join(subjects, ", "), issuer)
+}
+
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", fmt.Errorf("parsing the OIDC issuer of the certificate: %w", err)
+			}
+			return issuer, nil
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", errors.New("certificate has no OIDC issuer")
+}
+
+// certificateSubjects returns all the URIs and the emails in the subject alternative names of the certificate,
+// as Fulcio may issue a certificate with more than one of them.
+func certificateSubjects(cert *x509.Certificate) []string {
+	var subjects []string
+
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+
+	return append(subjects, cert.EmailAddresses...)
+}
+
+// LogEntry is an entry of the Rekor transparency log, with the signed entry timestamp promising its inclusion,
+// and the proof of its inclusion when known.
+type LogEntry struct {
+	// Body is the canonicalized body of the entry.
+	Body           []byte
+	IntegratedTime int64
+	LogIndex       int64
+	// LogID is the hex encoded ID of the log.
+	LogID                string
+	SignedEntryTimestamp []byte
+	// InclusionProof is nil for the entries of the Rekor bundles of cosign, which carry only the signed entry timestamp.
+	InclusionProof *InclusionProof
+}
+
+// VerifyLogEntry verifies the signed entry timestamp and the inclusion proof, if any, of the log entry with the trusted Rekor public keys,
+// and that the entry records the signature of the payload made with the key of the certificate. It returns the time the signature was logged,
+// at which the short-lived Fulcio certificate must be valid.
+func (v *Verifier) VerifyLogEntry(e LogEntry, cert *x509.Certificate, sig, payload []byte) (time.Time, error) {
+	key, ok := v.TrustedRoot.rekorKeys[e.LogID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("log entry of the untrusted log %s", e.LogID)
+	}
+
+	// The canonical JSON of the entry, with the keys sorted, signed by the log
+	set, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{base64.StdEncoding.EncodeToString(e.Body), e.IntegratedTime, e.LogID, e.LogIndex})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := VerifySignature(key, set, e.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("verifying signed entry timestamp: %w", err)
+	}
+
+	if e.InclusionProof != nil {
+		if err := verifyInclusionProof(e.InclusionProof, e.Body, e.LogID, key); err != nil {
+			return time.Time{}, fmt.Errorf("verifying inclusion proof: %w", err)
+		}
+	}
+
+	if err := verifyLogEntryBody(e.Body, cert, sig, payload); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(e.IntegratedTime, 0), nil
+}
+
+// verifyLogEntryBody verifies that the body of the log entry records the signature of the payload along with the certificate,
+// either as a hashedrekord of the signed payload, or as a dsse or intoto entry of the envelope of the payload.
+// Otherwise, an entry of the same signature made with another key would vouch for the certificate.
+func verifyLogEntryBody(body []byte, cert *x509.Certificate, sig, payload []byte) error {
+	type hash struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"value"`
+	}
+
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			// hashedrekord
+			Data struct {
+				Hash hash `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+
+			// dsse
+			PayloadHash hash `json:"payloadHash"`
+			Signatures  []struct {
+				Signature string `json:"signature"`
+				Verifier  []byte `json:"verifier"`
+			} `json:"signatures"`
+
+			// intoto
+			Content struct {
+				Envelope struct {
+					Signatures []struct {
+						Sig       string `json:"sig"`
+						PublicKey []byte `json:"publicKey"`
+					} `json:"signatures"`
+				} `json:"envelope"`
+				PayloadHash hash `json:"payloadHash"`
+			} `json:"content"`
+		} `json:"spec"`
+	}
+
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return fmt.Errorf("parsing log entry: %w", err)
+	}
+
+	sum := sha256.Sum256(payload)
+	digest := hash{Algorithm: "sha256", Value: hex.EncodeToString(sum[:])}
+	encodedSig := base64.StdEncoding.EncodeToString(sig)
+
+	switch entry.Kind {
+	case "hashedrekord":
+		if entry.Spec.Data.Hash != digest || entry.Spec.Signature.Content != encodedSig {
+			return errors.New("log entry records another signature")
+		}
+
+		if !isCertificate(entry.Spec.Signature.PublicKey.Content, cert) {
+			return errors.New("log entry records another certificate")
+		}
+	case "dsse":
+		if entry.Spec.PayloadHash != digest {
+			return errors.New("log entry records another payload")
+		}
+
+		var found bool
+		for _, s := range entry.Spec.Signatures {
+			found = found || s.Signature == encodedSig && isCertificate(s.Verifier, cert)
+		}
+
+		if !found {
+			return errors.New("log entry records another signature")
+		}
+	case "intoto":
+		if entry.Spec.Content.PayloadHash != digest {
+			return errors.New("log entry records another payload")
+		}
+
+		// The intoto entries encode the base64 encoded signatures of the envelope in base64 once more
+		doubleEncodedSig := base64.StdEncoding.EncodeToString([]byte(encodedSig))
+
+		var found bool
+		for _, s := range entry.Spec.Content.Envelope.Signatures {
+			found = found || s.Sig == doubleEncodedSig && isCertificate(s.PublicKey, cert)
+		}
+
+		if !found {
+			return errors.New("log entry records another signature")
+		}
+	default:
+		return fmt.Errorf("unsupported kind of log entry %q", entry.Kind)
+	}
+
+	return nil
+}
+
+// isCertificate returns true when the PEM encoded certificate recorded in a log entry is the certificate.
+func isCertificate(data []byte, cert *x509.Certificate) bool {
+	block, _ := pem.Decode(data)
+
+	return block != nil && block.Type == "CERTIFICATE" && bytes.Equal(block.Bytes, cert.Raw)
+}
+
+// VerifySignature verifies the signature of the message with the public key, hashed with SHA-256 unless the key is Ed25519.
+func VerifySignature(key crypto.PublicKey, message, sig []byte) error {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(k, sum[:], sig) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(message)
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			// cosign signs with PSS when the key is generated by KMS
+			if errPSS := rsa.VerifyPSS(k, crypto.SHA256, sum[:], sig, nil); errPSS != nil {
+				return err
+			}
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, message, sig) {
+			return errors.New("invalid Ed25519 signature")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported public key type %T", key)
+}
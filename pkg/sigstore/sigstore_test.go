@@ -0,0 +1,416 @@
+package sigstore_test
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/pkg/sigstore"
+	"github.com/actions/actions-runner-controller/pkg/sigstore/fake"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	issuer   = "https://token.actions.githubusercontent.com"
+	workflow = "https://github.com/actions/runner/.github/workflows/release.yml@refs/tags/v2.311.0"
+)
+
+func newVerifier(t *testing.T, s *fake.Sigstore, identities ...sigstore.Identity) *sigstore.Verifier {
+	root, err := sigstore.NewTrustedRoot(s.FulcioCertificates(), s.RekorPublicKeys())
+	require.NoError(t, err)
+
+	return &sigstore.Verifier{TrustedRoot: root, Identities: identities}
+}
+
+func parseCertificate(t *testing.T, data []byte) *x509.Certificate {
+	block, _ := pem.Decode(data)
+	c, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNewTrustedRoot(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	_, err = sigstore.NewTrustedRoot(s.FulcioCertificates(), nil)
+	require.ErrorContains(t, err, "no Rekor public key found")
+
+	_, err = sigstore.NewTrustedRoot(nil, s.RekorPublicKeys())
+	require.ErrorContains(t, err, "no PEM encoded certificate found")
+
+	// A leaf certificate isn't a root
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	_, err = sigstore.NewTrustedRoot(signer.Certificate, s.RekorPublicKeys())
+	require.ErrorContains(t, err, "no root Fulcio certificate found")
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	cert := parseCertificate(t, signer.Certificate)
+
+	emailSigner, err := s.NewSigner("https://accounts.google.com", "release@example.com")
+	require.NoError(t, err)
+	emailCert := parseCertificate(t, emailSigner.Certificate)
+
+	v := newVerifier(t, s,
+		sigstore.Identity{Issuer: issuer, SubjectRegExp: regexp.MustCompile(`^https://github\.com/actions/runner/\.github/workflows/release\.yml@refs/tags/v`)},
+		sigstore.Identity{Issuer: "https://accounts.google.com", Subject: "release@example.com"},
+	)
+
+	_, err = v.VerifyCertificate(cert, nil, time.Now())
+	require.NoError(t, err)
+
+	_, err = v.VerifyCertificate(emailCert, nil, time.Now())
+	require.NoError(t, err)
+
+	// The certificate is short-lived, and must have been valid at the signing time
+	_, err = v.VerifyCertificate(cert, nil, time.Now().Add(time.Hour))
+	require.ErrorContains(t, err, "expired")
+
+	// Issued to another identity
+	other, err := s.NewSigner(issuer, "https://github.com/attacker/runner/.github/workflows/release.yml@refs/tags/v2.311.0")
+	require.NoError(t, err)
+	_, err = v.VerifyCertificate(parseCertificate(t, other.Certificate), nil, time.Now())
+	require.ErrorContains(t, err, "matches none of the identities")
+
+	// Any of the subject alternative names matches
+	multi, err := s.NewSigner(issuer, "https://github.com/example/other/.github/workflows/ci.yml@refs/heads/main", "release@example.com")
+	require.NoError(t, err)
+	_, err = newVerifier(t, s, sigstore.Identity{Issuer: issuer, Subject: "release@example.com"}).VerifyCertificate(parseCertificate(t, multi.Certificate), nil, time.Now())
+	require.NoError(t, err)
+
+	// Issued by another CA
+	untrusted, err := fake.New()
+	require.NoError(t, err)
+	forged, err := untrusted.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	_, err = v.VerifyCertificate(parseCertificate(t, forged.Certificate), nil, time.Now())
+	require.ErrorContains(t, err, "unknown authority")
+}
+
+func TestVerifyLogEntry(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+
+	v := newVerifier(t, s, sigstore.Identity{Issuer: issuer, Subject: workflow})
+
+	cert := parseCertificate(t, signer.Certificate)
+
+	// Log more entries around the one verified, so that its audit path isn't trivial
+	_, _, err = signer.SignHashedRekord([]byte("before"))
+	require.NoError(t, err)
+
+	payload := []byte("payload")
+	sig, e, err := signer.SignHashedRekord(payload)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, _, err = signer.SignHashedRekord([]byte("after"))
+		require.NoError(t, err)
+	}
+
+	signedAt, err := v.VerifyLogEntry(e, cert, sig, payload)
+	require.NoError(t, err)
+	require.Equal(t, e.IntegratedTime, signedAt.Unix())
+
+	_, err = v.VerifyLogEntry(e, cert, sig, []byte("another payload"))
+	require.ErrorContains(t, err, "records another signature")
+
+	tampered := e
+	tampered.IntegratedTime--
+	_, err = v.VerifyLogEntry(tampered, cert, sig, payload)
+	require.ErrorContains(t, err, "verifying signed entry timestamp")
+
+	untrusted, err := fake.New()
+	require.NoError(t, err)
+	_, err = newVerifier(t, untrusted).VerifyLogEntry(e, cert, sig, payload)
+	require.ErrorContains(t, err, "untrusted log")
+
+	// The same signature, which the certificate of another key can't have made, logged along with the certificate of another signer
+	other, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	_, err = v.VerifyLogEntry(e, parseCertificate(t, other.Certificate), sig, payload)
+	require.ErrorContains(t, err, "records another certificate")
+}
+
+func TestVerifyLogEntryInclusionProof(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	cert := parseCertificate(t, signer.Certificate)
+
+	v := newVerifier(t, s, sigstore.Identity{Issuer: issuer, Subject: workflow})
+
+	payload := []byte("payload")
+
+	var entries []sigstore.LogEntry
+	var sigs [][]byte
+
+	// The last leaves of the trees of every size up to 7, with both complete and incomplete subtrees
+	for i := 0; i < 7; i++ {
+		sig, e, err := signer.SignHashedRekord(payload)
+		require.NoError(t, err)
+
+		entries = append(entries, e)
+		sigs = append(sigs, sig)
+	}
+
+	for i, e := range entries {
+		_, err := v.VerifyLogEntry(e, cert, sigs[i], payload)
+		require.NoError(t, err, "entry %d", i)
+	}
+
+	// The audit path of the leaf 6 of the tree of size 7 is the roots of the leaves 4 to 5 and 0 to 3
+	e := entries[6]
+
+	tamper := func(f func(p *sigstore.InclusionProof)) sigstore.LogEntry {
+		p := *e.InclusionProof
+		p.Hashes = append([][]byte(nil), p.Hashes...)
+		f(&p)
+
+		tampered := e
+		tampered.InclusionProof = &p
+
+		return tampered
+	}
+
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) { p.Hashes[0] = p.Hashes[1] }), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "inclusion proof doesn't lead to the root hash")
+
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) { p.Hashes = p.Hashes[1:] }), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "inclusion proof has 1 hashes, want 2")
+
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) { p.LogIndex = 4 }), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "inclusion proof has 2 hashes, want 3")
+
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) { p.LogIndex = p.TreeSize }), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "out of the tree")
+
+	// The proof of a root the log never signed
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) {
+		p.RootHash = sigstore.NodeHash(p.Hashes[0], sigstore.LeafHash(e.Body))
+		p.Hashes = p.Hashes[:1]
+		p.LogIndex, p.TreeSize = 1, 2
+	}), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "checkpoint is of the tree size")
+
+	// The checkpoint of the root signed by another log
+	untrusted, err := fake.New()
+	require.NoError(t, err)
+	forger, err := untrusted.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	_, forged, err := forger.SignHashedRekord(payload)
+	require.NoError(t, err)
+
+	_, err = v.VerifyLogEntry(tamper(func(p *sigstore.InclusionProof) {
+		p.RootHash = forged.InclusionProof.RootHash
+		p.Hashes = nil
+		p.LogIndex, p.TreeSize = 0, 1
+		p.Checkpoint = forged.InclusionProof.Checkpoint
+	}), cert, sigs[6], payload)
+	require.ErrorContains(t, err, "inclusion proof doesn't lead to the root hash")
+
+	e.InclusionProof = forged.InclusionProof
+	e.Body = forged.Body
+	_, err = v.VerifyLogEntry(e, cert, sigs[6], payload)
+	require.ErrorContains(t, err, "verifying signed entry timestamp")
+}
+
+func TestVerifyLogEntryInToto(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+
+	v := newVerifier(t, s, sigstore.Identity{Issuer: issuer, Subject: workflow})
+
+	payload := []byte(`{"_type":"https://in-toto.io/Statement/v1"}`)
+	sig, err := signer.Sign(sigstore.PAE(sigstore.InTotoPayloadType, payload))
+	require.NoError(t, err)
+	cert := parseCertificate(t, signer.Certificate)
+
+	// An intoto entry, like the ones logged by cosign attest and npm, encodes the base64 encoded signature in base64 again
+	logIntoto := func(sig, certificate []byte) sigstore.LogEntry {
+		sum := sha256.Sum256(payload)
+
+		body, err := json.Marshal(map[string]any{
+			"apiVersion": "0.0.2",
+			"kind":       "intoto",
+			"spec": map[string]any{
+				"content": map[string]any{
+					"envelope": map[string]any{
+						"payloadType": sigstore.InTotoPayloadType,
+						"signatures": []map[string]string{{
+							"sig":       base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString(sig))),
+							"publicKey": base64.StdEncoding.EncodeToString(certificate),
+						}},
+					},
+					"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		e, err := s.LogEntry(body)
+		require.NoError(t, err)
+
+		return e
+	}
+
+	_, err = v.VerifyLogEntry(logIntoto(sig, signer.Certificate), cert, sig, payload)
+	require.NoError(t, err)
+
+	// The entry of the same payload signed by another key doesn't vouch for the signature
+	other, err := signer.Sign([]byte("another message"))
+	require.NoError(t, err)
+	_, err = v.VerifyLogEntry(logIntoto(other, signer.Certificate), cert, sig, payload)
+	require.ErrorContains(t, err, "records another signature")
+
+	// Nor does the entry of the signature logged along with another certificate
+	stranger, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	_, err = v.VerifyLogEntry(logIntoto(sig, stranger.Certificate), cert, sig, payload)
+	require.ErrorContains(t, err, "records another signature")
+}
+
+// TestVerifyBundlePublicGood verifies the npm provenance attestations of sigstore-js logged in the public-good instance of Sigstore,
+// against the Fulcio certificates and the Rekor public key of the trusted root of the instance.
+// The bundles and the trusted root are the ones in the testdata of github.com/sigstore/sigstore-go.
+func TestVerifyBundlePublicGood(t *testing.T) {
+	fulcio, err := os.ReadFile("testdata/fulcio_v1.crt.pem")
+	require.NoError(t, err)
+
+	rekor, err := os.ReadFile("testdata/rekor.pub")
+	require.NoError(t, err)
+
+	root, err := sigstore.NewTrustedRoot(fulcio, rekor)
+	require.NoError(t, err)
+
+	release := sigstore.Identity{Issuer: issuer, Subject: "https://github.com/sigstore/sigstore-js/.github/workflows/release.yml@refs/heads/main"}
+
+	for _, tc := range []struct {
+		bundle        string
+		predicateType string
+		digest        string
+	}{
+		{
+			bundle:        "testdata/sigstore-js-provenance.sigstore.json",
+			predicateType: "https://slsa.dev/provenance/v0.2",
+			digest:        "sha512:76176ffa33808b54602c7c35de5c6e9a4deb96066dba6533f50ac234f4f1f4c6b3527515dc17c06fbe2860030f410eee69ea20079bd3a2c6f3dcf3b329b10751",
+		},
+		{
+			bundle:        "testdata/sigstore-js-2.0.0-provenance.sigstore.json",
+			predicateType: "https://slsa.dev/provenance/v1",
+			digest:        "sha512:46d4e2f74c4877316640000a6fdf8a8b59f1e0847667973e9859f774dd31b8f1e0937813b777fb66a2ac67d50540fe34640966eee9fc2ccca387082b4c85cd3c",
+		},
+	} {
+		t.Run(tc.bundle, func(t *testing.T) {
+			data, err := os.ReadFile(tc.bundle)
+			require.NoError(t, err)
+
+			v := &sigstore.Verifier{TrustedRoot: root, Identities: []sigstore.Identity{release}}
+
+			statement, err := v.VerifyBundle(data)
+			require.NoError(t, err)
+			require.Equal(t, tc.predicateType, statement.PredicateType)
+			require.True(t, statement.HasSubject(tc.digest))
+
+			// Signed by another workflow of the repository
+			v.Identities = []sigstore.Identity{{Issuer: issuer, Subject: "https://github.com/sigstore/sigstore-js/.github/workflows/ci.yml@refs/heads/main"}}
+			_, err = v.VerifyBundle(data)
+			require.ErrorContains(t, err, "matches none of the identities")
+
+			// The signature of the envelope swapped for another one that the log entry doesn't record
+			var b map[string]any
+			require.NoError(t, json.Unmarshal(data, &b))
+			sigs := b["dsseEnvelope"].(map[string]any)["signatures"].([]any)
+			sigs[0].(map[string]any)["sig"] = base64.StdEncoding.EncodeToString([]byte("forged"))
+			tampered, err := json.Marshal(b)
+			require.NoError(t, err)
+
+			v.Identities = []sigstore.Identity{release}
+			_, err = v.VerifyBundle(tampered)
+			require.ErrorContains(t, err, "verifying envelope")
+
+			// A hash of the audit path of the inclusion proof swapped, which the older bundle was made without
+			require.NoError(t, json.Unmarshal(data, &b))
+			proof, ok := b["verificationMaterial"].(map[string]any)["tlogEntries"].([]any)[0].(map[string]any)["inclusionProof"].(map[string]any)
+			if !ok {
+				return
+			}
+			hashes := proof["hashes"].([]any)
+			hashes[0] = hashes[1]
+			tampered, err = json.Marshal(b)
+			require.NoError(t, err)
+
+			_, err = v.VerifyBundle(tampered)
+			require.ErrorContains(t, err, "inclusion proof doesn't lead to the root hash")
+		})
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	s, err := fake.New()
+	require.NoError(t, err)
+
+	signer, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+
+	v := newVerifier(t, s, sigstore.Identity{Issuer: issuer, Subject: workflow})
+
+	digest := "sha256:29fc8cf2dab4c195bb147384e7e2c94cfd4d4022c793b346a6175435265aa278"
+
+	bundle, err := signer.Bundle("https://slsa.dev/provenance/v1", digest)
+	require.NoError(t, err)
+
+	statement, err := v.VerifyBundle(bundle)
+	require.NoError(t, err)
+	require.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	require.True(t, statement.HasSubject(digest))
+	require.False(t, statement.HasSubject("sha256:0000"))
+
+	// The statement swapped after signing
+	var b map[string]any
+	require.NoError(t, json.Unmarshal(bundle, &b))
+	b["dsseEnvelope"].(map[string]any)["payload"] = "eyJfdHlwZSI6ImZvcmdlZCJ9"
+	tampered, err := json.Marshal(b)
+	require.NoError(t, err)
+	_, err = v.VerifyBundle(tampered)
+	require.ErrorContains(t, err, "verifying envelope")
+
+	// The log entry without the inclusion proof, which only the signed entry timestamp promises
+	require.NoError(t, json.Unmarshal(bundle, &b))
+	delete(b["verificationMaterial"].(map[string]any)["tlogEntries"].([]any)[0].(map[string]any), "inclusionProof")
+	tampered, err = json.Marshal(b)
+	require.NoError(t, err)
+	_, err = v.VerifyBundle(tampered)
+	require.ErrorContains(t, err, "log entry has no inclusion proof")
+
+	// Logged after the certificate expired
+	late, err := s.NewSigner(issuer, workflow)
+	require.NoError(t, err)
+	s.Now = time.Now().Add(time.Hour)
+	bundle, err = late.Bundle("https://slsa.dev/provenance/v1", digest)
+	require.NoError(t, err)
+	_, err = v.VerifyBundle(bundle)
+	require.ErrorContains(t, err, "expired")
+}
@@ -0,0 +1,113 @@
+// Package specdrift tells whether the spec of a resource managed by a GitOps tool has been changed outside of the tool,
+// from the field managers recorded in the managed fields of the resource.
+//
+// See https://kubernetes.io/docs/reference/using-api/server-side-apply/#field-management for the managed fields.
+package specdrift
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Drift is a change to the spec made by a field manager other than the GitOps tool.
+type Drift struct {
+	// Manager is the field manager that made the change, like kubectl-edit.
+	Manager string
+	// Fields are the top-level fields of the spec the manager owns, sorted.
+	Fields []string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s changed spec.%s", d.Manager, strings.Join(d.Fields, ", spec."))
+}
+
+// ParseManagers parses the comma-separated field managers of the GitOps tools, like "argocd-controller,helm".
+func ParseManagers(v string) []string {
+	var managers []string
+
+	for _, m := range strings.Split(v, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			managers = append(managers, m)
+		}
+	}
+
+	return managers
+}
+
+// Detect returns the changes to the spec of the object made by the field managers other than the allowed ones,
+// sorted by the manager name.
+// ignoredFields are the top-level fields of the spec that the controllers change by themselves, like replicas set by an autoscaler.
+func Detect(obj metav1.Object, allowed []string, ignoredFields ...string) ([]Drift, error) {
+	isAllowed := func(manager string) bool {
+		for _, m := range allowed {
+			if m == manager {
+				return true
+			}
+		}
+		return false
+	}
+
+	ignored := map[string]bool{}
+	for _, f := range ignoredFields {
+		ignored[f] = true
+	}
+
+	fieldsByManager := map[string]map[string]bool{}
+
+	for _, e := range obj.GetManagedFields() {
+		// The status is reported by the controllers, not applied by the GitOps tools
+		if e.Subresource != "" || e.FieldsV1 == nil || isAllowed(e.Manager) {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(e.FieldsV1.Raw, &fields); err != nil {
+			return nil, fmt.Errorf("parsing the managed fields of %s: %w", e.Manager, err)
+		}
+
+		spec, ok := fields["f:spec"]
+		if !ok {
+			continue
+		}
+
+		var specFields map[string]json.RawMessage
+		if err := json.Unmarshal(spec, &specFields); err != nil {
+			return nil, fmt.Errorf("parsing the managed spec fields of %s: %w", e.Manager, err)
+		}
+
+		for k := range specFields {
+			name, ok := strings.CutPrefix(k, "f:")
+			if !ok || ignored[name] {
+				continue
+			}
+
+			if fieldsByManager[e.Manager] == nil {
+				fieldsByManager[e.Manager] = map[string]bool{}
+			}
+
+			fieldsByManager[e.Manager][name] = true
+		}
+	}
+
+	var drifts []Drift
+
+	for manager, fields := range fieldsByManager {
+		d := Drift{Manager: manager}
+		for f := range fields {
+			d.Fields = append(d.Fields, f)
+		}
+		sort.Strings(d.Fields)
+
+		drifts = append(drifts, d)
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Manager < drifts[j].Manager
+	})
+
+	return drifts, nil
+}
@@ -0,0 +1,47 @@
+package specdrift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDetect(t *testing.T) {
+	obj := &metav1.ObjectMeta{
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "argocd-controller",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{".":{},"f:replicas":{},"f:template":{}}}`)},
+			},
+			{
+				// The autoscaler sets the replicas
+				Manager:   "manager",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{}},"f:spec":{"f:replicas":{}}}`)},
+			},
+			{
+				Manager:     "manager",
+				Operation:   metav1.ManagedFieldsOperationUpdate,
+				Subresource: "status",
+				FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)},
+			},
+		},
+	}
+
+	drifts, err := Detect(obj, ParseManagers("argocd-controller, helm"), "replicas")
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	obj.ManagedFields = append(obj.ManagedFields, metav1.ManagedFieldsEntry{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:image":{}}},"f:minReadySeconds":{}}}`)},
+	})
+
+	drifts, err = Detect(obj, ParseManagers("argocd-controller"), "replicas")
+	require.NoError(t, err)
+	require.Equal(t, []Drift{{Manager: "kubectl-edit", Fields: []string{"minReadySeconds", "template"}}}, drifts)
+	require.Equal(t, "kubectl-edit changed spec.minReadySeconds, spec.template", drifts[0].String())
+}
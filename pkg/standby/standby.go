@@ -0,0 +1,83 @@
+// Package standby keeps the standby replicas of a leader-elected controller manager ready to take over the leadership.
+//
+// controller-runtime starts the informers of a controller only once the controller is started on the leader,
+// so a replica that has just been elected lists every object it watches before reconciling anything.
+// CacheWarmer starts the informers on every replica instead, so that the new leader reconciles from a synced cache
+// as soon as it acquires the lease.
+package standby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/leaderelection"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheWarmer is a manager.Runnable that starts and syncs the informers of the objects on every replica,
+// including the ones waiting for the leadership.
+type CacheWarmer struct {
+	Cache cache.Cache
+	Log   logr.Logger
+
+	// Objects are the kinds of the objects the controllers watch.
+	Objects []client.Object
+}
+
+func (w *CacheWarmer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start syncs the informers one by one and returns once all of them are synced.
+// The informers keep running with the cache after it returns.
+func (w *CacheWarmer) Start(ctx context.Context) error {
+	start := time.Now()
+
+	for _, obj := range w.Objects {
+		kind := fmt.Sprintf("%T", obj)
+
+		// An informer failing to start, like the one of a CRD not installed, is left to the controller watching it
+		if _, err := w.Cache.GetInformer(ctx, obj, cache.BlockUntilSynced(true)); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			w.Log.Error(err, "Failed to warm the cache", "kind", kind)
+		}
+	}
+
+	w.Log.Info("Warmed the caches for taking over the leadership", "kinds", len(w.Objects), "duration", time.Since(start).String())
+
+	return nil
+}
+
+// LeaderElectionConfig is how fast the leadership moves to a standby replica.
+// The leader keeps renewing the lease every RetryPeriod, and steps down when it fails to renew for RenewDeadline.
+// A standby acquires the lease once it has not been renewed for LeaseDuration.
+type LeaderElectionConfig struct {
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Validate returns an error when the durations would let two replicas lead at once,
+// which the leader election of client-go refuses only once the manager starts.
+func (c LeaderElectionConfig) Validate() error {
+	if c.LeaseDuration <= 0 || c.RenewDeadline <= 0 || c.RetryPeriod <= 0 {
+		return errors.New("lease duration, renew deadline, and retry period must be greater than 0")
+	}
+
+	if c.LeaseDuration <= c.RenewDeadline {
+		return fmt.Errorf("lease duration %s must be greater than renew deadline %s", c.LeaseDuration, c.RenewDeadline)
+	}
+
+	if float64(c.RenewDeadline) <= leaderelection.JitterFactor*float64(c.RetryPeriod) {
+		return fmt.Errorf("renew deadline %s must be greater than %.1f times retry period %s", c.RenewDeadline, leaderelection.JitterFactor, c.RetryPeriod)
+	}
+
+	return nil
+}
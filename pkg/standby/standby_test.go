@@ -0,0 +1,54 @@
+package standby
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeCache struct {
+	cache.Cache
+
+	synced []client.Object
+}
+
+func (c *fakeCache) GetInformer(ctx context.Context, obj client.Object, opts ...cache.InformerGetOption) (cache.Informer, error) {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return nil, errors.New("no matches for kind")
+	}
+
+	c.synced = append(c.synced, obj)
+
+	return nil, nil
+}
+
+func TestCacheWarmer(t *testing.T) {
+	c := &fakeCache{}
+
+	w := &CacheWarmer{
+		Cache:   c,
+		Log:     logr.Discard(),
+		Objects: []client.Object{&corev1.Pod{}, &corev1.Secret{}, &corev1.ServiceAccount{}},
+	}
+
+	assert.False(t, w.NeedLeaderElection())
+	require.NoError(t, w.Start(context.Background()))
+	assert.Equal(t, []client.Object{&corev1.Pod{}, &corev1.ServiceAccount{}}, c.synced, "a failing informer doesn't stop the others")
+}
+
+func TestLeaderElectionConfig_Validate(t *testing.T) {
+	assert.NoError(t, LeaderElectionConfig{LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second}.Validate())
+	assert.NoError(t, LeaderElectionConfig{LeaseDuration: 4 * time.Second, RenewDeadline: 3 * time.Second, RetryPeriod: time.Second}.Validate())
+
+	assert.Error(t, LeaderElectionConfig{}.Validate())
+	assert.Error(t, LeaderElectionConfig{LeaseDuration: 10 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: time.Second}.Validate())
+	assert.Error(t, LeaderElectionConfig{LeaseDuration: 15 * time.Second, RenewDeadline: 2 * time.Second, RetryPeriod: 2 * time.Second}.Validate())
+}
@@ -0,0 +1,222 @@
+// Package vault is a minimal client of the HTTP API of HashiCorp Vault, covering what ARC needs:
+// reading the GitHub API credentials, and handing the just-in-time configurations of the runners over to the runner pods
+// via the KV version 2 secrets engine and the response wrapping, without keeping them in Kubernetes Secrets.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// ErrNotFound is returned when the secret doesn't exist, or is deleted or destroyed.
+var ErrNotFound = errors.New("vault secret not found")
+
+// Client talks to Vault with Token when set, or with the client token it gets by logging in via the Kubernetes auth method
+// using the token of the service account of the pod otherwise. The client token is reused until its lease is about to expire.
+type Client struct {
+	// Address is the address of the Vault server like https://vault.example.com:8200.
+	Address string
+
+	Token string
+
+	// Role is the role to log in via the Kubernetes auth method.
+	Role string
+	// AuthPath is the mount path of the Kubernetes auth method. Defaults to kubernetes.
+	AuthPath string
+	// ServiceAccountTokenPath defaults to the path of the token of the service account mounted to the pod.
+	ServiceAccountTokenPath string
+
+	HTTPClient *http.Client
+
+	mu          sync.Mutex
+	clientToken string
+	expiresAt   time.Time
+}
+
+// Read returns the data of the secret at the path, including the mount.
+// The data of a KV version 2 secret, nested under data along with its metadata, is unwrapped.
+func (c *Client) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	var res struct {
+		Data map[string]interface{} `json:"data"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil, nil, &res); err != nil {
+		return nil, fmt.Errorf("reading vault secret %s: %w", path, err)
+	}
+
+	data := res.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	return data, nil
+}
+
+// Write writes the body to the path, including the mount. A KV version 2 secret needs its data nested under data.
+func (c *Client) Write(ctx context.Context, path string, body map[string]interface{}) error {
+	if err := c.do(ctx, http.MethodPost, "/v1/"+strings.TrimPrefix(path, "/"), nil, body, nil); err != nil {
+		return fmt.Errorf("writing vault secret %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the path, including the mount. Deleting the metadata of a KV version 2 secret destroys all its versions.
+// Deleting the path that doesn't exist succeeds.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	if err := c.do(ctx, http.MethodDelete, "/v1/"+strings.TrimPrefix(path, "/"), nil, nil, nil); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("deleting vault secret %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Wrap wraps the data in the cubbyhole of a single-use token expiring after the TTL, and returns the token.
+// The data can be read only once, by unwrapping the token via sys/wrapping/unwrap, and is gone after the TTL otherwise.
+func (c *Client) Wrap(ctx context.Context, data map[string]interface{}, ttl time.Duration) (string, error) {
+	var res struct {
+		WrapInfo struct {
+			Token string `json:"token"`
+		} `json:"wrap_info"`
+	}
+
+	header := map[string]string{"X-Vault-Wrap-TTL": fmt.Sprintf("%ds", int64(ttl.Seconds()))}
+
+	if err := c.do(ctx, http.MethodPost, "/v1/sys/wrapping/wrap", header, data, &res); err != nil {
+		return "", fmt.Errorf("wrapping vault secret: %w", err)
+	}
+
+	if res.WrapInfo.Token == "" {
+		return "", errors.New("wrapping vault secret: no wrapping token in response")
+	}
+
+	return res.WrapInfo.Token, nil
+}
+
+func (c *Client) token(ctx context.Context) (string, error) {
+	if c.Token != "" {
+		return c.Token, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clientToken != "" && time.Now().Before(c.expiresAt) {
+		return c.clientToken, nil
+	}
+
+	if c.Role == "" {
+		return "", errors.New("either vault token or vault role is required")
+	}
+
+	tokenPath := c.ServiceAccountTokenPath
+	if tokenPath == "" {
+		tokenPath = defaultServiceAccountTokenPath
+	}
+
+	jwt, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return "", fmt.Errorf("reading service account token: %w", err)
+	}
+
+	authPath := c.AuthPath
+	if authPath == "" {
+		authPath = "kubernetes"
+	}
+
+	body := map[string]interface{}{
+		"role": c.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+
+	var res struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+
+	if err := c.send(ctx, http.MethodPost, "/v1/auth/"+strings.Trim(authPath, "/")+"/login", "", nil, body, &res); err != nil {
+		return "", fmt.Errorf("logging in to vault: %w", err)
+	}
+
+	if res.Auth.ClientToken == "" {
+		return "", errors.New("logging in to vault: no client token in response")
+	}
+
+	c.clientToken = res.Auth.ClientToken
+	// Logs in again before the token expires, or on every call when the lease is unknown
+	c.expiresAt = time.Now().Add(time.Duration(res.Auth.LeaseDuration) * time.Second * 4 / 5)
+
+	return c.clientToken, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, header map[string]string, body, out interface{}) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.send(ctx, method, path, token, header, body, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path, token string, header map[string]string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.Address, "/")+path, r)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	var logins int
+	secrets := map[string]map[string]interface{}{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			logins++
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "vault-token", "lease_duration": 3600}}`))
+			return
+		}
+
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/v1/sys/wrapping/wrap":
+			require.Equal(t, "600s", r.Header.Get("X-Vault-Wrap-TTL"))
+			_, _ = w.Write([]byte(`{"wrap_info": {"token": "wrapping-token", "ttl": 600}}`))
+		case r.Method == http.MethodGet:
+			data, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case r.Method == http.MethodPost:
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			secrets[r.URL.Path] = body
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			delete(secrets, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	saToken := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(saToken, []byte("jwt"), 0600))
+
+	c := &Client{Address: srv.URL, Role: "arc", ServiceAccountTokenPath: saToken}
+
+	_, err := c.Read(ctx, "secret/data/arc")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, c.Write(ctx, "secret/data/arc", map[string]interface{}{"data": map[string]interface{}{"github_token": "pat"}}))
+
+	data, err := c.Read(ctx, "secret/data/arc")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"github_token": "pat"}, data)
+
+	token, err := c.Wrap(ctx, map[string]interface{}{"jitConfig": "config"}, 10*time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, "wrapping-token", token)
+
+	require.NoError(t, c.Delete(ctx, "secret/data/arc"))
+	require.NoError(t, c.Delete(ctx, "secret/data/arc"), "deleting the missing secret succeeds")
+
+	require.Equal(t, 1, logins, "the client token is reused until its lease is about to expire")
+
+	c = &Client{Address: srv.URL}
+	_, err = c.Read(ctx, "secret/data/arc")
+	require.EqualError(t, err, "reading vault secret secret/data/arc: either vault token or vault role is required")
+	require.False(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,301 @@
+// Package webhookingest consumes the GitHub webhook deliveries from a message broker instead of receiving them over HTTP,
+// for the organizations that centralize their webhook ingestion, and hands them to the webhook handler of the github-webhook-server.
+//
+// Each message is an envelope of a delivery, with the headers GitHub sent it with and the payload as is, like:
+//
+//	{
+//	  "headers": {
+//	    "X-GitHub-Event": "workflow_job",
+//	    "X-GitHub-Delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+//	    "X-Hub-Signature-256": "sha256=..."
+//	  },
+//	  "payload": {"action": "queued", ...}
+//	}
+//
+// The messages are processed at least once. A message is acknowledged to the broker only after the handler succeeded,
+// or failed in a way a retry can't fix. The handler is expected to skip the deliveries the broker redelivers by their GUIDs,
+// like the webhook handler does by claiming each delivery in its delivery store, shared by all the replicas of the webhook server.
+package webhookingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+
+	resultHandled  = "handled"
+	resultRejected = "rejected"
+	resultInvalid  = "invalid"
+	resultFailed   = "failed"
+)
+
+var ingestedDeliveries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "githubwebhook_ingested_deliveries_total",
+		Help: "Total number of webhook deliveries consumed from the message broker by result, either handled, rejected by the handler, invalid, or failed after the retries",
+	},
+	[]string{"source", "result"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(ingestedDeliveries)
+}
+
+// Message is a message consumed from the broker.
+type Message struct {
+	Data []byte
+
+	// Position tells where the message is in the broker for logging, like TOPIC/PARTITION@OFFSET.
+	Position string
+
+	// The messages of the client of the broker, which the source acknowledges
+	kafkaMsg kafka.Message
+	natsMsg  jetstream.Msg
+}
+
+// Source consumes the messages from a message broker.
+type Source interface {
+	// Name is the type of the source, like kafka or nats.
+	Name() string
+
+	// Fetch returns the next messages, waiting for them up to the poll timeout of the source. It returns no messages when none arrived in time.
+	Fetch(ctx context.Context) ([]Message, error)
+
+	// Commit acknowledges the fetched messages to the broker so that they are never redelivered.
+	Commit(ctx context.Context, msgs []Message) error
+
+	// Close releases the resources of the source, like the consumer instance and the connection.
+	Close(ctx context.Context) error
+}
+
+type envelope struct {
+	Headers map[string]string `json:"headers"`
+	Payload json.RawMessage   `json:"payload"`
+}
+
+type contextKey struct{}
+
+// FromBroker tells whether the request of the webhook delivery was consumed from the message broker rather than received over HTTP,
+// in which case the request has no remote address to verify the source of the delivery with.
+func FromBroker(ctx context.Context) bool {
+	v, _ := ctx.Value(contextKey{}).(bool)
+
+	return v
+}
+
+// Ingester hands the webhook deliveries consumed from the source to the handler, one at a time in the order of the messages.
+//
+// It implements controller-runtime's manager.Runnable so that it can be added to the webhook server's manager.
+type Ingester struct {
+	Log logr.Logger
+
+	Source Source
+
+	// Handler handles the deliveries as if they were received over HTTP, including the redeliveries of the deliveries it already handled.
+	// 2xx responses mark the deliveries handled, 4xx responses reject them, and the others are retried.
+	Handler http.Handler
+
+	// MaxAttempts is the number of attempts of a delivery the handler keeps failing, after which it's given up and acknowledged,
+	// so that a single poison message doesn't stop the consumption.
+	MaxAttempts int
+}
+
+func (in *Ingester) Start(ctx context.Context) error {
+	defer func() {
+		// The context is already done
+		closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := in.Source.Close(closeCtx); err != nil {
+			in.Log.Error(err, "Could not close the webhook delivery source")
+		}
+	}()
+
+	backoff := minBackoff
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := in.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			in.Log.Error(err, "Could not consume webhook deliveries from the message broker", "source", in.Source.Name(), "backoff", backoff)
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+
+			continue
+		}
+
+		backoff = minBackoff
+	}
+}
+
+// poll fetches the next messages, handles them, and commits them.
+func (in *Ingester) poll(ctx context.Context) error {
+	msgs, err := in.Source.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching messages: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	for _, m := range msgs {
+		result := in.handle(ctx, m)
+		if ctx.Err() != nil {
+			// Leave the rest to be redelivered to the next consumer
+			return nil
+		}
+
+		ingestedDeliveries.WithLabelValues(in.Source.Name(), result).Inc()
+	}
+
+	if err := in.Source.Commit(ctx, msgs); err != nil {
+		return fmt.Errorf("committing messages: %w", err)
+	}
+
+	return nil
+}
+
+// handle hands the delivery of the message to the handler, retrying it with backoff while the handler fails, and returns the result.
+func (in *Ingester) handle(ctx context.Context, m Message) string {
+	log := in.Log.WithValues("source", in.Source.Name(), "position", m.Position)
+
+	var e envelope
+	if err := json.Unmarshal(m.Data, &e); err != nil || len(e.Payload) == 0 {
+		if err == nil {
+			err = errors.New("payload is empty")
+		}
+
+		log.Error(err, "Skipping the message as it is not an envelope of a webhook delivery")
+
+		return resultInvalid
+	}
+
+	header := http.Header{}
+	for k, v := range e.Headers {
+		header.Set(k, v)
+	}
+
+	log = log.WithValues("delivery", header.Get("X-GitHub-Delivery"), "event", header.Get("X-GitHub-Event"))
+
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+
+	maxAttempts := in.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	backoff := minBackoff
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(context.WithValue(ctx, contextKey{}, true), http.MethodPost, "/", bytes.NewReader(e.Payload))
+		if err != nil {
+			log.Error(err, "Skipping the webhook delivery as the request could not be created")
+
+			return resultInvalid
+		}
+		req.Header = header.Clone()
+
+		w := &responseWriter{header: http.Header{}}
+
+		in.Handler.ServeHTTP(w, req)
+
+		status := w.statusCode()
+
+		switch {
+		case status/100 == 2:
+			log.V(1).Info("Handled the webhook delivery consumed from the message broker", "response", w.body.String())
+
+			return resultHandled
+		case status/100 == 4:
+			log.Info("Skipping the webhook delivery as the handler rejected it", "status", status, "response", w.body.String())
+
+			return resultRejected
+		}
+
+		if attempt >= maxAttempts {
+			log.Info("Giving up the webhook delivery as the handler kept failing", "status", status, "response", w.body.String(), "attempts", attempt)
+
+			return resultFailed
+		}
+
+		log.V(1).Info("Retrying the webhook delivery the handler failed", "status", status, "response", w.body.String(), "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return resultFailed
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// responseWriter captures the response of the handler to a consumed delivery.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
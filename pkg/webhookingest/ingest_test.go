@@ -0,0 +1,114 @@
+package webhookingest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	batches   [][]Message
+	committed [][]string
+	closed    bool
+}
+
+func (s *fakeSource) Name() string {
+	return "fake"
+}
+
+func (s *fakeSource) Fetch(ctx context.Context) ([]Message, error) {
+	if len(s.batches) == 0 {
+		return nil, nil
+	}
+
+	b := s.batches[0]
+	s.batches = s.batches[1:]
+
+	return b, nil
+}
+
+func (s *fakeSource) Commit(_ context.Context, msgs []Message) error {
+	var positions []string
+	for _, m := range msgs {
+		positions = append(positions, m.Position)
+	}
+
+	s.committed = append(s.committed, positions)
+
+	return nil
+}
+
+func (s *fakeSource) Close(_ context.Context) error {
+	s.closed = true
+
+	return nil
+}
+
+func delivery(id, event, payload string) string {
+	return `{"headers":{"x-github-delivery":"` + id + `","X-GitHub-Event":"` + event + `"},"payload":` + payload + `}`
+}
+
+func TestIngester(t *testing.T) {
+	source := &fakeSource{
+		batches: [][]Message{
+			{
+				{Position: "0", Data: []byte(delivery("a", "workflow_job", `{"action": "queued"}`))},
+				{Position: "1", Data: []byte(`not json`)},
+				{Position: "2", Data: []byte(delivery("b", "workflow_job", `{"action":"unavailable"}`))},
+			},
+			{
+				// Redelivered
+				{Position: "0", Data: []byte(delivery("a", "workflow_job", `{"action": "queued"}`))},
+				{Position: "3", Data: []byte(delivery("c", "push", `{}`))},
+			},
+		},
+	}
+
+	var (
+		handled  []string
+		attempts int
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, FromBroker(r.Context()))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch r.Header.Get("X-GitHub-Event") {
+		case "push":
+			http.Error(w, "unsupported event", http.StatusBadRequest)
+			return
+		}
+
+		if string(body) == `{"action":"unavailable"}` {
+			attempts++
+			if attempts < 2 {
+				http.Error(w, "queue full", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// The payload is passed as is for the signature validation
+		handled = append(handled, r.Header.Get("X-GitHub-Delivery")+" "+string(body))
+	})
+
+	in := &Ingester{Log: logr.Discard(), Source: source, Handler: handler}
+
+	ctx := context.Background()
+
+	require.NoError(t, in.poll(ctx))
+	require.NoError(t, in.poll(ctx))
+
+	// The redelivery is left to the handler to skip
+	require.Equal(t, []string{`a {"action": "queued"}`, `b {"action":"unavailable"}`, `a {"action": "queued"}`}, handled)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, [][]string{{"0", "1", "2"}, {"0", "3"}}, source.committed)
+
+	require.False(t, FromBroker(ctx))
+}
@@ -0,0 +1,161 @@
+package webhookingest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	DefaultKafkaPollTimeout = 5 * time.Second
+
+	kafkaDialTimeout = 10 * time.Second
+)
+
+// The SASL mechanisms KafkaSource authenticates to the brokers with.
+const (
+	KafkaSASLPlain       = "plain"
+	KafkaSASLSCRAMSHA256 = "scram-sha-256"
+	KafkaSASLSCRAMSHA512 = "scram-sha-512"
+)
+
+// kafkaReader is the part of kafka.Reader KafkaSource consumes the messages with.
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSource consumes the messages of a Kafka topic as a member of a consumer group,
+// so that the offsets are managed by Kafka per consumer group, and the replicas of the webhook server share the partitions.
+//
+// The offsets are committed only after the messages are handled, with auto commit disabled.
+type KafkaSource struct {
+	// Brokers are the addresses of the bootstrap brokers, like kafka-0.kafka:9092.
+	Brokers []string
+
+	Topic string
+	Group string
+
+	// Username and Password authenticate to the brokers with SASLMechanism, which defaults to KafkaSASLPlain.
+	Username      string
+	Password      string
+	SASLMechanism string
+
+	// TLSConfig enables TLS to the brokers when set.
+	TLSConfig *tls.Config
+
+	PollTimeout time.Duration
+
+	reader kafkaReader
+}
+
+func (s *KafkaSource) Name() string {
+	return "kafka"
+}
+
+// subscribe joins the consumer group, unless it's already joined.
+func (s *KafkaSource) subscribe() error {
+	if s.reader != nil {
+		return nil
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:   kafkaDialTimeout,
+		DualStack: true,
+		TLS:       s.TLSConfig,
+	}
+
+	if s.Username != "" {
+		mechanism, err := s.saslMechanism()
+		if err != nil {
+			return err
+		}
+
+		dialer.SASLMechanism = mechanism
+	}
+
+	s.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     s.Brokers,
+		GroupID:     s.Group,
+		Topic:       s.Topic,
+		Dialer:      dialer,
+		StartOffset: kafka.FirstOffset,
+		// Commit synchronously only the handled messages
+		CommitInterval: 0,
+	})
+
+	return nil
+}
+
+func (s *KafkaSource) saslMechanism() (sasl.Mechanism, error) {
+	switch s.SASLMechanism {
+	case "", KafkaSASLPlain:
+		return plain.Mechanism{Username: s.Username, Password: s.Password}, nil
+	case KafkaSASLSCRAMSHA256:
+		return scram.Mechanism(scram.SHA256, s.Username, s.Password)
+	case KafkaSASLSCRAMSHA512:
+		return scram.Mechanism(scram.SHA512, s.Username, s.Password)
+	}
+
+	return nil, fmt.Errorf("unsupported SASL mechanism %q", s.SASLMechanism)
+}
+
+// Fetch returns the next message, waiting for it up to the poll timeout.
+// The reader prefetches the following messages in the background, so that they are returned without a round trip.
+func (s *KafkaSource) Fetch(ctx context.Context) ([]Message, error) {
+	if err := s.subscribe(); err != nil {
+		return nil, err
+	}
+
+	timeout := s.PollTimeout
+	if timeout <= 0 {
+		timeout = DefaultKafkaPollTimeout
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	m, err := s.reader.FetchMessage(fetchCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return []Message{{
+		Data:     m.Value,
+		Position: fmt.Sprintf("%s/%d@%d", m.Topic, m.Partition, m.Offset),
+		kafkaMsg: m,
+	}}, nil
+}
+
+// Commit commits the offsets of the messages to the consumer group.
+func (s *KafkaSource) Commit(ctx context.Context, msgs []Message) error {
+	kafkaMsgs := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		kafkaMsgs = append(kafkaMsgs, m.kafkaMsg)
+	}
+
+	return s.reader.CommitMessages(ctx, kafkaMsgs...)
+}
+
+// Close leaves the consumer group, so that its partitions are assigned to the other replicas right away.
+func (s *KafkaSource) Close(_ context.Context) error {
+	if s.reader == nil {
+		return nil
+	}
+
+	err := s.reader.Close()
+
+	s.reader = nil
+
+	return err
+}
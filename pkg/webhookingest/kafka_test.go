@@ -0,0 +1,87 @@
+package webhookingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKafkaReader struct {
+	msgs      []kafka.Message
+	committed []int64
+	closed    bool
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+
+	m := r.msgs[0]
+	r.msgs = r.msgs[1:]
+
+	return m, nil
+}
+
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+
+	return nil
+}
+
+func (r *fakeKafkaReader) Close() error {
+	r.closed = true
+
+	return nil
+}
+
+func TestKafkaSource(t *testing.T) {
+	reader := &fakeKafkaReader{
+		msgs: []kafka.Message{
+			{Topic: "github-webhooks", Partition: 1, Offset: 7, Value: []byte(`{"a":1}`)},
+		},
+	}
+
+	s := &KafkaSource{Topic: "github-webhooks", Group: "webhooks", PollTimeout: 1, reader: reader}
+
+	ctx := context.Background()
+
+	msgs, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, `{"a":1}`, string(msgs[0].Data))
+	require.Equal(t, "github-webhooks/1@7", msgs[0].Position)
+
+	require.NoError(t, s.Commit(ctx, msgs))
+	require.Equal(t, []int64{7}, reader.committed)
+
+	// No message arrived within the poll timeout
+	msgs, err = s.Fetch(ctx)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = s.Fetch(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.NoError(t, s.Close(ctx))
+	require.True(t, reader.closed)
+}
+
+func TestKafkaSourceSASLMechanism(t *testing.T) {
+	for _, name := range []string{"", KafkaSASLPlain, KafkaSASLSCRAMSHA256, KafkaSASLSCRAMSHA512} {
+		m, err := (&KafkaSource{Username: "arc", Password: "secret", SASLMechanism: name}).saslMechanism()
+		require.NoError(t, err)
+		require.NotNil(t, m)
+	}
+
+	_, err := (&KafkaSource{Username: "arc", SASLMechanism: "gssapi"}).saslMechanism()
+	require.ErrorContains(t, err, `unsupported SASL mechanism "gssapi"`)
+}
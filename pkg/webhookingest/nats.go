@@ -0,0 +1,154 @@
+package webhookingest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	DefaultNATSBatchSize   = 10
+	DefaultNATSPollTimeout = 5 * time.Second
+)
+
+// NATSSource consumes the messages of a NATS JetStream stream with a durable pull consumer, so that the stream tracks the acknowledged messages,
+// and the replicas of the webhook server share the messages of the consumer.
+// The consumer should be created beforehand with the explicit ack policy, like:
+//
+//	nats consumer add GITHUB_WEBHOOKS github-webhook-server --pull --ack explicit --deliver all --filter 'github.webhooks.>'
+//
+// The messages are acknowledged only after they are handled, and the unacknowledged ones are redelivered after the ack wait of the consumer.
+type NATSSource struct {
+	// URL is the NATS server, like nats://nats:4222, or tls://nats:4222 for TLS.
+	URL string
+
+	Stream   string
+	Consumer string
+
+	// Username and Password, or Token, authenticate to the server.
+	Username string
+	Password string
+	Token    string
+
+	BatchSize   int
+	PollTimeout time.Duration
+
+	TLSConfig *tls.Config
+
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+}
+
+func (s *NATSSource) Name() string {
+	return "nats"
+}
+
+// connect connects to the server and looks up the consumer, unless it's already connected.
+// The connection reconnects by itself once connected.
+func (s *NATSSource) connect(ctx context.Context) error {
+	if s.consumer != nil {
+		return nil
+	}
+
+	opts := []nats.Option{nats.Name("github-webhook-server"), nats.MaxReconnects(-1)}
+
+	if s.Username != "" {
+		opts = append(opts, nats.UserInfo(s.Username, s.Password))
+	}
+
+	if s.Token != "" {
+		opts = append(opts, nats.Token(s.Token))
+	}
+
+	if s.TLSConfig != nil {
+		opts = append(opts, nats.Secure(s.TLSConfig))
+	}
+
+	conn, err := nats.Connect(s.URL, opts...)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", s.URL, err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	consumer, err := js.Consumer(ctx, s.Stream, s.Consumer)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("looking up consumer %s of stream %s: %w", s.Consumer, s.Stream, err)
+	}
+
+	s.conn = conn
+	s.consumer = consumer
+
+	return nil
+}
+
+func (s *NATSSource) Fetch(ctx context.Context) ([]Message, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultNATSBatchSize
+	}
+
+	pollTimeout := s.PollTimeout
+	if pollTimeout <= 0 {
+		pollTimeout = DefaultNATSPollTimeout
+	}
+
+	batch, err := s.consumer.Fetch(batchSize, jetstream.FetchMaxWait(pollTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []Message
+
+	for m := range batch.Messages() {
+		position := m.Subject()
+		if md, err := m.Metadata(); err == nil {
+			position = fmt.Sprintf("%s@%d", md.Stream, md.Sequence.Stream)
+		}
+
+		msgs = append(msgs, Message{Data: m.Data(), Position: position, natsMsg: m})
+	}
+
+	// The messages received before the error are still handled, and the rest are redelivered after the ack wait
+	if err := batch.Error(); err != nil && len(msgs) == 0 {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+// Commit acknowledges the messages, and waits for the server to have processed the acknowledgements,
+// so that the messages are never redelivered once committed.
+func (s *NATSSource) Commit(ctx context.Context, msgs []Message) error {
+	for _, m := range msgs {
+		if err := m.natsMsg.DoubleAck(ctx); err != nil {
+			return fmt.Errorf("acknowledging %s: %w", m.Position, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *NATSSource) Close(_ context.Context) error {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+
+	s.conn = nil
+	s.consumer = nil
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package webhookingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNATSMsg struct {
+	jetstream.Msg
+
+	data  string
+	seq   uint64
+	acked *[]uint64
+}
+
+func (m *fakeNATSMsg) Data() []byte {
+	return []byte(m.data)
+}
+
+func (m *fakeNATSMsg) Subject() string {
+	return "github.webhooks.workflow_job"
+}
+
+func (m *fakeNATSMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Stream: "GITHUB", Sequence: jetstream.SequencePair{Stream: m.seq}}, nil
+}
+
+func (m *fakeNATSMsg) DoubleAck(context.Context) error {
+	*m.acked = append(*m.acked, m.seq)
+	return nil
+}
+
+type fakeNATSBatch struct {
+	msgs chan jetstream.Msg
+	err  error
+}
+
+func (b *fakeNATSBatch) Messages() <-chan jetstream.Msg {
+	return b.msgs
+}
+
+func (b *fakeNATSBatch) Error() error {
+	return b.err
+}
+
+type fakeNATSConsumer struct {
+	jetstream.Consumer
+
+	batches []*fakeNATSBatch
+	fetched []int
+}
+
+func (c *fakeNATSConsumer) Fetch(batch int, _ ...jetstream.FetchOpt) (jetstream.MessageBatch, error) {
+	c.fetched = append(c.fetched, batch)
+
+	b := c.batches[0]
+	c.batches = c.batches[1:]
+
+	return b, nil
+}
+
+func newFakeNATSBatch(err error, msgs ...jetstream.Msg) *fakeNATSBatch {
+	b := &fakeNATSBatch{msgs: make(chan jetstream.Msg, len(msgs)), err: err}
+	for _, m := range msgs {
+		b.msgs <- m
+	}
+	close(b.msgs)
+
+	return b
+}
+
+func TestNATSSource(t *testing.T) {
+	var acked []uint64
+
+	consumer := &fakeNATSConsumer{
+		batches: []*fakeNATSBatch{
+			newFakeNATSBatch(nil,
+				&fakeNATSMsg{data: `{"a":1}`, seq: 41, acked: &acked},
+				&fakeNATSMsg{data: `{"b":2}`, seq: 42, acked: &acked},
+			),
+			// The request expired without any message
+			newFakeNATSBatch(nil),
+			newFakeNATSBatch(jetstream.ErrConsumerDeleted),
+		},
+	}
+
+	s := &NATSSource{Stream: "GITHUB", Consumer: "arc", BatchSize: 5, consumer: consumer}
+
+	ctx := context.Background()
+
+	msgs, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, `{"a":1}`, string(msgs[0].Data))
+	require.Equal(t, `{"b":2}`, string(msgs[1].Data))
+	require.Equal(t, "GITHUB@41", msgs[0].Position)
+	require.Equal(t, "GITHUB@42", msgs[1].Position)
+
+	require.NoError(t, s.Commit(ctx, msgs))
+	require.Equal(t, []uint64{41, 42}, acked)
+
+	msgs, err = s.Fetch(ctx)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	_, err = s.Fetch(ctx)
+	require.ErrorIs(t, err, jetstream.ErrConsumerDeleted)
+
+	require.Equal(t, []int{5, 5, 5}, consumer.fetched)
+}
@@ -13,6 +13,25 @@ graceful_stop() {
     log.warning "Detected configuration error: dockerd should be running but is already nowhere. This is wrong. Ensure that your init system to NOT pass SIGTERM directly to dockerd!"
   fi
 
+  # The processes using the GPUs, like a CUDA process of the job, are given some time to complete
+  # before the runner agent is stopped, so that a scale down doesn't kill them mid-way.
+  if [ -n "${RUNNER_GPU_DRAIN_TIMEOUT}" ] && command -v nvidia-smi > /dev/null; then
+    log.notice "Waiting for RUNNER_GPU_DRAIN_TIMEOUT=$RUNNER_GPU_DRAIN_TIMEOUT seconds at most until the processes using the GPUs exit."
+    i=0
+    while [[ $i -lt $RUNNER_GPU_DRAIN_TIMEOUT ]]; do
+      gpu_pids=$(nvidia-smi --query-compute-apps=pid --format=csv,noheader 2>/dev/null | grep -v '^\s*$')
+      if [ -z "$gpu_pids" ]; then
+        log.notice "No process is using the GPUs."
+        break
+      fi
+      sleep 1
+      i=$((i+1))
+    done
+    if [[ $i -ge $RUNNER_GPU_DRAIN_TIMEOUT ]]; then
+      log.warning "The processes using the GPUs didn't exit within RUNNER_GPU_DRAIN_TIMEOUT=$RUNNER_GPU_DRAIN_TIMEOUT seconds. Stopping anyway."
+    fi
+  fi
+
   # The below procedure atomically removes the runner from GitHub Actions service,
   # to ensure that the runner is not running any job.
   # This is required to not terminate the actions runner agent while running the job.
@@ -34,13 +53,20 @@ graceful_stop() {
   # config.sh can result in this graceful stop process to get skipped.
   # In that case, the pod is eventually and forcefully terminated by ARC and K8s, resulting
   # in the possible running workflow job after this graceful stop process failed might get cancelled prematurely.
-  log.notice "Waiting for the runner to register first."
-  while ! [ -f /runner/.runner ]; do
-    sleep 1
-  done
-  log.notice "Observed that the runner has been registered."
+  #
+  # A runner started with the just-in-time configuration has neither the registration file nor the token to remove itself.
+  # ARC removes such runner from GitHub by its ID instead, so we just wait for the runner agent to stop by itself.
+  if [ -n "${ACTIONS_RUNNER_INPUT_JITCONFIG}" ] || [ -n "${RUNNER_JITCONFIG_FILE}" ]; then
+    log.notice "Skipped removing the runner as it was registered with the just-in-time configuration."
+  else
+    log.notice "Waiting for the runner to register first."
+    while ! [ -f /runner/.runner ]; do
+      sleep 1
+    done
+    log.notice "Observed that the runner has been registered."
+  fi
 
-  if ! /runner/config.sh remove --token "$RUNNER_TOKEN"; then
+  if [ -n "${ACTIONS_RUNNER_INPUT_JITCONFIG}" ] || [ -n "${RUNNER_JITCONFIG_FILE}" ] || ! /runner/config.sh remove --token "$RUNNER_TOKEN"; then
     i=0
     log.notice "Waiting for RUNNER_GRACEFUL_STOP_TIMEOUT=$RUNNER_GRACEFUL_STOP_TIMEOUT seconds until the runner agent to stop by itself."
     while [[ $i -lt $RUNNER_GRACEFUL_STOP_TIMEOUT ]]; do
@@ -0,0 +1,94 @@
+# The Windows counterpart of startup.sh, run as the entrypoint of the Windows runner image.
+$ErrorActionPreference = 'Stop'
+
+$RunnerAssetsDir = if ($env:RUNNER_ASSETS_DIR) { $env:RUNNER_ASSETS_DIR } else { 'C:\runnertmp' }
+$RunnerHome = if ($env:RUNNER_HOME) { $env:RUNNER_HOME } else { 'C:\runner' }
+
+if ($env:STARTUP_DELAY_IN_SECONDS) {
+  Write-Host "Delaying startup by $($env:STARTUP_DELAY_IN_SECONDS) seconds"
+  Start-Sleep -Seconds ([int]$env:STARTUP_DELAY_IN_SECONDS)
+}
+
+$GitHubURL = if ($env:GITHUB_URL) { $env:GITHUB_URL } else { 'https://github.com/' }
+if (-not $GitHubURL.EndsWith('/')) {
+  $GitHubURL = "$GitHubURL/"
+}
+
+if (-not $env:RUNNER_NAME) {
+  Write-Error 'RUNNER_NAME must be set'
+  exit 1
+}
+
+if ($env:RUNNER_ORG -and $env:RUNNER_REPO -and $env:RUNNER_ENTERPRISE) {
+  $Attach = "$($env:RUNNER_ORG)/$($env:RUNNER_REPO)"
+} elseif ($env:RUNNER_ORG) {
+  $Attach = $env:RUNNER_ORG
+} elseif ($env:RUNNER_REPO) {
+  $Attach = $env:RUNNER_REPO
+} elseif ($env:RUNNER_ENTERPRISE) {
+  $Attach = "enterprises/$($env:RUNNER_ENTERPRISE)"
+} else {
+  Write-Error 'At least one of RUNNER_ORG, RUNNER_REPO, or RUNNER_ENTERPRISE must be set'
+  exit 1
+}
+
+if (-not $env:RUNNER_TOKEN -and -not $env:ACTIONS_RUNNER_INPUT_JITCONFIG) {
+  Write-Error 'Either RUNNER_TOKEN or ACTIONS_RUNNER_INPUT_JITCONFIG must be set'
+  exit 1
+}
+
+if (-not (Test-Path $RunnerHome)) {
+  Write-Error "$RunnerHome should be an emptyDir mount. Please fix the pod spec."
+  exit 1
+}
+
+Copy-Item -Path "$RunnerAssetsDir\*" -Destination $RunnerHome -Recurse -Force
+Set-Location $RunnerHome
+
+if ($env:ACTIONS_RUNNER_INPUT_JITCONFIG) {
+  # The runner has already been registered along with the just-in-time configuration,
+  # which run.cmd reads from ACTIONS_RUNNER_INPUT_JITCONFIG.
+  Write-Host 'Skipping the configuration as the just-in-time configuration is provided.'
+} else {
+  $ConfigArgs = @(
+    '--unattended', '--replace',
+    '--name', $env:RUNNER_NAME,
+    '--url', "$GitHubURL$Attach",
+    '--token', $env:RUNNER_TOKEN,
+    '--labels', $env:RUNNER_LABELS,
+    '--work', $env:RUNNER_WORKDIR
+  )
+  if (-not $env:RUNNER_REPO -and $env:RUNNER_GROUP) {
+    $ConfigArgs += @('--runnergroup', $env:RUNNER_GROUP)
+  }
+  if ($env:RUNNER_FEATURE_FLAG_ONCE -ne 'true' -and $env:RUNNER_EPHEMERAL -eq 'true') {
+    $ConfigArgs += '--ephemeral'
+  }
+  if ($env:DISABLE_RUNNER_UPDATE -eq 'true') {
+    $ConfigArgs += '--disableupdate'
+  }
+
+  for ($retries = 10; $retries -gt 0; $retries--) {
+    Write-Host 'Configuring the runner.'
+    & .\config.cmd @ConfigArgs
+    if (Test-Path .runner) {
+      Write-Host 'Runner successfully configured.'
+      break
+    }
+    Write-Host 'Configuration failed. Retrying'
+    Start-Sleep -Seconds 1
+  }
+
+  if (-not (Test-Path .runner)) {
+    Write-Error 'Configuration failed!'
+    exit 2
+  }
+
+  Get-Content .runner
+}
+
+# Unset entrypoint environment variables so they don't leak into the runner environment
+Remove-Item Env:RUNNER_NAME, Env:RUNNER_REPO, Env:RUNNER_TOKEN, Env:STARTUP_DELAY_IN_SECONDS -ErrorAction SilentlyContinue
+
+& .\run.cmd
+exit $LASTEXITCODE
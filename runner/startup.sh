@@ -45,8 +45,17 @@ else
   exit 1
 fi
 
-if [ -z "${RUNNER_TOKEN}" ]; then
-  log.error 'RUNNER_TOKEN must be set'
+if [ -n "${RUNNER_JITCONFIG_FILE}" ]; then
+  # The just-in-time configuration handed over via Vault is read once,
+  # and removed unless it's on the read-only volume of the Secrets Store CSI driver.
+  log.debug "Reading the just-in-time configuration from ${RUNNER_JITCONFIG_FILE}"
+  ACTIONS_RUNNER_INPUT_JITCONFIG="$(cat "${RUNNER_JITCONFIG_FILE}")"
+  export ACTIONS_RUNNER_INPUT_JITCONFIG
+  rm -f "${RUNNER_JITCONFIG_FILE}" 2>/dev/null || true
+fi
+
+if [ -z "${RUNNER_TOKEN}" ] && [ -z "${ACTIONS_RUNNER_INPUT_JITCONFIG}" ]; then
+  log.error 'Either RUNNER_TOKEN or ACTIONS_RUNNER_INPUT_JITCONFIG must be set'
   exit 1
 fi
 
@@ -88,56 +97,69 @@ if [ "${DISABLE_RUNNER_UPDATE:-}" == "true" ]; then
   log.debug 'Passing --disableupdate to config.sh to disable automatic runner updates.'
 fi
 
+if [ -n "${RUNNER_PRE_REGISTER_HOOK:-}" ]; then
+  if ! lifecycle-hook preRegister; then
+    log.error 'The preRegister lifecycle hook failed. Exiting without registering the runner.'
+    exit 1
+  fi
+fi
+
 update-status "Registering"
 
-retries_left=10
-while [[ ${retries_left} -gt 0 ]]; do
-  log.debug 'Configuring the runner.'
-  ./config.sh --unattended --replace \
-    --name "${RUNNER_NAME}" \
-    --url "${GITHUB_URL}${ATTACH}" \
-    --token "${RUNNER_TOKEN}" \
-    --runnergroup "${RUNNER_GROUPS}" \
-    --labels "${RUNNER_LABELS}" \
-    --work "${RUNNER_WORKDIR}" "${config_args[@]}"
-
-  if [ -f .runner ]; then
-    log.debug 'Runner successfully configured.'
-    break
-  fi
+if [ -n "${ACTIONS_RUNNER_INPUT_JITCONFIG}" ]; then
+  # The runner has already been registered along with the just-in-time configuration,
+  # which run.sh reads from ACTIONS_RUNNER_INPUT_JITCONFIG.
+  log.debug 'Skipping the configuration as the just-in-time configuration is provided.'
+else
+  retries_left=10
+  while [[ ${retries_left} -gt 0 ]]; do
+    log.debug 'Configuring the runner.'
+    ./config.sh --unattended --replace \
+      --name "${RUNNER_NAME}" \
+      --url "${GITHUB_URL}${ATTACH}" \
+      --token "${RUNNER_TOKEN}" \
+      --runnergroup "${RUNNER_GROUPS}" \
+      --labels "${RUNNER_LABELS}" \
+      --work "${RUNNER_WORKDIR}" "${config_args[@]}"
+
+    if [ -f .runner ]; then
+      log.debug 'Runner successfully configured.'
+      break
+    fi
 
-  log.debug 'Configuration failed. Retrying'
-  retries_left=$((retries_left - 1))
-  sleep 1
-done
+    log.debug 'Configuration failed. Retrying'
+    retries_left=$((retries_left - 1))
+    sleep 1
+  done
 
-if [ ! -f .runner ]; then
-  # we couldn't configure and register the runner; no point continuing
-  log.error 'Configuration failed!'
-  exit 2
-fi
+  if [ ! -f .runner ]; then
+    # we couldn't configure and register the runner; no point continuing
+    log.error 'Configuration failed!'
+    exit 2
+  fi
 
-cat .runner
-# Note: the `.runner` file's content should be something like the below:
-#
-# $ cat /runner/.runner
-# {
-# "agentId": 117, #=> corresponds to the ID of the runner
-# "agentName": "THE_RUNNER_POD_NAME",
-# "poolId": 1,
-# "poolName": "Default",
-# "serverUrl": "https://pipelines.actions.githubusercontent.com/SOME_RANDOM_ID",
-# "gitHubUrl": "https://github.com/USER/REPO",
-# "workFolder": "/some/work/dir" #=> corresponds to Runner.Spec.WorkDir
-# }
-#
-# Especially `agentId` is important, as other than listing all the runners in the repo,
-# this is the only change we could get the exact runnner ID which can be useful for further
-# GitHub API call like the below. Note that 171 is the agentId seen above.
-#   curl \
-#     -H "Accept: application/vnd.github.v3+json" \
-#     -H "Authorization: bearer ${GITHUB_TOKEN}"
-#     https://api.github.com/repos/USER/REPO/actions/runners/171
+  cat .runner
+  # Note: the `.runner` file's content should be something like the below:
+  #
+  # $ cat /runner/.runner
+  # {
+  # "agentId": 117, #=> corresponds to the ID of the runner
+  # "agentName": "THE_RUNNER_POD_NAME",
+  # "poolId": 1,
+  # "poolName": "Default",
+  # "serverUrl": "https://pipelines.actions.githubusercontent.com/SOME_RANDOM_ID",
+  # "gitHubUrl": "https://github.com/USER/REPO",
+  # "workFolder": "/some/work/dir" #=> corresponds to Runner.Spec.WorkDir
+  # }
+  #
+  # Especially `agentId` is important, as other than listing all the runners in the repo,
+  # this is the only change we could get the exact runnner ID which can be useful for further
+  # GitHub API call like the below. Note that 171 is the agentId seen above.
+  #   curl \
+  #     -H "Accept: application/vnd.github.v3+json" \
+  #     -H "Authorization: bearer ${GITHUB_TOKEN}"
+  #     https://api.github.com/repos/USER/REPO/actions/runners/171
+fi
 
 # Hack due to the DinD volumes
 if [ -z "${UNITTEST:-}" ] && [ -e ./externalstmp ]; then
@@ -158,7 +180,7 @@ else
 fi
 
 # Unset entrypoint environment variables so they don't leak into the runner environment
-unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
+unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN RUNNER_JITCONFIG_FILE STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
 
 # Docker ignores PAM and thus never loads the system environment variables that
 # are meant to be set in every environment of every user. We emulate the PAM